@@ -0,0 +1,52 @@
+package http_receiver
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+
+	"github.com/jdelaire/openslack/core/localhttp"
+)
+
+// DefaultAddr is where the receiver listens unless configured.
+const DefaultAddr = "127.0.0.1:8789"
+
+// TokenAccount is the keychain account holding the receiver's access
+// token.
+const TokenAccount = "http-receiver-token"
+
+// Config sets where the receiver listens and which chat its commands
+// come from. Addr must be a loopback address. ChatID is what the
+// allowlist, TOTP, approvals and the rest of the daemon see, and where
+// replies go.
+type Config struct {
+	Addr   string `json:"addr"`
+	ChatID int64  `json:"chat_id"`
+}
+
+// LoadConfig reads and validates an HTTP receiver config file.
+// Returns nil, nil if the file does not exist.
+func LoadConfig(path string) (*Config, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("read http receiver config: %w", err)
+	}
+
+	var cfg Config
+	if err := json.Unmarshal(data, &cfg); err != nil {
+		return nil, fmt.Errorf("parse http receiver config: %w", err)
+	}
+	if cfg.Addr == "" {
+		cfg.Addr = DefaultAddr
+	}
+	if err := localhttp.CheckLoopback(cfg.Addr); err != nil {
+		return nil, fmt.Errorf("http receiver %w", err)
+	}
+	if cfg.ChatID == 0 {
+		return nil, fmt.Errorf("http receiver config: chat_id is required")
+	}
+	return &cfg, nil
+}
@@ -0,0 +1,32 @@
+package http_receiver_test
+
+import (
+	"testing"
+
+	"github.com/jdelaire/openslack/adapters/http_receiver"
+	"github.com/jdelaire/openslack/core"
+	"github.com/jdelaire/openslack/core/adaptertest"
+)
+
+func TestConformance(t *testing.T) {
+	adaptertest.TestReceiver(t, adaptertest.ReceiverHarness{
+		New: func(t *testing.T, texts []string, handler core.MessageHandler) core.Receiver {
+			r := http_receiver.New("tok", testConfig(), handler, testLogger())
+			for _, text := range texts {
+				if code, body := post(r, "tok", map[string]string{"text": text}); code != 202 {
+					t.Fatalf("post %.20q = %d %s", text, code, body)
+				}
+			}
+			return r
+		},
+		NewFailing: func(t *testing.T, handler core.MessageHandler) core.Receiver {
+			r := http_receiver.New("tok", testConfig(), handler, testLogger())
+			for _, text := range []string{"/status", "/uptime"} {
+				if code, _ := post(r, "wrong", map[string]string{"text": text}); code != 401 {
+					t.Fatalf("post with wrong token = %d", code)
+				}
+			}
+			return r
+		},
+	})
+}
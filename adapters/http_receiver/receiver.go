@@ -0,0 +1,163 @@
+// Package http_receiver takes commands over HTTP, so CI jobs and scripts
+// on the same machine can run ops without Telegram. A POST to
+// /v1/commands becomes a message from the configured chat, handled like
+// one typed in Telegram; replies go to that chat.
+package http_receiver
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/jdelaire/openslack/core"
+	"github.com/jdelaire/openslack/core/localhttp"
+)
+
+const (
+	// maxBody bounds request bodies.
+	maxBody = 64 << 10
+	// queueLen is how many commands may wait for the handler before
+	// requests are refused with 503.
+	queueLen = 16
+)
+
+// Receiver serves POST /v1/commands and passes each command to the
+// handler, one at a time and in the order they were accepted. Requests
+// must carry the access token and name a loopback host; see
+// localhttp.Guard.
+//
+// Each command gets an UpdateID from the time in microseconds, bumped
+// past every ID handed out so far. It has no Telegram message to reply
+// to, so MessageID and UserID are left zero.
+type Receiver struct {
+	token   string
+	cfg     *Config
+	handler core.MessageHandler
+	logger  *slog.Logger
+	now     func() time.Time
+	queue   chan core.InboundMessage
+
+	mu     sync.Mutex
+	lastID int64
+	run    localhttp.Runner
+}
+
+// New creates an HTTP receiver with the access token and config. A nil
+// cfg listens on DefaultAddr as chat 0, which no allowlist admits.
+func New(token string, cfg *Config, handler core.MessageHandler, logger *slog.Logger) *Receiver {
+	if cfg == nil {
+		cfg = &Config{Addr: DefaultAddr}
+	}
+	if logger == nil {
+		logger = slog.Default()
+	}
+	return &Receiver{
+		token:   token,
+		cfg:     cfg,
+		handler: handler,
+		logger:  logger,
+		now:     time.Now,
+		queue:   make(chan core.InboundMessage, queueLen),
+	}
+}
+
+// Handler returns the receiver's routes.
+func (r *Receiver) Handler() http.Handler {
+	mux := http.NewServeMux()
+	mux.HandleFunc("POST /v1/commands", r.command)
+	return localhttp.Guard(r.token, "OpenSlack commands", localhttp.RequireJSON(maxBody, mux))
+}
+
+// Start listens on the configured address and hands accepted commands to
+// the handler until ctx is cancelled. Commands still queued then are
+// dropped. It fails at once without a token or if it cannot listen.
+func (r *Receiver) Start(ctx context.Context) error {
+	if r.token == "" {
+		return errors.New("http receiver: no access token")
+	}
+	if err := localhttp.CheckLoopback(r.cfg.Addr); err != nil {
+		return fmt.Errorf("http receiver %w", err)
+	}
+	if err := r.run.Start(ctx, "http receiver", r.cfg.Addr, r.Handler(), r.logger); err != nil {
+		return err
+	}
+
+	for {
+		select {
+		case msg := <-r.queue:
+			r.handler(msg)
+		case <-ctx.Done():
+			r.logger.Info("http receiver stopped")
+			return nil
+		}
+	}
+}
+
+// Addr returns the address Start is listening on, or "" before Start.
+func (r *Receiver) Addr() string { return r.run.Addr() }
+
+// command queues a posted {"text": "/status"} and replies 202 with the
+// update ID it was given. The body must be declared as JSON; see
+// localhttp.RequireJSON.
+func (r *Receiver) command(w http.ResponseWriter, req *http.Request) {
+	var body struct {
+		Text string `json:"text"`
+	}
+	dec := json.NewDecoder(req.Body)
+	dec.DisallowUnknownFields()
+	if err := dec.Decode(&body); err != nil {
+		writeError(w, http.StatusBadRequest, "invalid body: "+err.Error())
+		return
+	}
+	text := strings.TrimSpace(body.Text)
+	switch {
+	case text == "":
+		writeError(w, http.StatusBadRequest, "text is required")
+		return
+	case len(text) > core.MaxTextLen:
+		writeError(w, http.StatusBadRequest, fmt.Sprintf("text exceeds %d character limit", core.MaxTextLen))
+		return
+	}
+
+	id, ok := r.enqueue(text)
+	if !ok {
+		w.Header().Set("Retry-After", "1")
+		writeError(w, http.StatusServiceUnavailable, "busy: too many commands waiting")
+		return
+	}
+	r.logger.Info("http receiver command", "update_id", id)
+	writeJSON(w, http.StatusAccepted, map[string]int64{"update_id": id})
+}
+
+// enqueue gives text the next update ID and queues it, reporting false if
+// the queue is full. Both happen under one lock, so the handler sees IDs
+// in increasing order.
+func (r *Receiver) enqueue(text string) (int64, bool) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	now := r.now()
+	id := max(now.UnixMicro(), r.lastID+1)
+	select {
+	case r.queue <- core.InboundMessage{UpdateID: id, ChatID: r.cfg.ChatID, Text: text, Timestamp: now}:
+		r.lastID = id
+		return id, true
+	default:
+		return 0, false
+	}
+}
+
+func writeError(w http.ResponseWriter, code int, msg string) {
+	writeJSON(w, code, map[string]string{"error": msg})
+}
+
+func writeJSON(w http.ResponseWriter, code int, v any) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(code)
+	json.NewEncoder(w).Encode(v)
+}
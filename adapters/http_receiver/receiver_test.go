@@ -0,0 +1,174 @@
+package http_receiver_test
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/jdelaire/openslack/adapters/http_receiver"
+	"github.com/jdelaire/openslack/core"
+)
+
+func testLogger() *slog.Logger {
+	return slog.New(slog.NewTextHandler(io.Discard, nil))
+}
+
+func testConfig() *http_receiver.Config {
+	return &http_receiver.Config{Addr: "127.0.0.1:0", ChatID: 100}
+}
+
+// post sends body to r's handler as JSON with token, from a loopback host.
+func post(r *http_receiver.Receiver, token string, body any) (int, string) {
+	data, _ := json.Marshal(body)
+	req := httptest.NewRequest(http.MethodPost, "http://127.0.0.1/v1/commands", bytes.NewReader(data))
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("Authorization", "Bearer "+token)
+	rec := httptest.NewRecorder()
+	r.Handler().ServeHTTP(rec, req)
+	return rec.Code, rec.Body.String()
+}
+
+func TestCommand(t *testing.T) {
+	got := make(chan core.InboundMessage, 1)
+	r := http_receiver.New("tok", testConfig(), func(msg core.InboundMessage) { got <- msg }, testLogger())
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go r.Start(ctx)
+
+	deadline := time.Now().Add(5 * time.Second)
+	for r.Addr() == "" && time.Now().Before(deadline) {
+		time.Sleep(10 * time.Millisecond)
+	}
+	req, _ := http.NewRequest(http.MethodPost, "http://"+r.Addr()+"/v1/commands", strings.NewReader(`{"text": "  /deploy api  "}`))
+	req.Header.Set("Content-Type", "application/json; charset=utf-8")
+	req.Header.Set("Authorization", "Bearer tok")
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var reply struct {
+		UpdateID int64 `json:"update_id"`
+	}
+	json.NewDecoder(resp.Body).Decode(&reply)
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusAccepted || reply.UpdateID == 0 {
+		t.Fatalf("status %d, update_id %d", resp.StatusCode, reply.UpdateID)
+	}
+
+	select {
+	case msg := <-got:
+		if msg.Text != "/deploy api" || msg.ChatID != 100 || msg.UpdateID != reply.UpdateID || msg.MessageID != 0 || time.Since(msg.Timestamp) > time.Minute {
+			t.Errorf("message = %+v", msg)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("command not handled")
+	}
+}
+
+func TestCommandRejects(t *testing.T) {
+	r := http_receiver.New("tok", testConfig(), func(core.InboundMessage) {}, testLogger())
+
+	tests := []struct {
+		name  string
+		token string
+		body  any
+		want  int
+	}{
+		{"wrong token", "nope", map[string]string{"text": "/status"}, http.StatusUnauthorized},
+		{"empty text", "tok", map[string]string{"text": "  "}, http.StatusBadRequest},
+		{"unknown field", "tok", map[string]string{"text": "/status", "chat_id": "1"}, http.StatusBadRequest},
+		{"too long", "tok", map[string]string{"text": "/echo " + strings.Repeat("x", core.MaxTextLen)}, http.StatusBadRequest},
+	}
+	for _, tt := range tests {
+		if code, body := post(r, tt.token, tt.body); code != tt.want {
+			t.Errorf("%s: status %d %s, want %d", tt.name, code, body, tt.want)
+		}
+	}
+
+	req := httptest.NewRequest(http.MethodPost, "http://127.0.0.1/v1/commands", strings.NewReader(`{"text": "/status"}`))
+	req.Header.Set("Content-Type", "text/plain")
+	req.Header.Set("Authorization", "Bearer tok")
+	rec := httptest.NewRecorder()
+	r.Handler().ServeHTTP(rec, req)
+	if rec.Code != http.StatusUnsupportedMediaType {
+		t.Errorf("text/plain: status %d", rec.Code)
+	}
+
+	req = httptest.NewRequest(http.MethodPost, "http://ci.example.com/v1/commands", strings.NewReader(`{"text": "/status"}`))
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("Authorization", "Bearer tok")
+	rec = httptest.NewRecorder()
+	r.Handler().ServeHTTP(rec, req)
+	if rec.Code != http.StatusForbidden {
+		t.Errorf("non-loopback host: status %d", rec.Code)
+	}
+}
+
+func TestCommandQueueFull(t *testing.T) {
+	// Without Start nothing drains the queue.
+	r := http_receiver.New("tok", testConfig(), func(core.InboundMessage) {}, testLogger())
+	var code int
+	var last int64
+	for i := 0; ; i++ {
+		var body string
+		code, body = post(r, "tok", map[string]string{"text": "/status"})
+		if code != http.StatusAccepted {
+			break
+		}
+		var reply struct {
+			UpdateID int64 `json:"update_id"`
+		}
+		json.Unmarshal([]byte(body), &reply)
+		if reply.UpdateID <= last {
+			t.Fatalf("update ID %d after %d", reply.UpdateID, last)
+		}
+		last = reply.UpdateID
+		if i > 100 {
+			t.Fatal("queue never filled")
+		}
+	}
+	if code != http.StatusServiceUnavailable {
+		t.Errorf("full queue: status %d", code)
+	}
+}
+
+func TestStartWithoutToken(t *testing.T) {
+	r := http_receiver.New("", testConfig(), func(core.InboundMessage) {}, testLogger())
+	if err := r.Start(context.Background()); err == nil {
+		t.Error("Start without a token succeeded")
+	}
+}
+
+func TestLoadConfig(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "http_receiver.json")
+
+	cfg, err := http_receiver.LoadConfig(path)
+	if cfg != nil || err != nil {
+		t.Fatalf("missing file = %v, %v; want nil, nil", cfg, err)
+	}
+
+	os.WriteFile(path, []byte(`{"chat_id": 100}`), 0600)
+	cfg, err = http_receiver.LoadConfig(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if cfg.Addr != http_receiver.DefaultAddr || cfg.ChatID != 100 {
+		t.Errorf("config = %+v", cfg)
+	}
+
+	for _, bad := range []string{`{}`, `{"chat_id": 100, "addr": "0.0.0.0:8789"}`, `{"chat_id": "100"}`} {
+		os.WriteFile(path, []byte(bad), 0600)
+		if _, err := http_receiver.LoadConfig(path); err == nil {
+			t.Errorf("LoadConfig(%s) succeeded", bad)
+		}
+	}
+}
@@ -0,0 +1,236 @@
+// Package slack_notifier sends notifications to Slack channels with the
+// Web API's chat.postMessage.
+package slack_notifier
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"github.com/jdelaire/openslack/core"
+)
+
+// BotTokenAccount is the keychain account holding the bot token
+// (xoxb-...) messages are posted with.
+const BotTokenAccount = "slack_bot_token"
+
+// maxSectionLen is the most text a section block holds.
+const maxSectionLen = 3000
+
+// Notifier sends notifications via the Slack Web API. Chat IDs map to
+// channels as in the Slack config; a notification without one goes to the
+// default channel.
+type Notifier struct {
+	botToken       string
+	channels       map[int64]string
+	defaultChannel string
+	client         *http.Client
+	baseURL        string
+}
+
+// New creates a Slack notifier with the bot token, the channel of each
+// chat ID, and the default channel.
+func New(botToken string, channels map[int64]string, defaultChannel string) *Notifier {
+	return &Notifier{
+		botToken:       botToken,
+		channels:       channels,
+		defaultChannel: defaultChannel,
+		client:         &http.Client{Timeout: 10 * time.Second},
+		baseURL:        "https://slack.com/api",
+	}
+}
+
+// WithBaseURL sets a custom base URL (for testing).
+func (n *Notifier) WithBaseURL(baseURL string) *Notifier {
+	n.baseURL = baseURL
+	return n
+}
+
+func (n *Notifier) Name() string { return "slack" }
+
+// block is a Block Kit block; only the fields used here.
+type block struct {
+	Type     string   `json:"type"`
+	Text     *text    `json:"text,omitempty"`
+	Elements []button `json:"elements,omitempty"`
+}
+
+type text struct {
+	Type string `json:"type"`
+	Text string `json:"text"`
+}
+
+type button struct {
+	Type     string `json:"type"`
+	Text     text   `json:"text"`
+	Value    string `json:"value"`
+	ActionID string `json:"action_id"`
+}
+
+type postMessage struct {
+	Channel  string  `json:"channel"`
+	Text     string  `json:"text"`
+	Mrkdwn   bool    `json:"mrkdwn"`
+	ThreadTS string  `json:"thread_ts,omitempty"`
+	Blocks   []block `json:"blocks,omitempty"`
+}
+
+// Send delivers notif with chat.postMessage, in a thread under ReplyTo if
+// set. Structured notifications are rendered as mrkdwn; plain ones are
+// shown as written. Buttons come as an actions block under the text.
+// Slack has no silent messages, so Silent and low priority change nothing.
+func (n *Notifier) Send(ctx context.Context, notif core.Notification) error {
+	channel := n.defaultChannel
+	if notif.ChatID != 0 {
+		var ok bool
+		if channel, ok = n.channels[notif.ChatID]; !ok {
+			return fmt.Errorf("slack: no channel for chat %d", notif.ChatID)
+		}
+	}
+	msg := postMessage{Channel: channel, Text: escape(notif.Text)}
+	if notif.Structured() {
+		msg.Text = renderMrkdwn(notif)
+		msg.Mrkdwn = true
+	}
+	if notif.ReplyTo != 0 {
+		msg.ThreadTS = formatTS(notif.ReplyTo)
+	}
+	if len(notif.Buttons) > 0 {
+		msg.Blocks = blocks(msg.Text, msg.Mrkdwn, notif.Buttons)
+	}
+	return n.post(ctx, "chat.postMessage", msg)
+}
+
+// post calls a Web API method with a JSON body.
+func (n *Notifier) post(ctx context.Context, method string, body any) error {
+	data, err := json.Marshal(body)
+	if err != nil {
+		return fmt.Errorf("slack request: %w", err)
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, n.baseURL+"/"+method, bytes.NewReader(data))
+	if err != nil {
+		return fmt.Errorf("slack request: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/json; charset=utf-8")
+	req.Header.Set("Authorization", "Bearer "+n.botToken)
+
+	resp, err := n.client.Do(req)
+	if err != nil {
+		return fmt.Errorf("slack request: %w", err)
+	}
+	defer resp.Body.Close()
+
+	return checkResponse(resp)
+}
+
+// checkResponse reports a failed call. Slack answers most errors with
+// status 200 and ok false.
+func checkResponse(resp *http.Response) error {
+	if resp.StatusCode == http.StatusTooManyRequests {
+		return fmt.Errorf("slack API error 429: rate limited, retry after %ss", resp.Header.Get("Retry-After"))
+	}
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("slack API error %d", resp.StatusCode)
+	}
+	var body struct {
+		OK    bool   `json:"ok"`
+		Error string `json:"error"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&body); err != nil {
+		return fmt.Errorf("slack response: %w", err)
+	}
+	if !body.OK {
+		return fmt.Errorf("slack API error: %s", body.Error)
+	}
+	return nil
+}
+
+// blocks lays out msg as section blocks, split to fit, followed by the
+// buttons. A button's value is its data, which the receiver passes back
+// as the message text.
+func blocks(msg string, mrkdwn bool, buttons []core.Button) []block {
+	textType := "plain_text"
+	if mrkdwn {
+		textType = "mrkdwn"
+	}
+	var out []block
+	for msg != "" {
+		part := msg
+		if len(part) > maxSectionLen {
+			cut := maxSectionLen
+			for cut > 0 && !utf8.RuneStart(msg[cut]) {
+				cut--
+			}
+			part = msg[:cut]
+		}
+		out = append(out, block{Type: "section", Text: &text{Type: textType, Text: part}})
+		msg = msg[len(part):]
+	}
+	actions := block{Type: "actions"}
+	for i, b := range buttons {
+		actions.Elements = append(actions.Elements, button{
+			Type:     "button",
+			Text:     text{Type: "plain_text", Text: b.Text},
+			Value:    b.Data,
+			ActionID: fmt.Sprintf("button-%d", i),
+		})
+	}
+	return append(out, actions)
+}
+
+// renderMrkdwn renders a structured notification in Slack's mrkdwn: a
+// bold title flagged by priority, the text, bold field names, hashtags and
+// the link.
+func renderMrkdwn(notif core.Notification) string {
+	var sections []string
+	head := notif.Title
+	switch notif.Priority {
+	case core.PriorityHigh:
+		head = strings.TrimSpace("❗ " + head)
+	case core.PriorityUrgent:
+		head = strings.TrimSpace("🚨 " + head)
+	}
+	first := ""
+	if head != "" {
+		first = "*" + escape(head) + "*"
+	}
+	if notif.Text != "" {
+		if first != "" {
+			first += "\n"
+		}
+		first += escape(notif.Text)
+	}
+	if first != "" {
+		sections = append(sections, first)
+	}
+	if len(notif.Fields) > 0 {
+		lines := make([]string, len(notif.Fields))
+		for i, f := range notif.Fields {
+			lines[i] = "*" + escape(f.Name) + ":* " + escape(f.Value)
+		}
+		sections = append(sections, strings.Join(lines, "\n"))
+	}
+	if len(notif.Tags) > 0 {
+		sections = append(sections, escape("#"+strings.Join(notif.Tags, " #")))
+	}
+	if notif.URL != "" {
+		sections = append(sections, "<"+escape(notif.URL)+">")
+	}
+	return strings.Join(sections, "\n\n")
+}
+
+// escape escapes the characters Slack treats as markup in message text.
+func escape(s string) string {
+	return strings.NewReplacer("&", "&amp;", "<", "&lt;", ">", "&gt;").Replace(s)
+}
+
+// formatTS turns a message ID back into the Slack timestamp it came from:
+// 1712345678000100 is "1712345678.000100".
+func formatTS(id int64) string {
+	return fmt.Sprintf("%d.%06d", id/1e6, id%1e6)
+}
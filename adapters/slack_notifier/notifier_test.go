@@ -0,0 +1,173 @@
+package slack_notifier
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/jdelaire/openslack/core"
+)
+
+func newTestNotification() core.Notification {
+	return core.Notification{
+		ID:        "test-id",
+		Text:      "hello from test",
+		Source:    "test",
+		CreatedAt: time.Now(),
+	}
+}
+
+// recorder is a fake Web API that records each request's JSON body.
+func recorder(t *testing.T, bodies *[]map[string]any) *httptest.Server {
+	t.Helper()
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/chat.postMessage" {
+			t.Errorf("unexpected path: %s", r.URL.Path)
+		}
+		if got := r.Header.Get("Authorization"); got != "Bearer xoxb-test" {
+			t.Errorf("Authorization = %q", got)
+		}
+		var body map[string]any
+		if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+			t.Errorf("decode body: %v", err)
+		}
+		*bodies = append(*bodies, body)
+		w.Write([]byte(`{"ok":true}`))
+	}))
+	t.Cleanup(srv.Close)
+	return srv
+}
+
+func TestNotifier_SendSuccess(t *testing.T) {
+	var bodies []map[string]any
+	srv := recorder(t, &bodies)
+
+	n := New("xoxb-test", map[int64]string{1: "C1", 2: "C2"}, "C1").WithBaseURL(srv.URL)
+	notif := newTestNotification()
+	notif.Text = "a < b & *c*"
+	if err := n.Send(context.Background(), notif); err != nil {
+		t.Fatalf("Send: %v", err)
+	}
+	body := bodies[0]
+	if body["channel"] != "C1" || body["text"] != "a &lt; b &amp; *c*" || body["mrkdwn"] != false {
+		t.Errorf("sent %v", body)
+	}
+	if _, ok := body["thread_ts"]; ok {
+		t.Errorf("thread_ts set without ReplyTo: %v", body)
+	}
+}
+
+func TestNotifier_SendToChat(t *testing.T) {
+	var bodies []map[string]any
+	srv := recorder(t, &bodies)
+
+	n := New("xoxb-test", map[int64]string{1: "C1", 2: "C2"}, "C1").WithBaseURL(srv.URL)
+	notif := newTestNotification()
+	notif.ChatID = 2
+	notif.ReplyTo = 1712345678000100
+	if err := n.Send(context.Background(), notif); err != nil {
+		t.Fatalf("Send: %v", err)
+	}
+	if body := bodies[0]; body["channel"] != "C2" || body["thread_ts"] != "1712345678.000100" {
+		t.Errorf("sent %v, want channel C2 in thread 1712345678.000100", body)
+	}
+
+	notif.ChatID = 3
+	if err := n.Send(context.Background(), notif); err == nil || !strings.Contains(err.Error(), "no channel for chat 3") {
+		t.Errorf("Send to unknown chat = %v", err)
+	}
+	if len(bodies) != 1 {
+		t.Errorf("sent %d messages, want the unknown chat skipped", len(bodies))
+	}
+}
+
+func TestNotifier_SendButtons(t *testing.T) {
+	var bodies []map[string]any
+	srv := recorder(t, &bodies)
+
+	n := New("xoxb-test", nil, "C1").WithBaseURL(srv.URL)
+	notif := newTestNotification()
+	notif.Text = strings.Repeat("é", maxSectionLen) // two bytes each
+	notif.Buttons = []core.Button{{Text: "Approve", Data: "/approve abc"}, {Text: "Reject", Data: "/reject abc"}}
+	if err := n.Send(context.Background(), notif); err != nil {
+		t.Fatalf("Send: %v", err)
+	}
+
+	var got struct {
+		Blocks []block `json:"blocks"`
+	}
+	data, _ := json.Marshal(bodies[0])
+	json.Unmarshal(data, &got)
+	if len(got.Blocks) != 3 {
+		t.Fatalf("blocks = %+v, want two sections and actions", got.Blocks)
+	}
+	joined := ""
+	for _, b := range got.Blocks[:2] {
+		if b.Type != "section" || b.Text.Type != "plain_text" || len(b.Text.Text) > maxSectionLen {
+			t.Errorf("section = %s %s of %d bytes", b.Type, b.Text.Type, len(b.Text.Text))
+		}
+		joined += b.Text.Text
+	}
+	if joined != notif.Text {
+		t.Error("sections do not add up to the text")
+	}
+	actions := got.Blocks[2]
+	if actions.Type != "actions" || len(actions.Elements) != 2 {
+		t.Fatalf("actions = %+v", actions)
+	}
+	if b := actions.Elements[1]; b.Text.Text != "Reject" || b.Value != "/reject abc" || b.ActionID != "button-1" {
+		t.Errorf("button = %+v", b)
+	}
+}
+
+func TestNotifier_SendStructured(t *testing.T) {
+	var bodies []map[string]any
+	srv := recorder(t, &bodies)
+
+	notif := newTestNotification()
+	notif.Title = "Disk <full>"
+	notif.Priority = core.PriorityUrgent
+	notif.Fields = []core.Field{{Name: "host", Value: "db1"}}
+	notif.Tags = []string{"ops"}
+	notif.URL = "https://grafana.example/d/disk?a=1&b=2"
+
+	n := New("xoxb-test", nil, "C1").WithBaseURL(srv.URL)
+	if err := n.Send(context.Background(), notif); err != nil {
+		t.Fatalf("Send: %v", err)
+	}
+	want := "*🚨 Disk &lt;full&gt;*\nhello from test\n\n*host:* db1\n\n#ops\n\n<https://grafana.example/d/disk?a=1&amp;b=2>"
+	if body := bodies[0]; body["text"] != want || body["mrkdwn"] != true {
+		t.Errorf("sent %q (mrkdwn %v), want %q", body["text"], body["mrkdwn"], want)
+	}
+}
+
+func TestNotifier_SendAPIError(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(`{"ok":false,"error":"channel_not_found"}`))
+	}))
+	defer srv.Close()
+
+	n := New("xoxb-test", nil, "C1").WithBaseURL(srv.URL)
+	err := n.Send(context.Background(), newTestNotification())
+	if err == nil || !strings.Contains(err.Error(), "channel_not_found") {
+		t.Errorf("Send = %v, want channel_not_found", err)
+	}
+}
+
+func TestNotifier_SendRateLimited(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Retry-After", "30")
+		w.WriteHeader(http.StatusTooManyRequests)
+	}))
+	defer srv.Close()
+
+	n := New("xoxb-test", nil, "C1").WithBaseURL(srv.URL)
+	err := n.Send(context.Background(), newTestNotification())
+	if err == nil || !strings.Contains(err.Error(), "retry after 30s") {
+		t.Errorf("Send = %v, want rate limit error", err)
+	}
+}
@@ -0,0 +1,89 @@
+package slack_receiver
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"strings"
+)
+
+// AppTokenAccount is the keychain account holding the app-level token
+// (xapp-...) that opens Socket Mode connections.
+const AppTokenAccount = "slack_app_token"
+
+// defaultCommand is the slash command that runs ops, as in
+// "/openslack status".
+const defaultCommand = "/openslack"
+
+// Config is the Slack config file, shared by the receiver and the
+// notifier. Channels maps each Slack channel ID the daemon listens in to
+// the chat ID it stands for everywhere else: the allowlist, approvals,
+// tasks and rules. Messages from channels not listed are dropped.
+type Config struct {
+	Channels       map[string]int64 `json:"channels"`
+	DefaultChannel string           `json:"default_channel"`
+	Command        string           `json:"command"`
+}
+
+// LoadConfig reads and validates a Slack config file.
+// Returns nil, nil if the file does not exist.
+func LoadConfig(path string) (*Config, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("read slack config: %w", err)
+	}
+
+	var cfg Config
+	if err := json.Unmarshal(data, &cfg); err != nil {
+		return nil, fmt.Errorf("parse slack config: %w", err)
+	}
+	if len(cfg.Channels) == 0 {
+		return nil, fmt.Errorf("slack config: no channels")
+	}
+	chats := make(map[int64]string, len(cfg.Channels))
+	for channel, chatID := range cfg.Channels {
+		if channel == "" || strings.ContainsAny(channel, " \t\n") {
+			return nil, fmt.Errorf("slack config: bad channel ID %q", channel)
+		}
+		if chatID == 0 {
+			return nil, fmt.Errorf("slack config: channel %s needs a non-zero chat ID", channel)
+		}
+		if other, ok := chats[chatID]; ok {
+			return nil, fmt.Errorf("slack config: channels %s and %s share chat ID %d", min(channel, other), max(channel, other), chatID)
+		}
+		chats[chatID] = channel
+	}
+
+	switch {
+	case cfg.DefaultChannel != "":
+		if _, ok := cfg.Channels[cfg.DefaultChannel]; !ok {
+			return nil, fmt.Errorf("slack config: default_channel %s is not in channels", cfg.DefaultChannel)
+		}
+	case len(cfg.Channels) == 1:
+		for channel := range cfg.Channels {
+			cfg.DefaultChannel = channel
+		}
+	default:
+		return nil, fmt.Errorf("slack config: default_channel is required with more than one channel")
+	}
+
+	if cfg.Command == "" {
+		cfg.Command = defaultCommand
+	}
+	if !strings.HasPrefix(cfg.Command, "/") || len(cfg.Command) == 1 || strings.ContainsAny(cfg.Command, " \t\n") {
+		return nil, fmt.Errorf("slack config: command must be a slash command such as /openslack, got %q", cfg.Command)
+	}
+	return &cfg, nil
+}
+
+// Chats maps each chat ID back to its Slack channel, for the notifier.
+func (c *Config) Chats() map[int64]string {
+	chats := make(map[int64]string, len(c.Channels))
+	for channel, chatID := range c.Channels {
+		chats[chatID] = channel
+	}
+	return chats
+}
@@ -0,0 +1,32 @@
+package slack_receiver_test
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/jdelaire/openslack/adapters/slack_receiver"
+	"github.com/jdelaire/openslack/core"
+	"github.com/jdelaire/openslack/core/adaptertest"
+)
+
+func TestConformance(t *testing.T) {
+	adaptertest.TestReceiver(t, adaptertest.ReceiverHarness{
+		New: func(t *testing.T, texts []string, handler core.MessageHandler) core.Receiver {
+			var frames []any
+			for i, text := range texts {
+				frames = append(frames, messageFrame(fmt.Sprint("e", i), "C1", text, ts(i), nil))
+			}
+			f := newFakeSlack(t, frames)
+			return slack_receiver.New("xapp-test", testConfig(), handler, testLogger()).WithBaseURL(f.URL)
+		},
+		NewFailing: func(t *testing.T, handler core.MessageHandler) core.Receiver {
+			srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				w.WriteHeader(http.StatusInternalServerError)
+			}))
+			t.Cleanup(srv.Close)
+			return slack_receiver.New("xapp-test", testConfig(), handler, testLogger()).WithBaseURL(srv.URL)
+		},
+	})
+}
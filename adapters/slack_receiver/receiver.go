@@ -0,0 +1,441 @@
+// Package slack_receiver receives chat messages from Slack over Socket
+// Mode: a websocket the app opens to Slack, so the daemon needs no public
+// URL. Each Slack channel in the config stands for a chat ID, which is
+// what the allowlist and the rest of the daemon see.
+package slack_receiver
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"golang.org/x/net/websocket"
+
+	"github.com/jdelaire/openslack/core"
+)
+
+const (
+	defaultBaseURL = "https://slack.com/api"
+	httpTimeout    = 10 * time.Second
+)
+
+// errRefresh means Slack asked the receiver to reconnect, as it does every
+// few hours; it is not a failure.
+var errRefresh = errors.New("slack asked to reconnect")
+
+// envelope is one Socket Mode frame. Every frame but hello and
+// disconnect carries an envelope_id that must be acknowledged.
+type envelope struct {
+	Type       string          `json:"type"`
+	EnvelopeID string          `json:"envelope_id"`
+	Payload    json.RawMessage `json:"payload"`
+	Reason     string          `json:"reason"`
+}
+
+// eventCallback is the payload of an events_api envelope.
+type eventCallback struct {
+	Event struct {
+		Type     string `json:"type"`
+		Subtype  string `json:"subtype"`
+		BotID    string `json:"bot_id"`
+		Channel  string `json:"channel"`
+		Text     string `json:"text"`
+		TS       string `json:"ts"`
+		ThreadTS string `json:"thread_ts"`
+	} `json:"event"`
+}
+
+// interaction is the payload of an interactive envelope, such as a button
+// press.
+type interaction struct {
+	Type    string `json:"type"`
+	Channel struct {
+		ID string `json:"id"`
+	} `json:"channel"`
+	Message struct {
+		TS string `json:"ts"`
+	} `json:"message"`
+	Actions []struct {
+		Value    string `json:"value"`
+		ActionID string `json:"action_id"`
+		ActionTS string `json:"action_ts"`
+	} `json:"actions"`
+}
+
+// slashCommand is the payload of a slash_commands envelope.
+type slashCommand struct {
+	Command   string `json:"command"`
+	Text      string `json:"text"`
+	ChannelID string `json:"channel_id"`
+}
+
+// Receiver reads Slack events over a Socket Mode connection and passes
+// messages from configured channels to the handler.
+//
+// A message's ts, "1712345678.000100", becomes its MessageID and
+// UpdateID as the integer 1712345678000100. Slack redelivers an event it
+// thinks was lost with the same ts, so the policy's duplicate check drops
+// it. Slack user IDs are not numbers, so UserID is left zero.
+type Receiver struct {
+	appToken string
+	cfg      *Config
+	handler  core.MessageHandler
+	logger   *slog.Logger
+	client   *http.Client
+	baseURL  string
+
+	mu      sync.Mutex
+	lastID  int64
+	dropped map[string]int64
+	health  health
+}
+
+// New creates a Slack receiver with the app-level token and config.
+func New(appToken string, cfg *Config, handler core.MessageHandler, logger *slog.Logger) *Receiver {
+	return &Receiver{
+		appToken: appToken,
+		cfg:      cfg,
+		handler:  handler,
+		logger:   logger,
+		client:   &http.Client{Timeout: httpTimeout},
+		baseURL:  defaultBaseURL,
+		dropped:  make(map[string]int64),
+	}
+}
+
+// WithBaseURL overrides the Slack Web API base URL (for testing).
+func (r *Receiver) WithBaseURL(url string) *Receiver {
+	r.baseURL = url
+	return r
+}
+
+// Dropped returns how many events of each kind arrived but could not be
+// handled, such as messages from channels not in the config.
+func (r *Receiver) Dropped() map[string]int64 {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	counts := make(map[string]int64, len(r.dropped))
+	for k, v := range r.dropped {
+		counts[k] = v
+	}
+	return counts
+}
+
+// drop counts an unhandled event, logging the first of each kind.
+func (r *Receiver) drop(kind string) {
+	r.mu.Lock()
+	r.dropped[kind]++
+	first := r.dropped[kind] == 1
+	r.mu.Unlock()
+	if first {
+		r.logger.Warn("dropping unhandled slack event", "kind", kind)
+	}
+}
+
+// Start connects to Slack and handles events, reconnecting whenever the
+// connection drops. Blocks until ctx is cancelled.
+func (r *Receiver) Start(ctx context.Context) error {
+	r.logger.Info("slack receiver started")
+	for {
+		err := r.session(ctx)
+		if ctx.Err() != nil {
+			r.logger.Info("slack receiver stopped")
+			return nil
+		}
+		if errors.Is(err, errRefresh) {
+			r.logger.Info("slack connection refreshed")
+			continue
+		}
+		wait := r.failed(err)
+		r.logger.Error("slack connection error", "error", err, "retry_in", wait)
+		select {
+		case <-time.After(wait):
+		case <-ctx.Done():
+			r.logger.Info("slack receiver stopped")
+			return nil
+		}
+	}
+}
+
+// session opens one Socket Mode connection and reads it until it fails,
+// Slack asks to reconnect, or ctx is cancelled.
+func (r *Receiver) session(ctx context.Context) error {
+	wsURL, err := r.openConnection(ctx)
+	if err != nil {
+		return err
+	}
+	wsCfg, err := websocket.NewConfig(wsURL, "https://slack.com")
+	if err != nil {
+		return fmt.Errorf("websocket config: %w", err)
+	}
+	ws, err := wsCfg.DialContext(ctx)
+	if err != nil {
+		return fmt.Errorf("websocket dial: %w", err)
+	}
+	defer ws.Close()
+	stop := context.AfterFunc(ctx, func() { ws.Close() })
+	defer stop()
+
+	for {
+		var env envelope
+		if err := websocket.JSON.Receive(ws, &env); err != nil {
+			return fmt.Errorf("websocket read: %w", err)
+		}
+		switch env.Type {
+		case "hello":
+			r.succeeded()
+			continue
+		case "disconnect":
+			r.logger.Debug("slack disconnect", "reason", env.Reason)
+			return errRefresh
+		}
+		// Acknowledge first, so a slow handler does not make Slack
+		// redeliver the event.
+		if env.EnvelopeID != "" {
+			if err := websocket.JSON.Send(ws, map[string]string{"envelope_id": env.EnvelopeID}); err != nil {
+				return fmt.Errorf("websocket ack: %w", err)
+			}
+		}
+		r.handle(env)
+	}
+}
+
+// openConnection asks Slack for a Socket Mode websocket URL with
+// apps.connections.open.
+func (r *Receiver) openConnection(ctx context.Context) (string, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, r.baseURL+"/apps.connections.open", nil)
+	if err != nil {
+		return "", fmt.Errorf("create request: %w", err)
+	}
+	req.Header.Set("Authorization", "Bearer "+r.appToken)
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+
+	resp, err := r.client.Do(req)
+	if err != nil {
+		return "", fmt.Errorf("http post: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode == http.StatusTooManyRequests {
+		secs, _ := strconv.Atoi(resp.Header.Get("Retry-After"))
+		return "", &rateLimitedError{after: time.Duration(secs) * time.Second}
+	}
+	if resp.StatusCode != http.StatusOK {
+		return "", fmt.Errorf("api status: %d", resp.StatusCode)
+	}
+
+	var body struct {
+		OK    bool   `json:"ok"`
+		URL   string `json:"url"`
+		Error string `json:"error"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&body); err != nil {
+		return "", fmt.Errorf("decode response: %w", err)
+	}
+	if !body.OK {
+		return "", fmt.Errorf("apps.connections.open: %s", body.Error)
+	}
+	return body.URL, nil
+}
+
+// handle passes the message in env, if any, to the handler.
+func (r *Receiver) handle(env envelope) {
+	var msg core.InboundMessage
+	var ok bool
+	switch env.Type {
+	case "events_api":
+		var p eventCallback
+		if err := json.Unmarshal(env.Payload, &p); err != nil {
+			r.drop("events_api without event")
+			return
+		}
+		msg, ok = r.message(&p)
+	case "interactive":
+		var p interaction
+		if err := json.Unmarshal(env.Payload, &p); err != nil {
+			r.drop("interactive without payload")
+			return
+		}
+		msg, ok = r.buttonPress(&p)
+	case "slash_commands":
+		var p slashCommand
+		if err := json.Unmarshal(env.Payload, &p); err != nil {
+			r.drop("slash_commands without payload")
+			return
+		}
+		msg, ok = r.command(&p)
+	default:
+		r.drop(env.Type)
+		return
+	}
+	if ok {
+		r.handler(msg)
+	}
+}
+
+// message converts a message or app_mention event. Messages addressed to
+// the app, "@openslack status", run the command after the mention,
+// "/status"; other messages are passed as they are.
+func (r *Receiver) message(p *eventCallback) (core.InboundMessage, bool) {
+	ev := &p.Event
+	switch {
+	case ev.Type != "message" && ev.Type != "app_mention":
+		r.drop("event " + ev.Type)
+		return core.InboundMessage{}, false
+	case ev.BotID != "":
+		// Includes the app's own messages.
+		r.drop("message from a bot")
+		return core.InboundMessage{}, false
+	case ev.Subtype != "":
+		r.drop("message " + ev.Subtype)
+		return core.InboundMessage{}, false
+	}
+	chatID, ok := r.chat(ev.Channel)
+	if !ok {
+		return core.InboundMessage{}, false
+	}
+	id, at, ok := parseTS(ev.TS)
+	if !ok {
+		r.drop("message without ts")
+		return core.InboundMessage{}, false
+	}
+	text, mentioned := stripMention(ev.Text)
+	text = unescape(text)
+	if mentioned && !strings.HasPrefix(text, "/") {
+		text = "/" + text
+	}
+	if strings.TrimSpace(text) == "" || text == "/" {
+		r.drop("message without text")
+		return core.InboundMessage{}, false
+	}
+
+	msg := core.InboundMessage{
+		UpdateID:  r.seen(id),
+		MessageID: id,
+		ChatID:    chatID,
+		Text:      text,
+		Timestamp: at,
+	}
+	if thread, _, ok := parseTS(ev.ThreadTS); ok && thread != id {
+		msg.ReplyTo = thread
+	}
+	return msg, true
+}
+
+// buttonPress converts a press on a button of the notifier's, whose value
+// is the button's data.
+func (r *Receiver) buttonPress(p *interaction) (core.InboundMessage, bool) {
+	if p.Type != "block_actions" || len(p.Actions) == 0 || p.Actions[0].Value == "" {
+		r.drop("interactive " + p.Type)
+		return core.InboundMessage{}, false
+	}
+	chatID, ok := r.chat(p.Channel.ID)
+	if !ok {
+		return core.InboundMessage{}, false
+	}
+	a := p.Actions[0]
+	messageID, _, _ := parseTS(p.Message.TS)
+	updateID := r.nextID()
+	if id, _, ok := parseTS(a.ActionTS); ok {
+		updateID = r.seen(id)
+	}
+	return core.InboundMessage{
+		UpdateID:   updateID,
+		MessageID:  messageID,
+		ChatID:     chatID,
+		Text:       a.Value,
+		Timestamp:  time.Now(),
+		CallbackID: a.ActionID,
+	}, true
+}
+
+// command converts a slash command. The configured command runs the op
+// named in its text, so "/openslack status" is "/status"; other commands
+// the app has are passed with their text, as "/deploy api".
+func (r *Receiver) command(p *slashCommand) (core.InboundMessage, bool) {
+	chatID, ok := r.chat(p.ChannelID)
+	if !ok {
+		return core.InboundMessage{}, false
+	}
+	text := strings.TrimSpace(p.Text)
+	switch {
+	case p.Command != r.cfg.Command:
+		text = strings.TrimSpace(p.Command + " " + text)
+	case text == "":
+		text = "/help"
+	case !strings.HasPrefix(text, "/"):
+		text = "/" + text
+	}
+	return core.InboundMessage{
+		UpdateID:  r.nextID(),
+		ChatID:    chatID,
+		Text:      text,
+		Timestamp: time.Now(),
+	}, true
+}
+
+// chat returns the chat ID configured for channel, counting a drop if
+// there is none.
+func (r *Receiver) chat(channel string) (int64, bool) {
+	chatID, ok := r.cfg.Channels[channel]
+	if !ok {
+		r.drop("message from a channel not in the config")
+	}
+	return chatID, ok
+}
+
+// seen records id, from an event's ts, as handed out and returns it.
+func (r *Receiver) seen(id int64) int64 {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.lastID = max(r.lastID, id)
+	return id
+}
+
+// nextID returns an update ID for an event without a ts of its own: the
+// time in microseconds, bumped past every ID handed out so far.
+func (r *Receiver) nextID() int64 {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.lastID = max(time.Now().UnixMicro(), r.lastID+1)
+	return r.lastID
+}
+
+// parseTS parses a Slack timestamp, "1712345678.000100", into the integer
+// ID 1712345678000100 and the time it stands for.
+func parseTS(ts string) (int64, time.Time, bool) {
+	secStr, microStr, ok := strings.Cut(ts, ".")
+	if !ok || len(microStr) != 6 {
+		return 0, time.Time{}, false
+	}
+	sec, err1 := strconv.ParseInt(secStr, 10, 64)
+	micro, err2 := strconv.ParseInt(microStr, 10, 64)
+	if err1 != nil || err2 != nil || sec <= 0 || micro < 0 {
+		return 0, time.Time{}, false
+	}
+	return sec*1e6 + micro, time.Unix(sec, micro*1e3), true
+}
+
+// stripMention removes a leading user mention, "<@U0123ABC> ", from text.
+func stripMention(text string) (string, bool) {
+	if !strings.HasPrefix(text, "<@") {
+		return text, false
+	}
+	end := strings.IndexByte(text, '>')
+	if end == -1 {
+		return text, false
+	}
+	return strings.TrimSpace(text[end+1:]), true
+}
+
+// unescape undoes the escaping Slack applies to message text.
+func unescape(text string) string {
+	return strings.NewReplacer("&lt;", "<", "&gt;", ">", "&amp;", "&").Replace(text)
+}
@@ -0,0 +1,329 @@
+package slack_receiver_test
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"golang.org/x/net/websocket"
+
+	"github.com/jdelaire/openslack/adapters/slack_receiver"
+	"github.com/jdelaire/openslack/core"
+)
+
+func testLogger() *slog.Logger {
+	return slog.New(slog.NewTextHandler(io.Discard, nil))
+}
+
+func testConfig() *slack_receiver.Config {
+	return &slack_receiver.Config{
+		Channels:       map[string]int64{"C1": 1, "C2": 2},
+		DefaultChannel: "C1",
+		Command:        "/openslack",
+	}
+}
+
+// fakeSlack serves apps.connections.open and a Socket Mode websocket. The
+// nth connection gets hello followed by the nth list of frames; later
+// connections get hello only. Every connection is then held open,
+// collecting acks, until the client closes it.
+type fakeSlack struct {
+	URL string
+
+	mu    sync.Mutex
+	opens int
+	acks  []string
+}
+
+func newFakeSlack(t *testing.T, sessions ...[]any) *fakeSlack {
+	t.Helper()
+	f := &fakeSlack{}
+	mux := http.NewServeMux()
+	srv := httptest.NewServer(mux)
+	t.Cleanup(srv.Close)
+	f.URL = srv.URL
+
+	mux.HandleFunc("/apps.connections.open", func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Authorization") != "Bearer xapp-test" {
+			json.NewEncoder(w).Encode(map[string]any{"ok": false, "error": "invalid_auth"})
+			return
+		}
+		json.NewEncoder(w).Encode(map[string]any{"ok": true, "url": "ws" + strings.TrimPrefix(srv.URL, "http") + "/ws"})
+	})
+	mux.Handle("/ws", websocket.Handler(func(ws *websocket.Conn) {
+		f.mu.Lock()
+		n := f.opens
+		f.opens++
+		f.mu.Unlock()
+
+		websocket.JSON.Send(ws, map[string]any{"type": "hello"})
+		if n < len(sessions) {
+			for _, frame := range sessions[n] {
+				websocket.JSON.Send(ws, frame)
+			}
+		}
+		for {
+			var ack struct {
+				EnvelopeID string `json:"envelope_id"`
+			}
+			if err := websocket.JSON.Receive(ws, &ack); err != nil {
+				return
+			}
+			f.mu.Lock()
+			f.acks = append(f.acks, ack.EnvelopeID)
+			f.mu.Unlock()
+		}
+	}))
+	return f
+}
+
+func (f *fakeSlack) Acks() []string {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return append([]string(nil), f.acks...)
+}
+
+func (f *fakeSlack) Opens() int {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return f.opens
+}
+
+// ts returns a Slack timestamp for now plus micro microseconds.
+func ts(micro int) string {
+	return fmt.Sprintf("%d.%06d", time.Now().Unix(), micro)
+}
+
+func messageFrame(id, channel, text, ts string, extra map[string]any) map[string]any {
+	event := map[string]any{"type": "message", "channel": channel, "user": "U1", "text": text, "ts": ts}
+	for k, v := range extra {
+		event[k] = v
+	}
+	return map[string]any{
+		"type":        "events_api",
+		"envelope_id": id,
+		"payload":     map[string]any{"event_id": "Ev" + id, "event": event},
+	}
+}
+
+// receive runs a receiver against f until want messages arrive, then
+// stops it.
+func receive(t *testing.T, f *fakeSlack, cfg *slack_receiver.Config, want int) ([]core.InboundMessage, *slack_receiver.Receiver) {
+	t.Helper()
+	var mu sync.Mutex
+	var got []core.InboundMessage
+	done := make(chan struct{})
+	r := slack_receiver.New("xapp-test", cfg, func(msg core.InboundMessage) {
+		mu.Lock()
+		defer mu.Unlock()
+		got = append(got, msg)
+		if len(got) == want {
+			close(done)
+		}
+	}, testLogger()).WithBaseURL(f.URL)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	stopped := make(chan error, 1)
+	go func() { stopped <- r.Start(ctx) }()
+	select {
+	case <-done:
+	case <-time.After(5 * time.Second):
+		t.Errorf("timed out waiting for %d messages", want)
+	}
+	// Let the fake read the last acks.
+	time.Sleep(50 * time.Millisecond)
+	cancel()
+	if err := <-stopped; err != nil {
+		t.Errorf("Start returned %v", err)
+	}
+
+	mu.Lock()
+	defer mu.Unlock()
+	return got, r
+}
+
+func TestMessages(t *testing.T) {
+	root := ts(100)
+	f := newFakeSlack(t, []any{
+		messageFrame("e1", "C1", "/status", root, nil),
+		messageFrame("e2", "C1", "hi", ts(101), map[string]any{"bot_id": "B1"}),
+		messageFrame("e3", "C1", "joined", ts(102), map[string]any{"subtype": "channel_join"}),
+		messageFrame("e4", "C9", "/status", ts(103), nil),
+		messageFrame("e5", "C2", "<@U0BOT> tasks add milk &amp; eggs", ts(104), map[string]any{"thread_ts": root}),
+		messageFrame("e6", "C2", "deploy &lt;api&gt; now", ts(105), nil),
+		map[string]any{"type": "events_api", "envelope_id": "e7", "payload": map[string]any{"event": map[string]any{"type": "reaction_added"}}},
+		messageFrame("e8", "C1", "/status", root, nil), // redelivered
+	})
+	got, r := receive(t, f, testConfig(), 4)
+
+	if len(got) != 4 {
+		t.Fatalf("received %d messages, want 4: %+v", len(got), got)
+	}
+	rootID := got[0].MessageID
+	if got[0].Text != "/status" || got[0].ChatID != 1 || got[0].UpdateID != rootID || rootID%1e6 != 100 {
+		t.Errorf("message 0 = %+v", got[0])
+	}
+	if time.Since(got[0].Timestamp) > time.Minute {
+		t.Errorf("timestamp = %v, want the message's ts", got[0].Timestamp)
+	}
+	if got[1].Text != "/tasks add milk & eggs" || got[1].ChatID != 2 || got[1].ReplyTo != rootID {
+		t.Errorf("mention in thread = %+v, want /tasks in chat 2 replying to %d", got[1], rootID)
+	}
+	if got[2].Text != "deploy <api> now" {
+		t.Errorf("plain message = %q", got[2].Text)
+	}
+	if got[3].UpdateID != rootID {
+		t.Errorf("redelivery has update ID %d, want %d for the policy to drop it", got[3].UpdateID, rootID)
+	}
+
+	if acks := f.Acks(); len(acks) != 8 {
+		t.Errorf("acks = %v, want all 8 envelopes", acks)
+	}
+	dropped := r.Dropped()
+	for _, kind := range []string{"message from a bot", "message channel_join", "message from a channel not in the config", "event reaction_added"} {
+		if dropped[kind] != 1 {
+			t.Errorf("dropped[%q] = %d, want 1 (all: %v)", kind, dropped[kind], dropped)
+		}
+	}
+}
+
+func TestButtonPress(t *testing.T) {
+	f := newFakeSlack(t, []any{
+		map[string]any{
+			"type":        "interactive",
+			"envelope_id": "i1",
+			"payload": map[string]any{
+				"type":    "block_actions",
+				"channel": map[string]any{"id": "C2"},
+				"message": map[string]any{"ts": "1712345678.000100"},
+				"actions": []map[string]any{{"action_id": "button-0", "value": "/approve abc", "action_ts": ts(5)}},
+			},
+		},
+	})
+	got, _ := receive(t, f, testConfig(), 1)
+	if len(got) != 1 {
+		t.Fatalf("received %d messages, want 1", len(got))
+	}
+	msg := got[0]
+	if msg.Text != "/approve abc" || msg.ChatID != 2 || msg.MessageID != 1712345678000100 || msg.CallbackID != "button-0" {
+		t.Errorf("button press = %+v", msg)
+	}
+}
+
+func TestSlashCommand(t *testing.T) {
+	frame := func(id, command, text string) map[string]any {
+		return map[string]any{
+			"type":        "slash_commands",
+			"envelope_id": id,
+			"payload":     map[string]any{"command": command, "text": text, "channel_id": "C1"},
+		}
+	}
+	f := newFakeSlack(t, []any{
+		frame("s1", "/openslack", "status"),
+		frame("s2", "/openslack", ""),
+		frame("s3", "/deploy", "api"),
+		frame("s4", "/openslack", "/tasks"),
+	})
+	got, _ := receive(t, f, testConfig(), 4)
+
+	want := []string{"/status", "/help", "/deploy api", "/tasks"}
+	if len(got) != len(want) {
+		t.Fatalf("received %d messages, want %d", len(got), len(want))
+	}
+	for i, msg := range got {
+		if msg.Text != want[i] || msg.ChatID != 1 {
+			t.Errorf("command %d = %q in chat %d, want %q in chat 1", i, msg.Text, msg.ChatID, want[i])
+		}
+		if i > 0 && msg.UpdateID <= got[i-1].UpdateID {
+			t.Errorf("update IDs not increasing: %d then %d", got[i-1].UpdateID, msg.UpdateID)
+		}
+	}
+}
+
+func TestReconnectOnDisconnect(t *testing.T) {
+	f := newFakeSlack(t,
+		[]any{map[string]any{"type": "disconnect", "reason": "refresh_requested"}},
+		[]any{messageFrame("e1", "C1", "/status", ts(1), nil)},
+	)
+	got, r := receive(t, f, testConfig(), 1)
+	if len(got) != 1 || got[0].Text != "/status" {
+		t.Fatalf("received %+v, want /status after reconnecting", got)
+	}
+	if n := f.Opens(); n != 2 {
+		t.Errorf("connections = %d, want 2", n)
+	}
+	if _, _, degraded := r.Degraded(); degraded {
+		t.Error("receiver degraded after a requested reconnect")
+	}
+}
+
+func TestAuthFailure(t *testing.T) {
+	f := newFakeSlack(t)
+	r := slack_receiver.New("xapp-wrong", testConfig(), func(core.InboundMessage) {
+		t.Error("handler called")
+	}, testLogger()).WithBaseURL(f.URL)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 200*time.Millisecond)
+	defer cancel()
+	if err := r.Start(ctx); err != nil {
+		t.Errorf("Start returned %v", err)
+	}
+	if n := f.Opens(); n != 0 {
+		t.Errorf("connections = %d with a bad token, want 0", n)
+	}
+}
+
+func TestLoadConfig(t *testing.T) {
+	dir := t.TempDir()
+	write := func(content string) string {
+		path := filepath.Join(dir, "slack.json")
+		if err := os.WriteFile(path, []byte(content), 0o600); err != nil {
+			t.Fatal(err)
+		}
+		return path
+	}
+
+	cfg, err := slack_receiver.LoadConfig(filepath.Join(dir, "missing.json"))
+	if cfg != nil || err != nil {
+		t.Fatalf("missing file = %v, %v; want nil, nil", cfg, err)
+	}
+
+	cfg, err = slack_receiver.LoadConfig(write(`{"channels": {"C1": 1001}}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if cfg.DefaultChannel != "C1" || cfg.Command != "/openslack" {
+		t.Errorf("defaults = %q, %q", cfg.DefaultChannel, cfg.Command)
+	}
+
+	cfg, err = slack_receiver.LoadConfig(write(`{"channels": {"C1": 1001, "D2": 1002}, "default_channel": "D2", "command": "/ops"}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if chats := cfg.Chats(); chats[1001] != "C1" || chats[1002] != "D2" {
+		t.Errorf("Chats() = %v", chats)
+	}
+
+	for _, bad := range []string{
+		`{}`,
+		`{"channels": {"C1": 0}}`,
+		`{"channels": {"C1": 1, "C2": 1}, "default_channel": "C1"}`,
+		`{"channels": {"C1": 1, "C2": 2}}`,
+		`{"channels": {"C1": 1}, "default_channel": "C9"}`,
+		`{"channels": {"C1": 1}, "command": "openslack"}`,
+	} {
+		if _, err := slack_receiver.LoadConfig(write(bad)); err == nil {
+			t.Errorf("LoadConfig(%s) succeeded", bad)
+		}
+	}
+}
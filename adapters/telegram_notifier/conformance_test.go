@@ -0,0 +1,51 @@
+package telegram_notifier
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+
+	"github.com/jdelaire/openslack/core"
+	"github.com/jdelaire/openslack/core/adaptertest"
+)
+
+func TestConformance(t *testing.T) {
+	adaptertest.TestNotifier(t, adaptertest.NotifierHarness{
+		New: func(t *testing.T) (core.Notifier, func() []string) {
+			var mu sync.Mutex
+			var texts []string
+			srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				r.ParseForm()
+				mu.Lock()
+				texts = append(texts, r.FormValue("text"))
+				mu.Unlock()
+				w.Write([]byte(`{"ok":true}`))
+			}))
+			t.Cleanup(srv.Close)
+			delivered := func() []string {
+				mu.Lock()
+				defer mu.Unlock()
+				return append([]string(nil), texts...)
+			}
+			return New("tok", "1").WithBaseURL(srv.URL), delivered
+		},
+		NewFailing: func(t *testing.T) core.Notifier {
+			srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				w.WriteHeader(http.StatusBadRequest)
+				w.Write([]byte(`{"ok":false,"description":"Bad Request: chat not found"}`))
+			}))
+			t.Cleanup(srv.Close)
+			return New("tok", "1").WithBaseURL(srv.URL)
+		},
+		NewBlocking: func(t *testing.T) core.Notifier {
+			release := make(chan struct{})
+			srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				<-release
+			}))
+			t.Cleanup(srv.Close)
+			t.Cleanup(func() { close(release) })
+			return New("tok", "1").WithBaseURL(srv.URL)
+		},
+	})
+}
@@ -1,11 +1,16 @@
 package telegram_notifier
 
 import (
+	"bytes"
 	"context"
 	"encoding/json"
 	"fmt"
+	"html"
+	"mime/multipart"
 	"net/http"
 	"net/url"
+	"strconv"
+	"strings"
 	"time"
 
 	"github.com/jdelaire/openslack/core"
@@ -31,18 +36,237 @@ func New(botToken, chatID string) *Notifier {
 
 func (n *Notifier) Name() string { return "telegram" }
 
+// Send delivers notif with sendMessage. Structured notifications are
+// rendered as HTML; if Telegram rejects the markup, the plain-text
+// rendering is sent instead.
 func (n *Notifier) Send(ctx context.Context, notif core.Notification) error {
-	endpoint := fmt.Sprintf("%s/bot%s/sendMessage", n.baseURL, n.botToken)
-
-	resp, err := n.client.PostForm(endpoint, url.Values{
-		"chat_id": {n.chatID},
+	chatID := n.chatID
+	if notif.ChatID != 0 {
+		chatID = strconv.FormatInt(notif.ChatID, 10)
+	}
+	form := url.Values{
+		"chat_id": {chatID},
 		"text":    {notif.Text},
+	}
+	if notif.ReplyTo != 0 {
+		// Still deliver if the original message was deleted.
+		form.Set("reply_to_message_id", strconv.FormatInt(notif.ReplyTo, 10))
+		form.Set("allow_sending_without_reply", "true")
+	}
+	if notif.Silent || notif.Priority == core.PriorityLow {
+		form.Set("disable_notification", "true")
+	}
+	if len(notif.Buttons) > 0 {
+		form.Set("reply_markup", inlineKeyboard(notif.Buttons))
+	}
+	if !notif.Structured() {
+		return n.sendMessage(ctx, form)
+	}
+
+	form.Set("text", renderHTML(notif))
+	form.Set("parse_mode", "HTML")
+	err := n.sendMessage(ctx, form)
+	if err == nil || !strings.Contains(err.Error(), "can't parse entities") {
+		return err
+	}
+	form.Set("text", notif.PlainText())
+	form.Del("parse_mode")
+	return n.sendMessage(ctx, form)
+}
+
+func (n *Notifier) sendMessage(ctx context.Context, form url.Values) error {
+	return n.post(ctx, "sendMessage", form)
+}
+
+// React sets the bot's reaction on a chat message with
+// setMessageReaction. An empty emoji removes it. Telegram only accepts
+// emoji from its reaction set.
+func (n *Notifier) React(ctx context.Context, chatID, messageID int64, emoji string) error {
+	reaction := "[]"
+	if emoji != "" {
+		b, _ := json.Marshal([]map[string]string{{"type": "emoji", "emoji": emoji}})
+		reaction = string(b)
+	}
+	return n.post(ctx, "setMessageReaction", url.Values{
+		"chat_id":    {strconv.FormatInt(chatID, 10)},
+		"message_id": {strconv.FormatInt(messageID, 10)},
+		"reaction":   {reaction},
+	})
+}
+
+// SendTyping shows "typing…" in a chat with sendChatAction. Telegram
+// clears it after 5 seconds or when the bot next sends a message.
+func (n *Notifier) SendTyping(ctx context.Context, chatID int64) error {
+	return n.post(ctx, "sendChatAction", url.Values{
+		"chat_id": {strconv.FormatInt(chatID, 10)},
+		"action":  {"typing"},
 	})
+}
+
+// SetCommands replaces the bot's command menu with setMyCommands, in
+// chatID's scope, or the default scope for 0.
+func (n *Notifier) SetCommands(ctx context.Context, chatID int64, cmds []core.Command) error {
+	type command struct {
+		Command     string `json:"command"`
+		Description string `json:"description"`
+	}
+	list := make([]command, len(cmds))
+	for i, c := range cmds {
+		list[i] = command{Command: c.Name, Description: c.Description}
+	}
+	b, _ := json.Marshal(list)
+	form := url.Values{"commands": {string(b)}}
+	if chatID != 0 {
+		form.Set("scope", fmt.Sprintf(`{"type":"chat","chat_id":%d}`, chatID))
+	}
+	return n.post(ctx, "setMyCommands", form)
+}
+
+// post calls a Bot API method with a form-encoded body.
+func (n *Notifier) post(ctx context.Context, method string, form url.Values) error {
+	endpoint := fmt.Sprintf("%s/bot%s/%s", n.baseURL, n.botToken, method)
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, endpoint, strings.NewReader(form.Encode()))
+	if err != nil {
+		return fmt.Errorf("telegram request: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+
+	resp, err := n.client.Do(req)
 	if err != nil {
 		return fmt.Errorf("telegram request: %w", err)
 	}
 	defer resp.Body.Close()
 
+	return checkResponse(resp)
+}
+
+// renderHTML renders a structured notification in Telegram's HTML: a bold
+// title flagged by priority, the text, bold field names, hashtags and
+// the link.
+func renderHTML(notif core.Notification) string {
+	var sections []string
+	head := notif.Title
+	switch notif.Priority {
+	case core.PriorityHigh:
+		head = strings.TrimSpace("❗ " + head)
+	case core.PriorityUrgent:
+		head = strings.TrimSpace("🚨 " + head)
+	}
+	first := ""
+	if head != "" {
+		first = "<b>" + html.EscapeString(head) + "</b>"
+	}
+	if notif.Text != "" {
+		if first != "" {
+			first += "\n"
+		}
+		first += html.EscapeString(notif.Text)
+	}
+	if first != "" {
+		sections = append(sections, first)
+	}
+	if len(notif.Fields) > 0 {
+		lines := make([]string, len(notif.Fields))
+		for i, f := range notif.Fields {
+			lines[i] = "<b>" + html.EscapeString(f.Name) + ":</b> " + html.EscapeString(f.Value)
+		}
+		sections = append(sections, strings.Join(lines, "\n"))
+	}
+	if len(notif.Tags) > 0 {
+		sections = append(sections, "#"+strings.Join(notif.Tags, " #"))
+	}
+	if notif.URL != "" {
+		sections = append(sections, `<a href="`+html.EscapeString(notif.URL)+`">`+html.EscapeString(notif.URL)+"</a>")
+	}
+	return strings.Join(sections, "\n\n")
+}
+
+// inlineKeyboard encodes buttons as a single-row reply_markup.
+func inlineKeyboard(buttons []core.Button) string {
+	type button struct {
+		Text         string `json:"text"`
+		CallbackData string `json:"callback_data"`
+	}
+	row := make([]button, len(buttons))
+	for i, b := range buttons {
+		row[i] = button{Text: b.Text, CallbackData: b.Data}
+	}
+	markup, _ := json.Marshal(map[string][][]button{"inline_keyboard": {row}})
+	return string(markup)
+}
+
+// SendDocument uploads a file to the chat with sendDocument.
+func (n *Notifier) SendDocument(ctx context.Context, doc core.Document) error {
+	endpoint := fmt.Sprintf("%s/bot%s/sendDocument", n.baseURL, n.botToken)
+
+	var body bytes.Buffer
+	w := multipart.NewWriter(&body)
+	chatID := n.chatID
+	if doc.ChatID != 0 {
+		chatID = strconv.FormatInt(doc.ChatID, 10)
+	}
+	w.WriteField("chat_id", chatID)
+	if doc.Caption != "" {
+		w.WriteField("caption", doc.Caption)
+	}
+	part, err := w.CreateFormFile("document", doc.Name)
+	if err != nil {
+		return fmt.Errorf("build upload: %w", err)
+	}
+	part.Write(doc.Data)
+	if err := w.Close(); err != nil {
+		return fmt.Errorf("build upload: %w", err)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, endpoint, &body)
+	if err != nil {
+		return fmt.Errorf("telegram request: %w", err)
+	}
+	req.Header.Set("Content-Type", w.FormDataContentType())
+
+	resp, err := n.client.Do(req)
+	if err != nil {
+		return fmt.Errorf("telegram request: %w", err)
+	}
+	defer resp.Body.Close()
+
+	return checkResponse(resp)
+}
+
+// BotUsername checks the bot token with getMe and returns the bot's
+// username.
+func (n *Notifier) BotUsername(ctx context.Context) (string, error) {
+	endpoint := fmt.Sprintf("%s/bot%s/getMe", n.baseURL, n.botToken)
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, endpoint, nil)
+	if err != nil {
+		return "", fmt.Errorf("create request: %w", err)
+	}
+	resp, err := n.client.Do(req)
+	if err != nil {
+		return "", fmt.Errorf("telegram request: %w", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return "", checkResponse(resp)
+	}
+
+	var body struct {
+		Result struct {
+			Username string `json:"username"`
+		} `json:"result"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&body); err != nil {
+		return "", fmt.Errorf("decode getMe: %w", err)
+	}
+	return body.Result.Username, nil
+}
+
+// ChatID returns the chat notifications are sent to.
+func (n *Notifier) ChatID() string {
+	return n.chatID
+}
+
+func checkResponse(resp *http.Response) error {
 	if resp.StatusCode != http.StatusOK {
 		var body struct {
 			OK          bool   `json:"ok"`
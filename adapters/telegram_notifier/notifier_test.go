@@ -2,6 +2,7 @@ package telegram_notifier
 
 import (
 	"context"
+	"io"
 	"net/http"
 	"net/http/httptest"
 	"strings"
@@ -75,6 +76,230 @@ func TestNotifier_SendNetworkError(t *testing.T) {
 	}
 }
 
+func TestNotifier_SendReplyTo(t *testing.T) {
+	tests := []struct {
+		name    string
+		replyTo int64
+		want    string
+	}{
+		{"threaded", 321, "321"},
+		{"unthreaded", 0, ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var got, allow string
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				r.ParseForm()
+				got = r.FormValue("reply_to_message_id")
+				allow = r.FormValue("allow_sending_without_reply")
+				w.Write([]byte(`{"ok":true}`))
+			}))
+			defer server.Close()
+
+			notif := newTestNotification()
+			notif.ReplyTo = tt.replyTo
+			if err := New("token", "12345").WithBaseURL(server.URL).Send(context.Background(), notif); err != nil {
+				t.Fatalf("Send: %v", err)
+			}
+			if got != tt.want {
+				t.Errorf("reply_to_message_id = %q, want %q", got, tt.want)
+			}
+			if tt.replyTo != 0 && allow != "true" {
+				t.Errorf("allow_sending_without_reply = %q, want true", allow)
+			}
+		})
+	}
+}
+
+func TestNotifier_SendToChat(t *testing.T) {
+	var got string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		r.ParseForm()
+		got = r.FormValue("chat_id")
+		w.Write([]byte(`{"ok":true}`))
+	}))
+	defer server.Close()
+
+	notif := newTestNotification()
+	notif.ChatID = -1001
+	if err := New("token", "12345").WithBaseURL(server.URL).Send(context.Background(), notif); err != nil {
+		t.Fatalf("Send: %v", err)
+	}
+	if got != "-1001" {
+		t.Errorf("chat_id = %q, want -1001", got)
+	}
+}
+
+func TestNotifier_SendSilent(t *testing.T) {
+	var got string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		r.ParseForm()
+		got = r.FormValue("disable_notification")
+		w.Write([]byte(`{"ok":true}`))
+	}))
+	defer server.Close()
+
+	notif := newTestNotification()
+	notif.Silent = true
+	if err := New("token", "12345").WithBaseURL(server.URL).Send(context.Background(), notif); err != nil {
+		t.Fatalf("Send: %v", err)
+	}
+	if got != "true" {
+		t.Errorf("disable_notification = %q, want true", got)
+	}
+}
+
+func TestNotifier_SendButtons(t *testing.T) {
+	var markup string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		r.ParseForm()
+		markup = r.FormValue("reply_markup")
+		w.Write([]byte(`{"ok":true}`))
+	}))
+	defer server.Close()
+
+	n := New("token", "12345").WithBaseURL(server.URL)
+	if err := n.Send(context.Background(), newTestNotification()); err != nil {
+		t.Fatalf("Send: %v", err)
+	}
+	if markup != "" {
+		t.Errorf("reply_markup without buttons = %q", markup)
+	}
+
+	notif := newTestNotification()
+	notif.Buttons = []core.Button{{Text: "Approve", Data: "/approve ab12"}, {Text: "Reject", Data: "/reject ab12"}}
+	if err := n.Send(context.Background(), notif); err != nil {
+		t.Fatalf("Send: %v", err)
+	}
+	want := `{"inline_keyboard":[[{"text":"Approve","callback_data":"/approve ab12"},{"text":"Reject","callback_data":"/reject ab12"}]]}`
+	if markup != want {
+		t.Errorf("reply_markup = %s, want %s", markup, want)
+	}
+}
+
+func TestNotifier_SendStructured(t *testing.T) {
+	var forms []map[string]string
+	reject := false
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		r.ParseForm()
+		forms = append(forms, map[string]string{
+			"text":                 r.FormValue("text"),
+			"parse_mode":           r.FormValue("parse_mode"),
+			"disable_notification": r.FormValue("disable_notification"),
+		})
+		if reject && r.FormValue("parse_mode") == "HTML" {
+			w.WriteHeader(http.StatusBadRequest)
+			w.Write([]byte(`{"ok":false,"description":"Bad Request: can't parse entities"}`))
+			return
+		}
+		w.Write([]byte(`{"ok":true}`))
+	}))
+	defer server.Close()
+
+	notif := newTestNotification()
+	notif.Title = "Disk <full>"
+	notif.Priority = core.PriorityUrgent
+	notif.Fields = []core.Field{{Name: "host", Value: "db1"}}
+	notif.Tags = []string{"ops"}
+	notif.URL = "https://grafana.example/d/disk?a=1&b=2"
+
+	n := New("token", "12345").WithBaseURL(server.URL)
+	if err := n.Send(context.Background(), notif); err != nil {
+		t.Fatalf("Send: %v", err)
+	}
+	want := "<b>🚨 Disk &lt;full&gt;</b>\nhello from test\n\n<b>host:</b> db1\n\n#ops\n\n" +
+		`<a href="https://grafana.example/d/disk?a=1&amp;b=2">https://grafana.example/d/disk?a=1&amp;b=2</a>`
+	if got := forms[0]; got["text"] != want || got["parse_mode"] != "HTML" {
+		t.Errorf("sent %q (%s), want %q", got["text"], got["parse_mode"], want)
+	}
+
+	forms, reject = nil, true
+	notif.Priority = core.PriorityLow
+	if err := n.Send(context.Background(), notif); err != nil {
+		t.Fatalf("Send with rejected markup: %v", err)
+	}
+	if len(forms) != 2 || forms[1]["parse_mode"] != "" || forms[1]["text"] != notif.PlainText() {
+		t.Fatalf("fallback sent %v", forms)
+	}
+	if forms[1]["disable_notification"] != "true" {
+		t.Error("low priority was not sent silently")
+	}
+}
+
+func TestNotifier_React(t *testing.T) {
+	var path string
+	var form []string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		r.ParseForm()
+		path = r.URL.Path
+		form = append(form, r.FormValue("chat_id")+" "+r.FormValue("message_id")+" "+r.FormValue("reaction"))
+		w.Write([]byte(`{"ok":true}`))
+	}))
+	defer server.Close()
+
+	n := New("token", "12345").WithBaseURL(server.URL)
+	var _ core.Reactor = n
+	if err := n.React(context.Background(), -100, 7, "👀"); err != nil {
+		t.Fatalf("React: %v", err)
+	}
+	if err := n.React(context.Background(), -100, 7, ""); err != nil {
+		t.Fatalf("React to clear: %v", err)
+	}
+	if !strings.HasSuffix(path, "/setMessageReaction") {
+		t.Errorf("path = %s", path)
+	}
+	want := []string{`-100 7 [{"emoji":"👀","type":"emoji"}]`, "-100 7 []"}
+	if strings.Join(form, "\n") != strings.Join(want, "\n") {
+		t.Errorf("requests = %q, want %q", form, want)
+	}
+}
+
+func TestNotifier_SendTyping(t *testing.T) {
+	var path, chatID, action string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		r.ParseForm()
+		path, chatID, action = r.URL.Path, r.FormValue("chat_id"), r.FormValue("action")
+		w.Write([]byte(`{"ok":true}`))
+	}))
+	defer server.Close()
+
+	n := New("token", "12345").WithBaseURL(server.URL)
+	var _ core.TypingSender = n
+	if err := n.SendTyping(context.Background(), 42); err != nil {
+		t.Fatalf("SendTyping: %v", err)
+	}
+	if !strings.HasSuffix(path, "/sendChatAction") || chatID != "42" || action != "typing" {
+		t.Errorf("request = %s chat_id=%s action=%s", path, chatID, action)
+	}
+}
+
+func TestNotifier_SetCommands(t *testing.T) {
+	var path, commands, scope string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		r.ParseForm()
+		path, commands, scope = r.URL.Path, r.FormValue("commands"), r.FormValue("scope")
+		w.Write([]byte(`{"ok":true}`))
+	}))
+	defer server.Close()
+
+	n := New("token", "12345").WithBaseURL(server.URL)
+	var _ core.CommandPublisher = n
+	err := n.SetCommands(context.Background(), 0, []core.Command{{Name: "tasks", Description: "List open tasks"}})
+	if err != nil {
+		t.Fatalf("SetCommands: %v", err)
+	}
+	if !strings.HasSuffix(path, "/setMyCommands") || commands != `[{"command":"tasks","description":"List open tasks"}]` || scope != "" {
+		t.Errorf("request = %s commands=%s scope=%s", path, commands, scope)
+	}
+
+	if err := n.SetCommands(context.Background(), -100, nil); err != nil {
+		t.Fatalf("SetCommands for a chat: %v", err)
+	}
+	if commands != "[]" || scope != `{"type":"chat","chat_id":-100}` {
+		t.Errorf("chat menu: commands=%s scope=%s", commands, scope)
+	}
+}
+
 func TestNotifier_Name(t *testing.T) {
 	n := New("token", "chat")
 	if n.Name() != "telegram" {
@@ -99,3 +324,80 @@ func TestNotifier_BotTokenInURL(t *testing.T) {
 		t.Errorf("unexpected path: %s", requestedPath)
 	}
 }
+
+func TestNotifier_SendDocument(t *testing.T) {
+	var path, chatID, caption, filename, content string
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		path = r.URL.Path
+		if err := r.ParseMultipartForm(1 << 20); err != nil {
+			t.Errorf("parse multipart: %v", err)
+		}
+		chatID = r.FormValue("chat_id")
+		caption = r.FormValue("caption")
+		f, hdr, err := r.FormFile("document")
+		if err != nil {
+			t.Errorf("form file: %v", err)
+		} else {
+			data, _ := io.ReadAll(f)
+			filename, content = hdr.Filename, string(data)
+		}
+		w.WriteHeader(http.StatusOK)
+		w.Write([]byte(`{"ok":true}`))
+	}))
+	defer server.Close()
+
+	n := New("test-token", "12345").WithBaseURL(server.URL)
+	err := n.SendDocument(context.Background(), core.Document{Name: "report.csv", Data: []byte("a,b\n1,2\n"), Caption: "nightly"})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if path != "/bottest-token/sendDocument" {
+		t.Errorf("unexpected path: %s", path)
+	}
+	if chatID != "12345" || caption != "nightly" || filename != "report.csv" || content != "a,b\n1,2\n" {
+		t.Errorf("got chat_id=%q caption=%q filename=%q content=%q", chatID, caption, filename, content)
+	}
+
+	if err := n.SendDocument(context.Background(), core.Document{Name: "report.csv", Data: []byte("x"), ChatID: -100777}); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if chatID != "-100777" {
+		t.Errorf("document for another chat went to chat_id=%q", chatID)
+	}
+}
+
+func TestNotifier_SendDocumentAPIError(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusRequestEntityTooLarge)
+		w.Write([]byte(`{"ok":false,"description":"Request Entity Too Large"}`))
+	}))
+	defer server.Close()
+
+	n := New("test-token", "12345").WithBaseURL(server.URL)
+	err := n.SendDocument(context.Background(), core.Document{Name: "big.bin", Data: []byte("x")})
+	if err == nil || !strings.Contains(err.Error(), "Too Large") {
+		t.Fatalf("err = %v", err)
+	}
+}
+
+func TestNotifier_BotUsername(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/botgood-token/getMe" {
+			w.WriteHeader(http.StatusUnauthorized)
+			w.Write([]byte(`{"ok":false,"description":"Unauthorized"}`))
+			return
+		}
+		w.Write([]byte(`{"ok":true,"result":{"id":1,"is_bot":true,"username":"openslack_bot"}}`))
+	}))
+	defer server.Close()
+
+	name, err := New("good-token", "12345").WithBaseURL(server.URL).BotUsername(context.Background())
+	if err != nil || name != "openslack_bot" {
+		t.Errorf("BotUsername = %q, %v", name, err)
+	}
+	if _, err := New("bad-token", "12345").WithBaseURL(server.URL).BotUsername(context.Background()); err == nil || !strings.Contains(err.Error(), "Unauthorized") {
+		t.Errorf("bad token err = %v", err)
+	}
+}
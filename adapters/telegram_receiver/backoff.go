@@ -0,0 +1,105 @@
+package telegram_receiver
+
+import (
+	"errors"
+	"fmt"
+	"math/rand/v2"
+	"time"
+)
+
+const (
+	minBackoff = time.Second
+	maxBackoff = 2 * time.Minute
+
+	// breakerThreshold is how many polls in a row must fail before the
+	// receiver reports itself degraded and falls back to probing every
+	// maxBackoff.
+	breakerThreshold = 5
+)
+
+// rateLimitedError is a 429 from Telegram, which says how long to wait.
+type rateLimitedError struct {
+	after time.Duration
+}
+
+func (e *rateLimitedError) Error() string {
+	return fmt.Sprintf("rate limited, retry after %s", e.after)
+}
+
+// health tracks consecutive poll failures. Guarded by Receiver.mu.
+type health struct {
+	failures     int
+	firstFailure time.Time
+	lastErr      error
+	degraded     bool
+}
+
+// Degraded reports whether polling has failed breakerThreshold times in a
+// row, since when, and the most recent error.
+func (r *Receiver) Degraded() (since time.Time, lastErr error, degraded bool) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	h := r.health
+	if !h.degraded {
+		return time.Time{}, nil, false
+	}
+	return h.firstFailure, h.lastErr, true
+}
+
+// failed records a poll failure and returns how long to wait before the
+// next poll: Telegram's retry_after for a 429, otherwise exponential
+// backoff with jitter, capped at maxBackoff.
+func (r *Receiver) failed(err error) time.Duration {
+	r.mu.Lock()
+	h := &r.health
+	if h.failures == 0 {
+		h.firstFailure = time.Now()
+	}
+	h.failures++
+	h.lastErr = err
+	opened := !h.degraded && h.failures >= breakerThreshold
+	if opened {
+		h.degraded = true
+	}
+	failures, since := h.failures, h.firstFailure
+	r.mu.Unlock()
+
+	if opened {
+		r.logger.Warn("telegram receiver degraded", "since", since, "failures", failures)
+	}
+
+	var rl *rateLimitedError
+	if errors.As(err, &rl) && rl.after > 0 {
+		return rl.after
+	}
+	return jitter(backoff(failures))
+}
+
+// succeeded resets the failure count after a successful poll.
+func (r *Receiver) succeeded() {
+	r.mu.Lock()
+	h := r.health
+	r.health = health{}
+	r.mu.Unlock()
+
+	if h.degraded {
+		r.logger.Info("telegram receiver recovered", "down_for", time.Since(h.firstFailure).Truncate(time.Second))
+	}
+}
+
+// backoff is the delay after failures consecutive failures: minBackoff
+// doubled each time, up to maxBackoff.
+func backoff(failures int) time.Duration {
+	if failures >= breakerThreshold {
+		return maxBackoff
+	}
+	d := minBackoff << (failures - 1)
+	return min(d, maxBackoff)
+}
+
+// jitter spreads d over [d/2, d] so restarted clients do not retry in
+// lockstep.
+func jitter(d time.Duration) time.Duration {
+	half := d / 2
+	return half + rand.N(half+1)
+}
@@ -0,0 +1,50 @@
+package telegram_receiver
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+)
+
+// CatchUp is what the receiver does with messages sent while it was
+// offline.
+type CatchUp string
+
+// Catch-up policies.
+const (
+	CatchUpProcess   CatchUp = "process"   // handle them as if just sent
+	CatchUpDrop      CatchUp = "drop"      // skip them silently
+	CatchUpSummarize CatchUp = "summarize" // skip them and say how many
+)
+
+// Config is the receiver configuration.
+type Config struct {
+	CatchUp CatchUp `json:"catch_up"`
+}
+
+// LoadConfig reads and validates a receiver config file.
+// Returns nil, nil if the file does not exist.
+func LoadConfig(path string) (*Config, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("read receiver config: %w", err)
+	}
+
+	var cfg Config
+	if err := json.Unmarshal(data, &cfg); err != nil {
+		return nil, fmt.Errorf("parse receiver config: %w", err)
+	}
+
+	if cfg.CatchUp == "" {
+		cfg.CatchUp = CatchUpProcess
+	}
+	switch cfg.CatchUp {
+	case CatchUpProcess, CatchUpDrop, CatchUpSummarize:
+	default:
+		return nil, fmt.Errorf("catch_up must be process, drop or summarize, got %q", cfg.CatchUp)
+	}
+	return &cfg, nil
+}
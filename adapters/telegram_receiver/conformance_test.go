@@ -0,0 +1,54 @@
+package telegram_receiver_test
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/jdelaire/openslack/adapters/telegram_receiver"
+	"github.com/jdelaire/openslack/core"
+	"github.com/jdelaire/openslack/core/adaptertest"
+)
+
+func TestConformance(t *testing.T) {
+	adaptertest.TestReceiver(t, adaptertest.ReceiverHarness{
+		New: func(t *testing.T, texts []string, handler core.MessageHandler) core.Receiver {
+			var once sync.Once
+			srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				served := false
+				once.Do(func() {
+					served = true
+					var updates []map[string]any
+					for i, text := range texts {
+						updates = append(updates, map[string]any{
+							"update_id": 10 + i,
+							"message": map[string]any{
+								"message_id": i + 1,
+								"from":       map[string]any{"id": 1},
+								"chat":       map[string]any{"id": 1},
+								"date":       time.Now().Unix(),
+								"text":       text,
+							},
+						})
+					}
+					json.NewEncoder(w).Encode(map[string]any{"ok": true, "result": updates})
+				})
+				if !served {
+					<-r.Context().Done()
+				}
+			}))
+			t.Cleanup(srv.Close)
+			return telegram_receiver.New("tok", handler, testLogger()).WithBaseURL(srv.URL)
+		},
+		NewFailing: func(t *testing.T, handler core.MessageHandler) core.Receiver {
+			srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				w.WriteHeader(http.StatusInternalServerError)
+			}))
+			t.Cleanup(srv.Close)
+			return telegram_receiver.New("tok", handler, testLogger()).WithBaseURL(srv.URL)
+		},
+	})
+}
@@ -6,34 +6,65 @@ import (
 	"fmt"
 	"log/slog"
 	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"sync/atomic"
 	"time"
 
 	"github.com/jdelaire/openslack/core"
+	"github.com/jdelaire/openslack/core/persist"
 )
 
 const (
 	defaultBaseURL  = "https://api.telegram.org"
 	longPollTimeout = 30
 	httpTimeout     = 35 * time.Second
-	errorBackoff    = 5 * time.Second
+
+	// maxUpdates is the most updates getUpdates returns at once; a shorter
+	// batch means the backlog has been read.
+	maxUpdates = 100
+
+	// allowedUpdates are the update types the receiver handles; Telegram
+	// does not send others.
+	allowedUpdates = `["message","callback_query"]`
+
+	offsetNamespace = "telegram"
+	offsetKey       = "offset"
 )
 
 type apiResponse struct {
-	OK     bool            `json:"ok"`
-	Result json.RawMessage `json:"result"`
+	OK          bool            `json:"ok"`
+	Result      json.RawMessage `json:"result"`
+	Description string          `json:"description"`
+	Parameters  struct {
+		RetryAfter int `json:"retry_after"`
+	} `json:"parameters"`
 }
 
 type update struct {
-	UpdateID int64   `json:"update_id"`
-	Message  *message `json:"message"`
+	UpdateID      int64          `json:"update_id"`
+	Message       *message       `json:"message"`
+	CallbackQuery *callbackQuery `json:"callback_query"`
+
+	kind string // the update's type, such as "message" or "edited_message"
+}
+
+// callbackQuery is an inline button press.
+type callbackQuery struct {
+	ID      string   `json:"id"`
+	From    user     `json:"from"`
+	Message *message `json:"message"`
+	Data    string   `json:"data"`
 }
 
 type message struct {
-	MessageID int64  `json:"message_id"`
-	From      *user  `json:"from"`
-	Chat      chat   `json:"chat"`
-	Date      int64  `json:"date"`
-	Text      string `json:"text"`
+	MessageID      int64    `json:"message_id"`
+	From           *user    `json:"from"`
+	Chat           chat     `json:"chat"`
+	Date           int64    `json:"date"`
+	Text           string   `json:"text"`
+	ReplyToMessage *message `json:"reply_to_message"`
 }
 
 type user struct {
@@ -51,7 +82,22 @@ type Receiver struct {
 	logger   *slog.Logger
 	client   *http.Client
 	baseURL  string
+	after    func(time.Duration) <-chan time.Time
 	offset   int64
+	lastPoll atomic.Int64 // unix nanoseconds
+
+	store    persist.KV
+	handover bool
+
+	catchUp   CatchUp
+	notify    func(context.Context, string) error
+	startedAt time.Time
+	caughtUp  bool
+	skipped   int
+
+	mu      sync.Mutex
+	dropped map[string]int64
+	health  health
 }
 
 // New creates a Telegram receiver.
@@ -62,6 +108,9 @@ func New(botToken string, handler core.MessageHandler, logger *slog.Logger) *Rec
 		logger:   logger,
 		client:   &http.Client{Timeout: httpTimeout},
 		baseURL:  defaultBaseURL,
+		after:    time.After,
+		catchUp:  CatchUpProcess,
+		dropped:  make(map[string]int64),
 	}
 }
 
@@ -71,9 +120,89 @@ func (r *Receiver) WithBaseURL(url string) *Receiver {
 	return r
 }
 
+// WithWait overrides how the receiver waits out the backoff after a
+// failed poll (for testing). after is called with the delay, like
+// time.After.
+func (r *Receiver) WithWait(after func(time.Duration) <-chan time.Time) *Receiver {
+	r.after = after
+	return r
+}
+
+// WithOffset resumes polling from offset, the next update ID to fetch, as
+// handed over by the daemon being upgraded.
+func (r *Receiver) WithOffset(offset int64) *Receiver {
+	r.offset = offset
+	r.handover = true
+	return r
+}
+
+// WithOffsetStore saves the offset to kv after every batch and resumes
+// from it at Start, so a restart neither repeats nor refetches updates. An
+// offset handed over with WithOffset takes precedence.
+func (r *Receiver) WithOffsetStore(kv persist.KV) *Receiver {
+	r.store = kv
+	return r
+}
+
+// WithCatchUp sets what happens to messages sent before Start, while the
+// receiver was offline. notify delivers the CatchUpSummarize summary. After
+// an upgrade handover there is no backlog, so the policy does not apply.
+func (r *Receiver) WithCatchUp(policy CatchUp, notify func(context.Context, string) error) *Receiver {
+	r.catchUp = policy
+	r.notify = notify
+	return r
+}
+
+// Offset returns the next update ID to fetch. Call it after Start returns,
+// to hand polling over to another process without losing or repeating
+// updates.
+func (r *Receiver) Offset() int64 {
+	return r.offset
+}
+
+// LastPoll returns when Telegram last answered a poll, or the zero time if
+// it has not yet.
+func (r *Receiver) LastPoll() time.Time {
+	ns := r.lastPoll.Load()
+	if ns == 0 {
+		return time.Time{}
+	}
+	return time.Unix(0, ns)
+}
+
+// Dropped returns how many updates of each type arrived but could not be
+// handled, such as messages without text.
+func (r *Receiver) Dropped() map[string]int64 {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	counts := make(map[string]int64, len(r.dropped))
+	for k, v := range r.dropped {
+		counts[k] = v
+	}
+	return counts
+}
+
+// drop counts an unhandled update, logging the first of each type.
+func (r *Receiver) drop(kind string) {
+	r.mu.Lock()
+	r.dropped[kind]++
+	first := r.dropped[kind] == 1
+	r.mu.Unlock()
+	if first {
+		r.logger.Warn("dropping unhandled telegram update type", "type", kind)
+	}
+}
+
 // Start begins the long-poll loop. Blocks until ctx is cancelled.
 func (r *Receiver) Start(ctx context.Context) error {
 	r.logger.Info("telegram receiver started")
+	r.startedAt = time.Now()
+	r.caughtUp = r.handover || r.catchUp == CatchUpProcess
+	if r.store != nil && !r.handover {
+		if _, err := r.store.Get(offsetNamespace, offsetKey, &r.offset); err != nil {
+			r.logger.Warn("load offset failed", "error", err)
+		}
+	}
 	for {
 		if err := ctx.Err(); err != nil {
 			r.logger.Info("telegram receiver stopped")
@@ -86,17 +215,32 @@ func (r *Receiver) Start(ctx context.Context) error {
 				r.logger.Info("telegram receiver stopped")
 				return nil
 			}
-			r.logger.Error("poll error", "error", err)
+			wait := r.failed(err)
+			r.logger.Error("poll error", "error", err, "retry_in", wait)
 			select {
-			case <-time.After(errorBackoff):
+			case <-r.after(wait):
 			case <-ctx.Done():
 				return nil
 			}
 			continue
 		}
+		r.lastPoll.Store(time.Now().UnixNano())
+		r.succeeded()
 
+		prev := r.offset
 		for _, u := range updates {
-			if u.Message == nil || u.Message.Text == "" {
+			if q := u.CallbackQuery; q != nil {
+				r.handleCallback(ctx, u.UpdateID, q)
+				r.offset = u.UpdateID + 1
+				continue
+			}
+			if u.Message == nil {
+				r.drop(u.kind)
+				r.offset = u.UpdateID + 1
+				continue
+			}
+			if u.Message.Text == "" {
+				r.drop("message without text")
 				r.offset = u.UpdateID + 1
 				continue
 			}
@@ -106,25 +250,119 @@ func (r *Receiver) Start(ctx context.Context) error {
 				userID = u.Message.From.ID
 			}
 
+			if r.backlog(u.Message) {
+				r.offset = u.UpdateID + 1
+				continue
+			}
+
 			msg := core.InboundMessage{
 				UpdateID:  u.UpdateID,
+				MessageID: u.Message.MessageID,
 				ChatID:    u.Message.Chat.ID,
 				UserID:    userID,
 				Text:      u.Message.Text,
 				Timestamp: time.Unix(u.Message.Date, 0),
 			}
+			if u.Message.ReplyToMessage != nil {
+				msg.ReplyTo = u.Message.ReplyToMessage.MessageID
+			}
 
 			r.handler(msg)
 			r.offset = u.UpdateID + 1
 		}
+		r.saveOffset(prev)
+		if !r.caughtUp && len(updates) < maxUpdates {
+			r.finishCatchUp(ctx)
+		}
+	}
+}
+
+// backlog reports whether m was sent while offline and the catch-up
+// policy skips it.
+func (r *Receiver) backlog(m *message) bool {
+	if r.caughtUp || !time.Unix(m.Date, 0).Before(r.startedAt.Truncate(time.Second)) {
+		return false
+	}
+	r.skipped++
+	return true
+}
+
+// finishCatchUp ends the catch-up period once the backlog has been read,
+// summarizing what was skipped if the policy asks for it.
+func (r *Receiver) finishCatchUp(ctx context.Context) {
+	r.caughtUp = true
+	if r.skipped == 0 {
+		return
+	}
+	r.logger.Info("skipped messages sent while offline", "count", r.skipped, "policy", string(r.catchUp))
+	if r.catchUp != CatchUpSummarize || r.notify == nil {
+		return
+	}
+	noun := "messages"
+	if r.skipped == 1 {
+		noun = "message"
+	}
+	if err := r.notify(ctx, fmt.Sprintf("Skipped %d %s sent while offline.", r.skipped, noun)); err != nil {
+		r.logger.Warn("catch-up summary failed", "error", err)
 	}
 }
 
+// saveOffset persists the offset if it moved past prev.
+func (r *Receiver) saveOffset(prev int64) {
+	if r.store == nil || r.offset == prev {
+		return
+	}
+	if err := r.store.Put(offsetNamespace, offsetKey, r.offset); err != nil {
+		r.logger.Warn("save offset failed", "error", err)
+	}
+}
+
+// handleCallback passes a button press to the handler and answers it, so
+// Telegram stops showing the button as loading. Presses on messages too
+// old for Telegram to include are dropped.
+func (r *Receiver) handleCallback(ctx context.Context, updateID int64, q *callbackQuery) {
+	if q.Message == nil || q.Data == "" {
+		r.drop("callback_query without message")
+	} else {
+		r.handler(core.InboundMessage{
+			UpdateID:   updateID,
+			MessageID:  q.Message.MessageID,
+			ChatID:     q.Message.Chat.ID,
+			UserID:     q.From.ID,
+			Text:       q.Data,
+			Timestamp:  time.Now(),
+			CallbackID: q.ID,
+		})
+	}
+	if err := r.answerCallback(ctx, q.ID); err != nil {
+		r.logger.Warn("answer callback failed", "error", err)
+	}
+}
+
+func (r *Receiver) answerCallback(ctx context.Context, id string) error {
+	endpoint := fmt.Sprintf("%s/bot%s/answerCallbackQuery", r.baseURL, r.botToken)
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, endpoint,
+		strings.NewReader(url.Values{"callback_query_id": {id}}.Encode()))
+	if err != nil {
+		return fmt.Errorf("create request: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	resp, err := r.client.Do(req)
+	if err != nil {
+		return fmt.Errorf("http post: %w", err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("api status: %d", resp.StatusCode)
+	}
+	return nil
+}
+
 func (r *Receiver) poll(ctx context.Context) ([]update, error) {
-	url := fmt.Sprintf("%s/bot%s/getUpdates?offset=%d&timeout=%d",
-		r.baseURL, r.botToken, r.offset, longPollTimeout)
+	endpoint := fmt.Sprintf("%s/bot%s/getUpdates?offset=%d&timeout=%d&allowed_updates=%s",
+		r.baseURL, r.botToken, r.offset, longPollTimeout, url.QueryEscape(allowedUpdates))
 
-	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, endpoint, nil)
 	if err != nil {
 		return nil, fmt.Errorf("create request: %w", err)
 	}
@@ -135,6 +373,11 @@ func (r *Receiver) poll(ctx context.Context) ([]update, error) {
 	}
 	defer resp.Body.Close()
 
+	if resp.StatusCode == http.StatusTooManyRequests {
+		var apiResp apiResponse
+		json.NewDecoder(resp.Body).Decode(&apiResp)
+		return nil, &rateLimitedError{after: time.Duration(apiResp.Parameters.RetryAfter) * time.Second}
+	}
 	if resp.StatusCode != http.StatusOK {
 		return nil, fmt.Errorf("api status: %d", resp.StatusCode)
 	}
@@ -148,10 +391,30 @@ func (r *Receiver) poll(ctx context.Context) ([]update, error) {
 		return nil, fmt.Errorf("api returned ok=false")
 	}
 
-	var updates []update
-	if err := json.Unmarshal(apiResp.Result, &updates); err != nil {
+	var raw []json.RawMessage
+	if err := json.Unmarshal(apiResp.Result, &raw); err != nil {
 		return nil, fmt.Errorf("decode updates: %w", err)
 	}
+	updates := make([]update, len(raw))
+	for i, data := range raw {
+		var fields map[string]json.RawMessage
+		if err := json.Unmarshal(data, &updates[i]); err != nil {
+			return nil, fmt.Errorf("decode updates: %w", err)
+		}
+		json.Unmarshal(data, &fields)
+		updates[i].kind = updateKind(fields)
+	}
 
 	return updates, nil
 }
+
+// updateKind names an update's type from its fields. Each update carries
+// update_id and exactly one other field.
+func updateKind(fields map[string]json.RawMessage) string {
+	for k := range fields {
+		if k != "update_id" {
+			return k
+		}
+	}
+	return "unknown"
+}
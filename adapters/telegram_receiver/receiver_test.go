@@ -8,12 +8,16 @@ import (
 	"log/slog"
 	"net/http"
 	"net/http/httptest"
+	"os"
+	"path/filepath"
 	"sync"
+	"sync/atomic"
 	"testing"
 	"time"
 
 	"github.com/jdelaire/openslack/adapters/telegram_receiver"
 	"github.com/jdelaire/openslack/core"
+	"github.com/jdelaire/openslack/internal/state"
 )
 
 func testLogger() *slog.Logger {
@@ -47,6 +51,16 @@ func TestPollSuccess(t *testing.T) {
 							"text":       "/status",
 						},
 					},
+					{
+						"update_id": 101,
+						"message": map[string]any{
+							"message_id":       2,
+							"chat":             map[string]any{"id": 123},
+							"date":             time.Now().Unix(),
+							"text":             "/cancel",
+							"reply_to_message": map[string]any{"message_id": 1, "chat": map[string]any{"id": 123}},
+						},
+					},
 				},
 			})
 		} else {
@@ -64,8 +78,8 @@ func TestPollSuccess(t *testing.T) {
 
 	mu.Lock()
 	defer mu.Unlock()
-	if len(received) != 1 {
-		t.Fatalf("received %d messages, want 1", len(received))
+	if len(received) != 2 {
+		t.Fatalf("received %d messages, want 2", len(received))
 	}
 	if received[0].Text != "/status" {
 		t.Errorf("text = %q, want /status", received[0].Text)
@@ -79,6 +93,12 @@ func TestPollSuccess(t *testing.T) {
 	if received[0].UpdateID != 100 {
 		t.Errorf("updateID = %d, want 100", received[0].UpdateID)
 	}
+	if received[0].MessageID != 1 {
+		t.Errorf("messageID = %d, want 1", received[0].MessageID)
+	}
+	if received[0].ReplyTo != 0 || received[1].ReplyTo != 1 {
+		t.Errorf("replyTo = %d, %d, want 0, 1", received[0].ReplyTo, received[1].ReplyTo)
+	}
 }
 
 func TestEmptyResult(t *testing.T) {
@@ -151,11 +171,13 @@ func TestSkipsNoText(t *testing.T) {
 }
 
 func TestOffsetIncrement(t *testing.T) {
+	var mu sync.Mutex
 	var offsets []string
-	callCount := 0
 	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
 		offsets = append(offsets, r.URL.Query().Get("offset"))
-		callCount++
+		callCount := len(offsets)
+		mu.Unlock()
 		if callCount == 1 {
 			json.NewEncoder(w).Encode(map[string]any{
 				"ok": true,
@@ -186,6 +208,8 @@ func TestOffsetIncrement(t *testing.T) {
 	recv := telegram_receiver.New("tok", func(_ core.InboundMessage) {}, testLogger()).WithBaseURL(srv.URL)
 	recv.Start(ctx)
 
+	mu.Lock()
+	defer mu.Unlock()
 	if len(offsets) < 2 {
 		t.Fatalf("expected at least 2 polls, got %d", len(offsets))
 	}
@@ -222,11 +246,37 @@ func TestContextCancellation(t *testing.T) {
 	}
 }
 
+// waitRecorder stands in for time.After, recording each backoff the
+// receiver asks for and ending it at once.
+type waitRecorder struct {
+	mu     sync.Mutex
+	waits  []time.Duration
+	onWait func(n int)
+}
+
+func (w *waitRecorder) after(d time.Duration) <-chan time.Time {
+	w.mu.Lock()
+	w.waits = append(w.waits, d)
+	n := len(w.waits)
+	w.mu.Unlock()
+	if w.onWait != nil {
+		w.onWait(n)
+	}
+	ch := make(chan time.Time, 1)
+	ch <- time.Now()
+	return ch
+}
+
+func (w *waitRecorder) recorded() []time.Duration {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	return append([]time.Duration(nil), w.waits...)
+}
+
 func TestAPIErrorBackoff(t *testing.T) {
-	callCount := 0
+	var calls atomic.Int32
 	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
-		callCount++
-		if callCount <= 2 {
+		if calls.Add(1) <= 2 {
 			w.WriteHeader(http.StatusInternalServerError)
 			fmt.Fprintln(w, "internal error")
 		} else {
@@ -235,14 +285,367 @@ func TestAPIErrorBackoff(t *testing.T) {
 	}))
 	defer srv.Close()
 
-	ctx, cancel := context.WithTimeout(context.Background(), 12*time.Second)
+	ctx, cancel := context.WithTimeout(context.Background(), 500*time.Millisecond)
+	defer cancel()
+
+	wait := &waitRecorder{}
+	recv := telegram_receiver.New("tok", func(_ core.InboundMessage) {}, testLogger()).WithBaseURL(srv.URL).WithWait(wait.after)
+	recv.Start(ctx)
+
+	if got := calls.Load(); got != 3 {
+		t.Errorf("got %d polls, want 3 (two failures, then a retry)", got)
+	}
+	waits := wait.recorded()
+	if len(waits) != 2 || waits[0] < 500*time.Millisecond || waits[0] > time.Second || waits[1] < time.Second || waits[1] > 2*time.Second {
+		t.Errorf("backoffs = %v, want about 1s then 2s", waits)
+	}
+	if _, _, degraded := recv.Degraded(); degraded {
+		t.Error("degraded after two failures, want healthy")
+	}
+}
+
+func TestRetryAfter(t *testing.T) {
+	var calls atomic.Int32
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if calls.Add(1) == 1 {
+			w.WriteHeader(http.StatusTooManyRequests)
+			json.NewEncoder(w).Encode(map[string]any{
+				"ok":          false,
+				"error_code":  429,
+				"description": "Too Many Requests: retry after 2",
+				"parameters":  map[string]any{"retry_after": 2},
+			})
+			return
+		}
+		<-r.Context().Done()
+	}))
+	defer srv.Close()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 500*time.Millisecond)
+	defer cancel()
+
+	wait := &waitRecorder{}
+	recv := telegram_receiver.New("tok", func(_ core.InboundMessage) {}, testLogger()).WithBaseURL(srv.URL).WithWait(wait.after)
+	recv.Start(ctx)
+
+	if got := calls.Load(); got != 2 {
+		t.Fatalf("got %d polls, want 2", got)
+	}
+	if waits := wait.recorded(); len(waits) != 1 || waits[0] != 2*time.Second {
+		t.Errorf("backoffs = %v, want retry_after (2s)", waits)
+	}
+}
+
+func TestCircuitBreaker(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusBadGateway)
+	}))
+	defer srv.Close()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	// Stop after the fifth failure, which opens the breaker.
+	wait := &waitRecorder{onWait: func(n int) {
+		if n == 5 {
+			cancel()
+		}
+	}}
+	start := time.Now()
+	recv := telegram_receiver.New("tok", func(_ core.InboundMessage) {}, testLogger()).WithBaseURL(srv.URL).WithWait(wait.after)
+	recv.Start(ctx)
+
+	since, lastErr, degraded := recv.Degraded()
+	if !degraded {
+		t.Fatal("not degraded after repeated failures")
+	}
+	if since.Before(start) || since.After(start.Add(time.Second)) {
+		t.Errorf("degraded since %v, want the first failure", since)
+	}
+	if lastErr == nil || lastErr.Error() != "api status: 502" {
+		t.Errorf("last error = %v", lastErr)
+	}
+	// Backoff doubles from 1s with jitter, then probes every 2 minutes
+	// once degraded.
+	waits := wait.recorded()
+	if len(waits) != 5 {
+		t.Fatalf("backoffs = %v, want 5", waits)
+	}
+	for i, d := range waits[:4] {
+		if hi := time.Second << i; d < hi/2 || d > hi {
+			t.Errorf("backoff %d = %s, want between %s and %s", i+1, d, hi/2, hi)
+		}
+	}
+	if d := waits[4]; d < time.Minute || d > 2*time.Minute {
+		t.Errorf("degraded backoff = %s, want between 1m and 2m", d)
+	}
+}
+
+func TestOffsetHandover(t *testing.T) {
+	var first string
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if first == "" {
+			first = r.URL.Query().Get("offset")
+			json.NewEncoder(w).Encode(map[string]any{
+				"ok":     true,
+				"result": []map[string]any{{"update_id": 500}},
+			})
+			return
+		}
+		<-r.Context().Done()
+	}))
+	defer srv.Close()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 500*time.Millisecond)
+	defer cancel()
+
+	recv := telegram_receiver.New("tok", func(_ core.InboundMessage) {}, testLogger()).WithBaseURL(srv.URL).WithOffset(500)
+	recv.Start(ctx)
+
+	if first != "500" {
+		t.Errorf("first offset = %q, want '500'", first)
+	}
+	if got := recv.Offset(); got != 501 {
+		t.Errorf("Offset() = %d, want 501", got)
+	}
+	if recv.LastPoll().IsZero() {
+		t.Error("LastPoll() is zero after a successful poll")
+	}
+}
+
+func TestCallbackQuery(t *testing.T) {
+	var mu sync.Mutex
+	var received []core.InboundMessage
+	var answered string
+
+	polls := 0
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/bottok/answerCallbackQuery" {
+			r.ParseForm()
+			mu.Lock()
+			answered = r.FormValue("callback_query_id")
+			mu.Unlock()
+			w.Write([]byte(`{"ok":true,"result":true}`))
+			return
+		}
+		polls++
+		if polls > 1 {
+			<-r.Context().Done()
+			return
+		}
+		json.NewEncoder(w).Encode(map[string]any{
+			"ok": true,
+			"result": []map[string]any{{
+				"update_id": 70,
+				"callback_query": map[string]any{
+					"id":      "cb1",
+					"from":    map[string]any{"id": 42},
+					"message": map[string]any{"message_id": 9, "chat": map[string]any{"id": 123}, "date": 1},
+					"data":    "/reject ab12",
+				},
+			}},
+		})
+	}))
+	defer srv.Close()
+
+	handler := func(msg core.InboundMessage) {
+		mu.Lock()
+		received = append(received, msg)
+		mu.Unlock()
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 2*time.Second)
+	defer cancel()
+	recv := telegram_receiver.New("tok", handler, testLogger()).WithBaseURL(srv.URL)
+	recv.Start(ctx)
+
+	mu.Lock()
+	defer mu.Unlock()
+	if len(received) != 1 {
+		t.Fatalf("received %d messages, want 1", len(received))
+	}
+	got := received[0]
+	if got.Text != "/reject ab12" || got.ChatID != 123 || got.UserID != 42 || got.MessageID != 9 || got.CallbackID != "cb1" {
+		t.Errorf("message = %+v", got)
+	}
+	if time.Since(got.Timestamp) > time.Minute {
+		t.Errorf("timestamp = %v, want the time of the press", got.Timestamp)
+	}
+	if answered != "cb1" {
+		t.Errorf("answered callback %q, want cb1", answered)
+	}
+	if recv.Offset() != 71 {
+		t.Errorf("offset = %d, want 71", recv.Offset())
+	}
+}
+
+func TestOffsetStore(t *testing.T) {
+	store := state.NewStore(filepath.Join(t.TempDir(), "state.json"))
+	store.Put("telegram", "offset", int64(300))
+
+	var first string
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if first == "" {
+			first = r.URL.Query().Get("offset")
+			json.NewEncoder(w).Encode(map[string]any{
+				"ok":     true,
+				"result": []map[string]any{{"update_id": 300}, {"update_id": 301}},
+			})
+			return
+		}
+		<-r.Context().Done()
+	}))
+	defer srv.Close()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 500*time.Millisecond)
+	defer cancel()
+
+	recv := telegram_receiver.New("tok", func(_ core.InboundMessage) {}, testLogger()).WithBaseURL(srv.URL).WithOffsetStore(store)
+	recv.Start(ctx)
+
+	if first != "300" {
+		t.Errorf("first offset = %q, want '300'", first)
+	}
+	var saved int64
+	if _, err := store.Get("telegram", "offset", &saved); err != nil {
+		t.Fatal(err)
+	}
+	if saved != 302 {
+		t.Errorf("saved offset = %d, want 302", saved)
+	}
+}
+
+// catchUpServer serves one batch holding two messages sent an hour ago and
+// one sent now, then blocks.
+func catchUpServer() *httptest.Server {
+	var once sync.Once
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		served := false
+		once.Do(func() {
+			served = true
+			old := time.Now().Add(-time.Hour).Unix()
+			msg := func(id int64, date int64) map[string]any {
+				return map[string]any{
+					"update_id": id,
+					"message": map[string]any{
+						"message_id": id,
+						"from":       map[string]any{"id": 1},
+						"chat":       map[string]any{"id": 1},
+						"date":       date,
+						"text":       "/status",
+					},
+				}
+			}
+			json.NewEncoder(w).Encode(map[string]any{
+				"ok":     true,
+				"result": []map[string]any{msg(1, old), msg(2, old), msg(3, time.Now().Unix()+1)},
+			})
+		})
+		if !served {
+			<-r.Context().Done()
+		}
+	}))
+}
+
+func TestCatchUp(t *testing.T) {
+	tests := []struct {
+		policy  telegram_receiver.CatchUp
+		handled int
+		summary string
+	}{
+		{telegram_receiver.CatchUpProcess, 3, ""},
+		{telegram_receiver.CatchUpDrop, 1, ""},
+		{telegram_receiver.CatchUpSummarize, 1, "Skipped 2 messages sent while offline."},
+	}
+	for _, tt := range tests {
+		t.Run(string(tt.policy), func(t *testing.T) {
+			srv := catchUpServer()
+			defer srv.Close()
+
+			var handled int
+			var summary string
+			notify := func(_ context.Context, text string) error {
+				summary = text
+				return nil
+			}
+
+			ctx, cancel := context.WithTimeout(context.Background(), 500*time.Millisecond)
+			defer cancel()
+
+			recv := telegram_receiver.New("tok", func(_ core.InboundMessage) { handled++ }, testLogger()).
+				WithBaseURL(srv.URL).WithCatchUp(tt.policy, notify)
+			recv.Start(ctx)
+
+			if handled != tt.handled {
+				t.Errorf("handled %d messages, want %d", handled, tt.handled)
+			}
+			if summary != tt.summary {
+				t.Errorf("summary = %q, want %q", summary, tt.summary)
+			}
+			if recv.Offset() != 4 {
+				t.Errorf("offset = %d, want 4", recv.Offset())
+			}
+		})
+	}
+}
+
+func TestLoadConfig(t *testing.T) {
+	dir := t.TempDir()
+
+	cfg, err := telegram_receiver.LoadConfig(filepath.Join(dir, "missing.json"))
+	if cfg != nil || err != nil {
+		t.Fatalf("missing file: got %v, %v; want nil, nil", cfg, err)
+	}
+
+	path := filepath.Join(dir, "receiver.json")
+	os.WriteFile(path, []byte(`{}`), 0600)
+	cfg, err = telegram_receiver.LoadConfig(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if cfg.CatchUp != telegram_receiver.CatchUpProcess {
+		t.Errorf("default catch_up = %q, want process", cfg.CatchUp)
+	}
+
+	os.WriteFile(path, []byte(`{"catch_up": "ignore"}`), 0600)
+	if _, err := telegram_receiver.LoadConfig(path); err == nil {
+		t.Error("expected error for unknown catch_up policy")
+	}
+}
+
+func TestAllowedUpdatesAndDropped(t *testing.T) {
+	var allowed string
+	var once sync.Once
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		served := false
+		once.Do(func() {
+			served = true
+			allowed = r.URL.Query().Get("allowed_updates")
+			json.NewEncoder(w).Encode(map[string]any{
+				"ok": true,
+				"result": []map[string]any{
+					{"update_id": 1, "edited_message": map[string]any{"message_id": 1, "chat": map[string]any{"id": 1}, "text": "x"}},
+					{"update_id": 2, "message": map[string]any{"message_id": 2, "chat": map[string]any{"id": 1}, "date": time.Now().Unix()}},
+					{"update_id": 3, "message": map[string]any{"message_id": 3, "chat": map[string]any{"id": 1}, "date": time.Now().Unix()}},
+				},
+			})
+		})
+		if !served {
+			<-r.Context().Done()
+		}
+	}))
+	defer srv.Close()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 500*time.Millisecond)
 	defer cancel()
 
 	recv := telegram_receiver.New("tok", func(_ core.InboundMessage) {}, testLogger()).WithBaseURL(srv.URL)
 	recv.Start(ctx)
 
-	// Should have retried after backoff.
-	if callCount < 2 {
-		t.Errorf("expected at least 2 calls (with backoff), got %d", callCount)
+	if allowed != `["message","callback_query"]` {
+		t.Errorf("allowed_updates = %q", allowed)
+	}
+	dropped := recv.Dropped()
+	if dropped["edited_message"] != 1 || dropped["message without text"] != 2 {
+		t.Errorf("Dropped() = %v", dropped)
 	}
 }
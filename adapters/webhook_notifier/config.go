@@ -0,0 +1,109 @@
+package webhook_notifier
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/url"
+	"os"
+	"time"
+)
+
+// Defaults for unset destination fields.
+const (
+	DefaultTimeoutSec     = 10
+	DefaultRetryBackoffMs = 500
+)
+
+// maxRetries caps a destination's retries.
+const maxRetries = 5
+
+// Config lists the destinations every notification is posted to.
+type Config struct {
+	Destinations []Destination `json:"destinations"`
+}
+
+// Destination is one webhook URL. Its body is signed with the secret in
+// the keychain account SecretAccount. Each attempt is bounded by
+// TimeoutSec; a failed post is retried Retries times, waiting
+// RetryBackoffMs and doubling, up to a minute. Zero TimeoutSec and RetryBackoffMs take
+// their defaults.
+type Destination struct {
+	Name           string `json:"name"`
+	URL            string `json:"url"`
+	SecretAccount  string `json:"secret_account"`
+	TimeoutSec     int    `json:"timeout_sec"`
+	Retries        int    `json:"retries"`
+	RetryBackoffMs int    `json:"retry_backoff_ms"`
+}
+
+// LoadConfig reads and validates a webhook config file.
+// Returns nil, nil if the file does not exist.
+func LoadConfig(path string) (*Config, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("read webhook config: %w", err)
+	}
+
+	var cfg Config
+	if err := json.Unmarshal(data, &cfg); err != nil {
+		return nil, fmt.Errorf("parse webhook config: %w", err)
+	}
+	if len(cfg.Destinations) == 0 {
+		return nil, fmt.Errorf("webhook config: no destinations")
+	}
+	names := map[string]bool{}
+	for i := range cfg.Destinations {
+		d := &cfg.Destinations[i]
+		if err := d.validate(); err != nil {
+			return nil, fmt.Errorf("webhook config: destination %q: %w", d.Name, err)
+		}
+		if names[d.Name] {
+			return nil, fmt.Errorf("webhook config: duplicate destination %q", d.Name)
+		}
+		names[d.Name] = true
+	}
+	return &cfg, nil
+}
+
+func (d *Destination) validate() error {
+	if d.Name == "" {
+		return fmt.Errorf("missing name")
+	}
+	u, err := url.Parse(d.URL)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		return fmt.Errorf("url must be an http or https URL, got %q", d.URL)
+	}
+	if d.SecretAccount == "" {
+		return fmt.Errorf("missing secret_account")
+	}
+	if d.TimeoutSec < 0 || d.Retries < 0 || d.RetryBackoffMs < 0 {
+		return fmt.Errorf("settings must not be negative")
+	}
+	if d.TimeoutSec > 300 {
+		return fmt.Errorf("timeout_sec must be at most 300, got %d", d.TimeoutSec)
+	}
+	if d.Retries > maxRetries {
+		return fmt.Errorf("retries must be at most %d, got %d", maxRetries, d.Retries)
+	}
+	return nil
+}
+
+// timeout returns the bound on one attempt, zero meaning the default.
+func (d *Destination) timeout() time.Duration {
+	if d.TimeoutSec == 0 {
+		return DefaultTimeoutSec * time.Second
+	}
+	return time.Duration(d.TimeoutSec) * time.Second
+}
+
+// backoff returns the wait before the first retry, zero meaning the
+// default.
+func (d *Destination) backoff() time.Duration {
+	if d.RetryBackoffMs == 0 {
+		return DefaultRetryBackoffMs * time.Millisecond
+	}
+	return time.Duration(d.RetryBackoffMs) * time.Millisecond
+}
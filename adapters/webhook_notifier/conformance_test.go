@@ -0,0 +1,51 @@
+package webhook_notifier
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+
+	"github.com/jdelaire/openslack/core"
+	"github.com/jdelaire/openslack/core/adaptertest"
+)
+
+func TestConformance(t *testing.T) {
+	adaptertest.TestNotifier(t, adaptertest.NotifierHarness{
+		New: func(t *testing.T) (core.Notifier, func() []string) {
+			var mu sync.Mutex
+			var texts []string
+			srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				var n core.Notification
+				json.NewDecoder(r.Body).Decode(&n)
+				mu.Lock()
+				texts = append(texts, n.Text)
+				mu.Unlock()
+			}))
+			t.Cleanup(srv.Close)
+			delivered := func() []string {
+				mu.Lock()
+				defer mu.Unlock()
+				return append([]string(nil), texts...)
+			}
+			return newTestNotifier(t, Destination{Name: "test", URL: srv.URL, SecretAccount: "test"}), delivered
+		},
+		NewFailing: func(t *testing.T) core.Notifier {
+			srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				http.Error(w, "bad signature", http.StatusUnauthorized)
+			}))
+			t.Cleanup(srv.Close)
+			return newTestNotifier(t, Destination{Name: "test", URL: srv.URL, SecretAccount: "test"})
+		},
+		NewBlocking: func(t *testing.T) core.Notifier {
+			release := make(chan struct{})
+			srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				<-release
+			}))
+			t.Cleanup(srv.Close)
+			t.Cleanup(func() { close(release) })
+			return newTestNotifier(t, Destination{Name: "test", URL: srv.URL, SecretAccount: "test", Retries: 2})
+		},
+	})
+}
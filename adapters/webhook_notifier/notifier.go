@@ -0,0 +1,258 @@
+// Package webhook_notifier posts notifications as JSON to configured
+// URLs, so other systems can consume them. The body is the notification
+// as core.Notification marshals it, and each request is signed:
+//
+//	X-OpenSlack-Timestamp: 1767225600
+//	X-OpenSlack-Signature: sha256=<hex HMAC-SHA256 of "1767225600." + body>
+//
+// The notification's id is the same on every retry, so receivers can
+// drop repeats.
+package webhook_notifier
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jdelaire/openslack/core"
+)
+
+// Signing headers.
+const (
+	TimestampHeader = "X-OpenSlack-Timestamp"
+	SignatureHeader = "X-OpenSlack-Signature"
+)
+
+// maxRetryAfter caps the wait a destination can ask for with Retry-After.
+const maxRetryAfter = time.Minute
+
+// maxBackoff caps the doubling wait between retries.
+const maxBackoff = time.Minute
+
+// maxPartial bounds how many partly delivered notifications are
+// remembered; the oldest is forgotten first.
+const maxPartial = 256
+
+// SecretLookup reads a secret from a keychain account, as keychain.Get
+// does.
+type SecretLookup func(account string) (string, error)
+
+// destination is a configured destination with its secret.
+type destination struct {
+	Destination
+	secret []byte
+}
+
+// Notifier posts notifications to every configured destination. It
+// remembers which destinations took a notification that others failed,
+// so a retried Send with the same ID posts only to the ones that failed.
+type Notifier struct {
+	dests  []destination
+	client *http.Client
+	now    func() time.Time
+	after  func(time.Duration) <-chan time.Time
+
+	mu        sync.Mutex
+	delivered map[string][]bool // notification ID -> destinations that took it
+	partial   []string          // IDs in delivered, oldest first
+}
+
+// New creates a webhook notifier for cfg, reading each destination's
+// secret with lookup.
+func New(cfg *Config, lookup SecretLookup) (*Notifier, error) {
+	n := &Notifier{client: &http.Client{}, now: time.Now, after: time.After, delivered: make(map[string][]bool)}
+	for _, d := range cfg.Destinations {
+		secret, err := lookup(d.SecretAccount)
+		if err != nil {
+			return nil, fmt.Errorf("webhook %s: read secret %q: %w", d.Name, d.SecretAccount, err)
+		}
+		if secret == "" {
+			return nil, fmt.Errorf("webhook %s: secret %q is empty", d.Name, d.SecretAccount)
+		}
+		n.dests = append(n.dests, destination{Destination: d, secret: []byte(secret)})
+	}
+	return n, nil
+}
+
+func (n *Notifier) Name() string { return "webhook" }
+
+// Send posts notif to every destination at once, each under its own
+// timeout and retries, and fails if any of them did. Destinations that
+// already took a notification with the same ID are skipped. A
+// notification without an ID gets one.
+func (n *Notifier) Send(ctx context.Context, notif core.Notification) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	retryable := notif.ID != ""
+	if !retryable {
+		notif.ID = uuid.New().String()
+	}
+	body, err := json.Marshal(notif)
+	if err != nil {
+		return fmt.Errorf("webhook request: %w", err)
+	}
+
+	done := n.deliveredTo(notif.ID)
+	errs := make([]error, len(n.dests))
+	var wg sync.WaitGroup
+	for i := range n.dests {
+		if done[i] {
+			continue
+		}
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			errs[i] = n.deliver(ctx, &n.dests[i], body)
+		}()
+	}
+	wg.Wait()
+	if retryable {
+		n.record(notif.ID, done, errs)
+	}
+	return errors.Join(errs...)
+}
+
+// deliveredTo returns which destinations already took notification id.
+func (n *Notifier) deliveredTo(id string) []bool {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+	done := make([]bool, len(n.dests))
+	copy(done, n.delivered[id])
+	return done
+}
+
+// record notes the destinations that have now taken notification id, or
+// forgets it once all have.
+func (n *Notifier) record(id string, done []bool, errs []error) {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+	failed := false
+	for i, err := range errs {
+		if err == nil {
+			done[i] = true
+		} else {
+			failed = true
+		}
+	}
+	if !failed {
+		delete(n.delivered, id)
+	} else {
+		if _, known := n.delivered[id]; !known {
+			n.partial = append(n.partial, id)
+		}
+		n.delivered[id] = done
+	}
+	// Trim the order list of forgotten IDs and the oldest over the bound.
+	kept := n.partial[:0]
+	for _, p := range n.partial {
+		if _, ok := n.delivered[p]; ok {
+			kept = append(kept, p)
+		}
+	}
+	for len(kept) > maxPartial {
+		delete(n.delivered, kept[0])
+		kept = kept[1:]
+	}
+	n.partial = kept
+}
+
+// deliver posts body to d, retrying failures that may pass on another
+// try: network errors, timeouts, 408, 429 and 5xx. Retry-After is
+// honoured when longer than the backoff. The backoff doubles up to
+// maxBackoff.
+func (n *Notifier) deliver(ctx context.Context, d *destination, body []byte) error {
+	wait := min(d.backoff(), maxBackoff)
+	for attempt := 0; ; attempt++ {
+		err := n.post(ctx, d, body)
+		if err == nil {
+			return nil
+		}
+		var se *statusError
+		if attempt == d.Retries || errors.As(err, &se) && !se.retryable() {
+			return fmt.Errorf("webhook %s: %w", d.Name, err)
+		}
+		if se != nil && se.retryAfter > wait {
+			wait = se.retryAfter
+		}
+		select {
+		case <-n.after(wait):
+		case <-ctx.Done():
+			return fmt.Errorf("webhook %s: %w", d.Name, err)
+		}
+		wait = min(wait*2, maxBackoff)
+	}
+}
+
+// post makes one signed attempt.
+func (n *Notifier) post(ctx context.Context, d *destination, body []byte) error {
+	ctx, cancel := context.WithTimeout(ctx, d.timeout())
+	defer cancel()
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, d.URL, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	ts := strconv.FormatInt(n.now().Unix(), 10)
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("User-Agent", "openslack-webhook")
+	req.Header.Set(TimestampHeader, ts)
+	req.Header.Set(SignatureHeader, Sign(d.secret, ts, body))
+
+	resp, err := n.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	snippet, _ := io.ReadAll(io.LimitReader(resp.Body, 200))
+	if resp.StatusCode >= 200 && resp.StatusCode < 300 {
+		return nil
+	}
+	se := &statusError{code: resp.StatusCode, body: strings.TrimSpace(string(snippet))}
+	if s, err := strconv.Atoi(resp.Header.Get("Retry-After")); err == nil && s > 0 {
+		se.retryAfter = min(time.Duration(s)*time.Second, maxRetryAfter)
+	}
+	return se
+}
+
+// statusError is a reply other than 2xx.
+type statusError struct {
+	code       int
+	body       string
+	retryAfter time.Duration
+}
+
+func (e *statusError) Error() string {
+	if e.body == "" {
+		return fmt.Sprintf("status %d", e.code)
+	}
+	return fmt.Sprintf("status %d: %s", e.code, e.body)
+}
+
+func (e *statusError) retryable() bool {
+	return e.code == http.StatusRequestTimeout || e.code == http.StatusTooManyRequests || e.code >= 500
+}
+
+// Sign returns the signature header value for body sent at timestamp, a
+// decimal Unix time: "sha256=" and the hex HMAC-SHA256, keyed by secret,
+// of the timestamp, a dot and the body. Receivers compute the same and
+// compare in constant time, and should refuse old timestamps.
+func Sign(secret []byte, timestamp string, body []byte) string {
+	mac := hmac.New(sha256.New, secret)
+	mac.Write([]byte(timestamp))
+	mac.Write([]byte{'.'})
+	mac.Write(body)
+	return "sha256=" + hex.EncodeToString(mac.Sum(nil))
+}
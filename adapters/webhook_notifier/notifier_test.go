@@ -0,0 +1,286 @@
+package webhook_notifier
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/jdelaire/openslack/core"
+)
+
+// fakeHook answers each request with the next status in statuses, then
+// 200, and keeps what it was sent.
+type fakeHook struct {
+	mu       sync.Mutex
+	statuses []int
+	header   http.Header
+	requests []http.Header
+	bodies   [][]byte
+}
+
+func (f *fakeHook) serve(t *testing.T) string {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		f.mu.Lock()
+		f.requests = append(f.requests, r.Header.Clone())
+		f.bodies = append(f.bodies, body)
+		status := http.StatusOK
+		if len(f.statuses) > 0 {
+			status, f.statuses = f.statuses[0], f.statuses[1:]
+		}
+		for k, v := range f.header {
+			w.Header()[k] = v
+		}
+		f.mu.Unlock()
+		w.WriteHeader(status)
+		if status != http.StatusOK {
+			w.Write([]byte("try later\n"))
+		}
+	}))
+	t.Cleanup(srv.Close)
+	return srv.URL
+}
+
+func (f *fakeHook) count() int {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return len(f.bodies)
+}
+
+func secrets(account string) (string, error) {
+	if account == "missing" {
+		return "", errors.New("not in keychain")
+	}
+	return "secret-" + account, nil
+}
+
+func newTestNotifier(t *testing.T, dests ...Destination) *Notifier {
+	t.Helper()
+	n, err := New(&Config{Destinations: dests}, secrets)
+	if err != nil {
+		t.Fatal(err)
+	}
+	n.now = func() time.Time { return time.Unix(1767225600, 0) }
+	return n
+}
+
+func TestSend(t *testing.T) {
+	hook := &fakeHook{}
+	n := newTestNotifier(t, Destination{Name: "ci", URL: hook.serve(t), SecretAccount: "ci"})
+
+	notif := core.Notification{ID: "n-1", Text: "backup done", Source: "backup", Title: "Backup", Priority: core.PriorityHigh}
+	if err := n.Send(context.Background(), notif); err != nil {
+		t.Fatal(err)
+	}
+	if hook.count() != 1 {
+		t.Fatalf("got %d requests", hook.count())
+	}
+	h, body := hook.requests[0], hook.bodies[0]
+	if h.Get("Content-Type") != "application/json" || h.Get(TimestampHeader) != "1767225600" {
+		t.Errorf("headers = %v", h)
+	}
+	if want := Sign([]byte("secret-ci"), "1767225600", body); h.Get(SignatureHeader) != want {
+		t.Errorf("signature = %q, want %q", h.Get(SignatureHeader), want)
+	}
+
+	var got core.Notification
+	if err := json.Unmarshal(body, &got); err != nil {
+		t.Fatal(err)
+	}
+	if got.ID != "n-1" || got.Text != "backup done" || got.Title != "Backup" || got.Priority != core.PriorityHigh {
+		t.Errorf("body = %s", body)
+	}
+
+	// Notifications without an ID get one, so receivers can drop repeats.
+	if err := n.Send(context.Background(), core.Notification{Text: "no id"}); err != nil {
+		t.Fatal(err)
+	}
+	if err := json.Unmarshal(hook.bodies[1], &got); err != nil || got.ID == "" {
+		t.Errorf("body without an id = %s", hook.bodies[1])
+	}
+}
+
+func TestSign(t *testing.T) {
+	// echo -n '1767225600.{"id":"x"}' | openssl dgst -sha256 -hmac key
+	want := "sha256=9984ebfe0b27ca731952269e84541e89f285ea9284c4f84fb4a221eeda52884d"
+	if got := Sign([]byte("key"), "1767225600", []byte(`{"id":"x"}`)); got != want {
+		t.Errorf("Sign = %q, want %q", got, want)
+	}
+}
+
+func TestRetries(t *testing.T) {
+	tests := []struct {
+		name     string
+		statuses []int
+		retries  int
+		requests int
+		wantErr  string
+	}{
+		{"retried until it passes", []int{500, 503}, 2, 3, ""},
+		{"gives up after retries", []int{500, 500, 500}, 1, 2, "webhook ci: status 500: try later"},
+		{"client error is not retried", []int{400}, 3, 1, "status 400"},
+		{"too many requests is retried", []int{429}, 1, 2, ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			hook := &fakeHook{statuses: tt.statuses}
+			n := newTestNotifier(t, Destination{Name: "ci", URL: hook.serve(t), SecretAccount: "ci", Retries: tt.retries, RetryBackoffMs: 1})
+			err := n.Send(context.Background(), core.Notification{ID: "n-1", Text: "hi"})
+			if tt.wantErr == "" && err != nil || tt.wantErr != "" && (err == nil || !strings.Contains(err.Error(), tt.wantErr)) {
+				t.Errorf("Send = %v, want error %q", err, tt.wantErr)
+			}
+			if hook.count() != tt.requests {
+				t.Errorf("got %d requests, want %d", hook.count(), tt.requests)
+			}
+			for _, body := range hook.bodies {
+				if !strings.Contains(string(body), `"id":"n-1"`) {
+					t.Errorf("retry body = %s", body)
+				}
+			}
+		})
+	}
+}
+
+func TestRetryAfter(t *testing.T) {
+	hook := &fakeHook{statuses: []int{429}, header: http.Header{"Retry-After": {"1"}}}
+	n := newTestNotifier(t, Destination{Name: "ci", URL: hook.serve(t), SecretAccount: "ci", Retries: 1, RetryBackoffMs: 1})
+	start := time.Now()
+	if err := n.Send(context.Background(), core.Notification{Text: "hi"}); err != nil {
+		t.Fatal(err)
+	}
+	if took := time.Since(start); took < time.Second {
+		t.Errorf("retried after %s, before Retry-After", took)
+	}
+}
+
+func TestBackoffIsCapped(t *testing.T) {
+	hook := &fakeHook{statuses: []int{500, 500, 500, 500, 500}}
+	n := newTestNotifier(t, Destination{Name: "ci", URL: hook.serve(t), SecretAccount: "ci", Retries: 5, RetryBackoffMs: 20000})
+	var waits []time.Duration
+	n.after = func(d time.Duration) <-chan time.Time {
+		waits = append(waits, d)
+		ch := make(chan time.Time, 1)
+		ch <- time.Time{}
+		return ch
+	}
+	if err := n.Send(context.Background(), core.Notification{Text: "hi"}); err != nil {
+		t.Fatal(err)
+	}
+	want := []time.Duration{20 * time.Second, 40 * time.Second, time.Minute, time.Minute, time.Minute}
+	if fmt.Sprint(waits) != fmt.Sprint(want) {
+		t.Errorf("waits = %v, want %v", waits, want)
+	}
+}
+
+func TestRetrySkipsDelivered(t *testing.T) {
+	ok := &fakeHook{}
+	flaky := &fakeHook{statuses: []int{400}}
+	n := newTestNotifier(t,
+		Destination{Name: "ok", URL: ok.serve(t), SecretAccount: "ok"},
+		Destination{Name: "flaky", URL: flaky.serve(t), SecretAccount: "flaky"},
+	)
+	notif := core.Notification{ID: "n-1", Text: "hi"}
+	if err := n.Send(context.Background(), notif); err == nil || !strings.Contains(err.Error(), "webhook flaky:") {
+		t.Fatalf("first Send = %v, want flaky to fail", err)
+	}
+	if err := n.Send(context.Background(), notif); err != nil {
+		t.Fatalf("retried Send: %v", err)
+	}
+	if ok.count() != 1 || flaky.count() != 2 {
+		t.Errorf("requests: ok %d, flaky %d; want 1 and 2", ok.count(), flaky.count())
+	}
+
+	// Once every destination has it, the notification is forgotten.
+	if err := n.Send(context.Background(), notif); err != nil || ok.count() != 2 || flaky.count() != 3 {
+		t.Errorf("Send after delivery = %v; requests ok %d, flaky %d", err, ok.count(), flaky.count())
+	}
+}
+
+func TestDestinationsAreIndependent(t *testing.T) {
+	release := make(chan struct{})
+	slow := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		select {
+		case <-release:
+		case <-r.Context().Done():
+		}
+	}))
+	t.Cleanup(slow.Close)
+	t.Cleanup(func() { close(release) })
+	hook := &fakeHook{}
+
+	n := newTestNotifier(t,
+		Destination{Name: "slow", URL: slow.URL, SecretAccount: "slow", TimeoutSec: 1},
+		Destination{Name: "ci", URL: hook.serve(t), SecretAccount: "ci"},
+	)
+	start := time.Now()
+	err := n.Send(context.Background(), core.Notification{Text: "hi"})
+	if err == nil || !strings.Contains(err.Error(), "webhook slow:") || strings.Contains(err.Error(), "webhook ci") {
+		t.Errorf("Send = %v, want only the slow destination to fail", err)
+	}
+	if took := time.Since(start); took > 5*time.Second {
+		t.Errorf("Send took %s despite the 1s timeout", took)
+	}
+	if hook.count() != 1 {
+		t.Errorf("ci got %d requests", hook.count())
+	}
+}
+
+func TestNewReadsSecrets(t *testing.T) {
+	_, err := New(&Config{Destinations: []Destination{{Name: "ci", URL: "http://127.0.0.1", SecretAccount: "missing"}}}, secrets)
+	if err == nil || !strings.Contains(err.Error(), `webhook ci: read secret "missing"`) {
+		t.Errorf("New = %v", err)
+	}
+	empty := func(string) (string, error) { return "", nil }
+	if _, err := New(&Config{Destinations: []Destination{{Name: "ci", URL: "http://127.0.0.1", SecretAccount: "ci"}}}, empty); err == nil {
+		t.Error("New accepted an empty secret")
+	}
+}
+
+func TestLoadConfig(t *testing.T) {
+	dir := t.TempDir()
+	write := func(content string) string {
+		path := filepath.Join(dir, "webhooks.json")
+		if err := os.WriteFile(path, []byte(content), 0o600); err != nil {
+			t.Fatal(err)
+		}
+		return path
+	}
+
+	cfg, err := LoadConfig(filepath.Join(dir, "missing.json"))
+	if cfg != nil || err != nil {
+		t.Fatalf("missing file = %v, %v; want nil, nil", cfg, err)
+	}
+
+	cfg, err = LoadConfig(write(`{"destinations": [{"name": "ci", "url": "https://ci.example.com/hook", "secret_account": "webhook-ci", "retries": 3}]}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	d := cfg.Destinations[0]
+	if d.Retries != 3 || d.timeout() != DefaultTimeoutSec*time.Second || d.backoff() != DefaultRetryBackoffMs*time.Millisecond {
+		t.Errorf("destination = %+v", d)
+	}
+
+	for _, bad := range []string{
+		`{"destinations": []}`,
+		`{"destinations": [{"url": "https://a.example.com", "secret_account": "a"}]}`,
+		`{"destinations": [{"name": "a", "url": "ftp://a.example.com", "secret_account": "a"}]}`,
+		`{"destinations": [{"name": "a", "url": "https://a.example.com"}]}`,
+		`{"destinations": [{"name": "a", "url": "https://a.example.com", "secret_account": "a", "retries": 6}]}`,
+		`{"destinations": [{"name": "a", "url": "https://a.example.com", "secret_account": "a", "timeout_sec": -1}]}`,
+		`{"destinations": [{"name": "a", "url": "https://a.example.com", "secret_account": "a"}, {"name": "a", "url": "https://b.example.com", "secret_account": "b"}]}`,
+	} {
+		if _, err := LoadConfig(write(bad)); err == nil {
+			t.Errorf("LoadConfig(%s) succeeded", bad)
+		}
+	}
+}
@@ -15,5 +15,20 @@ go build -o "$BIN/openslackctl" "$ROOT/cmd/openslackctl"
 echo "Building sample-connector..."
 go build -o "$BIN/sample-connector" "$ROOT/connectors/sample"
 
+echo "Building homeassistant-connector..."
+go build -o "$BIN/homeassistant-connector" "$ROOT/connectors/homeassistant"
+
+echo "Building github-connector..."
+go build -o "$BIN/github-connector" "$ROOT/connectors/github"
+
+echo "Building chaos-connector..."
+go build -o "$BIN/chaos-connector" "$ROOT/connectors/chaos"
+
+echo "Building connector-check..."
+go build -o "$BIN/connector-check" "$ROOT/cmd/connector-check"
+
+echo "Building openslack-new-connector..."
+go build -o "$BIN/openslack-new-connector" "$ROOT/cmd/openslack-new-connector"
+
 echo "Done. Binaries in $BIN/"
 ls -lh "$BIN"/
@@ -0,0 +1,72 @@
+// Command connector-check runs a connector binary through the protocol
+// conformance checks and prints a pass/fail report. It exits 1 if any
+// check fails.
+//
+// Usage: connector-check [flags] /path/to/connector [args...]
+//
+// A connector that expects signed requests reads its secret from
+// OPENSLACK_CONNECTOR_SECRET; set it in connector-check's environment to
+// sign requests and verify replies with the same secret.
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"time"
+
+	"github.com/jdelaire/openslack/core/connector"
+	"github.com/jdelaire/openslack/core/connector/conformance"
+)
+
+func main() {
+	reqMax := flag.Int("req-max-bytes", connector.DefaultReqMaxBytes, "largest request the daemon sends (limits.req_max_bytes)")
+	respMax := flag.Int("resp-max-bytes", connector.DefaultRespMaxBytes, "largest response the daemon accepts (limits.resp_max_bytes)")
+	timeout := flag.Duration("timeout", connector.DefaultCallTimeoutMs*time.Millisecond, "per-call timeout (limits.call_timeout_ms)")
+	verbose := flag.Bool("v", false, "show the connector's stderr")
+	flag.Usage = func() {
+		fmt.Fprintln(os.Stderr, "Usage: connector-check [flags] /path/to/connector [args...]")
+		flag.PrintDefaults()
+	}
+	flag.Parse()
+	if flag.NArg() == 0 {
+		flag.Usage()
+		os.Exit(2)
+	}
+
+	scratch, err := os.MkdirTemp("", "connector-check-")
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "scratch dir: %s\n", err)
+		os.Exit(1)
+	}
+	defer os.RemoveAll(scratch)
+
+	opts := conformance.Options{
+		Exec:         flag.Arg(0),
+		Args:         flag.Args()[1:],
+		ScratchDir:   scratch,
+		ReqMaxBytes:  *reqMax,
+		RespMaxBytes: *respMax,
+		CallTimeout:  *timeout,
+	}
+	if secret := os.Getenv(connector.SecretEnv); secret != "" {
+		opts.Secret = []byte(secret)
+	}
+	if *verbose {
+		opts.Stderr = os.Stderr
+	}
+
+	results, err := conformance.Run(opts)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "start connector: %s\n", err)
+		os.RemoveAll(scratch)
+		os.Exit(1)
+	}
+	fmt.Println(conformance.Report(results))
+	for _, r := range results {
+		if !r.Passed() {
+			os.RemoveAll(scratch)
+			os.Exit(1)
+		}
+	}
+}
@@ -0,0 +1,53 @@
+// Command openslack-new-connector generates a ready-to-run connector in
+// Go, Python or bash, with a stub for each tool.
+//
+// Usage: openslack-new-connector [-lang go|python|bash] [-dir DIR] -tools a,b NAME
+//
+// The connector is written to connectors/NAME unless -dir is given.
+// Existing files are never overwritten.
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/jdelaire/openslack/core/connector/scaffold"
+)
+
+func main() {
+	lang := flag.String("lang", scaffold.LangGo, "connector language: go, python or bash")
+	tools := flag.String("tools", "", "comma-separated tool names")
+	dir := flag.String("dir", "", "output directory (default connectors/NAME)")
+	flag.Usage = func() {
+		fmt.Fprintln(os.Stderr, "Usage: openslack-new-connector [-lang go|python|bash] [-dir DIR] -tools a,b NAME")
+		flag.PrintDefaults()
+	}
+	flag.Parse()
+	if flag.NArg() != 1 {
+		flag.Usage()
+		os.Exit(2)
+	}
+
+	opts := scaffold.Options{Name: flag.Arg(0), Lang: *lang}
+	for _, t := range strings.Split(*tools, ",") {
+		if t = strings.TrimSpace(t); t != "" {
+			opts.Tools = append(opts.Tools, t)
+		}
+	}
+	if *dir == "" {
+		*dir = filepath.Join("connectors", opts.Name)
+	}
+
+	paths, err := scaffold.Generate(*dir, opts)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
+	for _, p := range paths {
+		fmt.Println("created", p)
+	}
+	fmt.Printf("\nNext: fill in the tools, run `make check` in %s, then add the connector to ~/.openslack/connectors.json.\n", *dir)
+}
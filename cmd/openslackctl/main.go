@@ -0,0 +1,124 @@
+// Command openslackctl administers a running daemon from the local
+// machine: it sends notifications over the daemon socket, runs ops, lists
+// tasks, tails the op log, checks connector health and triggers config
+// reloads through the admin API, and validates config files.
+//
+// Usage: openslackctl [-socket PATH] [-api ADDR] [-token TOKEN] COMMAND [ARGS]
+//
+// Run openslackctl help for the list of commands.
+package main
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"github.com/jdelaire/openslack/adapters/http_receiver"
+	"github.com/jdelaire/openslack/adapters/slack_receiver"
+	"github.com/jdelaire/openslack/adapters/telegram_receiver"
+	"github.com/jdelaire/openslack/adapters/webhook_notifier"
+	"github.com/jdelaire/openslack/core"
+	"github.com/jdelaire/openslack/core/adminapi"
+	"github.com/jdelaire/openslack/core/admission"
+	"github.com/jdelaire/openslack/core/assistant"
+	"github.com/jdelaire/openslack/core/auth"
+	"github.com/jdelaire/openslack/core/bots"
+	"github.com/jdelaire/openslack/core/connector"
+	"github.com/jdelaire/openslack/core/ctl"
+	"github.com/jdelaire/openslack/core/dashboard"
+	"github.com/jdelaire/openslack/core/delivery"
+	"github.com/jdelaire/openslack/core/grpcapi"
+	"github.com/jdelaire/openslack/core/hosts"
+	"github.com/jdelaire/openslack/core/kubeops"
+	"github.com/jdelaire/openslack/core/lease"
+	"github.com/jdelaire/openslack/core/limits"
+	"github.com/jdelaire/openslack/core/ops"
+	"github.com/jdelaire/openslack/core/persist"
+	"github.com/jdelaire/openslack/core/policy"
+	"github.com/jdelaire/openslack/core/ratelimit"
+	"github.com/jdelaire/openslack/core/redact"
+	"github.com/jdelaire/openslack/core/rules"
+	"github.com/jdelaire/openslack/core/scripts"
+	"github.com/jdelaire/openslack/core/style"
+	"github.com/jdelaire/openslack/internal/backup"
+	"github.com/jdelaire/openslack/internal/export"
+	"github.com/jdelaire/openslack/internal/inbox"
+	"github.com/jdelaire/openslack/internal/keychain"
+	"github.com/jdelaire/openslack/internal/monitors"
+	"github.com/jdelaire/openslack/internal/presence"
+	"github.com/jdelaire/openslack/internal/sources"
+	"github.com/jdelaire/openslack/internal/tasks"
+	"github.com/jdelaire/openslack/internal/templates"
+	"github.com/jdelaire/openslack/internal/transcript"
+	"github.com/jdelaire/openslack/internal/watchdogs"
+)
+
+// check wraps a config loader so its result is dropped.
+func check[T any](load func(string) (T, error)) func(string) error {
+	return func(path string) error {
+		_, err := load(path)
+		return err
+	}
+}
+
+// configs are the daemon's config files, as check validates them.
+var configs = []ctl.ConfigFile{
+	{Name: "admin_api.json", Load: check(adminapi.LoadConfig)},
+	{Name: "admission.json", Load: check(admission.LoadConfig)},
+	{Name: "allowlist.json", Load: check(policy.LoadAllowlist)},
+	{Name: "archive.json", Load: check(tasks.LoadArchiveConfig)},
+	{Name: "assistant.json", Load: check(assistant.LoadConfig)},
+	{Name: "backups.json", Load: check(backup.LoadConfig)},
+	{Name: "bots.json", Load: check(bots.LoadConfig)},
+	{Name: "broadcast.json", Load: check(ops.LoadBroadcastTargets)},
+	{Name: "commands.json", Load: check(ops.LoadCommands)},
+	{Name: "connectors.json", Load: check(connector.LoadConfig)},
+	{Name: "dashboard.json", Load: check(dashboard.LoadConfig)},
+	{Name: "delivery.json", Load: check(delivery.LoadConfig)},
+	{Name: "export.json", Load: check(export.LoadConfig)},
+	{Name: "grpc_api.json", Load: check(grpcapi.LoadConfig)},
+	{Name: "ha.json", Load: check(lease.LoadConfig)},
+	{Name: "hosts.json", Load: check(hosts.LoadConfig)},
+	{Name: "http_receiver.json", Load: check(http_receiver.LoadConfig)},
+	{Name: "inbox.json", Load: check(inbox.LoadConfig)},
+	{Name: "kube.json", Load: check(kubeops.LoadConfig)},
+	{Name: "limits.json", Load: check(limits.LoadConfig)},
+	{Name: "monitors.json", Load: check(monitors.LoadConfig)},
+	{Name: "presence.json", Load: check(presence.LoadConfig)},
+	{Name: "ratelimit.json", Load: check(ratelimit.LoadConfig)},
+	{Name: "reactions.json", Load: check(core.LoadReactions)},
+	{Name: "receiver.json", Load: check(telegram_receiver.LoadConfig)},
+	{Name: "redact.json", Load: check(redact.LoadConfig)},
+	{Name: "risk.json", Load: check(ops.LoadRiskOverrides)},
+	{Name: "rules.json", Load: check(rules.LoadConfig)},
+	{Name: "scheduler.json", Load: check(tasks.LoadSchedulerConfig)},
+	{Name: "scripts.json", Load: check(scripts.LoadConfig)},
+	{Name: "slack.json", Load: check(slack_receiver.LoadConfig)},
+	{Name: "sources.json", Load: check(sources.LoadConfig)},
+	{Name: "storage.json", Load: check(persist.LoadConfig)},
+	{Name: "style.json", Load: check(style.LoadConfig)},
+	{Name: "templates.json", Load: check(templates.Load)},
+	{Name: "timezones.json", Load: check(tasks.LoadZoneConfig)},
+	{Name: "totp.json", Load: check(auth.LoadConfig)},
+	{Name: "totp_exempt.json", Load: check(ops.LoadTOTPExemptions)},
+	{Name: "transcript.json", Load: check(transcript.LoadConfig)},
+	{Name: "watchdogs.json", Load: check(watchdogs.LoadConfig)},
+	{Name: "webhooks.json", Load: check(webhook_notifier.LoadConfig)},
+}
+
+func main() {
+	home, err := os.UserHomeDir()
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "home dir: %s\n", err)
+		os.Exit(1)
+	}
+	cli := &ctl.CLI{
+		Stdin:   os.Stdin,
+		Stdout:  os.Stdout,
+		Stderr:  os.Stderr,
+		Dir:     filepath.Join(home, ".openslack"),
+		Configs: configs,
+		Token:   func() (string, error) { return keychain.Get(adminapi.TokenAccount) },
+	}
+	os.Exit(cli.Run(os.Args[1:]))
+}
@@ -0,0 +1,179 @@
+// Command chaos-connector is a test connector that misbehaves on request,
+// for exercising the daemon's handling of broken connectors: slow and
+// malformed replies, replies with the wrong ID, output over the response
+// limit, and exits in the middle of a call.
+//
+// Each fault is a tool. Setting OPENSLACK_CHAOS_RATE to a chance between
+// 0 and 1 also gives every echo call that chance of a random fault, and
+// OPENSLACK_CHAOS_SEED makes the faults repeatable.
+package main
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"math/rand/v2"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const connectorVersion = "1.0.0"
+
+// defaultOversized is the size of an oversized reply when no bytes are
+// given; it is over the daemon's default 16 KiB response limit.
+const defaultOversized = 64 * 1024
+
+type request struct {
+	Version string          `json:"version"`
+	ID      string          `json:"id"`
+	Tool    string          `json:"tool"`
+	Args    json.RawMessage `json:"args"`
+}
+
+type response struct {
+	Version string          `json:"version"`
+	ID      string          `json:"id"`
+	OK      bool            `json:"ok"`
+	Data    json.RawMessage `json:"data,omitempty"`
+	Error   *respError      `json:"error,omitempty"`
+}
+
+type respError struct {
+	Code    string `json:"code"`
+	Message string `json:"message"`
+}
+
+// args holds the arguments of every tool.
+type args struct {
+	Text  string `json:"text"`
+	Ms    int    `json:"ms"`
+	MaxMs int    `json:"max_ms"`
+	Bytes int    `json:"bytes"`
+	Code  int    `json:"code"`
+}
+
+// faults are the tools a random fault is picked from.
+var faults = []string{"delay", "malformed", "wrong_id", "stale", "oversized", "exit"}
+
+var (
+	rate float64
+	rnd  *rand.Rand
+)
+
+func main() {
+	fmt.Fprintln(os.Stderr, "chaos-connector started")
+	configure()
+
+	scanner := bufio.NewScanner(os.Stdin)
+	scanner.Buffer(make([]byte, 64*1024), 64*1024)
+	for scanner.Scan() {
+		var req request
+		if err := json.Unmarshal(scanner.Bytes(), &req); err != nil {
+			writeError("", "INVALID_REQUEST", fmt.Sprintf("invalid json: %s", err))
+			continue
+		}
+		if req.Version != "v1" {
+			writeError(req.ID, "INVALID_REQUEST", fmt.Sprintf("unsupported version: %s", req.Version))
+			continue
+		}
+		handle(req)
+	}
+	if err := scanner.Err(); err != nil {
+		fmt.Fprintf(os.Stderr, "stdin error: %s\n", err)
+		os.Exit(1)
+	}
+}
+
+// configure reads the random fault settings from the environment.
+func configure() {
+	if s := os.Getenv("OPENSLACK_CHAOS_RATE"); s != "" {
+		r, err := strconv.ParseFloat(s, 64)
+		if err != nil || r < 0 || r > 1 {
+			fmt.Fprintf(os.Stderr, "OPENSLACK_CHAOS_RATE %q must be between 0 and 1\n", s)
+			os.Exit(1)
+		}
+		rate = r
+	}
+	seed := uint64(time.Now().UnixNano())
+	if s := os.Getenv("OPENSLACK_CHAOS_SEED"); s != "" {
+		n, err := strconv.ParseUint(s, 10, 64)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "OPENSLACK_CHAOS_SEED %q is not a number\n", s)
+			os.Exit(1)
+		}
+		seed = n
+	}
+	rnd = rand.New(rand.NewPCG(seed, seed))
+}
+
+func handle(req request) {
+	var a args
+	if len(req.Args) > 0 {
+		if err := json.Unmarshal(req.Args, &a); err != nil {
+			writeError(req.ID, "INVALID_ARGS", "invalid args")
+			return
+		}
+	}
+
+	tool := req.Tool
+	if tool == "echo" && rate > 0 && rnd.Float64() < rate {
+		tool = faults[rnd.IntN(len(faults))]
+		a.MaxMs = 15000
+		fmt.Fprintf(os.Stderr, "injecting %s into %s\n", tool, req.ID)
+	}
+
+	switch tool {
+	case "__introspect":
+		tools := []map[string]string{{"name": "echo"}}
+		for _, f := range faults {
+			tools = append(tools, map[string]string{"name": f})
+		}
+		writeOK(req.ID, map[string]any{"name": "chaos", "version": connectorVersion, "tools": tools})
+	case "echo":
+		writeOK(req.ID, map[string]string{"text": a.Text})
+	case "delay":
+		// Sleeps ms, or a random time up to max_ms, then replies.
+		ms := a.Ms
+		if ms <= 0 && a.MaxMs > 0 {
+			ms = rnd.IntN(a.MaxMs)
+		}
+		time.Sleep(time.Duration(ms) * time.Millisecond)
+		writeOK(req.ID, map[string]string{"slept": fmt.Sprintf("%dms", ms)})
+	case "malformed":
+		writeLine(`{"version":"v1","id":` + strconv.Quote(req.ID) + `,"ok":tru`)
+	case "wrong_id":
+		writeOK("wrong-"+req.ID, map[string]string{"text": a.Text})
+	case "stale":
+		// A late reply to an earlier call, then the real one.
+		writeOK("stale-"+req.ID, map[string]string{})
+		writeOK(req.ID, map[string]string{"text": a.Text})
+	case "oversized":
+		n := a.Bytes
+		if n <= 0 {
+			n = defaultOversized
+		}
+		writeOK(req.ID, map[string]string{"text": strings.Repeat("x", n)})
+	case "exit":
+		fmt.Fprintf(os.Stderr, "exiting with code %d during %s\n", a.Code, req.ID)
+		os.Exit(a.Code)
+	default:
+		writeError(req.ID, "NOT_SUPPORTED", fmt.Sprintf("unknown tool: %s", req.Tool))
+	}
+}
+
+func writeOK(id string, data any) {
+	raw, _ := json.Marshal(data)
+	out, _ := json.Marshal(response{Version: "v1", ID: id, OK: true, Data: raw})
+	writeLine(string(out))
+}
+
+func writeError(id, code, message string) {
+	out, _ := json.Marshal(response{Version: "v1", ID: id, OK: false, Error: &respError{Code: code, Message: message}})
+	writeLine(string(out))
+}
+
+func writeLine(line string) {
+	fmt.Fprintln(os.Stdout, line)
+}
@@ -0,0 +1,322 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/jdelaire/openslack/connectors/sdk"
+)
+
+const (
+	defaultAPIURL       = "https://api.github.com"
+	defaultPollInterval = 120
+	minPollInterval     = 30
+	httpTimeout         = 10 * time.Second
+	maxResponseBody     = 1 << 20
+	listLimit           = 20
+)
+
+// Config is the GitHub connector configuration. Every tool is restricted to
+// Repos; Watch lists the repos polled for failed CI runs.
+type Config struct {
+	APIURL          string   `json:"api_url"`
+	Repos           []string `json:"repos"`
+	Watch           []string `json:"watch"`
+	PollIntervalSec int      `json:"poll_interval_sec"`
+}
+
+// LoadConfig reads and validates the connector config file.
+func LoadConfig(p string) (*Config, error) {
+	data, err := os.ReadFile(p)
+	if err != nil {
+		return nil, fmt.Errorf("read github config: %w", err)
+	}
+	var cfg Config
+	if err := json.Unmarshal(data, &cfg); err != nil {
+		return nil, fmt.Errorf("parse github config: %w", err)
+	}
+	if len(cfg.Repos) == 0 {
+		return nil, fmt.Errorf("github config has no repos")
+	}
+	for _, r := range cfg.Repos {
+		if !validRepo(r) {
+			return nil, fmt.Errorf("github config: repo %q must be owner/name", r)
+		}
+	}
+	for _, r := range cfg.Watch {
+		if !cfg.allowed(r) {
+			return nil, fmt.Errorf("github config: watched repo %q is not in repos", r)
+		}
+	}
+	if cfg.APIURL == "" {
+		cfg.APIURL = defaultAPIURL
+	}
+	cfg.APIURL = strings.TrimRight(cfg.APIURL, "/")
+	if cfg.PollIntervalSec <= 0 {
+		cfg.PollIntervalSec = defaultPollInterval
+	}
+	if cfg.PollIntervalSec < minPollInterval {
+		cfg.PollIntervalSec = minPollInterval
+	}
+	return &cfg, nil
+}
+
+func validRepo(r string) bool {
+	owner, name, ok := strings.Cut(r, "/")
+	return ok && owner != "" && name != "" && !strings.ContainsAny(name, "/ ")
+}
+
+func (c *Config) allowed(repo string) bool {
+	for _, r := range c.Repos {
+		if strings.EqualFold(r, repo) {
+			return true
+		}
+	}
+	return false
+}
+
+// GitHub calls the GitHub REST API.
+type GitHub struct {
+	cfg    *Config
+	token  string
+	client *http.Client
+}
+
+// NewGitHub creates a GitHub client authenticating with a personal access token.
+func NewGitHub(cfg *Config, token string) *GitHub {
+	return &GitHub{cfg: cfg, token: token, client: &http.Client{Timeout: httpTimeout}}
+}
+
+// Register adds the prs, issues, merge, and rerun tools to c.
+func (g *GitHub) Register(c *sdk.Connector) {
+	c.Tool("prs", g.prs)
+	c.Tool("issues", g.issues)
+	c.Tool("merge", g.merge, sdk.Mutating())
+	c.Tool("rerun", g.rerun, sdk.Mutating())
+}
+
+// reposArg returns the repo named in args, or all configured repos if none.
+func (g *GitHub) reposArg(args json.RawMessage) ([]string, error) {
+	var a struct {
+		Repo string `json:"repo"`
+	}
+	json.Unmarshal(args, &a)
+	if a.Repo == "" {
+		a.Repo = sdk.TextArg(args)
+	}
+	if a.Repo == "" {
+		return g.cfg.Repos, nil
+	}
+	if !g.cfg.allowed(a.Repo) {
+		return nil, sdk.Unauthorized("repo %s is not allowed", a.Repo)
+	}
+	return []string{a.Repo}, nil
+}
+
+type ghUser struct {
+	Login string `json:"login"`
+}
+
+// prs lists open pull requests. Args: optional "owner/name".
+func (g *GitHub) prs(ctx context.Context, args json.RawMessage) (any, error) {
+	repos, err := g.reposArg(args)
+	if err != nil {
+		return nil, err
+	}
+	out := make(map[string]string, len(repos))
+	for _, repo := range repos {
+		var pulls []struct {
+			Number int    `json:"number"`
+			Title  string `json:"title"`
+			Draft  bool   `json:"draft"`
+			User   ghUser `json:"user"`
+		}
+		if err := g.do(ctx, http.MethodGet, fmt.Sprintf("/repos/%s/pulls?state=open&per_page=%d", repo, listLimit), nil, &pulls); err != nil {
+			return nil, err
+		}
+		var lines []string
+		for _, p := range pulls {
+			draft := ""
+			if p.Draft {
+				draft = " [draft]"
+			}
+			lines = append(lines, fmt.Sprintf("#%d %s (@%s)%s", p.Number, p.Title, p.User.Login, draft))
+		}
+		out[repo] = listOrNone(lines)
+	}
+	return out, nil
+}
+
+// issues lists open issues, excluding pull requests. Args: optional "owner/name".
+func (g *GitHub) issues(ctx context.Context, args json.RawMessage) (any, error) {
+	repos, err := g.reposArg(args)
+	if err != nil {
+		return nil, err
+	}
+	out := make(map[string]string, len(repos))
+	for _, repo := range repos {
+		var issues []struct {
+			Number      int             `json:"number"`
+			Title       string          `json:"title"`
+			User        ghUser          `json:"user"`
+			PullRequest json.RawMessage `json:"pull_request"`
+		}
+		if err := g.do(ctx, http.MethodGet, fmt.Sprintf("/repos/%s/issues?state=open&per_page=%d", repo, listLimit), nil, &issues); err != nil {
+			return nil, err
+		}
+		var lines []string
+		for _, i := range issues {
+			if i.PullRequest != nil {
+				continue
+			}
+			lines = append(lines, fmt.Sprintf("#%d %s (@%s)", i.Number, i.Title, i.User.Login))
+		}
+		out[repo] = listOrNone(lines)
+	}
+	return out, nil
+}
+
+func listOrNone(lines []string) string {
+	if len(lines) == 0 {
+		return "none open"
+	}
+	return strings.Join(lines, "\n")
+}
+
+// merge merges a pull request. Args: "owner/name#12 [merge|squash|rebase]"
+// or {"repo": "owner/name", "number": 12, "method": "squash"}.
+func (g *GitHub) merge(ctx context.Context, args json.RawMessage) (any, error) {
+	var a struct {
+		Repo   string `json:"repo"`
+		Number int    `json:"number"`
+		Method string `json:"method"`
+	}
+	json.Unmarshal(args, &a)
+	if a.Repo == "" {
+		fields := strings.Fields(sdk.TextArg(args))
+		if len(fields) == 0 || len(fields) > 3 {
+			return nil, sdk.InvalidArgs("usage: owner/name#<pr> [merge|squash|rebase]")
+		}
+		repo, num, ok := strings.Cut(fields[0], "#")
+		rest := fields[1:]
+		if !ok && len(rest) > 0 {
+			num, rest = rest[0], rest[1:]
+		}
+		a.Repo = repo
+		a.Number, _ = strconv.Atoi(num)
+		if len(rest) > 0 {
+			a.Method = rest[0]
+		}
+	}
+	if a.Repo == "" || a.Number <= 0 {
+		return nil, sdk.InvalidArgs("usage: owner/name#<pr> [merge|squash|rebase]")
+	}
+	if a.Method == "" {
+		a.Method = "merge"
+	}
+	if a.Method != "merge" && a.Method != "squash" && a.Method != "rebase" {
+		return nil, sdk.InvalidArgs("method must be merge, squash, or rebase")
+	}
+	if !g.cfg.allowed(a.Repo) {
+		return nil, sdk.Unauthorized("repo %s is not allowed", a.Repo)
+	}
+
+	var res struct {
+		SHA     string `json:"sha"`
+		Message string `json:"message"`
+	}
+	body := map[string]string{"merge_method": a.Method}
+	if err := g.do(ctx, http.MethodPut, fmt.Sprintf("/repos/%s/pulls/%d/merge", a.Repo, a.Number), body, &res); err != nil {
+		return nil, err
+	}
+	return map[string]string{"merged": fmt.Sprintf("%s#%d", a.Repo, a.Number), "sha": shortSHA(res.SHA)}, nil
+}
+
+// rerun re-runs the failed jobs of a workflow run. Args: "owner/name <run_id>"
+// or {"repo": "owner/name", "run_id": 123}.
+func (g *GitHub) rerun(ctx context.Context, args json.RawMessage) (any, error) {
+	var a struct {
+		Repo  string `json:"repo"`
+		RunID int64  `json:"run_id"`
+	}
+	json.Unmarshal(args, &a)
+	if a.Repo == "" {
+		fields := strings.Fields(sdk.TextArg(args))
+		if len(fields) == 2 {
+			a.Repo = fields[0]
+			a.RunID, _ = strconv.ParseInt(fields[1], 10, 64)
+		}
+	}
+	if a.Repo == "" || a.RunID <= 0 {
+		return nil, sdk.InvalidArgs("usage: owner/name <run_id>")
+	}
+	if !g.cfg.allowed(a.Repo) {
+		return nil, sdk.Unauthorized("repo %s is not allowed", a.Repo)
+	}
+	if err := g.do(ctx, http.MethodPost, fmt.Sprintf("/repos/%s/actions/runs/%d/rerun-failed-jobs", a.Repo, a.RunID), nil, nil); err != nil {
+		return nil, err
+	}
+	return map[string]string{"rerun": fmt.Sprintf("%s run %d", a.Repo, a.RunID)}, nil
+}
+
+func shortSHA(sha string) string {
+	if len(sha) > 7 {
+		return sha[:7]
+	}
+	return sha
+}
+
+func (g *GitHub) do(ctx context.Context, method, p string, body any, out any) error {
+	var r io.Reader
+	if body != nil {
+		data, err := json.Marshal(body)
+		if err != nil {
+			return fmt.Errorf("marshal body: %w", err)
+		}
+		r = bytes.NewReader(data)
+	}
+	req, err := http.NewRequestWithContext(ctx, method, g.cfg.APIURL+p, r)
+	if err != nil {
+		return fmt.Errorf("create request: %w", err)
+	}
+	req.Header.Set("Authorization", "Bearer "+g.token)
+	req.Header.Set("Accept", "application/vnd.github+json")
+	req.Header.Set("X-GitHub-Api-Version", "2022-11-28")
+	if body != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+
+	resp, err := g.client.Do(req)
+	if err != nil {
+		return fmt.Errorf("github request: %w", err)
+	}
+	defer resp.Body.Close()
+
+	data, err := io.ReadAll(io.LimitReader(resp.Body, maxResponseBody))
+	if err != nil {
+		return fmt.Errorf("read github response: %w", err)
+	}
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		var apiErr struct {
+			Message string `json:"message"`
+		}
+		if json.Unmarshal(data, &apiErr) == nil && apiErr.Message != "" {
+			return fmt.Errorf("github API error %d: %s", resp.StatusCode, apiErr.Message)
+		}
+		return fmt.Errorf("github API error %d", resp.StatusCode)
+	}
+	if out != nil {
+		if err := json.Unmarshal(data, out); err != nil {
+			return fmt.Errorf("decode github response: %w", err)
+		}
+	}
+	return nil
+}
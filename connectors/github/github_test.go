@@ -0,0 +1,244 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/jdelaire/openslack/connectors/sdk"
+)
+
+type ghRequest struct {
+	method, path, auth string
+	body               map[string]any
+}
+
+// newTestGitHub serves canned responses keyed by "METHOD path".
+func newTestGitHub(t *testing.T, routes map[string]string) (*GitHub, *[]ghRequest) {
+	t.Helper()
+	var reqs []ghRequest
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		data, _ := io.ReadAll(r.Body)
+		var body map[string]any
+		json.Unmarshal(data, &body)
+		reqs = append(reqs, ghRequest{method: r.Method, path: r.URL.Path, auth: r.Header.Get("Authorization"), body: body})
+
+		resp, ok := routes[r.Method+" "+r.URL.Path]
+		if !ok {
+			w.WriteHeader(http.StatusNotFound)
+			w.Write([]byte(`{"message":"Not Found"}`))
+			return
+		}
+		w.Write([]byte(resp))
+	}))
+	t.Cleanup(srv.Close)
+
+	cfg := &Config{
+		APIURL:          srv.URL,
+		Repos:           []string{"acme/api", "acme/web"},
+		Watch:           []string{"acme/api"},
+		PollIntervalSec: 60,
+	}
+	return NewGitHub(cfg, "tok"), &reqs
+}
+
+func wantCode(t *testing.T, err error, code string) {
+	t.Helper()
+	var toolErr *sdk.Error
+	if !errors.As(err, &toolErr) || toolErr.Code != code {
+		t.Fatalf("error = %v, want code %s", err, code)
+	}
+}
+
+func TestPRs(t *testing.T) {
+	gh, reqs := newTestGitHub(t, map[string]string{
+		"GET /repos/acme/api/pulls": `[{"number":12,"title":"Add retries","user":{"login":"ana"}},{"number":13,"title":"WIP","draft":true,"user":{"login":"bo"}}]`,
+	})
+
+	got, err := gh.prs(context.Background(), json.RawMessage(`{"text":"acme/api"}`))
+	if err != nil {
+		t.Fatalf("prs: %v", err)
+	}
+	want := "#12 Add retries (@ana)\n#13 WIP (@bo) [draft]"
+	if m := got.(map[string]string); m["acme/api"] != want {
+		t.Errorf("prs = %q, want %q", m["acme/api"], want)
+	}
+	if (*reqs)[0].auth != "Bearer tok" {
+		t.Errorf("auth = %q", (*reqs)[0].auth)
+	}
+}
+
+func TestIssuesAllReposSkipsPulls(t *testing.T) {
+	gh, _ := newTestGitHub(t, map[string]string{
+		"GET /repos/acme/api/issues": `[{"number":3,"title":"Crash on start","user":{"login":"ana"}},{"number":12,"title":"Add retries","user":{"login":"ana"},"pull_request":{}}]`,
+		"GET /repos/acme/web/issues": `[]`,
+	})
+
+	got, err := gh.issues(context.Background(), json.RawMessage(`{}`))
+	if err != nil {
+		t.Fatalf("issues: %v", err)
+	}
+	m := got.(map[string]string)
+	if m["acme/api"] != "#3 Crash on start (@ana)" || m["acme/web"] != "none open" {
+		t.Errorf("issues = %v", m)
+	}
+}
+
+func TestRepoNotAllowed(t *testing.T) {
+	gh, reqs := newTestGitHub(t, nil)
+
+	_, err := gh.prs(context.Background(), json.RawMessage(`{"text":"evil/repo"}`))
+	wantCode(t, err, "UNAUTHORIZED")
+	_, err = gh.merge(context.Background(), json.RawMessage(`{"text":"evil/repo#1"}`))
+	wantCode(t, err, "UNAUTHORIZED")
+	_, err = gh.rerun(context.Background(), json.RawMessage(`{"text":"evil/repo 5"}`))
+	wantCode(t, err, "UNAUTHORIZED")
+	if len(*reqs) != 0 {
+		t.Error("disallowed repo reached GitHub")
+	}
+}
+
+func TestMerge(t *testing.T) {
+	gh, reqs := newTestGitHub(t, map[string]string{
+		"PUT /repos/acme/api/pulls/12/merge": `{"sha":"0123456789abcdef","merged":true}`,
+	})
+
+	tests := []struct {
+		args, method string
+	}{
+		{`{"text":"acme/api#12"}`, "merge"},
+		{`{"text":"acme/api#12 squash"}`, "squash"},
+		{`{"text":"acme/api 12 rebase"}`, "rebase"},
+		{`{"repo":"acme/api","number":12,"method":"squash"}`, "squash"},
+	}
+	for i, tt := range tests {
+		got, err := gh.merge(context.Background(), json.RawMessage(tt.args))
+		if err != nil {
+			t.Fatalf("merge %s: %v", tt.args, err)
+		}
+		if m := got.(map[string]string); m["merged"] != "acme/api#12" || m["sha"] != "0123456" {
+			t.Errorf("merge %s = %v", tt.args, m)
+		}
+		if r := (*reqs)[i]; r.method != http.MethodPut || r.body["merge_method"] != tt.method {
+			t.Errorf("merge %s request = %+v", tt.args, r)
+		}
+	}
+}
+
+func TestMergeInvalidArgs(t *testing.T) {
+	gh, _ := newTestGitHub(t, nil)
+
+	for _, args := range []string{`{}`, `{"text":"acme/api"}`, `{"text":"acme/api#x"}`, `{"text":"acme/api#12 fast-forward"}`} {
+		_, err := gh.merge(context.Background(), json.RawMessage(args))
+		wantCode(t, err, "INVALID_ARGS")
+	}
+}
+
+func TestMergeAPIError(t *testing.T) {
+	gh, _ := newTestGitHub(t, nil)
+
+	_, err := gh.merge(context.Background(), json.RawMessage(`{"text":"acme/api#99"}`))
+	if err == nil || !strings.Contains(err.Error(), "github API error 404: Not Found") {
+		t.Errorf("error = %v", err)
+	}
+}
+
+func TestRerun(t *testing.T) {
+	gh, reqs := newTestGitHub(t, map[string]string{
+		"POST /repos/acme/api/actions/runs/777/rerun-failed-jobs": ``,
+	})
+
+	if _, err := gh.rerun(context.Background(), json.RawMessage(`{"text":"acme/api 777"}`)); err != nil {
+		t.Fatalf("rerun: %v", err)
+	}
+	if (*reqs)[0].method != http.MethodPost {
+		t.Errorf("method = %s", (*reqs)[0].method)
+	}
+	_, err := gh.rerun(context.Background(), json.RawMessage(`{"text":"acme/api"}`))
+	wantCode(t, err, "INVALID_ARGS")
+}
+
+func TestPollerEmitsNewFailures(t *testing.T) {
+	runs := `{"workflow_runs":[{"id":1,"name":"build","head_branch":"main","head_sha":"aaaaaaaaaa","html_url":"https://gh/run/1"}]}`
+	routes := map[string]string{"GET /repos/acme/api/actions/runs": runs}
+	gh, _ := newTestGitHub(t, routes)
+
+	type event struct {
+		name, text string
+	}
+	var events []event
+	p := NewPoller(gh, func(name, text string, _ any) error {
+		events = append(events, event{name, text})
+		return nil
+	})
+
+	// The first poll only seeds existing failures.
+	if err := p.poll(context.Background(), "acme/api"); err != nil {
+		t.Fatalf("poll: %v", err)
+	}
+	if len(events) != 0 {
+		t.Fatalf("seed poll emitted %d events", len(events))
+	}
+
+	routes["GET /repos/acme/api/actions/runs"] = `{"workflow_runs":[` +
+		`{"id":2,"name":"test","head_branch":"fix-x","head_sha":"bbbbbbbbbb","html_url":"https://gh/run/2"},` +
+		`{"id":1,"name":"build","head_branch":"main","head_sha":"aaaaaaaaaa","html_url":"https://gh/run/1"}]}`
+	if err := p.poll(context.Background(), "acme/api"); err != nil {
+		t.Fatalf("poll: %v", err)
+	}
+	if len(events) != 1 {
+		t.Fatalf("got %d events, want 1", len(events))
+	}
+	if events[0].name != "ci_failed" {
+		t.Errorf("event = %q", events[0].name)
+	}
+	for _, want := range []string{"acme/api", "test on fix-x (bbbbbbb)", "https://gh/run/2", "/github.rerun acme/api 2"} {
+		if !strings.Contains(events[0].text, want) {
+			t.Errorf("event text %q missing %q", events[0].text, want)
+		}
+	}
+
+	// Polling again with no new runs emits nothing.
+	if err := p.poll(context.Background(), "acme/api"); err != nil {
+		t.Fatalf("poll: %v", err)
+	}
+	if len(events) != 1 {
+		t.Errorf("repeat poll emitted %d events total, want 1", len(events))
+	}
+}
+
+func TestLoadConfig(t *testing.T) {
+	dir := t.TempDir()
+	valid := filepath.Join(dir, "ok.json")
+	os.WriteFile(valid, []byte(`{"repos":["acme/api"],"watch":["acme/api"],"poll_interval_sec":5}`), 0644)
+
+	cfg, err := LoadConfig(valid)
+	if err != nil {
+		t.Fatalf("LoadConfig: %v", err)
+	}
+	if cfg.APIURL != defaultAPIURL {
+		t.Errorf("api_url = %q", cfg.APIURL)
+	}
+	if cfg.PollIntervalSec != minPollInterval {
+		t.Errorf("poll_interval_sec = %d, want clamp to %d", cfg.PollIntervalSec, minPollInterval)
+	}
+
+	for name, data := range map[string]string{
+		"no repos":           `{}`,
+		"bad repo":           `{"repos":["acme"]}`,
+		"watch not in repos": `{"repos":["acme/api"],"watch":["acme/web"]}`,
+	} {
+		p := filepath.Join(dir, strings.ReplaceAll(name, " ", "_")+".json")
+		os.WriteFile(p, []byte(data), 0644)
+		if _, err := LoadConfig(p); err == nil {
+			t.Errorf("%s: expected error", name)
+		}
+	}
+}
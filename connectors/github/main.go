@@ -0,0 +1,53 @@
+// Command github-connector exposes GitHub pull requests, issues, merges, and
+// workflow re-runs as OpenSlack connector tools, and emits ci_failed events
+// for failed workflow runs on watched repos.
+//
+// Config is read from ~/.openslack/github.json (override with
+// OPENSLACK_GITHUB_CONFIG). The access token is read from the keychain
+// account "github-token".
+package main
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"github.com/jdelaire/openslack/connectors/sdk"
+	"github.com/jdelaire/openslack/internal/keychain"
+)
+
+const connectorVersion = "1.0.0"
+
+func main() {
+	cfgPath := os.Getenv("OPENSLACK_GITHUB_CONFIG")
+	if cfgPath == "" {
+		home, err := os.UserHomeDir()
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "home dir: %s\n", err)
+			os.Exit(1)
+		}
+		cfgPath = filepath.Join(home, ".openslack", "github.json")
+	}
+
+	cfg, err := LoadConfig(cfgPath)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
+
+	token, err := keychain.Get("github-token")
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "read github-token from keychain: %s\n", err)
+		os.Exit(1)
+	}
+
+	gh := NewGitHub(cfg, token)
+	c := sdk.New("github", connectorVersion)
+	gh.Register(c)
+
+	if len(cfg.Watch) > 0 {
+		go NewPoller(gh, c.Emit).Run(context.Background())
+	}
+	c.Run()
+}
@@ -0,0 +1,88 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"os"
+	"time"
+)
+
+// EmitFunc sends an event to the daemon; satisfied by (*sdk.Connector).Emit.
+type EmitFunc func(event, text string, data any) error
+
+// Poller watches repos for failed workflow runs and emits a ci_failed event
+// for each new one. Runs that already failed when the poller starts are
+// not reported.
+type Poller struct {
+	gh       *GitHub
+	emit     EmitFunc
+	interval time.Duration
+
+	seen map[string]map[int64]bool // repo -> run IDs from the last poll
+}
+
+// NewPoller creates a poller for the config's watched repos.
+func NewPoller(gh *GitHub, emit EmitFunc) *Poller {
+	return &Poller{
+		gh:       gh,
+		emit:     emit,
+		interval: time.Duration(gh.cfg.PollIntervalSec) * time.Second,
+		seen:     make(map[string]map[int64]bool),
+	}
+}
+
+// Run polls until ctx is cancelled.
+func (p *Poller) Run(ctx context.Context) {
+	ticker := time.NewTicker(p.interval)
+	defer ticker.Stop()
+
+	for {
+		for _, repo := range p.gh.cfg.Watch {
+			if err := p.poll(ctx, repo); err != nil {
+				fmt.Fprintf(os.Stderr, "poll %s: %s\n", repo, err)
+			}
+		}
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+type workflowRun struct {
+	ID         int64  `json:"id"`
+	Name       string `json:"name"`
+	HeadBranch string `json:"head_branch"`
+	HeadSHA    string `json:"head_sha"`
+	HTMLURL    string `json:"html_url"`
+}
+
+func (p *Poller) poll(ctx context.Context, repo string) error {
+	var res struct {
+		Runs []workflowRun `json:"workflow_runs"`
+	}
+	if err := p.gh.do(ctx, http.MethodGet, fmt.Sprintf("/repos/%s/actions/runs?status=failure&per_page=%d", repo, listLimit), nil, &res); err != nil {
+		return err
+	}
+
+	prev, seeded := p.seen[repo]
+	cur := make(map[int64]bool, len(res.Runs))
+	for _, run := range res.Runs {
+		cur[run.ID] = true
+		if !seeded || prev[run.ID] {
+			continue
+		}
+		text := fmt.Sprintf("CI failed: %s · %s on %s (%s)\n%s\nRerun: /github.rerun %s %d",
+			repo, run.Name, run.HeadBranch, shortSHA(run.HeadSHA), run.HTMLURL, repo, run.ID)
+		data := map[string]any{"repo": repo, "run_id": run.ID, "url": run.HTMLURL}
+		if err := p.emit("ci_failed", text, data); err != nil {
+			return fmt.Errorf("emit: %w", err)
+		}
+	}
+	// Only the latest page is remembered: a run that drops off it never
+	// reappears in the failure list, so the set stays bounded.
+	p.seen[repo] = cur
+	return nil
+}
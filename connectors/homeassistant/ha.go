@@ -0,0 +1,222 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"path"
+	"strings"
+	"time"
+
+	"github.com/jdelaire/openslack/connectors/sdk"
+)
+
+const (
+	httpTimeout     = 10 * time.Second
+	maxResponseBody = 1 << 20
+)
+
+// Config is the Home Assistant connector configuration. Entities and
+// services are glob patterns (e.g. "light.*"); anything not matched is
+// rejected before reaching Home Assistant.
+type Config struct {
+	URL      string   `json:"url"`
+	Entities []string `json:"entities"`
+	Services []string `json:"services"`
+}
+
+// LoadConfig reads and validates the connector config file.
+func LoadConfig(p string) (*Config, error) {
+	data, err := os.ReadFile(p)
+	if err != nil {
+		return nil, fmt.Errorf("read homeassistant config: %w", err)
+	}
+	var cfg Config
+	if err := json.Unmarshal(data, &cfg); err != nil {
+		return nil, fmt.Errorf("parse homeassistant config: %w", err)
+	}
+	if cfg.URL == "" {
+		return nil, fmt.Errorf("homeassistant config missing url")
+	}
+	if len(cfg.Entities) == 0 {
+		return nil, fmt.Errorf("homeassistant config has no allowed entities")
+	}
+	for _, pattern := range append(append([]string{}, cfg.Entities...), cfg.Services...) {
+		if _, err := path.Match(pattern, ""); err != nil {
+			return nil, fmt.Errorf("homeassistant config: bad pattern %q", pattern)
+		}
+	}
+	cfg.URL = strings.TrimRight(cfg.URL, "/")
+	return &cfg, nil
+}
+
+func matchAny(patterns []string, name string) bool {
+	for _, p := range patterns {
+		if ok, _ := path.Match(p, name); ok {
+			return true
+		}
+	}
+	return false
+}
+
+// HA calls the Home Assistant REST API.
+type HA struct {
+	cfg    *Config
+	token  string
+	client *http.Client
+}
+
+// NewHA creates a Home Assistant client authenticating with a long-lived
+// access token.
+func NewHA(cfg *Config, token string) *HA {
+	return &HA{cfg: cfg, token: token, client: &http.Client{Timeout: httpTimeout}}
+}
+
+// Register adds the state, call, and trigger tools to c.
+func (h *HA) Register(c *sdk.Connector) {
+	c.Tool("state", h.state)
+	c.Tool("call", h.call, sdk.Mutating())
+	c.Tool("trigger", h.trigger, sdk.Mutating())
+}
+
+// state returns an entity's current state. Args: "light.kitchen" or
+// {"entity_id": "light.kitchen"}.
+func (h *HA) state(ctx context.Context, args json.RawMessage) (any, error) {
+	var a struct {
+		EntityID string `json:"entity_id"`
+	}
+	json.Unmarshal(args, &a)
+	if a.EntityID == "" {
+		a.EntityID = sdk.TextArg(args)
+	}
+	if a.EntityID == "" {
+		return nil, sdk.InvalidArgs("entity_id is required")
+	}
+	if !matchAny(h.cfg.Entities, a.EntityID) {
+		return nil, sdk.Unauthorized("entity %s is not allowed", a.EntityID)
+	}
+
+	var st struct {
+		EntityID    string         `json:"entity_id"`
+		State       string         `json:"state"`
+		LastChanged string         `json:"last_changed"`
+		Attributes  map[string]any `json:"attributes"`
+	}
+	if err := h.do(ctx, http.MethodGet, "/api/states/"+url.PathEscape(a.EntityID), nil, &st); err != nil {
+		return nil, err
+	}
+	out := map[string]string{
+		"entity_id":    st.EntityID,
+		"state":        st.State,
+		"last_changed": st.LastChanged,
+	}
+	if name, ok := st.Attributes["friendly_name"].(string); ok {
+		out["name"] = name
+	}
+	return out, nil
+}
+
+// call invokes a service on an entity. Args: "light.turn_on light.kitchen"
+// or {"service": "light.turn_on", "entity_id": "light.kitchen", "data": {...}}.
+func (h *HA) call(ctx context.Context, args json.RawMessage) (any, error) {
+	var a struct {
+		Service  string         `json:"service"`
+		EntityID string         `json:"entity_id"`
+		Data     map[string]any `json:"data"`
+	}
+	json.Unmarshal(args, &a)
+	if a.Service == "" {
+		fields := strings.Fields(sdk.TextArg(args))
+		if len(fields) == 2 {
+			a.Service, a.EntityID = fields[0], fields[1]
+		}
+	}
+	if a.Service == "" || a.EntityID == "" {
+		return nil, sdk.InvalidArgs("usage: <domain.service> <entity_id>")
+	}
+	domain, service, ok := strings.Cut(a.Service, ".")
+	if !ok || domain == "" || service == "" {
+		return nil, sdk.InvalidArgs("service must be domain.service")
+	}
+	if !matchAny(h.cfg.Services, a.Service) {
+		return nil, sdk.Unauthorized("service %s is not allowed", a.Service)
+	}
+	if !matchAny(h.cfg.Entities, a.EntityID) {
+		return nil, sdk.Unauthorized("entity %s is not allowed", a.EntityID)
+	}
+
+	body := map[string]any{}
+	for k, v := range a.Data {
+		body[k] = v
+	}
+	body["entity_id"] = a.EntityID
+	if err := h.do(ctx, http.MethodPost, "/api/services/"+url.PathEscape(domain)+"/"+url.PathEscape(service), body, nil); err != nil {
+		return nil, err
+	}
+	return map[string]string{"called": a.Service, "entity_id": a.EntityID}, nil
+}
+
+// trigger fires an automation. Args: "automation.morning" or
+// {"entity_id": "automation.morning"}.
+func (h *HA) trigger(ctx context.Context, args json.RawMessage) (any, error) {
+	var a struct {
+		EntityID string `json:"entity_id"`
+	}
+	json.Unmarshal(args, &a)
+	if a.EntityID == "" {
+		a.EntityID = sdk.TextArg(args)
+	}
+	if !strings.HasPrefix(a.EntityID, "automation.") {
+		return nil, sdk.InvalidArgs("entity_id must be an automation.* entity")
+	}
+	if !matchAny(h.cfg.Entities, a.EntityID) {
+		return nil, sdk.Unauthorized("entity %s is not allowed", a.EntityID)
+	}
+	body := map[string]any{"entity_id": a.EntityID}
+	if err := h.do(ctx, http.MethodPost, "/api/services/automation/trigger", body, nil); err != nil {
+		return nil, err
+	}
+	return map[string]string{"triggered": a.EntityID}, nil
+}
+
+func (h *HA) do(ctx context.Context, method, p string, body any, out any) error {
+	var r io.Reader
+	if body != nil {
+		data, err := json.Marshal(body)
+		if err != nil {
+			return fmt.Errorf("marshal body: %w", err)
+		}
+		r = bytes.NewReader(data)
+	}
+	req, err := http.NewRequestWithContext(ctx, method, h.cfg.URL+p, r)
+	if err != nil {
+		return fmt.Errorf("create request: %w", err)
+	}
+	req.Header.Set("Authorization", "Bearer "+h.token)
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := h.client.Do(req)
+	if err != nil {
+		return fmt.Errorf("homeassistant request: %w", err)
+	}
+	defer resp.Body.Close()
+
+	data, err := io.ReadAll(io.LimitReader(resp.Body, maxResponseBody))
+	if err != nil {
+		return fmt.Errorf("read homeassistant response: %w", err)
+	}
+	if resp.StatusCode != http.StatusOK && resp.StatusCode != http.StatusCreated {
+		return fmt.Errorf("homeassistant API error %d: %s", resp.StatusCode, strings.TrimSpace(string(data)))
+	}
+	if out != nil {
+		if err := json.Unmarshal(data, out); err != nil {
+			return fmt.Errorf("decode homeassistant response: %w", err)
+		}
+	}
+	return nil
+}
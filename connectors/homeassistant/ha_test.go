@@ -0,0 +1,169 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/jdelaire/openslack/connectors/sdk"
+)
+
+type haRequest struct {
+	method, path, auth string
+	body               map[string]any
+}
+
+func newTestHA(t *testing.T) (*HA, *[]haRequest) {
+	t.Helper()
+	var reqs []haRequest
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		data, _ := io.ReadAll(r.Body)
+		var body map[string]any
+		json.Unmarshal(data, &body)
+		reqs = append(reqs, haRequest{method: r.Method, path: r.URL.Path, auth: r.Header.Get("Authorization"), body: body})
+
+		if r.Method == http.MethodGet {
+			w.Write([]byte(`{"entity_id":"light.kitchen","state":"on","last_changed":"2026-03-01T10:00:00Z","attributes":{"friendly_name":"Kitchen"}}`))
+			return
+		}
+		w.Write([]byte(`[]`))
+	}))
+	t.Cleanup(srv.Close)
+
+	cfg := &Config{
+		URL:      srv.URL,
+		Entities: []string{"light.*", "automation.morning"},
+		Services: []string{"light.turn_on", "light.turn_off"},
+	}
+	return NewHA(cfg, "tok"), &reqs
+}
+
+func wantCode(t *testing.T, err error, code string) {
+	t.Helper()
+	var toolErr *sdk.Error
+	if !errors.As(err, &toolErr) || toolErr.Code != code {
+		t.Fatalf("error = %v, want code %s", err, code)
+	}
+}
+
+func TestState(t *testing.T) {
+	ha, reqs := newTestHA(t)
+
+	got, err := ha.state(context.Background(), json.RawMessage(`{"text":"light.kitchen"}`))
+	if err != nil {
+		t.Fatalf("state: %v", err)
+	}
+	m := got.(map[string]string)
+	if m["state"] != "on" || m["name"] != "Kitchen" {
+		t.Errorf("state = %v", m)
+	}
+	r := (*reqs)[0]
+	if r.path != "/api/states/light.kitchen" || r.auth != "Bearer tok" {
+		t.Errorf("request = %+v", r)
+	}
+}
+
+func TestStateEntityNotAllowed(t *testing.T) {
+	ha, reqs := newTestHA(t)
+
+	_, err := ha.state(context.Background(), json.RawMessage(`{"entity_id":"lock.front_door"}`))
+	wantCode(t, err, "UNAUTHORIZED")
+	if len(*reqs) != 0 {
+		t.Error("disallowed entity reached Home Assistant")
+	}
+}
+
+func TestCallService(t *testing.T) {
+	ha, reqs := newTestHA(t)
+
+	_, err := ha.call(context.Background(), json.RawMessage(`{"service":"light.turn_on","entity_id":"light.kitchen","data":{"brightness":128}}`))
+	if err != nil {
+		t.Fatalf("call: %v", err)
+	}
+	r := (*reqs)[0]
+	if r.method != http.MethodPost || r.path != "/api/services/light/turn_on" {
+		t.Errorf("request = %s %s", r.method, r.path)
+	}
+	if r.body["entity_id"] != "light.kitchen" || r.body["brightness"] != float64(128) {
+		t.Errorf("body = %v", r.body)
+	}
+}
+
+func TestCallServiceText(t *testing.T) {
+	ha, reqs := newTestHA(t)
+
+	if _, err := ha.call(context.Background(), json.RawMessage(`{"text":"light.turn_off light.porch"}`)); err != nil {
+		t.Fatalf("call: %v", err)
+	}
+	if (*reqs)[0].path != "/api/services/light/turn_off" {
+		t.Errorf("path = %s", (*reqs)[0].path)
+	}
+}
+
+func TestCallServiceRejected(t *testing.T) {
+	ha, _ := newTestHA(t)
+
+	tests := []struct {
+		args string
+		code string
+	}{
+		{`{"text":"light.toggle light.kitchen"}`, "UNAUTHORIZED"},
+		{`{"text":"light.turn_on switch.heater"}`, "UNAUTHORIZED"},
+		{`{"text":"light.turn_on"}`, "INVALID_ARGS"},
+		{`{"service":"turn_on","entity_id":"light.kitchen"}`, "INVALID_ARGS"},
+	}
+	for _, tt := range tests {
+		_, err := ha.call(context.Background(), json.RawMessage(tt.args))
+		wantCode(t, err, tt.code)
+	}
+}
+
+func TestTrigger(t *testing.T) {
+	ha, reqs := newTestHA(t)
+
+	if _, err := ha.trigger(context.Background(), json.RawMessage(`{"text":"automation.morning"}`)); err != nil {
+		t.Fatalf("trigger: %v", err)
+	}
+	r := (*reqs)[0]
+	if r.path != "/api/services/automation/trigger" || r.body["entity_id"] != "automation.morning" {
+		t.Errorf("request = %+v", r)
+	}
+
+	_, err := ha.trigger(context.Background(), json.RawMessage(`{"text":"automation.evening"}`))
+	wantCode(t, err, "UNAUTHORIZED")
+	_, err = ha.trigger(context.Background(), json.RawMessage(`{"text":"light.kitchen"}`))
+	wantCode(t, err, "INVALID_ARGS")
+}
+
+func TestLoadConfig(t *testing.T) {
+	dir := t.TempDir()
+	valid := filepath.Join(dir, "ok.json")
+	os.WriteFile(valid, []byte(`{"url":"http://ha.local:8123/","entities":["light.*"],"services":["light.turn_on"]}`), 0644)
+
+	cfg, err := LoadConfig(valid)
+	if err != nil {
+		t.Fatalf("LoadConfig: %v", err)
+	}
+	if cfg.URL != "http://ha.local:8123" {
+		t.Errorf("url = %q", cfg.URL)
+	}
+
+	for name, data := range map[string]string{
+		"missing url":      `{"entities":["light.*"]}`,
+		"missing entities": `{"url":"http://ha"}`,
+		"bad pattern":      `{"url":"http://ha","entities":["light.["]}`,
+	} {
+		p := filepath.Join(dir, strings.ReplaceAll(name, " ", "_")+".json")
+		os.WriteFile(p, []byte(data), 0644)
+		if _, err := LoadConfig(p); err == nil {
+			t.Errorf("%s: expected error", name)
+		}
+	}
+}
@@ -0,0 +1,48 @@
+// Command homeassistant-connector exposes Home Assistant state queries,
+// service calls, and automation triggers as OpenSlack connector tools.
+// It uses the REST API only; states are read when asked, not subscribed
+// to.
+//
+// Config is read from ~/.openslack/homeassistant.json (override with
+// OPENSLACK_HA_CONFIG). The long-lived access token is read from the
+// keychain account "homeassistant-token".
+package main
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"github.com/jdelaire/openslack/connectors/sdk"
+	"github.com/jdelaire/openslack/internal/keychain"
+)
+
+const connectorVersion = "1.0.0"
+
+func main() {
+	cfgPath := os.Getenv("OPENSLACK_HA_CONFIG")
+	if cfgPath == "" {
+		home, err := os.UserHomeDir()
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "home dir: %s\n", err)
+			os.Exit(1)
+		}
+		cfgPath = filepath.Join(home, ".openslack", "homeassistant.json")
+	}
+
+	cfg, err := LoadConfig(cfgPath)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
+
+	token, err := keychain.Get("homeassistant-token")
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "read homeassistant-token from keychain: %s\n", err)
+		os.Exit(1)
+	}
+
+	c := sdk.New("homeassistant", connectorVersion)
+	NewHA(cfg, token).Register(c)
+	c.Run()
+}
@@ -70,6 +70,8 @@ func handle(req request) response {
 		return handleTime(req)
 	case "sleep":
 		return handleSleep(req)
+	case "notify":
+		return handleNotify(req)
 	default:
 		return response{
 			Version: "v1",
@@ -88,6 +90,7 @@ func handleIntrospect(req request) response {
 			{"name": "echo"},
 			{"name": "time"},
 			{"name": "sleep"},
+			{"name": "notify"},
 		},
 	})
 	return response{Version: "v1", ID: req.ID, OK: true, Data: data}
@@ -135,6 +138,25 @@ func handleSleep(req request) response {
 	return response{Version: "v1", ID: req.ID, OK: true, Data: data}
 }
 
+// handleNotify emits an event with the given text before responding.
+// Used to validate the event channel.
+func handleNotify(req request) response {
+	var args struct {
+		Text string `json:"text"`
+	}
+	if err := json.Unmarshal(req.Args, &args); err != nil || args.Text == "" {
+		return response{
+			Version: "v1", ID: req.ID, OK: false,
+			Error: &respError{Code: "INVALID_ARGS", Message: "text is required"},
+		}
+	}
+	out, _ := json.Marshal(map[string]string{"version": "v1", "event": "notice", "text": args.Text})
+	fmt.Fprintln(os.Stdout, string(out))
+
+	data, _ := json.Marshal(map[string]string{"emitted": "notice"})
+	return response{Version: "v1", ID: req.ID, OK: true, Data: data}
+}
+
 func writeError(id, code, message string) {
 	resp := response{
 		Version: "v1",
@@ -0,0 +1,279 @@
+// Package sdk implements the connector side of the OpenSlack connector
+// protocol so Go connectors only have to register tool handlers.
+package sdk
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"sync"
+
+	"github.com/jdelaire/openslack/core/connector"
+)
+
+const maxLineBytes = 64 * 1024
+
+// Handler serves a single tool call. The returned value is marshaled as the
+// response data. Returning an *Error sends that code to the caller; any
+// other error is reported as INTERNAL.
+type Handler func(ctx context.Context, args json.RawMessage) (any, error)
+
+// Error is a structured tool error.
+type Error struct {
+	Code    string
+	Message string
+}
+
+func (e *Error) Error() string { return e.Code + ": " + e.Message }
+
+// InvalidArgs returns an INVALID_ARGS error.
+func InvalidArgs(format string, a ...any) error {
+	return &Error{Code: connector.ErrInvalidArgs, Message: fmt.Sprintf(format, a...)}
+}
+
+// Unauthorized returns an UNAUTHORIZED error.
+func Unauthorized(format string, a ...any) error {
+	return &Error{Code: connector.ErrUnauthorized, Message: fmt.Sprintf(format, a...)}
+}
+
+// Connector dispatches protocol requests to registered tool handlers.
+type Connector struct {
+	name    string
+	version string
+	tools   map[string]Handler
+	flags   map[string]connector.IntrospectTool
+	order   []string
+	secret  []byte // signs responses and events; nil if unsigned
+
+	outMu sync.Mutex // serializes response and event lines
+	out   io.Writer
+}
+
+// New creates a connector with the given name and version, as reported by
+// __introspect. If the daemon passed a shared secret in the environment,
+// requests must carry a valid signature and every line written is signed.
+// The secret is removed from the environment so tools never see it.
+func New(name, version string) *Connector {
+	c := &Connector{
+		name:    name,
+		version: version,
+		tools:   make(map[string]Handler),
+		flags:   make(map[string]connector.IntrospectTool),
+		out:     os.Stdout,
+	}
+	if secret := os.Getenv(connector.SecretEnv); secret != "" {
+		os.Unsetenv(connector.SecretEnv)
+		c.secret = []byte(secret)
+	}
+	return c
+}
+
+// WithSecret sets the shared secret explicitly, e.g. in tests.
+func (c *Connector) WithSecret(secret string) *Connector {
+	c.secret = []byte(secret)
+	return c
+}
+
+// ToolOption declares a property of a tool, reported by __introspect.
+type ToolOption func(*connector.IntrospectTool)
+
+// Mutating marks a tool that changes state outside the connector. The
+// daemon gates such tools behind /do + /approve.
+func Mutating() ToolOption {
+	return func(t *connector.IntrospectTool) { t.Mutating = true }
+}
+
+// HighRisk marks a tool the daemon should gate behind /do + /approve even
+// though it does not change state, e.g. one that reveals secrets.
+func HighRisk() ToolOption {
+	return func(t *connector.IntrospectTool) { t.Risk = "high" }
+}
+
+// Tool registers a handler. Tool names must not use the reserved "__" prefix.
+func (c *Connector) Tool(name string, h Handler, opts ...ToolOption) {
+	if strings.HasPrefix(name, "__") {
+		panic(fmt.Sprintf("sdk: tool name %q uses reserved prefix __", name))
+	}
+	if _, exists := c.tools[name]; !exists {
+		c.order = append(c.order, name)
+	}
+	c.tools[name] = h
+	t := connector.IntrospectTool{Name: name}
+	for _, opt := range opts {
+		opt(&t)
+	}
+	c.flags[name] = t
+}
+
+// ScratchDir returns the private directory the daemon keeps for this
+// connector across restarts, or "" if it provides none.
+func ScratchDir() string {
+	return os.Getenv(connector.ScratchEnv)
+}
+
+// Run serves requests on stdin/stdout until stdin closes. It exits the
+// process with status 1 on a read error.
+func (c *Connector) Run() {
+	fmt.Fprintf(os.Stderr, "%s-connector started\n", c.name)
+	if err := c.Serve(context.Background(), os.Stdin, os.Stdout); err != nil {
+		fmt.Fprintf(os.Stderr, "stdin error: %s\n", err)
+		os.Exit(1)
+	}
+}
+
+// Serve reads newline-delimited requests from r and writes one response
+// line per request to w. Events passed to Emit are written to w as well.
+func (c *Connector) Serve(ctx context.Context, r io.Reader, w io.Writer) error {
+	c.outMu.Lock()
+	c.out = w
+	c.outMu.Unlock()
+
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, maxLineBytes), maxLineBytes)
+
+	for scanner.Scan() {
+		resp := c.handleLine(ctx, scanner.Bytes())
+		if c.secret != nil {
+			connector.SignResponse(resp, c.secret)
+		}
+		out, err := json.Marshal(resp)
+		if err != nil {
+			out, _ = json.Marshal(connector.NewErrorResponse(resp.ID, connector.ErrInternal, "marshal response"))
+		}
+		if err := c.writeLine(out); err != nil {
+			return err
+		}
+	}
+	return scanner.Err()
+}
+
+// Emit sends an unsolicited event to the daemon, which relays text through
+// its notifier. It is safe to call from any goroutine, e.g. a poller.
+func (c *Connector) Emit(event, text string, data any) error {
+	ev := connector.Event{Version: connector.ProtocolVersion, Event: event, Text: text}
+	if data != nil {
+		raw, err := json.Marshal(data)
+		if err != nil {
+			return fmt.Errorf("marshal event data: %w", err)
+		}
+		ev.Data = raw
+	}
+	if err := connector.ValidateEvent(&ev); err != nil {
+		return err
+	}
+	if c.secret != nil {
+		connector.SignEvent(&ev, c.secret)
+	}
+	out, err := json.Marshal(ev)
+	if err != nil {
+		return fmt.Errorf("marshal event: %w", err)
+	}
+	return c.writeLine(out)
+}
+
+func (c *Connector) writeLine(line []byte) error {
+	c.outMu.Lock()
+	defer c.outMu.Unlock()
+	_, err := fmt.Fprintln(c.out, string(line))
+	return err
+}
+
+func (c *Connector) handleLine(ctx context.Context, line []byte) *connector.Response {
+	var req connector.Request
+	if err := json.Unmarshal(line, &req); err != nil {
+		return connector.NewErrorResponse("", connector.ErrInvalidRequest, fmt.Sprintf("invalid json: %s", err))
+	}
+	if err := connector.ValidateRequest(&req); err != nil {
+		return connector.NewErrorResponse(req.ID, connector.ErrInvalidRequest, err.Error())
+	}
+	if c.secret != nil {
+		if err := connector.VerifyRequest(&req, c.secret); err != nil {
+			return connector.NewErrorResponse(req.ID, connector.ErrUnauthorized, "request "+err.Error())
+		}
+	}
+
+	if req.Tool == connector.BatchToolName {
+		return c.batch(ctx, &req)
+	}
+	return c.handle(ctx, &req)
+}
+
+// handle runs one validated request.
+func (c *Connector) handle(ctx context.Context, req *connector.Request) *connector.Response {
+	if req.Tool == connector.IntrospectToolName {
+		return c.ok(req.ID, c.introspect())
+	}
+
+	h, ok := c.tools[req.Tool]
+	if !ok {
+		return connector.NewErrorResponse(req.ID, connector.ErrNotSupported, fmt.Sprintf("unknown tool: %s", req.Tool))
+	}
+
+	data, err := h(ctx, req.Args)
+	if err != nil {
+		var toolErr *Error
+		if errors.As(err, &toolErr) {
+			return connector.NewErrorResponse(req.ID, toolErr.Code, toolErr.Message)
+		}
+		return connector.NewErrorResponse(req.ID, connector.ErrInternal, err.Error())
+	}
+	return c.ok(req.ID, data)
+}
+
+// batch runs the requests of a __batch in order. The batch as a whole is
+// signed, so its requests carry no signatures of their own.
+func (c *Connector) batch(ctx context.Context, req *connector.Request) *connector.Response {
+	var args connector.BatchArgs
+	if err := json.Unmarshal(req.Args, &args); err != nil {
+		return connector.NewErrorResponse(req.ID, connector.ErrInvalidArgs, fmt.Sprintf("invalid batch: %s", err))
+	}
+	if len(args.Requests) > connector.MaxBatchCalls {
+		return connector.NewErrorResponse(req.ID, connector.ErrInvalidArgs, fmt.Sprintf("batch exceeds %d calls", connector.MaxBatchCalls))
+	}
+
+	data := connector.BatchData{Responses: make([]connector.Response, len(args.Requests))}
+	for i := range args.Requests {
+		inner := &args.Requests[i]
+		var resp *connector.Response
+		if err := connector.ValidateRequest(inner); err != nil {
+			resp = connector.NewErrorResponse(inner.ID, connector.ErrInvalidRequest, err.Error())
+		} else if inner.Tool == connector.BatchToolName {
+			resp = connector.NewErrorResponse(inner.ID, connector.ErrInvalidRequest, "batches cannot be nested")
+		} else {
+			resp = c.handle(ctx, inner)
+		}
+		data.Responses[i] = *resp
+	}
+	return c.ok(req.ID, data)
+}
+
+func (c *Connector) ok(id string, data any) *connector.Response {
+	raw, err := json.Marshal(data)
+	if err != nil {
+		return connector.NewErrorResponse(id, connector.ErrInternal, fmt.Sprintf("marshal data: %s", err))
+	}
+	return &connector.Response{Version: connector.ProtocolVersion, ID: id, OK: true, Data: raw}
+}
+
+func (c *Connector) introspect() connector.IntrospectData {
+	tools := make([]connector.IntrospectTool, len(c.order))
+	for i, name := range c.order {
+		tools[i] = c.flags[name]
+	}
+	return connector.IntrospectData{Name: c.name, Version: c.version, Tools: tools}
+}
+
+// TextArg returns the "text" field the daemon sends for plain-text
+// arguments, trimmed. It returns "" if the field is absent.
+func TextArg(args json.RawMessage) string {
+	var a struct {
+		Text string `json:"text"`
+	}
+	json.Unmarshal(args, &a)
+	return strings.TrimSpace(a.Text)
+}
@@ -0,0 +1,210 @@
+package sdk
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/jdelaire/openslack/core/connector"
+)
+
+func serveLines(t *testing.T, c *Connector, lines ...string) []connector.Response {
+	t.Helper()
+	var out bytes.Buffer
+	if err := c.Serve(context.Background(), strings.NewReader(strings.Join(lines, "\n")+"\n"), &out); err != nil {
+		t.Fatalf("serve: %v", err)
+	}
+	var resps []connector.Response
+	for _, line := range strings.Split(strings.TrimSpace(out.String()), "\n") {
+		var r connector.Response
+		if err := json.Unmarshal([]byte(line), &r); err != nil {
+			t.Fatalf("decode %q: %v", line, err)
+		}
+		resps = append(resps, r)
+	}
+	return resps
+}
+
+func testConnector() *Connector {
+	c := New("demo", "0.1.0")
+	c.Tool("upper", func(_ context.Context, args json.RawMessage) (any, error) {
+		text := TextArg(args)
+		if text == "" {
+			return nil, InvalidArgs("text is required")
+		}
+		return map[string]string{"text": strings.ToUpper(text)}, nil
+	})
+	c.Tool("boom", func(_ context.Context, _ json.RawMessage) (any, error) {
+		return nil, errors.New("exploded")
+	})
+	return c
+}
+
+func TestServeToolCall(t *testing.T) {
+	resps := serveLines(t, testConnector(), `{"version":"v1","id":"r1","tool":"upper","args":{"text":" hi "}}`)
+
+	r := resps[0]
+	if !r.OK || r.ID != "r1" || r.Version != connector.ProtocolVersion {
+		t.Fatalf("response = %+v", r)
+	}
+	if string(r.Data) != `{"text":"HI"}` {
+		t.Errorf("data = %s", r.Data)
+	}
+}
+
+func TestServeErrors(t *testing.T) {
+	resps := serveLines(t, testConnector(),
+		`{"version":"v1","id":"r1","tool":"upper","args":{}}`,
+		`{"version":"v1","id":"r2","tool":"boom","args":{}}`,
+		`{"version":"v1","id":"r3","tool":"missing","args":{}}`,
+		`{"version":"v2","id":"r4","tool":"upper","args":{}}`,
+		`not json`,
+	)
+
+	want := []struct {
+		id   string
+		code string
+	}{
+		{"r1", connector.ErrInvalidArgs},
+		{"r2", connector.ErrInternal},
+		{"r3", connector.ErrNotSupported},
+		{"r4", connector.ErrInvalidRequest},
+		{"", connector.ErrInvalidRequest},
+	}
+	if len(resps) != len(want) {
+		t.Fatalf("got %d responses, want %d", len(resps), len(want))
+	}
+	for i, w := range want {
+		if resps[i].OK || resps[i].ID != w.id || resps[i].Error.Code != w.code {
+			t.Errorf("response %d = %+v %+v, want id=%q code=%s", i, resps[i], resps[i].Error, w.id, w.code)
+		}
+	}
+}
+
+func TestServeIntrospect(t *testing.T) {
+	resps := serveLines(t, testConnector(), `{"version":"v1","id":"r1","tool":"__introspect","args":{}}`)
+
+	var data connector.IntrospectData
+	if err := json.Unmarshal(resps[0].Data, &data); err != nil {
+		t.Fatalf("decode introspect: %v", err)
+	}
+	if data.Name != "demo" || data.Version != "0.1.0" {
+		t.Errorf("introspect = %+v", data)
+	}
+	if len(data.Tools) != 2 || data.Tools[0].Name != "upper" || data.Tools[1].Name != "boom" {
+		t.Errorf("tools = %+v, want registration order", data.Tools)
+	}
+}
+
+func TestIntrospectFlags(t *testing.T) {
+	c := testConnector()
+	c.Tool("wipe", func(context.Context, json.RawMessage) (any, error) { return nil, nil }, Mutating())
+	c.Tool("token", func(context.Context, json.RawMessage) (any, error) { return nil, nil }, HighRisk())
+	resps := serveLines(t, c, `{"version":"v1","id":"r1","tool":"__introspect","args":{}}`)
+
+	var data connector.IntrospectData
+	if err := json.Unmarshal(resps[0].Data, &data); err != nil {
+		t.Fatalf("decode introspect: %v", err)
+	}
+	want := []connector.IntrospectTool{
+		{Name: "upper"},
+		{Name: "boom"},
+		{Name: "wipe", Mutating: true},
+		{Name: "token", Risk: "high"},
+	}
+	if len(data.Tools) != len(want) {
+		t.Fatalf("tools = %+v", data.Tools)
+	}
+	for i, tool := range data.Tools {
+		if tool != want[i] {
+			t.Errorf("tool %d = %+v, want %+v", i, tool, want[i])
+		}
+	}
+}
+
+func TestEmit(t *testing.T) {
+	var out bytes.Buffer
+	c := testConnector()
+	if err := c.Serve(context.Background(), strings.NewReader(""), &out); err != nil {
+		t.Fatalf("serve: %v", err)
+	}
+
+	if err := c.Emit("ci_failed", "CI failed on main", map[string]int{"run": 7}); err != nil {
+		t.Fatalf("emit: %v", err)
+	}
+	var ev connector.Event
+	if err := json.Unmarshal(out.Bytes(), &ev); err != nil {
+		t.Fatalf("decode event %q: %v", out.String(), err)
+	}
+	if ev.Version != connector.ProtocolVersion || ev.Event != "ci_failed" || ev.Text != "CI failed on main" || string(ev.Data) != `{"run":7}` {
+		t.Errorf("event = %+v", ev)
+	}
+
+	if err := c.Emit("ci_failed", "", nil); err == nil {
+		t.Error("expected error for empty text")
+	}
+}
+
+func TestServeSigned(t *testing.T) {
+	secret := []byte("s3cret")
+	req := &connector.Request{Version: connector.ProtocolVersion, ID: "r1", Tool: "upper", Args: json.RawMessage(`{"text":"hi"}`)}
+	connector.SignRequest(req, secret)
+	signed, _ := json.Marshal(req)
+
+	resps := serveLines(t, testConnector().WithSecret("s3cret"),
+		string(signed),
+		`{"version":"v1","id":"r2","tool":"upper","args":{"text":"hi"}}`,
+	)
+	if !resps[0].OK || string(resps[0].Data) != `{"text":"HI"}` {
+		t.Errorf("signed request: %+v", resps[0])
+	}
+	if resps[1].OK || resps[1].Error.Code != connector.ErrUnauthorized {
+		t.Errorf("unsigned request: %+v", resps[1])
+	}
+	for _, r := range resps {
+		if err := connector.VerifyResponse(&r, secret); err != nil {
+			t.Errorf("response %s: %v", r.ID, err)
+		}
+	}
+}
+
+func TestServeBatch(t *testing.T) {
+	resps := serveLines(t, testConnector(), `{"version":"v1","id":"b1","tool":"__batch","args":{"requests":[`+
+		`{"version":"v1","id":"r1","tool":"upper","args":{"text":"a"}},`+
+		`{"version":"v1","id":"r2","tool":"upper","args":{}},`+
+		`{"version":"v1","id":"r3","tool":"__batch","args":{}},`+
+		`{"version":"v1","id":"r4","tool":"upper"}]}}`)
+
+	if !resps[0].OK || resps[0].ID != "b1" {
+		t.Fatalf("batch response = %+v", resps[0])
+	}
+	var data connector.BatchData
+	if err := json.Unmarshal(resps[0].Data, &data); err != nil {
+		t.Fatalf("decode batch: %v", err)
+	}
+	want := []struct {
+		id   string
+		ok   bool
+		code string
+	}{
+		{"r1", true, ""},
+		{"r2", false, connector.ErrInvalidArgs},
+		{"r3", false, connector.ErrInvalidRequest},
+		{"r4", false, connector.ErrInvalidRequest},
+	}
+	if len(data.Responses) != len(want) {
+		t.Fatalf("responses = %+v", data.Responses)
+	}
+	for i, w := range want {
+		r := data.Responses[i]
+		if r.ID != w.id || r.OK != w.ok || (!r.OK && r.Error.Code != w.code) {
+			t.Errorf("response %d = %+v, want %+v", i, r, w)
+		}
+	}
+	if string(data.Responses[0].Data) != `{"text":"A"}` {
+		t.Errorf("r1 data = %s", data.Responses[0].Data)
+	}
+}
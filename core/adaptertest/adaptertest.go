@@ -0,0 +1,238 @@
+// Package adaptertest provides conformance tests for Notifier and Receiver
+// implementations, so a new adapter (Slack, Matrix, ...) can check it
+// behaves as core expects: it honours context cancellation, reports
+// failures, carries full-size messages and keeps their order.
+//
+// An adapter's tests describe how to build it against a fake backend and
+// call TestNotifier or TestReceiver:
+//
+//	func TestConformance(t *testing.T) {
+//		adaptertest.TestNotifier(t, adaptertest.NotifierHarness{New: ..., NewFailing: ..., NewBlocking: ...})
+//	}
+package adaptertest
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/jdelaire/openslack/core"
+)
+
+// waitTimeout bounds how long an adapter may take to react to delivery,
+// cancellation or shutdown before a test fails.
+const waitTimeout = 5 * time.Second
+
+// NotifierHarness builds the notifier under test against fake backends.
+type NotifierHarness struct {
+	// New returns a notifier whose backend accepts every delivery, and a
+	// function returning the texts delivered so far, in arrival order.
+	New func(t *testing.T) (n core.Notifier, delivered func() []string)
+	// NewFailing returns a notifier whose backend rejects every delivery.
+	NewFailing func(t *testing.T) core.Notifier
+	// NewBlocking returns a notifier whose backend never answers.
+	NewBlocking func(t *testing.T) core.Notifier
+}
+
+// TestNotifier runs the notifier conformance tests.
+func TestNotifier(t *testing.T, h NotifierHarness) {
+	t.Run("Name", func(t *testing.T) {
+		n, _ := h.New(t)
+		if n.Name() == "" {
+			t.Error("Name() is empty")
+		}
+	})
+
+	t.Run("Delivers", func(t *testing.T) {
+		n, delivered := h.New(t)
+		if err := n.Send(context.Background(), notification("hello from adaptertest")); err != nil {
+			t.Fatalf("Send: %v", err)
+		}
+		if got := delivered(); len(got) != 1 || !strings.Contains(got[0], "hello from adaptertest") {
+			t.Errorf("delivered %q, want one message containing the text", got)
+		}
+	})
+
+	t.Run("Ordering", func(t *testing.T) {
+		n, delivered := h.New(t)
+		for i := range 5 {
+			if err := n.Send(context.Background(), notification(fmt.Sprintf("message-%d", i))); err != nil {
+				t.Fatalf("Send %d: %v", i, err)
+			}
+		}
+		got := delivered()
+		if len(got) != 5 {
+			t.Fatalf("delivered %d messages, want 5", len(got))
+		}
+		for i, text := range got {
+			if !strings.Contains(text, fmt.Sprintf("message-%d", i)) {
+				t.Errorf("message %d = %q, want message-%d", i, text, i)
+			}
+		}
+	})
+
+	t.Run("MaxSize", func(t *testing.T) {
+		n, delivered := h.New(t)
+		text := strings.Repeat("x", core.MaxTextLen)
+		if err := n.Send(context.Background(), notification(text)); err != nil {
+			t.Fatalf("Send of %d characters: %v", core.MaxTextLen, err)
+		}
+		if got := strings.Join(delivered(), ""); strings.Count(got, "x") != core.MaxTextLen {
+			t.Errorf("delivered %d of %d characters", strings.Count(got, "x"), core.MaxTextLen)
+		}
+	})
+
+	t.Run("ErrorPropagation", func(t *testing.T) {
+		n := h.NewFailing(t)
+		if err := n.Send(context.Background(), notification("rejected")); err == nil {
+			t.Error("Send succeeded against a failing backend")
+		}
+	})
+
+	t.Run("ContextCancellation", func(t *testing.T) {
+		n := h.NewBlocking(t)
+		ctx, cancel := context.WithTimeout(context.Background(), 100*time.Millisecond)
+		defer cancel()
+		err := within(t, "Send", func() error { return n.Send(ctx, notification("never answered")) })
+		if err == nil {
+			t.Error("Send succeeded although its context expired")
+		}
+	})
+
+	t.Run("CancelledContext", func(t *testing.T) {
+		n, delivered := h.New(t)
+		ctx, cancel := context.WithCancel(context.Background())
+		cancel()
+		if err := n.Send(ctx, notification("too late")); err == nil {
+			t.Error("Send succeeded with a cancelled context")
+		}
+		if got := delivered(); len(got) != 0 {
+			t.Errorf("delivered %q with a cancelled context", got)
+		}
+	})
+}
+
+// ReceiverHarness builds the receiver under test against fake backends.
+type ReceiverHarness struct {
+	// New returns a receiver whose backend serves texts once, in order,
+	// as messages from one chat, then nothing more.
+	New func(t *testing.T, texts []string, handler core.MessageHandler) core.Receiver
+	// NewFailing returns a receiver whose backend fails every request.
+	NewFailing func(t *testing.T, handler core.MessageHandler) core.Receiver
+}
+
+// TestReceiver runs the receiver conformance tests.
+func TestReceiver(t *testing.T, h ReceiverHarness) {
+	t.Run("OrderingAndSize", func(t *testing.T) {
+		texts := []string{"/first", "/second", "/third " + strings.Repeat("y", core.MaxTextLen-len("/third "))}
+		got := collect(t, h.New, texts, len(texts))
+		for i, want := range texts {
+			if i >= len(got) {
+				t.Fatalf("received %d messages, want %d", len(got), len(texts))
+			}
+			if got[i].Text != want {
+				t.Errorf("message %d = %.40q (%d characters), want %.40q (%d characters)", i, got[i].Text, len(got[i].Text), want, len(want))
+			}
+			if got[i].ChatID != got[0].ChatID {
+				t.Errorf("message %d from chat %d, want %d", i, got[i].ChatID, got[0].ChatID)
+			}
+		}
+		for i := 1; i < len(got); i++ {
+			if got[i].UpdateID <= got[i-1].UpdateID {
+				t.Errorf("update IDs not increasing: %d then %d", got[i-1].UpdateID, got[i].UpdateID)
+			}
+		}
+	})
+
+	t.Run("ContextCancellation", func(t *testing.T) {
+		r := h.New(t, nil, func(core.InboundMessage) {})
+		stop(t, r)
+	})
+
+	t.Run("KeepsRunningOnErrors", func(t *testing.T) {
+		var mu sync.Mutex
+		handled := 0
+		r := h.NewFailing(t, func(core.InboundMessage) {
+			mu.Lock()
+			handled++
+			mu.Unlock()
+		})
+		stop(t, r)
+		if handled != 0 {
+			t.Errorf("handled %d messages from a failing backend", handled)
+		}
+	})
+}
+
+// collect runs a receiver until want messages arrive, then stops it.
+func collect(t *testing.T, newReceiver func(*testing.T, []string, core.MessageHandler) core.Receiver, texts []string, want int) []core.InboundMessage {
+	t.Helper()
+	var mu sync.Mutex
+	var got []core.InboundMessage
+	done := make(chan struct{})
+	r := newReceiver(t, texts, func(msg core.InboundMessage) {
+		mu.Lock()
+		defer mu.Unlock()
+		got = append(got, msg)
+		if len(got) == want {
+			close(done)
+		}
+	})
+
+	ctx, cancel := context.WithCancel(context.Background())
+	stopped := make(chan error, 1)
+	go func() { stopped <- r.Start(ctx) }()
+	select {
+	case <-done:
+	case <-time.After(waitTimeout):
+		t.Errorf("timed out waiting for %d messages", want)
+	}
+	cancel()
+	if err := <-stopped; err != nil {
+		t.Errorf("Start returned %v after cancellation, want nil", err)
+	}
+
+	mu.Lock()
+	defer mu.Unlock()
+	return got
+}
+
+// stop starts r briefly and checks it returns nil promptly once its
+// context is cancelled.
+func stop(t *testing.T, r core.Receiver) {
+	t.Helper()
+	ctx, cancel := context.WithCancel(context.Background())
+	stopped := make(chan error, 1)
+	go func() { stopped <- r.Start(ctx) }()
+	time.Sleep(200 * time.Millisecond)
+	cancel()
+	select {
+	case err := <-stopped:
+		if err != nil {
+			t.Errorf("Start returned %v after cancellation, want nil", err)
+		}
+	case <-time.After(waitTimeout):
+		t.Fatal("Start did not return after its context was cancelled")
+	}
+}
+
+// within runs fn and fails the test if it takes longer than waitTimeout.
+func within(t *testing.T, name string, fn func() error) error {
+	t.Helper()
+	result := make(chan error, 1)
+	go func() { result <- fn() }()
+	select {
+	case err := <-result:
+		return err
+	case <-time.After(waitTimeout):
+		t.Fatalf("%s did not return within %s of its context expiring", name, waitTimeout)
+		return nil
+	}
+}
+
+func notification(text string) core.Notification {
+	return core.Notification{Text: text, Source: "adaptertest", CreatedAt: time.Now()}
+}
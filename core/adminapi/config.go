@@ -0,0 +1,47 @@
+package adminapi
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+
+	"github.com/jdelaire/openslack/core/localhttp"
+)
+
+// DefaultAddr is where the API listens unless configured.
+const DefaultAddr = "127.0.0.1:8788"
+
+// TokenAccount is the keychain account holding the API's access token.
+const TokenAccount = "admin-api-token"
+
+// Config sets where the API listens and which chat its op runs act as.
+// Addr must be a loopback address. ChatID decides which chat variables
+// and per-chat command limits apply to ops run through the API.
+type Config struct {
+	Addr   string `json:"addr"`
+	ChatID int64  `json:"chat_id"`
+}
+
+// LoadConfig reads and validates an admin API config file.
+// Returns nil, nil if the file does not exist.
+func LoadConfig(path string) (*Config, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("read admin api config: %w", err)
+	}
+
+	var cfg Config
+	if err := json.Unmarshal(data, &cfg); err != nil {
+		return nil, fmt.Errorf("parse admin api config: %w", err)
+	}
+	if cfg.Addr == "" {
+		cfg.Addr = DefaultAddr
+	}
+	if err := localhttp.CheckLoopback(cfg.Addr); err != nil {
+		return nil, fmt.Errorf("admin api %w", err)
+	}
+	return &cfg, nil
+}
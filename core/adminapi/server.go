@@ -0,0 +1,379 @@
+// Package adminapi serves a JSON REST API on localhost for the daemon's
+// management operations: listing and running ops, managing tasks, reading
+// the audit log and reloading config files. Scripts use it instead of
+// posing as a Telegram user.
+package adminapi
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/jdelaire/openslack/core"
+	"github.com/jdelaire/openslack/core/audit"
+	"github.com/jdelaire/openslack/core/connector"
+	"github.com/jdelaire/openslack/core/localhttp"
+	"github.com/jdelaire/openslack/core/ops"
+	"github.com/jdelaire/openslack/internal/tasks"
+)
+
+const (
+	// maxBody bounds request bodies.
+	maxBody = 64 << 10
+	// maxAuditLimit bounds how many op runs one request can list.
+	maxAuditLimit = 500
+)
+
+// AuditLog is the audit log the API reads. *audit.Log implements it.
+type AuditLog interface {
+	Run(ctx context.Context, name string, since time.Time) (audit.Result, error)
+	RecentOps(ctx context.Context, limit int) (audit.Result, error)
+}
+
+// Server serves the admin API. Requests must carry the access token and
+// name a loopback host; see localhttp.Guard. Routes for parts that are
+// not set with a With method answer 404.
+type Server struct {
+	cfg    *Config
+	token  string
+	logger *slog.Logger
+	now    func() time.Time
+
+	registry *ops.Registry
+	exec     core.OpExecutor
+	tasks    *tasks.TaskService
+	audit    AuditLog
+	reload   func() []string
+	manager  func() *connector.Manager
+
+	run localhttp.Runner
+}
+
+// NewServer returns an admin API server. A nil cfg listens on DefaultAddr
+// and runs ops as chat 0. The token must not be empty.
+func NewServer(cfg *Config, token string, logger *slog.Logger) (*Server, error) {
+	if cfg == nil {
+		cfg = &Config{Addr: DefaultAddr}
+	}
+	if err := localhttp.CheckLoopback(cfg.Addr); err != nil {
+		return nil, fmt.Errorf("admin api %w", err)
+	}
+	if token == "" {
+		return nil, errors.New("admin api: no access token")
+	}
+	if logger == nil {
+		logger = slog.Default()
+	}
+	return &Server{cfg: cfg, token: token, logger: logger, now: time.Now}, nil
+}
+
+// WithOps enables listing registry's ops and running them through exec,
+// with the same checks as scheduled commands: high-risk ops are refused.
+func (s *Server) WithOps(registry *ops.Registry, exec core.OpExecutor) *Server {
+	s.registry, s.exec = registry, exec
+	return s
+}
+
+// WithTasks enables the task routes.
+func (s *Server) WithTasks(svc *tasks.TaskService) *Server {
+	s.tasks = svc
+	return s
+}
+
+// WithAudit enables the audit routes.
+func (s *Server) WithAudit(log AuditLog) *Server {
+	s.audit = log
+	return s
+}
+
+// WithReload enables POST /v1/reload, which calls reload and reports the
+// config files it reloaded, e.g. configwatch.Watcher.ReloadAll.
+func (s *Server) WithReload(reload func() []string) *Server {
+	s.reload = reload
+	return s
+}
+
+// WithConnectors enables GET /v1/connectors. manager returns the current
+// connector manager, or nil if none are configured, as for
+// connector.ConnectorsOp.
+func (s *Server) WithConnectors(manager func() *connector.Manager) *Server {
+	s.manager = manager
+	return s
+}
+
+// WithClock replaces time.Now for audit query periods. A nil now is
+// ignored.
+func (s *Server) WithClock(now func() time.Time) *Server {
+	if now != nil {
+		s.now = now
+	}
+	return s
+}
+
+// Handler returns the API's routes.
+func (s *Server) Handler() http.Handler {
+	mux := http.NewServeMux()
+	if s.registry != nil && s.exec != nil {
+		mux.HandleFunc("GET /v1/ops", s.listOps)
+		mux.HandleFunc("POST /v1/ops/{name}", s.runOp)
+	}
+	if s.tasks != nil {
+		mux.HandleFunc("GET /v1/tasks", s.listTasks)
+		mux.HandleFunc("POST /v1/tasks", s.createTask)
+		mux.HandleFunc("POST /v1/tasks/{id}/done", s.completeTask)
+	}
+	if s.audit != nil {
+		mux.HandleFunc("GET /v1/audit/ops", s.recentOps)
+		mux.HandleFunc("GET /v1/audit/queries", s.listQueries)
+		mux.HandleFunc("GET /v1/audit/queries/{name}", s.runQuery)
+	}
+	if s.reload != nil {
+		mux.HandleFunc("POST /v1/reload", s.reloadConfigs)
+	}
+	if s.manager != nil {
+		mux.HandleFunc("GET /v1/connectors", s.listConnectors)
+	}
+	return localhttp.Guard(s.token, "OpenSlack admin", localhttp.RequireJSON(maxBody, mux))
+}
+
+// Start listens on the configured address and serves until ctx is
+// cancelled or Shutdown is called.
+func (s *Server) Start(ctx context.Context) error {
+	return s.run.Start(ctx, "admin api", s.cfg.Addr, s.Handler(), s.logger)
+}
+
+// Addr returns the address Start is listening on, or "" before Start.
+func (s *Server) Addr() string { return s.run.Addr() }
+
+// Shutdown stops serving, waiting up to 5s for in-flight requests.
+func (s *Server) Shutdown() { s.run.Shutdown() }
+
+type opInfo struct {
+	Name        string `json:"name"`
+	Description string `json:"description"`
+	Risk        string `json:"risk"`
+}
+
+func (s *Server) listOps(w http.ResponseWriter, _ *http.Request) {
+	list := []opInfo{}
+	for _, op := range s.registry.List() {
+		list = append(list, opInfo{Name: op.Name(), Description: op.Description(), Risk: s.registry.RiskOf(op).String()})
+	}
+	writeJSON(w, http.StatusOK, map[string]any{"ops": list})
+}
+
+func (s *Server) runOp(w http.ResponseWriter, r *http.Request) {
+	var req struct {
+		Args string `json:"args"`
+	}
+	if !decode(w, r, &req) {
+		return
+	}
+	name := r.PathValue("name")
+	op := s.registry.Get(name)
+	if op == nil {
+		writeError(w, http.StatusNotFound, fmt.Sprintf("unknown op %q", name))
+		return
+	}
+	if s.registry.RiskOfCall(op, req.Args) == ops.RiskHigh {
+		writeError(w, http.StatusForbidden, fmt.Sprintf("/%s needs approval and cannot run from the API", name))
+		return
+	}
+
+	s.logger.Info("admin api op", "op", name)
+	output, err := s.exec.Exec(r.Context(), s.cfg.ChatID, name, req.Args)
+	if err != nil {
+		writeJSON(w, http.StatusInternalServerError, map[string]string{"error": err.Error(), "output": output})
+		return
+	}
+	writeJSON(w, http.StatusOK, map[string]string{"output": output})
+}
+
+// listTasks lists open tasks, or with ?q= searches open, done and
+// archived tasks.
+func (s *Server) listTasks(w http.ResponseWriter, r *http.Request) {
+	var (
+		list []tasks.Task
+		err  error
+	)
+	if q := r.URL.Query().Get("q"); q != "" {
+		list, err = s.tasks.Find(q)
+	} else {
+		list, err = s.tasks.ListOpen()
+	}
+	if err != nil {
+		s.fail(w, "list tasks", err)
+		return
+	}
+	if list == nil {
+		list = []tasks.Task{}
+	}
+	writeJSON(w, http.StatusOK, map[string]any{"tasks": list})
+}
+
+func (s *Server) createTask(w http.ResponseWriter, r *http.Request) {
+	var req struct {
+		Text string `json:"text"`
+	}
+	if !decode(w, r, &req) {
+		return
+	}
+	task, err := s.tasks.CreateTomorrow(req.Text)
+	if errors.Is(err, tasks.ErrEmptyTaskText) {
+		writeError(w, http.StatusBadRequest, "text is required")
+		return
+	}
+	if err != nil {
+		s.fail(w, "create task", err)
+		return
+	}
+	writeJSON(w, http.StatusCreated, task)
+}
+
+func (s *Server) completeTask(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.Atoi(r.PathValue("id"))
+	if err != nil || id <= 0 {
+		writeError(w, http.StatusBadRequest, "task id must be a positive integer")
+		return
+	}
+	status, err := s.tasks.Complete(id)
+	if err != nil {
+		s.fail(w, "complete task", err)
+		return
+	}
+	switch status {
+	case tasks.CompleteUpdated:
+		writeJSON(w, http.StatusOK, map[string]string{"status": "done"})
+	case tasks.CompleteAlreadyDone:
+		writeJSON(w, http.StatusOK, map[string]string{"status": "already_done"})
+	default:
+		writeError(w, http.StatusNotFound, fmt.Sprintf("unknown task %d", id))
+	}
+}
+
+func (s *Server) recentOps(w http.ResponseWriter, r *http.Request) {
+	limit, ok := intParam(w, r, "limit", 50, 1, maxAuditLimit)
+	if !ok {
+		return
+	}
+	res, err := s.audit.RecentOps(r.Context(), limit)
+	if err != nil {
+		s.fail(w, "recent ops", err)
+		return
+	}
+	writeResult(w, res)
+}
+
+func (s *Server) listQueries(w http.ResponseWriter, _ *http.Request) {
+	type query struct {
+		Name        string   `json:"name"`
+		Description string   `json:"description"`
+		Columns     []string `json:"columns"`
+	}
+	list := make([]query, 0, len(audit.Queries))
+	for _, q := range audit.Queries {
+		list = append(list, query{q.Name, q.Description, q.Columns})
+	}
+	writeJSON(w, http.StatusOK, map[string]any{"queries": list})
+}
+
+func (s *Server) runQuery(w http.ResponseWriter, r *http.Request) {
+	name := r.PathValue("name")
+	if _, ok := audit.Lookup(name); !ok {
+		writeError(w, http.StatusNotFound, fmt.Sprintf("unknown query %q", name))
+		return
+	}
+	days, ok := intParam(w, r, "days", 7, 1, 90)
+	if !ok {
+		return
+	}
+	res, err := s.audit.Run(r.Context(), name, s.now().AddDate(0, 0, -days))
+	if err != nil {
+		s.fail(w, "audit query", err)
+		return
+	}
+	writeResult(w, res)
+}
+
+func (s *Server) reloadConfigs(w http.ResponseWriter, _ *http.Request) {
+	reloaded := s.reload()
+	if reloaded == nil {
+		reloaded = []string{}
+	}
+	s.logger.Info("admin api reload", "files", len(reloaded))
+	writeJSON(w, http.StatusOK, map[string]any{"reloaded": reloaded})
+}
+
+// ConnectorStatus is a connector as listed by GET /v1/connectors.
+type ConnectorStatus struct {
+	Name    string `json:"name"`
+	Running bool   `json:"running"`
+	Tools   int    `json:"tools"`
+}
+
+func (s *Server) listConnectors(w http.ResponseWriter, _ *http.Request) {
+	list := []ConnectorStatus{}
+	if mgr := s.manager(); mgr != nil {
+		for _, st := range mgr.Status() {
+			list = append(list, ConnectorStatus{Name: st.Name, Running: st.Running, Tools: st.Tools})
+		}
+	}
+	writeJSON(w, http.StatusOK, map[string]any{"connectors": list})
+}
+
+// fail logs err and answers 500.
+func (s *Server) fail(w http.ResponseWriter, what string, err error) {
+	s.logger.Error("admin api: "+what+" failed", "error", err)
+	writeError(w, http.StatusInternalServerError, err.Error())
+}
+
+// decode reads a JSON body into v, answering 400 if it cannot. An empty
+// body leaves v as is.
+func decode(w http.ResponseWriter, r *http.Request, v any) bool {
+	dec := json.NewDecoder(r.Body)
+	dec.DisallowUnknownFields()
+	if err := dec.Decode(v); err != nil && !errors.Is(err, io.EOF) {
+		writeError(w, http.StatusBadRequest, "invalid body: "+err.Error())
+		return false
+	}
+	return true
+}
+
+// intParam reads an integer query parameter between lo and hi, answering
+// 400 if it is not one.
+func intParam(w http.ResponseWriter, r *http.Request, name string, def, lo, hi int) (int, bool) {
+	text := r.URL.Query().Get(name)
+	if text == "" {
+		return def, true
+	}
+	n, err := strconv.Atoi(text)
+	if err != nil || n < lo || n > hi {
+		writeError(w, http.StatusBadRequest, fmt.Sprintf("%s must be between %d and %d", name, lo, hi))
+		return 0, false
+	}
+	return n, true
+}
+
+func writeResult(w http.ResponseWriter, res audit.Result) {
+	rows := res.Rows
+	if rows == nil {
+		rows = [][]string{}
+	}
+	writeJSON(w, http.StatusOK, map[string]any{"columns": res.Columns, "rows": rows})
+}
+
+func writeError(w http.ResponseWriter, code int, msg string) {
+	writeJSON(w, code, map[string]string{"error": msg})
+}
+
+func writeJSON(w http.ResponseWriter, code int, v any) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(code)
+	json.NewEncoder(w).Encode(v)
+}
@@ -0,0 +1,320 @@
+package adminapi
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/jdelaire/openslack/core/audit"
+	"github.com/jdelaire/openslack/core/connector"
+	"github.com/jdelaire/openslack/core/ops"
+	"github.com/jdelaire/openslack/internal/tasks"
+)
+
+type echoOp struct{ risk ops.RiskLevel }
+
+func (o *echoOp) Name() string                                           { return "echo" }
+func (o *echoOp) Description() string                                    { return "Echo args" }
+func (o *echoOp) Risk() ops.RiskLevel                                    { return o.risk }
+func (o *echoOp) Execute(_ context.Context, args string) (string, error) { return args, nil }
+
+type recordExec struct {
+	chatID int64
+	calls  []string
+	err    error
+}
+
+func (e *recordExec) Exec(_ context.Context, chatID int64, cmd, args string) (string, error) {
+	e.chatID = chatID
+	e.calls = append(e.calls, cmd+" "+args)
+	return "ran " + args, e.err
+}
+
+type fakeAudit struct{ since time.Time }
+
+func (a *fakeAudit) Run(_ context.Context, name string, since time.Time) (audit.Result, error) {
+	a.since = since
+	return audit.Result{Columns: []string{"Op", "Runs"}, Rows: [][]string{{"status", "3"}}}, nil
+}
+
+func (a *fakeAudit) RecentOps(_ context.Context, limit int) (audit.Result, error) {
+	return audit.Result{Columns: []string{"Op"}, Rows: [][]string{{strings.Repeat("x", limit)}}}, nil
+}
+
+var testNow = time.Date(2026, 3, 1, 12, 0, 0, 0, time.UTC)
+
+func newTestServer(t *testing.T) (*Server, *recordExec) {
+	t.Helper()
+	s, err := NewServer(&Config{Addr: DefaultAddr, ChatID: 42}, "secret", nil)
+	if err != nil {
+		t.Fatalf("new server: %v", err)
+	}
+	reg := ops.NewRegistry()
+	reg.Register(&echoOp{risk: ops.RiskLow})
+	exec := &recordExec{}
+	svc := tasks.NewTaskService(tasks.NewStore(filepath.Join(t.TempDir(), "tasks.json"))).
+		WithClock(func() time.Time { return testNow })
+	s.WithOps(reg, exec).WithTasks(svc).WithAudit(&fakeAudit{}).
+		WithReload(func() []string { return []string{"/cfg/commands.json"} }).
+		WithClock(func() time.Time { return testNow })
+	return s, exec
+}
+
+// call sends an authorized request and decodes the JSON reply into out.
+func call(t *testing.T, h http.Handler, method, target, body string, out any) int {
+	t.Helper()
+	req := httptest.NewRequest(method, target, strings.NewReader(body))
+	req.Host = "127.0.0.1:8788"
+	req.Header.Set("Authorization", "Bearer secret")
+	if method == http.MethodPost {
+		req.Header.Set("Content-Type", "application/json")
+	}
+	rec := httptest.NewRecorder()
+	h.ServeHTTP(rec, req)
+	if out != nil {
+		if err := json.Unmarshal(rec.Body.Bytes(), out); err != nil {
+			t.Fatalf("%s %s: decode %q: %v", method, target, rec.Body.String(), err)
+		}
+	}
+	return rec.Code
+}
+
+func TestOps(t *testing.T) {
+	s, exec := newTestServer(t)
+	h := s.Handler()
+
+	var list struct {
+		Ops []opInfo `json:"ops"`
+	}
+	if code := call(t, h, "GET", "/v1/ops", "", &list); code != http.StatusOK {
+		t.Fatalf("list status = %d", code)
+	}
+	if len(list.Ops) != 1 || list.Ops[0] != (opInfo{"echo", "Echo args", "low"}) {
+		t.Fatalf("ops = %+v", list.Ops)
+	}
+
+	var out map[string]string
+	if code := call(t, h, "POST", "/v1/ops/echo", `{"args": "hi"}`, &out); code != http.StatusOK {
+		t.Fatalf("run status = %d, %v", code, out)
+	}
+	if out["output"] != "ran hi" || exec.chatID != 42 {
+		t.Fatalf("output = %v, chat = %d", out, exec.chatID)
+	}
+
+	if code := call(t, h, "POST", "/v1/ops/nope", `{}`, &out); code != http.StatusNotFound {
+		t.Fatalf("unknown op status = %d", code)
+	}
+	if code := call(t, h, "POST", "/v1/ops/echo", `{"argz": 1}`, &out); code != http.StatusBadRequest {
+		t.Fatalf("bad body status = %d", code)
+	}
+
+	exec.err = errors.New("boom")
+	if code := call(t, h, "POST", "/v1/ops/echo", `{"args": "x"}`, &out); code != http.StatusInternalServerError || out["error"] != "boom" || out["output"] != "ran x" {
+		t.Fatalf("failing op = %d, %v", code, out)
+	}
+}
+
+func TestHighRiskOpRefused(t *testing.T) {
+	s, exec := newTestServer(t)
+	s.registry.SetRiskOverrides(map[string]ops.RiskLevel{"echo": ops.RiskHigh})
+
+	var out map[string]string
+	if code := call(t, s.Handler(), "POST", "/v1/ops/echo", `{}`, &out); code != http.StatusForbidden {
+		t.Fatalf("status = %d, %v", code, out)
+	}
+	if len(exec.calls) != 0 {
+		t.Fatalf("exec called: %v", exec.calls)
+	}
+}
+
+func TestTasks(t *testing.T) {
+	s, _ := newTestServer(t)
+	h := s.Handler()
+
+	var task tasks.Task
+	if code := call(t, h, "POST", "/v1/tasks", `{"text": "Buy eggs"}`, &task); code != http.StatusCreated {
+		t.Fatalf("create status = %d", code)
+	}
+	if task.ID != 1 || task.Text != "Buy eggs" || task.StartDate != "2026-03-02" {
+		t.Fatalf("task = %+v", task)
+	}
+	var errOut map[string]string
+	if code := call(t, h, "POST", "/v1/tasks", `{"text": " "}`, &errOut); code != http.StatusBadRequest {
+		t.Fatalf("empty create status = %d", code)
+	}
+
+	var list struct {
+		Tasks []tasks.Task `json:"tasks"`
+	}
+	if call(t, h, "GET", "/v1/tasks", "", &list); len(list.Tasks) != 1 {
+		t.Fatalf("open tasks = %+v", list.Tasks)
+	}
+
+	var status map[string]string
+	if code := call(t, h, "POST", "/v1/tasks/1/done", "", &status); code != http.StatusOK || status["status"] != "done" {
+		t.Fatalf("done = %d, %v", code, status)
+	}
+	if call(t, h, "POST", "/v1/tasks/1/done", "", &status); status["status"] != "already_done" {
+		t.Fatalf("done again = %v", status)
+	}
+	if code := call(t, h, "POST", "/v1/tasks/9/done", "", &status); code != http.StatusNotFound {
+		t.Fatalf("unknown task status = %d", code)
+	}
+	if code := call(t, h, "POST", "/v1/tasks/x/done", "", &status); code != http.StatusBadRequest {
+		t.Fatalf("bad id status = %d", code)
+	}
+
+	if call(t, h, "GET", "/v1/tasks", "", &list); len(list.Tasks) != 0 {
+		t.Fatalf("open tasks after done = %+v", list.Tasks)
+	}
+	if call(t, h, "GET", "/v1/tasks?q=EGGS", "", &list); len(list.Tasks) != 1 || list.Tasks[0].Status != tasks.TaskStatusDone {
+		t.Fatalf("search = %+v", list.Tasks)
+	}
+}
+
+func TestAudit(t *testing.T) {
+	s, _ := newTestServer(t)
+	h := s.Handler()
+
+	var res struct {
+		Columns []string   `json:"columns"`
+		Rows    [][]string `json:"rows"`
+	}
+	if code := call(t, h, "GET", "/v1/audit/queries/slowest?days=3", "", &res); code != http.StatusOK {
+		t.Fatalf("query status = %d", code)
+	}
+	if len(res.Rows) != 1 || res.Rows[0][0] != "status" {
+		t.Fatalf("query result = %+v", res)
+	}
+	if since := s.audit.(*fakeAudit).since; !since.Equal(testNow.AddDate(0, 0, -3)) {
+		t.Fatalf("since = %v", since)
+	}
+
+	var errOut map[string]string
+	if code := call(t, h, "GET", "/v1/audit/queries/drop", "", &errOut); code != http.StatusNotFound {
+		t.Fatalf("unknown query status = %d", code)
+	}
+	if code := call(t, h, "GET", "/v1/audit/queries/slowest?days=91", "", &errOut); code != http.StatusBadRequest {
+		t.Fatalf("bad days status = %d", code)
+	}
+
+	if code := call(t, h, "GET", "/v1/audit/ops?limit=4", "", &res); code != http.StatusOK || res.Rows[0][0] != "xxxx" {
+		t.Fatalf("recent ops = %d, %+v", code, res)
+	}
+
+	var queries struct {
+		Queries []struct{ Name string } `json:"queries"`
+	}
+	if call(t, h, "GET", "/v1/audit/queries", "", &queries); len(queries.Queries) != len(audit.Queries) {
+		t.Fatalf("queries = %+v", queries)
+	}
+}
+
+func TestReload(t *testing.T) {
+	s, _ := newTestServer(t)
+	var out struct {
+		Reloaded []string `json:"reloaded"`
+	}
+	if code := call(t, s.Handler(), "POST", "/v1/reload", "", &out); code != http.StatusOK {
+		t.Fatalf("status = %d", code)
+	}
+	if len(out.Reloaded) != 1 || out.Reloaded[0] != "/cfg/commands.json" {
+		t.Fatalf("reloaded = %v", out.Reloaded)
+	}
+}
+
+func TestRequestChecks(t *testing.T) {
+	s, exec := newTestServer(t)
+	h := s.Handler()
+
+	tests := []struct {
+		name    string
+		prepare func(*http.Request)
+		want    int
+	}{
+		{"no token", func(r *http.Request) { r.Header.Del("Authorization") }, http.StatusUnauthorized},
+		{"foreign host", func(r *http.Request) { r.Host = "attacker.example" }, http.StatusForbidden},
+		{"form post", func(r *http.Request) { r.Header.Set("Content-Type", "application/x-www-form-urlencoded") }, http.StatusUnsupportedMediaType},
+		{"no content type", func(r *http.Request) { r.Header.Del("Content-Type") }, http.StatusUnsupportedMediaType},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest("POST", "/v1/ops/echo", strings.NewReader(`{}`))
+			req.Host = "127.0.0.1:8788"
+			req.Header.Set("Authorization", "Bearer secret")
+			req.Header.Set("Content-Type", "application/json")
+			tt.prepare(req)
+			rec := httptest.NewRecorder()
+			h.ServeHTTP(rec, req)
+			if rec.Code != tt.want {
+				t.Fatalf("status = %d, want %d", rec.Code, tt.want)
+			}
+		})
+	}
+	if len(exec.calls) != 0 {
+		t.Fatalf("exec called: %v", exec.calls)
+	}
+}
+
+func TestDisabledRoutes(t *testing.T) {
+	s, err := NewServer(nil, "secret", nil)
+	if err != nil {
+		t.Fatalf("new server: %v", err)
+	}
+	req := httptest.NewRequest("GET", "/v1/tasks", nil)
+	req.Host = "localhost"
+	req.Header.Set("Authorization", "Bearer secret")
+	rec := httptest.NewRecorder()
+	s.Handler().ServeHTTP(rec, req)
+	if rec.Code != http.StatusNotFound {
+		t.Fatalf("status = %d, want 404", rec.Code)
+	}
+}
+
+func TestLoadConfig(t *testing.T) {
+	dir := t.TempDir()
+	if cfg, err := LoadConfig(filepath.Join(dir, "missing.json")); err != nil || cfg != nil {
+		t.Fatalf("missing = %v, %v", cfg, err)
+	}
+
+	path := filepath.Join(dir, "admin_api.json")
+	os.WriteFile(path, []byte(`{"chat_id": 7}`), 0o600)
+	cfg, err := LoadConfig(path)
+	if err != nil {
+		t.Fatalf("load: %v", err)
+	}
+	if *cfg != (Config{Addr: DefaultAddr, ChatID: 7}) {
+		t.Fatalf("config = %+v", *cfg)
+	}
+
+	os.WriteFile(path, []byte(`{"addr": "0.0.0.0:8788"}`), 0o600)
+	if _, err := LoadConfig(path); err == nil {
+		t.Fatal("non-loopback addr accepted")
+	}
+}
+
+func TestConnectors(t *testing.T) {
+	s, _ := newTestServer(t)
+	mgr := connector.NewManager(&connector.Config{Connectors: map[string]connector.ConnectorConfig{
+		"sample": {Exec: "/bin/false", Tools: []string{"echo", "time"}},
+	}}, slog.Default())
+	s.WithConnectors(func() *connector.Manager { return mgr })
+
+	var out struct {
+		Connectors []ConnectorStatus `json:"connectors"`
+	}
+	if code := call(t, s.Handler(), "GET", "/v1/connectors", "", &out); code != http.StatusOK {
+		t.Fatalf("status = %d", code)
+	}
+	if len(out.Connectors) != 1 || out.Connectors[0] != (ConnectorStatus{Name: "sample", Tools: 2}) {
+		t.Fatalf("connectors = %+v", out.Connectors)
+	}
+}
@@ -0,0 +1,220 @@
+// Package admission decides which work may start while the daemon is
+// under load. Work is classed by priority: background work such as
+// scheduled commands and feed checks is deferred when too many commands
+// are running or queued, or when they have been slow to finish, while
+// interactive commands and critical alerts are always admitted.
+package admission
+
+import (
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"os"
+	"sync"
+	"time"
+)
+
+// Defaults for a zero Config field.
+const (
+	DefaultMaxBusy      = 2
+	DefaultMaxLatencyMs = 10000
+)
+
+// latencyWindow is how far back command latencies count, and maxSamples
+// how many of them are kept.
+const (
+	latencyWindow = time.Minute
+	maxSamples    = 50
+)
+
+// Class is the priority of a piece of work.
+type Class int
+
+const (
+	Background  Class = iota // scheduled commands, feed and page checks
+	Interactive              // commands sent from a chat
+	Critical                 // alerts and notifications
+)
+
+func (c Class) String() string {
+	switch c {
+	case Background:
+		return "background"
+	case Interactive:
+		return "interactive"
+	case Critical:
+		return "critical"
+	}
+	return fmt.Sprintf("class(%d)", int(c))
+}
+
+// Config sets when the daemon counts as under load. MaxBusy is the number
+// of commands running or queued, and MaxLatencyMs the average time
+// commands took to finish over the last minute, at which background work
+// is deferred.
+type Config struct {
+	MaxBusy      int `json:"max_busy"`
+	MaxLatencyMs int `json:"max_latency_ms"`
+}
+
+// LoadConfig reads an admission config file.
+// Returns nil, nil if the file does not exist.
+func LoadConfig(path string) (*Config, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("read admission config: %w", err)
+	}
+
+	var cfg Config
+	if err := json.Unmarshal(data, &cfg); err != nil {
+		return nil, fmt.Errorf("parse admission config: %w", err)
+	}
+	if cfg.MaxBusy < 0 || cfg.MaxLatencyMs < 0 {
+		return nil, fmt.Errorf("admission config: limits must not be negative")
+	}
+	return &cfg, nil
+}
+
+func (c *Config) maxBusy() int {
+	if c == nil || c.MaxBusy == 0 {
+		return DefaultMaxBusy
+	}
+	return c.MaxBusy
+}
+
+func (c *Config) maxLatency() time.Duration {
+	if c == nil || c.MaxLatencyMs == 0 {
+		return DefaultMaxLatencyMs * time.Millisecond
+	}
+	return time.Duration(c.MaxLatencyMs) * time.Millisecond
+}
+
+// Controller tracks the commands in flight and how long recent ones took,
+// and admits work accordingly. A nil *Controller admits everything.
+type Controller struct {
+	cfg    *Config
+	logger *slog.Logger
+	now    func() time.Time
+
+	mu       sync.Mutex
+	busy     [Critical + 1]int
+	samples  []sample
+	shedding bool
+}
+
+type sample struct {
+	at   time.Time
+	took time.Duration
+}
+
+// Load is a snapshot of the controller's view of the daemon.
+type Load struct {
+	Busy     int           // commands running or queued
+	Latency  time.Duration // average over the last minute; 0 with none
+	Shedding bool          // whether background work is deferred
+}
+
+// New returns a controller with cfg's thresholds; a nil cfg uses the
+// defaults.
+func New(cfg *Config, logger *slog.Logger) *Controller {
+	if logger == nil {
+		logger = slog.Default()
+	}
+	return &Controller{cfg: cfg, logger: logger, now: time.Now}
+}
+
+// WithClock replaces time.Now for latency samples. A nil now is ignored.
+func (c *Controller) WithClock(now func() time.Time) *Controller {
+	if now != nil {
+		c.now = now
+	}
+	return c
+}
+
+// Begin records work of class cl entering the system, queued or running.
+// Call done when it finishes; the time between counts toward latency.
+func (c *Controller) Begin(cl Class) (done func()) {
+	if c == nil {
+		return func() {}
+	}
+	c.mu.Lock()
+	c.busy[cl]++
+	start := c.now()
+	c.mu.Unlock()
+
+	var once sync.Once
+	return func() {
+		once.Do(func() {
+			c.mu.Lock()
+			defer c.mu.Unlock()
+			c.busy[cl]--
+			now := c.now()
+			c.samples = append(c.samples, sample{at: now, took: now.Sub(start)})
+			if len(c.samples) > maxSamples {
+				c.samples = c.samples[len(c.samples)-maxSamples:]
+			}
+		})
+	}
+}
+
+// Admit reports whether work of class cl may start now. Only background
+// work is ever deferred.
+func (c *Controller) Admit(cl Class) bool {
+	if c == nil || cl != Background {
+		return true
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return !c.loadLocked().Shedding
+}
+
+// Gate returns a function reporting whether work of class cl may start
+// now, for runners that do not know about classes.
+func (c *Controller) Gate(cl Class) func() bool {
+	return func() bool { return c.Admit(cl) }
+}
+
+// Load returns the current load.
+func (c *Controller) Load() Load {
+	if c == nil {
+		return Load{}
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.loadLocked()
+}
+
+// loadLocked computes the load and logs when shedding starts or stops.
+// Callers hold c.mu.
+func (c *Controller) loadLocked() Load {
+	l := Load{}
+	for _, n := range c.busy {
+		l.Busy += n
+	}
+	cutoff := c.now().Add(-latencyWindow)
+	var total time.Duration
+	n := 0
+	for _, s := range c.samples {
+		if s.at.After(cutoff) {
+			total += s.took
+			n++
+		}
+	}
+	if n > 0 {
+		l.Latency = total / time.Duration(n)
+	}
+	l.Shedding = l.Busy >= c.cfg.maxBusy() || l.Latency >= c.cfg.maxLatency()
+
+	if l.Shedding != c.shedding {
+		c.shedding = l.Shedding
+		if l.Shedding {
+			c.logger.Warn("under load, deferring background work", "busy", l.Busy, "latency", l.Latency)
+		} else {
+			c.logger.Info("load eased, resuming background work", "busy", l.Busy, "latency", l.Latency)
+		}
+	}
+	return l
+}
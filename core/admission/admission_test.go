@@ -0,0 +1,77 @@
+package admission
+
+import (
+	"io"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestControllerShedsBackgroundWork(t *testing.T) {
+	now := time.Date(2026, 3, 1, 9, 0, 0, 0, time.UTC)
+	c := New(&Config{MaxBusy: 2, MaxLatencyMs: 5000}, slog.New(slog.NewTextHandler(io.Discard, nil))).
+		WithClock(func() time.Time { return now })
+
+	if !c.Admit(Background) {
+		t.Fatal("idle controller deferred background work")
+	}
+	first := c.Begin(Interactive)
+	second := c.Begin(Background)
+	if c.Admit(Background) || !c.Admit(Interactive) || !c.Admit(Critical) {
+		t.Errorf("with 2 busy: background %v, interactive %v, critical %v",
+			c.Admit(Background), c.Admit(Interactive), c.Admit(Critical))
+	}
+	first()
+	first() // a second call is ignored
+	if l := c.Load(); l.Busy != 1 || l.Shedding {
+		t.Errorf("after one finished: %+v", l)
+	}
+
+	// A slow command defers background work until it ages out.
+	now = now.Add(8 * time.Second)
+	second()
+	if l := c.Load(); l.Latency != 4*time.Second || l.Shedding {
+		t.Errorf("average 4s: %+v", l)
+	}
+	done := c.Begin(Interactive)
+	now = now.Add(10 * time.Second)
+	done()
+	if c.Admit(Background) {
+		t.Errorf("slow commands did not defer background work: %+v", c.Load())
+	}
+	now = now.Add(latencyWindow)
+	if !c.Admit(Background) {
+		t.Errorf("old samples still count: %+v", c.Load())
+	}
+
+	var nilC *Controller
+	nilC.Begin(Background)()
+	if !nilC.Admit(Background) || nilC.Load() != (Load{}) {
+		t.Error("nil controller does not admit everything")
+	}
+}
+
+func TestLoadConfig(t *testing.T) {
+	cfg, err := LoadConfig(filepath.Join(t.TempDir(), "missing.json"))
+	if cfg != nil || err != nil {
+		t.Fatalf("missing file: %v, %v", cfg, err)
+	}
+
+	path := filepath.Join(t.TempDir(), "admission.json")
+	os.WriteFile(path, []byte(`{"max_busy": 4}`), 0600)
+	cfg, err = LoadConfig(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if cfg.maxBusy() != 4 || cfg.maxLatency() != DefaultMaxLatencyMs*time.Millisecond {
+		t.Errorf("limits = %d, %s", cfg.maxBusy(), cfg.maxLatency())
+	}
+
+	os.WriteFile(path, []byte(`{"max_latency_ms": -1}`), 0600)
+	if _, err := LoadConfig(path); err == nil || !strings.Contains(err.Error(), "negative") {
+		t.Errorf("negative limit: %v", err)
+	}
+}
@@ -1,11 +1,16 @@
 package approval
 
 import (
+	"context"
 	"crypto/rand"
 	"encoding/hex"
 	"fmt"
+	"sort"
 	"sync"
 	"time"
+
+	"github.com/jdelaire/openslack/core/persist"
+	"github.com/jdelaire/openslack/core/redact"
 )
 
 const (
@@ -14,6 +19,21 @@ const (
 	maxPending = 100
 )
 
+const stateNamespace = "approvals"
+
+// sweepInterval is how often Run looks for lapsed approvals.
+const sweepInterval = 10 * time.Second
+
+// Approval is a pending two-step approval as listed by Pending. Its Args
+// have secrets masked.
+type Approval struct {
+	Nonce     string
+	ChatID    int64
+	OpName    string
+	Args      string
+	ExpiresAt time.Time
+}
+
 type pending struct {
 	chatID    int64
 	opName    string
@@ -21,11 +41,22 @@ type pending struct {
 	createdAt time.Time
 }
 
+// record is the persisted form of a pending approval.
+type record struct {
+	ChatID    int64     `json:"chat_id"`
+	OpName    string    `json:"op"`
+	Args      string    `json:"args"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
 // Store holds pending two-step approval requests.
 type Store struct {
 	mu      sync.Mutex
 	items   map[string]*pending
 	now     func() time.Time
+	kv      persist.KV
+	onExpire func(Approval)
+	redact   *redact.Redactor
 }
 
 // New creates an approval store.
@@ -36,6 +67,45 @@ func New() *Store {
 	}
 }
 
+// WithClock replaces time.Now for approval expiry. A nil now is ignored.
+func (s *Store) WithClock(now func() time.Time) *Store {
+	if now != nil {
+		s.now = now
+	}
+	return s
+}
+
+// WithRedactor sets the patterns used to mask secrets in listed args and
+// to keep approvals holding secrets out of kv. A nil r uses the defaults.
+func (s *Store) WithRedactor(r *redact.Redactor) *Store {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.redact = r
+	return s
+}
+
+// Open creates an approval store that keeps pending approvals in kv, so
+// they survive a restart. A nil kv behaves like New.
+func Open(kv persist.KV) (*Store, error) {
+	s := New()
+	if kv == nil {
+		return s, nil
+	}
+	keys, err := kv.Keys(stateNamespace)
+	if err != nil {
+		return nil, fmt.Errorf("load approvals: %w", err)
+	}
+	for _, nonce := range keys {
+		var r record
+		if _, err := kv.Get(stateNamespace, nonce, &r); err != nil {
+			return nil, fmt.Errorf("load approvals: %w", err)
+		}
+		s.items[nonce] = &pending{chatID: r.ChatID, opName: r.OpName, args: r.Args, createdAt: r.CreatedAt}
+	}
+	s.kv = kv
+	return s, nil
+}
+
 // Create registers a pending operation and returns a nonce.
 func (s *Store) Create(chatID int64, opName, args string) (string, error) {
 	s.mu.Lock()
@@ -52,15 +122,83 @@ func (s *Store) Create(chatID int64, opName, args string) (string, error) {
 		return "", fmt.Errorf("generate nonce: %w", err)
 	}
 
-	s.items[nonce] = &pending{
+	p := &pending{
 		chatID:    chatID,
 		opName:    opName,
 		args:      args,
 		createdAt: s.now(),
 	}
+	// Args holding a secret stay in memory only, and do not survive a
+	// restart; the approval lapses as if it had expired.
+	if s.kv != nil && s.redact.String(p.args) == p.args {
+		r := record{ChatID: p.chatID, OpName: p.opName, Args: p.args, CreatedAt: p.createdAt}
+		if err := s.kv.Put(stateNamespace, nonce, r); err != nil {
+			return "", fmt.Errorf("save approval: %w", err)
+		}
+	}
+	s.items[nonce] = p
 	return nonce, nil
 }
 
+// OnExpire calls fn for each approval that lapses unused. Calls are made
+// on their own goroutine.
+func (s *Store) OnExpire(fn func(Approval)) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.onExpire = fn
+}
+
+// Pending lists chatID's outstanding approvals, soonest to expire first.
+func (s *Store) Pending(chatID int64) []Approval {
+	return s.list(func(p *pending) bool { return p.chatID == chatID })
+}
+
+// All lists every chat's outstanding approvals, soonest to expire first.
+func (s *Store) All() []Approval {
+	return s.list(func(*pending) bool { return true })
+}
+
+func (s *Store) list(match func(*pending) bool) []Approval {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.pruneLocked()
+	var list []Approval
+	for nonce, p := range s.items {
+		if match(p) {
+			list = append(list, p.approval(nonce, s.redact))
+		}
+	}
+	sort.Slice(list, func(i, j int) bool {
+		if list[i].ExpiresAt.Equal(list[j].ExpiresAt) {
+			return list[i].Nonce < list[j].Nonce
+		}
+		return list[i].ExpiresAt.Before(list[j].ExpiresAt)
+	})
+	return list
+}
+
+// Run removes lapsed approvals as they expire, so OnExpire fires without
+// waiting for the next Create or Consume. It returns when ctx is cancelled.
+func (s *Store) Run(ctx context.Context) {
+	ticker := time.NewTicker(sweepInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			s.mu.Lock()
+			s.pruneLocked()
+			s.mu.Unlock()
+		}
+	}
+}
+
+func (p *pending) approval(nonce string, r *redact.Redactor) Approval {
+	return Approval{Nonce: nonce, ChatID: p.chatID, OpName: p.opName, Args: r.String(p.args), ExpiresAt: p.createdAt.Add(expiry)}
+}
+
 // Consume validates and removes a pending approval, returning the op and args.
 func (s *Store) Consume(nonce string, chatID int64) (opName, args string, err error) {
 	s.mu.Lock()
@@ -77,20 +215,46 @@ func (s *Store) Consume(nonce string, chatID int64) (opName, args string, err er
 		return "", "", fmt.Errorf("approval nonce belongs to a different chat")
 	}
 
-	delete(s.items, nonce)
+	if err := s.deleteLocked(nonce); err != nil {
+		return "", "", fmt.Errorf("consume approval: %w", err)
+	}
 	return p.opName, p.args, nil
 }
 
-// pruneLocked removes expired items. Must be called with mu held.
+// Cancel removes a pending approval without running it, returning its op.
+// The same chat that created it must cancel it.
+func (s *Store) Cancel(nonce string, chatID int64) (opName string, err error) {
+	opName, _, err = s.Consume(nonce, chatID)
+	return opName, err
+}
+
+// pruneLocked removes expired items, reporting each to onExpire. Must be
+// called with mu held.
 func (s *Store) pruneLocked() {
 	now := s.now()
 	for nonce, p := range s.items {
 		if now.Sub(p.createdAt) > expiry {
-			delete(s.items, nonce)
+			// A failed delete leaves an expired record in storage,
+			// which is pruned again after the next restart.
+			s.deleteLocked(nonce)
+			if s.onExpire != nil {
+				go s.onExpire(p.approval(nonce, s.redact))
+			}
 		}
 	}
 }
 
+// deleteLocked removes nonce from memory and storage. Must be called with
+// mu held.
+func (s *Store) deleteLocked(nonce string) error {
+	delete(s.items, nonce)
+	if s.kv == nil {
+		return nil
+	}
+	_, err := s.kv.Delete(stateNamespace, nonce)
+	return err
+}
+
 func generateNonce() (string, error) {
 	b := make([]byte, nonceBytes)
 	if _, err := rand.Read(b); err != nil {
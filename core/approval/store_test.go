@@ -1,9 +1,12 @@
 package approval
 
 import (
+	"path/filepath"
 	"strings"
 	"testing"
 	"time"
+
+	"github.com/jdelaire/openslack/internal/state"
 )
 
 func TestCreateAndConsume(t *testing.T) {
@@ -116,3 +119,135 @@ func TestExpiryFreesCapacity(t *testing.T) {
 		t.Errorf("Create after expiry = %v, want nil", err)
 	}
 }
+
+func TestOpenRestoresPendingApprovals(t *testing.T) {
+	kv := state.NewStore(filepath.Join(t.TempDir(), "state.json"))
+	s, err := Open(kv)
+	if err != nil {
+		t.Fatalf("Open: %v", err)
+	}
+	nonce, err := s.Create(100, "deploy", "prod")
+	if err != nil {
+		t.Fatalf("Create: %v", err)
+	}
+
+	restarted, err := Open(kv)
+	if err != nil {
+		t.Fatalf("reopen: %v", err)
+	}
+	opName, args, err := restarted.Consume(nonce, 100)
+	if err != nil || opName != "deploy" || args != "prod" {
+		t.Fatalf("Consume after restart = %q, %q, %v", opName, args, err)
+	}
+
+	again, _ := Open(kv)
+	if _, _, err := again.Consume(nonce, 100); err == nil {
+		t.Error("consumed nonce survived a restart")
+	}
+}
+
+func TestSecretArgsStayInMemory(t *testing.T) {
+	kv := state.NewStore(filepath.Join(t.TempDir(), "state.json"))
+	s, err := Open(kv)
+	if err != nil {
+		t.Fatalf("Open: %v", err)
+	}
+	nonce, err := s.Create(100, "db-login", "user=admin password=hunter2")
+	if err != nil {
+		t.Fatalf("Create: %v", err)
+	}
+	if got := s.Pending(100); len(got) != 1 || got[0].Args != "user=admin password=[redacted]" {
+		t.Errorf("Pending(100) = %+v, want the password masked", got)
+	}
+	if keys, _ := kv.Keys(stateNamespace); len(keys) != 0 {
+		t.Errorf("approval with a secret was persisted: %v", keys)
+	}
+	if _, args, err := s.Consume(nonce, 100); err != nil || args != "user=admin password=hunter2" {
+		t.Errorf("Consume = %q, %v, want the args as given", args, err)
+	}
+}
+
+func TestPendingListsChatApprovals(t *testing.T) {
+	now := time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)
+	s := New()
+	s.now = func() time.Time { return now }
+
+	first, _ := s.Create(100, "deploy", "prod")
+	now = now.Add(30 * time.Second)
+	second, _ := s.Create(100, "restart", "")
+	s.Create(200, "other", "")
+
+	got := s.Pending(100)
+	want := []Approval{
+		{Nonce: first, ChatID: 100, OpName: "deploy", Args: "prod", ExpiresAt: now.Add(expiry - 30*time.Second)},
+		{Nonce: second, ChatID: 100, OpName: "restart", ExpiresAt: now.Add(expiry)},
+	}
+	if len(got) != len(want) || got[0] != want[0] || got[1] != want[1] {
+		t.Fatalf("Pending(100) = %+v, want %+v", got, want)
+	}
+	if got := s.Pending(300); len(got) != 0 {
+		t.Errorf("Pending(300) = %+v, want none", got)
+	}
+}
+
+func TestAllListsEveryChat(t *testing.T) {
+	now := time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)
+	s := New()
+	s.now = func() time.Time { return now }
+
+	s.Create(200, "other", "")
+	now = now.Add(time.Second)
+	s.Create(100, "deploy", "prod")
+
+	got := s.All()
+	if len(got) != 2 || got[0].ChatID != 200 || got[1].ChatID != 100 {
+		t.Fatalf("All() = %+v", got)
+	}
+}
+
+func TestOnExpireReportsLapsedApprovals(t *testing.T) {
+	now := time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)
+	s := New()
+	s.now = func() time.Time { return now }
+	expired := make(chan Approval, 2)
+	s.OnExpire(func(a Approval) { expired <- a })
+
+	lapsed, _ := s.Create(100, "deploy", "prod")
+	used, _ := s.Create(100, "restart", "")
+	s.Consume(used, 100)
+
+	now = now.Add(expiry + time.Second)
+	if got := s.Pending(100); len(got) != 0 {
+		t.Fatalf("Pending after expiry = %+v", got)
+	}
+
+	select {
+	case a := <-expired:
+		if a.Nonce != lapsed || a.OpName != "deploy" || a.ChatID != 100 {
+			t.Errorf("expired = %+v, want %s for deploy", a, lapsed)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("OnExpire not called")
+	}
+	select {
+	case a := <-expired:
+		t.Errorf("consumed approval reported as expired: %+v", a)
+	case <-time.After(50 * time.Millisecond):
+	}
+}
+
+func TestCancel(t *testing.T) {
+	s := New()
+	nonce, _ := s.Create(100, "deploy", "prod")
+
+	if _, err := s.Cancel(nonce, 200); err == nil {
+		t.Error("Cancel from another chat succeeded")
+	}
+	opName, err := s.Cancel(nonce, 100)
+	if err != nil || opName != "deploy" {
+		t.Fatalf("Cancel = %q, %v, want deploy", opName, err)
+	}
+	if _, _, err := s.Consume(nonce, 100); err == nil {
+		t.Error("cancelled approval still consumable")
+	}
+}
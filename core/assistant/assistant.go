@@ -0,0 +1,217 @@
+package assistant
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/jdelaire/openslack/core/ops"
+)
+
+// opName is the name /ai is registered under; it is never offered to the
+// model.
+const opName = "ai"
+
+// maxCommandLen caps a proposed command line.
+const maxCommandLen = 1024
+
+// maxResponseBytes caps the endpoint's reply.
+const maxResponseBytes = 1 << 20
+
+// Entry describes one command to the model.
+type Entry struct {
+	Name        string `json:"name"`
+	Description string `json:"description"`
+	Usage       string `json:"usage,omitempty"`
+	Risk        string `json:"risk"`
+}
+
+// Proposal is what the model suggests for a request: a command line, or
+// none, with Explanation saying what the command does or why none fits.
+type Proposal struct {
+	Command     string `json:"command"`
+	Explanation string `json:"explanation"`
+}
+
+// Assistant asks the configured model for commands.
+type Assistant struct {
+	cfg      *Config
+	token    string
+	registry *ops.Registry
+	client   *http.Client
+	logger   *slog.Logger
+}
+
+// New creates an assistant for cfg. token is the endpoint's API key, or
+// empty for endpoints that need none.
+func New(cfg *Config, token string, registry *ops.Registry, logger *slog.Logger) *Assistant {
+	if logger == nil {
+		logger = slog.Default()
+	}
+	return &Assistant{
+		cfg:      cfg,
+		token:    token,
+		registry: registry,
+		client:   &http.Client{Timeout: time.Duration(cfg.TimeoutSec) * time.Second},
+		logger:   logger,
+	}
+}
+
+// Catalog lists the commands the model may propose for a call with ctx:
+// the registered ops its chat and bot may run, limited to the config's
+// ops, without /ai itself.
+func (a *Assistant) Catalog(ctx context.Context) []Entry {
+	chatID, hasChat := ops.ChatIDFrom(ctx)
+	var entries []Entry
+	for _, op := range a.registry.List() {
+		name := op.Name()
+		switch {
+		case name == opName,
+			len(a.cfg.Ops) > 0 && !slices.Contains(a.cfg.Ops, name),
+			hasChat && !ops.ChatAllowed(op, chatID),
+			!ops.BotAllowed(ctx, name):
+			continue
+		}
+		e := Entry{Name: name, Description: op.Description(), Risk: a.registry.RiskOf(op).String()}
+		if u, ok := op.(ops.Usager); ok {
+			e.Usage = strings.TrimSpace(strings.TrimPrefix(u.Usage(), "Usage:"))
+		}
+		entries = append(entries, e)
+	}
+	return entries
+}
+
+// Propose asks the model for a command for request. A proposed command is
+// checked to be one line naming a command in the catalog; the model's
+// word is not trusted for either.
+func (a *Assistant) Propose(ctx context.Context, request string) (Proposal, error) {
+	catalog := a.Catalog(ctx)
+	if len(catalog) == 0 {
+		return Proposal{}, errors.New("no commands are available to the assistant")
+	}
+	content, err := a.Complete(ctx, systemPrompt(catalog), request)
+	if err != nil {
+		return Proposal{}, err
+	}
+	p, err := parseProposal(content)
+	if err != nil {
+		return Proposal{}, err
+	}
+
+	p.Command = strings.TrimSpace(p.Command)
+	p.Explanation = strings.TrimSpace(p.Explanation)
+	if p.Command == "" {
+		return p, nil
+	}
+	if strings.ContainsAny(p.Command, "\r\n") || len(p.Command) > maxCommandLen || !strings.HasPrefix(p.Command, "/") {
+		return Proposal{}, fmt.Errorf("the model proposed something other than one command: %.80q", p.Command)
+	}
+	name, _, _ := strings.Cut(p.Command[1:], " ")
+	if !slices.ContainsFunc(catalog, func(e Entry) bool { return e.Name == name }) {
+		return Proposal{}, fmt.Errorf("the model proposed /%s, which is not an available command", name)
+	}
+	a.logger.Info("assistant proposed command", "op", name)
+	return p, nil
+}
+
+// systemPrompt tells the model what to do and which commands it has.
+func systemPrompt(catalog []Entry) string {
+	var b strings.Builder
+	b.WriteString("You turn requests into commands for OpenSlack, a bot that runs commands on its owner's machines.\n" +
+		`Reply with only a JSON object: {"command": "/name args", "explanation": "one sentence saying what it does"}.` + "\n" +
+		"Use only the commands listed below, with arguments as their usage shows. " +
+		"If none fits or the request is unclear, reply with an empty command and say why in explanation.\n" +
+		"Propose one command, never several. The user sees it and decides whether to run it; " +
+		"commands with risk low or high also need their approval.\n" +
+		"Commands, one JSON object per line:\n")
+	for _, e := range catalog {
+		line, _ := json.Marshal(e)
+		b.Write(line)
+		b.WriteByte('\n')
+	}
+	return b.String()
+}
+
+type message struct {
+	Role    string `json:"role"`
+	Content string `json:"content"`
+}
+
+type chatRequest struct {
+	Model       string    `json:"model"`
+	Messages    []message `json:"messages"`
+	Temperature float64   `json:"temperature"`
+}
+
+type chatResponse struct {
+	Choices []struct {
+		Message message `json:"message"`
+	} `json:"choices"`
+	Error *struct {
+		Message string `json:"message"`
+	} `json:"error"`
+}
+
+// Complete sends one chat completion request to the model and returns
+// its reply. Long output is summarized through it too.
+func (a *Assistant) Complete(ctx context.Context, system, user string) (string, error) {
+	body, err := json.Marshal(chatRequest{
+		Model:    a.cfg.Model,
+		Messages: []message{{Role: "system", Content: system}, {Role: "user", Content: user}},
+	})
+	if err != nil {
+		return "", fmt.Errorf("assistant request: %w", err)
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, a.cfg.Endpoint, bytes.NewReader(body))
+	if err != nil {
+		return "", fmt.Errorf("assistant request: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+	if a.token != "" {
+		req.Header.Set("Authorization", "Bearer "+a.token)
+	}
+
+	resp, err := a.client.Do(req)
+	if err != nil {
+		return "", fmt.Errorf("assistant request: %w", err)
+	}
+	defer resp.Body.Close()
+
+	var out chatResponse
+	decodeErr := json.NewDecoder(io.LimitReader(resp.Body, maxResponseBytes)).Decode(&out)
+	if resp.StatusCode != http.StatusOK {
+		if out.Error != nil && out.Error.Message != "" {
+			return "", fmt.Errorf("assistant endpoint status %d: %s", resp.StatusCode, out.Error.Message)
+		}
+		return "", fmt.Errorf("assistant endpoint status %d", resp.StatusCode)
+	}
+	if decodeErr != nil {
+		return "", fmt.Errorf("decode assistant response: %w", decodeErr)
+	}
+	if len(out.Choices) == 0 {
+		return "", errors.New("assistant response has no choices")
+	}
+	return out.Choices[0].Message.Content, nil
+}
+
+// parseProposal reads the JSON object in the model's reply, ignoring any
+// text or code fence around it.
+func parseProposal(content string) (Proposal, error) {
+	start, end := strings.IndexByte(content, '{'), strings.LastIndexByte(content, '}')
+	if start == -1 || end < start {
+		return Proposal{}, fmt.Errorf("the model did not reply with JSON: %.80q", content)
+	}
+	var p Proposal
+	if err := json.Unmarshal([]byte(content[start:end+1]), &p); err != nil {
+		return Proposal{}, fmt.Errorf("the model did not reply with JSON: %w", err)
+	}
+	return p, nil
+}
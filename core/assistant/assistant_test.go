@@ -0,0 +1,261 @@
+package assistant
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/jdelaire/openslack/core/ops"
+)
+
+type testOp struct {
+	name  string
+	risk  ops.RiskLevel
+	usage string
+	chats []int64
+}
+
+func (o *testOp) Name() string        { return o.name }
+func (o *testOp) Description() string { return "the " + o.name + " op" }
+func (o *testOp) Risk() ops.RiskLevel { return o.risk }
+func (o *testOp) Execute(context.Context, string) (string, error) {
+	return "ran " + o.name, nil
+}
+
+type usageTestOp struct{ testOp }
+
+func (o *usageTestOp) Usage() string { return o.usage }
+
+type restrictedOp struct{ testOp }
+
+func (o *restrictedOp) AllowsChat(chatID int64) bool {
+	for _, c := range o.chats {
+		if c == chatID {
+			return true
+		}
+	}
+	return false
+}
+
+func testRegistry(t *testing.T) *ops.Registry {
+	t.Helper()
+	reg := ops.NewRegistry()
+	for _, op := range []ops.Op{
+		&testOp{name: "status", risk: ops.RiskNone},
+		&usageTestOp{testOp{name: "backup", risk: ops.RiskLow, usage: "Usage:\n/backup\n/backup run <name>"}},
+		&testOp{name: "reboot", risk: ops.RiskHigh},
+		&restrictedOp{testOp{name: "secret", risk: ops.RiskNone, chats: []int64{7}}},
+		&Op{},
+	} {
+		if err := reg.Register(op); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return reg
+}
+
+// fakeModel serves chat completions, replying with content and keeping
+// the last request.
+type fakeModel struct {
+	content string
+	status  int
+	last    chatRequest
+	auth    string
+}
+
+func (f *fakeModel) serve(t *testing.T) string {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		f.auth = r.Header.Get("Authorization")
+		json.NewDecoder(r.Body).Decode(&f.last)
+		if f.status != 0 {
+			w.WriteHeader(f.status)
+			w.Write([]byte(`{"error": {"message": "model overloaded"}}`))
+			return
+		}
+		json.NewEncoder(w).Encode(map[string]any{
+			"choices": []map[string]any{{"message": map[string]string{"role": "assistant", "content": f.content}}},
+		})
+	}))
+	t.Cleanup(srv.Close)
+	return srv.URL
+}
+
+func newTestAssistant(t *testing.T, f *fakeModel, cfg Config) *Assistant {
+	cfg.Endpoint = f.serve(t)
+	cfg.Model = "test-model"
+	cfg.TimeoutSec = 5
+	return New(&cfg, "sk-test", testRegistry(t), slog.New(slog.NewTextHandler(io.Discard, nil)))
+}
+
+func TestPropose(t *testing.T) {
+	f := &fakeModel{content: "Sure!\n```json\n{\"command\": \"/backup run nas\", \"explanation\": \"Backs up the nas now.\"}\n```"}
+	a := newTestAssistant(t, f, Config{})
+
+	p, err := a.Propose(ops.WithChatID(context.Background(), 100), "back up the nas")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if p.Command != "/backup run nas" || p.Explanation != "Backs up the nas now." {
+		t.Errorf("proposal = %+v", p)
+	}
+
+	if f.auth != "Bearer sk-test" || f.last.Model != "test-model" || len(f.last.Messages) != 2 {
+		t.Fatalf("request = %+v with auth %q", f.last, f.auth)
+	}
+	if f.last.Messages[1].Content != "back up the nas" {
+		t.Errorf("user message = %q", f.last.Messages[1].Content)
+	}
+	system := f.last.Messages[0].Content
+	for _, want := range []string{
+		`{"name":"backup","description":"the backup op","usage":"/backup\n/backup run \u003cname\u003e","risk":"low"}`,
+		`"name":"status"`,
+		`"name":"reboot"`,
+	} {
+		if !strings.Contains(system, want) {
+			t.Errorf("system prompt lacks %s:\n%s", want, system)
+		}
+	}
+	for _, unwanted := range []string{`"name":"ai"`, `"name":"secret"`} {
+		if strings.Contains(system, unwanted) {
+			t.Errorf("system prompt offers %s", unwanted)
+		}
+	}
+}
+
+func TestProposeLimitsOps(t *testing.T) {
+	f := &fakeModel{content: `{"command": "/reboot", "explanation": "Reboots."}`}
+	a := newTestAssistant(t, f, Config{Ops: []string{"status", "backup"}})
+
+	_, err := a.Propose(context.Background(), "reboot it")
+	if err == nil || !strings.Contains(err.Error(), "/reboot, which is not an available command") {
+		t.Errorf("Propose = %v, want /reboot refused", err)
+	}
+	if strings.Contains(f.last.Messages[0].Content, "reboot") {
+		t.Error("system prompt offers /reboot although ops leaves it out")
+	}
+}
+
+func TestProposeRejects(t *testing.T) {
+	for _, tc := range []struct {
+		name, content, want string
+		status              int
+	}{
+		{"unknown op", `{"command": "/rm -rf /"}`, "not an available command", 0},
+		{"dispatcher command", `{"command": "/approve abc 123456"}`, "not an available command", 0},
+		{"two lines", `{"command": "/status\n/reboot"}`, "other than one command", 0},
+		{"no slash", `{"command": "status"}`, "other than one command", 0},
+		{"not JSON", `I would run /status.`, "did not reply with JSON", 0},
+		{"endpoint error", ``, "status 503: model overloaded", http.StatusServiceUnavailable},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			a := newTestAssistant(t, &fakeModel{content: tc.content, status: tc.status}, Config{})
+			_, err := a.Propose(context.Background(), "do it")
+			if err == nil || !strings.Contains(err.Error(), tc.want) {
+				t.Errorf("Propose = %v, want error containing %q", err, tc.want)
+			}
+		})
+	}
+}
+
+func TestOp(t *testing.T) {
+	run := func(t *testing.T, o *Op, ctx context.Context) (string, []ops.Button) {
+		t.Helper()
+		ctx, buttons := ops.WithReplyButtons(ops.WithChatID(ctx, 100))
+		out, err := o.Execute(ctx, "do the thing")
+		if err != nil {
+			t.Fatal(err)
+		}
+		return out, buttons()
+	}
+
+	for _, tc := range []struct {
+		name, command string
+		totp          bool
+		sudo          bool
+		want          string
+		button        bool
+	}{
+		{"risk none", "/status", true, false, "Press Run or send it to run it.", true},
+		{"low with TOTP", "/backup run nas", true, false, "Send it with your TOTP code at the end to run it.", false},
+		{"low in sudo", "/backup run nas", true, true, "Press Run or send it to run it.", true},
+		{"low without TOTP", "/backup run nas", false, false, "Press Run or send it to run it.", true},
+		{"high", "/reboot", true, false, "It is high-risk: send /do reboot <totp> to ask for approval.", false},
+		{"long", "/backup run " + strings.Repeat("n", 60), false, false, "Send it to run it.", false},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			content, _ := json.Marshal(Proposal{Command: tc.command, Explanation: "It does the thing."})
+			o := &Op{Assistant: newTestAssistant(t, &fakeModel{content: string(content)}, Config{}), TOTP: tc.totp}
+			ctx := context.Background()
+			if tc.sudo {
+				ctx = ops.WithSudo(ctx, time.Now().Add(time.Minute))
+			}
+			out, buttons := run(t, o, ctx)
+			want := "Suggested: " + tc.command + "\nIt does the thing.\n" + tc.want
+			if out != want {
+				t.Errorf("reply = %q, want %q", out, want)
+			}
+			if tc.button != (len(buttons) == 1) || tc.button && buttons[0] != (ops.Button{Text: "Run", Data: tc.command}) {
+				t.Errorf("buttons = %v, want a Run button: %v", buttons, tc.button)
+			}
+		})
+	}
+
+	t.Run("declined", func(t *testing.T) {
+		o := &Op{Assistant: newTestAssistant(t, &fakeModel{content: `{"command": "", "explanation": "Nothing can order pizza."}`}, Config{})}
+		out, buttons := run(t, o, context.Background())
+		if out != "No command fits: Nothing can order pizza." || len(buttons) != 0 {
+			t.Errorf("reply = %q with buttons %v", out, buttons)
+		}
+	})
+
+	t.Run("usage", func(t *testing.T) {
+		o := &Op{Assistant: newTestAssistant(t, &fakeModel{}, Config{})}
+		if out, _ := o.Execute(context.Background(), " "); out != aiUsage {
+			t.Errorf("reply = %q", out)
+		}
+	})
+}
+
+func TestLoadConfig(t *testing.T) {
+	dir := t.TempDir()
+	write := func(content string) string {
+		path := filepath.Join(dir, "assistant.json")
+		if err := os.WriteFile(path, []byte(content), 0o600); err != nil {
+			t.Fatal(err)
+		}
+		return path
+	}
+
+	cfg, err := LoadConfig(filepath.Join(dir, "missing.json"))
+	if cfg != nil || err != nil {
+		t.Fatalf("missing file = %v, %v; want nil, nil", cfg, err)
+	}
+
+	cfg, err = LoadConfig(write(`{"endpoint": "http://127.0.0.1:11434/v1/chat/completions", "model": "llama3.1"}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if cfg.TimeoutSec != DefaultTimeoutSec {
+		t.Errorf("timeout_sec = %d, want the default", cfg.TimeoutSec)
+	}
+
+	for _, bad := range []string{
+		`{"model": "m"}`,
+		`{"endpoint": "ftp://example.com", "model": "m"}`,
+		`{"endpoint": "https://example.com/v1/chat/completions"}`,
+		`{"endpoint": "https://example.com/v1/chat/completions", "model": "m", "ops": ["/status"]}`,
+		`{"endpoint": "https://example.com/v1/chat/completions", "model": "m", "timeout_sec": 301}`,
+	} {
+		if _, err := LoadConfig(write(bad)); err == nil {
+			t.Errorf("LoadConfig(%s) succeeded", bad)
+		}
+	}
+}
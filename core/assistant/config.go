@@ -0,0 +1,68 @@
+// Package assistant is /ai: it asks a language model to turn a request in
+// plain words into one of the registered commands, and offers that command
+// for the chat to send. The model only proposes. Nothing runs until the
+// chat sends the command, which then goes through the usual TOTP and
+// approval checks like any other.
+package assistant
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/url"
+	"os"
+	"strings"
+)
+
+// DefaultTimeoutSec bounds a call to the model when timeout_sec is unset.
+const DefaultTimeoutSec = 30
+
+// TokenAccount is the keychain account holding the endpoint's API key.
+// Endpoints that need none, such as a local model server, work without it.
+const TokenAccount = "assistant-token"
+
+// Config names the model endpoint. Endpoint is an OpenAI-compatible chat
+// completions URL, which OpenAI, Ollama, LM Studio, vLLM and most hosted
+// gateways serve. Ops, if set, limits the commands the model is told
+// about and may propose.
+type Config struct {
+	Endpoint   string   `json:"endpoint"`
+	Model      string   `json:"model"`
+	Ops        []string `json:"ops"`
+	TimeoutSec int      `json:"timeout_sec"`
+}
+
+// LoadConfig reads and validates an assistant config file.
+// Returns nil, nil if the file does not exist.
+func LoadConfig(path string) (*Config, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("read assistant config: %w", err)
+	}
+
+	var cfg Config
+	if err := json.Unmarshal(data, &cfg); err != nil {
+		return nil, fmt.Errorf("parse assistant config: %w", err)
+	}
+	u, err := url.Parse(cfg.Endpoint)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		return nil, fmt.Errorf("assistant config: endpoint must be an http or https URL, got %q", cfg.Endpoint)
+	}
+	if strings.TrimSpace(cfg.Model) == "" {
+		return nil, fmt.Errorf("assistant config: missing model")
+	}
+	for _, name := range cfg.Ops {
+		if name == "" || strings.ContainsAny(name, " /") {
+			return nil, fmt.Errorf("assistant config: bad op name %q in ops", name)
+		}
+	}
+	if cfg.TimeoutSec < 0 || cfg.TimeoutSec > 300 {
+		return nil, fmt.Errorf("assistant config: timeout_sec must be between 0 and 300")
+	}
+	if cfg.TimeoutSec == 0 {
+		cfg.TimeoutSec = DefaultTimeoutSec
+	}
+	return &cfg, nil
+}
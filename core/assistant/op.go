@@ -0,0 +1,81 @@
+package assistant
+
+import (
+	"context"
+	"strings"
+
+	"github.com/jdelaire/openslack/core/ops"
+)
+
+const aiUsage = "Usage: /ai <request>, e.g. /ai restart the nas backup"
+
+// maxButtonData is Telegram's limit on a button's data; longer commands
+// are offered as text only.
+const maxButtonData = 64
+
+// Op is /ai: it asks the assistant for a command and offers it, with a
+// Run button when pressing one is enough to run it. Commands that need a
+// TOTP code or approval are offered as text saying what to send.
+//
+//	/ai how full is the nas
+//	Suggested: /df nas
+//	Shows disk usage on the nas.
+//
+// Set TOTP when codes are set up, so low-risk commands are offered with
+// a code to add rather than a button that would be refused.
+type Op struct {
+	Assistant *Assistant
+	TOTP      bool
+}
+
+func (o *Op) Name() string        { return opName }
+func (o *Op) Description() string { return "Suggest a command for a request in plain words" }
+func (o *Op) Usage() string       { return aiUsage }
+func (o *Op) Risk() ops.RiskLevel { return ops.RiskNone }
+
+func (o *Op) Execute(ctx context.Context, args string) (string, error) {
+	request := strings.TrimSpace(args)
+	if request == "" {
+		return aiUsage, nil
+	}
+	p, err := o.Assistant.Propose(ctx, request)
+	if err != nil {
+		return "", err
+	}
+	if p.Command == "" {
+		if p.Explanation == "" {
+			return "No command fits that request.", nil
+		}
+		return "No command fits: " + p.Explanation, nil
+	}
+
+	lines := []string{"Suggested: " + p.Command}
+	if p.Explanation != "" {
+		lines = append(lines, p.Explanation)
+	}
+	return strings.Join(append(lines, o.howToRun(ctx, p.Command)), "\n"), nil
+}
+
+// howToRun says how to run command, attaching a Run button when pressing
+// it gets past the checks the command is under.
+func (o *Op) howToRun(ctx context.Context, command string) string {
+	name, args, _ := strings.Cut(command[1:], " ")
+	reg := o.Assistant.registry
+	risk := ops.RiskLow
+	if op := reg.Get(name); op != nil {
+		risk = reg.RiskOfCall(op, args)
+	}
+	chatID, _ := ops.ChatIDFrom(ctx)
+	_, sudo := ops.SudoFrom(ctx)
+
+	switch {
+	case o.TOTP && risk == ops.RiskHigh:
+		return "It is high-risk: send /do " + strings.TrimSpace(name+" "+args) + " <totp> to ask for approval."
+	case o.TOTP && risk == ops.RiskLow && !sudo && !reg.TOTPExempt(chatID, name):
+		return "Send it with your TOTP code at the end to run it."
+	case len(command) > maxButtonData:
+		return "Send it to run it."
+	}
+	ops.AddButton(ctx, "Run", command)
+	return "Press Run or send it to run it."
+}
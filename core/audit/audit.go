@@ -0,0 +1,213 @@
+// Package audit records op runs and notification deliveries in SQL, and
+// answers a fixed set of read-only queries over them.
+package audit
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/jdelaire/openslack/core/redact"
+)
+
+// retention is how long records are kept.
+const retention = 90 * 24 * time.Hour
+
+const schema = `
+CREATE TABLE IF NOT EXISTS audit_ops (
+	at      INTEGER NOT NULL,
+	chat_id INTEGER NOT NULL,
+	op      TEXT NOT NULL,
+	took_ms INTEGER NOT NULL,
+	ok      INTEGER NOT NULL,
+	error   TEXT NOT NULL
+);
+CREATE INDEX IF NOT EXISTS audit_ops_at ON audit_ops (at);
+CREATE TABLE IF NOT EXISTS audit_notifications (
+	at     INTEGER NOT NULL,
+	source TEXT NOT NULL,
+	ok     INTEGER NOT NULL,
+	error  TEXT NOT NULL
+);
+CREATE INDEX IF NOT EXISTS audit_notifications_at ON audit_notifications (at);`
+
+// Query is a canned read-only query. SQL takes the start of the period,
+// in unix milliseconds, as its only parameter.
+type Query struct {
+	Name        string
+	Description string
+	Columns     []string
+	SQL         string
+}
+
+// Queries are the queries Run accepts.
+var Queries = []Query{
+	{
+		Name:        "slowest",
+		Description: "Slowest ops",
+		Columns:     []string{"Op", "Runs", "Max ms", "Avg ms"},
+		SQL: `SELECT op, COUNT(*), MAX(took_ms), CAST(AVG(took_ms) AS INTEGER) FROM audit_ops
+			WHERE at >= ? GROUP BY op ORDER BY MAX(took_ms) DESC LIMIT 10`,
+	},
+	{
+		Name:        "failures",
+		Description: "Failure counts by op",
+		Columns:     []string{"Op", "Failed", "Runs"},
+		SQL: `SELECT op, SUM(1 - ok), COUNT(*) FROM audit_ops
+			WHERE at >= ? GROUP BY op HAVING SUM(1 - ok) > 0 ORDER BY SUM(1 - ok) DESC LIMIT 10`,
+	},
+	{
+		Name:        "sources",
+		Description: "Busiest notification sources",
+		Columns:     []string{"Source", "Sent", "Failed"},
+		SQL: `SELECT source, COUNT(*), SUM(1 - ok) FROM audit_notifications
+			WHERE at >= ? GROUP BY source ORDER BY COUNT(*) DESC LIMIT 10`,
+	},
+}
+
+// Result is the outcome of a query, one string per cell.
+type Result struct {
+	Columns []string
+	Rows    [][]string
+}
+
+// Log records to a SQL database. Recording is best-effort: a failed write
+// is dropped rather than failing the op or notification it describes.
+type Log struct {
+	db     *sql.DB
+	now    func() time.Time
+	redact *redact.Redactor
+
+	mu       sync.Mutex
+	prunedAt time.Time
+}
+
+// Open creates the audit tables in db if needed.
+func Open(db *sql.DB) (*Log, error) {
+	if _, err := db.Exec(schema); err != nil {
+		return nil, fmt.Errorf("create audit tables: %w", err)
+	}
+	return &Log{db: db, now: time.Now}, nil
+}
+
+// WithRedactor sets the patterns used to mask secrets in recorded error
+// text. A nil r uses the defaults.
+func (l *Log) WithRedactor(r *redact.Redactor) *Log {
+	l.redact = r
+	return l
+}
+
+// RecordOp records a finished op run.
+func (l *Log) RecordOp(chatID int64, op string, took time.Duration, err error) {
+	l.prune()
+	l.db.Exec(`INSERT INTO audit_ops (at, chat_id, op, took_ms, ok, error) VALUES (?, ?, ?, ?, ?, ?)`,
+		l.now().UnixMilli(), chatID, op, took.Milliseconds(), ok(err), l.redact.Error(err))
+}
+
+// RecordNotification records a notification delivery attempt.
+func (l *Log) RecordNotification(source string, err error) {
+	l.prune()
+	l.db.Exec(`INSERT INTO audit_notifications (at, source, ok, error) VALUES (?, ?, ?, ?)`,
+		l.now().UnixMilli(), source, ok(err), l.redact.Error(err))
+}
+
+// Run runs the named query over records since the given time.
+func (l *Log) Run(ctx context.Context, name string, since time.Time) (Result, error) {
+	q, found := Lookup(name)
+	if !found {
+		return Result{}, fmt.Errorf("unknown query %q", name)
+	}
+	rows, err := l.db.QueryContext(ctx, q.SQL, since.UnixMilli())
+	if err != nil {
+		return Result{}, fmt.Errorf("query %s: %w", name, err)
+	}
+	defer rows.Close()
+
+	res := Result{Columns: q.Columns}
+	for rows.Next() {
+		cells := make([]string, len(q.Columns))
+		dest := make([]any, len(cells))
+		for i := range cells {
+			dest[i] = &cells[i]
+		}
+		if err := rows.Scan(dest...); err != nil {
+			return Result{}, fmt.Errorf("query %s: %w", name, err)
+		}
+		res.Rows = append(res.Rows, cells)
+	}
+	if err := rows.Err(); err != nil {
+		return Result{}, fmt.Errorf("query %s: %w", name, err)
+	}
+	return res, nil
+}
+
+// RecentOps returns the latest limit op runs, newest first, with their
+// time, chat, op, duration and outcome: "ok" or the error.
+func (l *Log) RecentOps(ctx context.Context, limit int) (Result, error) {
+	rows, err := l.db.QueryContext(ctx, `SELECT at, chat_id, op, took_ms, ok, error FROM audit_ops
+		ORDER BY at DESC, rowid DESC LIMIT ?`, limit)
+	if err != nil {
+		return Result{}, fmt.Errorf("recent ops: %w", err)
+	}
+	defer rows.Close()
+
+	res := Result{Columns: []string{"Time", "Chat", "Op", "Took ms", "Result"}}
+	for rows.Next() {
+		var (
+			at, chatID, took int64
+			op, errText      string
+			succeeded        bool
+		)
+		if err := rows.Scan(&at, &chatID, &op, &took, &succeeded, &errText); err != nil {
+			return Result{}, fmt.Errorf("recent ops: %w", err)
+		}
+		result := "ok"
+		if !succeeded {
+			result = errText
+		}
+		res.Rows = append(res.Rows, []string{
+			time.UnixMilli(at).Local().Format(time.DateTime),
+			fmt.Sprint(chatID), op, fmt.Sprint(took), result,
+		})
+	}
+	if err := rows.Err(); err != nil {
+		return Result{}, fmt.Errorf("recent ops: %w", err)
+	}
+	return res, nil
+}
+
+// Lookup returns the canned query called name.
+func Lookup(name string) (Query, bool) {
+	for _, q := range Queries {
+		if q.Name == name {
+			return q, true
+		}
+	}
+	return Query{}, false
+}
+
+// prune deletes records older than retention, at most once a day.
+func (l *Log) prune() {
+	l.mu.Lock()
+	now := l.now()
+	due := now.Sub(l.prunedAt) >= 24*time.Hour
+	if due {
+		l.prunedAt = now
+	}
+	l.mu.Unlock()
+	if !due {
+		return
+	}
+	cutoff := now.Add(-retention).UnixMilli()
+	l.db.Exec(`DELETE FROM audit_ops WHERE at < ?`, cutoff)
+	l.db.Exec(`DELETE FROM audit_notifications WHERE at < ?`, cutoff)
+}
+
+func ok(err error) int {
+	if err != nil {
+		return 0
+	}
+	return 1
+}
@@ -0,0 +1,132 @@
+package auth
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"sync"
+
+	"github.com/jdelaire/openslack/core/persist"
+)
+
+const (
+	hotpNamespace = "hotp"
+
+	// resyncWindow is how far past the look-ahead window a code may be
+	// and still start resynchronization.
+	resyncWindow = 100
+)
+
+// HOTP implements RFC 4226 counter-based one-time passwords, for tokens
+// without a reliable clock. Each accepted code moves the counter past it,
+// so a code works once. A code up to Window ahead of the counter is
+// accepted; one further ahead is only accepted when followed by the code
+// right after it (RFC 4226 section 7.4).
+type HOTP struct {
+	secret []byte
+	params Params
+	kv     persist.KV
+	key    string
+
+	mu      sync.Mutex
+	counter int64 // next counter a code may use
+	resync  int64 // counter matched beyond the window, or -1
+}
+
+// NewHOTP creates an HOTP verifier that keeps its counter in kv, so codes
+// cannot be replayed after a restart. A nil kv keeps it in memory.
+func NewHOTP(base32Secret string, p Params, kv persist.KV) (*HOTP, error) {
+	if err := p.Validate(); err != nil {
+		return nil, err
+	}
+	if p.Mode != ModeHOTP {
+		return nil, fmt.Errorf("mode %q is not counter-based", p.Mode)
+	}
+	secret, err := decodeSecret(base32Secret)
+	if err != nil {
+		return nil, err
+	}
+	// Key the counter by secret so enrolling a new token starts over.
+	sum := sha256.Sum256(secret)
+	h := &HOTP{secret: secret, params: p, kv: kv, key: hex.EncodeToString(sum[:8]), resync: -1}
+	if kv != nil {
+		if _, err := kv.Get(hotpNamespace, h.key, &h.counter); err != nil {
+			return nil, fmt.Errorf("load hotp counter: %w", err)
+		}
+	}
+	return h, nil
+}
+
+// Verifier is a TOTP or HOTP code checker.
+type Verifier interface {
+	Verify(code string) bool
+	Params() Params
+}
+
+// NewVerifier creates a TOTP or HOTP verifier as p.Mode selects. kv holds
+// the HOTP counter and is unused for TOTP.
+func NewVerifier(base32Secret string, p Params, kv persist.KV) (Verifier, error) {
+	if p.Mode == ModeHOTP {
+		h, err := NewHOTP(base32Secret, p, kv)
+		if err != nil {
+			return nil, err
+		}
+		return h, nil
+	}
+	t, err := NewWithParams(base32Secret, p)
+	if err != nil {
+		return nil, err
+	}
+	return t, nil
+}
+
+// Params returns the parameters codes are checked with.
+func (h *HOTP) Params() Params {
+	return h.params
+}
+
+// Counter returns the next counter value a code may use.
+func (h *HOTP) Counter() int64 {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	return h.counter
+}
+
+// Verify checks code against the look-ahead window and advances the
+// counter past it. Failing to save the counter does not reject the code;
+// it stays used until the next restart.
+func (h *HOTP) Verify(code string) bool {
+	if len(code) != h.params.Digits {
+		return false
+	}
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	for c := h.counter; c < h.counter+int64(h.params.Window); c++ {
+		if constantTimeEqual(code, generate(h.params, h.secret, c)) {
+			h.advance(c + 1)
+			return true
+		}
+	}
+
+	if h.resync >= 0 && constantTimeEqual(code, generate(h.params, h.secret, h.resync+1)) {
+		h.advance(h.resync + 2)
+		return true
+	}
+	h.resync = -1
+	end := h.counter + int64(h.params.Window) + resyncWindow
+	for c := h.counter + int64(h.params.Window); c < end; c++ {
+		if constantTimeEqual(code, generate(h.params, h.secret, c)) {
+			h.resync = c
+			break
+		}
+	}
+	return false
+}
+
+func (h *HOTP) advance(next int64) {
+	h.counter, h.resync = next, -1
+	if h.kv != nil {
+		h.kv.Put(hotpNamespace, h.key, h.counter)
+	}
+}
@@ -0,0 +1,111 @@
+package auth
+
+import (
+	"path/filepath"
+	"testing"
+
+	"github.com/jdelaire/openslack/internal/state"
+)
+
+var hotpParams = Params{Mode: ModeHOTP, Algorithm: "SHA1", Digits: 6, Window: 5}
+
+// TestHOTPRFC4226Vectors checks the test values from RFC 4226 appendix D.
+func TestHOTPRFC4226Vectors(t *testing.T) {
+	secret := []byte("12345678901234567890")
+	want := []string{"755224", "287082", "359152", "969429", "338314", "254676", "287922", "162583", "399871", "520489"}
+	for i, w := range want {
+		if got := generate(hotpParams, secret, int64(i)); got != w {
+			t.Errorf("counter %d = %s, want %s", i, got, w)
+		}
+	}
+}
+
+func TestHOTPVerifyAdvancesCounter(t *testing.T) {
+	h, err := NewHOTP(testSecret, hotpParams, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	code := generate(hotpParams, h.secret, 2)
+	if !h.Verify(code) {
+		t.Fatal("code inside the window rejected")
+	}
+	if h.Counter() != 3 {
+		t.Errorf("counter = %d, want 3", h.Counter())
+	}
+	if h.Verify(code) {
+		t.Error("code accepted twice")
+	}
+	if h.Verify(generate(hotpParams, h.secret, 1)) {
+		t.Error("code behind the counter accepted")
+	}
+}
+
+func TestHOTPResync(t *testing.T) {
+	h, err := NewHOTP(testSecret, hotpParams, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if h.Verify(generate(hotpParams, h.secret, 40)) {
+		t.Fatal("code beyond the window accepted alone")
+	}
+	if h.Verify(generate(hotpParams, h.secret, 42)) {
+		t.Fatal("non-consecutive code completed resync")
+	}
+	if !h.Verify(generate(hotpParams, h.secret, 43)) {
+		t.Fatal("code after the last far-ahead code rejected")
+	}
+	if h.Counter() != 44 {
+		t.Errorf("counter = %d, want 44", h.Counter())
+	}
+	if h.Verify(generate(hotpParams, h.secret, 42)) {
+		t.Error("code before the resynced counter accepted")
+	}
+}
+
+func TestHOTPCounterPersists(t *testing.T) {
+	kv := state.NewStore(filepath.Join(t.TempDir(), "state.json"))
+	h, err := NewHOTP(testSecret, hotpParams, kv)
+	if err != nil {
+		t.Fatal(err)
+	}
+	code := generate(hotpParams, h.secret, 0)
+	if !h.Verify(code) {
+		t.Fatal("first code rejected")
+	}
+
+	h, err = NewHOTP(testSecret, hotpParams, kv)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if h.Counter() != 1 || h.Verify(code) {
+		t.Errorf("counter = %d after restart, code replayable: %v", h.Counter(), h.Verify(code))
+	}
+
+	other, err := NewHOTP("GEZDGNBVGY3TQOJQ", hotpParams, kv)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if other.Counter() != 0 {
+		t.Errorf("new secret counter = %d, want 0", other.Counter())
+	}
+}
+
+func TestNewVerifierMode(t *testing.T) {
+	v, err := NewVerifier(testSecret, hotpParams, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := v.(*HOTP); !ok {
+		t.Errorf("hotp mode built %T", v)
+	}
+	v, err = NewVerifier(testSecret, DefaultParams, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := v.(*TOTP); !ok {
+		t.Errorf("totp mode built %T", v)
+	}
+	if _, err := NewWithParams(testSecret, hotpParams); err == nil {
+		t.Error("NewWithParams accepted hotp mode")
+	}
+}
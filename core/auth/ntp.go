@@ -0,0 +1,102 @@
+package auth
+
+import (
+	"context"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"net"
+	"sync"
+	"time"
+)
+
+// DefaultNTPServer is queried when no server is configured.
+const DefaultNTPServer = "pool.ntp.org:123"
+
+// ntpEpochOffset is the number of seconds from 1900 to 1970.
+const ntpEpochOffset = 2208988800
+
+// ClockOffset asks an NTP server how far the local clock is off, using a
+// single SNTP exchange. A positive offset means the local clock is behind.
+func ClockOffset(ctx context.Context, server string) (time.Duration, error) {
+	var d net.Dialer
+	conn, err := d.DialContext(ctx, "udp", server)
+	if err != nil {
+		return 0, fmt.Errorf("dial ntp: %w", err)
+	}
+	defer conn.Close()
+	deadline, ok := ctx.Deadline()
+	if !ok {
+		deadline = time.Now().Add(5 * time.Second)
+	}
+	conn.SetDeadline(deadline)
+
+	req := make([]byte, 48)
+	req[0] = 0x23 // LI 0, version 4, mode 3 (client)
+	t1 := time.Now()
+	if _, err := conn.Write(req); err != nil {
+		return 0, fmt.Errorf("send ntp request: %w", err)
+	}
+	resp := make([]byte, 48)
+	n, err := conn.Read(resp)
+	if err != nil {
+		return 0, fmt.Errorf("read ntp response: %w", err)
+	}
+	t4 := time.Now()
+	if n < 48 || resp[0]&0x07 != 4 {
+		return 0, errors.New("invalid ntp response")
+	}
+	if resp[1] == 0 {
+		return 0, errors.New("ntp server sent kiss-of-death")
+	}
+
+	t2 := ntpTime(resp[32:40])
+	t3 := ntpTime(resp[40:48])
+	return (t2.Sub(t1) + t3.Sub(t4)) / 2, nil
+}
+
+// ntpTime decodes a 64-bit NTP timestamp.
+func ntpTime(b []byte) time.Time {
+	secs := int64(binary.BigEndian.Uint32(b[:4])) - ntpEpochOffset
+	frac := int64(binary.BigEndian.Uint32(b[4:]))
+	return time.Unix(secs, frac*1e9>>32)
+}
+
+// skewCacheTTL is how long SkewChecker reuses an NTP answer, so repeated
+// bad codes do not turn into a stream of NTP queries.
+const skewCacheTTL = 10 * time.Minute
+
+// SkewChecker measures local clock skew against an NTP server, caching the
+// result for skewCacheTTL.
+type SkewChecker struct {
+	server string
+	query  func(ctx context.Context, server string) (time.Duration, error)
+	now    func() time.Time
+
+	mu      sync.Mutex
+	checked time.Time
+	offset  time.Duration
+	err     error
+}
+
+// NewSkewChecker creates a SkewChecker for server. An empty server uses
+// DefaultNTPServer.
+func NewSkewChecker(server string) *SkewChecker {
+	if server == "" {
+		server = DefaultNTPServer
+	}
+	return &SkewChecker{server: server, query: ClockOffset, now: time.Now}
+}
+
+// Skew returns the clock offset as ClockOffset does, reusing a recent
+// answer or error.
+func (c *SkewChecker) Skew(ctx context.Context) (time.Duration, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if !c.checked.IsZero() && c.now().Sub(c.checked) < skewCacheTTL {
+		return c.offset, c.err
+	}
+	c.offset, c.err = c.query(ctx, c.server)
+	c.checked = c.now()
+	return c.offset, c.err
+}
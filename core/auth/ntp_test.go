@@ -0,0 +1,77 @@
+package auth
+
+import (
+	"context"
+	"encoding/binary"
+	"net"
+	"testing"
+	"time"
+)
+
+// serveNTP answers one SNTP request with the local time shifted by skew.
+func serveNTP(t *testing.T, skew time.Duration) string {
+	t.Helper()
+	pc, err := net.ListenPacket("udp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { pc.Close() })
+	go func() {
+		buf := make([]byte, 48)
+		_, addr, err := pc.ReadFrom(buf)
+		if err != nil {
+			return
+		}
+		resp := make([]byte, 48)
+		resp[0] = 0x24 // version 4, mode 4 (server)
+		resp[1] = 2
+		now := time.Now().Add(skew)
+		secs := uint32(now.Unix() + ntpEpochOffset)
+		frac := uint32((int64(now.Nanosecond()) << 32) / 1e9)
+		for _, off := range []int{32, 40} {
+			binary.BigEndian.PutUint32(resp[off:], secs)
+			binary.BigEndian.PutUint32(resp[off+4:], frac)
+		}
+		pc.WriteTo(resp, addr)
+	}()
+	return pc.LocalAddr().String()
+}
+
+func TestClockOffset(t *testing.T) {
+	addr := serveNTP(t, 45*time.Second)
+	ctx, cancel := context.WithTimeout(context.Background(), 2*time.Second)
+	defer cancel()
+
+	off, err := ClockOffset(ctx, addr)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if off < 44*time.Second || off > 46*time.Second {
+		t.Errorf("offset = %v, want about 45s", off)
+	}
+}
+
+func TestSkewCheckerCaches(t *testing.T) {
+	now := time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)
+	queries := 0
+	c := NewSkewChecker("")
+	c.now = func() time.Time { return now }
+	c.query = func(_ context.Context, server string) (time.Duration, error) {
+		if server != DefaultNTPServer {
+			t.Errorf("server = %q", server)
+		}
+		queries++
+		return 47 * time.Second, nil
+	}
+
+	for range 3 {
+		if off, err := c.Skew(context.Background()); err != nil || off != 47*time.Second {
+			t.Fatalf("skew = %v, %v", off, err)
+		}
+	}
+	now = now.Add(skewCacheTTL)
+	c.Skew(context.Background())
+	if queries != 2 {
+		t.Errorf("queries = %d, want 2", queries)
+	}
+}
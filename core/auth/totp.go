@@ -2,28 +2,145 @@ package auth
 
 import (
 	"crypto/hmac"
+	"crypto/rand"
 	"crypto/sha1"
+	"crypto/sha256"
+	"crypto/sha512"
 	"encoding/base32"
 	"encoding/binary"
+	"encoding/json"
+	"errors"
 	"fmt"
+	"hash"
+	"net/url"
+	"os"
+	"strconv"
 	"strings"
 	"time"
 )
 
+// Code modes.
 const (
-	totpPeriod = 30 // seconds per time step
-	totpDigits = 6
-	totpDrift  = 1 // +-1 step tolerance
+	ModeTOTP = "totp" // RFC 6238, time-based
+	ModeHOTP = "hotp" // RFC 4226, counter-based
 )
 
-// TOTP implements RFC 6238 time-based one-time passwords with HMAC-SHA1.
+// Params are the one-time password settings shared by the daemon and the
+// authenticator app or hardware token.
+type Params struct {
+	Mode      string `json:"mode"`      // totp (or empty) or hotp
+	Algorithm string `json:"algorithm"` // SHA1, SHA256 or SHA512
+	Digits    int    `json:"digits"`    // 6 or 8
+	Period    int    `json:"period"`    // totp: seconds per time step
+	Drift     int    `json:"drift"`     // totp: steps accepted either side of now
+	Window    int    `json:"window"`    // hotp: codes accepted ahead of the counter
+}
+
+// DefaultParams match what authenticator apps assume when an otpauth URI
+// does not say otherwise.
+var DefaultParams = Params{Mode: ModeTOTP, Algorithm: "SHA1", Digits: 6, Period: 30, Drift: 1, Window: 10}
+
+// Validate reports the first unsupported setting.
+func (p Params) Validate() error {
+	if _, err := p.hash(); err != nil {
+		return err
+	}
+	if p.Digits != 6 && p.Digits != 8 {
+		return fmt.Errorf("digits must be 6 or 8, got %d", p.Digits)
+	}
+	switch p.Mode {
+	case "", ModeTOTP:
+		if p.Period < 1 {
+			return fmt.Errorf("period must be positive, got %d", p.Period)
+		}
+		if p.Drift < 0 || p.Drift > 10 {
+			return fmt.Errorf("drift must be between 0 and 10 steps, got %d", p.Drift)
+		}
+	case ModeHOTP:
+		if p.Window < 1 || p.Window > 50 {
+			return fmt.Errorf("window must be between 1 and 50 codes, got %d", p.Window)
+		}
+	default:
+		return fmt.Errorf("unknown mode %q (want totp or hotp)", p.Mode)
+	}
+	return nil
+}
+
+// TimeBased reports whether codes depend on the clock.
+func (p Params) TimeBased() bool {
+	return p.Mode != ModeHOTP
+}
+
+// MaxSkew is the largest clock error that never rejects a valid code.
+func (p Params) MaxSkew() time.Duration {
+	return time.Duration(p.Drift*p.Period) * time.Second
+}
+
+func (p Params) hash() (func() hash.Hash, error) {
+	switch strings.ToUpper(p.Algorithm) {
+	case "SHA1":
+		return sha1.New, nil
+	case "SHA256":
+		return sha256.New, nil
+	case "SHA512":
+		return sha512.New, nil
+	}
+	return nil, fmt.Errorf("unsupported algorithm %q", p.Algorithm)
+}
+
+// LoadConfig reads TOTP parameters from path, filling unset fields from
+// DefaultParams. Returns DefaultParams if the file does not exist.
+func LoadConfig(path string) (Params, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			return DefaultParams, nil
+		}
+		return Params{}, fmt.Errorf("read totp config: %w", err)
+	}
+	p := DefaultParams
+	if err := json.Unmarshal(data, &p); err != nil {
+		return Params{}, fmt.Errorf("parse totp config: %w", err)
+	}
+	p.Mode = strings.ToLower(p.Mode)
+	p.Algorithm = strings.ToUpper(p.Algorithm)
+	if err := p.Validate(); err != nil {
+		return Params{}, fmt.Errorf("invalid totp config: %w", err)
+	}
+	return p, nil
+}
+
+// TOTP implements RFC 6238 time-based one-time passwords.
 type TOTP struct {
 	secret []byte
+	params Params
 	now    func() time.Time
 }
 
-// New creates a TOTP verifier from a base32-encoded secret.
+// New creates a TOTP verifier from a base32-encoded secret, using
+// DefaultParams.
 func New(base32Secret string) (*TOTP, error) {
+	return NewWithParams(base32Secret, DefaultParams)
+}
+
+// NewWithParams creates a TOTP verifier from a base32-encoded secret.
+func NewWithParams(base32Secret string, p Params) (*TOTP, error) {
+	if err := p.Validate(); err != nil {
+		return nil, err
+	}
+	if !p.TimeBased() {
+		return nil, fmt.Errorf("mode %q is not time-based", p.Mode)
+	}
+	secret, err := decodeSecret(base32Secret)
+	if err != nil {
+		return nil, err
+	}
+	return &TOTP{secret: secret, params: p, now: time.Now}, nil
+}
+
+// decodeSecret decodes a base32 secret, tolerating lowercase and missing
+// padding.
+func decodeSecret(base32Secret string) ([]byte, error) {
 	clean := strings.TrimRight(strings.ToUpper(strings.TrimSpace(base32Secret)), "=")
 	// Re-pad to valid base32 length.
 	if pad := len(clean) % 8; pad != 0 {
@@ -36,18 +153,37 @@ func New(base32Secret string) (*TOTP, error) {
 	if len(secret) == 0 {
 		return nil, fmt.Errorf("empty secret")
 	}
-	return &TOTP{secret: secret, now: time.Now}, nil
+	return secret, nil
+}
+
+// Params returns the parameters codes are checked with.
+func (t *TOTP) Params() Params {
+	return t.params
+}
+
+// WithClock replaces time.Now for code checks. A nil now is ignored.
+func (t *TOTP) WithClock(now func() time.Time) *TOTP {
+	if now != nil {
+		t.now = now
+	}
+	return t
+}
+
+// Code returns the code for the current period, as an authenticator app
+// would show it.
+func (t *TOTP) Code() string {
+	return generate(t.params, t.secret, t.now().Unix()/int64(t.params.Period))
 }
 
 // Verify checks whether code is valid for the current time +-drift.
 // Uses constant-time comparison.
 func (t *TOTP) Verify(code string) bool {
-	if len(code) != totpDigits {
+	if len(code) != t.params.Digits {
 		return false
 	}
-	counter := t.now().Unix() / totpPeriod
-	for offset := -int64(totpDrift); offset <= int64(totpDrift); offset++ {
-		expected := generate(t.secret, counter+offset)
+	counter := t.now().Unix() / int64(t.params.Period)
+	for offset := -int64(t.params.Drift); offset <= int64(t.params.Drift); offset++ {
+		expected := generate(t.params, t.secret, counter+offset)
 		if constantTimeEqual(code, expected) {
 			return true
 		}
@@ -55,20 +191,62 @@ func (t *TOTP) Verify(code string) bool {
 	return false
 }
 
-// generate computes a TOTP code for the given counter value.
-func generate(secret []byte, counter int64) string {
+// NewSecret returns a random base32 secret as long as the algorithm's
+// hash output, as RFC 4226 recommends.
+func NewSecret(p Params) (string, error) {
+	h, err := p.hash()
+	if err != nil {
+		return "", err
+	}
+	raw := make([]byte, h().Size())
+	rand.Read(raw)
+	return base32.StdEncoding.WithPadding(base32.NoPadding).EncodeToString(raw), nil
+}
+
+// URI returns the otpauth:// URI an authenticator app imports, spelling
+// out any parameter an app would otherwise assume. HOTP URIs start the
+// counter at 0.
+func URI(base32Secret, issuer, account string, p Params) string {
+	v := url.Values{"secret": {base32Secret}, "issuer": {issuer}}
+	kind := ModeTOTP
+	if p.Mode == ModeHOTP {
+		kind = ModeHOTP
+		v.Set("counter", "0")
+	}
+	if p.Algorithm != "" && !strings.EqualFold(p.Algorithm, DefaultParams.Algorithm) {
+		v.Set("algorithm", strings.ToUpper(p.Algorithm))
+	}
+	if p.Digits != 0 && p.Digits != DefaultParams.Digits {
+		v.Set("digits", strconv.Itoa(p.Digits))
+	}
+	if kind == ModeTOTP && p.Period != 0 && p.Period != DefaultParams.Period {
+		v.Set("period", strconv.Itoa(p.Period))
+	}
+	label := url.PathEscape(issuer)
+	if account != "" {
+		label += ":" + url.PathEscape(account)
+	}
+	return "otpauth://" + kind + "/" + label + "?" + v.Encode()
+}
+
+// generate computes an RFC 4226 code for the given counter value.
+func generate(p Params, secret []byte, counter int64) string {
 	buf := make([]byte, 8)
 	binary.BigEndian.PutUint64(buf, uint64(counter))
 
-	mac := hmac.New(sha1.New, secret)
+	h, _ := p.hash()
+	mac := hmac.New(h, secret)
 	mac.Write(buf)
 	sum := mac.Sum(nil)
 
 	offset := sum[len(sum)-1] & 0x0f
 	code := binary.BigEndian.Uint32(sum[offset:offset+4]) & 0x7fffffff
-	code = code % 1000000
+	mod := uint32(1)
+	for range p.Digits {
+		mod *= 10
+	}
 
-	return fmt.Sprintf("%06d", code)
+	return fmt.Sprintf("%0*d", p.Digits, code%mod)
 }
 
 // constantTimeEqual compares two strings in constant time.
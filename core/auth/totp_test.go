@@ -2,6 +2,9 @@ package auth
 
 import (
 	"encoding/base32"
+	"os"
+	"path/filepath"
+	"strings"
 	"testing"
 	"time"
 )
@@ -21,8 +24,8 @@ func newTestTOTP(t *testing.T, nowFn func() time.Time) *TOTP {
 }
 
 func codeAt(secret []byte, ts time.Time) string {
-	counter := ts.Unix() / totpPeriod
-	return generate(secret, counter)
+	counter := ts.Unix() / int64(DefaultParams.Period)
+	return generate(DefaultParams, secret, counter)
 }
 
 func TestVerifyCurrentCode(t *testing.T) {
@@ -35,6 +38,18 @@ func TestVerifyCurrentCode(t *testing.T) {
 	}
 }
 
+func TestCodeWithClock(t *testing.T) {
+	now := time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)
+	totp := newTestTOTP(t, nil).WithClock(func() time.Time { return now })
+
+	if got, want := totp.Code(), codeAt(totp.secret, now); got != want {
+		t.Errorf("Code() = %q, want %q", got, want)
+	}
+	if !totp.Verify(totp.Code()) {
+		t.Error("Verify(Code()) = false")
+	}
+}
+
 func TestVerifyPreviousStep(t *testing.T) {
 	now := time.Date(2025, 1, 1, 0, 0, 30, 0, time.UTC)
 	totp := newTestTOTP(t, func() time.Time { return now })
@@ -116,8 +131,8 @@ func TestNewLowercaseAndPadding(t *testing.T) {
 
 func TestGenerateDeterministic(t *testing.T) {
 	secret, _ := base32.StdEncoding.DecodeString(testSecret)
-	code1 := generate(secret, 100)
-	code2 := generate(secret, 100)
+	code1 := generate(DefaultParams, secret, 100)
+	code2 := generate(DefaultParams, secret, 100)
 	if code1 != code2 {
 		t.Errorf("generate not deterministic: %q != %q", code1, code2)
 	}
@@ -128,9 +143,110 @@ func TestGenerateDeterministic(t *testing.T) {
 
 func TestGenerateDifferentCounters(t *testing.T) {
 	secret, _ := base32.StdEncoding.DecodeString(testSecret)
-	code1 := generate(secret, 100)
-	code2 := generate(secret, 101)
+	code1 := generate(DefaultParams, secret, 100)
+	code2 := generate(DefaultParams, secret, 101)
 	if code1 == code2 {
 		t.Errorf("different counters produced same code: %q", code1)
 	}
 }
+
+// TestGenerateRFC6238Vectors checks the 8-digit test vectors from RFC 6238
+// appendix B for each algorithm.
+func TestGenerateRFC6238Vectors(t *testing.T) {
+	seed := "1234567890"
+	tests := []struct {
+		algorithm string
+		secret    string
+		unix      int64
+		want      string
+	}{
+		{"SHA1", strings.Repeat(seed, 2), 59, "94287082"},
+		{"SHA256", strings.Repeat(seed, 3) + "12", 59, "46119246"},
+		{"SHA512", strings.Repeat(seed, 6) + "1234", 59, "90693936"},
+		{"SHA1", strings.Repeat(seed, 2), 1111111109, "07081804"},
+		{"SHA256", strings.Repeat(seed, 3) + "12", 1111111109, "68084774"},
+		{"SHA512", strings.Repeat(seed, 6) + "1234", 1111111109, "25091201"},
+	}
+	for _, tt := range tests {
+		p := Params{Algorithm: tt.algorithm, Digits: 8, Period: 30, Drift: 1}
+		if got := generate(p, []byte(tt.secret), tt.unix/30); got != tt.want {
+			t.Errorf("%s at %d = %s, want %s", tt.algorithm, tt.unix, got, tt.want)
+		}
+	}
+}
+
+func TestVerifyWithParams(t *testing.T) {
+	p := Params{Algorithm: "SHA256", Digits: 8, Period: 60, Drift: 0}
+	secret, err := NewSecret(p)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(secret) != 52 { // 32 bytes of base32
+		t.Errorf("secret length = %d, want 52", len(secret))
+	}
+	totp, err := NewWithParams(secret, p)
+	if err != nil {
+		t.Fatal(err)
+	}
+	now := time.Date(2025, 1, 1, 0, 0, 30, 0, time.UTC)
+	totp.now = func() time.Time { return now }
+
+	if code := generate(p, totp.secret, now.Unix()/60); !totp.Verify(code) {
+		t.Errorf("Verify(current 8-digit code) = false")
+	}
+	if code := generate(p, totp.secret, now.Unix()/60-1); totp.Verify(code) {
+		t.Errorf("Verify(previous step) = true with drift 0")
+	}
+	if totp.Verify(generate(DefaultParams, totp.secret, now.Unix()/30)) {
+		t.Errorf("Verify accepted a 6-digit code")
+	}
+}
+
+func TestParamsValidate(t *testing.T) {
+	bad := []Params{
+		{Algorithm: "MD5", Digits: 6, Period: 30},
+		{Algorithm: "SHA1", Digits: 7, Period: 30},
+		{Algorithm: "SHA1", Digits: 6, Period: 0},
+		{Algorithm: "SHA1", Digits: 6, Period: 30, Drift: -1},
+	}
+	for _, p := range bad {
+		if p.Validate() == nil {
+			t.Errorf("Validate(%+v) = nil, want error", p)
+		}
+	}
+	if err := DefaultParams.Validate(); err != nil {
+		t.Errorf("Validate(DefaultParams) = %v", err)
+	}
+}
+
+func TestLoadConfig(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "totp.json")
+	if p, err := LoadConfig(path); err != nil || p != DefaultParams {
+		t.Errorf("missing file = %+v, %v", p, err)
+	}
+
+	os.WriteFile(path, []byte(`{"algorithm": "sha512", "digits": 8, "drift": 0}`), 0600)
+	p, err := LoadConfig(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := Params{Mode: ModeTOTP, Algorithm: "SHA512", Digits: 8, Period: 30, Drift: 0, Window: 10}
+	if p != want {
+		t.Errorf("LoadConfig = %+v, want %+v", p, want)
+	}
+
+	os.WriteFile(path, []byte(`{"digits": 10}`), 0600)
+	if _, err := LoadConfig(path); err == nil {
+		t.Error("10 digits accepted")
+	}
+}
+
+func TestURI(t *testing.T) {
+	if got, want := URI("ABC", "OpenSlack", "", DefaultParams), "otpauth://totp/OpenSlack?issuer=OpenSlack&secret=ABC"; got != want {
+		t.Errorf("URI(defaults) = %q, want %q", got, want)
+	}
+	p := Params{Algorithm: "SHA256", Digits: 8, Period: 60, Drift: 1}
+	if got, want := URI("ABC", "OpenSlack", "home", p), "otpauth://totp/OpenSlack:home?algorithm=SHA256&digits=8&issuer=OpenSlack&period=60&secret=ABC"; got != want {
+		t.Errorf("URI = %q, want %q", got, want)
+	}
+}
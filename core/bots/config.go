@@ -0,0 +1,108 @@
+// Package bots configures extra Telegram bots run from the same daemon,
+// such as a family bot beside the personal one. Each bot has its own
+// token, chat allowlist and command subset, and shares ops and security
+// state with the rest of the daemon.
+package bots
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"regexp"
+	"sort"
+	"strings"
+
+	"github.com/jdelaire/openslack/core/ops"
+)
+
+var validName = regexp.MustCompile(`^[a-z0-9_-]+$`)
+
+// Config is the extra-bots configuration, keyed by bot name.
+type Config struct {
+	Bots map[string]Bot `json:"bots"`
+}
+
+// Bot is one extra bot. TokenAccount is the keychain account holding its
+// token, by default telegram_bot_token_<name>. Ops limits the bot to those
+// commands; empty exposes every command.
+type Bot struct {
+	TokenAccount string   `json:"token_account"`
+	ChatIDs      []int64  `json:"chat_ids"`
+	Ops          []string `json:"ops"`
+}
+
+// LoadConfig reads and validates a bots config file.
+// Returns nil, nil if the file does not exist.
+func LoadConfig(path string) (*Config, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("read bots config: %w", err)
+	}
+
+	var cfg Config
+	if err := json.Unmarshal(data, &cfg); err != nil {
+		return nil, fmt.Errorf("parse bots config: %w", err)
+	}
+
+	if err := validateConfig(&cfg); err != nil {
+		return nil, err
+	}
+
+	applyDefaults(&cfg)
+	return &cfg, nil
+}
+
+func validateConfig(cfg *Config) error {
+	for name, b := range cfg.Bots {
+		if !validName.MatchString(name) {
+			return fmt.Errorf("bot name %q must be lowercase letters, digits, - or _", name)
+		}
+		if len(b.ChatIDs) == 0 {
+			return fmt.Errorf("bot %q has no chat_ids", name)
+		}
+		for _, op := range b.Ops {
+			if strings.TrimPrefix(op, "/") == "" {
+				return fmt.Errorf("bot %q has an empty op name", name)
+			}
+		}
+	}
+	return nil
+}
+
+func applyDefaults(cfg *Config) {
+	for name, b := range cfg.Bots {
+		if b.TokenAccount == "" {
+			b.TokenAccount = "telegram_bot_token_" + name
+		}
+		for i, op := range b.Ops {
+			b.Ops[i] = strings.ToLower(strings.TrimPrefix(op, "/"))
+		}
+		cfg.Bots[name] = b
+	}
+}
+
+// Names returns the configured bot names, sorted.
+func (c *Config) Names() []string {
+	names := make([]string, 0, len(c.Bots))
+	for name := range c.Bots {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// Scope returns the routing context for the bot called name, for
+// Dispatcher.WithBot.
+func (b Bot) Scope(name string) ops.Bot {
+	return ops.Bot{Name: name, Ops: b.Ops}
+}
+
+// StatePrefix is the namespace prefix for the bot's own state, such as
+// its Telegram offset and seen update IDs, for persist.Prefix. Update IDs
+// are per bot, so they must not share storage with another bot's.
+func StatePrefix(name string) string {
+	return "bot." + name + "."
+}
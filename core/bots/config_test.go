@@ -0,0 +1,68 @@
+package bots
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func writeConfig(t *testing.T, data string) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "bots.json")
+	if err := os.WriteFile(path, []byte(data), 0600); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+func TestLoadConfig(t *testing.T) {
+	path := writeConfig(t, `{"bots": {"family": {"chat_ids": [-1001], "ops": ["/Tasks", "status"]}, "ops": {"token_account": "ops_token", "chat_ids": [42]}}}`)
+	cfg, err := LoadConfig(path)
+	if err != nil {
+		t.Fatalf("LoadConfig: %v", err)
+	}
+
+	family := cfg.Bots["family"]
+	if family.TokenAccount != "telegram_bot_token_family" {
+		t.Errorf("default token account = %q", family.TokenAccount)
+	}
+	scope := family.Scope("family")
+	if !scope.Allows("tasks") || !scope.Allows("status") || scope.Allows("deploy") {
+		t.Errorf("scope %+v allows the wrong ops", scope)
+	}
+	if cfg.Bots["ops"].TokenAccount != "ops_token" || !cfg.Bots["ops"].Scope("ops").Allows("deploy") {
+		t.Errorf("ops bot = %+v", cfg.Bots["ops"])
+	}
+	if names := cfg.Names(); len(names) != 2 || names[0] != "family" || names[1] != "ops" {
+		t.Errorf("Names() = %v", names)
+	}
+}
+
+func TestLoadConfigMissingFile(t *testing.T) {
+	cfg, err := LoadConfig(filepath.Join(t.TempDir(), "missing.json"))
+	if cfg != nil || err != nil {
+		t.Errorf("got %v, %v; want nil, nil", cfg, err)
+	}
+}
+
+func TestLoadConfigInvalid(t *testing.T) {
+	tests := []struct {
+		name    string
+		data    string
+		wantErr string
+	}{
+		{"bad json", `{`, "parse bots config"},
+		{"bad name", `{"bots": {"Family Bot": {"chat_ids": [1]}}}`, "bot name"},
+		{"no chats", `{"bots": {"family": {}}}`, "no chat_ids"},
+		{"empty op", `{"bots": {"family": {"chat_ids": [1], "ops": ["/"]}}}`, "empty op name"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := LoadConfig(writeConfig(t, tt.data))
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("error = %v, want %q", err, tt.wantErr)
+			}
+		})
+	}
+}
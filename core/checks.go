@@ -0,0 +1,71 @@
+package core
+
+import (
+	"context"
+	"fmt"
+	"path/filepath"
+	"time"
+
+	"github.com/jdelaire/openslack/core/auth"
+	"github.com/jdelaire/openslack/core/ops"
+	"github.com/jdelaire/openslack/internal/platform"
+)
+
+// NotifierCheck is a self-test that sends a test message through n.
+func NotifierCheck(n Notifier) ops.Check {
+	return ops.Check{
+		Name: "Notifier " + n.Name() + " delivers",
+		Run: func(ctx context.Context) error {
+			return n.Send(ctx, Notification{
+				Text:      "OpenSlack setup: test notification",
+				Source:    "setup",
+				CreatedAt: time.Now(),
+			})
+		},
+	}
+}
+
+// SocketCheck is a self-test that connects to the socket server.
+func SocketCheck(socketPath string) ops.Check {
+	return ops.Check{
+		Name: "Socket server accepts connections",
+		Run: func(context.Context) error {
+			conn, err := platform.Dial(socketPath, time.Second)
+			if err != nil {
+				return fmt.Errorf("dial %s: %w", socketPath, err)
+			}
+			return conn.Close()
+		},
+	}
+}
+
+// ConfigCheck is a self-test that reloads a config file with load, the
+// file's LoadConfig wrapped to drop the result.
+func ConfigCheck(path string, load func(path string) error) ops.Check {
+	return ops.Check{
+		Name: "Config " + filepath.Base(path) + " is valid",
+		Run:  func(context.Context) error { return load(path) },
+	}
+}
+
+// ClockCheck is a self-test that compares the local clock with an NTP
+// server, failing when the skew could make valid TOTP codes under p be
+// rejected. An empty server uses auth.DefaultNTPServer.
+func ClockCheck(server string, p auth.Params) ops.Check {
+	if server == "" {
+		server = auth.DefaultNTPServer
+	}
+	return ops.Check{
+		Name: "Clock in sync for TOTP",
+		Run: func(ctx context.Context) error {
+			off, err := auth.ClockOffset(ctx, server)
+			if err != nil {
+				return err
+			}
+			if max := p.MaxSkew(); off.Abs() > max {
+				return fmt.Errorf("clock is off by %s, more than %s", off.Abs().Round(time.Second), max)
+			}
+			return nil
+		},
+	}
+}
@@ -0,0 +1,126 @@
+package core
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"regexp"
+	"time"
+	"unicode/utf8"
+
+	"github.com/jdelaire/openslack/core/ops"
+)
+
+// Telegram's limits on a published command list.
+const (
+	maxCommands       = 100
+	maxCommandDescLen = 256
+)
+
+// commandName matches the names Telegram accepts in a command list.
+// Connector tools, whose names contain a dot, do not qualify.
+var commandName = regexp.MustCompile(`^[a-z0-9_]{1,32}$`)
+
+// commandsSettle is how long publishing waits after a registry change,
+// so a reload that re-registers many ops is published once.
+var commandsSettle = time.Second
+
+// CommandMenu scopes the published command menu. The default menu, seen
+// in chats without their own, lists the ops open to every chat; each chat
+// in Chats gets a menu of the ops it may run. Bot, if set, limits every
+// menu to the bot's ops. High-risk ops are never listed, since they run
+// only through /do.
+type CommandMenu struct {
+	Chats []int64
+	Bot   *ops.Bot
+}
+
+// PublishCommands publishes reg's ops as n's command menus, and again
+// whenever reg changes, until ctx is done. Menus that did not change are
+// not sent again. It does nothing if n is not a CommandPublisher.
+func PublishCommands(ctx context.Context, reg *ops.Registry, n Notifier, menu CommandMenu, logger *slog.Logger) {
+	pub, ok := Unwrap(n).(CommandPublisher)
+	if !ok {
+		return
+	}
+	changes := make(chan struct{}, 1)
+	unsubscribe := reg.OnChange(func() {
+		select {
+		case changes <- struct{}{}:
+		default:
+		}
+	})
+
+	sent := map[int64]string{} // scope -> the menu it last got
+	publish := func() {
+		for _, chatID := range append([]int64{0}, menu.Chats...) {
+			cmds := menu.Commands(reg, chatID)
+			key := fmt.Sprint(cmds)
+			if prev, ok := sent[chatID]; ok && prev == key {
+				continue
+			}
+			pctx, cancel := context.WithTimeout(ctx, 10*time.Second)
+			err := pub.SetCommands(pctx, chatID, cmds)
+			cancel()
+			if err != nil {
+				logger.Warn("publish commands failed", "chat_id", chatID, "error", err)
+				continue
+			}
+			sent[chatID] = key
+			logger.Debug("commands published", "chat_id", chatID, "count", len(cmds))
+		}
+	}
+
+	go func() {
+		defer unsubscribe()
+		publish()
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-changes:
+			}
+			select {
+			case <-ctx.Done():
+				return
+			case <-time.After(commandsSettle):
+			}
+			select {
+			case <-changes:
+			default:
+			}
+			publish()
+		}
+	}()
+}
+
+// Commands returns the menu for chatID, or the default menu for chat 0:
+// the ops in scope with names the chat app accepts, in name order,
+// descriptions cut to fit.
+func (m CommandMenu) Commands(reg *ops.Registry, chatID int64) []Command {
+	var cmds []Command
+	for _, op := range reg.List() {
+		if !commandName.MatchString(op.Name()) || reg.RiskOf(op) == ops.RiskHigh {
+			continue
+		}
+		if m.Bot != nil && !m.Bot.Allows(op.Name()) {
+			continue
+		}
+		if _, restricted := op.(ops.ChatRestricter); restricted && (chatID == 0 || !ops.ChatAllowed(op, chatID)) {
+			continue
+		}
+		desc := op.Description()
+		if desc == "" {
+			desc = "/" + op.Name()
+		}
+		for len(desc) > maxCommandDescLen {
+			_, size := utf8.DecodeLastRuneInString(desc)
+			desc = desc[:len(desc)-size]
+		}
+		cmds = append(cmds, Command{Name: op.Name(), Description: desc})
+		if len(cmds) == maxCommands {
+			break
+		}
+	}
+	return cmds
+}
@@ -0,0 +1,131 @@
+package core
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/jdelaire/openslack/core/ops"
+)
+
+// commandSpy is a spyNotifier that also records published command lists.
+type commandSpy struct {
+	spyNotifier
+	published [][]Command
+	chats     []int64
+}
+
+func (s *commandSpy) SetCommands(_ context.Context, chatID int64, cmds []Command) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.published = append(s.published, cmds)
+	s.chats = append(s.chats, chatID)
+	return nil
+}
+
+func (s *commandSpy) lastPublished() (int, []Command) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if len(s.published) == 0 {
+		return 0, nil
+	}
+	return len(s.published), s.published[len(s.published)-1]
+}
+
+// namedOp is an op with any name and description.
+type namedOp struct{ name, desc string }
+
+func (o *namedOp) Name() string                                    { return o.name }
+func (o *namedOp) Description() string                             { return o.desc }
+func (o *namedOp) Execute(context.Context, string) (string, error) { return "", nil }
+
+func commandNames(cmds []Command) string {
+	names := make([]string, len(cmds))
+	for i, c := range cmds {
+		names[i] = c.Name
+	}
+	return strings.Join(names, ",")
+}
+
+// chatOnlyOp is an op only chat 100 may run.
+type chatOnlyOp struct{ namedOp }
+
+func (o *chatOnlyOp) AllowsChat(chatID int64) bool { return chatID == 100 }
+
+func TestCommandMenu(t *testing.T) {
+	reg := ops.NewRegistry()
+	reg.Register(&echoOp{})
+	reg.Register(&highRiskEchoOp{})
+	reg.Register(&namedOp{name: "sample.echo", desc: "connector tool"})
+	reg.Register(&namedOp{name: "long", desc: strings.Repeat("é", 200)})
+	reg.Register(&chatOnlyOp{namedOp{name: "deploy", desc: "Deploy"}})
+
+	var menu CommandMenu
+	cmds := menu.Commands(reg, 0)
+	if got := commandNames(cmds); got != "echo,long" {
+		t.Errorf("default menu = %s, want echo,long", got)
+	}
+	if len(cmds[1].Description) > maxCommandDescLen || !strings.HasPrefix(cmds[1].Description, "éé") {
+		t.Errorf("long description is %d bytes", len(cmds[1].Description))
+	}
+	if got := commandNames(menu.Commands(reg, 100)); got != "deploy,echo,long" {
+		t.Errorf("chat 100 menu = %s", got)
+	}
+	if got := commandNames(menu.Commands(reg, 200)); got != "echo,long" {
+		t.Errorf("chat 200 menu = %s", got)
+	}
+
+	reg.SetRiskOverrides(map[string]ops.RiskLevel{"danger": ops.RiskLow, "long": ops.RiskHigh})
+	menu.Bot = &ops.Bot{Name: "family", Ops: []string{"danger", "long"}}
+	if got := commandNames(menu.Commands(reg, 0)); got != "danger" {
+		t.Errorf("bot menu with overrides = %s", got)
+	}
+}
+
+func TestPublishCommands(t *testing.T) {
+	defer func(d time.Duration) { commandsSettle = d }(commandsSettle)
+	commandsSettle = 30 * time.Millisecond
+
+	reg := ops.NewRegistry()
+	reg.Register(&echoOp{})
+	spy := &commandSpy{}
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	PublishCommands(ctx, reg, spy, CommandMenu{Chats: []int64{100}}, testLogger())
+
+	waitFor(t, func() bool { n, _ := spy.lastPublished(); return n == 2 })
+	if spy.chats[0] != 0 || spy.chats[1] != 100 {
+		t.Errorf("published to %v, want the default scope and chat 100", spy.chats)
+	}
+
+	// A reload's burst of changes is published once.
+	reg.Unregister("echo")
+	reg.Register(&namedOp{name: "tasks", desc: "List tasks"})
+	reg.Register(&namedOp{name: "done", desc: "Complete a task"})
+	waitFor(t, func() bool { n, _ := spy.lastPublished(); return n >= 4 })
+	time.Sleep(50 * time.Millisecond)
+	n, cmds := spy.lastPublished()
+	if n != 4 || commandNames(cmds) != "done,tasks" {
+		t.Errorf("published %d times, last %s", n, commandNames(cmds))
+	}
+
+	// A change that leaves the menus as they were publishes nothing.
+	reg.SetRiskOverrides(nil)
+	time.Sleep(80 * time.Millisecond)
+	if n, _ := spy.lastPublished(); n != 4 {
+		t.Errorf("unchanged menus published again: %d", n)
+	}
+
+	// Nothing is published once ctx is done.
+	cancel()
+	time.Sleep(20 * time.Millisecond)
+	reg.Register(&namedOp{name: "late", desc: "late"})
+	time.Sleep(50 * time.Millisecond)
+	if n, _ := spy.lastPublished(); n != 4 {
+		t.Errorf("published after cancel: %d", n)
+	}
+
+	// Notifiers that cannot publish are left alone.
+	PublishCommands(context.Background(), reg, &spyNotifier{}, CommandMenu{}, testLogger())
+}
@@ -79,6 +79,28 @@ func (w *Watcher) poll() {
 	}
 }
 
+// ReloadAll invokes the callback of every watched file that exists, as if
+// it had changed, and returns their paths. It lets a reload be asked for
+// without touching the files.
+func (w *Watcher) ReloadAll() []string {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	var reloaded []string
+	for i := range w.entries {
+		e := &w.entries[i]
+		current := fileModTime(e.path)
+		if current.IsZero() {
+			continue
+		}
+		e.modTime = current
+		w.logger.Info("config reload requested", "path", e.path)
+		e.cb(e.path)
+		reloaded = append(reloaded, e.path)
+	}
+	return reloaded
+}
+
 // fileModTime returns the file's modification time, or zero if it can't be read.
 func fileModTime(path string) time.Time {
 	info, err := os.Stat(path)
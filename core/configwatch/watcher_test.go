@@ -143,3 +143,23 @@ func TestWatcherStopsOnContextCancel(t *testing.T) {
 		t.Fatal("Run did not exit after context cancel")
 	}
 }
+
+func TestWatcherReloadAll(t *testing.T) {
+	dir := t.TempDir()
+	present := filepath.Join(dir, "commands.json")
+	missing := filepath.Join(dir, "connectors.json")
+	os.WriteFile(present, []byte(`{}`), 0644)
+
+	var calls []string
+	w := configwatch.New(time.Hour, testLogger())
+	w.Watch(present, func(path string) { calls = append(calls, path) })
+	w.Watch(missing, func(path string) { calls = append(calls, path) })
+
+	got := w.ReloadAll()
+	if len(got) != 1 || got[0] != present {
+		t.Fatalf("ReloadAll() = %v, want [%s]", got, present)
+	}
+	if len(calls) != 1 || calls[0] != present {
+		t.Fatalf("callbacks = %v, want [%s]", calls, present)
+	}
+}
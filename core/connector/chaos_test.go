@@ -0,0 +1,88 @@
+package connector_test
+
+import (
+	"context"
+	"encoding/json"
+	"log/slog"
+	"os"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/jdelaire/openslack/core/connector"
+)
+
+func startChaos(t *testing.T, faults *connector.FaultConfig) *connector.Router {
+	t.Helper()
+	cfg := &connector.Config{
+		Connectors: map[string]connector.ConnectorConfig{
+			"chaos": {
+				Exec:  buildConnector(t, "chaos"),
+				Tools: []string{"echo", "delay", "malformed", "wrong_id", "stale", "oversized", "exit"},
+			},
+		},
+		Limits: connector.LimitsConfig{
+			ReqMaxBytes:   4096,
+			RespMaxBytes:  16384,
+			CallTimeoutMs: 300,
+		},
+		Faults: faults,
+	}
+	logger := slog.New(slog.NewJSONHandler(os.Stderr, &slog.HandlerOptions{Level: slog.LevelDebug}))
+	mgr := connector.NewManager(cfg, logger)
+	if err := mgr.Start(); err != nil {
+		t.Fatalf("start: %v", err)
+	}
+	t.Cleanup(mgr.Shutdown)
+	return connector.NewRouter(cfg, mgr, logger)
+}
+
+func echo(t *testing.T, router *connector.Router) {
+	t.Helper()
+	resp, err := router.Call(context.Background(), "chaos.echo", json.RawMessage(`{"text":"hi"}`))
+	if err != nil || !resp.OK {
+		t.Fatalf("echo after fault = %+v, %v", resp, err)
+	}
+}
+
+func TestChaosConnector(t *testing.T) {
+	router := startChaos(t, nil)
+
+	tests := []struct {
+		tool, args string
+		want       string // error substring; "" for success
+	}{
+		{"delay", `{"ms":500}`, "timed out"},
+		{"malformed", `{}`, "invalid response"},
+		{"wrong_id", `{"text":"hi"}`, "timed out"},
+		{"stale", `{"text":"hi"}`, ""},
+		{"oversized", `{}`, "token too long"},
+		{"exit", `{"code":3}`, "closed stdout"},
+	}
+	for _, tt := range tests {
+		_, err := router.Call(context.Background(), "chaos."+tt.tool, json.RawMessage(tt.args))
+		if tt.want == "" && err != nil || tt.want != "" && (err == nil || !strings.Contains(err.Error(), tt.want)) {
+			t.Errorf("%s: error = %v, want %q", tt.tool, err, tt.want)
+		}
+		if tt.tool == "delay" {
+			// Let the late reply arrive; the next call discards it.
+			time.Sleep(400 * time.Millisecond)
+		}
+		// Every fault leaves the connector usable, restarted if need be.
+		echo(t, router)
+	}
+}
+
+func TestFaultInjection(t *testing.T) {
+	router := startChaos(t, &connector.FaultConfig{Rate: 1, Kinds: []string{connector.FaultKill}, Seed: 1})
+	for range 2 {
+		if _, err := router.Call(context.Background(), "chaos.echo", json.RawMessage(`{"text":"hi"}`)); err == nil {
+			t.Error("call with a kill fault succeeded")
+		}
+	}
+
+	router = startChaos(t, &connector.FaultConfig{Rate: 1, Kinds: []string{connector.FaultDrop}})
+	if _, err := router.Call(context.Background(), "chaos.echo", json.RawMessage(`{"text":"hi"}`)); err == nil || !strings.Contains(err.Error(), "timed out") {
+		t.Errorf("dropped reply: %v", err)
+	}
+}
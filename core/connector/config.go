@@ -5,32 +5,64 @@ import (
 	"fmt"
 	"os"
 	"strings"
+
+	"github.com/jdelaire/openslack/core/ops"
+	"github.com/jdelaire/openslack/internal/homedir"
 )
 
 // Default limits.
 const (
-	DefaultReqMaxBytes  = 4096
-	DefaultRespMaxBytes = 16384
+	DefaultReqMaxBytes   = 4096
+	DefaultRespMaxBytes  = 16384
 	DefaultCallTimeoutMs = 10000
+	DefaultWasmMemoryMB  = 64
+)
+
+// MaxWasmMemoryMB is the most memory a WebAssembly module can address.
+const MaxWasmMemoryMB = 4096
+
+// Connector types. A process connector is an executable spoken to over
+// stdin/stdout; a wasm connector is a WebAssembly module run in-process
+// (see wasm.go).
+const (
+	TypeProcess = "process"
+	TypeWasm    = "wasm"
 )
 
-// Config is the top-level connector configuration.
+// Config is the top-level connector configuration. ScratchDir holds a
+// private directory per connector; it defaults to DefaultScratchDir and
+// leaving it empty in a Config built in code disables scratch directories.
+// Faults, when set, injects failures into calls for testing.
 type Config struct {
 	Connectors map[string]ConnectorConfig `json:"connectors"`
-	Limits     LimitsConfig              `json:"limits"`
+	Limits     LimitsConfig               `json:"limits"`
+	ScratchDir string                     `json:"scratch_dir"`
+	Faults     *FaultConfig               `json:"faults,omitempty"`
 }
 
 // ConnectorConfig defines a single connector's executable and allowed tools.
+// Type is TypeProcess, the default, or TypeWasm, in which case Exec is the
+// path of a .wasm module.
+// Tools listed in HighRisk go through the /do + /approve flow instead of TOTP.
+// ChatAccess limits which chats may call each tool, keyed by tool name.
+// SecretAccount names the keychain account holding a secret shared with
+// the connector; when set, requests, responses and events are signed.
 type ConnectorConfig struct {
-	Exec  string   `json:"exec"`
-	Tools []string `json:"tools"`
+	Type          string                    `json:"type,omitempty"`
+	Exec          string                    `json:"exec"`
+	Tools         []string                  `json:"tools"`
+	HighRisk      []string                  `json:"high_risk"`
+	ChatAccess    map[string]ops.ChatAccess `json:"chat_access"`
+	SecretAccount string                    `json:"secret_account"`
 }
 
-// LimitsConfig holds global resource limits.
+// LimitsConfig holds global resource limits. WasmMemoryMB caps the memory
+// of each wasm connector call.
 type LimitsConfig struct {
 	ReqMaxBytes   int `json:"req_max_bytes"`
 	RespMaxBytes  int `json:"resp_max_bytes"`
 	CallTimeoutMs int `json:"call_timeout_ms"`
+	WasmMemoryMB  int `json:"wasm_memory_mb"`
 }
 
 // LoadConfig reads and validates a connector config file.
@@ -68,6 +100,15 @@ func validateConfig(cfg *Config) error {
 		if cc.Exec == "" {
 			return fmt.Errorf("connector %q missing exec path", name)
 		}
+		switch cc.Type {
+		case "", TypeProcess:
+		case TypeWasm:
+			if cc.SecretAccount != "" {
+				return fmt.Errorf("connector %q: secret_account is not supported for wasm connectors", name)
+			}
+		default:
+			return fmt.Errorf("connector %q: unknown type %q", name, cc.Type)
+		}
 		if len(cc.Tools) == 0 {
 			return fmt.Errorf("connector %q has no allowed tools", name)
 		}
@@ -79,8 +120,21 @@ func validateConfig(cfg *Config) error {
 				return fmt.Errorf("connector %q: tool %q uses reserved prefix __", name, t)
 			}
 		}
+		for _, t := range cc.HighRisk {
+			if !cc.ToolAllowed(t) || t == IntrospectToolName {
+				return fmt.Errorf("connector %q: high_risk tool %q is not in tools", name, t)
+			}
+		}
+		for t := range cc.ChatAccess {
+			if !cc.ToolAllowed(t) || t == IntrospectToolName {
+				return fmt.Errorf("connector %q: chat_access tool %q is not in tools", name, t)
+			}
+		}
+	}
+	if cfg.Limits.WasmMemoryMB > MaxWasmMemoryMB {
+		return fmt.Errorf("limits.wasm_memory_mb must be at most %d", MaxWasmMemoryMB)
 	}
-	return nil
+	return validateFaults(cfg.Faults)
 }
 
 func applyDefaults(cfg *Config) {
@@ -93,6 +147,13 @@ func applyDefaults(cfg *Config) {
 	if cfg.Limits.CallTimeoutMs <= 0 {
 		cfg.Limits.CallTimeoutMs = DefaultCallTimeoutMs
 	}
+	if cfg.Limits.WasmMemoryMB <= 0 {
+		cfg.Limits.WasmMemoryMB = DefaultWasmMemoryMB
+	}
+	if cfg.ScratchDir == "" {
+		cfg.ScratchDir = DefaultScratchDir
+	}
+	cfg.ScratchDir = homedir.Expand(cfg.ScratchDir)
 }
 
 // ToolAllowed returns true if the given tool is in the connector's allowlist.
@@ -108,3 +169,18 @@ func (cc *ConnectorConfig) ToolAllowed(tool string) bool {
 	}
 	return false
 }
+
+// IsWasm reports whether the connector is a WebAssembly module.
+func (cc *ConnectorConfig) IsWasm() bool {
+	return cc.Type == TypeWasm
+}
+
+// IsHighRisk returns true if the tool is listed in the connector's high_risk list.
+func (cc *ConnectorConfig) IsHighRisk(tool string) bool {
+	for _, t := range cc.HighRisk {
+		if t == tool {
+			return true
+		}
+	}
+	return false
+}
@@ -72,6 +72,9 @@ func TestLoadConfigDefaults(t *testing.T) {
 	if cfg.Limits.CallTimeoutMs != DefaultCallTimeoutMs {
 		t.Errorf("call_timeout_ms = %d, want %d", cfg.Limits.CallTimeoutMs, DefaultCallTimeoutMs)
 	}
+	if !filepath.IsAbs(cfg.ScratchDir) || filepath.Base(cfg.ScratchDir) != "connector-data" {
+		t.Errorf("scratch_dir = %q, want ~/.openslack/connector-data expanded", cfg.ScratchDir)
+	}
 }
 
 func TestLoadConfigMissingFile(t *testing.T) {
@@ -142,3 +145,99 @@ func TestToolAllowed(t *testing.T) {
 		t.Error("expected __introspect to always be allowed")
 	}
 }
+
+func TestLoadConfigHighRiskNotInTools(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "connectors.json")
+	os.WriteFile(path, []byte(`{"connectors":{"github":{"exec":"./bin/gh","tools":["prs"],"high_risk":["merge"]}}}`), 0644)
+
+	_, err := LoadConfig(path)
+	if err == nil {
+		t.Fatal("expected error for high_risk tool not in tools")
+	}
+	if !strings.Contains(err.Error(), "not in tools") {
+		t.Errorf("unexpected error: %v", err)
+	}
+}
+
+func TestIsHighRisk(t *testing.T) {
+	cc := ConnectorConfig{Tools: []string{"prs", "merge"}, HighRisk: []string{"merge"}}
+
+	if !cc.IsHighRisk("merge") {
+		t.Error("expected merge to be high risk")
+	}
+	if cc.IsHighRisk("prs") {
+		t.Error("expected prs not to be high risk")
+	}
+}
+
+func TestLoadConfigChatAccess(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "connectors.json")
+	os.WriteFile(path, []byte(`{"connectors":{"infra":{"exec":"./bin/infra","tools":["deploy","status"],"chat_access":{"deploy":{"allowed_chats":[100]}}}}}`), 0644)
+
+	cfg, err := LoadConfig(path)
+	if err != nil {
+		t.Fatalf("LoadConfig: %v", err)
+	}
+	access := cfg.Connectors["infra"].ChatAccess
+	if !access["deploy"].AllowsChat(100) || access["deploy"].AllowsChat(200) || !access["status"].AllowsChat(200) {
+		t.Errorf("chat_access = %+v", access)
+	}
+
+	os.WriteFile(path, []byte(`{"connectors":{"infra":{"exec":"./bin/infra","tools":["status"],"chat_access":{"deploy":{"allowed_chats":[100]}}}}}`), 0644)
+	if _, err := LoadConfig(path); err == nil || !strings.Contains(err.Error(), "not in tools") {
+		t.Errorf("chat_access for unknown tool: err = %v", err)
+	}
+}
+
+func TestLoadConfigFaults(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "connectors.json")
+	os.WriteFile(path, []byte(`{"connectors":{},"faults":{"rate":0.2,"kinds":["drop","kill"],"seed":7}}`), 0644)
+
+	cfg, err := LoadConfig(path)
+	if err != nil {
+		t.Fatalf("LoadConfig: %v", err)
+	}
+	if f := cfg.Faults; f == nil || f.Rate != 0.2 || len(f.Kinds) != 2 || f.Seed != 7 {
+		t.Errorf("faults = %+v", cfg.Faults)
+	}
+
+	for data, want := range map[string]string{
+		`{"faults":{"rate":2}}`:                     "between 0 and 1",
+		`{"faults":{"rate":0.5,"kinds":["flood"]}}`: `unknown kind "flood"`,
+	} {
+		os.WriteFile(path, []byte(data), 0644)
+		if _, err := LoadConfig(path); err == nil || !strings.Contains(err.Error(), want) {
+			t.Errorf("%s: error = %v, want %q", data, err, want)
+		}
+	}
+}
+
+func TestLoadConfigWasm(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "connectors.json")
+	os.WriteFile(path, []byte(`{"connectors":{"calc":{"type":"wasm","exec":"./calc.wasm","tools":["add"]}}}`), 0644)
+
+	cfg, err := LoadConfig(path)
+	if err != nil {
+		t.Fatalf("LoadConfig: %v", err)
+	}
+	if cc := cfg.Connectors["calc"]; !cc.IsWasm() {
+		t.Errorf("calc type = %q", cc.Type)
+	}
+	if cfg.Limits.WasmMemoryMB != DefaultWasmMemoryMB {
+		t.Errorf("wasm_memory_mb = %d, want %d", cfg.Limits.WasmMemoryMB, DefaultWasmMemoryMB)
+	}
+
+	for data, want := range map[string]string{
+		`{"connectors":{"x":{"type":"docker","exec":"x","tools":["a"]}}}`:                        `unknown type "docker"`,
+		`{"connectors":{"x":{"type":"wasm","exec":"x","tools":["a"],"secret_account":"x-key"}}}`: "not supported for wasm",
+		`{"limits":{"wasm_memory_mb":8192}}`:                                                     "at most 4096",
+	} {
+		os.WriteFile(path, []byte(data), 0644)
+		if _, err := LoadConfig(path); err == nil || !strings.Contains(err.Error(), want) {
+			t.Errorf("%s: error = %v, want %q", data, err, want)
+		}
+	}
+}
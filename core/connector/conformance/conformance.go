@@ -0,0 +1,432 @@
+// Package conformance runs a connector binary through the protocol checks
+// the daemon relies on: introspect shape, id echoing, error codes, request
+// and response size limits, and call timeouts.
+package conformance
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"strings"
+	"time"
+
+	"github.com/jdelaire/openslack/core/connector"
+)
+
+// Options configures a run. Zero limits take the daemon's defaults.
+type Options struct {
+	Exec         string
+	Args         []string
+	Secret       []byte    // signs requests and verifies replies; nil if unsigned
+	ScratchDir   string    // passed in connector.ScratchEnv if set
+	Stderr       io.Writer // receives the connector's stderr; nil discards it
+	ReqMaxBytes  int
+	RespMaxBytes int
+	CallTimeout  time.Duration
+}
+
+// Result is the outcome of one check.
+type Result struct {
+	Name string
+	Err  error // nil if the check passed
+}
+
+// Passed reports whether the check passed.
+func (r Result) Passed() bool { return r.Err == nil }
+
+type check struct {
+	name string
+	run  func(*session) error
+}
+
+var checks = []check{
+	{"introspect", checkIntrospect},
+	{"id echo", checkIDEcho},
+	{"unknown tool", checkUnknownTool},
+	{"bad version", checkBadVersion},
+	{"malformed json", checkMalformed},
+	{"max-size request", checkMaxSize},
+}
+
+var errExited = errors.New("connector exited")
+
+var knownCodes = map[string]bool{
+	connector.ErrInvalidArgs:    true,
+	connector.ErrNotSupported:   true,
+	connector.ErrInternal:       true,
+	connector.ErrTimeout:        true,
+	connector.ErrUnauthorized:   true,
+	connector.ErrInvalidRequest: true,
+}
+
+// Run starts the connector, runs every check against it in order, then
+// closes its stdin and expects it to exit within the call timeout. The
+// error is non-nil only if the connector cannot be started.
+func Run(opts Options) ([]Result, error) {
+	applyDefaults(&opts)
+	s, err := start(opts)
+	if err != nil {
+		return nil, err
+	}
+
+	results := make([]Result, 0, len(checks)+1)
+	for _, c := range checks {
+		if s.exited() {
+			results = append(results, Result{Name: c.name, Err: errExited})
+			continue
+		}
+		results = append(results, Result{Name: c.name, Err: c.run(s)})
+	}
+	results = append(results, Result{Name: "exit on stdin close", Err: s.stop()})
+	return results, nil
+}
+
+// Report formats results as one PASS or FAIL line per check and a summary.
+func Report(results []Result) string {
+	var b strings.Builder
+	passed := 0
+	for _, r := range results {
+		if r.Passed() {
+			passed++
+			fmt.Fprintf(&b, "PASS %s\n", r.Name)
+		} else {
+			fmt.Fprintf(&b, "FAIL %s: %s\n", r.Name, r.Err)
+		}
+	}
+	fmt.Fprintf(&b, "%d/%d checks passed", passed, len(results))
+	return b.String()
+}
+
+func applyDefaults(opts *Options) {
+	if opts.ReqMaxBytes <= 0 {
+		opts.ReqMaxBytes = connector.DefaultReqMaxBytes
+	}
+	if opts.RespMaxBytes <= 0 {
+		opts.RespMaxBytes = connector.DefaultRespMaxBytes
+	}
+	if opts.CallTimeout <= 0 {
+		opts.CallTimeout = connector.DefaultCallTimeoutMs * time.Millisecond
+	}
+	if opts.Stderr == nil {
+		opts.Stderr = io.Discard
+	}
+}
+
+func checkIntrospect(s *session) error {
+	resp, err := s.call("introspect-1", connector.IntrospectToolName, json.RawMessage(`{}`))
+	if err != nil {
+		return err
+	}
+	if !resp.OK {
+		return fmt.Errorf("failed: %s", resp.Error)
+	}
+	var data connector.IntrospectData
+	if err := json.Unmarshal(resp.Data, &data); err != nil {
+		return fmt.Errorf("decode data: %w", err)
+	}
+	if data.Name == "" || data.Version == "" {
+		return fmt.Errorf("name and version are required")
+	}
+	if len(data.Tools) == 0 {
+		return fmt.Errorf("no tools listed")
+	}
+	seen := make(map[string]bool, len(data.Tools))
+	for _, t := range data.Tools {
+		switch {
+		case t.Name == "":
+			return fmt.Errorf("tool with empty name")
+		case strings.HasPrefix(t.Name, "__"):
+			return fmt.Errorf("tool %q uses reserved prefix __", t.Name)
+		case seen[t.Name]:
+			return fmt.Errorf("tool %q listed twice", t.Name)
+		case t.Risk != "" && t.Risk != "low" && t.Risk != "high":
+			return fmt.Errorf("tool %q: risk %q is not low or high", t.Name, t.Risk)
+		}
+		seen[t.Name] = true
+	}
+	return nil
+}
+
+func checkIDEcho(s *session) error {
+	ids := []string{"req_001", "id with spaces ✓", `quote"back\slash`, strings.Repeat("x", 128)}
+	for _, id := range ids {
+		if _, err := s.call(id, connector.IntrospectToolName, json.RawMessage(`{}`)); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func checkUnknownTool(s *session) error {
+	resp, err := s.call("unknown-1", "conformance_no_such_tool", json.RawMessage(`{}`))
+	if err != nil {
+		return err
+	}
+	return wantError(resp, connector.ErrNotSupported)
+}
+
+func checkBadVersion(s *session) error {
+	req := &connector.Request{Version: "v0", ID: "version-1", Tool: connector.IntrospectToolName, Args: json.RawMessage(`{}`)}
+	resp, err := s.send(req)
+	if err != nil {
+		return err
+	}
+	return wantError(resp, connector.ErrInvalidRequest)
+}
+
+// checkMalformed sends a truncated line. The reply may carry an empty id,
+// since none could be parsed.
+func checkMalformed(s *session) error {
+	if err := s.write([]byte(`{"version":"v1","id":`)); err != nil {
+		return err
+	}
+	resp, err := s.read()
+	if err != nil {
+		return err
+	}
+	if resp.ID != "" {
+		return fmt.Errorf("id %q, want empty", resp.ID)
+	}
+	return wantError(resp, connector.ErrInvalidRequest)
+}
+
+// checkMaxSize sends a request exactly as large as the daemon allows,
+// padded through an extra arg. Any well-formed reply passes.
+func checkMaxSize(s *session) error {
+	req := &connector.Request{Version: connector.ProtocolVersion, ID: "max-1", Tool: connector.IntrospectToolName, Args: json.RawMessage(`{"pad":""}`)}
+	line, err := s.marshal(req)
+	if err != nil {
+		return err
+	}
+	n := s.opts.ReqMaxBytes - len(line)
+	if n < 0 {
+		return fmt.Errorf("req_max_bytes %d is smaller than a minimal request", s.opts.ReqMaxBytes)
+	}
+	req.Args, _ = json.Marshal(map[string]string{"pad": strings.Repeat("x", n)})
+	_, err = s.send(req)
+	return err
+}
+
+func wantError(resp *connector.Response, code string) error {
+	if resp.OK {
+		return fmt.Errorf("ok response, want %s", code)
+	}
+	if resp.Error.Code != code {
+		return fmt.Errorf("error code %s, want %s", resp.Error.Code, code)
+	}
+	return nil
+}
+
+// session is a running connector under test.
+type session struct {
+	opts    Options
+	cmd     *exec.Cmd
+	stdin   io.WriteCloser
+	lines   chan []byte   // stdout lines; closed when stdout ends
+	readErr error         // set before lines is closed
+	waited  chan struct{} // closed when the process has exited
+}
+
+func start(opts Options) (*session, error) {
+	cmd := exec.Command(opts.Exec, opts.Args...)
+	cmd.Stderr = opts.Stderr
+	env := os.Environ()
+	if opts.Secret != nil {
+		env = append(env, connector.SecretEnv+"="+string(opts.Secret))
+	}
+	if opts.ScratchDir != "" {
+		env = append(env, connector.ScratchEnv+"="+opts.ScratchDir)
+	}
+	cmd.Env = env
+
+	stdin, err := cmd.StdinPipe()
+	if err != nil {
+		return nil, fmt.Errorf("stdin pipe: %w", err)
+	}
+	stdout, err := cmd.StdoutPipe()
+	if err != nil {
+		stdin.Close()
+		return nil, fmt.Errorf("stdout pipe: %w", err)
+	}
+	if err := cmd.Start(); err != nil {
+		return nil, fmt.Errorf("exec: %w", err)
+	}
+
+	s := &session{opts: opts, cmd: cmd, stdin: stdin, lines: make(chan []byte, 16), waited: make(chan struct{})}
+	scanner := bufio.NewScanner(stdout)
+	scanner.Buffer(make([]byte, opts.RespMaxBytes), opts.RespMaxBytes)
+	go func() {
+		for scanner.Scan() {
+			s.lines <- append([]byte(nil), scanner.Bytes()...)
+		}
+		s.readErr = scanner.Err()
+		close(s.lines)
+		cmd.Wait()
+		close(s.waited)
+	}()
+	return s, nil
+}
+
+func (s *session) exited() bool {
+	select {
+	case <-s.waited:
+		return true
+	default:
+		return false
+	}
+}
+
+// stop closes stdin and waits for the connector to exit, killing it after
+// the call timeout.
+func (s *session) stop() error {
+	s.stdin.Close()
+	timer := time.NewTimer(s.opts.CallTimeout)
+	defer timer.Stop()
+	for {
+		select {
+		case _, ok := <-s.lines:
+			if ok {
+				continue
+			}
+			select {
+			case <-s.waited:
+				return nil
+			case <-timer.C:
+			}
+		case <-timer.C:
+		}
+		s.cmd.Process.Kill()
+		return fmt.Errorf("still running %s after stdin closed", s.opts.CallTimeout)
+	}
+}
+
+// call sends a well-formed request and checks the reply echoes its id.
+func (s *session) call(id, tool string, args json.RawMessage) (*connector.Response, error) {
+	return s.send(&connector.Request{Version: connector.ProtocolVersion, ID: id, Tool: tool, Args: args})
+}
+
+func (s *session) send(req *connector.Request) (*connector.Response, error) {
+	line, err := s.marshal(req)
+	if err != nil {
+		return nil, err
+	}
+	if len(line) > s.opts.ReqMaxBytes {
+		return nil, fmt.Errorf("request exceeds %d byte limit (%d bytes)", s.opts.ReqMaxBytes, len(line))
+	}
+	if err := s.write(line); err != nil {
+		return nil, err
+	}
+	resp, err := s.read()
+	if err != nil {
+		return nil, err
+	}
+	if resp.ID != req.ID {
+		return nil, fmt.Errorf("response id %q, want %q", resp.ID, req.ID)
+	}
+	return resp, nil
+}
+
+func (s *session) marshal(req *connector.Request) ([]byte, error) {
+	if s.opts.Secret != nil {
+		connector.SignRequest(req, s.opts.Secret)
+	}
+	line, err := json.Marshal(req)
+	if err != nil {
+		return nil, fmt.Errorf("marshal request: %w", err)
+	}
+	return line, nil
+}
+
+func (s *session) write(line []byte) error {
+	if _, err := s.stdin.Write(append(line, '\n')); err != nil {
+		return fmt.Errorf("write request: %w", err)
+	}
+	return nil
+}
+
+// read returns the next response within the call timeout, checking any
+// events written before it.
+func (s *session) read() (*connector.Response, error) {
+	timer := time.NewTimer(s.opts.CallTimeout)
+	defer timer.Stop()
+	for {
+		var line []byte
+		select {
+		case <-timer.C:
+			return nil, fmt.Errorf("no response within %s", s.opts.CallTimeout)
+		case l, ok := <-s.lines:
+			if !ok {
+				if errors.Is(s.readErr, bufio.ErrTooLong) {
+					return nil, fmt.Errorf("line exceeds resp_max_bytes %d", s.opts.RespMaxBytes)
+				}
+				if s.readErr != nil {
+					return nil, fmt.Errorf("read stdout: %w", s.readErr)
+				}
+				return nil, errExited
+			}
+			line = l
+		}
+
+		var probe struct {
+			ID    string `json:"id"`
+			Event string `json:"event"`
+		}
+		if err := json.Unmarshal(line, &probe); err != nil {
+			return nil, fmt.Errorf("invalid json on stdout: %w", err)
+		}
+		if probe.ID == "" && probe.Event != "" {
+			if err := s.checkEvent(line); err != nil {
+				return nil, err
+			}
+			continue
+		}
+		return s.checkResponse(line)
+	}
+}
+
+func (s *session) checkEvent(line []byte) error {
+	var ev connector.Event
+	if err := json.Unmarshal(line, &ev); err != nil {
+		return fmt.Errorf("invalid event: %w", err)
+	}
+	if err := connector.ValidateEvent(&ev); err != nil {
+		return fmt.Errorf("invalid event: %w", err)
+	}
+	if s.opts.Secret != nil {
+		if err := connector.VerifyEvent(&ev, s.opts.Secret); err != nil {
+			return fmt.Errorf("event %s: %w", ev.Event, err)
+		}
+	}
+	return nil
+}
+
+// checkResponse decodes and validates a response line. Unlike
+// connector.ValidateResponse it allows an empty id, which checkMalformed
+// expects.
+func (s *session) checkResponse(line []byte) (*connector.Response, error) {
+	var resp connector.Response
+	if err := json.Unmarshal(line, &resp); err != nil {
+		return nil, fmt.Errorf("invalid response: %w", err)
+	}
+	if resp.Version != connector.ProtocolVersion {
+		return nil, fmt.Errorf("response version %q, want %q", resp.Version, connector.ProtocolVersion)
+	}
+	if !resp.OK {
+		if resp.Error == nil {
+			return nil, fmt.Errorf("error response without error object")
+		}
+		if !knownCodes[resp.Error.Code] {
+			return nil, fmt.Errorf("unknown error code %q", resp.Error.Code)
+		}
+	}
+	if s.opts.Secret != nil {
+		if err := connector.VerifyResponse(&resp, s.opts.Secret); err != nil {
+			return nil, fmt.Errorf("response %q: %w", resp.ID, err)
+		}
+	}
+	return &resp, nil
+}
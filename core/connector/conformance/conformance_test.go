@@ -0,0 +1,105 @@
+package conformance_test
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/jdelaire/openslack/connectors/sdk"
+	"github.com/jdelaire/openslack/core/connector/conformance"
+)
+
+// testConnectorEnv makes the test binary run as a connector: "sdk" for a
+// well-behaved SDK connector, "broken" for one that ignores the protocol.
+const testConnectorEnv = "OPENSLACK_TEST_CONNECTOR"
+
+func TestMain(m *testing.M) {
+	switch os.Getenv(testConnectorEnv) {
+	case "sdk":
+		c := sdk.New("check", "1.0.0")
+		c.Tool("echo", func(_ context.Context, args json.RawMessage) (any, error) {
+			return map[string]string{"text": sdk.TextArg(args)}, nil
+		}, sdk.Mutating())
+		c.Run()
+		os.Exit(0)
+	case "broken":
+		// Answers every line with a fixed id and an unknown error code.
+		scanner := bufio.NewScanner(os.Stdin)
+		for scanner.Scan() {
+			fmt.Println(`{"version":"v1","id":"fixed","ok":false,"error":{"code":"OOPS","message":"no"}}`)
+		}
+		os.Exit(0)
+	}
+	os.Exit(m.Run())
+}
+
+func TestRunSampleConnector(t *testing.T) {
+	bin := filepath.Join(t.TempDir(), "sample-connector")
+	cmd := exec.Command("go", "build", "-o", bin, "../../../connectors/sample")
+	if out, err := cmd.CombinedOutput(); err != nil {
+		t.Fatalf("build sample-connector: %v\n%s", err, out)
+	}
+
+	results, err := conformance.Run(conformance.Options{Exec: bin, CallTimeout: 5 * time.Second})
+	if err != nil {
+		t.Fatalf("run: %v", err)
+	}
+	for _, r := range results {
+		if !r.Passed() {
+			t.Errorf("%s: %v", r.Name, r.Err)
+		}
+	}
+}
+
+func TestRunSDKConnector(t *testing.T) {
+	t.Setenv(testConnectorEnv, "sdk")
+	for _, secret := range []string{"", "s3cret"} {
+		opts := conformance.Options{Exec: os.Args[0], CallTimeout: 5 * time.Second}
+		if secret != "" {
+			t.Setenv("OPENSLACK_CONNECTOR_SECRET", secret)
+			opts.Secret = []byte(secret)
+		}
+		results, err := conformance.Run(opts)
+		if err != nil {
+			t.Fatalf("run: %v", err)
+		}
+		for _, r := range results {
+			if !r.Passed() {
+				t.Errorf("secret %q: %s: %v", secret, r.Name, r.Err)
+			}
+		}
+	}
+}
+
+func TestRunBrokenConnector(t *testing.T) {
+	t.Setenv(testConnectorEnv, "broken")
+	results, err := conformance.Run(conformance.Options{Exec: os.Args[0], CallTimeout: 2 * time.Second})
+	if err != nil {
+		t.Fatalf("run: %v", err)
+	}
+
+	report := conformance.Report(results)
+	for _, want := range []string{
+		`FAIL introspect: unknown error code "OOPS"`,
+		"FAIL malformed json:",
+		"PASS exit on stdin close",
+		fmt.Sprintf("1/%d checks passed", len(results)),
+	} {
+		if !strings.Contains(report, want) {
+			t.Errorf("report missing %q:\n%s", want, report)
+		}
+	}
+}
+
+func TestRunMissingBinary(t *testing.T) {
+	if _, err := conformance.Run(conformance.Options{Exec: filepath.Join(t.TempDir(), "missing")}); err == nil {
+		t.Error("run with a missing binary succeeded")
+	}
+}
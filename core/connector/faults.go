@@ -0,0 +1,92 @@
+package connector
+
+import (
+	"fmt"
+	"math/rand/v2"
+	"sync"
+	"time"
+)
+
+// Fault kinds the Manager can inject into connector calls.
+const (
+	FaultDelay = "delay" // hold the request for part of the call timeout
+	FaultDrop  = "drop"  // send the request but ignore the reply, so the call times out
+	FaultKill  = "kill"  // kill the connector process before sending
+)
+
+var faultKinds = []string{FaultDelay, FaultDrop, FaultKill}
+
+// FaultConfig injects failures into connector calls on the daemon side,
+// to exercise error handling and restarts in CI or in a deployment being
+// validated. Rate is the chance, from 0 to 1, that a call gets a fault;
+// Kinds limits the faults to choose from, all of them when empty. A
+// nonzero Seed makes the sequence of faults repeatable.
+type FaultConfig struct {
+	Rate  float64  `json:"rate"`
+	Kinds []string `json:"kinds"`
+	Seed  uint64   `json:"seed"`
+}
+
+func validateFaults(f *FaultConfig) error {
+	if f == nil {
+		return nil
+	}
+	if f.Rate < 0 || f.Rate > 1 {
+		return fmt.Errorf("faults: rate %v must be between 0 and 1", f.Rate)
+	}
+	for _, k := range f.Kinds {
+		if !isFaultKind(k) {
+			return fmt.Errorf("faults: unknown kind %q", k)
+		}
+	}
+	return nil
+}
+
+func isFaultKind(kind string) bool {
+	for _, k := range faultKinds {
+		if k == kind {
+			return true
+		}
+	}
+	return false
+}
+
+// faultInjector picks the fault, if any, for each call.
+type faultInjector struct {
+	rate  float64
+	kinds []string
+
+	mu  sync.Mutex
+	rnd *rand.Rand
+}
+
+// newFaultInjector returns an injector for f, or nil if f injects nothing.
+func newFaultInjector(f *FaultConfig) *faultInjector {
+	if f == nil || f.Rate <= 0 {
+		return nil
+	}
+	kinds := f.Kinds
+	if len(kinds) == 0 {
+		kinds = faultKinds
+	}
+	seed := f.Seed
+	if seed == 0 {
+		seed = uint64(time.Now().UnixNano())
+	}
+	return &faultInjector{rate: f.Rate, kinds: kinds, rnd: rand.New(rand.NewPCG(seed, seed))}
+}
+
+// pick returns the fault to inject into a call, or "" for none, and how
+// long a delay fault holds the call within timeout.
+func (fi *faultInjector) pick(timeout time.Duration) (string, time.Duration) {
+	if fi == nil {
+		return "", 0
+	}
+	fi.mu.Lock()
+	defer fi.mu.Unlock()
+	if fi.rnd.Float64() >= fi.rate {
+		return "", 0
+	}
+	kind := fi.kinds[fi.rnd.IntN(len(fi.kinds))]
+	return kind, time.Duration(fi.rnd.Int64N(int64(timeout)))
+}
@@ -3,6 +3,8 @@ package connector_test
 import (
 	"context"
 	"encoding/json"
+	"fmt"
+	"io"
 	"log/slog"
 	"os"
 	"os/exec"
@@ -11,30 +13,73 @@ import (
 	"testing"
 	"time"
 
+	"github.com/jdelaire/openslack/connectors/sdk"
 	"github.com/jdelaire/openslack/core/connector"
+	"github.com/jdelaire/openslack/core/ops"
 )
 
+// sdkConnectorEnv makes the test binary run as an SDK connector, for the
+// signing tests.
+const sdkConnectorEnv = "OPENSLACK_TEST_SDK_CONNECTOR"
+
+func TestMain(m *testing.M) {
+	if os.Getenv(sdkConnectorEnv) == "1" {
+		runSDKConnector()
+		return
+	}
+	os.Exit(m.Run())
+}
+
+func runSDKConnector() {
+	c := sdk.New("signed", "1.0.0")
+	c.Tool("echo", func(_ context.Context, args json.RawMessage) (any, error) {
+		return map[string]string{"text": sdk.TextArg(args)}, nil
+	})
+	c.Tool("notify", func(_ context.Context, args json.RawMessage) (any, error) {
+		return map[string]string{}, c.Emit("notice", sdk.TextArg(args), nil)
+	})
+	c.Tool("wipe", func(context.Context, json.RawMessage) (any, error) {
+		return map[string]string{}, nil
+	}, sdk.Mutating())
+	c.Tool("stash", func(_ context.Context, args json.RawMessage) (any, error) {
+		path := filepath.Join(sdk.ScratchDir(), "stash")
+		if err := os.WriteFile(path, []byte(sdk.TextArg(args)), 0600); err != nil {
+			return nil, err
+		}
+		return map[string]string{"path": path}, nil
+	})
+	if err := c.Serve(context.Background(), os.Stdin, os.Stdout); err != nil {
+		os.Exit(1)
+	}
+	os.Exit(0)
+}
+
 // buildSampleConnector compiles the sample connector to a temp dir and returns the path.
 func buildSampleConnector(t *testing.T) string {
+	t.Helper()
+	return buildConnector(t, "sample")
+}
+
+// buildConnector compiles connectors/<name> to a temp dir and returns the path.
+func buildConnector(t *testing.T, name string) string {
 	t.Helper()
 	dir := t.TempDir()
-	bin := filepath.Join(dir, "sample-connector")
+	bin := filepath.Join(dir, name+"-connector")
 
 	// Find the project root by walking up from the test file.
-	// The connector source is at connectors/sample/main.go relative to repo root.
 	wd, err := os.Getwd()
 	if err != nil {
 		t.Fatalf("getwd: %v", err)
 	}
 	// We're in core/connector, go up two levels.
 	root := filepath.Join(wd, "..", "..")
-	src := filepath.Join(root, "connectors", "sample")
+	src := filepath.Join(root, "connectors", name)
 
 	cmd := exec.Command("go", "build", "-o", bin, src)
 	cmd.Dir = root
 	out, err := cmd.CombinedOutput()
 	if err != nil {
-		t.Fatalf("build sample-connector: %v\n%s", err, out)
+		t.Fatalf("build %s-connector: %v\n%s", name, err, out)
 	}
 	return bin
 }
@@ -153,6 +198,31 @@ func TestIntegrationIntrospect(t *testing.T) {
 	}
 }
 
+func TestIntegrationIntrospectChecks(t *testing.T) {
+	bin := buildSampleConnector(t)
+	cfg := testConfig(bin)
+	logger := slog.New(slog.NewJSONHandler(os.Stderr, &slog.HandlerOptions{Level: slog.LevelDebug}))
+
+	mgr := connector.NewManager(cfg, logger)
+	if err := mgr.Start(); err != nil {
+		t.Fatalf("start: %v", err)
+	}
+	defer mgr.Shutdown()
+
+	checks := connector.NewRouter(cfg, mgr, logger).IntrospectChecks()
+	if len(checks) != 1 {
+		t.Fatalf("got %d checks, want 1", len(checks))
+	}
+	if err := checks[0].Run(context.Background()); err != nil {
+		t.Errorf("%s: %v", checks[0].Name, err)
+	}
+
+	mgr.StopConnector("sample")
+	if err := checks[0].Run(context.Background()); err == nil {
+		t.Error("check passed with the connector stopped")
+	}
+}
+
 func TestIntegrationUnknownConnector(t *testing.T) {
 	bin := buildSampleConnector(t)
 	cfg := testConfig(bin)
@@ -357,3 +427,376 @@ func TestIntegrationMultipleCalls(t *testing.T) {
 		}
 	}
 }
+
+func TestIntegrationEvent(t *testing.T) {
+	bin := buildSampleConnector(t)
+	cfg := &connector.Config{
+		Connectors: map[string]connector.ConnectorConfig{
+			"sample": {
+				Exec:  bin,
+				Tools: []string{"notify", "echo"},
+			},
+		},
+		Limits: connector.LimitsConfig{
+			ReqMaxBytes:   4096,
+			RespMaxBytes:  16384,
+			CallTimeoutMs: 5000,
+		},
+	}
+	logger := slog.New(slog.NewJSONHandler(os.Stderr, &slog.HandlerOptions{Level: slog.LevelDebug}))
+
+	events := make(chan *connector.Event, 1)
+	mgr := connector.NewManager(cfg, logger)
+	mgr.SetEventHandler(func(name string, ev *connector.Event) {
+		if name != "sample" {
+			t.Errorf("event from %q, want sample", name)
+		}
+		events <- ev
+	})
+	if err := mgr.Start(); err != nil {
+		t.Fatalf("start: %v", err)
+	}
+	defer mgr.Shutdown()
+
+	router := connector.NewRouter(cfg, mgr, logger)
+
+	// The event line precedes the response; the call must still get its response.
+	resp, err := router.Call(context.Background(), "sample.notify", json.RawMessage(`{"text":"build failed"}`))
+	if err != nil {
+		t.Fatalf("call: %v", err)
+	}
+	if !resp.OK {
+		t.Fatalf("expected ok, got error: %v", resp.Error)
+	}
+
+	select {
+	case ev := <-events:
+		if ev.Event != "notice" || ev.Text != "build failed" {
+			t.Errorf("event = %+v", ev)
+		}
+	case <-time.After(2 * time.Second):
+		t.Fatal("no event received")
+	}
+
+	// Subsequent calls are unaffected.
+	resp, err = router.Call(context.Background(), "sample.echo", json.RawMessage(`{"text":"ping"}`))
+	if err != nil || !resp.OK {
+		t.Fatalf("echo after event: %v %+v", err, resp)
+	}
+}
+
+func TestIntegrationSlowEventHandler(t *testing.T) {
+	bin := buildSampleConnector(t)
+	cfg := &connector.Config{
+		Connectors: map[string]connector.ConnectorConfig{
+			"sample": {
+				Exec:  bin,
+				Tools: []string{"notify", "echo"},
+			},
+		},
+		Limits: connector.LimitsConfig{
+			ReqMaxBytes:   4096,
+			RespMaxBytes:  16384,
+			CallTimeoutMs: 2000,
+		},
+	}
+	logger := slog.New(slog.NewJSONHandler(io.Discard, nil))
+
+	release := make(chan struct{})
+	handled := make(chan string, 2)
+	mgr := connector.NewManager(cfg, logger)
+	mgr.SetEventHandler(func(_ string, ev *connector.Event) {
+		<-release
+		handled <- ev.Text
+	})
+	if err := mgr.Start(); err != nil {
+		t.Fatalf("start: %v", err)
+	}
+	defer mgr.Shutdown()
+	router := connector.NewRouter(cfg, mgr, logger)
+
+	// The handler is stuck on the first event, yet calls still get their
+	// responses.
+	for _, text := range []string{"first", "second"} {
+		resp, err := router.Call(context.Background(), "sample.notify", json.RawMessage(`{"text":"`+text+`"}`))
+		if err != nil || !resp.OK {
+			t.Fatalf("notify %s with a blocked handler: %v %+v", text, err, resp)
+		}
+	}
+	resp, err := router.Call(context.Background(), "sample.echo", json.RawMessage(`{"text":"ping"}`))
+	if err != nil || !resp.OK {
+		t.Fatalf("echo with a blocked handler: %v %+v", err, resp)
+	}
+
+	close(release)
+	for _, want := range []string{"first", "second"} {
+		select {
+		case got := <-handled:
+			if got != want {
+				t.Errorf("handled %q, want %q", got, want)
+			}
+		case <-time.After(2 * time.Second):
+			t.Fatalf("event %q not handled", want)
+		}
+	}
+}
+
+func TestIntegrationRunning(t *testing.T) {
+	bin := buildSampleConnector(t)
+	cfg := testConfig(bin)
+	logger := slog.New(slog.NewJSONHandler(os.Stderr, &slog.HandlerOptions{Level: slog.LevelDebug}))
+
+	mgr := connector.NewManager(cfg, logger)
+	if err := mgr.Start(); err != nil {
+		t.Fatalf("start: %v", err)
+	}
+	defer mgr.Shutdown()
+
+	if got := mgr.Running(); got != 1 {
+		t.Errorf("running = %d, want 1", got)
+	}
+	if err := mgr.StopConnector("sample"); err != nil {
+		t.Fatalf("stop: %v", err)
+	}
+	if got := mgr.Running(); got != 0 {
+		t.Errorf("running after stop = %d, want 0", got)
+	}
+}
+
+func signedConfig(bin string) *connector.Config {
+	return &connector.Config{
+		Connectors: map[string]connector.ConnectorConfig{
+			"signed": {
+				Exec:          bin,
+				Tools:         []string{"echo", "notify"},
+				SecretAccount: "signed-connector-secret",
+			},
+		},
+		Limits: connector.LimitsConfig{
+			ReqMaxBytes:   4096,
+			RespMaxBytes:  16384,
+			CallTimeoutMs: 1000,
+		},
+	}
+}
+
+func secretLookup(account string) (string, error) {
+	if account != "signed-connector-secret" {
+		return "", fmt.Errorf("no account %q", account)
+	}
+	return "s3cret", nil
+}
+
+func TestIntegrationSigned(t *testing.T) {
+	t.Setenv(sdkConnectorEnv, "1")
+	cfg := signedConfig(os.Args[0])
+	logger := slog.New(slog.NewJSONHandler(os.Stderr, &slog.HandlerOptions{Level: slog.LevelDebug}))
+
+	events := make(chan *connector.Event, 1)
+	mgr := connector.NewManager(cfg, logger)
+	mgr.SetSecretLookup(secretLookup)
+	mgr.SetEventHandler(func(_ string, ev *connector.Event) { events <- ev })
+	if err := mgr.Start(); err != nil {
+		t.Fatalf("start: %v", err)
+	}
+	defer mgr.Shutdown()
+
+	router := connector.NewRouter(cfg, mgr, logger)
+	resp, err := router.Call(context.Background(), "signed.echo", json.RawMessage(`{"text":"hello"}`))
+	if err != nil || !resp.OK || string(resp.Data) != `{"text":"hello"}` {
+		t.Fatalf("echo: %v %+v", err, resp)
+	}
+
+	if _, err := router.Call(context.Background(), "signed.notify", json.RawMessage(`{"text":"build failed"}`)); err != nil {
+		t.Fatalf("notify: %v", err)
+	}
+	select {
+	case ev := <-events:
+		if ev.Text != "build failed" {
+			t.Errorf("event = %+v", ev)
+		}
+	case <-time.After(2 * time.Second):
+		t.Fatal("signed event not delivered")
+	}
+}
+
+func TestIntegrationSignedRejectsUnsigned(t *testing.T) {
+	// The sample connector does not sign, so its replies are discarded
+	// as if another process had written them.
+	cfg := signedConfig(buildSampleConnector(t))
+	logger := slog.New(slog.NewJSONHandler(os.Stderr, &slog.HandlerOptions{Level: slog.LevelDebug}))
+
+	mgr := connector.NewManager(cfg, logger)
+	mgr.SetSecretLookup(secretLookup)
+	if err := mgr.Start(); err != nil {
+		t.Fatalf("start: %v", err)
+	}
+	defer mgr.Shutdown()
+
+	router := connector.NewRouter(cfg, mgr, logger)
+	_, err := router.Call(context.Background(), "signed.echo", json.RawMessage(`{"text":"hello"}`))
+	if err == nil || !strings.Contains(err.Error(), "timed out") {
+		t.Errorf("unsigned reply: err = %v, want timeout", err)
+	}
+}
+
+func TestIntegrationSignedNeedsLookup(t *testing.T) {
+	mgr := connector.NewManager(signedConfig(os.Args[0]), slog.New(slog.NewJSONHandler(io.Discard, nil)))
+	if err := mgr.Start(); err == nil || !strings.Contains(err.Error(), "no secret lookup") {
+		mgr.Shutdown()
+		t.Errorf("start without lookup: %v", err)
+	}
+}
+
+func TestIntegrationDeclaredRisk(t *testing.T) {
+	t.Setenv(sdkConnectorEnv, "1")
+	cfg := &connector.Config{
+		Connectors: map[string]connector.ConnectorConfig{
+			"sdk": {Exec: os.Args[0], Tools: []string{"echo", "wipe"}},
+			// Unsigned replies are discarded, so introspection fails.
+			"broken": {Exec: buildSampleConnector(t), Tools: []string{"echo"}, SecretAccount: "signed-connector-secret"},
+		},
+		Limits: connector.LimitsConfig{ReqMaxBytes: 4096, RespMaxBytes: 16384, CallTimeoutMs: 1000},
+	}
+	logger := slog.New(slog.NewJSONHandler(io.Discard, nil))
+
+	mgr := connector.NewManager(cfg, logger)
+	mgr.SetSecretLookup(secretLookup)
+	if err := mgr.Start(); err != nil {
+		t.Fatalf("start: %v", err)
+	}
+	defer mgr.Shutdown()
+
+	registry := ops.NewRegistry()
+	if err := connector.RegisterOps(cfg, connector.NewRouter(cfg, mgr, logger), registry); err != nil {
+		t.Fatalf("register: %v", err)
+	}
+	want := map[string]ops.RiskLevel{
+		"sdk.echo":    ops.RiskLow,
+		"sdk.wipe":    ops.RiskHigh,
+		"broken.echo": ops.RiskHigh,
+	}
+	for name, risk := range want {
+		if got := registry.RiskOf(registry.Get(name)); got != risk {
+			t.Errorf("%s risk = %v, want %v", name, got, risk)
+		}
+	}
+}
+
+func TestIntegrationBatchCall(t *testing.T) {
+	t.Setenv(sdkConnectorEnv, "1")
+	cfg := &connector.Config{
+		Connectors: map[string]connector.ConnectorConfig{
+			"sdk":    {Exec: os.Args[0], Tools: []string{"echo", "wipe"}},
+			"sample": {Exec: buildSampleConnector(t), Tools: []string{"echo", "time"}},
+		},
+		Limits: connector.LimitsConfig{ReqMaxBytes: 4096, RespMaxBytes: 16384, CallTimeoutMs: 5000},
+	}
+	logger := slog.New(slog.NewJSONHandler(io.Discard, nil))
+
+	mgr := connector.NewManager(cfg, logger)
+	if err := mgr.Start(); err != nil {
+		t.Fatalf("start: %v", err)
+	}
+	defer mgr.Shutdown()
+	router := connector.NewRouter(cfg, mgr, logger)
+
+	calls := []connector.ToolCall{
+		{Tool: "echo", Args: json.RawMessage(`{"text":"one"}`)},
+		{Tool: "echo", Args: json.RawMessage(`{"text":"two"}`)},
+	}
+	// The sample connector has no __batch, so the calls go one at a time.
+	for _, name := range []string{"sdk", "sample"} {
+		resps, err := router.BatchCall(context.Background(), name, calls)
+		if err != nil {
+			t.Fatalf("%s: batch: %v", name, err)
+		}
+		if len(resps) != 2 || !resps[0].OK || !resps[1].OK ||
+			string(resps[0].Data) != `{"text":"one"}` || string(resps[1].Data) != `{"text":"two"}` {
+			t.Errorf("%s: responses = %+v", name, resps)
+		}
+	}
+
+	if _, err := router.BatchCall(context.Background(), "sdk", []connector.ToolCall{{Tool: "notify"}}); err == nil {
+		t.Error("batch with a tool not in config succeeded")
+	}
+}
+
+func TestIntegrationScratchDir(t *testing.T) {
+	t.Setenv(sdkConnectorEnv, "1")
+	root := t.TempDir()
+	stale := filepath.Join(root, "removed")
+	if err := os.MkdirAll(stale, 0700); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.MkdirAll(filepath.Join(root, "sdk"), 0755); err != nil {
+		t.Fatal(err)
+	}
+	cfg := &connector.Config{
+		Connectors: map[string]connector.ConnectorConfig{
+			"sdk": {Exec: os.Args[0], Tools: []string{"stash"}},
+		},
+		Limits:     connector.LimitsConfig{ReqMaxBytes: 4096, RespMaxBytes: 16384, CallTimeoutMs: 5000},
+		ScratchDir: root,
+	}
+	logger := slog.New(slog.NewJSONHandler(io.Discard, nil))
+
+	mgr := connector.NewManager(cfg, logger)
+	if err := mgr.Start(); err != nil {
+		t.Fatalf("start: %v", err)
+	}
+	defer mgr.Shutdown()
+
+	if _, err := os.Stat(stale); !os.IsNotExist(err) {
+		t.Errorf("scratch dir of removed connector still exists: %v", err)
+	}
+	dir := filepath.Join(root, "sdk")
+	if info, err := os.Stat(dir); err != nil || info.Mode().Perm() != 0700 {
+		t.Fatalf("scratch dir = %v, %v; want mode 0700", info, err)
+	}
+
+	router := connector.NewRouter(cfg, mgr, logger)
+	resp, err := router.Call(context.Background(), "sdk.stash", json.RawMessage(`{"text":"token"}`))
+	if err != nil || !resp.OK {
+		t.Fatalf("stash: %+v, %v", resp, err)
+	}
+	if string(resp.Data) != `{"path":"`+filepath.Join(dir, "stash")+`"}` {
+		t.Errorf("stash data = %s", resp.Data)
+	}
+
+	st := mgr.Status()
+	if len(st) != 1 || st[0].Name != "sdk" || !st[0].Running || st[0].ScratchDir != dir || st[0].ScratchBytes != 5 {
+		t.Errorf("status = %+v", st)
+	}
+	op := &connector.ConnectorsOp{Manager: func() *connector.Manager { return mgr }}
+	out, err := op.Execute(context.Background(), "")
+	if err != nil || !strings.Contains(out, "running") || !strings.Contains(out, "5 B") {
+		t.Errorf("connectors op = %q, %v", out, err)
+	}
+}
+
+func BenchmarkRouterCall(b *testing.B) {
+	b.Setenv(sdkConnectorEnv, "1")
+	cfg := &connector.Config{
+		Connectors: map[string]connector.ConnectorConfig{
+			"sdk": {Exec: os.Args[0], Tools: []string{"echo"}},
+		},
+		Limits: connector.LimitsConfig{ReqMaxBytes: 4096, RespMaxBytes: 16384, CallTimeoutMs: 5000},
+	}
+	logger := slog.New(slog.NewJSONHandler(io.Discard, nil))
+	mgr := connector.NewManager(cfg, logger)
+	if err := mgr.Start(); err != nil {
+		b.Fatalf("start: %v", err)
+	}
+	defer mgr.Shutdown()
+	router := connector.NewRouter(cfg, mgr, logger)
+	args := json.RawMessage(`{"text":"hello world"}`)
+
+	b.ReportAllocs()
+	for b.Loop() {
+		resp, err := router.Call(context.Background(), "sdk.echo", args)
+		if err != nil || !resp.OK {
+			b.Fatalf("call: %+v, %v", resp, err)
+		}
+	}
+}
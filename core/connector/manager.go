@@ -2,58 +2,153 @@ package connector
 
 import (
 	"bufio"
+	"bytes"
 	"context"
 	"encoding/json"
 	"fmt"
 	"io"
 	"log/slog"
+	"os"
 	"os/exec"
 	"sync"
 	"time"
+
+	"github.com/jdelaire/openslack/core/ops"
+	"github.com/jdelaire/openslack/core/redact"
+	"github.com/jdelaire/openslack/internal/events"
+	"github.com/tetratelabs/wazero"
 )
 
-// Manager owns the lifecycle of connector processes and routes calls.
+// EventHandler receives events emitted by a connector. Each connector's
+// events are handled one at a time, in order, apart from its stdout
+// reader, so a slow handler delays later events but not call responses.
+type EventHandler func(connector string, ev *Event)
+
+// eventBacklog is how many of a connector's events may wait for the
+// handler before more are dropped.
+const eventBacklog = 64
+
+// Manager owns the lifecycle of connector processes and wasm modules, and
+// routes calls.
 type Manager struct {
 	cfg    *Config
 	logger *slog.Logger
 
-	mu    sync.RWMutex
-	procs map[string]*connectorProc
+	mu      sync.RWMutex
+	procs   map[string]*connectorProc
+	wasm    map[string]*wasmConnector
+	wasmRT  wazero.Runtime // created with the first wasm connector
+	onEvent EventHandler
+	secrets SecretLookup
+	redact  *redact.Redactor
+	events  *events.Bus
+
+	restartMu sync.Mutex // serializes restarts of exited connectors
+	faults    *faultInjector
 }
 
+// SecretLookup returns the secret stored under a keychain account.
+type SecretLookup func(account string) (string, error)
+
 // connectorProc tracks a running connector child process.
 type connectorProc struct {
-	name   string
-	cmd    *exec.Cmd
-	stdin  io.WriteCloser
-	stdout *bufio.Scanner
-	mu     sync.Mutex // serializes requests to this connector
+	name    string
+	exec    string
+	cmd     *exec.Cmd
+	stdin   io.WriteCloser
+	lines   chan []byte   // response lines; closed when stdout ends
+	events  chan []byte   // event lines for eventLoop; closed when stdout ends
+	readErr error         // set before lines is closed
+	done    chan struct{} // closed when stdout ends
+	secret  []byte        // signs requests and verifies replies; nil if unsigned
+	mu      sync.Mutex    // serializes requests to this connector
+	buf     bytes.Buffer  // request encoding buffer, reused under mu
 }
 
 // NewManager creates a connector manager from config.
 func NewManager(cfg *Config, logger *slog.Logger) *Manager {
+	if cfg.Faults != nil && cfg.Faults.Rate > 0 {
+		logger.Warn("connector fault injection enabled", "rate", cfg.Faults.Rate, "kinds", cfg.Faults.Kinds)
+	}
 	return &Manager{
 		cfg:    cfg,
 		logger: logger,
 		procs:  make(map[string]*connectorProc),
+		wasm:   make(map[string]*wasmConnector),
+		faults: newFaultInjector(cfg.Faults),
 	}
 }
 
+// SetEventHandler sets the handler for connector events. Events arriving
+// with no handler set are logged and dropped.
+func (m *Manager) SetEventHandler(h EventHandler) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.onEvent = h
+}
+
+// SetSecretLookup sets how the secret_account of signed connectors is
+// resolved. Starting a connector with a secret_account fails without it.
+func (m *Manager) SetSecretLookup(f SecretLookup) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.secrets = f
+}
+
+// SetRedactor sets the patterns used to mask secrets in logged connector
+// stderr. A nil r uses the defaults. It applies to connectors started
+// afterwards.
+func (m *Manager) SetRedactor(r *redact.Redactor) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.redact = r
+}
+
+// SetEvents publishes an events.ConnectorCrashed event to bus whenever a
+// connector process has to be restarted.
+func (m *Manager) SetEvents(bus *events.Bus) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.events = bus
+}
+
 // Start launches all configured connectors.
+// Scratch directories of connectors no longer configured are removed first.
 func (m *Manager) Start() error {
+	m.pruneScratch()
 	for name, cc := range m.cfg.Connectors {
-		if err := m.startConnector(name, cc.Exec); err != nil {
+		if err := m.StartConnector(name, cc.Exec); err != nil {
 			m.Shutdown()
 			return fmt.Errorf("start connector %q: %w", name, err)
 		}
-		m.logger.Info("connector started", "name", name, "exec", cc.Exec)
+		m.logger.Info("connector started", "name", name, "exec", cc.Exec, "type", cc.Type)
 	}
 	return nil
 }
 
 func (m *Manager) startConnector(name, execPath string) error {
+	secret, err := m.secret(name)
+	if err != nil {
+		return err
+	}
+
+	m.mu.RLock()
+	r := m.redact
+	m.mu.RUnlock()
+
 	cmd := exec.Command(execPath)
-	cmd.Stderr = &logWriter{logger: m.logger, connector: name}
+	cmd.Stderr = &logWriter{logger: m.logger, connector: name, redact: r}
+	env := os.Environ()
+	if secret != nil {
+		env = append(env, SecretEnv+"="+string(secret))
+	}
+	if dir := m.cfg.ScratchPath(name); dir != "" {
+		if err := prepareScratch(dir); err != nil {
+			return err
+		}
+		env = append(env, ScratchEnv+"="+dir)
+	}
+	cmd.Env = env
 
 	stdin, err := cmd.StdinPipe()
 	if err != nil {
@@ -75,10 +170,16 @@ func (m *Manager) startConnector(name, execPath string) error {
 
 	proc := &connectorProc{
 		name:   name,
+		exec:   execPath,
 		cmd:    cmd,
 		stdin:  stdin,
-		stdout: scanner,
+		lines:  make(chan []byte, 1),
+		events: make(chan []byte, eventBacklog),
+		done:   make(chan struct{}),
+		secret: secret,
 	}
+	go m.readLoop(proc, scanner)
+	go m.eventLoop(proc)
 
 	m.mu.Lock()
 	m.procs[name] = proc
@@ -87,22 +188,119 @@ func (m *Manager) startConnector(name, execPath string) error {
 	return nil
 }
 
+// readLoop reads a connector's stdout for its lifetime, queueing events
+// for eventLoop and handing responses to the pending Call. It never waits
+// on the event handler.
+func (m *Manager) readLoop(proc *connectorProc, scanner *bufio.Scanner) {
+	defer close(proc.done)
+	defer close(proc.lines)
+	defer close(proc.events)
+	for scanner.Scan() {
+		// Copy the bytes since scanner reuses the buffer.
+		line := make([]byte, len(scanner.Bytes()))
+		copy(line, scanner.Bytes())
+
+		if isEvent(line) {
+			select {
+			case proc.events <- line:
+			default:
+				m.logger.Warn("dropping connector event: handler backlog full", "connector", proc.name)
+			}
+			continue
+		}
+
+		select {
+		case proc.lines <- line:
+		default:
+			m.logger.Warn("dropping unsolicited connector output", "connector", proc.name)
+		}
+	}
+	proc.readErr = scanner.Err()
+}
+
+// eventLoop hands a connector's queued events to the event handler until
+// its stdout ends.
+func (m *Manager) eventLoop(proc *connectorProc) {
+	for line := range proc.events {
+		m.handleEvent(proc.name, proc.secret, line)
+	}
+}
+
+// secret resolves the shared secret of the named connector, or returns
+// nil if it has no secret_account.
+func (m *Manager) secret(name string) ([]byte, error) {
+	account := m.cfg.Connectors[name].SecretAccount
+	if account == "" {
+		return nil, nil
+	}
+	m.mu.RLock()
+	lookup := m.secrets
+	m.mu.RUnlock()
+	if lookup == nil {
+		return nil, fmt.Errorf("secret_account %q set but no secret lookup configured", account)
+	}
+	secret, err := lookup(account)
+	if err != nil {
+		return nil, fmt.Errorf("read secret %q: %w", account, err)
+	}
+	if secret == "" {
+		return nil, fmt.Errorf("secret %q is empty", account)
+	}
+	return []byte(secret), nil
+}
+
+// handleEvent validates an event line from the named connector and hands
+// it to the event handler. secret, if set, must have signed it.
+func (m *Manager) handleEvent(name string, secret []byte, line []byte) {
+	var ev Event
+	if err := json.Unmarshal(line, &ev); err != nil {
+		m.logger.Warn("invalid connector event", "connector", name, "error", err)
+		return
+	}
+	if err := ValidateEvent(&ev); err != nil {
+		m.logger.Warn("invalid connector event", "connector", name, "error", err)
+		return
+	}
+	if secret != nil {
+		if err := VerifyEvent(&ev, secret); err != nil {
+			m.logger.Warn("discarding unverified connector event", "connector", name, "error", err)
+			return
+		}
+	}
+
+	m.mu.RLock()
+	h := m.onEvent
+	m.mu.RUnlock()
+	if h == nil {
+		m.logger.Info("connector event dropped: no handler", "connector", name, "event", ev.Event)
+		return
+	}
+	h(name, &ev)
+}
+
 // Call sends a request to a connector and returns the response.
 func (m *Manager) Call(ctx context.Context, connectorName string, req *Request) (*Response, error) {
 	m.mu.RLock()
 	proc, ok := m.procs[connectorName]
+	w := m.wasm[connectorName]
 	m.mu.RUnlock()
+	if w != nil {
+		return m.callWasm(ctx, w, req)
+	}
 	if !ok {
 		return nil, fmt.Errorf("connector %q not running", connectorName)
 	}
-
-	// Enforce request size limit.
-	reqData, err := json.Marshal(req)
-	if err != nil {
-		return nil, fmt.Errorf("marshal request: %w", err)
+	select {
+	case <-proc.done:
+		var err error
+		if proc, err = m.restart(proc); err != nil {
+			return nil, err
+		}
+	default:
 	}
-	if len(reqData) > m.cfg.Limits.ReqMaxBytes {
-		return nil, fmt.Errorf("request exceeds %d byte limit (%d bytes)", m.cfg.Limits.ReqMaxBytes, len(reqData))
+
+	if proc.secret != nil {
+		SignRequest(req, proc.secret)
 	}
 
 	timeout := time.Duration(m.cfg.Limits.CallTimeoutMs) * time.Millisecond
@@ -113,60 +311,117 @@ func (m *Manager) Call(ctx context.Context, connectorName string, req *Request)
 	proc.mu.Lock()
 	defer proc.mu.Unlock()
 
-	// Write request.
-	reqData = append(reqData, '\n')
-	if _, err := proc.stdin.Write(reqData); err != nil {
-		return nil, fmt.Errorf("write to connector %q: %w", connectorName, err)
+	// Encode the request line, newline included, and enforce the size limit.
+	proc.buf.Reset()
+	if err := json.NewEncoder(&proc.buf).Encode(req); err != nil {
+		return nil, fmt.Errorf("marshal request: %w", err)
+	}
+	if n := proc.buf.Len() - 1; n > m.cfg.Limits.ReqMaxBytes {
+		return nil, fmt.Errorf("request exceeds %d byte limit (%d bytes)", m.cfg.Limits.ReqMaxBytes, n)
 	}
 
-	// Read response with timeout.
-	type scanResult struct {
-		line []byte
-		err  error
-	}
-	ch := make(chan scanResult, 1)
-	go func() {
-		if proc.stdout.Scan() {
-			// Copy the bytes since scanner reuses the buffer.
-			line := make([]byte, len(proc.stdout.Bytes()))
-			copy(line, proc.stdout.Bytes())
-			ch <- scanResult{line: line}
-		} else {
-			ch <- scanResult{err: proc.stdout.Err()}
+	fault, delay := m.faults.pick(timeout)
+	if fault != "" {
+		m.logger.Warn("injecting connector fault", "connector", connectorName, "id", req.ID, "fault", fault)
+	}
+	switch fault {
+	case FaultDelay:
+		select {
+		case <-ctx.Done():
+			return nil, fmt.Errorf("connector %q call timed out", connectorName)
+		case <-time.After(delay):
 		}
-	}()
+	case FaultKill:
+		proc.cmd.Process.Kill()
+	}
 
-	select {
-	case <-ctx.Done():
+	if _, err := proc.stdin.Write(proc.buf.Bytes()); err != nil {
+		return nil, fmt.Errorf("write to connector %q: %w", connectorName, err)
+	}
+	if fault == FaultDrop {
+		<-ctx.Done()
 		return nil, fmt.Errorf("connector %q call timed out", connectorName)
-	case result := <-ch:
-		if result.err != nil {
-			return nil, fmt.Errorf("read from connector %q: %w", connectorName, result.err)
-		}
-		if result.line == nil {
-			return nil, fmt.Errorf("connector %q closed stdout", connectorName)
-		}
-
-		// Enforce response size limit.
-		if len(result.line) > m.cfg.Limits.RespMaxBytes {
-			return nil, fmt.Errorf("response from %q exceeds %d byte limit", connectorName, m.cfg.Limits.RespMaxBytes)
-		}
+	}
 
-		var resp Response
-		if err := json.Unmarshal(result.line, &resp); err != nil {
-			return nil, fmt.Errorf("invalid response from %q: %w", connectorName, err)
+	// Read the matching response. Lines with another id are late replies
+	// to calls that already timed out and are discarded.
+	for {
+		select {
+		case <-ctx.Done():
+			return nil, fmt.Errorf("connector %q call timed out", connectorName)
+		case line, ok := <-proc.lines:
+			if !ok {
+				if proc.readErr != nil {
+					return nil, fmt.Errorf("read from connector %q: %w", connectorName, proc.readErr)
+				}
+				return nil, fmt.Errorf("connector %q closed stdout", connectorName)
+			}
+
+			var resp Response
+			if err := json.Unmarshal(line, &resp); err != nil {
+				return nil, fmt.Errorf("invalid response from %q: %w", connectorName, err)
+			}
+
+			if err := ValidateResponse(&resp); err != nil {
+				return nil, fmt.Errorf("invalid response from %q: %w", connectorName, err)
+			}
+
+			if resp.ID != req.ID {
+				m.logger.Warn("discarding stale connector response", "connector", connectorName, "got", resp.ID, "want", req.ID)
+				continue
+			}
+
+			// A line that fails verification did not come from the
+			// connector; keep waiting for the real reply.
+			if proc.secret != nil {
+				if err := VerifyResponse(&resp, proc.secret); err != nil {
+					m.logger.Warn("discarding unverified connector response", "connector", connectorName, "id", resp.ID, "error", err)
+					continue
+				}
+			}
+
+			return &resp, nil
 		}
+	}
+}
 
-		if err := ValidateResponse(&resp); err != nil {
-			return nil, fmt.Errorf("invalid response from %q: %w", connectorName, err)
-		}
+// restart replaces a connector whose stdout has ended, because its process
+// exited or wrote a line over the response limit, with a fresh process.
+// It returns the process to call, which another caller may already have
+// started.
+func (m *Manager) restart(dead *connectorProc) (*connectorProc, error) {
+	m.restartMu.Lock()
+	defer m.restartMu.Unlock()
 
-		if resp.ID != req.ID {
-			return nil, fmt.Errorf("response id mismatch from %q: got %q, want %q", connectorName, resp.ID, req.ID)
-		}
+	m.mu.RLock()
+	proc, ok := m.procs[dead.name]
+	m.mu.RUnlock()
+	if !ok {
+		return nil, fmt.Errorf("connector %q not running", dead.name)
+	}
+	if proc != dead {
+		return proc, nil
+	}
 
-		return &resp, nil
+	m.logger.Warn("connector stopped responding, restarting", "connector", dead.name, "error", dead.readErr)
+	attrs := map[string]string{"connector": dead.name}
+	if dead.readErr != nil {
+		attrs["error"] = dead.readErr.Error()
+	}
+	m.mu.RLock()
+	bus := m.events
+	m.mu.RUnlock()
+	bus.Publish(events.ConnectorCrashed, attrs)
+	dead.stdin.Close()
+	dead.cmd.Process.Kill()
+	dead.cmd.Wait()
+	if err := m.startConnector(dead.name, dead.exec); err != nil {
+		return nil, fmt.Errorf("restart connector %q: %w", dead.name, err)
 	}
+	m.mu.RLock()
+	proc = m.procs[dead.name]
+	m.mu.RUnlock()
+	return proc, nil
 }
 
 // StopConnector stops a single connector by name.
@@ -174,6 +429,12 @@ func (m *Manager) StopConnector(name string) error {
 	m.mu.Lock()
 	defer m.mu.Unlock()
 
+	if w, ok := m.wasm[name]; ok {
+		w.module.Close(context.Background())
+		delete(m.wasm, name)
+		m.logger.Info("connector stopped", "name", name)
+		return nil
+	}
 	proc, ok := m.procs[name]
 	if !ok {
 		return fmt.Errorf("connector %q not running", name)
@@ -189,12 +450,46 @@ func (m *Manager) StopConnector(name string) error {
 	return nil
 }
 
-// StartConnector launches a single connector by name using the given exec path.
+// Running returns the number of connectors whose process is still running,
+// counting every loaded wasm connector.
+func (m *Manager) Running() int {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	n := len(m.wasm)
+	for _, proc := range m.procs {
+		select {
+		case <-proc.done:
+		default:
+			n++
+		}
+	}
+	return n
+}
+
+// RunningCheck is a self-test that fails unless every configured connector
+// is running.
+func (m *Manager) RunningCheck() ops.Check {
+	return ops.Check{
+		Name: "Connectors running",
+		Run: func(context.Context) error {
+			if want, got := len(m.cfg.Connectors), m.Running(); got < want {
+				return fmt.Errorf("%d of %d running", got, want)
+			}
+			return nil
+		},
+	}
+}
+
+// StartConnector launches a single connector by name using the given exec
+// path, or compiles it if it is configured as a wasm connector.
 func (m *Manager) StartConnector(name, execPath string) error {
+	if cc := m.cfg.Connectors[name]; cc.IsWasm() {
+		return m.startWasm(name, execPath)
+	}
 	return m.startConnector(name, execPath)
 }
 
-// Shutdown stops all connector processes.
+// Shutdown stops all connector processes and unloads wasm connectors.
 func (m *Manager) Shutdown() {
 	m.mu.Lock()
 	defer m.mu.Unlock()
@@ -208,15 +503,17 @@ func (m *Manager) Shutdown() {
 		m.logger.Info("connector stopped", "name", name)
 	}
 	m.procs = make(map[string]*connectorProc)
+	m.closeWasm()
 }
 
 // logWriter adapts connector stderr to slog.
 type logWriter struct {
 	logger    *slog.Logger
 	connector string
+	redact    *redact.Redactor
 }
 
 func (w *logWriter) Write(p []byte) (int, error) {
-	w.logger.Debug("connector stderr", "connector", w.connector, "output", string(p))
+	w.logger.Debug("connector stderr", "connector", w.connector, "output", w.redact.String(string(p)))
 	return len(p), nil
 }
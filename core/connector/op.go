@@ -18,11 +18,23 @@ type ConnectorOp struct {
 	QualifiedName string // e.g. "sample.echo"
 	Desc          string
 	Router        *Router
+	HighRisk      bool // requires /do + /approve instead of TOTP; set by config or the connector's own declaration
+	Access        ops.ChatAccess
 }
 
 func (c *ConnectorOp) Name() string        { return c.QualifiedName }
-func (c *ConnectorOp) Description() string  { return c.Desc }
-func (c *ConnectorOp) Risk() ops.RiskLevel  { return ops.RiskLow }
+func (c *ConnectorOp) Description() string { return c.Desc }
+
+func (c *ConnectorOp) Risk() ops.RiskLevel {
+	if c.HighRisk {
+		return ops.RiskHigh
+	}
+	return ops.RiskLow
+}
+
+func (c *ConnectorOp) AllowsChat(chatID int64) bool {
+	return c.Access.AllowsChat(chatID)
+}
 
 func (c *ConnectorOp) Execute(ctx context.Context, args string) (string, error) {
 	jsonArgs := argsToJSON(args)
@@ -79,8 +91,10 @@ func formatData(data json.RawMessage) string {
 }
 
 // RegisterOps creates and registers a ConnectorOp for each allowed tool
-// in every configured connector.
+// in every configured connector. Connectors must be running: tools they
+// declare mutating or high risk are registered as high risk.
 func RegisterOps(cfg *Config, router *Router, registry *ops.Registry) error {
+	declared := router.DeclaredHighRisk(context.Background())
 	for connName, cc := range cfg.Connectors {
 		for _, tool := range cc.Tools {
 			qualified := connName + "." + tool
@@ -88,6 +102,8 @@ func RegisterOps(cfg *Config, router *Router, registry *ops.Registry) error {
 				QualifiedName: qualified,
 				Desc:          fmt.Sprintf("Connector: %s", qualified),
 				Router:        router,
+				HighRisk:      cc.IsHighRisk(tool) || declared[qualified],
+				Access:        cc.ChatAccess[tool],
 			}
 			if err := registry.Register(op); err != nil {
 				return fmt.Errorf("register connector op %q: %w", qualified, err)
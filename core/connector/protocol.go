@@ -1,6 +1,7 @@
 package connector
 
 import (
+	"bytes"
 	"encoding/json"
 	"fmt"
 )
@@ -14,6 +15,7 @@ type Request struct {
 	Tool    string          `json:"tool"`
 	Args    json.RawMessage `json:"args"`
 	Meta    *RequestMeta    `json:"meta,omitempty"`
+	Sig     string          `json:"sig,omitempty"` // see SignRequest
 }
 
 // RequestMeta carries optional tracing metadata.
@@ -29,6 +31,7 @@ type Response struct {
 	OK      bool            `json:"ok"`
 	Data    json.RawMessage `json:"data,omitempty"`
 	Error   *ResponseError  `json:"error,omitempty"`
+	Sig     string          `json:"sig,omitempty"` // see SignResponse
 }
 
 // ResponseError describes a structured error from a connector.
@@ -51,21 +54,60 @@ const (
 	ErrInvalidRequest = "INVALID_REQUEST"
 )
 
+// Event is an unsolicited line a connector writes to stdout outside of any
+// request, e.g. a CI failure noticed while polling. The daemon relays it
+// through the notifier. Events carry no id and are never answered.
+type Event struct {
+	Version string          `json:"version"`
+	Event   string          `json:"event"`
+	Text    string          `json:"text"`
+	Data    json.RawMessage `json:"data,omitempty"`
+	Sig     string          `json:"sig,omitempty"` // see SignEvent
+}
+
 // IntrospectData is returned by the __introspect tool.
 type IntrospectData struct {
-	Name    string          `json:"name"`
-	Version string          `json:"version"`
+	Name    string           `json:"name"`
+	Version string           `json:"version"`
 	Tools   []IntrospectTool `json:"tools"`
 }
 
-// IntrospectTool describes a single tool a connector exposes.
+// IntrospectTool describes a single tool a connector exposes. A tool that
+// changes anything outside the connector declares Mutating, or Risk
+// "high"; either makes the daemon gate it behind /do + /approve. Neither
+// can lower the risk the daemon's config gives a tool.
 type IntrospectTool struct {
-	Name string `json:"name"`
+	Name     string `json:"name"`
+	Risk     string `json:"risk,omitempty"` // "low" or "high"
+	Mutating bool   `json:"mutating,omitempty"`
+}
+
+// HighRisk reports whether the tool asks for approval gating.
+func (t IntrospectTool) HighRisk() bool {
+	return t.Mutating || t.Risk == "high"
 }
 
 // IntrospectToolName is the reserved tool name for introspection.
 const IntrospectToolName = "__introspect"
 
+// BatchToolName is the reserved tool that runs several requests in one
+// round trip. Its args are BatchArgs and its data BatchData.
+const BatchToolName = "__batch"
+
+// MaxBatchCalls caps the requests in one batch.
+const MaxBatchCalls = 20
+
+// BatchArgs carries the requests of a batch, run in order. They may not
+// themselves be batches.
+type BatchArgs struct {
+	Requests []Request `json:"requests"`
+}
+
+// BatchData carries one response per batched request, matched by id.
+type BatchData struct {
+	Responses []Response `json:"responses"`
+}
+
 // ValidateRequest checks a request for protocol correctness.
 func ValidateRequest(req *Request) error {
 	if req.Version != ProtocolVersion {
@@ -97,6 +139,36 @@ func ValidateResponse(resp *Response) error {
 	return nil
 }
 
+// ValidateEvent checks an event for protocol correctness.
+func ValidateEvent(ev *Event) error {
+	if ev.Version != ProtocolVersion {
+		return fmt.Errorf("unsupported protocol version %q", ev.Version)
+	}
+	if ev.Event == "" {
+		return fmt.Errorf("event name is required")
+	}
+	if ev.Text == "" {
+		return fmt.Errorf("event text is required")
+	}
+	return nil
+}
+
+// isEvent reports whether a stdout line is an event rather than a response.
+// Lines without an "event" key, which is every response, skip decoding.
+func isEvent(line []byte) bool {
+	if !bytes.Contains(line, []byte(`"event"`)) {
+		return false
+	}
+	var probe struct {
+		ID    string `json:"id"`
+		Event string `json:"event"`
+	}
+	if err := json.Unmarshal(line, &probe); err != nil {
+		return false
+	}
+	return probe.ID == "" && probe.Event != ""
+}
+
 // NewErrorResponse creates an error response for a given request ID.
 func NewErrorResponse(id, code, message string) *Response {
 	return &Response{
@@ -106,3 +106,66 @@ func TestNewErrorResponse(t *testing.T) {
 		t.Errorf("Message = %q, want %q", resp.Error.Message, "bad input")
 	}
 }
+
+func TestValidateEvent(t *testing.T) {
+	tests := []struct {
+		name    string
+		ev      Event
+		wantErr bool
+	}{
+		{
+			name:    "valid",
+			ev:      Event{Version: "v1", Event: "ci_failed", Text: "CI failed"},
+			wantErr: false,
+		},
+		{
+			name:    "bad version",
+			ev:      Event{Version: "v2", Event: "ci_failed", Text: "CI failed"},
+			wantErr: true,
+		},
+		{
+			name:    "missing event",
+			ev:      Event{Version: "v1", Text: "CI failed"},
+			wantErr: true,
+		},
+		{
+			name:    "missing text",
+			ev:      Event{Version: "v1", Event: "ci_failed"},
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := ValidateEvent(&tt.ev)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("ValidateEvent() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestIsEvent(t *testing.T) {
+	tests := []struct {
+		line string
+		want bool
+	}{
+		{`{"version":"v1","event":"ci_failed","text":"x"}`, true},
+		{`{"version":"v1","id":"req_1","ok":true}`, false},
+		{`{"version":"v1","id":"req_1","event":"x","ok":true}`, false},
+		{`not json`, false},
+	}
+	for _, tt := range tests {
+		if got := isEvent([]byte(tt.line)); got != tt.want {
+			t.Errorf("isEvent(%s) = %v, want %v", tt.line, got, tt.want)
+		}
+	}
+}
+
+func BenchmarkIsEvent(b *testing.B) {
+	line := []byte(`{"version":"v1","id":"req_0123abcd","ok":true,"data":{"text":"hello world"}}`)
+	b.ReportAllocs()
+	for b.Loop() {
+		isEvent(line)
+	}
+}
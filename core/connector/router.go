@@ -2,12 +2,17 @@ package connector
 
 import (
 	"context"
+	"crypto/rand"
+	"encoding/hex"
 	"encoding/json"
 	"fmt"
 	"log/slog"
+	"maps"
+	"slices"
 	"strings"
+	"time"
 
-	"github.com/google/uuid"
+	"github.com/jdelaire/openslack/core/ops"
 )
 
 // Router validates and dispatches connector tool calls.
@@ -45,7 +50,7 @@ func (r *Router) Call(ctx context.Context, qualifiedTool string, args json.RawMe
 
 	req := &Request{
 		Version: ProtocolVersion,
-		ID:      "req_" + uuid.New().String()[:8],
+		ID:      newRequestID(),
 		Tool:    toolName,
 		Args:    args,
 	}
@@ -60,6 +65,101 @@ func (r *Router) Call(ctx context.Context, qualifiedTool string, args json.RawMe
 	return resp, nil
 }
 
+// ToolCall is one call in a BatchCall.
+type ToolCall struct {
+	Tool string // unqualified, e.g. "echo"
+	Args json.RawMessage
+}
+
+// BatchCall runs several tool calls on one connector in a single round
+// trip, sharing one call timeout. It returns one response per call, in
+// order. Connectors that do not support __batch get the calls one at a
+// time instead.
+func (r *Router) BatchCall(ctx context.Context, connName string, calls []ToolCall) ([]*Response, error) {
+	cc, ok := r.cfg.Connectors[connName]
+	if !ok {
+		return nil, fmt.Errorf("unknown connector %q", connName)
+	}
+	if len(calls) == 0 {
+		return nil, nil
+	}
+	if len(calls) > MaxBatchCalls {
+		return nil, fmt.Errorf("batch exceeds %d calls", MaxBatchCalls)
+	}
+
+	batch := BatchArgs{Requests: make([]Request, len(calls))}
+	for i, call := range calls {
+		if !cc.ToolAllowed(call.Tool) {
+			return nil, fmt.Errorf("tool %q not allowed for connector %q", call.Tool, connName)
+		}
+		args := call.Args
+		if args == nil {
+			args = json.RawMessage(`{}`)
+		}
+		batch.Requests[i] = Request{Version: ProtocolVersion, ID: newRequestID(), Tool: call.Tool, Args: args}
+	}
+	args, err := json.Marshal(batch)
+	if err != nil {
+		return nil, fmt.Errorf("marshal batch: %w", err)
+	}
+	req := &Request{Version: ProtocolVersion, ID: newRequestID(), Tool: BatchToolName, Args: args}
+
+	r.logger.Info("routing connector batch", "connector", connName, "calls", len(calls), "id", req.ID)
+
+	resp, err := r.manager.Call(ctx, connName, req)
+	if err != nil {
+		return nil, fmt.Errorf("connector %q batch failed: %w", connName, err)
+	}
+	if !resp.OK {
+		if resp.Error.Code == ErrNotSupported {
+			return r.callEach(ctx, connName, calls)
+		}
+		return nil, fmt.Errorf("connector %q batch failed: %w", connName, resp.Error)
+	}
+
+	var data BatchData
+	if err := json.Unmarshal(resp.Data, &data); err != nil {
+		return nil, fmt.Errorf("decode batch from %q: %w", connName, err)
+	}
+	byID := make(map[string]*Response, len(data.Responses))
+	for i := range data.Responses {
+		inner := &data.Responses[i]
+		if err := ValidateResponse(inner); err != nil {
+			return nil, fmt.Errorf("invalid batch response from %q: %w", connName, err)
+		}
+		byID[inner.ID] = inner
+	}
+	out := make([]*Response, len(calls))
+	for i, inner := range batch.Requests {
+		out[i] = byID[inner.ID]
+		if out[i] == nil {
+			out[i] = NewErrorResponse(inner.ID, ErrInternal, "missing from batch response")
+		}
+	}
+	return out, nil
+}
+
+// callEach runs calls one at a time, for connectors without __batch.
+func (r *Router) callEach(ctx context.Context, connName string, calls []ToolCall) ([]*Response, error) {
+	out := make([]*Response, len(calls))
+	for i, call := range calls {
+		resp, err := r.Call(ctx, connName+"."+call.Tool, call.Args)
+		if err != nil {
+			return nil, err
+		}
+		out[i] = resp
+	}
+	return out, nil
+}
+
+// newRequestID returns a short random id, unique among a connector's
+// in-flight requests.
+func newRequestID() string {
+	var b [4]byte
+	rand.Read(b[:])
+	return "req_" + hex.EncodeToString(b[:])
+}
+
 // splitTool parses "connector.tool" into its two parts.
 func splitTool(qualified string) (connector, tool string, err error) {
 	idx := strings.IndexByte(qualified, '.')
@@ -76,3 +176,65 @@ func splitTool(qualified string) (connector, tool string, err error) {
 	}
 	return connector, tool, nil
 }
+
+// IntrospectChecks returns a self-test per configured connector that
+// round-trips its __introspect tool.
+func (r *Router) IntrospectChecks() []ops.Check {
+	names := slices.Sorted(maps.Keys(r.cfg.Connectors))
+	checks := make([]ops.Check, 0, len(names))
+	for _, name := range names {
+		checks = append(checks, ops.Check{
+			Name: "Connector " + name + " answers " + IntrospectToolName,
+			Run: func(ctx context.Context) error {
+				_, err := r.Introspect(ctx, name)
+				return err
+			},
+		})
+	}
+	return checks
+}
+
+// Introspect asks the named connector to describe itself.
+func (r *Router) Introspect(ctx context.Context, name string) (*IntrospectData, error) {
+	resp, err := r.Call(ctx, name+"."+IntrospectToolName, nil)
+	if err != nil {
+		return nil, err
+	}
+	if !resp.OK {
+		if resp.Error != nil {
+			return nil, resp.Error
+		}
+		return nil, fmt.Errorf("connector %q returned ok=false", name)
+	}
+	var data IntrospectData
+	if err := json.Unmarshal(resp.Data, &data); err != nil {
+		return nil, fmt.Errorf("decode introspection: %w", err)
+	}
+	return &data, nil
+}
+
+// DeclaredHighRisk returns the qualified names of configured tools whose
+// connector declares them mutating or high risk. A connector that cannot
+// be introspected has all its tools included, so a broken connector fails
+// closed.
+func (r *Router) DeclaredHighRisk(ctx context.Context) map[string]bool {
+	declared := make(map[string]bool)
+	for name, cc := range r.cfg.Connectors {
+		ictx, cancel := context.WithTimeout(ctx, 5*time.Second)
+		data, err := r.Introspect(ictx, name)
+		cancel()
+		if err != nil {
+			r.logger.Warn("connector introspection failed; treating its tools as high risk", "connector", name, "error", err)
+			for _, tool := range cc.Tools {
+				declared[name+"."+tool] = true
+			}
+			continue
+		}
+		for _, t := range data.Tools {
+			if t.HighRisk() && cc.ToolAllowed(t.Name) {
+				declared[name+"."+t.Name] = true
+			}
+		}
+	}
+	return declared
+}
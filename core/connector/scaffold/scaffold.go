@@ -0,0 +1,142 @@
+// Package scaffold generates a ready-to-run connector in Go, Python or
+// bash: the protocol loop, __introspect, argument parsing and a Makefile,
+// with a stub for each named tool.
+package scaffold
+
+import (
+	"bytes"
+	"embed"
+	"errors"
+	"fmt"
+	"os"
+	"path"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"text/template"
+)
+
+// Supported languages.
+const (
+	LangGo     = "go"
+	LangPython = "python"
+	LangBash   = "bash"
+)
+
+//go:embed templates
+var templates embed.FS
+
+// files lists each language's templates, relative to templates/<lang>.
+// Names are written without the .tmpl suffix.
+var files = map[string][]string{
+	LangGo:     {"main.go.tmpl", "Makefile.tmpl"},
+	LangPython: {"connector.py.tmpl", "Makefile.tmpl"},
+	LangBash:   {"connector.sh.tmpl", "Makefile.tmpl"},
+}
+
+var (
+	validName = regexp.MustCompile(`^[a-z][a-z0-9_-]*$`)
+	validTool = regexp.MustCompile(`^[a-z][a-z0-9_]*$`)
+)
+
+// ErrExists is returned when a file to generate already exists.
+var ErrExists = errors.New("file already exists")
+
+// Options describes the connector to generate.
+type Options struct {
+	Name  string   // connector name, as used in connectors.json
+	Lang  string   // LangGo, LangPython or LangBash
+	Tools []string // tool names; at least one
+}
+
+// Validate checks the name, language and tools.
+func (o Options) Validate() error {
+	if !validName.MatchString(o.Name) {
+		return fmt.Errorf("name %q must be lowercase letters, digits, - or _, starting with a letter", o.Name)
+	}
+	if _, ok := files[o.Lang]; !ok {
+		return fmt.Errorf("unknown language %q (want go, python or bash)", o.Lang)
+	}
+	if len(o.Tools) == 0 {
+		return fmt.Errorf("at least one tool is required")
+	}
+	seen := make(map[string]bool, len(o.Tools))
+	for _, t := range o.Tools {
+		if !validTool.MatchString(t) {
+			return fmt.Errorf("tool %q must be lowercase letters, digits or _, starting with a letter", t)
+		}
+		if seen[t] {
+			return fmt.Errorf("tool %q listed twice", t)
+		}
+		seen[t] = true
+	}
+	return nil
+}
+
+// Generate writes the connector's files into dir, creating it if needed,
+// and returns their paths. It never overwrites: if any file exists it
+// returns ErrExists and writes nothing.
+func Generate(dir string, opts Options) ([]string, error) {
+	if err := opts.Validate(); err != nil {
+		return nil, err
+	}
+
+	type output struct {
+		path string
+		data []byte
+		mode os.FileMode
+	}
+	var outs []output
+	for _, name := range files[opts.Lang] {
+		data, err := render(path.Join("templates", opts.Lang, name), opts)
+		if err != nil {
+			return nil, err
+		}
+		name = strings.TrimSuffix(name, ".tmpl")
+		mode := os.FileMode(0644)
+		if strings.HasSuffix(name, ".py") || strings.HasSuffix(name, ".sh") {
+			mode = 0755
+		}
+		p := filepath.Join(dir, name)
+		if _, err := os.Stat(p); err == nil {
+			return nil, fmt.Errorf("%s: %w", p, ErrExists)
+		}
+		outs = append(outs, output{p, data, mode})
+	}
+
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return nil, fmt.Errorf("create dir: %w", err)
+	}
+	paths := make([]string, 0, len(outs))
+	for _, o := range outs {
+		if err := os.WriteFile(o.path, o.data, o.mode); err != nil {
+			return nil, fmt.Errorf("write %s: %w", o.path, err)
+		}
+		paths = append(paths, o.path)
+	}
+	return paths, nil
+}
+
+func render(name string, opts Options) ([]byte, error) {
+	tmpl, err := template.New(path.Base(name)).Funcs(template.FuncMap{"handler": handlerName}).ParseFS(templates, name)
+	if err != nil {
+		return nil, fmt.Errorf("parse %s: %w", name, err)
+	}
+	var buf bytes.Buffer
+	if err := tmpl.Execute(&buf, opts); err != nil {
+		return nil, fmt.Errorf("render %s: %w", name, err)
+	}
+	return buf.Bytes(), nil
+}
+
+// handlerName turns a tool name like list_prs into handleListPrs.
+func handlerName(tool string) string {
+	var b strings.Builder
+	b.WriteString("handle")
+	for _, part := range strings.Split(tool, "_") {
+		if part != "" {
+			b.WriteString(strings.ToUpper(part[:1]) + part[1:])
+		}
+	}
+	return b.String()
+}
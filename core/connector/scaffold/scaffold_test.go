@@ -0,0 +1,124 @@
+package scaffold_test
+
+import (
+	"errors"
+	"go/format"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/jdelaire/openslack/core/connector/conformance"
+	"github.com/jdelaire/openslack/core/connector/scaffold"
+)
+
+func TestValidate(t *testing.T) {
+	bad := []scaffold.Options{
+		{Name: "Demo", Lang: scaffold.LangGo, Tools: []string{"list"}},
+		{Name: "de.mo", Lang: scaffold.LangGo, Tools: []string{"list"}},
+		{Name: "demo", Lang: "ruby", Tools: []string{"list"}},
+		{Name: "demo", Lang: scaffold.LangGo},
+		{Name: "demo", Lang: scaffold.LangGo, Tools: []string{"__introspect"}},
+		{Name: "demo", Lang: scaffold.LangGo, Tools: []string{"list-prs"}},
+		{Name: "demo", Lang: scaffold.LangGo, Tools: []string{"list", "list"}},
+	}
+	for _, o := range bad {
+		if err := o.Validate(); err == nil {
+			t.Errorf("Validate(%+v) succeeded", o)
+		}
+	}
+	ok := scaffold.Options{Name: "my-demo", Lang: scaffold.LangBash, Tools: []string{"list_prs", "add2"}}
+	if err := ok.Validate(); err != nil {
+		t.Errorf("Validate(%+v): %v", ok, err)
+	}
+}
+
+func TestGenerateGo(t *testing.T) {
+	dir := filepath.Join(t.TempDir(), "demo")
+	paths, err := scaffold.Generate(dir, scaffold.Options{Name: "demo", Lang: scaffold.LangGo, Tools: []string{"list_prs", "add"}})
+	if err != nil {
+		t.Fatalf("generate: %v", err)
+	}
+	if len(paths) != 2 {
+		t.Fatalf("paths = %v", paths)
+	}
+
+	src, err := os.ReadFile(filepath.Join(dir, "main.go"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	formatted, err := format.Source(src)
+	if err != nil {
+		t.Fatalf("main.go does not parse: %v\n%s", err, src)
+	}
+	if string(formatted) != string(src) {
+		t.Errorf("main.go is not gofmt-clean:\n%s", src)
+	}
+	for _, want := range []string{`c.Tool("list_prs", handleListPrs)`, `func handleAdd(`, `sdk.New("demo", connectorVersion)`} {
+		if !strings.Contains(string(src), want) {
+			t.Errorf("main.go missing %q", want)
+		}
+	}
+
+	mk, err := os.ReadFile(filepath.Join(dir, "Makefile"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(string(mk), "\tgo build -o $(BIN) .") {
+		t.Errorf("Makefile recipe not tab-indented:\n%s", mk)
+	}
+}
+
+func TestGenerateNeverOverwrites(t *testing.T) {
+	dir := t.TempDir()
+	if err := os.WriteFile(filepath.Join(dir, "Makefile"), []byte("keep"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	_, err := scaffold.Generate(dir, scaffold.Options{Name: "demo", Lang: scaffold.LangPython, Tools: []string{"list"}})
+	if !errors.Is(err, scaffold.ErrExists) {
+		t.Fatalf("err = %v, want ErrExists", err)
+	}
+	if _, err := os.Stat(filepath.Join(dir, "connector.py")); !os.IsNotExist(err) {
+		t.Errorf("connector.py written despite the conflict: %v", err)
+	}
+}
+
+// TestGenerateScriptsConform runs each generated script through the
+// conformance suite, skipping languages whose interpreter is missing.
+func TestGenerateScriptsConform(t *testing.T) {
+	for _, tc := range []struct {
+		lang, script string
+		needs        []string
+	}{
+		{scaffold.LangPython, "connector.py", []string{"python3"}},
+		{scaffold.LangBash, "connector.sh", []string{"bash", "jq"}},
+	} {
+		t.Run(tc.lang, func(t *testing.T) {
+			for _, bin := range tc.needs {
+				if _, err := exec.LookPath(bin); err != nil {
+					t.Skipf("%s not installed", bin)
+				}
+			}
+			dir := t.TempDir()
+			if _, err := scaffold.Generate(dir, scaffold.Options{Name: "demo", Lang: tc.lang, Tools: []string{"list_prs", "add"}}); err != nil {
+				t.Fatalf("generate: %v", err)
+			}
+			script := filepath.Join(dir, tc.script)
+			if info, err := os.Stat(script); err != nil || info.Mode().Perm()&0100 == 0 {
+				t.Fatalf("%s not executable: %v", tc.script, err)
+			}
+
+			results, err := conformance.Run(conformance.Options{Exec: script, CallTimeout: 10 * time.Second})
+			if err != nil {
+				t.Fatalf("run: %v", err)
+			}
+			for _, r := range results {
+				if !r.Passed() {
+					t.Errorf("%s: %v", r.Name, r.Err)
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,183 @@
+package connector
+
+import (
+	"context"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/jdelaire/openslack/core/ops"
+	"github.com/jdelaire/openslack/core/ops/format"
+)
+
+// ScratchEnv carries a connector's scratch directory to its process.
+// Connectors may keep tokens and caches there across restarts.
+const ScratchEnv = "OPENSLACK_CONNECTOR_SCRATCH"
+
+// DefaultScratchDir holds one scratch directory per connector.
+const DefaultScratchDir = "~/.openslack/connector-data"
+
+// ScratchPath returns the scratch directory of the named connector, or ""
+// if scratch directories are disabled.
+func (c *Config) ScratchPath(name string) string {
+	if c.ScratchDir == "" {
+		return ""
+	}
+	return filepath.Join(c.ScratchDir, name)
+}
+
+// prepareScratch creates dir if needed and makes it private to the user,
+// fixing the mode of a directory created elsewhere.
+func prepareScratch(dir string) error {
+	if err := os.MkdirAll(dir, 0700); err != nil {
+		return fmt.Errorf("create scratch dir: %w", err)
+	}
+	if err := os.Chmod(dir, 0700); err != nil {
+		return fmt.Errorf("chmod scratch dir: %w", err)
+	}
+	return nil
+}
+
+// pruneScratch removes the scratch directories of connectors that are no
+// longer configured.
+func (m *Manager) pruneScratch() {
+	if m.cfg.ScratchDir == "" {
+		return
+	}
+	entries, err := os.ReadDir(m.cfg.ScratchDir)
+	if err != nil {
+		if !os.IsNotExist(err) {
+			m.logger.Warn("read scratch dir", "dir", m.cfg.ScratchDir, "error", err)
+		}
+		return
+	}
+	for _, e := range entries {
+		if !e.IsDir() || strings.Contains(e.Name(), ".") {
+			continue
+		}
+		if _, ok := m.cfg.Connectors[e.Name()]; ok {
+			continue
+		}
+		dir := filepath.Join(m.cfg.ScratchDir, e.Name())
+		if err := os.RemoveAll(dir); err != nil {
+			m.logger.Warn("remove scratch dir", "dir", dir, "error", err)
+			continue
+		}
+		m.logger.Info("removed scratch dir of unconfigured connector", "name", e.Name())
+	}
+}
+
+// dirSize returns the total size of the regular files under dir.
+func dirSize(dir string) (int64, error) {
+	var total int64
+	err := filepath.WalkDir(dir, func(_ string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if d.Type().IsRegular() {
+			info, err := d.Info()
+			if err != nil {
+				return err
+			}
+			total += info.Size()
+		}
+		return nil
+	})
+	return total, err
+}
+
+// Status describes one configured connector.
+type Status struct {
+	Name         string
+	Running      bool
+	Tools        int
+	ScratchDir   string // "" if scratch directories are disabled
+	ScratchBytes int64  // -1 if the size could not be read
+}
+
+// Status reports every configured connector, sorted by name.
+func (m *Manager) Status() []Status {
+	m.mu.RLock()
+	running := make(map[string]bool, len(m.procs))
+	for name, proc := range m.procs {
+		select {
+		case <-proc.done:
+		default:
+			running[name] = true
+		}
+	}
+	for name := range m.wasm {
+		running[name] = true
+	}
+	m.mu.RUnlock()
+
+	out := make([]Status, 0, len(m.cfg.Connectors))
+	for name, cc := range m.cfg.Connectors {
+		st := Status{Name: name, Running: running[name], Tools: len(cc.Tools), ScratchDir: m.cfg.ScratchPath(name)}
+		if st.ScratchDir != "" {
+			size, err := dirSize(st.ScratchDir)
+			if err != nil && !os.IsNotExist(err) {
+				size = -1
+			}
+			st.ScratchBytes = size
+		}
+		out = append(out, st)
+	}
+	sort.Slice(out, func(i, j int) bool { return out[i].Name < out[j].Name })
+	return out
+}
+
+// ConnectorsOp lists the configured connectors with their state and
+// scratch directory size. Manager returns the current manager, which
+// changes when connectors are reloaded, or nil if none are configured.
+type ConnectorsOp struct {
+	Manager func() *Manager
+}
+
+func (o *ConnectorsOp) Name() string        { return "connectors" }
+func (o *ConnectorsOp) Description() string { return "List connectors and their scratch usage" }
+func (o *ConnectorsOp) Risk() ops.RiskLevel { return ops.RiskNone }
+
+func (o *ConnectorsOp) Execute(context.Context, string) (string, error) {
+	mgr := o.Manager()
+	if mgr == nil {
+		return "No connectors configured.", nil
+	}
+	statuses := mgr.Status()
+	if len(statuses) == 0 {
+		return "No connectors configured.", nil
+	}
+	rows := make([][]string, 0, len(statuses))
+	for _, st := range statuses {
+		state := "stopped"
+		if st.Running {
+			state = "running"
+		}
+		scratch := "-"
+		switch {
+		case st.ScratchDir == "":
+		case st.ScratchBytes < 0:
+			scratch = "?"
+		default:
+			scratch = formatSize(st.ScratchBytes)
+		}
+		rows = append(rows, []string{st.Name, state, fmt.Sprint(st.Tools), scratch})
+	}
+	return format.Table([]string{"Connector", "State", "Tools", "Scratch"}, rows, 0), nil
+}
+
+func formatSize(n int64) string {
+	const unit = 1024
+	if n < unit {
+		return fmt.Sprintf("%d B", n)
+	}
+	div, exp := int64(unit), 0
+	for m := n / unit; m >= unit; m /= unit {
+		div *= unit
+		exp++
+	}
+	return fmt.Sprintf("%.1f %ciB", float64(n)/float64(div), "KMGTPE"[exp])
+}
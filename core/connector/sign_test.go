@@ -0,0 +1,73 @@
+package connector
+
+import (
+	"encoding/json"
+	"errors"
+	"testing"
+)
+
+func TestSignRequest(t *testing.T) {
+	secret := []byte("s3cret")
+	req := &Request{Version: ProtocolVersion, ID: "req_1", Tool: "echo", Args: json.RawMessage(`{"text": "hi"}`)}
+	SignRequest(req, secret)
+	if err := VerifyRequest(req, secret); err != nil {
+		t.Fatalf("verify: %v", err)
+	}
+
+	// Re-encoding the args does not change the signature.
+	data, _ := json.Marshal(req)
+	var decoded Request
+	json.Unmarshal(data, &decoded)
+	if err := VerifyRequest(&decoded, secret); err != nil {
+		t.Errorf("verify after round trip: %v", err)
+	}
+
+	tampered := *req
+	tampered.Tool = "delete"
+	if err := VerifyRequest(&tampered, secret); !errors.Is(err, ErrBadSignature) {
+		t.Errorf("tampered tool: %v", err)
+	}
+	if err := VerifyRequest(req, []byte("other")); !errors.Is(err, ErrBadSignature) {
+		t.Errorf("wrong secret: %v", err)
+	}
+	req.Sig = ""
+	if err := VerifyRequest(req, secret); !errors.Is(err, ErrMissingSignature) {
+		t.Errorf("unsigned: %v", err)
+	}
+}
+
+func TestSignResponse(t *testing.T) {
+	secret := []byte("s3cret")
+	resp := &Response{Version: ProtocolVersion, ID: "req_1", OK: true, Data: json.RawMessage(`{"text":"hi"}`)}
+	SignResponse(resp, secret)
+	if err := VerifyResponse(resp, secret); err != nil {
+		t.Fatalf("verify: %v", err)
+	}
+
+	tests := map[string]func(r *Response){
+		"id":    func(r *Response) { r.ID = "req_2" },
+		"ok":    func(r *Response) { r.OK = false; r.Error = &ResponseError{Code: ErrInternal, Message: "x"} },
+		"data":  func(r *Response) { r.Data = json.RawMessage(`{"text":"bye"}`) },
+		"error": func(r *Response) { r.Error = &ResponseError{Code: ErrInternal} },
+	}
+	for name, tamper := range tests {
+		r := *resp
+		tamper(&r)
+		if err := VerifyResponse(&r, secret); !errors.Is(err, ErrBadSignature) {
+			t.Errorf("tampered %s: %v", name, err)
+		}
+	}
+}
+
+func TestSignEvent(t *testing.T) {
+	secret := []byte("s3cret")
+	ev := &Event{Version: ProtocolVersion, Event: "notice", Text: "build failed"}
+	SignEvent(ev, secret)
+	if err := VerifyEvent(ev, secret); err != nil {
+		t.Fatalf("verify: %v", err)
+	}
+	ev.Text = "click this link"
+	if err := VerifyEvent(ev, secret); !errors.Is(err, ErrBadSignature) {
+		t.Errorf("tampered text: %v", err)
+	}
+}
@@ -0,0 +1,216 @@
+package connector
+
+import (
+	"bytes"
+	"context"
+	"crypto/rand"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/tetratelabs/wazero"
+	"github.com/tetratelabs/wazero/imports/wasi_snapshot_preview1"
+	"github.com/tetratelabs/wazero/sys"
+)
+
+// A wasm connector is a WebAssembly module built for WASI (e.g. with
+// GOOS=wasip1 GOARCH=wasm) that speaks the same protocol as a process
+// connector: it reads request lines on stdin and writes responses and
+// events on stdout. Each call runs a fresh instance of the module inside
+// the daemon, with the request as its whole stdin, so a connector built
+// with the SDK needs no changes: it answers and exits at end of input.
+//
+// A module gets no environment, no network and no files beyond its
+// scratch directory, mounted at wasmScratchDir. Its memory is capped at
+// limits.wasm_memory_mb and each call at limits.call_timeout_ms.
+
+// wasmScratchDir is where a wasm connector sees its scratch directory.
+const wasmScratchDir = "/scratch"
+
+// wasmConnector is a compiled wasm connector.
+type wasmConnector struct {
+	name    string
+	module  wazero.CompiledModule
+	scratch string     // host path, "" if disabled
+	mu      sync.Mutex // one instance at a time, bounding memory
+}
+
+// wasmRuntime returns the manager's wasm runtime, creating it on first
+// use. Callers hold m.mu.
+func (m *Manager) wasmRuntime() wazero.Runtime {
+	if m.wasmRT != nil {
+		return m.wasmRT
+	}
+	mb := m.cfg.Limits.WasmMemoryMB
+	if mb <= 0 {
+		mb = DefaultWasmMemoryMB
+	}
+	cfg := wazero.NewRuntimeConfig().
+		WithMemoryLimitPages(uint32(mb) * 16). // 64 KiB pages
+		WithCloseOnContextDone(true)
+	ctx := context.Background()
+	m.wasmRT = wazero.NewRuntimeWithConfig(ctx, cfg)
+	wasi_snapshot_preview1.MustInstantiate(ctx, m.wasmRT)
+	return m.wasmRT
+}
+
+// startWasm compiles a wasm connector's module.
+func (m *Manager) startWasm(name, path string) error {
+	code, err := os.ReadFile(path)
+	if err != nil {
+		return fmt.Errorf("read module: %w", err)
+	}
+	scratch := m.cfg.ScratchPath(name)
+	if scratch != "" {
+		if err := prepareScratch(scratch); err != nil {
+			return err
+		}
+	}
+
+	m.mu.Lock()
+	rt := m.wasmRuntime()
+	m.mu.Unlock()
+	// Compiling takes a while for large modules; don't hold the lock.
+	module, err := rt.CompileModule(context.Background(), code)
+	if err != nil {
+		return fmt.Errorf("compile module: %w", err)
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if old := m.wasm[name]; old != nil {
+		old.module.Close(context.Background())
+	}
+	m.wasm[name] = &wasmConnector{name: name, module: module, scratch: scratch}
+	return nil
+}
+
+// callWasm runs one request through a fresh instance of a wasm connector.
+func (m *Manager) callWasm(ctx context.Context, w *wasmConnector, req *Request) (*Response, error) {
+	var line bytes.Buffer
+	if err := json.NewEncoder(&line).Encode(req); err != nil {
+		return nil, fmt.Errorf("marshal request: %w", err)
+	}
+	if n := line.Len() - 1; n > m.cfg.Limits.ReqMaxBytes {
+		return nil, fmt.Errorf("request exceeds %d byte limit (%d bytes)", m.cfg.Limits.ReqMaxBytes, n)
+	}
+
+	timeout := time.Duration(m.cfg.Limits.CallTimeoutMs) * time.Millisecond
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	m.mu.RLock()
+	rt, r := m.wasmRT, m.redact
+	m.mu.RUnlock()
+	if rt == nil {
+		return nil, fmt.Errorf("connector %q not running", w.name)
+	}
+
+	stdout := &cappedBuffer{max: m.cfg.Limits.RespMaxBytes}
+	mc := wazero.NewModuleConfig().
+		WithName("").
+		WithArgs(w.name).
+		WithStdin(&line).
+		WithStdout(stdout).
+		WithStderr(&logWriter{logger: m.logger, connector: w.name, redact: r}).
+		WithSysWalltime().
+		WithSysNanotime().
+		WithNanosleep(func(ns int64) {
+			select {
+			case <-ctx.Done():
+			case <-time.After(time.Duration(ns)):
+			}
+		}).
+		WithRandSource(rand.Reader)
+	if w.scratch != "" {
+		mc = mc.WithFSConfig(wazero.NewFSConfig().WithDirMount(w.scratch, wasmScratchDir)).
+			WithEnv(ScratchEnv, wasmScratchDir)
+	}
+
+	mod, err := rt.InstantiateModule(ctx, w.module, mc)
+	if mod != nil {
+		mod.Close(context.Background())
+	}
+	if ctx.Err() != nil {
+		return nil, fmt.Errorf("connector %q call timed out", w.name)
+	}
+	if stdout.over {
+		return nil, fmt.Errorf("connector %q output exceeds %d byte limit", w.name, stdout.max)
+	}
+
+	resp, perr := m.wasmResponse(w.name, req.ID, stdout.Bytes())
+	if resp != nil {
+		return resp, nil
+	}
+	var exit *sys.ExitError
+	if errors.As(err, &exit) {
+		return nil, fmt.Errorf("connector %q exited with code %d", w.name, exit.ExitCode())
+	}
+	if err != nil {
+		return nil, fmt.Errorf("run connector %q: %w", w.name, err)
+	}
+	return nil, perr
+}
+
+// wasmResponse picks the response to id out of a call's output, handing
+// any events to the event handler.
+func (m *Manager) wasmResponse(name, id string, out []byte) (*Response, error) {
+	var resp *Response
+	for _, line := range bytes.Split(out, []byte("\n")) {
+		if len(bytes.TrimSpace(line)) == 0 {
+			continue
+		}
+		if isEvent(line) {
+			m.handleEvent(name, nil, line)
+			continue
+		}
+		var r Response
+		if err := json.Unmarshal(line, &r); err != nil {
+			return nil, fmt.Errorf("invalid response from %q: %w", name, err)
+		}
+		if err := ValidateResponse(&r); err != nil {
+			return nil, fmt.Errorf("invalid response from %q: %w", name, err)
+		}
+		if r.ID == id && resp == nil {
+			resp = &r
+		}
+	}
+	if resp == nil {
+		return nil, fmt.Errorf("connector %q wrote no response", name)
+	}
+	return resp, nil
+}
+
+// closeWasm releases every compiled module and the runtime. Callers hold
+// m.mu.
+func (m *Manager) closeWasm() {
+	for name := range m.wasm {
+		m.logger.Info("connector stopped", "name", name)
+	}
+	m.wasm = make(map[string]*wasmConnector)
+	if m.wasmRT != nil {
+		m.wasmRT.Close(context.Background())
+		m.wasmRT = nil
+	}
+}
+
+// cappedBuffer collects a module's stdout, failing writes past max.
+type cappedBuffer struct {
+	bytes.Buffer
+	max  int
+	over bool
+}
+
+func (b *cappedBuffer) Write(p []byte) (int, error) {
+	if b.Len()+len(p) > b.max {
+		b.over = true
+		return 0, errors.New("output limit exceeded")
+	}
+	return b.Buffer.Write(p)
+}
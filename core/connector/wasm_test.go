@@ -0,0 +1,140 @@
+package connector_test
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"log/slog"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/jdelaire/openslack/core/connector"
+)
+
+// buildWasmConnector compiles connectors/<name> to a WASI module and
+// returns its path.
+func buildWasmConnector(t *testing.T, name string) string {
+	t.Helper()
+	wd, err := os.Getwd()
+	if err != nil {
+		t.Fatalf("getwd: %v", err)
+	}
+	root := filepath.Join(wd, "..", "..")
+	out := filepath.Join(t.TempDir(), name+".wasm")
+	cmd := exec.Command("go", "build", "-o", out, filepath.Join(root, "connectors", name))
+	cmd.Dir = root
+	cmd.Env = append(os.Environ(), "GOOS=wasip1", "GOARCH=wasm")
+	if msg, err := cmd.CombinedOutput(); err != nil {
+		t.Fatalf("build %s.wasm: %v\n%s", name, err, msg)
+	}
+	return out
+}
+
+func TestWasmConnector(t *testing.T) {
+	sample := buildWasmConnector(t, "sample")
+	chaos := buildWasmConnector(t, "chaos")
+	cfg := &connector.Config{
+		Connectors: map[string]connector.ConnectorConfig{
+			"sample": {Type: connector.TypeWasm, Exec: sample, Tools: []string{"echo", "sleep", "notify"}},
+			"chaos":  {Type: connector.TypeWasm, Exec: chaos, Tools: []string{"oversized", "exit", "malformed"}},
+		},
+		Limits: connector.LimitsConfig{
+			ReqMaxBytes:   4096,
+			RespMaxBytes:  16384,
+			CallTimeoutMs: 1000,
+		},
+		ScratchDir: t.TempDir(),
+	}
+	logger := slog.New(slog.NewTextHandler(io.Discard, nil))
+	mgr := connector.NewManager(cfg, logger)
+	if err := mgr.Start(); err != nil {
+		t.Fatalf("start: %v", err)
+	}
+	defer mgr.Shutdown()
+	router := connector.NewRouter(cfg, mgr, logger)
+
+	if got := mgr.Running(); got != 2 {
+		t.Errorf("running = %d, want 2", got)
+	}
+
+	t.Run("echo", func(t *testing.T) {
+		resp, err := router.Call(context.Background(), "sample.echo", json.RawMessage(`{"text":"hi"}`))
+		if err != nil {
+			t.Fatalf("call: %v", err)
+		}
+		if !resp.OK || string(resp.Data) != `{"text":"hi"}` {
+			t.Fatalf("resp = %+v, data %s", resp, resp.Data)
+		}
+	})
+
+	t.Run("introspect", func(t *testing.T) {
+		data, err := router.Introspect(context.Background(), "sample")
+		if err != nil || data.Name != "sample" {
+			t.Fatalf("introspect = %+v, %v", data, err)
+		}
+	})
+
+	t.Run("event", func(t *testing.T) {
+		events := make(chan string, 1)
+		mgr.SetEventHandler(func(name string, ev *connector.Event) { events <- name + ": " + ev.Text })
+		defer mgr.SetEventHandler(nil)
+		resp, err := router.Call(context.Background(), "sample.notify", json.RawMessage(`{"text":"build failed"}`))
+		if err != nil || !resp.OK {
+			t.Fatalf("call: %+v, %v", resp, err)
+		}
+		select {
+		case got := <-events:
+			if got != "sample: build failed" {
+				t.Fatalf("event = %q", got)
+			}
+		default:
+			t.Fatal("no event")
+		}
+	})
+
+	t.Run("timeout", func(t *testing.T) {
+		start := time.Now()
+		_, err := router.Call(context.Background(), "sample.sleep", json.RawMessage(`{"ms":10000}`))
+		if err == nil || !strings.Contains(err.Error(), "timed out") {
+			t.Fatalf("err = %v", err)
+		}
+		if took := time.Since(start); took > 3*time.Second {
+			t.Fatalf("took %s", took)
+		}
+		// The next call gets a fresh instance.
+		if resp, err := router.Call(context.Background(), "sample.echo", json.RawMessage(`{"text":"again"}`)); err != nil || !resp.OK {
+			t.Fatalf("call after timeout: %+v, %v", resp, err)
+		}
+	})
+
+	t.Run("failures", func(t *testing.T) {
+		for tool, want := range map[string]string{
+			`oversized`: "exceeds 16384 byte limit",
+			`exit`:      "exited with code 3",
+			`malformed`: "invalid response",
+		} {
+			args := json.RawMessage(`{"code":3,"bytes":20000}`)
+			if _, err := router.Call(context.Background(), "chaos."+tool, args); err == nil || !strings.Contains(err.Error(), want) {
+				t.Errorf("%s: err = %v, want %q", tool, err, want)
+			}
+		}
+	})
+
+	t.Run("status", func(t *testing.T) {
+		for _, st := range mgr.Status() {
+			if !st.Running {
+				t.Errorf("%s not running", st.Name)
+			}
+		}
+		if err := mgr.StopConnector("chaos"); err != nil {
+			t.Fatalf("stop: %v", err)
+		}
+		if _, err := router.Call(context.Background(), "chaos.exit", json.RawMessage(`{}`)); err == nil || !strings.Contains(err.Error(), "not running") {
+			t.Fatalf("call after stop: %v", err)
+		}
+	})
+}
@@ -0,0 +1,31 @@
+package core
+
+import (
+	"context"
+	"log/slog"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jdelaire/openslack/core/connector"
+)
+
+// ConnectorEventNotifier returns an event handler that relays connector
+// events through the notifier, prefixed with the connector name.
+func ConnectorEventNotifier(notifier Notifier, logger *slog.Logger) connector.EventHandler {
+	return func(name string, ev *connector.Event) {
+		n := Notification{
+			ID:        uuid.New().String(),
+			Text:      "[" + name + "] " + ev.Text,
+			Source:    "connector:" + name,
+			CreatedAt: time.Now(),
+		}
+		ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+		defer cancel()
+
+		if err := notifier.Send(ctx, n); err != nil {
+			logger.Error("failed to relay connector event", "connector", name, "event", ev.Event, "error", err)
+			return
+		}
+		logger.Info("connector event relayed", "connector", name, "event", ev.Event, "id", n.ID)
+	}
+}
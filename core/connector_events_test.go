@@ -0,0 +1,34 @@
+package core
+
+import (
+	"io"
+	"log/slog"
+	"testing"
+
+	"github.com/jdelaire/openslack/core/connector"
+)
+
+func TestConnectorEventNotifier(t *testing.T) {
+	echo := &echoNotifier{}
+	h := ConnectorEventNotifier(echo, slog.New(slog.NewJSONHandler(io.Discard, nil)))
+
+	h("github", &connector.Event{Version: "v1", Event: "ci_failed", Text: "CI failed: acme/api"})
+
+	if len(echo.notifications()) != 1 {
+		t.Fatalf("expected 1 notification, got %d", len(echo.notifications()))
+	}
+	n := echo.notifications()[0]
+	if n.Text != "[github] CI failed: acme/api" {
+		t.Errorf("text = %q", n.Text)
+	}
+	if n.Source != "connector:github" || n.ID == "" {
+		t.Errorf("notification = %+v", n)
+	}
+}
+
+func TestConnectorEventNotifierSendFailure(t *testing.T) {
+	h := ConnectorEventNotifier(&failNotifier{}, slog.New(slog.NewJSONHandler(io.Discard, nil)))
+
+	// Must not panic; the failure is only logged.
+	h("github", &connector.Event{Version: "v1", Event: "ci_failed", Text: "x"})
+}
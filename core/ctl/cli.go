@@ -0,0 +1,469 @@
+package ctl
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"net/url"
+	"os"
+	"os/signal"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"github.com/jdelaire/openslack/core"
+	"github.com/jdelaire/openslack/core/adminapi"
+	"github.com/jdelaire/openslack/internal/tasks"
+)
+
+// TokenEnv overrides the keychain lookup of the admin API token.
+const TokenEnv = "OPENSLACK_ADMIN_TOKEN"
+
+// apiTimeout bounds one admin API call. Ops may run for up to 30s.
+const apiTimeout = 45 * time.Second
+
+const usage = `Usage: openslackctl [-socket PATH] [-api ADDR] [-token TOKEN] COMMAND [ARGS]
+
+Commands:
+  notify [-source S] [-title T] [-priority P] [-ack] [-in D | -at TIME] [TEXT]
+                      send a notification; TEXT is read from stdin if omitted
+  run OP [ARGS]       run an op and print its reply
+  tasks               list open tasks
+  tasks add TEXT      create a task that starts tomorrow
+  tasks done ID       mark a task done
+  tasks find TEXT     search open, done and archived tasks
+  logs [-n N] [-f]    show recent op runs; -f follows new ones
+  connectors          show connector health; exits 1 if any is stopped
+  reload              reload every watched config file
+  check               validate the config files in the config directory
+
+The admin API token is read from -token, then $OPENSLACK_ADMIN_TOKEN, then
+the keychain account admin-api-token.`
+
+// ConfigFile is a config file check validates. Load reads it, dropping the
+// result; a missing file is not an error.
+type ConfigFile struct {
+	Name string
+	Load func(path string) error
+}
+
+// CLI runs openslackctl commands.
+type CLI struct {
+	Stdin          io.Reader
+	Stdout, Stderr io.Writer
+	// Dir is the config directory, normally ~/.openslack.
+	Dir string
+	// Configs are the files check validates, in Dir.
+	Configs []ConfigFile
+	// Token looks up the admin API token when neither the flag nor
+	// TokenEnv sets it.
+	Token func() (string, error)
+	// Interval is how often logs -f polls; zero means 2s.
+	Interval time.Duration
+}
+
+// errUsage reports a malformed command line; usage has been printed.
+var errUsage = errors.New("usage")
+
+// Run runs the command line args, without the program name, and returns
+// the exit code: 0 on success, 1 on failure, 2 on a usage error.
+func (c *CLI) Run(args []string) int {
+	fs := flag.NewFlagSet("openslackctl", flag.ContinueOnError)
+	fs.SetOutput(io.Discard)
+	socket := fs.String("socket", filepath.Join(c.Dir, "openslack.sock"), "daemon socket path")
+	api := fs.String("api", "", "admin API address (default from admin_api.json)")
+	token := fs.String("token", "", "admin API token")
+	if err := fs.Parse(args); err != nil || fs.NArg() == 0 {
+		fmt.Fprintln(c.Stderr, usage)
+		return 2
+	}
+
+	client := &Client{Socket: *socket, API: *api, Token: *token}
+	cmd, rest := fs.Arg(0), fs.Args()[1:]
+	var err error
+	switch cmd {
+	case "notify":
+		err = c.notify(client, rest)
+	case "check":
+		err = c.check(rest)
+	case "run", "tasks", "logs", "connectors", "reload":
+		if err = c.connectAPI(client); err == nil {
+			err = c.apiCommand(client, cmd, rest)
+		}
+	case "help", "-h", "-help", "--help":
+		fmt.Fprintln(c.Stdout, usage)
+		return 0
+	default:
+		fmt.Fprintf(c.Stderr, "unknown command %q\n\n%s\n", cmd, usage)
+		return 2
+	}
+
+	switch {
+	case errors.Is(err, errUsage):
+		return 2
+	case err != nil:
+		fmt.Fprintln(c.Stderr, "openslackctl:", err)
+		return 1
+	}
+	return 0
+}
+
+// connectAPI fills in the admin API address and token where the flags
+// left them unset.
+func (c *CLI) connectAPI(client *Client) error {
+	if client.API == "" {
+		cfg, err := adminapi.LoadConfig(filepath.Join(c.Dir, "admin_api.json"))
+		if err != nil {
+			return err
+		}
+		client.API = adminapi.DefaultAddr
+		if cfg != nil {
+			client.API = cfg.Addr
+		}
+	}
+	if client.Token == "" {
+		client.Token = os.Getenv(TokenEnv)
+	}
+	if client.Token == "" && c.Token != nil {
+		token, err := c.Token()
+		if err != nil {
+			return fmt.Errorf("admin API token: %w (set %s or pass -token)", err, TokenEnv)
+		}
+		client.Token = token
+	}
+	if client.Token == "" {
+		return fmt.Errorf("no admin API token: set %s or pass -token", TokenEnv)
+	}
+	return nil
+}
+
+func (c *CLI) apiCommand(client *Client, cmd string, args []string) error {
+	if cmd == "logs" {
+		return c.logs(client, args)
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), apiTimeout)
+	defer cancel()
+	switch cmd {
+	case "run":
+		return c.run(ctx, client, args)
+	case "tasks":
+		return c.tasks(ctx, client, args)
+	case "connectors":
+		return c.connectors(ctx, client, args)
+	default:
+		return c.reload(ctx, client, args)
+	}
+}
+
+func (c *CLI) usageError(format string, args ...any) error {
+	fmt.Fprintf(c.Stderr, format+"\n", args...)
+	return errUsage
+}
+
+func (c *CLI) notify(client *Client, args []string) error {
+	fs := flag.NewFlagSet("notify", flag.ContinueOnError)
+	fs.SetOutput(c.Stderr)
+	source := fs.String("source", "openslackctl", "source label")
+	title := fs.String("title", "", "title")
+	priority := fs.String("priority", "", "low, normal, high or urgent")
+	ack := fs.Bool("ack", false, "ask for an acknowledgement")
+	in := fs.Duration("in", 0, "send after this long, e.g. 90m")
+	at := fs.String("at", "", "send at this RFC 3339 time")
+	if err := fs.Parse(args); err != nil {
+		return errUsage
+	}
+
+	text := strings.Join(fs.Args(), " ")
+	if text == "" {
+		data, err := io.ReadAll(io.LimitReader(c.Stdin, core.MaxPayloadBytes))
+		if err != nil {
+			return fmt.Errorf("read stdin: %w", err)
+		}
+		text = strings.TrimRight(string(data), "\n")
+	}
+	if strings.TrimSpace(text) == "" {
+		return c.usageError("notify: no text")
+	}
+
+	payload := core.NotifyPayload{
+		Text: text, Source: *source, Ack: *ack,
+		Title: *title, Priority: core.Priority(*priority),
+	}
+	switch {
+	case *in != 0 && *at != "":
+		return c.usageError("notify: use -in or -at, not both")
+	case *in != 0:
+		t := time.Now().Add(*in)
+		payload.SendAt = &t
+	case *at != "":
+		t, err := time.Parse(time.RFC3339, *at)
+		if err != nil {
+			return c.usageError("notify: -at must be an RFC 3339 time, e.g. 2026-03-01T09:00:00+01:00")
+		}
+		payload.SendAt = &t
+	}
+
+	resp, err := client.Send("notify", payload)
+	if err != nil {
+		return err
+	}
+	switch {
+	case resp.Status != "":
+		fmt.Fprintln(c.Stdout, strings.TrimSpace(resp.Status+" "+resp.ID))
+	case resp.ID != "":
+		fmt.Fprintln(c.Stdout, "sent", resp.ID)
+	default:
+		fmt.Fprintln(c.Stdout, "sent")
+	}
+	return nil
+}
+
+func (c *CLI) run(ctx context.Context, client *Client, args []string) error {
+	if len(args) == 0 {
+		return c.usageError("usage: openslackctl run OP [ARGS]")
+	}
+	op := strings.TrimPrefix(args[0], "/")
+	var out struct {
+		Output string `json:"output"`
+	}
+	err := client.Call(ctx, "POST", "/v1/ops/"+url.PathEscape(op), map[string]string{"args": strings.Join(args[1:], " ")}, &out)
+	if err != nil {
+		return err
+	}
+	fmt.Fprintln(c.Stdout, out.Output)
+	return nil
+}
+
+func (c *CLI) tasks(ctx context.Context, client *Client, args []string) error {
+	sub := ""
+	if len(args) > 0 {
+		sub = args[0]
+	}
+	text := strings.Join(args[min(1, len(args)):], " ")
+
+	var list struct {
+		Tasks []tasks.Task `json:"tasks"`
+	}
+	switch {
+	case sub == "" && len(args) == 0:
+		if err := client.Call(ctx, "GET", "/v1/tasks", nil, &list); err != nil {
+			return err
+		}
+		if len(list.Tasks) == 0 {
+			fmt.Fprintln(c.Stdout, "No open tasks.")
+		}
+	case sub == "add" && text != "":
+		var task tasks.Task
+		if err := client.Call(ctx, "POST", "/v1/tasks", map[string]string{"text": text}, &task); err != nil {
+			return err
+		}
+		list.Tasks = []tasks.Task{task}
+	case sub == "done" && len(args) == 2:
+		id, err := strconv.Atoi(args[1])
+		if err != nil || id <= 0 {
+			return c.usageError("usage: openslackctl tasks done ID")
+		}
+		var out struct {
+			Status string `json:"status"`
+		}
+		if err := client.Call(ctx, "POST", fmt.Sprintf("/v1/tasks/%d/done", id), nil, &out); err != nil {
+			return err
+		}
+		if out.Status == "already_done" {
+			fmt.Fprintf(c.Stdout, "Already done: %d\n", id)
+		} else {
+			fmt.Fprintf(c.Stdout, "Done: %d\n", id)
+		}
+		return nil
+	case sub == "find" && text != "":
+		if err := client.Call(ctx, "GET", "/v1/tasks?q="+url.QueryEscape(text), nil, &list); err != nil {
+			return err
+		}
+		if len(list.Tasks) == 0 {
+			fmt.Fprintf(c.Stdout, "No tasks match %q.\n", text)
+		}
+	default:
+		return c.usageError("usage: openslackctl tasks [add TEXT | done ID | find TEXT]")
+	}
+
+	tw := tabwriter.NewWriter(c.Stdout, 0, 4, 2, ' ', 0)
+	for _, task := range list.Tasks {
+		line := fmt.Sprintf("%d\t%s", task.ID, task.Text)
+		if task.Status == tasks.TaskStatusDone {
+			line += "\t(done)"
+		}
+		fmt.Fprintln(tw, line)
+	}
+	return tw.Flush()
+}
+
+type table struct {
+	Columns []string   `json:"columns"`
+	Rows    [][]string `json:"rows"`
+}
+
+// logs prints recent op runs, oldest first. With -f it keeps polling and
+// prints runs as they appear, until interrupted.
+func (c *CLI) logs(client *Client, args []string) error {
+	fs := flag.NewFlagSet("logs", flag.ContinueOnError)
+	fs.SetOutput(c.Stderr)
+	n := fs.Int("n", 20, "how many runs to show, up to 500")
+	follow := fs.Bool("f", false, "keep printing new runs")
+	if err := fs.Parse(args); err != nil || fs.NArg() > 0 {
+		return errUsage
+	}
+	if *n < 1 || *n > 500 {
+		return c.usageError("logs: -n must be between 1 and 500")
+	}
+
+	ctx := context.Background()
+	if *follow {
+		var stop context.CancelFunc
+		ctx, stop = signal.NotifyContext(ctx, os.Interrupt)
+		defer stop()
+	}
+	interval := c.Interval
+	if interval == 0 {
+		interval = 2 * time.Second
+	}
+
+	var last []string
+	for {
+		callCtx, cancel := context.WithTimeout(ctx, apiTimeout)
+		var res table
+		err := client.Call(callCtx, "GET", fmt.Sprintf("/v1/audit/ops?limit=%d", *n), nil, &res)
+		cancel()
+		if err != nil {
+			if ctx.Err() != nil {
+				return nil
+			}
+			return err
+		}
+
+		// Rows come newest first; print those after the last one seen.
+		fresh := res.Rows
+		if last != nil {
+			for i, row := range res.Rows {
+				if slicesEqual(row, last) {
+					fresh = res.Rows[:i]
+					break
+				}
+			}
+		}
+		for i := len(fresh) - 1; i >= 0; i-- {
+			fmt.Fprintln(c.Stdout, strings.Join(fresh[i], "  "))
+		}
+		if len(res.Rows) > 0 {
+			last = res.Rows[0]
+		}
+
+		if !*follow {
+			return nil
+		}
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-time.After(interval):
+		}
+	}
+}
+
+func slicesEqual(a, b []string) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+	return true
+}
+
+func (c *CLI) connectors(ctx context.Context, client *Client, args []string) error {
+	if len(args) > 0 {
+		return c.usageError("usage: openslackctl connectors")
+	}
+	var out struct {
+		Connectors []adminapi.ConnectorStatus `json:"connectors"`
+	}
+	if err := client.Call(ctx, "GET", "/v1/connectors", nil, &out); err != nil {
+		return err
+	}
+	if len(out.Connectors) == 0 {
+		fmt.Fprintln(c.Stdout, "No connectors configured.")
+		return nil
+	}
+
+	tw := tabwriter.NewWriter(c.Stdout, 0, 4, 2, ' ', 0)
+	fmt.Fprintln(tw, "CONNECTOR\tSTATE\tTOOLS")
+	var stopped []string
+	for _, st := range out.Connectors {
+		state := "running"
+		if !st.Running {
+			state = "stopped"
+			stopped = append(stopped, st.Name)
+		}
+		fmt.Fprintf(tw, "%s\t%s\t%d\n", st.Name, state, st.Tools)
+	}
+	if err := tw.Flush(); err != nil {
+		return err
+	}
+	if len(stopped) > 0 {
+		return fmt.Errorf("stopped: %s", strings.Join(stopped, ", "))
+	}
+	return nil
+}
+
+func (c *CLI) reload(ctx context.Context, client *Client, args []string) error {
+	if len(args) > 0 {
+		return c.usageError("usage: openslackctl reload")
+	}
+	var out struct {
+		Reloaded []string `json:"reloaded"`
+	}
+	if err := client.Call(ctx, "POST", "/v1/reload", nil, &out); err != nil {
+		return err
+	}
+	if len(out.Reloaded) == 0 {
+		fmt.Fprintln(c.Stdout, "No config files to reload.")
+	}
+	for _, path := range out.Reloaded {
+		fmt.Fprintln(c.Stdout, "reloaded", path)
+	}
+	return nil
+}
+
+// check loads every known config file present in Dir and reports which
+// are invalid. It needs no running daemon.
+func (c *CLI) check(args []string) error {
+	if len(args) > 0 {
+		return c.usageError("usage: openslackctl check")
+	}
+	checked, invalid := 0, 0
+	for _, cf := range c.Configs {
+		path := filepath.Join(c.Dir, cf.Name)
+		if _, err := os.Stat(path); errors.Is(err, os.ErrNotExist) {
+			continue
+		}
+		checked++
+		if err := cf.Load(path); err != nil {
+			invalid++
+			fmt.Fprintf(c.Stdout, "FAIL  %s: %s\n", cf.Name, err)
+			continue
+		}
+		fmt.Fprintf(c.Stdout, "ok    %s\n", cf.Name)
+	}
+	if checked == 0 {
+		fmt.Fprintf(c.Stdout, "No config files in %s.\n", c.Dir)
+		return nil
+	}
+	if invalid > 0 {
+		return fmt.Errorf("%d of %d config files invalid", invalid, checked)
+	}
+	return nil
+}
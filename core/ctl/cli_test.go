@@ -0,0 +1,277 @@
+package ctl
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"log/slog"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/jdelaire/openslack/core"
+	"github.com/jdelaire/openslack/core/adminapi"
+	"github.com/jdelaire/openslack/core/audit"
+	"github.com/jdelaire/openslack/core/connector"
+	"github.com/jdelaire/openslack/core/ops"
+	"github.com/jdelaire/openslack/internal/platform"
+	"github.com/jdelaire/openslack/internal/tasks"
+)
+
+type echoOp struct{}
+
+func (echoOp) Name() string                                           { return "echo" }
+func (echoOp) Description() string                                    { return "Echo args" }
+func (echoOp) Risk() ops.RiskLevel                                    { return ops.RiskLow }
+func (echoOp) Execute(_ context.Context, args string) (string, error) { return args, nil }
+
+type echoExec struct{}
+
+func (echoExec) Exec(_ context.Context, _ int64, cmd, args string) (string, error) {
+	return cmd + ": " + args, nil
+}
+
+type fakeAudit struct{ rows [][]string }
+
+func (a *fakeAudit) Run(context.Context, string, time.Time) (audit.Result, error) {
+	return audit.Result{}, nil
+}
+
+func (a *fakeAudit) RecentOps(_ context.Context, limit int) (audit.Result, error) {
+	return audit.Result{Columns: []string{"Time", "Op"}, Rows: a.rows[:min(limit, len(a.rows))]}, nil
+}
+
+// newAPI starts an admin API on a loopback port and returns a CLI
+// pointed at it.
+func newAPI(t *testing.T, configure func(*adminapi.Server)) (*CLI, *bytes.Buffer, *bytes.Buffer) {
+	t.Helper()
+	s, err := adminapi.NewServer(&adminapi.Config{Addr: adminapi.DefaultAddr}, "secret", nil)
+	if err != nil {
+		t.Fatalf("new server: %v", err)
+	}
+	configure(s)
+	srv := httptest.NewServer(s.Handler())
+	t.Cleanup(srv.Close)
+
+	var stdout, stderr bytes.Buffer
+	t.Setenv(TokenEnv, "secret")
+	cli := &CLI{Stdout: &stdout, Stderr: &stderr, Dir: t.TempDir()}
+	os.WriteFile(filepath.Join(cli.Dir, "admin_api.json"),
+		[]byte(`{"addr": "`+strings.TrimPrefix(srv.URL, "http://")+`"}`), 0600)
+	return cli, &stdout, &stderr
+}
+
+func TestRunOp(t *testing.T) {
+	cli, stdout, stderr := newAPI(t, func(s *adminapi.Server) {
+		reg := ops.NewRegistry()
+		reg.Register(echoOp{})
+		s.WithOps(reg, echoExec{})
+	})
+
+	if code := cli.Run([]string{"run", "/echo", "hello", "world"}); code != 0 {
+		t.Fatalf("exit = %d, stderr %q", code, stderr)
+	}
+	if got := stdout.String(); got != "echo: hello world\n" {
+		t.Fatalf("stdout = %q", got)
+	}
+
+	if code := cli.Run([]string{"run", "nope"}); code != 1 {
+		t.Fatalf("unknown op exit = %d", code)
+	}
+	if !strings.Contains(stderr.String(), "nope") {
+		t.Fatalf("stderr = %q", stderr)
+	}
+}
+
+func TestTasks(t *testing.T) {
+	now := time.Date(2026, 3, 1, 12, 0, 0, 0, time.UTC)
+	cli, stdout, stderr := newAPI(t, func(s *adminapi.Server) {
+		svc := tasks.NewTaskService(tasks.NewStore(filepath.Join(t.TempDir(), "tasks.json"))).
+			WithClock(func() time.Time { return now })
+		s.WithTasks(svc)
+	})
+
+	steps := []struct {
+		args []string
+		want string
+	}{
+		{[]string{"tasks"}, "No open tasks.\n"},
+		{[]string{"tasks", "add", "Buy", "eggs"}, "1  Buy eggs\n"},
+		{[]string{"tasks", "add", "Call", "Bob"}, "2  Call Bob\n"},
+		{[]string{"tasks", "done", "1"}, "Done: 1\n"},
+		{[]string{"tasks", "done", "1"}, "Already done: 1\n"},
+		{[]string{"tasks"}, "2  Call Bob\n"},
+		{[]string{"tasks", "find", "eggs"}, "1  Buy eggs  (done)\n"},
+		{[]string{"tasks", "find", "milk"}, "No tasks match \"milk\".\n"},
+	}
+	for _, step := range steps {
+		stdout.Reset()
+		if code := cli.Run(step.args); code != 0 {
+			t.Fatalf("%v: exit = %d, stderr %q", step.args, code, stderr)
+		}
+		if got := stdout.String(); got != step.want {
+			t.Fatalf("%v: stdout = %q, want %q", step.args, got, step.want)
+		}
+	}
+
+	if code := cli.Run([]string{"tasks", "done", "x"}); code != 2 {
+		t.Fatalf("bad id exit = %d", code)
+	}
+	if code := cli.Run([]string{"tasks", "done", "9"}); code != 1 {
+		t.Fatalf("missing task exit = %d", code)
+	}
+}
+
+func TestLogs(t *testing.T) {
+	cli, stdout, _ := newAPI(t, func(s *adminapi.Server) {
+		s.WithAudit(&fakeAudit{rows: [][]string{{"12:02", "tasks"}, {"12:01", "status"}, {"12:00", "help"}}})
+	})
+
+	if code := cli.Run([]string{"logs", "-n", "2"}); code != 0 {
+		t.Fatalf("exit = %d", code)
+	}
+	if got, want := stdout.String(), "12:01  status\n12:02  tasks\n"; got != want {
+		t.Fatalf("stdout = %q, want %q", got, want)
+	}
+	if code := cli.Run([]string{"logs", "-n", "0"}); code != 2 {
+		t.Fatalf("-n 0 exit = %d", code)
+	}
+}
+
+func TestConnectors(t *testing.T) {
+	mgr := connector.NewManager(&connector.Config{Connectors: map[string]connector.ConnectorConfig{
+		"sample": {Exec: "/bin/false", Tools: []string{"echo"}},
+	}}, slog.Default())
+	cli, stdout, stderr := newAPI(t, func(s *adminapi.Server) {
+		s.WithConnectors(func() *connector.Manager { return mgr })
+	})
+
+	if code := cli.Run([]string{"connectors"}); code != 1 {
+		t.Fatalf("exit = %d, want 1 for a stopped connector", code)
+	}
+	if got := stdout.String(); !strings.Contains(got, "sample     stopped  1") {
+		t.Fatalf("stdout = %q", got)
+	}
+	if !strings.Contains(stderr.String(), "stopped: sample") {
+		t.Fatalf("stderr = %q", stderr)
+	}
+}
+
+func TestReload(t *testing.T) {
+	cli, stdout, _ := newAPI(t, func(s *adminapi.Server) {
+		s.WithReload(func() []string { return []string{"/cfg/commands.json"} })
+	})
+	if code := cli.Run([]string{"reload"}); code != 0 {
+		t.Fatalf("exit = %d", code)
+	}
+	if got := stdout.String(); got != "reloaded /cfg/commands.json\n" {
+		t.Fatalf("stdout = %q", got)
+	}
+}
+
+func TestToken(t *testing.T) {
+	cli, _, stderr := newAPI(t, func(s *adminapi.Server) {
+		s.WithReload(func() []string { return nil })
+	})
+	t.Setenv(TokenEnv, "")
+	cli.Token = func() (string, error) { return "", errors.New("not found") }
+	if code := cli.Run([]string{"reload"}); code != 1 {
+		t.Fatalf("exit = %d", code)
+	}
+	if !strings.Contains(stderr.String(), TokenEnv) {
+		t.Fatalf("stderr = %q", stderr)
+	}
+
+	cli.Token = func() (string, error) { return "wrong", nil }
+	if code := cli.Run([]string{"reload"}); code != 1 {
+		t.Fatalf("wrong token exit = %d", code)
+	}
+
+	if code := cli.Run([]string{"-token", "secret", "reload"}); code != 0 {
+		t.Fatalf("-token exit = %d, stderr %q", code, stderr)
+	}
+}
+
+func TestNotify(t *testing.T) {
+	dir, err := os.MkdirTemp("", "ctl")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { os.RemoveAll(dir) })
+	ln, err := platform.Listen(filepath.Join(dir, "openslack.sock"))
+	if err != nil {
+		t.Fatalf("listen: %v", err)
+	}
+	defer ln.Close()
+
+	got := make(chan core.Request, 1)
+	go func() {
+		conn, err := ln.Accept()
+		if err != nil {
+			return
+		}
+		defer conn.Close()
+		var req core.Request
+		json.NewDecoder(conn).Decode(&req)
+		got <- req
+		json.NewEncoder(conn).Encode(core.Response{OK: true, ID: "n-1", Status: "queued"})
+	}()
+
+	var stdout, stderr bytes.Buffer
+	cli := &CLI{Stdin: strings.NewReader("disk almost full\n"), Stdout: &stdout, Stderr: &stderr, Dir: dir}
+	if code := cli.Run([]string{"notify", "-title", "nas", "-priority", "high", "-ack"}); code != 0 {
+		t.Fatalf("exit = %d, stderr %q", code, stderr.String())
+	}
+	if stdout.String() != "queued n-1\n" {
+		t.Fatalf("stdout = %q", stdout.String())
+	}
+
+	req := <-got
+	var p core.NotifyPayload
+	if err := json.Unmarshal(req.Payload, &p); err != nil {
+		t.Fatalf("payload: %v", err)
+	}
+	if req.Action != "notify" || p.Text != "disk almost full" || p.Title != "nas" ||
+		p.Priority != core.PriorityHigh || !p.Ack || p.Source != "openslackctl" {
+		t.Fatalf("request = %+v, payload %+v", req, p)
+	}
+}
+
+func TestCheck(t *testing.T) {
+	var stdout, stderr bytes.Buffer
+	cli := &CLI{Stdout: &stdout, Stderr: &stderr, Dir: t.TempDir(), Configs: []ConfigFile{
+		{Name: "admin_api.json", Load: func(path string) error { _, err := adminapi.LoadConfig(path); return err }},
+		{Name: "dashboard.json", Load: func(string) error { t.Fatal("missing file loaded"); return nil }},
+		{Name: "limits.json", Load: func(string) error { return nil }},
+	}}
+	os.WriteFile(filepath.Join(cli.Dir, "admin_api.json"), []byte(`{"addr": "0.0.0.0:8788"}`), 0600)
+	os.WriteFile(filepath.Join(cli.Dir, "limits.json"), []byte(`{}`), 0600)
+
+	if code := cli.Run([]string{"check"}); code != 1 {
+		t.Fatalf("exit = %d", code)
+	}
+	out := stdout.String()
+	if !strings.HasPrefix(out, "FAIL  admin_api.json: admin api ") || !strings.HasSuffix(out, "ok    limits.json\n") {
+		t.Fatalf("stdout = %q", out)
+	}
+	if !strings.Contains(stderr.String(), "1 of 2 config files invalid") {
+		t.Fatalf("stderr = %q", stderr.String())
+	}
+}
+
+func TestUsage(t *testing.T) {
+	var stdout, stderr bytes.Buffer
+	cli := &CLI{Stdout: &stdout, Stderr: &stderr, Dir: t.TempDir()}
+	for _, args := range [][]string{nil, {"frobnicate"}, {"-bogus", "reload"}} {
+		if code := cli.Run(args); code != 2 {
+			t.Fatalf("%v: exit = %d", args, code)
+		}
+	}
+	if code := cli.Run([]string{"help"}); code != 0 || !strings.Contains(stdout.String(), "Commands:") {
+		t.Fatalf("help exit = %d, stdout %q", code, stdout.String())
+	}
+}
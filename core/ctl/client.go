@@ -0,0 +1,122 @@
+// Package ctl implements openslackctl, the command-line companion to the
+// daemon. Notifications go over the Unix socket; everything else goes to
+// the admin API, except config checks, which run locally.
+package ctl
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"time"
+
+	"github.com/jdelaire/openslack/core"
+	"github.com/jdelaire/openslack/internal/platform"
+)
+
+// socketTimeout matches the server's per-connection deadline.
+const socketTimeout = 5 * time.Second
+
+// Client talks to a running daemon.
+type Client struct {
+	// Socket is the daemon's Unix socket path.
+	Socket string
+	// API is the admin API's address, e.g. "127.0.0.1:8788", and Token its
+	// access token.
+	API   string
+	Token string
+	HTTP  *http.Client
+}
+
+// Send sends one request envelope over the socket and returns the reply.
+// A reply that is not OK is returned as an error.
+func (c *Client) Send(action string, payload any) (core.Response, error) {
+	data, err := json.Marshal(payload)
+	if err != nil {
+		return core.Response{}, err
+	}
+	req, err := json.Marshal(core.Request{Version: core.CurrentVersion, Action: action, Payload: data})
+	if err != nil {
+		return core.Response{}, err
+	}
+
+	conn, err := platform.Dial(c.Socket, socketTimeout)
+	if err != nil {
+		return core.Response{}, fmt.Errorf("connect to daemon at %s: %w", c.Socket, err)
+	}
+	defer conn.Close()
+	conn.SetDeadline(time.Now().Add(socketTimeout))
+	if _, err := conn.Write(req); err != nil {
+		return core.Response{}, fmt.Errorf("send request: %w", err)
+	}
+
+	var resp core.Response
+	if err := json.NewDecoder(conn).Decode(&resp); err != nil {
+		return core.Response{}, fmt.Errorf("read response: %w", err)
+	}
+	if !resp.OK {
+		return resp, fmt.Errorf("daemon: %s", resp.Error)
+	}
+	return resp, nil
+}
+
+// apiError is the admin API's error reply.
+type apiError struct {
+	Error  string `json:"error"`
+	Output string `json:"output"`
+}
+
+// Call makes an admin API request, encoding in as the JSON body if it is
+// not nil and decoding a successful reply into out.
+func (c *Client) Call(ctx context.Context, method, path string, in, out any) error {
+	var body io.Reader = http.NoBody
+	if in != nil {
+		data, err := json.Marshal(in)
+		if err != nil {
+			return err
+		}
+		body = bytes.NewReader(data)
+	}
+	req, err := http.NewRequestWithContext(ctx, method, "http://"+c.API+path, body)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Authorization", "Bearer "+c.Token)
+	if method != http.MethodGet {
+		req.Header.Set("Content-Type", "application/json")
+	}
+
+	hc := c.HTTP
+	if hc == nil {
+		hc = http.DefaultClient
+	}
+	resp, err := hc.Do(req)
+	if err != nil {
+		return fmt.Errorf("admin api: %w", err)
+	}
+	defer resp.Body.Close()
+	data, err := io.ReadAll(io.LimitReader(resp.Body, 4<<20))
+	if err != nil {
+		return fmt.Errorf("admin api: %w", err)
+	}
+
+	if resp.StatusCode >= 300 {
+		var e apiError
+		if json.Unmarshal(data, &e) != nil || e.Error == "" {
+			e.Error = fmt.Sprintf("%s: %s", resp.Status, bytes.TrimSpace(data))
+		}
+		if e.Output != "" {
+			return fmt.Errorf("%s\n%s", e.Error, e.Output)
+		}
+		return fmt.Errorf("%s", e.Error)
+	}
+	if out == nil {
+		return nil
+	}
+	if err := json.Unmarshal(data, out); err != nil {
+		return fmt.Errorf("admin api: decode reply: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,77 @@
+package dashboard
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+
+	"github.com/jdelaire/openslack/core/localhttp"
+)
+
+// Defaults for unset fields.
+const (
+	DefaultAddr       = "127.0.0.1:8787"
+	DefaultRefreshSec = 10
+)
+
+// TokenAccount is the keychain account holding the dashboard's access
+// token.
+const TokenAccount = "dashboard-token"
+
+// Config sets where the dashboard listens and how often the page reloads.
+// Addr must be a loopback address: the dashboard is never served to the
+// network.
+type Config struct {
+	Addr       string `json:"addr"`
+	RefreshSec int    `json:"refresh_sec"`
+}
+
+// Default returns the configuration used without a config file.
+func Default() *Config {
+	cfg := &Config{}
+	applyDefaults(cfg)
+	return cfg
+}
+
+// LoadConfig reads and validates a dashboard config file.
+// Returns nil, nil if the file does not exist.
+func LoadConfig(path string) (*Config, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("read dashboard config: %w", err)
+	}
+
+	var cfg Config
+	if err := json.Unmarshal(data, &cfg); err != nil {
+		return nil, fmt.Errorf("parse dashboard config: %w", err)
+	}
+	if cfg.RefreshSec < 0 {
+		return nil, fmt.Errorf("dashboard refresh_sec must not be negative")
+	}
+	applyDefaults(&cfg)
+	if err := checkLoopback(cfg.Addr); err != nil {
+		return nil, err
+	}
+	return &cfg, nil
+}
+
+func applyDefaults(cfg *Config) {
+	if cfg.Addr == "" {
+		cfg.Addr = DefaultAddr
+	}
+	if cfg.RefreshSec == 0 {
+		cfg.RefreshSec = DefaultRefreshSec
+	}
+}
+
+// checkLoopback rejects listen addresses other than 127.0.0.1, ::1 and
+// localhost.
+func checkLoopback(addr string) error {
+	if err := localhttp.CheckLoopback(addr); err != nil {
+		return fmt.Errorf("dashboard %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,172 @@
+package dashboard
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/jdelaire/openslack/core/approval"
+	"github.com/jdelaire/openslack/core/audit"
+	"github.com/jdelaire/openslack/core/connector"
+	"github.com/jdelaire/openslack/core/delivery"
+	"github.com/jdelaire/openslack/internal/acks"
+	"github.com/jdelaire/openslack/internal/outbox"
+	"github.com/jdelaire/openslack/internal/tasks"
+)
+
+// maxText caps a text cell, in characters.
+const maxText = 120
+
+// OpsPanel shows the latest n op runs from the audit log.
+func OpsPanel(log *audit.Log, n int) Panel {
+	return Panel{
+		Title: "Recent commands",
+		Empty: "No commands run yet.",
+		Load: func(ctx context.Context) (Table, error) {
+			res, err := log.RecentOps(ctx, n)
+			if err != nil {
+				return Table{}, err
+			}
+			return Table{Columns: res.Columns, Rows: res.Rows}, nil
+		},
+	}
+}
+
+// TasksPanel shows the open tasks.
+func TasksPanel(svc *tasks.TaskService) Panel {
+	return Panel{
+		Title: "Open tasks",
+		Empty: "No open tasks.",
+		Load: func(context.Context) (Table, error) {
+			open, err := svc.ListOpen()
+			if err != nil {
+				return Table{}, err
+			}
+			t := Table{Columns: []string{"ID", "Task", "Starts"}}
+			for _, task := range open {
+				t.Rows = append(t.Rows, []string{strconv.Itoa(task.ID), clip(task.Text), task.StartDate})
+			}
+			return t, nil
+		},
+	}
+}
+
+// ConnectorsPanel shows each configured connector and whether its process
+// is running. manager returns the current manager, or nil if none are
+// configured, as for connector.ConnectorsOp.
+func ConnectorsPanel(manager func() *connector.Manager) Panel {
+	return Panel{
+		Title: "Connectors",
+		Empty: "No connectors configured.",
+		Load: func(context.Context) (Table, error) {
+			t := Table{Columns: []string{"Connector", "State", "Tools"}}
+			mgr := manager()
+			if mgr == nil {
+				return t, nil
+			}
+			for _, st := range mgr.Status() {
+				state := "stopped"
+				if st.Running {
+					state = "running"
+				}
+				t.Rows = append(t.Rows, []string{st.Name, state, strconv.Itoa(st.Tools)})
+			}
+			return t, nil
+		},
+	}
+}
+
+// ApprovalsPanel shows every chat's outstanding /do approvals. Nonces are
+// left out: the page is not a way to approve.
+func ApprovalsPanel(store *approval.Store) Panel {
+	return Panel{
+		Title: "Pending approvals",
+		Empty: "No pending approvals.",
+		Load: func(context.Context) (Table, error) {
+			t := Table{Columns: []string{"Chat", "Op", "Args", "Expires"}}
+			for _, a := range store.All() {
+				t.Rows = append(t.Rows, []string{
+					strconv.FormatInt(a.ChatID, 10), a.OpName, clip(a.Args), a.ExpiresAt.Local().Format(time.TimeOnly),
+				})
+			}
+			return t, nil
+		},
+	}
+}
+
+// Queues are the queues QueuesPanel counts. Nil ones are left out.
+type Queues struct {
+	Scheduled *outbox.Queue
+	Acks      *acks.Tracker
+	Approvals *approval.Store
+}
+
+// QueuesPanel shows how many items wait in each queue: notifications
+// scheduled for later, notifications awaiting acknowledgement, and
+// approvals awaiting /approve.
+func QueuesPanel(q Queues) Panel {
+	return Panel{
+		Title: "Queues",
+		Load: func(context.Context) (Table, error) {
+			t := Table{Columns: []string{"Queue", "Waiting"}}
+			if q.Scheduled != nil {
+				msgs, err := q.Scheduled.Pending()
+				if err != nil {
+					return Table{}, err
+				}
+				t.Rows = append(t.Rows, []string{"Scheduled notifications", strconv.Itoa(len(msgs))})
+			}
+			if q.Acks != nil {
+				recs, err := q.Acks.Pending()
+				if err != nil {
+					return Table{}, err
+				}
+				t.Rows = append(t.Rows, []string{"Awaiting acknowledgement", strconv.Itoa(len(recs))})
+			}
+			if q.Approvals != nil {
+				t.Rows = append(t.Rows, []string{"Pending approvals", strconv.Itoa(len(q.Approvals.All()))})
+			}
+			return t, nil
+		},
+	}
+}
+
+// NotificationsPanel shows the latest n outbound deliveries from the
+// delivery log.
+func NotificationsPanel(log *delivery.Log, n int) Panel {
+	return Panel{
+		Title: "Notifications",
+		Empty: "No notifications sent yet.",
+		Load: func(context.Context) (Table, error) {
+			recs, err := log.Recent(n, false)
+			if err != nil {
+				return Table{}, err
+			}
+			t := Table{Columns: []string{"Time", "Source", "Status", "Text"}}
+			for _, r := range recs {
+				status := string(r.Status)
+				if r.Attempts > 1 {
+					status = fmt.Sprintf("%s after %d attempts", r.Status, r.Attempts)
+				}
+				if r.Error != "" {
+					status += ": " + r.Error
+				}
+				t.Rows = append(t.Rows, []string{
+					r.Updated.Local().Format(time.DateTime), r.Notification.Source, status, clip(r.Notification.PlainText()),
+				})
+			}
+			return t, nil
+		},
+	}
+}
+
+// clip flattens text to one line of at most maxText characters.
+func clip(text string) string {
+	text = strings.Join(strings.Fields(text), " ")
+	if r := []rune(text); len(r) > maxText {
+		text = string(r[:maxText]) + "…"
+	}
+	return text
+}
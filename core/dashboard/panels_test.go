@@ -0,0 +1,63 @@
+package dashboard
+
+import (
+	"context"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/jdelaire/openslack/core/approval"
+	"github.com/jdelaire/openslack/internal/tasks"
+)
+
+func TestTasksPanel(t *testing.T) {
+	svc := tasks.NewTaskService(tasks.NewStore(filepath.Join(t.TempDir(), "tasks.json")))
+	svc.CreateTomorrow("Buy\neggs")
+	svc.CreateTomorrow("Call mom")
+	svc.Complete(2)
+
+	table, err := TasksPanel(svc).Load(context.Background())
+	if err != nil {
+		t.Fatalf("load: %v", err)
+	}
+	if len(table.Rows) != 1 || table.Rows[0][0] != "1" || table.Rows[0][1] != "Buy eggs" {
+		t.Fatalf("rows = %v", table.Rows)
+	}
+}
+
+func TestApprovalsAndQueuesPanels(t *testing.T) {
+	store := approval.New()
+	store.Create(100, "deploy", "prod")
+	store.Create(200, "restart", "")
+
+	table, err := ApprovalsPanel(store).Load(context.Background())
+	if err != nil {
+		t.Fatalf("load approvals: %v", err)
+	}
+	if len(table.Rows) != 2 {
+		t.Fatalf("approval rows = %v", table.Rows)
+	}
+	for _, row := range table.Rows {
+		if len(row) != len(table.Columns) {
+			t.Errorf("row %v does not match columns %v", row, table.Columns)
+		}
+	}
+
+	table, err = QueuesPanel(Queues{Approvals: store}).Load(context.Background())
+	if err != nil {
+		t.Fatalf("load queues: %v", err)
+	}
+	if len(table.Rows) != 1 || table.Rows[0][0] != "Pending approvals" || table.Rows[0][1] != "2" {
+		t.Fatalf("queue rows = %v", table.Rows)
+	}
+}
+
+func TestClip(t *testing.T) {
+	if got := clip("a\n  b\tc"); got != "a b c" {
+		t.Errorf("clip = %q", got)
+	}
+	long := strings.Repeat("x", maxText+5)
+	if got := clip(long); len([]rune(got)) != maxText+1 || !strings.HasSuffix(got, "…") {
+		t.Errorf("clip(long) = %q", got)
+	}
+}
@@ -0,0 +1,215 @@
+// Package dashboard serves a read-only status page on localhost: recent
+// op runs, open tasks, connector health, pending approvals, queue depths
+// and notification history, on one page instead of one chat question at a
+// time. It only shows what panels load, and offers no way to change
+// anything.
+package dashboard
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"html/template"
+	"log/slog"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/jdelaire/openslack/core/localhttp"
+)
+
+// loadTimeout bounds each panel's Load.
+const loadTimeout = 5 * time.Second
+
+// Table is a panel's content, one string per cell.
+type Table struct {
+	Columns []string   `json:"columns"`
+	Rows    [][]string `json:"rows"`
+}
+
+// Panel is one section of the dashboard. Load runs on every page view, so
+// the page always shows current state.
+type Panel struct {
+	Title string
+	// Empty is shown instead of a table with no rows.
+	Empty string
+	Load  func(ctx context.Context) (Table, error)
+}
+
+// view is a loaded panel, as rendered and as served by /status.json.
+type view struct {
+	Title string `json:"title"`
+	Empty string `json:"empty,omitempty"`
+	Table
+	Error string `json:"error,omitempty"`
+}
+
+// Server serves the dashboard over HTTP. Every request must carry the
+// access token, as the password of HTTP basic auth or as a bearer token,
+// and name a loopback host, so a web page the browser visits cannot read
+// it through DNS rebinding.
+type Server struct {
+	cfg    *Config
+	token  string
+	panels []Panel
+	logger *slog.Logger
+	now    func() time.Time
+
+	run localhttp.Runner
+}
+
+// NewServer returns a dashboard server. A nil cfg uses the defaults. The
+// token must not be empty.
+func NewServer(cfg *Config, token string, logger *slog.Logger) (*Server, error) {
+	if cfg == nil {
+		cfg = Default()
+	}
+	if err := checkLoopback(cfg.Addr); err != nil {
+		return nil, err
+	}
+	if token == "" {
+		return nil, errors.New("dashboard: no access token")
+	}
+	if logger == nil {
+		logger = slog.Default()
+	}
+	return &Server{cfg: cfg, token: token, logger: logger, now: time.Now}, nil
+}
+
+// WithPanels adds panels, shown in the order given.
+func (s *Server) WithPanels(panels ...Panel) *Server {
+	s.panels = append(s.panels, panels...)
+	return s
+}
+
+// WithClock replaces time.Now for the page's timestamp. A nil now is
+// ignored.
+func (s *Server) WithClock(now func() time.Time) *Server {
+	if now != nil {
+		s.now = now
+	}
+	return s
+}
+
+// Handler returns the dashboard's routes: the page at / and the same data
+// as JSON at /status.json.
+func (s *Server) Handler() http.Handler {
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /{$}", s.page)
+	mux.HandleFunc("GET /status.json", s.status)
+	return s.guard(mux)
+}
+
+// Start listens on the configured address and serves until ctx is
+// cancelled or Shutdown is called.
+func (s *Server) Start(ctx context.Context) error {
+	return s.run.Start(ctx, "dashboard", s.cfg.Addr, s.Handler(), s.logger)
+}
+
+// Addr returns the address Start is listening on, or "" before Start.
+func (s *Server) Addr() string { return s.run.Addr() }
+
+// Shutdown stops serving, waiting up to 5s for in-flight requests.
+func (s *Server) Shutdown() { s.run.Shutdown() }
+
+// guard checks the host and token, and keeps the page out of frames.
+func (s *Server) guard(next http.Handler) http.Handler {
+	return localhttp.Guard(s.token, "OpenSlack", http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		h := w.Header()
+		h.Set("Content-Security-Policy", "default-src 'none'; style-src 'unsafe-inline'; frame-ancestors 'none'")
+		h.Set("X-Frame-Options", "DENY")
+		h.Set("Referrer-Policy", "no-referrer")
+		next.ServeHTTP(w, r)
+	}))
+}
+
+// load runs every panel's Load. A failing panel shows its error rather
+// than failing the page.
+func (s *Server) load(ctx context.Context) []view {
+	views := make([]view, len(s.panels))
+	var wg sync.WaitGroup
+	for i, p := range s.panels {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			views[i] = s.loadPanel(ctx, p)
+		}()
+	}
+	wg.Wait()
+	return views
+}
+
+func (s *Server) loadPanel(ctx context.Context, p Panel) (v view) {
+	v = view{Title: p.Title, Empty: p.Empty}
+	ctx, cancel := context.WithTimeout(ctx, loadTimeout)
+	defer cancel()
+	defer func() {
+		if r := recover(); r != nil {
+			v.Table, v.Error = Table{}, fmt.Sprintf("panic: %v", r)
+			s.logger.Error("dashboard panel panicked", "panel", p.Title, "panic", r)
+		}
+	}()
+
+	table, err := p.Load(ctx)
+	if err != nil {
+		s.logger.Warn("dashboard panel failed", "panel", p.Title, "error", err)
+		v.Error = err.Error()
+		return v
+	}
+	v.Table = table
+	return v
+}
+
+func (s *Server) status(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(struct {
+		Generated time.Time `json:"generated"`
+		Panels    []view    `json:"panels"`
+	}{s.now(), s.load(r.Context())})
+}
+
+func (s *Server) page(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	err := pageTemplate.Execute(w, struct {
+		Refresh   int
+		Generated string
+		Panels    []view
+	}{s.cfg.RefreshSec, s.now().Format(time.DateTime), s.load(r.Context())})
+	if err != nil {
+		s.logger.Error("dashboard render failed", "error", err)
+	}
+}
+
+var pageTemplate = template.Must(template.New("page").Parse(`<!DOCTYPE html>
+<html lang="en">
+<head>
+<meta charset="utf-8">
+<meta http-equiv="refresh" content="{{.Refresh}}">
+<title>OpenSlack</title>
+<style>
+body { font: 14px system-ui, sans-serif; margin: 1.5em; color: #222; }
+h1 { font-size: 1.3em; }
+h2 { font-size: 1.05em; margin-top: 1.6em; }
+table { border-collapse: collapse; }
+th, td { text-align: left; padding: 0.25em 0.8em 0.25em 0; vertical-align: top; }
+th { border-bottom: 1px solid #ccc; }
+.note { color: #777; }
+.error { color: #b00; }
+</style>
+</head>
+<body>
+<h1>OpenSlack</h1>
+<p class="note">Updated {{.Generated}}, refreshes every {{.Refresh}}s.</p>
+{{range .Panels}}
+<h2>{{.Title}}</h2>
+{{if .Error}}<p class="error">Unavailable: {{.Error}}</p>
+{{else if not .Rows}}<p class="note">{{or .Empty "Nothing to show."}}</p>
+{{else}}<table>
+<tr>{{range .Columns}}<th>{{.}}</th>{{end}}</tr>
+{{range .Rows}}<tr>{{range .}}<td>{{.}}</td>{{end}}</tr>
+{{end}}</table>
+{{end}}{{end}}
+</body>
+</html>
+`))
@@ -0,0 +1,228 @@
+package dashboard
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func newTestServer(t *testing.T, panels ...Panel) *Server {
+	t.Helper()
+	s, err := NewServer(nil, "secret", nil)
+	if err != nil {
+		t.Fatalf("new server: %v", err)
+	}
+	return s.WithPanels(panels...).WithClock(func() time.Time {
+		return time.Date(2026, 3, 1, 9, 30, 0, 0, time.UTC)
+	})
+}
+
+func get(t *testing.T, h http.Handler, target string, prepare func(*http.Request)) *httptest.ResponseRecorder {
+	t.Helper()
+	req := httptest.NewRequest(http.MethodGet, target, nil)
+	req.Host = "127.0.0.1:8787"
+	if prepare != nil {
+		prepare(req)
+	}
+	rec := httptest.NewRecorder()
+	h.ServeHTTP(rec, req)
+	return rec
+}
+
+func withToken(token string) func(*http.Request) {
+	return func(r *http.Request) { r.SetBasicAuth("", token) }
+}
+
+func TestAuth(t *testing.T) {
+	h := newTestServer(t).Handler()
+
+	tests := []struct {
+		name    string
+		prepare func(*http.Request)
+		want    int
+	}{
+		{"no token", nil, http.StatusUnauthorized},
+		{"wrong token", withToken("guess"), http.StatusUnauthorized},
+		{"basic auth", withToken("secret"), http.StatusOK},
+		{"bearer", func(r *http.Request) { r.Header.Set("Authorization", "Bearer secret") }, http.StatusOK},
+		{"foreign host", func(r *http.Request) {
+			r.SetBasicAuth("", "secret")
+			r.Host = "evil.example:8787"
+		}, http.StatusForbidden},
+		{"localhost", func(r *http.Request) {
+			r.SetBasicAuth("", "secret")
+			r.Host = "localhost:8787"
+		}, http.StatusOK},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rec := get(t, h, "/", tt.prepare)
+			if rec.Code != tt.want {
+				t.Fatalf("status = %d, want %d", rec.Code, tt.want)
+			}
+			if tt.want == http.StatusUnauthorized && rec.Header().Get("WWW-Authenticate") == "" {
+				t.Error("no WWW-Authenticate challenge")
+			}
+		})
+	}
+}
+
+func TestReadOnly(t *testing.T) {
+	h := newTestServer(t).Handler()
+	req := httptest.NewRequest(http.MethodPost, "/", strings.NewReader("x"))
+	req.Host = "127.0.0.1"
+	req.SetBasicAuth("", "secret")
+	rec := httptest.NewRecorder()
+	h.ServeHTTP(rec, req)
+	if rec.Code != http.StatusMethodNotAllowed {
+		t.Fatalf("POST status = %d, want 405", rec.Code)
+	}
+	if rec := get(t, h, "/other", withToken("secret")); rec.Code != http.StatusNotFound {
+		t.Fatalf("/other status = %d, want 404", rec.Code)
+	}
+}
+
+func TestPageRendersPanels(t *testing.T) {
+	h := newTestServer(t,
+		Panel{Title: "Open tasks", Load: func(context.Context) (Table, error) {
+			return Table{Columns: []string{"ID", "Task"}, Rows: [][]string{{"1", "<b>eggs</b>"}}}, nil
+		}},
+		Panel{Title: "Connectors", Empty: "No connectors configured.", Load: func(context.Context) (Table, error) {
+			return Table{}, nil
+		}},
+		Panel{Title: "Queues", Load: func(context.Context) (Table, error) {
+			return Table{}, errors.New("state store locked")
+		}},
+		Panel{Title: "Broken", Load: func(context.Context) (Table, error) {
+			panic("boom")
+		}},
+	).Handler()
+
+	rec := get(t, h, "/", withToken("secret"))
+	if rec.Code != http.StatusOK {
+		t.Fatalf("status = %d", rec.Code)
+	}
+	body := rec.Body.String()
+	for _, want := range []string{
+		`content="10"`,
+		"Updated 2026-03-01 09:30:00",
+		"<td>&lt;b&gt;eggs&lt;/b&gt;</td>",
+		"No connectors configured.",
+		"Unavailable: state store locked",
+		"Unavailable: panic: boom",
+	} {
+		if !strings.Contains(body, want) {
+			t.Errorf("page lacks %q:\n%s", want, body)
+		}
+	}
+	if csp := rec.Header().Get("Content-Security-Policy"); !strings.Contains(csp, "default-src 'none'") {
+		t.Errorf("Content-Security-Policy = %q", csp)
+	}
+}
+
+func TestStatusJSON(t *testing.T) {
+	h := newTestServer(t, Panel{Title: "Open tasks", Load: func(context.Context) (Table, error) {
+		return Table{Columns: []string{"ID", "Task"}, Rows: [][]string{{"1", "eggs"}}}, nil
+	}}).Handler()
+
+	rec := get(t, h, "/status.json", withToken("secret"))
+	if rec.Code != http.StatusOK {
+		t.Fatalf("status = %d", rec.Code)
+	}
+	var got struct {
+		Generated time.Time `json:"generated"`
+		Panels    []struct {
+			Title string     `json:"title"`
+			Rows  [][]string `json:"rows"`
+		} `json:"panels"`
+	}
+	if err := json.Unmarshal(rec.Body.Bytes(), &got); err != nil {
+		t.Fatalf("decode: %v", err)
+	}
+	if len(got.Panels) != 1 || got.Panels[0].Title != "Open tasks" || got.Panels[0].Rows[0][1] != "eggs" {
+		t.Fatalf("status = %+v", got)
+	}
+}
+
+func TestNewServerRejects(t *testing.T) {
+	if _, err := NewServer(nil, "", nil); err == nil {
+		t.Error("empty token accepted")
+	}
+	if _, err := NewServer(&Config{Addr: "0.0.0.0:8787"}, "secret", nil); err == nil {
+		t.Error("non-loopback addr accepted")
+	}
+}
+
+func TestStartServes(t *testing.T) {
+	s, err := NewServer(&Config{Addr: "127.0.0.1:0", RefreshSec: 10}, "secret", nil)
+	if err != nil {
+		t.Fatalf("new server: %v", err)
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	if err := s.Start(ctx); err != nil {
+		t.Fatalf("start: %v", err)
+	}
+	defer s.Shutdown()
+
+	req, _ := http.NewRequest(http.MethodGet, "http://"+s.Addr()+"/status.json", nil)
+	req.Header.Set("Authorization", "Bearer secret")
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatalf("get: %v", err)
+	}
+	defer resp.Body.Close()
+	io.Copy(io.Discard, resp.Body)
+	if resp.StatusCode != http.StatusOK {
+		t.Fatalf("status = %d", resp.StatusCode)
+	}
+}
+
+func TestLoadConfig(t *testing.T) {
+	dir := t.TempDir()
+	cfg, err := LoadConfig(filepath.Join(dir, "missing.json"))
+	if err != nil || cfg != nil {
+		t.Fatalf("missing = %v, %v", cfg, err)
+	}
+
+	path := filepath.Join(dir, "dashboard.json")
+	tests := []struct {
+		body    string
+		want    Config
+		wantErr bool
+	}{
+		{body: `{}`, want: Config{Addr: DefaultAddr, RefreshSec: DefaultRefreshSec}},
+		{body: `{"addr": "[::1]:9000", "refresh_sec": 30}`, want: Config{Addr: "[::1]:9000", RefreshSec: 30}},
+		{body: `{"addr": "localhost:9000"}`, want: Config{Addr: "localhost:9000", RefreshSec: DefaultRefreshSec}},
+		{body: `{"addr": ":8787"}`, wantErr: true},
+		{body: `{"addr": "192.168.1.5:8787"}`, wantErr: true},
+		{body: `{"refresh_sec": -1}`, wantErr: true},
+	}
+	for _, tt := range tests {
+		if err := os.WriteFile(path, []byte(tt.body), 0o600); err != nil {
+			t.Fatal(err)
+		}
+		cfg, err := LoadConfig(path)
+		if tt.wantErr {
+			if err == nil {
+				t.Errorf("%s: no error", tt.body)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("%s: %v", tt.body, err)
+			continue
+		}
+		if *cfg != tt.want {
+			t.Errorf("%s: config = %+v, want %+v", tt.body, *cfg, tt.want)
+		}
+	}
+}
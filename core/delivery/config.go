@@ -0,0 +1,82 @@
+package delivery
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"time"
+)
+
+// Defaults for unset fields.
+const (
+	DefaultTimeoutSec         = 5
+	DefaultRetryBackoffMs     = 500
+	DefaultBreakerFailures    = 5
+	DefaultBreakerCooldownSec = 60
+)
+
+// Config sets the middleware wrapped around every notifier. Each attempt
+// is bounded by TimeoutSec; a failed send is retried Retries times,
+// waiting RetryBackoffMs and doubling. After BreakerFailures failed sends
+// in a row the notifier fails fast for BreakerCooldownSec, and Fallback,
+// a registered notifier's name, is told.
+type Config struct {
+	TimeoutSec         int    `json:"timeout_sec"`
+	Retries            int    `json:"retries"`
+	RetryBackoffMs     int    `json:"retry_backoff_ms"`
+	BreakerFailures    int    `json:"breaker_failures"`
+	BreakerCooldownSec int    `json:"breaker_cooldown_sec"`
+	Fallback           string `json:"fallback"`
+}
+
+// Default returns the configuration used without a config file.
+func Default() *Config {
+	cfg := &Config{}
+	applyDefaults(cfg)
+	return cfg
+}
+
+// LoadConfig reads and validates a delivery config file.
+// Returns nil, nil if the file does not exist.
+func LoadConfig(path string) (*Config, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("read delivery config: %w", err)
+	}
+
+	var cfg Config
+	if err := json.Unmarshal(data, &cfg); err != nil {
+		return nil, fmt.Errorf("parse delivery config: %w", err)
+	}
+	if cfg.TimeoutSec < 0 || cfg.Retries < 0 || cfg.RetryBackoffMs < 0 || cfg.BreakerFailures < 0 || cfg.BreakerCooldownSec < 0 {
+		return nil, fmt.Errorf("delivery settings must not be negative")
+	}
+	if cfg.Retries > 5 {
+		return nil, fmt.Errorf("delivery retries must be at most 5, got %d", cfg.Retries)
+	}
+
+	applyDefaults(&cfg)
+	return &cfg, nil
+}
+
+func applyDefaults(cfg *Config) {
+	if cfg.TimeoutSec == 0 {
+		cfg.TimeoutSec = DefaultTimeoutSec
+	}
+	if cfg.RetryBackoffMs == 0 {
+		cfg.RetryBackoffMs = DefaultRetryBackoffMs
+	}
+	if cfg.BreakerFailures == 0 {
+		cfg.BreakerFailures = DefaultBreakerFailures
+	}
+	if cfg.BreakerCooldownSec == 0 {
+		cfg.BreakerCooldownSec = DefaultBreakerCooldownSec
+	}
+}
+
+func (c *Config) timeout() time.Duration  { return time.Duration(c.TimeoutSec) * time.Second }
+func (c *Config) backoff() time.Duration  { return time.Duration(c.RetryBackoffMs) * time.Millisecond }
+func (c *Config) cooldown() time.Duration { return time.Duration(c.BreakerCooldownSec) * time.Second }
@@ -0,0 +1,170 @@
+// Package delivery provides notifier middleware: a per-send timeout,
+// bounded retries with backoff, and a circuit breaker that fails fast
+// while a channel is down and reports it through a fallback notifier.
+package delivery
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"sync"
+	"time"
+
+	"github.com/jdelaire/openslack/core"
+)
+
+// ErrCircuitOpen is returned while a notifier's breaker is failing fast.
+var ErrCircuitOpen = errors.New("notifier circuit open")
+
+// Middleware returns the middleware cfg describes, outermost first, for
+// core.Registry.Use. The fallback notifier is looked up in reg when the
+// breaker opens.
+func (c *Config) Middleware(reg *core.Registry, logger *slog.Logger) []core.Middleware {
+	if logger == nil {
+		logger = slog.Default()
+	}
+	fallback := func() core.Notifier {
+		if c.Fallback == "" {
+			return nil
+		}
+		n, err := reg.Get(c.Fallback)
+		if err != nil {
+			logger.Error("delivery fallback unavailable", "error", err)
+			return nil
+		}
+		return n
+	}
+	return []core.Middleware{
+		Breaker(c.BreakerFailures, c.cooldown(), fallback, logger),
+		Retry(c.Retries, c.backoff()),
+		Timeout(c.timeout()),
+	}
+}
+
+// notifier is a middleware layer: it keeps the wrapped notifier's name
+// and replaces Send.
+type notifier struct {
+	next core.Notifier
+	send func(ctx context.Context, n core.Notification) error
+}
+
+func (m *notifier) Name() string                                        { return m.next.Name() }
+func (m *notifier) Send(ctx context.Context, n core.Notification) error { return m.send(ctx, n) }
+func (m *notifier) Unwrap() core.Notifier                               { return m.next }
+
+// Timeout bounds each send to d.
+func Timeout(d time.Duration) core.Middleware {
+	return func(next core.Notifier) core.Notifier {
+		return &notifier{next: next, send: func(ctx context.Context, n core.Notification) error {
+			ctx, cancel := context.WithTimeout(ctx, d)
+			defer cancel()
+			return next.Send(ctx, n)
+		}}
+	}
+}
+
+// Retry retries a failed send up to retries times, waiting backoff before
+// the first retry and doubling it each time. It stops early when ctx ends.
+// The attempts it made are reported to a Log's middleware.
+func Retry(retries int, backoff time.Duration) core.Middleware {
+	return func(next core.Notifier) core.Notifier {
+		return &notifier{next: next, send: func(ctx context.Context, n core.Notification) error {
+			wait := backoff
+			attempts := 1
+			if c, ok := ctx.Value(attemptsKey{}).(*int); ok {
+				defer func() { *c = attempts }()
+			}
+			err := next.Send(ctx, n)
+			for i := 0; i < retries && err != nil; i++ {
+				select {
+				case <-time.After(wait):
+				case <-ctx.Done():
+					return err
+				}
+				wait *= 2
+				attempts++
+				err = next.Send(ctx, n)
+			}
+			return err
+		}}
+	}
+}
+
+// Breaker fails sends fast with ErrCircuitOpen for cooldown once failures
+// sends in a row have failed, then lets one send through to probe. The
+// notifier fallback returns, if any, is told when the breaker opens and
+// when the channel recovers.
+func Breaker(failures int, cooldown time.Duration, fallback func() core.Notifier, logger *slog.Logger) core.Middleware {
+	return func(next core.Notifier) core.Notifier {
+		b := &breaker{next: next, failures: failures, cooldown: cooldown, fallback: fallback, logger: logger, now: time.Now}
+		return &notifier{next: next, send: b.send}
+	}
+}
+
+type breaker struct {
+	next     core.Notifier
+	failures int
+	cooldown time.Duration
+	fallback func() core.Notifier
+	logger   *slog.Logger
+	now      func() time.Time
+
+	mu        sync.Mutex
+	failed    int
+	openUntil time.Time
+	open      bool
+}
+
+func (b *breaker) send(ctx context.Context, n core.Notification) error {
+	b.mu.Lock()
+	if b.open && b.now().Before(b.openUntil) {
+		b.mu.Unlock()
+		return fmt.Errorf("%w: %s", ErrCircuitOpen, b.next.Name())
+	}
+	b.mu.Unlock()
+
+	err := b.next.Send(ctx, n)
+
+	b.mu.Lock()
+	if err == nil {
+		recovered := b.open
+		b.failed, b.open = 0, false
+		b.mu.Unlock()
+		if recovered {
+			b.logger.Info("notifier recovered", "notifier", b.next.Name())
+			b.alert(fmt.Sprintf("Notifier %s recovered.", b.next.Name()))
+		}
+		return nil
+	}
+	b.failed++
+	opened := b.failed >= b.failures
+	wasOpen := b.open
+	if opened {
+		b.open = true
+		b.openUntil = b.now().Add(b.cooldown)
+	}
+	b.mu.Unlock()
+
+	if opened && !wasOpen {
+		b.logger.Warn("notifier failing, circuit open", "notifier", b.next.Name(), "error", err, "cooldown", b.cooldown)
+		b.alert(fmt.Sprintf("Notifier %s is failing (%s); failing fast for %s.", b.next.Name(), err, b.cooldown))
+	}
+	return err
+}
+
+// alert tells the fallback notifier, unless it is the failing one.
+func (b *breaker) alert(text string) {
+	if b.fallback == nil {
+		return
+	}
+	fb := b.fallback()
+	if fb == nil || fb.Name() == b.next.Name() {
+		return
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	if err := fb.Send(ctx, core.Notification{Text: text, Source: "delivery", CreatedAt: time.Now()}); err != nil {
+		b.logger.Error("delivery fallback failed", "notifier", fb.Name(), "error", err)
+	}
+}
@@ -0,0 +1,170 @@
+package delivery
+
+import (
+	"context"
+	"errors"
+	"io"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/jdelaire/openslack/core"
+)
+
+func testLogger() *slog.Logger {
+	return slog.New(slog.NewTextHandler(io.Discard, nil))
+}
+
+// fakeNotifier fails its first failFirst sends, or blocks until ctx ends
+// when block is set.
+type fakeNotifier struct {
+	name      string
+	failFirst int
+	block     bool
+
+	mu    sync.Mutex
+	calls int
+	texts []string
+}
+
+func (f *fakeNotifier) Name() string { return f.name }
+
+func (f *fakeNotifier) Send(ctx context.Context, n core.Notification) error {
+	f.mu.Lock()
+	f.calls++
+	call := f.calls
+	f.mu.Unlock()
+	if f.block {
+		<-ctx.Done()
+		return ctx.Err()
+	}
+	if call <= f.failFirst {
+		return errors.New("api status: 502")
+	}
+	f.mu.Lock()
+	f.texts = append(f.texts, n.Text)
+	f.mu.Unlock()
+	return nil
+}
+
+func TestTimeout(t *testing.T) {
+	n := Timeout(50 * time.Millisecond)(&fakeNotifier{name: "slow", block: true})
+	start := time.Now()
+	err := n.Send(context.Background(), core.Notification{Text: "hi"})
+	if !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("err = %v, want deadline exceeded", err)
+	}
+	if took := time.Since(start); took > time.Second {
+		t.Errorf("send took %s, want about 50ms", took)
+	}
+}
+
+func TestRetry(t *testing.T) {
+	f := &fakeNotifier{name: "flaky", failFirst: 2}
+	n := Retry(2, time.Millisecond)(f)
+	if err := n.Send(context.Background(), core.Notification{Text: "hi"}); err != nil {
+		t.Fatalf("Send: %v", err)
+	}
+	if f.calls != 3 {
+		t.Errorf("calls = %d, want 3", f.calls)
+	}
+
+	f = &fakeNotifier{name: "down", failFirst: 100}
+	n = Retry(2, time.Millisecond)(f)
+	if err := n.Send(context.Background(), core.Notification{Text: "hi"}); err == nil {
+		t.Error("Send succeeded although every attempt failed")
+	}
+	if f.calls != 3 {
+		t.Errorf("calls = %d, want 3 (1 + 2 retries)", f.calls)
+	}
+}
+
+func TestBreaker(t *testing.T) {
+	down := &fakeNotifier{name: "telegram", failFirst: 3}
+	fallback := &fakeNotifier{name: "email"}
+	now := time.Now()
+	br := &breaker{
+		next:     down,
+		failures: 2,
+		cooldown: time.Minute,
+		fallback: func() core.Notifier { return fallback },
+		logger:   testLogger(),
+		now:      func() time.Time { return now },
+	}
+
+	ctx := context.Background()
+	br.send(ctx, core.Notification{Text: "1"})
+	br.send(ctx, core.Notification{Text: "2"})
+	if len(fallback.texts) != 1 || !strings.Contains(fallback.texts[0], "Notifier telegram is failing") {
+		t.Fatalf("fallback got %q, want one failing alert", fallback.texts)
+	}
+
+	if err := br.send(ctx, core.Notification{Text: "3"}); !errors.Is(err, ErrCircuitOpen) {
+		t.Errorf("err while open = %v, want ErrCircuitOpen", err)
+	}
+	if down.calls != 2 {
+		t.Errorf("calls while open = %d, want 2", down.calls)
+	}
+
+	// After the cooldown one probe goes through; it fails and reopens
+	// without a second alert.
+	now = now.Add(time.Minute)
+	if err := br.send(ctx, core.Notification{Text: "4"}); err == nil || errors.Is(err, ErrCircuitOpen) {
+		t.Errorf("probe err = %v, want the send's own error", err)
+	}
+	if len(fallback.texts) != 1 {
+		t.Errorf("fallback got %d alerts, want 1", len(fallback.texts))
+	}
+
+	now = now.Add(time.Minute)
+	if err := br.send(ctx, core.Notification{Text: "5"}); err != nil {
+		t.Errorf("send after recovery: %v", err)
+	}
+	if len(fallback.texts) != 2 || fallback.texts[1] != "Notifier telegram recovered." {
+		t.Errorf("fallback got %q, want a recovery notice", fallback.texts)
+	}
+}
+
+func TestMiddlewareKeepsName(t *testing.T) {
+	reg := core.NewRegistry()
+	inner := &fakeNotifier{name: "telegram"}
+	reg.Register(inner)
+	reg.Use(Default().Middleware(reg, testLogger())...)
+
+	n, _ := reg.Get("telegram")
+	if n.Name() != "telegram" {
+		t.Errorf("Name() = %q", n.Name())
+	}
+	if core.Unwrap(n) != inner {
+		t.Error("Unwrap did not reach the registered notifier")
+	}
+}
+
+func TestLoadConfig(t *testing.T) {
+	dir := t.TempDir()
+	cfg, err := LoadConfig(filepath.Join(dir, "missing.json"))
+	if cfg != nil || err != nil {
+		t.Fatalf("missing file: got %v, %v", cfg, err)
+	}
+
+	path := filepath.Join(dir, "delivery.json")
+	os.WriteFile(path, []byte(`{"retries": 2, "fallback": "email"}`), 0600)
+	cfg, err = LoadConfig(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if cfg.TimeoutSec != DefaultTimeoutSec || cfg.Retries != 2 || cfg.BreakerFailures != DefaultBreakerFailures || cfg.Fallback != "email" {
+		t.Errorf("cfg = %+v", cfg)
+	}
+
+	for _, bad := range []string{`{`, `{"timeout_sec": -1}`, `{"retries": 9}`} {
+		os.WriteFile(path, []byte(bad), 0600)
+		if _, err := LoadConfig(path); err == nil {
+			t.Errorf("LoadConfig(%s) succeeded", bad)
+		}
+	}
+}
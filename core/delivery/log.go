@@ -0,0 +1,241 @@
+package delivery
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"log/slog"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/jdelaire/openslack/core"
+	"github.com/jdelaire/openslack/internal/state"
+)
+
+const (
+	logNamespace = "deliveries"
+
+	// MaxRecords is how many deliveries the log keeps; older ones are
+	// pruned as new ones are recorded.
+	MaxRecords = 200
+)
+
+// Status is the outcome of a delivery.
+type Status string
+
+const (
+	StatusSent   Status = "sent"
+	StatusFailed Status = "failed"
+)
+
+// Record is one outbound notification and what became of it. Attempts
+// counts sends tried, retries and resends included; it is 0 when the
+// breaker failed the delivery fast.
+type Record struct {
+	ID           string            `json:"id"`
+	Notifier     string            `json:"notifier"`
+	Status       Status            `json:"status"`
+	Attempts     int               `json:"attempts"`
+	Error        string            `json:"error,omitempty"`
+	Created      time.Time         `json:"created"`
+	Updated      time.Time         `json:"updated"`
+	Notification core.Notification `json:"notification"`
+}
+
+// Log persists outbound deliveries in the shared state store.
+type Log struct {
+	store  *state.Store
+	logger *slog.Logger
+	now    func() time.Time
+
+	mu sync.Mutex
+}
+
+// NewLog returns a delivery log kept in store.
+func NewLog(store *state.Store, logger *slog.Logger) *Log {
+	if logger == nil {
+		logger = slog.Default()
+	}
+	return &Log{store: store, logger: logger, now: time.Now}
+}
+
+// WithClock replaces time.Now for record timestamps. A nil now is ignored.
+func (l *Log) WithClock(now func() time.Time) *Log {
+	if now != nil {
+		l.now = now
+	}
+	return l
+}
+
+// Middleware logs every send through the notifiers it wraps. Put it
+// outermost, so the log sees the outcome after retries and the breaker.
+func (l *Log) Middleware() core.Middleware {
+	return func(next core.Notifier) core.Notifier {
+		return &notifier{next: next, send: func(ctx context.Context, n core.Notification) error {
+			attempts := 0
+			err := next.Send(context.WithValue(ctx, attemptsKey{}, &attempts), n)
+			if !errors.Is(err, ErrCircuitOpen) {
+				// Without Retry, nothing counts the one attempt made.
+				attempts = max(attempts, 1)
+			}
+			id, _ := ctx.Value(resendKey{}).(string)
+			l.record(id, next.Name(), n, attempts, err)
+			return err
+		}}
+	}
+}
+
+// attemptsKey carries a counter that Retry sets to the attempts it made.
+type attemptsKey struct{}
+
+// resendKey carries the ID of the record a send redelivers.
+type resendKey struct{}
+
+// record adds a delivery, or updates the one id names for a resend.
+// Logging is best effort: a failure to persist never fails the send.
+func (l *Log) record(id, notifierName string, n core.Notification, attempts int, sendErr error) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	now := l.now()
+	var rec Record
+	if id != "" {
+		ok, err := l.store.Get(logNamespace, id, &rec)
+		if err != nil || !ok {
+			l.logger.Error("delivery log: resent record missing", "id", id, "error", err)
+			return
+		}
+		rec.Attempts += attempts
+	} else {
+		var err error
+		if id, err = l.newID(); err != nil {
+			l.logger.Error("delivery log: allocate id failed", "error", err)
+			return
+		}
+		rec = Record{ID: id, Notifier: notifierName, Attempts: attempts, Created: now, Notification: n}
+	}
+	rec.Updated = now
+	rec.Status, rec.Error = StatusSent, ""
+	if sendErr != nil {
+		rec.Status, rec.Error = StatusFailed, sendErr.Error()
+	}
+	if err := l.store.Put(logNamespace, id, rec); err != nil {
+		l.logger.Error("delivery log: save failed", "id", id, "error", err)
+		return
+	}
+	if err := l.prune(); err != nil {
+		l.logger.Error("delivery log: prune failed", "error", err)
+	}
+}
+
+// newID returns an unused 6-hex-digit record ID. Callers hold l.mu.
+func (l *Log) newID() (string, error) {
+	buf := make([]byte, 3)
+	for range 10 {
+		if _, err := rand.Read(buf); err != nil {
+			return "", err
+		}
+		id := hex.EncodeToString(buf)
+		ok, err := l.store.Get(logNamespace, id, &Record{})
+		if err != nil {
+			return "", err
+		}
+		if !ok {
+			return id, nil
+		}
+	}
+	return "", fmt.Errorf("could not allocate delivery id")
+}
+
+// prune drops the oldest records beyond MaxRecords. Callers hold l.mu.
+func (l *Log) prune() error {
+	recs, err := l.all()
+	if err != nil {
+		return err
+	}
+	for _, rec := range recs[min(len(recs), MaxRecords):] {
+		if _, err := l.store.Delete(logNamespace, rec.ID); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Recent returns up to n records, newest first. With failedOnly, only
+// failed deliveries are returned.
+func (l *Log) Recent(n int, failedOnly bool) ([]Record, error) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	recs, err := l.all()
+	if err != nil {
+		return nil, err
+	}
+	out := recs[:0]
+	for _, rec := range recs {
+		if failedOnly && rec.Status != StatusFailed {
+			continue
+		}
+		out = append(out, rec)
+		if len(out) == n {
+			break
+		}
+	}
+	return out, nil
+}
+
+// Get returns the record with id. It reports false if there is none.
+func (l *Log) Get(id string) (Record, bool, error) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	var rec Record
+	ok, err := l.store.Get(logNamespace, id, &rec)
+	return rec, ok, err
+}
+
+// all returns every record, newest first. Callers hold l.mu.
+func (l *Log) all() ([]Record, error) {
+	keys, err := l.store.Keys(logNamespace)
+	if err != nil {
+		return nil, err
+	}
+	recs := make([]Record, 0, len(keys))
+	for _, k := range keys {
+		var rec Record
+		if _, err := l.store.Get(logNamespace, k, &rec); err != nil {
+			return nil, err
+		}
+		recs = append(recs, rec)
+	}
+	sort.Slice(recs, func(i, j int) bool {
+		if recs[i].Created.Equal(recs[j].Created) {
+			return recs[i].ID > recs[j].ID
+		}
+		return recs[i].Created.After(recs[j].Created)
+	})
+	return recs, nil
+}
+
+// Resend redelivers the failed delivery id through reg's notifier of the
+// same name, and updates its record with the outcome.
+func (l *Log) Resend(ctx context.Context, reg *core.Registry, id string) error {
+	rec, ok, err := l.Get(id)
+	if err != nil {
+		return err
+	}
+	if !ok {
+		return fmt.Errorf("no delivery %s", id)
+	}
+	if rec.Status != StatusFailed {
+		return fmt.Errorf("delivery %s was already sent", id)
+	}
+	n, err := reg.Get(rec.Notifier)
+	if err != nil {
+		return err
+	}
+	return n.Send(context.WithValue(ctx, resendKey{}, id), rec.Notification)
+}
@@ -0,0 +1,141 @@
+package delivery
+
+import (
+	"context"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/jdelaire/openslack/core"
+	"github.com/jdelaire/openslack/internal/state"
+)
+
+func newTestLog(t *testing.T) *Log {
+	t.Helper()
+	return NewLog(state.NewStore(filepath.Join(t.TempDir(), "state.json")), testLogger())
+}
+
+func TestLogRecordsDeliveries(t *testing.T) {
+	log := newTestLog(t)
+	fake := &fakeNotifier{name: "telegram", failFirst: 3}
+	reg := core.NewRegistry()
+	reg.Use(log.Middleware(), Retry(1, time.Millisecond))
+	if err := reg.Register(fake); err != nil {
+		t.Fatal(err)
+	}
+	n, _ := reg.Get("telegram")
+
+	// Fails twice (both attempts), then fails once and succeeds on retry.
+	if err := n.Send(context.Background(), core.Notification{Text: "backup failed", ChatID: 42}); err == nil {
+		t.Fatal("first send succeeded")
+	}
+	if err := n.Send(context.Background(), core.Notification{Text: "disk ok"}); err != nil {
+		t.Fatal(err)
+	}
+
+	recs, err := log.Recent(10, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(recs) != 2 {
+		t.Fatalf("recorded %d deliveries, want 2", len(recs))
+	}
+	byText := map[string]Record{}
+	for _, r := range recs {
+		byText[r.Notification.Text] = r
+	}
+	failed, sent := byText["backup failed"], byText["disk ok"]
+	if failed.Status != StatusFailed || failed.Attempts != 2 || !strings.Contains(failed.Error, "502") || failed.Notifier != "telegram" {
+		t.Errorf("failed record = %+v", failed)
+	}
+	if sent.Status != StatusSent || sent.Attempts != 2 || sent.Error != "" {
+		t.Errorf("sent record = %+v", sent)
+	}
+
+	failedOnly, _ := log.Recent(10, true)
+	if len(failedOnly) != 1 || failedOnly[0].ID != failed.ID {
+		t.Errorf("failed only = %+v", failedOnly)
+	}
+
+	// The resend goes through and updates the same record.
+	if err := log.Resend(context.Background(), reg, failed.ID); err != nil {
+		t.Fatalf("Resend: %v", err)
+	}
+	rec, _, _ := log.Get(failed.ID)
+	if rec.Status != StatusSent || rec.Attempts != 3 || rec.Error != "" {
+		t.Errorf("resent record = %+v", rec)
+	}
+	if err := log.Resend(context.Background(), reg, failed.ID); err == nil || !strings.Contains(err.Error(), "already sent") {
+		t.Errorf("second resend: %v", err)
+	}
+	if all, _ := log.Recent(10, false); len(all) != 2 {
+		t.Errorf("resend added a record: %d", len(all))
+	}
+}
+
+func TestLogBreakerFailsFast(t *testing.T) {
+	log := newTestLog(t)
+	fake := &fakeNotifier{name: "telegram", failFirst: 10}
+	n := log.Middleware()(Breaker(1, time.Hour, nil, testLogger())(fake))
+
+	n.Send(context.Background(), core.Notification{Text: "one"})
+	n.Send(context.Background(), core.Notification{Text: "two"})
+	recs, _ := log.Recent(10, true)
+	if len(recs) != 2 {
+		t.Fatalf("recorded %d failures, want 2", len(recs))
+	}
+	if recs[0].Attempts+recs[1].Attempts != 1 {
+		t.Errorf("attempts = %d and %d, want one of them 0", recs[0].Attempts, recs[1].Attempts)
+	}
+}
+
+func TestLogPrunes(t *testing.T) {
+	log := newTestLog(t)
+	start := time.Date(2026, 3, 1, 9, 0, 0, 0, time.UTC)
+	now := start
+	log.WithClock(func() time.Time { return now })
+	n := log.Middleware()(&fakeNotifier{name: "telegram"})
+	for i := range MaxRecords + 5 {
+		now = start.Add(time.Duration(i) * time.Second)
+		n.Send(context.Background(), core.Notification{Text: "msg"})
+	}
+	recs, _ := log.Recent(MaxRecords+10, false)
+	if len(recs) != MaxRecords {
+		t.Fatalf("kept %d records, want %d", len(recs), MaxRecords)
+	}
+	if oldest := recs[len(recs)-1].Created; !oldest.Equal(start.Add(5 * time.Second)) {
+		t.Errorf("oldest kept record is from %s", oldest)
+	}
+}
+
+func TestDeliveryOps(t *testing.T) {
+	log := newTestLog(t)
+	reg := core.NewRegistry()
+	reg.Use(log.Middleware())
+	fake := &fakeNotifier{name: "telegram", failFirst: 1}
+	reg.Register(fake)
+	n, _ := reg.Get("telegram")
+	n.Send(context.Background(), core.Notification{Text: "nightly backup failed\nsee logs"})
+
+	list := &DeliveriesOp{Log: log}
+	out, err := list.Execute(context.Background(), "failed")
+	if err != nil {
+		t.Fatal(err)
+	}
+	recs, _ := log.Recent(1, true)
+	if !strings.Contains(out, recs[0].ID) || !strings.Contains(out, "nightly backup fa") || !strings.Contains(out, "/resend "+recs[0].ID) {
+		t.Errorf("/deliveries failed =\n%s", out)
+	}
+
+	resend := &ResendOp{Log: log, Registry: reg}
+	if out, err := resend.Execute(context.Background(), recs[0].ID); err != nil || out != "Resent "+recs[0].ID+"." {
+		t.Errorf("/resend = %q, %v", out, err)
+	}
+	if out, _ := list.Execute(context.Background(), "failed"); out != "No failed deliveries." {
+		t.Errorf("after resend: %q", out)
+	}
+	if out, _ := resend.Execute(context.Background(), ""); !strings.HasPrefix(out, "Usage:") {
+		t.Errorf("/resend without id = %q", out)
+	}
+}
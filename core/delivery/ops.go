@@ -0,0 +1,122 @@
+package delivery
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/jdelaire/openslack/core"
+	"github.com/jdelaire/openslack/core/ops"
+	"github.com/jdelaire/openslack/core/ops/format"
+)
+
+// recentShown is how many deliveries /deliveries lists.
+const recentShown = 15
+
+// DeliveriesOp lists recent outbound deliveries, or only the failed ones
+// with "/deliveries failed".
+type DeliveriesOp struct {
+	Log *Log
+}
+
+func (o *DeliveriesOp) Name() string        { return "deliveries" }
+func (o *DeliveriesOp) Description() string { return "List recent outbound deliveries" }
+func (o *DeliveriesOp) Risk() ops.RiskLevel { return ops.RiskNone }
+
+func (o *DeliveriesOp) Execute(_ context.Context, args string) (string, error) {
+	failedOnly := false
+	switch strings.TrimSpace(args) {
+	case "":
+	case "failed":
+		failedOnly = true
+	default:
+		return "Usage: /deliveries [failed]", nil
+	}
+
+	recs, err := o.Log.Recent(recentShown, failedOnly)
+	if err != nil {
+		return "", err
+	}
+	if len(recs) == 0 {
+		if failedOnly {
+			return "No failed deliveries.", nil
+		}
+		return "No deliveries yet.", nil
+	}
+	now := o.Log.now()
+	rows := make([][]string, len(recs))
+	for i, r := range recs {
+		rows[i] = []string{r.ID, when(r.Updated, now), target(r), status(r), oneLine(r.Notification)}
+	}
+	out := format.Table([]string{"ID", "When", "To", "Status", "Message"}, rows, 0)
+	if failed := newestFailed(recs); failed != "" {
+		out += "\n\nResend with /resend <id>, e.g. /resend " + failed
+	}
+	return out, nil
+}
+
+// when formats t as a time of day if it is on now's date, else as a date,
+// to leave the message column room.
+func when(t, now time.Time) string {
+	t, now = t.Local(), now.Local()
+	if t.YearDay() == now.YearDay() && t.Year() == now.Year() {
+		return t.Format("15:04")
+	}
+	return t.Format("01-02")
+}
+
+// status shows a delivery's outcome, with its attempts when there were
+// several, e.g. "failed×3".
+func status(r Record) string {
+	if r.Attempts > 1 {
+		return fmt.Sprintf("%s×%d", r.Status, r.Attempts)
+	}
+	return string(r.Status)
+}
+
+// target names where a delivery went: the notifier, and the chat when it
+// was not the notifier's own.
+func target(r Record) string {
+	if r.Notification.ChatID != 0 {
+		return fmt.Sprintf("%s:%d", r.Notifier, r.Notification.ChatID)
+	}
+	return r.Notifier
+}
+
+// oneLine flattens a notification's plain text to its first line.
+func oneLine(n core.Notification) string {
+	text, _, _ := strings.Cut(n.PlainText(), "\n")
+	return text
+}
+
+// newestFailed returns the ID of the newest failed delivery in recs, or "".
+func newestFailed(recs []Record) string {
+	for _, r := range recs {
+		if r.Status == StatusFailed {
+			return r.ID
+		}
+	}
+	return ""
+}
+
+// ResendOp redelivers a failed delivery: /resend <id>. It has no declared
+// risk, so it needs a TOTP code.
+type ResendOp struct {
+	Log      *Log
+	Registry *core.Registry
+}
+
+func (o *ResendOp) Name() string        { return "resend" }
+func (o *ResendOp) Description() string { return "Redeliver a failed notification" }
+
+func (o *ResendOp) Execute(ctx context.Context, args string) (string, error) {
+	id := strings.TrimSpace(args)
+	if id == "" || strings.ContainsAny(id, " \t") {
+		return "Usage: /resend <id>", nil
+	}
+	if err := o.Log.Resend(ctx, o.Registry, id); err != nil {
+		return "", err
+	}
+	return fmt.Sprintf("Resent %s.", id), nil
+}
@@ -2,25 +2,62 @@ package core
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"log/slog"
+	"maps"
+	"sort"
+	"strconv"
 	"strings"
+	"sync"
 	"time"
 
+	"github.com/jdelaire/openslack/core/admission"
+	"github.com/jdelaire/openslack/core/auth"
+	"github.com/jdelaire/openslack/core/limits"
 	"github.com/jdelaire/openslack/core/ops"
 	"github.com/jdelaire/openslack/core/policy"
+	"github.com/jdelaire/openslack/core/redact"
+	"github.com/jdelaire/openslack/core/style"
+	"github.com/jdelaire/openslack/core/summary"
+	"github.com/jdelaire/openslack/internal/chatvars"
+	"github.com/jdelaire/openslack/internal/events"
+	"github.com/jdelaire/openslack/internal/transcript"
 )
 
 const (
 	maxConcurrentOps = 2
 	opTimeout        = 30 * time.Second
+	confirmTimeout   = 30 * time.Second
+	sudoWindow       = 15 * time.Minute
+	typingDelay      = 2 * time.Second // before an op shows "typing…"
+	typingRefresh    = 4 * time.Second // Telegram clears it after 5s
+	skewTimeout      = 3 * time.Second
 )
 
+// Authorizer decides whether an inbound message may be processed.
+// *policy.Policy implements it.
+type Authorizer interface {
+	Authorize(chatID int64, updateID int64, timestamp time.Time) error
+}
+
 // TOTPVerifier verifies time-based one-time passwords.
 type TOTPVerifier interface {
 	Verify(code string) bool
 }
 
+// totpParamser is implemented by verifiers that may use other than the
+// default code length and drift, such as *auth.TOTP.
+type totpParamser interface {
+	Params() auth.Params
+}
+
+// ClockChecker measures how far the local clock is off, so TOTP failures
+// caused by skew can say so. *auth.SkewChecker implements it.
+type ClockChecker interface {
+	Skew(ctx context.Context) (time.Duration, error)
+}
+
 // RateLimiter tracks authentication failures and enforces lockouts.
 type RateLimiter interface {
 	Check(chatID int64) error
@@ -32,11 +69,67 @@ type RateLimiter interface {
 type ApprovalStore interface {
 	Create(chatID int64, opName, args string) (nonce string, err error)
 	Consume(nonce string, chatID int64) (opName, args string, err error)
+	Cancel(nonce string, chatID int64) (opName string, err error)
+}
+
+// VarStore holds per-chat variables referenced as {name} in commands.
+type VarStore interface {
+	All(chatID int64) (map[string]string, error)
+}
+
+// ResultStore keeps op results for /last and /diff.
+type ResultStore interface {
+	Record(chatID int64, op, output string, at time.Time) error
+}
+
+// Transcript keeps what each chat sent and what the bot replied, for
+// /transcript. *transcript.Log implements it.
+type Transcript interface {
+	Record(chatID int64, from, text string, at time.Time) error
+}
+
+// Summarizer writes the short summary sent for output under the summarize
+// overflow policy. *summary.LLM implements it.
+type Summarizer interface {
+	Summarize(ctx context.Context, op, text string) (string, error)
+}
+
+// AuditLog records op runs and notification deliveries. *audit.Log
+// implements it.
+type AuditLog interface {
+	RecordOp(chatID int64, op string, took time.Duration, err error)
+	RecordNotification(source string, err error)
+}
+
+// MultiAudit records to each of logs, e.g. the SQLite audit log and an
+// exporter.
+func MultiAudit(logs ...AuditLog) AuditLog {
+	return multiAudit(logs)
+}
+
+type multiAudit []AuditLog
+
+func (m multiAudit) RecordOp(chatID int64, op string, took time.Duration, err error) {
+	for _, l := range m {
+		l.RecordOp(chatID, op, took, err)
+	}
+}
+
+func (m multiAudit) RecordNotification(source string, err error) {
+	for _, l := range m {
+		l.RecordNotification(source, err)
+	}
+}
+
+// Claimer lets a chat that is not on the allowlist add itself with a
+// one-time code. *ops.SetupOp implements it.
+type Claimer interface {
+	Claim(chatID int64, code string) (string, error)
 }
 
 // Dispatcher authorizes inbound messages and dispatches commands to ops.
 type Dispatcher struct {
-	policy    *policy.Policy
+	policy    Authorizer
 	ops       *ops.Registry
 	notifier  Notifier
 	logger    *slog.Logger
@@ -44,17 +137,105 @@ type Dispatcher struct {
 	totp      TOTPVerifier
 	limiter   RateLimiter
 	approvals ApprovalStore
+	vars      VarStore
+	results   ResultStore
+	history   Transcript
+	style     *style.Theme
+	claimer   Claimer
+	clock     ClockChecker
+	audit     AuditLog
+	bot       *ops.Bot
+	limits    *limits.Config
+	summary   Summarizer
+	reactions *Reactions
+	redact    *redact.Redactor
+	admission *admission.Controller
+	events    *events.Bus
+	plainOp   string
+	now       func() time.Time
+
+	opTimeout      time.Duration // per-op deadline
+	confirmTimeout time.Duration // how long /yes is accepted
+	sudoWindow     time.Duration // how long /sudo pre-authorizes low-risk ops
+	typingDelay    time.Duration
+	typingRefresh  time.Duration
+	queueDepth     int // runs that may wait for a slot; 0 refuses them
+
+	mu       sync.Mutex
+	confirms map[int64]pendingConfirm
+	more     map[int64]string    // chat -> full text of its last summarized reply
+	sudo     map[int64]time.Time // chat -> end of its /sudo window
+	running  map[int]*runningOp  // by job ID
+	lastJob  int
+	queued   int        // runs waiting for a slot
+	tickets  int        // queue tickets handed out
+	serving  int        // the ticket whose turn it is
+	turn     *sync.Cond // signalled when serving moves on
+}
+
+// runningOp is an op in flight, which /cancel can stop.
+type runningOp struct {
+	id        int
+	cmd       string
+	chatID    int64
+	messageID int64 // the command message
+	started   time.Time
+	cancel    context.CancelFunc
+	cancelled bool
+}
+
+// pendingConfirm is a resolved call waiting for /yes.
+type pendingConfirm struct {
+	cmd     string
+	args    string
+	msg     InboundMessage
+	vars    map[string]string
+	hasVars bool
+	expires time.Time
 }
 
 // NewDispatcher creates a Dispatcher.
-func NewDispatcher(pol *policy.Policy, opsReg *ops.Registry, notifier Notifier, logger *slog.Logger) *Dispatcher {
-	return &Dispatcher{
+func NewDispatcher(pol Authorizer, opsReg *ops.Registry, notifier Notifier, logger *slog.Logger) *Dispatcher {
+	d := &Dispatcher{
 		policy:   pol,
 		ops:      opsReg,
 		notifier: notifier,
 		logger:   logger,
 		sem:      make(chan struct{}, maxConcurrentOps),
+		now:      time.Now,
+		confirms: map[int64]pendingConfirm{},
+		more:     map[int64]string{},
+		sudo:     map[int64]time.Time{},
+		running:  map[int]*runningOp{},
+
+		opTimeout:      opTimeout,
+		confirmTimeout: confirmTimeout,
+		sudoWindow:     sudoWindow,
+		typingDelay:    typingDelay,
+		typingRefresh:  typingRefresh,
+	}
+	d.turn = sync.NewCond(&d.mu)
+	return d
+}
+
+// claim handles "/setup <code>" from a chat the policy rejected. It replies
+// only when the claim succeeds, so the bot stays silent to strangers.
+func (d *Dispatcher) claim(msg InboundMessage) bool {
+	if d.claimer == nil {
+		return false
+	}
+	fields := strings.Fields(msg.Text)
+	if len(fields) != 2 || fields[0] != "/setup" {
+		return false
+	}
+	reply, err := d.claimer.Claim(msg.ChatID, fields[1])
+	if err != nil {
+		d.logger.Warn("setup claim failed", "chat_id", msg.ChatID, "error", err)
+		return false
 	}
+	d.logger.Info("chat claimed with setup code", "chat_id", msg.ChatID)
+	d.respond(msg, style.Success, reply)
+	return true
 }
 
 // WithSecurity attaches Phase 3 security components. Nil values disable
@@ -66,24 +247,185 @@ func (d *Dispatcher) WithSecurity(totp TOTPVerifier, limiter RateLimiter, approv
 	return d
 }
 
+// WithVars enables chat variables: {name} references in args and shell-op
+// commands are resolved at execution time.
+func (d *Dispatcher) WithVars(vars VarStore) *Dispatcher {
+	d.vars = vars
+	return d
+}
+
+// WithResults records each successful op result per chat.
+func (d *Dispatcher) WithResults(results ResultStore) *Dispatcher {
+	d.results = results
+	return d
+}
+
+// WithTranscript records each message from an authorized chat, with any
+// trailing TOTP code and the redactor's secrets masked, and every reply
+// to it.
+func (d *Dispatcher) WithTranscript(t Transcript) *Dispatcher {
+	d.history = t
+	return d
+}
+
+// WithStyle decorates replies with theme's severity prefixes. Without it
+// replies are sent as the ops wrote them.
+func (d *Dispatcher) WithStyle(theme *style.Theme) *Dispatcher {
+	d.style = theme
+	return d
+}
+
+// WithLimits sizes replies to cfg's message length and lays out longer
+// ones under its overflow policies. Without it, replies over 4096 bytes
+// keep their head and tail.
+func (d *Dispatcher) WithLimits(cfg *limits.Config) *Dispatcher {
+	d.limits = cfg
+	return d
+}
+
+// WithSummarizer has s write the summaries of replies under the
+// summarize overflow policy, from output with the redactor's secrets
+// masked. Without it, or when it fails, the rule-based summary is sent.
+func (d *Dispatcher) WithSummarizer(s Summarizer) *Dispatcher {
+	d.summary = s
+	return d
+}
+
+// WithReactions reacts to each command message as its op runs, when the
+// notifier is a Reactor. Replies still follow unless the op is quiet.
+func (d *Dispatcher) WithReactions(r *Reactions) *Dispatcher {
+	d.reactions = r
+	return d
+}
+
+// WithRedactor sets the patterns used to mask secrets in logged args and
+// errors, and in the /yes preview. A nil r uses the defaults.
+func (d *Dispatcher) WithRedactor(r *redact.Redactor) *Dispatcher {
+	d.redact = r
+	return d
+}
+
+// WithAdmission reports each command, queued or running, and how long it
+// took to c, so c can defer background work while the daemon is busy.
+// Chat commands count as interactive and Exec calls as background.
+func (d *Dispatcher) WithAdmission(c *admission.Controller) *Dispatcher {
+	d.admission = c
+	return d
+}
+
+// WithClockCheck makes TOTP failures report clock skew beyond what codes
+// tolerate, instead of a bare "Invalid TOTP code.".
+func (d *Dispatcher) WithClockCheck(c ClockChecker) *Dispatcher {
+	d.clock = c
+	return d
+}
+
+// WithClock replaces time.Now for confirmation expiry, run durations and
+// reply timestamps. A nil now is ignored.
+func (d *Dispatcher) WithClock(now func() time.Time) *Dispatcher {
+	if now != nil {
+		d.now = now
+	}
+	return d
+}
+
+// WithTimeouts overrides how long an op may run and how long a /yes
+// confirmation is accepted. Zero keeps the 30-second defaults.
+func (d *Dispatcher) WithTimeouts(op, confirm time.Duration) *Dispatcher {
+	if op > 0 {
+		d.opTimeout = op
+	}
+	if confirm > 0 {
+		d.confirmTimeout = confirm
+	}
+	return d
+}
+
+// WithSudoWindow overrides how long "/sudo <totp>" lets a chat run
+// low-risk ops without a code. Zero keeps the 15-minute default.
+func (d *Dispatcher) WithSudoWindow(window time.Duration) *Dispatcher {
+	if window > 0 {
+		d.sudoWindow = window
+	}
+	return d
+}
+
+// WithQueue lets up to depth commands wait for a free slot when the
+// concurrency limit is reached, instead of being refused as busy. Each
+// is told how many are queued ahead of it. Zero, the default, refuses.
+func (d *Dispatcher) WithQueue(depth int) *Dispatcher {
+	d.queueDepth = max(depth, 0)
+	return d
+}
+
+// WithAudit records every op run, with its duration and outcome, in log.
+func (d *Dispatcher) WithAudit(log AuditLog) *Dispatcher {
+	d.audit = log
+	return d
+}
+
+// WithEvents publishes an events.CommandCompleted event to bus after
+// every op run, and an events.MessageReceived event for every message
+// that is not a command.
+func (d *Dispatcher) WithEvents(bus *events.Bus) *Dispatcher {
+	d.events = bus
+	return d
+}
+
+// WithPlainMessages runs op for chat messages that are not commands, with
+// the message as its args, as if sent as "/op <text>". Such messages are
+// still published as message.received.
+func (d *Dispatcher) WithPlainMessages(op string) *Dispatcher {
+	d.plainOp = op
+	return d
+}
+
+// WithBot names the bot this dispatcher serves, when one daemon runs
+// several, and limits it to b.Ops. Ops see the bot through ops.BotFrom.
+func (d *Dispatcher) WithBot(b ops.Bot) *Dispatcher {
+	d.bot = &b
+	return d
+}
+
+// WithClaimer lets unauthorized chats send "/setup <code>" to add
+// themselves to the allowlist. Other messages from them are still dropped.
+func (d *Dispatcher) WithClaimer(c Claimer) *Dispatcher {
+	d.claimer = c
+	return d
+}
+
 // Handle processes an inbound message: authorize, parse, execute, respond.
 func (d *Dispatcher) Handle(msg InboundMessage) {
 	if err := d.policy.Authorize(msg.ChatID, msg.UpdateID, msg.Timestamp); err != nil {
+		if errors.Is(err, policy.ErrUnauthorizedChat) && d.claim(msg) {
+			return
+		}
 		d.logger.Debug("message rejected by policy", "chat_id", msg.ChatID, "error", err)
 		return
 	}
+	d.record(msg.ChatID, transcript.FromChat, d.maskTOTP(msg.Text))
 
 	// Rate limit check.
 	if d.limiter != nil {
 		if err := d.limiter.Check(msg.ChatID); err != nil {
-			d.respond(msg.ChatID, fmt.Sprintf("Locked out: %s", err))
+			d.respond(msg, style.Warn, fmt.Sprintf("Locked out: %s", err))
 			return
 		}
 	}
 
 	cmd, args := parseCommand(msg.Text)
 	if cmd == "" {
-		return
+		text := strings.TrimSpace(msg.Text)
+		if d.events != nil && text != "" {
+			d.events.Publish(events.MessageReceived, map[string]string{
+				"chat_id": strconv.FormatInt(msg.ChatID, 10),
+				"text":    msg.Text,
+			})
+		}
+		if d.plainOp == "" || text == "" {
+			return
+		}
+		cmd, args = d.plainOp, text
 	}
 
 	// Built-in two-step commands.
@@ -97,16 +439,48 @@ func (d *Dispatcher) Handle(msg InboundMessage) {
 		d.handleApprove(msg, args)
 		return
 	}
+	if cmd == "reject" && d.approvals != nil && d.totp != nil {
+		d.logger.Info("command received", "cmd", cmd, "chat_id", msg.ChatID)
+		d.handleReject(msg, args)
+		return
+	}
+
+	if cmd == "sudo" && d.totp != nil {
+		d.logger.Info("command received", "cmd", cmd, "chat_id", msg.ChatID)
+		d.handleSudo(msg, args)
+		return
+	}
+
+	if cmd == "cancel" {
+		d.logger.Info("command received", "cmd", cmd, "chat_id", msg.ChatID)
+		d.handleCancel(msg, args)
+		return
+	}
+
+	if cmd == "yes" {
+		d.logger.Info("command received", "cmd", cmd, "chat_id", msg.ChatID)
+		d.handleYes(msg)
+		return
+	}
+
+	if cmd == "more" && d.limits.Summarizes() {
+		d.logger.Info("command received", "cmd", cmd, "chat_id", msg.ChatID)
+		d.handleMore(msg)
+		return
+	}
 
 	d.logger.Info("command received", "cmd", cmd, "chat_id", msg.ChatID)
 
-	op := d.ops.Get(cmd)
+	op := d.op(cmd, msg.ChatID)
 	if op == nil {
-		d.respond(msg.ChatID, fmt.Sprintf("Unknown command: /%s\nSend /help for available commands.", cmd))
+		d.respond(msg, style.Warn, fmt.Sprintf("Unknown command: /%s\nSend /help for available commands.", cmd))
 		return
 	}
 
-	risk := ops.RiskOf(op)
+	// Classify without the trailing TOTP code so per-call classifiers see
+	// the same args the op will execute with.
+	classifyArgs, _ := extractTOTP(args, d.totpParams().Digits)
+	risk := d.ops.RiskOfCall(op, classifyArgs)
 
 	// Risk-level branching.
 	switch risk {
@@ -114,15 +488,20 @@ func (d *Dispatcher) Handle(msg InboundMessage) {
 		// Execute immediately, no TOTP.
 	case ops.RiskLow:
 		if d.totp != nil {
-			realArgs, code := extractTOTP(args)
+			realArgs, code := extractTOTP(args, d.totpParams().Digits)
+			if _, sudo := d.sudoUntil(msg.ChatID); sudo || d.ops.TOTPExempt(msg.ChatID, op.Name()) {
+				// Pre-authorized or exempt in this chat; a code sent out
+				// of habit is dropped.
+				args = realArgs
+				break
+			}
 			if code == "" {
 				d.recordFailure(msg.ChatID)
-				d.respond(msg.ChatID, fmt.Sprintf("/%s requires a TOTP code as the last argument.", cmd))
+				d.respond(msg, style.Warn, fmt.Sprintf("/%s requires a TOTP code as the last argument.", cmd))
 				return
 			}
 			if !d.totp.Verify(code) {
-				d.recordFailure(msg.ChatID)
-				d.respond(msg.ChatID, "Invalid TOTP code.")
+				d.rejectTOTP(msg)
 				return
 			}
 			d.resetFailures(msg.ChatID)
@@ -130,40 +509,19 @@ func (d *Dispatcher) Handle(msg InboundMessage) {
 		}
 	case ops.RiskHigh:
 		if d.totp != nil {
-			d.respond(msg.ChatID, fmt.Sprintf("/%s is a high-risk operation. Use /do %s <args> <totp> for two-step approval.", cmd, cmd))
+			d.respond(msg, style.Info, fmt.Sprintf("/%s is a high-risk operation. Use /do %s <args> <totp> for two-step approval.", cmd, cmd))
 			return
 		}
 	}
 
-	// Non-blocking semaphore acquire.
-	select {
-	case d.sem <- struct{}{}:
-	default:
-		d.respond(msg.ChatID, "Busy — too many operations running. Try again shortly.")
-		return
-	}
-
-	defer func() { <-d.sem }()
-
-	ctx, cancel := context.WithTimeout(context.Background(), opTimeout)
-	defer cancel()
-
-	result, err := op.Execute(ctx, args)
-	if err != nil {
-		d.logger.Error("op failed", "op", cmd, "error", err)
-		d.respond(msg.ChatID, fmt.Sprintf("Error running /%s: %s", cmd, err))
-		return
-	}
-
-	d.logger.Info("command completed", "cmd", cmd, "chat_id", msg.ChatID)
-	d.respond(msg.ChatID, result)
+	d.execute(msg, cmd, op, args)
 }
 
 // handleDo initiates a two-step approval: /do <opName> [args] <totp>
 func (d *Dispatcher) handleDo(msg InboundMessage, args string) {
 	parts := strings.SplitN(args, " ", 2)
 	if len(parts) == 0 || parts[0] == "" {
-		d.respond(msg.ChatID, "Usage: /do <command> [args] <totp>")
+		d.respond(msg, style.Info, "Usage: /do <command> [args] <totp>")
 		return
 	}
 
@@ -174,48 +532,54 @@ func (d *Dispatcher) handleDo(msg InboundMessage, args string) {
 	}
 
 	// Extract TOTP from the end of opArgs.
-	realArgs, code := extractTOTP(opArgs)
+	realArgs, code := extractTOTP(opArgs, d.totpParams().Digits)
 	if code == "" {
 		d.recordFailure(msg.ChatID)
-		d.respond(msg.ChatID, "/do requires a TOTP code as the last argument.")
+		d.respond(msg, style.Warn, "/do requires a TOTP code as the last argument.")
 		return
 	}
 
 	if !d.totp.Verify(code) {
-		d.recordFailure(msg.ChatID)
-		d.respond(msg.ChatID, "Invalid TOTP code.")
+		d.rejectTOTP(msg)
 		return
 	}
 	d.resetFailures(msg.ChatID)
 
 	// Verify op exists.
-	op := d.ops.Get(opName)
+	op := d.op(opName, msg.ChatID)
 	if op == nil {
-		d.respond(msg.ChatID, fmt.Sprintf("Unknown command: /%s", opName))
+		d.respond(msg, style.Warn, fmt.Sprintf("Unknown command: /%s", opName))
 		return
 	}
 
 	nonce, err := d.approvals.Create(msg.ChatID, opName, realArgs)
 	if err != nil {
-		d.respond(msg.ChatID, fmt.Sprintf("Failed to create approval: %s", err))
+		d.respond(msg, style.Error, fmt.Sprintf("Failed to create approval: %s", err))
 		return
 	}
 
-	d.respond(msg.ChatID, fmt.Sprintf("Pending approval for /%s. Send:\n/approve %s <totp>", opName, nonce))
+	d.send(msg, "", style.Info, fmt.Sprintf("Pending approval for /%s. Send:\n/approve %s <totp>", opName, nonce), []Button{
+		{Text: "Approve", Data: "/approve " + nonce},
+		{Text: "Reject", Data: "/reject " + nonce},
+	})
 }
 
 // handleApprove completes a two-step approval: /approve <nonce> <totp>
 func (d *Dispatcher) handleApprove(msg InboundMessage, args string) {
-	realArgs, code := extractTOTP(args)
+	realArgs, code := extractTOTP(args, d.totpParams().Digits)
+	if code == "" && msg.CallbackID != "" {
+		// The Approve button cannot carry a code; ask for it.
+		d.respond(msg, style.Info, fmt.Sprintf("Send /approve %s <totp> to run it.", strings.TrimSpace(args)))
+		return
+	}
 	if code == "" {
 		d.recordFailure(msg.ChatID)
-		d.respond(msg.ChatID, "Usage: /approve <nonce> <totp>")
+		d.respond(msg, style.Info, "Usage: /approve <nonce> <totp>")
 		return
 	}
 
 	if !d.totp.Verify(code) {
-		d.recordFailure(msg.ChatID)
-		d.respond(msg.ChatID, "Invalid TOTP code.")
+		d.rejectTOTP(msg)
 		return
 	}
 	d.resetFailures(msg.ChatID)
@@ -223,37 +587,589 @@ func (d *Dispatcher) handleApprove(msg InboundMessage, args string) {
 	nonce := strings.TrimSpace(realArgs)
 	opName, opArgs, err := d.approvals.Consume(nonce, msg.ChatID)
 	if err != nil {
-		d.respond(msg.ChatID, fmt.Sprintf("Approval failed: %s", err))
+		d.respond(msg, style.Error, fmt.Sprintf("Approval failed: %s", err))
 		return
 	}
 
-	op := d.ops.Get(opName)
+	op := d.op(opName, msg.ChatID)
 	if op == nil {
-		d.respond(msg.ChatID, fmt.Sprintf("Operation /%s no longer registered.", opName))
+		d.respond(msg, style.Error, fmt.Sprintf("Operation /%s no longer registered.", opName))
 		return
 	}
 
-	// Non-blocking semaphore acquire.
-	select {
-	case d.sem <- struct{}{}:
+	d.execute(msg, opName, op, opArgs)
+}
+
+// handleReject cancels a pending approval: /reject <nonce>. It needs no
+// TOTP code, since it can only stop an op from running.
+func (d *Dispatcher) handleReject(msg InboundMessage, args string) {
+	nonce := strings.TrimSpace(args)
+	if nonce == "" {
+		d.respond(msg, style.Info, "Usage: /reject <nonce>")
+		return
+	}
+	opName, err := d.approvals.Cancel(nonce, msg.ChatID)
+	if err != nil {
+		d.respond(msg, style.Warn, fmt.Sprintf("Reject failed: %s", err))
+		return
+	}
+	d.respond(msg, style.Success, fmt.Sprintf("Rejected /%s.", opName))
+}
+
+// handleSudo opens, shows or closes the chat's pre-authorization window:
+// "/sudo <totp>" lets low-risk ops run without a code until it ends, and
+// "/sudo off" ends it early. High-risk ops still need /do.
+func (d *Dispatcher) handleSudo(msg InboundMessage, args string) {
+	realArgs, code := extractTOTP(args, d.totpParams().Digits)
+	switch strings.TrimSpace(realArgs) {
+	case "off":
+		d.mu.Lock()
+		_, ok := d.sudo[msg.ChatID]
+		delete(d.sudo, msg.ChatID)
+		d.mu.Unlock()
+		if !ok {
+			d.respond(msg, style.Info, "Sudo is not on.")
+			return
+		}
+		d.logger.Info("sudo ended", "chat_id", msg.ChatID)
+		d.respond(msg, style.Success, "Sudo off. Low-risk commands need a TOTP code again.")
+		return
+	case "":
+	default:
+		d.respond(msg, style.Info, "Usage: /sudo <totp> or /sudo off")
+		return
+	}
+
+	if code == "" {
+		if until, ok := d.sudoUntil(msg.ChatID); ok {
+			d.respond(msg, style.Info, fmt.Sprintf("Sudo is on until %s. Send /sudo off to end it.", until.Format("15:04")))
+			return
+		}
+		d.respond(msg, style.Info, "Usage: /sudo <totp> or /sudo off")
+		return
+	}
+	if !d.totp.Verify(code) {
+		d.rejectTOTP(msg)
+		return
+	}
+	d.resetFailures(msg.ChatID)
+
+	until := d.now().Add(d.sudoWindow)
+	d.mu.Lock()
+	d.sudo[msg.ChatID] = until
+	d.mu.Unlock()
+	d.logger.Info("sudo started", "chat_id", msg.ChatID, "until", until)
+	d.respond(msg, style.Success, fmt.Sprintf("Sudo on until %s: low-risk commands run without a TOTP code. Send /sudo off to end it.", until.Format("15:04")))
+}
+
+// sudoUntil returns when the chat's /sudo window ends, if one is open.
+func (d *Dispatcher) sudoUntil(chatID int64) (time.Time, bool) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	until, ok := d.sudo[chatID]
+	if ok && !d.now().Before(until) {
+		delete(d.sudo, chatID)
+		return time.Time{}, false
+	}
+	return until, ok
+}
+
+// acquire takes a run slot. A run takes a free slot at once only while
+// no run is queued; otherwise it queues behind them until a slot frees,
+// telling the chat how many runs are ahead of it. It reports false,
+// after replying busy, when queueing is off or the queue is full.
+func (d *Dispatcher) acquire(msg InboundMessage) bool {
+	d.mu.Lock()
+	if d.queued == 0 {
+		select {
+		case d.sem <- struct{}{}:
+			d.mu.Unlock()
+			return true
+		default:
+		}
+	}
+	ahead := d.queued
+	full := ahead >= d.queueDepth
+	ticket := d.tickets
+	if !full {
+		d.queued++
+		d.tickets++
+	}
+	d.mu.Unlock()
+
+	switch {
+	case full && d.queueDepth == 0:
+		d.respond(msg, style.Warn, "Busy — too many operations running. Try again shortly.")
+		return false
+	case full:
+		d.respond(msg, style.Warn, fmt.Sprintf("Busy — %d commands already queued. Try again shortly.", ahead))
+		return false
+	case ahead == 0:
+		d.respond(msg, style.Info, "Queued: next in line.")
+	default:
+		d.respond(msg, style.Info, fmt.Sprintf("Queued: %d ahead of you.", ahead))
+	}
+
+	// Only the run whose turn it is waits on the semaphore, so queued
+	// runs start in the order they arrived.
+	d.mu.Lock()
+	for d.serving != ticket {
+		d.turn.Wait()
+	}
+	d.mu.Unlock()
+	d.sem <- struct{}{}
+	d.mu.Lock()
+	d.queued--
+	d.serving++
+	d.turn.Broadcast()
+	d.mu.Unlock()
+	return true
+}
+
+// track registers a run for /cancel and returns its job ID.
+func (d *Dispatcher) track(msg InboundMessage, cmd string, start time.Time, cancel context.CancelFunc) int {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	d.lastJob++
+	d.running[d.lastJob] = &runningOp{
+		id:        d.lastJob,
+		cmd:       cmd,
+		chatID:    msg.ChatID,
+		messageID: msg.MessageID,
+		started:   start,
+		cancel:    cancel,
+	}
+	return d.lastJob
+}
+
+// untrack removes a finished run and reports whether /cancel stopped it.
+func (d *Dispatcher) untrack(id int) (cancelled bool) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	run := d.running[id]
+	delete(d.running, id)
+	return run.cancelled
+}
+
+// handleCancel stops a command running in the chat: the one named by job
+// ID, "/cancel <id>", the one replied to, or the only one running. The
+// run itself reports the cancellation. No TOTP code is needed, since
+// /cancel can only stop an op.
+func (d *Dispatcher) handleCancel(msg InboundMessage, args string) {
+	d.mu.Lock()
+	var runs []*runningOp
+	for _, run := range d.running {
+		if run.chatID == msg.ChatID && !run.cancelled {
+			runs = append(runs, run)
+		}
+	}
+	sort.Slice(runs, func(i, j int) bool { return runs[i].id < runs[j].id })
+
+	var target *runningOp
+	var reply string
+	switch arg := strings.TrimSpace(args); {
+	case arg != "":
+		id, err := strconv.Atoi(strings.TrimPrefix(arg, "#"))
+		if err != nil {
+			reply = "Usage: /cancel [job id]"
+			break
+		}
+		for _, run := range runs {
+			if run.id == id {
+				target = run
+			}
+		}
+		if target == nil {
+			reply = fmt.Sprintf("No running command with job ID %d.", id)
+		}
+	case msg.ReplyTo != 0:
+		for _, run := range runs {
+			if run.messageID == msg.ReplyTo {
+				target = run
+			}
+		}
+		if target == nil {
+			reply = "That command is not running."
+		}
+	case len(runs) == 0:
+		reply = "Nothing is running."
+	case len(runs) == 1:
+		target = runs[0]
 	default:
-		d.respond(msg.ChatID, "Busy — too many operations running. Try again shortly.")
+		lines := []string{"Several commands are running:"}
+		for _, run := range runs {
+			lines = append(lines, fmt.Sprintf("%d  /%s (%s)", run.id, run.cmd, d.now().Sub(run.started).Round(time.Second)))
+		}
+		lines = append(lines, "Send /cancel <id>, or reply /cancel to the command.")
+		reply = strings.Join(lines, "\n")
+	}
+	if target != nil {
+		target.cancelled = true
+		target.cancel()
+	}
+	d.mu.Unlock()
+
+	if target != nil {
+		d.logger.Info("cancelling op", "op", target.cmd, "job", target.id, "chat_id", msg.ChatID)
+		return
+	}
+	d.respond(msg, style.Info, reply)
+}
+
+// handleYes runs the command held by the chat's pending confirmation.
+func (d *Dispatcher) handleYes(msg InboundMessage) {
+	d.mu.Lock()
+	pc, ok := d.confirms[msg.ChatID]
+	delete(d.confirms, msg.ChatID)
+	d.mu.Unlock()
+
+	if !ok {
+		d.respond(msg, style.Warn, "Nothing to confirm.")
+		return
+	}
+	if d.now().After(pc.expires) {
+		d.respond(msg, style.Warn, fmt.Sprintf("Confirmation for /%s expired; send the command again.", pc.cmd))
+		return
+	}
+	op := d.op(pc.cmd, msg.ChatID)
+	if op == nil {
+		d.respond(msg, style.Error, fmt.Sprintf("Operation /%s no longer registered.", pc.cmd))
+		return
+	}
+
+	// Run with the variables that were previewed and reply under the
+	// original command.
+	base := d.context(context.Background(), pc.msg.ChatID, pc.msg.MessageID)
+	if pc.hasVars {
+		base = ops.WithVars(base, pc.vars)
+	}
+	d.logger.Info("command confirmed", "cmd", pc.cmd, "chat_id", msg.ChatID)
+	d.run(base, pc.msg, pc.cmd, op, pc.args)
+}
+
+// execute resolves chat variables and runs op. Ops that ask for
+// confirmation get a preview of the resolved command instead, and run only
+// after /yes.
+func (d *Dispatcher) execute(msg InboundMessage, cmd string, op ops.Op, args string) {
+	base := d.context(context.Background(), msg.ChatID, msg.MessageID)
+	base, args, ok := d.resolveVars(base, msg, cmd, op, args)
+	if !ok {
+		return
+	}
+	if c, ok := op.(ops.Confirmer); ok && c.NeedsConfirmation(base, args) {
+		d.requestConfirmation(base, msg, cmd, op, args)
+		return
+	}
+	d.run(base, msg, cmd, op, args)
+}
+
+// run executes op under the concurrency limit and replies with the result.
+func (d *Dispatcher) run(base context.Context, msg InboundMessage, cmd string, op ops.Op, args string) {
+	defer d.admission.Begin(admission.Interactive)()
+
+	if !d.acquire(msg) {
 		return
 	}
 	defer func() { <-d.sem }()
 
-	ctx, cancel := context.WithTimeout(context.Background(), opTimeout)
+	ctx, cancel := context.WithTimeout(base, d.opTimeout)
+	defer cancel()
+	ctx, replyButtons := ops.WithReplyButtons(ctx)
+	replyFiles := func() []ops.File { return nil }
+	if _, ok := Unwrap(d.notifier).(DocumentSender); ok {
+		ctx, replyFiles = ops.WithReplyFiles(ctx)
+	}
+
+	d.react(msg, d.reactions.start)
+	stopTyping := d.typing(msg.ChatID)
+	start := d.now()
+	job := d.track(msg, cmd, start, cancel)
+	result, err := op.Execute(ctx, args)
+	cancelled := d.untrack(job)
+	stopTyping()
+	d.recordRun(base, msg.ChatID, cmd, start, err)
+	if err != nil && cancelled {
+		d.logger.Info("op cancelled", "op", cmd, "chat_id", msg.ChatID)
+		d.react(msg, d.reactions.failed)
+		d.respond(msg, style.Warn, fmt.Sprintf("/%s cancelled after %s.", cmd, d.now().Sub(start).Round(time.Second)))
+		return
+	}
+	if err != nil {
+		d.logger.Error("op failed", "op", cmd, "error", d.redact.Error(err))
+		d.react(msg, d.reactions.failed)
+		d.respond(msg, style.Error, fmt.Sprintf("Error running /%s: %s", cmd, d.redact.Error(err)))
+		return
+	}
+
+	d.logger.Info("command completed", "cmd", cmd, "chat_id", msg.ChatID)
+	if d.results != nil {
+		if err := d.results.Record(msg.ChatID, cmd, result, d.now()); err != nil {
+			d.logger.Error("record result failed", "cmd", cmd, "error", err)
+		}
+	}
+	if !d.react(msg, d.reactions.done) || !d.reactions.quiet(cmd) {
+		var buttons []Button
+		for _, b := range replyButtons() {
+			buttons = append(buttons, Button{Text: b.Text, Data: b.Data})
+		}
+		d.send(msg, cmd, resultSeverity(result), result, buttons)
+	}
+	d.attach(msg, replyFiles())
+}
+
+// attach sends the files an op attached to its reply.
+func (d *Dispatcher) attach(msg InboundMessage, files []ops.File) {
+	sender, ok := Unwrap(d.notifier).(DocumentSender)
+	if !ok || len(files) == 0 {
+		return
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
 	defer cancel()
+	for _, f := range files {
+		doc := Document{Name: f.Name, Data: f.Data, Caption: f.Caption, ChatID: msg.ChatID}
+		if err := sender.SendDocument(ctx, doc); err != nil {
+			d.logger.Error("failed to attach file", "chat_id", msg.ChatID, "file", f.Name, "error", err)
+		}
+	}
+}
+
+// typing shows the chat a typing indicator once an op has run for
+// typingDelay, refreshed until the returned stop is called. Stop waits
+// for an indicator in flight, so none follows the reply.
+func (d *Dispatcher) typing(chatID int64) (stop func()) {
+	sender, ok := Unwrap(d.notifier).(TypingSender)
+	if !ok {
+		return func() {}
+	}
+	done := make(chan struct{})
+	var wg sync.WaitGroup
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		timer := time.NewTimer(d.typingDelay)
+		defer timer.Stop()
+		for {
+			select {
+			case <-done:
+				return
+			case <-timer.C:
+			}
+			ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+			if err := sender.SendTyping(ctx, chatID); err != nil {
+				d.logger.Debug("typing indicator failed", "chat_id", chatID, "error", err)
+			}
+			cancel()
+			timer.Reset(d.typingRefresh)
+		}
+	}()
+	return func() {
+		close(done)
+		wg.Wait()
+	}
+}
+
+// react sets the reaction emoji picks on the command message, if reactions
+// are on and the notifier supports them. It reports whether the reaction
+// was set. Button presses are not reacted to, since their message is the
+// bot's own.
+func (d *Dispatcher) react(msg InboundMessage, emoji func() string) bool {
+	if d.reactions == nil || msg.MessageID == 0 || msg.CallbackID != "" {
+		return false
+	}
+	reactor, ok := Unwrap(d.notifier).(Reactor)
+	if !ok {
+		return false
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	if err := reactor.React(ctx, msg.ChatID, msg.MessageID, emoji()); err != nil {
+		d.logger.Warn("react failed", "chat_id", msg.ChatID, "error", err)
+		return false
+	}
+	return true
+}
+
+// requestConfirmation holds a call for /yes, replacing any earlier one
+// from the same chat, and echoes the command that will run.
+func (d *Dispatcher) requestConfirmation(ctx context.Context, msg InboundMessage, cmd string, op ops.Op, args string) {
+	preview := strings.TrimSpace("/" + cmd + " " + args)
+	if r, ok := op.(ops.Resolver); ok {
+		resolved, err := r.Resolve(ctx, args)
+		if err != nil {
+			d.respond(msg, style.Error, fmt.Sprintf("Cannot run /%s: %s", cmd, err))
+			return
+		}
+		preview = resolved
+	}
+	vars, hasVars := ops.VarsFrom(ctx)
+
+	d.mu.Lock()
+	d.confirms[msg.ChatID] = pendingConfirm{
+		cmd:     cmd,
+		args:    args,
+		msg:     msg,
+		vars:    maps.Clone(vars),
+		hasVars: hasVars,
+		expires: d.now().Add(d.confirmTimeout),
+	}
+	d.mu.Unlock()
+
+	d.respond(msg, style.Info, fmt.Sprintf("/%s will run:\n%s\nSend /yes within %s to confirm.", cmd, d.redact.String(preview), d.confirmTimeout))
+}
+
+// resolveVars expands chat variables in args and attaches them to ctx for
+// ops that expand their own templates. Shell commands and calls that used
+// a variable are logged as finally resolved. It returns false after
+// replying when a referenced variable is unset.
+func (d *Dispatcher) resolveVars(ctx context.Context, msg InboundMessage, cmd string, op ops.Op, args string) (context.Context, string, bool) {
+	ctx, resolvedArgs, err := d.expandVars(ctx, msg.ChatID, cmd, op, args)
+	if err != nil {
+		d.respond(msg, style.Error, fmt.Sprintf("Cannot run /%s: %s", cmd, err))
+		return ctx, args, false
+	}
+	return ctx, resolvedArgs, true
+}
 
-	result, err := op.Execute(ctx, opArgs)
+// expandVars is resolveVars without the reply, for callers with no
+// message to answer.
+func (d *Dispatcher) expandVars(ctx context.Context, chatID int64, cmd string, op ops.Op, args string) (context.Context, string, error) {
+	if d.vars == nil {
+		return ctx, args, nil
+	}
+	vars, err := d.vars.All(chatID)
+	if err != nil {
+		d.logger.Error("load chat variables failed", "chat_id", chatID, "error", err)
+		return ctx, args, err
+	}
+	ctx = ops.WithVars(ctx, vars)
+
+	resolvedArgs, err := chatvars.Expand(args, vars)
 	if err != nil {
-		d.logger.Error("op failed", "op", opName, "error", err)
-		d.respond(msg.ChatID, fmt.Sprintf("Error running /%s: %s", opName, err))
+		return ctx, args, err
+	}
+	if r, ok := op.(ops.Resolver); ok {
+		resolved, err := r.Resolve(ctx, resolvedArgs)
+		if err != nil {
+			return ctx, args, err
+		}
+		d.logger.Info("command resolved", "cmd", cmd, "chat_id", chatID, "resolved", d.redact.String(resolved))
+	} else if resolvedArgs != args {
+		d.logger.Info("command resolved", "cmd", cmd, "chat_id", chatID, "resolved", d.redact.String(resolvedArgs))
+	}
+	return ctx, resolvedArgs, nil
+}
+
+// Exec runs a command for chatID without a triggering message, as
+// scheduled commands do. Chat variables apply and it waits for a free
+// slot instead of reporting busy. High-risk ops are refused because they
+// need interactive approval.
+func (d *Dispatcher) Exec(ctx context.Context, chatID int64, cmd, args string) (string, error) {
+	op := d.op(cmd, chatID)
+	if op == nil {
+		return "", fmt.Errorf("unknown command /%s", cmd)
+	}
+	if d.ops.RiskOfCall(op, args) == ops.RiskHigh {
+		return "", fmt.Errorf("/%s needs approval and cannot run unattended", cmd)
+	}
+
+	ctx = ops.WithChatID(ctx, chatID)
+	if d.bot != nil {
+		ctx = ops.WithBot(ctx, *d.bot)
+	}
+	ctx, args, err := d.expandVars(ctx, chatID, cmd, op, args)
+	if err != nil {
+		return "", err
+	}
+
+	defer d.admission.Begin(admission.Background)()
+	select {
+	case d.sem <- struct{}{}:
+	case <-ctx.Done():
+		return "", ctx.Err()
+	}
+	defer func() { <-d.sem }()
+
+	ctx, cancel := context.WithTimeout(ctx, d.opTimeout)
+	defer cancel()
+
+	d.logger.Info("scheduled command", "cmd", cmd, "chat_id", chatID)
+	start := d.now()
+	result, err := op.Execute(ctx, args)
+	d.recordRun(ctx, chatID, cmd, start, err)
+	return result, err
+}
+
+func (d *Dispatcher) recordRun(ctx context.Context, chatID int64, cmd string, start time.Time, err error) {
+	took := d.now().Sub(start)
+	if d.audit != nil {
+		d.audit.RecordOp(chatID, cmd, took, err)
+	}
+	if d.events == nil {
 		return
 	}
+	attrs := map[string]string{
+		"op":          cmd,
+		"chat_id":     strconv.FormatInt(chatID, 10),
+		"ok":          strconv.FormatBool(err == nil),
+		"duration_ms": strconv.FormatInt(took.Milliseconds(), 10),
+	}
+	if err != nil {
+		attrs["error"] = d.redact.Error(err)
+	}
+	if rule, ok := events.RuleFrom(ctx); ok {
+		attrs["rule"] = rule
+	}
+	d.events.Publish(events.CommandCompleted, attrs)
+}
+
+// op returns the op named cmd, or nil if it is not registered, chatID
+// may not run it or this dispatcher's bot does not expose it.
+func (d *Dispatcher) op(cmd string, chatID int64) ops.Op {
+	op := d.ops.Get(cmd)
+	if op == nil || !ops.ChatAllowed(op, chatID) || (d.bot != nil && !d.bot.Allows(cmd)) {
+		return nil
+	}
+	return op
+}
+
+// context returns ctx carrying the chat and message a command came from,
+// and the bot when one is set.
+func (d *Dispatcher) context(ctx context.Context, chatID, messageID int64) context.Context {
+	ctx = ops.WithMessageID(ops.WithChatID(ctx, chatID), messageID)
+	if d.bot != nil {
+		ctx = ops.WithBot(ctx, *d.bot)
+	}
+	if until, ok := d.sudoUntil(chatID); ok {
+		ctx = ops.WithSudo(ctx, until)
+	}
+	return ctx
+}
+
+// totpParams returns the settings codes are checked with.
+func (d *Dispatcher) totpParams() auth.Params {
+	if p, ok := d.totp.(totpParamser); ok {
+		return p.Params()
+	}
+	return auth.DefaultParams
+}
 
-	d.logger.Info("command completed", "cmd", opName, "chat_id", msg.ChatID)
-	d.respond(msg.ChatID, result)
+// rejectTOTP counts a wrong TOTP code against the chat and tells the user,
+// blaming the clock when it is off by more than codes allow.
+func (d *Dispatcher) rejectTOTP(msg InboundMessage) {
+	d.recordFailure(msg.ChatID)
+	if d.clock != nil && d.totpParams().TimeBased() {
+		ctx, cancel := context.WithTimeout(context.Background(), skewTimeout)
+		skew, err := d.clock.Skew(ctx)
+		cancel()
+		if err != nil {
+			d.logger.Debug("clock skew check failed", "error", err)
+		} else if skew.Abs() > d.totpParams().MaxSkew() {
+			d.logger.Warn("clock skew exceeds TOTP window", "skew", skew)
+			d.respond(msg, style.Error, fmt.Sprintf("Invalid TOTP code: clock is off by %s. Sync the daemon's clock and try again.", skew.Abs().Round(time.Second)))
+			return
+		}
+	}
+	d.respond(msg, style.Error, "Invalid TOTP code.")
 }
 
 func (d *Dispatcher) recordFailure(chatID int64) {
@@ -268,28 +1184,162 @@ func (d *Dispatcher) resetFailures(chatID int64) {
 	}
 }
 
-const maxMessageLen = 4096
+// respond replies to msg, marked for sev by the configured style.
+func (d *Dispatcher) respond(msg InboundMessage, sev style.Severity, text string) {
+	d.send(msg, "", sev, text, nil)
+}
+
+// send is respond with inline buttons under the reply. Text too long for
+// one message is laid out under the overflow policy for op, the command
+// whose output it is, or the global policy when op is empty.
+func (d *Dispatcher) send(msg InboundMessage, op string, sev style.Severity, text string, buttons []Button) {
+	d.sendAs(msg, op, d.limits.PolicyFor(op), sev, text, buttons)
+}
+
+// sendAs is send under the overflow policy overflow.
+func (d *Dispatcher) sendAs(msg InboundMessage, op string, overflow limits.Policy, sev style.Severity, text string, buttons []Button) {
+	prefix := ""
+	if p := d.style.Prefix(sev); p != "" {
+		prefix = p + " "
+	}
+
+	room := d.limits.MessageLen() - len(prefix)
+	reply := limits.Reply{Messages: []string{text}}
+	sender, canAttach := Unwrap(d.notifier).(DocumentSender)
+	if len(text) > room {
+		if overflow == limits.Attach && !canAttach {
+			overflow = limits.Truncate
+		}
+		if overflow == limits.Summarize {
+			reply = d.summarize(msg.ChatID, op, text, room, canAttach)
+		} else {
+			reply = d.limits.Layout(overflow, text, room)
+		}
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	for i, page := range reply.Messages {
+		var b []Button
+		if i == len(reply.Messages)-1 {
+			b = buttons
+		}
+		if !d.sendText(ctx, msg, prefix+page, b) {
+			return
+		}
+	}
+	if reply.File != "" {
+		doc := Document{Name: "reply.txt", Data: []byte(reply.File), ChatID: msg.ChatID}
+		if op != "" {
+			doc.Name, doc.Caption = op+".txt", "Full output of /"+op
+		}
+		if err := sender.SendDocument(ctx, doc); err != nil {
+			d.logger.Error("failed to attach response", "chat_id", msg.ChatID, "error", err)
+		}
+	}
+}
+
+// summaryTimeout bounds writing a summary, which may ask a model.
+const summaryTimeout = 30 * time.Second
 
-func (d *Dispatcher) respond(chatID int64, text string) {
-	if len(text) > maxMessageLen {
-		text = "…" + text[len(text)-maxMessageLen+len("…"):]
+// summarize lays out text as a summary followed by where the full text
+// is: attached when the notifier takes files, and kept for /more either
+// way.
+func (d *Dispatcher) summarize(chatID int64, op, text string, room int, attach bool) limits.Reply {
+	d.mu.Lock()
+	d.more[chatID] = text
+	d.mu.Unlock()
+
+	var s string
+	if d.summary != nil {
+		ctx, cancel := context.WithTimeout(context.Background(), summaryTimeout)
+		var err error
+		// The model may be a hosted service, so it never sees secrets.
+		s, err = d.summary.Summarize(ctx, op, d.redact.String(text))
+		cancel()
+		if err != nil {
+			d.logger.Warn("summary failed, counting lines instead", "op", op, "error", d.redact.Error(err))
+		}
+	}
+	if s == "" {
+		s = summary.Rules(text)
 	}
+
+	reply := limits.Reply{}
+	note := "\n(send /more for the full output)"
+	if attach {
+		note = "\n(full output attached, or send /more)"
+		reply.File = text
+	}
+	reply.Messages = []string{limits.Cut(s, room-len(note)) + note}
+	return reply
+}
+
+// handleMore sends the full text of the chat's last summarized reply,
+// split across messages.
+func (d *Dispatcher) handleMore(msg InboundMessage) {
+	d.mu.Lock()
+	text, ok := d.more[msg.ChatID]
+	d.mu.Unlock()
+	if !ok {
+		d.respond(msg, style.Info, "Nothing more to show.")
+		return
+	}
+	d.sendAs(msg, "", limits.Paginate, resultSeverity(text), text, nil)
+}
+
+// sendText sends one reply message and reports whether it was delivered.
+func (d *Dispatcher) sendText(ctx context.Context, msg InboundMessage, text string, buttons []Button) bool {
 	n := Notification{
 		Text:      text,
 		Source:    "dispatcher",
-		CreatedAt: time.Now(),
+		CreatedAt: d.now(),
+		ChatID:    msg.ChatID,
+		ReplyTo:   msg.MessageID,
+		Buttons:   buttons,
 	}
-	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
-	defer cancel()
-
 	if err := d.notifier.Send(ctx, n); err != nil {
-		d.logger.Error("failed to send response", "chat_id", chatID, "error", err)
+		d.logger.Error("failed to send response", "chat_id", msg.ChatID, "error", err)
+		return false
+	}
+	d.record(msg.ChatID, transcript.FromBot, text)
+	return true
+}
+
+// record adds a message to the chat's transcript, if one is kept, with
+// secrets masked.
+func (d *Dispatcher) record(chatID int64, from, text string) {
+	if d.history == nil || strings.TrimSpace(text) == "" {
+		return
+	}
+	if err := d.history.Record(chatID, from, d.redact.String(text), d.now()); err != nil {
+		d.logger.Error("record transcript failed", "chat_id", chatID, "error", err)
+	}
+}
+
+// maskTOTP hides a TOTP code at the end of a command, so transcripts do
+// not keep codes.
+func (d *Dispatcher) maskTOTP(text string) string {
+	digits := d.totpParams().Digits
+	if rest, code := extractTOTP(text, digits); code != "" {
+		return strings.TrimSpace(rest + " " + strings.Repeat("•", digits))
 	}
+	return text
 }
 
-// extractTOTP splits a 6-digit TOTP code from the last token of args.
+// resultSeverity classifies an op's output. Ops return usage text as a
+// normal result, so it is recognised by its "Usage:" prefix.
+func resultSeverity(result string) style.Severity {
+	if strings.HasPrefix(result, "Usage:") {
+		return style.Info
+	}
+	return style.Success
+}
+
+// extractTOTP splits a TOTP code of the given length from the last token
+// of args.
 // Returns (remainingArgs, code). If no valid code found, code is "".
-func extractTOTP(args string) (realArgs, code string) {
+func extractTOTP(args string, digits int) (realArgs, code string) {
 	args = strings.TrimSpace(args)
 	if args == "" {
 		return "", ""
@@ -298,21 +1348,21 @@ func extractTOTP(args string) (realArgs, code string) {
 	lastSpace := strings.LastIndex(args, " ")
 	if lastSpace == -1 {
 		// The entire args string might be the code.
-		if isTOTPCode(args) {
+		if isTOTPCode(args, digits) {
 			return "", args
 		}
 		return args, ""
 	}
 
 	candidate := args[lastSpace+1:]
-	if isTOTPCode(candidate) {
+	if isTOTPCode(candidate, digits) {
 		return strings.TrimSpace(args[:lastSpace]), candidate
 	}
 	return args, ""
 }
 
-func isTOTPCode(s string) bool {
-	if len(s) != 6 {
+func isTOTPCode(s string, digits int) bool {
+	if len(s) != digits {
 		return false
 	}
 	for _, c := range s {
@@ -332,11 +1382,8 @@ func parseCommand(text string) (cmd, args string) {
 	}
 
 	text = text[1:] // strip leading "/"
-	parts := strings.SplitN(text, " ", 2)
-	cmd = parts[0]
-	if len(parts) > 1 {
-		args = strings.TrimSpace(parts[1])
-	}
+	cmd, args, _ = strings.Cut(text, " ")
+	args = strings.TrimSpace(args)
 
 	// Strip @botname suffix.
 	if at := strings.Index(cmd, "@"); at != -1 {
@@ -0,0 +1,73 @@
+package grpcapi
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/jdelaire/openslack/core/localhttp"
+	"github.com/jdelaire/openslack/internal/homedir"
+)
+
+// DefaultAddr is where the API listens unless configured.
+const DefaultAddr = "127.0.0.1:8790"
+
+// TokenAccount is the keychain account holding the API's access token.
+const TokenAccount = "grpc-api-token"
+
+// unixPrefix marks an Addr that is a Unix socket path.
+const unixPrefix = "unix:"
+
+// Config sets where the API listens and which chat its op runs act as.
+// Addr is a loopback address, or "unix:" and a socket path, which is
+// created readable and writable only by the daemon's user. ChatID
+// decides which chat variables and per-chat command limits apply to ops
+// run through the API.
+type Config struct {
+	Addr   string `json:"addr"`
+	ChatID int64  `json:"chat_id"`
+}
+
+// LoadConfig reads and validates a gRPC API config file. A leading "~/"
+// in a socket path is expanded.
+// Returns nil, nil if the file does not exist.
+func LoadConfig(path string) (*Config, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("read grpc api config: %w", err)
+	}
+
+	var cfg Config
+	if err := json.Unmarshal(data, &cfg); err != nil {
+		return nil, fmt.Errorf("parse grpc api config: %w", err)
+	}
+	if cfg.Addr == "" {
+		cfg.Addr = DefaultAddr
+	}
+	if socket, ok := strings.CutPrefix(cfg.Addr, unixPrefix); ok {
+		cfg.Addr = unixPrefix + homedir.Expand(socket)
+	}
+	if err := checkAddr(cfg.Addr); err != nil {
+		return nil, err
+	}
+	return &cfg, nil
+}
+
+// checkAddr accepts a loopback address or a non-empty socket path.
+func checkAddr(addr string) error {
+	if socket, ok := strings.CutPrefix(addr, unixPrefix); ok {
+		if socket == "" {
+			return errors.New("grpc api addr: socket path is empty")
+		}
+		return nil
+	}
+	if err := localhttp.CheckLoopback(addr); err != nil {
+		return fmt.Errorf("grpc api %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,330 @@
+// Package grpcapi serves the typed API in api/openslack/v1 over gRPC on
+// localhost or a Unix socket: notifications through the socket's pipeline, op runs
+// through the dispatcher and the task list. It is the gRPC counterpart of
+// the socket's JSON-RPC and of the admin API.
+package grpcapi
+
+import (
+	"context"
+	"crypto/subtle"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/types/known/timestamppb"
+
+	openslackv1 "github.com/jdelaire/openslack/api/openslack/v1"
+	"github.com/jdelaire/openslack/core"
+	"github.com/jdelaire/openslack/core/ops"
+	"github.com/jdelaire/openslack/internal/platform"
+	"github.com/jdelaire/openslack/internal/tasks"
+)
+
+// maxMsg bounds request messages.
+const maxMsg = 64 << 10
+
+// Server serves the gRPC API. Calls must carry the access token as
+// "authorization: Bearer <token>" metadata. Services that are not set
+// with a With method answer Unimplemented.
+type Server struct {
+	cfg    *Config
+	token  string
+	logger *slog.Logger
+
+	socket   *core.Server
+	registry *ops.Registry
+	exec     core.OpExecutor
+	tasks    *tasks.TaskService
+
+	mu  sync.Mutex
+	srv *grpc.Server
+	ln  net.Listener
+}
+
+// NewServer returns a gRPC API server. A nil cfg listens on DefaultAddr
+// and runs ops as chat 0. The token must not be empty.
+func NewServer(cfg *Config, token string, logger *slog.Logger) (*Server, error) {
+	if cfg == nil {
+		cfg = &Config{Addr: DefaultAddr}
+	}
+	if err := checkAddr(cfg.Addr); err != nil {
+		return nil, err
+	}
+	if token == "" {
+		return nil, errors.New("grpc api: no access token")
+	}
+	if logger == nil {
+		logger = slog.Default()
+	}
+	return &Server{cfg: cfg, token: token, logger: logger}, nil
+}
+
+// WithNotifications enables the Notifications service. Calls run through
+// socket's notify and ack-status handling, under its limits, sources and
+// templates.
+func (s *Server) WithNotifications(socket *core.Server) *Server {
+	s.socket = socket
+	return s
+}
+
+// WithDispatch enables the Dispatch service, running registry's ops
+// through exec with the same checks as scheduled commands: high-risk ops
+// are refused.
+func (s *Server) WithDispatch(registry *ops.Registry, exec core.OpExecutor) *Server {
+	s.registry, s.exec = registry, exec
+	return s
+}
+
+// WithTasks enables the Tasks service.
+func (s *Server) WithTasks(svc *tasks.TaskService) *Server {
+	s.tasks = svc
+	return s
+}
+
+// Start listens on the configured address and serves until ctx is
+// cancelled or Shutdown is called.
+func (s *Server) Start(ctx context.Context) error {
+	ln, err := listen(s.cfg.Addr)
+	if err != nil {
+		return fmt.Errorf("grpc api listen: %w", err)
+	}
+	srv := grpc.NewServer(grpc.MaxRecvMsgSize(maxMsg), grpc.UnaryInterceptor(s.authorize))
+	if s.socket != nil {
+		openslackv1.RegisterNotificationsServer(srv, &notifications{s: s})
+	}
+	if s.registry != nil && s.exec != nil {
+		openslackv1.RegisterDispatchServer(srv, &dispatch{s: s})
+	}
+	if s.tasks != nil {
+		openslackv1.RegisterTasksServer(srv, &taskList{s: s})
+	}
+	s.mu.Lock()
+	s.srv, s.ln = srv, ln
+	s.mu.Unlock()
+	s.logger.Info("grpc api listening", "addr", s.Addr())
+
+	go func() {
+		if err := srv.Serve(ln); err != nil {
+			s.logger.Error("grpc api serve failed", "error", err)
+		}
+	}()
+	context.AfterFunc(ctx, srv.Stop)
+	return nil
+}
+
+// Addr returns the address Start is listening on, or "" before Start. A
+// socket is returned as "unix:" and its path, which grpc.NewClient takes
+// as a target.
+func (s *Server) Addr() string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.ln == nil {
+		return ""
+	}
+	if s.ln.Addr().Network() == "unix" {
+		return unixPrefix + s.ln.Addr().String()
+	}
+	return s.ln.Addr().String()
+}
+
+// listen opens addr: a TCP address, or a socket path after "unix:". A
+// socket left behind by a previous run is removed, unless something still
+// answers on it.
+func listen(addr string) (net.Listener, error) {
+	path, ok := strings.CutPrefix(addr, unixPrefix)
+	if !ok {
+		return net.Listen("tcp", addr)
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0700); err != nil {
+		return nil, fmt.Errorf("create socket directory: %w", err)
+	}
+	if _, err := os.Stat(path); err == nil {
+		if conn, err := platform.Dial(path, 500*time.Millisecond); err == nil {
+			conn.Close()
+			return nil, fmt.Errorf("another instance is already listening on %s", path)
+		}
+		if err := os.Remove(path); err != nil {
+			return nil, fmt.Errorf("remove stale socket: %w", err)
+		}
+	}
+	return platform.Listen(path)
+}
+
+// Shutdown stops serving, waiting up to 5s for in-flight calls.
+func (s *Server) Shutdown() {
+	s.mu.Lock()
+	srv := s.srv
+	s.mu.Unlock()
+	if srv == nil {
+		return
+	}
+	done := make(chan struct{})
+	go func() {
+		srv.GracefulStop()
+		close(done)
+	}()
+	select {
+	case <-done:
+	case <-time.After(5 * time.Second):
+		srv.Stop()
+	}
+}
+
+// authorize rejects calls that do not carry the access token.
+func (s *Server) authorize(ctx context.Context, req any, _ *grpc.UnaryServerInfo, next grpc.UnaryHandler) (any, error) {
+	md, _ := metadata.FromIncomingContext(ctx)
+	for _, v := range md.Get("authorization") {
+		got, ok := strings.CutPrefix(v, "Bearer ")
+		if ok && subtle.ConstantTimeCompare([]byte(got), []byte(s.token)) == 1 {
+			return next(ctx, req)
+		}
+	}
+	return nil, status.Error(codes.Unauthenticated, "unauthorized")
+}
+
+// call runs a socket action. Requests the socket would reject as invalid
+// are InvalidArgument; failures it reports in the response are Unknown,
+// like JSON-RPC's server error.
+func (s *Server) call(ctx context.Context, action string, payload any) (core.Response, error) {
+	data, err := json.Marshal(payload)
+	if err != nil {
+		return core.Response{}, status.Error(codes.InvalidArgument, err.Error())
+	}
+	resp, err := s.socket.Call(ctx, action, data)
+	if err != nil {
+		return core.Response{}, status.Error(codes.InvalidArgument, err.Error())
+	}
+	if !resp.OK {
+		return core.Response{}, status.Error(codes.Unknown, resp.Error)
+	}
+	return resp, nil
+}
+
+type notifications struct {
+	openslackv1.UnimplementedNotificationsServer
+	s *Server
+}
+
+func (n *notifications) Notify(ctx context.Context, req *openslackv1.NotifyRequest) (*openslackv1.NotifyResponse, error) {
+	payload := core.NotifyPayload{
+		Text:     req.GetText(),
+		Template: req.GetTemplate(),
+		Vars:     req.GetVars(),
+		Source:   req.GetSource(),
+		Ack:      req.GetAck(),
+	}
+	if req.SendAt != nil {
+		if err := req.SendAt.CheckValid(); err != nil {
+			return nil, status.Error(codes.InvalidArgument, "send_at: "+err.Error())
+		}
+		at := req.SendAt.AsTime()
+		payload.SendAt = &at
+	}
+	resp, err := n.s.call(ctx, "notify", payload)
+	if err != nil {
+		return nil, err
+	}
+	return &openslackv1.NotifyResponse{Id: resp.ID, Status: resp.Status}, nil
+}
+
+func (n *notifications) AckStatus(ctx context.Context, req *openslackv1.AckStatusRequest) (*openslackv1.AckStatusResponse, error) {
+	resp, err := n.s.call(ctx, "ack-status", core.AckStatusPayload{ID: req.GetId()})
+	if err != nil {
+		return nil, err
+	}
+	out := &openslackv1.AckStatusResponse{Status: resp.Status}
+	if resp.AckedAt != nil {
+		out.AckedAt = timestamppb.New(*resp.AckedAt)
+	}
+	return out, nil
+}
+
+type dispatch struct {
+	openslackv1.UnimplementedDispatchServer
+	s *Server
+}
+
+func (d *dispatch) Exec(ctx context.Context, req *openslackv1.ExecRequest) (*openslackv1.ExecResponse, error) {
+	name := req.GetCommand()
+	op := d.s.registry.Get(name)
+	if op == nil {
+		return nil, status.Errorf(codes.NotFound, "unknown op %q", name)
+	}
+	if d.s.registry.RiskOfCall(op, req.GetArgs()) == ops.RiskHigh {
+		return nil, status.Errorf(codes.PermissionDenied, "/%s needs approval and cannot run from the API", name)
+	}
+
+	d.s.logger.Info("grpc api op", "op", name)
+	output, err := d.s.exec.Exec(ctx, d.s.cfg.ChatID, name, req.GetArgs())
+	if err != nil {
+		return &openslackv1.ExecResponse{Output: output, Error: err.Error()}, nil
+	}
+	return &openslackv1.ExecResponse{Output: output}, nil
+}
+
+type taskList struct {
+	openslackv1.UnimplementedTasksServer
+	s *Server
+}
+
+func (t *taskList) CreateTask(_ context.Context, req *openslackv1.CreateTaskRequest) (*openslackv1.Task, error) {
+	task, err := t.s.tasks.CreateTomorrow(req.GetText())
+	if errors.Is(err, tasks.ErrEmptyTaskText) {
+		return nil, status.Error(codes.InvalidArgument, "text is required")
+	}
+	if err != nil {
+		return nil, t.s.fail("create task", err)
+	}
+	return taskMessage(task), nil
+}
+
+func (t *taskList) ListTasks(context.Context, *openslackv1.ListTasksRequest) (*openslackv1.ListTasksResponse, error) {
+	open, err := t.s.tasks.ListOpen()
+	if err != nil {
+		return nil, t.s.fail("list tasks", err)
+	}
+	out := &openslackv1.ListTasksResponse{}
+	for _, task := range open {
+		out.Tasks = append(out.Tasks, taskMessage(task))
+	}
+	return out, nil
+}
+
+func (t *taskList) CompleteTask(_ context.Context, req *openslackv1.CompleteTaskRequest) (*openslackv1.CompleteTaskResponse, error) {
+	id := req.GetId()
+	if id <= 0 || int64(int(id)) != id {
+		return nil, status.Error(codes.InvalidArgument, "task id must be a positive integer")
+	}
+	st, err := t.s.tasks.Complete(int(id))
+	if err != nil {
+		return nil, t.s.fail("complete task", err)
+	}
+	switch st {
+	case tasks.CompleteUpdated:
+		return &openslackv1.CompleteTaskResponse{Status: "done"}, nil
+	case tasks.CompleteAlreadyDone:
+		return &openslackv1.CompleteTaskResponse{Status: "already_done"}, nil
+	default:
+		return &openslackv1.CompleteTaskResponse{Status: "not_found"}, nil
+	}
+}
+
+func taskMessage(task tasks.Task) *openslackv1.Task {
+	return &openslackv1.Task{Id: int64(task.ID), Text: task.Text, StartDate: task.StartDate}
+}
+
+func (s *Server) fail(what string, err error) error {
+	s.logger.Error("grpc api: "+what+" failed", "error", err)
+	return status.Error(codes.Internal, err.Error())
+}
@@ -0,0 +1,342 @@
+package grpcapi
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"runtime"
+	"sync"
+	"testing"
+	"time"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+
+	openslackv1 "github.com/jdelaire/openslack/api/openslack/v1"
+	"github.com/jdelaire/openslack/core"
+	"github.com/jdelaire/openslack/core/ops"
+	"github.com/jdelaire/openslack/internal/tasks"
+)
+
+type echoOp struct {
+	name string
+	risk ops.RiskLevel
+}
+
+func (o *echoOp) Name() string                                           { return o.name }
+func (o *echoOp) Description() string                                    { return "Echo args" }
+func (o *echoOp) Risk() ops.RiskLevel                                    { return o.risk }
+func (o *echoOp) Execute(_ context.Context, args string) (string, error) { return args, nil }
+
+type recordExec struct {
+	mu     sync.Mutex
+	chatID int64
+	calls  []string
+	err    error
+}
+
+func (e *recordExec) Exec(_ context.Context, chatID int64, cmd, args string) (string, error) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	e.chatID = chatID
+	e.calls = append(e.calls, cmd+" "+args)
+	return "ran " + args, e.err
+}
+
+func (e *recordExec) recorded() (int64, []string) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	return e.chatID, append([]string(nil), e.calls...)
+}
+
+type recordNotifier struct {
+	mu   sync.Mutex
+	sent []string
+}
+
+func (n *recordNotifier) Name() string { return "record" }
+
+func (n *recordNotifier) Send(_ context.Context, msg core.Notification) error {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+	n.sent = append(n.sent, msg.Text)
+	return nil
+}
+
+func (n *recordNotifier) texts() []string {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+	return append([]string(nil), n.sent...)
+}
+
+var testNow = time.Date(2026, 3, 1, 12, 0, 0, 0, time.UTC)
+
+// startServer starts s on a free port and returns a connection to it.
+func startServer(t *testing.T, s *Server) *grpc.ClientConn {
+	t.Helper()
+	ctx, cancel := context.WithCancel(context.Background())
+	t.Cleanup(cancel)
+	if err := s.Start(ctx); err != nil {
+		t.Fatalf("start: %v", err)
+	}
+	t.Cleanup(s.Shutdown)
+	conn, err := grpc.NewClient(s.Addr(), grpc.WithTransportCredentials(insecure.NewCredentials()))
+	if err != nil {
+		t.Fatalf("dial: %v", err)
+	}
+	t.Cleanup(func() { conn.Close() })
+	return conn
+}
+
+func newTestServer(t *testing.T) (*Server, *recordExec, *recordNotifier) {
+	t.Helper()
+	s, err := NewServer(&Config{Addr: "127.0.0.1:0", ChatID: 42}, "secret", nil)
+	if err != nil {
+		t.Fatalf("new server: %v", err)
+	}
+	reg := ops.NewRegistry()
+	reg.Register(&echoOp{name: "echo", risk: ops.RiskLow})
+	reg.Register(&echoOp{name: "reboot", risk: ops.RiskHigh})
+	exec := &recordExec{}
+
+	notifier := &recordNotifier{}
+	notifiers := core.NewRegistry()
+	notifiers.Register(notifier)
+	socket := core.NewServer(filepath.Join(t.TempDir(), "test.sock"), notifiers, slog.Default())
+
+	svc := tasks.NewTaskService(tasks.NewStore(filepath.Join(t.TempDir(), "tasks.json"))).
+		WithClock(func() time.Time { return testNow })
+	s.WithNotifications(socket).WithDispatch(reg, exec).WithTasks(svc)
+	return s, exec, notifier
+}
+
+func authed() context.Context {
+	return metadata.AppendToOutgoingContext(context.Background(), "authorization", "Bearer secret")
+}
+
+func TestNewServerNeedsToken(t *testing.T) {
+	if _, err := NewServer(nil, "", nil); err == nil {
+		t.Error("expected error for empty token")
+	}
+	if _, err := NewServer(&Config{Addr: "0.0.0.0:8790"}, "secret", nil); err == nil {
+		t.Error("expected error for non-loopback addr")
+	}
+}
+
+func TestAuthorization(t *testing.T) {
+	s, _, _ := newTestServer(t)
+	client := openslackv1.NewTasksClient(startServer(t, s))
+
+	for _, ctx := range []context.Context{
+		context.Background(),
+		metadata.AppendToOutgoingContext(context.Background(), "authorization", "Bearer wrong"),
+		metadata.AppendToOutgoingContext(context.Background(), "authorization", "secret"),
+	} {
+		_, err := client.ListTasks(ctx, &openslackv1.ListTasksRequest{})
+		if status.Code(err) != codes.Unauthenticated {
+			t.Errorf("got %v, want Unauthenticated", err)
+		}
+	}
+	if _, err := client.ListTasks(authed(), &openslackv1.ListTasksRequest{}); err != nil {
+		t.Errorf("authorized call: %v", err)
+	}
+}
+
+func TestNotify(t *testing.T) {
+	s, _, notifier := newTestServer(t)
+	client := openslackv1.NewNotificationsClient(startServer(t, s))
+
+	if _, err := client.Notify(authed(), &openslackv1.NotifyRequest{Text: "backup done"}); err != nil {
+		t.Fatalf("notify: %v", err)
+	}
+	if got := notifier.texts(); len(got) != 1 || got[0] != "backup done" {
+		t.Errorf("sent %q", got)
+	}
+
+	_, err := client.Notify(authed(), &openslackv1.NotifyRequest{})
+	if status.Code(err) != codes.InvalidArgument {
+		t.Errorf("empty notify: got %v, want InvalidArgument", err)
+	}
+	_, err = client.Notify(authed(), &openslackv1.NotifyRequest{Template: "daily"})
+	if status.Code(err) != codes.Unknown || status.Convert(err).Message() != "templates not configured" {
+		t.Errorf("template notify: got %v", err)
+	}
+	_, err = client.AckStatus(authed(), &openslackv1.AckStatusRequest{Id: "x"})
+	if status.Code(err) != codes.Unknown {
+		t.Errorf("ack status without tracking: got %v, want Unknown", err)
+	}
+}
+
+func TestExec(t *testing.T) {
+	s, exec, _ := newTestServer(t)
+	client := openslackv1.NewDispatchClient(startServer(t, s))
+
+	resp, err := client.Exec(authed(), &openslackv1.ExecRequest{Command: "echo", Args: "hi"})
+	if err != nil {
+		t.Fatalf("exec: %v", err)
+	}
+	if resp.GetOutput() != "ran hi" || resp.GetError() != "" {
+		t.Errorf("got %+v", resp)
+	}
+	if chatID, _ := exec.recorded(); chatID != 42 {
+		t.Errorf("ran as chat %d, want 42", chatID)
+	}
+
+	_, err = client.Exec(authed(), &openslackv1.ExecRequest{Command: "reboot"})
+	if status.Code(err) != codes.PermissionDenied {
+		t.Errorf("high-risk op: got %v, want PermissionDenied", err)
+	}
+	_, err = client.Exec(authed(), &openslackv1.ExecRequest{Command: "nope"})
+	if status.Code(err) != codes.NotFound {
+		t.Errorf("unknown op: got %v, want NotFound", err)
+	}
+	if _, calls := exec.recorded(); len(calls) != 1 {
+		t.Errorf("refused ops reached exec: %q", calls)
+	}
+
+	exec.mu.Lock()
+	exec.err = errors.New("exit status 1")
+	exec.mu.Unlock()
+	resp, err = client.Exec(authed(), &openslackv1.ExecRequest{Command: "echo", Args: "partial"})
+	if err != nil {
+		t.Fatalf("failed op: %v", err)
+	}
+	if resp.GetOutput() != "ran partial" || resp.GetError() != "exit status 1" {
+		t.Errorf("failed op: got %+v", resp)
+	}
+}
+
+func TestTasks(t *testing.T) {
+	s, _, _ := newTestServer(t)
+	client := openslackv1.NewTasksClient(startServer(t, s))
+
+	task, err := client.CreateTask(authed(), &openslackv1.CreateTaskRequest{Text: "buy milk"})
+	if err != nil {
+		t.Fatalf("create: %v", err)
+	}
+	if task.GetText() != "buy milk" || task.GetStartDate() != "2026-03-02" {
+		t.Errorf("created %+v", task)
+	}
+	_, err = client.CreateTask(authed(), &openslackv1.CreateTaskRequest{Text: "  "})
+	if status.Code(err) != codes.InvalidArgument {
+		t.Errorf("empty text: got %v, want InvalidArgument", err)
+	}
+
+	list, err := client.ListTasks(authed(), &openslackv1.ListTasksRequest{})
+	if err != nil {
+		t.Fatalf("list: %v", err)
+	}
+	if len(list.GetTasks()) != 1 || list.GetTasks()[0].GetId() != task.GetId() {
+		t.Errorf("listed %+v", list.GetTasks())
+	}
+
+	for _, want := range []string{"done", "already_done"} {
+		resp, err := client.CompleteTask(authed(), &openslackv1.CompleteTaskRequest{Id: task.GetId()})
+		if err != nil {
+			t.Fatalf("complete: %v", err)
+		}
+		if resp.GetStatus() != want {
+			t.Errorf("status %q, want %q", resp.GetStatus(), want)
+		}
+	}
+	resp, err := client.CompleteTask(authed(), &openslackv1.CompleteTaskRequest{Id: 99})
+	if err != nil || resp.GetStatus() != "not_found" {
+		t.Errorf("unknown task: got %v, %v", resp, err)
+	}
+	_, err = client.CompleteTask(authed(), &openslackv1.CompleteTaskRequest{Id: -1})
+	if status.Code(err) != codes.InvalidArgument {
+		t.Errorf("negative id: got %v, want InvalidArgument", err)
+	}
+}
+
+func TestServicesNotEnabled(t *testing.T) {
+	s, err := NewServer(&Config{Addr: "127.0.0.1:0"}, "secret", nil)
+	if err != nil {
+		t.Fatalf("new server: %v", err)
+	}
+	client := openslackv1.NewDispatchClient(startServer(t, s))
+	_, err = client.Exec(authed(), &openslackv1.ExecRequest{Command: "echo"})
+	if status.Code(err) != codes.Unimplemented {
+		t.Errorf("got %v, want Unimplemented", err)
+	}
+}
+
+func TestLoadConfig(t *testing.T) {
+	dir := t.TempDir()
+	cfg, err := LoadConfig(filepath.Join(dir, "missing.json"))
+	if cfg != nil || err != nil {
+		t.Errorf("missing file: got %v, %v", cfg, err)
+	}
+
+	path := filepath.Join(dir, "grpc_api.json")
+	os.WriteFile(path, []byte(`{"chat_id": 7}`), 0o600)
+	cfg, err = LoadConfig(path)
+	if err != nil {
+		t.Fatalf("load: %v", err)
+	}
+	if cfg.Addr != DefaultAddr || cfg.ChatID != 7 {
+		t.Errorf("got %+v", cfg)
+	}
+
+	os.WriteFile(path, []byte(`{"addr": "192.168.1.5:8790"}`), 0o600)
+	if _, err := LoadConfig(path); err == nil {
+		t.Error("expected error for non-loopback addr")
+	}
+	os.WriteFile(path, []byte(`{"addr": "unix:"}`), 0o600)
+	if _, err := LoadConfig(path); err == nil {
+		t.Error("expected error for empty socket path")
+	}
+
+	home, err := os.UserHomeDir()
+	if err != nil {
+		t.Skip("no home directory")
+	}
+	os.WriteFile(path, []byte(`{"addr": "unix:~/.openslack/grpc.sock"}`), 0o600)
+	cfg, err = LoadConfig(path)
+	if err != nil {
+		t.Fatalf("load socket: %v", err)
+	}
+	if want := "unix:" + filepath.Join(home, ".openslack", "grpc.sock"); cfg.Addr != want {
+		t.Errorf("addr %q, want %q", cfg.Addr, want)
+	}
+}
+
+func TestUnixSocket(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("Windows ignores socket file modes")
+	}
+	path := filepath.Join(t.TempDir(), "grpc.sock")
+	s, err := NewServer(&Config{Addr: "unix:" + path}, "secret", nil)
+	if err != nil {
+		t.Fatalf("new server: %v", err)
+	}
+	s.WithTasks(tasks.NewTaskService(tasks.NewStore(filepath.Join(t.TempDir(), "tasks.json"))))
+	// A socket left by a crashed run is replaced.
+	os.WriteFile(path, nil, 0o600)
+	client := openslackv1.NewTasksClient(startServer(t, s))
+
+	if s.Addr() != "unix:"+path {
+		t.Errorf("addr %q", s.Addr())
+	}
+	info, err := os.Stat(path)
+	if err != nil {
+		t.Fatalf("stat socket: %v", err)
+	}
+	if perm := info.Mode().Perm(); perm != 0o600 {
+		t.Errorf("socket mode %o, want 600", perm)
+	}
+	if _, err := client.ListTasks(authed(), &openslackv1.ListTasksRequest{}); err != nil {
+		t.Errorf("list over socket: %v", err)
+	}
+
+	other, _ := NewServer(&Config{Addr: "unix:" + path}, "secret", nil)
+	if err := other.Start(context.Background()); err == nil {
+		other.Shutdown()
+		t.Error("second server started on a live socket")
+	}
+}
@@ -0,0 +1,107 @@
+package hosts
+
+import (
+	"encoding/json"
+	"fmt"
+	"net"
+	"os"
+	"strings"
+
+	"github.com/jdelaire/openslack/internal/homedir"
+)
+
+// Default limits.
+const (
+	DefaultPort      = "22"
+	DefaultTimeoutMs = 10000
+)
+
+// Config is the top-level hosts configuration.
+type Config struct {
+	Hosts      map[string]HostConfig `json:"hosts"`
+	Groups     map[string][]string   `json:"groups"`
+	KnownHosts string                `json:"known_hosts"`
+}
+
+// HostConfig defines how to reach a single remote host over SSH.
+type HostConfig struct {
+	Address   string `json:"address"`
+	User      string `json:"user"`
+	Key       string `json:"key"`
+	TimeoutMs int    `json:"timeout_ms"`
+}
+
+// LoadConfig reads and validates a hosts config file.
+// Returns nil, nil if the file does not exist.
+func LoadConfig(path string) (*Config, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("read hosts config: %w", err)
+	}
+
+	var cfg Config
+	if err := json.Unmarshal(data, &cfg); err != nil {
+		return nil, fmt.Errorf("parse hosts config: %w", err)
+	}
+
+	if err := validateConfig(&cfg); err != nil {
+		return nil, err
+	}
+
+	applyDefaults(&cfg)
+	return &cfg, nil
+}
+
+func validateConfig(cfg *Config) error {
+	for name, hc := range cfg.Hosts {
+		if name == "" {
+			return fmt.Errorf("host name cannot be empty")
+		}
+		if strings.ContainsAny(name, " \t") {
+			return fmt.Errorf("host name %q must not contain whitespace", name)
+		}
+		if hc.Address == "" {
+			return fmt.Errorf("host %q missing address", name)
+		}
+		if hc.User == "" {
+			return fmt.Errorf("host %q missing user", name)
+		}
+		if hc.Key == "" {
+			return fmt.Errorf("host %q missing key path", name)
+		}
+	}
+	for group, members := range cfg.Groups {
+		if group == "" {
+			return fmt.Errorf("group name cannot be empty")
+		}
+		if len(members) == 0 {
+			return fmt.Errorf("group %q has no hosts", group)
+		}
+		for _, m := range members {
+			if _, ok := cfg.Hosts[m]; !ok {
+				return fmt.Errorf("group %q references unknown host %q", group, m)
+			}
+		}
+	}
+	return nil
+}
+
+func applyDefaults(cfg *Config) {
+	for name, hc := range cfg.Hosts {
+		if _, _, err := net.SplitHostPort(hc.Address); err != nil {
+			hc.Address = net.JoinHostPort(hc.Address, DefaultPort)
+		}
+		if hc.TimeoutMs <= 0 {
+			hc.TimeoutMs = DefaultTimeoutMs
+		}
+		hc.Key = homedir.Expand(hc.Key)
+		cfg.Hosts[name] = hc
+	}
+	if cfg.KnownHosts == "" {
+		cfg.KnownHosts = "~/.ssh/known_hosts"
+	}
+	cfg.KnownHosts = homedir.Expand(cfg.KnownHosts)
+}
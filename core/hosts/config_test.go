@@ -0,0 +1,129 @@
+package hosts
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestLoadConfigValid(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "hosts.json")
+	data := `{
+		"hosts": {
+			"nas": {"address": "nas.local:2222", "user": "admin", "key": "/keys/nas", "timeout_ms": 3000}
+		},
+		"known_hosts": "/etc/ssh/known_hosts"
+	}`
+	os.WriteFile(path, []byte(data), 0644)
+
+	cfg, err := LoadConfig(path)
+	if err != nil {
+		t.Fatalf("LoadConfig: %v", err)
+	}
+	hc, ok := cfg.Hosts["nas"]
+	if !ok {
+		t.Fatal("expected nas host")
+	}
+	if hc.Address != "nas.local:2222" {
+		t.Errorf("address = %q", hc.Address)
+	}
+	if hc.User != "admin" || hc.Key != "/keys/nas" {
+		t.Errorf("user/key = %q/%q", hc.User, hc.Key)
+	}
+	if hc.TimeoutMs != 3000 {
+		t.Errorf("timeout_ms = %d", hc.TimeoutMs)
+	}
+	if cfg.KnownHosts != "/etc/ssh/known_hosts" {
+		t.Errorf("known_hosts = %q", cfg.KnownHosts)
+	}
+}
+
+func TestLoadConfigDefaults(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "hosts.json")
+	os.WriteFile(path, []byte(`{"hosts":{"pi":{"address":"10.0.0.5","user":"pi","key":"~/.ssh/id_ed25519"}}}`), 0644)
+
+	cfg, err := LoadConfig(path)
+	if err != nil {
+		t.Fatalf("LoadConfig: %v", err)
+	}
+	hc := cfg.Hosts["pi"]
+	if hc.Address != "10.0.0.5:22" {
+		t.Errorf("address = %q, want default port", hc.Address)
+	}
+	if hc.TimeoutMs != DefaultTimeoutMs {
+		t.Errorf("timeout_ms = %d, want %d", hc.TimeoutMs, DefaultTimeoutMs)
+	}
+	if strings.HasPrefix(hc.Key, "~") {
+		t.Errorf("key path not expanded: %q", hc.Key)
+	}
+	if !strings.HasSuffix(cfg.KnownHosts, filepath.Join(".ssh", "known_hosts")) {
+		t.Errorf("known_hosts = %q", cfg.KnownHosts)
+	}
+}
+
+func TestLoadConfigGroups(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "hosts.json")
+	data := `{
+		"hosts": {
+			"nas": {"address": "nas", "user": "a", "key": "k"},
+			"pi": {"address": "pi", "user": "a", "key": "k"}
+		},
+		"groups": {"all": ["nas", "pi"]}
+	}`
+	os.WriteFile(path, []byte(data), 0644)
+
+	cfg, err := LoadConfig(path)
+	if err != nil {
+		t.Fatalf("LoadConfig: %v", err)
+	}
+	pool := NewPool(cfg, testLogger())
+	if got := pool.Group("all"); len(got) != 2 || got[0] != "nas" || got[1] != "pi" {
+		t.Errorf("Group(all) = %v", got)
+	}
+	if got := pool.Group("none"); got != nil {
+		t.Errorf("Group(none) = %v, want nil", got)
+	}
+}
+
+func TestLoadConfigMissingFile(t *testing.T) {
+	cfg, err := LoadConfig("/nonexistent/hosts.json")
+	if err != nil {
+		t.Fatalf("expected nil error, got: %v", err)
+	}
+	if cfg != nil {
+		t.Fatal("expected nil config")
+	}
+}
+
+func TestLoadConfigInvalid(t *testing.T) {
+	tests := []struct {
+		name    string
+		data    string
+		wantErr string
+	}{
+		{"malformed", `not json`, "parse hosts config"},
+		{"missing address", `{"hosts":{"nas":{"user":"a","key":"k"}}}`, "missing address"},
+		{"missing user", `{"hosts":{"nas":{"address":"h","key":"k"}}}`, "missing user"},
+		{"missing key", `{"hosts":{"nas":{"address":"h","user":"a"}}}`, "missing key path"},
+		{"empty group", `{"hosts":{"nas":{"address":"h","user":"a","key":"k"}},"groups":{"all":[]}}`, "has no hosts"},
+		{"unknown group member", `{"hosts":{"nas":{"address":"h","user":"a","key":"k"}},"groups":{"all":["nas","pi"]}}`, "unknown host \"pi\""},
+		{"whitespace name", `{"hosts":{"my nas":{"address":"h","user":"a","key":"k"}}}`, "must not contain whitespace"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			path := filepath.Join(t.TempDir(), "hosts.json")
+			os.WriteFile(path, []byte(tt.data), 0644)
+
+			_, err := LoadConfig(path)
+			if err == nil {
+				t.Fatal("expected error")
+			}
+			if !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("error = %v, want %q", err, tt.wantErr)
+			}
+		})
+	}
+}
@@ -0,0 +1,235 @@
+package hosts
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"net"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	"golang.org/x/crypto/ssh"
+	"golang.org/x/crypto/ssh/knownhosts"
+)
+
+// Pool keeps one SSH connection per host and runs each command in a fresh
+// session on that connection.
+type Pool struct {
+	cfg    *Config
+	logger *slog.Logger
+
+	mu       sync.Mutex
+	conns    map[string]*hostConn
+	hostKeys ssh.HostKeyCallback
+}
+
+// hostConn guards dialing for a single host so that slow hosts do not block
+// each other.
+type hostConn struct {
+	mu     sync.Mutex
+	client *ssh.Client
+}
+
+// NewPool creates a connection pool for the configured hosts. No
+// connections are opened until the first Run.
+func NewPool(cfg *Config, logger *slog.Logger) *Pool {
+	return &Pool{
+		cfg:    cfg,
+		logger: logger,
+		conns:  make(map[string]*hostConn),
+	}
+}
+
+// Has reports whether a host with the given name is configured.
+func (p *Pool) Has(host string) bool {
+	_, ok := p.cfg.Hosts[host]
+	return ok
+}
+
+// Group returns the host names in a group, or nil if the group is unknown.
+func (p *Pool) Group(name string) []string {
+	return p.cfg.Groups[name]
+}
+
+// Run executes command on the named host and returns its combined output.
+// If workdir is set, the command runs from that directory. The host's
+// timeout covers both connecting and running the command.
+func (p *Pool) Run(ctx context.Context, host, command, workdir string) (string, error) {
+	hc, ok := p.cfg.Hosts[host]
+	if !ok {
+		return "", fmt.Errorf("unknown host %q", host)
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, time.Duration(hc.TimeoutMs)*time.Millisecond)
+	defer cancel()
+
+	if workdir != "" {
+		command = "cd " + shellQuote(workdir) + " && " + command
+	}
+
+	session, err := p.session(ctx, host, hc)
+	if err != nil {
+		return "", err
+	}
+	defer session.Close()
+
+	type runResult struct {
+		out []byte
+		err error
+	}
+	ch := make(chan runResult, 1)
+	go func() {
+		out, err := session.CombinedOutput(command)
+		ch <- runResult{out: out, err: err}
+	}()
+
+	select {
+	case <-ctx.Done():
+		session.Signal(ssh.SIGKILL)
+		return "", fmt.Errorf("host %q: command timed out", host)
+	case r := <-ch:
+		out := strings.TrimSpace(string(r.out))
+		if r.err != nil {
+			return out, fmt.Errorf("host %q: %w", host, r.err)
+		}
+		return out, nil
+	}
+}
+
+// session opens a session on the pooled client for host, dialing if needed.
+// A pooled client that can no longer open sessions is dropped and redialed
+// once.
+func (p *Pool) session(ctx context.Context, host string, hc HostConfig) (*ssh.Session, error) {
+	for attempt := 0; attempt < 2; attempt++ {
+		client, err := p.client(ctx, host, hc)
+		if err != nil {
+			return nil, err
+		}
+		session, err := client.NewSession()
+		if err == nil {
+			return session, nil
+		}
+		p.logger.Warn("ssh session failed, reconnecting", "host", host, "error", err)
+		p.drop(host, client)
+	}
+	return nil, fmt.Errorf("host %q: could not open session", host)
+}
+
+func (p *Pool) client(ctx context.Context, host string, hc HostConfig) (*ssh.Client, error) {
+	hostKeys, err := p.knownHosts()
+	if err != nil {
+		return nil, err
+	}
+
+	p.mu.Lock()
+	hconn, ok := p.conns[host]
+	if !ok {
+		hconn = &hostConn{}
+		p.conns[host] = hconn
+	}
+	p.mu.Unlock()
+
+	hconn.mu.Lock()
+	defer hconn.mu.Unlock()
+
+	if hconn.client != nil {
+		return hconn.client, nil
+	}
+
+	signer, err := loadSigner(hc.Key)
+	if err != nil {
+		return nil, fmt.Errorf("host %q: %w", host, err)
+	}
+
+	clientCfg := &ssh.ClientConfig{
+		User:            hc.User,
+		Auth:            []ssh.AuthMethod{ssh.PublicKeys(signer)},
+		HostKeyCallback: hostKeys,
+		Timeout:         time.Duration(hc.TimeoutMs) * time.Millisecond,
+	}
+
+	var d net.Dialer
+	conn, err := d.DialContext(ctx, "tcp", hc.Address)
+	if err != nil {
+		return nil, fmt.Errorf("host %q: dial: %w", host, err)
+	}
+	if deadline, ok := ctx.Deadline(); ok {
+		conn.SetDeadline(deadline)
+	}
+	sshConn, chans, reqs, err := ssh.NewClientConn(conn, hc.Address, clientCfg)
+	if err != nil {
+		conn.Close()
+		return nil, fmt.Errorf("host %q: handshake: %w", host, err)
+	}
+	conn.SetDeadline(time.Time{})
+
+	hconn.client = ssh.NewClient(sshConn, chans, reqs)
+	p.logger.Info("ssh connected", "host", host, "address", hc.Address)
+	return hconn.client, nil
+}
+
+// knownHosts loads the known_hosts file on first use.
+func (p *Pool) knownHosts() (ssh.HostKeyCallback, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if p.hostKeys == nil {
+		cb, err := knownhosts.New(p.cfg.KnownHosts)
+		if err != nil {
+			return nil, fmt.Errorf("load known_hosts: %w", err)
+		}
+		p.hostKeys = cb
+	}
+	return p.hostKeys, nil
+}
+
+// drop closes and forgets a pooled client if it is still the current one.
+func (p *Pool) drop(host string, c *ssh.Client) {
+	p.mu.Lock()
+	hconn := p.conns[host]
+	p.mu.Unlock()
+
+	if hconn != nil {
+		hconn.mu.Lock()
+		if hconn.client == c {
+			hconn.client = nil
+		}
+		hconn.mu.Unlock()
+	}
+	c.Close()
+}
+
+// Close closes all pooled connections.
+func (p *Pool) Close() {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	for host, hconn := range p.conns {
+		hconn.mu.Lock()
+		if hconn.client != nil {
+			hconn.client.Close()
+			p.logger.Info("ssh disconnected", "host", host)
+		}
+		hconn.mu.Unlock()
+	}
+	p.conns = make(map[string]*hostConn)
+}
+
+func loadSigner(path string) (ssh.Signer, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("read key: %w", err)
+	}
+	signer, err := ssh.ParsePrivateKey(data)
+	if err != nil {
+		return nil, fmt.Errorf("parse key: %w", err)
+	}
+	return signer, nil
+}
+
+// shellQuote wraps s in single quotes for the remote shell.
+func shellQuote(s string) string {
+	return "'" + strings.ReplaceAll(s, "'", `'\''`) + "'"
+}
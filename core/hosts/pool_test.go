@@ -0,0 +1,256 @@
+package hosts
+
+import (
+	"context"
+	"crypto/ed25519"
+	"crypto/rand"
+	"encoding/binary"
+	"encoding/pem"
+	"errors"
+	"io"
+	"log/slog"
+	"net"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"golang.org/x/crypto/ssh"
+	"golang.org/x/crypto/ssh/knownhosts"
+)
+
+func testLogger() *slog.Logger {
+	return slog.New(slog.NewTextHandler(io.Discard, nil))
+}
+
+// testServer is a minimal SSH server that answers exec requests with
+// "ran: <command>". Commands containing "fail" exit with status 3 and
+// commands containing "hang" never finish.
+type testServer struct {
+	addr    string
+	hostKey ssh.PublicKey
+	conns   atomic.Int32
+}
+
+func startTestServer(t *testing.T, clientKey ssh.PublicKey) *testServer {
+	t.Helper()
+
+	_, hostPriv, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatalf("host key: %v", err)
+	}
+	hostSigner, err := ssh.NewSignerFromKey(hostPriv)
+	if err != nil {
+		t.Fatalf("host signer: %v", err)
+	}
+
+	cfg := &ssh.ServerConfig{
+		PublicKeyCallback: func(_ ssh.ConnMetadata, key ssh.PublicKey) (*ssh.Permissions, error) {
+			if string(key.Marshal()) == string(clientKey.Marshal()) {
+				return nil, nil
+			}
+			return nil, io.EOF
+		},
+	}
+	cfg.AddHostKey(hostSigner)
+
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("listen: %v", err)
+	}
+	t.Cleanup(func() { ln.Close() })
+
+	srv := &testServer{addr: ln.Addr().String(), hostKey: hostSigner.PublicKey()}
+	go func() {
+		for {
+			conn, err := ln.Accept()
+			if err != nil {
+				return
+			}
+			srv.conns.Add(1)
+			go srv.serve(conn, cfg)
+		}
+	}()
+	return srv
+}
+
+func (s *testServer) serve(conn net.Conn, cfg *ssh.ServerConfig) {
+	_, chans, reqs, err := ssh.NewServerConn(conn, cfg)
+	if err != nil {
+		conn.Close()
+		return
+	}
+	go ssh.DiscardRequests(reqs)
+	for nc := range chans {
+		ch, chReqs, err := nc.Accept()
+		if err != nil {
+			continue
+		}
+		go func() {
+			for req := range chReqs {
+				if req.Type != "exec" {
+					req.Reply(false, nil)
+					continue
+				}
+				n := binary.BigEndian.Uint32(req.Payload)
+				command := string(req.Payload[4 : 4+n])
+				req.Reply(true, nil)
+				if strings.Contains(command, "hang") {
+					continue
+				}
+				io.WriteString(ch, "ran: "+command+"\n")
+				status := uint32(0)
+				if strings.Contains(command, "fail") {
+					status = 3
+				}
+				ch.SendRequest("exit-status", false, ssh.Marshal(struct{ Status uint32 }{status}))
+				ch.Close()
+			}
+		}()
+	}
+}
+
+// writeClientKey writes a fresh private key to dir and returns its path and
+// public half.
+func writeClientKey(t *testing.T, dir string) (string, ssh.PublicKey) {
+	t.Helper()
+	pub, priv, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatalf("client key: %v", err)
+	}
+	block, err := ssh.MarshalPrivateKey(priv, "")
+	if err != nil {
+		t.Fatalf("marshal key: %v", err)
+	}
+	path := filepath.Join(dir, "id_ed25519")
+	if err := os.WriteFile(path, pem.EncodeToMemory(block), 0600); err != nil {
+		t.Fatalf("write key: %v", err)
+	}
+	sshPub, err := ssh.NewPublicKey(pub)
+	if err != nil {
+		t.Fatalf("public key: %v", err)
+	}
+	return path, sshPub
+}
+
+func writeKnownHosts(t *testing.T, dir, addr string, key ssh.PublicKey) string {
+	t.Helper()
+	path := filepath.Join(dir, "known_hosts")
+	line := knownhosts.Line([]string{knownhosts.Normalize(addr)}, key) + "\n"
+	if err := os.WriteFile(path, []byte(line), 0600); err != nil {
+		t.Fatalf("write known_hosts: %v", err)
+	}
+	return path
+}
+
+func newTestPool(t *testing.T) (*Pool, *testServer) {
+	t.Helper()
+	dir := t.TempDir()
+	keyPath, clientPub := writeClientKey(t, dir)
+	srv := startTestServer(t, clientPub)
+
+	cfg := &Config{
+		Hosts: map[string]HostConfig{
+			"nas": {Address: srv.addr, User: "admin", Key: keyPath, TimeoutMs: 2000},
+		},
+		KnownHosts: writeKnownHosts(t, dir, srv.addr, srv.hostKey),
+	}
+	pool := NewPool(cfg, testLogger())
+	t.Cleanup(pool.Close)
+	return pool, srv
+}
+
+func TestPoolRun(t *testing.T) {
+	pool, _ := newTestPool(t)
+
+	out, err := pool.Run(context.Background(), "nas", "uptime", "")
+	if err != nil {
+		t.Fatalf("Run: %v", err)
+	}
+	if out != "ran: uptime" {
+		t.Errorf("output = %q", out)
+	}
+}
+
+func TestPoolRunWorkDir(t *testing.T) {
+	pool, _ := newTestPool(t)
+
+	out, err := pool.Run(context.Background(), "nas", "ls", "/srv/it's here")
+	if err != nil {
+		t.Fatalf("Run: %v", err)
+	}
+	want := `ran: cd '/srv/it'\''s here' && ls`
+	if out != want {
+		t.Errorf("output = %q, want %q", out, want)
+	}
+}
+
+func TestPoolReusesConnection(t *testing.T) {
+	pool, srv := newTestPool(t)
+
+	for i := 0; i < 3; i++ {
+		if _, err := pool.Run(context.Background(), "nas", "true", ""); err != nil {
+			t.Fatalf("Run %d: %v", i, err)
+		}
+	}
+	if got := srv.conns.Load(); got != 1 {
+		t.Errorf("connections = %d, want 1", got)
+	}
+}
+
+func TestPoolRunExitStatus(t *testing.T) {
+	pool, _ := newTestPool(t)
+
+	out, err := pool.Run(context.Background(), "nas", "fail now", "")
+	if err == nil {
+		t.Fatal("expected error for non-zero exit")
+	}
+	var exitErr *ssh.ExitError
+	if !errors.As(err, &exitErr) || exitErr.ExitStatus() != 3 {
+		t.Errorf("error = %v, want exit status 3", err)
+	}
+	if out != "ran: fail now" {
+		t.Errorf("output = %q", out)
+	}
+}
+
+func TestPoolRunTimeout(t *testing.T) {
+	pool, _ := newTestPool(t)
+	hc := pool.cfg.Hosts["nas"]
+	hc.TimeoutMs = 200
+	pool.cfg.Hosts["nas"] = hc
+
+	start := time.Now()
+	_, err := pool.Run(context.Background(), "nas", "hang", "")
+	if err == nil || !strings.Contains(err.Error(), "timed out") {
+		t.Fatalf("error = %v, want timeout", err)
+	}
+	if time.Since(start) > 2*time.Second {
+		t.Error("timeout not enforced")
+	}
+}
+
+func TestPoolUnknownHost(t *testing.T) {
+	pool, _ := newTestPool(t)
+
+	_, err := pool.Run(context.Background(), "nope", "true", "")
+	if err == nil || !strings.Contains(err.Error(), "unknown host") {
+		t.Fatalf("error = %v, want unknown host", err)
+	}
+}
+
+func TestPoolRejectsUnknownHostKey(t *testing.T) {
+	pool, _ := newTestPool(t)
+
+	// Replace known_hosts with an unrelated key for the same address.
+	otherPub, _, _ := ed25519.GenerateKey(rand.Reader)
+	otherKey, _ := ssh.NewPublicKey(otherPub)
+	writeKnownHosts(t, filepath.Dir(pool.cfg.KnownHosts), pool.cfg.Hosts["nas"].Address, otherKey)
+
+	_, err := pool.Run(context.Background(), "nas", "true", "")
+	if err == nil || !strings.Contains(err.Error(), "handshake") {
+		t.Fatalf("error = %v, want handshake failure", err)
+	}
+}
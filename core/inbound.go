@@ -2,13 +2,19 @@ package core
 
 import "time"
 
-// InboundMessage represents a message received from Telegram.
+// InboundMessage represents a message received from Telegram. A button
+// press is delivered as a message with CallbackID set, Text holding the
+// button's data and MessageID the message the button was on. ReplyTo is
+// the message a reply was sent to.
 type InboundMessage struct {
-	UpdateID  int64
-	ChatID    int64
-	UserID    int64
-	Text      string
-	Timestamp time.Time
+	UpdateID   int64
+	MessageID  int64
+	ChatID     int64
+	UserID     int64
+	Text       string
+	Timestamp  time.Time
+	CallbackID string
+	ReplyTo    int64
 }
 
 // MessageHandler processes an inbound message.
@@ -0,0 +1,281 @@
+package core
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"time"
+)
+
+// JSON-RPC 2.0 error codes. Delivery and configuration failures use the
+// implementation-defined rpcServerError.
+const (
+	rpcParseError     = -32700
+	rpcInvalidRequest = -32600
+	rpcMethodNotFound = -32601
+	rpcInvalidParams  = -32602
+	rpcServerError    = -32000
+)
+
+// RPCRequest is a JSON-RPC 2.0 request. Method is an action name and
+// Params its payload. Without an ID it is a notification and gets no
+// response.
+type RPCRequest struct {
+	JSONRPC string          `json:"jsonrpc"`
+	Method  string          `json:"method"`
+	Params  json.RawMessage `json:"params,omitempty"`
+	ID      json.RawMessage `json:"id,omitempty"`
+}
+
+// RPCResponse is a JSON-RPC 2.0 response. Exactly one of Result or Error
+// is set.
+type RPCResponse struct {
+	JSONRPC string          `json:"jsonrpc"`
+	Result  *RPCResult      `json:"result,omitempty"`
+	Error   *RPCError       `json:"error,omitempty"`
+	ID      json.RawMessage `json:"id"`
+}
+
+// RPCResult carries the fields of a successful Response.
+type RPCResult struct {
+	ID      string     `json:"id,omitempty"`
+	Status  string     `json:"status,omitempty"`
+	AckedAt *time.Time `json:"acked_at,omitempty"`
+	Output  string     `json:"output,omitempty"`
+}
+
+// maxSniffBytes bounds how far isJSONRPC peeks for the first member name.
+const maxSniffBytes = 64
+
+// RPCError is a JSON-RPC 2.0 error object. Data carries the ID of a drop
+// kept in the inbox despite a failed delivery, or the output of a failed
+// dispatch.
+type RPCError struct {
+	Code    int        `json:"code"`
+	Message string     `json:"message"`
+	Data    *RPCResult `json:"data,omitempty"`
+}
+
+// isJSONRPC reports whether a connection speaks JSON-RPC: a batch, or an
+// object whose first member is not one of the native envelope's
+// "version", "action" and "payload". Only the leading bytes are peeked,
+// so a native request can still be decoded as it streams in.
+func isJSONRPC(br *bufio.Reader) bool {
+	for n := 1; n <= maxSniffBytes; n++ {
+		b, err := br.Peek(n)
+		if t := bytes.TrimLeft(b, " \t\r\n"); len(t) > 0 {
+			if t[0] != '{' {
+				return t[0] == '['
+			}
+			key := bytes.TrimLeft(t[1:], " \t\r\n")
+			if len(key) > 0 && key[0] != '"' {
+				return false
+			}
+			if len(key) > 1 {
+				if end := bytes.IndexByte(key[1:], '"'); end >= 0 {
+					switch string(key[1 : 1+end]) {
+					case "version", "action", "payload":
+						return false
+					}
+					return true
+				}
+			}
+		}
+		if err != nil {
+			return false
+		}
+	}
+	return false
+}
+
+// serveRPC answers a JSON-RPC request or batch, decoded in a single pass
+// as it arrives, under the same size limit as native requests. Batch
+// entries run in order once the batch has been read; a batch of
+// notifications gets no reply.
+func (s *Server) serveRPC(ctx context.Context, conn net.Conn, br *bufio.Reader) {
+	lr := &limitReader{r: br, n: MaxDropRequestBytes + 1}
+	dec := json.NewDecoder(lr)
+
+	if !isRPCBatch(br) {
+		var call RPCRequest
+		err := dec.Decode(&call)
+		if resp, failed := s.rpcReadError(err, lr); failed {
+			s.failRPC(conn, br, resp)
+			return
+		}
+		if resp, ok := s.callRPC(ctx, call, err); ok {
+			s.writeRPC(conn, resp)
+		}
+		return
+	}
+
+	type entry struct {
+		call RPCRequest
+		err  error
+	}
+	var batch []entry
+	_, err := dec.Token()
+	for err == nil && dec.More() {
+		if len(batch) == MaxBatchLen {
+			s.failRPC(conn, br, rpcError(nil, rpcInvalidRequest, fmt.Sprintf("batch exceeds %d requests", MaxBatchLen), nil))
+			return
+		}
+		var e entry
+		e.err = dec.Decode(&e.call)
+		if _, failed := s.rpcReadError(e.err, lr); failed {
+			err = e.err
+			break
+		}
+		batch = append(batch, e)
+	}
+	if err == nil {
+		_, err = dec.Token()
+	}
+	if resp, failed := s.rpcReadError(err, lr); failed {
+		s.failRPC(conn, br, resp)
+		return
+	}
+	if len(batch) == 0 {
+		s.writeRPC(conn, rpcError(nil, rpcInvalidRequest, "empty batch", nil))
+		return
+	}
+
+	var responses []RPCResponse
+	for _, e := range batch {
+		if resp, ok := s.callRPC(ctx, e.call, e.err); ok {
+			responses = append(responses, resp)
+		}
+	}
+	if len(responses) > 0 {
+		s.writeRPC(conn, responses)
+	}
+}
+
+// failRPC replies with resp to a request that could not be read in full.
+// What the client is still sending is drained first, so it reads the
+// error instead of a broken pipe.
+func (s *Server) failRPC(conn net.Conn, br *bufio.Reader, resp RPCResponse) {
+	io.Copy(io.Discard, io.LimitReader(br, MaxDropRequestBytes))
+	s.writeRPC(conn, resp)
+}
+
+// isRPCBatch reports whether the peeked request is a JSON array.
+func isRPCBatch(br *bufio.Reader) bool {
+	for n := 1; n <= maxSniffBytes; n++ {
+		b, err := br.Peek(n)
+		if t := bytes.TrimLeft(b, " \t\r\n"); len(t) > 0 {
+			return t[0] == '['
+		}
+		if err != nil {
+			return false
+		}
+	}
+	return false
+}
+
+// rpcReadError turns a decode error that leaves the rest of the request
+// unreadable into the reply for the whole connection: a read failure, a
+// request over the size limit, or malformed JSON. failed is false for nil
+// and for errors that only concern one request's fields, which callRPC
+// reports as an invalid request.
+func (s *Server) rpcReadError(err error, lr *limitReader) (resp RPCResponse, failed bool) {
+	if err == nil {
+		return RPCResponse{}, false
+	}
+	if lr.read > lr.limit() {
+		return rpcError(nil, rpcInvalidRequest, fmt.Sprintf("payload exceeds %d byte limit", lr.limit()), nil), true
+	}
+	var netErr net.Error
+	if errors.As(err, &netErr) {
+		return rpcError(nil, rpcParseError, "read error", nil), true
+	}
+	var typeErr *json.UnmarshalTypeError
+	if errors.As(err, &typeErr) {
+		return RPCResponse{}, false
+	}
+	return rpcError(nil, rpcParseError, "parse error: "+err.Error(), nil), true
+}
+
+// callRPC runs one JSON-RPC request; err is the error decoding it into
+// call, if any. ok is false for notifications.
+func (s *Server) callRPC(ctx context.Context, call RPCRequest, err error) (resp RPCResponse, ok bool) {
+	if err != nil {
+		return rpcError(nil, rpcInvalidRequest, "invalid request: "+err.Error(), nil), true
+	}
+	if !validRPCID(call.ID) {
+		return rpcError(nil, rpcInvalidRequest, "id must be a string, number or null", nil), true
+	}
+	notification := call.ID == nil
+	reply := func(r RPCResponse) (RPCResponse, bool) { return r, !notification }
+
+	if call.JSONRPC != "2.0" {
+		return reply(rpcError(call.ID, rpcInvalidRequest, `jsonrpc must be "2.0"`, nil))
+	}
+	switch call.Method {
+	case "notify", "drop", "ack-status", "dispatch":
+	case "":
+		return reply(rpcError(call.ID, rpcInvalidRequest, "method is required", nil))
+	default:
+		return reply(rpcError(call.ID, rpcMethodNotFound, fmt.Sprintf("method %q not found", call.Method), nil))
+	}
+
+	params := call.Params
+	if params == nil {
+		params = json.RawMessage("{}")
+	}
+	if t := bytes.TrimLeft(params, " \t\r\n"); len(t) == 0 || t[0] != '{' {
+		return reply(rpcError(call.ID, rpcInvalidParams, "params must be an object", nil))
+	}
+	result, err := s.Call(ctx, call.Method, params)
+	if err != nil {
+		s.logger.Warn("invalid request", "error", err)
+		return reply(rpcError(call.ID, rpcInvalidParams, err.Error(), nil))
+	}
+	if !result.OK {
+		var data *RPCResult
+		if result.ID != "" || result.Output != "" {
+			data = &RPCResult{ID: result.ID, Output: result.Output}
+		}
+		return reply(rpcError(call.ID, rpcServerError, result.Error, data))
+	}
+	return reply(RPCResponse{
+		JSONRPC: "2.0",
+		Result:  &RPCResult{ID: result.ID, Status: result.Status, AckedAt: result.AckedAt, Output: result.Output},
+		ID:      call.ID,
+	})
+}
+
+// validRPCID reports whether id is absent, a string, a number or null.
+func validRPCID(id json.RawMessage) bool {
+	if id == nil {
+		return true
+	}
+	var v any
+	if err := json.Unmarshal(id, &v); err != nil {
+		return false
+	}
+	switch v.(type) {
+	case nil, string, float64:
+		return true
+	}
+	return false
+}
+
+func rpcError(id json.RawMessage, code int, msg string, data *RPCResult) RPCResponse {
+	if id == nil {
+		id = json.RawMessage("null")
+	}
+	return RPCResponse{JSONRPC: "2.0", Error: &RPCError{Code: code, Message: msg, Data: data}, ID: id}
+}
+
+// writeRPC writes a response or batch. Requests can run past the read
+// deadline, so the write gets its own.
+func (s *Server) writeRPC(conn net.Conn, v any) {
+	conn.SetWriteDeadline(time.Now().Add(5 * time.Second))
+	json.NewEncoder(conn).Encode(v)
+}
@@ -0,0 +1,207 @@
+package core
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"net"
+	"strings"
+	"testing"
+	"time"
+)
+
+// sendRaw sends data and returns the raw reply, which is empty when the
+// server does not answer.
+func sendRaw(t *testing.T, sockPath string, data string) string {
+	t.Helper()
+	conn, err := net.DialTimeout("unix", sockPath, 2*time.Second)
+	if err != nil {
+		t.Fatalf("dial: %v", err)
+	}
+	defer conn.Close()
+
+	conn.SetDeadline(time.Now().Add(2 * time.Second))
+	if _, err := conn.Write([]byte(data)); err != nil {
+		t.Fatalf("write: %v", err)
+	}
+	conn.(*net.UnixConn).CloseWrite()
+	out, err := io.ReadAll(conn)
+	if err != nil {
+		t.Fatalf("read: %v", err)
+	}
+	return strings.TrimSpace(string(out))
+}
+
+func TestServer_JSONRPC(t *testing.T) {
+	echo := &echoNotifier{}
+	srv, sockPath, cancel := setupTestServer(t, echo)
+	defer func() { cancel(); srv.Shutdown() }()
+
+	tests := []struct {
+		name string
+		req  string
+		want string
+	}{
+		{
+			"notify",
+			`{"jsonrpc":"2.0","method":"notify","params":{"text":"hello","source":"rpc"},"id":1}`,
+			`{"jsonrpc":"2.0","result":{"id":"*"},"id":1}`,
+		},
+		{
+			"null id",
+			`{"jsonrpc":"2.0","method":"notify","params":{"text":"hello"},"id":null}`,
+			`{"jsonrpc":"2.0","result":{"id":"*"},"id":null}`,
+		},
+		{
+			"notification",
+			`{"jsonrpc":"2.0","method":"notify","params":{"text":"quiet"}}`,
+			``,
+		},
+		{
+			"invalid params",
+			`{"jsonrpc":"2.0","method":"notify","params":{"text":""},"id":"a"}`,
+			`{"jsonrpc":"2.0","error":{"code":-32602,"message":"text is required"},"id":"a"}`,
+		},
+		{
+			"params not an object",
+			`{"jsonrpc":"2.0","method":"notify","params":["hello"],"id":2}`,
+			`{"jsonrpc":"2.0","error":{"code":-32602,"message":"params must be an object"},"id":2}`,
+		},
+		{
+			"unknown method",
+			`{"jsonrpc":"2.0","method":"shout","id":3}`,
+			`{"jsonrpc":"2.0","error":{"code":-32601,"message":"method \"shout\" not found"},"id":3}`,
+		},
+		{
+			"wrong version",
+			`{"jsonrpc":"1.0","method":"notify","id":4}`,
+			`{"jsonrpc":"2.0","error":{"code":-32600,"message":"jsonrpc must be \"2.0\""},"id":4}`,
+		},
+		{
+			"server error",
+			`{"jsonrpc":"2.0","method":"ack-status","params":{"id":"x"},"id":5}`,
+			`{"jsonrpc":"2.0","error":{"code":-32000,"message":"acknowledgement tracking not enabled"},"id":5}`,
+		},
+		{
+			"parse error",
+			`{"jsonrpc":"2.0","method":`,
+			`{"jsonrpc":"2.0","error":{"code":-32700,"message":"parse error: *"},"id":null}`,
+		},
+		{
+			"empty batch",
+			`[]`,
+			`{"jsonrpc":"2.0","error":{"code":-32600,"message":"empty batch"},"id":null}`,
+		},
+		{
+			"batch",
+			`[{"jsonrpc":"2.0","method":"notify","params":{"text":"one"},"id":1},{"jsonrpc":"2.0","method":"notify","params":{"text":"two"}},1]`,
+			`[{"jsonrpc":"2.0","result":{"id":"*"},"id":1},{"jsonrpc":"2.0","error":{"code":-32600,"message":"invalid request: *"},"id":null}]`,
+		},
+		{
+			"malformed batch entry",
+			`[{"jsonrpc":"2.0","method":"notify","params":{"text":"never"},"id":1},{"jsonrpc":}]`,
+			`{"jsonrpc":"2.0","error":{"code":-32700,"message":"parse error: *"},"id":null}`,
+		},
+		{
+			"batch of notifications",
+			`[{"jsonrpc":"2.0","method":"notify","params":{"text":"three"}}]`,
+			``,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := sendRaw(t, sockPath, tt.req)
+			if !wildcardMatch(tt.want, got) {
+				t.Errorf("reply = %s\nwant    %s", got, tt.want)
+			}
+		})
+	}
+
+	var texts []string
+	for _, n := range echo.notifications() {
+		texts = append(texts, n.Text)
+	}
+	if got := strings.Join(texts, ","); got != "hello,hello,quiet,one,two,three" {
+		t.Errorf("delivered = %s", got)
+	}
+
+	srv.WithDispatch(&fakeExecutor{}, 1)
+	got := sendRaw(t, sockPath, `{"jsonrpc":"2.0","method":"dispatch","params":{"op":"status"},"id":6}`)
+	if want := `{"jsonrpc":"2.0","result":{"output":"all good"},"id":6}`; got != want {
+		t.Errorf("dispatch reply = %s, want %s", got, want)
+	}
+
+	big := `{"jsonrpc":"2.0","method":"notify","params":{"text":"` + strings.Repeat("x", MaxDropRequestBytes) + `"},"id":7}`
+	if got, want := sendRaw(t, sockPath, big), fmt.Sprintf(`{"jsonrpc":"2.0","error":{"code":-32600,"message":"payload exceeds %d byte limit"},"id":null}`, MaxDropRequestBytes); got != want {
+		t.Errorf("oversized reply = %s, want %s", got, want)
+	}
+
+	// The native protocol is unchanged on the same socket.
+	if resp := sendRequest(t, sockPath, []byte(`{"version":1,"action":"notify","payload":{"text":"native"}}`)); !resp.OK {
+		t.Errorf("native request: %+v", resp)
+	}
+}
+
+func TestServer_JSONRPCWithoutHalfClose(t *testing.T) {
+	srv, sockPath, cancel := setupTestServer(t, &echoNotifier{})
+	defer func() { cancel(); srv.Shutdown() }()
+
+	conn, err := net.DialTimeout("unix", sockPath, 2*time.Second)
+	if err != nil {
+		t.Fatalf("dial: %v", err)
+	}
+	defer conn.Close()
+	conn.SetDeadline(time.Now().Add(2 * time.Second))
+
+	// The reply comes once the request ends, while the write side is
+	// still open.
+	if _, err := conn.Write([]byte(`{"jsonrpc":"2.0","method":"notify","params":{"text":"hi"},"id":1}`)); err != nil {
+		t.Fatalf("write: %v", err)
+	}
+	line, err := bufio.NewReader(conn).ReadString('\n')
+	if err != nil {
+		t.Fatalf("read: %v", err)
+	}
+	if want := `{"jsonrpc":"2.0","result":{"id":"*"},"id":1}`; !wildcardMatch(want, strings.TrimSpace(line)) {
+		t.Errorf("reply = %s, want %s", line, want)
+	}
+}
+
+// wildcardMatch matches got against want, where each * in want stands
+// for any run of characters within a JSON string.
+func wildcardMatch(want, got string) bool {
+	parts := strings.Split(want, "*")
+	if !strings.HasPrefix(got, parts[0]) {
+		return false
+	}
+	got = got[len(parts[0]):]
+	for _, p := range parts[1:] {
+		i := strings.Index(got, p)
+		if i < 0 {
+			return false
+		}
+		got = got[i+len(p):]
+	}
+	return got == ""
+}
+
+func TestIsJSONRPC(t *testing.T) {
+	tests := []struct {
+		data string
+		want bool
+	}{
+		{`{"version":1,"action":"notify"}`, false},
+		{` { "payload": {}, "version": 1}`, false},
+		{`{}`, false},
+		{`not json`, false},
+		{``, false},
+		{`{"jsonrpc":"2.0","method":"notify"}`, true},
+		{`{"method":"notify","jsonrpc":"2.0"}`, true},
+		{"\n[{\"jsonrpc\":\"2.0\"}]", true},
+	}
+	for _, tt := range tests {
+		if got := isJSONRPC(bufio.NewReader(strings.NewReader(tt.data))); got != tt.want {
+			t.Errorf("isJSONRPC(%q) = %v, want %v", tt.data, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,64 @@
+package kubeops
+
+import (
+	"errors"
+	"fmt"
+	"time"
+
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/client-go/kubernetes"
+	_ "k8s.io/client-go/plugin/pkg/client/auth" // kubeconfig auth-provider plugins
+	"k8s.io/client-go/rest"
+	"k8s.io/client-go/tools/clientcmd"
+)
+
+const httpTimeout = 10 * time.Second
+
+// Client is the Kubernetes clientset the /k op calls. It connects the way
+// kubectl does: from a kubeconfig, with its exec credential and
+// auth-provider plugins; from the pod's service account when in_cluster
+// is set; or from server and token_file. client-go re-reads token files
+// as they rotate.
+type Client struct {
+	kube kubernetes.Interface
+}
+
+// NewClient creates a client from config.
+func NewClient(cfg *Config) (*Client, error) {
+	rc, err := restConfig(cfg)
+	if err != nil {
+		return nil, fmt.Errorf("kube client config: %w", err)
+	}
+	rc.Timeout = httpTimeout
+	kube, err := kubernetes.NewForConfig(rc)
+	if err != nil {
+		return nil, fmt.Errorf("create kube client: %w", err)
+	}
+	return &Client{kube: kube}, nil
+}
+
+func restConfig(cfg *Config) (*rest.Config, error) {
+	switch {
+	case cfg.InCluster:
+		return rest.InClusterConfig()
+	case cfg.Kubeconfig != "":
+		rules := &clientcmd.ClientConfigLoadingRules{ExplicitPath: cfg.Kubeconfig}
+		overrides := &clientcmd.ConfigOverrides{CurrentContext: cfg.Context}
+		return clientcmd.NewNonInteractiveDeferredLoadingClientConfig(rules, overrides).ClientConfig()
+	}
+	return &rest.Config{
+		Host:            cfg.Server,
+		BearerTokenFile: cfg.TokenFile,
+		TLSClientConfig: rest.TLSClientConfig{CAFile: cfg.CAFile},
+	}, nil
+}
+
+// apiError wraps err from the API server with its status code, keeping
+// it for apierrors.IsForbidden and the like.
+func apiError(err error) error {
+	var status apierrors.APIStatus
+	if errors.As(err, &status) && status.Status().Code != 0 {
+		return fmt.Errorf("kube API error %d: %w", status.Status().Code, err)
+	}
+	return fmt.Errorf("kube request: %w", err)
+}
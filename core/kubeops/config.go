@@ -0,0 +1,101 @@
+package kubeops
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+
+	"github.com/jdelaire/openslack/internal/homedir"
+)
+
+// DefaultNamespace is used when neither the config nor the command names one.
+const DefaultNamespace = "default"
+
+// Config is the Kubernetes ops configuration. The cluster is reached
+// through exactly one of: Kubeconfig (optionally with Context), InCluster,
+// or Server with TokenFile and CAFile.
+type Config struct {
+	Server     string              `json:"server"`
+	TokenFile  string              `json:"token_file"`
+	CAFile     string              `json:"ca_file"`
+	Kubeconfig string              `json:"kubeconfig"`
+	Context    string              `json:"context"`
+	InCluster  bool                `json:"in_cluster"`
+	Namespace  string              `json:"namespace"`
+	Chats      map[string][]string `json:"chats"`
+}
+
+// LoadConfig reads and validates a Kubernetes ops config file.
+// Returns nil, nil if the file does not exist.
+func LoadConfig(path string) (*Config, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("read kube config: %w", err)
+	}
+
+	var cfg Config
+	if err := json.Unmarshal(data, &cfg); err != nil {
+		return nil, fmt.Errorf("parse kube config: %w", err)
+	}
+
+	if err := validateConfig(&cfg); err != nil {
+		return nil, err
+	}
+
+	if cfg.Namespace == "" {
+		cfg.Namespace = DefaultNamespace
+	}
+	cfg.Kubeconfig = homedir.Expand(cfg.Kubeconfig)
+	return &cfg, nil
+}
+
+func validateConfig(cfg *Config) error {
+	sources := 0
+	for _, set := range []bool{cfg.Server != "", cfg.Kubeconfig != "", cfg.InCluster} {
+		if set {
+			sources++
+		}
+	}
+	switch {
+	case sources > 1:
+		return fmt.Errorf("kube config: set only one of server, kubeconfig and in_cluster")
+	case cfg.Context != "" && cfg.Kubeconfig == "":
+		return fmt.Errorf("kube config: context needs kubeconfig")
+	case cfg.Kubeconfig != "" || cfg.InCluster:
+		if cfg.TokenFile != "" || cfg.CAFile != "" {
+			return fmt.Errorf("kube config: token_file and ca_file go with server")
+		}
+	case cfg.Server == "":
+		return fmt.Errorf("kube config missing server, kubeconfig or in_cluster")
+	case !strings.HasPrefix(cfg.Server, "https://") && !strings.HasPrefix(cfg.Server, "http://"):
+		return fmt.Errorf("kube server %q must be an http(s) URL", cfg.Server)
+	case cfg.TokenFile == "":
+		return fmt.Errorf("kube config missing token_file")
+	}
+	for chat, rules := range cfg.Chats {
+		if _, err := strconv.ParseInt(chat, 10, 64); err != nil {
+			return fmt.Errorf("kube chats: invalid chat id %q", chat)
+		}
+		for _, rule := range rules {
+			if _, ok := commands[rule]; !ok {
+				return fmt.Errorf("kube chats: chat %s: unknown rule %q", chat, rule)
+			}
+		}
+	}
+	return nil
+}
+
+// Allowed reports whether chatID may run the given rule (e.g. "get pods").
+func (c *Config) Allowed(chatID int64, rule string) bool {
+	for _, r := range c.Chats[strconv.FormatInt(chatID, 10)] {
+		if r == rule {
+			return true
+		}
+	}
+	return false
+}
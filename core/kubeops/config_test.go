@@ -0,0 +1,83 @@
+package kubeops
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestLoadConfigValid(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "kube.json")
+	data := `{
+		"server": "https://k8s.local:6443",
+		"token_file": "/var/run/token",
+		"chats": {"100": ["get pods", "rollout restart"]}
+	}`
+	os.WriteFile(path, []byte(data), 0644)
+
+	cfg, err := LoadConfig(path)
+	if err != nil {
+		t.Fatalf("LoadConfig: %v", err)
+	}
+	if cfg.Namespace != DefaultNamespace {
+		t.Errorf("namespace = %q, want default", cfg.Namespace)
+	}
+	if !cfg.Allowed(100, "get pods") || !cfg.Allowed(100, "rollout restart") {
+		t.Error("expected chat 100 rules to be allowed")
+	}
+	if cfg.Allowed(100, "get nodes") {
+		t.Error("get nodes should not be allowed")
+	}
+	if cfg.Allowed(200, "get pods") {
+		t.Error("unlisted chat should not be allowed")
+	}
+}
+
+func TestLoadConfigKubeconfig(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "kube.json")
+	os.WriteFile(path, []byte(`{"kubeconfig": "~/.kube/config", "context": "prod"}`), 0644)
+
+	cfg, err := LoadConfig(path)
+	if err != nil {
+		t.Fatalf("LoadConfig: %v", err)
+	}
+	if strings.HasPrefix(cfg.Kubeconfig, "~") || !strings.HasSuffix(cfg.Kubeconfig, filepath.Join(".kube", "config")) || cfg.Context != "prod" {
+		t.Errorf("config = %+v", cfg)
+	}
+}
+
+func TestLoadConfigMissingFile(t *testing.T) {
+	cfg, err := LoadConfig("/nonexistent/kube.json")
+	if err != nil || cfg != nil {
+		t.Fatalf("LoadConfig = %v, %v; want nil, nil", cfg, err)
+	}
+}
+
+func TestLoadConfigInvalid(t *testing.T) {
+	tests := []struct {
+		name    string
+		data    string
+		wantErr string
+	}{
+		{"malformed", `{`, "parse kube config"},
+		{"missing server", `{"token_file":"t"}`, "missing server, kubeconfig or in_cluster"},
+		{"two sources", `{"server":"https://k","token_file":"t","in_cluster":true}`, "only one of"},
+		{"token with kubeconfig", `{"kubeconfig":"~/.kube/config","token_file":"t"}`, "go with server"},
+		{"context without kubeconfig", `{"in_cluster":true,"context":"prod"}`, "context needs kubeconfig"},
+		{"bad server", `{"server":"k8s.local","token_file":"t"}`, "must be an http(s) URL"},
+		{"missing token", `{"server":"https://k"}`, "missing token_file"},
+		{"bad chat", `{"server":"https://k","token_file":"t","chats":{"me":["get pods"]}}`, "invalid chat id"},
+		{"unknown rule", `{"server":"https://k","token_file":"t","chats":{"1":["delete pods"]}}`, "unknown rule"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			path := filepath.Join(t.TempDir(), "kube.json")
+			os.WriteFile(path, []byte(tt.data), 0644)
+			_, err := LoadConfig(path)
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Fatalf("error = %v, want %q", err, tt.wantErr)
+			}
+		})
+	}
+}
@@ -0,0 +1,133 @@
+package kubeops
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/jdelaire/openslack/core/ops"
+)
+
+// command is one allowlistable /k action.
+type command struct {
+	risk      ops.RiskLevel
+	needsName bool
+	run       func(ctx context.Context, c *Client, ns, name string, now time.Time) (string, error)
+}
+
+// commands maps rule names (as used in the per-chat allowlist) to actions.
+var commands = map[string]command{
+	"get pods":        {risk: ops.RiskLow, run: getPods},
+	"get deployments": {risk: ops.RiskLow, run: getDeployments},
+	"get nodes":       {risk: ops.RiskLow, run: getNodes},
+	"get services":    {risk: ops.RiskLow, run: getServices},
+	"rollout restart": {risk: ops.RiskHigh, needsName: true, run: rolloutRestart},
+}
+
+// resourceAliases maps kubectl-style short names to canonical resources.
+var resourceAliases = map[string]string{
+	"pod": "pods", "po": "pods",
+	"deployment": "deployments", "deploy": "deployments",
+	"node": "nodes", "no": "nodes",
+	"service": "services", "svc": "services",
+}
+
+const usage = "Usage: /k get <pods|deployments|nodes|services> [-n namespace]\n" +
+	"       /k rollout restart deployment/<name> [-n namespace]"
+
+// KubeOp exposes a restricted subset of kubectl as /k. Each chat may only
+// run the rules listed for it in config. Read-only queries are RiskLow;
+// mutations are RiskHigh.
+type KubeOp struct {
+	Config *Config
+	Client *Client
+	now    func() time.Time
+}
+
+func (k *KubeOp) Name() string        { return "k" }
+func (k *KubeOp) Description() string { return "Query or restart Kubernetes workloads" }
+func (k *KubeOp) Usage() string       { return usage }
+
+// RiskFor classifies a call by its rule. Unparseable args are RiskLow so the
+// usage message is still TOTP-gated.
+func (k *KubeOp) RiskFor(args string) ops.RiskLevel {
+	inv, err := parseArgs(args)
+	if err != nil {
+		return ops.RiskLow
+	}
+	return commands[inv.rule].risk
+}
+
+func (k *KubeOp) Execute(ctx context.Context, args string) (string, error) {
+	inv, err := parseArgs(args)
+	if err != nil {
+		return usage, nil
+	}
+
+	chatID, ok := ops.ChatIDFrom(ctx)
+	if !ok || !k.Config.Allowed(chatID, inv.rule) {
+		return fmt.Sprintf("Not allowed: /k %s", inv.rule), nil
+	}
+
+	ns := inv.namespace
+	if ns == "" {
+		ns = k.Config.Namespace
+	}
+	now := time.Now
+	if k.now != nil {
+		now = k.now
+	}
+	return commands[inv.rule].run(ctx, k.Client, ns, inv.name, now())
+}
+
+// invocation is a parsed /k command line.
+type invocation struct {
+	rule      string
+	name      string
+	namespace string
+}
+
+func parseArgs(args string) (invocation, error) {
+	var inv invocation
+	var words []string
+	fields := strings.Fields(args)
+	for i := 0; i < len(fields); i++ {
+		if fields[i] == "-n" || fields[i] == "--namespace" {
+			if i+1 >= len(fields) {
+				return inv, fmt.Errorf("missing namespace")
+			}
+			inv.namespace = fields[i+1]
+			i++
+			continue
+		}
+		words = append(words, fields[i])
+	}
+
+	switch {
+	case len(words) == 2 && words[0] == "get":
+		resource := words[1]
+		if canonical, ok := resourceAliases[resource]; ok {
+			resource = canonical
+		}
+		inv.rule = "get " + resource
+	case len(words) == 3 && words[0] == "rollout" && words[1] == "restart":
+		kind, name, found := strings.Cut(words[2], "/")
+		if !found || (kind != "deployment" && kind != "deploy") || name == "" {
+			return inv, fmt.Errorf("rollout restart needs deployment/<name>")
+		}
+		inv.rule = "rollout restart"
+		inv.name = name
+	default:
+		return inv, fmt.Errorf("unrecognized command")
+	}
+
+	cmd, ok := commands[inv.rule]
+	if !ok {
+		return inv, fmt.Errorf("unknown rule %q", inv.rule)
+	}
+	if cmd.needsName && inv.name == "" {
+		return inv, fmt.Errorf("%s needs a name", inv.rule)
+	}
+	return inv, nil
+}
@@ -0,0 +1,278 @@
+package kubeops
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"encoding/pem"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+
+	"github.com/jdelaire/openslack/core/ops"
+)
+
+var testNow = time.Date(2026, 3, 1, 12, 0, 0, 0, time.UTC)
+
+type recordedRequest struct {
+	method, path, contentType, auth string
+	body                            string
+}
+
+func newTestOp(t *testing.T, handler func(w http.ResponseWriter, r *http.Request)) (*KubeOp, *[]recordedRequest) {
+	t.Helper()
+	srv, reqs := newFakeAPI(t, handler)
+
+	dir := t.TempDir()
+	tokenFile := filepath.Join(dir, "token")
+	os.WriteFile(tokenFile, []byte("secret-token\n"), 0600)
+	caFile := filepath.Join(dir, "ca.crt")
+	os.WriteFile(caFile, caPEM(srv), 0600)
+
+	cfg := &Config{
+		Server:    srv.URL,
+		TokenFile: tokenFile,
+		CAFile:    caFile,
+		Namespace: "apps",
+		Chats:     map[string][]string{"100": {"get pods", "rollout restart"}},
+	}
+	client, err := NewClient(cfg)
+	if err != nil {
+		t.Fatalf("NewClient: %v", err)
+	}
+	op := &KubeOp{Config: cfg, Client: client, now: func() time.Time { return testNow }}
+	return op, reqs
+}
+
+// newFakeAPI serves handler as the Kubernetes API over TLS, recording
+// each request. client-go only sends credentials over TLS.
+func newFakeAPI(t *testing.T, handler func(w http.ResponseWriter, r *http.Request)) (*httptest.Server, *[]recordedRequest) {
+	t.Helper()
+	var (
+		mu   sync.Mutex
+		reqs []recordedRequest
+	)
+	srv := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		mu.Lock()
+		reqs = append(reqs, recordedRequest{
+			method: r.Method, path: r.URL.Path, contentType: r.Header.Get("Content-Type"),
+			auth: r.Header.Get("Authorization"), body: string(body),
+		})
+		mu.Unlock()
+		w.Header().Set("Content-Type", "application/json")
+		handler(w, r)
+	}))
+	t.Cleanup(srv.Close)
+	return srv, &reqs
+}
+
+// caPEM returns srv's certificate, PEM-encoded, to trust as its CA.
+func caPEM(srv *httptest.Server) []byte {
+	return pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: srv.Certificate().Raw})
+}
+
+func chatCtx(id int64) context.Context {
+	return ops.WithChatID(context.Background(), id)
+}
+
+func TestKubeOpGetPods(t *testing.T) {
+	op, reqs := newTestOp(t, func(w http.ResponseWriter, _ *http.Request) {
+		json.NewEncoder(w).Encode(map[string]any{
+			"items": []map[string]any{{
+				"metadata": map[string]any{"name": "web-7d4b9", "creationTimestamp": testNow.Add(-3 * time.Hour)},
+				"status": map[string]any{
+					"phase": "Running",
+					"containerStatuses": []map[string]any{
+						{"ready": true, "restartCount": 1},
+						{"ready": false, "restartCount": 4, "state": map[string]any{"waiting": map[string]any{"reason": "CrashLoopBackOff"}}},
+					},
+				},
+			}},
+		})
+	})
+
+	got, err := op.Execute(chatCtx(100), "get po -n prod")
+	if err != nil {
+		t.Fatalf("execute: %v", err)
+	}
+	lines := strings.Split(got, "\n")
+	if len(lines) != 2 {
+		t.Fatalf("lines = %q", lines)
+	}
+	if fields := strings.Fields(lines[0]); strings.Join(fields, " ") != "NAME READY STATUS RESTARTS AGE" {
+		t.Errorf("header = %q", lines[0])
+	}
+	if fields := strings.Fields(lines[1]); strings.Join(fields, " ") != "web-7d4b9 1/2 CrashLoopBackOff 5 3h" {
+		t.Errorf("row = %q", lines[1])
+	}
+
+	r := (*reqs)[0]
+	if r.method != http.MethodGet || r.path != "/api/v1/namespaces/prod/pods" {
+		t.Errorf("request = %s %s", r.method, r.path)
+	}
+	if r.auth != "Bearer secret-token" {
+		t.Errorf("auth = %q", r.auth)
+	}
+}
+
+func TestKubeOpRolloutRestart(t *testing.T) {
+	op, reqs := newTestOp(t, func(w http.ResponseWriter, _ *http.Request) {
+		w.Write([]byte(`{}`))
+	})
+
+	got, err := op.Execute(chatCtx(100), "rollout restart deployment/web")
+	if err != nil {
+		t.Fatalf("execute: %v", err)
+	}
+	if got != "deployment/web restarted (apps)" {
+		t.Errorf("result = %q", got)
+	}
+
+	r := (*reqs)[0]
+	if r.method != http.MethodPatch || r.path != "/apis/apps/v1/namespaces/apps/deployments/web" {
+		t.Errorf("request = %s %s", r.method, r.path)
+	}
+	if r.contentType != "application/strategic-merge-patch+json" {
+		t.Errorf("content-type = %q", r.contentType)
+	}
+	if !strings.Contains(r.body, `"kubectl.kubernetes.io/restartedAt":"2026-03-01T12:00:00Z"`) {
+		t.Errorf("patch body = %s", r.body)
+	}
+}
+
+func TestKubeOpAllowlist(t *testing.T) {
+	op, reqs := newTestOp(t, func(w http.ResponseWriter, _ *http.Request) {
+		w.Write([]byte(`{"items":[]}`))
+	})
+
+	tests := []struct {
+		name string
+		ctx  context.Context
+		args string
+		want string
+	}{
+		{"rule not allowed", chatCtx(100), "get nodes", "Not allowed: /k get nodes"},
+		{"chat not listed", chatCtx(200), "get pods", "Not allowed: /k get pods"},
+		{"no chat in context", context.Background(), "get pods", "Not allowed: /k get pods"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := op.Execute(tt.ctx, tt.args)
+			if err != nil {
+				t.Fatalf("execute: %v", err)
+			}
+			if got != tt.want {
+				t.Errorf("result = %q, want %q", got, tt.want)
+			}
+		})
+	}
+	if len(*reqs) != 0 {
+		t.Errorf("denied calls reached the API: %d requests", len(*reqs))
+	}
+}
+
+func TestKubeOpAPIError(t *testing.T) {
+	op, _ := newTestOp(t, func(w http.ResponseWriter, _ *http.Request) {
+		w.WriteHeader(http.StatusForbidden)
+		w.Write([]byte(`{"kind":"Status","apiVersion":"v1","status":"Failure","message":"pods is forbidden","reason":"Forbidden","code":403}`))
+	})
+
+	_, err := op.Execute(chatCtx(100), "get pods")
+	if err == nil || !strings.Contains(err.Error(), "403: pods is forbidden") {
+		t.Fatalf("error = %v", err)
+	}
+	if !apierrors.IsForbidden(err) {
+		t.Errorf("error %v is not a Forbidden API error", err)
+	}
+}
+
+func TestKubeOpKubeconfig(t *testing.T) {
+	srv, reqs := newFakeAPI(t, func(w http.ResponseWriter, _ *http.Request) {
+		w.Write([]byte(`{"items":[]}`))
+	})
+	kubeconfig := filepath.Join(t.TempDir(), "config")
+	os.WriteFile(kubeconfig, []byte(`apiVersion: v1
+kind: Config
+clusters:
+- name: lab
+  cluster: {server: "`+srv.URL+`", certificate-authority-data: `+base64.StdEncoding.EncodeToString(caPEM(srv))+`}
+users:
+- name: ops
+  user: {token: kubeconfig-token}
+contexts:
+- name: prod
+  context: {cluster: lab, user: ops}
+- name: other
+  context: {cluster: missing, user: ops}
+current-context: other
+`), 0600)
+
+	cfg := &Config{Kubeconfig: kubeconfig, Context: "prod", Namespace: "apps", Chats: map[string][]string{"100": {"get nodes"}}}
+	client, err := NewClient(cfg)
+	if err != nil {
+		t.Fatalf("NewClient: %v", err)
+	}
+	op := &KubeOp{Config: cfg, Client: client, now: func() time.Time { return testNow }}
+	got, err := op.Execute(chatCtx(100), "get nodes")
+	if err != nil || got != "No resources found." {
+		t.Fatalf("execute = %q, %v", got, err)
+	}
+	if r := (*reqs)[0]; r.path != "/api/v1/nodes" || r.auth != "Bearer kubeconfig-token" {
+		t.Errorf("request = %s with auth %q", r.path, r.auth)
+	}
+}
+
+func TestKubeOpRiskFor(t *testing.T) {
+	op := &KubeOp{}
+	tests := []struct {
+		args string
+		want ops.RiskLevel
+	}{
+		{"get pods", ops.RiskLow},
+		{"get deploy -n prod", ops.RiskLow},
+		{"rollout restart deployment/web", ops.RiskHigh},
+		{"rollout restart deploy/web -n prod", ops.RiskHigh},
+		{"bogus", ops.RiskLow},
+	}
+	for _, tt := range tests {
+		if got := op.RiskFor(tt.args); got != tt.want {
+			t.Errorf("RiskFor(%q) = %d, want %d", tt.args, got, tt.want)
+		}
+	}
+}
+
+func TestKubeOpUsage(t *testing.T) {
+	op := &KubeOp{Config: &Config{}}
+	for _, args := range []string{"", "get", "get secrets", "rollout restart web", "get pods -n"} {
+		got, err := op.Execute(chatCtx(100), args)
+		if err != nil {
+			t.Fatalf("execute(%q): %v", args, err)
+		}
+		if !strings.HasPrefix(got, "Usage: /k") {
+			t.Errorf("execute(%q) = %q, want usage", args, got)
+		}
+	}
+}
+
+func TestFormatTableCapsRows(t *testing.T) {
+	rows := make([][]string, maxTableRows+5)
+	for i := range rows {
+		rows[i] = []string{"x"}
+	}
+	got := formatTable([]string{"NAME"}, rows)
+	if !strings.HasSuffix(got, "… 5 more") {
+		t.Errorf("table tail = %q", got[len(got)-20:])
+	}
+	if formatTable([]string{"NAME"}, nil) != "No resources found." {
+		t.Error("empty table message")
+	}
+}
@@ -0,0 +1,163 @@
+package kubeops
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+
+	"github.com/jdelaire/openslack/core/ops/format"
+)
+
+const maxTableRows = 40
+
+func getPods(ctx context.Context, c *Client, ns, _ string, now time.Time) (string, error) {
+	list, err := c.kube.CoreV1().Pods(ns).List(ctx, metav1.ListOptions{})
+	if err != nil {
+		return "", apiError(err)
+	}
+	rows := make([][]string, 0, len(list.Items))
+	for _, p := range list.Items {
+		ready, restarts := 0, 0
+		status := string(p.Status.Phase)
+		for _, cs := range p.Status.ContainerStatuses {
+			if cs.Ready {
+				ready++
+			}
+			restarts += int(cs.RestartCount)
+			if cs.State.Waiting != nil && cs.State.Waiting.Reason != "" {
+				status = cs.State.Waiting.Reason
+			}
+		}
+		rows = append(rows, []string{
+			p.Name,
+			fmt.Sprintf("%d/%d", ready, len(p.Status.ContainerStatuses)),
+			status,
+			strconv.Itoa(restarts),
+			age(now, p.CreationTimestamp.Time),
+		})
+	}
+	return formatTable([]string{"NAME", "READY", "STATUS", "RESTARTS", "AGE"}, rows), nil
+}
+
+func getDeployments(ctx context.Context, c *Client, ns, _ string, now time.Time) (string, error) {
+	list, err := c.kube.AppsV1().Deployments(ns).List(ctx, metav1.ListOptions{})
+	if err != nil {
+		return "", apiError(err)
+	}
+	rows := make([][]string, 0, len(list.Items))
+	for _, d := range list.Items {
+		rows = append(rows, []string{
+			d.Name,
+			fmt.Sprintf("%d/%d", d.Status.ReadyReplicas, d.Status.Replicas),
+			strconv.Itoa(int(d.Status.UpdatedReplicas)),
+			strconv.Itoa(int(d.Status.AvailableReplicas)),
+			age(now, d.CreationTimestamp.Time),
+		})
+	}
+	return formatTable([]string{"NAME", "READY", "UP-TO-DATE", "AVAILABLE", "AGE"}, rows), nil
+}
+
+func getNodes(ctx context.Context, c *Client, _, _ string, now time.Time) (string, error) {
+	list, err := c.kube.CoreV1().Nodes().List(ctx, metav1.ListOptions{})
+	if err != nil {
+		return "", apiError(err)
+	}
+	rows := make([][]string, 0, len(list.Items))
+	for _, n := range list.Items {
+		status := "Unknown"
+		for _, cond := range n.Status.Conditions {
+			if cond.Type == corev1.NodeReady {
+				status = "NotReady"
+				if cond.Status == corev1.ConditionTrue {
+					status = "Ready"
+				}
+			}
+		}
+		rows = append(rows, []string{
+			n.Name,
+			status,
+			age(now, n.CreationTimestamp.Time),
+			n.Status.NodeInfo.KubeletVersion,
+		})
+	}
+	return formatTable([]string{"NAME", "STATUS", "AGE", "VERSION"}, rows), nil
+}
+
+func getServices(ctx context.Context, c *Client, ns, _ string, now time.Time) (string, error) {
+	list, err := c.kube.CoreV1().Services(ns).List(ctx, metav1.ListOptions{})
+	if err != nil {
+		return "", apiError(err)
+	}
+	rows := make([][]string, 0, len(list.Items))
+	for _, s := range list.Items {
+		ports := make([]string, 0, len(s.Spec.Ports))
+		for _, p := range s.Spec.Ports {
+			ports = append(ports, fmt.Sprintf("%d/%s", p.Port, p.Protocol))
+		}
+		rows = append(rows, []string{
+			s.Name,
+			string(s.Spec.Type),
+			s.Spec.ClusterIP,
+			strings.Join(ports, ","),
+			age(now, s.CreationTimestamp.Time),
+		})
+	}
+	return formatTable([]string{"NAME", "TYPE", "CLUSTER-IP", "PORTS", "AGE"}, rows), nil
+}
+
+// rolloutRestart triggers a rolling restart the same way kubectl does: by
+// stamping the pod template with a restartedAt annotation.
+func rolloutRestart(ctx context.Context, c *Client, ns, name string, now time.Time) (string, error) {
+	patch, err := json.Marshal(map[string]any{
+		"spec": map[string]any{
+			"template": map[string]any{
+				"metadata": map[string]any{
+					"annotations": map[string]string{
+						"kubectl.kubernetes.io/restartedAt": now.Format(time.RFC3339),
+					},
+				},
+			},
+		},
+	})
+	if err != nil {
+		return "", fmt.Errorf("marshal patch: %w", err)
+	}
+	if _, err := c.kube.AppsV1().Deployments(ns).Patch(ctx, name, types.StrategicMergePatchType, patch, metav1.PatchOptions{}); err != nil {
+		return "", apiError(err)
+	}
+	return fmt.Sprintf("deployment/%s restarted (%s)", name, ns), nil
+}
+
+// formatTable renders rows as a compact aligned table, capped at
+// maxTableRows rows.
+func formatTable(headers []string, rows [][]string) string {
+	if len(rows) == 0 {
+		return "No resources found."
+	}
+	return format.Table(headers, rows, maxTableRows)
+}
+
+// age renders the time since t in kubectl's short form (e.g. 5d, 3h, 12m).
+func age(now, t time.Time) string {
+	if t.IsZero() {
+		return "-"
+	}
+	d := now.Sub(t)
+	switch {
+	case d >= 48*time.Hour:
+		return fmt.Sprintf("%dd", int(d.Hours()/24))
+	case d >= time.Hour:
+		return fmt.Sprintf("%dh", int(d.Hours()))
+	case d >= time.Minute:
+		return fmt.Sprintf("%dm", int(d.Minutes()))
+	default:
+		return fmt.Sprintf("%ds", int(d.Seconds()))
+	}
+}
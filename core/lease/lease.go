@@ -0,0 +1,333 @@
+// Package lease elects one leader among daemon instances that share a
+// bot token, through a lease file on storage they all reach. The leader
+// polls Telegram, runs schedules and starts connectors; the others stay
+// passive until its lease lapses, then one takes over.
+package lease
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+
+	"github.com/jdelaire/openslack/internal/homedir"
+	"github.com/jdelaire/openslack/internal/platform"
+)
+
+// Defaults for a zero Config field.
+const (
+	DefaultPath   = "~/.openslack/leader.lease"
+	DefaultTTLSec = 30
+)
+
+// Config sets up leader election. Path is the lease file, on storage every
+// instance shares. TTLSec is how long a lease lasts without renewal; the
+// leader renews it every third of that. Holder names this instance, and
+// defaults to the host name and process ID.
+type Config struct {
+	Path   string `json:"path"`
+	TTLSec int    `json:"ttl_sec"`
+	Holder string `json:"holder"`
+}
+
+// LoadConfig reads a leader election config file.
+// Returns nil, nil if the file does not exist.
+func LoadConfig(path string) (*Config, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("read lease config: %w", err)
+	}
+
+	var cfg Config
+	if err := json.Unmarshal(data, &cfg); err != nil {
+		return nil, fmt.Errorf("parse lease config: %w", err)
+	}
+	if cfg.TTLSec < 0 || (cfg.TTLSec > 0 && cfg.TTLSec < 3) {
+		return nil, fmt.Errorf("lease config: ttl_sec must be at least 3")
+	}
+	applyDefaults(&cfg)
+	return &cfg, nil
+}
+
+func applyDefaults(cfg *Config) {
+	if cfg.Path == "" {
+		cfg.Path = DefaultPath
+	}
+	cfg.Path = homedir.Expand(cfg.Path)
+	if cfg.TTLSec == 0 {
+		cfg.TTLSec = DefaultTTLSec
+	}
+	if cfg.Holder == "" {
+		host, _ := os.Hostname()
+		cfg.Holder = fmt.Sprintf("%s:%d", host, os.Getpid())
+	}
+}
+
+// record is the lease file's content.
+type record struct {
+	Holder  string    `json:"holder"`
+	Expires time.Time `json:"expires"`
+}
+
+// Lease contends for leadership through a lease file.
+type Lease struct {
+	path   string
+	ttl    time.Duration
+	holder string
+	logger *slog.Logger
+	now    func() time.Time
+
+	mu      sync.Mutex
+	leading bool
+	until   time.Time // when this instance's lease lapses, if leading
+	current record    // the lease as last read
+}
+
+// New returns a lease for cfg; a nil cfg uses the defaults.
+func New(cfg *Config, logger *slog.Logger) *Lease {
+	if cfg == nil {
+		cfg = &Config{}
+	}
+	c := *cfg
+	applyDefaults(&c)
+	if logger == nil {
+		logger = slog.Default()
+	}
+	return &Lease{
+		path:   c.Path,
+		ttl:    time.Duration(c.TTLSec) * time.Second,
+		holder: c.Holder,
+		logger: logger,
+		now:    time.Now,
+	}
+}
+
+// WithClock replaces time.Now for lease expiry. A nil now is ignored.
+func (l *Lease) WithClock(now func() time.Time) *Lease {
+	if now != nil {
+		l.now = now
+	}
+	return l
+}
+
+// TryAcquire takes the lease if it is free or lapsed, or renews it if this
+// instance holds it. It reports whether this instance holds it now.
+func (l *Lease) TryAcquire() (bool, error) {
+	unlock, err := l.lock()
+	if err != nil {
+		return false, err
+	}
+	defer unlock()
+
+	cur, err := l.read()
+	if err != nil {
+		return false, err
+	}
+	now := l.now()
+	if cur.Holder != "" && cur.Holder != l.holder && now.Before(cur.Expires) {
+		l.mu.Lock()
+		l.current = cur
+		l.mu.Unlock()
+		return false, nil
+	}
+
+	next := record{Holder: l.holder, Expires: now.Add(l.ttl)}
+	if err := l.write(next); err != nil {
+		return false, err
+	}
+	l.mu.Lock()
+	l.current, l.until = next, next.Expires
+	l.mu.Unlock()
+	return true, nil
+}
+
+// Release gives up the lease if this instance holds it, so a standby can
+// take over at once instead of waiting for it to lapse.
+func (l *Lease) Release() error {
+	unlock, err := l.lock()
+	if err != nil {
+		return err
+	}
+	defer unlock()
+
+	cur, err := l.read()
+	if err != nil {
+		return err
+	}
+	if cur.Holder != l.holder {
+		return nil
+	}
+	if err := os.Remove(l.path); err != nil && !os.IsNotExist(err) {
+		return fmt.Errorf("remove lease: %w", err)
+	}
+	l.mu.Lock()
+	l.current, l.until = record{}, time.Time{}
+	l.mu.Unlock()
+	return nil
+}
+
+// Status reports whether this instance leads, and the current holder and
+// when its lease lapses, as last seen.
+func (l *Lease) Status() (leading bool, holder string, expires time.Time) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return l.leading, l.current.Holder, l.current.Expires
+}
+
+// Run contends for the lease until ctx is done. While this instance leads,
+// lead runs with a context that is cancelled when leadership is lost; it
+// should start the leader's work and return when that context is done.
+// The lease is released when ctx is done.
+func (l *Lease) Run(ctx context.Context, lead func(ctx context.Context)) {
+	ticker := time.NewTicker(l.ttl / 3)
+	defer ticker.Stop()
+
+	var cur *term
+	stepDown := func(reason string) {
+		if cur == nil {
+			return
+		}
+		if reason != "" {
+			l.logger.Warn("leadership lost, standing by", "holder", l.holder, "reason", reason)
+		}
+		cur.end()
+		cur = nil
+		l.setLeading(false)
+	}
+	defer func() {
+		stepDown("")
+		if err := l.Release(); err != nil {
+			l.logger.Error("release lease failed", "error", err)
+		}
+	}()
+
+	for {
+		held, err := l.TryAcquire()
+		switch {
+		case err != nil:
+			l.logger.Error("lease check failed", "error", err)
+			// Keep leading only while the last renewal still holds.
+			l.mu.Lock()
+			lapsed := !l.now().Before(l.until.Add(-l.ttl / 3))
+			l.mu.Unlock()
+			if lapsed {
+				stepDown("lease could not be renewed")
+			}
+		case !held:
+			stepDown("lease held by another instance")
+		case cur == nil:
+			l.logger.Info("elected leader", "holder", l.holder)
+			l.setLeading(true)
+			cur = startTerm(ctx, lead)
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// term is one stretch of leadership.
+type term struct {
+	cancel context.CancelFunc
+	done   chan struct{}
+}
+
+func startTerm(ctx context.Context, lead func(ctx context.Context)) *term {
+	ctx, cancel := context.WithCancel(ctx)
+	t := &term{cancel: cancel, done: make(chan struct{})}
+	go func() {
+		defer close(t.done)
+		lead(ctx)
+	}()
+	return t
+}
+
+// end stops the leader's work and waits for lead to return.
+func (t *term) end() {
+	t.cancel()
+	<-t.done
+}
+
+func (l *Lease) setLeading(leading bool) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.leading = leading
+}
+
+// lock takes an exclusive lock on a file next to the lease, so instances
+// read and write the lease one at a time. The system drops the lock when
+// its holder exits, so a crashed instance never leaves it held.
+func (l *Lease) lock() (unlock func(), err error) {
+	if err := os.MkdirAll(filepath.Dir(l.path), 0o700); err != nil {
+		return nil, fmt.Errorf("create lease dir: %w", err)
+	}
+	lockPath := l.path + ".lock"
+	f, err := os.OpenFile(lockPath, os.O_CREATE|os.O_RDWR, 0o600)
+	if err != nil {
+		return nil, fmt.Errorf("lock lease: %w", err)
+	}
+	for attempt := 0; ; attempt++ {
+		err := platform.TryLock(f)
+		if err == nil {
+			return func() {
+				platform.Unlock(f)
+				f.Close()
+			}, nil
+		}
+		if !errors.Is(err, platform.ErrLocked) {
+			f.Close()
+			return nil, fmt.Errorf("lock lease: %w", err)
+		}
+		if attempt == 20 {
+			f.Close()
+			return nil, fmt.Errorf("lock lease: %s is held", lockPath)
+		}
+		time.Sleep(50 * time.Millisecond)
+	}
+}
+
+// read returns the lease, or a zero record if there is none.
+func (l *Lease) read() (record, error) {
+	var r record
+	data, err := os.ReadFile(l.path)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return r, nil
+		}
+		return r, fmt.Errorf("read lease: %w", err)
+	}
+	if err := json.Unmarshal(data, &r); err != nil {
+		// A torn or foreign file holds no valid lease.
+		l.logger.Warn("ignoring unreadable lease", "path", l.path, "error", err)
+		return record{}, nil
+	}
+	return r, nil
+}
+
+// write replaces the lease atomically.
+func (l *Lease) write(r record) error {
+	data, err := json.Marshal(r)
+	if err != nil {
+		return err
+	}
+	tmp := l.path + ".tmp"
+	if err := os.WriteFile(tmp, data, 0o600); err != nil {
+		return fmt.Errorf("write lease: %w", err)
+	}
+	if err := os.Rename(tmp, l.path); err != nil {
+		os.Remove(tmp)
+		return fmt.Errorf("write lease: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,154 @@
+package lease
+
+import (
+	"context"
+	"io"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func testLogger() *slog.Logger { return slog.New(slog.NewTextHandler(io.Discard, nil)) }
+
+func TestLeaseElectsOneHolder(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "leader.lease")
+	now := time.Date(2026, 3, 1, 9, 0, 0, 0, time.UTC)
+	clock := func() time.Time { return now }
+	a := New(&Config{Path: path, TTLSec: 30, Holder: "a"}, testLogger()).WithClock(clock)
+	b := New(&Config{Path: path, TTLSec: 30, Holder: "b"}, testLogger()).WithClock(clock)
+
+	if held, err := a.TryAcquire(); !held || err != nil {
+		t.Fatalf("a: %v, %v", held, err)
+	}
+	if held, _ := b.TryAcquire(); held {
+		t.Fatal("b took a held lease")
+	}
+	if _, holder, _ := b.Status(); holder != "a" {
+		t.Errorf("b sees holder %q", holder)
+	}
+
+	// a renews within the TTL, so b still waits.
+	now = now.Add(20 * time.Second)
+	a.TryAcquire()
+	now = now.Add(20 * time.Second)
+	if held, _ := b.TryAcquire(); held {
+		t.Fatal("b took a renewed lease")
+	}
+
+	// a stops renewing; once its lease lapses, b takes over.
+	now = now.Add(11 * time.Second)
+	if held, _ := b.TryAcquire(); !held {
+		t.Fatal("b did not take a lapsed lease")
+	}
+	if held, _ := a.TryAcquire(); held {
+		t.Fatal("a took the lease back")
+	}
+
+	// Releasing hands the lease over at once.
+	if err := b.Release(); err != nil {
+		t.Fatal(err)
+	}
+	if held, _ := a.TryAcquire(); !held {
+		t.Error("a did not take a released lease")
+	}
+	if err := b.Release(); err != nil {
+		t.Errorf("release by a non-holder: %v", err)
+	}
+	if _, err := os.Stat(path); err != nil {
+		t.Errorf("non-holder removed the lease: %v", err)
+	}
+}
+
+func TestLeaseLock(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "leader.lease")
+	a := New(&Config{Path: path, Holder: "a"}, testLogger())
+	b := New(&Config{Path: path, Holder: "b"}, testLogger())
+
+	// A lock file left behind holds nothing once its owner is gone.
+	if err := os.WriteFile(path+".lock", []byte("old"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	if held, err := a.TryAcquire(); err != nil || !held {
+		t.Fatalf("a with a leftover lock file: held %v, err %v", held, err)
+	}
+
+	// While one instance holds the lock, another waits and then gives up
+	// without touching the lease.
+	unlock, err := a.lock()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := b.TryAcquire(); err == nil {
+		t.Error("b acquired while a held the lock")
+	}
+	unlock()
+	if held, err := b.TryAcquire(); err != nil || held {
+		t.Errorf("b after unlock: held %v, err %v; want a's lease respected", held, err)
+	}
+}
+
+func TestLeaseRun(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "leader.lease")
+	a := New(&Config{Path: path, Holder: "a"}, testLogger())
+	b := New(&Config{Path: path, Holder: "b"}, testLogger())
+	a.ttl, b.ttl = 300*time.Millisecond, 300*time.Millisecond
+
+	var leaders atomic.Int32
+	lead := func(ctx context.Context) {
+		leaders.Add(1)
+		<-ctx.Done()
+		leaders.Add(-1)
+	}
+	ctxA, stopA := context.WithCancel(context.Background())
+	doneA := make(chan struct{})
+	go func() { a.Run(ctxA, lead); close(doneA) }()
+	waitFor(t, func() bool { leading, _, _ := a.Status(); return leading && leaders.Load() == 1 })
+
+	ctxB, stopB := context.WithCancel(context.Background())
+	doneB := make(chan struct{})
+	go func() { b.Run(ctxB, lead); close(doneB) }()
+	defer func() { stopB(); <-doneB }()
+	time.Sleep(200 * time.Millisecond)
+	if leading, _, _ := b.Status(); leading || leaders.Load() != 1 {
+		t.Fatalf("b leads alongside a: %d leaders", leaders.Load())
+	}
+
+	// a shuts down and releases; b takes over.
+	stopA()
+	<-doneA
+	waitFor(t, func() bool { leading, _, _ := b.Status(); return leading && leaders.Load() == 1 })
+}
+
+func TestLoadConfig(t *testing.T) {
+	cfg, err := LoadConfig(filepath.Join(t.TempDir(), "missing.json"))
+	if cfg != nil || err != nil {
+		t.Fatalf("missing file: %v, %v", cfg, err)
+	}
+	path := filepath.Join(t.TempDir(), "ha.json")
+	os.WriteFile(path, []byte(`{"path": "/srv/shared/openslack.lease"}`), 0600)
+	cfg, err = LoadConfig(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if cfg.Path != "/srv/shared/openslack.lease" || cfg.TTLSec != DefaultTTLSec || cfg.Holder == "" {
+		t.Errorf("config = %+v", cfg)
+	}
+	os.WriteFile(path, []byte(`{"ttl_sec": 1}`), 0600)
+	if _, err := LoadConfig(path); err == nil {
+		t.Error("ttl_sec 1 accepted")
+	}
+}
+
+func waitFor(t *testing.T, cond func() bool) {
+	t.Helper()
+	deadline := time.Now().Add(3 * time.Second)
+	for !cond() {
+		if time.Now().After(deadline) {
+			t.Fatal("condition not met in time")
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+}
@@ -0,0 +1,299 @@
+// Package limits holds the configurable message size limits and the
+// overflow policy that decides what happens to a reply too long for one
+// chat message.
+package limits
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"strings"
+	"unicode/utf8"
+)
+
+const (
+	// DefaultPayloadBytes caps a socket request other than a drop.
+	DefaultPayloadBytes = 8192
+	// DefaultMessageLen is Telegram's limit on one message.
+	DefaultMessageLen = 4096
+	// DefaultMaxPages caps the messages a paginated reply is split into.
+	DefaultMaxPages = 5
+
+	// Bounds accepted in the config file.
+	minPayloadBytes = 1024
+	maxPayloadBytes = 1 << 20
+	minMessageLen   = 256
+	maxPages        = 20
+)
+
+// Policy says what to do with a reply longer than a message.
+type Policy string
+
+const (
+	Truncate  Policy = "truncate"  // keep the head and the tail around a marker
+	Attach    Policy = "attach"    // send a truncated preview and the full text as a file
+	Paginate  Policy = "paginate"  // split the reply across several messages
+	Summarize Policy = "summarize" // send a short summary, the full text attached or kept for /more
+)
+
+// Summarizers write the summary for the summarize policy.
+const (
+	SummarizerRules = "rules" // counted lines, errors and warnings, worked out locally
+	SummarizerLLM   = "llm"   // written by the model configured for the assistant
+)
+
+// Config is the limits file. Zero fields take their defaults, and a nil
+// *Config behaves as an empty one.
+type Config struct {
+	MaxPayloadBytes int               `json:"max_payload_bytes,omitempty"`
+	MaxTextLen      int               `json:"max_text_len,omitempty"`    // notify text; defaults to the message length
+	MaxMessageLen   int               `json:"max_message_len,omitempty"` // one chat message, style prefix included
+	Overflow        Policy            `json:"overflow,omitempty"`
+	MaxPages        int               `json:"max_pages,omitempty"`
+	Ops             map[string]Policy `json:"ops,omitempty"`        // per-op overflow policy, keyed by op name
+	Summarizer      string            `json:"summarizer,omitempty"` // rules (the default) or llm
+}
+
+// LoadConfig reads a limits config file, e.g.
+// {"max_message_len": 3000, "overflow": "truncate", "ops": {"logs": "attach"}}.
+// Returns nil, nil if the file does not exist.
+func LoadConfig(path string) (*Config, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("read limits config: %w", err)
+	}
+
+	var cfg Config
+	if err := json.Unmarshal(data, &cfg); err != nil {
+		return nil, fmt.Errorf("parse limits config: %w", err)
+	}
+	if err := cfg.validate(); err != nil {
+		return nil, fmt.Errorf("limits config: %w", err)
+	}
+	ops := make(map[string]Policy, len(cfg.Ops))
+	for name, p := range cfg.Ops {
+		ops[strings.ToLower(strings.TrimPrefix(name, "/"))] = p
+	}
+	cfg.Ops = ops
+	return &cfg, nil
+}
+
+func (c *Config) validate() error {
+	if c.MaxPayloadBytes != 0 && (c.MaxPayloadBytes < minPayloadBytes || c.MaxPayloadBytes > maxPayloadBytes) {
+		return fmt.Errorf("max_payload_bytes must be between %d and %d", minPayloadBytes, maxPayloadBytes)
+	}
+	if c.MaxMessageLen != 0 && (c.MaxMessageLen < minMessageLen || c.MaxMessageLen > DefaultMessageLen) {
+		return fmt.Errorf("max_message_len must be between %d and %d", minMessageLen, DefaultMessageLen)
+	}
+	if c.MaxTextLen < 0 || c.MaxTextLen > c.MessageLen() {
+		return fmt.Errorf("max_text_len must be between 1 and max_message_len (%d)", c.MessageLen())
+	}
+	if c.MaxTextLen > c.PayloadBytes() {
+		return fmt.Errorf("max_text_len must not exceed max_payload_bytes (%d)", c.PayloadBytes())
+	}
+	if c.MaxPages < 0 || c.MaxPages > maxPages {
+		return fmt.Errorf("max_pages must be between 1 and %d", maxPages)
+	}
+	if c.Overflow != "" {
+		if err := c.Overflow.check(); err != nil {
+			return fmt.Errorf("overflow: %w", err)
+		}
+	}
+	for name, p := range c.Ops {
+		if err := p.check(); err != nil {
+			return fmt.Errorf("ops: /%s: %w", name, err)
+		}
+	}
+	switch c.Summarizer {
+	case "", SummarizerRules, SummarizerLLM:
+	default:
+		return fmt.Errorf("unknown summarizer %q (want rules or llm)", c.Summarizer)
+	}
+	return nil
+}
+
+func (p Policy) check() error {
+	switch p {
+	case Truncate, Attach, Paginate, Summarize:
+		return nil
+	}
+	return fmt.Errorf("unknown policy %q (want truncate, attach, paginate or summarize)", p)
+}
+
+// PayloadBytes returns the socket request limit for actions other than drop.
+func (c *Config) PayloadBytes() int {
+	if c == nil || c.MaxPayloadBytes == 0 {
+		return DefaultPayloadBytes
+	}
+	return c.MaxPayloadBytes
+}
+
+// TextLen returns the longest notify text accepted.
+func (c *Config) TextLen() int {
+	if c == nil || c.MaxTextLen == 0 {
+		return c.MessageLen()
+	}
+	return c.MaxTextLen
+}
+
+// MessageLen returns the longest message the dispatcher sends.
+func (c *Config) MessageLen() int {
+	if c == nil || c.MaxMessageLen == 0 {
+		return DefaultMessageLen
+	}
+	return c.MaxMessageLen
+}
+
+// Pages returns the most messages a paginated reply is split into.
+func (c *Config) Pages() int {
+	if c == nil || c.MaxPages == 0 {
+		return DefaultMaxPages
+	}
+	return c.MaxPages
+}
+
+// PolicyFor returns the overflow policy for replies from op, falling back
+// to the global one. An empty op, for replies that are not an op's
+// output, gets the global policy.
+func (c *Config) PolicyFor(op string) Policy {
+	if c == nil {
+		return Truncate
+	}
+	if p, ok := c.Ops[op]; ok && op != "" {
+		return p
+	}
+	if c.Overflow != "" {
+		return c.Overflow
+	}
+	return Truncate
+}
+
+// Summarizes reports whether any reply is laid out as a summary, so /more
+// has something to show.
+func (c *Config) Summarizes() bool {
+	if c == nil {
+		return false
+	}
+	if c.Overflow == Summarize {
+		return true
+	}
+	for _, p := range c.Ops {
+		if p == Summarize {
+			return true
+		}
+	}
+	return false
+}
+
+// Reply is a reply laid out for sending.
+type Reply struct {
+	Messages []string
+	File     string // the full text, set when Attach cut the preview
+}
+
+// attachNote ends the preview of an attached reply.
+const attachNote = "\n(full output attached)"
+
+// Layout fits text into messages of at most room bytes under policy p.
+// Text that already fits is returned as is, whatever the policy. Summaries
+// are the caller's to write; Summarize lays out as Truncate here.
+func (c *Config) Layout(p Policy, text string, room int) Reply {
+	if len(text) <= room {
+		return Reply{Messages: []string{text}}
+	}
+	switch p {
+	case Attach:
+		return Reply{Messages: []string{Cut(text, room-len(attachNote)) + attachNote}, File: text}
+	case Paginate:
+		return Reply{Messages: paginate(text, room, c.Pages())}
+	default:
+		return Reply{Messages: []string{Cut(text, room)}}
+	}
+}
+
+// Cut shortens text to at most n bytes, keeping its head and its tail
+// around a marker that counts the bytes left out. Cuts fall on rune
+// boundaries.
+func Cut(text string, n int) string {
+	if len(text) <= n {
+		return text
+	}
+	// Size the marker for the largest count it can show; the real count
+	// is never longer.
+	keep := n - len(marker(len(text)))
+	if keep <= 0 {
+		return text[:runeFloor(text, max(n, 0))]
+	}
+	head := runeFloor(text, keep/2)
+	tail := runeCeil(text, len(text)-(keep-head))
+	return text[:head] + marker(tail-head) + text[tail:]
+}
+
+func marker(omitted int) string {
+	return fmt.Sprintf("\n… %d bytes omitted …\n", omitted)
+}
+
+// pageFooterRoom is reserved on each page for its "(i/n)" footer.
+const pageFooterRoom = len("\n(20/20)")
+
+// paginate splits text into at most pages messages of room bytes,
+// breaking at newlines where it can. Text too long for every page is cut
+// in the middle first, so the last page still shows how the output ended.
+func paginate(text string, room, pages int) []string {
+	size := room - pageFooterRoom
+	if size <= utf8.UTFMax {
+		return []string{Cut(text, room)}
+	}
+	// Leave each page room for a rune that does not fit whole.
+	if budget := (size - utf8.UTFMax) * pages; len(text) > budget {
+		text = Cut(text, budget)
+	}
+	chunks := split(text, size, true)
+	if len(chunks) > pages {
+		// Breaking at newlines left pages short; fill them instead.
+		chunks = split(text, size, false)
+	}
+	if len(chunks) > 1 {
+		for i := range chunks {
+			chunks[i] = fmt.Sprintf("%s\n(%d/%d)", strings.TrimSuffix(chunks[i], "\n"), i+1, len(chunks))
+		}
+	}
+	return chunks
+}
+
+// split cuts text into chunks of at most size bytes, at a newline in the
+// second half of the chunk when lines is set.
+func split(text string, size int, lines bool) []string {
+	var chunks []string
+	for len(text) > 0 {
+		end := len(text)
+		if end > size {
+			end = runeFloor(text, size)
+			if nl := strings.LastIndexByte(text[:end], '\n'); lines && nl >= size/2 {
+				end = nl + 1
+			}
+		}
+		chunks = append(chunks, text[:end])
+		text = text[end:]
+	}
+	return chunks
+}
+
+// runeFloor returns the largest rune boundary in s at or before i.
+func runeFloor(s string, i int) int {
+	for i > 0 && i < len(s) && !utf8.RuneStart(s[i]) {
+		i--
+	}
+	return i
+}
+
+// runeCeil returns the smallest rune boundary in s at or after i.
+func runeCeil(s string, i int) int {
+	for i < len(s) && !utf8.RuneStart(s[i]) {
+		i++
+	}
+	return i
+}
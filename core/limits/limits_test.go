@@ -0,0 +1,127 @@
+package limits
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"unicode/utf8"
+)
+
+func writeConfig(t *testing.T, data string) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "limits.json")
+	if err := os.WriteFile(path, []byte(data), 0600); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+func TestLoadConfig(t *testing.T) {
+	cfg, err := LoadConfig(filepath.Join(t.TempDir(), "missing.json"))
+	if cfg != nil || err != nil {
+		t.Fatalf("missing file: %v, %v", cfg, err)
+	}
+
+	cfg, err = LoadConfig(writeConfig(t, `{"max_message_len": 2000, "overflow": "paginate", "ops": {"/Logs": "attach"}}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if cfg.MessageLen() != 2000 || cfg.TextLen() != 2000 || cfg.PayloadBytes() != DefaultPayloadBytes {
+		t.Errorf("limits = %d, %d, %d", cfg.MessageLen(), cfg.TextLen(), cfg.PayloadBytes())
+	}
+	if cfg.PolicyFor("logs") != Attach || cfg.PolicyFor("status") != Paginate || cfg.PolicyFor("") != Paginate {
+		t.Errorf("policies = %s, %s", cfg.PolicyFor("logs"), cfg.PolicyFor("status"))
+	}
+
+	if cfg.Summarizes() {
+		t.Error("Summarizes without a summarize policy")
+	}
+
+	cfg, err = LoadConfig(writeConfig(t, `{"ops": {"logs": "summarize"}, "summarizer": "llm"}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if cfg.PolicyFor("logs") != Summarize || !cfg.Summarizes() || cfg.Summarizer != SummarizerLLM {
+		t.Errorf("policy = %s, summarizes = %v, summarizer = %q", cfg.PolicyFor("logs"), cfg.Summarizes(), cfg.Summarizer)
+	}
+
+	var nilCfg *Config
+	if nilCfg.PolicyFor("logs") != Truncate || nilCfg.MessageLen() != DefaultMessageLen || nilCfg.Pages() != DefaultMaxPages || nilCfg.Summarizes() {
+		t.Error("nil config does not use the defaults")
+	}
+}
+
+func TestLoadConfigInvalid(t *testing.T) {
+	tests := []struct {
+		data string
+		want string
+	}{
+		{`{"max_payload_bytes": 10}`, "max_payload_bytes"},
+		{`{"max_message_len": 9000}`, "max_message_len"},
+		{`{"max_message_len": 1000, "max_text_len": 2000}`, "max_text_len"},
+		{`{"max_pages": 100}`, "max_pages"},
+		{`{"overflow": "shred"}`, `unknown policy "shred"`},
+		{`{"ops": {"logs": "zip"}}`, "/logs"},
+		{`{"summarizer": "magic"}`, `unknown summarizer "magic"`},
+		{`{"unknown": 1`, "parse limits config"},
+	}
+	for _, tt := range tests {
+		_, err := LoadConfig(writeConfig(t, tt.data))
+		if err == nil || !strings.Contains(err.Error(), tt.want) {
+			t.Errorf("%s: error = %v, want %q", tt.data, err, tt.want)
+		}
+	}
+}
+
+func TestCut(t *testing.T) {
+	text := "HEAD" + strings.Repeat("é", 3000) + "TAIL"
+	for _, n := range []int{100, 1001, 4096} {
+		got := Cut(text, n)
+		if len(got) > n {
+			t.Errorf("Cut(%d) is %d bytes", n, len(got))
+		}
+		if !strings.HasPrefix(got, "HEAD") || !strings.HasSuffix(got, "TAIL") || !strings.Contains(got, "bytes omitted") {
+			t.Errorf("Cut(%d) = %q", n, got)
+		}
+		if !utf8.ValidString(got) {
+			t.Errorf("Cut(%d) split a rune", n)
+		}
+	}
+	if got := Cut("short", 10); got != "short" {
+		t.Errorf("Cut of short text = %q", got)
+	}
+}
+
+func TestLayout(t *testing.T) {
+	cfg := &Config{MaxPages: 3}
+	text := strings.Repeat("0123456789abcdef\n", 100) // 1700 bytes
+
+	reply := cfg.Layout(Paginate, text, 500)
+	if len(reply.Messages) != 3 {
+		t.Fatalf("paginate gave %d pages, want 3", len(reply.Messages))
+	}
+	for i, page := range reply.Messages {
+		if len(page) > 500 {
+			t.Errorf("page %d is %d bytes", i, len(page))
+		}
+	}
+	if first := reply.Messages[0]; !strings.HasSuffix(first, "0123456789abcdef\n(1/3)") {
+		t.Errorf("page 1 does not break at a line: %q", first[len(first)-30:])
+	}
+
+	reply = cfg.Layout(Paginate, text, 1000)
+	if len(reply.Messages) != 2 || strings.Contains(strings.Join(reply.Messages, ""), "omitted") {
+		t.Errorf("paginate cut text that fits in 2 pages: %d pages", len(reply.Messages))
+	}
+
+	reply = cfg.Layout(Attach, text, 500)
+	if len(reply.Messages) != 1 || len(reply.Messages[0]) > 500 || reply.File != text {
+		t.Errorf("attach = %d messages, file %d bytes", len(reply.Messages), len(reply.File))
+	}
+
+	reply = cfg.Layout(Truncate, "fits", 500)
+	if len(reply.Messages) != 1 || reply.Messages[0] != "fits" || reply.File != "" {
+		t.Errorf("short reply = %+v", reply)
+	}
+}
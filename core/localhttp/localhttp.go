@@ -0,0 +1,182 @@
+// Package localhttp holds what the daemon's HTTP servers share: they only
+// listen on loopback and only answer requests that carry an access token.
+package localhttp
+
+import (
+	"context"
+	"crypto/subtle"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"mime"
+	"net"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+)
+
+// CheckLoopback rejects listen addresses other than 127.0.0.1, ::1 and
+// localhost.
+func CheckLoopback(addr string) error {
+	host, _, err := net.SplitHostPort(addr)
+	if err != nil {
+		return fmt.Errorf("addr %q: %w", addr, err)
+	}
+	if !IsLoopback(host) {
+		return fmt.Errorf("addr %q: must be a loopback address", addr)
+	}
+	return nil
+}
+
+// IsLoopback reports whether host is localhost or a loopback IP.
+func IsLoopback(host string) bool {
+	if host == "localhost" {
+		return true
+	}
+	ip := net.ParseIP(strings.Trim(host, "[]"))
+	return ip != nil && ip.IsLoopback()
+}
+
+// Guard serves next only to requests that name a loopback host, so a web
+// page the browser visits cannot reach it through DNS rebinding, and that
+// carry token as the password of HTTP basic auth or as a bearer token.
+// Responses are not cached.
+func Guard(token, realm string, next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		host := r.Host
+		if h, _, err := net.SplitHostPort(host); err == nil {
+			host = h
+		}
+		if !IsLoopback(host) {
+			http.Error(w, "forbidden", http.StatusForbidden)
+			return
+		}
+		if !authorized(r, token) {
+			w.Header().Set("WWW-Authenticate", fmt.Sprintf(`Basic realm=%q, charset="UTF-8"`, realm))
+			http.Error(w, "unauthorized", http.StatusUnauthorized)
+			return
+		}
+		w.Header().Set("Cache-Control", "no-store")
+		w.Header().Set("X-Content-Type-Options", "nosniff")
+		next.ServeHTTP(w, r)
+	})
+}
+
+func authorized(r *http.Request, token string) bool {
+	if token == "" {
+		return false
+	}
+	given, ok := "", false
+	if _, pass, basic := r.BasicAuth(); basic {
+		given, ok = pass, true
+	} else if bearer, found := strings.CutPrefix(r.Header.Get("Authorization"), "Bearer "); found {
+		given, ok = bearer, true
+	}
+	return ok && subtle.ConstantTimeCompare([]byte(given), []byte(token)) == 1
+}
+
+// RequireJSON refuses POST requests not declared as JSON with 415 and a
+// JSON error, and limits their bodies to limit bytes. A web page can only
+// send such a request after a CORS preflight, which these servers never
+// approve, so a browser holding the token cannot be made to post to them.
+func RequireJSON(limit int64, next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method == http.MethodPost {
+			mt, _, err := mime.ParseMediaType(r.Header.Get("Content-Type"))
+			if err != nil || mt != "application/json" {
+				w.Header().Set("Content-Type", "application/json")
+				w.WriteHeader(http.StatusUnsupportedMediaType)
+				json.NewEncoder(w).Encode(map[string]string{"error": "Content-Type must be application/json"})
+				return
+			}
+			r.Body = http.MaxBytesReader(w, r.Body, limit)
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+// Runner holds the Server a daemon component starts, for the component's
+// own Start, Addr and Shutdown. It is safe for concurrent use.
+type Runner struct {
+	mu  sync.Mutex
+	srv *Server
+}
+
+// Start starts the server as the package-level Start does.
+func (r *Runner) Start(ctx context.Context, name, addr string, h http.Handler, logger *slog.Logger) error {
+	srv, err := Start(ctx, name, addr, h, logger)
+	if err != nil {
+		return err
+	}
+	r.mu.Lock()
+	r.srv = srv
+	r.mu.Unlock()
+	return nil
+}
+
+// Addr returns the address Start is listening on, or "" before Start.
+func (r *Runner) Addr() string {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.srv == nil {
+		return ""
+	}
+	return r.srv.Addr()
+}
+
+// Shutdown stops the server, if started, waiting up to 5s for in-flight
+// requests.
+func (r *Runner) Shutdown() {
+	r.mu.Lock()
+	srv := r.srv
+	r.mu.Unlock()
+	if srv != nil {
+		srv.Shutdown()
+	}
+}
+
+// Server is a running HTTP server.
+type Server struct {
+	srv *http.Server
+	ln  net.Listener
+}
+
+// Start listens on addr and serves h until ctx is cancelled or Shutdown is
+// called. name labels its log entries.
+func Start(ctx context.Context, name, addr string, h http.Handler, logger *slog.Logger) (*Server, error) {
+	ln, err := net.Listen("tcp", addr)
+	if err != nil {
+		return nil, fmt.Errorf("%s listen: %w", name, err)
+	}
+	srv := &http.Server{
+		Handler:           h,
+		ReadHeaderTimeout: 5 * time.Second,
+		WriteTimeout:      time.Minute,
+		IdleTimeout:       time.Minute,
+	}
+	logger.Info(name+" listening", "addr", ln.Addr().String())
+
+	go func() {
+		if err := srv.Serve(ln); err != nil && !errors.Is(err, http.ErrServerClosed) {
+			logger.Error(name+" serve failed", "error", err)
+		}
+	}()
+	context.AfterFunc(ctx, func() { srv.Close() })
+	return &Server{srv: srv, ln: ln}, nil
+}
+
+// Addr returns the address the server listens on.
+func (s *Server) Addr() string {
+	return s.ln.Addr().String()
+}
+
+// Shutdown stops serving, waiting up to 5s for in-flight requests.
+func (s *Server) Shutdown() {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	if err := s.srv.Shutdown(ctx); err != nil {
+		s.srv.Close()
+	}
+}
@@ -0,0 +1,84 @@
+package localhttp
+
+import (
+	"context"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestCheckLoopback(t *testing.T) {
+	tests := []struct {
+		addr string
+		ok   bool
+	}{
+		{"127.0.0.1:8787", true},
+		{"[::1]:8787", true},
+		{"localhost:8787", true},
+		{":8787", false},
+		{"0.0.0.0:8787", false},
+		{"192.168.1.5:8787", false},
+		{"example.com:8787", false},
+		{"127.0.0.1", false},
+	}
+	for _, tt := range tests {
+		if err := CheckLoopback(tt.addr); (err == nil) != tt.ok {
+			t.Errorf("CheckLoopback(%q) = %v, want ok=%v", tt.addr, err, tt.ok)
+		}
+	}
+}
+
+func TestRequireJSON(t *testing.T) {
+	h := RequireJSON(8, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if _, err := io.ReadAll(r.Body); err != nil {
+			http.Error(w, err.Error(), http.StatusRequestEntityTooLarge)
+		}
+	}))
+	tests := []struct {
+		method, contentType, body string
+		want                      int
+	}{
+		{http.MethodGet, "", "", http.StatusOK},
+		{http.MethodPost, "application/json; charset=utf-8", "{}", http.StatusOK},
+		{http.MethodPost, "text/plain", "{}", http.StatusUnsupportedMediaType},
+		{http.MethodPost, "", "{}", http.StatusUnsupportedMediaType},
+		{http.MethodPost, "application/json", `{"text":"too long"}`, http.StatusRequestEntityTooLarge},
+	}
+	for _, tt := range tests {
+		req := httptest.NewRequest(tt.method, "/", strings.NewReader(tt.body))
+		if tt.contentType != "" {
+			req.Header.Set("Content-Type", tt.contentType)
+		}
+		rec := httptest.NewRecorder()
+		h.ServeHTTP(rec, req)
+		if rec.Code != tt.want {
+			t.Errorf("%s %q: status %d, want %d", tt.method, tt.contentType, rec.Code, tt.want)
+		}
+	}
+}
+
+func TestRunner(t *testing.T) {
+	var r Runner
+	if r.Addr() != "" {
+		t.Errorf("Addr before Start = %q", r.Addr())
+	}
+	r.Shutdown() // not started: no-op
+
+	logger := slog.New(slog.NewTextHandler(io.Discard, nil))
+	h := http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) { w.Write([]byte("ok")) })
+	if err := r.Start(context.Background(), "test", "127.0.0.1:0", h, logger); err != nil {
+		t.Fatal(err)
+	}
+	resp, err := http.Get("http://" + r.Addr())
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+	r.Shutdown()
+	if _, err := http.Get("http://" + r.Addr()); err == nil {
+		t.Error("server still answering after Shutdown")
+	}
+}
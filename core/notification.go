@@ -1,11 +1,121 @@
 package core
 
-import "time"
+import (
+	"strings"
+	"time"
+)
 
 // Notification represents an outbound notification to be delivered.
+// ChatID, when non-zero, delivers it to that chat instead of the
+// notifier's own. ReplyTo, when non-zero, threads it under that chat message.
+// Silent delivers it without a sound, where the channel supports it. Buttons are
+// shown under the message by notifiers that support them; others send the
+// text alone, so it must still say what to do.
+//
+// Title, Tags, Priority, URL and Fields give it structure. Notifiers render
+// them as their channel allows, and fall back to PlainText.
 type Notification struct {
 	ID        string    `json:"id"`
 	Text      string    `json:"text"`
 	Source    string    `json:"source"`
 	CreatedAt time.Time `json:"created_at"`
+	ChatID    int64     `json:"chat_id,omitempty"`
+	ReplyTo   int64     `json:"reply_to,omitempty"`
+	Silent    bool      `json:"silent,omitempty"`
+	Buttons   []Button  `json:"buttons,omitempty"`
+
+	Title    string   `json:"title,omitempty"`
+	Tags     []string `json:"tags,omitempty"`
+	Priority Priority `json:"priority,omitempty"`
+	URL      string   `json:"url,omitempty"`
+	Fields   []Field  `json:"fields,omitempty"`
+}
+
+// Priority ranks a notification. The zero value is normal.
+type Priority string
+
+const (
+	PriorityLow    Priority = "low" // delivered silently
+	PriorityNormal Priority = "normal"
+	PriorityHigh   Priority = "high"
+	PriorityUrgent Priority = "urgent"
+)
+
+// Marker returns a short plain-text flag for high and urgent priorities,
+// and "" otherwise.
+func (p Priority) Marker() string {
+	switch p {
+	case PriorityHigh:
+		return "[high]"
+	case PriorityUrgent:
+		return "[urgent]"
+	}
+	return ""
+}
+
+// Field is a labelled value listed under a notification's text.
+type Field struct {
+	Name  string `json:"name"`
+	Value string `json:"value"`
+}
+
+// Structured reports whether n has any field beyond its text.
+func (n Notification) Structured() bool {
+	return n.Title != "" || len(n.Tags) > 0 || n.Priority.Marker() != "" || n.URL != "" || len(n.Fields) > 0
+}
+
+// PlainText renders n as plain text: the title line with any priority
+// marker, the text, one "name: value" line per field, the tags as
+// hashtags, and the link. Unstructured notifications render as their text.
+func (n Notification) PlainText() string {
+	if !n.Structured() {
+		return n.Text
+	}
+	var b strings.Builder
+	head := strings.TrimSpace(n.Priority.Marker() + " " + n.Title)
+	if head != "" {
+		b.WriteString(head)
+	}
+	section := func(s string) {
+		if b.Len() > 0 {
+			b.WriteString("\n\n")
+		}
+		b.WriteString(s)
+	}
+	if n.Text != "" {
+		if head != "" {
+			b.WriteString("\n")
+		}
+		b.WriteString(n.Text)
+	}
+	if len(n.Fields) > 0 {
+		lines := make([]string, len(n.Fields))
+		for i, f := range n.Fields {
+			lines[i] = f.Name + ": " + f.Value
+		}
+		section(strings.Join(lines, "\n"))
+	}
+	if len(n.Tags) > 0 {
+		section("#" + strings.Join(n.Tags, " #"))
+	}
+	if n.URL != "" {
+		section(n.URL)
+	}
+	return b.String()
+}
+
+// Button is an inline button. Pressing it arrives as an InboundMessage
+// whose Text is Data and whose CallbackID is set.
+type Button struct {
+	Text string `json:"text"`
+	Data string `json:"data"`
+}
+
+// Document is a file delivered to the chat. ChatID, when non-zero,
+// delivers it to that chat instead of the notifier's own.
+type Document struct {
+	Name    string
+	Data    []byte
+	Caption string
+	ChatID  int64
 }
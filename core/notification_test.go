@@ -0,0 +1,29 @@
+package core
+
+import "testing"
+
+func TestNotificationPlainText(t *testing.T) {
+	n := Notification{Text: "hello"}
+	if n.Structured() || n.PlainText() != "hello" {
+		t.Errorf("plain notification renders as %q", n.PlainText())
+	}
+
+	n = Notification{
+		Text:     "Disk at 93%",
+		Title:    "Disk almost full",
+		Priority: PriorityHigh,
+		Fields:   []Field{{Name: "host", Value: "db1"}, {Name: "mount", Value: "/var"}},
+		Tags:     []string{"ops", "disk"},
+		URL:      "https://grafana.example/d/disk",
+	}
+	want := "[high] Disk almost full\nDisk at 93%\n\nhost: db1\nmount: /var\n\n#ops #disk\n\nhttps://grafana.example/d/disk"
+	if got := n.PlainText(); got != want {
+		t.Errorf("PlainText() = %q, want %q", got, want)
+	}
+
+	// Low priority only silences; it adds nothing to render.
+	n = Notification{Text: "fyi", Priority: PriorityLow}
+	if n.Structured() || n.PlainText() != "fyi" {
+		t.Errorf("low priority renders as %q", n.PlainText())
+	}
+}
@@ -1,9 +1,112 @@
 package core
 
-import "context"
+import (
+	"context"
+	"time"
+
+	"github.com/jdelaire/openslack/core/ops"
+	"github.com/jdelaire/openslack/internal/sources"
+)
 
 // Notifier delivers notifications to an external channel.
 type Notifier interface {
 	Name() string
 	Send(ctx context.Context, n Notification) error
 }
+
+// SendFunc adapts n to the send callback taken by background runners.
+// When ctx carries the triggering chat and message (see ops.WithChatID and
+// ops.WithMessageID), the notification goes to that chat, threaded under
+// the message.
+func SendFunc(n Notifier, source string) func(context.Context, string) error {
+	return func(ctx context.Context, text string) error {
+		chatID, _ := ops.ChatIDFrom(ctx)
+		replyTo, _ := ops.MessageIDFrom(ctx)
+		return n.Send(ctx, Notification{
+			Text:      text,
+			Source:    source,
+			CreatedAt: time.Now(),
+			ChatID:    chatID,
+			ReplyTo:   replyTo,
+		})
+	}
+}
+
+// ChatSendFunc adapts n to a callback that sends to a given chat, for ops
+// such as /broadcast that reach beyond the notifier's own chat.
+func ChatSendFunc(n Notifier, source string) func(ctx context.Context, chatID int64, text string) error {
+	return func(ctx context.Context, chatID int64, text string) error {
+		return n.Send(ctx, Notification{
+			Text:      text,
+			Source:    source,
+			CreatedAt: time.Now(),
+			ChatID:    chatID,
+		})
+	}
+}
+
+// DocumentSender is implemented by notifiers that can deliver files.
+type DocumentSender interface {
+	SendDocument(ctx context.Context, doc Document) error
+}
+
+// Reactor is implemented by notifiers that can react to a chat message
+// with an emoji. An empty emoji removes the reaction.
+type Reactor interface {
+	React(ctx context.Context, chatID, messageID int64, emoji string) error
+}
+
+// TypingSender is implemented by notifiers that can show a chat that the
+// bot is working on a reply.
+type TypingSender interface {
+	SendTyping(ctx context.Context, chatID int64) error
+}
+
+// CommandPublisher is implemented by notifiers that can publish the bot's
+// command list to the chat app, for its command menu. A chatID of 0 sets
+// the default menu; others set that chat's own.
+type CommandPublisher interface {
+	SetCommands(ctx context.Context, chatID int64, cmds []Command) error
+}
+
+// Command is an entry in the published command list.
+type Command struct {
+	Name        string
+	Description string
+}
+
+// DropStore retains dropped files so they can be fetched again later.
+type DropStore interface {
+	Save(name, source string, data []byte) (id string, err error)
+}
+
+// AckTracker records notifications that ask for acknowledgement with /ack.
+type AckTracker interface {
+	Track(text, source string) (id string, err error)
+	// AckedAt returns the zero time while id is pending; found is false
+	// for unknown IDs.
+	AckedAt(id string) (ackedAt time.Time, found bool, err error)
+}
+
+// TemplateRenderer renders named notification templates for the socket
+// API. *templates.Set implements it.
+type TemplateRenderer interface {
+	Render(name string, vars map[string]string) (string, error)
+}
+
+// SourceRouter decorates and routes notifications by their source.
+// *sources.Registry implements it.
+type SourceRouter interface {
+	Route(source, text string) (sources.Route, error)
+}
+
+// OpExecutor runs a registered op without a triggering chat message.
+// *Dispatcher implements it.
+type OpExecutor interface {
+	Exec(ctx context.Context, chatID int64, cmd, args string) (string, error)
+}
+
+// OutboundQueue holds notifications scheduled with send_at.
+type OutboundQueue interface {
+	Schedule(text, source string, sendAt time.Time) (id string, err error)
+}
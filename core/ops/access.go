@@ -0,0 +1,56 @@
+package ops
+
+import (
+	"context"
+	"slices"
+)
+
+// ChatRestricter is an optional interface for ops that only some chats may
+// run. The dispatcher treats a refused chat as if the op did not exist
+// there.
+type ChatRestricter interface {
+	AllowsChat(chatID int64) bool
+}
+
+// ChatAllowed reports whether chatID may run op.
+func ChatAllowed(op Op, chatID int64) bool {
+	if cr, ok := op.(ChatRestricter); ok {
+		return cr.AllowsChat(chatID)
+	}
+	return true
+}
+
+// ChatAccess limits an op to some of the allowlisted chats. A chat in
+// DeniedChats is always refused; a non-empty AllowedChats refuses every
+// chat not in it.
+type ChatAccess struct {
+	AllowedChats []int64 `json:"allowed_chats,omitempty"`
+	DeniedChats  []int64 `json:"denied_chats,omitempty"`
+}
+
+// AllowsChat implements ChatRestricter.
+func (a ChatAccess) AllowsChat(chatID int64) bool {
+	if slices.Contains(a.DeniedChats, chatID) {
+		return false
+	}
+	return len(a.AllowedChats) == 0 || slices.Contains(a.AllowedChats, chatID)
+}
+
+// Bot is the Telegram bot a command arrived through, when one daemon runs
+// several. Ops limits the bot to those commands; empty means all.
+type Bot struct {
+	Name string
+	Ops  []string
+}
+
+// Allows reports whether the bot exposes the op called name.
+func (b Bot) Allows(name string) bool {
+	return len(b.Ops) == 0 || slices.Contains(b.Ops, name)
+}
+
+// BotAllowed reports whether the bot in ctx, if any, exposes the op
+// called name.
+func BotAllowed(ctx context.Context, name string) bool {
+	b, ok := BotFrom(ctx)
+	return !ok || b.Allows(name)
+}
@@ -0,0 +1,82 @@
+package ops
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/jdelaire/openslack/internal/acks"
+	"github.com/jdelaire/openslack/internal/watchdogs"
+)
+
+// AckOp acknowledges watchdog alerts, silencing repeats until the check
+// passes again, and notifications sent with an acknowledgement request.
+// Either field may be nil when that feature is not configured.
+type AckOp struct {
+	Runner        *watchdogs.Runner
+	Notifications *acks.Tracker
+}
+
+func (o *AckOp) Name() string        { return "ack" }
+func (o *AckOp) Description() string { return "Acknowledge an alert or notification" }
+func (o *AckOp) Risk() RiskLevel     { return RiskNone }
+
+func (o *AckOp) Execute(_ context.Context, args string) (string, error) {
+	fields := strings.Fields(args)
+	switch len(fields) {
+	case 0:
+		return o.list()
+	case 1:
+	default:
+		return "Usage: /ack [name]", nil
+	}
+
+	name := fields[0]
+	if o.Notifications != nil {
+		ok, err := o.Notifications.Ack(name)
+		switch {
+		case err == nil && ok:
+			return fmt.Sprintf("Acknowledged %s", name), nil
+		case err == nil:
+			return fmt.Sprintf("%s was already acknowledged", name), nil
+		case !errors.Is(err, acks.ErrNotFound):
+			return "", err
+		}
+	}
+	if o.Runner == nil {
+		return fmt.Sprintf("Unknown ack id: %s", name), nil
+	}
+
+	ok, err := o.Runner.Ack(name)
+	if errors.Is(err, watchdogs.ErrUnknownWatchdog) {
+		return fmt.Sprintf("Unknown watchdog: %s", name), nil
+	}
+	if err != nil {
+		return "", err
+	}
+	if !ok {
+		return fmt.Sprintf("%s is not alerting", name), nil
+	}
+	return fmt.Sprintf("Acknowledged %s; silenced until it recovers", name), nil
+}
+
+func (o *AckOp) list() (string, error) {
+	var lines []string
+	if o.Runner != nil {
+		lines = o.Runner.Alerting()
+	}
+	if o.Notifications != nil {
+		pending, err := o.Notifications.Pending()
+		if err != nil {
+			return "", err
+		}
+		for _, rec := range pending {
+			lines = append(lines, fmt.Sprintf("%s: awaiting ack: %s", rec.ID, rec.Text))
+		}
+	}
+	if len(lines) == 0 {
+		return "No active alerts.", nil
+	}
+	return strings.Join(lines, "\n"), nil
+}
@@ -0,0 +1,74 @@
+package ops_test
+
+import (
+	"context"
+	"path/filepath"
+	"testing"
+
+	"github.com/jdelaire/openslack/core/ops"
+	"github.com/jdelaire/openslack/internal/acks"
+	"github.com/jdelaire/openslack/internal/state"
+	"github.com/jdelaire/openslack/internal/watchdogs"
+)
+
+func TestAckOp(t *testing.T) {
+	dir := t.TempDir()
+	cfg := &watchdogs.Config{
+		Disks:       map[string]watchdogs.DiskConfig{"root": {Path: dir, MinFreePercent: 99.9999, IntervalSec: 60}},
+		RepeatHours: 24,
+	}
+	runner := watchdogs.NewRunner(cfg, state.NewStore(filepath.Join(dir, "state.json")), nil, nil)
+	op := &ops.AckOp{Runner: runner}
+	ctx := context.Background()
+
+	tests := []struct {
+		args string
+		want string
+	}{
+		{"", "No active alerts."},
+		{"root", "root is not alerting"},
+		{"nope", "Unknown watchdog: nope"},
+		{"a b", "Usage: /ack [name]"},
+	}
+	for _, tt := range tests {
+		got, err := op.Execute(ctx, tt.args)
+		if err != nil {
+			t.Fatalf("execute %q: %v", tt.args, err)
+		}
+		if got != tt.want {
+			t.Errorf("execute %q = %q, want %q", tt.args, got, tt.want)
+		}
+	}
+}
+
+func TestAckOpNotifications(t *testing.T) {
+	dir := t.TempDir()
+	tracker := acks.NewTracker(state.NewStore(filepath.Join(dir, "state.json")))
+	op := &ops.AckOp{Notifications: tracker}
+	ctx := context.Background()
+
+	id, err := tracker.Track("Batch finished, verify output", "batch.sh")
+	if err != nil {
+		t.Fatalf("track: %v", err)
+	}
+
+	tests := []struct {
+		args string
+		want string
+	}{
+		{"", id + ": awaiting ack: Batch finished, verify output"},
+		{id, "Acknowledged " + id},
+		{id, id + " was already acknowledged"},
+		{"", "No active alerts."},
+		{"nope", "Unknown ack id: nope"},
+	}
+	for _, tt := range tests {
+		got, err := op.Execute(ctx, tt.args)
+		if err != nil {
+			t.Fatalf("execute %q: %v", tt.args, err)
+		}
+		if got != tt.want {
+			t.Errorf("execute %q = %q, want %q", tt.args, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,56 @@
+package ops
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/jdelaire/openslack/internal/backup"
+)
+
+const backupUsage = "Usage:\n/backup\n/backup run <name>"
+
+// BackupOp lists backup jobs with their verification status and starts
+// jobs on demand. Runs continue in the background and report to chat.
+type BackupOp struct {
+	Runner *backup.Runner
+}
+
+func (o *BackupOp) Name() string        { return "backup" }
+func (o *BackupOp) Description() string { return "List or run backup jobs" }
+func (o *BackupOp) Usage() string       { return backupUsage }
+
+// RiskFor allows listing without TOTP; starting a job needs it.
+func (o *BackupOp) RiskFor(args string) RiskLevel {
+	fields := strings.Fields(args)
+	if len(fields) == 0 || fields[0] == "list" {
+		return RiskNone
+	}
+	return RiskLow
+}
+
+func (o *BackupOp) Execute(ctx context.Context, args string) (string, error) {
+	fields := strings.Fields(args)
+	if len(fields) == 0 || fields[0] == "list" {
+		status := o.Runner.Status()
+		if len(status) == 0 {
+			return "No backup jobs configured.", nil
+		}
+		return strings.Join(status, "\n"), nil
+	}
+	if fields[0] != "run" || len(fields) != 2 {
+		return backupUsage, nil
+	}
+
+	name := fields[1]
+	switch err := o.Runner.Start(ctx, name); {
+	case errors.Is(err, backup.ErrUnknownJob):
+		return fmt.Sprintf("Unknown backup job: %s", name), nil
+	case errors.Is(err, backup.ErrRunning):
+		return fmt.Sprintf("Backup %s is already running", name), nil
+	case err != nil:
+		return "", err
+	}
+	return fmt.Sprintf("Backup %s started; progress will follow", name), nil
+}
@@ -0,0 +1,62 @@
+package ops_test
+
+import (
+	"context"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/jdelaire/openslack/core/ops"
+	"github.com/jdelaire/openslack/internal/backup"
+	"github.com/jdelaire/openslack/internal/state"
+)
+
+func TestBackupOp(t *testing.T) {
+	dir := t.TempDir()
+	cfg := &backup.Config{
+		Jobs: map[string]backup.Job{
+			"db": {Command: "true", Artifact: filepath.Join(dir, "*.sql"), MaxAgeHours: 26, TimeoutMin: 1},
+		},
+		VerifyIntervalSec: 3600,
+	}
+	var sent []string
+	runner := backup.NewRunner(cfg, state.NewStore(filepath.Join(dir, "state.json")), func(_ context.Context, text string) error {
+		sent = append(sent, text)
+		return nil
+	}, nil)
+	op := &ops.BackupOp{Runner: runner}
+	ctx := context.Background()
+
+	tests := []struct {
+		args string
+		want string
+	}{
+		{"", "db: no artifact matching"},
+		{"run", "Usage:"},
+		{"run nope", "Unknown backup job: nope"},
+		{"run db", "Backup db started; progress will follow"},
+	}
+	for _, tt := range tests {
+		got, err := op.Execute(ctx, tt.args)
+		if err != nil {
+			t.Fatalf("execute %q: %v", tt.args, err)
+		}
+		if !strings.HasPrefix(got, tt.want) {
+			t.Errorf("execute %q = %q, want prefix %q", tt.args, got, tt.want)
+		}
+	}
+	runner.Wait()
+	if len(sent) != 1 || !strings.Contains(sent[0], "verification failed") {
+		t.Errorf("sent = %q", sent)
+	}
+}
+
+func TestBackupOpRisk(t *testing.T) {
+	op := &ops.BackupOp{}
+	if got := ops.RiskOfCall(op, ""); got != ops.RiskNone {
+		t.Errorf("list risk = %v, want none", got)
+	}
+	if got := ops.RiskOfCall(op, "run db"); got != ops.RiskLow {
+		t.Errorf("run risk = %v, want low", got)
+	}
+}
@@ -0,0 +1,84 @@
+package ops
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"slices"
+	"strings"
+	"time"
+)
+
+// broadcastTimeout bounds delivery to each chat, so one unreachable chat
+// cannot use up the op timeout.
+const broadcastTimeout = 5 * time.Second
+
+// BroadcastOp sends an announcement to every allowlisted chat and any
+// extra target groups, and reports delivery per chat.
+type BroadcastOp struct {
+	Chats   func() []int64 // the allowlist
+	Targets []int64        // extra groups, from LoadBroadcastTargets
+	Send    func(ctx context.Context, chatID int64, text string) error
+}
+
+func (o *BroadcastOp) Name() string        { return "broadcast" }
+func (o *BroadcastOp) Description() string { return "Send an announcement to every chat" }
+func (o *BroadcastOp) Risk() RiskLevel     { return RiskHigh }
+
+func (o *BroadcastOp) Execute(ctx context.Context, args string) (string, error) {
+	text := strings.TrimSpace(args)
+	if text == "" {
+		return "Usage: /broadcast <text>", nil
+	}
+	chats := o.targets()
+	if len(chats) == 0 {
+		return "No chats to broadcast to.", nil
+	}
+
+	delivered := 0
+	var b strings.Builder
+	for _, chatID := range chats {
+		sctx, cancel := context.WithTimeout(ctx, broadcastTimeout)
+		err := o.Send(sctx, chatID, text)
+		cancel()
+		if err != nil {
+			fmt.Fprintf(&b, "\nFAIL %d: %s", chatID, err)
+			continue
+		}
+		delivered++
+		fmt.Fprintf(&b, "\nok   %d", chatID)
+	}
+	return fmt.Sprintf("Broadcast: %d/%d delivered", delivered, len(chats)) + b.String(), nil
+}
+
+// targets returns the allowlist and extra groups without duplicates.
+func (o *BroadcastOp) targets() []int64 {
+	var chats []int64
+	if o.Chats != nil {
+		chats = append(chats, o.Chats()...)
+	}
+	chats = append(chats, o.Targets...)
+	slices.Sort(chats)
+	return slices.Compact(chats)
+}
+
+// LoadBroadcastTargets reads extra broadcast groups from a file such as
+// {"targets": [-1001234567890]}. Returns nil, nil if the file does not
+// exist.
+func LoadBroadcastTargets(path string) ([]int64, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("read broadcast config: %w", err)
+	}
+	var cfg struct {
+		Targets []int64 `json:"targets"`
+	}
+	if err := json.Unmarshal(data, &cfg); err != nil {
+		return nil, fmt.Errorf("parse broadcast config: %w", err)
+	}
+	return cfg.Targets, nil
+}
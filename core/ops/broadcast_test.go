@@ -0,0 +1,58 @@
+package ops_test
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/jdelaire/openslack/core/ops"
+)
+
+func TestBroadcastOp(t *testing.T) {
+	sent := map[int64]string{}
+	op := &ops.BroadcastOp{
+		Chats:   func() []int64 { return []int64{100, 200} },
+		Targets: []int64{-300, 100},
+		Send: func(_ context.Context, chatID int64, text string) error {
+			if chatID == 200 {
+				return errors.New("chat not found")
+			}
+			sent[chatID] = text
+			return nil
+		},
+	}
+	if op.Risk() != ops.RiskHigh {
+		t.Errorf("risk = %v, want high", op.Risk())
+	}
+
+	got, err := op.Execute(context.Background(), "  rebooting the NAS in 5 minutes ")
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := "Broadcast: 2/3 delivered\nok   -300\nok   100\nFAIL 200: chat not found"
+	if got != want {
+		t.Errorf("result =\n%s\nwant\n%s", got, want)
+	}
+	if len(sent) != 2 || sent[100] != "rebooting the NAS in 5 minutes" {
+		t.Errorf("sent = %v", sent)
+	}
+
+	if got, _ := op.Execute(context.Background(), ""); got != "Usage: /broadcast <text>" {
+		t.Errorf("without text = %q", got)
+	}
+}
+
+func TestLoadBroadcastTargets(t *testing.T) {
+	dir := t.TempDir()
+	if targets, err := ops.LoadBroadcastTargets(filepath.Join(dir, "missing.json")); targets != nil || err != nil {
+		t.Errorf("missing file = %v, %v", targets, err)
+	}
+	path := filepath.Join(dir, "broadcast.json")
+	os.WriteFile(path, []byte(`{"targets": [-1001, -1002]}`), 0600)
+	targets, err := ops.LoadBroadcastTargets(path)
+	if err != nil || len(targets) != 2 || targets[0] != -1001 {
+		t.Errorf("targets = %v, %v", targets, err)
+	}
+}
@@ -0,0 +1,157 @@
+package ops
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+type chatIDKey struct{}
+
+// WithChatID returns a context carrying the chat that triggered an op.
+func WithChatID(ctx context.Context, chatID int64) context.Context {
+	return context.WithValue(ctx, chatIDKey{}, chatID)
+}
+
+// ChatIDFrom returns the chat ID stored by WithChatID, if any.
+func ChatIDFrom(ctx context.Context) (int64, bool) {
+	id, ok := ctx.Value(chatIDKey{}).(int64)
+	return id, ok
+}
+
+type messageIDKey struct{}
+
+// WithMessageID returns a context carrying the ID of the chat message that
+// triggered an op, so replies and later progress can be threaded under it.
+func WithMessageID(ctx context.Context, messageID int64) context.Context {
+	return context.WithValue(ctx, messageIDKey{}, messageID)
+}
+
+// MessageIDFrom returns the message ID stored by WithMessageID, if any.
+func MessageIDFrom(ctx context.Context) (int64, bool) {
+	id, ok := ctx.Value(messageIDKey{}).(int64)
+	return id, ok
+}
+
+type varsKey struct{}
+
+// WithVars returns a context carrying the chat variables in effect for an
+// op, so it can expand {name} references in its own configured templates.
+func WithVars(ctx context.Context, vars map[string]string) context.Context {
+	return context.WithValue(ctx, varsKey{}, vars)
+}
+
+// VarsFrom returns the variables stored by WithVars. ok is false when chat
+// variables are not enabled.
+func VarsFrom(ctx context.Context) (vars map[string]string, ok bool) {
+	vars, ok = ctx.Value(varsKey{}).(map[string]string)
+	return vars, ok
+}
+
+type botKey struct{}
+
+// WithBot returns a context carrying the bot a command arrived through.
+func WithBot(ctx context.Context, b Bot) context.Context {
+	return context.WithValue(ctx, botKey{}, b)
+}
+
+// BotFrom returns the bot stored by WithBot. ok is false when the daemon
+// runs a single bot.
+func BotFrom(ctx context.Context) (Bot, bool) {
+	b, ok := ctx.Value(botKey{}).(Bot)
+	return b, ok
+}
+
+type sudoKey struct{}
+
+// WithSudo returns a context carrying the end of the chat's /sudo window.
+func WithSudo(ctx context.Context, until time.Time) context.Context {
+	return context.WithValue(ctx, sudoKey{}, until)
+}
+
+// SudoFrom returns when the chat's /sudo window ends. ok is false when no
+// window is open.
+func SudoFrom(ctx context.Context) (until time.Time, ok bool) {
+	until, ok = ctx.Value(sudoKey{}).(time.Time)
+	return until, ok
+}
+
+// Button is a button an op offers under its reply. Pressing it sends Data
+// to the chat as if typed, so a command in it goes through the usual
+// checks.
+type Button struct {
+	Text string
+	Data string
+}
+
+type buttonsKey struct{}
+
+type replyButtons struct {
+	mu      sync.Mutex
+	buttons []Button
+}
+
+// WithReplyButtons returns a context an op can attach buttons to with
+// AddButton, and a func returning the buttons attached so far.
+func WithReplyButtons(ctx context.Context) (context.Context, func() []Button) {
+	rb := &replyButtons{}
+	return context.WithValue(ctx, buttonsKey{}, rb), func() []Button {
+		rb.mu.Lock()
+		defer rb.mu.Unlock()
+		return append([]Button(nil), rb.buttons...)
+	}
+}
+
+// AddButton attaches a button to the reply to the op call ctx belongs to.
+// It does nothing when the reply cannot carry buttons, as for runs by
+// rules or schedules.
+func AddButton(ctx context.Context, text, data string) {
+	rb, ok := ctx.Value(buttonsKey{}).(*replyButtons)
+	if !ok {
+		return
+	}
+	rb.mu.Lock()
+	defer rb.mu.Unlock()
+	rb.buttons = append(rb.buttons, Button{Text: text, Data: data})
+}
+
+// File is a file an op attaches to its reply.
+type File struct {
+	Name    string
+	Data    []byte
+	Caption string
+}
+
+type filesKey struct{}
+
+type replyFiles struct {
+	mu    sync.Mutex
+	files []File
+}
+
+// WithReplyFiles returns a context an op can attach files to with
+// AttachFile, and a func returning the files attached so far. Only calls
+// whose reply can carry files get one.
+func WithReplyFiles(ctx context.Context) (context.Context, func() []File) {
+	rf := &replyFiles{}
+	return context.WithValue(ctx, filesKey{}, rf), func() []File {
+		rf.mu.Lock()
+		defer rf.mu.Unlock()
+		return append([]File(nil), rf.files...)
+	}
+}
+
+// AttachFile attaches f to the reply to the op call ctx belongs to, and
+// reports whether it could. Replies through notifiers that cannot send
+// files, and runs by rules or schedules, take none, so the op should put
+// the content in its reply instead.
+func AttachFile(ctx context.Context, f File) bool {
+	rf, ok := ctx.Value(filesKey{}).(*replyFiles)
+	if !ok {
+		return false
+	}
+	rf.mu.Lock()
+	defer rf.mu.Unlock()
+	rf.files = append(rf.files, f)
+	return true
+}
@@ -0,0 +1,90 @@
+package ops
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/jdelaire/openslack/core/ops/format"
+	"github.com/jdelaire/openslack/internal/cron"
+)
+
+// CronOp lists the jobs on the shared cron engine with their next runs.
+type CronOp struct {
+	Engine *cron.Engine
+}
+
+func (o *CronOp) Name() string        { return "cron" }
+func (o *CronOp) Description() string { return "List scheduled jobs and their next runs" }
+func (o *CronOp) Risk() RiskLevel     { return RiskNone }
+
+func (o *CronOp) Execute(_ context.Context, args string) (string, error) {
+	if a := strings.TrimSpace(args); a != "" && a != "list" {
+		return "Usage: /cron [list]", nil
+	}
+	entries := o.Engine.List()
+	if len(entries) == 0 {
+		return "No scheduled jobs.", nil
+	}
+
+	rows := make([][]string, 0, len(entries))
+	var failing []string
+	for _, e := range entries {
+		next := "never"
+		switch {
+		case e.Running:
+			next = "running"
+		case !e.Next.IsZero():
+			next = e.Next.In(e.Location).Format("01-02 15:04")
+		}
+		last := "-"
+		if !e.LastRun.IsZero() {
+			last = e.LastRun.In(e.Location).Format("01-02 15:04")
+		}
+		rows = append(rows, []string{e.Name, e.Spec, next, last})
+		switch {
+		case e.Failures == 1:
+			failing = append(failing, fmt.Sprintf("%s failed: %s", e.Name, e.LastError))
+		case e.Failures > 1:
+			failing = append(failing, fmt.Sprintf("%s failed %d times in a row: %s", e.Name, e.Failures, e.LastError))
+		}
+	}
+
+	var b strings.Builder
+	b.WriteString(format.Table([]string{"JOB", "SCHEDULE", "NEXT", "LAST"}, rows, 0))
+	if zones := cronZones(entries); zones != "" {
+		b.WriteString("\nTimes in " + zones)
+	}
+	for _, f := range failing {
+		b.WriteString("\n" + f)
+	}
+	return b.String(), nil
+}
+
+// cronZones names the zones the listed times are in.
+func cronZones(entries []cron.Entry) string {
+	var names []string
+	seen := make(map[string]bool)
+	for _, e := range entries {
+		if name := zoneName(e.Location); !seen[name] {
+			seen[name] = true
+			names = append(names, name)
+		}
+	}
+	if len(names) == 1 {
+		return names[0]
+	}
+	parts := make([]string, 0, len(entries))
+	for _, e := range entries {
+		parts = append(parts, e.Name+": "+zoneName(e.Location))
+	}
+	return strings.Join(parts, ", ")
+}
+
+func zoneName(loc *time.Location) string {
+	if loc == time.Local {
+		return "server time"
+	}
+	return loc.String()
+}
@@ -0,0 +1,43 @@
+package ops_test
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/jdelaire/openslack/core/ops"
+	"github.com/jdelaire/openslack/internal/cron"
+)
+
+func TestCronOp(t *testing.T) {
+	now := time.Date(2026, 3, 4, 10, 17, 0, 0, time.UTC)
+	engine := cron.New(nil, nil).WithClock(func() time.Time { return now })
+	op := &ops.CronOp{Engine: engine}
+
+	got, err := op.Execute(context.Background(), "")
+	if err != nil || got != "No scheduled jobs." {
+		t.Fatalf("empty: %q, %v", got, err)
+	}
+
+	noop := func(context.Context, time.Time) error { return nil }
+	engine.Add(cron.Job{Name: "tasks-reminder", Spec: "0 6 * * *", Location: time.UTC, Run: noop})
+	engine.Add(cron.Job{Name: "digest", Spec: "*/30 * * * *", Location: time.UTC, Run: noop})
+
+	got, err = op.Execute(context.Background(), "list")
+	if err != nil {
+		t.Fatalf("execute: %v", err)
+	}
+	lines := strings.Split(got, "\n")
+	if len(lines) != 4 ||
+		!strings.HasPrefix(lines[1], "digest") || !strings.Contains(lines[1], "03-04 10:30") ||
+		!strings.HasPrefix(lines[2], "tasks-reminder") || !strings.Contains(lines[2], "03-05 06:00") ||
+		lines[3] != "Times in UTC" {
+		t.Fatalf("list =\n%s", got)
+	}
+
+	got, _ = op.Execute(context.Background(), "remove digest")
+	if got != "Usage: /cron [list]" {
+		t.Errorf("usage = %q", got)
+	}
+}
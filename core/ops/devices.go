@@ -0,0 +1,24 @@
+package ops
+
+import (
+	"context"
+	"strings"
+
+	"github.com/jdelaire/openslack/internal/presence"
+)
+
+// DevicesOp reports tracked devices and recently seen network devices.
+type DevicesOp struct {
+	Tracker *presence.Tracker
+}
+
+func (o *DevicesOp) Name() string        { return "devices" }
+func (o *DevicesOp) Description() string { return "Show devices on the local network" }
+func (o *DevicesOp) Risk() RiskLevel     { return RiskNone }
+
+func (o *DevicesOp) Execute(_ context.Context, args string) (string, error) {
+	if strings.TrimSpace(args) != "" {
+		return "Usage: /devices", nil
+	}
+	return o.Tracker.Report()
+}
@@ -0,0 +1,30 @@
+package ops_test
+
+import (
+	"context"
+	"path/filepath"
+	"testing"
+
+	"github.com/jdelaire/openslack/core/ops"
+	"github.com/jdelaire/openslack/internal/presence"
+	"github.com/jdelaire/openslack/internal/state"
+)
+
+func TestDevicesOp(t *testing.T) {
+	cfg := &presence.Config{Tracked: map[string]string{"phone": "a4:83:e7:01:02:03"}}
+	store := state.NewStore(filepath.Join(t.TempDir(), "state.json"))
+	op := &ops.DevicesOp{Tracker: presence.NewTracker(cfg, store, nil, nil)}
+
+	got, err := op.Execute(context.Background(), "")
+	if err != nil {
+		t.Fatalf("execute: %v", err)
+	}
+	if got != "phone: never seen" {
+		t.Errorf("result = %q", got)
+	}
+
+	got, _ = op.Execute(context.Background(), "extra")
+	if got != "Usage: /devices" {
+		t.Errorf("usage = %q", got)
+	}
+}
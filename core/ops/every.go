@@ -0,0 +1,130 @@
+package ops
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/jdelaire/openslack/internal/every"
+)
+
+const everyUsage = "Usage:\n/every\n/every <interval> [diff] /<command> [args]\n/every remove <id>"
+
+// EveryOp schedules commands to run on an interval. In diff mode the
+// output is only sent when it changes.
+type EveryOp struct {
+	Service  *every.Service
+	Registry *Registry
+}
+
+func (o *EveryOp) Name() string        { return "every" }
+func (o *EveryOp) Description() string { return "Run a command on an interval" }
+func (o *EveryOp) Usage() string       { return everyUsage }
+
+// RiskFor allows listing without TOTP; scheduling or removing needs it.
+func (o *EveryOp) RiskFor(args string) RiskLevel {
+	fields := strings.Fields(args)
+	if len(fields) == 0 || fields[0] == "list" {
+		return RiskNone
+	}
+	return RiskLow
+}
+
+func (o *EveryOp) Execute(ctx context.Context, args string) (string, error) {
+	chatID, ok := ChatIDFrom(ctx)
+	if !ok {
+		return "", errors.New("every: no chat in context")
+	}
+	fields := strings.Fields(args)
+	if len(fields) == 0 || fields[0] == "list" {
+		return o.list(chatID)
+	}
+	if fields[0] == "remove" || fields[0] == "rm" {
+		if len(fields) != 2 {
+			return everyUsage, nil
+		}
+		id, err := strconv.Atoi(strings.TrimPrefix(fields[1], "#"))
+		if err != nil {
+			return everyUsage, nil
+		}
+		ok, err := o.Service.Remove(chatID, id)
+		if err != nil {
+			return "", err
+		}
+		if !ok {
+			return fmt.Sprintf("Unknown scheduled command: #%d", id), nil
+		}
+		return fmt.Sprintf("Removed #%d", id), nil
+	}
+	return o.add(ctx, chatID, args)
+}
+
+func (o *EveryOp) list(chatID int64) (string, error) {
+	jobs, err := o.Service.List(chatID)
+	if err != nil {
+		return "", err
+	}
+	if len(jobs) == 0 {
+		return "No scheduled commands. Add one with /every <interval> /<command>", nil
+	}
+	lines := make([]string, 0, len(jobs))
+	for _, j := range jobs {
+		lines = append(lines, j.String())
+	}
+	return strings.Join(lines, "\n"), nil
+}
+
+// add parses "<interval> [diff] /<command> [args]", keeping the command's
+// args as typed.
+func (o *EveryOp) add(ctx context.Context, chatID int64, args string) (string, error) {
+	interval, rest, _ := strings.Cut(strings.TrimSpace(args), " ")
+	d, err := time.ParseDuration(interval)
+	if err != nil {
+		return everyUsage, nil
+	}
+	rest = strings.TrimSpace(rest)
+	job := every.Job{ChatID: chatID, IntervalSec: int(d / time.Second)}
+	if word, after, _ := strings.Cut(rest, " "); word == "diff" {
+		job.Diff = true
+		rest = strings.TrimSpace(after)
+	}
+	cmd, cmdArgs := parseCommand(rest)
+	if cmd == "" {
+		return everyUsage, nil
+	}
+	job.Command, job.Args = cmd, cmdArgs
+
+	op := o.Registry.Get(cmd)
+	switch {
+	case op == nil, !ChatAllowed(op, chatID), !BotAllowed(ctx, cmd):
+		return fmt.Sprintf("Unknown command: /%s", cmd), nil
+	case cmd == o.Name():
+		return "Cannot schedule /every itself", nil
+	case o.Registry.RiskOfCall(op, cmdArgs) == RiskHigh:
+		return fmt.Sprintf("/%s needs approval and cannot run unattended", cmd), nil
+	}
+
+	added, err := o.Service.Add(job)
+	switch {
+	case errors.Is(err, every.ErrTooFrequent), errors.Is(err, every.ErrTooMany):
+		return err.Error(), nil
+	case err != nil:
+		return "", err
+	}
+	if added.Diff {
+		return fmt.Sprintf("Scheduled %s; output is sent when it changes", added), nil
+	}
+	return fmt.Sprintf("Scheduled %s", added), nil
+}
+
+// parseCommand splits "/name args" into a lowercased name and its args.
+func parseCommand(text string) (cmd, args string) {
+	if !strings.HasPrefix(text, "/") {
+		return "", ""
+	}
+	cmd, args, _ = strings.Cut(text[1:], " ")
+	return strings.ToLower(cmd), strings.TrimSpace(args)
+}
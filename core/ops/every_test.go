@@ -0,0 +1,67 @@
+package ops_test
+
+import (
+	"context"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/jdelaire/openslack/core/ops"
+	"github.com/jdelaire/openslack/internal/every"
+	"github.com/jdelaire/openslack/internal/state"
+)
+
+func TestEveryOp(t *testing.T) {
+	reg := ops.NewRegistry()
+	reg.Register(&ops.HelpOp{Registry: reg})
+	reg.Register(&ops.ShellOp{CmdName: "disk", Command: "df -h"})
+	reg.Register(&ops.RunOp{})
+	svc := every.NewService(state.NewStore(filepath.Join(t.TempDir(), "state.json")))
+	op := &ops.EveryOp{Service: svc, Registry: reg}
+	reg.Register(op)
+	ctx := ops.WithChatID(context.Background(), 100)
+
+	tests := []struct {
+		args string
+		want string
+	}{
+		{"", "No scheduled commands."},
+		{"15m diff /disk -h /data", "Scheduled #1 every 15m: /disk -h /data (diff); output is sent when it changes"},
+		{"2h /help", "Scheduled #2 every 2h: /help"},
+		{"", "#1 every 15m: /disk -h /data (diff)\n#2 every 2h: /help"},
+		{"10s /help", "interval must be at least 1m0s"},
+		{"1h /nope", "Unknown command: /nope"},
+		{"1h /every", "Cannot schedule /every itself"},
+		{"1h /run all uptime", "/run needs approval and cannot run unattended"},
+		{"1h help", "Usage:"},
+		{"soon /help", "Usage:"},
+		{"remove 2", "Removed #2"},
+		{"remove 2", "Unknown scheduled command: #2"},
+		{"remove", "Usage:"},
+	}
+	for _, tt := range tests {
+		got, err := op.Execute(ctx, tt.args)
+		if err != nil {
+			t.Fatalf("execute %q: %v", tt.args, err)
+		}
+		if !strings.HasPrefix(got, tt.want) {
+			t.Errorf("execute %q = %q, want prefix %q", tt.args, got, tt.want)
+		}
+	}
+
+	// Jobs are per chat.
+	other := ops.WithChatID(context.Background(), 200)
+	if got, _ := op.Execute(other, "remove 1"); !strings.HasPrefix(got, "Unknown scheduled command") {
+		t.Errorf("other chat remove = %q", got)
+	}
+}
+
+func TestEveryOpRisk(t *testing.T) {
+	op := &ops.EveryOp{}
+	if got := ops.RiskOfCall(op, ""); got != ops.RiskNone {
+		t.Errorf("list risk = %v, want none", got)
+	}
+	if got := ops.RiskOfCall(op, "1h /status"); got != ops.RiskLow {
+		t.Errorf("add risk = %v, want low", got)
+	}
+}
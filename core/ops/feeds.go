@@ -0,0 +1,104 @@
+package ops
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/jdelaire/openslack/internal/feeds"
+)
+
+const feedsUsage = "Usage:\n/feeds\n/feeds add <name> <url> [interval] [+keyword ...] [-keyword ...]\n/feeds remove <name>"
+
+// FeedsOp lists and manages RSS/Atom feed subscriptions.
+type FeedsOp struct {
+	Service *feeds.Service
+}
+
+func (o *FeedsOp) Name() string        { return "feeds" }
+func (o *FeedsOp) Description() string { return "List or manage feed subscriptions" }
+func (o *FeedsOp) Usage() string       { return feedsUsage }
+
+// RiskFor allows listing without TOTP; adding or removing a feed needs it.
+func (o *FeedsOp) RiskFor(args string) RiskLevel {
+	fields := strings.Fields(args)
+	if len(fields) == 0 || fields[0] == "list" {
+		return RiskNone
+	}
+	return RiskLow
+}
+
+func (o *FeedsOp) Execute(_ context.Context, args string) (string, error) {
+	fields := strings.Fields(args)
+	if len(fields) == 0 || fields[0] == "list" {
+		return o.list()
+	}
+
+	switch fields[0] {
+	case "add":
+		return o.add(fields[1:])
+	case "remove", "rm":
+		if len(fields) != 2 {
+			return feedsUsage, nil
+		}
+		ok, err := o.Service.Remove(fields[1])
+		if err != nil {
+			return "", err
+		}
+		if !ok {
+			return fmt.Sprintf("Unknown feed: %s", fields[1]), nil
+		}
+		return fmt.Sprintf("Removed feed %s", fields[1]), nil
+	default:
+		return feedsUsage, nil
+	}
+}
+
+func (o *FeedsOp) list() (string, error) {
+	subs, err := o.Service.List()
+	if err != nil {
+		return "", err
+	}
+	if len(subs) == 0 {
+		return "No feeds. Add one with /feeds add <name> <url>", nil
+	}
+	lines := make([]string, 0, len(subs))
+	for _, sub := range subs {
+		lines = append(lines, sub.String())
+	}
+	return strings.Join(lines, "\n"), nil
+}
+
+func (o *FeedsOp) add(fields []string) (string, error) {
+	if len(fields) < 2 {
+		return feedsUsage, nil
+	}
+	sub := feeds.Subscription{Name: fields[0], URL: fields[1]}
+	for _, f := range fields[2:] {
+		switch {
+		case strings.HasPrefix(f, "+") && len(f) > 1:
+			sub.Include = append(sub.Include, f[1:])
+		case strings.HasPrefix(f, "-") && len(f) > 1:
+			sub.Exclude = append(sub.Exclude, f[1:])
+		default:
+			d, err := time.ParseDuration(f)
+			if err != nil || d <= 0 || sub.IntervalSec != 0 {
+				return feedsUsage, nil
+			}
+			sub.IntervalSec = int(d / time.Second)
+		}
+	}
+
+	added, err := o.Service.Add(sub)
+	switch {
+	case errors.Is(err, feeds.ErrInvalidName), errors.Is(err, feeds.ErrInvalidURL):
+		return err.Error(), nil
+	case errors.Is(err, feeds.ErrExists):
+		return fmt.Sprintf("Feed %s already exists", sub.Name), nil
+	case err != nil:
+		return "", err
+	}
+	return fmt.Sprintf("Added feed %s (every %s)", added.Name, feeds.FormatInterval(added.Interval())), nil
+}
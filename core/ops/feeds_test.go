@@ -0,0 +1,53 @@
+package ops_test
+
+import (
+	"context"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/jdelaire/openslack/core/ops"
+	"github.com/jdelaire/openslack/internal/feeds"
+	"github.com/jdelaire/openslack/internal/state"
+)
+
+func TestFeedsOp(t *testing.T) {
+	svc := feeds.NewService(state.NewStore(filepath.Join(t.TempDir(), "state.json")))
+	op := &ops.FeedsOp{Service: svc}
+	ctx := context.Background()
+
+	tests := []struct {
+		args string
+		want string
+	}{
+		{"", "No feeds. Add one with /feeds add <name> <url>"},
+		{"add hn https://hnrss.org/frontpage 1h +go -crypto", "Added feed hn (every 1h)"},
+		{"add blog https://example.com/feed.xml", "Added feed blog (every 30m)"},
+		{"add hn https://example.com/other", "Feed hn already exists"},
+		{"add bad https://example.com soon", "Usage:"},
+		{"add x ftp://example.com", "feed url must be http or https"},
+		{"list", "blog (every 30m) https://example.com/feed.xml\nhn (every 1h) https://hnrss.org/frontpage +go -crypto"},
+		{"remove blog", "Removed feed blog"},
+		{"remove blog", "Unknown feed: blog"},
+		{"bogus", "Usage:"},
+	}
+	for _, tt := range tests {
+		got, err := op.Execute(ctx, tt.args)
+		if err != nil {
+			t.Fatalf("execute %q: %v", tt.args, err)
+		}
+		if !strings.HasPrefix(got, tt.want) {
+			t.Errorf("execute %q = %q, want prefix %q", tt.args, got, tt.want)
+		}
+	}
+}
+
+func TestFeedsOpRisk(t *testing.T) {
+	op := &ops.FeedsOp{}
+	if got := ops.RiskOfCall(op, ""); got != ops.RiskNone {
+		t.Errorf("list risk = %v, want none", got)
+	}
+	if got := ops.RiskOfCall(op, "add hn https://hnrss.org/frontpage"); got != ops.RiskLow {
+		t.Errorf("add risk = %v, want low", got)
+	}
+}
@@ -0,0 +1,135 @@
+// Package format renders op output in a consistent shape: aligned tables,
+// key-value blocks and progress bars, sized to fit a phone-width Telegram
+// message in a monospace font.
+package format
+
+import (
+	"fmt"
+	"math"
+	"strings"
+	"unicode/utf8"
+)
+
+// Width is the line length, in characters, that Table fits rows into: about
+// what a Telegram message bubble shows in monospace before wrapping.
+const Width = 56
+
+// colGap separates table columns.
+const colGap = "  "
+
+// Table renders rows under headers as aligned columns. Rows past maxRows
+// (0 for no limit) are summarised as "… N more". When a row would be wider
+// than Width, the widest columns are shortened with "…" until it fits,
+// never below the width of their header. An empty table renders as "".
+func Table(headers []string, rows [][]string, maxRows int) string {
+	if len(rows) == 0 {
+		return ""
+	}
+	shown := rows
+	if maxRows > 0 && len(rows) > maxRows {
+		shown = rows[:maxRows]
+	}
+
+	widths := make([]int, len(headers))
+	for i, h := range headers {
+		widths[i] = runeLen(h)
+	}
+	for _, row := range shown {
+		for i := 0; i < len(row) && i < len(widths); i++ {
+			widths[i] = max(widths[i], runeLen(row[i]))
+		}
+	}
+	fit(widths, headers)
+
+	var b strings.Builder
+	writeRow(&b, headers, widths)
+	for _, row := range shown {
+		writeRow(&b, row, widths)
+	}
+	if len(shown) < len(rows) {
+		fmt.Fprintf(&b, "… %d more", len(rows)-len(shown))
+	}
+	return strings.TrimRight(b.String(), "\n")
+}
+
+// fit shrinks the widest shrinkable column one character at a time until
+// the table fits Width or nothing is left to shrink.
+func fit(widths []int, headers []string) {
+	total := func() int {
+		n := len(colGap) * (len(widths) - 1)
+		for _, w := range widths {
+			n += w
+		}
+		return n
+	}
+	for total() > Width {
+		widest := -1
+		for i, w := range widths {
+			if w > max(runeLen(headers[i]), 1) && (widest < 0 || w > widths[widest]) {
+				widest = i
+			}
+		}
+		if widest < 0 {
+			return
+		}
+		widths[widest]--
+	}
+}
+
+func writeRow(b *strings.Builder, cells []string, widths []int) {
+	var line strings.Builder
+	for i, w := range widths {
+		cell := ""
+		if i < len(cells) {
+			cell = truncate(cells[i], w)
+		}
+		if i > 0 {
+			line.WriteString(colGap)
+		}
+		line.WriteString(cell)
+		line.WriteString(strings.Repeat(" ", w-runeLen(cell)))
+	}
+	b.WriteString(strings.TrimRight(line.String(), " "))
+	b.WriteByte('\n')
+}
+
+// KeyValue renders pairs as "key: value" lines with the values aligned.
+// Values are never shortened.
+func KeyValue(pairs [][2]string) string {
+	width := 0
+	for _, p := range pairs {
+		width = max(width, runeLen(p[0]))
+	}
+	lines := make([]string, len(pairs))
+	for i, p := range pairs {
+		lines[i] = p[0] + ":" + strings.Repeat(" ", width-runeLen(p[0])+1) + p[1]
+	}
+	return strings.Join(lines, "\n")
+}
+
+// Bar renders fraction, clamped to [0, 1], as a bar of width cells and a
+// percentage, such as "[██████░░░░] 60%".
+func Bar(fraction float64, width int) string {
+	if math.IsNaN(fraction) {
+		fraction = 0
+	}
+	fraction = min(max(fraction, 0), 1)
+	filled := int(math.Round(fraction * float64(width)))
+	return fmt.Sprintf("[%s%s] %d%%", strings.Repeat("█", filled), strings.Repeat("░", width-filled), int(math.Round(fraction*100)))
+}
+
+// truncate shortens s to at most n characters, ending in "…" if cut.
+func truncate(s string, n int) string {
+	if runeLen(s) <= n {
+		return s
+	}
+	if n <= 0 {
+		return ""
+	}
+	r := []rune(s)
+	return string(r[:n-1]) + "…"
+}
+
+func runeLen(s string) int {
+	return utf8.RuneCountInString(s)
+}
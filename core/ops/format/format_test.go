@@ -0,0 +1,67 @@
+package format
+
+import (
+	"strings"
+	"testing"
+	"unicode/utf8"
+)
+
+func TestTable(t *testing.T) {
+	got := Table([]string{"NAME", "STATUS"}, [][]string{{"web-1", "Running"}, {"db", "CrashLoopBackOff"}}, 0)
+	want := "NAME   STATUS\nweb-1  Running\ndb     CrashLoopBackOff"
+	if got != want {
+		t.Errorf("got\n%s\nwant\n%s", got, want)
+	}
+	if Table([]string{"NAME"}, nil, 0) != "" {
+		t.Error("empty table should render as empty string")
+	}
+}
+
+func TestTableMaxRows(t *testing.T) {
+	rows := [][]string{{"a"}, {"b"}, {"c"}}
+	if got := Table([]string{"N"}, rows, 2); got != "N\na\nb\n… 1 more" {
+		t.Errorf("got %q", got)
+	}
+}
+
+func TestTableFitsWidth(t *testing.T) {
+	long := strings.Repeat("x", 80)
+	got := Table([]string{"ID", "TEXT", "AGE"}, [][]string{{"1", long, "3d"}, {"22", "short", "5m"}}, 0)
+	for _, line := range strings.Split(got, "\n") {
+		if n := utf8.RuneCountInString(line); n > Width {
+			t.Errorf("line %q is %d wide, want <= %d", line, n, Width)
+		}
+	}
+	if !strings.Contains(got, "x…  3d") {
+		t.Errorf("long cell not shortened:\n%s", got)
+	}
+	if !strings.Contains(got, "22  short") {
+		t.Errorf("short columns changed:\n%s", got)
+	}
+}
+
+func TestKeyValue(t *testing.T) {
+	got := KeyValue([][2]string{{"Status", "OK"}, {"Goroutines", "12"}})
+	want := "Status:     OK\nGoroutines: 12"
+	if got != want {
+		t.Errorf("got\n%s\nwant\n%s", got, want)
+	}
+}
+
+func TestBar(t *testing.T) {
+	tests := []struct {
+		fraction float64
+		want     string
+	}{
+		{0, "[░░░░░░░░░░] 0%"},
+		{0.6, "[██████░░░░] 60%"},
+		{1, "[██████████] 100%"},
+		{1.7, "[██████████] 100%"},
+		{-1, "[░░░░░░░░░░] 0%"},
+	}
+	for _, tt := range tests {
+		if got := Bar(tt.fraction, 10); got != tt.want {
+			t.Errorf("Bar(%v) = %q, want %q", tt.fraction, got, tt.want)
+		}
+	}
+}
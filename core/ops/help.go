@@ -3,20 +3,33 @@ package ops
 import (
 	"context"
 	"fmt"
+	"slices"
 	"strings"
+	"sync"
 )
 
-// HelpOp lists all registered operations.
+// HelpOp lists all registered operations. If Badge is set, each line is
+// marked with the badge for the op's risk level. The list is cached, and
+// refreshed when the registry changes.
 type HelpOp struct {
 	Registry *Registry
+	Badge    func(RiskLevel) string
+
+	once   sync.Once
+	mu     sync.Mutex
+	cached []Op // nil until listed, and after a change
 }
 
 func (h *HelpOp) Name() string        { return "help" }
 func (h *HelpOp) Description() string  { return "List available commands" }
 func (h *HelpOp) Risk() RiskLevel      { return RiskNone }
 
-func (h *HelpOp) Execute(_ context.Context, _ string) (string, error) {
-	all := h.Registry.List()
+func (h *HelpOp) Execute(ctx context.Context, _ string) (string, error) {
+	all := h.ops()
+	if chatID, ok := ChatIDFrom(ctx); ok {
+		all = slices.DeleteFunc(all, func(op Op) bool { return !ChatAllowed(op, chatID) })
+	}
+	all = slices.DeleteFunc(all, func(op Op) bool { return !BotAllowed(ctx, op.Name()) })
 	if len(all) == 0 {
 		return "No commands available.", nil
 	}
@@ -24,7 +37,31 @@ func (h *HelpOp) Execute(_ context.Context, _ string) (string, error) {
 	var b strings.Builder
 	b.WriteString("Available commands:\n")
 	for _, op := range all {
-		fmt.Fprintf(&b, "  /%s — %s\n", op.Name(), op.Description())
+		badge := ""
+		if h.Badge != nil {
+			if badge = h.Badge(h.Registry.RiskOf(op)); badge != "" {
+				badge += " "
+			}
+		}
+		fmt.Fprintf(&b, "  %s/%s — %s\n", badge, op.Name(), op.Description())
 	}
 	return b.String(), nil
 }
+
+// ops returns the registered ops, listing them again only after the
+// registry has changed.
+func (h *HelpOp) ops() []Op {
+	h.once.Do(func() {
+		h.Registry.OnChange(func() {
+			h.mu.Lock()
+			h.cached = nil
+			h.mu.Unlock()
+		})
+	})
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	if h.cached == nil {
+		h.cached = h.Registry.List()
+	}
+	return slices.Clone(h.cached)
+}
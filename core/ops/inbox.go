@@ -0,0 +1,100 @@
+package ops
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/jdelaire/openslack/internal/inbox"
+)
+
+const inboxUsage = "Usage:\n/inbox\n/inbox get <id>\n/inbox rm <id>"
+
+// InboxOp browses files retained from socket drops. Send delivers a file
+// back to the chat as a document.
+type InboxOp struct {
+	Inbox *inbox.Inbox
+	Send  func(ctx context.Context, name string, data []byte) error
+}
+
+func (o *InboxOp) Name() string        { return "inbox" }
+func (o *InboxOp) Description() string { return "List, fetch or delete dropped files" }
+func (o *InboxOp) Usage() string       { return inboxUsage }
+
+// RiskFor allows listing without TOTP; fetching or deleting content needs it.
+func (o *InboxOp) RiskFor(args string) RiskLevel {
+	fields := strings.Fields(args)
+	if len(fields) == 0 || fields[0] == "list" {
+		return RiskNone
+	}
+	return RiskLow
+}
+
+func (o *InboxOp) Execute(ctx context.Context, args string) (string, error) {
+	fields := strings.Fields(args)
+	if len(fields) == 0 || fields[0] == "list" {
+		return o.list()
+	}
+	if len(fields) != 2 {
+		return inboxUsage, nil
+	}
+
+	id := fields[1]
+	switch fields[0] {
+	case "get":
+		item, data, err := o.Inbox.Open(id)
+		if errors.Is(err, inbox.ErrNotFound) {
+			return fmt.Sprintf("Unknown inbox item: %s", id), nil
+		}
+		if err != nil {
+			return "", err
+		}
+		if err := o.Send(ctx, item.Name, data); err != nil {
+			return "", err
+		}
+		return fmt.Sprintf("Sent %s", item.Name), nil
+	case "rm", "remove":
+		ok, err := o.Inbox.Delete(id)
+		if err != nil {
+			return "", err
+		}
+		if !ok {
+			return fmt.Sprintf("Unknown inbox item: %s", id), nil
+		}
+		return fmt.Sprintf("Deleted %s", id), nil
+	default:
+		return inboxUsage, nil
+	}
+}
+
+func (o *InboxOp) list() (string, error) {
+	items, err := o.Inbox.List()
+	if err != nil {
+		return "", err
+	}
+	if len(items) == 0 {
+		return "Inbox is empty.", nil
+	}
+	lines := make([]string, 0, len(items))
+	for _, item := range items {
+		line := fmt.Sprintf("%s %s (%s, %s)", item.ID, item.Name, formatBytes(item.Size), item.Created.Format(time.DateTime))
+		if item.Source != "" {
+			line += " from " + item.Source
+		}
+		lines = append(lines, line)
+	}
+	return strings.Join(lines, "\n"), nil
+}
+
+func formatBytes(n int) string {
+	switch {
+	case n >= 1<<20:
+		return fmt.Sprintf("%.1f MiB", float64(n)/(1<<20))
+	case n >= 1<<10:
+		return fmt.Sprintf("%.1f KiB", float64(n)/(1<<10))
+	default:
+		return fmt.Sprintf("%d B", n)
+	}
+}
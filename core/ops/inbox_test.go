@@ -0,0 +1,68 @@
+package ops_test
+
+import (
+	"context"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/jdelaire/openslack/core/ops"
+	"github.com/jdelaire/openslack/internal/inbox"
+)
+
+func TestInboxOp(t *testing.T) {
+	box, err := inbox.New(&inbox.Config{Dir: filepath.Join(t.TempDir(), "inbox"), MaxItems: 10, RetainDays: 7}, nil, nil)
+	if err != nil {
+		t.Fatalf("inbox: %v", err)
+	}
+	var sentName, sentData string
+	op := &ops.InboxOp{Inbox: box, Send: func(_ context.Context, name string, data []byte) error {
+		sentName, sentData = name, string(data)
+		return nil
+	}}
+	ctx := context.Background()
+
+	if got, _ := op.Execute(ctx, ""); got != "Inbox is empty." {
+		t.Errorf("empty list = %q", got)
+	}
+
+	id, err := box.Save("build.log", "ci", []byte(strings.Repeat("x", 2048)))
+	if err != nil {
+		t.Fatalf("save: %v", err)
+	}
+
+	tests := []struct {
+		args string
+		want string
+	}{
+		{"", id + " build.log (2.0 KiB, "},
+		{"get " + id, "Sent build.log"},
+		{"get deadbeef", "Unknown inbox item: deadbeef"},
+		{"get", "Usage:"},
+		{"rm " + id, "Deleted " + id},
+		{"rm " + id, "Unknown inbox item: " + id},
+		{"bogus x", "Usage:"},
+	}
+	for _, tt := range tests {
+		got, err := op.Execute(ctx, tt.args)
+		if err != nil {
+			t.Fatalf("execute %q: %v", tt.args, err)
+		}
+		if !strings.HasPrefix(got, tt.want) {
+			t.Errorf("execute %q = %q, want prefix %q", tt.args, got, tt.want)
+		}
+	}
+	if sentName != "build.log" || len(sentData) != 2048 {
+		t.Errorf("sent %q (%d bytes)", sentName, len(sentData))
+	}
+}
+
+func TestInboxOpRisk(t *testing.T) {
+	op := &ops.InboxOp{}
+	if got := ops.RiskOfCall(op, ""); got != ops.RiskNone {
+		t.Errorf("list risk = %v, want none", got)
+	}
+	if got := ops.RiskOfCall(op, "get abcd1234"); got != ops.RiskLow {
+		t.Errorf("get risk = %v, want low", got)
+	}
+}
@@ -0,0 +1,61 @@
+package ops
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/jdelaire/openslack/core/ops/format"
+	"github.com/jdelaire/openslack/core/ratelimit"
+)
+
+// LockoutsOp lists chats with recent TOTP failures or an active lockout.
+type LockoutsOp struct {
+	Limiter *ratelimit.Limiter
+}
+
+func (o *LockoutsOp) Name() string        { return "lockouts" }
+func (o *LockoutsOp) Description() string { return "List chats with failed codes or lockouts" }
+func (o *LockoutsOp) Risk() RiskLevel     { return RiskNone }
+
+func (o *LockoutsOp) Execute(_ context.Context, args string) (string, error) {
+	if strings.TrimSpace(args) != "" {
+		return "Usage: /lockouts", nil
+	}
+	entries := o.Limiter.Entries()
+	if len(entries) == 0 {
+		return "No failed codes or lockouts.", nil
+	}
+	rows := make([][]string, 0, len(entries))
+	for _, e := range entries {
+		locked := "no"
+		if !e.LockedUntil.IsZero() {
+			locked = time.Until(e.LockedUntil).Round(time.Second).String() + " left"
+		}
+		rows = append(rows, []string{strconv.FormatInt(e.ChatID, 10), strconv.Itoa(e.Failures), locked})
+	}
+	return format.Table([]string{"Chat", "Failures", "Locked out"}, rows, 20), nil
+}
+
+// UnlockChatOp clears a chat's failures and lockout. It is high risk,
+// since it undoes the brute-force protection.
+type UnlockChatOp struct {
+	Limiter *ratelimit.Limiter
+}
+
+func (o *UnlockChatOp) Name() string        { return "unlock-chat" }
+func (o *UnlockChatOp) Description() string { return "Clear a chat's lockout" }
+func (o *UnlockChatOp) Risk() RiskLevel     { return RiskHigh }
+
+func (o *UnlockChatOp) Execute(_ context.Context, args string) (string, error) {
+	chatID, err := strconv.ParseInt(strings.TrimSpace(args), 10, 64)
+	if err != nil {
+		return "Usage: /unlock-chat <chat id>", nil
+	}
+	if !o.Limiter.Unlock(chatID) {
+		return fmt.Sprintf("Chat %d has no failed codes or lockout.", chatID), nil
+	}
+	return fmt.Sprintf("Chat %d unlocked.", chatID), nil
+}
@@ -0,0 +1,57 @@
+package ops_test
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/jdelaire/openslack/core/ops"
+	"github.com/jdelaire/openslack/core/ratelimit"
+)
+
+func TestLockoutsOps(t *testing.T) {
+	limiter := ratelimit.New()
+	list := &ops.LockoutsOp{Limiter: limiter}
+	unlock := &ops.UnlockChatOp{Limiter: limiter}
+	ctx := context.Background()
+
+	if got, _ := list.Execute(ctx, ""); got != "No failed codes or lockouts." {
+		t.Errorf("empty list = %q", got)
+	}
+
+	for i := 0; i < 5; i++ {
+		limiter.RecordFailure(200)
+	}
+	limiter.RecordFailure(300)
+
+	got, err := list.Execute(ctx, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	lines := strings.Split(got, "\n")
+	if len(lines) != 3 {
+		t.Fatalf("list =\n%s", got)
+	}
+	if f := strings.Fields(lines[1]); len(f) != 4 || f[0] != "200" || f[1] != "5" || f[3] != "left" {
+		t.Errorf("locked row = %q", lines[1])
+	}
+	if f := strings.Fields(lines[2]); len(f) != 3 || f[0] != "300" || f[1] != "1" || f[2] != "no" {
+		t.Errorf("failures row = %q", lines[2])
+	}
+
+	if got, _ := unlock.Execute(ctx, "200"); got != "Chat 200 unlocked." {
+		t.Errorf("unlock = %q", got)
+	}
+	if err := limiter.Check(200); err != nil {
+		t.Errorf("still locked out: %v", err)
+	}
+	if got, _ := unlock.Execute(ctx, "200"); got != "Chat 200 has no failed codes or lockout." {
+		t.Errorf("second unlock = %q", got)
+	}
+	if got, _ := unlock.Execute(ctx, "abc"); got != "Usage: /unlock-chat <chat id>" {
+		t.Errorf("bad id = %q", got)
+	}
+	if unlock.Risk() != ops.RiskHigh {
+		t.Errorf("unlock risk = %v, want high", unlock.Risk())
+	}
+}
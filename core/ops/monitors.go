@@ -0,0 +1,24 @@
+package ops
+
+import (
+	"context"
+	"strings"
+
+	"github.com/jdelaire/openslack/internal/monitors"
+)
+
+// MonitorsOp reports the status of uptime monitors.
+type MonitorsOp struct {
+	Runner *monitors.Runner
+}
+
+func (o *MonitorsOp) Name() string        { return "monitors" }
+func (o *MonitorsOp) Description() string { return "Show uptime monitor status" }
+func (o *MonitorsOp) Risk() RiskLevel     { return RiskNone }
+
+func (o *MonitorsOp) Execute(_ context.Context, args string) (string, error) {
+	if strings.TrimSpace(args) != "" {
+		return "Usage: /monitors", nil
+	}
+	return o.Runner.StatusReport(), nil
+}
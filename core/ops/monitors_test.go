@@ -0,0 +1,32 @@
+package ops_test
+
+import (
+	"context"
+	"path/filepath"
+	"testing"
+
+	"github.com/jdelaire/openslack/core/ops"
+	"github.com/jdelaire/openslack/internal/monitors"
+	"github.com/jdelaire/openslack/internal/state"
+)
+
+func TestMonitorsOp(t *testing.T) {
+	cfg := &monitors.Config{Monitors: map[string]monitors.MonitorConfig{
+		"nas": {Type: monitors.TypeTCP, Target: "10.0.0.2:445", Failures: 3},
+	}}
+	store := state.NewStore(filepath.Join(t.TempDir(), "state.json"))
+	op := &ops.MonitorsOp{Runner: monitors.NewRunner(cfg, store, nil, nil)}
+
+	got, err := op.Execute(context.Background(), "")
+	if err != nil {
+		t.Fatalf("execute: %v", err)
+	}
+	if got != "nas [tcp 10.0.0.2:445]: not checked yet" {
+		t.Errorf("result = %q", got)
+	}
+
+	got, _ = op.Execute(context.Background(), "extra")
+	if got != "Usage: /monitors" {
+		t.Errorf("usage = %q", got)
+	}
+}
@@ -0,0 +1,137 @@
+package ops
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/jdelaire/openslack/internal/sources"
+)
+
+const (
+	muteUsage = "Usage: /mute <source> [duration] [digest]"
+
+	maxMute = 30 * 24 * time.Hour
+)
+
+// MuteOp snoozes a notification source, optionally holding its messages
+// for a digest when the snooze ends. Without arguments it lists snoozes.
+type MuteOp struct {
+	Registry *sources.Registry
+}
+
+func (o *MuteOp) Name() string        { return "mute" }
+func (o *MuteOp) Description() string { return "Mute a notification source for a while" }
+func (o *MuteOp) Usage() string       { return muteUsage }
+
+// RiskFor allows listing without TOTP; muting needs it.
+func (o *MuteOp) RiskFor(args string) RiskLevel {
+	if strings.TrimSpace(args) == "" {
+		return RiskNone
+	}
+	return RiskLow
+}
+
+func (o *MuteOp) Execute(_ context.Context, args string) (string, error) {
+	fields := strings.Fields(args)
+	if len(fields) == 0 {
+		return o.list()
+	}
+	if len(fields) > 3 {
+		return muteUsage, nil
+	}
+
+	source := fields[0]
+	var d time.Duration
+	digest := false
+	for _, f := range fields[1:] {
+		if f == "digest" && !digest {
+			digest = true
+			continue
+		}
+		parsed, err := time.ParseDuration(f)
+		if err != nil || d != 0 {
+			return muteUsage, nil
+		}
+		if parsed <= 0 || parsed > maxMute {
+			return "Duration must be between 1m and 720h.", nil
+		}
+		d = parsed
+	}
+
+	var until time.Time
+	if d > 0 {
+		until = time.Now().Add(d).Truncate(time.Minute)
+	}
+	if err := o.Registry.Mute(source, until, digest); err != nil {
+		return "", err
+	}
+
+	reply := "Muted " + source
+	if until.IsZero() {
+		reply += " until /unmute"
+	} else {
+		reply += " until " + until.Local().Format("Jan 2 15:04")
+	}
+	if digest {
+		reply += "; messages are held for a digest"
+	}
+	return reply, nil
+}
+
+func (o *MuteOp) list() (string, error) {
+	snoozes, err := o.Registry.Snoozes()
+	if err != nil {
+		return "", err
+	}
+	now := time.Now()
+	var lines []string
+	for _, sn := range snoozes {
+		if !sn.Active(now) {
+			continue
+		}
+		line := sn.Source
+		if sn.Until.IsZero() {
+			line += " until /unmute"
+		} else {
+			line += " until " + sn.Until.Local().Format("Jan 2 15:04")
+		}
+		if sn.Digest {
+			line += fmt.Sprintf(", %d held", len(sn.Held)+sn.Dropped)
+		}
+		lines = append(lines, line)
+	}
+	if len(lines) == 0 {
+		return "No muted sources. " + muteUsage, nil
+	}
+	return strings.Join(lines, "\n"), nil
+}
+
+// UnmuteOp ends a snooze, replying with any messages held for its digest.
+type UnmuteOp struct {
+	Registry *sources.Registry
+}
+
+func (o *UnmuteOp) Name() string        { return "unmute" }
+func (o *UnmuteOp) Description() string { return "Unmute a notification source" }
+func (o *UnmuteOp) Risk() RiskLevel     { return RiskLow }
+
+func (o *UnmuteOp) Execute(_ context.Context, args string) (string, error) {
+	fields := strings.Fields(args)
+	if len(fields) != 1 {
+		return "Usage: /unmute <source>", nil
+	}
+	sn, muted, err := o.Registry.Unmute(fields[0])
+	if err != nil {
+		return "", err
+	}
+	if !muted {
+		return fields[0] + " is not muted.", nil
+	}
+	reply := "Unmuted " + fields[0]
+	if digest := sn.DigestText(); digest != "" {
+		reply += "\n\n" + digest
+	}
+	return reply, nil
+}
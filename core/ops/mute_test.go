@@ -0,0 +1,55 @@
+package ops_test
+
+import (
+	"context"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/jdelaire/openslack/core/ops"
+	"github.com/jdelaire/openslack/internal/sources"
+	"github.com/jdelaire/openslack/internal/state"
+)
+
+func TestMuteOps(t *testing.T) {
+	reg := sources.NewRegistry(nil, state.NewStore(filepath.Join(t.TempDir(), "state.json")))
+	mute := &ops.MuteOp{Registry: reg}
+	unmute := &ops.UnmuteOp{Registry: reg}
+	ctx := context.Background()
+
+	tests := []struct {
+		op   ops.Op
+		args string
+		want string
+	}{
+		{mute, "", "No muted sources."},
+		{mute, "cron 8h", "Muted cron until "},
+		{mute, "backup digest", "Muted backup until /unmute; messages are held for a digest"},
+		{mute, "x 0s", "Duration must be between 1m and 720h."},
+		{mute, "x 1h 2h", "Usage:"},
+		{mute, "x soon", "Usage:"},
+		{mute, "", "backup until /unmute, 0 held\ncron until "},
+		{unmute, "cron", "Unmuted cron"},
+		{unmute, "cron", "cron is not muted."},
+		{unmute, "", "Usage:"},
+	}
+	for _, tt := range tests {
+		got, err := tt.op.Execute(ctx, tt.args)
+		if err != nil {
+			t.Fatalf("/%s %q: %v", tt.op.Name(), tt.args, err)
+		}
+		if !strings.Contains(got, tt.want) {
+			t.Errorf("/%s %q = %q, want it to contain %q", tt.op.Name(), tt.args, got, tt.want)
+		}
+	}
+
+	reg.Route("backup", "nightly run done")
+	got, _ := unmute.Execute(ctx, "backup")
+	if want := "Unmuted backup\n\nHeld while backup was muted (1):\n- nightly run done"; got != want {
+		t.Errorf("unmute with digest = %q, want %q", got, want)
+	}
+
+	if mute.RiskFor("") != ops.RiskNone || mute.RiskFor("cron") != ops.RiskLow {
+		t.Error("unexpected risk levels")
+	}
+}
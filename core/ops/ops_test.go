@@ -2,8 +2,10 @@ package ops_test
 
 import (
 	"context"
+	"errors"
 	"strings"
 	"testing"
+	"time"
 
 	"github.com/jdelaire/openslack/core/ops"
 )
@@ -14,8 +16,8 @@ func TestStatusOutput(t *testing.T) {
 	if err != nil {
 		t.Fatalf("execute: %v", err)
 	}
-	if !strings.Contains(result, "Status: OK") {
-		t.Errorf("missing 'Status: OK' in %q", result)
+	if !strings.HasPrefix(result, "Status:     OK\n") {
+		t.Errorf("missing aligned 'Status: OK' in %q", result)
 	}
 	if !strings.Contains(result, "Uptime:") {
 		t.Errorf("missing 'Uptime:' in %q", result)
@@ -28,6 +30,39 @@ func TestStatusOutput(t *testing.T) {
 	}
 }
 
+func TestStatusDroppedUpdates(t *testing.T) {
+	op := &ops.StatusOp{DroppedUpdates: func() map[string]int64 {
+		return map[string]int64{"message without text": 2, "edited_message": 5}
+	}}
+	result, err := op.Execute(context.Background(), "")
+	if err != nil {
+		t.Fatalf("execute: %v", err)
+	}
+	if !strings.Contains(result, "Dropped updates: edited_message 5, message without text 2") {
+		t.Errorf("missing dropped updates in %q", result)
+	}
+
+	op.DroppedUpdates = func() map[string]int64 { return nil }
+	result, _ = op.Execute(context.Background(), "")
+	if strings.Contains(result, "Dropped") {
+		t.Errorf("unexpected dropped updates line in %q", result)
+	}
+}
+
+func TestStatusReceiverDegraded(t *testing.T) {
+	since := time.Date(2026, 3, 2, 10, 42, 0, 0, time.Local)
+	op := &ops.StatusOp{ReceiverDegraded: func() (time.Time, error, bool) {
+		return since, errors.New("api status: 502"), true
+	}}
+	result, err := op.Execute(context.Background(), "")
+	if err != nil {
+		t.Fatalf("execute: %v", err)
+	}
+	if !strings.Contains(result, "Receiver:   degraded since 10:42, last error: api status: 502") {
+		t.Errorf("missing degraded receiver in %q", result)
+	}
+}
+
 func TestStatusName(t *testing.T) {
 	op := &ops.StatusOp{}
 	if op.Name() != "status" {
@@ -78,6 +113,36 @@ func TestHelpSortOrder(t *testing.T) {
 	}
 }
 
+func TestHelpFollowsRegistryChanges(t *testing.T) {
+	reg := ops.NewRegistry()
+	reg.Register(&mockOp{name: "tasks", desc: "t"})
+	op := &ops.HelpOp{Registry: reg}
+	op.Execute(context.Background(), "")
+
+	reg.Register(&mockOp{name: "deploy", desc: "d"})
+	reg.Unregister("tasks")
+	result, _ := op.Execute(context.Background(), "")
+	if !strings.Contains(result, "/deploy") || strings.Contains(result, "/tasks") {
+		t.Errorf("help after reload = %q", result)
+	}
+}
+
+func TestHelpBotScope(t *testing.T) {
+	reg := ops.NewRegistry()
+	reg.Register(&mockOp{name: "tasks", desc: "t"})
+	reg.Register(&mockOp{name: "deploy", desc: "d"})
+
+	op := &ops.HelpOp{Registry: reg}
+	ctx := ops.WithBot(context.Background(), ops.Bot{Name: "family", Ops: []string{"tasks"}})
+	result, err := op.Execute(ctx, "")
+	if err != nil {
+		t.Fatalf("execute: %v", err)
+	}
+	if !strings.Contains(result, "/tasks") || strings.Contains(result, "/deploy") {
+		t.Errorf("help for family bot = %q, want only /tasks", result)
+	}
+}
+
 func TestHelpEmpty(t *testing.T) {
 	reg := ops.NewRegistry()
 	op := &ops.HelpOp{Registry: reg}
@@ -89,3 +154,41 @@ func TestHelpEmpty(t *testing.T) {
 		t.Errorf("expected empty message, got: %q", result)
 	}
 }
+
+func TestHelpBadges(t *testing.T) {
+	reg := ops.NewRegistry()
+	reg.Register(&ops.StatusOp{})
+	reg.Register(&ops.HelpOp{Registry: reg})
+
+	badges := map[ops.RiskLevel]string{ops.RiskNone: "[open]", ops.RiskLow: "[totp]"}
+	op := &ops.HelpOp{Registry: reg, Badge: func(r ops.RiskLevel) string { return badges[r] }}
+	result, err := op.Execute(context.Background(), "")
+	if err != nil {
+		t.Fatalf("execute: %v", err)
+	}
+	for _, want := range []string{"  [open] /help — ", "  [totp] /status — "} {
+		if !strings.Contains(result, want) {
+			t.Errorf("missing %q in %q", want, result)
+		}
+	}
+}
+
+func TestHelpHidesOpsDeniedToChat(t *testing.T) {
+	reg := ops.NewRegistry()
+	reg.Register(&ops.StatusOp{})
+	reg.Register(&ops.ShellOp{CmdName: "deploy", Desc: "deploy", Command: "true", ChatAccess: ops.ChatAccess{AllowedChats: []int64{100}}})
+	op := &ops.HelpOp{Registry: reg}
+
+	for chatID, want := range map[int64]bool{100: true, 200: false} {
+		result, err := op.Execute(ops.WithChatID(context.Background(), chatID), "")
+		if err != nil {
+			t.Fatalf("execute: %v", err)
+		}
+		if got := strings.Contains(result, "/deploy"); got != want {
+			t.Errorf("chat %d sees /deploy = %v, want %v", chatID, got, want)
+		}
+		if !strings.Contains(result, "/status") {
+			t.Errorf("chat %d missing /status: %q", chatID, result)
+		}
+	}
+}
@@ -0,0 +1,118 @@
+package ops
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/jdelaire/openslack/core/approval"
+	"github.com/jdelaire/openslack/internal/outbox"
+)
+
+const pendingUsage = "Usage:\n/pending\n/pending cancel <id>"
+
+// PendingOp lists notifications queued with send_at and cancels them.
+// With Approvals set, the listing also shows the chat's outstanding /do
+// approvals.
+type PendingOp struct {
+	Queue     *outbox.Queue
+	Approvals *approval.Store
+}
+
+func (o *PendingOp) Name() string        { return "pending" }
+func (o *PendingOp) Description() string { return "List approvals and scheduled notifications" }
+func (o *PendingOp) Usage() string       { return pendingUsage }
+
+// RiskFor allows listing without TOTP; cancelling needs it.
+func (o *PendingOp) RiskFor(args string) RiskLevel {
+	if len(strings.Fields(args)) == 0 {
+		return RiskNone
+	}
+	return RiskLow
+}
+
+func (o *PendingOp) Execute(ctx context.Context, args string) (string, error) {
+	fields := strings.Fields(args)
+	if len(fields) == 0 {
+		if o.Approvals == nil {
+			return o.list()
+		}
+		return o.listAll(ctx)
+	}
+	if len(fields) != 2 || fields[0] != "cancel" {
+		return pendingUsage, nil
+	}
+
+	ok, err := o.Queue.Cancel(fields[1])
+	if err != nil {
+		return "", err
+	}
+	if !ok {
+		return fmt.Sprintf("No scheduled notification %s", fields[1]), nil
+	}
+	return fmt.Sprintf("Cancelled %s", fields[1]), nil
+}
+
+// listAll lists the chat's approvals above the scheduled notifications.
+func (o *PendingOp) listAll(ctx context.Context) (string, error) {
+	chatID, ok := ChatIDFrom(ctx)
+	if !ok {
+		return "", errors.New("pending: no chat in context")
+	}
+	var b strings.Builder
+	approvals := o.Approvals.Pending(chatID)
+	if len(approvals) == 0 {
+		b.WriteString("No pending approvals.")
+	} else {
+		b.WriteString("Approvals:")
+		for _, a := range approvals {
+			left := time.Until(a.ExpiresAt).Round(time.Second)
+			fmt.Fprintf(&b, "\n%s /%s", a.Nonce, a.OpName)
+			if a.Args != "" {
+				fmt.Fprintf(&b, " %s", preview(a.Args))
+			}
+			fmt.Fprintf(&b, " (%s left)", left)
+		}
+	}
+	if o.Queue == nil {
+		return b.String(), nil
+	}
+	scheduled, err := o.list()
+	if err != nil {
+		return "", err
+	}
+	if !strings.HasPrefix(scheduled, "No ") {
+		scheduled = "Scheduled:\n" + scheduled
+	}
+	return b.String() + "\n\n" + scheduled, nil
+}
+
+func (o *PendingOp) list() (string, error) {
+	msgs, err := o.Queue.Pending()
+	if err != nil {
+		return "", err
+	}
+	if len(msgs) == 0 {
+		return "No scheduled notifications.", nil
+	}
+	lines := make([]string, 0, len(msgs))
+	for _, m := range msgs {
+		line := fmt.Sprintf("%s %s %s", m.ID, m.SendAt.Local().Format("2006-01-02 15:04"), preview(m.Text))
+		if m.Source != "" {
+			line += " (" + m.Source + ")"
+		}
+		lines = append(lines, line)
+	}
+	return strings.Join(lines, "\n"), nil
+}
+
+// preview flattens text to one line of at most 60 characters.
+func preview(text string) string {
+	text = strings.ReplaceAll(text, "\n", " ")
+	if r := []rune(text); len(r) > 60 {
+		text = string(r[:60]) + "…"
+	}
+	return text
+}
@@ -0,0 +1,95 @@
+package ops_test
+
+import (
+	"context"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/jdelaire/openslack/core/approval"
+	"github.com/jdelaire/openslack/core/ops"
+	"github.com/jdelaire/openslack/internal/outbox"
+	"github.com/jdelaire/openslack/internal/state"
+)
+
+func TestPendingOp(t *testing.T) {
+	queue := outbox.NewQueue(state.NewStore(filepath.Join(t.TempDir(), "state.json")), nil, nil)
+	op := &ops.PendingOp{Queue: queue}
+	ctx := context.Background()
+
+	if got, _ := op.Execute(ctx, ""); got != "No scheduled notifications." {
+		t.Errorf("empty list = %q", got)
+	}
+
+	sendAt := time.Date(2026, 3, 2, 7, 0, 0, 0, time.Local)
+	id, err := queue.Schedule("Batch finished\nall green", "batch.sh", sendAt)
+	if err != nil {
+		t.Fatalf("schedule: %v", err)
+	}
+
+	tests := []struct {
+		args string
+		want string
+	}{
+		{"", id + " 2026-03-02 07:00 Batch finished all green (batch.sh)"},
+		{"cancel " + id, "Cancelled " + id},
+		{"cancel " + id, "No scheduled notification " + id},
+		{"cancel", "Usage:"},
+		{"bogus x", "Usage:"},
+	}
+	for _, tt := range tests {
+		got, err := op.Execute(ctx, tt.args)
+		if err != nil {
+			t.Fatalf("execute %q: %v", tt.args, err)
+		}
+		if !strings.HasPrefix(got, tt.want) {
+			t.Errorf("execute %q = %q, want prefix %q", tt.args, got, tt.want)
+		}
+	}
+}
+
+func TestPendingOpListsApprovals(t *testing.T) {
+	queue := outbox.NewQueue(state.NewStore(filepath.Join(t.TempDir(), "state.json")), nil, nil)
+	approvals := approval.New()
+	op := &ops.PendingOp{Queue: queue, Approvals: approvals}
+	ctx := ops.WithChatID(context.Background(), 100)
+
+	got, err := op.Execute(ctx, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := "No pending approvals.\n\nNo scheduled notifications."; got != want {
+		t.Errorf("empty list = %q, want %q", got, want)
+	}
+
+	nonce, _ := approvals.Create(100, "deploy", "prod\n--force")
+	approvals.Create(200, "restart", "")
+	id, _ := queue.Schedule("Batch finished", "", time.Date(2026, 3, 2, 7, 0, 0, 0, time.Local))
+
+	got, err = op.Execute(ctx, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	lines := strings.Split(got, "\n")
+	if len(lines) != 5 || lines[0] != "Approvals:" || lines[3] != "Scheduled:" || !strings.HasPrefix(lines[4], id) {
+		t.Fatalf("list = %q", got)
+	}
+	if !strings.HasPrefix(lines[1], nonce+" /deploy prod --force (") || !strings.HasSuffix(lines[1], "s left)") {
+		t.Errorf("approval line = %q", lines[1])
+	}
+
+	if _, err := op.Execute(context.Background(), ""); err == nil {
+		t.Error("listing without a chat succeeded")
+	}
+}
+
+func TestPendingOpRisk(t *testing.T) {
+	op := &ops.PendingOp{}
+	if got := ops.RiskOfCall(op, ""); got != ops.RiskNone {
+		t.Errorf("list risk = %v, want none", got)
+	}
+	if got := ops.RiskOfCall(op, "cancel abc123"); got != ops.RiskLow {
+		t.Errorf("cancel risk = %v, want low", got)
+	}
+}
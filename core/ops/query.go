@@ -0,0 +1,71 @@
+package ops
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/jdelaire/openslack/core/audit"
+	"github.com/jdelaire/openslack/core/ops/format"
+)
+
+const queryUsage = "Usage: /query <name> [days]"
+
+// AuditQuerier runs the canned audit queries. *audit.Log implements it.
+type AuditQuerier interface {
+	Run(ctx context.Context, name string, since time.Time) (audit.Result, error)
+}
+
+// QueryOp answers the canned audit queries as chat tables. Only the
+// queries in audit.Queries can run, so it is read-only.
+type QueryOp struct {
+	Log AuditQuerier
+}
+
+func (o *QueryOp) Name() string        { return "query" }
+func (o *QueryOp) Description() string { return "Report on op runs and notifications" }
+func (o *QueryOp) Usage() string       { return queryUsage }
+func (o *QueryOp) Risk() RiskLevel     { return RiskNone }
+
+func (o *QueryOp) Execute(ctx context.Context, args string) (string, error) {
+	fields := strings.Fields(args)
+	if len(fields) == 0 {
+		return o.list(), nil
+	}
+	if len(fields) > 2 {
+		return queryUsage, nil
+	}
+	q, ok := audit.Lookup(fields[0])
+	if !ok {
+		return fmt.Sprintf("Unknown query %q.\n\n%s", fields[0], o.list()), nil
+	}
+	days := 7
+	if len(fields) == 2 {
+		n, err := strconv.Atoi(fields[1])
+		if err != nil || n < 1 || n > 90 {
+			return "Days must be between 1 and 90.", nil
+		}
+		days = n
+	}
+
+	res, err := o.Log.Run(ctx, q.Name, time.Now().AddDate(0, 0, -days))
+	if err != nil {
+		return "", err
+	}
+	title := fmt.Sprintf("%s, last %d days", q.Description, days)
+	if len(res.Rows) == 0 {
+		return title + ": nothing recorded.", nil
+	}
+	return title + "\n" + format.Table(res.Columns, res.Rows, 10), nil
+}
+
+func (o *QueryOp) list() string {
+	var b strings.Builder
+	b.WriteString(queryUsage + "\nQueries:")
+	for _, q := range audit.Queries {
+		fmt.Fprintf(&b, "\n  %s — %s", q.Name, q.Description)
+	}
+	return b.String()
+}
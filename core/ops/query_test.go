@@ -0,0 +1,65 @@
+package ops_test
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/jdelaire/openslack/core/audit"
+	"github.com/jdelaire/openslack/core/ops"
+)
+
+type fakeAudit struct {
+	name  string
+	since time.Time
+	res   audit.Result
+	err   error
+}
+
+func (f *fakeAudit) Run(_ context.Context, name string, since time.Time) (audit.Result, error) {
+	f.name, f.since = name, since
+	return f.res, f.err
+}
+
+func TestQueryOp(t *testing.T) {
+	log := &fakeAudit{res: audit.Result{
+		Columns: []string{"Op", "Failed", "Runs"},
+		Rows:    [][]string{{"deploy", "3", "10"}},
+	}}
+	op := &ops.QueryOp{Log: log}
+	ctx := context.Background()
+
+	got, err := op.Execute(ctx, "failures 30")
+	if err != nil {
+		t.Fatal(err)
+	}
+	lines := strings.Split(got, "\n")
+	if len(lines) != 3 || lines[0] != "Failure counts by op, last 30 days" || strings.Join(strings.Fields(lines[2]), " ") != "deploy 3 10" {
+		t.Errorf("result = %q", got)
+	}
+	if log.name != "failures" || time.Since(log.since).Round(time.Hour) != 30*24*time.Hour {
+		t.Errorf("ran %q since %v", log.name, log.since)
+	}
+
+	log.res.Rows = nil
+	if got, _ := op.Execute(ctx, "sources"); got != "Busiest notification sources, last 7 days: nothing recorded." {
+		t.Errorf("empty result = %q", got)
+	}
+
+	for _, args := range []string{"", "drop", "slowest 0", "slowest x", "slowest 1 2"} {
+		got, err := op.Execute(ctx, args)
+		if err != nil || got == "" {
+			t.Errorf("Execute(%q) = %q, %v", args, got, err)
+		}
+		if strings.Contains(got, "last") {
+			t.Errorf("Execute(%q) ran a query: %q", args, got)
+		}
+	}
+
+	log.err = errors.New("database is locked")
+	if _, err := op.Execute(ctx, "slowest"); err == nil {
+		t.Error("query error not returned")
+	}
+}
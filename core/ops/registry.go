@@ -14,10 +14,23 @@ type Op interface {
 	Execute(ctx context.Context, args string) (string, error)
 }
 
-// Registry holds registered operations keyed by name.
+// Usager is an optional interface for ops that describe their arguments,
+// with the usage text they reply with when called wrongly.
+type Usager interface {
+	Usage() string
+}
+
+// Registry holds registered operations keyed by name, and any configured
+// risk overrides and TOTP exemptions.
 type Registry struct {
-	mu  sync.RWMutex
-	ops map[string]Op
+	mu         sync.RWMutex
+	ops        map[string]Op
+	overrides  map[string]RiskLevel
+	exemptions TOTPExemptions
+
+	subMu   sync.Mutex
+	subs    map[int]func()
+	lastSub int
 }
 
 // NewRegistry creates an empty operation registry.
@@ -28,21 +41,27 @@ func NewRegistry() *Registry {
 // Register adds an operation. Returns an error if the name is already registered.
 func (r *Registry) Register(op Op) error {
 	r.mu.Lock()
-	defer r.mu.Unlock()
-
 	name := op.Name()
 	if _, exists := r.ops[name]; exists {
+		r.mu.Unlock()
 		return fmt.Errorf("op already registered: %s", name)
 	}
 	r.ops[name] = op
+	r.mu.Unlock()
+
+	r.changed()
 	return nil
 }
 
 // Unregister removes an operation by name. No-op if the name doesn't exist.
 func (r *Registry) Unregister(name string) {
 	r.mu.Lock()
-	defer r.mu.Unlock()
+	_, exists := r.ops[name]
 	delete(r.ops, name)
+	r.mu.Unlock()
+	if exists {
+		r.changed()
+	}
 }
 
 // Get returns the operation with the given name, or nil if not found.
@@ -52,6 +71,89 @@ func (r *Registry) Get(name string) Op {
 	return r.ops[name]
 }
 
+// SetRiskOverrides replaces the configured risk levels, keyed by op name.
+// An override applies to every call of the op, in place of what the op
+// declares. Names need not be registered yet.
+func (r *Registry) SetRiskOverrides(overrides map[string]RiskLevel) {
+	r.mu.Lock()
+	r.overrides = overrides
+	r.mu.Unlock()
+	r.changed()
+}
+
+// RiskOf is RiskOf, honouring any override for op.
+func (r *Registry) RiskOf(op Op) RiskLevel {
+	if risk, ok := r.override(op.Name()); ok {
+		return risk
+	}
+	return RiskOf(op)
+}
+
+// RiskOfCall is RiskOfCall, honouring any override for op.
+func (r *Registry) RiskOfCall(op Op, args string) RiskLevel {
+	if risk, ok := r.override(op.Name()); ok {
+		return risk
+	}
+	return RiskOfCall(op, args)
+}
+
+func (r *Registry) override(name string) (RiskLevel, bool) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	risk, ok := r.overrides[name]
+	return risk, ok
+}
+
+// SetTOTPExemptions replaces the ops each chat may run without a TOTP
+// code. Names need not be registered yet.
+func (r *Registry) SetTOTPExemptions(exemptions TOTPExemptions) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.exemptions = exemptions
+}
+
+// TOTPExempt reports whether low-risk calls of the op named name run
+// without a TOTP code in chatID. High-risk calls are never exempt.
+func (r *Registry) TOTPExempt(chatID int64, name string) bool {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	return r.exemptions.Exempt(chatID, name)
+}
+
+// OnChange calls fn after every change to the registered ops or the risk
+// overrides, so consumers can refresh what they derived from List instead
+// of listing on every use. fn runs synchronously, outside the registry's
+// lock, and should be cheap: a reload calls it once per op. The returned
+// func unsubscribes.
+func (r *Registry) OnChange(fn func()) (cancel func()) {
+	r.subMu.Lock()
+	defer r.subMu.Unlock()
+	if r.subs == nil {
+		r.subs = make(map[int]func())
+	}
+	r.lastSub++
+	id := r.lastSub
+	r.subs[id] = fn
+	return func() {
+		r.subMu.Lock()
+		defer r.subMu.Unlock()
+		delete(r.subs, id)
+	}
+}
+
+// changed calls the OnChange subscribers.
+func (r *Registry) changed() {
+	r.subMu.Lock()
+	subs := make([]func(), 0, len(r.subs))
+	for _, fn := range r.subs {
+		subs = append(subs, fn)
+	}
+	r.subMu.Unlock()
+	for _, fn := range subs {
+		fn()
+	}
+}
+
 // List returns all registered operation names sorted alphabetically.
 func (r *Registry) List() []Op {
 	r.mu.RLock()
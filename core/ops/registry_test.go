@@ -118,3 +118,24 @@ func TestListEmpty(t *testing.T) {
 		t.Fatalf("len = %d, want 0", len(list))
 	}
 }
+
+func TestOnChange(t *testing.T) {
+	reg := ops.NewRegistry()
+	calls := 0
+	cancel := reg.OnChange(func() { calls++ })
+
+	reg.Register(&mockOp{name: "a"})
+	reg.Register(&mockOp{name: "a"}) // duplicate, no change
+	reg.Unregister("missing")        // no change
+	reg.Unregister("a")
+	reg.SetRiskOverrides(map[string]ops.RiskLevel{"a": ops.RiskHigh})
+	if calls != 3 {
+		t.Errorf("OnChange called %d times, want 3", calls)
+	}
+
+	cancel()
+	reg.Register(&mockOp{name: "b"})
+	if calls != 3 {
+		t.Errorf("OnChange called after cancel")
+	}
+}
@@ -0,0 +1,108 @@
+package ops
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/jdelaire/openslack/internal/results"
+	"github.com/jdelaire/openslack/internal/textdiff"
+)
+
+const (
+	resultTimeLayout = "2006-01-02 15:04"
+	maxResultDiff    = 40
+)
+
+// LastOp shows the previous output of a command without running it again.
+// Viewing a result needs the same TOTP as running the command would.
+type LastOp struct {
+	Results  *results.Store
+	Registry *Registry
+}
+
+func (o *LastOp) Name() string        { return "last" }
+func (o *LastOp) Description() string { return "Show a command's last output" }
+
+func (o *LastOp) RiskFor(args string) RiskLevel {
+	return resultRisk(o.Registry, args)
+}
+
+func (o *LastOp) Execute(ctx context.Context, args string) (string, error) {
+	name, runs, err := loadRuns(ctx, o.Results, args)
+	if err != nil {
+		return "", err
+	}
+	if name == "" {
+		return "Usage: /last <command>", nil
+	}
+	if len(runs) == 0 {
+		return fmt.Sprintf("No saved output for /%s", name), nil
+	}
+	last := runs[len(runs)-1]
+	return fmt.Sprintf("/%s at %s:\n%s", name, last.At.Format(resultTimeLayout), last.Output), nil
+}
+
+// DiffOp shows what changed between the last two runs of a command.
+type DiffOp struct {
+	Results  *results.Store
+	Registry *Registry
+}
+
+func (o *DiffOp) Name() string        { return "diff" }
+func (o *DiffOp) Description() string { return "Diff a command's last two outputs" }
+
+func (o *DiffOp) RiskFor(args string) RiskLevel {
+	return resultRisk(o.Registry, args)
+}
+
+func (o *DiffOp) Execute(ctx context.Context, args string) (string, error) {
+	name, runs, err := loadRuns(ctx, o.Results, args)
+	if err != nil {
+		return "", err
+	}
+	if name == "" {
+		return "Usage: /diff <command>", nil
+	}
+	if len(runs) < 2 {
+		return fmt.Sprintf("Need two saved runs of /%s to diff", name), nil
+	}
+	prev, last := runs[len(runs)-2], runs[len(runs)-1]
+	if prev.Output == last.Output {
+		return fmt.Sprintf("/%s unchanged between %s and %s", name, prev.At.Format(resultTimeLayout), last.At.Format(resultTimeLayout)), nil
+	}
+	return fmt.Sprintf("/%s %s → %s:\n%s", name, prev.At.Format(resultTimeLayout), last.At.Format(resultTimeLayout),
+		textdiff.Lines(prev.Output, last.Output, maxResultDiff)), nil
+}
+
+// resultRisk is the risk of the named command, capped at RiskLow since
+// showing a saved result runs nothing. Unknown commands need TOTP.
+func resultRisk(reg *Registry, args string) RiskLevel {
+	fields := strings.Fields(args)
+	if reg == nil || len(fields) != 1 {
+		return RiskLow
+	}
+	op := reg.Get(strings.ToLower(strings.TrimPrefix(fields[0], "/")))
+	if op == nil || reg.RiskOf(op) != RiskNone {
+		return RiskLow
+	}
+	return RiskNone
+}
+
+// loadRuns parses a single command name, with or without its leading
+// slash, and returns its saved runs for the calling chat. name is "" when
+// args are not a single command.
+func loadRuns(ctx context.Context, store *results.Store, args string) (name string, runs []results.Run, err error) {
+	fields := strings.Fields(args)
+	if len(fields) != 1 {
+		return "", nil, nil
+	}
+	chatID, ok := ChatIDFrom(ctx)
+	if !ok {
+		return "", nil, errors.New("no chat in context")
+	}
+	name = strings.ToLower(strings.TrimPrefix(fields[0], "/"))
+	runs, err = store.Runs(chatID, name)
+	return name, runs, err
+}
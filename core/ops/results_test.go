@@ -0,0 +1,76 @@
+package ops_test
+
+import (
+	"context"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/jdelaire/openslack/core/ops"
+	"github.com/jdelaire/openslack/internal/results"
+	"github.com/jdelaire/openslack/internal/state"
+)
+
+func TestLastAndDiffOps(t *testing.T) {
+	store := results.NewStore(state.NewStore(filepath.Join(t.TempDir(), "state.json")))
+	last := &ops.LastOp{Results: store}
+	diff := &ops.DiffOp{Results: store}
+	ctx := ops.WithChatID(context.Background(), 100)
+	at := time.Date(2026, 3, 1, 9, 0, 0, 0, time.Local)
+
+	check := func(op ops.Op, args, want string) {
+		t.Helper()
+		got, err := op.Execute(ctx, args)
+		if err != nil {
+			t.Fatalf("/%s %q: %v", op.Name(), args, err)
+		}
+		if !strings.HasPrefix(got, want) {
+			t.Errorf("/%s %q = %q, want prefix %q", op.Name(), args, got, want)
+		}
+	}
+
+	check(last, "status", "No saved output for /status")
+	check(last, "", "Usage:")
+	check(diff, "a b", "Usage:")
+
+	store.Record(100, "status", "disk 40%\nload 0.1", at)
+	check(last, "/status", "/status at 2026-03-01 09:00:\ndisk 40%\nload 0.1")
+	check(diff, "status", "Need two saved runs of /status")
+
+	store.Record(100, "status", "disk 40%\nload 0.1", at.Add(time.Hour))
+	check(diff, "status", "/status unchanged between 2026-03-01 09:00 and 2026-03-01 10:00")
+
+	store.Record(100, "status", "disk 55%\nload 0.1", at.Add(2*time.Hour))
+	check(diff, "STATUS", "/status 2026-03-01 10:00 → 2026-03-01 11:00:\n+ disk 55%\n- disk 40%")
+
+	// Results are per chat.
+	other := ops.WithChatID(context.Background(), 200)
+	if got, _ := last.Execute(other, "status"); !strings.HasPrefix(got, "No saved output") {
+		t.Errorf("other chat /last = %q", got)
+	}
+}
+
+func TestLastAndDiffOpRisk(t *testing.T) {
+	reg := ops.NewRegistry()
+	reg.Register(&ops.HelpOp{Registry: reg})
+	reg.Register(&ops.ShellOp{CmdName: "disk", Command: "df -h"})
+
+	tests := []struct {
+		args string
+		want ops.RiskLevel
+	}{
+		{"help", ops.RiskNone},
+		{"/help", ops.RiskNone},
+		{"disk", ops.RiskLow},
+		{"unknown", ops.RiskLow},
+		{"", ops.RiskLow},
+	}
+	for _, op := range []ops.Op{&ops.LastOp{Registry: reg}, &ops.DiffOp{Registry: reg}} {
+		for _, tt := range tests {
+			if got := ops.RiskOfCall(op, tt.args); got != tt.want {
+				t.Errorf("/%s %q risk = %v, want %v", op.Name(), tt.args, got, tt.want)
+			}
+		}
+	}
+}
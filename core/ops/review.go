@@ -0,0 +1,130 @@
+package ops
+
+import (
+	"context"
+	"fmt"
+	"slices"
+	"strconv"
+	"strings"
+
+	"github.com/jdelaire/openslack/core/ops/format"
+	"github.com/jdelaire/openslack/core/policy"
+	"github.com/jdelaire/openslack/internal/sources"
+)
+
+// AccessOp reports who can do what: the allowlisted chats, and for each
+// command its risk level, the chats that may run it and the chats exempt
+// from its TOTP code, then the muted sources. Connector tools appear as
+// commands, so their chat_access rules show too. TOTP says whether codes
+// are checked at all; Sources may be nil.
+type AccessOp struct {
+	Registry *Registry
+	Policy   *policy.Policy
+	Sources  *sources.Registry
+	TOTP     bool
+}
+
+func (o *AccessOp) Name() string        { return "access" }
+func (o *AccessOp) Description() string { return "Review who can run which commands" }
+
+func (o *AccessOp) Execute(ctx context.Context, args string) (string, error) {
+	if strings.TrimSpace(args) != "" {
+		return "Usage: /access", nil
+	}
+	chats := o.Policy.ChatIDs()
+
+	var b strings.Builder
+	fmt.Fprintf(&b, "Chats: %s\n", joinChats(chats))
+	if o.TOTP {
+		b.WriteString("TOTP: low-risk commands need a code; high-risk ones need /do and /approve.\n\n")
+	} else {
+		b.WriteString("TOTP: off. Every command runs without a code or approval.\n\n")
+	}
+
+	overridden := false
+	var rows [][]string
+	for _, op := range o.Registry.List() {
+		if !BotAllowed(ctx, op.Name()) {
+			continue
+		}
+		risk, mark := o.risk(op)
+		overridden = overridden || strings.HasSuffix(mark, "*")
+		var allowed, exempt []int64
+		for _, id := range chats {
+			if !ChatAllowed(op, id) {
+				continue
+			}
+			allowed = append(allowed, id)
+			if o.Registry.TOTPExempt(id, op.Name()) {
+				exempt = append(exempt, id)
+			}
+		}
+		who := "all"
+		if len(allowed) < len(chats) {
+			who = joinChats(allowed)
+		}
+		if risk != RiskLow {
+			exempt = nil // exemptions only apply to low-risk calls
+		}
+		rows = append(rows, []string{op.Name(), mark, who, joinChats(exempt)})
+	}
+	b.WriteString(format.Table([]string{"Command", "Risk", "Chats", "No TOTP in"}, rows, 0))
+	if overridden {
+		b.WriteString("\n* set in risk.json")
+	}
+
+	if o.Sources != nil {
+		muted, err := o.mutedSources()
+		if err != nil {
+			return "", err
+		}
+		if len(muted) > 0 {
+			fmt.Fprintf(&b, "\n\nMuted sources: %s", strings.Join(muted, ", "))
+		}
+	}
+	return b.String(), nil
+}
+
+// risk returns op's effective risk level and how to show it: the level
+// name, "by args" for ops that classify each call, and a "*" when an
+// override applies.
+func (o *AccessOp) risk(op Op) (RiskLevel, string) {
+	if risk, ok := o.Registry.override(op.Name()); ok {
+		return risk, risk.String() + "*"
+	}
+	if _, ok := op.(ArgsRiskClassifier); ok {
+		return RiskOf(op), "by args"
+	}
+	risk := RiskOf(op)
+	return risk, risk.String()
+}
+
+func (o *AccessOp) mutedSources() ([]string, error) {
+	entries, err := o.Sources.List()
+	if err != nil {
+		return nil, err
+	}
+	var muted []string
+	for _, e := range entries {
+		switch {
+		case !e.MutedUntil.IsZero():
+			muted = append(muted, fmt.Sprintf("%s (until %s)", e.Key, e.MutedUntil.Local().Format("15:04")))
+		case e.Muted:
+			muted = append(muted, e.Key)
+		}
+	}
+	return muted, nil
+}
+
+// joinChats lists chat IDs in order, or "-" for none.
+func joinChats(ids []int64) string {
+	if len(ids) == 0 {
+		return "-"
+	}
+	ids = slices.Sorted(slices.Values(ids))
+	parts := make([]string, len(ids))
+	for i, id := range ids {
+		parts[i] = strconv.FormatInt(id, 10)
+	}
+	return strings.Join(parts, ", ")
+}
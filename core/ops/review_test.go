@@ -0,0 +1,63 @@
+package ops_test
+
+import (
+	"context"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/jdelaire/openslack/core/ops"
+	"github.com/jdelaire/openslack/core/policy"
+	"github.com/jdelaire/openslack/internal/sources"
+	"github.com/jdelaire/openslack/internal/state"
+)
+
+type restrictedOp struct {
+	mockOp
+	ops.ChatAccess
+}
+
+func TestAccessOp(t *testing.T) {
+	reg := ops.NewRegistry()
+	reg.Register(&mockOp{name: "tasks", desc: "t"})
+	reg.Register(&ops.HelpOp{Registry: reg})
+	reg.Register(&highRiskOp{mockOp{name: "deploy"}})
+	reg.Register(&argsRiskOp{mockOp{name: "mixed"}})
+	reg.Register(&restrictedOp{mockOp{name: "nas.wake"}, ops.ChatAccess{AllowedChats: []int64{100}}})
+	reg.SetRiskOverrides(map[string]ops.RiskLevel{"deploy": ops.RiskLow})
+	reg.SetTOTPExemptions(ops.TOTPExemptions{200: {"tasks": true, "help": true}})
+
+	srcs := sources.NewRegistry(&sources.Config{Sources: map[string]sources.Source{
+		"ci":   {Name: "CI", Muted: true},
+		"cron": {Name: "Cron"},
+	}}, state.NewStore(filepath.Join(t.TempDir(), "state.json")))
+	op := &ops.AccessOp{Registry: reg, Policy: policy.New([]int64{200, 100}), Sources: srcs, TOTP: true}
+
+	got, err := op.Execute(context.Background(), "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, want := range []string{
+		"Chats: 100, 200",
+		"low-risk commands need a code",
+		"deploy    low*     all    -",
+		"help      none     all    -",
+		"mixed     by args  all    -",
+		"nas.wake  low      100    -",
+		"tasks     low      all    200",
+		"* set in risk.json",
+		"Muted sources: ci",
+	} {
+		if !strings.Contains(got, want) {
+			t.Errorf("report lacks %q:\n%s", want, got)
+		}
+	}
+
+	op = &ops.AccessOp{Registry: reg, Policy: policy.New([]int64{100})}
+	if got, _ := op.Execute(context.Background(), ""); !strings.Contains(got, "TOTP: off") || strings.Contains(got, "Muted") {
+		t.Errorf("report without TOTP or sources:\n%s", got)
+	}
+	if got, _ := op.Execute(context.Background(), "x"); got != "Usage: /access" {
+		t.Errorf("/access x = %q", got)
+	}
+}
@@ -1,5 +1,13 @@
 package ops
 
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+)
+
 // RiskLevel classifies how dangerous an operation is.
 type RiskLevel int
 
@@ -9,6 +17,17 @@ const (
 	RiskHigh                  // Two-step /do + /approve flow
 )
 
+// String returns the level's config name: "none", "low" or "high".
+func (r RiskLevel) String() string {
+	switch r {
+	case RiskNone:
+		return "none"
+	case RiskHigh:
+		return "high"
+	}
+	return "low"
+}
+
 // RiskClassifier is an optional interface ops may implement to declare
 // their risk level. Ops that don't implement it default to RiskLow.
 type RiskClassifier interface {
@@ -23,3 +42,103 @@ func RiskOf(op Op) RiskLevel {
 	}
 	return RiskLow
 }
+
+// ArgsRiskClassifier is an optional interface for ops whose risk depends on
+// the arguments, e.g. a read-only query versus a mutation through the same
+// command. It takes precedence over RiskClassifier.
+type ArgsRiskClassifier interface {
+	RiskFor(args string) RiskLevel
+}
+
+// RiskOfCall returns the risk level of invoking op with args. Ops that
+// implement ArgsRiskClassifier are asked per call; others fall back to RiskOf.
+func RiskOfCall(op Op, args string) RiskLevel {
+	if ac, ok := op.(ArgsRiskClassifier); ok {
+		return ac.RiskFor(args)
+	}
+	return RiskOf(op)
+}
+
+// ParseRiskLevel parses "none", "low" or "high".
+func ParseRiskLevel(s string) (RiskLevel, error) {
+	switch strings.ToLower(strings.TrimSpace(s)) {
+	case "none":
+		return RiskNone, nil
+	case "low":
+		return RiskLow, nil
+	case "high":
+		return RiskHigh, nil
+	}
+	return 0, fmt.Errorf("unknown risk level %q (want none, low or high)", s)
+}
+
+// LoadRiskOverrides reads a risk config file mapping op names to a risk
+// level, e.g. {"deploy": "high", "sample.echo": "none"}. Returns nil, nil
+// if the file does not exist.
+func LoadRiskOverrides(path string) (map[string]RiskLevel, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("read risk config: %w", err)
+	}
+
+	var raw map[string]string
+	if err := json.Unmarshal(data, &raw); err != nil {
+		return nil, fmt.Errorf("parse risk config: %w", err)
+	}
+	overrides := make(map[string]RiskLevel, len(raw))
+	for name, level := range raw {
+		risk, err := ParseRiskLevel(level)
+		if err != nil {
+			return nil, fmt.Errorf("risk config: /%s: %w", name, err)
+		}
+		overrides[strings.ToLower(strings.TrimPrefix(name, "/"))] = risk
+	}
+	return overrides, nil
+}
+
+// TOTPExemptions lists, per chat ID, the op names whose low-risk calls run
+// without a TOTP code in that chat.
+type TOTPExemptions map[int64]map[string]bool
+
+// Exempt reports whether op needs no TOTP code in chatID.
+func (e TOTPExemptions) Exempt(chatID int64, op string) bool {
+	return e[chatID][op]
+}
+
+// LoadTOTPExemptions reads a TOTP exemption file mapping chat IDs to op
+// names, e.g. {"123456789": ["tasks", "done"]}. Returns nil, nil if the
+// file does not exist.
+func LoadTOTPExemptions(path string) (TOTPExemptions, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("read totp exemptions: %w", err)
+	}
+
+	var raw map[string][]string
+	if err := json.Unmarshal(data, &raw); err != nil {
+		return nil, fmt.Errorf("parse totp exemptions: %w", err)
+	}
+	exemptions := make(TOTPExemptions, len(raw))
+	for chat, names := range raw {
+		chatID, err := strconv.ParseInt(chat, 10, 64)
+		if err != nil {
+			return nil, fmt.Errorf("totp exemptions: chat %q is not a chat ID", chat)
+		}
+		set := make(map[string]bool, len(names))
+		for _, name := range names {
+			name = strings.ToLower(strings.TrimPrefix(strings.TrimSpace(name), "/"))
+			if name == "" {
+				return nil, fmt.Errorf("totp exemptions: chat %d: empty command name", chatID)
+			}
+			set[name] = true
+		}
+		exemptions[chatID] = set
+	}
+	return exemptions, nil
+}
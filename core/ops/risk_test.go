@@ -1,6 +1,8 @@
 package ops_test
 
 import (
+	"os"
+	"path/filepath"
 	"testing"
 
 	"github.com/jdelaire/openslack/core/ops"
@@ -41,6 +43,15 @@ func TestRiskLevelValues(t *testing.T) {
 	}
 }
 
+func TestRiskLevelString(t *testing.T) {
+	for _, s := range []string{"none", "low", "high"} {
+		r, err := ops.ParseRiskLevel(s)
+		if err != nil || r.String() != s {
+			t.Errorf("ParseRiskLevel(%q).String() = %q, %v", s, r.String(), err)
+		}
+	}
+}
+
 type highRiskOp struct{ mockOp }
 
 func (h *highRiskOp) Risk() ops.RiskLevel { return ops.RiskHigh }
@@ -51,3 +62,95 @@ func TestRiskOfHighRisk(t *testing.T) {
 		t.Errorf("RiskOf(highRisk) = %d, want RiskHigh (%d)", got, ops.RiskHigh)
 	}
 }
+
+type argsRiskOp struct{ mockOp }
+
+func (a *argsRiskOp) Risk() ops.RiskLevel { return ops.RiskNone }
+func (a *argsRiskOp) RiskFor(args string) ops.RiskLevel {
+	if args == "delete" {
+		return ops.RiskHigh
+	}
+	return ops.RiskLow
+}
+
+func TestRiskOfCall(t *testing.T) {
+	mixed := &argsRiskOp{mockOp{name: "mixed"}}
+	tests := []struct {
+		name string
+		op   ops.Op
+		args string
+		want ops.RiskLevel
+	}{
+		{"plain op", &mockOp{name: "plain"}, "anything", ops.RiskLow},
+		{"static classifier", &ops.HelpOp{Registry: ops.NewRegistry()}, "", ops.RiskNone},
+		{"args classifier read", mixed, "list", ops.RiskLow},
+		{"args classifier write", mixed, "delete", ops.RiskHigh},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := ops.RiskOfCall(tt.op, tt.args); got != tt.want {
+				t.Errorf("RiskOfCall = %d, want %d", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestRegistryRiskOverrides(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "risk.json")
+	if got, err := ops.LoadRiskOverrides(path); got != nil || err != nil {
+		t.Fatalf("missing file = %v, %v", got, err)
+	}
+	os.WriteFile(path, []byte(`{"mixed": "none", "/Plain": "HIGH"}`), 0600)
+	overrides, err := ops.LoadRiskOverrides(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	reg := ops.NewRegistry()
+	reg.SetRiskOverrides(overrides)
+	mixed := &argsRiskOp{mockOp{name: "mixed"}}
+	plain := &mockOp{name: "plain"}
+	other := &mockOp{name: "other"}
+	if got := reg.RiskOfCall(mixed, "delete"); got != ops.RiskNone {
+		t.Errorf("overridden per-call risk = %d, want none", got)
+	}
+	if got := reg.RiskOf(plain); got != ops.RiskHigh {
+		t.Errorf("overridden risk = %d, want high", got)
+	}
+	if got := reg.RiskOfCall(other, ""); got != ops.RiskLow {
+		t.Errorf("risk without override = %d, want low", got)
+	}
+
+	os.WriteFile(path, []byte(`{"plain": "critical"}`), 0600)
+	if _, err := ops.LoadRiskOverrides(path); err == nil {
+		t.Error("unknown risk level accepted")
+	}
+}
+
+func TestRegistryTOTPExemptions(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "totp_exempt.json")
+	if got, err := ops.LoadTOTPExemptions(path); got != nil || err != nil {
+		t.Fatalf("missing file = %v, %v", got, err)
+	}
+	os.WriteFile(path, []byte(`{"100": ["tasks", "/Done"], "-200": ["status"]}`), 0600)
+	exemptions, err := ops.LoadTOTPExemptions(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	reg := ops.NewRegistry()
+	reg.SetTOTPExemptions(exemptions)
+	if !reg.TOTPExempt(100, "tasks") || !reg.TOTPExempt(100, "done") || !reg.TOTPExempt(-200, "status") {
+		t.Error("configured exemption not applied")
+	}
+	if reg.TOTPExempt(100, "status") || reg.TOTPExempt(300, "tasks") {
+		t.Error("exemption leaked to another op or chat")
+	}
+
+	for _, bad := range []string{`{"me": ["tasks"]}`, `{"100": [""]}`, `{"100": "tasks"}`} {
+		os.WriteFile(path, []byte(bad), 0600)
+		if _, err := ops.LoadTOTPExemptions(path); err == nil {
+			t.Errorf("%s accepted", bad)
+		}
+	}
+}
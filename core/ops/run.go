@@ -0,0 +1,128 @@
+package ops
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
+	"unicode/utf8"
+)
+
+const (
+	defaultRunParallelism = 4
+	maxHostOutput         = 1024
+)
+
+// GroupRunner executes commands on remote hosts and resolves host groups.
+type GroupRunner interface {
+	HostRunner
+	Group(name string) []string
+}
+
+// RunOp executes a command on every host in a group concurrently and
+// replies with one summary. Usage: /run <group> <command>
+type RunOp struct {
+	Hosts       GroupRunner
+	Parallelism int // max hosts running at once; 0 means 4
+}
+
+func (o *RunOp) Name() string        { return "run" }
+func (o *RunOp) Description() string { return "Run a command on all hosts in a group" }
+func (o *RunOp) Risk() RiskLevel     { return RiskHigh }
+
+// hostResult is the outcome of running the command on a single host.
+type hostResult struct {
+	host     string
+	output   string
+	exitCode int
+	err      error
+}
+
+func (o *RunOp) Execute(ctx context.Context, args string) (string, error) {
+	parts := strings.SplitN(strings.TrimSpace(args), " ", 2)
+	if len(parts) < 2 || strings.TrimSpace(parts[1]) == "" {
+		return "Usage: /run <group> <command>", nil
+	}
+	group, command := parts[0], strings.TrimSpace(parts[1])
+
+	members := o.Hosts.Group(group)
+	if len(members) == 0 {
+		return fmt.Sprintf("Unknown host group: %s", group), nil
+	}
+
+	parallelism := o.Parallelism
+	if parallelism <= 0 {
+		parallelism = defaultRunParallelism
+	}
+
+	results := make([]hostResult, len(members))
+	sem := make(chan struct{}, parallelism)
+	var wg sync.WaitGroup
+	for i, host := range members {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			sem <- struct{}{}
+			defer func() { <-sem }()
+
+			out, err := o.Hosts.Run(ctx, host, command, "")
+			results[i] = hostResult{host: host, output: out, exitCode: exitCode(err), err: err}
+		}()
+	}
+	wg.Wait()
+
+	return formatRunResults(group, results), nil
+}
+
+// exitCode extracts a remote exit status from err. It returns 0 for a nil
+// error and -1 when the command did not report a status (e.g. connection
+// failure or timeout).
+func exitCode(err error) int {
+	if err == nil {
+		return 0
+	}
+	var status interface{ ExitStatus() int }
+	if errors.As(err, &status) {
+		return status.ExitStatus()
+	}
+	return -1
+}
+
+func formatRunResults(group string, results []hostResult) string {
+	failed := 0
+	for _, r := range results {
+		if r.err != nil {
+			failed++
+		}
+	}
+
+	var b strings.Builder
+	fmt.Fprintf(&b, "/run %s: %d ok, %d failed\n", group, len(results)-failed, failed)
+	for _, r := range results {
+		switch {
+		case r.exitCode >= 0:
+			fmt.Fprintf(&b, "\n[%s] exit %d\n", r.host, r.exitCode)
+		default:
+			fmt.Fprintf(&b, "\n[%s] error: %s\n", r.host, r.err)
+		}
+		if r.output != "" {
+			b.WriteString(tail(r.output, maxHostOutput))
+			b.WriteString("\n")
+		}
+	}
+	return strings.TrimRight(b.String(), "\n")
+}
+
+// tail returns at most the last n bytes of s, marking truncation with "…".
+// The cut moves forward to a rune boundary, so no character is split.
+func tail(s string, n int) string {
+	if len(s) <= n {
+		return s
+	}
+	cut := len(s) - n
+	for cut < len(s) && !utf8.RuneStart(s[cut]) {
+		cut++
+	}
+	return "…" + s[cut:]
+}
@@ -0,0 +1,126 @@
+package ops_test
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+	"unicode/utf8"
+
+	"github.com/jdelaire/openslack/core/ops"
+)
+
+type exitErr struct{ code int }
+
+func (e *exitErr) Error() string   { return fmt.Sprintf("exit status %d", e.code) }
+func (e *exitErr) ExitStatus() int { return e.code }
+
+type fakeGroupRunner struct {
+	groups  map[string][]string
+	results map[string]error
+
+	mu       sync.Mutex
+	running  int32
+	maxAlive int32
+}
+
+func (f *fakeGroupRunner) Group(name string) []string { return f.groups[name] }
+
+func (f *fakeGroupRunner) Run(_ context.Context, host, command, _ string) (string, error) {
+	n := atomic.AddInt32(&f.running, 1)
+	f.mu.Lock()
+	if n > f.maxAlive {
+		f.maxAlive = n
+	}
+	f.mu.Unlock()
+	time.Sleep(10 * time.Millisecond)
+	atomic.AddInt32(&f.running, -1)
+	return host + ": " + command, f.results[host]
+}
+
+func TestRunOpAggregatesResults(t *testing.T) {
+	runner := &fakeGroupRunner{
+		groups: map[string][]string{"web": {"a", "b", "c"}},
+		results: map[string]error{
+			"b": fmt.Errorf("host %q: %w", "b", &exitErr{code: 2}),
+			"c": fmt.Errorf("host %q: dial: connection refused", "c"),
+		},
+	}
+	op := &ops.RunOp{Hosts: runner}
+
+	got, err := op.Execute(context.Background(), "web uptime")
+	if err != nil {
+		t.Fatalf("execute: %v", err)
+	}
+	for _, want := range []string{
+		"/run web: 1 ok, 2 failed",
+		"[a] exit 0\na: uptime",
+		"[b] exit 2\nb: uptime",
+		"[c] error: host \"c\": dial: connection refused",
+	} {
+		if !strings.Contains(got, want) {
+			t.Errorf("missing %q in:\n%s", want, got)
+		}
+	}
+	if strings.Index(got, "[a]") > strings.Index(got, "[c]") {
+		t.Error("results should follow group order")
+	}
+}
+
+func TestRunOpTruncatesOnRuneBoundary(t *testing.T) {
+	runner := &fakeGroupRunner{groups: map[string][]string{"web": {"a"}}}
+	op := &ops.RunOp{Hosts: runner}
+
+	got, err := op.Execute(context.Background(), "web "+strings.Repeat("€", 500))
+	if err != nil {
+		t.Fatalf("execute: %v", err)
+	}
+	if !utf8.ValidString(got) || !strings.Contains(got, "…€€") {
+		t.Errorf("truncated output is not valid UTF-8: %q", got[len(got)-40:])
+	}
+}
+
+func TestRunOpBoundedParallelism(t *testing.T) {
+	runner := &fakeGroupRunner{
+		groups: map[string][]string{"all": {"h1", "h2", "h3", "h4", "h5", "h6"}},
+	}
+	op := &ops.RunOp{Hosts: runner, Parallelism: 2}
+
+	if _, err := op.Execute(context.Background(), "all true"); err != nil {
+		t.Fatalf("execute: %v", err)
+	}
+	if runner.maxAlive > 2 {
+		t.Errorf("max concurrent = %d, want <= 2", runner.maxAlive)
+	}
+}
+
+func TestRunOpUsage(t *testing.T) {
+	op := &ops.RunOp{Hosts: &fakeGroupRunner{groups: map[string][]string{"web": {"a"}}}}
+
+	tests := []struct {
+		args string
+		want string
+	}{
+		{"", "Usage: /run <group> <command>"},
+		{"web", "Usage: /run <group> <command>"},
+		{"db uptime", "Unknown host group: db"},
+	}
+	for _, tt := range tests {
+		got, err := op.Execute(context.Background(), tt.args)
+		if err != nil {
+			t.Fatalf("execute(%q): %v", tt.args, err)
+		}
+		if got != tt.want {
+			t.Errorf("execute(%q) = %q, want %q", tt.args, got, tt.want)
+		}
+	}
+}
+
+func TestRunOpIsHighRisk(t *testing.T) {
+	if got := ops.RiskOf(&ops.RunOp{}); got != ops.RiskHigh {
+		t.Errorf("RiskOf(RunOp) = %d, want RiskHigh", got)
+	}
+}
@@ -0,0 +1,66 @@
+package ops
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+)
+
+// checkTimeout bounds each self-test so one hung subsystem cannot eat the
+// whole op timeout.
+const checkTimeout = 10 * time.Second
+
+// Check is one self-test run by /setup and /selftest, such as sending
+// through the notifier or dialling the socket server.
+type Check struct {
+	Name string
+	Run  func(ctx context.Context) error
+}
+
+// SelfTestOp runs every check at once and reports which passed.
+type SelfTestOp struct {
+	Checks []Check
+}
+
+func (o *SelfTestOp) Name() string        { return "selftest" }
+func (o *SelfTestOp) Description() string { return "Check every subsystem end to end" }
+func (o *SelfTestOp) Risk() RiskLevel     { return RiskNone }
+
+func (o *SelfTestOp) Execute(ctx context.Context, args string) (string, error) {
+	if strings.TrimSpace(args) != "" {
+		return "Usage: /selftest", nil
+	}
+	if len(o.Checks) == 0 {
+		return "No self-tests configured.", nil
+	}
+
+	errs := make([]error, len(o.Checks))
+	took := make([]time.Duration, len(o.Checks))
+	var wg sync.WaitGroup
+	for i, c := range o.Checks {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			cctx, cancel := context.WithTimeout(ctx, checkTimeout)
+			defer cancel()
+			start := time.Now()
+			errs[i] = c.Run(cctx)
+			took[i] = time.Since(start)
+		}()
+	}
+	wg.Wait()
+
+	passed := 0
+	var b strings.Builder
+	for i, c := range o.Checks {
+		if errs[i] != nil {
+			fmt.Fprintf(&b, "\nFAIL %s: %s", c.Name, errs[i])
+			continue
+		}
+		passed++
+		fmt.Fprintf(&b, "\nok   %s (%s)", c.Name, took[i].Round(time.Millisecond))
+	}
+	return fmt.Sprintf("Self-test: %d/%d passed", passed, len(o.Checks)) + b.String(), nil
+}
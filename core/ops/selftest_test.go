@@ -0,0 +1,43 @@
+package ops_test
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/jdelaire/openslack/core/ops"
+)
+
+func TestSelfTestOp(t *testing.T) {
+	op := &ops.SelfTestOp{Checks: []ops.Check{
+		{Name: "Notifier", Run: func(context.Context) error { return nil }},
+		{Name: "Tasks", Run: func(context.Context) error { return errors.New("read-only file system") }},
+	}}
+	got, err := op.Execute(context.Background(), "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	lines := strings.Split(got, "\n")
+	if len(lines) != 3 || lines[0] != "Self-test: 1/2 passed" ||
+		!strings.HasPrefix(lines[1], "ok   Notifier (") ||
+		lines[2] != "FAIL Tasks: read-only file system" {
+		t.Errorf("result =\n%s", got)
+	}
+
+	if got, _ := op.Execute(context.Background(), "x"); got != "Usage: /selftest" {
+		t.Errorf("with args = %q", got)
+	}
+}
+
+func TestSelfTestOpTimesOut(t *testing.T) {
+	op := &ops.SelfTestOp{Checks: []ops.Check{
+		{Name: "Hang", Run: func(ctx context.Context) error { <-ctx.Done(); return ctx.Err() }},
+	}}
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	got, _ := op.Execute(ctx, "")
+	if !strings.Contains(got, "FAIL Hang: context canceled") {
+		t.Errorf("result = %q", got)
+	}
+}
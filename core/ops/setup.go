@@ -0,0 +1,242 @@
+package ops
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/subtle"
+	"encoding/base32"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/jdelaire/openslack/core/auth"
+)
+
+// TOTPAccount is the secret store account holding the TOTP secret.
+const TOTPAccount = "totp_secret"
+
+// maxClaimFailures is how many wrong setup codes close claiming for good.
+const maxClaimFailures = 5
+
+// BotChecker verifies the bot token. The Telegram notifier implements it.
+type BotChecker interface {
+	BotUsername(ctx context.Context) (string, error)
+}
+
+// Allowlist is the set of chats allowed to send commands. *policy.Policy
+// implements it.
+type Allowlist interface {
+	Allowed(chatID int64) bool
+	Allow(chatID int64) error
+}
+
+// SecretStore holds secrets such as the TOTP secret.
+type SecretStore interface {
+	Get(account string) (string, error)
+	Set(account, value string) error
+}
+
+// SetupOp guides a fresh install: it checks the bot token and allowlist,
+// enrolls TOTP, and runs self-tests. A chat that is not yet allowlisted
+// claims the bot by sending /setup with Code, the one-time code the daemon
+// logs at startup; see Claim. Any field may be nil or empty when that part
+// is not configured.
+type SetupOp struct {
+	Bot       BotChecker
+	Allowlist Allowlist
+	Secrets   SecretStore
+	Checks    []Check
+	ReplyChat string // the chat replies are sent to
+	Code      string
+	TOTP      auth.Params // zero means auth.DefaultParams
+
+	mu       sync.Mutex
+	pending  map[int64]string // TOTP secrets awaiting /setup verify
+	claimed  bool
+	failures int
+}
+
+func (o *SetupOp) Name() string        { return "setup" }
+func (o *SetupOp) Description() string { return "Check and finish setting up OpenSlack" }
+
+// RiskFor lets anyone allowlisted view the checklist, but changing the
+// allowlist or TOTP secret needs the current TOTP code once one is set.
+func (o *SetupOp) RiskFor(args string) RiskLevel {
+	if strings.TrimSpace(args) == "" {
+		return RiskNone
+	}
+	return RiskLow
+}
+
+func (o *SetupOp) Execute(ctx context.Context, args string) (string, error) {
+	chatID, ok := ChatIDFrom(ctx)
+	if !ok {
+		return "", errors.New("setup: no chat in context")
+	}
+	fields := strings.Fields(args)
+	switch {
+	case len(fields) == 0:
+		return o.checklist(ctx, chatID), nil
+	case fields[0] == "allow" && len(fields) == 2:
+		return o.allow(fields[1])
+	case fields[0] == "totp" && len(fields) == 1:
+		return o.startTOTP(chatID)
+	case fields[0] == "verify" && len(fields) == 2:
+		return o.verifyTOTP(chatID, fields[1])
+	}
+	return "Usage: /setup [allow <chat id> | totp | verify <code>]", nil
+}
+
+func (o *SetupOp) checklist(ctx context.Context, chatID int64) string {
+	var b strings.Builder
+	b.WriteString("Setup checklist:\n")
+	item := func(ok bool, format string, args ...any) {
+		mark := "[ ]"
+		if ok {
+			mark = "[x]"
+		}
+		fmt.Fprintf(&b, "%s %s\n", mark, fmt.Sprintf(format, args...))
+	}
+
+	if o.Bot != nil {
+		name, err := o.Bot.BotUsername(ctx)
+		if err != nil {
+			item(false, "Bot token: %s", err)
+		} else {
+			item(true, "Bot token: @%s", name)
+		}
+	}
+
+	if o.Allowlist != nil {
+		if o.Allowlist.Allowed(chatID) {
+			item(true, "Chat %d is allowlisted", chatID)
+		} else {
+			item(false, "Chat %d is not allowlisted: send /setup allow %d", chatID, chatID)
+		}
+	}
+	if o.ReplyChat != "" && o.ReplyChat != strconv.FormatInt(chatID, 10) {
+		item(false, "Replies go to chat %s, not this chat (%d); update telegram_chat_id", o.ReplyChat, chatID)
+	}
+
+	switch {
+	case o.Secrets == nil:
+	case o.totpEnrolled():
+		item(true, "TOTP enrolled")
+	default:
+		item(false, "TOTP not enrolled: send /setup totp")
+	}
+
+	for _, c := range o.Checks {
+		if err := c.Run(ctx); err != nil {
+			item(false, "%s: %s", c.Name, err)
+		} else {
+			item(true, "%s", c.Name)
+		}
+	}
+	return strings.TrimRight(b.String(), "\n")
+}
+
+func (o *SetupOp) allow(arg string) (string, error) {
+	if o.Allowlist == nil {
+		return "No allowlist configured.", nil
+	}
+	id, err := strconv.ParseInt(arg, 10, 64)
+	if err != nil {
+		return "Usage: /setup allow <chat id>", nil
+	}
+	if o.Allowlist.Allowed(id) {
+		return fmt.Sprintf("Chat %d is already allowlisted.", id), nil
+	}
+	if err := o.Allowlist.Allow(id); err != nil {
+		return "", err
+	}
+	return fmt.Sprintf("Chat %d added to the allowlist.", id), nil
+}
+
+func (o *SetupOp) totpEnrolled() bool {
+	secret, err := o.Secrets.Get(TOTPAccount)
+	return err == nil && secret != ""
+}
+
+func (o *SetupOp) startTOTP(chatID int64) (string, error) {
+	if o.Secrets == nil {
+		return "No secret store configured.", nil
+	}
+	secret, err := auth.NewSecret(o.totpParams())
+	if err != nil {
+		return "", err
+	}
+
+	o.mu.Lock()
+	if o.pending == nil {
+		o.pending = make(map[int64]string)
+	}
+	o.pending[chatID] = secret
+	o.mu.Unlock()
+
+	uri := auth.URI(secret, "OpenSlack", "", o.totpParams())
+	return fmt.Sprintf("Add this key to your authenticator app:\n%s\n\n%s\n\nThen send /setup verify <code>. Delete this message once it is saved.", secret, uri), nil
+}
+
+func (o *SetupOp) totpParams() auth.Params {
+	if o.TOTP == (auth.Params{}) {
+		return auth.DefaultParams
+	}
+	return o.TOTP
+}
+
+func (o *SetupOp) verifyTOTP(chatID int64, code string) (string, error) {
+	o.mu.Lock()
+	secret, ok := o.pending[chatID]
+	o.mu.Unlock()
+	if !ok {
+		return "No enrollment in progress; send /setup totp first.", nil
+	}
+
+	totp, err := auth.NewVerifier(secret, o.totpParams(), nil)
+	if err != nil {
+		return "", err
+	}
+	if !totp.Verify(code) {
+		return "Code does not match; check the key and the clock on your device, then try again.", nil
+	}
+	if err := o.Secrets.Set(TOTPAccount, secret); err != nil {
+		return "", fmt.Errorf("save TOTP secret: %w", err)
+	}
+
+	o.mu.Lock()
+	delete(o.pending, chatID)
+	o.mu.Unlock()
+	return "TOTP enrolled. Restart the daemon to require codes for protected commands.", nil
+}
+
+// Claim adds chatID to the allowlist if code is the setup code. It is
+// meant for chats the allowlist would otherwise reject, so the code works
+// once, and claiming closes after maxClaimFailures wrong codes.
+func (o *SetupOp) Claim(chatID int64, code string) (string, error) {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	if o.Code == "" || o.Allowlist == nil || o.claimed || o.failures >= maxClaimFailures {
+		return "", errors.New("setup: claiming is closed")
+	}
+	if subtle.ConstantTimeCompare([]byte(code), []byte(o.Code)) != 1 {
+		o.failures++
+		return "", errors.New("setup: wrong code")
+	}
+	if err := o.Allowlist.Allow(chatID); err != nil {
+		return "", err
+	}
+	o.claimed = true
+	return fmt.Sprintf("Chat %d added to the allowlist. Send /setup to continue.", chatID), nil
+}
+
+// NewSetupCode returns a random one-time code for SetupOp.Code.
+func NewSetupCode() (string, error) {
+	raw := make([]byte, 10)
+	if _, err := rand.Read(raw); err != nil {
+		return "", fmt.Errorf("generate setup code: %w", err)
+	}
+	return strings.ToLower(base32.StdEncoding.EncodeToString(raw)), nil
+}
@@ -0,0 +1,147 @@
+package ops_test
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha1"
+	"encoding/base32"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/jdelaire/openslack/core/ops"
+)
+
+type fakeBot struct{ err error }
+
+func (b fakeBot) BotUsername(context.Context) (string, error) { return "openslack_bot", b.err }
+
+type fakeAllowlist map[int64]bool
+
+func (a fakeAllowlist) Allowed(id int64) bool { return a[id] }
+func (a fakeAllowlist) Allow(id int64) error  { a[id] = true; return nil }
+
+type fakeSecrets map[string]string
+
+func (s fakeSecrets) Get(account string) (string, error) {
+	v, ok := s[account]
+	if !ok {
+		return "", errors.New("not found")
+	}
+	return v, nil
+}
+func (s fakeSecrets) Set(account, value string) error { s[account] = value; return nil }
+
+// totpCode computes the current RFC 6238 code for a base32 secret.
+func totpCode(t *testing.T, secret string) string {
+	t.Helper()
+	key, err := base32.StdEncoding.WithPadding(base32.NoPadding).DecodeString(secret)
+	if err != nil {
+		t.Fatal(err)
+	}
+	buf := make([]byte, 8)
+	binary.BigEndian.PutUint64(buf, uint64(time.Now().Unix()/30))
+	mac := hmac.New(sha1.New, key)
+	mac.Write(buf)
+	sum := mac.Sum(nil)
+	off := sum[len(sum)-1] & 0x0f
+	return fmt.Sprintf("%06d", (binary.BigEndian.Uint32(sum[off:off+4])&0x7fffffff)%1000000)
+}
+
+func TestSetupChecklist(t *testing.T) {
+	op := &ops.SetupOp{
+		Bot:       fakeBot{},
+		Allowlist: fakeAllowlist{},
+		Secrets:   fakeSecrets{},
+		ReplyChat: "7",
+		Checks: []ops.Check{
+			{Name: "Notifier", Run: func(context.Context) error { return nil }},
+			{Name: "Socket", Run: func(context.Context) error { return errors.New("connection refused") }},
+		},
+	}
+	got, err := op.Execute(ops.WithChatID(context.Background(), 42), "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := `Setup checklist:
+[x] Bot token: @openslack_bot
+[ ] Chat 42 is not allowlisted: send /setup allow 42
+[ ] Replies go to chat 7, not this chat (42); update telegram_chat_id
+[ ] TOTP not enrolled: send /setup totp
+[x] Notifier
+[ ] Socket: connection refused`
+	if got != want {
+		t.Errorf("checklist =\n%s\nwant\n%s", got, want)
+	}
+}
+
+func TestSetupAllowAndTOTP(t *testing.T) {
+	allow, secrets := fakeAllowlist{}, fakeSecrets{}
+	op := &ops.SetupOp{Allowlist: allow, Secrets: secrets}
+	ctx := ops.WithChatID(context.Background(), 42)
+
+	if got, _ := op.Execute(ctx, "allow 42"); got != "Chat 42 added to the allowlist." || !allow[42] {
+		t.Errorf("allow = %q, allowlist %v", got, allow)
+	}
+	if got, _ := op.Execute(ctx, "verify 123456"); !strings.HasPrefix(got, "No enrollment in progress") {
+		t.Errorf("verify before totp = %q", got)
+	}
+
+	got, err := op.Execute(ctx, "totp")
+	if err != nil {
+		t.Fatal(err)
+	}
+	secret := strings.Split(got, "\n")[1]
+	if !strings.Contains(got, "otpauth://totp/OpenSlack?") {
+		t.Errorf("totp reply missing URI: %q", got)
+	}
+
+	if got, _ := op.Execute(ctx, "verify 000000x"); !strings.HasPrefix(got, "Code does not match") {
+		t.Errorf("wrong code = %q", got)
+	}
+	if got, _ := op.Execute(ctx, "verify "+totpCode(t, secret)); !strings.HasPrefix(got, "TOTP enrolled") {
+		t.Errorf("verify = %q", got)
+	}
+	if secrets[ops.TOTPAccount] != secret {
+		t.Errorf("stored secret = %q, want %q", secrets[ops.TOTPAccount], secret)
+	}
+}
+
+func TestSetupClaim(t *testing.T) {
+	allow := fakeAllowlist{}
+	op := &ops.SetupOp{Allowlist: allow, Code: "letmein"}
+
+	if _, err := op.Claim(42, "wrong"); err == nil {
+		t.Error("wrong code accepted")
+	}
+	if _, err := op.Claim(42, "letmein"); err != nil || !allow[42] {
+		t.Fatalf("claim: %v, allowlist %v", err, allow)
+	}
+	if _, err := op.Claim(43, "letmein"); err == nil {
+		t.Error("code accepted twice")
+	}
+}
+
+func TestSetupClaimLocksAfterFailures(t *testing.T) {
+	op := &ops.SetupOp{Allowlist: fakeAllowlist{}, Code: "letmein"}
+	for i := 0; i < 5; i++ {
+		op.Claim(42, "wrong")
+	}
+	if _, err := op.Claim(42, "letmein"); err == nil {
+		t.Error("claim accepted after repeated failures")
+	}
+}
+
+func TestNewSetupCode(t *testing.T) {
+	a, err := ops.NewSetupCode()
+	if err != nil {
+		t.Fatalf("NewSetupCode: %v", err)
+	}
+	b, _ := ops.NewSetupCode()
+	if len(a) != 16 || a == b {
+		t.Errorf("codes = %q, %q, want two distinct 16-character codes", a, b)
+	}
+}
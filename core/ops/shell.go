@@ -1,37 +1,138 @@
 package ops
 
 import (
+	"bytes"
 	"context"
 	"encoding/json"
 	"fmt"
 	"os"
-	"os/exec"
+	"path/filepath"
 	"strings"
+
+	"github.com/jdelaire/openslack/internal/chatvars"
+	"github.com/jdelaire/openslack/internal/platform"
 )
 
-// ShellOp is a generic shell command loaded from config.
+// HostRunner executes commands on named remote hosts.
+type HostRunner interface {
+	Run(ctx context.Context, host, command, workdir string) (string, error)
+}
+
+// Resolver is an optional interface for ops that can report the final
+// command a call would run, after chat variables and args are applied.
+type Resolver interface {
+	Resolve(ctx context.Context, args string) (string, error)
+}
+
+// Confirmer is an optional interface for ops that want a call previewed
+// and confirmed with /yes before it runs.
+type Confirmer interface {
+	NeedsConfirmation(ctx context.Context, args string) bool
+}
+
+// ShellOp is a generic shell command loaded from config. If Host is set,
+// the command runs on that remote host through Hosts instead of locally.
+// {name} references in Command are filled from chat variables. With
+// Confirm set, calls that change the configured command are confirmed
+// with /yes first. Shell and Login choose how local commands run; see
+// platform.Command.
+//
+// A non-nil EnvAllowlist limits the command's environment to the matching
+// variables (see platform.FilterEnv). JailDir confines a command to a
+// directory by checking its working directory and every path passed in
+// args or variables; Chroot enforces that natively by making the
+// directory the command's root. RunAs names the account the command runs
+// as; root is refused unless AllowRoot is set. ChatAccess limits which
+// chats may run it.
 type ShellOp struct {
-	CmdName string `json:"name"`
-	Desc    string `json:"description"`
-	Command string `json:"command"`
-	WorkDir string `json:"workdir"`
+	CmdName      string     `json:"name"`
+	Desc         string     `json:"description"`
+	Command      string     `json:"command"`
+	WorkDir      string     `json:"workdir"`
+	Host         string     `json:"host"`
+	Confirm      bool       `json:"confirm"`
+	Shell        string     `json:"shell"`
+	Login        *bool      `json:"login"`
+	EnvAllowlist []string   `json:"env_allowlist"`
+	JailDir      string     `json:"jail_dir"`
+	Chroot       string     `json:"chroot"`
+	RunAs        string     `json:"run_as"`
+	AllowRoot    bool       `json:"allow_root"`
+	Hosts        HostRunner `json:"-"`
+	ChatAccess
 }
 
 func (s *ShellOp) Name() string        { return s.CmdName }
-func (s *ShellOp) Description() string  { return s.Desc }
+func (s *ShellOp) Description() string { return s.Desc }
 
-func (s *ShellOp) Execute(ctx context.Context, args string) (string, error) {
+// Resolve returns the command Execute would run for args.
+func (s *ShellOp) Resolve(ctx context.Context, args string) (string, error) {
 	command := s.Command
+	if vars, ok := VarsFrom(ctx); ok {
+		expanded, err := chatvars.Expand(command, vars)
+		if err != nil {
+			return "", err
+		}
+		command = expanded
+	}
 	if strings.Contains(command, "{}") {
 		// Placeholder mode: replace first {} with args.
 		command = strings.Replace(command, "{}", args, 1)
 	} else if args != "" {
 		// Append mode: add args to the end.
-		command = s.Command + " " + args
+		command = command + " " + args
 	}
-	cmd := exec.CommandContext(ctx, "bash", "-l", "-c", command)
-	if s.WorkDir != "" {
-		cmd.Dir = s.WorkDir
+	return command, nil
+}
+
+// NeedsConfirmation reports whether args or chat variables change the
+// command from its configured form. Calls that cannot be resolved report
+// true so the preview surfaces the error.
+func (s *ShellOp) NeedsConfirmation(ctx context.Context, args string) bool {
+	if !s.Confirm {
+		return false
+	}
+	command, err := s.Resolve(ctx, args)
+	return err != nil || command != s.Command
+}
+
+func (s *ShellOp) Execute(ctx context.Context, args string) (string, error) {
+	command, err := s.Resolve(ctx, args)
+	if err != nil {
+		return "", err
+	}
+	if s.Host != "" {
+		return s.executeRemote(ctx, command)
+	}
+	cmd, err := platform.Command(ctx, s.Shell, s.Login == nil || *s.Login, command)
+	if err != nil {
+		return "", fmt.Errorf("%s: %w", s.CmdName, err)
+	}
+	cmd.Dir = s.WorkDir
+	if s.JailDir != "" {
+		if cmd.Dir, err = s.jail(command); err != nil {
+			return "", fmt.Errorf("%s: %w", s.CmdName, err)
+		}
+	}
+	if s.Chroot != "" {
+		if cmd.Dir == "" {
+			cmd.Dir = "/"
+		}
+		if err := platform.Chroot(cmd, s.Chroot); err != nil {
+			return "", fmt.Errorf("%s: %w", s.CmdName, err)
+		}
+	}
+	if s.EnvAllowlist != nil {
+		cmd.Env = platform.FilterEnv(os.Environ(), s.EnvAllowlist)
+	}
+	if s.RunAs != "" {
+		u, err := s.runAsUser()
+		if err != nil {
+			return "", fmt.Errorf("%s: %w", s.CmdName, err)
+		}
+		if err := platform.RunAs(cmd, u); err != nil {
+			return "", fmt.Errorf("%s: %w", s.CmdName, err)
+		}
 	}
 	out, err := cmd.CombinedOutput()
 	if err != nil {
@@ -40,7 +141,127 @@ func (s *ShellOp) Execute(ctx context.Context, args string) (string, error) {
 	return strings.TrimSpace(string(out)), nil
 }
 
-// LoadCommands reads a JSON config file and returns ShellOps.
+// runAsUser looks up RunAs. It is checked on every run as well as at load,
+// since the account can change while the daemon runs.
+func (s *ShellOp) runAsUser() (*platform.User, error) {
+	u, err := platform.LookupUser(s.RunAs)
+	if err != nil {
+		return nil, fmt.Errorf("run_as: %w", err)
+	}
+	if u.IsRoot() && !s.AllowRoot {
+		return nil, fmt.Errorf("run_as: %s is root; set allow_root to permit it", s.RunAs)
+	}
+	return u, nil
+}
+
+// jail returns the working directory for command under JailDir. It fails
+// if the working directory, or any path in a word that args or variables
+// added to the configured command, lies outside the jail, or if such a
+// word holds shell metacharacters and the command runs through a shell.
+// This is a guard against arguments, not a sandbox: the configured
+// command itself is trusted.
+func (s *ShellOp) jail(command string) (string, error) {
+	root, err := filepath.EvalSymlinks(s.JailDir)
+	if err != nil {
+		return "", fmt.Errorf("jail: %w", err)
+	}
+	dir := root
+	if s.WorkDir != "" {
+		dir = s.WorkDir
+		if !filepath.IsAbs(dir) {
+			dir = filepath.Join(root, dir)
+		}
+		if !insideDir(root, resolvePath(dir)) {
+			return "", fmt.Errorf("workdir %s is outside %s", s.WorkDir, s.JailDir)
+		}
+	}
+
+	configured := make(map[string]bool)
+	for _, w := range jailWords(s.Command) {
+		configured[w] = true
+	}
+	for _, w := range jailWords(command) {
+		if configured[w] {
+			continue
+		}
+		if s.Shell != platform.ShellNone && strings.ContainsAny(w, shellMeta) {
+			return "", fmt.Errorf("%q: shell metacharacters are not allowed in jailed arguments", w)
+		}
+		candidates := []string{w}
+		if _, value, ok := strings.Cut(w, "="); ok {
+			candidates = append(candidates, value)
+		}
+		for _, p := range candidates {
+			p = strings.Trim(p, `"'`)
+			if strings.HasPrefix(p, "~") {
+				return "", fmt.Errorf("%s is outside %s", p, s.JailDir)
+			}
+			if !filepath.IsAbs(p) {
+				p = filepath.Join(dir, p)
+			}
+			if !insideDir(root, resolvePath(p)) {
+				return "", fmt.Errorf("%s is outside %s", w, s.JailDir)
+			}
+		}
+	}
+	return dir, nil
+}
+
+// shellMeta holds the characters a shell would expand, substitute, glob,
+// quote or treat as a command separator. The jail only checks words as
+// literal paths, so through a shell it refuses added words with any of
+// them.
+const shellMeta = "$`*?[]{}()<>|&;'\"\\\n\r"
+
+// jailWords splits command on spaces and tabs only, so a newline stays
+// in its word and is caught as a command separator.
+func jailWords(command string) []string {
+	return strings.FieldsFunc(command, func(r rune) bool { return r == ' ' || r == '\t' })
+}
+
+// resolvePath cleans path and resolves symlinks in as much of it as
+// exists, so a link inside the jail cannot point out of it.
+func resolvePath(path string) string {
+	path = filepath.Clean(path)
+	if real, err := filepath.EvalSymlinks(path); err == nil {
+		return real
+	}
+	parent := filepath.Dir(path)
+	if parent == path {
+		return path
+	}
+	return filepath.Join(resolvePath(parent), filepath.Base(path))
+}
+
+// insideDir reports whether path is root or below it.
+func insideDir(root, path string) bool {
+	rel, err := filepath.Rel(root, path)
+	return err == nil && rel != ".." && !strings.HasPrefix(rel, ".."+string(filepath.Separator))
+}
+
+func (s *ShellOp) executeRemote(ctx context.Context, command string) (string, error) {
+	if s.Hosts == nil {
+		return "", fmt.Errorf("%s: host %q: no hosts configured", s.CmdName, s.Host)
+	}
+	out, err := s.Hosts.Run(ctx, s.Host, command, s.WorkDir)
+	if err != nil {
+		return "", fmt.Errorf("%s: %w\n%s", s.CmdName, err, out)
+	}
+	return out, nil
+}
+
+// commandsFile is the object form of the commands config, which sets the
+// shell defaults for every command alongside the list.
+type commandsFile struct {
+	Shell        string    `json:"shell"`
+	Login        *bool     `json:"login"`
+	EnvAllowlist []string  `json:"env_allowlist"`
+	Commands     []ShellOp `json:"commands"`
+}
+
+// LoadCommands reads a JSON config file and returns ShellOps. The file is
+// either a list of commands or an object holding them under "commands"
+// with default "shell", "login" and "env_allowlist" settings.
 // Returns nil, nil if the file does not exist.
 func LoadCommands(path string) ([]ShellOp, error) {
 	data, err := os.ReadFile(path)
@@ -51,11 +272,49 @@ func LoadCommands(path string) ([]ShellOp, error) {
 		return nil, fmt.Errorf("read commands config: %w", err)
 	}
 
-	var cmds []ShellOp
-	if err := json.Unmarshal(data, &cmds); err != nil {
+	var file commandsFile
+	if trimmed := bytes.TrimSpace(data); len(trimmed) > 0 && trimmed[0] == '[' {
+		err = json.Unmarshal(data, &file.Commands)
+	} else {
+		err = json.Unmarshal(data, &file)
+	}
+	if err != nil {
 		return nil, fmt.Errorf("parse commands config: %w", err)
 	}
+	if err := platform.ValidShell(file.Shell); err != nil {
+		return nil, fmt.Errorf("commands config: %w", err)
+	}
 
+	cmds := file.Commands
+	for i := range cmds {
+		c := &cmds[i]
+		if c.Host != "" {
+			// These limits apply to local processes only; refuse them
+			// rather than run a remote command without them.
+			for _, f := range []struct {
+				name string
+				set  bool
+			}{
+				{"run_as", c.RunAs != ""},
+				{"env_allowlist", c.EnvAllowlist != nil},
+				{"jail_dir", c.JailDir != ""},
+				{"chroot", c.Chroot != ""},
+			} {
+				if f.set {
+					return nil, fmt.Errorf("command %q: %s cannot be used with host", c.CmdName, f.name)
+				}
+			}
+		}
+		if c.Shell == "" {
+			c.Shell = file.Shell
+		}
+		if c.Login == nil {
+			c.Login = file.Login
+		}
+		if c.EnvAllowlist == nil && c.Host == "" {
+			c.EnvAllowlist = file.EnvAllowlist
+		}
+	}
 	for i, c := range cmds {
 		if c.CmdName == "" {
 			return nil, fmt.Errorf("command at index %d missing name", i)
@@ -63,6 +322,22 @@ func LoadCommands(path string) ([]ShellOp, error) {
 		if c.Command == "" {
 			return nil, fmt.Errorf("command %q missing command field", c.CmdName)
 		}
+		if err := platform.ValidShell(c.Shell); err != nil {
+			return nil, fmt.Errorf("command %q: %w", c.CmdName, err)
+		}
+		if c.JailDir != "" && c.Chroot != "" {
+			return nil, fmt.Errorf("command %q: set only one of jail_dir and chroot", c.CmdName)
+		}
+		if c.RunAs != "" {
+			if _, err := c.runAsUser(); err != nil {
+				return nil, fmt.Errorf("command %q: %w", c.CmdName, err)
+			}
+		}
+		for field, dir := range map[string]string{"jail_dir": c.JailDir, "chroot": c.Chroot} {
+			if dir != "" && !filepath.IsAbs(dir) {
+				return nil, fmt.Errorf("command %q: %s must be an absolute path", c.CmdName, field)
+			}
+		}
 	}
 
 	return cmds, nil
@@ -3,6 +3,7 @@ package ops_test
 import (
 	"context"
 	"os"
+	"os/user"
 	"path/filepath"
 	"strings"
 	"testing"
@@ -159,6 +160,58 @@ func TestLoadCommandsValidFile(t *testing.T) {
 	}
 }
 
+func TestShellOpNeedsConfirmation(t *testing.T) {
+	vars := ops.WithVars(context.Background(), map[string]string{"env": "prod"})
+	tests := []struct {
+		name string
+		op   ops.ShellOp
+		ctx  context.Context
+		args string
+		want bool
+	}{
+		{"confirm off", ops.ShellOp{Command: "rm -rf {}"}, context.Background(), "/tmp/x", false},
+		{"no args", ops.ShellOp{Command: "df -h", Confirm: true}, context.Background(), "", false},
+		{"args", ops.ShellOp{Command: "rm -rf {}", Confirm: true}, context.Background(), "/tmp/x", true},
+		{"variable", ops.ShellOp{Command: "deploy {env}", Confirm: true}, vars, "", true},
+		{"unset variable", ops.ShellOp{Command: "deploy {cluster}", Confirm: true}, vars, "", true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.op.NeedsConfirmation(tt.ctx, tt.args); got != tt.want {
+				t.Errorf("NeedsConfirmation = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestLoadCommandsConfirm(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "commands.json")
+	os.WriteFile(path, []byte(`[{"name":"purge","description":"purge","command":"rm -rf {}","confirm":true}]`), 0644)
+
+	cmds, err := ops.LoadCommands(path)
+	if err != nil {
+		t.Fatalf("LoadCommands: %v", err)
+	}
+	if !cmds[0].Confirm {
+		t.Error("confirm = false, want true")
+	}
+}
+
+func TestLoadCommandsChatAccess(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "commands.json")
+	os.WriteFile(path, []byte(`[{"name":"restart","description":"restart","command":"true","allowed_chats":[100,200],"denied_chats":[200]}]`), 0644)
+
+	cmds, err := ops.LoadCommands(path)
+	if err != nil {
+		t.Fatalf("LoadCommands: %v", err)
+	}
+	for chatID, want := range map[int64]bool{100: true, 200: false, 300: false} {
+		if got := ops.ChatAllowed(&cmds[0], chatID); got != want {
+			t.Errorf("ChatAllowed(%d) = %v, want %v", chatID, got, want)
+		}
+	}
+}
+
 func TestLoadCommandsMissingFile(t *testing.T) {
 	cmds, err := ops.LoadCommands("/nonexistent/commands.json")
 	if err != nil {
@@ -224,3 +277,301 @@ func TestLoadCommandsEmpty(t *testing.T) {
 		t.Fatalf("len = %d, want 0", len(cmds))
 	}
 }
+
+type fakeHosts struct {
+	host, command, workdir string
+}
+
+func (f *fakeHosts) Run(_ context.Context, host, command, workdir string) (string, error) {
+	f.host, f.command, f.workdir = host, command, workdir
+	return "remote ok", nil
+}
+
+func TestShellOpRemoteHost(t *testing.T) {
+	hosts := &fakeHosts{}
+	op := &ops.ShellOp{
+		CmdName: "nas-df",
+		Command: "df -h",
+		WorkDir: "/srv",
+		Host:    "nas",
+		Hosts:   hosts,
+	}
+
+	result, err := op.Execute(context.Background(), "/data")
+	if err != nil {
+		t.Fatalf("Execute: %v", err)
+	}
+	if result != "remote ok" {
+		t.Errorf("result = %q, want %q", result, "remote ok")
+	}
+	if hosts.host != "nas" || hosts.command != "df -h /data" || hosts.workdir != "/srv" {
+		t.Errorf("runner got host=%q command=%q workdir=%q", hosts.host, hosts.command, hosts.workdir)
+	}
+}
+
+func TestShellOpRemoteHostWithoutRunner(t *testing.T) {
+	op := &ops.ShellOp{CmdName: "nas-df", Command: "df -h", Host: "nas"}
+
+	_, err := op.Execute(context.Background(), "")
+	if err == nil || !strings.Contains(err.Error(), "no hosts configured") {
+		t.Fatalf("error = %v, want no hosts configured", err)
+	}
+}
+
+func TestLoadCommandsHost(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "commands.json")
+	os.WriteFile(path, []byte(`[{"name":"nas-df","description":"disk","command":"df -h","host":"nas"}]`), 0644)
+
+	cmds, err := ops.LoadCommands(path)
+	if err != nil {
+		t.Fatalf("LoadCommands: %v", err)
+	}
+	if cmds[0].Host != "nas" {
+		t.Errorf("host = %q, want %q", cmds[0].Host, "nas")
+	}
+}
+
+func TestShellOpShellModes(t *testing.T) {
+	noLogin := false
+	tests := []struct {
+		name string
+		op   ops.ShellOp
+		args string
+		want string
+	}{
+		{"sh non-login", ops.ShellOp{Command: "echo $0", Shell: "sh", Login: &noLogin}, "", "sh"},
+		{"none keeps quoted args together", ops.ShellOp{Command: "printf %s|", Shell: "none"}, `"a b" c`, "a b|c|"},
+		{"none does not expand", ops.ShellOp{Command: "echo $HOME", Shell: "none"}, "", "$HOME"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tt.op.CmdName = "test"
+			got, err := tt.op.Execute(context.Background(), tt.args)
+			if err != nil {
+				t.Fatalf("Execute: %v", err)
+			}
+			if got != tt.want {
+				t.Errorf("output = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestLoadCommandsShellDefaults(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "commands.json")
+	os.WriteFile(path, []byte(`{
+		"shell": "sh",
+		"login": false,
+		"commands": [
+			{"name":"a","description":"a","command":"uptime"},
+			{"name":"b","description":"b","command":"uptime","shell":"none","login":true}
+		]
+	}`), 0644)
+
+	cmds, err := ops.LoadCommands(path)
+	if err != nil {
+		t.Fatalf("LoadCommands: %v", err)
+	}
+	if cmds[0].Shell != "sh" || cmds[0].Login == nil || *cmds[0].Login {
+		t.Errorf("a: shell=%q login=%v, want defaults sh, false", cmds[0].Shell, cmds[0].Login)
+	}
+	if cmds[1].Shell != "none" || cmds[1].Login == nil || !*cmds[1].Login {
+		t.Errorf("b: shell=%q login=%v, want overrides none, true", cmds[1].Shell, cmds[1].Login)
+	}
+}
+
+func TestLoadCommandsUnknownShell(t *testing.T) {
+	for _, data := range []string{
+		`[{"name":"a","description":"a","command":"uptime","shell":"zsh"}]`,
+		`{"shell":"fish","commands":[]}`,
+	} {
+		path := filepath.Join(t.TempDir(), "commands.json")
+		os.WriteFile(path, []byte(data), 0644)
+		if _, err := ops.LoadCommands(path); err == nil || !strings.Contains(err.Error(), "unknown shell") {
+			t.Errorf("%s: err = %v, want unknown shell", data, err)
+		}
+	}
+}
+
+func TestShellOpEnvAllowlist(t *testing.T) {
+	t.Setenv("OPENSLACK_TEST_SECRET", "hunter2")
+	t.Setenv("OPENSLACK_TEST_LANG", "C")
+	op := &ops.ShellOp{CmdName: "env", Command: "env", Shell: "none", EnvAllowlist: []string{"OPENSLACK_TEST_L*"}}
+	out, err := op.Execute(context.Background(), "")
+	if err != nil {
+		t.Fatalf("Execute: %v", err)
+	}
+	if out != "OPENSLACK_TEST_LANG=C" {
+		t.Errorf("env = %q, want only OPENSLACK_TEST_LANG", out)
+	}
+}
+
+func TestShellOpJailDir(t *testing.T) {
+	jail := t.TempDir()
+	os.Mkdir(filepath.Join(jail, "logs"), 0755)
+	os.WriteFile(filepath.Join(jail, "logs", "app.log"), []byte("ok\n"), 0644)
+	os.Symlink("/etc", filepath.Join(jail, "escape"))
+
+	op := &ops.ShellOp{CmdName: "cat", Command: "cat {}", Shell: "none", JailDir: jail}
+	tests := []struct {
+		args    string
+		wantErr bool
+	}{
+		{"logs/app.log", false},
+		{filepath.Join(jail, "logs", "app.log"), false},
+		{"/etc/hostname", true},
+		{"../outside", true},
+		{"logs/../../outside", true},
+		{"escape/hostname", true},
+		{"-n", false},
+		{filepath.Join(jail, "escape", "hostname"), true},
+		{"~/.ssh/id_rsa", true},
+		{"--file=/etc/hostname", true},
+	}
+	for _, tt := range tests {
+		_, err := op.Execute(context.Background(), tt.args)
+		outside := err != nil && strings.Contains(err.Error(), "outside")
+		if outside != tt.wantErr {
+			t.Errorf("args %q: err = %v, want outside error %v", tt.args, err, tt.wantErr)
+		}
+	}
+
+	shell := &ops.ShellOp{CmdName: "cat", Command: "cat {}", Shell: "sh", JailDir: jail}
+	for _, args := range []string{
+		"$HOME/.ssh/id_rsa",
+		"$(cat /etc/hostname)",
+		"`cat /etc/hostname`",
+		"/???/hostname",
+		"logs/*",
+		"logs/app.log;cat /etc/hostname",
+		"logs/app.log|cat",
+		"logs/app.log>logs/copy",
+		"'/etc/hostname'",
+		"logs/app.log\ncat /etc/hostname",
+	} {
+		if _, err := shell.Execute(context.Background(), args); err == nil || !strings.Contains(err.Error(), "metacharacters") {
+			t.Errorf("sh args %q: err = %v, want metacharacter error", args, err)
+		}
+	}
+	if out, err := shell.Execute(context.Background(), "logs/app.log"); err != nil || out != "ok" {
+		t.Errorf("sh plain path: out = %q, err = %v", out, err)
+	}
+
+	pwd := &ops.ShellOp{CmdName: "pwd", Command: "pwd", JailDir: jail, WorkDir: "logs"}
+	out, err := pwd.Execute(context.Background(), "")
+	if err != nil {
+		t.Fatalf("pwd: %v", err)
+	}
+	if real, _ := filepath.EvalSymlinks(filepath.Join(jail, "logs")); !strings.HasSuffix(out, real) {
+		t.Errorf("pwd = %q, want %s", out, real)
+	}
+
+	escape := &ops.ShellOp{CmdName: "pwd", Command: "pwd", JailDir: jail, WorkDir: "/tmp"}
+	if _, err := escape.Execute(context.Background(), ""); err == nil || !strings.Contains(err.Error(), "outside") {
+		t.Errorf("workdir outside jail: err = %v", err)
+	}
+}
+
+func TestLoadCommandsJail(t *testing.T) {
+	tests := []struct {
+		data string
+		want string
+	}{
+		{`[{"name":"a","description":"a","command":"ls","jail_dir":"data"}]`, "jail_dir must be an absolute path"},
+		{`[{"name":"a","description":"a","command":"ls","chroot":"/srv/a","jail_dir":"/srv/a"}]`, "only one of"},
+	}
+	for _, tt := range tests {
+		path := filepath.Join(t.TempDir(), "commands.json")
+		os.WriteFile(path, []byte(tt.data), 0644)
+		if _, err := ops.LoadCommands(path); err == nil || !strings.Contains(err.Error(), tt.want) {
+			t.Errorf("%s: err = %v, want %q", tt.data, err, tt.want)
+		}
+	}
+
+	path := filepath.Join(t.TempDir(), "commands.json")
+	os.WriteFile(path, []byte(`{"env_allowlist":["PATH"],"commands":[
+		{"name":"a","description":"a","command":"ls"},
+		{"name":"b","description":"b","command":"ls","env_allowlist":[]}
+	]}`), 0644)
+	cmds, err := ops.LoadCommands(path)
+	if err != nil {
+		t.Fatalf("LoadCommands: %v", err)
+	}
+	if len(cmds[0].EnvAllowlist) != 1 || cmds[1].EnvAllowlist == nil || len(cmds[1].EnvAllowlist) != 0 {
+		t.Errorf("allowlists = %q, %q", cmds[0].EnvAllowlist, cmds[1].EnvAllowlist)
+	}
+}
+
+func TestLoadCommandsHostLimits(t *testing.T) {
+	tests := []struct {
+		data string
+		want string
+	}{
+		{`[{"name":"a","description":"a","command":"id","host":"web","run_as":"nobody"}]`, `command "a": run_as cannot be used with host`},
+		{`[{"name":"a","description":"a","command":"env","host":"web","env_allowlist":["PATH"]}]`, `command "a": env_allowlist cannot be used with host`},
+		{`[{"name":"a","description":"a","command":"ls","host":"web","jail_dir":"/srv/a"}]`, `command "a": jail_dir cannot be used with host`},
+		{`[{"name":"a","description":"a","command":"ls","host":"web","chroot":"/srv/a"}]`, `command "a": chroot cannot be used with host`},
+	}
+	for _, tt := range tests {
+		path := filepath.Join(t.TempDir(), "commands.json")
+		os.WriteFile(path, []byte(tt.data), 0644)
+		if _, err := ops.LoadCommands(path); err == nil || !strings.Contains(err.Error(), tt.want) {
+			t.Errorf("%s: err = %v, want %q", tt.data, err, tt.want)
+		}
+	}
+
+	// A file-wide allowlist is for local commands and is not passed on.
+	path := filepath.Join(t.TempDir(), "commands.json")
+	os.WriteFile(path, []byte(`{"env_allowlist":["PATH"],"commands":[
+		{"name":"a","description":"a","command":"uptime","host":"web"}
+	]}`), 0644)
+	cmds, err := ops.LoadCommands(path)
+	if err != nil {
+		t.Fatalf("LoadCommands: %v", err)
+	}
+	if cmds[0].EnvAllowlist != nil {
+		t.Errorf("host command allowlist = %q, want none", cmds[0].EnvAllowlist)
+	}
+}
+
+func TestLoadCommandsRunAs(t *testing.T) {
+	tests := []struct {
+		data string
+		want string
+	}{
+		{`[{"name":"a","description":"a","command":"id","run_as":"no-such-user-openslack"}]`, "run_as"},
+		{`[{"name":"a","description":"a","command":"id","run_as":"root"}]`, "set allow_root"},
+		{`[{"name":"a","description":"a","command":"id","run_as":"root","allow_root":true}]`, ""},
+	}
+	for _, tt := range tests {
+		path := filepath.Join(t.TempDir(), "commands.json")
+		os.WriteFile(path, []byte(tt.data), 0644)
+		_, err := ops.LoadCommands(path)
+		if tt.want == "" && err != nil || tt.want != "" && (err == nil || !strings.Contains(err.Error(), tt.want)) {
+			t.Errorf("%s: err = %v, want %q", tt.data, err, tt.want)
+		}
+	}
+}
+
+func TestShellOpRunAs(t *testing.T) {
+	if os.Geteuid() != 0 {
+		t.Skip("switching users needs root")
+	}
+	nobody, err := user.Lookup("nobody")
+	if err != nil {
+		t.Skip("no nobody user")
+	}
+	op := &ops.ShellOp{CmdName: "id", Command: "id -u", Shell: "none", RunAs: "nobody"}
+	out, err := op.Execute(context.Background(), "")
+	if err != nil {
+		t.Fatalf("Execute: %v", err)
+	}
+	if out != nobody.Uid {
+		t.Errorf("uid = %q, want %s", out, nobody.Uid)
+	}
+
+	root := &ops.ShellOp{CmdName: "id", Command: "id -u", Shell: "none", RunAs: "root"}
+	if _, err := root.Execute(context.Background(), ""); err == nil || !strings.Contains(err.Error(), "allow_root") {
+		t.Errorf("root without allow_root: err = %v", err)
+	}
+}
@@ -0,0 +1,82 @@
+package ops
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/jdelaire/openslack/core/ops/format"
+	"github.com/jdelaire/openslack/internal/sources"
+)
+
+const sourcesUsage = "Usage:\n/sources\n/sources mute <source>\n/sources unmute <source>"
+
+// SourcesOp lists the registered notify sources and mutes or unmutes them.
+type SourcesOp struct {
+	Registry *sources.Registry
+}
+
+func (o *SourcesOp) Name() string        { return "sources" }
+func (o *SourcesOp) Description() string { return "List and mute notification sources" }
+func (o *SourcesOp) Usage() string       { return sourcesUsage }
+
+// RiskFor allows listing without TOTP; muting or unmuting needs it.
+func (o *SourcesOp) RiskFor(args string) RiskLevel {
+	fields := strings.Fields(args)
+	if len(fields) == 0 || fields[0] == "list" {
+		return RiskNone
+	}
+	return RiskLow
+}
+
+func (o *SourcesOp) Execute(_ context.Context, args string) (string, error) {
+	fields := strings.Fields(args)
+	if len(fields) == 0 || fields[0] == "list" {
+		return o.list()
+	}
+	if len(fields) != 2 || (fields[0] != "mute" && fields[0] != "unmute") {
+		return sourcesUsage, nil
+	}
+
+	muted := fields[0] == "mute"
+	if err := o.Registry.SetMuted(fields[1], muted); err != nil {
+		if errors.Is(err, sources.ErrUnknown) {
+			return fmt.Sprintf("Unknown source: %s", fields[1]), nil
+		}
+		return "", err
+	}
+	if muted {
+		return fmt.Sprintf("Muted %s", fields[1]), nil
+	}
+	return fmt.Sprintf("Unmuted %s", fields[1]), nil
+}
+
+func (o *SourcesOp) list() (string, error) {
+	entries, err := o.Registry.List()
+	if err != nil {
+		return "", err
+	}
+	if len(entries) == 0 {
+		return "No sources registered.", nil
+	}
+	rows := make([][]string, 0, len(entries))
+	for _, e := range entries {
+		label := e.Name
+		if e.Emoji != "" {
+			label = e.Emoji + " " + label
+		}
+		chat := "default"
+		if e.ChatID != 0 {
+			chat = fmt.Sprint(e.ChatID)
+		}
+		state := "on"
+		if !e.MutedUntil.IsZero() {
+			state = "until " + e.MutedUntil.Local().Format("15:04")
+		} else if e.Muted {
+			state = "muted"
+		}
+		rows = append(rows, []string{e.Key, label, e.Priority, chat, state})
+	}
+	return format.Table([]string{"Source", "Label", "Priority", "Chat", "State"}, rows, 0), nil
+}
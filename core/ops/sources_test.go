@@ -0,0 +1,54 @@
+package ops_test
+
+import (
+	"context"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/jdelaire/openslack/core/ops"
+	"github.com/jdelaire/openslack/internal/sources"
+	"github.com/jdelaire/openslack/internal/state"
+)
+
+func TestSourcesOp(t *testing.T) {
+	reg := sources.NewRegistry(&sources.Config{Sources: map[string]sources.Source{
+		"backup": {Name: "Backup", Emoji: "💾", Priority: sources.PriorityLow, ChatID: -100},
+		"cron":   {Name: "Cron", Priority: sources.PriorityNormal},
+	}}, state.NewStore(filepath.Join(t.TempDir(), "state.json")))
+	op := &ops.SourcesOp{Registry: reg}
+	ctx := context.Background()
+
+	tests := []struct {
+		args string
+		want string
+	}{
+		{"", "backup  💾 Backup  low       -100     on"},
+		{"mute cron", "Muted cron"},
+		{"list", "cron    Cron      normal    default  muted"},
+		{"unmute cron", "Unmuted cron"},
+		{"mute nope", "Unknown source: nope"},
+		{"mute", "Usage:"},
+		{"bogus x", "Usage:"},
+	}
+	for _, tt := range tests {
+		got, err := op.Execute(ctx, tt.args)
+		if err != nil {
+			t.Fatalf("execute %q: %v", tt.args, err)
+		}
+		if !strings.Contains(got, tt.want) {
+			t.Errorf("execute %q = %q, want it to contain %q", tt.args, got, tt.want)
+		}
+	}
+
+	if op.RiskFor("") != ops.RiskNone || op.RiskFor("mute cron") != ops.RiskLow {
+		t.Error("unexpected risk levels")
+	}
+}
+
+func TestSourcesOpEmpty(t *testing.T) {
+	op := &ops.SourcesOp{Registry: sources.NewRegistry(nil, nil)}
+	if got, _ := op.Execute(context.Background(), ""); got != "No sources registered." {
+		t.Errorf("empty list = %q", got)
+	}
+}
@@ -4,19 +4,103 @@ import (
 	"context"
 	"fmt"
 	"runtime"
+	"sort"
+	"strconv"
+	"strings"
 	"time"
+
+	"github.com/jdelaire/openslack/core/admission"
+	"github.com/jdelaire/openslack/core/ops/format"
 )
 
 var startTime = time.Now()
 
-// StatusOp returns daemon uptime, Go version, and goroutine count.
-type StatusOp struct{}
+// StatusOp returns daemon uptime, Go version, and goroutine count, and
+// when the chat's /sudo window ends if one is open.
+// DroppedUpdates, if set, reports Telegram updates the receiver could not
+// handle, keyed by type. ReceiverDegraded, if set, reports whether polling
+// Telegram keeps failing. Load, if set, reports commands in flight and
+// whether background work is deferred. Lease, if set, reports whether this
+// instance leads and who holds the leader lease.
+type StatusOp struct {
+	DroppedUpdates   func() map[string]int64
+	ReceiverDegraded func() (since time.Time, lastErr error, degraded bool)
+	Load             func() admission.Load
+	Lease            func() (leading bool, holder string, expires time.Time)
+}
 
 func (s *StatusOp) Name() string        { return "status" }
 func (s *StatusOp) Description() string  { return "Show daemon status" }
 
-func (s *StatusOp) Execute(_ context.Context, _ string) (string, error) {
+func (s *StatusOp) Execute(ctx context.Context, _ string) (string, error) {
 	uptime := time.Since(startTime).Truncate(time.Second)
-	return fmt.Sprintf("Status: OK\nUptime: %s\nGo: %s\nGoroutines: %d",
-		uptime, runtime.Version(), runtime.NumGoroutine()), nil
+	rows := [][2]string{
+		{"Status", "OK"},
+		{"Uptime", uptime.String()},
+		{"Go", runtime.Version()},
+		{"Goroutines", strconv.Itoa(runtime.NumGoroutine())},
+	}
+	if s.ReceiverDegraded != nil {
+		if since, lastErr, degraded := s.ReceiverDegraded(); degraded {
+			rows = append(rows, [2]string{"Receiver", fmt.Sprintf("degraded since %s, last error: %v", since.Format("15:04"), lastErr)})
+		}
+	}
+	if s.Lease != nil {
+		rows = append(rows, [2]string{"Role", formatRole(s.Lease())})
+	}
+	if s.Load != nil {
+		rows = append(rows, [2]string{"Load", formatLoad(s.Load())})
+	}
+	if until, ok := SudoFrom(ctx); ok {
+		rows = append(rows, [2]string{"Sudo", "on until " + until.Format("15:04")})
+	}
+	if s.DroppedUpdates != nil {
+		if dropped := formatCounts(s.DroppedUpdates()); dropped != "" {
+			rows = append(rows, [2]string{"Dropped updates", dropped})
+		}
+	}
+	return format.KeyValue(rows), nil
+}
+
+// formatRole renders this instance's part in leader election, e.g.
+// "standby, leader host-b:812 until 15:04:05".
+func formatRole(leading bool, holder string, expires time.Time) string {
+	switch {
+	case leading:
+		return "leader"
+	case holder == "":
+		return "standby, no leader"
+	}
+	return fmt.Sprintf("standby, leader %s until %s", holder, expires.Local().Format("15:04:05"))
+}
+
+// formatLoad renders a load as "3 busy, 1.2s avg, deferring background".
+func formatLoad(l admission.Load) string {
+	text := fmt.Sprintf("%d busy", l.Busy)
+	if l.Latency > 0 {
+		text += fmt.Sprintf(", %s avg", l.Latency.Round(100*time.Millisecond))
+	}
+	if l.Shedding {
+		text += ", deferring background"
+	}
+	return text
+}
+
+// formatCounts renders counts as "a 2, b 1", largest first.
+func formatCounts(counts map[string]int64) string {
+	keys := make([]string, 0, len(counts))
+	for k := range counts {
+		keys = append(keys, k)
+	}
+	sort.Slice(keys, func(i, j int) bool {
+		if counts[keys[i]] != counts[keys[j]] {
+			return counts[keys[i]] > counts[keys[j]]
+		}
+		return keys[i] < keys[j]
+	})
+	parts := make([]string, len(keys))
+	for i, k := range keys {
+		parts[i] = fmt.Sprintf("%s %d", k, counts[k])
+	}
+	return strings.Join(parts, ", ")
 }
@@ -7,6 +7,7 @@ import (
 	"strconv"
 	"strings"
 
+	"github.com/jdelaire/openslack/core/ops/format"
 	tasksvc "github.com/jdelaire/openslack/internal/tasks"
 )
 
@@ -19,8 +20,12 @@ func (o *TaskTomorrowOp) Name() string        { return "tomorrow" }
 func (o *TaskTomorrowOp) Description() string { return "Create a task that starts tomorrow" }
 func (o *TaskTomorrowOp) Risk() RiskLevel     { return RiskNone }
 
-func (o *TaskTomorrowOp) Execute(_ context.Context, args string) (string, error) {
-	task, err := o.Service.CreateTomorrow(args)
+func (o *TaskTomorrowOp) Execute(ctx context.Context, args string) (string, error) {
+	create := o.Service.CreateTomorrow
+	if chatID, ok := ChatIDFrom(ctx); ok {
+		create = func(text string) (tasksvc.Task, error) { return o.Service.CreateTomorrowFor(chatID, text) }
+	}
+	task, err := create(args)
 	if err != nil {
 		if errors.Is(err, tasksvc.ErrEmptyTaskText) {
 			return "Usage: /tomorrow <task description>", nil
@@ -52,11 +57,11 @@ func (o *TaskListOp) Execute(_ context.Context, args string) (string, error) {
 		return "No open tasks.", nil
 	}
 
-	lines := make([]string, 0, len(tasks))
+	pairs := make([][2]string, 0, len(tasks))
 	for _, task := range tasks {
-		lines = append(lines, fmt.Sprintf("%d: %s", task.ID, task.Text))
+		pairs = append(pairs, [2]string{strconv.Itoa(task.ID), task.Text})
 	}
-	return strings.Join(lines, "\n"), nil
+	return format.KeyValue(pairs), nil
 }
 
 // TaskDoneOp marks a task done.
@@ -89,6 +94,52 @@ func (o *TaskDoneOp) Execute(_ context.Context, args string) (string, error) {
 	}
 }
 
+// maxFound caps how many tasks /find lists.
+const maxFound = 20
+
+// TaskFindOp searches open, done and archived tasks by text.
+type TaskFindOp struct {
+	Service *tasksvc.TaskService
+}
+
+func (o *TaskFindOp) Name() string        { return "find" }
+func (o *TaskFindOp) Description() string { return "Search tasks, including archived ones" }
+func (o *TaskFindOp) Risk() RiskLevel     { return RiskNone }
+
+func (o *TaskFindOp) Execute(_ context.Context, args string) (string, error) {
+	query := strings.TrimSpace(args)
+	if query == "" {
+		return "Usage: /find <text>", nil
+	}
+
+	tasks, err := o.Service.Find(query)
+	if err != nil {
+		return "", err
+	}
+	if len(tasks) == 0 {
+		return fmt.Sprintf("No tasks match %q.", query), nil
+	}
+
+	shown := tasks[:min(len(tasks), maxFound)]
+	pairs := make([][2]string, 0, len(shown))
+	for _, task := range shown {
+		text := task.Text
+		if task.Status == tasksvc.TaskStatusDone {
+			text += " (done"
+			if len(task.CompletedAt) >= len("2006-01-02") {
+				text += " " + task.CompletedAt[:len("2006-01-02")]
+			}
+			text += ")"
+		}
+		pairs = append(pairs, [2]string{strconv.Itoa(task.ID), text})
+	}
+	out := format.KeyValue(pairs)
+	if more := len(tasks) - len(shown); more > 0 {
+		out += fmt.Sprintf("\n... and %d more", more)
+	}
+	return out, nil
+}
+
 func parseDoneID(args string) (int, bool) {
 	parts := strings.Fields(strings.TrimSpace(args))
 	if len(parts) != 1 {
@@ -3,6 +3,7 @@ package ops_test
 import (
 	"context"
 	"path/filepath"
+	"strings"
 	"testing"
 	"time"
 
@@ -101,3 +102,61 @@ func TestTaskListAndDoneOps(t *testing.T) {
 		t.Fatalf("list usage result = %q", got)
 	}
 }
+
+func TestTaskListAlignsIDs(t *testing.T) {
+	svc := newTaskService(t)
+	tomorrow := &ops.TaskTomorrowOp{Service: svc}
+	for i := 0; i < 10; i++ {
+		if _, err := tomorrow.Execute(context.Background(), "Task"); err != nil {
+			t.Fatalf("create: %v", err)
+		}
+	}
+
+	got, err := (&ops.TaskListOp{Service: svc}).Execute(context.Background(), "")
+	if err != nil {
+		t.Fatalf("list: %v", err)
+	}
+	lines := strings.Split(got, "\n")
+	if lines[0] != "1:  Task" || lines[9] != "10: Task" {
+		t.Errorf("list = %q", got)
+	}
+}
+
+func TestTaskFindOp(t *testing.T) {
+	svc := newTaskService(t)
+	op := &ops.TaskFindOp{Service: svc}
+
+	for _, text := range []string{"Buy eggs", "Call mom", "Boil eggs"} {
+		if _, err := svc.CreateTomorrow(text); err != nil {
+			t.Fatalf("create: %v", err)
+		}
+	}
+	if _, err := svc.Complete(1); err != nil {
+		t.Fatalf("complete: %v", err)
+	}
+
+	got, err := op.Execute(context.Background(), "eggs")
+	if err != nil {
+		t.Fatalf("execute: %v", err)
+	}
+	want := "3: Boil eggs\n1: Buy eggs (done 2026-02-25)"
+	if got != want {
+		t.Fatalf("result = %q, want %q", got, want)
+	}
+
+	got, err = op.Execute(context.Background(), "bread")
+	if err != nil {
+		t.Fatalf("execute: %v", err)
+	}
+	if got != `No tasks match "bread".` {
+		t.Fatalf("result = %q", got)
+	}
+
+	got, err = op.Execute(context.Background(), " ")
+	if err != nil {
+		t.Fatalf("execute: %v", err)
+	}
+	if got != "Usage: /find <text>" {
+		t.Fatalf("result = %q", got)
+	}
+}
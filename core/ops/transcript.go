@@ -0,0 +1,112 @@
+package ops
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/jdelaire/openslack/internal/transcript"
+)
+
+const transcriptUsage = "Usage: /transcript [today|yesterday|<YYYY-MM-DD>|<duration>], e.g. /transcript 3h"
+
+// maxTranscriptSpan caps the duration /transcript looks back.
+const maxTranscriptSpan = 366 * 24 * time.Hour
+
+// TranscriptReader returns a chat's transcript. *transcript.Log
+// implements it.
+type TranscriptReader interface {
+	Between(chatID int64, from, to time.Time) ([]transcript.Entry, error)
+}
+
+// TranscriptOp exports the calling chat's transcript as a Markdown file,
+// or as the reply where files cannot be sent. The transcript holds op
+// output, so reading it needs TOTP.
+type TranscriptOp struct {
+	Log TranscriptReader
+}
+
+func (o *TranscriptOp) Name() string        { return "transcript" }
+func (o *TranscriptOp) Description() string { return "Export this chat's commands and replies" }
+func (o *TranscriptOp) Usage() string       { return transcriptUsage }
+func (o *TranscriptOp) Risk() RiskLevel     { return RiskLow }
+
+func (o *TranscriptOp) Execute(ctx context.Context, args string) (string, error) {
+	chatID, ok := ChatIDFrom(ctx)
+	if !ok {
+		return "", errors.New("no chat in context")
+	}
+	from, to, period, ok := transcriptPeriod(strings.TrimSpace(args), time.Now())
+	if !ok {
+		return transcriptUsage, nil
+	}
+	entries, err := o.Log.Between(chatID, from, to)
+	if err != nil {
+		return "", err
+	}
+	if len(entries) == 0 {
+		return fmt.Sprintf("Nothing in the transcript for %s.", period), nil
+	}
+
+	doc := renderTranscript(chatID, period, entries)
+	name := fmt.Sprintf("transcript-%d-%s.md", chatID, from.Format("2006-01-02-1504"))
+	if !AttachFile(ctx, File{Name: name, Data: []byte(doc), Caption: "Transcript for " + period}) {
+		return doc, nil
+	}
+	return fmt.Sprintf("Transcript for %s: %d messages, attached as %s.", period, len(entries), name), nil
+}
+
+// transcriptPeriod parses /transcript's argument into the range it covers
+// and how to name it.
+func transcriptPeriod(arg string, now time.Time) (from, to time.Time, period string, ok bool) {
+	y, m, d := now.Date()
+	today := time.Date(y, m, d, 0, 0, 0, 0, now.Location())
+	tomorrow := today.AddDate(0, 0, 1)
+	switch arg {
+	case "", "today":
+		return today, tomorrow, "today (" + today.Format("2006-01-02") + ")", true
+	case "yesterday":
+		day := today.AddDate(0, 0, -1)
+		return day, today, "yesterday (" + day.Format("2006-01-02") + ")", true
+	}
+	if day, err := time.ParseInLocation("2006-01-02", arg, now.Location()); err == nil {
+		return day, day.AddDate(0, 0, 1), day.Format("2006-01-02"), true
+	}
+	if span, err := time.ParseDuration(arg); err == nil && span > 0 && span <= maxTranscriptSpan {
+		return now.Add(-span), tomorrow, "the last " + arg, true
+	}
+	return time.Time{}, time.Time{}, "", false
+}
+
+// renderTranscript writes entries as Markdown, each message in a code
+// block so its text shows as sent.
+func renderTranscript(chatID int64, period string, entries []transcript.Entry) string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "# Transcript of chat %d, %s\n", chatID, period)
+	for _, e := range entries {
+		who := "Chat"
+		if e.From == transcript.FromBot {
+			who = "Bot"
+		}
+		fence := codeFence(e.Text)
+		fmt.Fprintf(&b, "\n**%s %s**\n\n%s\n%s\n%s\n", e.At.Format("2006-01-02 15:04:05"), who, fence, e.Text, fence)
+	}
+	return b.String()
+}
+
+// codeFence returns a backtick fence longer than any run of backticks in
+// text, so the text cannot close it.
+func codeFence(text string) string {
+	longest, run := 0, 0
+	for _, r := range text {
+		if r == '`' {
+			run++
+			longest = max(longest, run)
+		} else {
+			run = 0
+		}
+	}
+	return strings.Repeat("`", max(3, longest+1))
+}
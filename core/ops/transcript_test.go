@@ -0,0 +1,87 @@
+package ops_test
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/jdelaire/openslack/core/ops"
+	"github.com/jdelaire/openslack/internal/transcript"
+)
+
+type fakeTranscript struct {
+	chatID   int64
+	from, to time.Time
+	entries  []transcript.Entry
+}
+
+func (f *fakeTranscript) Between(chatID int64, from, to time.Time) ([]transcript.Entry, error) {
+	f.chatID, f.from, f.to = chatID, from, to
+	return f.entries, nil
+}
+
+func TestTranscriptOp(t *testing.T) {
+	at := time.Date(2026, 10, 18, 9, 30, 0, 0, time.Local)
+	log := &fakeTranscript{entries: []transcript.Entry{
+		{At: at, From: transcript.FromChat, Text: "/logs api"},
+		{At: at.Add(time.Second), From: transcript.FromBot, Text: "```panic```\nexit 2"},
+	}}
+	op := &ops.TranscriptOp{Log: log}
+	ctx, files := ops.WithReplyFiles(ops.WithChatID(context.Background(), 100))
+
+	got, err := op.Execute(ctx, "2026-10-18")
+	if err != nil {
+		t.Fatal(err)
+	}
+	day := time.Date(2026, 10, 18, 0, 0, 0, 0, time.Local)
+	if log.chatID != 100 || !log.from.Equal(day) || !log.to.Equal(day.AddDate(0, 0, 1)) {
+		t.Errorf("read chat %d from %s to %s", log.chatID, log.from, log.to)
+	}
+	if got != "Transcript for 2026-10-18: 2 messages, attached as transcript-100-2026-10-18-0000.md." {
+		t.Errorf("reply = %q", got)
+	}
+	attached := files()
+	if len(attached) != 1 || attached[0].Name != "transcript-100-2026-10-18-0000.md" || attached[0].Caption != "Transcript for 2026-10-18" {
+		t.Fatalf("files = %+v", attached)
+	}
+	want := "# Transcript of chat 100, 2026-10-18\n" +
+		"\n**2026-10-18 09:30:00 Chat**\n\n```\n/logs api\n```\n" +
+		"\n**2026-10-18 09:30:01 Bot**\n\n````\n```panic```\nexit 2\n````\n"
+	if doc := string(attached[0].Data); doc != want {
+		t.Errorf("document =\n%s\nwant\n%s", doc, want)
+	}
+
+	// Without file support the transcript is the reply.
+	got, err = op.Execute(ops.WithChatID(context.Background(), 100), "today")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.HasPrefix(got, "# Transcript of chat 100, today (") || !strings.Contains(got, "/logs api") {
+		t.Errorf("inline transcript = %q", got)
+	}
+}
+
+func TestTranscriptOpPeriods(t *testing.T) {
+	log := &fakeTranscript{}
+	op := &ops.TranscriptOp{Log: log}
+	ctx := ops.WithChatID(context.Background(), 100)
+	now := time.Now()
+	today := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, time.Local)
+
+	got, _ := op.Execute(ctx, "yesterday")
+	if !log.from.Equal(today.AddDate(0, 0, -1)) || !log.to.Equal(today) || !strings.HasPrefix(got, "Nothing in the transcript for yesterday (") {
+		t.Errorf("yesterday: %s to %s, reply %q", log.from, log.to, got)
+	}
+
+	got, _ = op.Execute(ctx, "90m")
+	if since := time.Since(log.from); since < 90*time.Minute || since > 91*time.Minute || got != "Nothing in the transcript for the last 90m." {
+		t.Errorf("90m: from %s, reply %q", log.from, got)
+	}
+
+	for _, bad := range []string{"last week", "-2h", "9000h", "2026-13-01"} {
+		if got, _ := op.Execute(ctx, bad); !strings.HasPrefix(got, "Usage: /transcript") {
+			t.Errorf("%q: reply = %q", bad, got)
+		}
+	}
+}
@@ -0,0 +1,91 @@
+package ops
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/jdelaire/openslack/internal/chatvars"
+)
+
+const setUsage = "Usage:\n/set <name> <value>\n/set <name> (clears it)"
+
+// SetOp sets or clears a chat variable referenced as {name} in commands.
+type SetOp struct {
+	Vars *chatvars.Store
+}
+
+func (o *SetOp) Name() string        { return "set" }
+func (o *SetOp) Description() string { return "Set a chat variable used as {name} in commands" }
+func (o *SetOp) Usage() string       { return setUsage }
+
+func (o *SetOp) Execute(ctx context.Context, args string) (string, error) {
+	chatID, ok := ChatIDFrom(ctx)
+	if !ok {
+		return "", errors.New("set: no chat in context")
+	}
+	name, value, _ := strings.Cut(strings.TrimSpace(args), " ")
+	if name == "" {
+		return setUsage, nil
+	}
+	value = strings.TrimSpace(value)
+
+	switch err := o.Vars.Set(chatID, name, value); {
+	case errors.Is(err, chatvars.ErrInvalidName), errors.Is(err, chatvars.ErrTooLong), errors.Is(err, chatvars.ErrTooMany):
+		return fmt.Sprintf("Cannot set %s: %s", name, err), nil
+	case err != nil:
+		return "", err
+	}
+	if value == "" {
+		return fmt.Sprintf("Cleared %s", name), nil
+	}
+	return fmt.Sprintf("%s = %s", name, value), nil
+}
+
+// GetOp shows chat variables.
+type GetOp struct {
+	Vars *chatvars.Store
+}
+
+func (o *GetOp) Name() string        { return "get" }
+func (o *GetOp) Description() string { return "Show chat variables" }
+func (o *GetOp) Risk() RiskLevel     { return RiskNone }
+
+func (o *GetOp) Execute(ctx context.Context, args string) (string, error) {
+	chatID, ok := ChatIDFrom(ctx)
+	if !ok {
+		return "", errors.New("get: no chat in context")
+	}
+	fields := strings.Fields(args)
+	if len(fields) > 1 {
+		return "Usage: /get [name]", nil
+	}
+
+	vars, err := o.Vars.All(chatID)
+	if err != nil {
+		return "", err
+	}
+	if len(fields) == 1 {
+		value, ok := vars[fields[0]]
+		if !ok {
+			return fmt.Sprintf("%s is not set", fields[0]), nil
+		}
+		return fmt.Sprintf("%s = %s", fields[0], value), nil
+	}
+
+	if len(vars) == 0 {
+		return "No variables set.", nil
+	}
+	names := make([]string, 0, len(vars))
+	for name := range vars {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	lines := make([]string, len(names))
+	for i, name := range names {
+		lines[i] = fmt.Sprintf("%s = %s", name, vars[name])
+	}
+	return strings.Join(lines, "\n"), nil
+}
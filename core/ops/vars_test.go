@@ -0,0 +1,81 @@
+package ops_test
+
+import (
+	"context"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/jdelaire/openslack/core/ops"
+	"github.com/jdelaire/openslack/internal/chatvars"
+	"github.com/jdelaire/openslack/internal/state"
+)
+
+func TestSetGetOps(t *testing.T) {
+	vars := chatvars.NewStore(state.NewStore(filepath.Join(t.TempDir(), "state.json")))
+	set := &ops.SetOp{Vars: vars}
+	get := &ops.GetOp{Vars: vars}
+	ctx := ops.WithChatID(context.Background(), 100)
+
+	tests := []struct {
+		op   ops.Op
+		args string
+		want string
+	}{
+		{get, "", "No variables set."},
+		{set, "env prod", "env = prod"},
+		{set, "region  eu west ", "region = eu west"},
+		{get, "", "env = prod\nregion = eu west"},
+		{get, "env", "env = prod"},
+		{set, "env", "Cleared env"},
+		{get, "env", "env is not set"},
+		{set, "1bad x", "Cannot set 1bad:"},
+		{set, "", "Usage:"},
+		{get, "a b", "Usage:"},
+	}
+	for _, tt := range tests {
+		got, err := tt.op.Execute(ctx, tt.args)
+		if err != nil {
+			t.Fatalf("/%s %q: %v", tt.op.Name(), tt.args, err)
+		}
+		if !strings.HasPrefix(got, tt.want) {
+			t.Errorf("/%s %q = %q, want prefix %q", tt.op.Name(), tt.args, got, tt.want)
+		}
+	}
+
+	if _, err := set.Execute(context.Background(), "env prod"); err == nil {
+		t.Error("set without chat in context: want error")
+	}
+}
+
+func TestSetGetOpRisk(t *testing.T) {
+	if got := ops.RiskOfCall(&ops.SetOp{}, "env prod"); got != ops.RiskLow {
+		t.Errorf("set risk = %v, want low", got)
+	}
+	if got := ops.RiskOfCall(&ops.GetOp{}, ""); got != ops.RiskNone {
+		t.Errorf("get risk = %v, want none", got)
+	}
+}
+
+func TestShellOpResolveVars(t *testing.T) {
+	op := &ops.ShellOp{CmdName: "deploy", Command: "deploy --env {env} {} ${HOME}"}
+	ctx := ops.WithVars(context.Background(), map[string]string{"env": "prod"})
+
+	got, err := op.Resolve(ctx, "api")
+	if err != nil {
+		t.Fatalf("Resolve: %v", err)
+	}
+	if want := "deploy --env prod api ${HOME}"; got != want {
+		t.Errorf("Resolve = %q, want %q", got, want)
+	}
+
+	if _, err := op.Resolve(ops.WithVars(context.Background(), map[string]string{}), "api"); err == nil || !strings.Contains(err.Error(), "{env} is not set") {
+		t.Errorf("unset var error = %v", err)
+	}
+
+	// Without chat variables enabled, templates are left alone.
+	got, err = op.Resolve(context.Background(), "api")
+	if err != nil || got != "deploy --env {env} api ${HOME}" {
+		t.Errorf("Resolve without vars = %q, %v", got, err)
+	}
+}
@@ -0,0 +1,122 @@
+package ops
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/jdelaire/openslack/internal/watch"
+)
+
+const watchUsage = "Usage:\n/watch\n/watch add <url> <selector> [every <interval>] [below <n>|above <n>]\n/watch remove <id>"
+
+// WatchOp lists and manages page and price watches.
+type WatchOp struct {
+	Service *watch.Service
+}
+
+func (o *WatchOp) Name() string        { return "watch" }
+func (o *WatchOp) Description() string { return "Watch a page value for changes" }
+func (o *WatchOp) Usage() string       { return watchUsage }
+
+// RiskFor allows listing without TOTP; adding or removing a watch needs it.
+func (o *WatchOp) RiskFor(args string) RiskLevel {
+	fields := strings.Fields(args)
+	if len(fields) == 0 || fields[0] == "list" {
+		return RiskNone
+	}
+	return RiskLow
+}
+
+func (o *WatchOp) Execute(_ context.Context, args string) (string, error) {
+	fields := strings.Fields(args)
+	if len(fields) == 0 || fields[0] == "list" {
+		return o.list()
+	}
+
+	switch fields[0] {
+	case "add":
+		return o.add(fields[1:])
+	case "remove", "rm":
+		if len(fields) != 2 {
+			return watchUsage, nil
+		}
+		id, err := strconv.Atoi(strings.TrimPrefix(fields[1], "#"))
+		if err != nil {
+			return watchUsage, nil
+		}
+		ok, err := o.Service.Remove(id)
+		if err != nil {
+			return "", err
+		}
+		if !ok {
+			return fmt.Sprintf("Unknown watch: #%d", id), nil
+		}
+		return fmt.Sprintf("Removed watch #%d", id), nil
+	default:
+		return watchUsage, nil
+	}
+}
+
+func (o *WatchOp) list() (string, error) {
+	watches, err := o.Service.List()
+	if err != nil {
+		return "", err
+	}
+	if len(watches) == 0 {
+		return "No watches. Add one with /watch add <url> <selector>", nil
+	}
+	lines := make([]string, 0, len(watches))
+	for _, w := range watches {
+		lines = append(lines, w.String())
+	}
+	return strings.Join(lines, "\n"), nil
+}
+
+// add parses "<url> <selector...> [every d] [below n|above n]". Options are
+// keyword pairs taken from the end so selectors may contain spaces.
+func (o *WatchOp) add(fields []string) (string, error) {
+	if len(fields) < 2 {
+		return watchUsage, nil
+	}
+	w := watch.Watch{URL: fields[0]}
+	rest := fields[1:]
+options:
+	for len(rest) >= 3 {
+		key, val := rest[len(rest)-2], rest[len(rest)-1]
+		switch key {
+		case "every":
+			d, err := time.ParseDuration(val)
+			if err != nil || d <= 0 || w.IntervalSec != 0 {
+				return watchUsage, nil
+			}
+			w.IntervalSec = int(d / time.Second)
+		case "below", "above":
+			n, err := strconv.ParseFloat(val, 64)
+			if err != nil {
+				return watchUsage, nil
+			}
+			if key == "below" {
+				w.Below = &n
+			} else {
+				w.Above = &n
+			}
+		default:
+			break options
+		}
+		rest = rest[:len(rest)-2]
+	}
+	w.Selector = strings.Join(rest, " ")
+
+	added, err := o.Service.Add(w)
+	switch {
+	case errors.Is(err, watch.ErrInvalidURL), errors.Is(err, watch.ErrBothBounds), errors.Is(err, watch.ErrInvalidSelector):
+		return err.Error(), nil
+	case err != nil:
+		return "", err
+	}
+	return fmt.Sprintf("Added watch %s", added), nil
+}
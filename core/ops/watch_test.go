@@ -0,0 +1,56 @@
+package ops_test
+
+import (
+	"context"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/jdelaire/openslack/core/ops"
+	"github.com/jdelaire/openslack/internal/state"
+	"github.com/jdelaire/openslack/internal/watch"
+)
+
+func TestWatchOp(t *testing.T) {
+	svc := watch.NewService(state.NewStore(filepath.Join(t.TempDir(), "state.json")))
+	op := &ops.WatchOp{Service: svc}
+	ctx := context.Background()
+
+	tests := []struct {
+		args string
+		want string
+	}{
+		{"", "No watches. Add one with /watch add <url> <selector>"},
+		{"add https://shop.example.com/w #product > .price every 30m below 100", "Added watch #1 https://shop.example.com/w #product > .price (every 30m) below 100"},
+		{"add https://api.example.com/v1 $.data.version", "Added watch #2 https://api.example.com/v1 $.data.version (every 1h)"},
+		{"add https://example.com a:hover", "invalid selector"},
+		{"add https://example.com h1 below 1 above 2", "use either below or above, not both"},
+		{"add https://example.com h1 every soon", "Usage:"},
+		{"add ftp://example.com h1", "watch url must be http or https"},
+		{"add https://example.com", "Usage:"},
+		{"list", "#1 https://shop.example.com/w #product > .price (every 30m) below 100\n#2 https://api.example.com/v1"},
+		{"remove #1", "Removed watch #1"},
+		{"remove 1", "Unknown watch: #1"},
+		{"remove x", "Usage:"},
+		{"bogus", "Usage:"},
+	}
+	for _, tt := range tests {
+		got, err := op.Execute(ctx, tt.args)
+		if err != nil {
+			t.Fatalf("execute %q: %v", tt.args, err)
+		}
+		if !strings.HasPrefix(got, tt.want) {
+			t.Errorf("execute %q = %q, want prefix %q", tt.args, got, tt.want)
+		}
+	}
+}
+
+func TestWatchOpRisk(t *testing.T) {
+	op := &ops.WatchOp{}
+	if got := ops.RiskOfCall(op, "list"); got != ops.RiskNone {
+		t.Errorf("list risk = %v, want none", got)
+	}
+	if got := ops.RiskOfCall(op, "remove 1"); got != ops.RiskLow {
+		t.Errorf("remove risk = %v, want low", got)
+	}
+}
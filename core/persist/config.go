@@ -0,0 +1,139 @@
+package persist
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+
+	"github.com/jdelaire/openslack/internal/homedir"
+)
+
+// Backend names.
+const (
+	BackendMemory = "memory"
+	BackendFile   = "file"
+	BackendSQLite = "sqlite"
+)
+
+// Components whose state can be persisted.
+const (
+	ComponentApprovals = "approvals"
+	ComponentRateLimit = "ratelimit"
+	ComponentDedupe    = "dedupe"
+	ComponentHOTP      = "hotp"
+	ComponentAudit     = "audit"
+)
+
+// DefaultSQLiteDriver is the database/sql driver name used for SQLite
+// unless configured otherwise. This package registers it, with the
+// pure-Go modernc.org/sqlite.
+const DefaultSQLiteDriver = "sqlite"
+
+// Config chooses a backend per component. Components left empty use
+// Default, which itself defaults to memory. The audit log is the
+// exception: it is only kept when Audit is "sqlite".
+type Config struct {
+	Default   string       `json:"default"`
+	Approvals string       `json:"approvals"`
+	RateLimit string       `json:"ratelimit"`
+	Dedupe    string       `json:"dedupe"`
+	HOTP      string       `json:"hotp"`
+	Audit     string       `json:"audit"`
+	File      string       `json:"file"`
+	SQLite    SQLiteConfig `json:"sqlite"`
+}
+
+// SQLiteConfig locates the database for the sqlite backend. A driver
+// other than the default must be registered with database/sql by the
+// binary, and Path is passed to it unchanged; with the default driver,
+// Open adds a busy timeout and WAL mode.
+type SQLiteConfig struct {
+	Path   string `json:"path"`
+	Driver string `json:"driver"`
+}
+
+// LoadConfig reads and validates a storage config file.
+// Returns nil, nil if the file does not exist.
+func LoadConfig(path string) (*Config, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("read storage config: %w", err)
+	}
+
+	var cfg Config
+	if err := json.Unmarshal(data, &cfg); err != nil {
+		return nil, fmt.Errorf("parse storage config: %w", err)
+	}
+
+	applyDefaults(&cfg)
+	if err := validateConfig(&cfg); err != nil {
+		return nil, err
+	}
+	return &cfg, nil
+}
+
+// Backend returns the backend chosen for component.
+func (c *Config) Backend(component string) string {
+	var b string
+	switch component {
+	case ComponentApprovals:
+		b = c.Approvals
+	case ComponentRateLimit:
+		b = c.RateLimit
+	case ComponentDedupe:
+		b = c.Dedupe
+	case ComponentHOTP:
+		b = c.HOTP
+	case ComponentAudit:
+		if c.Audit == "" {
+			return BackendMemory
+		}
+		return c.Audit
+	}
+	if b == "" {
+		return c.Default
+	}
+	return b
+}
+
+func applyDefaults(cfg *Config) {
+	if cfg.Default == "" {
+		cfg.Default = BackendMemory
+	}
+	if cfg.File == "" {
+		cfg.File = "~/.openslack/dispatcher-state.json"
+	}
+	cfg.File = homedir.Expand(cfg.File)
+	if cfg.SQLite.Path == "" {
+		cfg.SQLite.Path = "~/.openslack/dispatcher.db"
+	}
+	cfg.SQLite.Path = homedir.Expand(cfg.SQLite.Path)
+	if cfg.SQLite.Driver == "" {
+		cfg.SQLite.Driver = DefaultSQLiteDriver
+	}
+}
+
+func validateConfig(cfg *Config) error {
+	for _, c := range []struct{ name, backend string }{
+		{"default", cfg.Default},
+		{ComponentApprovals, cfg.Approvals},
+		{ComponentRateLimit, cfg.RateLimit},
+		{ComponentDedupe, cfg.Dedupe},
+		{ComponentHOTP, cfg.HOTP},
+	} {
+		switch c.backend {
+		case "", BackendMemory, BackendFile, BackendSQLite:
+		default:
+			return fmt.Errorf("%s: unknown backend %q (want memory, file or sqlite)", c.name, c.backend)
+		}
+	}
+	switch cfg.Audit {
+	case "", BackendMemory, BackendSQLite:
+	default:
+		return fmt.Errorf("%s: unsupported backend %q (want memory or sqlite)", ComponentAudit, cfg.Audit)
+	}
+	return nil
+}
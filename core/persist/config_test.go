@@ -0,0 +1,185 @@
+package persist
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/jdelaire/openslack/internal/state"
+)
+
+func TestLoadConfigDefaults(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "storage.json")
+	os.WriteFile(path, []byte(`{"approvals": "file", "file": "/tmp/state.json"}`), 0644)
+
+	cfg, err := LoadConfig(path)
+	if err != nil {
+		t.Fatalf("LoadConfig: %v", err)
+	}
+	tests := []struct{ component, want string }{
+		{ComponentApprovals, BackendFile},
+		{ComponentRateLimit, BackendMemory},
+		{ComponentDedupe, BackendMemory},
+		{ComponentHOTP, BackendMemory},
+		{ComponentAudit, BackendMemory},
+	}
+	for _, tt := range tests {
+		if got := cfg.Backend(tt.component); got != tt.want {
+			t.Errorf("Backend(%s) = %q, want %q", tt.component, got, tt.want)
+		}
+	}
+	if cfg.File != "/tmp/state.json" || cfg.SQLite.Driver != DefaultSQLiteDriver || !strings.HasSuffix(cfg.SQLite.Path, "dispatcher.db") {
+		t.Errorf("cfg = %+v", cfg)
+	}
+}
+
+func TestLoadConfigMissingFile(t *testing.T) {
+	cfg, err := LoadConfig(filepath.Join(t.TempDir(), "missing.json"))
+	if err != nil || cfg != nil {
+		t.Fatalf("LoadConfig = %v, %v; want nil, nil", cfg, err)
+	}
+}
+
+func TestLoadConfigInvalid(t *testing.T) {
+	tests := []struct {
+		name    string
+		data    string
+		wantErr string
+	}{
+		{"bad json", `{`, "parse storage config"},
+		{"unknown default", `{"default": "redis"}`, `default: unknown backend "redis"`},
+		{"unknown component backend", `{"dedupe": "disk"}`, `dedupe: unknown backend "disk"`},
+		{"audit in file", `{"audit": "file"}`, `audit: unsupported backend "file"`},
+	}
+	dir := t.TempDir()
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			path := filepath.Join(dir, "storage.json")
+			os.WriteFile(path, []byte(tt.data), 0644)
+			_, err := LoadConfig(path)
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("error = %v, want %q", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestOpenBackends(t *testing.T) {
+	cfg := &Config{Default: BackendFile, Dedupe: BackendMemory, File: filepath.Join(t.TempDir(), "state.json")}
+	b, err := Open(cfg)
+	if err != nil {
+		t.Fatalf("Open: %v", err)
+	}
+	defer b.Close()
+
+	if b.For(ComponentApprovals) == nil || b.For(ComponentApprovals) != b.For(ComponentRateLimit) {
+		t.Error("file components should share one store")
+	}
+	if b.For(ComponentDedupe) != nil {
+		t.Error("memory component should have no storage")
+	}
+
+	if b.DB(ComponentAudit) != nil || b.For(ComponentAudit) != nil {
+		t.Error("audit log kept without \"audit\": \"sqlite\"")
+	}
+
+	cfg = &Config{Default: BackendSQLite, SQLite: SQLiteConfig{Driver: "no-such-driver", Path: "x.db"}}
+	if _, err := Open(cfg); err == nil || !strings.Contains(err.Error(), "open sqlite storage") {
+		t.Errorf("unregistered driver error = %v", err)
+	}
+}
+
+func TestOpenSQLite(t *testing.T) {
+	cfg := &Config{Default: BackendSQLite, Audit: BackendSQLite, SQLite: SQLiteConfig{Driver: DefaultSQLiteDriver, Path: filepath.Join(t.TempDir(), "dispatcher.db")}}
+	b, err := Open(cfg)
+	if err != nil {
+		t.Fatalf("Open: %v", err)
+	}
+	defer b.Close()
+	db := b.DB(ComponentAudit)
+	if db == nil {
+		t.Fatal("no database for the audit log")
+	}
+	var mode string
+	if err := db.QueryRow(`PRAGMA journal_mode`).Scan(&mode); err != nil || mode != "wal" {
+		t.Errorf("journal_mode = %q, %v; want wal", mode, err)
+	}
+
+	kv := b.For(ComponentApprovals)
+	if err := kv.Put("approvals", "a1", map[string]int{"n": 1}); err != nil {
+		t.Fatalf("Put: %v", err)
+	}
+	if err := kv.Put("approvals", "a1", map[string]int{"n": 2}); err != nil {
+		t.Fatalf("Put again: %v", err)
+	}
+	kv.Put("approvals", "a0", 0)
+	var got map[string]int
+	if ok, err := kv.Get("approvals", "a1", &got); !ok || err != nil || got["n"] != 2 {
+		t.Errorf("Get = %v, %v, %v", got, ok, err)
+	}
+	if keys, err := kv.Keys("approvals"); err != nil || strings.Join(keys, ",") != "a0,a1" {
+		t.Errorf("Keys = %v, %v", keys, err)
+	}
+	if ok, err := kv.Delete("approvals", "a1"); !ok || err != nil {
+		t.Errorf("Delete = %v, %v", ok, err)
+	}
+	if ok, _ := kv.Get("approvals", "a1", &got); ok {
+		t.Error("deleted key still found")
+	}
+}
+
+func TestSQLiteConcurrentPut(t *testing.T) {
+	cfg := &Config{Default: BackendSQLite, SQLite: SQLiteConfig{Driver: DefaultSQLiteDriver, Path: filepath.Join(t.TempDir(), "dispatcher.db")}}
+	b, err := Open(cfg)
+	if err != nil {
+		t.Fatalf("Open: %v", err)
+	}
+	defer b.Close()
+	kv := b.For(ComponentDedupe)
+
+	const writers, puts = 20, 50
+	var wg sync.WaitGroup
+	errs := make(chan error, writers*puts)
+	for w := range writers {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := range puts {
+				if err := kv.Put("dedupe", fmt.Sprintf("%d-%d", w, i), i); err != nil {
+					errs <- err
+				}
+			}
+		}()
+	}
+	wg.Wait()
+	close(errs)
+	for err := range errs {
+		t.Fatalf("Put: %v", err)
+	}
+	if keys, err := kv.Keys("dedupe"); err != nil || len(keys) != writers*puts {
+		t.Errorf("Keys = %d, %v; want %d", len(keys), err, writers*puts)
+	}
+}
+
+func TestPrefix(t *testing.T) {
+	store := state.NewStore(filepath.Join(t.TempDir(), "state.json"))
+	family := Prefix(store, "bot.family.")
+
+	family.Put("telegram", "offset", 7)
+	if found, _ := store.Get("telegram", "offset", new(int)); found {
+		t.Error("prefixed put visible without prefix")
+	}
+	var got int
+	if found, _ := store.Get("bot.family.telegram", "offset", &got); !found || got != 7 {
+		t.Errorf("stored offset = %d, %v; want 7 under the prefixed namespace", got, found)
+	}
+	if keys, _ := family.Keys("telegram"); len(keys) != 1 || keys[0] != "offset" {
+		t.Errorf("Keys = %v", keys)
+	}
+	if Prefix(nil, "x.") != nil {
+		t.Error("Prefix(nil) should stay nil")
+	}
+}
@@ -0,0 +1,128 @@
+// Package persist selects where the dispatcher's security state is kept:
+// in memory (lost on restart), in a JSON state file, or in SQLite.
+package persist
+
+import (
+	"database/sql"
+	"fmt"
+	"strings"
+
+	_ "modernc.org/sqlite" // the pure-Go "sqlite" driver
+
+	"github.com/jdelaire/openslack/internal/state"
+)
+
+// KV is the namespaced key-value storage components persist through. Values
+// are JSON-encoded. internal/state.Store implements it for the file backend.
+type KV interface {
+	Get(ns, key string, out any) (bool, error)
+	Put(ns, key string, v any) error
+	Delete(ns, key string) (bool, error)
+	Keys(ns string) ([]string, error)
+}
+
+// Prefix returns kv with prefix added to every namespace, so several
+// instances of a component, such as one receiver per bot, can share
+// storage without seeing each other's state. A nil kv stays nil.
+func Prefix(kv KV, prefix string) KV {
+	if kv == nil {
+		return nil
+	}
+	return prefixKV{kv: kv, prefix: prefix}
+}
+
+type prefixKV struct {
+	kv     KV
+	prefix string
+}
+
+func (p prefixKV) Get(ns, key string, out any) (bool, error) { return p.kv.Get(p.prefix+ns, key, out) }
+func (p prefixKV) Put(ns, key string, v any) error           { return p.kv.Put(p.prefix+ns, key, v) }
+func (p prefixKV) Delete(ns, key string) (bool, error)       { return p.kv.Delete(p.prefix+ns, key) }
+func (p prefixKV) Keys(ns string) ([]string, error)          { return p.kv.Keys(p.prefix + ns) }
+
+// Backends holds the opened storage for each component. File and SQLite
+// storage are shared by the components that use them.
+type Backends struct {
+	cfg  Config
+	file *state.Store
+	db   *sql.DB
+	kv   KV
+}
+
+// Open opens the storage cfg selects. A nil cfg keeps everything in memory.
+func Open(cfg *Config) (*Backends, error) {
+	if cfg == nil {
+		cfg = &Config{}
+		applyDefaults(cfg)
+	}
+	b := &Backends{cfg: *cfg}
+	for _, c := range []string{ComponentApprovals, ComponentRateLimit, ComponentDedupe, ComponentHOTP, ComponentAudit} {
+		switch cfg.Backend(c) {
+		case BackendFile:
+			if b.file == nil {
+				b.file = state.NewStore(cfg.File)
+			}
+		case BackendSQLite:
+			if b.db == nil {
+				db, err := sql.Open(cfg.SQLite.Driver, dsn(cfg.SQLite))
+				if err != nil {
+					return nil, fmt.Errorf("open sqlite storage: %w", err)
+				}
+				// SQLite allows one writer at a time; more connections
+				// only turn concurrent writes into SQLITE_BUSY errors.
+				db.SetMaxOpenConns(1)
+				kv, err := newSQLKV(db)
+				if err != nil {
+					db.Close()
+					return nil, fmt.Errorf("open sqlite storage: %w", err)
+				}
+				b.db, b.kv = db, kv
+			}
+		}
+	}
+	return b, nil
+}
+
+// dsn returns the data source name for cfg. With the default driver it
+// sets a busy timeout, so a write waits for another process holding the
+// database, and WAL mode, so readers do not block the writer.
+func dsn(cfg SQLiteConfig) string {
+	if cfg.Driver != DefaultSQLiteDriver {
+		return cfg.Path
+	}
+	sep := "?"
+	if strings.Contains(cfg.Path, "?") {
+		sep = "&"
+	}
+	return cfg.Path + sep + "_pragma=busy_timeout(5000)&_pragma=journal_mode(WAL)"
+}
+
+// For returns component's storage, or nil when it is kept in memory.
+func (b *Backends) For(component string) KV {
+	switch b.cfg.Backend(component) {
+	case BackendFile:
+		return b.file
+	case BackendSQLite:
+		return b.kv
+	}
+	return nil
+}
+
+// DB returns the SQLite database when component is kept there, for
+// components that need SQL rather than key-value storage. It is nil
+// otherwise.
+func (b *Backends) DB(component string) *sql.DB {
+	if b.cfg.Backend(component) != BackendSQLite {
+		return nil
+	}
+	return b.db
+}
+
+// Close releases the SQLite database, if one was opened.
+func (b *Backends) Close() error {
+	if b.db == nil {
+		return nil
+	}
+	return b.db.Close()
+}
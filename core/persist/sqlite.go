@@ -0,0 +1,75 @@
+package persist
+
+import (
+	"database/sql"
+	"encoding/json"
+	"errors"
+)
+
+const createTable = `CREATE TABLE IF NOT EXISTS kv (
+	ns    TEXT NOT NULL,
+	key   TEXT NOT NULL,
+	value TEXT NOT NULL,
+	PRIMARY KEY (ns, key)
+)`
+
+// sqlKV implements KV on a single SQLite table.
+type sqlKV struct {
+	db *sql.DB
+}
+
+func newSQLKV(db *sql.DB) (*sqlKV, error) {
+	if _, err := db.Exec(createTable); err != nil {
+		return nil, err
+	}
+	return &sqlKV{db: db}, nil
+}
+
+func (s *sqlKV) Get(ns, key string, out any) (bool, error) {
+	var value string
+	err := s.db.QueryRow(`SELECT value FROM kv WHERE ns = ? AND key = ?`, ns, key).Scan(&value)
+	if errors.Is(err, sql.ErrNoRows) {
+		return false, nil
+	}
+	if err != nil {
+		return false, err
+	}
+	return true, json.Unmarshal([]byte(value), out)
+}
+
+func (s *sqlKV) Put(ns, key string, v any) error {
+	data, err := json.Marshal(v)
+	if err != nil {
+		return err
+	}
+	_, err = s.db.Exec(`INSERT INTO kv (ns, key, value) VALUES (?, ?, ?)
+		ON CONFLICT (ns, key) DO UPDATE SET value = excluded.value`, ns, key, string(data))
+	return err
+}
+
+func (s *sqlKV) Delete(ns, key string) (bool, error) {
+	res, err := s.db.Exec(`DELETE FROM kv WHERE ns = ? AND key = ?`, ns, key)
+	if err != nil {
+		return false, err
+	}
+	n, err := res.RowsAffected()
+	return n > 0, err
+}
+
+func (s *sqlKV) Keys(ns string) ([]string, error) {
+	rows, err := s.db.Query(`SELECT key FROM kv WHERE ns = ? ORDER BY key`, ns)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var keys []string
+	for rows.Next() {
+		var k string
+		if err := rows.Scan(&k); err != nil {
+			return nil, err
+		}
+		keys = append(keys, k)
+	}
+	return keys, rows.Err()
+}
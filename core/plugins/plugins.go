@@ -0,0 +1,110 @@
+// Package plugins lets Go packages compiled into the daemon add native
+// ops. Unlike connectors, plugin ops run in-process: they get the
+// triggering context and the daemon's notifier directly, with no
+// serialization or process overhead, and so are as trusted as the daemon.
+//
+// A plugin registers a Factory from an init function, and is enabled by
+// importing its package for side effects in the daemon's main package:
+//
+//	import _ "example.com/openslack-uptime"
+//
+// There is no dynamic loading: a plugin is only ever code the daemon was
+// built with.
+package plugins
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"sort"
+	"sync"
+
+	"github.com/jdelaire/openslack/core"
+	"github.com/jdelaire/openslack/core/ops"
+)
+
+// Host is what the daemon hands each plugin factory.
+type Host struct {
+	// Context is cancelled when the daemon shuts down. Background work a
+	// plugin starts should stop with it.
+	Context context.Context
+	// Notifier sends to the daemon's chat.
+	Notifier core.Notifier
+	// Logger is the daemon's logger, tagged with the plugin's name.
+	Logger *slog.Logger
+	// Dir is the config directory, normally ~/.openslack, where a plugin
+	// may keep its own config file.
+	Dir string
+}
+
+// Send returns a callback that sends text to the chat as source, threaded
+// under the triggering message when ctx carries one.
+func (h Host) Send(source string) func(ctx context.Context, text string) error {
+	return core.SendFunc(h.Notifier, source)
+}
+
+// Factory builds a plugin's ops. It runs once, at startup.
+type Factory func(h Host) ([]ops.Op, error)
+
+var (
+	mu        sync.Mutex
+	factories = make(map[string]Factory)
+)
+
+// Register makes a plugin's factory available under name. It is meant to
+// be called from the plugin package's init function, and panics if name
+// is empty, f is nil, or name is already registered.
+func Register(name string, f Factory) {
+	mu.Lock()
+	defer mu.Unlock()
+	if name == "" || f == nil {
+		panic("plugins: Register needs a name and a factory")
+	}
+	if _, dup := factories[name]; dup {
+		panic("plugins: Register called twice for " + name)
+	}
+	factories[name] = f
+}
+
+// Names returns the registered plugins, sorted.
+func Names() []string {
+	mu.Lock()
+	defer mu.Unlock()
+	names := make([]string, 0, len(factories))
+	for name := range factories {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// RegisterFactories runs every registered factory, in name order, and adds
+// the ops it returns to registry. It stops at the first factory that fails
+// or returns an op whose name is taken.
+func RegisterFactories(registry *ops.Registry, host Host) error {
+	if host.Context == nil {
+		host.Context = context.Background()
+	}
+	if host.Logger == nil {
+		host.Logger = slog.Default()
+	}
+	for _, name := range Names() {
+		mu.Lock()
+		f := factories[name]
+		mu.Unlock()
+
+		h := host
+		h.Logger = host.Logger.With("plugin", name)
+		built, err := f(h)
+		if err != nil {
+			return fmt.Errorf("plugin %s: %w", name, err)
+		}
+		for _, op := range built {
+			if err := registry.Register(op); err != nil {
+				return fmt.Errorf("plugin %s: %w", name, err)
+			}
+		}
+		host.Logger.Info("plugin loaded", "plugin", name, "ops", len(built))
+	}
+	return nil
+}
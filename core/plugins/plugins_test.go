@@ -0,0 +1,112 @@
+package plugins
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/jdelaire/openslack/core"
+	"github.com/jdelaire/openslack/core/ops"
+)
+
+type namedOp struct{ name string }
+
+func (o namedOp) Name() string                                    { return o.name }
+func (o namedOp) Description() string                             { return "test op" }
+func (o namedOp) Execute(context.Context, string) (string, error) { return o.name, nil }
+
+type recordNotifier struct{ sent []core.Notification }
+
+func (n *recordNotifier) Name() string { return "record" }
+func (n *recordNotifier) Send(_ context.Context, msg core.Notification) error {
+	n.sent = append(n.sent, msg)
+	return nil
+}
+
+// reset empties the factory table for one test.
+func reset(t *testing.T) {
+	t.Helper()
+	mu.Lock()
+	saved := factories
+	factories = make(map[string]Factory)
+	mu.Unlock()
+	t.Cleanup(func() {
+		mu.Lock()
+		factories = saved
+		mu.Unlock()
+	})
+}
+
+func TestRegisterFactories(t *testing.T) {
+	reset(t)
+	notifier := &recordNotifier{}
+	var seen Host
+	Register("beta", func(h Host) ([]ops.Op, error) {
+		return []ops.Op{namedOp{"b1"}}, nil
+	})
+	Register("alpha", func(h Host) ([]ops.Op, error) {
+		seen = h
+		return []ops.Op{namedOp{"a1"}, namedOp{"a2"}}, nil
+	})
+
+	if got := strings.Join(Names(), ","); got != "alpha,beta" {
+		t.Fatalf("names = %s", got)
+	}
+	reg := ops.NewRegistry()
+	if err := RegisterFactories(reg, Host{Notifier: notifier, Dir: "/cfg"}); err != nil {
+		t.Fatalf("register: %v", err)
+	}
+	for _, name := range []string{"a1", "a2", "b1"} {
+		if reg.Get(name) == nil {
+			t.Errorf("op %s not registered", name)
+		}
+	}
+
+	if seen.Context == nil || seen.Logger == nil || seen.Dir != "/cfg" {
+		t.Fatalf("host = %+v", seen)
+	}
+	if err := seen.Send("alpha")(context.Background(), "hi"); err != nil {
+		t.Fatalf("send: %v", err)
+	}
+	if len(notifier.sent) != 1 || notifier.sent[0].Text != "hi" || notifier.sent[0].Source != "alpha" {
+		t.Fatalf("sent = %+v", notifier.sent)
+	}
+}
+
+func TestRegisterFactoriesErrors(t *testing.T) {
+	reset(t)
+	Register("clash", func(Host) ([]ops.Op, error) { return []ops.Op{namedOp{"status"}}, nil })
+	reg := ops.NewRegistry()
+	reg.Register(namedOp{"status"})
+	err := RegisterFactories(reg, Host{})
+	if err == nil || !strings.Contains(err.Error(), "plugin clash: op already registered: status") {
+		t.Fatalf("err = %v", err)
+	}
+
+	reset(t)
+	Register("broken", func(Host) ([]ops.Op, error) { return nil, errors.New("no config") })
+	if err := RegisterFactories(ops.NewRegistry(), Host{}); err == nil || err.Error() != "plugin broken: no config" {
+		t.Fatalf("err = %v", err)
+	}
+}
+
+func TestRegisterPanics(t *testing.T) {
+	reset(t)
+	f := func(Host) ([]ops.Op, error) { return nil, nil }
+	Register("once", f)
+	for name, call := range map[string]func(){
+		"duplicate":  func() { Register("once", f) },
+		"empty name": func() { Register("", f) },
+		"nil":        func() { Register("nil", nil) },
+	} {
+		func() {
+			defer func() {
+				if recover() == nil {
+					t.Errorf("%s: no panic", name)
+				}
+			}()
+			call()
+		}()
+	}
+}
@@ -0,0 +1,93 @@
+// Package sample is an example plugin. Importing it for side effects
+// registers two ops:
+//
+//	/hello [name]        replies with a greeting
+//	/later <dur> <text>  sends text to the chat after dur, e.g. 20m
+//
+// /later shows what plugins can do that connectors cannot: it keeps
+// running after replying, and sends through the daemon's notifier.
+package sample
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"strings"
+	"time"
+
+	"github.com/jdelaire/openslack/core/ops"
+	"github.com/jdelaire/openslack/core/plugins"
+)
+
+// MaxDelay bounds how far ahead /later schedules.
+const MaxDelay = 24 * time.Hour
+
+func init() {
+	plugins.Register("sample", New)
+}
+
+// New builds the sample plugin's ops.
+func New(h plugins.Host) ([]ops.Op, error) {
+	return []ops.Op{
+		&HelloOp{},
+		&LaterOp{Context: h.Context, Send: h.Send("later"), Logger: h.Logger},
+	}, nil
+}
+
+// HelloOp handles /hello.
+type HelloOp struct{}
+
+func (o *HelloOp) Name() string        { return "hello" }
+func (o *HelloOp) Description() string { return "Say hello (sample plugin)" }
+func (o *HelloOp) Risk() ops.RiskLevel { return ops.RiskNone }
+
+func (o *HelloOp) Execute(_ context.Context, args string) (string, error) {
+	name := strings.TrimSpace(args)
+	if name == "" {
+		name = "world"
+	}
+	return fmt.Sprintf("Hello, %s!", name), nil
+}
+
+// LaterOp handles /later. Pending messages are dropped when Context is
+// cancelled.
+type LaterOp struct {
+	Context context.Context
+	Send    func(ctx context.Context, text string) error
+	Logger  *slog.Logger
+	// After defaults to time.After.
+	After func(time.Duration) <-chan time.Time
+}
+
+func (o *LaterOp) Name() string        { return "later" }
+func (o *LaterOp) Description() string { return "Send a message after a delay (sample plugin)" }
+
+func (o *LaterOp) Execute(_ context.Context, args string) (string, error) {
+	dur, text, _ := strings.Cut(strings.TrimSpace(args), " ")
+	text = strings.TrimSpace(text)
+	d, err := time.ParseDuration(dur)
+	if err != nil || text == "" {
+		return "Usage: /later <duration> <text>, e.g. /later 20m check the oven", nil
+	}
+	if d < time.Second || d > MaxDelay {
+		return fmt.Sprintf("Delay must be between 1s and %s.", MaxDelay), nil
+	}
+
+	after := o.After
+	if after == nil {
+		after = time.After
+	}
+	fire := after(d)
+	go func() {
+		select {
+		case <-o.Context.Done():
+		case <-fire:
+			ctx, cancel := context.WithTimeout(o.Context, 10*time.Second)
+			defer cancel()
+			if err := o.Send(ctx, text); err != nil {
+				o.Logger.Error("later: send failed", "error", err)
+			}
+		}
+	}()
+	return fmt.Sprintf("OK, in %s.", d), nil
+}
@@ -0,0 +1,59 @@
+package sample
+
+import (
+	"context"
+	"log/slog"
+	"testing"
+	"time"
+
+	"github.com/jdelaire/openslack/core/ops"
+	"github.com/jdelaire/openslack/core/plugins"
+)
+
+func TestRegistered(t *testing.T) {
+	reg := ops.NewRegistry()
+	if err := plugins.RegisterFactories(reg, plugins.Host{}); err != nil {
+		t.Fatalf("register: %v", err)
+	}
+	out, err := reg.Get("hello").Execute(context.Background(), "")
+	if err != nil || out != "Hello, world!" {
+		t.Fatalf("hello = %q, %v", out, err)
+	}
+	if reg.Get("later") == nil {
+		t.Fatal("later not registered")
+	}
+}
+
+func TestLater(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	fire := make(chan time.Time)
+	sent := make(chan string, 1)
+	var asked time.Duration
+	op := &LaterOp{
+		Context: ctx,
+		Send:    func(_ context.Context, text string) error { sent <- text; return nil },
+		Logger:  slog.Default(),
+		After:   func(d time.Duration) <-chan time.Time { asked = d; return fire },
+	}
+
+	out, _ := op.Execute(context.Background(), "20m check the oven")
+	if out != "OK, in 20m0s." || asked != 20*time.Minute {
+		t.Fatalf("out = %q, delay %s", out, asked)
+	}
+	fire <- time.Now()
+	if got := <-sent; got != "check the oven" {
+		t.Fatalf("sent %q", got)
+	}
+
+	for args, want := range map[string]string{
+		"":            "Usage: /later <duration> <text>, e.g. /later 20m check the oven",
+		"soon hi":     "Usage: /later <duration> <text>, e.g. /later 20m check the oven",
+		"20m":         "Usage: /later <duration> <text>, e.g. /later 20m check the oven",
+		"48h too far": "Delay must be between 1s and 24h0m0s.",
+	} {
+		if out, _ := op.Execute(context.Background(), args); out != want {
+			t.Errorf("%q: out = %q", args, out)
+		}
+	}
+}
@@ -1,17 +1,28 @@
 package policy
 
 import (
+	"encoding/json"
+	"errors"
 	"fmt"
+	"os"
+	"slices"
 	"sync"
 	"time"
+
+	"github.com/jdelaire/openslack/core/persist"
 )
 
 const (
+	stateNamespace  = "dedupe"
 	freshnessWindow = 5 * time.Minute
 	maxSeenIDs      = 10000
 	pruneCount      = 1000
 )
 
+// ErrUnauthorizedChat is returned by Authorize for a chat not on the
+// allowlist.
+var ErrUnauthorizedChat = errors.New("unauthorized chat")
+
 // Policy authorizes inbound messages against a chat allowlist,
 // freshness window, and update_id deduplication.
 type Policy struct {
@@ -19,6 +30,10 @@ type Policy struct {
 	allowed  map[int64]bool
 	seen     map[int64]bool
 	seenOrder []int64
+	kv       persist.KV
+	file     string  // where chats added by Allow are saved
+	added    []int64 // chats added by Allow
+	now      func() time.Time
 }
 
 // New creates a Policy that authorizes only the given chat IDs.
@@ -30,7 +45,38 @@ func New(chatIDs []int64) *Policy {
 	return &Policy{
 		allowed:  allowed,
 		seen:     make(map[int64]bool),
+		now:      time.Now,
+	}
+}
+
+// WithClock replaces time.Now for the freshness check. A nil now is
+// ignored.
+func (p *Policy) WithClock(now func() time.Time) *Policy {
+	if now != nil {
+		p.now = now
 	}
+	return p
+}
+
+// Open creates a Policy that keeps seen update IDs in kv, so a restart
+// cannot replay a recent update. A nil kv behaves like New. Failing to
+// save a seen ID does not reject the message; the ID is still
+// deduplicated until the next restart.
+func Open(chatIDs []int64, kv persist.KV) (*Policy, error) {
+	p := New(chatIDs)
+	if kv == nil {
+		return p, nil
+	}
+	var order []int64
+	if _, err := kv.Get(stateNamespace, "seen", &order); err != nil {
+		return nil, fmt.Errorf("load seen updates: %w", err)
+	}
+	for _, id := range order {
+		p.seen[id] = true
+	}
+	p.seenOrder = order
+	p.kv = kv
+	return p, nil
 }
 
 // Authorize checks whether a message should be processed.
@@ -39,11 +85,11 @@ func (p *Policy) Authorize(chatID int64, updateID int64, timestamp time.Time) er
 	defer p.mu.Unlock()
 
 	if !p.allowed[chatID] {
-		return fmt.Errorf("unauthorized chat: %d", chatID)
+		return fmt.Errorf("%w: %d", ErrUnauthorizedChat, chatID)
 	}
 
-	if time.Since(timestamp) > freshnessWindow {
-		return fmt.Errorf("stale message: %v old", time.Since(timestamp).Truncate(time.Second))
+	if age := p.now().Sub(timestamp); age > freshnessWindow {
+		return fmt.Errorf("stale message: %v old", age.Truncate(time.Second))
 	}
 
 	if p.seen[updateID] {
@@ -60,6 +106,92 @@ func (p *Policy) Authorize(chatID int64, updateID int64, timestamp time.Time) er
 
 	p.seen[updateID] = true
 	p.seenOrder = append(p.seenOrder, updateID)
+	if p.kv != nil {
+		p.kv.Put(stateNamespace, "seen", p.seenOrder)
+	}
 
 	return nil
 }
+
+// allowlistFile is the file holding chats added with Allow.
+type allowlistFile struct {
+	ChatIDs []int64 `json:"chat_ids"`
+}
+
+// LoadAllowlist reads the chats previously added with Allow.
+// Returns nil, nil if the file does not exist.
+func LoadAllowlist(path string) ([]int64, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("read allowlist: %w", err)
+	}
+	var f allowlistFile
+	if err := json.Unmarshal(data, &f); err != nil {
+		return nil, fmt.Errorf("parse allowlist: %w", err)
+	}
+	return f.ChatIDs, nil
+}
+
+// WithAllowlistFile allows the chats saved in path, as read by
+// LoadAllowlist, and makes Allow save added chats there.
+func (p *Policy) WithAllowlistFile(path string) (*Policy, error) {
+	ids, err := LoadAllowlist(path)
+	if err != nil {
+		return nil, err
+	}
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.file = path
+	for _, id := range ids {
+		if !p.allowed[id] {
+			p.allowed[id] = true
+			p.added = append(p.added, id)
+		}
+	}
+	return p, nil
+}
+
+// Allowed reports whether chatID is on the allowlist.
+func (p *Policy) Allowed(chatID int64) bool {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return p.allowed[chatID]
+}
+
+// ChatIDs returns the allowlisted chats, sorted.
+func (p *Policy) ChatIDs() []int64 {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	ids := make([]int64, 0, len(p.allowed))
+	for id := range p.allowed {
+		ids = append(ids, id)
+	}
+	slices.Sort(ids)
+	return ids
+}
+
+// Allow adds chatID to the allowlist, saving it to the allowlist file if
+// one is set. The chat stays allowed for this run even if saving fails.
+func (p *Policy) Allow(chatID int64) error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if p.allowed[chatID] {
+		return nil
+	}
+	p.allowed[chatID] = true
+	p.added = append(p.added, chatID)
+	if p.file == "" {
+		return nil
+	}
+	data, err := json.MarshalIndent(allowlistFile{ChatIDs: p.added}, "", "  ")
+	if err != nil {
+		return err
+	}
+	if err := os.WriteFile(p.file, append(data, '\n'), 0600); err != nil {
+		return fmt.Errorf("save allowlist: %w", err)
+	}
+	return nil
+}
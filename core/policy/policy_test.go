@@ -1,11 +1,14 @@
 package policy_test
 
 import (
+	"errors"
+	"path/filepath"
 	"strings"
 	"testing"
 	"time"
 
 	"github.com/jdelaire/openslack/core/policy"
+	"github.com/jdelaire/openslack/internal/state"
 )
 
 func TestAuthorizeAllowedChat(t *testing.T) {
@@ -39,6 +42,19 @@ func TestAuthorizeStaleMessage(t *testing.T) {
 	}
 }
 
+func TestAuthorizeClock(t *testing.T) {
+	now := time.Date(2026, 3, 1, 12, 0, 0, 0, time.UTC)
+	p := policy.New([]int64{100}).WithClock(func() time.Time { return now })
+
+	if err := p.Authorize(100, 1, now.Add(-4*time.Minute)); err != nil {
+		t.Fatalf("4-minute-old message: %v", err)
+	}
+	err := p.Authorize(100, 2, now.Add(-6*time.Minute))
+	if err == nil || !strings.Contains(err.Error(), "stale message: 6m0s old") {
+		t.Errorf("6-minute-old message: err = %v", err)
+	}
+}
+
 func TestAuthorizeDuplicateUpdateID(t *testing.T) {
 	p := policy.New([]int64{100})
 	now := time.Now()
@@ -85,3 +101,55 @@ func TestAuthorizeEmptyAllowlist(t *testing.T) {
 		t.Fatal("expected error for empty allowlist")
 	}
 }
+
+func TestOpenRejectsReplayAfterRestart(t *testing.T) {
+	kv := state.NewStore(filepath.Join(t.TempDir(), "state.json"))
+	p, err := policy.Open([]int64{100}, kv)
+	if err != nil {
+		t.Fatalf("Open: %v", err)
+	}
+	if err := p.Authorize(100, 42, time.Now()); err != nil {
+		t.Fatalf("authorize: %v", err)
+	}
+
+	restarted, err := policy.Open([]int64{100}, kv)
+	if err != nil {
+		t.Fatalf("reopen: %v", err)
+	}
+	err = restarted.Authorize(100, 42, time.Now())
+	if err == nil || !strings.Contains(err.Error(), "duplicate update") {
+		t.Errorf("replay after restart error = %v, want duplicate", err)
+	}
+}
+
+func TestAllowPersists(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "allowlist.json")
+	p, err := policy.New([]int64{100}).WithAllowlistFile(path)
+	if err != nil {
+		t.Fatalf("with allowlist file: %v", err)
+	}
+	if err := p.Authorize(300, 1, time.Now()); !errors.Is(err, policy.ErrUnauthorizedChat) {
+		t.Fatalf("err = %v, want ErrUnauthorizedChat", err)
+	}
+	if err := p.Allow(300); err != nil {
+		t.Fatalf("allow: %v", err)
+	}
+	if !p.Allowed(300) {
+		t.Fatal("300 not allowed after Allow")
+	}
+
+	restarted, err := policy.New([]int64{100}).WithAllowlistFile(path)
+	if err != nil {
+		t.Fatalf("reload: %v", err)
+	}
+	if err := restarted.Authorize(300, 2, time.Now()); err != nil {
+		t.Errorf("added chat rejected after restart: %v", err)
+	}
+	ids, _ := policy.LoadAllowlist(path)
+	if len(ids) != 1 || ids[0] != 300 {
+		t.Errorf("saved ids = %v, want only the added chat", ids)
+	}
+	if ids := restarted.ChatIDs(); len(ids) != 2 || ids[0] != 100 || ids[1] != 300 {
+		t.Errorf("ChatIDs() = %v, want [100 300]", ids)
+	}
+}
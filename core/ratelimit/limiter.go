@@ -1,9 +1,16 @@
 package ratelimit
 
 import (
+	"encoding/json"
+	"errors"
 	"fmt"
+	"os"
+	"sort"
+	"strconv"
 	"sync"
 	"time"
+
+	"github.com/jdelaire/openslack/core/persist"
 )
 
 const (
@@ -12,45 +19,192 @@ const (
 	lockoutDuration = 15 * time.Minute
 )
 
+// Escalation defaults: a lockout starting within escalationWindow of the
+// previous one ending lasts for the next step.
+var defaultLockouts = []time.Duration{lockoutDuration, time.Hour, 24 * time.Hour}
+
+const escalationWindow = 24 * time.Hour
+
+const stateNamespace = "ratelimit"
+
+// Config overrides the limiter's thresholds. Zero fields keep the defaults.
+type Config struct {
+	MaxFailures     int   `json:"max_failures"`     // failures that trigger a lockout
+	WindowMin       int   `json:"window_min"`       // minutes failures are counted over
+	LockoutMin      []int `json:"lockout_min"`      // lockout length for the 1st, 2nd, ... lockout
+	EscalationHours int   `json:"escalation_hours"` // how soon after one lockout the next escalates
+}
+
+// LoadConfig reads a rate limit config file.
+// Returns nil, nil if the file does not exist.
+func LoadConfig(path string) (*Config, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("read rate limit config: %w", err)
+	}
+	var cfg Config
+	if err := json.Unmarshal(data, &cfg); err != nil {
+		return nil, fmt.Errorf("parse rate limit config: %w", err)
+	}
+	if cfg.MaxFailures < 0 || cfg.WindowMin < 0 || cfg.EscalationHours < 0 {
+		return nil, fmt.Errorf("rate limit config: values must not be negative")
+	}
+	for _, m := range cfg.LockoutMin {
+		if m < 1 {
+			return nil, fmt.Errorf("rate limit config: lockout_min entries must be at least 1")
+		}
+	}
+	return &cfg, nil
+}
+
 type record struct {
 	failures []time.Time
-	lockedAt time.Time
+	lockedAt time.Time // zero unless locked out
+	until    time.Time // end of the current or last lockout
+	level    int       // lockouts in a row, each within the escalation window of the last
+}
+
+// stored is the persisted form of a record.
+type stored struct {
+	Failures []time.Time `json:"failures"`
+	LockedAt time.Time   `json:"locked_at,omitempty"`
+	Until    time.Time   `json:"until,omitempty"`
+	Level    int         `json:"level,omitempty"`
+}
+
+// Lockout describes a chat being locked out, for OnLockout.
+type Lockout struct {
+	ChatID   int64
+	Failures int
+	Until    time.Time
+	Level    int // 1 for a first lockout, 2 for one soon after it, ...
 }
 
 // Limiter tracks authentication failures per chat ID and locks out
-// chats that exceed the failure threshold.
+// chats that exceed the failure threshold. Chats locked out again soon
+// after are locked out for longer.
 type Limiter struct {
-	mu      sync.Mutex
-	records map[int64]*record
-	now     func() time.Time
+	mu        sync.Mutex
+	records   map[int64]*record
+	now       func() time.Time
+	kv        persist.KV
+	onLockout func(Lockout)
+
+	maxFailures int
+	window      time.Duration
+	lockouts    []time.Duration
+	escalation  time.Duration
 }
 
 // New creates a rate limiter.
 func New() *Limiter {
 	return &Limiter{
-		records: make(map[int64]*record),
-		now:     time.Now,
+		records:     make(map[int64]*record),
+		now:         time.Now,
+		maxFailures: maxFailures,
+		window:      failureWindow,
+		lockouts:    defaultLockouts,
+		escalation:  escalationWindow,
 	}
 }
 
+// WithClock replaces time.Now for failure windows and lockouts. A nil now
+// is ignored.
+func (l *Limiter) WithClock(now func() time.Time) *Limiter {
+	if now != nil {
+		l.now = now
+	}
+	return l
+}
+
+// Open creates a rate limiter that keeps failures and lockouts in kv, so a
+// restart does not lift a lockout. A nil kv behaves like New. Storage
+// errors after loading are ignored: the in-memory state stays
+// authoritative and the limiter keeps enforcing it.
+func Open(kv persist.KV) (*Limiter, error) {
+	l := New()
+	if kv == nil {
+		return l, nil
+	}
+	keys, err := kv.Keys(stateNamespace)
+	if err != nil {
+		return nil, fmt.Errorf("load rate limits: %w", err)
+	}
+	for _, k := range keys {
+		chatID, err := strconv.ParseInt(k, 10, 64)
+		if err != nil {
+			continue
+		}
+		var st stored
+		if _, err := kv.Get(stateNamespace, k, &st); err != nil {
+			return nil, fmt.Errorf("load rate limits: %w", err)
+		}
+		r := &record{failures: st.Failures, lockedAt: st.LockedAt, until: st.Until, level: st.Level}
+		if !r.lockedAt.IsZero() && r.until.IsZero() {
+			r.until, r.level = r.lockedAt.Add(lockoutDuration), 1
+		}
+		l.records[chatID] = r
+	}
+	l.kv = kv
+	return l, nil
+}
+
+// WithConfig applies cfg's thresholds. A nil cfg keeps the defaults.
+func (l *Limiter) WithConfig(cfg *Config) *Limiter {
+	if cfg == nil {
+		return l
+	}
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if cfg.MaxFailures > 0 {
+		l.maxFailures = cfg.MaxFailures
+	}
+	if cfg.WindowMin > 0 {
+		l.window = time.Duration(cfg.WindowMin) * time.Minute
+	}
+	if len(cfg.LockoutMin) > 0 {
+		l.lockouts = make([]time.Duration, len(cfg.LockoutMin))
+		for i, m := range cfg.LockoutMin {
+			l.lockouts[i] = time.Duration(m) * time.Minute
+		}
+	}
+	if cfg.EscalationHours > 0 {
+		l.escalation = time.Duration(cfg.EscalationHours) * time.Hour
+	}
+	return l
+}
+
+// OnLockout calls fn, outside the limiter's lock, each time a chat is
+// locked out.
+func (l *Limiter) OnLockout(fn func(Lockout)) *Limiter {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.onLockout = fn
+	return l
+}
+
 // Check returns an error if the chat is currently locked out.
 func (l *Limiter) Check(chatID int64) error {
 	l.mu.Lock()
 	defer l.mu.Unlock()
 
 	r := l.records[chatID]
-	if r == nil {
+	if r == nil || r.lockedAt.IsZero() {
 		return nil
 	}
 
-	if !r.lockedAt.IsZero() {
-		if l.now().Sub(r.lockedAt) < lockoutDuration {
-			remaining := lockoutDuration - l.now().Sub(r.lockedAt)
-			return fmt.Errorf("rate limited — try again in %s", remaining.Truncate(time.Second))
-		}
-		// Lockout expired — reset.
-		delete(l.records, chatID)
+	now := l.now()
+	if now.Before(r.until) {
+		remaining := r.until.Sub(now)
+		return fmt.Errorf("rate limited — try again in %s", remaining.Truncate(time.Second))
 	}
+	// Lockout expired — start counting afresh, but keep its end and level
+	// for escalation.
+	r.failures, r.lockedAt = nil, time.Time{}
+	l.saveLocked(chatID, r)
 	return nil
 }
 
@@ -58,7 +212,6 @@ func (l *Limiter) Check(chatID int64) error {
 // If failures exceed the threshold within the window, the chat is locked out.
 func (l *Limiter) RecordFailure(chatID int64) {
 	l.mu.Lock()
-	defer l.mu.Unlock()
 
 	now := l.now()
 
@@ -69,7 +222,7 @@ func (l *Limiter) RecordFailure(chatID int64) {
 	}
 
 	// Prune old failures outside the window.
-	cutoff := now.Add(-failureWindow)
+	cutoff := now.Add(-l.window)
 	fresh := r.failures[:0]
 	for _, t := range r.failures {
 		if t.After(cutoff) {
@@ -78,8 +231,23 @@ func (l *Limiter) RecordFailure(chatID int64) {
 	}
 	r.failures = append(fresh, now)
 
-	if len(r.failures) >= maxFailures {
-		r.lockedAt = now
+	var lockout *Lockout
+	if len(r.failures) >= l.maxFailures && r.lockedAt.IsZero() {
+		if r.level > 0 && now.Sub(r.until) < l.escalation {
+			r.level++
+		} else {
+			r.level = 1
+		}
+		step := min(r.level, len(l.lockouts)) - 1
+		r.lockedAt, r.until = now, now.Add(l.lockouts[step])
+		lockout = &Lockout{ChatID: chatID, Failures: len(r.failures), Until: r.until, Level: r.level}
+	}
+	l.saveLocked(chatID, r)
+	fn := l.onLockout
+	l.mu.Unlock()
+
+	if lockout != nil && fn != nil {
+		fn(*lockout)
 	}
 }
 
@@ -87,5 +255,76 @@ func (l *Limiter) RecordFailure(chatID int64) {
 func (l *Limiter) Reset(chatID int64) {
 	l.mu.Lock()
 	defer l.mu.Unlock()
+	l.deleteLocked(chatID)
+}
+
+// Entry is one chat's recent authentication failures.
+type Entry struct {
+	ChatID      int64
+	Failures    int       // failures within the failure window
+	LockedUntil time.Time // zero unless locked out
+}
+
+// Entries lists chats with recent failures or an active lockout, locked
+// out chats first, soonest to unlock first, then by chat ID.
+func (l *Limiter) Entries() []Entry {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	now := l.now()
+	cutoff := now.Add(-l.window)
+	var entries []Entry
+	for chatID, r := range l.records {
+		e := Entry{ChatID: chatID}
+		for _, t := range r.failures {
+			if t.After(cutoff) {
+				e.Failures++
+			}
+		}
+		if !r.lockedAt.IsZero() && now.Before(r.until) {
+			e.LockedUntil = r.until
+		}
+		if e.Failures > 0 || !e.LockedUntil.IsZero() {
+			entries = append(entries, e)
+		}
+	}
+	sort.Slice(entries, func(i, j int) bool {
+		a, b := entries[i], entries[j]
+		if a.LockedUntil.IsZero() != b.LockedUntil.IsZero() {
+			return !a.LockedUntil.IsZero()
+		}
+		if !a.LockedUntil.Equal(b.LockedUntil) {
+			return a.LockedUntil.Before(b.LockedUntil)
+		}
+		return a.ChatID < b.ChatID
+	})
+	return entries
+}
+
+// Unlock clears a chat's failures, lockout and lockout history, reporting
+// whether it had any.
+func (l *Limiter) Unlock(chatID int64) bool {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	_, ok := l.records[chatID]
+	l.deleteLocked(chatID)
+	return ok
+}
+
+// saveLocked persists a chat's record. Must be called with mu held.
+func (l *Limiter) saveLocked(chatID int64, r *record) {
+	if l.kv != nil {
+		l.kv.Put(stateNamespace, strconv.FormatInt(chatID, 10), stored{Failures: r.failures, LockedAt: r.lockedAt, Until: r.until, Level: r.level})
+	}
+}
+
+// deleteLocked clears a chat's record. Must be called with mu held.
+func (l *Limiter) deleteLocked(chatID int64) {
+	if _, ok := l.records[chatID]; !ok {
+		return
+	}
 	delete(l.records, chatID)
+	if l.kv != nil {
+		l.kv.Delete(stateNamespace, strconv.FormatInt(chatID, 10))
+	}
 }
@@ -1,8 +1,12 @@
 package ratelimit
 
 import (
+	"os"
+	"path/filepath"
 	"testing"
 	"time"
+
+	"github.com/jdelaire/openslack/internal/state"
 )
 
 func TestCheckAllowsNewChat(t *testing.T) {
@@ -103,3 +107,163 @@ func TestRecordFailureAfterReset(t *testing.T) {
 		t.Errorf("Check(1 failure after reset) = %v, want nil", err)
 	}
 }
+
+func TestOpenKeepsLockoutAcrossRestart(t *testing.T) {
+	kv := state.NewStore(filepath.Join(t.TempDir(), "state.json"))
+	l, err := Open(kv)
+	if err != nil {
+		t.Fatalf("Open: %v", err)
+	}
+	for i := 0; i < maxFailures; i++ {
+		l.RecordFailure(100)
+	}
+
+	restarted, err := Open(kv)
+	if err != nil {
+		t.Fatalf("reopen: %v", err)
+	}
+	if err := restarted.Check(100); err == nil {
+		t.Fatal("lockout lifted by restart")
+	}
+
+	restarted.Reset(100)
+	again, _ := Open(kv)
+	if err := again.Check(100); err != nil {
+		t.Errorf("Check after reset and restart = %v, want nil", err)
+	}
+}
+
+func TestEntriesAndUnlock(t *testing.T) {
+	now := time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)
+	kv := state.NewStore(filepath.Join(t.TempDir(), "state.json"))
+	l, err := Open(kv)
+	if err != nil {
+		t.Fatal(err)
+	}
+	l.now = func() time.Time { return now }
+
+	l.RecordFailure(300)
+	for i := 0; i < maxFailures; i++ {
+		l.RecordFailure(200)
+	}
+
+	got := l.Entries()
+	want := []Entry{
+		{ChatID: 200, Failures: maxFailures, LockedUntil: now.Add(lockoutDuration)},
+		{ChatID: 300, Failures: 1},
+	}
+	if len(got) != len(want) || got[0] != want[0] || got[1] != want[1] {
+		t.Fatalf("Entries() = %+v, want %+v", got, want)
+	}
+
+	if !l.Unlock(200) {
+		t.Error("Unlock(200) = false, want true")
+	}
+	if l.Unlock(200) {
+		t.Error("second Unlock(200) = true, want false")
+	}
+	if err := l.Check(200); err != nil {
+		t.Errorf("Check after unlock = %v", err)
+	}
+
+	l2, err := Open(kv)
+	if err != nil {
+		t.Fatal(err)
+	}
+	l2.now = l.now
+	if got := l2.Entries(); len(got) != 1 || got[0].ChatID != 300 {
+		t.Errorf("after restart Entries() = %+v, want only chat 300", got)
+	}
+
+	now = now.Add(failureWindow)
+	if got := l2.Entries(); len(got) != 0 {
+		t.Errorf("stale failures listed: %+v", got)
+	}
+}
+
+func TestLockoutsEscalate(t *testing.T) {
+	now := time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)
+	l := New()
+	l.now = func() time.Time { return now }
+	var got []Lockout
+	l.OnLockout(func(lo Lockout) { got = append(got, lo) })
+
+	lockOut := func() time.Duration {
+		t.Helper()
+		for i := 0; i < maxFailures; i++ {
+			l.RecordFailure(100)
+		}
+		e := l.Entries()
+		if len(e) != 1 || e[0].LockedUntil.IsZero() {
+			t.Fatalf("not locked out: %+v", e)
+		}
+		d := e[0].LockedUntil.Sub(now)
+		now = e[0].LockedUntil.Add(time.Second)
+		if err := l.Check(100); err != nil {
+			t.Fatalf("still locked after lockout: %v", err)
+		}
+		return d
+	}
+
+	for i, want := range []time.Duration{15 * time.Minute, time.Hour, 24 * time.Hour, 24 * time.Hour} {
+		if d := lockOut(); d != want {
+			t.Errorf("lockout %d lasted %v, want %v", i+1, d, want)
+		}
+	}
+	if len(got) != 4 || got[0].Level != 1 || got[3].Level != 4 || got[1].Failures != maxFailures {
+		t.Errorf("OnLockout calls = %+v", got)
+	}
+
+	// A quiet day resets the escalation.
+	now = now.Add(escalationWindow)
+	if d := lockOut(); d != lockoutDuration {
+		t.Errorf("lockout after a quiet day lasted %v, want %v", d, lockoutDuration)
+	}
+}
+
+func TestWithConfig(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "ratelimit.json")
+	if cfg, err := LoadConfig(path); cfg != nil || err != nil {
+		t.Fatalf("missing file = %+v, %v", cfg, err)
+	}
+	os.WriteFile(path, []byte(`{"max_failures": 3, "window_min": 5, "lockout_min": [2, 30]}`), 0600)
+	cfg, err := LoadConfig(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	now := time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)
+	l := New().WithConfig(cfg)
+	l.now = func() time.Time { return now }
+
+	l.RecordFailure(100)
+	now = now.Add(6 * time.Minute)
+	l.RecordFailure(100)
+	l.RecordFailure(100)
+	if err := l.Check(100); err != nil {
+		t.Fatalf("failure outside the 5m window counted: %v", err)
+	}
+	l.RecordFailure(100)
+	if e := l.Entries(); len(e) != 1 || e[0].LockedUntil != now.Add(2*time.Minute) {
+		t.Fatalf("Entries() = %+v, want a 2m lockout", e)
+	}
+
+	os.WriteFile(path, []byte(`{"lockout_min": [0]}`), 0600)
+	if _, err := LoadConfig(path); err == nil {
+		t.Error("zero lockout accepted")
+	}
+}
+
+func TestOpenReadsLegacyLockout(t *testing.T) {
+	now := time.Now()
+	kv := state.NewStore(filepath.Join(t.TempDir(), "state.json"))
+	kv.Put(stateNamespace, "100", map[string]any{"failures": []time.Time{now}, "locked_at": now})
+
+	l, err := Open(kv)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if e := l.Entries(); len(e) != 1 || !e[0].LockedUntil.Equal(now.Add(lockoutDuration)) {
+		t.Errorf("Entries() = %+v, want lockout until %v", e, now.Add(lockoutDuration))
+	}
+}
@@ -0,0 +1,66 @@
+package core
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"slices"
+	"strings"
+)
+
+// Default reactions. Telegram only accepts emoji from its reaction set,
+// which has no ✅ or ❌.
+const (
+	DefaultReactionStart  = "👀"
+	DefaultReactionDone   = "👍"
+	DefaultReactionFailed = "👎"
+)
+
+// Reactions configures emoji reactions on the command message: one when
+// an op starts, replaced by another when it succeeds or fails. Empty
+// emoji take the defaults. Quiet ops reply to a success with the reaction
+// alone; their errors are still sent as text.
+type Reactions struct {
+	Start  string   `json:"start,omitempty"`
+	Done   string   `json:"done,omitempty"`
+	Failed string   `json:"failed,omitempty"`
+	Quiet  []string `json:"quiet,omitempty"` // op names
+}
+
+// LoadReactions reads a reactions config file, e.g.
+// {"done": "👌", "quiet": ["done", "tasks"]}. Returns nil, nil if the file
+// does not exist.
+func LoadReactions(path string) (*Reactions, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("read reactions config: %w", err)
+	}
+
+	var r Reactions
+	if err := json.Unmarshal(data, &r); err != nil {
+		return nil, fmt.Errorf("parse reactions config: %w", err)
+	}
+	for i, name := range r.Quiet {
+		r.Quiet[i] = strings.ToLower(strings.TrimPrefix(strings.TrimSpace(name), "/"))
+	}
+	return &r, nil
+}
+
+func (r *Reactions) start() string  { return orDefault(r.Start, DefaultReactionStart) }
+func (r *Reactions) done() string   { return orDefault(r.Done, DefaultReactionDone) }
+func (r *Reactions) failed() string { return orDefault(r.Failed, DefaultReactionFailed) }
+
+// quiet reports whether op replies to a success with its reaction alone.
+func (r *Reactions) quiet(op string) bool {
+	return slices.Contains(r.Quiet, op)
+}
+
+func orDefault(s, def string) string {
+	if s == "" {
+		return def
+	}
+	return s
+}
@@ -0,0 +1,146 @@
+// Package redact masks secrets, such as tokens, passwords and private
+// keys, in text before it is logged or stored.
+package redact
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"regexp"
+)
+
+// Mask replaces each secret found.
+const Mask = "[redacted]"
+
+// defaultPatterns find common secrets. When a pattern has a capture
+// group, only the group is masked, so "password=hunter2" keeps its key.
+var defaultPatterns = []string{
+	// key=value and key: value, for keys that name a secret.
+	`(?i)\b(?:password|passwd|pwd|passphrase|secret|token|api[_-]?key|access[_-]?key|private[_-]?key|client[_-]?secret|auth)\s*[=:]\s*("[^"]*"|'[^']*'|[^\s,;&]+)`,
+	// Command-line flags that take a secret.
+	`(?i)--?(?:password|passwd|pass|secret|token|api-key)[= ]("[^"]*"|'[^']*'|\S+)`,
+	// Authorization headers.
+	`(?i)\b(?:bearer|basic)\s+([A-Za-z0-9._~+/=-]{8,})`,
+	// Passwords in URLs.
+	`://[^/\s:@]+:([^/\s@]+)@`,
+	// Telegram bot tokens.
+	`\b\d{6,12}:[A-Za-z0-9_-]{30,}`,
+	// AWS access key IDs.
+	`\bAKIA[0-9A-Z]{16}\b`,
+	// GitHub and Slack tokens.
+	`\bgh[pousr]_[A-Za-z0-9]{30,}`,
+	`\bxox[abprs]-[A-Za-z0-9-]{10,}`,
+	// JSON web tokens.
+	`\beyJ[A-Za-z0-9_-]+\.[A-Za-z0-9_-]+\.[A-Za-z0-9_-]+`,
+	// PEM private keys.
+	`-----BEGIN [A-Z ]*PRIVATE KEY-----[\s\S]*?(?:-----END [A-Z ]*PRIVATE KEY-----|$)`,
+}
+
+var defaults = mustCompile(defaultPatterns)
+
+// Redactor masks the secrets its patterns find. A nil *Redactor uses the
+// default patterns.
+type Redactor struct {
+	patterns []*regexp.Regexp
+}
+
+// New returns a Redactor using the default patterns and extra ones.
+func New(extra ...string) (*Redactor, error) {
+	compiled, err := compile(extra)
+	if err != nil {
+		return nil, err
+	}
+	return &Redactor{patterns: append(append([]*regexp.Regexp(nil), defaults...), compiled...)}, nil
+}
+
+// Config is the redaction file.
+type Config struct {
+	Patterns []string `json:"patterns"` // added to the defaults
+}
+
+// LoadConfig reads a redaction config file, e.g.
+// {"patterns": ["(?i)pin=(\\d+)"]}, and returns a Redactor for it.
+// Returns nil, nil if the file does not exist.
+func LoadConfig(path string) (*Redactor, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("read redact config: %w", err)
+	}
+
+	var cfg Config
+	if err := json.Unmarshal(data, &cfg); err != nil {
+		return nil, fmt.Errorf("parse redact config: %w", err)
+	}
+	r, err := New(cfg.Patterns...)
+	if err != nil {
+		return nil, fmt.Errorf("redact config: %w", err)
+	}
+	return r, nil
+}
+
+// String returns s with every secret found replaced by Mask.
+func (r *Redactor) String(s string) string {
+	patterns := defaults
+	if r != nil {
+		patterns = r.patterns
+	}
+	for _, re := range patterns {
+		s = mask(re, s)
+	}
+	return s
+}
+
+// Error returns err's message with secrets masked, or "" for nil.
+func (r *Redactor) Error(err error) string {
+	if err == nil {
+		return ""
+	}
+	return r.String(err.Error())
+}
+
+// mask replaces re's matches in s: the first capture group when re has
+// one, else the whole match.
+func mask(re *regexp.Regexp, s string) string {
+	if re.NumSubexp() == 0 {
+		return re.ReplaceAllLiteralString(s, Mask)
+	}
+	matches := re.FindAllStringSubmatchIndex(s, -1)
+	if matches == nil {
+		return s
+	}
+	out := make([]byte, 0, len(s))
+	last := 0
+	for _, m := range matches {
+		start, end := m[2], m[3]
+		if start < 0 {
+			continue // the group did not take part in the match
+		}
+		out = append(out, s[last:start]...)
+		out = append(out, Mask...)
+		last = end
+	}
+	return string(append(out, s[last:]...))
+}
+
+func compile(patterns []string) ([]*regexp.Regexp, error) {
+	out := make([]*regexp.Regexp, len(patterns))
+	for i, p := range patterns {
+		re, err := regexp.Compile(p)
+		if err != nil {
+			return nil, fmt.Errorf("pattern %q: %w", p, err)
+		}
+		out[i] = re
+	}
+	return out, nil
+}
+
+func mustCompile(patterns []string) []*regexp.Regexp {
+	out, err := compile(patterns)
+	if err != nil {
+		panic(err)
+	}
+	return out
+}
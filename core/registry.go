@@ -5,17 +5,25 @@ import (
 	"sync"
 )
 
+// Middleware wraps a notifier, for example to bound or retry its sends.
+// The returned notifier keeps the wrapped one's name and should implement
+// Unwrap() Notifier so optional interfaces stay reachable.
+type Middleware func(Notifier) Notifier
+
 // Registry holds named Notifier implementations and tracks the default.
 type Registry struct {
 	mu          sync.RWMutex
-	notifiers   map[string]Notifier
+	notifiers   map[string]Notifier // wrapped in middleware
+	raw         map[string]Notifier // as registered
 	defaultName string
+	middleware  []Middleware
 }
 
 // NewRegistry creates an empty registry.
 func NewRegistry() *Registry {
 	return &Registry{
 		notifiers: make(map[string]Notifier),
+		raw:       make(map[string]Notifier),
 	}
 }
 
@@ -28,7 +36,8 @@ func (r *Registry) Register(n Notifier) error {
 	if _, exists := r.notifiers[name]; exists {
 		return fmt.Errorf("notifier %q already registered", name)
 	}
-	r.notifiers[name] = n
+	r.raw[name] = n
+	r.notifiers[name] = wrap(n, r.middleware)
 	if r.defaultName == "" {
 		r.defaultName = name
 	}
@@ -57,3 +66,34 @@ func (r *Registry) Get(name string) (Notifier, error) {
 	}
 	return n, nil
 }
+
+// Use wraps every notifier, already registered or registered later, with
+// mw. Middleware runs in the order added: the first is outermost.
+func (r *Registry) Use(mw ...Middleware) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.middleware = append(r.middleware, mw...)
+	for name, n := range r.raw {
+		r.notifiers[name] = wrap(n, r.middleware)
+	}
+}
+
+func wrap(n Notifier, mw []Middleware) Notifier {
+	for i := len(mw) - 1; i >= 0; i-- {
+		n = mw[i](n)
+	}
+	return n
+}
+
+// Unwrap returns the notifier underneath any middleware, for optional
+// interfaces such as DocumentSender.
+func Unwrap(n Notifier) Notifier {
+	for {
+		u, ok := n.(interface{ Unwrap() Notifier })
+		if !ok {
+			return n
+		}
+		n = u.Unwrap()
+	}
+}
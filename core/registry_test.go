@@ -80,3 +80,47 @@ func TestRegistry_DefaultEmpty(t *testing.T) {
 		t.Fatal("expected error for empty registry")
 	}
 }
+
+func TestRegistry_Use(t *testing.T) {
+	r := NewRegistry()
+	before := &mockNotifier{name: "before"}
+	r.Register(before)
+
+	var order []string
+	tag := func(label string) Middleware {
+		return func(n Notifier) Notifier {
+			return &tagged{Notifier: n, label: label, order: &order}
+		}
+	}
+	r.Use(tag("outer"), tag("inner"))
+	after := &mockNotifier{name: "after"}
+	r.Register(after)
+
+	for _, name := range []string{"before", "after"} {
+		order = nil
+		n, _ := r.Get(name)
+		n.Send(context.Background(), Notification{})
+		if len(order) != 2 || order[0] != "outer" || order[1] != "inner" {
+			t.Errorf("%s: middleware ran in order %v, want [outer inner]", name, order)
+		}
+		if n.Name() != name {
+			t.Errorf("Name() = %q, want %q", n.Name(), name)
+		}
+	}
+	if n, _ := r.Get("after"); Unwrap(n) != after {
+		t.Error("Unwrap did not reach the registered notifier")
+	}
+}
+
+type tagged struct {
+	Notifier
+	label string
+	order *[]string
+}
+
+func (t *tagged) Send(ctx context.Context, n Notification) error {
+	*t.order = append(*t.order, t.label)
+	return t.Notifier.Send(ctx, n)
+}
+
+func (t *tagged) Unwrap() Notifier { return t.Notifier }
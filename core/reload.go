@@ -1,6 +1,7 @@
 package core
 
 import (
+	"context"
 	"fmt"
 	"log/slog"
 	"sync"
@@ -13,6 +14,9 @@ import (
 type Reloader struct {
 	registry *ops.Registry
 	connMgr  *connector.Manager
+	hosts    ops.HostRunner
+	onEvent  connector.EventHandler
+	secrets  connector.SecretLookup
 	logger   *slog.Logger
 
 	mu           sync.Mutex
@@ -38,6 +42,38 @@ func (r *Reloader) SetConnectorManager(mgr *connector.Manager) {
 	r.connMgr = mgr
 }
 
+// ConnectorManager returns the current connector manager, or nil if no
+// connectors are running.
+func (r *Reloader) ConnectorManager() *connector.Manager {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.connMgr
+}
+
+// SetHostRunner sets the runner attached to reloaded shell ops that target
+// a remote host.
+func (r *Reloader) SetHostRunner(hosts ops.HostRunner) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.hosts = hosts
+}
+
+// SetEventHandler sets the handler attached to connector managers started
+// by a reload.
+func (r *Reloader) SetEventHandler(h connector.EventHandler) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.onEvent = h
+}
+
+// SetSecretLookup sets how connector managers started by a reload
+// resolve each connector's secret_account.
+func (r *Reloader) SetSecretLookup(f connector.SecretLookup) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.secrets = f
+}
+
 // TrackShellOps records names of shell ops loaded at startup so we know what to unregister.
 func (r *Reloader) TrackShellOps(names []string) {
 	r.mu.Lock()
@@ -75,6 +111,7 @@ func (r *Reloader) ReloadCommands(path string) {
 
 	var names []string
 	for i := range cmds {
+		cmds[i].Hosts = r.hosts
 		if err := r.registry.Register(&cmds[i]); err != nil {
 			r.logger.Warn("skip reloaded command", "name", cmds[i].Name(), "error", err)
 			continue
@@ -86,6 +123,32 @@ func (r *Reloader) ReloadCommands(path string) {
 	r.logger.Info("commands reloaded", "count", len(names))
 }
 
+// ReloadRiskOverrides loads per-op risk levels from the config file and
+// applies them. An invalid file keeps the previous overrides; a missing
+// one clears them.
+func (r *Reloader) ReloadRiskOverrides(path string) {
+	overrides, err := ops.LoadRiskOverrides(path)
+	if err != nil {
+		r.logger.Error("reload risk overrides failed", "path", path, "error", err)
+		return
+	}
+	r.registry.SetRiskOverrides(overrides)
+	r.logger.Info("risk overrides reloaded", "count", len(overrides))
+}
+
+// ReloadTOTPExemptions loads the per-chat TOTP exemptions from the config
+// file and applies them. An invalid file keeps the previous exemptions; a
+// missing one clears them.
+func (r *Reloader) ReloadTOTPExemptions(path string) {
+	exemptions, err := ops.LoadTOTPExemptions(path)
+	if err != nil {
+		r.logger.Error("reload totp exemptions failed", "path", path, "error", err)
+		return
+	}
+	r.registry.SetTOTPExemptions(exemptions)
+	r.logger.Info("totp exemptions reloaded", "chats", len(exemptions))
+}
+
 // ReloadConnectors stops old connectors, unregisters their ops, loads new config,
 // starts new connectors, and registers new ops.
 func (r *Reloader) ReloadConnectors(path string) {
@@ -118,6 +181,8 @@ func (r *Reloader) ReloadConnectors(path string) {
 
 	// Start new connectors.
 	mgr := connector.NewManager(cfg, r.logger)
+	mgr.SetEventHandler(r.onEvent)
+	mgr.SetSecretLookup(r.secrets)
 	if err := mgr.Start(); err != nil {
 		r.logger.Error("reload connectors: start failed", "error", err)
 		return
@@ -126,6 +191,7 @@ func (r *Reloader) ReloadConnectors(path string) {
 
 	// Register new connector ops.
 	router := connector.NewRouter(cfg, mgr, r.logger)
+	declared := router.DeclaredHighRisk(context.Background())
 	var names []string
 	for connName, cc := range cfg.Connectors {
 		for _, tool := range cc.Tools {
@@ -134,6 +200,8 @@ func (r *Reloader) ReloadConnectors(path string) {
 				QualifiedName: qualified,
 				Desc:          fmt.Sprintf("Connector: %s", qualified),
 				Router:        router,
+				HighRisk:      cc.IsHighRisk(tool) || declared[qualified],
+				Access:        cc.ChatAccess[tool],
 			}
 			if err := r.registry.Register(op); err != nil {
 				r.logger.Warn("skip reloaded connector op", "name", qualified, "error", err)
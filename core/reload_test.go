@@ -148,3 +148,55 @@ func TestReloadPreservesBuiltinOps(t *testing.T) {
 		t.Error("expected built-in status op to survive reload")
 	}
 }
+
+func TestReloadRiskOverrides(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "risk.json")
+	reg := ops.NewRegistry()
+	help := &ops.HelpOp{Registry: reg}
+	reg.Register(help)
+	reloader := core.NewReloader(reg, nil, testLogger())
+
+	os.WriteFile(path, []byte(`{"/help": "high"}`), 0644)
+	reloader.ReloadRiskOverrides(path)
+	if got := reg.RiskOfCall(help, ""); got != ops.RiskHigh {
+		t.Fatalf("risk after reload = %d, want high", got)
+	}
+
+	// An invalid file keeps the previous overrides.
+	os.WriteFile(path, []byte(`{"help": "critical"}`), 0644)
+	reloader.ReloadRiskOverrides(path)
+	if got := reg.RiskOf(help); got != ops.RiskHigh {
+		t.Errorf("risk after invalid reload = %d, want high", got)
+	}
+
+	os.Remove(path)
+	reloader.ReloadRiskOverrides(path)
+	if got := reg.RiskOf(help); got != ops.RiskNone {
+		t.Errorf("risk after file removed = %d, want none", got)
+	}
+}
+
+func TestReloadTOTPExemptions(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "totp_exempt.json")
+	reg := ops.NewRegistry()
+	reloader := core.NewReloader(reg, nil, testLogger())
+
+	os.WriteFile(path, []byte(`{"100": ["tasks"]}`), 0644)
+	reloader.ReloadTOTPExemptions(path)
+	if !reg.TOTPExempt(100, "tasks") {
+		t.Fatal("exemption not applied after reload")
+	}
+
+	// An invalid file keeps the previous exemptions.
+	os.WriteFile(path, []byte(`{"me": ["tasks"]}`), 0644)
+	reloader.ReloadTOTPExemptions(path)
+	if !reg.TOTPExempt(100, "tasks") {
+		t.Error("exemption dropped by invalid reload")
+	}
+
+	os.Remove(path)
+	reloader.ReloadTOTPExemptions(path)
+	if reg.TOTPExempt(100, "tasks") {
+		t.Error("exemption kept after file removed")
+	}
+}
@@ -0,0 +1,385 @@
+// Package rules is the automation engine: rules in rules.json that react
+// to a trigger (an event on the events bus, a cron schedule or a keyword in
+// chat), check conditions (attr matchers, a time window, a rate) and take
+// actions (run an op, send a notification, mute a source, create a task).
+// "When monitor.down and host=nas, run /restart-nas via approval."
+package rules
+
+import (
+	"encoding/json"
+	"fmt"
+	"maps"
+	"os"
+	"path"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/jdelaire/openslack/internal/cron"
+	"github.com/jdelaire/openslack/internal/events"
+)
+
+// Rule is one automation rule. It has exactly one trigger: On, an events
+// topic, Schedule, a cron expression, or Keyword, a word in a chat
+// message (the message.received topic).
+//
+// When lists attrs the event must have, as glob patterns like path.Match.
+// Between ("22:00-07:00") and Days ("mon", "tue", ...) limit when the
+// rule may fire, read in Timezone (default local), which also applies to
+// Schedule. Rate caps how often it fires.
+//
+// Run, Approval and Quiet are shorthand for a single run action, which
+// comes before any in Actions. The actions run for ChatID, falling back
+// to the config's chat_id.
+type Rule struct {
+	Name     string            `json:"name"`
+	On       events.Topic      `json:"on"`
+	Schedule string            `json:"schedule"`
+	Keyword  string            `json:"keyword"`
+	When     map[string]string `json:"when"`
+	Between  string            `json:"between"`
+	Days     []string          `json:"days"`
+	Timezone string            `json:"timezone"`
+	Rate     *Rate             `json:"rate"`
+	ChatID   int64             `json:"chat_id"`
+
+	Run      string   `json:"run"`
+	Approval bool     `json:"approval"`
+	Quiet    bool     `json:"quiet"`
+	Actions  []Action `json:"actions"`
+
+	loc      *time.Location
+	from, to int // minutes past midnight; from == to means all day
+	days     map[time.Weekday]bool
+}
+
+// Rate lets a rule fire at most Max times in any WindowMin minutes.
+type Rate struct {
+	Max       int `json:"max"`
+	WindowMin int `json:"window_min"`
+}
+
+// Action is one thing a rule does. Exactly one of Run, Notify, Mute and
+// Task is set. In each, {event.<attr>} is replaced with the event's attr.
+//
+//   - Run runs a command line, "/op args". Its reply goes to the chat
+//     unless Quiet. With Approval, and always for high-risk ops, the chat
+//     is asked to /approve it instead.
+//   - Notify sends the text to the chat.
+//   - Mute mutes a notification source, for For (a duration) or until
+//     /unmute, holding its messages for a digest if Digest.
+//   - Task creates a task with the text.
+type Action struct {
+	Run      string `json:"run"`
+	Approval bool   `json:"approval"`
+	Quiet    bool   `json:"quiet"`
+	Notify   string `json:"notify"`
+	Mute     string `json:"mute"`
+	For      string `json:"for"`
+	Digest   bool   `json:"digest"`
+	Task     string `json:"task"`
+
+	op, args string
+	muteFor  time.Duration
+}
+
+// Config is the rules config file.
+type Config struct {
+	ChatID int64  `json:"chat_id"`
+	Rules  []Rule `json:"rules"`
+}
+
+// maxMute matches the longest /mute.
+const maxMute = 30 * 24 * time.Hour
+
+// LoadConfig reads and validates a rules config file.
+// Returns nil, nil if the file does not exist.
+func LoadConfig(path string) (*Config, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("read rules config: %w", err)
+	}
+
+	var cfg Config
+	if err := json.Unmarshal(data, &cfg); err != nil {
+		return nil, fmt.Errorf("parse rules config: %w", err)
+	}
+	seen := make(map[string]bool)
+	for i := range cfg.Rules {
+		r := &cfg.Rules[i]
+		r.Name = strings.TrimSpace(r.Name)
+		if r.Name == "" || strings.ContainsAny(r.Name, " \t\n") {
+			return nil, fmt.Errorf("rules config: rule at index %d needs a one-word name", i)
+		}
+		if seen[r.Name] {
+			return nil, fmt.Errorf("rules config: duplicate rule %q", r.Name)
+		}
+		seen[r.Name] = true
+		if err := r.validate(cfg.ChatID); err != nil {
+			return nil, fmt.Errorf("rules config: rule %q: %w", r.Name, err)
+		}
+	}
+	return &cfg, nil
+}
+
+func (r *Rule) validate(defaultChat int64) error {
+	if err := r.validateTrigger(); err != nil {
+		return err
+	}
+	for attr, pattern := range r.When {
+		if _, err := path.Match(pattern, ""); err != nil {
+			return fmt.Errorf("when %s: bad pattern %q", attr, pattern)
+		}
+	}
+	if err := r.validateWindow(); err != nil {
+		return err
+	}
+	if r.Rate != nil && (r.Rate.Max < 1 || r.Rate.WindowMin < 1) {
+		return fmt.Errorf("rate needs max and window_min of at least 1")
+	}
+
+	if r.Run != "" {
+		r.Actions = append([]Action{{Run: r.Run, Approval: r.Approval, Quiet: r.Quiet}}, r.Actions...)
+	} else if r.Approval || r.Quiet {
+		return fmt.Errorf("approval and quiet need run")
+	}
+	if len(r.Actions) == 0 {
+		return fmt.Errorf("no actions")
+	}
+	for i := range r.Actions {
+		if err := r.Actions[i].validate(); err != nil {
+			return fmt.Errorf("action %d: %w", i+1, err)
+		}
+	}
+
+	if r.ChatID == 0 {
+		r.ChatID = defaultChat
+	}
+	if r.ChatID == 0 {
+		return fmt.Errorf("missing chat_id")
+	}
+	return nil
+}
+
+func (r *Rule) validateTrigger() error {
+	triggers := 0
+	for _, set := range []bool{r.On != "", r.Schedule != "", r.Keyword != ""} {
+		if set {
+			triggers++
+		}
+	}
+	if triggers != 1 {
+		return fmt.Errorf("set exactly one of on, schedule and keyword")
+	}
+	switch {
+	case r.Keyword != "":
+		r.Keyword = strings.ToLower(strings.TrimSpace(r.Keyword))
+		r.On = events.MessageReceived
+	case r.Schedule != "":
+		if _, err := cron.Parse(r.Schedule); err != nil {
+			return fmt.Errorf("schedule: %w", err)
+		}
+		if len(r.When) > 0 {
+			return fmt.Errorf("when cannot be used with schedule")
+		}
+	case !events.Known(r.On):
+		return fmt.Errorf("unknown topic %q", r.On)
+	}
+	return nil
+}
+
+var weekdays = map[string]time.Weekday{
+	"sun": time.Sunday, "mon": time.Monday, "tue": time.Tuesday, "wed": time.Wednesday,
+	"thu": time.Thursday, "fri": time.Friday, "sat": time.Saturday,
+}
+
+func (r *Rule) validateWindow() error {
+	r.loc = time.Local
+	if r.Timezone != "" {
+		loc, err := time.LoadLocation(r.Timezone)
+		if err != nil {
+			return fmt.Errorf("timezone: %w", err)
+		}
+		r.loc = loc
+	}
+	if r.Between != "" {
+		from, to, ok := strings.Cut(r.Between, "-")
+		var err1, err2 error
+		r.from, err1 = parseClock(from)
+		r.to, err2 = parseClock(to)
+		if !ok || err1 != nil || err2 != nil {
+			return fmt.Errorf("between must be HH:MM-HH:MM, got %q", r.Between)
+		}
+	}
+	if len(r.Days) > 0 {
+		r.days = make(map[time.Weekday]bool)
+		for _, d := range r.Days {
+			wd, ok := weekdays[strings.ToLower(d)]
+			if !ok {
+				return fmt.Errorf("unknown day %q (want mon, tue, ...)", d)
+			}
+			r.days[wd] = true
+		}
+	}
+	return nil
+}
+
+// parseClock parses "HH:MM" into minutes past midnight.
+func parseClock(s string) (int, error) {
+	t, err := time.Parse("15:04", strings.TrimSpace(s))
+	if err != nil {
+		return 0, err
+	}
+	return t.Hour()*60 + t.Minute(), nil
+}
+
+func (a *Action) validate() error {
+	set := 0
+	for _, s := range []string{a.Run, a.Notify, a.Mute, a.Task} {
+		if s != "" {
+			set++
+		}
+	}
+	if set != 1 {
+		return fmt.Errorf("set exactly one of run, notify, mute and task")
+	}
+	if a.Run == "" && (a.Approval || a.Quiet) {
+		return fmt.Errorf("approval and quiet apply to run only")
+	}
+	if a.Mute == "" && (a.For != "" || a.Digest) {
+		return fmt.Errorf("for and digest apply to mute only")
+	}
+
+	if a.Run != "" {
+		run := strings.TrimSpace(a.Run)
+		if !strings.HasPrefix(run, "/") || len(run) == 1 {
+			return fmt.Errorf("run must be a command such as /status")
+		}
+		op, args, _ := strings.Cut(run[1:], " ")
+		a.op, a.args = strings.ToLower(op), strings.TrimSpace(args)
+	}
+	if a.For != "" {
+		d, err := time.ParseDuration(a.For)
+		if err != nil || d <= 0 || d > maxMute {
+			return fmt.Errorf("for must be a duration between 1m and 720h, got %q", a.For)
+		}
+		a.muteFor = d
+	}
+	return nil
+}
+
+// text returns the part of a that event attrs are filled into.
+func (a *Action) text() string {
+	switch {
+	case a.Run != "":
+		return a.args
+	case a.Notify != "":
+		return a.Notify
+	case a.Mute != "":
+		return a.Mute
+	}
+	return a.Task
+}
+
+// Matches reports whether ev triggers r, before its time window and rate
+// are checked. Scheduled rules match no event.
+func (r *Rule) Matches(ev events.Event) bool {
+	if r.Schedule != "" || ev.Topic != r.On {
+		return false
+	}
+	if r.Keyword != "" && !containsWord(ev.Attrs["text"], r.Keyword) {
+		return false
+	}
+	for attr, pattern := range r.When {
+		v, ok := ev.Attrs[attr]
+		if !ok {
+			return false
+		}
+		if matched, _ := path.Match(pattern, v); !matched {
+			return false
+		}
+	}
+	return true
+}
+
+// containsWord reports whether text has word as a whole word, ignoring
+// case.
+func containsWord(text, word string) bool {
+	text = strings.ToLower(text)
+	for i := 0; ; {
+		j := strings.Index(text[i:], word)
+		if j == -1 {
+			return false
+		}
+		start, end := i+j, i+j+len(word)
+		if (start == 0 || !isWordByte(text[start-1])) && (end == len(text) || !isWordByte(text[end])) {
+			return true
+		}
+		i = start + 1
+	}
+}
+
+func isWordByte(b byte) bool {
+	return b == '_' || b >= '0' && b <= '9' || b >= 'a' && b <= 'z' || b >= 0x80
+}
+
+// InWindow reports whether t falls in r's days and hours.
+func (r *Rule) InWindow(t time.Time) bool {
+	t = t.In(r.loc)
+	if r.days != nil && !r.days[t.Weekday()] {
+		return false
+	}
+	if r.from == r.to {
+		return true
+	}
+	m := t.Hour()*60 + t.Minute()
+	if r.from < r.to {
+		return m >= r.from && m < r.to
+	}
+	return m >= r.from || m < r.to // spans midnight
+}
+
+// trigger describes r's trigger for listings.
+func (r *Rule) trigger() string {
+	switch {
+	case r.Schedule != "":
+		return "schedule " + strconv.Quote(r.Schedule)
+	case r.Keyword != "":
+		return "keyword " + strconv.Quote(r.Keyword)
+	}
+	s := string(r.On)
+	for _, attr := range slices.Sorted(maps.Keys(r.When)) {
+		s += " " + attr + "=" + r.When[attr]
+	}
+	return s
+}
+
+// expand replaces each {event.<attr>} in s with ev's attr.
+func expand(s string, ev events.Event) (string, error) {
+	var b strings.Builder
+	for {
+		open := strings.Index(s, "{event.")
+		if open == -1 {
+			b.WriteString(s)
+			return b.String(), nil
+		}
+		end := strings.IndexByte(s[open:], '}')
+		if end == -1 {
+			b.WriteString(s)
+			return b.String(), nil
+		}
+		end += open
+		attr := s[open+len("{event.") : end]
+		v, ok := ev.Attrs[attr]
+		if !ok {
+			return "", fmt.Errorf("%s event has no attr %q", ev.Topic, attr)
+		}
+		b.WriteString(s[:open])
+		b.WriteString(v)
+		s = s[end+1:]
+	}
+}
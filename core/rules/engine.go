@@ -0,0 +1,389 @@
+package rules
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"sync"
+	"time"
+
+	"github.com/jdelaire/openslack/core"
+	"github.com/jdelaire/openslack/core/ops"
+	"github.com/jdelaire/openslack/internal/cron"
+	"github.com/jdelaire/openslack/internal/events"
+	"github.com/jdelaire/openslack/internal/tasks"
+)
+
+const source = "rules"
+
+// scheduleTopic is the topic of the event a scheduled rule fires with.
+// Its one attr is "at", the scheduled time.
+const scheduleTopic events.Topic = "schedule"
+
+// Muter mutes notification sources. *sources.Registry implements it.
+type Muter interface {
+	Mute(source string, until time.Time, digest bool) error
+}
+
+// TaskCreator creates tasks. *tasks.TaskService implements it.
+type TaskCreator interface {
+	CreateTomorrowFor(chatID int64, text string) (tasks.Task, error)
+}
+
+// Engine runs rules: it subscribes to the events bus and schedules cron
+// jobs, and runs the actions of each rule whose trigger and conditions
+// match. Events are handled one at a time in the order published; actions
+// of a rule run in order and stop at the first that fails.
+//
+// Events caused by a rule's own run carry a "rule" attr and trigger no
+// rules, so a rule on command.completed cannot set off a loop.
+type Engine struct {
+	bus       *events.Bus
+	exec      core.OpExecutor
+	notifier  core.Notifier
+	approvals core.ApprovalStore
+	registry  *ops.Registry
+	muter     Muter
+	tasks     TaskCreator
+	cron      *cron.Engine
+	logger    *slog.Logger
+	now       func() time.Time
+
+	mu   sync.RWMutex
+	cfg  *Config
+	jobs map[string]string // rule name -> schedule registered with cron
+
+	rateMu sync.Mutex
+	fired  map[string][]time.Time // rule name -> recent firings
+}
+
+// NewEngine creates an engine for cfg, which may be nil for no rules.
+func NewEngine(cfg *Config, bus *events.Bus, exec core.OpExecutor, notifier core.Notifier, logger *slog.Logger) *Engine {
+	if logger == nil {
+		logger = slog.Default()
+	}
+	if cfg == nil {
+		cfg = &Config{}
+	}
+	return &Engine{
+		bus:      bus,
+		exec:     exec,
+		notifier: notifier,
+		logger:   logger,
+		now:      time.Now,
+		cfg:      cfg,
+		jobs:     make(map[string]string),
+		fired:    make(map[string][]time.Time),
+	}
+}
+
+// WithApprovals lets rules run ops via two-step approval: the store holds
+// the pending run, which the chat completes with /approve, and registry
+// tells which ops are high-risk and must go that way. The dispatcher must
+// share the store and have TOTP set up. Without approvals, rules that
+// need one fail.
+func (e *Engine) WithApprovals(store core.ApprovalStore, registry *ops.Registry) *Engine {
+	e.approvals = store
+	e.registry = registry
+	return e
+}
+
+// WithMuter enables mute actions.
+func (e *Engine) WithMuter(m Muter) *Engine {
+	e.muter = m
+	return e
+}
+
+// WithTasks enables task actions.
+func (e *Engine) WithTasks(t TaskCreator) *Engine {
+	e.tasks = t
+	return e
+}
+
+// WithCron runs scheduled rules as jobs named "rule-<name>" on c, so they
+// show in /cron. Without it, scheduled rules never fire.
+func (e *Engine) WithCron(c *cron.Engine) *Engine {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	e.cron = c
+	e.syncJobsLocked()
+	return e
+}
+
+// WithClock replaces time.Now for conditions.
+func (e *Engine) WithClock(now func() time.Time) *Engine {
+	e.now = now
+	return e
+}
+
+// Rules returns the current rules.
+func (e *Engine) Rules() []Rule {
+	e.mu.RLock()
+	defer e.mu.RUnlock()
+	return e.cfg.Rules
+}
+
+// SetConfig replaces the rules. A nil cfg clears them. Rate counts carry
+// over for rules that keep their name.
+func (e *Engine) SetConfig(cfg *Config) {
+	if cfg == nil {
+		cfg = &Config{}
+	}
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	e.cfg = cfg
+	e.syncJobsLocked()
+}
+
+// Reload loads the rules from the config file and applies them. An
+// invalid file keeps the previous rules; a missing one clears them.
+func (e *Engine) Reload(path string) {
+	cfg, err := LoadConfig(path)
+	if err != nil {
+		e.logger.Error("reload rules failed", "path", path, "error", err)
+		return
+	}
+	e.SetConfig(cfg)
+	e.logger.Info("rules reloaded", "count", len(e.Rules()))
+}
+
+// syncJobsLocked registers a cron job for each scheduled rule, replacing
+// jobs whose schedule changed and removing those of rules now gone.
+func (e *Engine) syncJobsLocked() {
+	if e.cron == nil {
+		return
+	}
+	want := make(map[string]string)
+	for _, r := range e.cfg.Rules {
+		if r.Schedule != "" {
+			want[r.Name] = r.Schedule + " " + r.loc.String()
+		}
+	}
+	for name, spec := range e.jobs {
+		if want[name] == spec {
+			continue
+		}
+		if _, err := e.cron.Remove("rule-" + name); err != nil {
+			e.logger.Error("rules: remove job failed", "rule", name, "error", err)
+		}
+		delete(e.jobs, name)
+	}
+	for _, r := range e.cfg.Rules {
+		if r.Schedule == "" || e.jobs[r.Name] != "" {
+			continue
+		}
+		name := r.Name
+		err := e.cron.Add(cron.Job{
+			Name:     "rule-" + name,
+			Spec:     r.Schedule,
+			Location: r.loc,
+			Run: func(ctx context.Context, at time.Time) error {
+				return e.fireScheduled(ctx, name, at)
+			},
+		})
+		if err != nil {
+			e.logger.Error("rules: add job failed", "rule", name, "error", err)
+			continue
+		}
+		e.jobs[name] = want[name]
+	}
+}
+
+// Run handles events until ctx is cancelled.
+func (e *Engine) Run(ctx context.Context) {
+	ch, cancel := e.bus.Subscribe()
+	defer cancel()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case ev := <-ch:
+			e.Handle(ctx, ev)
+		}
+	}
+}
+
+// Handle fires every rule that matches ev.
+func (e *Engine) Handle(ctx context.Context, ev events.Event) {
+	if _, ok := ev.Attrs["rule"]; ok {
+		return
+	}
+	for _, r := range e.Rules() {
+		if r.Matches(ev) {
+			e.fire(ctx, &r, ev)
+		}
+	}
+}
+
+func (e *Engine) fireScheduled(ctx context.Context, name string, at time.Time) error {
+	for _, r := range e.Rules() {
+		if r.Name == name && r.Schedule != "" {
+			return e.fire(ctx, &r, events.Event{Topic: scheduleTopic, Time: at, Attrs: map[string]string{"at": at.Format(time.RFC3339)}})
+		}
+	}
+	return nil
+}
+
+// fire runs r's actions for ev if its time window and rate allow, and
+// reports failures to the rule's chat.
+func (e *Engine) fire(ctx context.Context, r *Rule, ev events.Event) error {
+	now := e.now()
+	if !r.InWindow(now) {
+		e.logger.Debug("rule outside its window", "rule", r.Name)
+		return nil
+	}
+	if !e.allow(r, now) {
+		e.logger.Info("rule over its rate", "rule", r.Name)
+		return nil
+	}
+	e.logger.Info("rule triggered", "rule", r.Name, "topic", ev.Topic)
+
+	for i := range r.Actions {
+		if err := e.act(ctx, r, &r.Actions[i], ev); err != nil {
+			e.logger.Warn("rules: action failed", "rule", r.Name, "action", i+1, "error", err)
+			e.send(ctx, r.ChatID, fmt.Sprintf("Rule %s: %s", r.Name, err), nil)
+			return err
+		}
+	}
+	return nil
+}
+
+// allow records a firing of r at now and reports whether its rate allows
+// it.
+func (e *Engine) allow(r *Rule, now time.Time) bool {
+	if r.Rate == nil {
+		return true
+	}
+	e.rateMu.Lock()
+	defer e.rateMu.Unlock()
+	recent := e.recentLocked(r, now)
+	if len(recent) >= r.Rate.Max {
+		return false
+	}
+	e.fired[r.Name] = append(recent, now)
+	return true
+}
+
+// overRate reports whether r has used up its rate at now.
+func (e *Engine) overRate(r *Rule, now time.Time) bool {
+	if r.Rate == nil {
+		return false
+	}
+	e.rateMu.Lock()
+	defer e.rateMu.Unlock()
+	return len(e.recentLocked(r, now)) >= r.Rate.Max
+}
+
+// recentLocked drops r's firings older than its rate window and returns
+// the rest. Callers hold e.rateMu.
+func (e *Engine) recentLocked(r *Rule, now time.Time) []time.Time {
+	since := now.Add(-time.Duration(r.Rate.WindowMin) * time.Minute)
+	recent := e.fired[r.Name][:0]
+	for _, t := range e.fired[r.Name] {
+		if t.After(since) {
+			recent = append(recent, t)
+		}
+	}
+	e.fired[r.Name] = recent
+	return recent
+}
+
+// act takes one action of r for ev.
+func (e *Engine) act(ctx context.Context, r *Rule, a *Action, ev events.Event) error {
+	switch {
+	case a.Run != "":
+		args, err := expand(a.args, ev)
+		if err != nil {
+			return err
+		}
+		if a.Approval || e.highRisk(a.op, args) {
+			return e.requestApproval(ctx, r, ev, a.op, args)
+		}
+		out, err := e.exec.Exec(events.WithRule(ctx, r.Name), r.ChatID, a.op, args)
+		if err != nil {
+			return fmt.Errorf("/%s failed: %w", a.op, err)
+		}
+		if !a.Quiet {
+			e.send(ctx, r.ChatID, fmt.Sprintf("Rule %s ran /%s:\n%s", r.Name, a.op, out), nil)
+		}
+
+	case a.Notify != "":
+		text, err := expand(a.Notify, ev)
+		if err != nil {
+			return err
+		}
+		e.send(ctx, r.ChatID, text, nil)
+
+	case a.Mute != "":
+		if e.muter == nil {
+			return errors.New("mute actions are not set up")
+		}
+		src, err := expand(a.Mute, ev)
+		if err != nil {
+			return err
+		}
+		var until time.Time
+		if a.muteFor > 0 {
+			until = e.now().Add(a.muteFor).Truncate(time.Minute)
+		}
+		if err := e.muter.Mute(src, until, a.Digest); err != nil {
+			return fmt.Errorf("mute %s: %w", src, err)
+		}
+		e.logger.Info("rule muted source", "rule", r.Name, "source", src, "until", until)
+
+	case a.Task != "":
+		if e.tasks == nil {
+			return errors.New("task actions are not set up")
+		}
+		text, err := expand(a.Task, ev)
+		if err != nil {
+			return err
+		}
+		task, err := e.tasks.CreateTomorrowFor(r.ChatID, text)
+		if err != nil {
+			return fmt.Errorf("create task: %w", err)
+		}
+		e.logger.Info("rule created task", "rule", r.Name, "id", task.ID)
+	}
+	return nil
+}
+
+func (e *Engine) highRisk(op, args string) bool {
+	if e.registry == nil {
+		return false
+	}
+	o := e.registry.Get(op)
+	return o != nil && e.registry.RiskOfCall(o, args) == ops.RiskHigh
+}
+
+func (e *Engine) requestApproval(ctx context.Context, r *Rule, ev events.Event, op, args string) error {
+	if e.approvals == nil {
+		return fmt.Errorf("/%s needs approval, which is not set up", op)
+	}
+	nonce, err := e.approvals.Create(r.ChatID, op, args)
+	if err != nil {
+		return fmt.Errorf("failed to create approval: %w", err)
+	}
+	e.send(ctx, r.ChatID, fmt.Sprintf("Rule %s (%s) wants to run /%s. Send:\n/approve %s <totp>", r.Name, ev.Topic, op, nonce), []core.Button{
+		{Text: "Approve", Data: "/approve " + nonce},
+		{Text: "Reject", Data: "/reject " + nonce},
+	})
+	return nil
+}
+
+func (e *Engine) send(ctx context.Context, chatID int64, text string, buttons []core.Button) {
+	ctx, cancel := context.WithTimeout(ctx, 10*time.Second)
+	defer cancel()
+	err := e.notifier.Send(ctx, core.Notification{
+		Text:      text,
+		Source:    source,
+		CreatedAt: e.now(),
+		ChatID:    chatID,
+		Buttons:   buttons,
+	})
+	if err != nil {
+		e.logger.Error("rules: send failed", "chat_id", chatID, "error", err)
+	}
+}
@@ -0,0 +1,185 @@
+package rules
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/jdelaire/openslack/core/ops"
+	"github.com/jdelaire/openslack/internal/events"
+)
+
+const rulesUsage = "Usage: /rules [test <topic> [attr=value ...] | test <rule>]"
+
+// Op is /rules: it lists the rules, and with "test" shows what an event
+// would do without doing it.
+//
+//	/rules test monitor.down host=nas monitor=nas-http
+//	/rules test message.received text=deploy the api
+//	/rules test nightly
+//
+// A rule name tests that rule as if its trigger had fired, which is how
+// scheduled rules are tested.
+type Op struct {
+	Engine *Engine
+}
+
+func (o *Op) Name() string        { return "rules" }
+func (o *Op) Description() string { return "List automation rules or test which would fire" }
+func (o *Op) Usage() string       { return rulesUsage }
+func (o *Op) Risk() ops.RiskLevel { return ops.RiskNone }
+
+func (o *Op) Execute(_ context.Context, args string) (string, error) {
+	fields := strings.Fields(args)
+	if len(fields) == 0 {
+		return o.list(), nil
+	}
+	if fields[0] != "test" || len(fields) < 2 {
+		return rulesUsage, nil
+	}
+
+	target := fields[1]
+	attrs, ok := parseAttrs(fields[2:])
+	if !ok {
+		return rulesUsage, nil
+	}
+	ev := events.Event{Topic: events.Topic(target), Time: o.Engine.now(), Attrs: attrs}
+	if events.Known(ev.Topic) {
+		return strings.Join(o.Engine.Explain(ev), "\n"), nil
+	}
+	for _, r := range o.Engine.Rules() {
+		if r.Name == target {
+			if r.Schedule != "" {
+				ev.Topic = scheduleTopic
+				ev.Attrs["at"] = ev.Time.Format(time.RFC3339)
+			} else {
+				ev.Topic = r.On
+			}
+			return r.Name + ": " + o.Engine.explain(&r, ev), nil
+		}
+	}
+	return fmt.Sprintf("No topic or rule %q. Topics: %s.", target, topicList()), nil
+}
+
+func (o *Op) list() string {
+	rules := o.Engine.Rules()
+	if len(rules) == 0 {
+		return "No rules."
+	}
+	lines := make([]string, 0, len(rules))
+	for _, r := range rules {
+		acts := make([]string, len(r.Actions))
+		for i := range r.Actions {
+			a := &r.Actions[i]
+			acts[i] = describe(a, a.text(), a.Approval)
+		}
+		lines = append(lines, fmt.Sprintf("%s: on %s, %s", r.Name, r.trigger(), strings.Join(acts, ", then ")))
+	}
+	return strings.Join(lines, "\n")
+}
+
+// parseAttrs parses "key=value" fields. A field without "=" continues the
+// previous value, so values may contain spaces.
+func parseAttrs(fields []string) (map[string]string, bool) {
+	attrs := make(map[string]string)
+	last := ""
+	for _, f := range fields {
+		k, v, ok := strings.Cut(f, "=")
+		if !ok || k == "" {
+			if last == "" {
+				return nil, false
+			}
+			attrs[last] += " " + f
+			continue
+		}
+		attrs[k], last = v, k
+	}
+	return attrs, true
+}
+
+func topicList() string {
+	names := make([]string, len(events.Topics))
+	for i, t := range events.Topics {
+		names[i] = string(t)
+	}
+	return strings.Join(names, ", ")
+}
+
+// Explain reports what ev would do now: a line for each rule it triggers
+// saying what the rule would do, or why it would not.
+func (e *Engine) Explain(ev events.Event) []string {
+	var lines []string
+	for _, r := range e.Rules() {
+		if r.Matches(ev) {
+			lines = append(lines, r.Name+": "+e.explain(&r, ev))
+		}
+	}
+	if len(lines) == 0 {
+		return []string{"No rule matches."}
+	}
+	return lines
+}
+
+// explain says what r would do for ev now, without doing it or counting
+// against its rate.
+func (e *Engine) explain(r *Rule, ev events.Event) string {
+	now := e.now()
+	if !r.InWindow(now) {
+		return "matches, but not now (" + r.window() + ")"
+	}
+	if e.overRate(r, now) {
+		return fmt.Sprintf("matches, but is over its rate of %d per %dm", r.Rate.Max, r.Rate.WindowMin)
+	}
+	acts := make([]string, 0, len(r.Actions))
+	for i := range r.Actions {
+		a := &r.Actions[i]
+		text, err := expand(a.text(), ev)
+		if err != nil {
+			return "would fail: " + err.Error()
+		}
+		acts = append(acts, describe(a, text, a.Approval || (a.Run != "" && e.highRisk(a.op, text))))
+	}
+	return "would " + strings.Join(acts, ", then ")
+}
+
+// describe says what a does, given its text: the command's args, the
+// notification, the source or the task, with event attrs filled in or not.
+func describe(a *Action, text string, approval bool) string {
+	switch {
+	case a.Run != "":
+		s := "run /" + a.op
+		if text != "" {
+			s += " " + text
+		}
+		if approval {
+			s += " via approval"
+		}
+		return s
+	case a.Notify != "":
+		return "notify " + strconv.Quote(text)
+	case a.Mute != "":
+		if a.For == "" {
+			return "mute " + text + " until /unmute"
+		}
+		return "mute " + text + " for " + a.For
+	default:
+		return "create task " + strconv.Quote(text)
+	}
+}
+
+// window describes r's days and hours.
+func (r *Rule) window() string {
+	var parts []string
+	if len(r.Days) > 0 {
+		parts = append(parts, strings.Join(r.Days, ","))
+	}
+	if r.Between != "" {
+		parts = append(parts, r.Between)
+	}
+	if r.Timezone != "" {
+		parts = append(parts, r.Timezone)
+	}
+	return strings.Join(parts, " ")
+}
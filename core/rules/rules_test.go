@@ -0,0 +1,442 @@
+package rules
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/jdelaire/openslack/core/approval"
+	"github.com/jdelaire/openslack/core/ops"
+	"github.com/jdelaire/openslack/core/testkit"
+	"github.com/jdelaire/openslack/internal/cron"
+	"github.com/jdelaire/openslack/internal/events"
+	"github.com/jdelaire/openslack/internal/tasks"
+)
+
+type restartOp struct{ risk ops.RiskLevel }
+
+func (restartOp) Name() string                                    { return "restart" }
+func (restartOp) Description() string                             { return "Restart a host" }
+func (o restartOp) Risk() ops.RiskLevel                           { return o.risk }
+func (restartOp) Execute(context.Context, string) (string, error) { return "", nil }
+
+type call struct {
+	chatID   int64
+	cmd      string
+	args     string
+	rule     string
+	withRule bool
+}
+
+type fakeExec struct {
+	calls []call
+	err   error
+}
+
+func (e *fakeExec) Exec(ctx context.Context, chatID int64, cmd, args string) (string, error) {
+	rule, ok := events.RuleFrom(ctx)
+	e.calls = append(e.calls, call{chatID, cmd, args, rule, ok})
+	return "restarted " + args, e.err
+}
+
+func writeConfig(t *testing.T, body string) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "rules.json")
+	if err := os.WriteFile(path, []byte(body), 0600); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+func TestLoadConfig(t *testing.T) {
+	if cfg, err := LoadConfig(filepath.Join(t.TempDir(), "missing.json")); cfg != nil || err != nil {
+		t.Fatalf("missing file = %v, %v", cfg, err)
+	}
+
+	cfg, err := LoadConfig(writeConfig(t, `{"chat_id": 42, "rules": [
+		{"name": "nas", "on": "monitor.down", "when": {"host": "nas*"}, "run": "/Restart {event.host} now"},
+		{"name": "own", "on": "task.completed", "run": "/status", "chat_id": 7}
+	]}`))
+	if err != nil {
+		t.Fatalf("load: %v", err)
+	}
+	a := cfg.Rules[0].Actions[0]
+	if cfg.Rules[0].ChatID != 42 || a.op != "restart" || a.args != "{event.host} now" || cfg.Rules[1].ChatID != 7 {
+		t.Fatalf("rules = %+v", cfg.Rules)
+	}
+
+	bad := map[string]string{
+		"no name":      `{"chat_id": 1, "rules": [{"on": "monitor.down", "run": "/x"}]}`,
+		"duplicate":    `{"chat_id": 1, "rules": [{"name": "a", "on": "monitor.down", "run": "/x"}, {"name": "a", "on": "monitor.down", "run": "/y"}]}`,
+		"topic":        `{"chat_id": 1, "rules": [{"name": "a", "on": "monitor.up", "run": "/x"}]}`,
+		"run":          `{"chat_id": 1, "rules": [{"name": "a", "on": "monitor.down", "run": "restart"}]}`,
+		"pattern":      `{"chat_id": 1, "rules": [{"name": "a", "on": "monitor.down", "when": {"host": "["}, "run": "/x"}]}`,
+		"chat":         `{"rules": [{"name": "a", "on": "monitor.down", "run": "/x"}]}`,
+		"invalid json": `{`,
+	}
+	for name, body := range bad {
+		if _, err := LoadConfig(writeConfig(t, body)); err == nil {
+			t.Errorf("%s: loaded", name)
+		}
+	}
+}
+
+func newEngine(t *testing.T, body string, exec *fakeExec) (*Engine, *testkit.Notifier) {
+	t.Helper()
+	cfg, err := LoadConfig(writeConfig(t, body))
+	if err != nil {
+		t.Fatalf("load: %v", err)
+	}
+	n := testkit.NewNotifier()
+	logger := slog.New(slog.NewTextHandler(io.Discard, nil))
+	return NewEngine(cfg, events.NewBus(logger), exec, n, logger), n
+}
+
+func TestEngineRunsMatchingRules(t *testing.T) {
+	exec := &fakeExec{}
+	e, n := newEngine(t, `{"chat_id": 42, "rules": [
+		{"name": "nas", "on": "monitor.down", "when": {"host": "nas"}, "run": "/restart {event.host}"},
+		{"name": "quiet", "on": "monitor.down", "run": "/restart all", "quiet": true}
+	]}`, exec)
+	ctx := context.Background()
+
+	e.Handle(ctx, events.Event{Topic: events.MonitorDown, Attrs: map[string]string{"host": "nas"}})
+	e.Handle(ctx, events.Event{Topic: events.MonitorDown, Attrs: map[string]string{"host": "router"}})
+	e.Handle(ctx, events.Event{Topic: events.TaskCompleted, Attrs: map[string]string{"host": "nas"}})
+
+	want := []call{
+		{42, "restart", "nas", "nas", true},
+		{42, "restart", "all", "quiet", true},
+		{42, "restart", "all", "quiet", true},
+	}
+	if len(exec.calls) != len(want) {
+		t.Fatalf("calls = %+v", exec.calls)
+	}
+	for i := range want {
+		if exec.calls[i] != want[i] {
+			t.Fatalf("call %d = %+v, want %+v", i, exec.calls[i], want[i])
+		}
+	}
+	if texts := n.Texts(); len(texts) != 1 || texts[0] != "Rule nas ran /restart:\nrestarted nas" {
+		t.Fatalf("sent = %q", texts)
+	}
+
+	// Events a rule caused trigger nothing.
+	e.Handle(ctx, events.Event{Topic: events.MonitorDown, Attrs: map[string]string{"host": "nas", "rule": "nas"}})
+	if len(exec.calls) != len(want) {
+		t.Fatalf("rule-caused event ran %+v", exec.calls[len(want):])
+	}
+}
+
+func TestEngineReportsFailures(t *testing.T) {
+	exec := &fakeExec{err: errors.New("boom")}
+	e, n := newEngine(t, `{"chat_id": 42, "rules": [
+		{"name": "nas", "on": "connector.crashed", "run": "/restart {event.connector}", "quiet": true},
+		{"name": "typo", "on": "connector.crashed", "run": "/restart {event.nope}"}
+	]}`, exec)
+
+	e.Handle(context.Background(), events.Event{Topic: events.ConnectorCrashed, Attrs: map[string]string{"connector": "mail"}})
+	texts := n.Texts()
+	if len(texts) != 2 || texts[0] != "Rule nas: /restart failed: boom" || !strings.Contains(texts[1], `no attr "nope"`) {
+		t.Fatalf("sent = %q", texts)
+	}
+	if len(exec.calls) != 1 {
+		t.Fatalf("calls = %+v", exec.calls)
+	}
+}
+
+func TestEngineApproval(t *testing.T) {
+	exec := &fakeExec{}
+	e, n := newEngine(t, `{"chat_id": 42, "rules": [
+		{"name": "asked", "on": "monitor.down", "run": "/status", "approval": true},
+		{"name": "risky", "on": "monitor.down", "run": "/restart {event.host}"}
+	]}`, exec)
+	ctx := context.Background()
+	ev := events.Event{Topic: events.MonitorDown, Attrs: map[string]string{"host": "nas"}}
+
+	// Without an approval store, nothing runs.
+	e.Handle(ctx, ev)
+	if len(exec.calls) != 1 || exec.calls[0].cmd != "restart" {
+		t.Fatalf("calls = %+v", exec.calls)
+	}
+	if texts := n.Texts(); len(texts) != 2 || !strings.Contains(texts[0], "needs approval") {
+		t.Fatalf("sent = %q", texts)
+	}
+
+	reg := ops.NewRegistry()
+	reg.Register(restartOp{risk: ops.RiskHigh})
+	store := approval.New()
+	e.WithApprovals(store, reg)
+	exec.calls = nil
+	e.Handle(ctx, ev)
+	if len(exec.calls) != 0 {
+		t.Fatalf("ran without approval: %+v", exec.calls)
+	}
+	pending := store.Pending(42)
+	if len(pending) != 2 {
+		t.Fatalf("pending = %+v", pending)
+	}
+	sent := n.Sent()
+	last := sent[len(sent)-1]
+	if !strings.HasPrefix(last.Text, "Rule risky (monitor.down) wants to run /restart.") || len(last.Buttons) != 2 || last.ChatID != 42 {
+		t.Fatalf("approval request = %+v", last)
+	}
+	op, args, err := store.Consume(strings.TrimPrefix(last.Buttons[0].Data, "/approve "), 42)
+	if err != nil || op != "restart" || args != "nas" {
+		t.Fatalf("consume = %q %q %v", op, args, err)
+	}
+}
+
+func TestEngineRun(t *testing.T) {
+	exec := &fakeExec{}
+	e, n := newEngine(t, `{"chat_id": 42, "rules": [{"name": "done", "on": "task.completed", "run": "/restart {event.id}"}]}`, exec)
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan struct{})
+	go func() {
+		e.Run(ctx)
+		close(done)
+	}()
+
+	// Run subscribes asynchronously; publish until the rule fires.
+	for i := 0; ; i++ {
+		e.bus.Publish(events.TaskCompleted, map[string]string{"id": "3"})
+		if _, ok := n.Wait("restarted 3", 10*time.Millisecond); ok {
+			break
+		}
+		if i == 100 {
+			t.Fatal("rule never fired")
+		}
+	}
+	cancel()
+	<-done
+}
+
+type fakeMuter struct{ muted []string }
+
+func (m *fakeMuter) Mute(source string, until time.Time, digest bool) error {
+	m.muted = append(m.muted, fmt.Sprintf("%s %s %v", source, until.Format("15:04"), digest))
+	return nil
+}
+
+type fakeTasks struct{ created []string }
+
+func (f *fakeTasks) CreateTomorrowFor(chatID int64, text string) (tasks.Task, error) {
+	if text == "" {
+		return tasks.Task{}, tasks.ErrEmptyTaskText
+	}
+	f.created = append(f.created, fmt.Sprintf("%d %s", chatID, text))
+	return tasks.Task{ID: len(f.created), Text: text}, nil
+}
+
+func TestConditions(t *testing.T) {
+	exec := &fakeExec{}
+	e, _ := newEngine(t, `{"chat_id": 42, "rules": [
+		{"name": "night", "on": "monitor.down", "between": "22:00-07:00", "days": ["sat", "sun"], "timezone": "UTC", "run": "/restart night", "quiet": true},
+		{"name": "capped", "on": "monitor.down", "rate": {"max": 2, "window_min": 60}, "run": "/restart capped", "quiet": true}
+	]}`, exec)
+	now := time.Date(2026, 3, 7, 23, 30, 0, 0, time.UTC) // a Saturday
+	e.WithClock(func() time.Time { return now })
+	ev := events.Event{Topic: events.MonitorDown}
+
+	fire := func(at time.Time) []string {
+		now = at
+		exec.calls = nil
+		e.Handle(context.Background(), ev)
+		var ran []string
+		for _, c := range exec.calls {
+			ran = append(ran, c.args)
+		}
+		return ran
+	}
+
+	steps := []struct {
+		at   time.Time
+		want string
+	}{
+		{now, "night capped"},
+		{now.Add(10 * time.Minute), "night capped"},
+		{now.Add(20 * time.Minute), "night"},        // capped is over its rate
+		{now.Add(61 * time.Minute), "night capped"}, // Sunday 00:31; the first firing has aged out
+		{now.Add(8 * time.Hour), "capped"},          // Sunday 07:30 is outside night's hours
+		{now.Add(48 * time.Hour), "capped"},         // Monday 23:30
+	}
+	for i, step := range steps {
+		if got := strings.Join(fire(step.at), " "); got != step.want {
+			t.Errorf("step %d at %s: ran %q, want %q", i, step.at.Format("Mon 15:04"), got, step.want)
+		}
+	}
+}
+
+func TestActions(t *testing.T) {
+	exec := &fakeExec{}
+	e, n := newEngine(t, `{"chat_id": 42, "rules": [
+		{"name": "chain", "on": "connector.crashed", "actions": [
+			{"notify": "{event.connector} crashed"},
+			{"mute": "{event.connector}", "for": "1h", "digest": true},
+			{"task": "Check {event.connector}"},
+			{"run": "/restart {event.connector}", "quiet": true},
+			{"mute": "forever"}
+		]},
+		{"name": "broken", "on": "task.completed", "actions": [{"task": "{event.text}"}, {"notify": "unreached"}]}
+	]}`, exec)
+	muter, creator := &fakeMuter{}, &fakeTasks{}
+	now := time.Date(2026, 3, 1, 12, 0, 0, 0, time.UTC)
+	e.WithMuter(muter).WithTasks(creator).WithClock(func() time.Time { return now })
+
+	e.Handle(context.Background(), events.Event{Topic: events.ConnectorCrashed, Attrs: map[string]string{"connector": "mail"}})
+	if texts := n.Texts(); len(texts) != 1 || texts[0] != "mail crashed" {
+		t.Fatalf("sent = %q", texts)
+	}
+	if want := fmt.Sprintf("mail %s true", now.Add(time.Hour).Format("15:04")); len(muter.muted) != 2 || muter.muted[0] != want || muter.muted[1] != "forever 00:00 false" {
+		t.Fatalf("muted = %q, want %q first", muter.muted, want)
+	}
+	if len(creator.created) != 1 || creator.created[0] != "42 Check mail" {
+		t.Fatalf("created = %q", creator.created)
+	}
+	if len(exec.calls) != 1 || exec.calls[0].args != "mail" {
+		t.Fatalf("calls = %+v", exec.calls)
+	}
+
+	// A failing action stops the rest.
+	e.Handle(context.Background(), events.Event{Topic: events.TaskCompleted, Attrs: map[string]string{"text": ""}})
+	texts := n.Texts()
+	if last := texts[len(texts)-1]; !strings.HasPrefix(last, "Rule broken: create task: ") {
+		t.Fatalf("sent = %q", texts)
+	}
+}
+
+func TestKeyword(t *testing.T) {
+	exec := &fakeExec{}
+	e, _ := newEngine(t, `{"chat_id": 42, "rules": [
+		{"name": "deploy", "keyword": "Deploy", "when": {"chat_id": "7"}, "run": "/restart api", "quiet": true}
+	]}`, exec)
+	for _, tc := range []struct {
+		text, chat string
+		fires      bool
+	}{
+		{"please DEPLOY now", "7", true},
+		{"deploy", "7", true},
+		{"redeploy now", "7", false},
+		{"deployment done", "7", false},
+		{"deploy", "8", false},
+	} {
+		exec.calls = nil
+		e.Handle(context.Background(), events.Event{Topic: events.MessageReceived, Attrs: map[string]string{"text": tc.text, "chat_id": tc.chat}})
+		if fired := len(exec.calls) == 1; fired != tc.fires {
+			t.Errorf("%q in chat %s: fired = %v", tc.text, tc.chat, fired)
+		}
+	}
+}
+
+func TestScheduleAndReload(t *testing.T) {
+	exec := &fakeExec{}
+	path := writeConfig(t, `{"chat_id": 42, "rules": [
+		{"name": "nightly", "schedule": "0 3 * * *", "timezone": "UTC", "run": "/restart at {event.at}", "quiet": true}
+	]}`)
+	cfg, err := LoadConfig(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	logger := slog.New(slog.NewTextHandler(io.Discard, nil))
+	c := cron.New(nil, logger)
+	e := NewEngine(cfg, events.NewBus(logger), exec, testkit.NewNotifier(), logger).WithCron(c)
+
+	jobs := c.List()
+	if len(jobs) != 1 || jobs[0].Name != "rule-nightly" || jobs[0].Spec != "0 3 * * *" {
+		t.Fatalf("jobs = %+v", jobs)
+	}
+	at := time.Date(2026, 3, 1, 3, 0, 0, 0, time.UTC)
+	if err := e.fireScheduled(context.Background(), "nightly", at); err != nil {
+		t.Fatal(err)
+	}
+	if len(exec.calls) != 1 || exec.calls[0].args != "at 2026-03-01T03:00:00Z" {
+		t.Fatalf("calls = %+v", exec.calls)
+	}
+	// Scheduled rules match no event.
+	e.Handle(context.Background(), events.Event{Topic: scheduleTopic})
+	if len(exec.calls) != 1 {
+		t.Fatalf("event fired a scheduled rule")
+	}
+
+	// An invalid file keeps the rules.
+	os.WriteFile(path, []byte(`{"rules": [{"name": "x"}]}`), 0600)
+	e.Reload(path)
+	if len(e.Rules()) != 1 {
+		t.Fatalf("invalid reload left %d rules", len(e.Rules()))
+	}
+
+	os.WriteFile(path, []byte(`{"chat_id": 42, "rules": [
+		{"name": "nightly", "schedule": "30 4 * * *", "run": "/restart"},
+		{"name": "crash", "on": "connector.crashed", "run": "/restart"}
+	]}`), 0600)
+	e.Reload(path)
+	if jobs := c.List(); len(jobs) != 1 || jobs[0].Spec != "30 4 * * *" {
+		t.Fatalf("jobs after reload = %+v", jobs)
+	}
+
+	os.Remove(path)
+	e.Reload(path)
+	if len(e.Rules()) != 0 || len(c.List()) != 0 {
+		t.Fatalf("missing file left rules %+v, jobs %+v", e.Rules(), c.List())
+	}
+}
+
+func TestRulesOp(t *testing.T) {
+	exec := &fakeExec{}
+	e, n := newEngine(t, `{"chat_id": 42, "rules": [
+		{"name": "nas", "on": "monitor.down", "when": {"host": "nas", "type": "tcp"}, "run": "/restart {event.host}", "approval": true},
+		{"name": "office", "on": "monitor.down", "between": "09:00-17:00", "timezone": "UTC", "actions": [{"notify": "{event.host} is down"}, {"task": "Fix {event.host}"}]},
+		{"name": "nightly", "schedule": "0 3 * * *", "actions": [{"mute": "backups", "for": "2h"}]},
+		{"name": "capped", "on": "task.completed", "rate": {"max": 1, "window_min": 60}, "run": "/restart", "quiet": true}
+	]}`, exec)
+	now := time.Date(2026, 3, 1, 20, 0, 0, 0, time.UTC)
+	e.WithClock(func() time.Time { return now })
+	op := &Op{Engine: e}
+
+	tests := []struct {
+		args, want string
+	}{
+		{"", "nas: on monitor.down host=nas type=tcp, run /restart {event.host} via approval\n" +
+			"office: on monitor.down, notify \"{event.host} is down\", then create task \"Fix {event.host}\"\n" +
+			"nightly: on schedule \"0 3 * * *\", mute backups for 2h\n" +
+			"capped: on task.completed, run /restart"},
+		{"test monitor.down host=nas type=tcp", "nas: would run /restart nas via approval\noffice: matches, but not now (09:00-17:00 UTC)"},
+		{"test monitor.down host=router", "office: matches, but not now (09:00-17:00 UTC)"},
+		{"test task.completed", "capped: would run /restart"},
+		{"test message.received text=hello there", "No rule matches."},
+		{"test nightly", "nightly: would mute backups for 2h"},
+		{"test office", "office: matches, but not now (09:00-17:00 UTC)"},
+		{"test nope", "No topic or rule \"nope\". Topics: command.completed, connector.crashed, task.completed, monitor.down, message.received."},
+		{"test", rulesUsage},
+		{"test monitor.down nas", rulesUsage},
+		{"frob", rulesUsage},
+	}
+	for _, tt := range tests {
+		got, err := op.Execute(context.Background(), tt.args)
+		if err != nil || got != tt.want {
+			t.Errorf("/rules %s = %q, %v\nwant %q", tt.args, got, err, tt.want)
+		}
+	}
+
+	now = time.Date(2026, 3, 1, 10, 0, 0, 0, time.UTC)
+	got, _ := op.Execute(context.Background(), "test monitor.down")
+	if want := `office: would fail: monitor.down event has no attr "host"`; got != want {
+		t.Errorf("missing attr = %q, want %q", got, want)
+	}
+
+	// Testing does nothing and uses none of the rate.
+	e.Handle(context.Background(), events.Event{Topic: events.TaskCompleted})
+	if got, _ := op.Execute(context.Background(), "test capped"); got != "capped: matches, but is over its rate of 1 per 60m" {
+		t.Errorf("over rate = %q", got)
+	}
+	if len(exec.calls) != 1 || len(n.Texts()) != 0 {
+		t.Errorf("test ran %+v, sent %q", exec.calls, n.Texts())
+	}
+}
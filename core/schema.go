@@ -4,13 +4,49 @@ import (
 	"bytes"
 	"encoding/json"
 	"fmt"
+	"io"
+	"net/url"
+	"strings"
+	"time"
+	"unicode"
+
+	"github.com/jdelaire/openslack/core/limits"
 )
 
 const (
-	MaxPayloadBytes = 8192
-	MaxTextLen      = 4096
+	// Defaults for the limits a limits config can change.
+	MaxPayloadBytes = limits.DefaultPayloadBytes
+	MaxTextLen      = limits.DefaultMessageLen
+
 	MaxSourceLen    = 128
-	CurrentVersion  = 1
+	MaxTemplateLen  = 64
+	MaxTemplateVars = 32
+	MaxOpNameLen    = 64
+
+	// CurrentVersion is the newest envelope version. Version 2 adds the
+	// structured notify fields; version 1 requests are still accepted.
+	CurrentVersion = 2
+
+	// Limits on the structured notify fields.
+	MaxTitleLen      = 256
+	MaxTags          = 10
+	MaxTagLen        = 32
+	MaxFields        = 20
+	MaxFieldNameLen  = 64
+	MaxFieldValueLen = 1024
+	MaxURLLen        = 2048
+
+	// Drop requests carry base64 file data, so they get a larger limit.
+	MaxDropBytes        = 1 << 20
+	MaxDropRequestBytes = MaxDropBytes/3*4 + MaxPayloadBytes
+	MaxDropNameLen      = 128
+	MaxCaptionLen       = 1024
+
+	// MaxBatchLen caps the requests in one JSON-RPC batch.
+	MaxBatchLen = 20
+
+	// MaxSendAhead bounds how far ahead send_at may schedule a notify.
+	MaxSendAhead = 90 * 24 * time.Hour
 )
 
 // Request is the JSON envelope sent over the socket.
@@ -18,74 +54,432 @@ type Request struct {
 	Version int             `json:"version"`
 	Action  string          `json:"action"`
 	Payload json.RawMessage `json:"payload"`
+
+	// parsed holds the typed payload decoded during validation. When it
+	// is set, Payload may be nil: payloads that follow the action are
+	// decoded straight from the stream without a raw copy.
+	parsed any
 }
 
-// NotifyPayload is the payload for the "notify" action.
+// NotifyPayload is the payload for the "notify" action. With Ack set, the
+// message asks for confirmation and the response ID can be polled with
+// "ack-status". With SendAt (RFC 3339) in the future, the message is
+// queued instead of sent. Template names a configured message template
+// to render with Vars, in place of Text. Title, Tags, Priority, URL and
+// Fields need version 2; see Notification.
 type NotifyPayload struct {
-	Text   string `json:"text"`
-	Source string `json:"source,omitempty"`
+	Text     string            `json:"text,omitempty"`
+	Template string            `json:"template,omitempty"`
+	Vars     map[string]string `json:"vars,omitempty"`
+	Source   string            `json:"source,omitempty"`
+	Ack      bool              `json:"ack,omitempty"`
+	SendAt   *time.Time        `json:"send_at,omitempty"`
+
+	Title    string   `json:"title,omitempty"`
+	Tags     []string `json:"tags,omitempty"`
+	Priority Priority `json:"priority,omitempty"`
+	URL      string   `json:"url,omitempty"`
+	Fields   []Field  `json:"fields,omitempty"`
+}
+
+// Notification returns the structured part of the payload as a
+// notification carrying text.
+func (p NotifyPayload) Notification(text string) Notification {
+	return Notification{
+		Text:     text,
+		Source:   p.Source,
+		Title:    p.Title,
+		Tags:     p.Tags,
+		Priority: p.Priority,
+		URL:      p.URL,
+		Fields:   p.Fields,
+	}
+}
+
+// structured reports whether the payload uses any version 2 field.
+func (p *NotifyPayload) structured() bool {
+	return p.Title != "" || p.Tags != nil || p.Priority != "" || p.URL != "" || p.Fields != nil
+}
+
+// AckStatusPayload is the payload for the "ack-status" action.
+type AckStatusPayload struct {
+	ID string `json:"id"`
+}
+
+// DispatchPayload is the payload for the "dispatch" action: run the
+// registered op named Op with Args and return its output.
+type DispatchPayload struct {
+	Op   string `json:"op"`
+	Args string `json:"args,omitempty"`
+}
+
+// DropPayload is the payload for the "drop" action: a small file (Data,
+// base64 in JSON) or text blob forwarded to the chat as a document.
+// Exactly one of Text or Data must be set.
+type DropPayload struct {
+	Name    string `json:"name,omitempty"`
+	Text    string `json:"text,omitempty"`
+	Data    []byte `json:"data,omitempty"`
+	Caption string `json:"caption,omitempty"`
+	Source  string `json:"source,omitempty"`
+}
+
+// Content returns the dropped bytes.
+func (p DropPayload) Content() []byte {
+	if p.Data != nil {
+		return p.Data
+	}
+	return []byte(p.Text)
 }
 
 // Response is the JSON envelope sent back to the client.
 type Response struct {
-	OK    bool   `json:"ok"`
-	Error string `json:"error,omitempty"`
-	ID    string `json:"id,omitempty"`
+	OK      bool       `json:"ok"`
+	Error   string     `json:"error,omitempty"`
+	ID      string     `json:"id,omitempty"`
+	Status  string     `json:"status,omitempty"`   // "scheduled" for queued notifies; "pending" or "acked" for "ack-status"
+	AckedAt *time.Time `json:"acked_at,omitempty"` // "ack-status": when acked
+	Output  string     `json:"output,omitempty"`   // "dispatch": the op's reply
 }
 
 // ValidateRequest checks the request envelope and returns a typed payload for known actions.
 func ValidateRequest(data []byte) (*Request, error) {
-	if len(data) > MaxPayloadBytes {
-		return nil, fmt.Errorf("payload exceeds %d byte limit", MaxPayloadBytes)
+	return validateRequest(data, nil)
+}
+
+// validateRequest is ValidateRequest under the limits of lim.
+func validateRequest(data []byte, lim *limits.Config) (*Request, error) {
+	if len(data) > MaxDropRequestBytes {
+		return nil, fmt.Errorf("payload exceeds %d byte limit", MaxDropRequestBytes)
+	}
+	return decodeRequest(bytes.NewReader(data), lim)
+}
+
+// DecodeRequest reads one request envelope from r and validates it in a
+// single pass. Once the action is known, the byte limit tightens to the
+// action's limit, and a payload that follows the action is decoded
+// straight into its typed form. Reading stops at the end of the envelope.
+func DecodeRequest(r io.Reader) (*Request, error) {
+	return decodeRequest(r, nil)
+}
+
+// decodeRequest is DecodeRequest under the limits of lim.
+func decodeRequest(r io.Reader, lim *limits.Config) (*Request, error) {
+	lr := &limitReader{r: r, n: MaxDropRequestBytes + 1}
+	req, err := decodeEnvelope(lr, lim)
+	if lr.read > lr.limit() {
+		return nil, fmt.Errorf("payload exceeds %d byte limit", lr.limit())
 	}
+	return req, err
+}
 
-	dec := json.NewDecoder(bytes.NewReader(data))
+func decodeEnvelope(lr *limitReader, lim *limits.Config) (*Request, error) {
+	dec := json.NewDecoder(lr)
 	dec.DisallowUnknownFields()
 
 	var req Request
-	if err := dec.Decode(&req); err != nil {
+	if err := expectDelim(dec, '{'); err != nil {
+		return nil, fmt.Errorf("invalid JSON: %w", err)
+	}
+	for dec.More() {
+		tok, err := dec.Token()
+		if err != nil {
+			return nil, fmt.Errorf("invalid JSON: %w", err)
+		}
+		switch key := tok.(string); key {
+		case "version":
+			err = dec.Decode(&req.Version)
+		case "action":
+			err = dec.Decode(&req.Action)
+			if limit := payloadLimit(req.Action, lim); err == nil && limit < MaxDropRequestBytes {
+				lr.tighten(limit)
+			}
+		case "payload":
+			if p := newPayload(req.Action); p != nil {
+				if err := dec.Decode(p); err != nil {
+					return nil, fmt.Errorf("invalid %s payload: %w", req.Action, err)
+				}
+				req.parsed, req.Payload = p, nil
+				continue
+			}
+			req.parsed = nil
+			err = dec.Decode(&req.Payload)
+		default:
+			err = fmt.Errorf("json: unknown field %q", key)
+		}
+		if err != nil {
+			return nil, fmt.Errorf("invalid JSON: %w", err)
+		}
+	}
+	if err := expectDelim(dec, '}'); err != nil {
 		return nil, fmt.Errorf("invalid JSON: %w", err)
 	}
 
-	if req.Version != CurrentVersion {
-		return nil, fmt.Errorf("unsupported version %d, expected %d", req.Version, CurrentVersion)
+	if req.Version < 1 || req.Version > CurrentVersion {
+		return nil, fmt.Errorf("unsupported version %d, expected 1 to %d", req.Version, CurrentVersion)
+	}
+	p := newPayload(req.Action)
+	if p == nil {
+		return nil, fmt.Errorf("unknown action %q", req.Action)
+	}
+	if req.parsed == nil {
+		// The payload came before the action, or was missing.
+		if req.Payload == nil {
+			return nil, fmt.Errorf("missing payload")
+		}
+		if err := decodePayload(req.Payload, p); err != nil {
+			return nil, fmt.Errorf("invalid %s payload: %w", req.Action, err)
+		}
+		req.parsed = p
+	}
+	if err := req.parsed.(payload).validate(lim); err != nil {
+		return nil, err
+	}
+	if n, ok := req.parsed.(*NotifyPayload); ok && req.Version < 2 && n.structured() {
+		return nil, fmt.Errorf("title, tags, priority, url and fields need version 2")
 	}
+	return &req, nil
+}
+
+// payload is implemented by the typed payload of each action.
+type payload interface {
+	validate(lim *limits.Config) error
+}
 
-	switch req.Action {
+// newPayload returns an empty typed payload for action, or nil if the
+// action is unknown.
+func newPayload(action string) payload {
+	switch action {
 	case "notify":
-		if err := validateNotifyPayload(req.Payload); err != nil {
-			return nil, err
-		}
-	default:
-		return nil, fmt.Errorf("unknown action %q", req.Action)
+		return &NotifyPayload{}
+	case "drop":
+		return &DropPayload{}
+	case "ack-status":
+		return &AckStatusPayload{}
+	case "dispatch":
+		return &DispatchPayload{}
 	}
+	return nil
+}
 
-	return &req, nil
+// payloadLimit returns the request byte limit for action under lim. Only drops may
+// use the larger limit.
+func payloadLimit(action string, lim *limits.Config) int {
+	switch action {
+	case "notify", "dispatch":
+		return lim.PayloadBytes()
+	}
+	return MaxDropRequestBytes
 }
 
-func validateNotifyPayload(raw json.RawMessage) error {
-	if raw == nil {
-		return fmt.Errorf("missing payload")
+// parsedPayload returns the payload decoded during validation, or parses
+// the raw payload of a request built by hand.
+func parsedPayload[T any](req *Request) (T, error) {
+	if p, ok := req.parsed.(*T); ok {
+		return *p, nil
 	}
+	var p T
+	err := json.Unmarshal(req.Payload, &p)
+	return p, err
+}
 
+func decodePayload(raw json.RawMessage, p payload) error {
 	dec := json.NewDecoder(bytes.NewReader(raw))
 	dec.DisallowUnknownFields()
+	return dec.Decode(p)
+}
 
-	var p NotifyPayload
-	if err := dec.Decode(&p); err != nil {
-		return fmt.Errorf("invalid notify payload: %w", err)
+func expectDelim(dec *json.Decoder, want json.Delim) error {
+	tok, err := dec.Token()
+	if err != nil {
+		return err
 	}
+	if d, ok := tok.(json.Delim); !ok || d != want {
+		return fmt.Errorf("expected %q, got %v", want, tok)
+	}
+	return nil
+}
+
+// limitReader is an io.LimitReader whose limit can be lowered once the
+// action is known. It counts bytes read so an overrun can be reported as
+// a size error rather than a truncated document.
+type limitReader struct {
+	r    io.Reader
+	n    int64 // bytes left to read
+	read int64
+	max  int64 // limit set by tighten, 0 for the default
+}
 
-	if p.Text == "" {
-		return fmt.Errorf("text is required")
+func (l *limitReader) Read(p []byte) (int, error) {
+	if l.n <= 0 {
+		return 0, io.EOF
 	}
-	if len(p.Text) > MaxTextLen {
-		return fmt.Errorf("text exceeds %d character limit", MaxTextLen)
+	if int64(len(p)) > l.n {
+		p = p[:l.n]
+	}
+	n, err := l.r.Read(p)
+	l.n -= int64(n)
+	l.read += int64(n)
+	return n, err
+}
+
+// tighten lowers the limit to max bytes in total. One byte past max is
+// still read, so an oversized request is detected rather than cut short.
+func (l *limitReader) tighten(max int) {
+	l.max = int64(max)
+	l.n = min(l.n, l.max+1-l.read)
+}
+
+func (l *limitReader) limit() int64 {
+	if l.max > 0 {
+		return l.max
+	}
+	return MaxDropRequestBytes
+}
+
+func (p *NotifyPayload) validate(lim *limits.Config) error {
+	if p.Template != "" {
+		if p.Text != "" {
+			return fmt.Errorf("text cannot be combined with template")
+		}
+		if len(p.Template) > MaxTemplateLen {
+			return fmt.Errorf("template exceeds %d character limit", MaxTemplateLen)
+		}
+		if len(p.Vars) > MaxTemplateVars {
+			return fmt.Errorf("vars exceeds %d entries", MaxTemplateVars)
+		}
+	} else {
+		if p.Text == "" {
+			return fmt.Errorf("text is required")
+		}
+		if len(p.Vars) > 0 {
+			return fmt.Errorf("vars require a template")
+		}
+	}
+	if len(p.Text) > lim.TextLen() {
+		return fmt.Errorf("text exceeds %d character limit", lim.TextLen())
 	}
 	if len(p.Source) > MaxSourceLen {
 		return fmt.Errorf("source exceeds %d character limit", MaxSourceLen)
 	}
+	if err := p.validateStructure(lim); err != nil {
+		return err
+	}
+	if p.SendAt != nil {
+		if p.Ack {
+			return fmt.Errorf("ack cannot be combined with send_at")
+		}
+		if time.Until(*p.SendAt) > MaxSendAhead {
+			return fmt.Errorf("send_at is more than %d days ahead", int(MaxSendAhead.Hours()/24))
+		}
+		if time.Since(*p.SendAt) > time.Minute {
+			return fmt.Errorf("send_at is in the past")
+		}
+	}
+
+	return nil
+}
 
+// validateStructure checks the version 2 fields, and that the plain-text
+// rendering still fits a notify's text limit.
+func (p *NotifyPayload) validateStructure(lim *limits.Config) error {
+	if len(p.Title) > MaxTitleLen || strings.ContainsAny(p.Title, "\r\n") {
+		return fmt.Errorf("title must be one line of at most %d characters", MaxTitleLen)
+	}
+	if len(p.Tags) > MaxTags {
+		return fmt.Errorf("tags exceeds %d entries", MaxTags)
+	}
+	for _, tag := range p.Tags {
+		if !validTag(tag) {
+			return fmt.Errorf("tag %q must be 1 to %d letters, digits or underscores", tag, MaxTagLen)
+		}
+	}
+	switch p.Priority {
+	case "", PriorityLow, PriorityNormal, PriorityHigh, PriorityUrgent:
+	default:
+		return fmt.Errorf("priority must be low, normal, high or urgent")
+	}
+	if p.URL != "" {
+		u, err := url.Parse(p.URL)
+		if err != nil || (u.Scheme != "https" && u.Scheme != "http") || u.Host == "" || len(p.URL) > MaxURLLen {
+			return fmt.Errorf("url must be an http or https URL of at most %d characters", MaxURLLen)
+		}
+	}
+	if len(p.Fields) > MaxFields {
+		return fmt.Errorf("fields exceeds %d entries", MaxFields)
+	}
+	for _, f := range p.Fields {
+		if f.Name == "" || len(f.Name) > MaxFieldNameLen || strings.ContainsAny(f.Name, "\r\n") {
+			return fmt.Errorf("field names must be one line of 1 to %d characters", MaxFieldNameLen)
+		}
+		if len(f.Value) > MaxFieldValueLen {
+			return fmt.Errorf("field %q exceeds %d character limit", f.Name, MaxFieldValueLen)
+		}
+	}
+	if n := p.Notification(p.Text); n.Structured() && len(n.PlainText()) > lim.TextLen() {
+		return fmt.Errorf("notification exceeds %d character limit", lim.TextLen())
+	}
+	return nil
+}
+
+func validTag(tag string) bool {
+	if tag == "" || len(tag) > MaxTagLen {
+		return false
+	}
+	for _, r := range tag {
+		if r != '_' && !unicode.IsLetter(r) && !unicode.IsDigit(r) {
+			return false
+		}
+	}
+	return true
+}
+
+func (p *DropPayload) validate(lim *limits.Config) error {
+	if (p.Text == "") == (len(p.Data) == 0) {
+		return fmt.Errorf("exactly one of text or data is required")
+	}
+	if len(p.Content()) > MaxDropBytes {
+		return fmt.Errorf("drop exceeds %d byte limit", MaxDropBytes)
+	}
+	if len(p.Name) > MaxDropNameLen {
+		return fmt.Errorf("name exceeds %d character limit", MaxDropNameLen)
+	}
+	if strings.ContainsAny(p.Name, "/\\\x00") || p.Name == "." || p.Name == ".." {
+		return fmt.Errorf("name must be a plain file name")
+	}
+	if len(p.Caption) > MaxCaptionLen {
+		return fmt.Errorf("caption exceeds %d character limit", MaxCaptionLen)
+	}
+	if len(p.Source) > MaxSourceLen {
+		return fmt.Errorf("source exceeds %d character limit", MaxSourceLen)
+	}
+
+	return nil
+}
+
+func (p *AckStatusPayload) validate(lim *limits.Config) error {
+	if p.ID == "" {
+		return fmt.Errorf("id is required")
+	}
+	if len(p.ID) > 64 {
+		return fmt.Errorf("id exceeds 64 character limit")
+	}
+	return nil
+}
+
+func (p *DispatchPayload) validate(lim *limits.Config) error {
+	if p.Op == "" {
+		return fmt.Errorf("op is required")
+	}
+	if len(p.Op) > MaxOpNameLen {
+		return fmt.Errorf("op exceeds %d character limit", MaxOpNameLen)
+	}
+	if strings.HasPrefix(p.Op, "/") || strings.ContainsAny(p.Op, " \t\n") {
+		return fmt.Errorf("op must be a bare op name, without / or arguments")
+	}
+	if len(p.Args) > MaxTextLen {
+		return fmt.Errorf("args exceeds %d character limit", MaxTextLen)
+	}
 	return nil
 }
 
@@ -1,8 +1,12 @@
 package core
 
 import (
+	"bytes"
+	"encoding/base64"
+	"io"
 	"strings"
 	"testing"
+	"time"
 )
 
 func TestValidateRequest_ValidNotify(t *testing.T) {
@@ -71,6 +75,27 @@ func TestValidateRequest_EmptyText(t *testing.T) {
 	}
 }
 
+func TestValidateRequest_Template(t *testing.T) {
+	tests := []struct {
+		payload string
+		wantErr string
+	}{
+		{`{"template":"backup_done","vars":{"host":"nas"}}`, ""},
+		{`{"template":"backup_done","text":"hi"}`, "text cannot be combined with template"},
+		{`{"text":"hi","vars":{"host":"nas"}}`, "vars require a template"},
+		{`{"template":"` + strings.Repeat("t", 65) + `"}`, "template exceeds"},
+	}
+	for _, tt := range tests {
+		_, err := ValidateRequest([]byte(`{"version":1,"action":"notify","payload":` + tt.payload + `}`))
+		if tt.wantErr == "" && err != nil {
+			t.Errorf("%s: unexpected error: %v", tt.payload, err)
+		}
+		if tt.wantErr != "" && (err == nil || !strings.Contains(err.Error(), tt.wantErr)) {
+			t.Errorf("%s: error = %v, want %q", tt.payload, err, tt.wantErr)
+		}
+	}
+}
+
 func TestValidateRequest_TextTooLong(t *testing.T) {
 	long := `{"version":1,"action":"notify","payload":{"text":"` + strings.Repeat("a", MaxTextLen+1) + `"}}`
 	_, err := ValidateRequest([]byte(long))
@@ -129,3 +154,239 @@ func TestParseNotifyPayload(t *testing.T) {
 		t.Errorf("expected source cli, got %s", p.Source)
 	}
 }
+
+func TestValidateRequest_Drop(t *testing.T) {
+	tests := []struct {
+		payload string
+		want    string
+	}{
+		{`{"text":"hello"}`, ""},
+		{`{"name":"report.pdf","data":"aGVsbG8=","caption":"weekly","source":"cron"}`, ""},
+		{`{}`, "exactly one of text or data"},
+		{`{"text":"a","data":"aGVsbG8="}`, "exactly one of text or data"},
+		{`{"name":"../etc/passwd","text":"a"}`, "plain file name"},
+		{`{"name":"..","text":"a"}`, "plain file name"},
+		{`{"name":"` + strings.Repeat("n", MaxDropNameLen+1) + `","text":"a"}`, "name exceeds"},
+		{`{"text":"a","caption":"` + strings.Repeat("c", MaxCaptionLen+1) + `"}`, "caption exceeds"},
+		{`{"data":"not base64!"}`, "invalid drop payload"},
+		{`{"text":"a","extra":1}`, "invalid drop payload"},
+	}
+	for _, tt := range tests {
+		_, err := ValidateRequest([]byte(`{"version":1,"action":"drop","payload":` + tt.payload + `}`))
+		switch {
+		case tt.want == "" && err != nil:
+			t.Errorf("payload %s: unexpected error %v", tt.payload, err)
+		case tt.want != "" && (err == nil || !strings.Contains(err.Error(), tt.want)):
+			t.Errorf("payload %s: error = %v, want %q", tt.payload, err, tt.want)
+		}
+	}
+}
+
+func TestValidateRequest_DropTooLarge(t *testing.T) {
+	text := strings.Repeat("x", MaxDropBytes+1)
+	_, err := ValidateRequest([]byte(`{"version":1,"action":"drop","payload":{"text":"` + text + `"}}`))
+	if err == nil || !strings.Contains(err.Error(), "drop exceeds") {
+		t.Errorf("unexpected error: %v", err)
+	}
+
+	_, err = ValidateRequest([]byte(`{"version":1,"action":"drop","payload":{"text":"` + strings.Repeat("x", MaxDropRequestBytes) + `"}}`))
+	if err == nil || !strings.Contains(err.Error(), "byte limit") {
+		t.Errorf("unexpected error: %v", err)
+	}
+}
+
+func TestValidateRequest_AckStatus(t *testing.T) {
+	if _, err := ValidateRequest([]byte(`{"version":1,"action":"ack-status","payload":{"id":"3f2a9c"}}`)); err != nil {
+		t.Errorf("unexpected error: %v", err)
+	}
+	for _, payload := range []string{`{}`, `{"id":""}`, `{"id":"x","extra":1}`} {
+		if _, err := ValidateRequest([]byte(`{"version":1,"action":"ack-status","payload":` + payload + `}`)); err == nil {
+			t.Errorf("payload %s: expected error", payload)
+		}
+	}
+	if _, err := ValidateRequest([]byte(`{"version":1,"action":"notify","payload":{"text":"hi","ack":true}}`)); err != nil {
+		t.Errorf("notify with ack: %v", err)
+	}
+}
+
+func TestValidateRequest_Dispatch(t *testing.T) {
+	if _, err := ValidateRequest([]byte(`{"version":1,"action":"dispatch","payload":{"op":"status","args":"disk"}}`)); err != nil {
+		t.Errorf("unexpected error: %v", err)
+	}
+	for _, payload := range []string{`{}`, `{"op":"/status"}`, `{"op":"status disk"}`, `{"op":"status","chat_id":1}`} {
+		if _, err := ValidateRequest([]byte(`{"version":1,"action":"dispatch","payload":` + payload + `}`)); err == nil {
+			t.Errorf("payload %s: expected error", payload)
+		}
+	}
+}
+
+func TestValidateRequest_SendAt(t *testing.T) {
+	at := func(d time.Duration) string { return time.Now().Add(d).UTC().Format(time.RFC3339) }
+	tests := []struct {
+		payload string
+		want    string
+	}{
+		{`{"text":"hi","send_at":"` + at(time.Hour) + `"}`, ""},
+		{`{"text":"hi","send_at":"` + at(-10*time.Minute) + `"}`, "in the past"},
+		{`{"text":"hi","send_at":"` + at(MaxSendAhead+time.Hour) + `"}`, "days ahead"},
+		{`{"text":"hi","send_at":"tomorrow"}`, "invalid notify payload"},
+		{`{"text":"hi","ack":true,"send_at":"` + at(time.Hour) + `"}`, "cannot be combined"},
+	}
+	for _, tt := range tests {
+		_, err := ValidateRequest([]byte(`{"version":1,"action":"notify","payload":` + tt.payload + `}`))
+		switch {
+		case tt.want == "" && err != nil:
+			t.Errorf("payload %s: unexpected error %v", tt.payload, err)
+		case tt.want != "" && (err == nil || !strings.Contains(err.Error(), tt.want)):
+			t.Errorf("payload %s: error = %v, want %q", tt.payload, err, tt.want)
+		}
+	}
+}
+
+func BenchmarkValidateRequest(b *testing.B) {
+	data := []byte(`{"version":1,"action":"notify","payload":{"text":"Backup finished in 42s","source":"backup"}}`)
+	b.ReportAllocs()
+	for b.Loop() {
+		if _, err := ValidateRequest(data); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+func TestValidateRequest_Structured(t *testing.T) {
+	tests := []struct {
+		req  string
+		want string
+	}{
+		{`{"version":2,"action":"notify","payload":{"text":"hi","title":"Deploy","tags":["prod","api_v2"],"priority":"high","url":"https://ci.example/1","fields":[{"name":"sha","value":"abc123"}]}}`, ""},
+		{`{"version":2,"action":"notify","payload":{"text":"hi"}}`, ""},
+		{`{"version":1,"action":"notify","payload":{"text":"hi","title":"Deploy"}}`, "need version 2"},
+		{`{"version":2,"action":"notify","payload":{"text":"hi","title":"two\nlines"}}`, "title must be one line"},
+		{`{"version":2,"action":"notify","payload":{"text":"hi","tags":["has space"]}}`, "letters, digits or underscores"},
+		{`{"version":2,"action":"notify","payload":{"text":"hi","priority":"critical"}}`, "priority must be"},
+		{`{"version":2,"action":"notify","payload":{"text":"hi","url":"javascript:alert(1)"}}`, "http or https URL"},
+		{`{"version":2,"action":"notify","payload":{"text":"hi","fields":[{"name":"","value":"x"}]}}`, "field names"},
+		{`{"version":2,"action":"notify","payload":{"text":"` + strings.Repeat("x", MaxTextLen-10) + `","title":"too long once rendered"}}`, "notification exceeds"},
+		{`{"version":3,"action":"notify","payload":{"text":"hi"}}`, "unsupported version"},
+	}
+	for _, tt := range tests {
+		_, err := ValidateRequest([]byte(tt.req))
+		switch {
+		case tt.want == "" && err != nil:
+			t.Errorf("%.80s: unexpected error %v", tt.req, err)
+		case tt.want != "" && (err == nil || !strings.Contains(err.Error(), tt.want)):
+			t.Errorf("%.80s: error = %v, want %q", tt.req, err, tt.want)
+		}
+	}
+}
+
+func TestDecodeRequest_PayloadBeforeAction(t *testing.T) {
+	req, err := DecodeRequest(strings.NewReader(`{"payload":{"op":"status"},"version":1,"action":"dispatch"}`))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	p, err := parsedPayload[DispatchPayload](req)
+	if err != nil || p.Op != "status" {
+		t.Errorf("payload = %+v, %v", p, err)
+	}
+
+	_, err = DecodeRequest(strings.NewReader(`{"payload":{"op":"status","x":1},"version":1,"action":"dispatch"}`))
+	if err == nil || !strings.Contains(err.Error(), "invalid dispatch payload") {
+		t.Errorf("unexpected error: %v", err)
+	}
+}
+
+func TestDecodeRequest_StopsAtLimit(t *testing.T) {
+	text := strings.Repeat("x", MaxDropRequestBytes)
+	r := &countingReader{r: strings.NewReader(`{"version":1,"action":"notify","payload":{"text":"` + text + `"}}`)}
+	_, err := DecodeRequest(r)
+	if err == nil || !strings.Contains(err.Error(), "8192 byte limit") {
+		t.Errorf("unexpected error: %v", err)
+	}
+	if r.n > MaxPayloadBytes+1 {
+		t.Errorf("read %d bytes, want at most %d", r.n, MaxPayloadBytes+1)
+	}
+}
+
+func TestDecodeRequest_StopsAtEnvelope(t *testing.T) {
+	// A reader that fails past the envelope proves nothing after it is read.
+	r := io.MultiReader(strings.NewReader(`{"version":1,"action":"notify","payload":{"text":"hi"}}`), errReader{})
+	if _, err := DecodeRequest(r); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+}
+
+type countingReader struct {
+	r io.Reader
+	n int
+}
+
+func (c *countingReader) Read(p []byte) (int, error) {
+	n, err := c.r.Read(p)
+	c.n += n
+	return n, err
+}
+
+type errReader struct{}
+
+func (errReader) Read([]byte) (int, error) { return 0, io.ErrUnexpectedEOF }
+
+// nearLimitRequests returns notify and drop requests just under their
+// byte limits.
+func nearLimitRequests() (notify, drop []byte) {
+	notify = []byte(`{"version":1,"action":"notify","payload":{"text":"` + strings.Repeat("n", MaxTextLen) + `","source":"bench"}}`)
+	data := base64.StdEncoding.EncodeToString(bytes.Repeat([]byte{0xa5}, MaxDropBytes))
+	drop = []byte(`{"version":1,"action":"drop","payload":{"name":"dump.bin","data":"` + data + `","source":"bench"}}`)
+	return notify, drop
+}
+
+func BenchmarkValidateRequestNearLimit(b *testing.B) {
+	notify, drop := nearLimitRequests()
+	for _, bc := range []struct {
+		name string
+		data []byte
+	}{{"notify", notify}, {"drop", drop}} {
+		b.Run(bc.name, func(b *testing.B) {
+			b.ReportAllocs()
+			b.SetBytes(int64(len(bc.data)))
+			for b.Loop() {
+				if _, err := ValidateRequest(bc.data); err != nil {
+					b.Fatal(err)
+				}
+			}
+		})
+	}
+}
+
+// BenchmarkDecodeRequestNearLimit compares the socket read path: reading
+// the whole connection before validating, against decoding as it streams.
+func BenchmarkDecodeRequestNearLimit(b *testing.B) {
+	notify, drop := nearLimitRequests()
+	for _, bc := range []struct {
+		name string
+		data []byte
+	}{{"notify", notify}, {"drop", drop}} {
+		b.Run(bc.name+"/readall", func(b *testing.B) {
+			b.ReportAllocs()
+			b.SetBytes(int64(len(bc.data)))
+			for b.Loop() {
+				data, err := io.ReadAll(io.LimitReader(struct{ io.Reader }{bytes.NewReader(bc.data)}, MaxDropRequestBytes+1))
+				if err != nil {
+					b.Fatal(err)
+				}
+				if _, err := ValidateRequest(data); err != nil {
+					b.Fatal(err)
+				}
+			}
+		})
+		b.Run(bc.name+"/stream", func(b *testing.B) {
+			b.ReportAllocs()
+			b.SetBytes(int64(len(bc.data)))
+			for b.Loop() {
+				if _, err := DecodeRequest(struct{ io.Reader }{bytes.NewReader(bc.data)}); err != nil {
+					b.Fatal(err)
+				}
+			}
+		})
+	}
+}
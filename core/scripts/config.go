@@ -0,0 +1,134 @@
+// Package scripts runs ops written as small Starlark scripts in config.
+// A script is glue: it parses its arguments or another op's output,
+// branches, calls other ops and connector tools, and formats the reply,
+// without a new binary or a shell pipeline. Starlark is a Python dialect
+// with no access to files, the network or the clock, so a script can only
+// act through the ops it calls.
+package scripts
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/jdelaire/openslack/core/ops"
+	"go.starlark.net/starlark"
+	"go.starlark.net/syntax"
+)
+
+// DefaultMaxSteps bounds the work one run of a script may do. A simple
+// loop iteration costs a handful of steps.
+const DefaultMaxSteps = 1_000_000
+
+// fileOptions are the Starlark dialect scripts are written in. Recursion
+// stays off; while loops are allowed since runs are bounded by steps.
+var fileOptions = &syntax.FileOptions{Set: true, While: true, TopLevelControl: true, GlobalReassign: true}
+
+// Script is one scripted op. Its source, given inline in Source or read
+// from File, must define run(args), which returns the reply. Risk is
+// "none", "low" (the default) or "high", and also caps the risk of the
+// ops the script may call. MaxSteps overrides DefaultMaxSteps.
+type Script struct {
+	Name        string `json:"name"`
+	Description string `json:"description"`
+	Source      string `json:"script"`
+	File        string `json:"file"`
+	Risk        string `json:"risk"`
+	MaxSteps    uint64 `json:"max_steps"`
+	ops.ChatAccess
+
+	prog *starlark.Program
+	risk ops.RiskLevel
+}
+
+// Config is the scripts config file.
+type Config struct {
+	Scripts []Script `json:"scripts"`
+}
+
+// LoadConfig reads a scripts config file and compiles every script. A
+// relative File is read from the config file's directory.
+// Returns nil, nil if the file does not exist.
+func LoadConfig(path string) (*Config, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("read scripts config: %w", err)
+	}
+
+	var cfg Config
+	if err := json.Unmarshal(data, &cfg); err != nil {
+		return nil, fmt.Errorf("parse scripts config: %w", err)
+	}
+	seen := make(map[string]bool)
+	for i := range cfg.Scripts {
+		s := &cfg.Scripts[i]
+		s.Name = strings.ToLower(strings.TrimPrefix(strings.TrimSpace(s.Name), "/"))
+		if s.Name == "" || strings.ContainsAny(s.Name, " \t\n") {
+			return nil, fmt.Errorf("scripts config: script at index %d needs a one-word name", i)
+		}
+		if seen[s.Name] {
+			return nil, fmt.Errorf("scripts config: duplicate script %q", s.Name)
+		}
+		seen[s.Name] = true
+		if err := s.compile(filepath.Dir(path)); err != nil {
+			return nil, fmt.Errorf("scripts config: /%s: %w", s.Name, err)
+		}
+	}
+	return &cfg, nil
+}
+
+// compile loads and compiles the script's source and parses its risk.
+func (s *Script) compile(dir string) error {
+	s.risk = ops.RiskLow
+	if s.Risk != "" {
+		risk, err := ops.ParseRiskLevel(s.Risk)
+		if err != nil {
+			return err
+		}
+		s.risk = risk
+	}
+
+	filename := s.Name + ".star"
+	src := s.Source
+	switch {
+	case src != "" && s.File != "":
+		return fmt.Errorf("set only one of script and file")
+	case s.File != "":
+		filename = s.File
+		if !filepath.IsAbs(filename) {
+			filename = filepath.Join(dir, filename)
+		}
+		data, err := os.ReadFile(filename)
+		if err != nil {
+			return fmt.Errorf("read script: %w", err)
+		}
+		src = string(data)
+	case src == "":
+		return fmt.Errorf("missing script or file")
+	}
+
+	f, prog, err := starlark.SourceProgramOptions(fileOptions, filename, src, isPredeclared)
+	if err != nil {
+		return err
+	}
+	if !definesRun(f) {
+		return fmt.Errorf("script does not define run(args)")
+	}
+	s.prog = prog
+	return nil
+}
+
+// definesRun reports whether f has a top-level def run with one parameter.
+func definesRun(f *syntax.File) bool {
+	for _, stmt := range f.Stmts {
+		if def, ok := stmt.(*syntax.DefStmt); ok && def.Name.Name == "run" && len(def.Params) == 1 {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,174 @@
+package scripts
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"strings"
+
+	"github.com/jdelaire/openslack/core/ops"
+	"go.starlark.net/lib/json"
+	"go.starlark.net/starlark"
+)
+
+// maxDepth bounds scripts calling scripts.
+const maxDepth = 4
+
+type depthKey struct{}
+
+// Op runs a Script. Ops it calls are looked up in Registry and run
+// directly, inside this op's run: they share its timeout and are not
+// audited separately. Calls that would need /yes are refused.
+type Op struct {
+	Script   *Script
+	Registry *ops.Registry
+	Logger   *slog.Logger
+}
+
+// RegisterOps creates and registers an Op for every script in cfg.
+func RegisterOps(cfg *Config, registry *ops.Registry, logger *slog.Logger) error {
+	for i := range cfg.Scripts {
+		s := &cfg.Scripts[i]
+		if err := registry.Register(&Op{Script: s, Registry: registry, Logger: logger}); err != nil {
+			return fmt.Errorf("register script %q: %w", s.Name, err)
+		}
+	}
+	return nil
+}
+
+func (o *Op) Name() string { return o.Script.Name }
+
+func (o *Op) Description() string {
+	if o.Script.Description != "" {
+		return o.Script.Description
+	}
+	return "Script"
+}
+
+func (o *Op) Risk() ops.RiskLevel { return o.Script.risk }
+
+// AllowsChat implements ops.ChatRestricter.
+func (o *Op) AllowsChat(chatID int64) bool { return o.Script.AllowsChat(chatID) }
+
+// predeclared lists the names every script may use besides the Starlark
+// built-ins.
+var predeclared = []string{"call", "vars", "json"}
+
+func isPredeclared(name string) bool {
+	for _, p := range predeclared {
+		if p == name {
+			return true
+		}
+	}
+	return false
+}
+
+// Execute runs the script's run function with args and returns its reply:
+// the string it returns, or "Done." if it returns None.
+func (o *Op) Execute(ctx context.Context, args string) (string, error) {
+	depth, _ := ctx.Value(depthKey{}).(int)
+	if depth >= maxDepth {
+		return "", fmt.Errorf("%s: scripts nested more than %d deep", o.Name(), maxDepth)
+	}
+	ctx = context.WithValue(ctx, depthKey{}, depth+1)
+
+	thread := &starlark.Thread{
+		Name: o.Name(),
+		Print: func(_ *starlark.Thread, msg string) {
+			o.logger().Info("script print", "op", o.Name(), "msg", msg)
+		},
+	}
+	steps := o.Script.MaxSteps
+	if steps == 0 {
+		steps = DefaultMaxSteps
+	}
+	thread.SetMaxExecutionSteps(steps)
+	stop := context.AfterFunc(ctx, func() { thread.Cancel("timed out") })
+	defer stop()
+
+	globals, err := o.Script.prog.Init(thread, o.builtins(ctx))
+	if err != nil {
+		return "", o.error(err)
+	}
+	v, err := starlark.Call(thread, globals["run"], starlark.Tuple{starlark.String(args)}, nil)
+	if err != nil {
+		return "", o.error(err)
+	}
+	switch v := v.(type) {
+	case starlark.NoneType:
+		return "Done.", nil
+	case starlark.String:
+		return string(v), nil
+	default:
+		return v.String(), nil
+	}
+}
+
+// error reports a script failure with the script's own message, and the
+// Starlark call stack in the log.
+func (o *Op) error(err error) error {
+	var evalErr *starlark.EvalError
+	if errors.As(err, &evalErr) {
+		o.logger().Warn("script failed", "op", o.Name(), "error", evalErr.Backtrace())
+		err = errors.New(strings.TrimPrefix(evalErr.Msg, "fail: "))
+	}
+	return fmt.Errorf("%s: %w", o.Name(), err)
+}
+
+func (o *Op) logger() *slog.Logger {
+	if o.Logger == nil {
+		return slog.Default()
+	}
+	return o.Logger
+}
+
+// builtins returns the predeclared names for one run in ctx.
+func (o *Op) builtins(ctx context.Context) starlark.StringDict {
+	vars := starlark.NewDict(0)
+	if vs, ok := ops.VarsFrom(ctx); ok {
+		for k, v := range vs {
+			vars.SetKey(starlark.String(k), starlark.String(v))
+		}
+	}
+	vars.Freeze()
+
+	return starlark.StringDict{
+		"call": starlark.NewBuiltin("call", func(thread *starlark.Thread, fn *starlark.Builtin, args starlark.Tuple, kwargs []starlark.Tuple) (starlark.Value, error) {
+			var name, opArgs string
+			if err := starlark.UnpackArgs(fn.Name(), args, kwargs, "name", &name, "args?", &opArgs); err != nil {
+				return nil, err
+			}
+			out, err := o.call(ctx, name, opArgs)
+			if err != nil {
+				return nil, err
+			}
+			return starlark.String(out), nil
+		}),
+		"vars": vars,
+		"json": json.Module,
+	}
+}
+
+// call runs the op called name for the script. The op must be one the
+// chat could run, and no riskier than the script itself, risk overrides
+// included, so a script cannot lend its TOTP or approval exemption to a
+// riskier op. Calls the op would ask to confirm with /yes are refused:
+// nobody is there to confirm them.
+func (o *Op) call(ctx context.Context, name, args string) (string, error) {
+	name = strings.ToLower(strings.TrimPrefix(name, "/"))
+	op := o.Registry.Get(name)
+	if op == nil || !ops.BotAllowed(ctx, name) {
+		return "", fmt.Errorf("unknown op /%s", name)
+	}
+	if chatID, ok := ops.ChatIDFrom(ctx); ok && !ops.ChatAllowed(op, chatID) {
+		return "", fmt.Errorf("unknown op /%s", name)
+	}
+	if o.Registry.RiskOfCall(op, args) > o.Registry.RiskOf(o) {
+		return "", fmt.Errorf("/%s is riskier than /%s and cannot be called from it", name, o.Name())
+	}
+	if c, ok := op.(ops.Confirmer); ok && c.NeedsConfirmation(ctx, args) {
+		return "", fmt.Errorf("/%s %s needs confirmation and cannot be called from /%s", name, args, o.Name())
+	}
+	return op.Execute(ctx, args)
+}
@@ -0,0 +1,198 @@
+package scripts
+
+import (
+	"context"
+	"io"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/jdelaire/openslack/core/ops"
+)
+
+type fakeOp struct {
+	name string
+	risk ops.RiskLevel
+	out  string
+	ops.ChatAccess
+}
+
+func (o *fakeOp) Name() string        { return o.name }
+func (o *fakeOp) Description() string { return "fake" }
+func (o *fakeOp) Risk() ops.RiskLevel { return o.risk }
+func (o *fakeOp) Execute(_ context.Context, args string) (string, error) {
+	return strings.ReplaceAll(o.out, "{}", args), nil
+}
+
+// confirmOp asks for confirmation unless args are empty.
+type confirmOp struct{ fakeOp }
+
+func (o *confirmOp) NeedsConfirmation(_ context.Context, args string) bool { return args != "" }
+
+// load writes a scripts config and registers its ops alongside a few
+// fake ones.
+func load(t *testing.T, config string) *ops.Registry {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "scripts.json")
+	os.WriteFile(path, []byte(config), 0600)
+	cfg, err := LoadConfig(path)
+	if err != nil {
+		t.Fatalf("LoadConfig: %v", err)
+	}
+	reg := ops.NewRegistry()
+	reg.Register(&fakeOp{name: "df", risk: ops.RiskNone, out: "/ 91%\n/data 40%"})
+	reg.Register(&fakeOp{name: "restart", risk: ops.RiskLow, out: "restarted {}"})
+	reg.Register(&fakeOp{name: "wipe", risk: ops.RiskHigh, out: "wiped"})
+	reg.Register(&fakeOp{name: "secret", risk: ops.RiskNone, out: "s", ChatAccess: ops.ChatAccess{AllowedChats: []int64{7}}})
+	reg.Register(&fakeOp{name: "status", risk: ops.RiskNone, out: `{"ok": true, "jobs": 3}`})
+	reg.Register(&confirmOp{fakeOp{name: "deploy", risk: ops.RiskNone, out: "deployed {}"}})
+	if err := RegisterOps(cfg, reg, slog.New(slog.NewTextHandler(io.Discard, nil))); err != nil {
+		t.Fatalf("RegisterOps: %v", err)
+	}
+	return reg
+}
+
+func run(t *testing.T, reg *ops.Registry, name, args string) (string, error) {
+	t.Helper()
+	ctx := ops.WithChatID(context.Background(), 42)
+	return reg.Get(name).Execute(ctx, args)
+}
+
+func TestScript(t *testing.T) {
+	reg := load(t, `{"scripts": [
+		{"name": "full", "risk": "none", "description": "Disks over a limit",
+		 "script": "def run(args):\n  limit = int(args or '90')\n  full = [l.split(' ')[0] for l in call('df').splitlines() if int(l.split(' ')[1].rstrip('%')) > limit]\n  return ', '.join(full) or 'All disks below %d%%.' % limit"},
+		{"name": "jobs", "risk": "none",
+		 "script": "def run(args):\n  s = json.decode(call('/status'))\n  return 'jobs: %d, host: %s' % (s['jobs'], vars.get('host', '?'))"},
+		{"name": "quiet", "risk": "none", "script": "def run(args):\n  pass"}
+	]}`)
+
+	for _, tc := range []struct{ name, args, want string }{
+		{"full", "", "/"},
+		{"full", "30", "/, /data"},
+		{"full", "95", "All disks below 95%."},
+		{"jobs", "", "jobs: 3, host: ?"},
+		{"quiet", "", "Done."},
+	} {
+		out, err := run(t, reg, tc.name, tc.args)
+		if err != nil || out != tc.want {
+			t.Errorf("/%s %s = %q, %v; want %q", tc.name, tc.args, out, err, tc.want)
+		}
+	}
+
+	if got := reg.Get("full").Description(); got != "Disks over a limit" {
+		t.Errorf("description = %q", got)
+	}
+	ctx := ops.WithVars(context.Background(), map[string]string{"host": "nas"})
+	if out, _ := reg.Get("jobs").Execute(ctx, ""); out != "jobs: 3, host: nas" {
+		t.Errorf("with vars = %q", out)
+	}
+}
+
+func TestScriptCallLimits(t *testing.T) {
+	reg := load(t, `{"scripts": [
+		{"name": "relay", "risk": "none", "script": "def run(args):\n  name, _, rest = args.partition(' ')\n  return call(name, rest)"},
+		{"name": "relay-low", "script": "def run(args):\n  return call('restart', args)"},
+		{"name": "loop", "risk": "none", "script": "def run(args):\n  return call('loop')"}
+	]}`)
+
+	if out, err := run(t, reg, "relay-low", "nginx"); err != nil || out != "restarted nginx" {
+		t.Fatalf("relay-low = %q, %v", out, err)
+	}
+	for args, want := range map[string]string{
+		"restart nginx": "relay: /restart is riskier than /relay and cannot be called from it",
+		"wipe":          "relay: /wipe is riskier than /relay",
+		"secret":        "relay: unknown op /secret",
+		"nope":          "relay: unknown op /nope",
+		"deploy main":   "relay: /deploy main needs confirmation and cannot be called from /relay",
+	} {
+		if _, err := run(t, reg, "relay", args); err == nil || !strings.HasPrefix(err.Error(), want) {
+			t.Errorf("relay %s: err = %v, want %q", args, err, want)
+		}
+	}
+
+	if out, err := run(t, reg, "relay", "deploy"); err != nil || out != "deployed " {
+		t.Errorf("relay deploy without args = %q, %v", out, err)
+	}
+
+	// A risk override on the script raises what it may call.
+	reg.SetRiskOverrides(map[string]ops.RiskLevel{"relay": ops.RiskLow})
+	if out, err := run(t, reg, "relay", "restart db"); err != nil || out != "restarted db" {
+		t.Errorf("relay restart with override = %q, %v", out, err)
+	}
+
+	if _, err := run(t, reg, "loop", ""); err == nil || !strings.Contains(err.Error(), "nested more than 4 deep") {
+		t.Errorf("loop: err = %v", err)
+	}
+}
+
+func TestScriptFailures(t *testing.T) {
+	reg := load(t, `{"scripts": [
+		{"name": "strict", "risk": "none", "script": "def run(args):\n  if not args:\n    fail('usage: /strict <n>')\n  return str(int(args) * 2)"},
+		{"name": "spin", "risk": "none", "max_steps": 1000, "script": "def run(args):\n  n = 0\n  while True:\n    n += 1"},
+		{"name": "slow", "risk": "none", "max_steps": 1000000000000, "script": "def run(args):\n  n = 0\n  while True:\n    n += 1"}
+	]}`)
+
+	if out, _ := run(t, reg, "strict", "21"); out != "42" {
+		t.Errorf("strict 21 = %q", out)
+	}
+	if _, err := run(t, reg, "strict", ""); err == nil || err.Error() != "strict: usage: /strict <n>" {
+		t.Errorf("strict: err = %v", err)
+	}
+	if _, err := run(t, reg, "spin", ""); err == nil || !strings.Contains(err.Error(), "too many steps") {
+		t.Errorf("spin: err = %v", err)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	start := time.Now()
+	if _, err := reg.Get("slow").Execute(ctx, ""); err == nil || !strings.Contains(err.Error(), "timed out") {
+		t.Errorf("slow: err = %v", err)
+	}
+	if took := time.Since(start); took > 5*time.Second {
+		t.Errorf("slow took %s", took)
+	}
+}
+
+func TestLoadConfig(t *testing.T) {
+	dir := t.TempDir()
+	os.WriteFile(filepath.Join(dir, "hello.star"), []byte("def run(args):\n  return 'hi ' + args\n"), 0600)
+	path := filepath.Join(dir, "scripts.json")
+	os.WriteFile(path, []byte(`{"scripts": [{"name": "/Hello", "file": "hello.star", "risk": "high"}]}`), 0600)
+
+	cfg, err := LoadConfig(path)
+	if err != nil {
+		t.Fatalf("LoadConfig: %v", err)
+	}
+	op := &Op{Script: &cfg.Scripts[0], Registry: ops.NewRegistry()}
+	if op.Name() != "hello" || op.Risk() != ops.RiskHigh {
+		t.Fatalf("op = %s, risk %v", op.Name(), op.Risk())
+	}
+	if out, err := op.Execute(context.Background(), "there"); err != nil || out != "hi there" {
+		t.Fatalf("execute = %q, %v", out, err)
+	}
+
+	if cfg, err := LoadConfig(filepath.Join(dir, "missing.json")); cfg != nil || err != nil {
+		t.Fatalf("missing file = %v, %v", cfg, err)
+	}
+
+	for data, want := range map[string]string{
+		`{"scripts": [{"script": "def run(args): pass"}]}`:                                                              "needs a one-word name",
+		`{"scripts": [{"name": "a", "script": "def run(args): pass"}, {"name": "A", "script": "def run(args): pass"}]}`: `duplicate script "a"`,
+		`{"scripts": [{"name": "a"}]}`:                                                                                  "missing script or file",
+		`{"scripts": [{"name": "a", "script": "x", "file": "y"}]}`:                                                      "set only one",
+		`{"scripts": [{"name": "a", "script": "def main(): pass"}]}`:                                                    "does not define run(args)",
+		`{"scripts": [{"name": "a", "script": "def run(args) pass"}]}`:                                                  "got pass",
+		`{"scripts": [{"name": "a", "script": "def run(args): return open('x')"}]}`:                                     "undefined: open",
+		`{"scripts": [{"name": "a", "risk": "max", "script": "def run(args): pass"}]}`:                                  "unknown risk level",
+		`{"scripts": [{"name": "a", "file": "nope.star"}]}`:                                                             "read script",
+	} {
+		os.WriteFile(path, []byte(data), 0600)
+		if _, err := LoadConfig(path); err == nil || !strings.Contains(err.Error(), want) {
+			t.Errorf("%s: err = %v, want %q", data, err, want)
+		}
+	}
+}
@@ -0,0 +1,105 @@
+package core
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"time"
+
+	"github.com/jdelaire/openslack/core/approval"
+	"github.com/jdelaire/openslack/core/audit"
+	"github.com/jdelaire/openslack/core/auth"
+	"github.com/jdelaire/openslack/core/persist"
+	"github.com/jdelaire/openslack/core/policy"
+	"github.com/jdelaire/openslack/core/ratelimit"
+)
+
+// SecurityState is the dispatcher's policy, rate limiter and approval
+// store, each kept in the backend chosen by the storage config.
+type SecurityState struct {
+	Policy    *policy.Policy
+	Limiter   *ratelimit.Limiter
+	Approvals *approval.Store
+
+	backends *persist.Backends
+}
+
+// OpenSecurityState builds the dispatcher's security components for the
+// allowlisted chats. A nil cfg keeps everything in memory.
+func OpenSecurityState(cfg *persist.Config, chatIDs []int64) (*SecurityState, error) {
+	backends, err := persist.Open(cfg)
+	if err != nil {
+		return nil, err
+	}
+	st := &SecurityState{backends: backends}
+	if st.Policy, err = policy.Open(chatIDs, backends.For(persist.ComponentDedupe)); err != nil {
+		backends.Close()
+		return nil, err
+	}
+	if st.Limiter, err = ratelimit.Open(backends.For(persist.ComponentRateLimit)); err != nil {
+		backends.Close()
+		return nil, err
+	}
+	if st.Approvals, err = approval.Open(backends.For(persist.ComponentApprovals)); err != nil {
+		backends.Close()
+		return nil, err
+	}
+	return st, nil
+}
+
+// Verifier builds the one-time password verifier p selects. HOTP counters
+// are kept in the backend chosen for the hotp component.
+func (s *SecurityState) Verifier(secret string, p auth.Params) (TOTPVerifier, error) {
+	return auth.NewVerifier(secret, p, s.backends.For(persist.ComponentHOTP))
+}
+
+// AuditLog opens the audit log in the SQLite database when the storage
+// config sets "audit": "sqlite". It returns nil, nil otherwise.
+func (s *SecurityState) AuditLog() (*audit.Log, error) {
+	db := s.backends.DB(persist.ComponentAudit)
+	if db == nil {
+		return nil, nil
+	}
+	return audit.Open(db)
+}
+
+// NotifyLockouts sends a message through n whenever a chat is locked out,
+// so the owner hears about guessing attempts as they happen.
+func (s *SecurityState) NotifyLockouts(n Notifier, logger *slog.Logger) {
+	s.Limiter.OnLockout(func(lo ratelimit.Lockout) {
+		text := fmt.Sprintf("Chat %d locked out for %s after %d failed codes.",
+			lo.ChatID, time.Until(lo.Until).Round(time.Minute), lo.Failures)
+		if lo.Level > 1 {
+			text += fmt.Sprintf(" Lockout %d in a row.", lo.Level)
+		}
+		text += fmt.Sprintf(" Send /do unlock-chat %d <totp> to lift it.", lo.ChatID)
+		go func() {
+			ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+			defer cancel()
+			err := n.Send(ctx, Notification{Text: text, Source: "ratelimit", CreatedAt: time.Now()})
+			if err != nil {
+				logger.Error("lockout notification failed", "chat_id", lo.ChatID, "error", err)
+			}
+		}()
+	})
+}
+
+// NotifyExpiredApprovals sends a message through n whenever an approval
+// lapses unused, instead of leaving /approve to fail silently later. Run
+// the approval store so lapses are noticed without further traffic.
+func (s *SecurityState) NotifyExpiredApprovals(n Notifier, logger *slog.Logger) {
+	s.Approvals.OnExpire(func(a approval.Approval) {
+		ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+		defer cancel()
+		text := fmt.Sprintf("Approval %s for /%s expired unused.", a.Nonce, a.OpName)
+		err := n.Send(ctx, Notification{Text: text, Source: "approval", CreatedAt: time.Now()})
+		if err != nil {
+			logger.Error("approval expiry notification failed", "nonce", a.Nonce, "error", err)
+		}
+	})
+}
+
+// Close releases any database the components use.
+func (s *SecurityState) Close() error {
+	return s.backends.Close()
+}
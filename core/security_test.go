@@ -0,0 +1,104 @@
+package core
+
+import (
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/jdelaire/openslack/core/auth"
+	"github.com/jdelaire/openslack/core/persist"
+)
+
+func TestOpenSecurityStateFileBackend(t *testing.T) {
+	cfg := &persist.Config{Default: persist.BackendFile, File: filepath.Join(t.TempDir(), "state.json")}
+
+	st, err := OpenSecurityState(cfg, []int64{100})
+	if err != nil {
+		t.Fatalf("OpenSecurityState: %v", err)
+	}
+	nonce, err := st.Approvals.Create(100, "echo", "hi")
+	if err != nil {
+		t.Fatalf("Create: %v", err)
+	}
+	if err := st.Policy.Authorize(100, 7, time.Now()); err != nil {
+		t.Fatalf("Authorize: %v", err)
+	}
+	st.Close()
+
+	st, err = OpenSecurityState(cfg, []int64{100})
+	if err != nil {
+		t.Fatalf("reopen: %v", err)
+	}
+	defer st.Close()
+	if _, _, err := st.Approvals.Consume(nonce, 100); err != nil {
+		t.Errorf("approval lost across restart: %v", err)
+	}
+	if err := st.Policy.Authorize(100, 7, time.Now()); err == nil {
+		t.Error("update replayed across restart")
+	}
+}
+
+func TestOpenSecurityStateDefaultsToMemory(t *testing.T) {
+	st, err := OpenSecurityState(nil, []int64{100})
+	if err != nil {
+		t.Fatalf("OpenSecurityState: %v", err)
+	}
+	defer st.Close()
+	if st.Policy == nil || st.Limiter == nil || st.Approvals == nil {
+		t.Fatalf("state = %+v", st)
+	}
+}
+
+func TestSecurityStateHOTPCounterSurvivesRestart(t *testing.T) {
+	cfg := &persist.Config{Default: persist.BackendMemory, HOTP: persist.BackendFile, File: filepath.Join(t.TempDir(), "state.json")}
+	p := auth.Params{Mode: auth.ModeHOTP, Algorithm: "SHA1", Digits: 6, Window: 10}
+	const secret = "GEZDGNBVGY3TQOJQGEZDGNBVGY3TQOJQ" // "12345678901234567890"
+
+	st, err := OpenSecurityState(cfg, []int64{100})
+	if err != nil {
+		t.Fatalf("OpenSecurityState: %v", err)
+	}
+	v, err := st.Verifier(secret, p)
+	if err != nil {
+		t.Fatalf("Verifier: %v", err)
+	}
+	if !v.Verify("755224") { // RFC 4226 counter 0
+		t.Fatal("first code rejected")
+	}
+	st.Close()
+
+	st, err = OpenSecurityState(cfg, []int64{100})
+	if err != nil {
+		t.Fatalf("reopen: %v", err)
+	}
+	defer st.Close()
+	if v, _ = st.Verifier(secret, p); v.Verify("755224") {
+		t.Error("code replayed across restart")
+	}
+	if !v.Verify("287082") { // counter 1
+		t.Error("next code rejected after restart")
+	}
+}
+
+func TestNotifyLockouts(t *testing.T) {
+	st, err := OpenSecurityState(nil, []int64{100})
+	if err != nil {
+		t.Fatalf("OpenSecurityState: %v", err)
+	}
+	defer st.Close()
+	spy := &spyNotifier{}
+	st.NotifyLockouts(spy, testLogger())
+
+	for i := 0; i < 5; i++ {
+		st.Limiter.RecordFailure(999)
+	}
+
+	deadline := time.Now().Add(time.Second)
+	for spy.count() == 0 && time.Now().Before(deadline) {
+		time.Sleep(5 * time.Millisecond)
+	}
+	want := "Chat 999 locked out for 15m0s after 5 failed codes. Send /do unlock-chat 999 <totp> to lift it."
+	if got := spy.lastText(); got != want {
+		t.Errorf("notification = %q, want %q", got, want)
+	}
+}
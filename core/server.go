@@ -1,8 +1,10 @@
 package core
 
 import (
+	"bufio"
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"io"
 	"log/slog"
@@ -13,13 +15,26 @@ import (
 	"time"
 
 	"github.com/google/uuid"
+	"github.com/jdelaire/openslack/core/limits"
+	"github.com/jdelaire/openslack/internal/platform"
+	"github.com/jdelaire/openslack/internal/sources"
 )
 
 // Server listens on a Unix domain socket and dispatches requests.
 type Server struct {
 	socketPath string
 	registry   *Registry
+	inbox      DropStore
+	acks       AckTracker
+	outbox     OutboundQueue
+	templates  TemplateRenderer
+	sources    SourceRouter
+	exec       OpExecutor
+	execChatID int64
+	audit      AuditLog
+	limits     *limits.Config
 	listener   net.Listener
+	inherited  net.Listener
 	wg         sync.WaitGroup
 	logger     *slog.Logger
 }
@@ -33,9 +48,78 @@ func NewServer(socketPath string, registry *Registry, logger *slog.Logger) *Serv
 	}
 }
 
+// WithInbox retains every dropped file in store. Without it, drops are
+// only forwarded.
+func (s *Server) WithInbox(store DropStore) *Server {
+	s.inbox = store
+	return s
+}
+
+// WithAcks enables acknowledgement tracking for notify requests that set
+// "ack", and the "ack-status" action.
+func (s *Server) WithAcks(tracker AckTracker) *Server {
+	s.acks = tracker
+	return s
+}
+
+// WithOutbox enables notify requests with a future send_at.
+func (s *Server) WithOutbox(queue OutboundQueue) *Server {
+	s.outbox = queue
+	return s
+}
+
+// WithTemplates lets notify requests name a message template instead of
+// sending text.
+func (s *Server) WithTemplates(t TemplateRenderer) *Server {
+	s.templates = t
+	return s
+}
+
+// WithSources decorates notify requests with their registered source's
+// label, routes them to its chat and drops or holds those from muted sources.
+func (s *Server) WithSources(r SourceRouter) *Server {
+	s.sources = r
+	return s
+}
+
+// WithLimits applies the request size and notify text limits of cfg in
+// place of the defaults.
+func (s *Server) WithLimits(cfg *limits.Config) *Server {
+	s.limits = cfg
+	return s
+}
+
+// WithDispatch enables the "dispatch" action, which runs ops through exec
+// as if sent from chatID and returns their output.
+func (s *Server) WithDispatch(exec OpExecutor, chatID int64) *Server {
+	s.exec = exec
+	s.execChatID = chatID
+	return s
+}
+
+// WithAudit records each notify delivery, by source, in log.
+func (s *Server) WithAudit(log AuditLog) *Server {
+	s.audit = log
+	return s
+}
+
+// WithListener makes Start serve ln, typically the socket inherited from
+// the daemon being upgraded, instead of creating the socket.
+func (s *Server) WithListener(ln net.Listener) *Server {
+	s.inherited = ln
+	return s
+}
+
 // Start begins listening. It cleans up stale sockets, creates the directory
-// with 0700 permissions, and sets the socket to 0600.
+// with 0700 permissions, and restricts the socket to the current user.
 func (s *Server) Start(ctx context.Context) error {
+	if s.inherited != nil {
+		s.listener = s.inherited
+		s.logger.Info("listening on inherited socket", "path", s.socketPath)
+		s.serve(ctx)
+		return nil
+	}
+
 	dir := filepath.Dir(s.socketPath)
 	if err := os.MkdirAll(dir, 0700); err != nil {
 		return fmt.Errorf("create socket directory: %w", err)
@@ -44,7 +128,7 @@ func (s *Server) Start(ctx context.Context) error {
 	// Clean up stale socket.
 	if _, err := os.Stat(s.socketPath); err == nil {
 		// Check if something is listening.
-		conn, err := net.DialTimeout("unix", s.socketPath, 500*time.Millisecond)
+		conn, err := platform.Dial(s.socketPath, 500*time.Millisecond)
 		if err == nil {
 			conn.Close()
 			return fmt.Errorf("another instance is already listening on %s", s.socketPath)
@@ -55,26 +139,46 @@ func (s *Server) Start(ctx context.Context) error {
 		}
 	}
 
-	ln, err := net.Listen("unix", s.socketPath)
+	ln, err := platform.Listen(s.socketPath)
 	if err != nil {
-		return fmt.Errorf("listen: %w", err)
-	}
-
-	if err := os.Chmod(s.socketPath, 0600); err != nil {
-		ln.Close()
-		return fmt.Errorf("chmod socket: %w", err)
+		return err
 	}
 
 	s.listener = ln
 	s.logger.Info("listening", "path", s.socketPath)
+	s.serve(ctx)
+	return nil
+}
 
+func (s *Server) serve(ctx context.Context) {
 	s.wg.Add(1)
 	go func() {
 		defer s.wg.Done()
 		s.acceptLoop(ctx)
 	}()
+}
 
-	return nil
+// ListenerFile returns a duplicate of the listening socket's descriptor,
+// for handing to a replacement process.
+func (s *Server) ListenerFile() (*os.File, error) {
+	ul, ok := s.listener.(*net.UnixListener)
+	if !ok {
+		return nil, fmt.Errorf("listener is not a unix socket")
+	}
+	return ul.File()
+}
+
+// Drain stops accepting connections and waits for in-flight requests. Unlike
+// Shutdown it leaves the socket file in place for the process that
+// inherited the listener.
+func (s *Server) Drain() {
+	if ul, ok := s.listener.(*net.UnixListener); ok {
+		ul.SetUnlinkOnClose(false)
+	}
+	if s.listener != nil {
+		s.listener.Close()
+	}
+	s.wg.Wait()
 }
 
 // Shutdown gracefully stops the server and waits for in-flight connections.
@@ -115,64 +219,253 @@ func (s *Server) handleConnection(ctx context.Context, conn net.Conn) {
 
 	conn.SetDeadline(time.Now().Add(5 * time.Second))
 
-	data, err := io.ReadAll(io.LimitReader(conn, MaxPayloadBytes+1))
-	if err != nil {
-		s.writeResponse(conn, Response{OK: false, Error: "read error"})
-		return
-	}
-
-	if len(data) > MaxPayloadBytes {
-		s.writeResponse(conn, Response{OK: false, Error: fmt.Sprintf("payload exceeds %d byte limit", MaxPayloadBytes)})
+	br := bufio.NewReader(conn)
+	if isJSONRPC(br) {
+		s.serveRPC(ctx, conn, br)
 		return
 	}
 
-	req, err := ValidateRequest(data)
+	req, err := decodeRequest(br, s.limits)
 	if err != nil {
+		var netErr net.Error
+		if errors.As(err, &netErr) {
+			s.writeResponse(conn, Response{OK: false, Error: "read error"})
+			return
+		}
 		s.logger.Warn("invalid request", "error", err)
+		// Drain what the client is still sending so it reads the error
+		// instead of a broken pipe.
+		io.Copy(io.Discard, io.LimitReader(br, MaxDropRequestBytes))
 		s.writeResponse(conn, Response{OK: false, Error: err.Error()})
 		return
 	}
+	s.writeResponse(conn, s.handle(ctx, req))
+}
 
+// Call runs action with payload, a JSON object, as if it had arrived on
+// the socket: it is validated under the server's limits, then handled.
+// An invalid request returns an error; a failed action returns a
+// Response with OK false. Other local APIs use it to share the socket's
+// pipeline.
+func (s *Server) Call(ctx context.Context, action string, payload json.RawMessage) (Response, error) {
+	envelope, err := json.Marshal(Request{Version: CurrentVersion, Action: action, Payload: payload})
+	if err != nil {
+		return Response{}, err
+	}
+	req, err := validateRequest(envelope, s.limits)
+	if err != nil {
+		return Response{}, err
+	}
+	return s.handle(ctx, req), nil
+}
+
+// handle runs a validated request.
+func (s *Server) handle(ctx context.Context, req *Request) Response {
 	switch req.Action {
 	case "notify":
-		s.handleNotify(ctx, conn, req)
+		return s.handleNotify(ctx, req)
+	case "drop":
+		return s.handleDrop(ctx, req)
+	case "ack-status":
+		return s.handleAckStatus(req)
+	case "dispatch":
+		return s.handleDispatch(ctx, req)
 	default:
-		s.writeResponse(conn, Response{OK: false, Error: fmt.Sprintf("unknown action %q", req.Action)})
+		return Response{OK: false, Error: fmt.Sprintf("unknown action %q", req.Action)}
 	}
 }
 
-func (s *Server) handleNotify(ctx context.Context, conn net.Conn, req *Request) {
-	payload, err := ParseNotifyPayload(req.Payload)
+func (s *Server) handleNotify(ctx context.Context, req *Request) Response {
+	payload, err := parsedPayload[NotifyPayload](req)
 	if err != nil {
-		s.writeResponse(conn, Response{OK: false, Error: err.Error()})
-		return
+		return Response{OK: false, Error: err.Error()}
+	}
+
+	if payload.Template != "" {
+		if s.templates == nil {
+			return Response{OK: false, Error: "templates not configured"}
+		}
+		text, err := s.templates.Render(payload.Template, payload.Vars)
+		if err != nil {
+			return Response{OK: false, Error: err.Error()}
+		}
+		if len(payload.Notification(text).PlainText()) > s.limits.TextLen() {
+			return Response{OK: false, Error: fmt.Sprintf("rendered text exceeds %d character limit", s.limits.TextLen())}
+		}
+		payload.Text = text
+	}
+
+	var route sources.Route
+	if s.sources != nil {
+		route, err = s.sources.Route(payload.Source, payload.Text)
+		if err != nil {
+			return Response{OK: false, Error: err.Error()}
+		}
+		if route.Muted {
+			status := "muted"
+			if route.Held {
+				status = "held"
+			}
+			s.logger.Info("notification muted", "source", payload.Source, "status", status)
+			return Response{OK: true, Status: status}
+		}
+		payload.Text = route.Text
+	}
+
+	if payload.SendAt != nil && payload.SendAt.After(time.Now()) {
+		return s.scheduleNotify(payload)
 	}
 
 	notifier, err := s.registry.Default()
 	if err != nil {
 		s.logger.Error("no default notifier", "error", err)
-		s.writeResponse(conn, Response{OK: false, Error: "no notifier configured"})
-		return
+		return Response{OK: false, Error: "no notifier configured"}
 	}
 
 	id := uuid.New().String()
-	n := Notification{
-		ID:        id,
-		Text:      payload.Text,
-		Source:    payload.Source,
-		CreatedAt: time.Now(),
+	text := payload.Text
+	if payload.Ack {
+		if s.acks == nil {
+			return Response{OK: false, Error: "acknowledgement tracking not enabled"}
+		}
+		id, err = s.acks.Track(payload.Text, payload.Source)
+		if err != nil {
+			s.logger.Error("track ack failed", "error", err)
+			return Response{OK: false, Error: "could not track acknowledgement"}
+		}
+		text += fmt.Sprintf("\n\nReply /ack %s to confirm", id)
 	}
 
-	if err := notifier.Send(ctx, n); err != nil {
+	n := payload.Notification(text)
+	n.ID = id
+	n.CreatedAt = time.Now()
+	n.ChatID = route.ChatID
+	n.Silent = route.Silent || payload.Priority == PriorityLow
+
+	err = notifier.Send(ctx, n)
+	if s.audit != nil {
+		s.audit.RecordNotification(payload.Source, err)
+	}
+	if err != nil {
 		s.logger.Error("send failed", "notifier", notifier.Name(), "error", err)
-		s.writeResponse(conn, Response{OK: false, Error: "delivery failed"})
-		return
+		return Response{OK: false, Error: "delivery failed"}
 	}
 
 	s.logger.Info("notification sent", "id", id, "notifier", notifier.Name(), "source", payload.Source)
-	s.writeResponse(conn, Response{OK: true, ID: id})
+	return Response{OK: true, ID: id}
+}
+
+func (s *Server) scheduleNotify(payload NotifyPayload) Response {
+	if s.outbox == nil {
+		return Response{OK: false, Error: "scheduled delivery not enabled"}
+	}
+	// The queue keeps text only, so structured notifications are
+	// flattened to their plain-text form.
+	id, err := s.outbox.Schedule(payload.Notification(payload.Text).PlainText(), payload.Source, *payload.SendAt)
+	if err != nil {
+		s.logger.Error("schedule notification failed", "error", err)
+		return Response{OK: false, Error: "could not schedule notification"}
+	}
+	s.logger.Info("notification scheduled", "id", id, "send_at", payload.SendAt, "source", payload.Source)
+	return Response{OK: true, ID: id, Status: "scheduled"}
+}
+
+func (s *Server) handleAckStatus(req *Request) Response {
+	payload, err := parsedPayload[AckStatusPayload](req)
+	if err != nil {
+		return Response{OK: false, Error: err.Error()}
+	}
+	if s.acks == nil {
+		return Response{OK: false, Error: "acknowledgement tracking not enabled"}
+	}
+
+	ackedAt, found, err := s.acks.AckedAt(payload.ID)
+	if err != nil {
+		s.logger.Error("ack status failed", "id", payload.ID, "error", err)
+		return Response{OK: false, Error: "ack status unavailable"}
+	}
+	if !found {
+		return Response{OK: false, Error: fmt.Sprintf("unknown ack id %q", payload.ID)}
+	}
+
+	resp := Response{OK: true, ID: payload.ID, Status: "pending"}
+	if !ackedAt.IsZero() {
+		resp.Status, resp.AckedAt = "acked", &ackedAt
+	}
+	return resp
+}
+
+func (s *Server) handleDispatch(ctx context.Context, req *Request) Response {
+	payload, err := parsedPayload[DispatchPayload](req)
+	if err != nil {
+		return Response{OK: false, Error: err.Error()}
+	}
+	if s.exec == nil {
+		return Response{OK: false, Error: "dispatch not enabled"}
+	}
+
+	output, err := s.exec.Exec(ctx, s.execChatID, payload.Op, payload.Args)
+	if err != nil {
+		s.logger.Warn("dispatch failed", "op", payload.Op, "error", err)
+		return Response{OK: false, Error: err.Error(), Output: output}
+	}
+	s.logger.Info("dispatched", "op", payload.Op)
+	return Response{OK: true, Output: output}
+}
+
+func (s *Server) handleDrop(ctx context.Context, req *Request) Response {
+	payload, err := parsedPayload[DropPayload](req)
+	if err != nil {
+		return Response{OK: false, Error: err.Error()}
+	}
+
+	notifier, err := s.registry.Default()
+	if err != nil {
+		s.logger.Error("no default notifier", "error", err)
+		return Response{OK: false, Error: "no notifier configured"}
+	}
+	sender, ok := Unwrap(notifier).(DocumentSender)
+	if !ok {
+		return Response{OK: false, Error: fmt.Sprintf("notifier %s cannot send documents", notifier.Name())}
+	}
+
+	doc := Document{Name: payload.Name, Data: payload.Content(), Caption: payload.Caption}
+	if doc.Name == "" {
+		ext := ".bin"
+		if payload.Text != "" {
+			ext = ".txt"
+		}
+		doc.Name = "drop-" + time.Now().Format("20060102-150405") + ext
+	}
+	if doc.Caption == "" && payload.Source != "" {
+		doc.Caption = "From " + payload.Source
+	}
+
+	id := uuid.New().String()
+	if s.inbox != nil {
+		id, err = s.inbox.Save(doc.Name, payload.Source, doc.Data)
+		if err != nil {
+			s.logger.Error("save drop failed", "error", err)
+			return Response{OK: false, Error: "could not save to inbox"}
+		}
+	}
+
+	if err := sender.SendDocument(ctx, doc); err != nil {
+		s.logger.Error("send document failed", "notifier", notifier.Name(), "error", err)
+		resp := Response{OK: false, Error: "delivery failed"}
+		if s.inbox != nil {
+			resp.Error, resp.ID = "delivery failed; kept in inbox", id
+		}
+		return resp
+	}
+
+	s.logger.Info("drop sent", "id", id, "notifier", notifier.Name(), "source", payload.Source, "bytes", len(doc.Data))
+	return Response{OK: true, ID: id}
 }
 
+// writeResponse writes resp. Ops run by "dispatch" can outlast the read
+// deadline, so the write gets its own.
 func (s *Server) writeResponse(conn net.Conn, resp Response) {
+	conn.SetWriteDeadline(time.Now().Add(5 * time.Second))
 	json.NewEncoder(conn).Encode(resp)
 }
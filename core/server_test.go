@@ -10,20 +10,34 @@ import (
 	"os"
 	"path/filepath"
 	"strings"
+	"sync"
 	"testing"
 	"time"
+
+	"github.com/jdelaire/openslack/core/limits"
+	"github.com/jdelaire/openslack/internal/sources"
 )
 
 type echoNotifier struct {
+	mu   sync.Mutex
 	sent []Notification
 }
 
 func (e *echoNotifier) Name() string { return "echo" }
 func (e *echoNotifier) Send(_ context.Context, n Notification) error {
+	e.mu.Lock()
+	defer e.mu.Unlock()
 	e.sent = append(e.sent, n)
 	return nil
 }
 
+// notifications returns a copy of what has been sent so far.
+func (e *echoNotifier) notifications() []Notification {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	return append([]Notification(nil), e.sent...)
+}
+
 type failNotifier struct{}
 
 func (f *failNotifier) Name() string { return "fail" }
@@ -92,14 +106,14 @@ func TestServer_NotifySuccess(t *testing.T) {
 	if resp.ID == "" {
 		t.Error("expected non-empty ID")
 	}
-	if len(echo.sent) != 1 {
-		t.Fatalf("expected 1 sent notification, got %d", len(echo.sent))
+	if len(echo.notifications()) != 1 {
+		t.Fatalf("expected 1 sent notification, got %d", len(echo.notifications()))
 	}
-	if echo.sent[0].Text != "hello" {
-		t.Errorf("expected text hello, got %s", echo.sent[0].Text)
+	if echo.notifications()[0].Text != "hello" {
+		t.Errorf("expected text hello, got %s", echo.notifications()[0].Text)
 	}
-	if echo.sent[0].Source != "test" {
-		t.Errorf("expected source test, got %s", echo.sent[0].Source)
+	if echo.notifications()[0].Source != "test" {
+		t.Errorf("expected source test, got %s", echo.notifications()[0].Source)
 	}
 }
 
@@ -155,6 +169,61 @@ func TestServer_PayloadTooLarge(t *testing.T) {
 	}
 }
 
+func TestServer_StructuredNotify(t *testing.T) {
+	echo := &echoNotifier{}
+	srv, sockPath, cancel := setupTestServer(t, echo)
+	defer func() { cancel(); srv.Shutdown() }()
+
+	resp := sendRequest(t, sockPath, []byte(`{"version":2,"action":"notify","payload":{"text":"Disk at 93%","title":"Disk almost full","priority":"low","fields":[{"name":"host","value":"db1"}]}}`))
+	if !resp.OK {
+		t.Fatalf("notify: %s", resp.Error)
+	}
+	n := echo.notifications()[0]
+	if n.Title != "Disk almost full" || len(n.Fields) != 1 || n.Fields[0].Value != "db1" || !n.Silent {
+		t.Errorf("sent %+v", n)
+	}
+}
+
+func TestServer_WithLimits(t *testing.T) {
+	srv, sockPath, cancel := setupTestServer(t, &echoNotifier{})
+	defer func() { cancel(); srv.Shutdown() }()
+	srv.WithLimits(&limits.Config{MaxPayloadBytes: 2048, MaxTextLen: 100})
+
+	resp := sendRequest(t, sockPath, []byte(`{"version":1,"action":"notify","payload":{"text":"`+strings.Repeat("x", 101)+`"}}`))
+	if resp.OK || !strings.Contains(resp.Error, "100 character limit") {
+		t.Errorf("long text: %+v", resp)
+	}
+	resp = sendRequest(t, sockPath, []byte(`{"version":1,"action":"notify","payload":{"text":"hi","source":"`+strings.Repeat("s", 3000)+`"}}`))
+	if resp.OK || !strings.Contains(resp.Error, "2048 byte limit") {
+		t.Errorf("large request: %+v", resp)
+	}
+}
+
+func TestServer_RepliesBeforeEOF(t *testing.T) {
+	echo := &echoNotifier{}
+	srv, sockPath, cancel := setupTestServer(t, echo)
+	defer func() { cancel(); srv.Shutdown() }()
+
+	conn, err := net.DialTimeout("unix", sockPath, 2*time.Second)
+	if err != nil {
+		t.Fatalf("dial: %v", err)
+	}
+	defer conn.Close()
+	conn.SetDeadline(time.Now().Add(2 * time.Second))
+
+	// The write side stays open: the envelope alone ends the request.
+	if _, err := conn.Write([]byte(`{"version":1,"action":"notify","payload":{"text":"hi"}}`)); err != nil {
+		t.Fatalf("write: %v", err)
+	}
+	var resp Response
+	if err := json.NewDecoder(conn).Decode(&resp); err != nil {
+		t.Fatalf("decode response: %v", err)
+	}
+	if !resp.OK || len(echo.notifications()) != 1 {
+		t.Errorf("resp = %+v, sent %d", resp, len(echo.notifications()))
+	}
+}
+
 func TestServer_SocketPermissions(t *testing.T) {
 	srv, sockPath, cancel := setupTestServer(t, &echoNotifier{})
 	defer func() { cancel(); srv.Shutdown() }()
@@ -255,7 +324,327 @@ func TestServer_MultipleConnections(t *testing.T) {
 		}
 	}
 
-	if len(echo.sent) != 5 {
-		t.Errorf("expected 5 sent, got %d", len(echo.sent))
+	if len(echo.notifications()) != 5 {
+		t.Errorf("expected 5 sent, got %d", len(echo.notifications()))
+	}
+}
+
+type docNotifier struct {
+	echoNotifier
+	docs []Document
+	fail bool
+}
+
+func (d *docNotifier) SendDocument(_ context.Context, doc Document) error {
+	if d.fail {
+		return fmt.Errorf("upload failed")
+	}
+	d.docs = append(d.docs, doc)
+	return nil
+}
+
+type memDropStore struct {
+	saved map[string][]byte
+}
+
+func (m *memDropStore) Save(name, _ string, data []byte) (string, error) {
+	id := fmt.Sprintf("item%d", len(m.saved)+1)
+	m.saved[id] = data
+	return id, nil
+}
+
+func TestServer_DropText(t *testing.T) {
+	docs := &docNotifier{}
+	srv, sockPath, cancel := setupTestServer(t, docs)
+	defer func() { cancel(); srv.Shutdown() }()
+
+	resp := sendRequest(t, sockPath, []byte(`{"version":1,"action":"drop","payload":{"text":"line1\nline2","source":"backup.sh"}}`))
+	if !resp.OK || resp.ID == "" {
+		t.Fatalf("response = %+v", resp)
+	}
+	if len(docs.docs) != 1 {
+		t.Fatalf("expected 1 document, got %d", len(docs.docs))
+	}
+	doc := docs.docs[0]
+	if !strings.HasPrefix(doc.Name, "drop-") || !strings.HasSuffix(doc.Name, ".txt") {
+		t.Errorf("name = %q", doc.Name)
+	}
+	if string(doc.Data) != "line1\nline2" || doc.Caption != "From backup.sh" {
+		t.Errorf("doc = %+v", doc)
+	}
+}
+
+func TestServer_DropFileToInbox(t *testing.T) {
+	docs := &docNotifier{}
+	srv, sockPath, cancel := setupTestServer(t, docs)
+	defer func() { cancel(); srv.Shutdown() }()
+	store := &memDropStore{saved: map[string][]byte{}}
+	srv.WithInbox(store)
+
+	// "aGVsbG8=" is base64 for "hello". Files larger than the notify limit
+	// are accepted.
+	big := strings.Repeat("QUFB", MaxPayloadBytes/2)
+	resp := sendRequest(t, sockPath, []byte(`{"version":1,"action":"drop","payload":{"name":"big.bin","data":"`+big+`","caption":"nightly"}}`))
+	if !resp.OK || resp.ID != "item1" {
+		t.Fatalf("response = %+v", resp)
+	}
+	if len(store.saved["item1"]) != MaxPayloadBytes/2*3 {
+		t.Errorf("saved %d bytes", len(store.saved["item1"]))
+	}
+
+	docs.fail = true
+	resp = sendRequest(t, sockPath, []byte(`{"version":1,"action":"drop","payload":{"name":"a.txt","data":"aGVsbG8="}}`))
+	if resp.OK || resp.ID != "item2" || !strings.Contains(resp.Error, "kept in inbox") {
+		t.Errorf("failed delivery response = %+v", resp)
+	}
+	if string(store.saved["item2"]) != "hello" {
+		t.Errorf("saved = %q", store.saved["item2"])
+	}
+}
+
+func TestServer_DropUnsupportedNotifier(t *testing.T) {
+	srv, sockPath, cancel := setupTestServer(t, &echoNotifier{})
+	defer func() { cancel(); srv.Shutdown() }()
+
+	resp := sendRequest(t, sockPath, []byte(`{"version":1,"action":"drop","payload":{"text":"hi"}}`))
+	if resp.OK || !strings.Contains(resp.Error, "cannot send documents") {
+		t.Errorf("response = %+v", resp)
+	}
+}
+
+type memAckTracker struct {
+	acked map[string]time.Time
+}
+
+func (m *memAckTracker) Track(_, _ string) (string, error) {
+	id := fmt.Sprintf("a%d", len(m.acked)+1)
+	m.acked[id] = time.Time{}
+	return id, nil
+}
+
+func (m *memAckTracker) AckedAt(id string) (time.Time, bool, error) {
+	at, ok := m.acked[id]
+	return at, ok, nil
+}
+
+func TestServer_NotifyWithAck(t *testing.T) {
+	echo := &echoNotifier{}
+	srv, sockPath, cancel := setupTestServer(t, echo)
+	defer func() { cancel(); srv.Shutdown() }()
+
+	notify := []byte(`{"version":1,"action":"notify","payload":{"text":"batch done","ack":true}}`)
+	if resp := sendRequest(t, sockPath, notify); resp.OK || !strings.Contains(resp.Error, "not enabled") {
+		t.Fatalf("without tracker: %+v", resp)
+	}
+
+	tracker := &memAckTracker{acked: map[string]time.Time{}}
+	srv.WithAcks(tracker)
+
+	resp := sendRequest(t, sockPath, notify)
+	if !resp.OK || resp.ID != "a1" {
+		t.Fatalf("notify response = %+v", resp)
+	}
+	if want := "batch done\n\nReply /ack a1 to confirm"; len(echo.notifications()) != 1 || echo.notifications()[0].Text != want {
+		t.Fatalf("sent = %+v, want text %q", echo.notifications(), want)
+	}
+
+	status := []byte(`{"version":1,"action":"ack-status","payload":{"id":"a1"}}`)
+	resp = sendRequest(t, sockPath, status)
+	if !resp.OK || resp.Status != "pending" || resp.AckedAt != nil {
+		t.Errorf("pending status = %+v", resp)
+	}
+
+	ackedAt := time.Date(2026, 3, 1, 8, 0, 0, 0, time.UTC)
+	tracker.acked["a1"] = ackedAt
+	resp = sendRequest(t, sockPath, status)
+	if !resp.OK || resp.Status != "acked" || resp.AckedAt == nil || !resp.AckedAt.Equal(ackedAt) {
+		t.Errorf("acked status = %+v", resp)
+	}
+
+	resp = sendRequest(t, sockPath, []byte(`{"version":1,"action":"ack-status","payload":{"id":"zz"}}`))
+	if resp.OK || !strings.Contains(resp.Error, "unknown ack id") {
+		t.Errorf("unknown id status = %+v", resp)
+	}
+}
+
+type memOutbox struct {
+	scheduled []NotifyPayload
+}
+
+func (m *memOutbox) Schedule(text, source string, sendAt time.Time) (string, error) {
+	m.scheduled = append(m.scheduled, NotifyPayload{Text: text, Source: source, SendAt: &sendAt})
+	return "q1", nil
+}
+
+func TestServer_NotifySendAt(t *testing.T) {
+	echo := &echoNotifier{}
+	srv, sockPath, cancel := setupTestServer(t, echo)
+	defer func() { cancel(); srv.Shutdown() }()
+
+	sendAt := time.Now().Add(10 * time.Hour).UTC().Truncate(time.Second)
+	data := []byte(`{"version":1,"action":"notify","payload":{"text":"morning","send_at":"` + sendAt.Format(time.RFC3339) + `"}}`)
+	if resp := sendRequest(t, sockPath, data); resp.OK || !strings.Contains(resp.Error, "not enabled") {
+		t.Fatalf("without outbox: %+v", resp)
+	}
+
+	queue := &memOutbox{}
+	srv.WithOutbox(queue)
+	resp := sendRequest(t, sockPath, data)
+	if !resp.OK || resp.ID != "q1" || resp.Status != "scheduled" {
+		t.Fatalf("response = %+v", resp)
+	}
+	if len(echo.notifications()) != 0 {
+		t.Errorf("scheduled notification sent immediately: %+v", echo.notifications())
+	}
+	if len(queue.scheduled) != 1 || !queue.scheduled[0].SendAt.Equal(sendAt) || queue.scheduled[0].Text != "morning" {
+		t.Errorf("scheduled = %+v", queue.scheduled)
+	}
+
+	// A send_at that has just passed is delivered now.
+	recent := time.Now().Add(-10 * time.Second).UTC().Format(time.RFC3339)
+	resp = sendRequest(t, sockPath, []byte(`{"version":1,"action":"notify","payload":{"text":"now","send_at":"`+recent+`"}}`))
+	if !resp.OK || resp.Status != "" || len(echo.notifications()) != 1 {
+		t.Errorf("recent send_at: response %+v, sent %d", resp, len(echo.notifications()))
+	}
+}
+
+type mapTemplates map[string]string
+
+func (m mapTemplates) Render(name string, vars map[string]string) (string, error) {
+	text, ok := m[name]
+	if !ok {
+		return "", fmt.Errorf("unknown template %q", name)
+	}
+	return strings.ReplaceAll(text, "{host}", vars["host"]), nil
+}
+
+func TestServer_NotifyTemplate(t *testing.T) {
+	echo := &echoNotifier{}
+	srv, sockPath, cancel := setupTestServer(t, echo)
+	defer func() { cancel(); srv.Shutdown() }()
+
+	data := []byte(`{"version":1,"action":"notify","payload":{"template":"backup_done","vars":{"host":"nas"}}}`)
+	if resp := sendRequest(t, sockPath, data); resp.OK || resp.Error != "templates not configured" {
+		t.Fatalf("without templates: %+v", resp)
+	}
+
+	srv.WithTemplates(mapTemplates{"backup_done": "Backup of {host} finished"})
+	if resp := sendRequest(t, sockPath, data); !resp.OK {
+		t.Fatalf("response = %+v", resp)
+	}
+	if len(echo.notifications()) != 1 || echo.notifications()[0].Text != "Backup of nas finished" {
+		t.Errorf("sent = %+v", echo.notifications())
+	}
+
+	resp := sendRequest(t, sockPath, []byte(`{"version":1,"action":"notify","payload":{"template":"nope"}}`))
+	if resp.OK || resp.Error != `unknown template "nope"` {
+		t.Errorf("unknown template: %+v", resp)
+	}
+}
+
+func TestServer_NotifySources(t *testing.T) {
+	echo := &echoNotifier{}
+	srv, sockPath, cancel := setupTestServer(t, echo)
+	defer func() { cancel(); srv.Shutdown() }()
+
+	srv.WithSources(sources.NewRegistry(&sources.Config{
+		Require: true,
+		Sources: map[string]sources.Source{
+			"backup": {Name: "Backup", Emoji: "💾", Priority: sources.PriorityLow, ChatID: -100},
+			"noisy":  {Name: "Noisy", Priority: sources.PriorityNormal, Muted: true},
+		},
+	}, nil))
+
+	resp := sendRequest(t, sockPath, []byte(`{"version":1,"action":"notify","payload":{"text":"done","source":"backup"}}`))
+	if !resp.OK {
+		t.Fatalf("response = %+v", resp)
+	}
+	if len(echo.notifications()) != 1 {
+		t.Fatalf("sent = %+v", echo.notifications())
+	}
+	if n := echo.notifications()[0]; n.Text != "💾 Backup: done" || n.ChatID != -100 || !n.Silent {
+		t.Errorf("notification = %+v", n)
+	}
+
+	resp = sendRequest(t, sockPath, []byte(`{"version":1,"action":"notify","payload":{"text":"hi","source":"noisy"}}`))
+	if !resp.OK || resp.Status != "muted" || len(echo.notifications()) != 1 {
+		t.Errorf("muted source: %+v, sent %d", resp, len(echo.notifications()))
+	}
+
+	resp = sendRequest(t, sockPath, []byte(`{"version":1,"action":"notify","payload":{"text":"hi","source":"other"}}`))
+	if resp.OK || resp.Error != `unregistered source "other"` {
+		t.Errorf("unregistered source: %+v", resp)
+	}
+}
+
+type fakeExecutor struct {
+	chatID int64
+	calls  []string
+}
+
+func (f *fakeExecutor) Exec(_ context.Context, chatID int64, cmd, args string) (string, error) {
+	f.chatID = chatID
+	f.calls = append(f.calls, cmd+" "+args)
+	if cmd != "status" {
+		return "", fmt.Errorf("unknown command /%s", cmd)
+	}
+	return "all good", nil
+}
+
+func TestServer_Dispatch(t *testing.T) {
+	srv, sockPath, cancel := setupTestServer(t, &echoNotifier{})
+	defer func() { cancel(); srv.Shutdown() }()
+
+	data := []byte(`{"version":1,"action":"dispatch","payload":{"op":"status","args":"disk"}}`)
+	if resp := sendRequest(t, sockPath, data); resp.OK || resp.Error != "dispatch not enabled" {
+		t.Fatalf("without dispatch: %+v", resp)
+	}
+
+	exec := &fakeExecutor{}
+	srv.WithDispatch(exec, 42)
+	resp := sendRequest(t, sockPath, data)
+	if !resp.OK || resp.Output != "all good" {
+		t.Errorf("response = %+v", resp)
+	}
+	if exec.chatID != 42 || len(exec.calls) != 1 || exec.calls[0] != "status disk" {
+		t.Errorf("exec = %+v", exec)
+	}
+
+	resp = sendRequest(t, sockPath, []byte(`{"version":1,"action":"dispatch","payload":{"op":"nope"}}`))
+	if resp.OK || resp.Error != "unknown command /nope" {
+		t.Errorf("unknown op: %+v", resp)
+	}
+}
+
+func TestServer_ListenerHandover(t *testing.T) {
+	old, sockPath, cancel := setupTestServer(t, &echoNotifier{})
+	defer cancel()
+
+	f, err := old.ListenerFile()
+	if err != nil {
+		t.Fatalf("listener file: %v", err)
+	}
+	ln, err := net.FileListener(f)
+	f.Close()
+	if err != nil {
+		t.Fatalf("file listener: %v", err)
+	}
+
+	reg := NewRegistry()
+	reg.Register(&echoNotifier{})
+	next := NewServer(sockPath, reg, slog.New(slog.NewJSONHandler(io.Discard, nil))).WithListener(ln)
+	if err := next.Start(context.Background()); err != nil {
+		t.Fatalf("start with inherited listener: %v", err)
+	}
+	defer next.Shutdown()
+
+	old.Drain()
+	if _, err := os.Stat(sockPath); err != nil {
+		t.Fatalf("socket removed by drain: %v", err)
+	}
+
+	resp := sendRequest(t, sockPath, []byte(`{"version":1,"action":"notify","payload":{"text":"after upgrade"}}`))
+	if !resp.OK {
+		t.Fatalf("expected ok from new server, got: %s", resp.Error)
 	}
 }
@@ -0,0 +1,147 @@
+// Package style decorates dispatcher replies: a prefix per severity and a
+// badge per risk level, taken from a configurable theme.
+package style
+
+import (
+	"encoding/json"
+	"fmt"
+	"maps"
+	"os"
+	"sort"
+	"strings"
+
+	"github.com/jdelaire/openslack/core/ops"
+)
+
+// Severity classifies a reply.
+type Severity int
+
+const (
+	Info    Severity = iota // Usage text, previews, pending approvals
+	Success                 // An op's result
+	Warn                    // The command was refused but may be retried
+	Error                   // The command failed
+)
+
+// Theme holds the prefix for each severity and the badge for each risk
+// level. Empty strings leave replies undecorated.
+type Theme struct {
+	Info    string            `json:"info"`
+	Success string            `json:"success"`
+	Warn    string            `json:"warn"`
+	Error   string            `json:"error"`
+	Risk    map[string]string `json:"risk"` // keyed by "none", "low", "high"
+}
+
+// Themes are the built-in themes.
+var Themes = map[string]Theme{
+	"emoji": {
+		Success: "✅",
+		Warn:    "⚠️",
+		Error:   "❌",
+		Risk:    map[string]string{"none": "🟢", "low": "🟡", "high": "🔴"},
+	},
+	"plain": {
+		Warn:  "[warn]",
+		Error: "[error]",
+		Risk:  map[string]string{"none": "[open]", "low": "[totp]", "high": "[approve]"},
+	},
+	"none": {},
+}
+
+// Prefix returns the theme's prefix for sev.
+func (t *Theme) Prefix(sev Severity) string {
+	if t == nil {
+		return ""
+	}
+	switch sev {
+	case Success:
+		return t.Success
+	case Warn:
+		return t.Warn
+	case Error:
+		return t.Error
+	default:
+		return t.Info
+	}
+}
+
+// Apply prefixes text with the theme's mark for sev. A nil theme returns
+// text unchanged.
+func (t *Theme) Apply(sev Severity, text string) string {
+	if p := t.Prefix(sev); p != "" {
+		return p + " " + text
+	}
+	return text
+}
+
+// Badge returns the theme's badge for risk level r.
+func (t *Theme) Badge(r ops.RiskLevel) string {
+	if t == nil {
+		return ""
+	}
+	return t.Risk[r.String()]
+}
+
+// config is the style file: a built-in theme name plus overrides.
+type config struct {
+	Theme
+	Name string `json:"theme"`
+}
+
+// LoadConfig reads a style config file: {"theme": "emoji"} picks a built-in
+// theme, and any other field overrides its entry. Returns nil, nil if the
+// file does not exist.
+func LoadConfig(path string) (*Theme, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("read style config: %w", err)
+	}
+
+	var cfg config
+	if err := json.Unmarshal(data, &cfg); err != nil {
+		return nil, fmt.Errorf("parse style config: %w", err)
+	}
+	if cfg.Name == "" {
+		cfg.Name = "emoji"
+	}
+	base, ok := Themes[cfg.Name]
+	if !ok {
+		return nil, fmt.Errorf("style config: unknown theme %q (want %s)", cfg.Name, themeNames())
+	}
+
+	theme := base
+	theme.Risk = maps.Clone(base.Risk)
+	if theme.Risk == nil {
+		theme.Risk = map[string]string{}
+	}
+	override(&theme.Info, cfg.Info)
+	override(&theme.Success, cfg.Success)
+	override(&theme.Warn, cfg.Warn)
+	override(&theme.Error, cfg.Error)
+	for k, v := range cfg.Risk {
+		if k != "none" && k != "low" && k != "high" {
+			return nil, fmt.Errorf("style config: unknown risk level %q (want none, low or high)", k)
+		}
+		theme.Risk[k] = v
+	}
+	return &theme, nil
+}
+
+func override(dst *string, v string) {
+	if v != "" {
+		*dst = v
+	}
+}
+
+func themeNames() string {
+	names := make([]string, 0, len(Themes))
+	for name := range Themes {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return strings.Join(names, ", ")
+}
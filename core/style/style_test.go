@@ -0,0 +1,81 @@
+package style
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/jdelaire/openslack/core/ops"
+)
+
+func TestApply(t *testing.T) {
+	emoji := Themes["emoji"]
+	tests := []struct {
+		theme *Theme
+		sev   Severity
+		want  string
+	}{
+		{nil, Error, "boom"},
+		{&emoji, Success, "✅ boom"},
+		{&emoji, Warn, "⚠️ boom"},
+		{&emoji, Error, "❌ boom"},
+		{&emoji, Info, "boom"},
+	}
+	for _, tt := range tests {
+		if got := tt.theme.Apply(tt.sev, "boom"); got != tt.want {
+			t.Errorf("Apply(%d) = %q, want %q", tt.sev, got, tt.want)
+		}
+	}
+}
+
+func TestBadge(t *testing.T) {
+	plain := Themes["plain"]
+	if got := plain.Badge(ops.RiskHigh); got != "[approve]" {
+		t.Errorf("Badge(RiskHigh) = %q", got)
+	}
+	var none *Theme
+	if got := none.Badge(ops.RiskHigh); got != "" {
+		t.Errorf("nil theme badge = %q", got)
+	}
+}
+
+func TestLoadConfig(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "style.json")
+	os.WriteFile(path, []byte(`{"theme":"plain","success":"[ok]","risk":{"high":"[!!]"}}`), 0600)
+
+	theme, err := LoadConfig(path)
+	if err != nil {
+		t.Fatalf("load: %v", err)
+	}
+	if theme.Success != "[ok]" || theme.Error != "[error]" {
+		t.Errorf("prefixes = %+v", theme)
+	}
+	if theme.Badge(ops.RiskHigh) != "[!!]" || theme.Badge(ops.RiskLow) != "[totp]" {
+		t.Errorf("risk = %v", theme.Risk)
+	}
+	if Themes["plain"].Risk["high"] != "[approve]" {
+		t.Error("override modified the built-in theme")
+	}
+}
+
+func TestLoadConfigErrors(t *testing.T) {
+	if theme, err := LoadConfig(filepath.Join(t.TempDir(), "missing.json")); theme != nil || err != nil {
+		t.Errorf("missing file = %v, %v", theme, err)
+	}
+	tests := []struct {
+		data string
+		want string
+	}{
+		{`{"theme":"neon"}`, "unknown theme"},
+		{`{"risk":{"extreme":"x"}}`, "unknown risk level"},
+		{`{`, "parse style config"},
+	}
+	for _, tt := range tests {
+		path := filepath.Join(t.TempDir(), "style.json")
+		os.WriteFile(path, []byte(tt.data), 0600)
+		if _, err := LoadConfig(path); err == nil || !strings.Contains(err.Error(), tt.want) {
+			t.Errorf("%s: err = %v, want %q", tt.data, err, tt.want)
+		}
+	}
+}
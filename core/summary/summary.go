@@ -0,0 +1,171 @@
+// Package summary shortens long op output to a few lines for the
+// summarize overflow policy: a rule-based summary worked out locally, or
+// one written by a language model.
+package summary
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"regexp"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+
+	"github.com/jdelaire/openslack/core/limits"
+)
+
+// maxQuoted caps a line quoted in a rule-based summary.
+const maxQuoted = 120
+
+var (
+	errorLine   = regexp.MustCompile(`(?i)\b(error|errors|err|fail|failed|failure|fatal|panic|exception|critical)\b`)
+	warningLine = regexp.MustCompile(`(?i)\b(warn|warning|warnings)\b`)
+)
+
+// stopWords are never what errors are said to mention.
+var stopWords = map[string]bool{
+	"the": true, "and": true, "for": true, "with": true, "from": true, "not": true,
+	"was": true, "were": true, "this": true, "that": true, "into": true, "has": true,
+	"have": true, "are": true, "can": true, "cannot": true, "could": true, "while": true,
+	"when": true, "after": true, "line": true, "errors": true, "error": true, "err": true,
+	"fail": true, "failed": true, "failure": true, "fatal": true, "panic": true,
+	"exception": true, "critical": true,
+}
+
+// Rules summarizes text by counting its lines, errors and warnings, e.g.
+//
+//	142 lines, 3 errors mentioning disk, 1 warning.
+//	First error: write /var/backup: disk quota exceeded
+//	Last line: backup finished with errors
+//
+// A line is an error or a warning when it says so in a word such as
+// "failed" or "warning". The word errors mention is the one most of them
+// share, if two or more do.
+func Rules(text string) string {
+	lines := strings.Split(strings.TrimRight(text, "\n"), "\n")
+	var errs []string
+	warnings := 0
+	for _, line := range lines {
+		switch {
+		case errorLine.MatchString(line):
+			errs = append(errs, line)
+		case warningLine.MatchString(line):
+			warnings++
+		}
+	}
+
+	counts := []string{count(len(lines), "line")}
+	switch {
+	case len(errs) == 0 && warnings == 0:
+		counts = append(counts, "no errors or warnings")
+	case len(errs) > 0:
+		e := count(len(errs), "error")
+		if topic := topic(errs); topic != "" {
+			e += " mentioning " + topic
+		}
+		counts = append(counts, e)
+	}
+	if warnings > 0 {
+		counts = append(counts, count(warnings, "warning"))
+	}
+
+	out := []string{strings.Join(counts, ", ") + "."}
+	if len(errs) > 0 {
+		out = append(out, "First error: "+clip(strings.TrimSpace(errs[0])))
+	}
+	for i := len(lines) - 1; i >= 0; i-- {
+		if last := strings.TrimSpace(lines[i]); last != "" {
+			out = append(out, "Last line: "+clip(last))
+			break
+		}
+	}
+	return strings.Join(out, "\n")
+}
+
+func count(n int, noun string) string {
+	if n == 1 {
+		return "1 " + noun
+	}
+	return fmt.Sprintf("%d %ss", n, noun)
+}
+
+// topic returns the word found in the most of lines, if in two or more.
+// Ties go to the word seen first.
+func topic(lines []string) string {
+	seen := map[string]int{}
+	var order []string
+	for _, line := range lines {
+		inLine := map[string]bool{}
+		for _, w := range strings.FieldsFunc(strings.ToLower(line), func(r rune) bool { return !unicode.IsLetter(r) }) {
+			if utf8.RuneCountInString(w) < 3 || stopWords[w] || inLine[w] {
+				continue
+			}
+			inLine[w] = true
+			if seen[w] == 0 {
+				order = append(order, w)
+			}
+			seen[w]++
+		}
+	}
+	best := ""
+	for _, w := range order {
+		if seen[w] >= 2 && seen[w] > seen[best] {
+			best = w
+		}
+	}
+	return best
+}
+
+// clip shortens a quoted line to maxQuoted bytes on a rune boundary.
+func clip(s string) string {
+	if len(s) <= maxQuoted {
+		return s
+	}
+	end := maxQuoted
+	for end > 0 && !utf8.RuneStart(s[end]) {
+		end--
+	}
+	return s[:end] + "…"
+}
+
+// maxInput caps the output sent to the model; longer output keeps its
+// head and tail.
+const maxInput = 32 << 10
+
+// Completer sends one chat completion request. *assistant.Assistant
+// implements it.
+type Completer interface {
+	Complete(ctx context.Context, system, user string) (string, error)
+}
+
+// LLM has a language model write summaries.
+type LLM struct {
+	model Completer
+}
+
+// NewLLM creates a summarizer that asks model.
+func NewLLM(model Completer) *LLM {
+	return &LLM{model: model}
+}
+
+const llmPrompt = "You summarize the output of commands run by OpenSlack, a bot that runs commands on its owner's machines.\n" +
+	"Reply with at most three short lines of plain text saying what the output shows. " +
+	"Count errors and warnings and say what they mention. Do not repeat the output or give advice."
+
+// Summarize asks the model to summarize the output of op.
+func (l *LLM) Summarize(ctx context.Context, op, text string) (string, error) {
+	user := "Output:\n"
+	if op != "" {
+		user = "Output of /" + op + ":\n"
+	}
+	out, err := l.model.Complete(ctx, llmPrompt, user+limits.Cut(text, maxInput))
+	if err != nil {
+		return "", err
+	}
+	out = strings.TrimSpace(out)
+	if out == "" {
+		return "", errors.New("the model replied with an empty summary")
+	}
+	return out, nil
+}
@@ -0,0 +1,88 @@
+package summary
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+)
+
+func TestRules(t *testing.T) {
+	tests := []struct {
+		name, text, want string
+	}{
+		{
+			"clean",
+			"a\nb\nall done\n",
+			"3 lines, no errors or warnings.\nLast line: all done",
+		},
+		{
+			"errors with a topic",
+			"start\nERROR: disk /dev/sda1 full\nwarning: slow\nwrite failed: disk quota exceeded\nfinished\n",
+			"5 lines, 2 errors mentioning disk, 1 warning.\nFirst error: ERROR: disk /dev/sda1 full\nLast line: finished",
+		},
+		{
+			"errors without a topic",
+			"connection refused: error\nfatal: out of memory\n\n",
+			"2 lines, 2 errors.\nFirst error: connection refused: error\nLast line: fatal: out of memory",
+		},
+		{
+			"one error",
+			"Panic in handler",
+			"1 line, 1 error.\nFirst error: Panic in handler\nLast line: Panic in handler",
+		},
+		{
+			"error inside a word",
+			"errorless run\nterror\n",
+			"2 lines, no errors or warnings.\nLast line: terror",
+		},
+	}
+	for _, tt := range tests {
+		if got := Rules(tt.text); got != tt.want {
+			t.Errorf("%s: Rules = %q, want %q", tt.name, got, tt.want)
+		}
+	}
+
+	long := "error: " + strings.Repeat("é", 100)
+	got := Rules(long)
+	first, _, _ := strings.Cut(strings.TrimPrefix(got, "1 line, 1 error.\nFirst error: "), "\n")
+	if len(first) > maxQuoted+len("…") || !strings.HasSuffix(first, "é…") {
+		t.Errorf("quoted line = %q", first)
+	}
+}
+
+// fakeModel records the last request and replies with reply or err.
+type fakeModel struct {
+	system, user string
+	reply        string
+	err          error
+}
+
+func (f *fakeModel) Complete(_ context.Context, system, user string) (string, error) {
+	f.system, f.user = system, user
+	return f.reply, f.err
+}
+
+func TestLLM(t *testing.T) {
+	model := &fakeModel{reply: "  Backed up 3 hosts; nas failed with a full disk.\n"}
+	text := strings.Repeat("x", 2*maxInput)
+	got, err := NewLLM(model).Summarize(context.Background(), "backup", text)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got != "Backed up 3 hosts; nas failed with a full disk." {
+		t.Errorf("summary = %q", got)
+	}
+	if !strings.HasPrefix(model.user, "Output of /backup:\nxxx") || len(model.user) > maxInput+len("Output of /backup:\n") {
+		t.Errorf("model was sent %d bytes starting %.30q", len(model.user), model.user)
+	}
+	if !strings.Contains(model.system, "at most three short lines") {
+		t.Errorf("system prompt = %q", model.system)
+	}
+
+	for _, m := range []*fakeModel{{reply: " \n"}, {err: errors.New("model down")}} {
+		if _, err := NewLLM(m).Summarize(context.Background(), "", "out"); err == nil {
+			t.Errorf("Summarize with reply %q and error %v succeeded", m.reply, m.err)
+		}
+	}
+}
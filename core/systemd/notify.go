@@ -0,0 +1,129 @@
+// Package systemd lets systemd supervise the daemon: readiness, watchdog
+// pings and a status line for systemctl status, sent over the sd_notify
+// protocol. Everything is a no-op when the daemon is not started by systemd.
+package systemd
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"net"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Notify sends state, newline-separated KEY=VALUE assignments, to the socket
+// in $NOTIFY_SOCKET. It reports false without error when the variable is
+// unset.
+func Notify(state string) (bool, error) {
+	path := os.Getenv("NOTIFY_SOCKET")
+	if path == "" {
+		return false, nil
+	}
+	if strings.HasPrefix(path, "@") {
+		// Abstract namespace socket.
+		path = "\x00" + path[1:]
+	}
+
+	conn, err := net.DialUnix("unixgram", nil, &net.UnixAddr{Name: path, Net: "unixgram"})
+	if err != nil {
+		return false, fmt.Errorf("dial notify socket: %w", err)
+	}
+	defer conn.Close()
+	if _, err := conn.Write([]byte(state)); err != nil {
+		return false, fmt.Errorf("write notify socket: %w", err)
+	}
+	return true, nil
+}
+
+// Ready tells systemd that start-up is complete. It also reports this
+// process as the main PID, so a daemon started by an upgrade takes over
+// supervision from the one it replaces.
+func Ready(status string) error {
+	_, err := Notify(fmt.Sprintf("READY=1\nMAINPID=%d\nSTATUS=%s", os.Getpid(), status))
+	return err
+}
+
+// Stopping tells systemd that the daemon is shutting down.
+func Stopping() error {
+	_, err := Notify("STOPPING=1")
+	return err
+}
+
+// WatchdogInterval returns the watchdog timeout systemd expects pings
+// within, from $WATCHDOG_USEC. ok is false when the watchdog is disabled or
+// meant for another process.
+func WatchdogInterval() (time.Duration, bool) {
+	usec, err := strconv.ParseInt(os.Getenv("WATCHDOG_USEC"), 10, 64)
+	if err != nil || usec <= 0 {
+		return 0, false
+	}
+	if pid := os.Getenv("WATCHDOG_PID"); pid != "" && pid != strconv.Itoa(os.Getpid()) {
+		return 0, false
+	}
+	return time.Duration(usec) * time.Microsecond, true
+}
+
+// Heartbeat pings the systemd watchdog and refreshes the status line.
+type Heartbeat struct {
+	status   func() string
+	interval time.Duration
+	logger   *slog.Logger
+}
+
+// NewHeartbeat creates a heartbeat reporting status() on every ping. It pings
+// at half the watchdog timeout, or every 30s to refresh the status when the
+// watchdog is disabled.
+func NewHeartbeat(status func() string, logger *slog.Logger) *Heartbeat {
+	if logger == nil {
+		logger = slog.Default()
+	}
+	interval := 30 * time.Second
+	if timeout, ok := WatchdogInterval(); ok {
+		interval = timeout / 2
+	}
+	return &Heartbeat{status: status, interval: interval, logger: logger}
+}
+
+// Interval returns the time between pings.
+func (h *Heartbeat) Interval() time.Duration {
+	return h.interval
+}
+
+// Beat pings the watchdog once. Call it from the daemon's main loop, so a
+// hung loop stops the pings and systemd restarts the daemon.
+func (h *Heartbeat) Beat() {
+	state := "STATUS=" + h.status()
+	if _, ok := WatchdogInterval(); ok {
+		state = "WATCHDOG=1\n" + state
+	}
+	if _, err := Notify(state); err != nil {
+		h.logger.Warn("systemd notify failed", "error", err)
+	}
+}
+
+// Run calls Beat every interval until ctx is cancelled.
+func (h *Heartbeat) Run(ctx context.Context) {
+	ticker := time.NewTicker(h.interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			h.Beat()
+		}
+	}
+}
+
+// FormatStatus renders the systemctl status line: running connectors and
+// how long ago Telegram was last polled.
+func FormatStatus(connectorsUp, connectors int, lastPoll, now time.Time) string {
+	poll := "never polled"
+	if !lastPoll.IsZero() {
+		poll = "last poll " + now.Sub(lastPoll).Truncate(time.Second).String() + " ago"
+	}
+	return fmt.Sprintf("%d/%d connectors up, %s", connectorsUp, connectors, poll)
+}
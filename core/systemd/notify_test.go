@@ -0,0 +1,112 @@
+package systemd
+
+import (
+	"io"
+	"log/slog"
+	"net"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+)
+
+// listen sets NOTIFY_SOCKET to a fresh socket and returns it.
+func listen(t *testing.T) *net.UnixConn {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "notify.sock")
+	conn, err := net.ListenUnixgram("unixgram", &net.UnixAddr{Name: path, Net: "unixgram"})
+	if err != nil {
+		t.Fatalf("listen: %v", err)
+	}
+	t.Cleanup(func() { conn.Close() })
+	t.Setenv("NOTIFY_SOCKET", path)
+	return conn
+}
+
+func receive(t *testing.T, conn *net.UnixConn) string {
+	t.Helper()
+	conn.SetReadDeadline(time.Now().Add(2 * time.Second))
+	buf := make([]byte, 4096)
+	n, err := conn.Read(buf)
+	if err != nil {
+		t.Fatalf("read: %v", err)
+	}
+	return string(buf[:n])
+}
+
+func TestNotifyWithoutSocket(t *testing.T) {
+	t.Setenv("NOTIFY_SOCKET", "")
+	sent, err := Notify("READY=1")
+	if sent || err != nil {
+		t.Errorf("Notify() = %v, %v; want no-op", sent, err)
+	}
+}
+
+func TestReady(t *testing.T) {
+	conn := listen(t)
+	if err := Ready("starting"); err != nil {
+		t.Fatalf("ready: %v", err)
+	}
+	want := "READY=1\nMAINPID=" + strconv.Itoa(os.Getpid()) + "\nSTATUS=starting"
+	if got := receive(t, conn); got != want {
+		t.Errorf("got %q, want %q", got, want)
+	}
+}
+
+func TestHeartbeat(t *testing.T) {
+	conn := listen(t)
+	logger := slog.New(slog.NewJSONHandler(io.Discard, nil))
+
+	t.Setenv("WATCHDOG_USEC", "")
+	h := NewHeartbeat(func() string { return "ok" }, logger)
+	h.Beat()
+	if got := receive(t, conn); got != "STATUS=ok" {
+		t.Errorf("without watchdog got %q", got)
+	}
+
+	t.Setenv("WATCHDOG_USEC", "10000000")
+	t.Setenv("WATCHDOG_PID", strconv.Itoa(os.Getpid()))
+	h = NewHeartbeat(func() string { return "ok" }, logger)
+	if h.Interval() != 5*time.Second {
+		t.Errorf("interval = %s, want 5s", h.Interval())
+	}
+	h.Beat()
+	if got := receive(t, conn); got != "WATCHDOG=1\nSTATUS=ok" {
+		t.Errorf("with watchdog got %q", got)
+	}
+}
+
+func TestWatchdogInterval(t *testing.T) {
+	tests := []struct {
+		usec, pid string
+		want      time.Duration
+		ok        bool
+	}{
+		{"", "", 0, false},
+		{"0", "", 0, false},
+		{"bogus", "", 0, false},
+		{"3000000", "", 3 * time.Second, true},
+		{"3000000", strconv.Itoa(os.Getpid()), 3 * time.Second, true},
+		{"3000000", "1", 0, false},
+	}
+	for _, tt := range tests {
+		t.Setenv("WATCHDOG_USEC", tt.usec)
+		t.Setenv("WATCHDOG_PID", tt.pid)
+		got, ok := WatchdogInterval()
+		if got != tt.want || ok != tt.ok {
+			t.Errorf("usec=%q pid=%q: got %s, %v; want %s, %v", tt.usec, tt.pid, got, ok, tt.want, tt.ok)
+		}
+	}
+}
+
+func TestFormatStatus(t *testing.T) {
+	now := time.Date(2026, 3, 1, 10, 0, 0, 0, time.UTC)
+	if got := FormatStatus(2, 3, now.Add(-12500*time.Millisecond), now); got != "2/3 connectors up, last poll 12s ago" {
+		t.Errorf("got %q", got)
+	}
+	if got := FormatStatus(0, 0, time.Time{}, now); !strings.HasSuffix(got, "never polled") {
+		t.Errorf("got %q", got)
+	}
+}
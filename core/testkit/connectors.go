@@ -0,0 +1,119 @@
+package testkit
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/jdelaire/openslack/connectors/sdk"
+	"github.com/jdelaire/openslack/core/connector"
+)
+
+// linkPrefix names links to the test binary that serve a connector.
+const linkPrefix = "testkit-connector-"
+
+// ServeConnectors lets the test binary act as an SDK connector. Call it at
+// the top of TestMain with every connector the tests load: when the
+// binary was started through a link made by LoadConnectors, it serves the
+// named connector on stdin/stdout and exits; otherwise it returns.
+func ServeConnectors(connectors map[string]func() *sdk.Connector) {
+	// Connector names have no dots, so any extension is the binary's own.
+	base := filepath.Base(os.Args[0])
+	name, ok := strings.CutPrefix(strings.TrimSuffix(base, filepath.Ext(base)), linkPrefix)
+	if !ok {
+		return
+	}
+	build, ok := connectors[name]
+	if !ok {
+		fmt.Fprintf(os.Stderr, "testkit: no connector %q\n", name)
+		os.Exit(1)
+	}
+	build().Run()
+	os.Exit(0)
+}
+
+// LoadConnectors writes cfg to a connectors.json and reloads connectors
+// from it, as the daemon does when the file changes. A connector with no
+// exec runs the test binary as the connector of the same name passed to
+// ServeConnectors.
+func (k *Kit) LoadConnectors(cfg connector.Config) {
+	k.t.Helper()
+	conns := make(map[string]connector.ConnectorConfig, len(cfg.Connectors))
+	for name, cc := range cfg.Connectors {
+		if cc.Exec == "" {
+			cc.Exec = k.link(name)
+		}
+		conns[name] = cc
+	}
+	cfg.Connectors = conns
+	if cfg.ScratchDir == "" {
+		cfg.ScratchDir = filepath.Join(k.dir, "connector-data")
+	}
+	k.Reloader.ReloadConnectors(k.writeJSON("connectors.json", cfg))
+}
+
+// LoadCommands writes commandsJSON to a commands.json and reloads shell
+// commands from it.
+func (k *Kit) LoadCommands(commandsJSON string) {
+	k.t.Helper()
+	k.Reloader.ReloadCommands(k.writeFile("commands.json", []byte(commandsJSON)))
+}
+
+func (k *Kit) writeJSON(name string, v any) string {
+	k.t.Helper()
+	data, err := json.Marshal(v)
+	if err != nil {
+		k.t.Fatalf("testkit: marshal %s: %v", name, err)
+	}
+	return k.writeFile(name, data)
+}
+
+func (k *Kit) writeFile(name string, data []byte) string {
+	k.t.Helper()
+	path := filepath.Join(k.dir, name)
+	if err := os.WriteFile(path, data, 0600); err != nil {
+		k.t.Fatalf("testkit: write %s: %v", name, err)
+	}
+	return path
+}
+
+// link returns a link to the test binary named for connector name,
+// copying the binary where links are not allowed.
+func (k *Kit) link(name string) string {
+	k.t.Helper()
+	self, err := os.Executable()
+	if err != nil {
+		k.t.Fatalf("testkit: executable: %v", err)
+	}
+	path := filepath.Join(k.dir, linkPrefix+name+filepath.Ext(self))
+	if _, err := os.Lstat(path); err == nil {
+		return path
+	}
+	if err := os.Symlink(self, path); err == nil {
+		return path
+	}
+	if err := copyFile(self, path); err != nil {
+		k.t.Fatalf("testkit: copy test binary: %v", err)
+	}
+	return path
+}
+
+func copyFile(src, dst string) error {
+	in, err := os.Open(src)
+	if err != nil {
+		return err
+	}
+	defer in.Close()
+	out, err := os.OpenFile(dst, os.O_CREATE|os.O_WRONLY|os.O_EXCL, 0755)
+	if err != nil {
+		return err
+	}
+	if _, err := io.Copy(out, in); err != nil {
+		out.Close()
+		return err
+	}
+	return out.Close()
+}
@@ -0,0 +1,104 @@
+package testkit
+
+import (
+	"context"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/jdelaire/openslack/core"
+)
+
+// Notifier is an in-memory core.Notifier that records what it is sent.
+type Notifier struct {
+	mu      sync.Mutex
+	sent    []core.Notification
+	changed chan struct{} // closed and replaced on every Send
+}
+
+// NewNotifier returns an empty notifier.
+func NewNotifier() *Notifier {
+	return &Notifier{changed: make(chan struct{})}
+}
+
+func (n *Notifier) Name() string { return "testkit" }
+
+func (n *Notifier) Send(_ context.Context, note core.Notification) error {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+	n.sent = append(n.sent, note)
+	close(n.changed)
+	n.changed = make(chan struct{})
+	return nil
+}
+
+// Sent returns every notification so far, oldest first.
+func (n *Notifier) Sent() []core.Notification {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+	return append([]core.Notification(nil), n.sent...)
+}
+
+// Texts returns the text of every notification so far, oldest first.
+func (n *Notifier) Texts() []string {
+	sent := n.Sent()
+	texts := make([]string, len(sent))
+	for i, note := range sent {
+		texts[i] = note.Text
+	}
+	return texts
+}
+
+// Wait returns the first notification containing substr, waiting up to
+// timeout for one to arrive, e.g. a connector event relayed from another
+// goroutine.
+func (n *Notifier) Wait(substr string, timeout time.Duration) (core.Notification, bool) {
+	deadline := time.NewTimer(timeout)
+	defer deadline.Stop()
+	for {
+		n.mu.Lock()
+		for _, note := range n.sent {
+			if strings.Contains(note.Text, substr) {
+				n.mu.Unlock()
+				return note, true
+			}
+		}
+		changed := n.changed
+		n.mu.Unlock()
+
+		select {
+		case <-changed:
+		case <-deadline.C:
+			return core.Notification{}, false
+		}
+	}
+}
+
+// Receiver is a core.Receiver that hands pushed messages to a handler in
+// order, as a chat adapter would.
+type Receiver struct {
+	handler core.MessageHandler
+	queue   chan core.InboundMessage
+}
+
+// NewReceiver returns a receiver delivering to handler.
+func NewReceiver(handler core.MessageHandler) *Receiver {
+	return &Receiver{handler: handler, queue: make(chan core.InboundMessage, 64)}
+}
+
+// Push queues msg for delivery by Start.
+func (r *Receiver) Push(msg core.InboundMessage) {
+	r.queue <- msg
+}
+
+// Start delivers pushed messages until ctx is done.
+func (r *Receiver) Start(ctx context.Context) error {
+	for {
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case msg := <-r.queue:
+			r.handler(msg)
+		}
+	}
+}
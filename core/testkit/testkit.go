@@ -0,0 +1,236 @@
+// Package testkit wires a real dispatcher, ops registry, policy, TOTP,
+// rate limiter, approval store and connector reloader to an in-memory
+// notifier and a scripted chat, so features such as the /do flow or
+// connector reloads can be tested end to end without Telegram.
+//
+// Every component shares the kit's clock, which only moves when the test
+// advances it:
+//
+//	k := testkit.New(t)
+//	k.Registry.Register(&deployOp{})
+//	k.Run(
+//		testkit.Step{Send: "/do deploy api {totp}", Want: "Pending approval"},
+//		testkit.Step{Send: "/approve {nonce} {totp}", Want: "deployed api"},
+//	)
+package testkit
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"log/slog"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/jdelaire/openslack/core"
+	"github.com/jdelaire/openslack/core/approval"
+	"github.com/jdelaire/openslack/core/auth"
+	"github.com/jdelaire/openslack/core/ops"
+	"github.com/jdelaire/openslack/core/policy"
+	"github.com/jdelaire/openslack/core/ratelimit"
+)
+
+// ChatID is the chat the kit's messages come from. It is the only chat
+// the policy allows.
+const ChatID int64 = 1001
+
+// Secret is the base32 TOTP secret the kit verifies codes against.
+const Secret = "JBSWY3DPEHPK3PXP"
+
+// Start is the kit's initial clock reading.
+var Start = time.Date(2026, 1, 5, 9, 0, 0, 0, time.UTC)
+
+// Kit is a wired daemon under test. Its fields are the real components,
+// for assertions and for setup the kit has no helper for.
+type Kit struct {
+	Registry   *ops.Registry
+	Dispatcher *core.Dispatcher
+	Notifier   *Notifier
+	Receiver   *Receiver
+	Policy     *policy.Policy
+	TOTP       *auth.TOTP
+	Limiter    *ratelimit.Limiter
+	Approvals  *approval.Store
+	Reloader   *core.Reloader
+	Logger     *slog.Logger
+
+	t        testing.TB
+	dir      string
+	mu       sync.Mutex
+	now      time.Time
+	updateID int64
+}
+
+// New wires a kit and stops its connectors when the test ends.
+func New(t testing.TB) *Kit {
+	t.Helper()
+	k := &Kit{t: t, dir: t.TempDir(), now: Start}
+	k.Logger = slog.New(slog.NewTextHandler(io.Discard, nil))
+
+	totp, err := auth.New(Secret)
+	if err != nil {
+		t.Fatalf("testkit: totp: %v", err)
+	}
+	k.TOTP = totp.WithClock(k.Now)
+	k.Policy = policy.New([]int64{ChatID}).WithClock(k.Now)
+	k.Limiter = ratelimit.New().WithClock(k.Now)
+	k.Approvals = approval.New().WithClock(k.Now)
+	k.Registry = ops.NewRegistry()
+	k.Notifier = NewNotifier()
+	k.Dispatcher = core.NewDispatcher(k.Policy, k.Registry, k.Notifier, k.Logger).
+		WithSecurity(k.TOTP, k.Limiter, k.Approvals).
+		WithClock(k.Now)
+	k.Receiver = NewReceiver(k.Dispatcher.Handle)
+
+	k.Reloader = core.NewReloader(k.Registry, nil, k.Logger)
+	k.Reloader.SetEventHandler(core.ConnectorEventNotifier(k.Notifier, k.Logger))
+	t.Cleanup(func() {
+		if mgr := k.Reloader.ConnectorManager(); mgr != nil {
+			mgr.Shutdown()
+		}
+	})
+	return k
+}
+
+// Now returns the kit's clock.
+func (k *Kit) Now() time.Time {
+	k.mu.Lock()
+	defer k.mu.Unlock()
+	return k.now
+}
+
+// Advance moves the kit's clock forward by d.
+func (k *Kit) Advance(d time.Duration) {
+	k.mu.Lock()
+	defer k.mu.Unlock()
+	k.now = k.now.Add(d)
+}
+
+// Code returns the TOTP code valid at the kit's clock.
+func (k *Kit) Code() string {
+	return k.TOTP.Code()
+}
+
+// Send delivers text from ChatID to the dispatcher, as if typed in the
+// chat, and returns the notifications sent while it was handled.
+func (k *Kit) Send(text string) []core.Notification {
+	return k.deliver(k.message(text))
+}
+
+// Press delivers a press of the button labelled label on the most recent
+// notification that has one. It fails the test if there is none.
+func (k *Kit) Press(label string) []core.Notification {
+	k.t.Helper()
+	sent := k.Notifier.Sent()
+	for i := len(sent) - 1; i >= 0; i-- {
+		for _, b := range sent[i].Buttons {
+			if b.Text == label {
+				msg := k.message(b.Data)
+				msg.CallbackID = fmt.Sprintf("cb-%d", msg.UpdateID)
+				return k.deliver(msg)
+			}
+		}
+	}
+	k.t.Fatalf("testkit: no button %q to press", label)
+	return nil
+}
+
+func (k *Kit) message(text string) core.InboundMessage {
+	k.mu.Lock()
+	defer k.mu.Unlock()
+	k.updateID++
+	return core.InboundMessage{
+		UpdateID:  k.updateID,
+		MessageID: k.updateID,
+		ChatID:    ChatID,
+		UserID:    ChatID,
+		Text:      text,
+		Timestamp: k.now,
+	}
+}
+
+// deliver hands msg to the dispatcher directly, so replies are in by the
+// time it returns. Receiver.Start is for tests that want an adapter's
+// goroutine instead.
+func (k *Kit) deliver(msg core.InboundMessage) []core.Notification {
+	before := len(k.Notifier.Sent())
+	k.Dispatcher.Handle(msg)
+	return k.Notifier.Sent()[before:]
+}
+
+// Nonce returns the nonce of the most recent pending approval, read from
+// its Approve button, or "" if there is none.
+func (k *Kit) Nonce() string {
+	sent := k.Notifier.Sent()
+	for i := len(sent) - 1; i >= 0; i-- {
+		for _, b := range sent[i].Buttons {
+			if nonce, ok := strings.CutPrefix(b.Data, "/approve "); ok {
+				return nonce
+			}
+		}
+	}
+	return ""
+}
+
+// Step is one scripted chat action.
+type Step struct {
+	Advance time.Duration // moves the clock before the step
+	Send    string        // message text; {totp} and {nonce} are replaced by Code and Nonce
+	Press   string        // label of a button to press instead of sending
+	Want    string        // a reply must contain this; "" checks nothing
+	NoReply bool          // the step must get no reply at all
+}
+
+// Run performs steps in order, reporting each unmet expectation with the
+// step's number and the replies it got.
+func (k *Kit) Run(steps ...Step) {
+	k.t.Helper()
+	for i, s := range steps {
+		k.Advance(s.Advance)
+		var replies []core.Notification
+		label := s.Press
+		if s.Press != "" {
+			replies = k.Press(s.Press)
+		} else {
+			text := strings.NewReplacer("{totp}", k.Code(), "{nonce}", k.Nonce()).Replace(s.Send)
+			label = text
+			replies = k.Send(text)
+		}
+
+		texts := make([]string, len(replies))
+		for j, r := range replies {
+			texts[j] = r.Text
+		}
+		if s.NoReply && len(replies) > 0 {
+			k.t.Errorf("step %d (%s): got replies %q, want none", i+1, label, texts)
+		}
+		if s.Want != "" && !containsAny(texts, s.Want) {
+			k.t.Errorf("step %d (%s): replies %q, want one containing %q", i+1, label, texts, s.Want)
+		}
+	}
+}
+
+func containsAny(texts []string, substr string) bool {
+	for _, t := range texts {
+		if strings.Contains(t, substr) {
+			return true
+		}
+	}
+	return false
+}
+
+// StartReceiver runs Receiver.Start until the test ends.
+func (k *Kit) StartReceiver() {
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		k.Receiver.Start(ctx)
+	}()
+	k.t.Cleanup(func() {
+		cancel()
+		<-done
+	})
+}
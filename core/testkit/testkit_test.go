@@ -0,0 +1,135 @@
+package testkit_test
+
+import (
+	"context"
+	"encoding/json"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/jdelaire/openslack/connectors/sdk"
+	"github.com/jdelaire/openslack/core"
+	"github.com/jdelaire/openslack/core/connector"
+	"github.com/jdelaire/openslack/core/ops"
+	"github.com/jdelaire/openslack/core/testkit"
+)
+
+func TestMain(m *testing.M) {
+	testkit.ServeConnectors(map[string]func() *sdk.Connector{"notes": newNotesConnector})
+	os.Exit(m.Run())
+}
+
+func newNotesConnector() *sdk.Connector {
+	c := sdk.New("notes", "1.0.0")
+	c.Tool("add", func(_ context.Context, args json.RawMessage) (any, error) {
+		return map[string]string{"added": sdk.TextArg(args)}, nil
+	})
+	c.Tool("announce", func(_ context.Context, args json.RawMessage) (any, error) {
+		return map[string]string{}, c.Emit("announce", sdk.TextArg(args), nil)
+	})
+	c.Tool("wipe", func(context.Context, json.RawMessage) (any, error) {
+		return map[string]string{"wiped": "all"}, nil
+	}, sdk.Mutating())
+	return c
+}
+
+type deployOp struct{}
+
+func (deployOp) Name() string        { return "deploy" }
+func (deployOp) Description() string { return "Deploy a service" }
+func (deployOp) Risk() ops.RiskLevel { return ops.RiskHigh }
+func (deployOp) Execute(_ context.Context, args string) (string, error) {
+	return "deployed " + args, nil
+}
+
+func TestDoFlow(t *testing.T) {
+	k := testkit.New(t)
+	k.Registry.Register(deployOp{})
+
+	k.Run(
+		testkit.Step{Send: "/deploy api {totp}", Want: "is a high-risk operation"},
+		testkit.Step{Send: "/do deploy api {totp}", Want: "Pending approval for /deploy"},
+		testkit.Step{Send: "/approve {nonce} 000000", Want: "Invalid TOTP code."},
+		testkit.Step{Send: "/approve {nonce} {totp}", Want: "deployed api"},
+		testkit.Step{Send: "/approve {nonce} {totp}", Want: "Approval failed"},
+	)
+}
+
+func TestDoFlowButtons(t *testing.T) {
+	k := testkit.New(t)
+	k.Registry.Register(deployOp{})
+
+	k.Run(
+		testkit.Step{Send: "/do deploy api {totp}", Want: "Pending approval"},
+		testkit.Step{Press: "Reject", Want: "Rejected /deploy."},
+		testkit.Step{Send: "/do deploy web {totp}", Want: "Pending approval"},
+		testkit.Step{Press: "Approve", Want: "Send /approve"},
+		testkit.Step{Advance: 3 * time.Minute, Send: "/approve {nonce} {totp}", Want: "Approval failed"},
+	)
+}
+
+func TestLockout(t *testing.T) {
+	k := testkit.New(t)
+	k.Registry.Register(deployOp{})
+
+	for range 5 {
+		k.Send("/do deploy api 000000")
+	}
+	k.Run(
+		testkit.Step{Send: "/do deploy api {totp}", Want: "Locked out"},
+		testkit.Step{Advance: 16 * time.Minute, Send: "/do deploy api {totp}", Want: "Pending approval"},
+	)
+}
+
+func TestUnknownChatGetsNoReply(t *testing.T) {
+	k := testkit.New(t)
+	k.Dispatcher.Handle(core.InboundMessage{UpdateID: 99, ChatID: 42, Text: "/help", Timestamp: k.Now()})
+	if sent := k.Notifier.Sent(); len(sent) != 0 {
+		t.Errorf("sent %v to a stranger", sent)
+	}
+}
+
+func TestConnectorReload(t *testing.T) {
+	k := testkit.New(t)
+	k.LoadConnectors(connector.Config{Connectors: map[string]connector.ConnectorConfig{
+		"notes": {Tools: []string{"add", "announce", "wipe"}},
+	}})
+
+	k.Run(
+		testkit.Step{Send: "/notes.add milk {totp}", Want: "added: milk"},
+		testkit.Step{Send: "/notes.wipe {totp}", Want: "is a high-risk operation"},
+		testkit.Step{Send: "/do notes.wipe {totp}", Want: "Pending approval"},
+		testkit.Step{Send: "/approve {nonce} {totp}", Want: "wiped: all"},
+		testkit.Step{Send: "/notes.announce standup {totp}"},
+	)
+	if _, ok := k.Notifier.Wait("[notes] standup", 5*time.Second); !ok {
+		t.Errorf("event not relayed; sent %q", k.Notifier.Texts())
+	}
+
+	k.LoadConnectors(connector.Config{Connectors: map[string]connector.ConnectorConfig{
+		"notes": {Tools: []string{"add"}},
+	}})
+	if k.Registry.Get("notes.wipe") != nil {
+		t.Error("notes.wipe still registered after it was dropped from config")
+	}
+	k.Run(testkit.Step{Send: "/notes.add eggs {totp}", Want: "added: eggs"})
+}
+
+func TestLoadCommands(t *testing.T) {
+	k := testkit.New(t)
+	k.LoadCommands(`[{"name":"hello","description":"say hi","command":"echo hi","risk":"none"}]`)
+	if k.Registry.Get("hello") == nil {
+		t.Fatal("hello not registered")
+	}
+}
+
+func TestReceiver(t *testing.T) {
+	k := testkit.New(t)
+	k.Registry.Register(deployOp{})
+	k.StartReceiver()
+
+	k.Receiver.Push(core.InboundMessage{UpdateID: 1, ChatID: testkit.ChatID, Text: "/do deploy api " + k.Code(), Timestamp: k.Now()})
+	if _, ok := k.Notifier.Wait("Pending approval", 5*time.Second); !ok {
+		t.Errorf("no reply via the receiver; sent %q", k.Notifier.Texts())
+	}
+}
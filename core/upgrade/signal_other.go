@@ -0,0 +1,9 @@
+//go:build !unix
+
+package upgrade
+
+import "os"
+
+// NotifySignal does nothing: upgrades need SIGUSR2, which this platform
+// lacks.
+func NotifySignal(c chan<- os.Signal) {}
@@ -0,0 +1,14 @@
+//go:build unix
+
+package upgrade
+
+import (
+	"os"
+	"os/signal"
+	"syscall"
+)
+
+// NotifySignal relays SIGUSR2, the upgrade signal, to c.
+func NotifySignal(c chan<- os.Signal) {
+	signal.Notify(c, syscall.SIGUSR2)
+}
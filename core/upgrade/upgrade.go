@@ -0,0 +1,173 @@
+// Package upgrade replaces the running daemon with a new binary without
+// dropping requests from local scripts.
+//
+// On the upgrade signal the daemon stops its Telegram receiver, then
+// starts the new binary with Start, passing the socket listener and the
+// receiver offset. The child serves the inherited socket (Inherited),
+// resumes polling from the offset, and calls Ready once all subsystems are
+// up. The parent waits for that with WaitReady, drains in-flight requests
+// and exits. If the child never becomes ready, the parent kills it and
+// resumes polling itself.
+package upgrade
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"os"
+	"os/exec"
+	"strconv"
+	"strings"
+)
+
+// Environment variables describing the handed over state.
+const (
+	EnvListenFD = "OPENSLACK_LISTEN_FD"
+	EnvReadyFD  = "OPENSLACK_READY_FD"
+	EnvOffset   = "OPENSLACK_RECEIVER_OFFSET"
+)
+
+// State is what the running daemon hands to its replacement.
+type State struct {
+	// Listener is the socket listener's descriptor; nil if the socket
+	// server is not running.
+	Listener *os.File
+	// Offset is the next Telegram update ID to fetch.
+	Offset int64
+}
+
+// Child is a replacement daemon started by Start.
+type Child struct {
+	cmd   *exec.Cmd
+	ready *os.File
+	done  chan error
+}
+
+// Start launches binary with args and the current environment, handing
+// over st. The child shares the parent's stdout and stderr.
+func Start(binary string, args []string, st State) (*Child, error) {
+	readyR, readyW, err := os.Pipe()
+	if err != nil {
+		return nil, fmt.Errorf("create ready pipe: %w", err)
+	}
+	defer readyW.Close()
+
+	cmd := exec.Command(binary, args...)
+	cmd.Stdout, cmd.Stderr = os.Stdout, os.Stderr
+	cmd.Env = append(withoutHandover(os.Environ()), fmt.Sprintf("%s=%d", EnvOffset, st.Offset))
+	// ExtraFiles[i] becomes descriptor 3+i in the child.
+	cmd.ExtraFiles = []*os.File{readyW}
+	cmd.Env = append(cmd.Env, EnvReadyFD+"=3")
+	if st.Listener != nil {
+		cmd.ExtraFiles = append(cmd.ExtraFiles, st.Listener)
+		cmd.Env = append(cmd.Env, EnvListenFD+"=4")
+	}
+	if err := cmd.Start(); err != nil {
+		readyR.Close()
+		return nil, fmt.Errorf("start %s: %w", binary, err)
+	}
+
+	c := &Child{cmd: cmd, ready: readyR, done: make(chan error, 1)}
+	go func() { c.done <- cmd.Wait() }()
+	return c, nil
+}
+
+// WaitReady blocks until the child calls Ready. It fails if the child exits
+// or closes the pipe first, or ctx ends.
+func (c *Child) WaitReady(ctx context.Context) error {
+	result := make(chan error, 1)
+	go func() {
+		buf := make([]byte, 1)
+		_, err := c.ready.Read(buf)
+		if errors.Is(err, io.EOF) {
+			err = errors.New("child exited before it was ready")
+		}
+		result <- err
+	}()
+	defer c.ready.Close()
+
+	select {
+	case err := <-result:
+		return err
+	case <-ctx.Done():
+		return fmt.Errorf("waiting for child: %w", ctx.Err())
+	}
+}
+
+// Kill stops a child that failed to become ready.
+func (c *Child) Kill() error {
+	if err := c.cmd.Process.Kill(); err != nil {
+		return err
+	}
+	<-c.done
+	return nil
+}
+
+// Pid returns the child's process ID.
+func (c *Child) Pid() int {
+	return c.cmd.Process.Pid
+}
+
+// Inherited returns the state handed over by the parent daemon. ok is false
+// for a normal start. The listener is nil if none was handed over.
+func Inherited() (ln net.Listener, offset int64, ok bool, err error) {
+	raw, ok := os.LookupEnv(EnvOffset)
+	if !ok {
+		return nil, 0, false, nil
+	}
+	offset, err = strconv.ParseInt(raw, 10, 64)
+	if err != nil {
+		return nil, 0, true, fmt.Errorf("parse %s: %w", EnvOffset, err)
+	}
+
+	if raw, found := os.LookupEnv(EnvListenFD); found {
+		fd, err := strconv.Atoi(raw)
+		if err != nil {
+			return nil, 0, true, fmt.Errorf("parse %s: %w", EnvListenFD, err)
+		}
+		f := os.NewFile(uintptr(fd), "listener")
+		ln, err = net.FileListener(f)
+		f.Close()
+		if err != nil {
+			return nil, 0, true, fmt.Errorf("inherit listener: %w", err)
+		}
+	}
+	return ln, offset, true, nil
+}
+
+// Ready tells the parent daemon that this process has taken over, so it
+// can drain and exit. It does nothing for a normal start.
+func Ready() error {
+	raw, ok := os.LookupEnv(EnvReadyFD)
+	if !ok {
+		return nil
+	}
+	fd, err := strconv.Atoi(raw)
+	if err != nil {
+		return fmt.Errorf("parse %s: %w", EnvReadyFD, err)
+	}
+	f := os.NewFile(uintptr(fd), "ready")
+	defer f.Close()
+	for _, key := range []string{EnvListenFD, EnvReadyFD, EnvOffset} {
+		os.Unsetenv(key)
+	}
+	if _, err := f.Write([]byte{1}); err != nil {
+		return fmt.Errorf("signal ready: %w", err)
+	}
+	return nil
+}
+
+// withoutHandover drops handover variables inherited from an earlier
+// upgrade.
+func withoutHandover(env []string) []string {
+	out := env[:0:0]
+	for _, kv := range env {
+		if strings.HasPrefix(kv, EnvListenFD+"=") || strings.HasPrefix(kv, EnvReadyFD+"=") || strings.HasPrefix(kv, EnvOffset+"=") {
+			continue
+		}
+		out = append(out, kv)
+	}
+	return out
+}
@@ -0,0 +1,104 @@
+package upgrade
+
+import (
+	"context"
+	"net"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+// TestHelperProcess is the replacement daemon started by the tests below.
+func TestHelperProcess(t *testing.T) {
+	mode := os.Getenv("UPGRADE_HELPER")
+	if mode == "" {
+		return
+	}
+	if mode == "crash" {
+		os.Exit(3)
+	}
+
+	ln, offset, ok, err := Inherited()
+	if err != nil || !ok || ln == nil {
+		os.Exit(2)
+	}
+	if err := Ready(); err != nil {
+		os.Exit(2)
+	}
+	conn, err := ln.Accept()
+	if err != nil {
+		os.Exit(2)
+	}
+	conn.Write([]byte(strings.Repeat("x", int(offset))))
+	conn.Close()
+	os.Exit(0)
+}
+
+func startHelper(t *testing.T, mode string, st State) *Child {
+	t.Helper()
+	t.Setenv("UPGRADE_HELPER", mode)
+	c, err := Start(os.Args[0], []string{"-test.run=^TestHelperProcess$"}, st)
+	if err != nil {
+		t.Fatalf("start: %v", err)
+	}
+	return c
+}
+
+func TestHandover(t *testing.T) {
+	sock := filepath.Join(t.TempDir(), "d.sock")
+	ln, err := net.Listen("unix", sock)
+	if err != nil {
+		t.Fatalf("listen: %v", err)
+	}
+	ul := ln.(*net.UnixListener)
+	ul.SetUnlinkOnClose(false)
+	f, err := ul.File()
+	if err != nil {
+		t.Fatalf("file: %v", err)
+	}
+
+	c := startHelper(t, "serve", State{Listener: f, Offset: 4})
+	f.Close()
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	if err := c.WaitReady(ctx); err != nil {
+		t.Fatalf("wait ready: %v", err)
+	}
+	ln.Close()
+
+	conn, err := net.Dial("unix", sock)
+	if err != nil {
+		t.Fatalf("dial after handover: %v", err)
+	}
+	defer conn.Close()
+	conn.SetDeadline(time.Now().Add(5 * time.Second))
+	buf := make([]byte, 16)
+	n, _ := conn.Read(buf)
+	if got := string(buf[:n]); got != "xxxx" {
+		t.Errorf("child replied %q, want offset 4 as \"xxxx\"", got)
+	}
+}
+
+func TestWaitReadyChildExits(t *testing.T) {
+	c := startHelper(t, "crash", State{Offset: 1})
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	err := c.WaitReady(ctx)
+	if err == nil || !strings.Contains(err.Error(), "before it was ready") {
+		t.Fatalf("err = %v, want early exit", err)
+	}
+}
+
+func TestInheritedNormalStart(t *testing.T) {
+	t.Setenv(EnvOffset, "")
+	os.Unsetenv(EnvOffset)
+	ln, _, ok, err := Inherited()
+	if ln != nil || ok || err != nil {
+		t.Errorf("Inherited() = %v, %v, %v; want nothing", ln, ok, err)
+	}
+	if err := Ready(); err != nil {
+		t.Errorf("Ready() = %v on normal start", err)
+	}
+}
@@ -0,0 +1,173 @@
+// Package acks tracks notifications that ask a human to confirm them with
+// /ack, so the script that sent them can wait for the answer.
+package acks
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/jdelaire/openslack/internal/state"
+)
+
+const (
+	stateNamespace = "acks"
+
+	// Retention is how long records are kept before they are pruned.
+	Retention = 7 * 24 * time.Hour
+	maxText   = 80
+)
+
+var ErrNotFound = errors.New("unknown ack id")
+
+// Record is one tracked notification.
+type Record struct {
+	ID      string    `json:"id"`
+	Text    string    `json:"text"`
+	Source  string    `json:"source,omitempty"`
+	Created time.Time `json:"created"`
+	AckedAt time.Time `json:"acked_at,omitempty"`
+}
+
+// Acked reports whether the notification has been acknowledged.
+func (r Record) Acked() bool {
+	return !r.AckedAt.IsZero()
+}
+
+// Tracker stores ack records in the shared state store. IDs are short so
+// they are easy to type in /ack.
+type Tracker struct {
+	store *state.Store
+	now   func() time.Time
+
+	mu sync.Mutex
+}
+
+func NewTracker(store *state.Store) *Tracker {
+	return &Tracker{
+		store: store,
+		now:   time.Now,
+	}
+}
+
+// Track records a notification awaiting acknowledgement and returns its ID.
+func (t *Tracker) Track(text, source string) (string, error) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	t.prune()
+	buf := make([]byte, 3)
+	for range 10 {
+		if _, err := rand.Read(buf); err != nil {
+			return "", err
+		}
+		id := hex.EncodeToString(buf)
+		ok, err := t.store.Get(stateNamespace, id, &Record{})
+		if err != nil {
+			return "", err
+		}
+		if ok {
+			continue
+		}
+		rec := Record{ID: id, Text: truncate(text), Source: source, Created: t.now()}
+		if err := t.store.Put(stateNamespace, id, rec); err != nil {
+			return "", err
+		}
+		return id, nil
+	}
+	return "", fmt.Errorf("could not allocate ack id")
+}
+
+// Ack marks a notification acknowledged. It reports false if it already
+// was, and ErrNotFound for unknown IDs.
+func (t *Tracker) Ack(id string) (bool, error) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	rec, err := t.get(id)
+	if err != nil || rec.Acked() {
+		return false, err
+	}
+	rec.AckedAt = t.now()
+	return true, t.store.Put(stateNamespace, id, rec)
+}
+
+// Status returns the record for id, or ErrNotFound.
+func (t *Tracker) Status(id string) (Record, error) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	return t.get(id)
+}
+
+// AckedAt returns when id was acknowledged, or the zero time if it is
+// still pending. found is false for unknown IDs.
+func (t *Tracker) AckedAt(id string) (time.Time, bool, error) {
+	rec, err := t.Status(id)
+	if errors.Is(err, ErrNotFound) {
+		return time.Time{}, false, nil
+	}
+	return rec.AckedAt, err == nil, err
+}
+
+// Pending returns unacknowledged records, oldest first.
+func (t *Tracker) Pending() ([]Record, error) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	keys, err := t.store.Keys(stateNamespace)
+	if err != nil {
+		return nil, err
+	}
+	var pending []Record
+	for _, k := range keys {
+		var rec Record
+		if _, err := t.store.Get(stateNamespace, k, &rec); err != nil {
+			return nil, err
+		}
+		if !rec.Acked() {
+			pending = append(pending, rec)
+		}
+	}
+	sort.Slice(pending, func(i, j int) bool { return pending[i].Created.Before(pending[j].Created) })
+	return pending, nil
+}
+
+func (t *Tracker) get(id string) (Record, error) {
+	var rec Record
+	ok, err := t.store.Get(stateNamespace, id, &rec)
+	if err != nil {
+		return Record{}, err
+	}
+	if !ok {
+		return Record{}, ErrNotFound
+	}
+	return rec, nil
+}
+
+// prune deletes records older than Retention. Errors are ignored; pruning
+// is retried on the next Track.
+func (t *Tracker) prune() {
+	keys, err := t.store.Keys(stateNamespace)
+	if err != nil {
+		return
+	}
+	cutoff := t.now().Add(-Retention)
+	for _, k := range keys {
+		var rec Record
+		if _, err := t.store.Get(stateNamespace, k, &rec); err == nil && rec.Created.Before(cutoff) {
+			t.store.Delete(stateNamespace, k)
+		}
+	}
+}
+
+func truncate(s string) string {
+	r := []rune(s)
+	if len(r) <= maxText {
+		return s
+	}
+	return string(r[:maxText]) + "…"
+}
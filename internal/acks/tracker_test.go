@@ -0,0 +1,69 @@
+package acks
+
+import (
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/jdelaire/openslack/internal/state"
+)
+
+func TestTrackAndAck(t *testing.T) {
+	tr := NewTracker(state.NewStore(filepath.Join(t.TempDir(), "state.json")))
+	now := time.Date(2026, 3, 1, 8, 0, 0, 0, time.UTC)
+	tr.now = func() time.Time { return now }
+
+	id, err := tr.Track("Deploy finished, please verify "+strings.Repeat("x", 100), "deploy.sh")
+	if err != nil {
+		t.Fatalf("track: %v", err)
+	}
+	if len(id) != 6 {
+		t.Errorf("id = %q, want 6 hex chars", id)
+	}
+
+	rec, err := tr.Status(id)
+	if err != nil || rec.Acked() || rec.Source != "deploy.sh" || !strings.HasSuffix(rec.Text, "…") {
+		t.Fatalf("status = %+v, %v", rec, err)
+	}
+	if pending, _ := tr.Pending(); len(pending) != 1 {
+		t.Errorf("pending = %+v", pending)
+	}
+
+	now = now.Add(time.Minute)
+	if ok, err := tr.Ack(id); !ok || err != nil {
+		t.Fatalf("ack = %v, %v", ok, err)
+	}
+	if ok, err := tr.Ack(id); ok || err != nil {
+		t.Errorf("second ack = %v, %v; want false, nil", ok, err)
+	}
+	rec, _ = tr.Status(id)
+	if !rec.AckedAt.Equal(now) {
+		t.Errorf("acked_at = %v", rec.AckedAt)
+	}
+	if pending, _ := tr.Pending(); len(pending) != 0 {
+		t.Errorf("pending after ack = %+v", pending)
+	}
+
+	if _, err := tr.Ack("zzzzzz"); err != ErrNotFound {
+		t.Errorf("ack unknown = %v", err)
+	}
+	if _, err := tr.Status("zzzzzz"); err != ErrNotFound {
+		t.Errorf("status unknown = %v", err)
+	}
+}
+
+func TestTrackPrunesOldRecords(t *testing.T) {
+	tr := NewTracker(state.NewStore(filepath.Join(t.TempDir(), "state.json")))
+	now := time.Date(2026, 3, 1, 8, 0, 0, 0, time.UTC)
+	tr.now = func() time.Time { return now }
+
+	old, _ := tr.Track("old", "")
+	now = now.Add(Retention + time.Hour)
+	if _, err := tr.Track("new", ""); err != nil {
+		t.Fatalf("track: %v", err)
+	}
+	if _, err := tr.Status(old); err != ErrNotFound {
+		t.Errorf("old record status = %v, want pruned", err)
+	}
+}
@@ -0,0 +1,78 @@
+package backup
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+// Artifact describes the newest file matching a job's artifact pattern.
+type Artifact struct {
+	Path    string
+	Size    int64
+	ModTime time.Time
+}
+
+// latestArtifact returns the most recently modified regular file matching
+// pattern, or nil if nothing matches.
+func latestArtifact(pattern string) (*Artifact, error) {
+	paths, err := filepath.Glob(pattern)
+	if err != nil {
+		return nil, err
+	}
+	var latest *Artifact
+	for _, p := range paths {
+		info, err := os.Stat(p)
+		if err != nil || !info.Mode().IsRegular() {
+			continue
+		}
+		if latest == nil || info.ModTime().After(latest.ModTime) {
+			latest = &Artifact{Path: p, Size: info.Size(), ModTime: info.ModTime()}
+		}
+	}
+	return latest, nil
+}
+
+// verify returns the job's latest artifact and a problem description, or
+// "" if it is fresh and large enough.
+func verify(job Job, now time.Time) (*Artifact, string) {
+	a, err := latestArtifact(job.Artifact)
+	if err != nil {
+		return nil, "check failed: " + err.Error()
+	}
+	if a == nil {
+		return nil, fmt.Sprintf("no artifact matching %s", job.Artifact)
+	}
+	maxAge := time.Duration(job.MaxAgeHours) * time.Hour
+	if age := now.Sub(a.ModTime); age > maxAge {
+		return a, fmt.Sprintf("latest artifact %s is %s old (max %dh)", filepath.Base(a.Path), formatAge(age), job.MaxAgeHours)
+	}
+	if a.Size < job.MinSizeBytes {
+		return a, fmt.Sprintf("latest artifact %s is %s (min %s)", filepath.Base(a.Path), formatSize(a.Size), formatSize(job.MinSizeBytes))
+	}
+	return a, ""
+}
+
+func formatAge(d time.Duration) string {
+	if d >= 48*time.Hour {
+		return fmt.Sprintf("%dd", d/(24*time.Hour))
+	}
+	if d >= time.Hour {
+		return fmt.Sprintf("%dh", d/time.Hour)
+	}
+	return fmt.Sprintf("%dm", d/time.Minute)
+}
+
+func formatSize(n int64) string {
+	const unit = 1024
+	if n < unit {
+		return fmt.Sprintf("%d B", n)
+	}
+	div, exp := int64(unit), 0
+	for m := n / unit; m >= unit; m /= unit {
+		div *= unit
+		exp++
+	}
+	return fmt.Sprintf("%.1f %ciB", float64(n)/float64(div), "KMGTPE"[exp])
+}
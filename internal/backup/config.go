@@ -0,0 +1,108 @@
+// Package backup runs declared backup jobs on demand and verifies on a
+// schedule that each job's latest artifact is fresh and large enough.
+package backup
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// Defaults.
+const (
+	DefaultMaxAgeHours       = 26
+	DefaultTimeoutMin        = 60
+	DefaultVerifyIntervalSec = 3600
+	MinVerifyIntervalSec     = 60
+)
+
+// Config is the top-level backups configuration.
+type Config struct {
+	Jobs              map[string]Job `json:"jobs"`
+	VerifyIntervalSec int            `json:"verify_interval_sec"`
+}
+
+// Job is one backup. Exactly one of Command (run with the platform shell) or
+// Connector (a qualified connector tool such as "nas.snapshot") must be
+// set. Artifact is a path or glob; the newest match is verified.
+type Job struct {
+	Command      string `json:"command"`
+	WorkDir      string `json:"workdir"`
+	Connector    string `json:"connector"`
+	Args         string `json:"args"`
+	Artifact     string `json:"artifact"`
+	MinSizeBytes int64  `json:"min_size_bytes"`
+	MaxAgeHours  int    `json:"max_age_hours"`
+	TimeoutMin   int    `json:"timeout_min"`
+}
+
+// LoadConfig reads and validates a backups config file.
+// Returns nil, nil if the file does not exist.
+func LoadConfig(path string) (*Config, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("read backups config: %w", err)
+	}
+
+	var cfg Config
+	if err := json.Unmarshal(data, &cfg); err != nil {
+		return nil, fmt.Errorf("parse backups config: %w", err)
+	}
+
+	if err := validateConfig(&cfg); err != nil {
+		return nil, err
+	}
+
+	applyDefaults(&cfg)
+	return &cfg, nil
+}
+
+func validateConfig(cfg *Config) error {
+	for name, job := range cfg.Jobs {
+		if name == "" || strings.ContainsAny(name, " \t\n") {
+			return fmt.Errorf("invalid backup job name %q", name)
+		}
+		if (job.Command == "") == (job.Connector == "") {
+			return fmt.Errorf("backup job %q needs exactly one of command or connector", name)
+		}
+		if job.Connector != "" && !strings.Contains(job.Connector, ".") {
+			return fmt.Errorf("backup job %q: connector must be a qualified tool like nas.snapshot", name)
+		}
+		if job.Artifact == "" {
+			return fmt.Errorf("backup job %q missing artifact", name)
+		}
+		if _, err := filepath.Match(job.Artifact, ""); err != nil {
+			return fmt.Errorf("backup job %q: bad artifact pattern: %w", name, err)
+		}
+		if job.MinSizeBytes < 0 || job.MaxAgeHours < 0 || job.TimeoutMin < 0 {
+			return fmt.Errorf("backup job %q: sizes and durations must not be negative", name)
+		}
+	}
+	if cfg.VerifyIntervalSec < 0 {
+		return fmt.Errorf("verify_interval_sec must not be negative")
+	}
+	return nil
+}
+
+func applyDefaults(cfg *Config) {
+	for name, job := range cfg.Jobs {
+		if job.MaxAgeHours == 0 {
+			job.MaxAgeHours = DefaultMaxAgeHours
+		}
+		if job.TimeoutMin == 0 {
+			job.TimeoutMin = DefaultTimeoutMin
+		}
+		cfg.Jobs[name] = job
+	}
+	if cfg.VerifyIntervalSec == 0 {
+		cfg.VerifyIntervalSec = DefaultVerifyIntervalSec
+	}
+	if cfg.VerifyIntervalSec < MinVerifyIntervalSec {
+		cfg.VerifyIntervalSec = MinVerifyIntervalSec
+	}
+}
@@ -0,0 +1,62 @@
+package backup
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestLoadConfigDefaults(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "backups.json")
+	os.WriteFile(path, []byte(`{
+		"jobs": {
+			"db": {"command": "pg_dump app > /backups/db.sql", "artifact": "/backups/db-*.sql", "min_size_bytes": 1024},
+			"nas": {"connector": "nas.snapshot", "artifact": "/mnt/nas/snap-*", "max_age_hours": 170}
+		}
+	}`), 0644)
+
+	cfg, err := LoadConfig(path)
+	if err != nil {
+		t.Fatalf("LoadConfig: %v", err)
+	}
+	if j := cfg.Jobs["db"]; j.MaxAgeHours != DefaultMaxAgeHours || j.TimeoutMin != DefaultTimeoutMin {
+		t.Errorf("db = %+v", j)
+	}
+	if j := cfg.Jobs["nas"]; j.MaxAgeHours != 170 {
+		t.Errorf("nas = %+v", j)
+	}
+	if cfg.VerifyIntervalSec != DefaultVerifyIntervalSec {
+		t.Errorf("verify_interval_sec = %d", cfg.VerifyIntervalSec)
+	}
+}
+
+func TestLoadConfigMissingFile(t *testing.T) {
+	cfg, err := LoadConfig(filepath.Join(t.TempDir(), "missing.json"))
+	if err != nil || cfg != nil {
+		t.Fatalf("LoadConfig = %v, %v; want nil, nil", cfg, err)
+	}
+}
+
+func TestLoadConfigInvalid(t *testing.T) {
+	tests := []struct {
+		json string
+		want string
+	}{
+		{`{"jobs": {"db": {"artifact": "/x"}}}`, "exactly one of command or connector"},
+		{`{"jobs": {"db": {"command": "x", "connector": "a.b", "artifact": "/x"}}}`, "exactly one of command or connector"},
+		{`{"jobs": {"db": {"connector": "nas", "artifact": "/x"}}}`, "qualified tool"},
+		{`{"jobs": {"db": {"command": "x"}}}`, "missing artifact"},
+		{`{"jobs": {"db": {"command": "x", "artifact": "/x/["}}}`, "bad artifact pattern"},
+		{`{"jobs": {"my db": {"command": "x", "artifact": "/x"}}}`, "invalid backup job name"},
+		{`{"jobs": {"db": {"command": "x", "artifact": "/x", "min_size_bytes": -1}}}`, "must not be negative"},
+	}
+	for _, tt := range tests {
+		path := filepath.Join(t.TempDir(), "backups.json")
+		os.WriteFile(path, []byte(tt.json), 0644)
+		_, err := LoadConfig(path)
+		if err == nil || !strings.Contains(err.Error(), tt.want) {
+			t.Errorf("LoadConfig(%s) error = %v, want %q", tt.json, err, tt.want)
+		}
+	}
+}
@@ -0,0 +1,358 @@
+package backup
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/jdelaire/openslack/internal/platform"
+	"github.com/jdelaire/openslack/internal/state"
+)
+
+const (
+	stateNamespace = "backups"
+
+	defaultProgressEvery = 30 * time.Second
+	failureTailLines     = 5
+	maxOutputBytes       = 64 << 10
+)
+
+var (
+	ErrUnknownJob = errors.New("unknown backup job")
+	ErrRunning    = errors.New("backup job already running")
+)
+
+// ToolCaller invokes a qualified connector tool with plain-text args, the
+// same way /<connector>.<tool> does from chat.
+type ToolCaller func(ctx context.Context, tool, args string) (string, error)
+
+// State is the persisted state of one backup job.
+type State struct {
+	Alerting  bool      `json:"alerting"`
+	Problem   string    `json:"problem,omitempty"`
+	LastCheck time.Time `json:"last_check,omitempty"`
+	LastRun   time.Time `json:"last_run,omitempty"`
+	LastError string    `json:"last_error,omitempty"`
+}
+
+// Runner executes backup jobs on request and periodically verifies their
+// artifacts, alerting once when a job goes stale or undersized and again
+// when it recovers.
+type Runner struct {
+	jobs     map[string]Job
+	interval time.Duration
+	store    *state.Store
+	send     func(context.Context, string) error
+	callTool ToolCaller
+	logger   *slog.Logger
+	now      func() time.Time
+
+	progressEvery time.Duration
+
+	mu      sync.Mutex
+	running map[string]bool
+	wg      sync.WaitGroup
+}
+
+func NewRunner(cfg *Config, store *state.Store, send func(context.Context, string) error, logger *slog.Logger) *Runner {
+	if logger == nil {
+		logger = slog.Default()
+	}
+	return &Runner{
+		jobs:          cfg.Jobs,
+		interval:      time.Duration(cfg.VerifyIntervalSec) * time.Second,
+		store:         store,
+		send:          send,
+		logger:        logger,
+		now:           time.Now,
+		progressEvery: defaultProgressEvery,
+		running:       make(map[string]bool),
+	}
+}
+
+// WithConnectors sets how connector-backed jobs are invoked. Without it
+// they fail with an error.
+func (r *Runner) WithConnectors(call ToolCaller) *Runner {
+	r.callTool = call
+	return r
+}
+
+// Names returns the configured job names, sorted.
+func (r *Runner) Names() []string {
+	names := make([]string, 0, len(r.jobs))
+	for name := range r.jobs {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// Run verifies every job's artifact each interval until ctx is cancelled.
+func (r *Runner) Run(ctx context.Context) {
+	ticker := time.NewTicker(r.interval)
+	defer ticker.Stop()
+
+	for {
+		for _, name := range r.Names() {
+			r.runVerify(ctx, name)
+		}
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+func (r *Runner) runVerify(ctx context.Context, name string) {
+	_, problem := verify(r.jobs[name], r.now())
+	if msg := r.record(name, problem); msg != "" {
+		r.notify(ctx, name, msg)
+	}
+}
+
+// record applies a verification result and returns the alert to send, or "".
+func (r *Runner) record(name, problem string) string {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	st := r.load(name)
+	st.LastCheck = r.now()
+
+	var msg string
+	switch {
+	case problem == "" && st.Alerting:
+		msg = fmt.Sprintf("Backup %s: verified OK again", name)
+	case problem != "" && !st.Alerting:
+		msg = fmt.Sprintf("Backup %s: %s\nRun /backup run %s to retry", name, problem, name)
+	}
+	st.Alerting, st.Problem = problem != "", problem
+
+	r.save(name, st)
+	return msg
+}
+
+// Start runs a job in the background, posting progress and the result to
+// chat. The job outlives ctx but keeps its values, so notifications can
+// be threaded under the triggering command. It returns ErrUnknownJob or
+// ErrRunning without starting anything.
+func (r *Runner) Start(ctx context.Context, name string) error {
+	job, ok := r.jobs[name]
+	if !ok {
+		return ErrUnknownJob
+	}
+
+	r.mu.Lock()
+	if r.running[name] {
+		r.mu.Unlock()
+		return ErrRunning
+	}
+	r.running[name] = true
+	r.mu.Unlock()
+
+	r.wg.Add(1)
+	go func() {
+		defer r.wg.Done()
+		defer func() {
+			r.mu.Lock()
+			delete(r.running, name)
+			r.mu.Unlock()
+		}()
+		r.execute(context.WithoutCancel(ctx), name, job)
+	}()
+	return nil
+}
+
+// Wait blocks until all jobs started with Start have finished.
+func (r *Runner) Wait() {
+	r.wg.Wait()
+}
+
+func (r *Runner) execute(parent context.Context, name string, job Job) {
+	ctx, cancel := context.WithTimeout(parent, time.Duration(job.TimeoutMin)*time.Minute)
+	defer cancel()
+
+	start := r.now()
+	var output string
+	var err error
+	if job.Command != "" {
+		output, err = r.runCommand(ctx, name, job)
+	} else {
+		output, err = r.runConnector(ctx, job)
+	}
+	elapsed := r.now().Sub(start).Round(time.Second)
+
+	r.mu.Lock()
+	st := r.load(name)
+	st.LastRun, st.LastError = start, ""
+	if err != nil {
+		st.LastError = err.Error()
+	}
+	r.save(name, st)
+	r.mu.Unlock()
+
+	if err != nil {
+		msg := fmt.Sprintf("Backup %s failed after %s: %s", name, elapsed, err)
+		if tail := lastLines(output, failureTailLines); tail != "" {
+			msg += "\n" + tail
+		}
+		r.notify(parent, name, msg)
+		return
+	}
+
+	a, problem := verify(job, r.now())
+	r.record(name, problem)
+	if problem != "" {
+		r.notify(parent, name, fmt.Sprintf("Backup %s finished in %s but verification failed: %s", name, elapsed, problem))
+		return
+	}
+	r.notify(parent, name, fmt.Sprintf("Backup %s finished in %s\n%s, %s", name, elapsed, filepath.Base(a.Path), formatSize(a.Size)))
+}
+
+// runCommand runs the job's shell command, posting its latest output line
+// every progressEvery while it runs.
+func (r *Runner) runCommand(ctx context.Context, name string, job Job) (string, error) {
+	cmd := platform.ShellCommand(ctx, job.Command)
+	if job.WorkDir != "" {
+		cmd.Dir = job.WorkDir
+	}
+	out := &progressBuffer{}
+	cmd.Stdout, cmd.Stderr = out, out
+	cmd.WaitDelay = 5 * time.Second
+
+	if err := cmd.Start(); err != nil {
+		return "", err
+	}
+
+	done := make(chan error, 1)
+	go func() { done <- cmd.Wait() }()
+
+	ticker := time.NewTicker(r.progressEvery)
+	defer ticker.Stop()
+	for {
+		select {
+		case err := <-done:
+			if ctx.Err() == context.DeadlineExceeded {
+				err = fmt.Errorf("timed out after %dm", job.TimeoutMin)
+			}
+			return out.String(), err
+		case <-ticker.C:
+			if line, ok := out.takeUpdate(); ok {
+				r.notify(ctx, name, fmt.Sprintf("Backup %s: %s", name, line))
+			}
+		}
+	}
+}
+
+func (r *Runner) runConnector(ctx context.Context, job Job) (string, error) {
+	if r.callTool == nil {
+		return "", fmt.Errorf("connectors not available")
+	}
+	return r.callTool(ctx, job.Connector, job.Args)
+}
+
+// Status lists each job with its latest artifact and alert state.
+func (r *Runner) Status() []string {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	now := r.now()
+	var lines []string
+	for _, name := range r.Names() {
+		job := r.jobs[name]
+		line := name
+		if r.running[name] {
+			line += " (running)"
+		}
+		a, problem := verify(job, now)
+		switch {
+		case problem != "":
+			line += ": " + problem
+		default:
+			line += fmt.Sprintf(": OK, %s %s ago, %s", filepath.Base(a.Path), formatAge(now.Sub(a.ModTime)), formatSize(a.Size))
+		}
+		if st := r.load(name); st.LastError != "" {
+			line += fmt.Sprintf("\n  last run failed: %s", st.LastError)
+		}
+		lines = append(lines, line)
+	}
+	return lines
+}
+
+func (r *Runner) notify(ctx context.Context, name, msg string) {
+	sendCtx, cancel := context.WithTimeout(context.WithoutCancel(ctx), 10*time.Second)
+	defer cancel()
+	if err := r.send(sendCtx, msg); err != nil {
+		r.logger.Error("backup: send notification failed", "job", name, "error", err)
+	}
+}
+
+func (r *Runner) load(name string) State {
+	var st State
+	if _, err := r.store.Get(stateNamespace, name, &st); err != nil {
+		r.logger.Error("backup: load state failed", "job", name, "error", err)
+	}
+	return st
+}
+
+func (r *Runner) save(name string, st State) {
+	if err := r.store.Put(stateNamespace, name, st); err != nil {
+		r.logger.Error("backup: save state failed", "job", name, "error", err)
+	}
+}
+
+// progressBuffer keeps the tail of command output and tracks whether a new line has
+// arrived since the last progress update.
+type progressBuffer struct {
+	mu      sync.Mutex
+	buf     bytes.Buffer
+	updated bool
+}
+
+func (p *progressBuffer) Write(b []byte) (int, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.updated = true
+	n, err := p.buf.Write(b)
+	if over := p.buf.Len() - maxOutputBytes; over > 0 {
+		p.buf.Next(over)
+	}
+	return n, err
+}
+
+func (p *progressBuffer) String() string {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return p.buf.String()
+}
+
+// takeUpdate returns the last non-empty output line if output arrived
+// since the previous call.
+func (p *progressBuffer) takeUpdate() (string, bool) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if !p.updated {
+		return "", false
+	}
+	p.updated = false
+	line := lastLines(p.buf.String(), 1)
+	return line, line != ""
+}
+
+func lastLines(s string, n int) string {
+	lines := strings.Split(strings.TrimSpace(strings.ReplaceAll(s, "\r", "\n")), "\n")
+	var kept []string
+	for i := len(lines) - 1; i >= 0 && len(kept) < n; i-- {
+		if l := strings.TrimSpace(lines[i]); l != "" {
+			kept = append([]string{l}, kept...)
+		}
+	}
+	return strings.Join(kept, "\n")
+}
@@ -0,0 +1,179 @@
+package backup
+
+import (
+	"context"
+	"io"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/jdelaire/openslack/internal/state"
+)
+
+type sink struct {
+	mu   sync.Mutex
+	msgs []string
+}
+
+func (s *sink) send(_ context.Context, text string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.msgs = append(s.msgs, text)
+	return nil
+}
+
+func (s *sink) all() []string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return append([]string(nil), s.msgs...)
+}
+
+func newTestRunner(t *testing.T, jobs map[string]Job) (*Runner, *sink) {
+	t.Helper()
+	cfg := &Config{Jobs: jobs}
+	applyDefaults(cfg)
+	s := &sink{}
+	r := NewRunner(cfg, state.NewStore(filepath.Join(t.TempDir(), "state.json")), s.send, slog.New(slog.NewJSONHandler(io.Discard, nil)))
+	return r, s
+}
+
+func TestStartCommandJob(t *testing.T) {
+	dir := t.TempDir()
+	r, s := newTestRunner(t, map[string]Job{
+		"db": {
+			Command:      "echo dumping; sleep 0.3; head -c 2048 /dev/zero > db-1.sql; echo done",
+			WorkDir:      dir,
+			Artifact:     filepath.Join(dir, "db-*.sql"),
+			MinSizeBytes: 1024,
+		},
+	})
+	r.progressEvery = 100 * time.Millisecond
+
+	if err := r.Start(context.Background(), "db"); err != nil {
+		t.Fatalf("start: %v", err)
+	}
+	if err := r.Start(context.Background(), "db"); err != ErrRunning {
+		t.Errorf("second start err = %v, want ErrRunning", err)
+	}
+	r.Wait()
+
+	msgs := s.all()
+	if len(msgs) < 2 {
+		t.Fatalf("messages = %q, want progress and result", msgs)
+	}
+	if !strings.Contains(strings.Join(msgs, "\n"), "Backup db: dumping\n") {
+		t.Errorf("no progress update in %q", msgs)
+	}
+	last := msgs[len(msgs)-1]
+	if !strings.HasPrefix(last, "Backup db finished in") || !strings.HasSuffix(last, "db-1.sql, 2.0 KiB") {
+		t.Errorf("result = %q", last)
+	}
+}
+
+func TestStartFailures(t *testing.T) {
+	dir := t.TempDir()
+	r, s := newTestRunner(t, map[string]Job{
+		"broken": {Command: "echo disk full >&2; exit 3", Artifact: filepath.Join(dir, "x")},
+		"small":  {Command: "echo hi > small.bin", WorkDir: dir, Artifact: filepath.Join(dir, "small.bin"), MinSizeBytes: 1 << 20},
+		"nas":    {Connector: "nas.snapshot", Artifact: filepath.Join(dir, "snap")},
+	})
+
+	if err := r.Start(context.Background(), "nope"); err != ErrUnknownJob {
+		t.Errorf("unknown start err = %v", err)
+	}
+	for _, name := range []string{"broken", "small", "nas"} {
+		if err := r.Start(context.Background(), name); err != nil {
+			t.Fatalf("start %s: %v", name, err)
+		}
+		r.Wait()
+	}
+
+	msgs := strings.Join(s.all(), "\n")
+	for _, want := range []string{
+		"Backup broken failed after",
+		"exit status 3",
+		"disk full",
+		"Backup small finished in",
+		"verification failed: latest artifact small.bin is 3 B (min 1.0 MiB)",
+		"Backup nas failed after 0s: connectors not available",
+	} {
+		if !strings.Contains(msgs, want) {
+			t.Errorf("messages missing %q:\n%s", want, msgs)
+		}
+	}
+}
+
+func TestStartConnectorJob(t *testing.T) {
+	dir := t.TempDir()
+	r, s := newTestRunner(t, map[string]Job{
+		"nas": {Connector: "nas.snapshot", Args: "vol1", Artifact: filepath.Join(dir, "snap-*")},
+	})
+	var gotTool, gotArgs string
+	r.WithConnectors(func(_ context.Context, tool, args string) (string, error) {
+		gotTool, gotArgs = tool, args
+		return "ok", os.WriteFile(filepath.Join(dir, "snap-1"), []byte("data"), 0600)
+	})
+
+	if err := r.Start(context.Background(), "nas"); err != nil {
+		t.Fatalf("start: %v", err)
+	}
+	r.Wait()
+
+	if gotTool != "nas.snapshot" || gotArgs != "vol1" {
+		t.Errorf("called %q %q", gotTool, gotArgs)
+	}
+	if msgs := s.all(); len(msgs) != 1 || !strings.HasSuffix(msgs[0], "snap-1, 4 B") {
+		t.Errorf("messages = %q", msgs)
+	}
+}
+
+func TestVerifyAlertsOnceAndRecovers(t *testing.T) {
+	dir := t.TempDir()
+	artifact := filepath.Join(dir, "db.sql")
+	os.WriteFile(artifact, make([]byte, 100), 0600)
+	modTime := time.Date(2026, 3, 1, 2, 0, 0, 0, time.UTC)
+	os.Chtimes(artifact, modTime, modTime)
+
+	r, s := newTestRunner(t, map[string]Job{"db": {Command: "true", Artifact: artifact}})
+	now := modTime.Add(time.Hour)
+	r.now = func() time.Time { return now }
+	ctx := context.Background()
+
+	r.runVerify(ctx, "db")
+	if len(s.all()) != 0 {
+		t.Fatalf("fresh artifact alerted: %q", s.all())
+	}
+
+	now = modTime.Add(30 * time.Hour)
+	r.runVerify(ctx, "db")
+	r.runVerify(ctx, "db")
+	msgs := s.all()
+	if len(msgs) != 1 || !strings.HasPrefix(msgs[0], "Backup db: latest artifact db.sql is 30h old (max 26h)") {
+		t.Fatalf("messages = %q", msgs)
+	}
+	if status := r.Status(); len(status) != 1 || !strings.Contains(status[0], "30h old") {
+		t.Errorf("status = %q", status)
+	}
+
+	os.Chtimes(artifact, now, now)
+	r.runVerify(ctx, "db")
+	msgs = s.all()
+	if len(msgs) != 2 || msgs[1] != "Backup db: verified OK again" {
+		t.Errorf("messages = %q", msgs)
+	}
+	if status := r.Status(); !strings.HasPrefix(status[0], "db: OK, db.sql 0m ago, 100 B") {
+		t.Errorf("status = %q", status)
+	}
+}
+
+func TestVerifyMissingArtifact(t *testing.T) {
+	r, s := newTestRunner(t, map[string]Job{"db": {Command: "true", Artifact: filepath.Join(t.TempDir(), "*.sql")}})
+	r.runVerify(context.Background(), "db")
+	if msgs := s.all(); len(msgs) != 1 || !strings.Contains(msgs[0], "no artifact matching") {
+		t.Errorf("messages = %q", msgs)
+	}
+}
@@ -0,0 +1,284 @@
+// Package catchup tells the admin chat, on startup, what the daemon missed
+// while it was down: the task reminder it slept through, queued
+// notifications that came due, and scheduled commands that skipped runs.
+package catchup
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"strings"
+	"time"
+
+	"github.com/jdelaire/openslack/internal/every"
+	"github.com/jdelaire/openslack/internal/outbox"
+	"github.com/jdelaire/openslack/internal/state"
+	"github.com/jdelaire/openslack/internal/tasks"
+)
+
+const (
+	stateNamespace = "catchup"
+	aliveKey       = "alive"
+
+	// beatInterval is how often the daemon records that it is up.
+	beatInterval = time.Minute
+	// MinDowntime is the shortest gap worth a summary; restarts and
+	// upgrades are quicker.
+	MinDowntime = 2 * beatInterval
+)
+
+// Catchup records when the daemon was last up and, on the next start,
+// sends one message summarizing what was missed since.
+type Catchup struct {
+	store  *state.Store
+	send   func(context.Context, string) error
+	logger *slog.Logger
+	now    func() time.Time
+
+	tasks  *tasks.TaskService
+	outbox *outbox.Queue
+	every  *every.Service
+}
+
+func New(store *state.Store, send func(context.Context, string) error, logger *slog.Logger) *Catchup {
+	if logger == nil {
+		logger = slog.Default()
+	}
+	return &Catchup{store: store, send: send, logger: logger, now: time.Now}
+}
+
+// WithClock replaces time.Now. A nil now is ignored.
+func (c *Catchup) WithClock(now func() time.Time) *Catchup {
+	if now != nil {
+		c.now = now
+	}
+	return c
+}
+
+// WithTasks sends the daily task reminder late if it fell in the downtime.
+func (c *Catchup) WithTasks(svc *tasks.TaskService) *Catchup {
+	c.tasks = svc
+	return c
+}
+
+// WithOutbox reports queued notifications that came due in the downtime.
+func (c *Catchup) WithOutbox(q *outbox.Queue) *Catchup {
+	c.outbox = q
+	return c
+}
+
+// WithEvery reports scheduled commands that skipped runs in the downtime.
+func (c *Catchup) WithEvery(svc *every.Service) *Catchup {
+	c.every = svc
+	return c
+}
+
+// SendSummary sends the summary to the admin chat, if the daemon was down
+// long enough for one. Call it before starting the task scheduler, outbox
+// and every runner, so it sees what they are about to catch up on.
+func (c *Catchup) SendSummary(ctx context.Context) {
+	msg, err := c.Summary()
+	if err != nil {
+		c.logger.Error("catchup: summarize downtime failed", "error", err)
+		return
+	}
+	if msg == "" {
+		return
+	}
+	sendCtx, cancel := context.WithTimeout(ctx, 10*time.Second)
+	defer cancel()
+	if err := c.send(sendCtx, msg); err != nil {
+		c.logger.Error("catchup: send summary failed", "error", err)
+	}
+}
+
+// Run records that the daemon is up every minute, and once more when ctx
+// is cancelled.
+func (c *Catchup) Run(ctx context.Context) {
+	ticker := time.NewTicker(beatInterval)
+	defer ticker.Stop()
+	for {
+		c.beat()
+		select {
+		case <-ctx.Done():
+			c.beat()
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+func (c *Catchup) beat() {
+	if err := c.store.Put(stateNamespace, aliveKey, c.now()); err != nil {
+		c.logger.Error("catchup: record alive failed", "error", err)
+	}
+}
+
+// Summary returns the "while I was away" message for the downtime since
+// the daemon was last up, or "" if it was not down for MinDowntime or has
+// never run before. A missed task reminder is marked sent.
+func (c *Catchup) Summary() (string, error) {
+	var down time.Time
+	ok, err := c.store.Get(stateNamespace, aliveKey, &down)
+	if err != nil {
+		return "", fmt.Errorf("read last alive: %w", err)
+	}
+	now := c.now()
+	if !ok || now.Sub(down) < MinDowntime {
+		return "", nil
+	}
+
+	var sections []string
+	if c.tasks != nil {
+		section, err := c.missedReminder(down, now)
+		if err != nil {
+			return "", err
+		}
+		sections = append(sections, section)
+	}
+	if c.outbox != nil {
+		section, err := c.dueNotifications(down, now)
+		if err != nil {
+			return "", err
+		}
+		sections = append(sections, section)
+	}
+	if c.every != nil {
+		section, err := c.skippedRuns(down, now)
+		if err != nil {
+			return "", err
+		}
+		sections = append(sections, section)
+	}
+
+	var b strings.Builder
+	fmt.Fprintf(&b, "While I was away (down %s, since %s):", formatDowntime(now.Sub(down)), formatTime(down, now))
+	missed := false
+	for _, s := range sections {
+		if s != "" {
+			b.WriteString("\n\n" + s)
+			missed = true
+		}
+	}
+	if !missed {
+		b.WriteString("\nNothing was missed.")
+	}
+	return b.String(), nil
+}
+
+// missedReminder returns the daily task reminders, flagged late, for each
+// zone whose reminder time fell in the downtime.
+func (c *Catchup) missedReminder(down, now time.Time) (string, error) {
+	zones, err := c.tasks.ReminderZones()
+	if err != nil {
+		return "", fmt.Errorf("list reminder zones: %w", err)
+	}
+	def := c.tasks.Zones().Default()
+	var reminders []string
+	for _, loc := range zones {
+		at := tasks.PreviousReminder(now, loc)
+		if at.Before(down) {
+			continue
+		}
+		day := at.Format("2006-01-02")
+		due, err := c.tasks.PrepareReminder(loc, day)
+		if err != nil {
+			return "", fmt.Errorf("select missed reminder: %w", err)
+		}
+		if len(due) == 0 {
+			continue
+		}
+		title := day
+		if loc.String() != def.String() {
+			title += " (" + loc.String() + ")"
+		}
+		reminders = append(reminders, fmt.Sprintf("Late %s reminder:\n%s", at.Format("15:04"), tasks.FormatReminderMessage(title, due)))
+	}
+	return strings.Join(reminders, "\n\n"), nil
+}
+
+// dueNotifications reports queued notifications that came due in the
+// downtime: those the outbox still delivers, and those too late for it.
+func (c *Catchup) dueNotifications(down, now time.Time) (string, error) {
+	msgs, err := c.outbox.Pending()
+	if err != nil {
+		return "", fmt.Errorf("list queued notifications: %w", err)
+	}
+	late, dropped := 0, 0
+	for _, m := range msgs {
+		switch {
+		case m.SendAt.Before(down) || m.SendAt.After(now):
+		case now.Sub(m.SendAt) > outbox.MaxDelay:
+			dropped++
+		default:
+			late++
+		}
+	}
+	var lines []string
+	if late > 0 {
+		lines = append(lines, fmt.Sprintf("%s came due; delivering now, late.", plural(late, "queued notification")))
+	}
+	if dropped > 0 {
+		lines = append(lines, fmt.Sprintf("%s over %s late will be dropped. See /pending.", plural(dropped, "queued notification"), every.FormatInterval(outbox.MaxDelay)))
+	}
+	return strings.Join(lines, "\n"), nil
+}
+
+// skippedRuns lists scheduled commands that came due in the downtime, with
+// how many runs they skipped. Each runs once on the every runner's next
+// tick rather than once per skipped run.
+func (c *Catchup) skippedRuns(down, now time.Time) (string, error) {
+	due, err := c.every.Due()
+	if err != nil {
+		return "", fmt.Errorf("list due commands: %w", err)
+	}
+	var lines []string
+	for _, j := range due {
+		// A job already overdue before the downtime was deferred, not missed.
+		if j.LastRun.IsZero() || j.LastRun.Add(j.Interval()).Before(down) {
+			continue
+		}
+		skipped := int(now.Sub(j.LastRun) / j.Interval())
+		lines = append(lines, fmt.Sprintf("%s (every %s): %s skipped, running once now.",
+			j.CommandLine(), every.FormatInterval(j.Interval()), plural(skipped, "run")))
+	}
+	if len(lines) == 0 {
+		return "", nil
+	}
+	return "Scheduled commands:\n" + strings.Join(lines, "\n"), nil
+}
+
+func plural(n int, noun string) string {
+	if n == 1 {
+		return "1 " + noun
+	}
+	return fmt.Sprintf("%d %ss", n, noun)
+}
+
+// formatDowntime renders d in days, hours and minutes, e.g. "1d 3h",
+// "3h 12m" or "45m".
+func formatDowntime(d time.Duration) string {
+	d = d.Round(time.Minute)
+	days, hours, mins := int(d/(24*time.Hour)), int(d%(24*time.Hour)/time.Hour), int(d%time.Hour/time.Minute)
+	switch {
+	case days > 0 && hours > 0:
+		return fmt.Sprintf("%dd %dh", days, hours)
+	case days > 0:
+		return fmt.Sprintf("%dd", days)
+	case hours > 0 && mins > 0:
+		return fmt.Sprintf("%dh %dm", hours, mins)
+	case hours > 0:
+		return fmt.Sprintf("%dh", hours)
+	}
+	return fmt.Sprintf("%dm", mins)
+}
+
+// formatTime shows t as a time of day if it is on now's date, else with
+// its date.
+func formatTime(t, now time.Time) string {
+	t, now = t.Local(), now.Local()
+	if t.YearDay() == now.YearDay() && t.Year() == now.Year() {
+		return t.Format("15:04")
+	}
+	return t.Format("01-02 15:04")
+}
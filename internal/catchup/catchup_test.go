@@ -0,0 +1,161 @@
+package catchup
+
+import (
+	"context"
+	"io"
+	"log/slog"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/jdelaire/openslack/internal/every"
+	"github.com/jdelaire/openslack/internal/outbox"
+	"github.com/jdelaire/openslack/internal/state"
+	"github.com/jdelaire/openslack/internal/tasks"
+)
+
+func newTestCatchup(t *testing.T, now *time.Time) (*Catchup, *state.Store) {
+	t.Helper()
+	store := state.NewStore(filepath.Join(t.TempDir(), "state.json"))
+	c := New(store, func(context.Context, string) error { return nil }, slog.New(slog.NewJSONHandler(io.Discard, nil))).
+		WithClock(func() time.Time { return *now })
+	return c, store
+}
+
+func TestSummaryWhileAway(t *testing.T) {
+	// Down from 23:00 to 09:30 the next day, across the 06:00 reminder.
+	down := time.Date(2026, 3, 1, 23, 0, 0, 0, time.Local)
+	now := down
+	c, store := newTestCatchup(t, &now)
+	c.beat()
+
+	svc := tasks.NewTaskService(tasks.NewStore(filepath.Join(t.TempDir(), "tasks.json"))).
+		WithClock(func() time.Time { return down })
+	if _, err := svc.CreateTomorrow("renew passport"); err != nil {
+		t.Fatalf("create task: %v", err)
+	}
+
+	q := outbox.NewQueue(store, nil, nil)
+	for _, at := range []time.Time{down.Add(2 * time.Hour), down.Add(3 * time.Hour), down.Add(-time.Hour), down.Add(24 * time.Hour)} {
+		if _, err := q.Schedule("note", "", at); err != nil {
+			t.Fatalf("schedule: %v", err)
+		}
+	}
+
+	jobs := []every.Job{
+		{ID: 1, Command: "status", IntervalSec: 3600, LastRun: down.Add(-30 * time.Minute)},
+		{ID: 2, Command: "disk", IntervalSec: 86400, LastRun: down.Add(-time.Hour)},
+		{ID: 3, Command: "stuck", IntervalSec: 600, LastRun: down.Add(-time.Hour)},
+	}
+	for _, j := range jobs {
+		if err := store.Put("every", strconv.Itoa(j.ID), j); err != nil {
+			t.Fatalf("put job: %v", err)
+		}
+	}
+	scheduled := every.NewService(store).WithClock(func() time.Time { return now })
+
+	now = down.Add(10*time.Hour + 30*time.Minute)
+	c.WithTasks(svc).WithOutbox(q).WithEvery(scheduled)
+	msg, err := c.Summary()
+	if err != nil {
+		t.Fatalf("summary: %v", err)
+	}
+
+	for _, want := range []string{
+		"While I was away (down 10h 30m, since 03-01 23:00):",
+		"Late 06:00 reminder:\nTasks for 2026-03-02\n1: renew passport",
+		"2 queued notifications came due; delivering now, late.",
+		"/status (every 1h): 11 runs skipped, running once now.",
+	} {
+		if !strings.Contains(msg, want) {
+			t.Errorf("summary missing %q:\n%s", want, msg)
+		}
+	}
+	for _, unwanted := range []string{"/disk", "/stuck", "dropped"} {
+		if strings.Contains(msg, unwanted) {
+			t.Errorf("summary should not mention %q:\n%s", unwanted, msg)
+		}
+	}
+
+	// The late reminder counts as sent, so the scheduler does not repeat it.
+	if due, err := svc.PrepareDailyReminder("2026-03-02"); err != nil || len(due) != 0 {
+		t.Fatalf("reminder still due after catch-up: %v, %v", due, err)
+	}
+}
+
+func TestSummarySkipsShortOrFirstRun(t *testing.T) {
+	now := time.Date(2026, 3, 1, 12, 0, 0, 0, time.Local)
+	c, _ := newTestCatchup(t, &now)
+
+	if msg, err := c.Summary(); err != nil || msg != "" {
+		t.Fatalf("first run: got %q, %v", msg, err)
+	}
+
+	c.beat()
+	now = now.Add(MinDowntime - time.Second)
+	if msg, err := c.Summary(); err != nil || msg != "" {
+		t.Fatalf("quick restart: got %q, %v", msg, err)
+	}
+
+	now = now.Add(time.Hour)
+	msg, err := c.Summary()
+	if err != nil {
+		t.Fatalf("summary: %v", err)
+	}
+	if want := "While I was away (down 1h 2m, since 12:00):\nNothing was missed."; msg != want {
+		t.Fatalf("got %q, want %q", msg, want)
+	}
+}
+
+func TestSummaryReportsDroppedNotifications(t *testing.T) {
+	down := time.Date(2026, 3, 1, 12, 0, 0, 0, time.Local)
+	now := down
+	c, store := newTestCatchup(t, &now)
+	c.beat()
+
+	q := outbox.NewQueue(store, nil, nil)
+	if _, err := q.Schedule("stale", "", down.Add(time.Hour)); err != nil {
+		t.Fatalf("schedule: %v", err)
+	}
+	now = down.Add(3 * 24 * time.Hour)
+	msg, err := c.WithOutbox(q).Summary()
+	if err != nil {
+		t.Fatalf("summary: %v", err)
+	}
+	if want := "1 queued notification over 24h late will be dropped."; !strings.Contains(msg, want) {
+		t.Fatalf("summary missing %q:\n%s", want, msg)
+	}
+}
+
+func TestSendSummaryThenRun(t *testing.T) {
+	down := time.Date(2026, 3, 1, 12, 0, 0, 0, time.Local)
+	now := down
+	c, store := newTestCatchup(t, &now)
+	c.beat()
+	now = down.Add(time.Hour)
+
+	var sent []string
+	c.send = func(_ context.Context, text string) error {
+		sent = append(sent, text)
+		return nil
+	}
+	c.SendSummary(context.Background())
+	if len(sent) != 1 || !strings.HasPrefix(sent[0], "While I was away (down 1h, since 12:00)") {
+		t.Fatalf("sent %q", sent)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	c.Run(ctx)
+
+	var alive time.Time
+	if ok, err := store.Get(stateNamespace, aliveKey, &alive); err != nil || !ok || !alive.Equal(now) {
+		t.Fatalf("alive = %v (%v, %v), want %v", alive, ok, err, now)
+	}
+	c.SendSummary(context.Background())
+	if len(sent) != 1 {
+		t.Fatalf("summary sent again right after startup: %q", sent)
+	}
+}
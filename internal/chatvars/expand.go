@@ -0,0 +1,49 @@
+package chatvars
+
+import (
+	"fmt"
+	"strings"
+)
+
+// UnsetError reports a {name} reference with no value.
+type UnsetError struct {
+	Name string
+}
+
+func (e *UnsetError) Error() string {
+	return fmt.Sprintf("variable {%s} is not set; use /set %s <value>", e.Name, e.Name)
+}
+
+// Expand replaces each {name} in s with its value from vars. Shell-style
+// ${name}, the {} args placeholder, and braces around anything that is not
+// a valid name are left untouched. Referencing an unset variable returns
+// an *UnsetError.
+func Expand(s string, vars map[string]string) (string, error) {
+	var b strings.Builder
+	for {
+		open := strings.IndexByte(s, '{')
+		if open == -1 {
+			b.WriteString(s)
+			return b.String(), nil
+		}
+		end := strings.IndexByte(s[open:], '}')
+		if end == -1 {
+			b.WriteString(s)
+			return b.String(), nil
+		}
+		end += open
+		name := s[open+1 : end]
+		if !ValidName(name) || (open > 0 && s[open-1] == '$') {
+			b.WriteString(s[:open+1])
+			s = s[open+1:]
+			continue
+		}
+		value, ok := vars[name]
+		if !ok {
+			return "", &UnsetError{Name: name}
+		}
+		b.WriteString(s[:open])
+		b.WriteString(value)
+		s = s[end+1:]
+	}
+}
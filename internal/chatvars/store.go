@@ -0,0 +1,102 @@
+// Package chatvars stores per-chat variables set with /set and referenced
+// as {name} in commands, so one command can serve several environments.
+package chatvars
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+	"sync"
+
+	"github.com/jdelaire/openslack/internal/state"
+)
+
+const (
+	stateNamespace = "vars"
+
+	MaxVars     = 50
+	MaxValueLen = 256
+)
+
+var (
+	ErrInvalidName = errors.New("variable names use letters, digits and _ and cannot start with a digit")
+	ErrTooLong     = fmt.Errorf("variable values are limited to %d bytes", MaxValueLen)
+	ErrTooMany     = fmt.Errorf("at most %d variables per chat", MaxVars)
+)
+
+// Store keeps each chat's variables in the shared state store.
+type Store struct {
+	store *state.Store
+	mu    sync.Mutex
+}
+
+func NewStore(store *state.Store) *Store {
+	return &Store{store: store}
+}
+
+// Set assigns name in chatID. An empty value removes the variable.
+func (s *Store) Set(chatID int64, name, value string) error {
+	if !ValidName(name) {
+		return ErrInvalidName
+	}
+	if len(value) > MaxValueLen {
+		return ErrTooLong
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	vars, err := s.load(chatID)
+	if err != nil {
+		return err
+	}
+	if value == "" {
+		delete(vars, name)
+	} else {
+		if _, ok := vars[name]; !ok && len(vars) >= MaxVars {
+			return ErrTooMany
+		}
+		vars[name] = value
+	}
+
+	key := strconv.FormatInt(chatID, 10)
+	if len(vars) == 0 {
+		_, err := s.store.Delete(stateNamespace, key)
+		return err
+	}
+	return s.store.Put(stateNamespace, key, vars)
+}
+
+// All returns a copy of chatID's variables. It never returns a nil map.
+func (s *Store) All(chatID int64) (map[string]string, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.load(chatID)
+}
+
+func (s *Store) load(chatID int64) (map[string]string, error) {
+	vars := map[string]string{}
+	if _, err := s.store.Get(stateNamespace, strconv.FormatInt(chatID, 10), &vars); err != nil {
+		return nil, err
+	}
+	if vars == nil {
+		vars = map[string]string{}
+	}
+	return vars, nil
+}
+
+// ValidName reports whether name can be referenced as {name}.
+func ValidName(name string) bool {
+	if name == "" {
+		return false
+	}
+	for i, c := range name {
+		switch {
+		case c == '_', c >= 'a' && c <= 'z', c >= 'A' && c <= 'Z':
+		case c >= '0' && c <= '9' && i > 0:
+		default:
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,92 @@
+package chatvars
+
+import (
+	"errors"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/jdelaire/openslack/internal/state"
+)
+
+func newTestStore(t *testing.T) *Store {
+	t.Helper()
+	return NewStore(state.NewStore(filepath.Join(t.TempDir(), "state.json")))
+}
+
+func TestStoreSetAndAll(t *testing.T) {
+	s := newTestStore(t)
+
+	if err := s.Set(1, "env", "prod"); err != nil {
+		t.Fatalf("Set: %v", err)
+	}
+	if err := s.Set(2, "env", "staging"); err != nil {
+		t.Fatalf("Set: %v", err)
+	}
+
+	vars, err := s.All(1)
+	if err != nil {
+		t.Fatalf("All: %v", err)
+	}
+	if len(vars) != 1 || vars["env"] != "prod" {
+		t.Errorf("chat 1 vars = %v", vars)
+	}
+
+	if err := s.Set(1, "env", ""); err != nil {
+		t.Fatalf("Set empty: %v", err)
+	}
+	vars, err = s.All(1)
+	if err != nil || vars == nil || len(vars) != 0 {
+		t.Errorf("after clear vars = %v, %v; want empty map", vars, err)
+	}
+	if vars, _ := s.All(2); vars["env"] != "staging" {
+		t.Errorf("chat 2 vars = %v", vars)
+	}
+}
+
+func TestStoreSetInvalid(t *testing.T) {
+	s := newTestStore(t)
+	tests := []struct {
+		name, value string
+		want        error
+	}{
+		{"1env", "x", ErrInvalidName},
+		{"env-name", "x", ErrInvalidName},
+		{"", "x", ErrInvalidName},
+		{"env", strings.Repeat("x", MaxValueLen+1), ErrTooLong},
+	}
+	for _, tt := range tests {
+		if err := s.Set(1, tt.name, tt.value); !errors.Is(err, tt.want) {
+			t.Errorf("Set(%q) = %v, want %v", tt.name, err, tt.want)
+		}
+	}
+}
+
+func TestExpand(t *testing.T) {
+	vars := map[string]string{"env": "prod", "region": "eu"}
+	tests := []struct {
+		in, want string
+		unset    string
+	}{
+		{"deploy {env}", "deploy prod", ""},
+		{"{env}-{region}-{env}", "prod-eu-prod", ""},
+		{"echo ${HOME} {}", "echo ${HOME} {}", ""},
+		{"awk '{print $1}' {1..3} {a,b}", "awk '{print $1}' {1..3} {a,b}", ""},
+		{"{{env}}", "{prod}", ""},
+		{"open { brace", "open { brace", ""},
+		{"deploy {cluster}", "", "cluster"},
+	}
+	for _, tt := range tests {
+		got, err := Expand(tt.in, vars)
+		if tt.unset != "" {
+			var unset *UnsetError
+			if !errors.As(err, &unset) || unset.Name != tt.unset {
+				t.Errorf("Expand(%q) error = %v, want unset %q", tt.in, err, tt.unset)
+			}
+			continue
+		}
+		if err != nil || got != tt.want {
+			t.Errorf("Expand(%q) = %q, %v; want %q", tt.in, got, err, tt.want)
+		}
+	}
+}
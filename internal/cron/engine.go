@@ -0,0 +1,366 @@
+// Package cron runs jobs on cron schedules for the daemon's timed work,
+// such as task reminders. Each job's last run and last error are kept in
+// the shared state store, so a run missed while the daemon was down can
+// be made up on the next start.
+package cron
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"math/rand/v2"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/jdelaire/openslack/internal/state"
+)
+
+const (
+	stateNamespace = "cron"
+
+	// maxWait caps how long the engine sleeps before looking at the clock
+	// again, so a wall clock that jumps, e.g. after the host resumes from
+	// sleep, delays a run by at most this much.
+	maxWait = 10 * time.Minute
+	// maxCatchUp bounds the search for the latest missed run.
+	maxCatchUp = 100000
+)
+
+var (
+	ErrDuplicate = errors.New("a job with that name already exists")
+	ErrNoRun     = errors.New("job has no run function")
+)
+
+// Job is a named unit of work on a cron schedule.
+type Job struct {
+	Name string
+	// Spec is a cron expression; see Parse.
+	Spec string
+	// Location is the zone Spec is read in; nil means the local zone.
+	Location *time.Location
+	// Jitter delays each run by a random amount up to this long, so jobs
+	// on the same schedule do not all start at once.
+	Jitter time.Duration
+	// CatchUp runs the job once on start if a run was missed while the
+	// daemon was down. A job that has never run has nothing to make up.
+	CatchUp bool
+	// Run does the work. at is the scheduled time, which is earlier than
+	// now when the run was missed or jittered.
+	Run func(ctx context.Context, at time.Time) error
+}
+
+// Entry describes a job for listing.
+type Entry struct {
+	Name      string
+	Spec      string
+	Location  *time.Location
+	Next      time.Time // zero if the schedule never matches again
+	LastRun   time.Time // scheduled time of the last run; zero if none
+	LastError string    // from the last run; "" if it succeeded
+	Failures  int       // consecutive failed runs
+	Running   bool
+}
+
+// record is a job's persisted state.
+type record struct {
+	Spec      string    `json:"spec"`
+	Zone      string    `json:"zone"`
+	LastRun   time.Time `json:"last_run"`
+	LastError string    `json:"last_error,omitempty"`
+	Failures  int       `json:"failures,omitempty"`
+}
+
+type job struct {
+	Job
+	sched *Schedule
+	rec   record
+	// at is the next scheduled time, fire when it actually starts, with
+	// jitter.
+	at, fire time.Time
+	running  bool
+}
+
+// Engine runs jobs when their schedules come due.
+type Engine struct {
+	store   *state.Store
+	logger  *slog.Logger
+	now     func() time.Time
+	onError func(name string, err error)
+
+	mu      sync.Mutex
+	jobs    map[string]*job
+	started bool
+	wake    chan struct{}
+	wg      sync.WaitGroup
+}
+
+// New returns an engine that persists job state in store. A nil store
+// keeps it in memory, so nothing is made up after a restart.
+func New(store *state.Store, logger *slog.Logger) *Engine {
+	if logger == nil {
+		logger = slog.Default()
+	}
+	return &Engine{
+		store:  store,
+		logger: logger,
+		now:    time.Now,
+		jobs:   make(map[string]*job),
+		wake:   make(chan struct{}, 1),
+	}
+}
+
+// WithClock replaces time.Now. A nil now is ignored.
+func (e *Engine) WithClock(now func() time.Time) *Engine {
+	if now != nil {
+		e.now = now
+	}
+	return e
+}
+
+// WithErrorHandler calls onError after a job fails, e.g. to alert the
+// admin chat. Failures are always logged and recorded.
+func (e *Engine) WithErrorHandler(onError func(name string, err error)) *Engine {
+	e.onError = onError
+	return e
+}
+
+// Add registers j. It may be called before or while the engine runs.
+func (e *Engine) Add(j Job) error {
+	if j.Run == nil {
+		return ErrNoRun
+	}
+	sched, err := Parse(j.Spec)
+	if err != nil {
+		return err
+	}
+	if j.Location == nil {
+		j.Location = time.Local
+	}
+
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	if _, ok := e.jobs[j.Name]; ok {
+		return fmt.Errorf("cron job %q: %w", j.Name, ErrDuplicate)
+	}
+	jb := &job{Job: j, sched: sched}
+	if e.store != nil {
+		if _, err := e.store.Get(stateNamespace, j.Name, &jb.rec); err != nil {
+			return err
+		}
+	}
+	if e.started {
+		e.scheduleLocked(jb, e.now())
+	}
+	e.jobs[j.Name] = jb
+	e.poke()
+	return nil
+}
+
+// Remove unregisters a job and forgets its state. It reports false if
+// there is no such job.
+func (e *Engine) Remove(name string) (bool, error) {
+	e.mu.Lock()
+	_, ok := e.jobs[name]
+	delete(e.jobs, name)
+	e.mu.Unlock()
+	if !ok {
+		return false, nil
+	}
+	if e.store != nil {
+		if _, err := e.store.Delete(stateNamespace, name); err != nil {
+			return true, err
+		}
+	}
+	return true, nil
+}
+
+// List returns the jobs sorted by next run, those that never run again
+// last.
+func (e *Engine) List() []Entry {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	now := e.now()
+	entries := make([]Entry, 0, len(e.jobs))
+	for _, jb := range e.jobs {
+		next := jb.fire
+		if !e.started {
+			next = jb.sched.Next(now, jb.Location)
+		}
+		entries = append(entries, Entry{
+			Name:      jb.Name,
+			Spec:      jb.Spec,
+			Location:  jb.Location,
+			Next:      next,
+			LastRun:   jb.rec.LastRun,
+			LastError: jb.rec.LastError,
+			Failures:  jb.rec.Failures,
+			Running:   jb.running,
+		})
+	}
+	sort.Slice(entries, func(i, j int) bool {
+		a, b := entries[i], entries[j]
+		if a.Next.IsZero() != b.Next.IsZero() {
+			return b.Next.IsZero()
+		}
+		if !a.Next.Equal(b.Next) {
+			return a.Next.Before(b.Next)
+		}
+		return a.Name < b.Name
+	})
+	return entries
+}
+
+// Run starts due jobs until ctx is cancelled, then waits for running jobs
+// to return. Missed runs of CatchUp jobs start first.
+func (e *Engine) Run(ctx context.Context) {
+	e.start()
+	defer e.wg.Wait()
+
+	for {
+		e.runTick(ctx)
+
+		timer := time.NewTimer(e.wait())
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return
+		case <-e.wake:
+			timer.Stop()
+		case <-timer.C:
+		}
+	}
+}
+
+// start schedules every job: at its latest missed time if it should
+// catch up, else at its next time from now.
+func (e *Engine) start() {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	e.started = true
+	now := e.now()
+	for _, jb := range e.jobs {
+		e.scheduleLocked(jb, now)
+	}
+}
+
+// scheduleLocked sets a newly added job's next run. Callers hold e.mu.
+func (e *Engine) scheduleLocked(jb *job, now time.Time) {
+	if jb.CatchUp && !jb.rec.LastRun.IsZero() {
+		if missed := latestMissed(jb.sched, jb.Location, jb.rec.LastRun, now); !missed.IsZero() {
+			jb.at, jb.fire = missed, now
+			return
+		}
+	}
+	e.advanceLocked(jb, now)
+}
+
+// advanceLocked sets jb's next run to its first scheduled time after
+// from. Callers hold e.mu.
+func (e *Engine) advanceLocked(jb *job, from time.Time) {
+	jb.at = jb.sched.Next(from, jb.Location)
+	jb.fire = jb.at
+	if jb.Jitter > 0 && !jb.at.IsZero() {
+		jb.fire = jb.at.Add(rand.N(jb.Jitter))
+	}
+}
+
+// latestMissed returns the last scheduled time after last and at or
+// before now, or zero if there is none.
+func latestMissed(sched *Schedule, loc *time.Location, last, now time.Time) time.Time {
+	var missed time.Time
+	t := last
+	for range maxCatchUp {
+		t = sched.Next(t, loc)
+		if t.IsZero() || t.After(now) {
+			break
+		}
+		missed = t
+	}
+	return missed
+}
+
+// wait returns how long to sleep until the next job fires.
+func (e *Engine) wait() time.Duration {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	wait := maxWait
+	now := e.now()
+	for _, jb := range e.jobs {
+		if jb.running || jb.fire.IsZero() {
+			continue
+		}
+		if d := jb.fire.Sub(now); d < wait {
+			wait = max(d, 0)
+		}
+	}
+	return wait
+}
+
+// poke wakes Run to recompute its wait.
+func (e *Engine) poke() {
+	select {
+	case e.wake <- struct{}{}:
+	default:
+	}
+}
+
+// runTick starts each job whose time has come and is not still running
+// from its previous time.
+func (e *Engine) runTick(ctx context.Context) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	now := e.now()
+	for _, jb := range e.jobs {
+		if jb.running || jb.fire.IsZero() || jb.fire.After(now) {
+			continue
+		}
+		at := jb.at
+		jb.running = true
+		e.advanceLocked(jb, now)
+		e.wg.Add(1)
+		go e.execute(ctx, jb, at)
+	}
+}
+
+// execute runs one job and records the outcome. A panic counts as a
+// failure.
+func (e *Engine) execute(ctx context.Context, jb *job, at time.Time) {
+	defer e.wg.Done()
+	err := func() (err error) {
+		defer func() {
+			if r := recover(); r != nil {
+				err = fmt.Errorf("panic: %v", r)
+			}
+		}()
+		return jb.Run(ctx, at)
+	}()
+
+	e.mu.Lock()
+	jb.running = false
+	jb.rec.Spec, jb.rec.Zone, jb.rec.LastRun = jb.Spec, jb.Location.String(), at
+	if err != nil {
+		jb.rec.LastError = err.Error()
+		jb.rec.Failures++
+	} else {
+		jb.rec.LastError, jb.rec.Failures = "", 0
+	}
+	rec := jb.rec
+	_, current := e.jobs[jb.Name]
+	e.mu.Unlock()
+	e.poke()
+
+	if err != nil {
+		e.logger.Error("cron job failed", "job", jb.Name, "scheduled", at, "failures", rec.Failures, "error", err)
+		if e.onError != nil {
+			e.onError(jb.Name, err)
+		}
+	}
+	if e.store != nil && current {
+		if err := e.store.Put(stateNamespace, jb.Name, rec); err != nil {
+			e.logger.Error("cron: record run failed", "job", jb.Name, "error", err)
+		}
+	}
+}
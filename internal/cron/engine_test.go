@@ -0,0 +1,256 @@
+package cron
+
+import (
+	"context"
+	"errors"
+	"io"
+	"log/slog"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/jdelaire/openslack/internal/state"
+)
+
+type clock struct {
+	mu sync.Mutex
+	t  time.Time
+}
+
+func (c *clock) now() time.Time {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.t
+}
+
+func (c *clock) set(t time.Time) {
+	c.mu.Lock()
+	c.t = t
+	c.mu.Unlock()
+}
+
+func newTestEngine(t *testing.T, store *state.Store, start time.Time) (*Engine, *clock) {
+	t.Helper()
+	clk := &clock{t: start}
+	e := New(store, slog.New(slog.NewJSONHandler(io.Discard, nil))).WithClock(clk.now)
+	return e, clk
+}
+
+// tick runs due jobs and waits for them to finish.
+func tick(e *Engine) {
+	e.runTick(context.Background())
+	e.wg.Wait()
+}
+
+type runs struct {
+	mu sync.Mutex
+	at []time.Time
+}
+
+func (r *runs) job(err error) func(context.Context, time.Time) error {
+	return func(_ context.Context, at time.Time) error {
+		r.mu.Lock()
+		defer r.mu.Unlock()
+		r.at = append(r.at, at)
+		return err
+	}
+}
+
+func (r *runs) list() []time.Time {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return append([]time.Time(nil), r.at...)
+}
+
+func TestEngineAddValidates(t *testing.T) {
+	e, _ := newTestEngine(t, nil, time.Now())
+	noop := func(context.Context, time.Time) error { return nil }
+	if err := e.Add(Job{Name: "a", Spec: "bad", Run: noop}); err == nil {
+		t.Fatal("expected an error for a bad spec")
+	}
+	if err := e.Add(Job{Name: "a", Spec: "@daily"}); !errors.Is(err, ErrNoRun) {
+		t.Fatalf("no run: %v", err)
+	}
+	if err := e.Add(Job{Name: "a", Spec: "@daily", Run: noop}); err != nil {
+		t.Fatalf("add: %v", err)
+	}
+	if err := e.Add(Job{Name: "a", Spec: "@hourly", Run: noop}); !errors.Is(err, ErrDuplicate) {
+		t.Fatalf("duplicate: %v", err)
+	}
+}
+
+func TestEngineRunsDueJobs(t *testing.T) {
+	start := time.Date(2026, 3, 4, 10, 17, 0, 0, time.UTC)
+	e, clk := newTestEngine(t, nil, start)
+	var quarter, daily runs
+	e.Add(Job{Name: "quarter", Spec: "*/15 * * * *", Location: time.UTC, Run: quarter.job(nil)})
+	e.Add(Job{Name: "daily", Spec: "0 6 * * *", Location: time.UTC, Run: daily.job(nil)})
+	e.start()
+
+	// The quarter-hour job is due in 13 minutes, past maxWait.
+	if got := e.wait(); got != maxWait {
+		t.Fatalf("wait = %v", got)
+	}
+	tick(e)
+	if len(quarter.list()) != 0 {
+		t.Fatal("ran before due")
+	}
+
+	clk.set(start.Add(13 * time.Minute))
+	tick(e)
+	clk.set(start.Add(14 * time.Minute))
+	tick(e)
+	if got := quarter.list(); len(got) != 1 || !got[0].Equal(start.Add(13*time.Minute)) {
+		t.Fatalf("quarter runs = %v", got)
+	}
+	if len(daily.list()) != 0 {
+		t.Fatalf("daily ran early: %v", daily.list())
+	}
+
+	// Asleep for an hour: each job runs once, at its missed time.
+	clk.set(start.Add(73 * time.Minute))
+	tick(e)
+	if got := quarter.list(); len(got) != 2 || !got[1].Equal(start.Add(28*time.Minute)) {
+		t.Fatalf("quarter runs after sleep = %v", got)
+	}
+
+	list := e.List()
+	if len(list) != 2 || list[0].Name != "quarter" || !list[0].Next.Equal(start.Add(88*time.Minute)) {
+		t.Fatalf("list = %+v", list)
+	}
+	if !list[1].Next.Equal(time.Date(2026, 3, 5, 6, 0, 0, 0, time.UTC)) {
+		t.Fatalf("daily next = %v", list[1].Next)
+	}
+}
+
+func TestEngineRecordsFailures(t *testing.T) {
+	store := state.NewStore(filepath.Join(t.TempDir(), "state.json"))
+	start := time.Date(2026, 3, 4, 10, 0, 0, 0, time.UTC)
+	e, clk := newTestEngine(t, store, start)
+	var mu sync.Mutex
+	var failed []string
+	e.WithErrorHandler(func(name string, err error) {
+		mu.Lock()
+		failed = append(failed, name+": "+err.Error())
+		mu.Unlock()
+	})
+
+	var r runs
+	e.Add(Job{Name: "flaky", Spec: "@hourly", Location: time.UTC, Run: r.job(errors.New("boom"))})
+	e.Add(Job{Name: "panicky", Spec: "@hourly", Location: time.UTC, Run: func(context.Context, time.Time) error { panic("oops") }})
+	e.start()
+
+	clk.set(start.Add(time.Hour))
+	tick(e)
+	clk.set(start.Add(2 * time.Hour))
+	tick(e)
+
+	for _, entry := range e.List() {
+		if entry.Failures != 2 || entry.LastError == "" || !entry.LastRun.Equal(start.Add(2*time.Hour)) {
+			t.Fatalf("entry = %+v", entry)
+		}
+	}
+	if len(failed) != 4 {
+		t.Fatalf("error handler calls = %q", failed)
+	}
+	var rec record
+	if ok, err := store.Get(stateNamespace, "panicky", &rec); err != nil || !ok || rec.LastError != "panic: oops" || rec.Zone != "UTC" {
+		t.Fatalf("record = %+v, %v, %v", rec, ok, err)
+	}
+}
+
+func TestEngineCatchesUpAfterRestart(t *testing.T) {
+	store := state.NewStore(filepath.Join(t.TempDir(), "state.json"))
+	start := time.Date(2026, 3, 4, 5, 0, 0, 0, time.UTC)
+	e, clk := newTestEngine(t, store, start)
+	var first runs
+	e.Add(Job{Name: "report", Spec: "0 6 * * *", Location: time.UTC, CatchUp: true, Run: first.job(nil)})
+	e.Add(Job{Name: "cleanup", Spec: "0 6 * * *", Location: time.UTC, Run: first.job(nil)})
+	e.start()
+	clk.set(start.Add(time.Hour))
+	tick(e)
+	if len(first.list()) != 2 {
+		t.Fatalf("first runs = %v", first.list())
+	}
+
+	// Down from before Mar 5 06:00 until Mar 6 09:00.
+	restart := time.Date(2026, 3, 6, 9, 0, 0, 0, time.UTC)
+	e2, _ := newTestEngine(t, store, restart)
+	var report, cleanup runs
+	e2.Add(Job{Name: "report", Spec: "0 6 * * *", Location: time.UTC, CatchUp: true, Run: report.job(nil)})
+	e2.Add(Job{Name: "cleanup", Spec: "0 6 * * *", Location: time.UTC, Run: cleanup.job(nil)})
+	e2.Add(Job{Name: "fresh", Spec: "0 6 * * *", Location: time.UTC, CatchUp: true, Run: cleanup.job(nil)})
+	e2.start()
+	tick(e2)
+	if got := report.list(); len(got) != 1 || !got[0].Equal(time.Date(2026, 3, 6, 6, 0, 0, 0, time.UTC)) {
+		t.Fatalf("report catch-up runs = %v", got)
+	}
+	if got := cleanup.list(); len(got) != 0 {
+		t.Fatalf("jobs without catch-up or history ran: %v", got)
+	}
+	tick(e2)
+	if len(report.list()) != 1 {
+		t.Fatalf("caught up twice: %v", report.list())
+	}
+}
+
+func TestEngineJitter(t *testing.T) {
+	start := time.Date(2026, 3, 4, 10, 0, 0, 0, time.UTC)
+	e, clk := newTestEngine(t, nil, start)
+	var r runs
+	e.Add(Job{Name: "spread", Spec: "@hourly", Location: time.UTC, Jitter: 5 * time.Minute, Run: r.job(nil)})
+	e.start()
+
+	fire := e.List()[0].Next
+	at := start.Add(time.Hour)
+	if fire.Before(at) || !fire.Before(at.Add(5*time.Minute)) {
+		t.Fatalf("fire at %v, want within 5m after %v", fire, at)
+	}
+	clk.set(fire.Add(-time.Second))
+	tick(e)
+	if len(r.list()) != 0 {
+		t.Fatal("ran before its jittered time")
+	}
+	clk.set(fire)
+	tick(e)
+	if got := r.list(); len(got) != 1 || !got[0].Equal(at) {
+		t.Fatalf("runs = %v, want the scheduled time %v", got, at)
+	}
+}
+
+func TestEngineRemove(t *testing.T) {
+	store := state.NewStore(filepath.Join(t.TempDir(), "state.json"))
+	e, _ := newTestEngine(t, store, time.Now())
+	store.Put(stateNamespace, "gone", record{Spec: "@daily"})
+	e.Add(Job{Name: "gone", Spec: "@daily", Run: func(context.Context, time.Time) error { return nil }})
+	if ok, err := e.Remove("gone"); !ok || err != nil {
+		t.Fatalf("remove = %v, %v", ok, err)
+	}
+	if ok, _ := e.Remove("gone"); ok {
+		t.Fatal("removed twice")
+	}
+	if ok, _ := store.Get(stateNamespace, "gone", &record{}); ok {
+		t.Fatal("record kept after remove")
+	}
+	if len(e.List()) != 0 {
+		t.Fatal("job still listed")
+	}
+}
+
+func TestEngineRunStops(t *testing.T) {
+	e, _ := newTestEngine(t, nil, time.Now())
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		e.Run(ctx)
+	}()
+	e.Add(Job{Name: "late", Spec: "@yearly", Run: func(context.Context, time.Time) error { return nil }})
+	cancel()
+	select {
+	case <-done:
+	case <-time.After(2 * time.Second):
+		t.Fatal("Run did not return")
+	}
+}
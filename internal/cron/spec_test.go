@@ -0,0 +1,93 @@
+package cron
+
+import (
+	"testing"
+	"time"
+)
+
+func TestParseErrors(t *testing.T) {
+	for _, spec := range []string{
+		"",
+		"* * * *",
+		"60 * * * *",
+		"* 24 * * *",
+		"* * 0 * *",
+		"* * * 13 *",
+		"* * * * 8",
+		"5-1 * * * *",
+		"*/0 * * * *",
+		"* * * foo *",
+		"@often",
+	} {
+		if _, err := Parse(spec); err == nil {
+			t.Errorf("Parse(%q): expected an error", spec)
+		}
+	}
+}
+
+func TestNext(t *testing.T) {
+	from := time.Date(2026, 3, 4, 10, 17, 30, 0, time.UTC) // a Wednesday
+	tests := []struct {
+		spec string
+		want time.Time
+	}{
+		{"* * * * *", time.Date(2026, 3, 4, 10, 18, 0, 0, time.UTC)},
+		{"*/15 * * * *", time.Date(2026, 3, 4, 10, 30, 0, 0, time.UTC)},
+		{"0 6 * * *", time.Date(2026, 3, 5, 6, 0, 0, 0, time.UTC)},
+		{"@hourly", time.Date(2026, 3, 4, 11, 0, 0, 0, time.UTC)},
+		{"@daily", time.Date(2026, 3, 5, 0, 0, 0, 0, time.UTC)},
+		{"@weekly", time.Date(2026, 3, 8, 0, 0, 0, 0, time.UTC)},
+		{"@monthly", time.Date(2026, 4, 1, 0, 0, 0, 0, time.UTC)},
+		{"0 9 * * mon-fri", time.Date(2026, 3, 5, 9, 0, 0, 0, time.UTC)},
+		{"0 9 * * sat,7", time.Date(2026, 3, 7, 9, 0, 0, 0, time.UTC)},
+		{"30 8 1 jan *", time.Date(2027, 1, 1, 8, 30, 0, 0, time.UTC)},
+		{"0 12 29 2 *", time.Date(2028, 2, 29, 12, 0, 0, 0, time.UTC)},
+		{"5-10/5 10 * * *", time.Date(2026, 3, 5, 10, 5, 0, 0, time.UTC)},
+		// Both day fields restricted: either matches.
+		{"0 0 15 * fri", time.Date(2026, 3, 6, 0, 0, 0, 0, time.UTC)},
+	}
+	for _, tt := range tests {
+		sched, err := Parse(tt.spec)
+		if err != nil {
+			t.Fatalf("Parse(%q): %v", tt.spec, err)
+		}
+		if got := sched.Next(from, time.UTC); !got.Equal(tt.want) {
+			t.Errorf("%q: Next = %v, want %v", tt.spec, got, tt.want)
+		}
+	}
+
+	never, _ := Parse("0 0 30 2 *")
+	if got := never.Next(from, time.UTC); !got.IsZero() {
+		t.Fatalf("Feb 30: Next = %v, want zero", got)
+	}
+}
+
+func TestNextAcrossDST(t *testing.T) {
+	ny, err := time.LoadLocation("America/New_York")
+	if err != nil {
+		t.Skipf("zone unavailable: %v", err)
+	}
+	daily, _ := Parse("0 6 * * *")
+	for _, from := range []time.Time{
+		time.Date(2026, 3, 7, 22, 0, 0, 0, ny),   // spring forward overnight
+		time.Date(2026, 10, 31, 22, 0, 0, 0, ny), // fall back overnight
+	} {
+		got := daily.Next(from, ny)
+		if h, m, _ := got.Clock(); h != 6 || m != 0 || got.YearDay() != from.YearDay()+1 {
+			t.Errorf("Next(%v) = %v, want 06:00 the next day", from, got)
+		}
+	}
+
+	// 02:30 does not exist on the spring-forward day, so it is skipped.
+	gap, _ := Parse("30 2 * * *")
+	if got, want := gap.Next(time.Date(2026, 3, 8, 0, 0, 0, 0, ny), ny), time.Date(2026, 3, 9, 2, 30, 0, 0, ny); !got.Equal(want) {
+		t.Errorf("gap: Next = %v, want %v", got, want)
+	}
+
+	// 01:30 happens twice on the fall-back day; it runs once.
+	repeat, _ := Parse("30 1 * * *")
+	first := repeat.Next(time.Date(2026, 11, 1, 0, 0, 0, 0, ny), ny)
+	if second := repeat.Next(first, ny); second.Day() != 2 {
+		t.Errorf("repeated hour: runs at %v then %v", first, second)
+	}
+}
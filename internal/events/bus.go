@@ -0,0 +1,152 @@
+// Package events is the daemon's internal pub/sub bus. Subsystems publish
+// typed events as things happen (an op completed, a connector crashed, a
+// task was done, a monitor went down) and subscribers, such as config
+// rules, react to them without the publisher knowing who listens.
+package events
+
+import (
+	"context"
+	"log/slog"
+	"sync"
+	"time"
+)
+
+// Topic names a kind of event.
+type Topic string
+
+const (
+	// CommandCompleted is published after every op run, successful or not.
+	// Attrs: op, chat_id, ok ("true" or "false"), error, duration_ms, and
+	// rule when a rule started the run.
+	CommandCompleted Topic = "command.completed"
+	// ConnectorCrashed is published when a connector's process exits or
+	// stops responding and is restarted. Attrs: connector, error.
+	ConnectorCrashed Topic = "connector.crashed"
+	// TaskCompleted is published when a task is marked done. Attrs: id,
+	// text.
+	TaskCompleted Topic = "task.completed"
+	// MonitorDown is published when a monitor goes down. Attrs: monitor,
+	// type, target, host, error, failures.
+	MonitorDown Topic = "monitor.down"
+	// MessageReceived is published for each chat message from an
+	// authorized chat that is not a command. Attrs: chat_id, text.
+	MessageReceived Topic = "message.received"
+)
+
+// Topics lists every topic, in the order documented.
+var Topics = []Topic{CommandCompleted, ConnectorCrashed, TaskCompleted, MonitorDown, MessageReceived}
+
+// Known reports whether t is one of Topics.
+func Known(t Topic) bool {
+	for _, k := range Topics {
+		if k == t {
+			return true
+		}
+	}
+	return false
+}
+
+// Event is one published event. Attrs are flat strings so rules can match
+// them without knowing the publisher's types.
+type Event struct {
+	Topic Topic             `json:"topic"`
+	Time  time.Time         `json:"time"`
+	Attrs map[string]string `json:"attrs,omitempty"`
+}
+
+// subBuffer is how many events a subscriber may fall behind by before
+// further events are dropped for it.
+const subBuffer = 64
+
+// Bus fans out events to subscribers. Publishing never blocks: an event
+// for a subscriber that has fallen behind is dropped and logged, so a
+// stuck rule cannot stall the op or connector that published it. A nil
+// *Bus is valid and discards everything, so publishers need no checks.
+type Bus struct {
+	logger *slog.Logger
+	now    func() time.Time
+
+	mu   sync.Mutex
+	subs map[*subscription]struct{}
+}
+
+type subscription struct {
+	topics map[Topic]bool // nil means every topic
+	ch     chan Event
+}
+
+func NewBus(logger *slog.Logger) *Bus {
+	if logger == nil {
+		logger = slog.Default()
+	}
+	return &Bus{logger: logger, now: time.Now, subs: make(map[*subscription]struct{})}
+}
+
+// WithClock sets the clock events are stamped with.
+func (b *Bus) WithClock(now func() time.Time) *Bus {
+	b.now = now
+	return b
+}
+
+// Publish sends an event on topic to every subscriber of it.
+func (b *Bus) Publish(topic Topic, attrs map[string]string) {
+	if b == nil {
+		return
+	}
+	ev := Event{Topic: topic, Time: b.now(), Attrs: attrs}
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	for s := range b.subs {
+		if s.topics != nil && !s.topics[topic] {
+			continue
+		}
+		select {
+		case s.ch <- ev:
+		default:
+			b.logger.Warn("events: subscriber behind, dropping event", "topic", topic)
+		}
+	}
+}
+
+// Subscribe returns a channel of events on topics, or on every topic if
+// none are given, and a func that cancels the subscription and closes the
+// channel.
+func (b *Bus) Subscribe(topics ...Topic) (<-chan Event, func()) {
+	s := &subscription{ch: make(chan Event, subBuffer)}
+	if len(topics) > 0 {
+		s.topics = make(map[Topic]bool, len(topics))
+		for _, t := range topics {
+			s.topics[t] = true
+		}
+	}
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.subs[s] = struct{}{}
+
+	cancel := func() {
+		b.mu.Lock()
+		defer b.mu.Unlock()
+		if _, ok := b.subs[s]; ok {
+			delete(b.subs, s)
+			close(s.ch)
+		}
+	}
+	return s.ch, cancel
+}
+
+type ruleKey struct{}
+
+// WithRule returns ctx marking work started by the named rule. Publishers
+// with a context copy it into the event's "rule" attr, so rules can ignore
+// events they caused themselves rather than loop.
+func WithRule(ctx context.Context, name string) context.Context {
+	return context.WithValue(ctx, ruleKey{}, name)
+}
+
+// RuleFrom returns the rule set by WithRule, if any.
+func RuleFrom(ctx context.Context) (string, bool) {
+	name, ok := ctx.Value(ruleKey{}).(string)
+	return name, ok
+}
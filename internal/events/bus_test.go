@@ -0,0 +1,66 @@
+package events
+
+import (
+	"context"
+	"io"
+	"log/slog"
+	"testing"
+	"time"
+)
+
+func newTestBus() *Bus {
+	return NewBus(slog.New(slog.NewTextHandler(io.Discard, nil)))
+}
+
+func TestBusTopics(t *testing.T) {
+	now := time.Date(2026, 3, 1, 12, 0, 0, 0, time.UTC)
+	bus := newTestBus().WithClock(func() time.Time { return now })
+	down, cancelDown := bus.Subscribe(MonitorDown)
+	defer cancelDown()
+	all, cancelAll := bus.Subscribe()
+	defer cancelAll()
+
+	bus.Publish(TaskCompleted, map[string]string{"id": "1"})
+	bus.Publish(MonitorDown, map[string]string{"monitor": "nas"})
+
+	if len(down) != 1 {
+		t.Fatalf("monitor.down subscriber got %d events", len(down))
+	}
+	if ev := <-down; ev.Topic != MonitorDown || ev.Attrs["monitor"] != "nas" || !ev.Time.Equal(now) {
+		t.Fatalf("event = %+v", ev)
+	}
+	if len(all) != 2 || (<-all).Topic != TaskCompleted {
+		t.Fatal("catch-all subscriber missed events or got them out of order")
+	}
+}
+
+func TestBusDropsForSlowSubscriber(t *testing.T) {
+	bus := newTestBus()
+	ch, cancel := bus.Subscribe(CommandCompleted)
+	for i := 0; i < subBuffer+10; i++ {
+		bus.Publish(CommandCompleted, nil) // must not block
+	}
+	if len(ch) != subBuffer {
+		t.Fatalf("buffered %d events, want %d", len(ch), subBuffer)
+	}
+
+	cancel()
+	cancel()
+	for range ch {
+	}
+	bus.Publish(CommandCompleted, nil) // no subscribers left
+}
+
+func TestNilBus(t *testing.T) {
+	var bus *Bus
+	bus.Publish(ConnectorCrashed, map[string]string{"connector": "x"})
+}
+
+func TestRuleContext(t *testing.T) {
+	if _, ok := RuleFrom(context.Background()); ok {
+		t.Fatal("rule set on a bare context")
+	}
+	if name, ok := RuleFrom(WithRule(context.Background(), "restart-nas")); !ok || name != "restart-nas" {
+		t.Fatalf("RuleFrom = %q, %v", name, ok)
+	}
+}
@@ -0,0 +1,106 @@
+package every
+
+import (
+	"context"
+	"log/slog"
+	"time"
+
+	"github.com/jdelaire/openslack/internal/textdiff"
+)
+
+const (
+	tickInterval    = 30 * time.Second
+	maxStoredOutput = 16 << 10
+	diffContext     = 2
+	maxDiffLines    = 40
+)
+
+// Exec runs a command on behalf of a chat and returns its output.
+type Exec func(ctx context.Context, chatID int64, command, args string) (string, error)
+
+// Runner runs due jobs and sends their output.
+type Runner struct {
+	service *Service
+	exec    Exec
+	send    func(context.Context, string) error
+	admit   func() bool
+	logger  *slog.Logger
+}
+
+func NewRunner(service *Service, exec Exec, send func(context.Context, string) error, logger *slog.Logger) *Runner {
+	if logger == nil {
+		logger = slog.Default()
+	}
+	return &Runner{
+		service: service,
+		exec:    exec,
+		send:    send,
+		logger:  logger,
+	}
+}
+
+// WithGate defers jobs while admit reports false, e.g. while the daemon is
+// under load. Deferred jobs stay due and run on a later tick.
+func (r *Runner) WithGate(admit func() bool) *Runner {
+	r.admit = admit
+	return r
+}
+
+// Run checks for due jobs every 30 seconds until ctx is cancelled.
+func (r *Runner) Run(ctx context.Context) {
+	ticker := time.NewTicker(tickInterval)
+	defer ticker.Stop()
+
+	for {
+		r.runTick(ctx)
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+func (r *Runner) runTick(ctx context.Context) {
+	due, err := r.service.Due()
+	if err != nil {
+		r.logger.Error("every: list due failed", "error", err)
+		return
+	}
+	for _, j := range due {
+		if r.admit != nil && !r.admit() {
+			r.logger.Debug("every: deferring due jobs under load", "jobs", len(due))
+			return
+		}
+		// Errors are treated as output, so in diff mode a failure and the
+		// recovery are each reported once.
+		output, err := r.exec(ctx, j.ChatID, j.Command, j.Args)
+		if err != nil {
+			r.logger.Warn("every: command failed", "id", j.ID, "cmd", j.Command, "error", err)
+			output = "Error: " + err.Error()
+		}
+		msg, err := r.service.record(j.ID, output)
+		if err != nil {
+			r.logger.Error("every: record run failed", "id", j.ID, "error", err)
+		}
+		if msg == "" {
+			continue
+		}
+		sendCtx, cancel := context.WithTimeout(ctx, 10*time.Second)
+		if err := r.send(sendCtx, msg); err != nil {
+			r.logger.Error("every: send output failed", "id", j.ID, "error", err)
+		}
+		cancel()
+	}
+}
+
+func renderDiff(old, new string) string {
+	return textdiff.Unified(old, new, diffContext, maxDiffLines)
+}
+
+func truncate(s string, n int) string {
+	if len(s) <= n {
+		return s
+	}
+	return s[:n]
+}
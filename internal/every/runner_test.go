@@ -0,0 +1,153 @@
+package every
+
+import (
+	"context"
+	"errors"
+	"io"
+	"log/slog"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/jdelaire/openslack/internal/state"
+)
+
+type everyTest struct {
+	svc    *Service
+	r      *Runner
+	output *string
+	err    *error
+	sent   *[]string
+	now    *time.Time
+}
+
+func newEveryTest(t *testing.T) everyTest {
+	t.Helper()
+	now := time.Date(2026, 3, 1, 10, 0, 0, 0, time.UTC)
+	svc := NewService(state.NewStore(filepath.Join(t.TempDir(), "state.json"))).WithClock(func() time.Time { return now })
+	var output string
+	var execErr error
+	var sent []string
+	exec := func(_ context.Context, chatID int64, command, args string) (string, error) {
+		return output, execErr
+	}
+	r := NewRunner(svc, exec, func(_ context.Context, text string) error {
+		sent = append(sent, text)
+		return nil
+	}, slog.New(slog.NewJSONHandler(io.Discard, nil)))
+	return everyTest{svc: svc, r: r, output: &output, err: &execErr, sent: &sent, now: &now}
+}
+
+func (et everyTest) tick(output string) {
+	*et.output = output
+	et.r.runTick(context.Background())
+	*et.now = et.now.Add(time.Hour)
+}
+
+func TestRunnerReportsEveryRun(t *testing.T) {
+	et := newEveryTest(t)
+	if _, err := et.svc.Add(Job{ChatID: 1, Command: "status", IntervalSec: 3600}); err != nil {
+		t.Fatalf("add: %v", err)
+	}
+
+	et.tick("up")
+	et.tick("up")
+
+	if len(*et.sent) != 2 || (*et.sent)[1] != "/status (every 1h):\nup" {
+		t.Errorf("sent = %q", *et.sent)
+	}
+}
+
+func TestRunnerDiffMode(t *testing.T) {
+	et := newEveryTest(t)
+	if _, err := et.svc.Add(Job{ChatID: 1, Command: "disk", Args: "-h", IntervalSec: 3600, Diff: true}); err != nil {
+		t.Fatalf("add: %v", err)
+	}
+
+	et.tick("/ 40%\n/data 70%")
+	if len(*et.sent) != 1 || !strings.HasPrefix((*et.sent)[0], "/disk -h (every 1h, reporting changes):\n/ 40%") {
+		t.Fatalf("first run sent %q", *et.sent)
+	}
+
+	et.tick("/ 40%\n/data 70%")
+	if len(*et.sent) != 1 {
+		t.Fatalf("unchanged output sent %q", *et.sent)
+	}
+
+	et.tick("/ 40%\n/data 85%")
+	want := "/disk -h changed:\n@@ -1,2 +1,2 @@\n / 40%\n-/data 70%\n+/data 85%"
+	if len(*et.sent) != 2 || (*et.sent)[1] != want {
+		t.Fatalf("sent %q, want %q", *et.sent, want)
+	}
+
+	// A failure is reported once, as is the recovery.
+	*et.err = errors.New("exit status 1")
+	et.tick("")
+	et.tick("")
+	*et.err = nil
+	et.tick("/ 40%\n/data 85%")
+	if len(*et.sent) != 4 {
+		t.Fatalf("sent %d messages, want 4: %q", len(*et.sent), *et.sent)
+	}
+	if !strings.Contains((*et.sent)[2], "+Error: exit status 1") {
+		t.Errorf("failure message = %q", (*et.sent)[2])
+	}
+}
+
+func TestRunnerSkipsJobsNotDue(t *testing.T) {
+	et := newEveryTest(t)
+	et.svc.Add(Job{ChatID: 1, Command: "status", IntervalSec: 7200})
+
+	et.tick("up") // first run
+	et.tick("up") // 1h later: not due
+	et.tick("up") // 2h later: due
+	if len(*et.sent) != 2 {
+		t.Errorf("sent %d messages, want 2", len(*et.sent))
+	}
+}
+
+func TestRunnerDefersUnderLoad(t *testing.T) {
+	et := newEveryTest(t)
+	et.svc.Add(Job{ChatID: 1, Command: "status", IntervalSec: 60})
+	busy := true
+	et.r.WithGate(func() bool { return !busy })
+
+	et.tick("up")
+	if len(*et.sent) != 0 {
+		t.Fatalf("ran under load: %q", *et.sent)
+	}
+	busy = false
+	et.tick("up")
+	if len(*et.sent) != 1 {
+		t.Errorf("deferred job sent %d messages once load eased, want 1", len(*et.sent))
+	}
+}
+
+func TestServiceAddRemove(t *testing.T) {
+	et := newEveryTest(t)
+
+	if _, err := et.svc.Add(Job{ChatID: 1, Command: "status", IntervalSec: 30}); !errors.Is(err, ErrTooFrequent) {
+		t.Errorf("30s interval err = %v, want ErrTooFrequent", err)
+	}
+	if _, err := et.svc.Add(Job{ChatID: 1, IntervalSec: 60}); !errors.Is(err, ErrNoCommand) {
+		t.Errorf("no command err = %v, want ErrNoCommand", err)
+	}
+
+	j, err := et.svc.Add(Job{ChatID: 1, Command: "status", IntervalSec: 60})
+	if err != nil {
+		t.Fatalf("add: %v", err)
+	}
+	if ok, _ := et.svc.Remove(2, j.ID); ok {
+		t.Error("another chat removed the job")
+	}
+	if jobs, _ := et.svc.List(2); len(jobs) != 0 {
+		t.Errorf("chat 2 jobs = %v", jobs)
+	}
+	if ok, err := et.svc.Remove(1, j.ID); !ok || err != nil {
+		t.Errorf("remove = %v, %v", ok, err)
+	}
+	if jobs, _ := et.svc.List(1); len(jobs) != 0 {
+		t.Errorf("jobs after remove = %v", jobs)
+	}
+}
@@ -0,0 +1,230 @@
+// Package every runs chat commands on an interval, optionally reporting
+// only when their output changes.
+package every
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/jdelaire/openslack/internal/state"
+)
+
+const (
+	jobNamespace  = "every"
+	metaNamespace = "every.meta"
+
+	MinInterval = time.Minute
+	MaxJobs     = 20
+)
+
+var (
+	ErrTooFrequent = fmt.Errorf("interval must be at least %s", MinInterval)
+	ErrTooMany     = fmt.Errorf("at most %d scheduled commands", MaxJobs)
+	ErrNoCommand   = errors.New("missing command")
+)
+
+// Job is a persisted scheduled command. In diff mode only changed output
+// is reported, as a unified diff against the previous run.
+type Job struct {
+	ID          int       `json:"id"`
+	ChatID      int64     `json:"chat_id"`
+	Command     string    `json:"command"`
+	Args        string    `json:"args,omitempty"`
+	IntervalSec int       `json:"interval_sec"`
+	Diff        bool      `json:"diff,omitempty"`
+	LastRun     time.Time `json:"last_run,omitempty"`
+	LastOutput  string    `json:"last_output,omitempty"`
+	HasOutput   bool      `json:"has_output,omitempty"`
+}
+
+// Interval returns the run interval.
+func (j Job) Interval() time.Duration {
+	return time.Duration(j.IntervalSec) * time.Second
+}
+
+// CommandLine returns the command as typed, e.g. "/status -v".
+func (j Job) CommandLine() string {
+	return strings.TrimSpace("/" + j.Command + " " + j.Args)
+}
+
+// String formats a job for /every.
+func (j Job) String() string {
+	s := fmt.Sprintf("#%d every %s: %s", j.ID, FormatInterval(j.Interval()), j.CommandLine())
+	if j.Diff {
+		s += " (diff)"
+	}
+	if !j.LastRun.IsZero() {
+		s += ", last run " + j.LastRun.Format("01-02 15:04")
+	}
+	return s
+}
+
+// Service manages jobs in the shared state store.
+type Service struct {
+	store *state.Store
+	now   func() time.Time
+	mu    sync.Mutex
+}
+
+func NewService(store *state.Store) *Service {
+	return &Service{
+		store: store,
+		now:   time.Now,
+	}
+}
+
+func (s *Service) WithClock(now func() time.Time) *Service {
+	if now != nil {
+		s.now = now
+	}
+	return s
+}
+
+// Add validates j, assigns it an ID, and stores it. The first run happens
+// on the next check.
+func (s *Service) Add(j Job) (Job, error) {
+	if j.Command == "" {
+		return Job{}, ErrNoCommand
+	}
+	if j.Interval() < MinInterval {
+		return Job{}, ErrTooFrequent
+	}
+	j.LastRun, j.LastOutput, j.HasOutput = time.Time{}, "", false
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	keys, err := s.store.Keys(jobNamespace)
+	if err != nil {
+		return Job{}, err
+	}
+	if len(keys) >= MaxJobs {
+		return Job{}, ErrTooMany
+	}
+
+	next := 1
+	if _, err := s.store.Get(metaNamespace, "next_id", &next); err != nil {
+		return Job{}, err
+	}
+	j.ID = next
+	if err := s.store.Put(jobNamespace, strconv.Itoa(j.ID), j); err != nil {
+		return Job{}, err
+	}
+	if err := s.store.Put(metaNamespace, "next_id", next+1); err != nil {
+		return Job{}, err
+	}
+	return j, nil
+}
+
+// Remove deletes chatID's job. It reports false if the chat has no such
+// job.
+func (s *Service) Remove(chatID int64, id int) (bool, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	key := strconv.Itoa(id)
+	var j Job
+	ok, err := s.store.Get(jobNamespace, key, &j)
+	if err != nil || !ok || j.ChatID != chatID {
+		return false, err
+	}
+	return s.store.Delete(jobNamespace, key)
+}
+
+// List returns chatID's jobs sorted by ID.
+func (s *Service) List(chatID int64) ([]Job, error) {
+	all, err := s.all()
+	if err != nil {
+		return nil, err
+	}
+	var jobs []Job
+	for _, j := range all {
+		if j.ChatID == chatID {
+			jobs = append(jobs, j)
+		}
+	}
+	return jobs, nil
+}
+
+// Due returns jobs whose interval has elapsed since their last run.
+func (s *Service) Due() ([]Job, error) {
+	all, err := s.all()
+	if err != nil {
+		return nil, err
+	}
+	now := s.now()
+	var due []Job
+	for _, j := range all {
+		if j.LastRun.IsZero() || !now.Before(j.LastRun.Add(j.Interval())) {
+			due = append(due, j)
+		}
+	}
+	return due, nil
+}
+
+func (s *Service) all() ([]Job, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	keys, err := s.store.Keys(jobNamespace)
+	if err != nil {
+		return nil, err
+	}
+	jobs := make([]Job, 0, len(keys))
+	for _, k := range keys {
+		var j Job
+		if _, err := s.store.Get(jobNamespace, k, &j); err != nil {
+			return nil, err
+		}
+		jobs = append(jobs, j)
+	}
+	sort.Slice(jobs, func(a, b int) bool { return jobs[a].ID < jobs[b].ID })
+	return jobs, nil
+}
+
+// record stores a run's output and returns the message to send, or "".
+// Outside diff mode every run is reported. In diff mode the first run is
+// reported in full and later runs only when the output changed.
+func (s *Service) record(id int, output string) (string, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	key := strconv.Itoa(id)
+	var j Job
+	ok, err := s.store.Get(jobNamespace, key, &j)
+	if err != nil || !ok {
+		return "", err
+	}
+	prev, hadOutput := j.LastOutput, j.HasOutput
+	j.LastRun = s.now()
+	j.LastOutput, j.HasOutput = truncate(output, maxStoredOutput), true
+	if err := s.store.Put(jobNamespace, key, j); err != nil {
+		return "", err
+	}
+
+	switch {
+	case !j.Diff:
+		return fmt.Sprintf("%s (every %s):\n%s", j.CommandLine(), FormatInterval(j.Interval()), output), nil
+	case !hadOutput:
+		return fmt.Sprintf("%s (every %s, reporting changes):\n%s", j.CommandLine(), FormatInterval(j.Interval()), output), nil
+	case prev == j.LastOutput:
+		return "", nil
+	}
+	return fmt.Sprintf("%s changed:\n%s", j.CommandLine(), renderDiff(prev, j.LastOutput)), nil
+}
+
+// FormatInterval renders whole hours or minutes compactly ("2h", "15m").
+func FormatInterval(d time.Duration) string {
+	switch {
+	case d%time.Hour == 0:
+		return fmt.Sprintf("%dh", d/time.Hour)
+	case d%time.Minute == 0:
+		return fmt.Sprintf("%dm", d/time.Minute)
+	}
+	return d.String()
+}
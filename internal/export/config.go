@@ -0,0 +1,62 @@
+package export
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/url"
+	"os"
+
+	"github.com/jdelaire/openslack/internal/homedir"
+)
+
+// DefaultIntervalSec is how often buffered records are written out.
+const DefaultIntervalSec = 60
+
+// Config chooses where records go: appended to daily JSONL files in Dir,
+// or, when URL is set, POSTed to it as JSONL instead. Token, if set, is
+// sent as a bearer token with each POST.
+type Config struct {
+	Dir         string `json:"dir"`
+	URL         string `json:"url"`
+	Token       string `json:"token"`
+	IntervalSec int    `json:"interval_sec"`
+}
+
+// LoadConfig reads and validates an export config file.
+// Returns nil, nil if the file does not exist.
+func LoadConfig(path string) (*Config, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("read export config: %w", err)
+	}
+
+	var cfg Config
+	if err := json.Unmarshal(data, &cfg); err != nil {
+		return nil, fmt.Errorf("parse export config: %w", err)
+	}
+	if cfg.IntervalSec < 0 {
+		return nil, fmt.Errorf("export interval_sec must not be negative")
+	}
+	if cfg.URL != "" {
+		u, err := url.Parse(cfg.URL)
+		if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+			return nil, fmt.Errorf("export url %q must be an http or https URL", cfg.URL)
+		}
+	}
+
+	applyDefaults(&cfg)
+	return &cfg, nil
+}
+
+func applyDefaults(cfg *Config) {
+	if cfg.Dir == "" {
+		cfg.Dir = "~/.openslack/export"
+	}
+	cfg.Dir = homedir.Expand(cfg.Dir)
+	if cfg.IntervalSec == 0 {
+		cfg.IntervalSec = DefaultIntervalSec
+	}
+}
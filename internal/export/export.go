@@ -0,0 +1,207 @@
+// Package export writes command history and notifications out as JSONL,
+// to daily files or an HTTP endpoint, for analysis in external tools.
+package export
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+)
+
+// maxBuffered caps records held while the destination is failing; the
+// oldest are dropped first.
+const maxBuffered = 10000
+
+// Record is one exported line.
+type Record struct {
+	Time   time.Time `json:"time"`
+	Type   string    `json:"type"` // "op" or "notification"
+	ChatID int64     `json:"chat_id,omitempty"`
+	Op     string    `json:"op,omitempty"`
+	TookMs int64     `json:"took_ms,omitempty"`
+	Source string    `json:"source,omitempty"`
+	OK     bool      `json:"ok"`
+	Error  string    `json:"error,omitempty"`
+}
+
+// Exporter buffers records and writes them out from Run. It implements
+// the dispatcher's and server's audit hook.
+type Exporter struct {
+	cfg    Config
+	client *http.Client
+	logger *slog.Logger
+	now    func() time.Time
+
+	mu      sync.Mutex
+	pending []Record
+	dropped int
+}
+
+// New creates an Exporter for cfg.
+func New(cfg Config, logger *slog.Logger) *Exporter {
+	if logger == nil {
+		logger = slog.Default()
+	}
+	return &Exporter{
+		cfg:    cfg,
+		client: &http.Client{Timeout: 30 * time.Second},
+		logger: logger,
+		now:    time.Now,
+	}
+}
+
+// RecordOp buffers a finished op run.
+func (e *Exporter) RecordOp(chatID int64, op string, took time.Duration, err error) {
+	e.add(Record{Type: "op", ChatID: chatID, Op: op, TookMs: took.Milliseconds(), OK: err == nil, Error: errText(err)})
+}
+
+// RecordNotification buffers a notification delivery attempt.
+func (e *Exporter) RecordNotification(source string, err error) {
+	e.add(Record{Type: "notification", Source: source, OK: err == nil, Error: errText(err)})
+}
+
+func (e *Exporter) add(r Record) {
+	r.Time = e.now()
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	if len(e.pending) >= maxBuffered {
+		e.pending = e.pending[1:]
+		e.dropped++
+	}
+	e.pending = append(e.pending, r)
+}
+
+// Run flushes buffered records every interval, and once more when ctx is
+// cancelled.
+func (e *Exporter) Run(ctx context.Context) {
+	ticker := time.NewTicker(time.Duration(e.cfg.IntervalSec) * time.Second)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			flushCtx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+			e.flushAndLog(flushCtx)
+			cancel()
+			return
+		case <-ticker.C:
+			e.flushAndLog(ctx)
+		}
+	}
+}
+
+func (e *Exporter) flushAndLog(ctx context.Context) {
+	if err := e.Flush(ctx); err != nil {
+		e.logger.Error("export failed", "error", err)
+	}
+}
+
+// Flush writes out everything buffered. Records that could not be written
+// stay buffered for the next flush.
+func (e *Exporter) Flush(ctx context.Context) error {
+	e.mu.Lock()
+	records := e.pending
+	e.pending = nil
+	dropped := e.dropped
+	e.dropped = 0
+	e.mu.Unlock()
+
+	if dropped > 0 {
+		e.logger.Warn("export buffer full, dropped oldest records", "count", dropped)
+	}
+	if len(records) == 0 {
+		return nil
+	}
+
+	var err error
+	if e.cfg.URL != "" {
+		err = e.post(ctx, records)
+	} else {
+		err = e.appendFiles(records)
+	}
+	if err != nil {
+		e.mu.Lock()
+		e.pending = append(records, e.pending...)
+		if over := len(e.pending) - maxBuffered; over > 0 {
+			e.pending = e.pending[over:]
+			e.dropped += over
+		}
+		e.mu.Unlock()
+	}
+	return err
+}
+
+// appendFiles appends records to one file per local date, e.g.
+// 2026-03-02.jsonl. A partial failure rewrites nothing already appended,
+// so a retried date may repeat lines.
+func (e *Exporter) appendFiles(records []Record) error {
+	if err := os.MkdirAll(e.cfg.Dir, 0700); err != nil {
+		return fmt.Errorf("create export directory: %w", err)
+	}
+	byDate := map[string]*bytes.Buffer{}
+	var dates []string
+	for _, r := range records {
+		date := r.Time.Local().Format("2006-01-02")
+		buf, ok := byDate[date]
+		if !ok {
+			buf = &bytes.Buffer{}
+			byDate[date] = buf
+			dates = append(dates, date)
+		}
+		json.NewEncoder(buf).Encode(r)
+	}
+	for _, date := range dates {
+		path := filepath.Join(e.cfg.Dir, date+".jsonl")
+		f, err := os.OpenFile(path, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0600)
+		if err != nil {
+			return fmt.Errorf("open export file: %w", err)
+		}
+		_, err = f.Write(byDate[date].Bytes())
+		if cerr := f.Close(); err == nil {
+			err = cerr
+		}
+		if err != nil {
+			return fmt.Errorf("write export file: %w", err)
+		}
+	}
+	return nil
+}
+
+// post sends records as one JSONL body.
+func (e *Exporter) post(ctx context.Context, records []Record) error {
+	var body bytes.Buffer
+	enc := json.NewEncoder(&body)
+	for _, r := range records {
+		enc.Encode(r)
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, e.cfg.URL, &body)
+	if err != nil {
+		return fmt.Errorf("create request: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/x-ndjson")
+	if e.cfg.Token != "" {
+		req.Header.Set("Authorization", "Bearer "+e.cfg.Token)
+	}
+	resp, err := e.client.Do(req)
+	if err != nil {
+		return fmt.Errorf("export post: %w", err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return fmt.Errorf("export post: status %d", resp.StatusCode)
+	}
+	return nil
+}
+
+func errText(err error) string {
+	if err == nil {
+		return ""
+	}
+	return err.Error()
+}
@@ -0,0 +1,120 @@
+package export
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+func readLines(t *testing.T, path string) []Record {
+	t.Helper()
+	f, err := os.Open(path)
+	if err != nil {
+		t.Fatalf("open %s: %v", path, err)
+	}
+	defer f.Close()
+	var out []Record
+	sc := bufio.NewScanner(f)
+	for sc.Scan() {
+		var r Record
+		if err := json.Unmarshal(sc.Bytes(), &r); err != nil {
+			t.Fatalf("bad line %q: %v", sc.Text(), err)
+		}
+		out = append(out, r)
+	}
+	return out
+}
+
+func TestFlushPartitionsByDate(t *testing.T) {
+	dir := t.TempDir()
+	e := New(Config{Dir: dir, IntervalSec: 60}, nil)
+	now := time.Date(2026, 3, 1, 23, 59, 0, 0, time.Local)
+	e.now = func() time.Time { return now }
+
+	e.RecordOp(100, "status", 1500*time.Millisecond, nil)
+	now = now.Add(2 * time.Minute)
+	e.RecordNotification("batch.sh", errors.New("telegram down"))
+	if err := e.Flush(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	e.RecordOp(100, "deploy", 0, nil)
+	if err := e.Flush(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+
+	day1 := readLines(t, filepath.Join(dir, "2026-03-01.jsonl"))
+	if len(day1) != 1 || day1[0].Type != "op" || day1[0].Op != "status" || day1[0].TookMs != 1500 || !day1[0].OK {
+		t.Errorf("2026-03-01 = %+v", day1)
+	}
+	day2 := readLines(t, filepath.Join(dir, "2026-03-02.jsonl"))
+	if len(day2) != 2 || day2[0].Source != "batch.sh" || day2[0].OK || day2[0].Error != "telegram down" || day2[1].Op != "deploy" {
+		t.Errorf("2026-03-02 = %+v", day2)
+	}
+}
+
+func TestFlushPostsAndRetries(t *testing.T) {
+	var mu sync.Mutex
+	var bodies []string
+	fail := true
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		defer mu.Unlock()
+		if r.Header.Get("Authorization") != "Bearer secret" || r.Header.Get("Content-Type") != "application/x-ndjson" {
+			t.Errorf("headers = %v", r.Header)
+		}
+		if fail {
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+		data, _ := io.ReadAll(r.Body)
+		bodies = append(bodies, string(data))
+	}))
+	defer srv.Close()
+
+	e := New(Config{URL: srv.URL, Token: "secret", IntervalSec: 60}, nil)
+	e.RecordOp(100, "status", 0, nil)
+	if err := e.Flush(context.Background()); err == nil {
+		t.Fatal("Flush succeeded against a failing endpoint")
+	}
+
+	mu.Lock()
+	fail = false
+	mu.Unlock()
+	e.RecordNotification("cron", nil)
+	if err := e.Flush(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	if len(bodies) != 1 {
+		t.Fatalf("posted %d bodies, want 1", len(bodies))
+	}
+	lines := strings.Split(strings.TrimSpace(bodies[0]), "\n")
+	if len(lines) != 2 || !strings.Contains(lines[0], `"op":"status"`) || !strings.Contains(lines[1], `"source":"cron"`) {
+		t.Errorf("body = %q", bodies[0])
+	}
+}
+
+func TestLoadConfig(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "export.json")
+	if cfg, err := LoadConfig(path); cfg != nil || err != nil {
+		t.Fatalf("missing file = %+v, %v", cfg, err)
+	}
+	os.WriteFile(path, []byte(`{"dir": "/var/lib/openslack/export"}`), 0600)
+	cfg, err := LoadConfig(path)
+	if err != nil || cfg.Dir != "/var/lib/openslack/export" || cfg.IntervalSec != DefaultIntervalSec {
+		t.Errorf("LoadConfig = %+v, %v", cfg, err)
+	}
+	os.WriteFile(path, []byte(`{"url": "ftp://example.com/in"}`), 0600)
+	if _, err := LoadConfig(path); err == nil {
+		t.Error("non-http url accepted")
+	}
+}
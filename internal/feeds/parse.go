@@ -0,0 +1,105 @@
+package feeds
+
+import (
+	"bytes"
+	"encoding/xml"
+	"fmt"
+	"strings"
+)
+
+// Item is a single feed entry.
+type Item struct {
+	ID    string
+	Title string
+	Link  string
+}
+
+type rssDoc struct {
+	Channel struct {
+		Items []struct {
+			GUID  string `xml:"guid"`
+			Title string `xml:"title"`
+			Link  string `xml:"link"`
+		} `xml:"item"`
+	} `xml:"channel"`
+}
+
+type atomDoc struct {
+	Entries []struct {
+		ID    string `xml:"id"`
+		Title string `xml:"title"`
+		Links []struct {
+			Href string `xml:"href,attr"`
+			Rel  string `xml:"rel,attr"`
+		} `xml:"link"`
+	} `xml:"entry"`
+}
+
+// Parse decodes an RSS 2.0 or Atom document. Items are returned in
+// document order, which for both formats is newest first by convention.
+func Parse(data []byte) ([]Item, error) {
+	root, err := rootElement(data)
+	if err != nil {
+		return nil, err
+	}
+
+	switch root {
+	case "rss":
+		var doc rssDoc
+		if err := xml.Unmarshal(data, &doc); err != nil {
+			return nil, fmt.Errorf("parse rss: %w", err)
+		}
+		items := make([]Item, 0, len(doc.Channel.Items))
+		for _, it := range doc.Channel.Items {
+			items = append(items, newItem(it.GUID, it.Title, it.Link))
+		}
+		return items, nil
+	case "feed":
+		var doc atomDoc
+		if err := xml.Unmarshal(data, &doc); err != nil {
+			return nil, fmt.Errorf("parse atom: %w", err)
+		}
+		items := make([]Item, 0, len(doc.Entries))
+		for _, e := range doc.Entries {
+			var link string
+			for _, l := range e.Links {
+				if l.Rel == "" || l.Rel == "alternate" {
+					link = l.Href
+					break
+				}
+			}
+			items = append(items, newItem(e.ID, e.Title, link))
+		}
+		return items, nil
+	default:
+		return nil, fmt.Errorf("unsupported feed format <%s>", root)
+	}
+}
+
+func newItem(id, title, link string) Item {
+	it := Item{
+		ID:    strings.TrimSpace(id),
+		Title: strings.Join(strings.Fields(title), " "),
+		Link:  strings.TrimSpace(link),
+	}
+	if it.ID == "" {
+		it.ID = it.Link
+	}
+	if it.ID == "" {
+		it.ID = it.Title
+	}
+	return it
+}
+
+func rootElement(data []byte) (string, error) {
+	dec := xml.NewDecoder(bytes.NewReader(data))
+	for {
+		tok, err := dec.Token()
+		if err != nil {
+			return "", fmt.Errorf("parse feed: %w", err)
+		}
+		if se, ok := tok.(xml.StartElement); ok {
+			return se.Name.Local, nil
+		}
+	}
+}
@@ -0,0 +1,52 @@
+package feeds
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestParseRSS(t *testing.T) {
+	data := []byte(`<?xml version="1.0"?>
+<rss version="2.0"><channel><title>Blog</title>
+<item><guid>post-2</guid><title>Second
+  post</title><link>https://example.com/2</link></item>
+<item><title>First post</title><link>https://example.com/1</link></item>
+</channel></rss>`)
+
+	got, err := Parse(data)
+	if err != nil {
+		t.Fatalf("parse: %v", err)
+	}
+	want := []Item{
+		{ID: "post-2", Title: "Second post", Link: "https://example.com/2"},
+		{ID: "https://example.com/1", Title: "First post", Link: "https://example.com/1"},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("got %+v, want %+v", got, want)
+	}
+}
+
+func TestParseAtom(t *testing.T) {
+	data := []byte(`<?xml version="1.0" encoding="utf-8"?>
+<feed xmlns="http://www.w3.org/2005/Atom"><title>Go Blog</title>
+<entry><id>tag:go.dev,2026:1</id><title>Go 1.26</title>
+<link rel="self" href="https://go.dev/self"/><link rel="alternate" href="https://go.dev/blog/go1.26"/></entry>
+</feed>`)
+
+	got, err := Parse(data)
+	if err != nil {
+		t.Fatalf("parse: %v", err)
+	}
+	want := []Item{{ID: "tag:go.dev,2026:1", Title: "Go 1.26", Link: "https://go.dev/blog/go1.26"}}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("got %+v, want %+v", got, want)
+	}
+}
+
+func TestParseUnsupported(t *testing.T) {
+	for _, data := range []string{`<html><body/></html>`, `not xml`, ``} {
+		if _, err := Parse([]byte(data)); err == nil {
+			t.Errorf("Parse(%q): expected error", data)
+		}
+	}
+}
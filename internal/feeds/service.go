@@ -0,0 +1,292 @@
+package feeds
+
+import (
+	"errors"
+	"fmt"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/jdelaire/openslack/internal/state"
+)
+
+const (
+	subsNamespace = "feeds"
+	seenNamespace = "feeds.seen"
+
+	DefaultInterval = 30 * time.Minute
+	MinInterval     = 5 * time.Minute
+
+	// maxSeen bounds the remembered item IDs per feed; feeds drop old
+	// items long before this.
+	maxSeen = 500
+)
+
+var (
+	ErrInvalidName = errors.New("feed name must be 1-32 letters, digits, - or _")
+	ErrInvalidURL  = errors.New("feed url must be http or https")
+	ErrExists      = errors.New("feed already exists")
+)
+
+// Subscription is a persisted feed subscription. Include and Exclude are
+// case-insensitive keywords matched against item titles.
+type Subscription struct {
+	Name        string    `json:"name"`
+	URL         string    `json:"url"`
+	IntervalSec int       `json:"interval_sec"`
+	Include     []string  `json:"include,omitempty"`
+	Exclude     []string  `json:"exclude,omitempty"`
+	LastChecked time.Time `json:"last_checked,omitempty"`
+	LastError   string    `json:"last_error,omitempty"`
+}
+
+// Interval returns the poll interval.
+func (s Subscription) Interval() time.Duration {
+	return time.Duration(s.IntervalSec) * time.Second
+}
+
+// Matches reports whether an item title passes the keyword filters.
+func (s Subscription) Matches(title string) bool {
+	title = strings.ToLower(title)
+	for _, w := range s.Exclude {
+		if strings.Contains(title, strings.ToLower(w)) {
+			return false
+		}
+	}
+	if len(s.Include) == 0 {
+		return true
+	}
+	for _, w := range s.Include {
+		if strings.Contains(title, strings.ToLower(w)) {
+			return true
+		}
+	}
+	return false
+}
+
+// Service manages feed subscriptions in the shared state store.
+type Service struct {
+	store *state.Store
+	now   func() time.Time
+	mu    sync.Mutex
+}
+
+func NewService(store *state.Store) *Service {
+	return &Service{
+		store: store,
+		now:   time.Now,
+	}
+}
+
+func (s *Service) WithClock(now func() time.Time) *Service {
+	if now != nil {
+		s.now = now
+	}
+	return s
+}
+
+// Add validates and stores a new subscription. A zero interval uses
+// DefaultInterval; shorter than MinInterval is raised to it.
+func (s *Service) Add(sub Subscription) (Subscription, error) {
+	if !validName(sub.Name) {
+		return Subscription{}, ErrInvalidName
+	}
+	u, err := url.Parse(sub.URL)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		return Subscription{}, ErrInvalidURL
+	}
+	if sub.IntervalSec <= 0 {
+		sub.IntervalSec = int(DefaultInterval / time.Second)
+	}
+	if sub.Interval() < MinInterval {
+		sub.IntervalSec = int(MinInterval / time.Second)
+	}
+	sub.LastChecked = time.Time{}
+	sub.LastError = ""
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	var existing Subscription
+	ok, err := s.store.Get(subsNamespace, sub.Name, &existing)
+	if err != nil {
+		return Subscription{}, err
+	}
+	if ok {
+		return Subscription{}, ErrExists
+	}
+	if err := s.store.Put(subsNamespace, sub.Name, sub); err != nil {
+		return Subscription{}, err
+	}
+	return sub, nil
+}
+
+// Remove deletes a subscription and its seen items. It reports false if
+// no such feed exists.
+func (s *Service) Remove(name string) (bool, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	ok, err := s.store.Delete(subsNamespace, name)
+	if err != nil || !ok {
+		return ok, err
+	}
+	if _, err := s.store.Delete(seenNamespace, name); err != nil {
+		return true, err
+	}
+	return true, nil
+}
+
+// List returns all subscriptions sorted by name.
+func (s *Service) List() ([]Subscription, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	names, err := s.store.Keys(subsNamespace)
+	if err != nil {
+		return nil, err
+	}
+	subs := make([]Subscription, 0, len(names))
+	for _, name := range names {
+		var sub Subscription
+		if _, err := s.store.Get(subsNamespace, name, &sub); err != nil {
+			return nil, err
+		}
+		subs = append(subs, sub)
+	}
+	return subs, nil
+}
+
+// Due returns subscriptions whose interval has elapsed since the last check.
+func (s *Service) Due() ([]Subscription, error) {
+	subs, err := s.List()
+	if err != nil {
+		return nil, err
+	}
+	now := s.now()
+	var due []Subscription
+	for _, sub := range subs {
+		if sub.LastChecked.IsZero() || !now.Before(sub.LastChecked.Add(sub.Interval())) {
+			due = append(due, sub)
+		}
+	}
+	return due, nil
+}
+
+// recordCheck stores the outcome of a fetch, unless the feed was removed
+// while it was being fetched.
+func (s *Service) recordCheck(name string, checkErr error) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	var sub Subscription
+	ok, err := s.store.Get(subsNamespace, name, &sub)
+	if err != nil || !ok {
+		return err
+	}
+	sub.LastChecked = s.now()
+	sub.LastError = ""
+	if checkErr != nil {
+		sub.LastError = checkErr.Error()
+	}
+	return s.store.Put(subsNamespace, name, sub)
+}
+
+// filterNew returns items not seen before. The first call for a feed
+// records all of items as seen and returns none, so subscribing does not
+// replay the whole feed. Later items are recorded by markSeen once they
+// have been sent, so items whose send failed come back on the next check.
+func (s *Service) filterNew(name string, items []Item) ([]Item, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	var seen []string
+	seeded, err := s.store.Get(seenNamespace, name, &seen)
+	if err != nil {
+		return nil, err
+	}
+	if !seeded {
+		return nil, s.putSeen(name, nil, items)
+	}
+	known := make(map[string]bool, len(seen))
+	for _, id := range seen {
+		known[id] = true
+	}
+
+	var fresh []Item
+	for _, it := range items {
+		if !known[it.ID] {
+			known[it.ID] = true
+			fresh = append(fresh, it)
+		}
+	}
+	return fresh, nil
+}
+
+// markSeen records items as seen, so filterNew no longer returns them.
+func (s *Service) markSeen(name string, items []Item) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	var seen []string
+	if _, err := s.store.Get(seenNamespace, name, &seen); err != nil {
+		return err
+	}
+	return s.putSeen(name, seen, items)
+}
+
+// putSeen stores seen plus the IDs of items not already in it, keeping
+// the newest maxSeen. s.mu must be held.
+func (s *Service) putSeen(name string, seen []string, items []Item) error {
+	known := make(map[string]bool, len(seen))
+	for _, id := range seen {
+		known[id] = true
+	}
+	for _, it := range items {
+		if !known[it.ID] {
+			known[it.ID] = true
+			seen = append(seen, it.ID)
+		}
+	}
+	if len(seen) > maxSeen {
+		seen = seen[len(seen)-maxSeen:]
+	}
+	return s.store.Put(seenNamespace, name, seen)
+}
+
+func validName(name string) bool {
+	if name == "" || len(name) > 32 {
+		return false
+	}
+	for _, r := range name {
+		if !(r >= 'a' && r <= 'z' || r >= 'A' && r <= 'Z' || r >= '0' && r <= '9' || r == '-' || r == '_') {
+			return false
+		}
+	}
+	return true
+}
+
+// String formats a subscription for /feeds.
+func (s Subscription) String() string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "%s (every %s) %s", s.Name, FormatInterval(s.Interval()), s.URL)
+	for _, w := range s.Include {
+		b.WriteString(" +" + w)
+	}
+	for _, w := range s.Exclude {
+		b.WriteString(" -" + w)
+	}
+	if s.LastError != "" {
+		fmt.Fprintf(&b, "\n  last error: %s", s.LastError)
+	}
+	return b.String()
+}
+
+// FormatInterval renders an interval compactly, e.g. "30m" or "2h".
+func FormatInterval(d time.Duration) string {
+	if d%time.Hour == 0 {
+		return fmt.Sprintf("%dh", d/time.Hour)
+	}
+	return fmt.Sprintf("%dm", d/time.Minute)
+}
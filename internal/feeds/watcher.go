@@ -0,0 +1,150 @@
+package feeds
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"strings"
+	"time"
+)
+
+const (
+	tickInterval    = time.Minute
+	fetchTimeout    = 15 * time.Second
+	maxFeedBytes    = 2 << 20
+	maxItemsPerPost = 10
+)
+
+// Watcher polls due subscriptions and sends new matching items.
+type Watcher struct {
+	service *Service
+	send    func(context.Context, string) error
+	client  *http.Client
+	admit   func() bool
+	logger  *slog.Logger
+}
+
+func NewWatcher(service *Service, send func(context.Context, string) error, logger *slog.Logger) *Watcher {
+	if logger == nil {
+		logger = slog.Default()
+	}
+	return &Watcher{
+		service: service,
+		send:    send,
+		client:  &http.Client{Timeout: fetchTimeout},
+		logger:  logger,
+	}
+}
+
+// WithGate defers checks while admit reports false, e.g. while the daemon
+// is under load. Deferred feeds stay due and are checked on a later tick.
+func (w *Watcher) WithGate(admit func() bool) *Watcher {
+	w.admit = admit
+	return w
+}
+
+// Run checks due feeds every minute until ctx is cancelled.
+func (w *Watcher) Run(ctx context.Context) {
+	ticker := time.NewTicker(tickInterval)
+	defer ticker.Stop()
+
+	for {
+		w.runTick(ctx)
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+func (w *Watcher) runTick(ctx context.Context) {
+	due, err := w.service.Due()
+	if err != nil {
+		w.logger.Error("feeds: list due failed", "error", err)
+		return
+	}
+	for _, sub := range due {
+		if w.admit != nil && !w.admit() {
+			w.logger.Debug("feeds: deferring due checks under load", "feeds", len(due))
+			return
+		}
+		err := w.check(ctx, sub)
+		if err != nil {
+			w.logger.Warn("feeds: check failed", "feed", sub.Name, "error", err)
+		}
+		if err := w.service.recordCheck(sub.Name, err); err != nil {
+			w.logger.Error("feeds: record check failed", "feed", sub.Name, "error", err)
+		}
+	}
+}
+
+func (w *Watcher) check(ctx context.Context, sub Subscription) error {
+	items, err := w.fetch(ctx, sub.URL)
+	if err != nil {
+		return err
+	}
+	fresh, err := w.service.filterNew(sub.Name, items)
+	if err != nil {
+		return err
+	}
+
+	var matched []Item
+	for _, it := range fresh {
+		if sub.Matches(it.Title) {
+			matched = append(matched, it)
+		}
+	}
+	if len(matched) > 0 {
+		sendCtx, cancel := context.WithTimeout(ctx, 10*time.Second)
+		defer cancel()
+		if err := w.send(sendCtx, FormatItems(sub.Name, matched)); err != nil {
+			// Leave the items unseen, so the next check sends them again.
+			return fmt.Errorf("send items: %w", err)
+		}
+	}
+	return w.service.markSeen(sub.Name, fresh)
+}
+
+func (w *Watcher) fetch(ctx context.Context, url string) ([]Item, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		return nil, fmt.Errorf("create request: %w", err)
+	}
+	req.Header.Set("User-Agent", "openslack-feeds")
+
+	resp, err := w.client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("fetch feed: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("fetch feed: HTTP %d", resp.StatusCode)
+	}
+	data, err := io.ReadAll(io.LimitReader(resp.Body, maxFeedBytes))
+	if err != nil {
+		return nil, fmt.Errorf("read feed: %w", err)
+	}
+	return Parse(data)
+}
+
+// FormatItems renders new items for one feed as a single message. Items
+// arrive newest first; at most maxItemsPerPost are listed.
+func FormatItems(name string, items []Item) string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "%s: %d new", name, len(items))
+	for i, it := range items {
+		if i == maxItemsPerPost {
+			fmt.Fprintf(&b, "\n\n…and %d more", len(items)-maxItemsPerPost)
+			break
+		}
+		fmt.Fprintf(&b, "\n\n%s", it.Title)
+		if it.Link != "" {
+			fmt.Fprintf(&b, "\n%s", it.Link)
+		}
+	}
+	return b.String()
+}
@@ -0,0 +1,197 @@
+package feeds
+
+import (
+	"context"
+	"errors"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/jdelaire/openslack/internal/state"
+)
+
+func rss(items ...string) string {
+	var b strings.Builder
+	b.WriteString(`<rss><channel>`)
+	for _, it := range items {
+		b.WriteString(`<item><guid>` + it + `</guid><title>` + it + `</title><link>https://example.com/` + it + `</link></item>`)
+	}
+	b.WriteString(`</channel></rss>`)
+	return b.String()
+}
+
+type feedTest struct {
+	svc     *Service
+	w       *Watcher
+	feed    *string
+	sent    *[]string
+	sendErr *error
+	now     *time.Time
+}
+
+func newFeedTest(t *testing.T) feedTest {
+	t.Helper()
+	feed := rss("a", "b")
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if feed == "" {
+			w.WriteHeader(http.StatusBadGateway)
+			return
+		}
+		w.Write([]byte(feed))
+	}))
+	t.Cleanup(srv.Close)
+
+	now := time.Date(2026, 3, 1, 10, 0, 0, 0, time.UTC)
+	svc := NewService(state.NewStore(filepath.Join(t.TempDir(), "state.json"))).WithClock(func() time.Time { return now })
+	var sent []string
+	var sendErr error
+	w := NewWatcher(svc, func(_ context.Context, text string) error {
+		if sendErr != nil {
+			return sendErr
+		}
+		sent = append(sent, text)
+		return nil
+	}, slog.New(slog.NewJSONHandler(io.Discard, nil)))
+
+	if _, err := svc.Add(Subscription{Name: "blog", URL: srv.URL}); err != nil {
+		t.Fatalf("add: %v", err)
+	}
+	return feedTest{svc: svc, w: w, feed: &feed, sent: &sent, sendErr: &sendErr, now: &now}
+}
+
+func TestWatcherSeedsThenSendsNewItems(t *testing.T) {
+	ft := newFeedTest(t)
+
+	// First check records existing items without sending them.
+	ft.w.runTick(context.Background())
+	if len(*ft.sent) != 0 {
+		t.Fatalf("seed tick sent %q", *ft.sent)
+	}
+
+	// Not due again until the interval elapses.
+	*ft.feed = rss("c", "a", "b")
+	*ft.now = ft.now.Add(10 * time.Minute)
+	ft.w.runTick(context.Background())
+	if len(*ft.sent) != 0 {
+		t.Fatalf("tick before interval sent %q", *ft.sent)
+	}
+
+	*ft.now = ft.now.Add(DefaultInterval)
+	ft.w.runTick(context.Background())
+	if len(*ft.sent) != 1 {
+		t.Fatalf("sent %d messages, want 1", len(*ft.sent))
+	}
+	want := "blog: 1 new\n\nc\nhttps://example.com/c"
+	if (*ft.sent)[0] != want {
+		t.Errorf("message = %q, want %q", (*ft.sent)[0], want)
+	}
+
+	// The same items are not sent twice.
+	*ft.now = ft.now.Add(DefaultInterval)
+	ft.w.runTick(context.Background())
+	if len(*ft.sent) != 1 {
+		t.Errorf("duplicate send: %q", *ft.sent)
+	}
+}
+
+func TestWatcherRetriesFailedSends(t *testing.T) {
+	ft := newFeedTest(t)
+	ft.w.runTick(context.Background())
+
+	*ft.feed = rss("c", "a", "b")
+	*ft.sendErr = errors.New("telegram down")
+	*ft.now = ft.now.Add(DefaultInterval)
+	ft.w.runTick(context.Background())
+	if subs, _ := ft.svc.List(); !strings.Contains(subs[0].LastError, "telegram down") {
+		t.Errorf("last error = %q", subs[0].LastError)
+	}
+
+	// The item whose send failed is sent on the next check.
+	*ft.sendErr = nil
+	*ft.now = ft.now.Add(DefaultInterval)
+	ft.w.runTick(context.Background())
+	if len(*ft.sent) != 1 || !strings.HasPrefix((*ft.sent)[0], "blog: 1 new\n\nc") {
+		t.Fatalf("sent %q", *ft.sent)
+	}
+
+	*ft.now = ft.now.Add(DefaultInterval)
+	ft.w.runTick(context.Background())
+	if len(*ft.sent) != 1 {
+		t.Errorf("duplicate send: %q", *ft.sent)
+	}
+}
+
+func TestSubscriptionMatches(t *testing.T) {
+	sub := Subscription{Name: "hn", Include: []string{"Go"}, Exclude: []string{"crypto"}}
+	tests := []struct {
+		title string
+		want  bool
+	}{
+		{"Go 1.26 released", true},
+		{"Why I left go for crypto", false},
+		{"Rust news", false},
+	}
+	for _, tt := range tests {
+		if got := sub.Matches(tt.title); got != tt.want {
+			t.Errorf("Matches(%q) = %v, want %v", tt.title, got, tt.want)
+		}
+	}
+}
+
+func TestWatcherRecordsErrors(t *testing.T) {
+	ft := newFeedTest(t)
+	*ft.feed = ""
+
+	ft.w.runTick(context.Background())
+
+	subs, err := ft.svc.List()
+	if err != nil {
+		t.Fatalf("list: %v", err)
+	}
+	if !strings.Contains(subs[0].LastError, "HTTP 502") {
+		t.Errorf("last error = %q", subs[0].LastError)
+	}
+	if !subs[0].LastChecked.Equal(*ft.now) {
+		t.Errorf("last checked = %v", subs[0].LastChecked)
+	}
+}
+
+func TestServiceAddValidation(t *testing.T) {
+	svc := NewService(state.NewStore(filepath.Join(t.TempDir(), "state.json")))
+
+	tests := []struct {
+		sub  Subscription
+		want error
+	}{
+		{Subscription{Name: "bad name", URL: "https://x.dev/feed"}, ErrInvalidName},
+		{Subscription{Name: "x", URL: "file:///etc/passwd"}, ErrInvalidURL},
+		{Subscription{Name: "x", URL: "https://x.dev/feed"}, nil},
+		{Subscription{Name: "x", URL: "https://x.dev/other"}, ErrExists},
+	}
+	for _, tt := range tests {
+		if _, err := svc.Add(tt.sub); err != tt.want {
+			t.Errorf("Add(%+v) = %v, want %v", tt.sub, err, tt.want)
+		}
+	}
+
+	got, _ := svc.Add(Subscription{Name: "fast", URL: "https://x.dev/feed", IntervalSec: 10})
+	if got.Interval() != MinInterval {
+		t.Errorf("interval = %v, want clamp to %v", got.Interval(), MinInterval)
+	}
+}
+
+func TestFormatItemsCaps(t *testing.T) {
+	items := make([]Item, maxItemsPerPost+3)
+	for i := range items {
+		items[i] = Item{Title: "t"}
+	}
+	got := FormatItems("hn", items)
+	if !strings.HasPrefix(got, "hn: 13 new") || !strings.HasSuffix(got, "…and 3 more") {
+		t.Errorf("message = %q", got)
+	}
+}
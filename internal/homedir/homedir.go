@@ -0,0 +1,23 @@
+// Package homedir expands the "~/" that config files use for paths under
+// the user's home directory.
+package homedir
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// Expand replaces a leading "~/" with the user's home directory. Other
+// paths, and all paths when the home directory is unknown, are returned
+// as they are.
+func Expand(path string) string {
+	if !strings.HasPrefix(path, "~/") {
+		return path
+	}
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return path
+	}
+	return filepath.Join(home, path[2:])
+}
@@ -0,0 +1,25 @@
+package homedir
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestExpand(t *testing.T) {
+	home, err := os.UserHomeDir()
+	if err != nil {
+		t.Skip("no home directory")
+	}
+	tests := map[string]string{
+		"~/.openslack/inbox": filepath.Join(home, ".openslack", "inbox"),
+		"/var/lib/openslack": "/var/lib/openslack",
+		"~other/dir":         "~other/dir",
+		"relative/~/dir":     "relative/~/dir",
+	}
+	for in, want := range tests {
+		if got := Expand(in); got != want {
+			t.Errorf("Expand(%q) = %q, want %q", in, got, want)
+		}
+	}
+}
@@ -0,0 +1,61 @@
+package inbox
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+
+	"github.com/jdelaire/openslack/internal/homedir"
+)
+
+// Defaults.
+const (
+	DefaultRetainDays = 7
+	DefaultMaxItems   = 100
+)
+
+// Config controls the drop inbox. Dir defaults to ~/.openslack/inbox.
+// With Encrypt set, items are sealed with a key kept in the keychain.
+type Config struct {
+	Dir        string `json:"dir"`
+	RetainDays int    `json:"retain_days"`
+	MaxItems   int    `json:"max_items"`
+	Encrypt    bool   `json:"encrypt"`
+}
+
+// LoadConfig reads and validates an inbox config file.
+// Returns nil, nil if the file does not exist.
+func LoadConfig(path string) (*Config, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("read inbox config: %w", err)
+	}
+
+	var cfg Config
+	if err := json.Unmarshal(data, &cfg); err != nil {
+		return nil, fmt.Errorf("parse inbox config: %w", err)
+	}
+
+	if cfg.RetainDays < 0 || cfg.MaxItems < 0 {
+		return nil, fmt.Errorf("inbox retain_days and max_items must not be negative")
+	}
+
+	applyDefaults(&cfg)
+	return &cfg, nil
+}
+
+func applyDefaults(cfg *Config) {
+	if cfg.Dir == "" {
+		cfg.Dir = "~/.openslack/inbox"
+	}
+	cfg.Dir = homedir.Expand(cfg.Dir)
+	if cfg.RetainDays == 0 {
+		cfg.RetainDays = DefaultRetainDays
+	}
+	if cfg.MaxItems == 0 {
+		cfg.MaxItems = DefaultMaxItems
+	}
+}
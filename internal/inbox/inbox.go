@@ -0,0 +1,288 @@
+// Package inbox retains files dropped over the socket so they can be
+// listed and fetched again from chat.
+package inbox
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/jdelaire/openslack/internal/keychain"
+)
+
+// KeyAccount is the keychain account holding the base64 inbox key.
+const KeyAccount = "inbox-key"
+
+var ErrNotFound = errors.New("inbox item not found")
+
+// Item describes one retained drop.
+type Item struct {
+	ID      string    `json:"id"`
+	Name    string    `json:"name"`
+	Source  string    `json:"source,omitempty"`
+	Size    int       `json:"size"`
+	Created time.Time `json:"created"`
+}
+
+// Inbox stores each item as <id>.meta and <id>.data in dir. With a key,
+// both files are sealed with AES-256-GCM.
+type Inbox struct {
+	dir      string
+	aead     cipher.AEAD
+	maxItems int
+	maxAge   time.Duration
+	logger   *slog.Logger
+	now      func() time.Time
+
+	mu sync.Mutex
+}
+
+// New opens the inbox in cfg.Dir, creating it with 0700 permissions. key
+// must be nil (no encryption) or 32 bytes. Items that cannot be read are
+// logged to logger and left out of listings.
+func New(cfg *Config, key []byte, logger *slog.Logger) (*Inbox, error) {
+	if err := os.MkdirAll(cfg.Dir, 0700); err != nil {
+		return nil, fmt.Errorf("create inbox directory: %w", err)
+	}
+	if logger == nil {
+		logger = slog.Default()
+	}
+	b := &Inbox{
+		dir:      cfg.Dir,
+		maxItems: cfg.MaxItems,
+		maxAge:   time.Duration(cfg.RetainDays) * 24 * time.Hour,
+		logger:   logger,
+		now:      time.Now,
+	}
+	if key != nil {
+		if len(key) != 32 {
+			return nil, fmt.Errorf("inbox key must be 32 bytes")
+		}
+		block, err := aes.NewCipher(key)
+		if err != nil {
+			return nil, fmt.Errorf("inbox key: %w", err)
+		}
+		b.aead, err = cipher.NewGCM(block)
+		if err != nil {
+			return nil, err
+		}
+	}
+	return b, nil
+}
+
+// KeyFromKeychain returns the inbox key, generating and storing one on
+// first use.
+func KeyFromKeychain() ([]byte, error) {
+	if s, err := keychain.Get(KeyAccount); err == nil {
+		key, err := base64.StdEncoding.DecodeString(s)
+		if err != nil || len(key) != 32 {
+			return nil, fmt.Errorf("keychain %s is not a base64 32-byte key", KeyAccount)
+		}
+		return key, nil
+	}
+	key := make([]byte, 32)
+	if _, err := rand.Read(key); err != nil {
+		return nil, err
+	}
+	if err := keychain.Set(KeyAccount, base64.StdEncoding.EncodeToString(key)); err != nil {
+		return nil, fmt.Errorf("store inbox key: %w", err)
+	}
+	return key, nil
+}
+
+// Save stores data and returns the new item's ID. Old items beyond the
+// retention limits are pruned.
+func (b *Inbox) Save(name, source string, data []byte) (string, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	id, err := b.newID()
+	if err != nil {
+		return "", err
+	}
+	item := Item{ID: id, Name: name, Source: source, Size: len(data), Created: b.now()}
+	meta, err := json.Marshal(item)
+	if err != nil {
+		return "", err
+	}
+	if err := b.write(id+".data", data); err != nil {
+		return "", err
+	}
+	if err := b.write(id+".meta", meta); err != nil {
+		os.Remove(filepath.Join(b.dir, id+".data"))
+		return "", err
+	}
+	b.prune()
+	return id, nil
+}
+
+// List returns retained items, newest first.
+func (b *Inbox) List() ([]Item, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.list()
+}
+
+// Open returns an item and its content.
+func (b *Inbox) Open(id string) (Item, []byte, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if !validID(id) {
+		return Item{}, nil, ErrNotFound
+	}
+	item, err := b.readMeta(id)
+	if err != nil {
+		return Item{}, nil, err
+	}
+	data, err := b.read(id + ".data")
+	if err != nil {
+		return Item{}, nil, err
+	}
+	return item, data, nil
+}
+
+// Delete removes an item. It reports false if no such item exists.
+func (b *Inbox) Delete(id string) (bool, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if !validID(id) {
+		return false, nil
+	}
+	err := os.Remove(filepath.Join(b.dir, id+".meta"))
+	if os.IsNotExist(err) {
+		return false, nil
+	}
+	if err != nil {
+		return false, err
+	}
+	os.Remove(filepath.Join(b.dir, id+".data"))
+	return true, nil
+}
+
+// list reads every item's metadata. An item that cannot be read, such as
+// one sealed with another key, is skipped so it does not hide the rest.
+func (b *Inbox) list() ([]Item, error) {
+	paths, err := filepath.Glob(filepath.Join(b.dir, "*.meta"))
+	if err != nil {
+		return nil, err
+	}
+	items := make([]Item, 0, len(paths))
+	for _, p := range paths {
+		id := strings.TrimSuffix(filepath.Base(p), ".meta")
+		item, err := b.readMeta(id)
+		if err != nil {
+			b.logger.Warn("inbox: skipping unreadable item", "id", id, "error", err)
+			continue
+		}
+		items = append(items, item)
+	}
+	sort.Slice(items, func(i, j int) bool { return items[i].Created.After(items[j].Created) })
+	return items, nil
+}
+
+// prune drops items older than maxAge and beyond maxItems.
+func (b *Inbox) prune() {
+	items, err := b.list()
+	if err != nil {
+		return
+	}
+	cutoff := b.now().Add(-b.maxAge)
+	for i, item := range items {
+		if i >= b.maxItems || item.Created.Before(cutoff) {
+			os.Remove(filepath.Join(b.dir, item.ID+".meta"))
+			os.Remove(filepath.Join(b.dir, item.ID+".data"))
+		}
+	}
+}
+
+func (b *Inbox) newID() (string, error) {
+	buf := make([]byte, 4)
+	for range 10 {
+		if _, err := rand.Read(buf); err != nil {
+			return "", err
+		}
+		id := hex.EncodeToString(buf)
+		if _, err := os.Stat(filepath.Join(b.dir, id+".meta")); os.IsNotExist(err) {
+			return id, nil
+		}
+	}
+	return "", fmt.Errorf("could not allocate inbox id")
+}
+
+func (b *Inbox) readMeta(id string) (Item, error) {
+	data, err := b.read(id + ".meta")
+	if err != nil {
+		return Item{}, err
+	}
+	var item Item
+	if err := json.Unmarshal(data, &item); err != nil {
+		return Item{}, fmt.Errorf("parse inbox item %s: %w", id, err)
+	}
+	return item, nil
+}
+
+// write stores data atomically with 0600 permissions, sealing it if the
+// inbox is encrypted.
+func (b *Inbox) write(name string, data []byte) error {
+	if b.aead != nil {
+		nonce := make([]byte, b.aead.NonceSize())
+		if _, err := rand.Read(nonce); err != nil {
+			return err
+		}
+		data = b.aead.Seal(nonce, nonce, data, []byte(name))
+	}
+	path := filepath.Join(b.dir, name)
+	tmp := path + ".tmp"
+	if err := os.WriteFile(tmp, data, 0600); err != nil {
+		return fmt.Errorf("write inbox item: %w", err)
+	}
+	if err := os.Rename(tmp, path); err != nil {
+		os.Remove(tmp)
+		return fmt.Errorf("write inbox item: %w", err)
+	}
+	return nil
+}
+
+func (b *Inbox) read(name string) ([]byte, error) {
+	data, err := os.ReadFile(filepath.Join(b.dir, name))
+	if os.IsNotExist(err) {
+		return nil, ErrNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("read inbox item: %w", err)
+	}
+	if b.aead == nil {
+		return data, nil
+	}
+	n := b.aead.NonceSize()
+	if len(data) < n {
+		return nil, fmt.Errorf("inbox item %s is corrupt", name)
+	}
+	plain, err := b.aead.Open(nil, data[:n], data[n:], []byte(name))
+	if err != nil {
+		return nil, fmt.Errorf("decrypt inbox item %s: %w", name, err)
+	}
+	return plain, nil
+}
+
+func validID(id string) bool {
+	if len(id) != 8 {
+		return false
+	}
+	_, err := hex.DecodeString(id)
+	return err == nil
+}
@@ -0,0 +1,155 @@
+package inbox
+
+import (
+	"bytes"
+	"io"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func newTestInbox(t *testing.T, key []byte, maxItems int) *Inbox {
+	t.Helper()
+	cfg := &Config{Dir: filepath.Join(t.TempDir(), "inbox"), MaxItems: maxItems}
+	applyDefaults(cfg)
+	b, err := New(cfg, key, slog.New(slog.NewTextHandler(io.Discard, nil)))
+	if err != nil {
+		t.Fatalf("New: %v", err)
+	}
+	return b
+}
+
+func TestSaveOpenDelete(t *testing.T) {
+	b := newTestInbox(t, nil, 0)
+
+	id, err := b.Save("notes.txt", "cron", []byte("hello"))
+	if err != nil {
+		t.Fatalf("save: %v", err)
+	}
+	item, data, err := b.Open(id)
+	if err != nil {
+		t.Fatalf("open: %v", err)
+	}
+	if item.Name != "notes.txt" || item.Source != "cron" || item.Size != 5 || string(data) != "hello" {
+		t.Errorf("item = %+v, data = %q", item, data)
+	}
+
+	info, err := os.Stat(filepath.Join(b.dir, id+".data"))
+	if err != nil || info.Mode().Perm() != 0600 {
+		t.Errorf("data file = %v, %v; want 0600", info, err)
+	}
+
+	if ok, err := b.Delete(id); !ok || err != nil {
+		t.Fatalf("delete = %v, %v", ok, err)
+	}
+	if ok, _ := b.Delete(id); ok {
+		t.Error("second delete reported true")
+	}
+	if _, _, err := b.Open(id); err != ErrNotFound {
+		t.Errorf("open deleted = %v, want ErrNotFound", err)
+	}
+	if _, _, err := b.Open("../../etc/passwd"); err != ErrNotFound {
+		t.Errorf("open traversal = %v, want ErrNotFound", err)
+	}
+}
+
+func TestEncryptedAtRest(t *testing.T) {
+	key := bytes.Repeat([]byte{7}, 32)
+	b := newTestInbox(t, key, 0)
+
+	id, err := b.Save("secret-report.txt", "", []byte("top secret payload"))
+	if err != nil {
+		t.Fatalf("save: %v", err)
+	}
+	for _, name := range []string{id + ".data", id + ".meta"} {
+		raw, _ := os.ReadFile(filepath.Join(b.dir, name))
+		if strings.Contains(string(raw), "secret") {
+			t.Errorf("%s stored in plaintext: %q", name, raw)
+		}
+	}
+	if _, data, err := b.Open(id); err != nil || string(data) != "top secret payload" {
+		t.Errorf("open = %q, %v", data, err)
+	}
+
+	other, err := New(&Config{Dir: b.dir, MaxItems: 10, RetainDays: 1}, bytes.Repeat([]byte{8}, 32), nil)
+	if err != nil {
+		t.Fatalf("New: %v", err)
+	}
+	if _, _, err := other.Open(id); err == nil || !strings.Contains(err.Error(), "decrypt") {
+		t.Errorf("open with wrong key = %v", err)
+	}
+
+	if _, err := New(&Config{Dir: b.dir}, []byte("short"), nil); err == nil {
+		t.Error("New accepted a short key")
+	}
+}
+
+func TestRetention(t *testing.T) {
+	b := newTestInbox(t, nil, 3)
+	now := time.Date(2026, 3, 1, 8, 0, 0, 0, time.UTC)
+	b.now = func() time.Time { return now }
+
+	var ids []string
+	for i := range 4 {
+		id, err := b.Save("f", "", []byte{byte(i)})
+		if err != nil {
+			t.Fatalf("save: %v", err)
+		}
+		ids = append(ids, id)
+		now = now.Add(time.Minute)
+	}
+
+	items, err := b.List()
+	if err != nil {
+		t.Fatalf("list: %v", err)
+	}
+	if len(items) != 3 || items[0].ID != ids[3] || items[2].ID != ids[1] {
+		t.Fatalf("items = %+v, want newest 3", items)
+	}
+
+	now = now.Add(DefaultRetainDays * 24 * time.Hour)
+	if _, err := b.Save("new", "", []byte("x")); err != nil {
+		t.Fatalf("save: %v", err)
+	}
+	items, _ = b.List()
+	if len(items) != 1 || items[0].Name != "new" {
+		t.Errorf("items after expiry = %+v", items)
+	}
+}
+
+func TestListSkipsUnreadableItems(t *testing.T) {
+	b := newTestInbox(t, nil, 2)
+	now := time.Date(2026, 3, 1, 8, 0, 0, 0, time.UTC)
+	b.now = func() time.Time { return now }
+
+	var ids []string
+	for i := range 2 {
+		id, err := b.Save("f", "", []byte{byte(i)})
+		if err != nil {
+			t.Fatalf("save: %v", err)
+		}
+		ids = append(ids, id)
+		now = now.Add(time.Minute)
+	}
+	os.WriteFile(filepath.Join(b.dir, "0badc0de.meta"), []byte("{not json"), 0600)
+
+	items, err := b.List()
+	if err != nil {
+		t.Fatalf("list: %v", err)
+	}
+	if len(items) != 2 || items[0].ID != ids[1] || items[1].ID != ids[0] {
+		t.Fatalf("items = %+v, want the two readable ones", items)
+	}
+
+	// Pruning still runs past the corrupt item.
+	if _, err := b.Save("new", "", []byte("x")); err != nil {
+		t.Fatalf("save: %v", err)
+	}
+	items, _ = b.List()
+	if len(items) != 2 || items[0].Name != "new" || items[1].ID != ids[1] {
+		t.Errorf("items after prune = %+v", items)
+	}
+}
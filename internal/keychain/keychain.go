@@ -13,3 +13,13 @@ func Get(account string) (string, error) {
 func Set(account, value string) error {
 	return keyring.Set(serviceName, account, value)
 }
+
+// Store is the system keychain as a value, for code that takes a secret
+// store such as ops.SetupOp.
+type Store struct{}
+
+// Get retrieves a secret from the system keychain.
+func (Store) Get(account string) (string, error) { return Get(account) }
+
+// Set stores a secret in the system keychain.
+func (Store) Set(account, value string) error { return Set(account, value) }
@@ -0,0 +1,86 @@
+package monitors
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"os/exec"
+	"time"
+)
+
+// Checker runs one probe against a monitor's target. A nil error means
+// the target is up.
+type Checker func(ctx context.Context, mc MonitorConfig) error
+
+// Check dispatches to the probe for mc.Type, bounded by mc.TimeoutMs.
+func Check(ctx context.Context, mc MonitorConfig) error {
+	ctx, cancel := context.WithTimeout(ctx, time.Duration(mc.TimeoutMs)*time.Millisecond)
+	defer cancel()
+
+	switch mc.Type {
+	case TypeHTTP:
+		return checkHTTP(ctx, mc)
+	case TypeTCP:
+		return checkTCP(ctx, mc)
+	case TypeICMP:
+		return checkICMP(ctx, mc)
+	default:
+		return fmt.Errorf("unknown check type %q", mc.Type)
+	}
+}
+
+var httpClient = &http.Client{
+	// Report redirects as the final status rather than following them
+	// somewhere else entirely.
+	CheckRedirect: func(*http.Request, []*http.Request) error { return http.ErrUseLastResponse },
+}
+
+func checkHTTP(ctx context.Context, mc MonitorConfig) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, mc.Target, nil)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("User-Agent", "openslack-monitor")
+
+	resp, err := httpClient.Do(req)
+	if err != nil {
+		return err
+	}
+	io.Copy(io.Discard, io.LimitReader(resp.Body, 64*1024))
+	resp.Body.Close()
+
+	if mc.ExpectStatus != 0 {
+		if resp.StatusCode != mc.ExpectStatus {
+			return fmt.Errorf("HTTP %d, want %d", resp.StatusCode, mc.ExpectStatus)
+		}
+		return nil
+	}
+	if resp.StatusCode >= 400 {
+		return fmt.Errorf("HTTP %d", resp.StatusCode)
+	}
+	return nil
+}
+
+func checkTCP(ctx context.Context, mc MonitorConfig) error {
+	var d net.Dialer
+	conn, err := d.DialContext(ctx, "tcp", mc.Target)
+	if err != nil {
+		return err
+	}
+	return conn.Close()
+}
+
+// checkICMP uses the system ping binary, which has the privileges raw
+// ICMP sockets need.
+func checkICMP(ctx context.Context, mc MonitorConfig) error {
+	cmd := exec.CommandContext(ctx, "ping", "-c", "1", mc.Target)
+	if err := cmd.Run(); err != nil {
+		if ctx.Err() != nil {
+			return fmt.Errorf("ping timed out")
+		}
+		return fmt.Errorf("ping failed: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,115 @@
+package monitors
+
+import (
+	"encoding/json"
+	"fmt"
+	"net"
+	"net/url"
+	"os"
+	"strings"
+)
+
+// Check types.
+const (
+	TypeHTTP = "http"
+	TypeTCP  = "tcp"
+	TypeICMP = "icmp"
+)
+
+// Defaults.
+const (
+	DefaultIntervalSec = 60
+	MinIntervalSec     = 10
+	DefaultFailures    = 3
+	DefaultTimeoutMs   = 5000
+)
+
+// Config is the top-level monitors configuration.
+type Config struct {
+	Monitors map[string]MonitorConfig `json:"monitors"`
+}
+
+// MonitorConfig defines a single check. Target is a URL for http, a
+// host:port for tcp, and a host for icmp.
+type MonitorConfig struct {
+	Type         string `json:"type"`
+	Target       string `json:"target"`
+	IntervalSec  int    `json:"interval_sec"`
+	Failures     int    `json:"failures"`
+	TimeoutMs    int    `json:"timeout_ms"`
+	ExpectStatus int    `json:"expect_status"`
+}
+
+// LoadConfig reads and validates a monitors config file.
+// Returns nil, nil if the file does not exist.
+func LoadConfig(path string) (*Config, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("read monitors config: %w", err)
+	}
+
+	var cfg Config
+	if err := json.Unmarshal(data, &cfg); err != nil {
+		return nil, fmt.Errorf("parse monitors config: %w", err)
+	}
+
+	if err := validateConfig(&cfg); err != nil {
+		return nil, err
+	}
+
+	applyDefaults(&cfg)
+	return &cfg, nil
+}
+
+func validateConfig(cfg *Config) error {
+	for name, mc := range cfg.Monitors {
+		if name == "" || strings.ContainsAny(name, " \t\n") {
+			return fmt.Errorf("invalid monitor name %q", name)
+		}
+		if mc.Target == "" {
+			return fmt.Errorf("monitor %q missing target", name)
+		}
+		switch mc.Type {
+		case TypeHTTP:
+			u, err := url.Parse(mc.Target)
+			if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+				return fmt.Errorf("monitor %q: target must be an http(s) URL", name)
+			}
+		case TypeTCP:
+			if _, _, err := net.SplitHostPort(mc.Target); err != nil {
+				return fmt.Errorf("monitor %q: target must be host:port", name)
+			}
+		case TypeICMP:
+			if strings.HasPrefix(mc.Target, "-") || strings.ContainsAny(mc.Target, " \t/") {
+				return fmt.Errorf("monitor %q: target must be a host", name)
+			}
+		default:
+			return fmt.Errorf("monitor %q: unknown type %q (want http, tcp, or icmp)", name, mc.Type)
+		}
+		if mc.ExpectStatus != 0 && mc.Type != TypeHTTP {
+			return fmt.Errorf("monitor %q: expect_status only applies to http", name)
+		}
+	}
+	return nil
+}
+
+func applyDefaults(cfg *Config) {
+	for name, mc := range cfg.Monitors {
+		if mc.IntervalSec <= 0 {
+			mc.IntervalSec = DefaultIntervalSec
+		}
+		if mc.IntervalSec < MinIntervalSec {
+			mc.IntervalSec = MinIntervalSec
+		}
+		if mc.Failures <= 0 {
+			mc.Failures = DefaultFailures
+		}
+		if mc.TimeoutMs <= 0 {
+			mc.TimeoutMs = DefaultTimeoutMs
+		}
+		cfg.Monitors[name] = mc
+	}
+}
@@ -0,0 +1,65 @@
+package monitors
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestLoadConfigValid(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "monitors.json")
+	os.WriteFile(path, []byte(`{
+		"monitors": {
+			"nas": {"type": "http", "target": "https://nas.local", "expect_status": 200, "interval_sec": 5},
+			"ssh": {"type": "tcp", "target": "10.0.0.2:22", "failures": 1},
+			"pi":  {"type": "icmp", "target": "10.0.0.5"}
+		}
+	}`), 0644)
+
+	cfg, err := LoadConfig(path)
+	if err != nil {
+		t.Fatalf("LoadConfig: %v", err)
+	}
+	nas := cfg.Monitors["nas"]
+	if nas.IntervalSec != MinIntervalSec || nas.Failures != DefaultFailures || nas.TimeoutMs != DefaultTimeoutMs {
+		t.Errorf("nas defaults = %+v", nas)
+	}
+	if cfg.Monitors["ssh"].Failures != 1 || cfg.Monitors["pi"].IntervalSec != DefaultIntervalSec {
+		t.Errorf("monitors = %+v", cfg.Monitors)
+	}
+}
+
+func TestLoadConfigMissingFile(t *testing.T) {
+	cfg, err := LoadConfig(filepath.Join(t.TempDir(), "missing.json"))
+	if err != nil || cfg != nil {
+		t.Fatalf("LoadConfig = %v, %v; want nil, nil", cfg, err)
+	}
+}
+
+func TestLoadConfigInvalid(t *testing.T) {
+	tests := []struct {
+		name    string
+		monitor string
+		wantErr string
+	}{
+		{"missing target", `{"type":"http"}`, "missing target"},
+		{"unknown type", `{"type":"udp","target":"x:1"}`, "unknown type"},
+		{"http without scheme", `{"type":"http","target":"nas.local"}`, "http(s) URL"},
+		{"tcp without port", `{"type":"tcp","target":"10.0.0.2"}`, "host:port"},
+		{"icmp flag injection", `{"type":"icmp","target":"-f"}`, "must be a host"},
+		{"expect_status on tcp", `{"type":"tcp","target":"x:1","expect_status":200}`, "only applies to http"},
+	}
+	dir := t.TempDir()
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			path := filepath.Join(dir, "monitors.json")
+			os.WriteFile(path, []byte(`{"monitors":{"m":`+tt.monitor+`}}`), 0644)
+			_, err := LoadConfig(path)
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("error = %v, want %q", err, tt.wantErr)
+			}
+		})
+	}
+}
@@ -0,0 +1,269 @@
+package monitors
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"net"
+	"net/url"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/jdelaire/openslack/internal/events"
+	"github.com/jdelaire/openslack/internal/state"
+)
+
+const stateNamespace = "monitors"
+
+// Status is a monitor's current state.
+type Status string
+
+const (
+	StatusUnknown Status = "unknown"
+	StatusUp      Status = "up"
+	StatusDown    Status = "down"
+)
+
+// State is the persisted state of one monitor. Since is when the current
+// status began; for a down monitor it is the first failed check.
+type State struct {
+	Status    Status    `json:"status"`
+	Since     time.Time `json:"since"`
+	Failures  int       `json:"failures"`
+	LastError string    `json:"last_error,omitempty"`
+	LastCheck time.Time `json:"last_check"`
+}
+
+// Runner schedules checks and sends down and recovery notifications.
+// State is kept in the shared state store so an outage spanning a restart
+// still reports its full duration on recovery.
+type Runner struct {
+	cfg    *Config
+	store  *state.Store
+	send   func(context.Context, string) error
+	check  Checker
+	logger *slog.Logger
+	now    func() time.Time
+	events *events.Bus
+
+	mu     sync.Mutex
+	states map[string]State
+}
+
+func NewRunner(cfg *Config, store *state.Store, send func(context.Context, string) error, logger *slog.Logger) *Runner {
+	if logger == nil {
+		logger = slog.Default()
+	}
+	return &Runner{
+		cfg:    cfg,
+		store:  store,
+		send:   send,
+		check:  Check,
+		logger: logger,
+		now:    time.Now,
+		states: make(map[string]State),
+	}
+}
+
+// WithEvents publishes an events.MonitorDown event to bus whenever a
+// monitor goes down.
+func (r *Runner) WithEvents(bus *events.Bus) *Runner {
+	r.events = bus
+	return r
+}
+
+// Run checks every monitor on its own interval until ctx is cancelled.
+func (r *Runner) Run(ctx context.Context) {
+	var wg sync.WaitGroup
+	for name, mc := range r.cfg.Monitors {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			r.loop(ctx, name, mc)
+		}()
+	}
+	wg.Wait()
+}
+
+func (r *Runner) loop(ctx context.Context, name string, mc MonitorConfig) {
+	ticker := time.NewTicker(time.Duration(mc.IntervalSec) * time.Second)
+	defer ticker.Stop()
+
+	for {
+		r.runCheck(ctx, name, mc)
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+func (r *Runner) runCheck(ctx context.Context, name string, mc MonitorConfig) {
+	checkErr := r.check(ctx, mc)
+	if ctx.Err() != nil {
+		return
+	}
+
+	msg := r.record(name, mc, checkErr)
+	if msg == "" {
+		return
+	}
+	sendCtx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	if err := r.send(sendCtx, msg); err != nil {
+		r.logger.Error("monitors: send notification failed", "monitor", name, "error", err)
+	}
+}
+
+// record applies a check result and returns the notification to send on a
+// state change, or "".
+func (r *Runner) record(name string, mc MonitorConfig, checkErr error) string {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	now := r.now()
+	st, err := r.loadState(name)
+	if err != nil {
+		r.logger.Error("monitors: load state failed", "monitor", name, "error", err)
+	}
+	st.LastCheck = now
+
+	var msg string
+	if checkErr == nil {
+		if st.Status == StatusDown {
+			msg = fmt.Sprintf("%s: down for %s, now recovered", name, formatDuration(now.Sub(st.Since)))
+		}
+		if st.Status != StatusUp {
+			st.Status = StatusUp
+			st.Since = now
+		}
+		st.Failures = 0
+		st.LastError = ""
+	} else {
+		if st.Failures == 0 && st.Status != StatusDown {
+			st.Since = now
+		}
+		st.Failures++
+		st.LastError = checkErr.Error()
+		if st.Status != StatusDown && st.Failures >= mc.Failures {
+			st.Status = StatusDown
+			msg = fmt.Sprintf("%s: DOWN (%s) after %d failed checks", name, st.LastError, st.Failures)
+			r.events.Publish(events.MonitorDown, map[string]string{
+				"monitor":  name,
+				"type":     mc.Type,
+				"target":   mc.Target,
+				"host":     targetHost(mc),
+				"error":    st.LastError,
+				"failures": strconv.Itoa(st.Failures),
+			})
+		}
+	}
+
+	r.states[name] = st
+	if err := r.store.Put(stateNamespace, name, st); err != nil {
+		r.logger.Error("monitors: save state failed", "monitor", name, "error", err)
+	}
+	return msg
+}
+
+// targetHost returns the host a monitor checks: the URL's host for http,
+// the host of host:port for tcp, and the target itself for icmp.
+func targetHost(mc MonitorConfig) string {
+	switch mc.Type {
+	case TypeHTTP:
+		if u, err := url.Parse(mc.Target); err == nil {
+			return u.Hostname()
+		}
+	case TypeTCP:
+		if host, _, err := net.SplitHostPort(mc.Target); err == nil {
+			return host
+		}
+	}
+	return mc.Target
+}
+
+func (r *Runner) loadState(name string) (State, error) {
+	if st, ok := r.states[name]; ok {
+		return st, nil
+	}
+	st := State{Status: StatusUnknown}
+	if _, err := r.store.Get(stateNamespace, name, &st); err != nil {
+		return State{Status: StatusUnknown}, err
+	}
+	return st, nil
+}
+
+// StatusReport summarizes every monitor, down monitors first.
+func (r *Runner) StatusReport() string {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if len(r.cfg.Monitors) == 0 {
+		return "No monitors configured."
+	}
+
+	names := make([]string, 0, len(r.cfg.Monitors))
+	for name := range r.cfg.Monitors {
+		names = append(names, name)
+	}
+	states := make(map[string]State, len(names))
+	for _, name := range names {
+		st, err := r.loadState(name)
+		if err != nil {
+			r.logger.Error("monitors: load state failed", "monitor", name, "error", err)
+		}
+		states[name] = st
+	}
+	sort.Slice(names, func(i, j int) bool {
+		di, dj := states[names[i]].Status == StatusDown, states[names[j]].Status == StatusDown
+		if di != dj {
+			return di
+		}
+		return names[i] < names[j]
+	})
+
+	now := r.now()
+	lines := make([]string, 0, len(names))
+	for _, name := range names {
+		st := states[name]
+		mc := r.cfg.Monitors[name]
+		line := fmt.Sprintf("%s [%s %s]: ", name, mc.Type, mc.Target)
+		switch st.Status {
+		case StatusUp:
+			line += "up " + formatDuration(now.Sub(st.Since))
+			if st.Failures > 0 {
+				line += fmt.Sprintf(" (%d/%d failures: %s)", st.Failures, mc.Failures, st.LastError)
+			}
+		case StatusDown:
+			line += fmt.Sprintf("DOWN %s (%s)", formatDuration(now.Sub(st.Since)), st.LastError)
+		default:
+			line += "not checked yet"
+			if st.Failures > 0 {
+				line += fmt.Sprintf(" (%d/%d failures: %s)", st.Failures, mc.Failures, st.LastError)
+			}
+		}
+		lines = append(lines, line)
+	}
+	return strings.Join(lines, "\n")
+}
+
+// formatDuration renders a duration at minute precision, e.g. "6m" or "2h5m".
+func formatDuration(d time.Duration) string {
+	if d < time.Minute {
+		return fmt.Sprintf("%ds", int(d.Seconds()))
+	}
+	d = d.Round(time.Minute)
+	h, m := int(d.Hours()), int(d.Minutes())%60
+	switch {
+	case h >= 24:
+		return fmt.Sprintf("%dd%dh", h/24, h%24)
+	case h > 0:
+		return fmt.Sprintf("%dh%dm", h, m)
+	default:
+		return fmt.Sprintf("%dm", m)
+	}
+}
@@ -0,0 +1,212 @@
+package monitors
+
+import (
+	"context"
+	"errors"
+	"io"
+	"log/slog"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/jdelaire/openslack/internal/events"
+	"github.com/jdelaire/openslack/internal/state"
+)
+
+type runnerTest struct {
+	r       *Runner
+	sent    *[]string
+	now     *time.Time
+	failing *error
+	store   *state.Store
+}
+
+func newRunnerTest(t *testing.T, store *state.Store) runnerTest {
+	t.Helper()
+	if store == nil {
+		store = state.NewStore(filepath.Join(t.TempDir(), "state.json"))
+	}
+	cfg := &Config{Monitors: map[string]MonitorConfig{
+		"nas": {Type: TypeHTTP, Target: "https://nas.local", IntervalSec: 60, Failures: 3, TimeoutMs: 1000},
+	}}
+	now := time.Date(2026, 3, 1, 10, 0, 0, 0, time.UTC)
+	var sent []string
+	var failing error
+	r := NewRunner(cfg, store, func(_ context.Context, text string) error {
+		sent = append(sent, text)
+		return nil
+	}, slog.New(slog.NewJSONHandler(io.Discard, nil)))
+	r.now = func() time.Time { return now }
+	r.check = func(context.Context, MonitorConfig) error { return failing }
+	return runnerTest{r: r, sent: &sent, now: &now, failing: &failing, store: store}
+}
+
+func (rt runnerTest) tick() {
+	rt.r.runCheck(context.Background(), "nas", rt.r.cfg.Monitors["nas"])
+	*rt.now = rt.now.Add(time.Minute)
+}
+
+func TestRunnerPublishesMonitorDown(t *testing.T) {
+	rt := newRunnerTest(t, nil)
+	bus := events.NewBus(slog.New(slog.NewJSONHandler(io.Discard, nil)))
+	rt.r.WithEvents(bus)
+	ch, cancel := bus.Subscribe(events.MonitorDown)
+	defer cancel()
+
+	*rt.failing = errors.New("timeout")
+	for i := 0; i < 5; i++ {
+		rt.tick()
+	}
+	if len(ch) != 1 {
+		t.Fatalf("published %d events, want 1 per outage", len(ch))
+	}
+	ev := <-ch
+	if ev.Attrs["monitor"] != "nas" || ev.Attrs["host"] != "nas.local" || ev.Attrs["error"] != "timeout" || ev.Attrs["failures"] != "3" {
+		t.Fatalf("attrs = %v", ev.Attrs)
+	}
+}
+
+func TestRunnerDownAfterThresholdThenRecovers(t *testing.T) {
+	rt := newRunnerTest(t, nil)
+
+	rt.tick() // up
+	*rt.failing = errors.New("connection refused")
+	rt.tick()
+	rt.tick()
+	if len(*rt.sent) != 0 {
+		t.Fatalf("alerted before threshold: %q", *rt.sent)
+	}
+	rt.tick() // third failure
+	if len(*rt.sent) != 1 || (*rt.sent)[0] != "nas: DOWN (connection refused) after 3 failed checks" {
+		t.Fatalf("sent = %q", *rt.sent)
+	}
+
+	// Still down: no repeat alert.
+	for i := 0; i < 3; i++ {
+		rt.tick()
+	}
+	if len(*rt.sent) != 1 {
+		t.Fatalf("repeat alerts: %q", *rt.sent)
+	}
+
+	*rt.failing = nil
+	rt.tick()
+	// First failure at 10:01, recovery check at 10:07.
+	if len(*rt.sent) != 2 || (*rt.sent)[1] != "nas: down for 6m, now recovered" {
+		t.Fatalf("sent = %q", *rt.sent)
+	}
+}
+
+func TestRunnerFlapBelowThresholdIsQuiet(t *testing.T) {
+	rt := newRunnerTest(t, nil)
+
+	rt.tick()
+	*rt.failing = errors.New("timeout")
+	rt.tick()
+	rt.tick()
+	*rt.failing = nil
+	rt.tick()
+	*rt.failing = errors.New("timeout")
+	rt.tick()
+	rt.tick()
+
+	if len(*rt.sent) != 0 {
+		t.Errorf("sent = %q", *rt.sent)
+	}
+}
+
+func TestRunnerStatePersistsAcrossRestart(t *testing.T) {
+	rt := newRunnerTest(t, nil)
+	*rt.failing = errors.New("refused")
+	for i := 0; i < 3; i++ {
+		rt.tick()
+	}
+
+	// A new runner on the same store still knows the monitor is down.
+	rt2 := newRunnerTest(t, rt.store)
+	*rt2.now = rt.now.Add(20 * time.Minute)
+	rt2.tick()
+	if len(*rt2.sent) != 1 || (*rt2.sent)[0] != "nas: down for 23m, now recovered" {
+		t.Errorf("sent = %q", *rt2.sent)
+	}
+}
+
+func TestStatusReport(t *testing.T) {
+	rt := newRunnerTest(t, nil)
+	rt.r.cfg.Monitors["ssh"] = MonitorConfig{Type: TypeTCP, Target: "10.0.0.2:22", Failures: 1}
+
+	if got := rt.r.StatusReport(); !strings.Contains(got, "nas [http https://nas.local]: not checked yet") {
+		t.Errorf("report = %q", got)
+	}
+
+	rt.tick()
+	*rt.now = rt.now.Add(2 * time.Hour)
+	*rt.failing = errors.New("refused")
+	rt.r.runCheck(context.Background(), "ssh", rt.r.cfg.Monitors["ssh"])
+	*rt.now = rt.now.Add(5 * time.Minute)
+
+	want := "ssh [tcp 10.0.0.2:22]: DOWN 5m (refused)\nnas [http https://nas.local]: up 2h6m"
+	if got := rt.r.StatusReport(); got != want {
+		t.Errorf("report = %q, want %q", got, want)
+	}
+}
+
+func TestCheckHTTP(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/broken" {
+			w.WriteHeader(http.StatusServiceUnavailable)
+		}
+	}))
+	defer srv.Close()
+
+	mc := MonitorConfig{Type: TypeHTTP, Target: srv.URL, TimeoutMs: 1000}
+	if err := Check(context.Background(), mc); err != nil {
+		t.Errorf("healthy: %v", err)
+	}
+	mc.Target = srv.URL + "/broken"
+	if err := Check(context.Background(), mc); err == nil || !strings.Contains(err.Error(), "HTTP 503") {
+		t.Errorf("broken: %v", err)
+	}
+	mc.ExpectStatus = http.StatusServiceUnavailable
+	if err := Check(context.Background(), mc); err != nil {
+		t.Errorf("expected status: %v", err)
+	}
+}
+
+func TestCheckTCP(t *testing.T) {
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("listen: %v", err)
+	}
+	addr := ln.Addr().String()
+
+	mc := MonitorConfig{Type: TypeTCP, Target: addr, TimeoutMs: 1000}
+	if err := Check(context.Background(), mc); err != nil {
+		t.Errorf("open port: %v", err)
+	}
+	ln.Close()
+	if err := Check(context.Background(), mc); err == nil {
+		t.Error("expected error for closed port")
+	}
+}
+
+func TestFormatDuration(t *testing.T) {
+	tests := []struct {
+		d    time.Duration
+		want string
+	}{
+		{30 * time.Second, "30s"},
+		{6 * time.Minute, "6m"},
+		{125 * time.Minute, "2h5m"},
+		{50 * time.Hour, "2d2h"},
+	}
+	for _, tt := range tests {
+		if got := formatDuration(tt.d); got != tt.want {
+			t.Errorf("formatDuration(%v) = %q, want %q", tt.d, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,165 @@
+// Package outbox holds notifications scheduled for later delivery and
+// sends them when they come due.
+package outbox
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
+	"log/slog"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/jdelaire/openslack/internal/state"
+)
+
+const (
+	stateNamespace = "outbox"
+
+	tickInterval = 15 * time.Second
+	// MaxDelay is how late a message may be delivered, e.g. after the
+	// daemon was down, before it is dropped instead.
+	MaxDelay = 24 * time.Hour
+)
+
+// Message is a queued notification.
+type Message struct {
+	ID      string    `json:"id"`
+	Text    string    `json:"text"`
+	Source  string    `json:"source,omitempty"`
+	SendAt  time.Time `json:"send_at"`
+	Created time.Time `json:"created"`
+}
+
+// Queue persists scheduled messages in the shared state store and delivers
+// them from Run.
+type Queue struct {
+	store  *state.Store
+	send   func(context.Context, string) error
+	logger *slog.Logger
+	now    func() time.Time
+
+	mu sync.Mutex
+}
+
+func NewQueue(store *state.Store, send func(context.Context, string) error, logger *slog.Logger) *Queue {
+	if logger == nil {
+		logger = slog.Default()
+	}
+	return &Queue{
+		store:  store,
+		send:   send,
+		logger: logger,
+		now:    time.Now,
+	}
+}
+
+// Schedule queues text for delivery at sendAt and returns its ID.
+func (q *Queue) Schedule(text, source string, sendAt time.Time) (string, error) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	buf := make([]byte, 3)
+	for range 10 {
+		if _, err := rand.Read(buf); err != nil {
+			return "", err
+		}
+		id := hex.EncodeToString(buf)
+		ok, err := q.store.Get(stateNamespace, id, &Message{})
+		if err != nil {
+			return "", err
+		}
+		if ok {
+			continue
+		}
+		msg := Message{ID: id, Text: text, Source: source, SendAt: sendAt, Created: q.now()}
+		if err := q.store.Put(stateNamespace, id, msg); err != nil {
+			return "", err
+		}
+		return id, nil
+	}
+	return "", fmt.Errorf("could not allocate outbox id")
+}
+
+// Cancel removes a queued message. It reports false if no such message
+// is queued.
+func (q *Queue) Cancel(id string) (bool, error) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	return q.store.Delete(stateNamespace, id)
+}
+
+// Pending returns queued messages, soonest first.
+func (q *Queue) Pending() ([]Message, error) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	keys, err := q.store.Keys(stateNamespace)
+	if err != nil {
+		return nil, err
+	}
+	msgs := make([]Message, 0, len(keys))
+	for _, k := range keys {
+		var m Message
+		if _, err := q.store.Get(stateNamespace, k, &m); err != nil {
+			return nil, err
+		}
+		msgs = append(msgs, m)
+	}
+	sort.Slice(msgs, func(i, j int) bool {
+		if msgs[i].SendAt.Equal(msgs[j].SendAt) {
+			return msgs[i].ID < msgs[j].ID
+		}
+		return msgs[i].SendAt.Before(msgs[j].SendAt)
+	})
+	return msgs, nil
+}
+
+// Run delivers due messages until ctx is cancelled.
+func (q *Queue) Run(ctx context.Context) {
+	ticker := time.NewTicker(tickInterval)
+	defer ticker.Stop()
+
+	for {
+		q.runTick(ctx)
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// runTick sends every due message. Failed sends stay queued and are
+// retried on the next tick until they are MaxDelay late.
+func (q *Queue) runTick(ctx context.Context) {
+	msgs, err := q.Pending()
+	if err != nil {
+		q.logger.Error("outbox: list pending failed", "error", err)
+		return
+	}
+	now := q.now()
+	for _, m := range msgs {
+		if m.SendAt.After(now) {
+			break
+		}
+		if now.Sub(m.SendAt) > MaxDelay {
+			q.logger.Error("outbox: dropping overdue message", "id", m.ID, "send_at", m.SendAt)
+			q.Cancel(m.ID)
+			continue
+		}
+
+		sendCtx, cancel := context.WithTimeout(ctx, 10*time.Second)
+		err := q.send(sendCtx, m.Text)
+		cancel()
+		if err != nil {
+			q.logger.Error("outbox: send failed", "id", m.ID, "error", err)
+			continue
+		}
+		if _, err := q.Cancel(m.ID); err != nil {
+			q.logger.Error("outbox: remove sent message failed", "id", m.ID, "error", err)
+		}
+	}
+}
@@ -0,0 +1,101 @@
+package outbox
+
+import (
+	"context"
+	"errors"
+	"io"
+	"log/slog"
+	"path/filepath"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/jdelaire/openslack/internal/state"
+)
+
+func newTestQueue(t *testing.T) (*Queue, *[]string, *time.Time, *error) {
+	t.Helper()
+	var sent []string
+	var sendErr error
+	q := NewQueue(state.NewStore(filepath.Join(t.TempDir(), "state.json")), func(_ context.Context, text string) error {
+		if sendErr != nil {
+			return sendErr
+		}
+		sent = append(sent, text)
+		return nil
+	}, slog.New(slog.NewJSONHandler(io.Discard, nil)))
+	now := time.Date(2026, 3, 1, 22, 0, 0, 0, time.UTC)
+	q.now = func() time.Time { return now }
+	return q, &sent, &now, &sendErr
+}
+
+func TestQueueDeliversWhenDue(t *testing.T) {
+	q, sent, now, _ := newTestQueue(t)
+	ctx := context.Background()
+
+	morning := now.Add(9 * time.Hour)
+	if _, err := q.Schedule("batch finished", "batch.sh", morning); err != nil {
+		t.Fatalf("schedule: %v", err)
+	}
+	if _, err := q.Schedule("later", "", morning.Add(time.Hour)); err != nil {
+		t.Fatalf("schedule: %v", err)
+	}
+
+	q.runTick(ctx)
+	if len(*sent) != 0 {
+		t.Fatalf("sent early: %q", *sent)
+	}
+
+	*now = morning
+	q.runTick(ctx)
+	if !reflect.DeepEqual(*sent, []string{"batch finished"}) {
+		t.Fatalf("sent = %q", *sent)
+	}
+	pending, _ := q.Pending()
+	if len(pending) != 1 || pending[0].Text != "later" {
+		t.Errorf("pending = %+v", pending)
+	}
+}
+
+func TestQueueRetriesAndDropsOverdue(t *testing.T) {
+	q, sent, now, sendErr := newTestQueue(t)
+	ctx := context.Background()
+
+	if _, err := q.Schedule("retry me", "", *now); err != nil {
+		t.Fatalf("schedule: %v", err)
+	}
+	*sendErr = errors.New("telegram down")
+	q.runTick(ctx)
+	if pending, _ := q.Pending(); len(pending) != 1 {
+		t.Fatalf("failed message not kept: %+v", pending)
+	}
+
+	*sendErr = nil
+	q.runTick(ctx)
+	if len(*sent) != 1 {
+		t.Fatalf("retry sent = %q", *sent)
+	}
+
+	if _, err := q.Schedule("stale", "", *now); err != nil {
+		t.Fatalf("schedule: %v", err)
+	}
+	*now = now.Add(MaxDelay + time.Minute)
+	q.runTick(ctx)
+	if len(*sent) != 1 {
+		t.Errorf("overdue message sent: %q", *sent)
+	}
+	if pending, _ := q.Pending(); len(pending) != 0 {
+		t.Errorf("overdue message kept: %+v", pending)
+	}
+}
+
+func TestQueueCancel(t *testing.T) {
+	q, _, now, _ := newTestQueue(t)
+	id, _ := q.Schedule("x", "", now.Add(time.Hour))
+	if ok, err := q.Cancel(id); !ok || err != nil {
+		t.Errorf("cancel = %v, %v", ok, err)
+	}
+	if ok, _ := q.Cancel(id); ok {
+		t.Error("second cancel reported true")
+	}
+}
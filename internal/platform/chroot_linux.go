@@ -0,0 +1,26 @@
+//go:build linux
+
+package platform
+
+import (
+	"os"
+	"os/exec"
+	"syscall"
+)
+
+// Chroot makes cmd run with dir as its root directory. cmd.Dir is then
+// resolved inside dir. Without root, the child gets its own user namespace,
+// which lets it chroot while keeping the daemon user's IDs; this needs
+// unprivileged user namespaces, which most distributions enable.
+func Chroot(cmd *exec.Cmd, dir string) error {
+	if cmd.SysProcAttr == nil {
+		cmd.SysProcAttr = &syscall.SysProcAttr{}
+	}
+	cmd.SysProcAttr.Chroot = dir
+	if os.Geteuid() != 0 {
+		cmd.SysProcAttr.Cloneflags |= syscall.CLONE_NEWUSER
+		cmd.SysProcAttr.UidMappings = []syscall.SysProcIDMap{{ContainerID: os.Getuid(), HostID: os.Getuid(), Size: 1}}
+		cmd.SysProcAttr.GidMappings = []syscall.SysProcIDMap{{ContainerID: os.Getgid(), HostID: os.Getgid(), Size: 1}}
+	}
+	return nil
+}
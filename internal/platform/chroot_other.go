@@ -0,0 +1,13 @@
+//go:build !unix
+
+package platform
+
+import (
+	"errors"
+	"os/exec"
+)
+
+// Chroot is not supported on this platform.
+func Chroot(cmd *exec.Cmd, dir string) error {
+	return errors.New("chroot not supported on this platform")
+}
@@ -0,0 +1,23 @@
+//go:build unix && !linux
+
+package platform
+
+import (
+	"errors"
+	"os"
+	"os/exec"
+	"syscall"
+)
+
+// Chroot makes cmd run with dir as its root directory. cmd.Dir is then
+// resolved inside dir. Outside Linux this needs the daemon to run as root.
+func Chroot(cmd *exec.Cmd, dir string) error {
+	if os.Geteuid() != 0 {
+		return errors.New("chroot requires root on this platform")
+	}
+	if cmd.SysProcAttr == nil {
+		cmd.SysProcAttr = &syscall.SysProcAttr{}
+	}
+	cmd.SysProcAttr.Chroot = dir
+	return nil
+}
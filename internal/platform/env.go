@@ -0,0 +1,21 @@
+package platform
+
+import "strings"
+
+// FilterEnv returns the entries of environ whose names match allow. A
+// pattern ending in "*" matches names with that prefix; any other pattern
+// matches one name exactly.
+func FilterEnv(environ, allow []string) []string {
+	out := []string{}
+	for _, kv := range environ {
+		name, _, _ := strings.Cut(kv, "=")
+		for _, pattern := range allow {
+			prefix, wildcard := strings.CutSuffix(pattern, "*")
+			if name == pattern || wildcard && strings.HasPrefix(name, prefix) {
+				out = append(out, kv)
+				break
+			}
+		}
+	}
+	return out
+}
@@ -0,0 +1,23 @@
+//go:build !windows
+
+package platform
+
+import (
+	"fmt"
+	"net"
+	"os"
+)
+
+// Listen creates the local API socket at path, readable and writable only
+// by the current user.
+func Listen(path string) (net.Listener, error) {
+	ln, err := net.Listen("unix", path)
+	if err != nil {
+		return nil, fmt.Errorf("listen: %w", err)
+	}
+	if err := os.Chmod(path, 0600); err != nil {
+		ln.Close()
+		return nil, fmt.Errorf("chmod socket: %w", err)
+	}
+	return ln, nil
+}
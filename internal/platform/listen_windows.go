@@ -0,0 +1,21 @@
+//go:build windows
+
+package platform
+
+import (
+	"fmt"
+	"net"
+)
+
+// Listen creates the local API socket at path as an AF_UNIX socket, which
+// Windows 10 1803 and later support natively. Windows ignores Unix file
+// modes, so access is limited by the ACL of the directory holding path;
+// the default under the user profile admits only that user and
+// administrators.
+func Listen(path string) (net.Listener, error) {
+	ln, err := net.Listen("unix", path)
+	if err != nil {
+		return nil, fmt.Errorf("listen: %w", err)
+	}
+	return ln, nil
+}
@@ -0,0 +1,18 @@
+//go:build (!unix && !windows) || aix || solaris
+
+package platform
+
+import (
+	"errors"
+	"os"
+)
+
+// TryLock is not supported on this platform.
+func TryLock(f *os.File) error {
+	return errors.New("file locks not supported on this platform")
+}
+
+// Unlock is not supported on this platform.
+func Unlock(f *os.File) error {
+	return errors.New("file locks not supported on this platform")
+}
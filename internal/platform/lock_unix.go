@@ -0,0 +1,25 @@
+//go:build unix && !aix && !solaris
+
+package platform
+
+import (
+	"errors"
+	"os"
+	"syscall"
+)
+
+// TryLock takes an exclusive advisory lock on f without waiting. It fails
+// with ErrLocked if another open file holds the lock. The lock is dropped
+// by Unlock, or when f is closed or the process exits.
+func TryLock(f *os.File) error {
+	err := syscall.Flock(int(f.Fd()), syscall.LOCK_EX|syscall.LOCK_NB)
+	if errors.Is(err, syscall.EWOULDBLOCK) {
+		return ErrLocked
+	}
+	return err
+}
+
+// Unlock drops a lock taken with TryLock.
+func Unlock(f *os.File) error {
+	return syscall.Flock(int(f.Fd()), syscall.LOCK_UN)
+}
@@ -0,0 +1,13 @@
+// Package platform hides the differences between Unix and Windows for
+// running shell commands, serving the local socket API and locking files.
+package platform
+
+import (
+	"net"
+	"time"
+)
+
+// Dial connects to the local API socket at path.
+func Dial(path string, timeout time.Duration) (net.Conn, error) {
+	return net.DialTimeout("unix", path, timeout)
+}
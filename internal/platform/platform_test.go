@@ -0,0 +1,137 @@
+package platform
+
+import (
+	"context"
+	"errors"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+// These tests hold on every platform, keeping the Unix and Windows
+// implementations in step.
+
+func TestShellCommand(t *testing.T) {
+	out, err := ShellCommand(context.Background(), "echo hello").CombinedOutput()
+	if err != nil {
+		t.Fatalf("run: %v\n%s", err, out)
+	}
+	if !strings.Contains(string(out), "hello") {
+		t.Errorf("output = %q, want hello", out)
+	}
+}
+
+func TestShellCommandExitStatus(t *testing.T) {
+	if err := ShellCommand(context.Background(), "exit 3").Run(); err == nil {
+		t.Error("expected error for non-zero exit")
+	}
+}
+
+func TestListenDial(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "test.sock")
+	ln, err := Listen(path)
+	if err != nil {
+		t.Fatalf("listen: %v", err)
+	}
+	defer ln.Close()
+
+	go func() {
+		conn, err := ln.Accept()
+		if err != nil {
+			return
+		}
+		conn.Write([]byte("pong"))
+		conn.Close()
+	}()
+
+	conn, err := Dial(path, 2*time.Second)
+	if err != nil {
+		t.Fatalf("dial: %v", err)
+	}
+	defer conn.Close()
+	got, err := io.ReadAll(conn)
+	if err != nil || string(got) != "pong" {
+		t.Errorf("read = %q, %v", got, err)
+	}
+}
+
+func TestSplitWords(t *testing.T) {
+	tests := []struct {
+		in      string
+		want    []string
+		wantErr bool
+	}{
+		{"ls -la", []string{"ls", "-la"}, false},
+		{`  grep  "a b"  c `, []string{"grep", "a b", "c"}, false},
+		{`echo 'it''s' "say \"hi\"" a\ b`, []string{"echo", "its", `say "hi"`, "a b"}, false},
+		{`echo "" ''`, []string{"echo", "", ""}, false},
+		{`echo "a\$b"`, []string{"echo", `a\$b`}, false},
+		{`echo "open`, nil, true},
+		{`echo a\`, nil, true},
+	}
+	for _, tt := range tests {
+		got, err := SplitWords(tt.in)
+		if (err != nil) != tt.wantErr {
+			t.Errorf("SplitWords(%q) err = %v", tt.in, err)
+			continue
+		}
+		if strings.Join(got, "|") != strings.Join(tt.want, "|") || len(got) != len(tt.want) {
+			t.Errorf("SplitWords(%q) = %q, want %q", tt.in, got, tt.want)
+		}
+	}
+}
+
+func TestCommandUnknownShell(t *testing.T) {
+	if _, err := Command(context.Background(), "zsh", false, "true"); !errors.Is(err, ErrUnknownShell) {
+		t.Errorf("err = %v, want ErrUnknownShell", err)
+	}
+}
+
+func TestFilterEnv(t *testing.T) {
+	env := []string{"PATH=/bin", "HOME=/root", "LC_ALL=C", "LC_TIME=C", "TOKEN=x", "PATHEXT=.exe"}
+	got := FilterEnv(env, []string{"PATH", "LC_*"})
+	if strings.Join(got, " ") != "PATH=/bin LC_ALL=C LC_TIME=C" {
+		t.Errorf("FilterEnv = %q", got)
+	}
+	if got := FilterEnv(env, nil); got == nil || len(got) != 0 {
+		t.Errorf("empty allowlist = %#v, want empty non-nil", got)
+	}
+}
+
+func TestTryLock(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "lock")
+	a, err := os.OpenFile(path, os.O_CREATE|os.O_RDWR, 0o600)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer a.Close()
+	b, err := os.OpenFile(path, os.O_RDWR, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer b.Close()
+
+	if err := TryLock(a); err != nil {
+		t.Fatalf("TryLock: %v", err)
+	}
+	if err := TryLock(b); !errors.Is(err, ErrLocked) {
+		t.Errorf("second TryLock = %v, want ErrLocked", err)
+	}
+	if err := Unlock(a); err != nil {
+		t.Fatalf("Unlock: %v", err)
+	}
+	if err := TryLock(b); err != nil {
+		t.Errorf("TryLock after Unlock: %v", err)
+	}
+	Unlock(b)
+
+	// Closing the file drops its lock, as a crash would.
+	TryLock(a)
+	a.Close()
+	if err := TryLock(b); err != nil {
+		t.Errorf("TryLock after the holder closed: %v", err)
+	}
+}
@@ -0,0 +1,126 @@
+package platform
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os/exec"
+	"strings"
+)
+
+// Shell names accepted by Command. The empty name selects the platform
+// default: bash on Unix, PowerShell on Windows.
+const (
+	ShellDefault = ""
+	ShellSh      = "sh"
+	ShellBash    = "bash"
+	ShellNone    = "none"
+)
+
+// ErrUnknownShell is returned for a shell name Command does not support.
+var ErrUnknownShell = errors.New("unknown shell")
+
+// ValidShell reports an error unless name is a supported shell.
+func ValidShell(name string) error {
+	switch name {
+	case ShellDefault, ShellSh, ShellBash, ShellNone:
+		return nil
+	}
+	return fmt.Errorf("%w %q (want sh, bash or none)", ErrUnknownShell, name)
+}
+
+// ShellCommand runs command through the platform default shell in login
+// mode.
+func ShellCommand(ctx context.Context, command string) *exec.Cmd {
+	return defaultShell(ctx, command, true)
+}
+
+// Command returns a command running command through the named shell. With
+// login set, sh and bash start as login shells. ShellNone splits command
+// into words, honouring quotes and backslashes, and runs them directly
+// without any shell.
+func Command(ctx context.Context, shell string, login bool, command string) (*exec.Cmd, error) {
+	switch shell {
+	case ShellDefault:
+		return defaultShell(ctx, command, login), nil
+	case ShellSh, ShellBash:
+		return posixShell(ctx, shell, command, login), nil
+	case ShellNone:
+		argv, err := SplitWords(command)
+		if err != nil {
+			return nil, err
+		}
+		if len(argv) == 0 {
+			return nil, errors.New("empty command")
+		}
+		return exec.CommandContext(ctx, argv[0], argv[1:]...), nil
+	}
+	return nil, ValidShell(shell)
+}
+
+func posixShell(ctx context.Context, shell, command string, login bool) *exec.Cmd {
+	if login {
+		return exec.CommandContext(ctx, shell, "-l", "-c", command)
+	}
+	return exec.CommandContext(ctx, shell, "-c", command)
+}
+
+// SplitWords splits s into words the way a POSIX shell would, without
+// expansions: single quotes are literal, double quotes allow backslash
+// escapes of " and \, and an unquoted backslash escapes the next byte.
+func SplitWords(s string) ([]string, error) {
+	var (
+		words   []string
+		word    strings.Builder
+		inWord  bool
+		quote   byte
+		escaped bool
+	)
+	for i := 0; i < len(s); i++ {
+		c := s[i]
+		switch {
+		case escaped:
+			word.WriteByte(c)
+			escaped = false
+		case quote == '\'':
+			if c == '\'' {
+				quote = 0
+			} else {
+				word.WriteByte(c)
+			}
+		case quote == '"':
+			switch {
+			case c == '"':
+				quote = 0
+			case c == '\\' && i+1 < len(s) && (s[i+1] == '"' || s[i+1] == '\\'):
+				i++
+				word.WriteByte(s[i])
+			default:
+				word.WriteByte(c)
+			}
+		case c == '\\':
+			escaped, inWord = true, true
+		case c == '\'' || c == '"':
+			quote, inWord = c, true
+		case c == ' ' || c == '\t' || c == '\n':
+			if inWord {
+				words = append(words, word.String())
+				word.Reset()
+				inWord = false
+			}
+		default:
+			word.WriteByte(c)
+			inWord = true
+		}
+	}
+	if quote != 0 {
+		return nil, fmt.Errorf("unterminated %c quote", quote)
+	}
+	if escaped {
+		return nil, errors.New("trailing backslash")
+	}
+	if inWord {
+		words = append(words, word.String())
+	}
+	return words, nil
+}
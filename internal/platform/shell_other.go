@@ -0,0 +1,14 @@
+//go:build !windows
+
+package platform
+
+import (
+	"context"
+	"os/exec"
+)
+
+// defaultShell runs command through bash, as a login shell so the user's
+// PATH and profile apply unless login is false.
+func defaultShell(ctx context.Context, command string, login bool) *exec.Cmd {
+	return posixShell(ctx, "bash", command, login)
+}
@@ -0,0 +1,18 @@
+//go:build windows
+
+package platform
+
+import (
+	"context"
+	"os/exec"
+)
+
+// defaultShell runs command through Windows PowerShell, or cmd.exe where
+// PowerShell is not installed. Neither has a login mode; the PowerShell
+// profile is always skipped to keep commands fast and predictable.
+func defaultShell(ctx context.Context, command string, _ bool) *exec.Cmd {
+	if path, err := exec.LookPath("powershell.exe"); err == nil {
+		return exec.CommandContext(ctx, path, "-NoLogo", "-NoProfile", "-NonInteractive", "-Command", command)
+	}
+	return exec.CommandContext(ctx, "cmd.exe", "/C", command)
+}
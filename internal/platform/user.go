@@ -0,0 +1,14 @@
+package platform
+
+// User is an account a command can run as.
+type User struct {
+	Name     string
+	Home     string
+	UID, GID uint32
+	Groups   []uint32
+}
+
+// IsRoot reports whether u is the superuser.
+func (u *User) IsRoot() bool {
+	return u.UID == 0
+}
@@ -0,0 +1,20 @@
+//go:build !unix
+
+package platform
+
+import (
+	"errors"
+	"os/exec"
+)
+
+var errRunAs = errors.New("running commands as another user is not supported on this platform")
+
+// LookupUser is not supported on this platform.
+func LookupUser(name string) (*User, error) {
+	return nil, errRunAs
+}
+
+// RunAs is not supported on this platform.
+func RunAs(cmd *exec.Cmd, u *User) error {
+	return errRunAs
+}
@@ -0,0 +1,69 @@
+//go:build unix
+
+package platform
+
+import (
+	"fmt"
+	"os"
+	"os/exec"
+	"os/user"
+	"strconv"
+	"strings"
+	"syscall"
+)
+
+// LookupUser finds the account called name.
+func LookupUser(name string) (*User, error) {
+	u, err := user.Lookup(name)
+	if err != nil {
+		return nil, err
+	}
+	uid, err := strconv.ParseUint(u.Uid, 10, 32)
+	if err != nil {
+		return nil, fmt.Errorf("user %s: uid %q: %w", name, u.Uid, err)
+	}
+	gid, err := strconv.ParseUint(u.Gid, 10, 32)
+	if err != nil {
+		return nil, fmt.Errorf("user %s: gid %q: %w", name, u.Gid, err)
+	}
+	out := &User{Name: u.Username, Home: u.HomeDir, UID: uint32(uid), GID: uint32(gid)}
+
+	ids, err := u.GroupIds()
+	if err != nil {
+		return nil, fmt.Errorf("user %s: groups: %w", name, err)
+	}
+	for _, id := range ids {
+		g, err := strconv.ParseUint(id, 10, 32)
+		if err != nil {
+			return nil, fmt.Errorf("user %s: group %q: %w", name, id, err)
+		}
+		out.Groups = append(out.Groups, uint32(g))
+	}
+	return out, nil
+}
+
+// RunAs makes cmd run with u's user, group and supplementary groups, and
+// points HOME, USER and LOGNAME at u where the environment has them.
+// Switching to another user needs the daemon to run as root.
+func RunAs(cmd *exec.Cmd, u *User) error {
+	if cmd.SysProcAttr == nil {
+		cmd.SysProcAttr = &syscall.SysProcAttr{}
+	}
+	cmd.SysProcAttr.Credential = &syscall.Credential{Uid: u.UID, Gid: u.GID, Groups: u.Groups}
+
+	env := cmd.Env
+	if env == nil {
+		env = os.Environ()
+	}
+	cmd.Env = make([]string, 0, len(env))
+	for _, kv := range env {
+		switch key, _, _ := strings.Cut(kv, "="); key {
+		case "HOME":
+			kv = "HOME=" + u.Home
+		case "USER", "LOGNAME":
+			kv = key + "=" + u.Name
+		}
+		cmd.Env = append(cmd.Env, kv)
+	}
+	return nil
+}
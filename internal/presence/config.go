@@ -0,0 +1,112 @@
+// Package presence scans the local network for devices and reports new
+// devices and tracked devices arriving or leaving.
+package presence
+
+import (
+	"encoding/json"
+	"fmt"
+	"net"
+	"os"
+	"strings"
+)
+
+// Defaults.
+const (
+	DefaultIntervalSec  = 300
+	MinIntervalSec      = 60
+	DefaultAwayAfterSec = 900
+	DefaultForgetDays   = 30
+	maxSweepHosts       = 1024
+)
+
+// Config is the presence configuration. Tracked maps a friendly name to a
+// MAC address. Subnet, if set, is swept before each scan so the ARP table
+// includes idle devices. Untracked devices not seen for ForgetAfterDays
+// are forgotten, and reported as new if they come back.
+type Config struct {
+	IntervalSec     int               `json:"interval_sec"`
+	AwayAfterSec    int               `json:"away_after_sec"`
+	ForgetAfterDays int               `json:"forget_after_days"`
+	Subnet          string            `json:"subnet"`
+	MDNS            *bool             `json:"mdns"`
+	NotifyUnknown   *bool             `json:"notify_unknown"`
+	Tracked         map[string]string `json:"tracked"`
+}
+
+// LoadConfig reads and validates a presence config file.
+// Returns nil, nil if the file does not exist.
+func LoadConfig(path string) (*Config, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("read presence config: %w", err)
+	}
+
+	var cfg Config
+	if err := json.Unmarshal(data, &cfg); err != nil {
+		return nil, fmt.Errorf("parse presence config: %w", err)
+	}
+
+	if err := validateConfig(&cfg); err != nil {
+		return nil, err
+	}
+
+	applyDefaults(&cfg)
+	return &cfg, nil
+}
+
+func validateConfig(cfg *Config) error {
+	if cfg.Subnet != "" {
+		ip, ipnet, err := net.ParseCIDR(cfg.Subnet)
+		if err != nil || ip.To4() == nil {
+			return fmt.Errorf("presence subnet must be an IPv4 CIDR like 192.168.1.0/24")
+		}
+		if ones, bits := ipnet.Mask.Size(); bits-ones > 10 {
+			return fmt.Errorf("presence subnet %s is too large to sweep (max /22)", cfg.Subnet)
+		}
+	}
+	seen := make(map[string]string)
+	for name, mac := range cfg.Tracked {
+		if name == "" || strings.ContainsAny(name, " \t\n") {
+			return fmt.Errorf("invalid tracked device name %q", name)
+		}
+		hw, err := net.ParseMAC(mac)
+		if err != nil {
+			return fmt.Errorf("tracked device %q: invalid mac %q", name, mac)
+		}
+		if other, dup := seen[hw.String()]; dup {
+			return fmt.Errorf("tracked devices %q and %q share mac %s", other, name, hw)
+		}
+		seen[hw.String()] = name
+		cfg.Tracked[name] = hw.String()
+	}
+	if cfg.IntervalSec < 0 || cfg.AwayAfterSec < 0 || cfg.ForgetAfterDays < 0 {
+		return fmt.Errorf("presence intervals must not be negative")
+	}
+	return nil
+}
+
+func applyDefaults(cfg *Config) {
+	if cfg.IntervalSec == 0 {
+		cfg.IntervalSec = DefaultIntervalSec
+	}
+	if cfg.IntervalSec < MinIntervalSec {
+		cfg.IntervalSec = MinIntervalSec
+	}
+	if cfg.AwayAfterSec == 0 {
+		cfg.AwayAfterSec = DefaultAwayAfterSec
+	}
+	if cfg.ForgetAfterDays == 0 {
+		cfg.ForgetAfterDays = DefaultForgetDays
+	}
+	if cfg.MDNS == nil {
+		enabled := true
+		cfg.MDNS = &enabled
+	}
+	if cfg.NotifyUnknown == nil {
+		enabled := true
+		cfg.NotifyUnknown = &enabled
+	}
+}
@@ -0,0 +1,56 @@
+package presence
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestLoadConfigDefaults(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "presence.json")
+	os.WriteFile(path, []byte(`{"subnet": "192.168.1.0/24", "tracked": {"phone": "A4-83-E7-01-02-03"}, "interval_sec": 30}`), 0644)
+
+	cfg, err := LoadConfig(path)
+	if err != nil {
+		t.Fatalf("LoadConfig: %v", err)
+	}
+	if cfg.IntervalSec != MinIntervalSec || cfg.AwayAfterSec != DefaultAwayAfterSec || cfg.ForgetAfterDays != DefaultForgetDays {
+		t.Errorf("intervals = %d, %d, %d", cfg.IntervalSec, cfg.AwayAfterSec, cfg.ForgetAfterDays)
+	}
+	if !*cfg.MDNS || !*cfg.NotifyUnknown {
+		t.Errorf("mdns = %v, notify_unknown = %v", *cfg.MDNS, *cfg.NotifyUnknown)
+	}
+	if mac := cfg.Tracked["phone"]; mac != "a4:83:e7:01:02:03" {
+		t.Errorf("tracked mac = %q, want normalized", mac)
+	}
+}
+
+func TestLoadConfigMissingFile(t *testing.T) {
+	cfg, err := LoadConfig(filepath.Join(t.TempDir(), "missing.json"))
+	if err != nil || cfg != nil {
+		t.Fatalf("LoadConfig = %v, %v; want nil, nil", cfg, err)
+	}
+}
+
+func TestLoadConfigInvalid(t *testing.T) {
+	tests := []struct {
+		json string
+		want string
+	}{
+		{`{"subnet": "192.168.1.0"}`, "IPv4 CIDR"},
+		{`{"subnet": "10.0.0.0/16"}`, "too large"},
+		{`{"tracked": {"phone": "nope"}}`, "invalid mac"},
+		{`{"tracked": {"my phone": "a4:83:e7:01:02:03"}}`, "invalid tracked device name"},
+		{`{"tracked": {"a": "a4:83:e7:01:02:03", "b": "A4:83:E7:01:02:03"}}`, "share mac"},
+		{`{"forget_after_days": -1}`, "must not be negative"},
+	}
+	for _, tt := range tests {
+		path := filepath.Join(t.TempDir(), "presence.json")
+		os.WriteFile(path, []byte(tt.json), 0644)
+		_, err := LoadConfig(path)
+		if err == nil || !strings.Contains(err.Error(), tt.want) {
+			t.Errorf("LoadConfig(%s) error = %v, want %q", tt.json, err, tt.want)
+		}
+	}
+}
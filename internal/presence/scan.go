@@ -0,0 +1,236 @@
+package presence
+
+import (
+	"bufio"
+	"context"
+	"encoding/binary"
+	"fmt"
+	"io"
+	"net"
+	"os"
+	"os/exec"
+	"strings"
+	"sync"
+	"time"
+
+	"golang.org/x/net/dns/dnsmessage"
+)
+
+const (
+	sweepWorkers = 64
+	sweepSettle  = 2 * time.Second
+	mdnsWait     = 2 * time.Second
+)
+
+// Device is one host seen on the network.
+type Device struct {
+	MAC      string
+	IP       string
+	Hostname string
+}
+
+// scan sweeps the subnet if configured, asks mDNS responders for their
+// names, and returns the devices in the ARP table.
+func scan(ctx context.Context, cfg *Config) ([]Device, error) {
+	if cfg.Subnet != "" {
+		sweep(ctx, cfg.Subnet)
+	}
+	var names map[string]string
+	if *cfg.MDNS {
+		names = queryMDNS(ctx)
+	}
+	devices, err := readARP(ctx)
+	if err != nil {
+		return nil, err
+	}
+	for i := range devices {
+		devices[i].Hostname = names[devices[i].IP]
+	}
+	return devices, nil
+}
+
+// sweep sends a UDP datagram to the discard port of every host in subnet.
+// Nothing needs to answer: resolving each address is enough to populate
+// the kernel's ARP cache, and it needs no privileges.
+func sweep(ctx context.Context, subnet string) {
+	_, ipnet, err := net.ParseCIDR(subnet)
+	if err != nil {
+		return
+	}
+	base := binary.BigEndian.Uint32(ipnet.IP.To4())
+	ones, bits := ipnet.Mask.Size()
+	size := uint32(1) << (bits - ones)
+	if size > maxSweepHosts {
+		return
+	}
+
+	hosts := make(chan net.IP)
+	var wg sync.WaitGroup
+	for range sweepWorkers {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for ip := range hosts {
+				conn, err := net.Dial("udp4", net.JoinHostPort(ip.String(), "9"))
+				if err != nil {
+					continue
+				}
+				conn.Write([]byte{0})
+				conn.Close()
+			}
+		}()
+	}
+	for i := uint32(1); i < size-1; i++ {
+		ip := make(net.IP, 4)
+		binary.BigEndian.PutUint32(ip, base+i)
+		select {
+		case hosts <- ip:
+		case <-ctx.Done():
+		}
+		if ctx.Err() != nil {
+			break
+		}
+	}
+	close(hosts)
+	wg.Wait()
+
+	select {
+	case <-time.After(sweepSettle):
+	case <-ctx.Done():
+	}
+}
+
+// queryMDNS sends a DNS-SD service enumeration query and collects the A
+// records in the responses, mapping IP to hostname. Responders answer a
+// query from a non-5353 port by unicast, so no multicast join is needed.
+func queryMDNS(ctx context.Context) map[string]string {
+	names := make(map[string]string)
+	conn, err := net.ListenUDP("udp4", &net.UDPAddr{})
+	if err != nil {
+		return names
+	}
+	defer conn.Close()
+
+	q, err := mdnsQuery()
+	if err != nil {
+		return names
+	}
+	if _, err := conn.WriteToUDP(q, &net.UDPAddr{IP: net.IPv4(224, 0, 0, 251), Port: 5353}); err != nil {
+		return names
+	}
+
+	deadline := time.Now().Add(mdnsWait)
+	if d, ok := ctx.Deadline(); ok && d.Before(deadline) {
+		deadline = d
+	}
+	conn.SetReadDeadline(deadline)
+	buf := make([]byte, 9000)
+	for {
+		n, _, err := conn.ReadFromUDP(buf)
+		if err != nil {
+			return names
+		}
+		parseMDNSResponse(buf[:n], names)
+	}
+}
+
+func mdnsQuery() ([]byte, error) {
+	b := dnsmessage.NewBuilder(nil, dnsmessage.Header{})
+	if err := b.StartQuestions(); err != nil {
+		return nil, err
+	}
+	if err := b.Question(dnsmessage.Question{
+		Name:  dnsmessage.MustNewName("_services._dns-sd._udp.local."),
+		Type:  dnsmessage.TypePTR,
+		Class: dnsmessage.ClassINET,
+	}); err != nil {
+		return nil, err
+	}
+	return b.Finish()
+}
+
+// parseMDNSResponse adds A records from msg to names.
+func parseMDNSResponse(msg []byte, names map[string]string) {
+	var m dnsmessage.Message
+	if err := m.Unpack(msg); err != nil {
+		return
+	}
+	for _, rr := range append(m.Answers, m.Additionals...) {
+		a, ok := rr.Body.(*dnsmessage.AResource)
+		if !ok {
+			continue
+		}
+		name := strings.TrimSuffix(strings.TrimSuffix(rr.Header.Name.String(), "."), ".local")
+		names[net.IP(a.A[:]).String()] = name
+	}
+}
+
+// readARP returns resolved entries from the kernel ARP table, using
+// /proc/net/arp on Linux and `arp -an` elsewhere.
+func readARP(ctx context.Context) ([]Device, error) {
+	if f, err := os.Open("/proc/net/arp"); err == nil {
+		defer f.Close()
+		return parseProcARP(f), nil
+	}
+	out, err := exec.CommandContext(ctx, "arp", "-an").Output()
+	if err != nil {
+		return nil, fmt.Errorf("arp: %w", err)
+	}
+	return parseARPCommand(strings.NewReader(string(out))), nil
+}
+
+func parseProcARP(r io.Reader) []Device {
+	var devices []Device
+	sc := bufio.NewScanner(r)
+	sc.Scan() // header
+	for sc.Scan() {
+		f := strings.Fields(sc.Text())
+		if len(f) < 4 || f[2] == "0x0" {
+			continue
+		}
+		if d, ok := device(f[0], f[3]); ok {
+			devices = append(devices, d)
+		}
+	}
+	return devices
+}
+
+// parseARPCommand parses `arp -an` lines such as
+// "? (192.168.1.20) at a4:83:e7:1:2:3 on en0 ifscope [ethernet]".
+func parseARPCommand(r io.Reader) []Device {
+	var devices []Device
+	sc := bufio.NewScanner(r)
+	for sc.Scan() {
+		f := strings.Fields(sc.Text())
+		if len(f) < 4 || f[2] != "at" {
+			continue
+		}
+		if d, ok := device(strings.Trim(f[1], "()"), f[3]); ok {
+			devices = append(devices, d)
+		}
+	}
+	return devices
+}
+
+// device normalizes an ARP entry, dropping incomplete, broadcast and
+// multicast ones.
+// macOS prints MAC octets without leading zeros, so they are padded first.
+func device(ip, mac string) (Device, bool) {
+	parts := strings.Split(mac, ":")
+	if len(parts) != 6 {
+		return Device{}, false
+	}
+	for i, p := range parts {
+		if len(p) == 1 {
+			parts[i] = "0" + p
+		}
+	}
+	hw, err := net.ParseMAC(strings.Join(parts, ":"))
+	if err != nil || hw.String() == "00:00:00:00:00:00" || hw[0]&1 == 1 {
+		return Device{}, false
+	}
+	if net.ParseIP(ip) == nil {
+		return Device{}, false
+	}
+	return Device{MAC: hw.String(), IP: ip}, true
+}
@@ -0,0 +1,65 @@
+package presence
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+
+	"golang.org/x/net/dns/dnsmessage"
+)
+
+func TestParseProcARP(t *testing.T) {
+	in := `IP address       HW type     Flags       HW address            Mask     Device
+192.168.1.1      0x1         0x2         34:12:98:aa:bb:cc     *        wlan0
+192.168.1.20     0x1         0x2         A4:83:E7:01:02:03     *        wlan0
+192.168.1.30     0x1         0x0         00:00:00:00:00:00     *        wlan0
+`
+	want := []Device{
+		{MAC: "34:12:98:aa:bb:cc", IP: "192.168.1.1"},
+		{MAC: "a4:83:e7:01:02:03", IP: "192.168.1.20"},
+	}
+	if got := parseProcARP(strings.NewReader(in)); !reflect.DeepEqual(got, want) {
+		t.Errorf("parseProcARP = %+v, want %+v", got, want)
+	}
+}
+
+func TestParseARPCommand(t *testing.T) {
+	in := `? (192.168.1.1) at 34:12:98:aa:bb:cc on en0 ifscope [ethernet]
+? (192.168.1.20) at a4:83:e7:1:2:3 on en0 ifscope [ethernet]
+? (192.168.1.31) at (incomplete) on en0 ifscope [ethernet]
+? (192.168.1.255) at ff:ff:ff:ff:ff:ff on en0 ifscope [ethernet]
+? (224.0.0.251) at 1:0:5e:0:0:fb on en0 ifscope permanent [ethernet]
+`
+	want := []Device{
+		{MAC: "34:12:98:aa:bb:cc", IP: "192.168.1.1"},
+		{MAC: "a4:83:e7:01:02:03", IP: "192.168.1.20"},
+	}
+	if got := parseARPCommand(strings.NewReader(in)); !reflect.DeepEqual(got, want) {
+		t.Errorf("parseARPCommand = %+v, want %+v", got, want)
+	}
+}
+
+func TestParseMDNSResponse(t *testing.T) {
+	b := dnsmessage.NewBuilder(nil, dnsmessage.Header{Response: true, Authoritative: true})
+	b.StartAnswers()
+	b.PTRResource(dnsmessage.ResourceHeader{
+		Name:  dnsmessage.MustNewName("_services._dns-sd._udp.local."),
+		Class: dnsmessage.ClassINET,
+	}, dnsmessage.PTRResource{PTR: dnsmessage.MustNewName("_airplay._tcp.local.")})
+	b.StartAdditionals()
+	b.AResource(dnsmessage.ResourceHeader{
+		Name:  dnsmessage.MustNewName("Living-Room-TV.local."),
+		Class: dnsmessage.ClassINET,
+	}, dnsmessage.AResource{A: [4]byte{192, 168, 1, 40}})
+	msg, err := b.Finish()
+	if err != nil {
+		t.Fatalf("build: %v", err)
+	}
+
+	names := make(map[string]string)
+	parseMDNSResponse(msg, names)
+	parseMDNSResponse([]byte("garbage"), names)
+	if want := map[string]string{"192.168.1.40": "Living-Room-TV"}; !reflect.DeepEqual(names, want) {
+		t.Errorf("names = %v, want %v", names, want)
+	}
+}
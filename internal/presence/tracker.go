@@ -0,0 +1,272 @@
+package presence
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/jdelaire/openslack/internal/state"
+)
+
+const (
+	devicesNamespace = "presence"
+	metaNamespace    = "presence.meta"
+)
+
+// Record is the persisted state of one device, keyed by MAC.
+type Record struct {
+	MAC       string    `json:"mac"`
+	IP        string    `json:"ip"`
+	Hostname  string    `json:"hostname,omitempty"`
+	FirstSeen time.Time `json:"first_seen"`
+	LastSeen  time.Time `json:"last_seen"`
+	Present   bool      `json:"present"`
+}
+
+// Tracker scans on an interval and notifies when an unknown device first
+// appears or a tracked device arrives or leaves. A tracked device leaves
+// once it has been missing for AwayAfterSec, since phones drop off Wi-Fi
+// while asleep.
+type Tracker struct {
+	cfg    *Config
+	names  map[string]string // MAC -> tracked name
+	store  *state.Store
+	send   func(context.Context, string) error
+	scan   func(context.Context) ([]Device, error)
+	logger *slog.Logger
+	now    func() time.Time
+
+	mu sync.Mutex
+}
+
+func NewTracker(cfg *Config, store *state.Store, send func(context.Context, string) error, logger *slog.Logger) *Tracker {
+	if logger == nil {
+		logger = slog.Default()
+	}
+	names := make(map[string]string, len(cfg.Tracked))
+	for name, mac := range cfg.Tracked {
+		names[mac] = name
+	}
+	return &Tracker{
+		cfg:    cfg,
+		names:  names,
+		store:  store,
+		send:   send,
+		scan:   func(ctx context.Context) ([]Device, error) { return scan(ctx, cfg) },
+		logger: logger,
+		now:    time.Now,
+	}
+}
+
+// Run scans every IntervalSec until ctx is cancelled.
+func (t *Tracker) Run(ctx context.Context) {
+	ticker := time.NewTicker(time.Duration(t.cfg.IntervalSec) * time.Second)
+	defer ticker.Stop()
+
+	for {
+		t.runScan(ctx)
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+func (t *Tracker) runScan(ctx context.Context) {
+	scanCtx, cancel := context.WithTimeout(ctx, time.Minute)
+	devices, err := t.scan(scanCtx)
+	cancel()
+	if err != nil {
+		if ctx.Err() == nil {
+			t.logger.Warn("presence: scan failed", "error", err)
+		}
+		return
+	}
+
+	for _, msg := range t.record(devices) {
+		sendCtx, cancel := context.WithTimeout(ctx, 10*time.Second)
+		if err := t.send(sendCtx, msg); err != nil {
+			t.logger.Error("presence: send notification failed", "error", err)
+		}
+		cancel()
+	}
+}
+
+// record applies a scan result and returns the notifications to send. The
+// first scan only learns the network, so existing devices are not reported
+// as new. Untracked devices past ForgetAfterDays are dropped, and the
+// records are written back at once.
+func (t *Tracker) record(devices []Device) []string {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	now := t.now()
+	var seeded bool
+	if _, err := t.store.Get(metaNamespace, "seeded", &seeded); err != nil {
+		t.logger.Error("presence: load state failed", "error", err)
+		return nil
+	}
+	recs, err := t.loadAll()
+	if err != nil {
+		t.logger.Error("presence: load state failed", "error", err)
+		return nil
+	}
+
+	var msgs []string
+	seen := make(map[string]bool, len(devices))
+	for _, d := range devices {
+		if seen[d.MAC] {
+			continue
+		}
+		seen[d.MAC] = true
+
+		rec, known := recs[d.MAC]
+		if !known {
+			rec = Record{MAC: d.MAC, FirstSeen: now}
+		}
+		rec.IP, rec.LastSeen = d.IP, now
+		if d.Hostname != "" {
+			rec.Hostname = d.Hostname
+		}
+
+		name, tracked := t.names[d.MAC]
+		switch {
+		case tracked && !rec.Present:
+			if seeded {
+				msgs = append(msgs, fmt.Sprintf("%s arrived (%s)", name, rec.IP))
+			}
+		case !known && !tracked && seeded && *t.cfg.NotifyUnknown:
+			msgs = append(msgs, fmt.Sprintf("New device on network: %s", rec.label()))
+		}
+		rec.Present = true
+		recs[d.MAC] = rec
+	}
+
+	away := time.Duration(t.cfg.AwayAfterSec) * time.Second
+	for mac, name := range t.names {
+		if seen[mac] {
+			continue
+		}
+		rec, known := recs[mac]
+		if !known || !rec.Present || now.Sub(rec.LastSeen) < away {
+			continue
+		}
+		rec.Present = false
+		recs[mac] = rec
+		msgs = append(msgs, fmt.Sprintf("%s left (last seen %s)", name, rec.LastSeen.Format("15:04")))
+	}
+
+	forget := now.AddDate(0, 0, -t.cfg.ForgetAfterDays)
+	values := make(map[string]any, len(recs))
+	for mac, rec := range recs {
+		if _, tracked := t.names[mac]; tracked || rec.LastSeen.After(forget) {
+			values[mac] = rec
+		}
+	}
+	if err := t.store.Replace(devicesNamespace, values); err != nil {
+		t.logger.Error("presence: save state failed", "error", err)
+	}
+
+	if !seeded {
+		if err := t.store.Put(metaNamespace, "seeded", true); err != nil {
+			t.logger.Error("presence: save state failed", "error", err)
+		}
+	}
+	sort.Strings(msgs)
+	return msgs
+}
+
+// Report lists tracked devices with home/away state, then every other
+// device seen within the last day.
+func (t *Tracker) Report() (string, error) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	now := t.now()
+	recs, err := t.loadAll()
+	if err != nil {
+		return "", err
+	}
+
+	var tracked []string
+	for mac, name := range t.names {
+		rec, ok := recs[mac]
+		switch {
+		case !ok:
+			tracked = append(tracked, fmt.Sprintf("%s: never seen", name))
+		case rec.Present:
+			tracked = append(tracked, fmt.Sprintf("%s: home (%s)", name, rec.IP))
+		default:
+			tracked = append(tracked, fmt.Sprintf("%s: away, last seen %s ago", name, formatAgo(now.Sub(rec.LastSeen))))
+		}
+	}
+	sort.Strings(tracked)
+
+	var others []Record
+	for mac, rec := range recs {
+		if _, ok := t.names[mac]; !ok && now.Sub(rec.LastSeen) < 24*time.Hour {
+			others = append(others, rec)
+		}
+	}
+	sort.Slice(others, func(i, j int) bool { return others[i].IP < others[j].IP })
+
+	var b strings.Builder
+	if len(tracked) > 0 {
+		b.WriteString(strings.Join(tracked, "\n"))
+	}
+	if len(others) > 0 {
+		if b.Len() > 0 {
+			b.WriteString("\n\n")
+		}
+		fmt.Fprintf(&b, "Seen in the last 24h (%d):", len(others))
+		for _, rec := range others {
+			fmt.Fprintf(&b, "\n%s, %s ago", rec.label(), formatAgo(now.Sub(rec.LastSeen)))
+		}
+	}
+	if b.Len() == 0 {
+		return "No devices seen yet.", nil
+	}
+	return b.String(), nil
+}
+
+func (r Record) label() string {
+	if r.Hostname != "" {
+		return fmt.Sprintf("%s (%s, %s)", r.Hostname, r.IP, r.MAC)
+	}
+	return fmt.Sprintf("%s (%s)", r.IP, r.MAC)
+}
+
+func formatAgo(d time.Duration) string {
+	switch {
+	case d < time.Minute:
+		return "<1m"
+	case d < time.Hour:
+		return fmt.Sprintf("%dm", d/time.Minute)
+	case d < 48*time.Hour:
+		return fmt.Sprintf("%dh", d/time.Hour)
+	default:
+		return fmt.Sprintf("%dd", d/(24*time.Hour))
+	}
+}
+
+// loadAll returns every device record, keyed by MAC.
+func (t *Tracker) loadAll() (map[string]Record, error) {
+	macs, err := t.store.Keys(devicesNamespace)
+	if err != nil {
+		return nil, err
+	}
+	recs := make(map[string]Record, len(macs))
+	for _, mac := range macs {
+		var rec Record
+		if _, err := t.store.Get(devicesNamespace, mac, &rec); err != nil {
+			return nil, err
+		}
+		recs[mac] = rec
+	}
+	return recs, nil
+}
@@ -0,0 +1,123 @@
+package presence
+
+import (
+	"context"
+	"io"
+	"log/slog"
+	"path/filepath"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/jdelaire/openslack/internal/state"
+)
+
+const phoneMAC = "a4:83:e7:01:02:03"
+
+func newTestTracker(t *testing.T) (*Tracker, *[]Device, *[]string, *time.Time) {
+	t.Helper()
+	cfg := &Config{Tracked: map[string]string{"phone": phoneMAC}}
+	applyDefaults(cfg)
+	var sent []string
+	tr := NewTracker(cfg, state.NewStore(filepath.Join(t.TempDir(), "state.json")), func(_ context.Context, text string) error {
+		sent = append(sent, text)
+		return nil
+	}, slog.New(slog.NewJSONHandler(io.Discard, nil)))
+
+	var devices []Device
+	tr.scan = func(context.Context) ([]Device, error) { return devices, nil }
+	now := time.Date(2026, 3, 1, 8, 0, 0, 0, time.UTC)
+	tr.now = func() time.Time { return now }
+	return tr, &devices, &sent, &now
+}
+
+func TestTrackerArrivalsAndDepartures(t *testing.T) {
+	tr, devices, sent, now := newTestTracker(t)
+	ctx := context.Background()
+	router := Device{MAC: "34:12:98:aa:bb:cc", IP: "192.168.1.1"}
+	phone := Device{MAC: phoneMAC, IP: "192.168.1.20"}
+
+	// The first scan learns the network silently.
+	*devices = []Device{router, phone}
+	tr.runScan(ctx)
+	if len(*sent) != 0 {
+		t.Fatalf("seed scan sent %q", *sent)
+	}
+
+	// Missing briefly is not leaving.
+	*devices = []Device{router}
+	*now = now.Add(5 * time.Minute)
+	tr.runScan(ctx)
+	if len(*sent) != 0 {
+		t.Fatalf("short absence sent %q", *sent)
+	}
+
+	*now = now.Add(15 * time.Minute)
+	tr.runScan(ctx)
+	tr.runScan(ctx)
+	if want := []string{"phone left (last seen 08:00)"}; !reflect.DeepEqual(*sent, want) {
+		t.Fatalf("sent = %q, want %q", *sent, want)
+	}
+
+	*devices = []Device{router, phone, {MAC: "de:ad:be:ef:00:01", IP: "192.168.1.50", Hostname: "Guest-Laptop"}}
+	*now = now.Add(time.Hour)
+	tr.runScan(ctx)
+	want := []string{
+		"phone left (last seen 08:00)",
+		"New device on network: Guest-Laptop (192.168.1.50, de:ad:be:ef:00:01)",
+		"phone arrived (192.168.1.20)",
+	}
+	if !reflect.DeepEqual(*sent, want) {
+		t.Fatalf("sent = %q, want %q", *sent, want)
+	}
+
+	tr.runScan(ctx)
+	if len(*sent) != 3 {
+		t.Errorf("repeat scan sent %q", (*sent)[3:])
+	}
+}
+
+func TestTrackerReport(t *testing.T) {
+	tr, devices, _, now := newTestTracker(t)
+	if got, _ := tr.Report(); got != "phone: never seen" {
+		t.Errorf("empty report = %q", got)
+	}
+
+	*devices = []Device{{MAC: phoneMAC, IP: "192.168.1.20"}, {MAC: "34:12:98:aa:bb:cc", IP: "192.168.1.1"}}
+	tr.runScan(context.Background())
+	*now = now.Add(2 * time.Hour)
+	*devices = nil
+	tr.runScan(context.Background())
+
+	got, err := tr.Report()
+	if err != nil {
+		t.Fatalf("report: %v", err)
+	}
+	want := "phone: away, last seen 2h ago\n\nSeen in the last 24h (1):\n192.168.1.1 (34:12:98:aa:bb:cc), 2h ago"
+	if got != want {
+		t.Errorf("report = %q, want %q", got, want)
+	}
+}
+
+func TestTrackerForgetsOldDevices(t *testing.T) {
+	tr, devices, sent, now := newTestTracker(t)
+	ctx := context.Background()
+	guest := Device{MAC: "de:ad:be:ef:00:01", IP: "192.168.1.50"}
+
+	*devices = []Device{guest, {MAC: phoneMAC, IP: "192.168.1.20"}}
+	tr.runScan(ctx)
+
+	*devices = nil
+	*now = now.AddDate(0, 0, DefaultForgetDays+1)
+	tr.runScan(ctx)
+	if keys, _ := tr.store.Keys(devicesNamespace); !reflect.DeepEqual(keys, []string{phoneMAC}) {
+		t.Fatalf("kept %v, want only the tracked phone", keys)
+	}
+
+	// A forgotten device that comes back is new again.
+	*devices = []Device{guest}
+	tr.runScan(ctx)
+	if n := len(*sent); n == 0 || (*sent)[n-1] != "New device on network: 192.168.1.50 (de:ad:be:ef:00:01)" {
+		t.Errorf("sent = %q", *sent)
+	}
+}
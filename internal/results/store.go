@@ -0,0 +1,75 @@
+// Package results keeps the latest output of each op per chat, so /last
+// can show it again and /diff can compare the last two runs.
+package results
+
+import (
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/jdelaire/openslack/internal/state"
+)
+
+const (
+	stateNamespace = "results"
+
+	// MaxOutput caps the stored size of one result.
+	MaxOutput = 16 << 10
+	keepRuns  = 2
+)
+
+// Run is one recorded op result.
+type Run struct {
+	Output string    `json:"output"`
+	At     time.Time `json:"at"`
+}
+
+// Store records results in the shared state store.
+type Store struct {
+	store *state.Store
+	mu    sync.Mutex
+}
+
+func NewStore(store *state.Store) *Store {
+	return &Store{store: store}
+}
+
+// Record saves output as the latest result of op in chatID, keeping the
+// previous one for diffing.
+func (s *Store) Record(chatID int64, op, output string, at time.Time) error {
+	if len(output) > MaxOutput {
+		output = output[:MaxOutput]
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	runs, err := s.load(chatID, op)
+	if err != nil {
+		return err
+	}
+	runs = append(runs, Run{Output: output, At: at})
+	if len(runs) > keepRuns {
+		runs = runs[len(runs)-keepRuns:]
+	}
+	return s.store.Put(stateNamespace, key(chatID, op), runs)
+}
+
+// Runs returns up to the last two results of op in chatID, oldest first.
+func (s *Store) Runs(chatID int64, op string) ([]Run, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.load(chatID, op)
+}
+
+func (s *Store) load(chatID int64, op string) ([]Run, error) {
+	var runs []Run
+	if _, err := s.store.Get(stateNamespace, key(chatID, op), &runs); err != nil {
+		return nil, err
+	}
+	return runs, nil
+}
+
+func key(chatID int64, op string) string {
+	return fmt.Sprintf("%d:%s", chatID, op)
+}
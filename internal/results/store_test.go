@@ -0,0 +1,50 @@
+package results
+
+import (
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/jdelaire/openslack/internal/state"
+)
+
+func TestStoreKeepsLastTwoRuns(t *testing.T) {
+	s := NewStore(state.NewStore(filepath.Join(t.TempDir(), "state.json")))
+	start := time.Date(2026, 3, 1, 9, 0, 0, 0, time.UTC)
+
+	for i, out := range []string{"one", "two", "three"} {
+		if err := s.Record(100, "status", out, start.Add(time.Duration(i)*time.Hour)); err != nil {
+			t.Fatalf("Record: %v", err)
+		}
+	}
+	if err := s.Record(200, "status", "other chat", start); err != nil {
+		t.Fatalf("Record: %v", err)
+	}
+
+	runs, err := s.Runs(100, "status")
+	if err != nil {
+		t.Fatalf("Runs: %v", err)
+	}
+	if len(runs) != 2 || runs[0].Output != "two" || runs[1].Output != "three" {
+		t.Fatalf("runs = %+v, want two then three", runs)
+	}
+	if !runs[1].At.Equal(start.Add(2 * time.Hour)) {
+		t.Errorf("at = %v", runs[1].At)
+	}
+
+	if runs, _ := s.Runs(100, "disk"); len(runs) != 0 {
+		t.Errorf("unknown op runs = %+v", runs)
+	}
+}
+
+func TestStoreTruncatesOutput(t *testing.T) {
+	s := NewStore(state.NewStore(filepath.Join(t.TempDir(), "state.json")))
+	if err := s.Record(1, "big", strings.Repeat("x", MaxOutput+10), time.Now()); err != nil {
+		t.Fatalf("Record: %v", err)
+	}
+	runs, _ := s.Runs(1, "big")
+	if len(runs[0].Output) != MaxOutput {
+		t.Errorf("len = %d, want %d", len(runs[0].Output), MaxOutput)
+	}
+}
@@ -0,0 +1,65 @@
+package sources
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+)
+
+// Priorities a source can have.
+const (
+	PriorityLow    = "low"    // delivered silently
+	PriorityNormal = "normal" // delivered as sent
+	PriorityHigh   = "high"   // marked with ❗
+)
+
+// Config registers notify sources, keyed by the source string scripts
+// send. With Require set, notifications from unregistered sources are
+// rejected.
+type Config struct {
+	Require bool              `json:"require"`
+	Sources map[string]Source `json:"sources"`
+}
+
+// Source is a registered notify source. Name and Emoji prefix its
+// messages; ChatID, when set, sends them to that chat instead of the
+// default one. Muted is the initial state; /sources can change it.
+type Source struct {
+	Name     string `json:"name"`
+	Emoji    string `json:"emoji"`
+	Priority string `json:"priority"`
+	ChatID   int64  `json:"chat_id"`
+	Muted    bool   `json:"muted"`
+}
+
+// LoadConfig reads and validates a sources config file.
+// Returns nil, nil if the file does not exist.
+func LoadConfig(path string) (*Config, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("read sources config: %w", err)
+	}
+
+	var cfg Config
+	if err := json.Unmarshal(data, &cfg); err != nil {
+		return nil, fmt.Errorf("parse sources config: %w", err)
+	}
+
+	for key, src := range cfg.Sources {
+		if key == "" {
+			return nil, fmt.Errorf("source key cannot be empty")
+		}
+		switch src.Priority {
+		case "":
+			src.Priority = PriorityNormal
+			cfg.Sources[key] = src
+		case PriorityLow, PriorityNormal, PriorityHigh:
+		default:
+			return nil, fmt.Errorf("source %q: priority must be low, normal or high, got %q", key, src.Priority)
+		}
+	}
+	return &cfg, nil
+}
@@ -0,0 +1,189 @@
+// Package sources registers the sources scripts send notifications from,
+// so the server can label, route and mute them consistently.
+package sources
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/jdelaire/openslack/internal/state"
+)
+
+const (
+	stateNamespace  = "sources"
+	mutedKey        = "muted"
+	snoozeNamespace = "snoozes"
+)
+
+var (
+	ErrUnregistered = errors.New("unregistered source")
+	ErrUnknown      = errors.New("unknown source")
+
+	errNoStore = errors.New("muting needs a state store")
+)
+
+// Route is how to deliver one notification.
+type Route struct {
+	Text   string // decorated with the source's emoji and name
+	ChatID int64  // 0 for the default chat
+	Silent bool
+	Muted  bool // drop instead of delivering
+	Held   bool // muted, but kept for the digest sent on unmute
+}
+
+// Entry is a registered source and its current mute state. MutedUntil
+// is set while a /mute with a duration is in effect.
+type Entry struct {
+	Key string
+	Source
+	MutedUntil time.Time
+}
+
+// Registry applies the sources config to notifications. Mute changes from
+// chat are kept in the shared state store and override the config.
+type Registry struct {
+	cfg   Config
+	store *state.Store
+	now   func() time.Time
+
+	mu sync.Mutex
+}
+
+// NewRegistry creates a registry for cfg. A nil cfg registers nothing and
+// accepts every source unchanged.
+func NewRegistry(cfg *Config, store *state.Store) *Registry {
+	r := &Registry{store: store, now: time.Now}
+	if cfg != nil {
+		r.cfg = *cfg
+	}
+	return r
+}
+
+// Route decorates text from source and says where it goes. While the
+// source is snoozed with a digest, text is held for it.
+func (r *Registry) Route(source, text string) (Route, error) {
+	src, ok := r.cfg.Sources[source]
+	if !ok && r.cfg.Require {
+		return Route{}, fmt.Errorf("%w %q", ErrUnregistered, source)
+	}
+	route := Route{Text: text}
+	if ok {
+		route = Route{
+			Text:   decorate(src, text),
+			ChatID: src.ChatID,
+			Silent: src.Priority == PriorityLow,
+		}
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	held, err := r.hold(source, route.Text)
+	if err != nil {
+		return Route{}, err
+	}
+	if held != nil {
+		route.Muted = true
+		route.Held = held.Digest
+		return route, nil
+	}
+	if ok {
+		route.Muted, err = r.muted(source, src)
+		if err != nil {
+			return Route{}, err
+		}
+	}
+	return route, nil
+}
+
+// SetMuted mutes or unmutes a registered source.
+func (r *Registry) SetMuted(key string, muted bool) error {
+	if _, ok := r.cfg.Sources[key]; !ok {
+		return fmt.Errorf("%w %q", ErrUnknown, key)
+	}
+	if r.store == nil {
+		return errNoStore
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	overrides, err := r.overrides()
+	if err != nil {
+		return err
+	}
+	overrides[key] = muted
+	return r.store.Put(stateNamespace, mutedKey, overrides)
+}
+
+// List returns the registered sources with their current mute state,
+// sorted by key.
+func (r *Registry) List() ([]Entry, error) {
+	r.mu.Lock()
+	overrides, err := r.overrides()
+	var snoozes []Snooze
+	if err == nil {
+		snoozes, err = r.snoozes()
+	}
+	r.mu.Unlock()
+	if err != nil {
+		return nil, err
+	}
+	now := r.now()
+	entries := make([]Entry, 0, len(r.cfg.Sources))
+	for key, src := range r.cfg.Sources {
+		if m, ok := overrides[key]; ok {
+			src.Muted = m
+		}
+		e := Entry{Key: key, Source: src}
+		for _, sn := range snoozes {
+			if sn.Source == key && sn.Active(now) {
+				e.Muted = true
+				e.MutedUntil = sn.Until
+			}
+		}
+		entries = append(entries, e)
+	}
+	sort.Slice(entries, func(i, j int) bool { return entries[i].Key < entries[j].Key })
+	return entries, nil
+}
+
+// muted reports whether the config or /sources mutes src. Callers hold
+// r.mu.
+func (r *Registry) muted(key string, src Source) (bool, error) {
+	overrides, err := r.overrides()
+	if err != nil {
+		return false, err
+	}
+	if m, ok := overrides[key]; ok {
+		return m, nil
+	}
+	return src.Muted, nil
+}
+
+// overrides loads the mute changes made from chat. Callers hold r.mu.
+func (r *Registry) overrides() (map[string]bool, error) {
+	overrides := map[string]bool{}
+	if r.store == nil {
+		return overrides, nil
+	}
+	if _, err := r.store.Get(stateNamespace, mutedKey, &overrides); err != nil {
+		return nil, fmt.Errorf("load muted sources: %w", err)
+	}
+	return overrides, nil
+}
+
+// decorate prefixes text with the source's emoji and name.
+func decorate(src Source, text string) string {
+	prefix := src.Name
+	if src.Emoji != "" {
+		prefix = src.Emoji + " " + prefix
+	}
+	if src.Priority == PriorityHigh {
+		prefix = "❗" + prefix
+	}
+	if prefix == "" {
+		return text
+	}
+	return prefix + ": " + text
+}
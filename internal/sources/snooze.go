@@ -0,0 +1,238 @@
+package sources
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"sort"
+	"strings"
+	"time"
+)
+
+// maxHeld caps the messages a digest keeps; later ones are only counted.
+const maxHeld = 50
+
+// Snooze mutes one source, registered or not, from chat. A zero Until
+// mutes it until /unmute. With Digest set, muted messages are held and
+// delivered together when the snooze ends.
+type Snooze struct {
+	Source  string    `json:"source"`
+	Until   time.Time `json:"until,omitempty"`
+	Digest  bool      `json:"digest,omitempty"`
+	Held    []string  `json:"held,omitempty"`
+	Dropped int       `json:"dropped,omitempty"`
+}
+
+// Active reports whether the snooze still mutes its source at now.
+func (s Snooze) Active(now time.Time) bool {
+	return s.Until.IsZero() || now.Before(s.Until)
+}
+
+// DigestText renders the messages held while the source was muted, or ""
+// if there are none.
+func (s Snooze) DigestText() string {
+	total := len(s.Held) + s.Dropped
+	if total == 0 {
+		return ""
+	}
+	var b strings.Builder
+	fmt.Fprintf(&b, "Held while %s was muted (%d):", s.Source, total)
+	for _, text := range s.Held {
+		b.WriteString("\n- " + text)
+	}
+	if s.Dropped > 0 {
+		fmt.Fprintf(&b, "\n… %d more", s.Dropped)
+	}
+	return b.String()
+}
+
+// Mute snoozes source until the given time, or until Unmute if until is
+// zero. Muting an already snoozed source replaces its end time and digest
+// setting but keeps anything already held.
+func (r *Registry) Mute(source string, until time.Time, digest bool) error {
+	if source == "" {
+		return fmt.Errorf("source cannot be empty")
+	}
+	if r.store == nil {
+		return errNoStore
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	var sn Snooze
+	if _, err := r.store.Get(snoozeNamespace, source, &sn); err != nil {
+		return fmt.Errorf("load snooze: %w", err)
+	}
+	sn.Source = source
+	sn.Until = until
+	sn.Digest = digest
+	return r.store.Put(snoozeNamespace, source, sn)
+}
+
+// Unmute ends any snooze of source and lifts a /sources or config mute of
+// a registered source. It returns the ended snooze, so its digest can be
+// delivered, and reports whether source was muted at all.
+func (r *Registry) Unmute(source string) (Snooze, bool, error) {
+	if r.store == nil {
+		return Snooze{}, false, errNoStore
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	var sn Snooze
+	snoozed, err := r.store.Get(snoozeNamespace, source, &sn)
+	if err != nil {
+		return Snooze{}, false, fmt.Errorf("load snooze: %w", err)
+	}
+	if snoozed {
+		if _, err := r.store.Delete(snoozeNamespace, source); err != nil {
+			return Snooze{}, false, err
+		}
+	}
+
+	src, registered := r.cfg.Sources[source]
+	if !registered {
+		return sn, snoozed, nil
+	}
+	muted, err := r.muted(source, src)
+	if err != nil || !muted {
+		return sn, snoozed, err
+	}
+	overrides, err := r.overrides()
+	if err != nil {
+		return sn, snoozed, err
+	}
+	overrides[source] = false
+	return sn, true, r.store.Put(stateNamespace, mutedKey, overrides)
+}
+
+// Snoozes returns the current snoozes, including ended ones whose digest
+// has not been delivered yet, sorted by source.
+func (r *Registry) Snoozes() ([]Snooze, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.snoozes()
+}
+
+// Expired removes and returns the snoozes that have ended.
+func (r *Registry) Expired() ([]Snooze, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	snoozes, err := r.snoozes()
+	if err != nil {
+		return nil, err
+	}
+	now := r.now()
+	var ended []Snooze
+	for _, sn := range snoozes {
+		if sn.Active(now) {
+			continue
+		}
+		if _, err := r.store.Delete(snoozeNamespace, sn.Source); err != nil {
+			return ended, err
+		}
+		ended = append(ended, sn)
+	}
+	return ended, nil
+}
+
+// snoozes loads every snooze. Callers hold r.mu.
+func (r *Registry) snoozes() ([]Snooze, error) {
+	if r.store == nil {
+		return nil, nil
+	}
+	keys, err := r.store.Keys(snoozeNamespace)
+	if err != nil {
+		return nil, err
+	}
+	snoozes := make([]Snooze, 0, len(keys))
+	for _, k := range keys {
+		var sn Snooze
+		if _, err := r.store.Get(snoozeNamespace, k, &sn); err != nil {
+			return nil, fmt.Errorf("load snooze: %w", err)
+		}
+		snoozes = append(snoozes, sn)
+	}
+	sort.Slice(snoozes, func(i, j int) bool { return snoozes[i].Source < snoozes[j].Source })
+	return snoozes, nil
+}
+
+// hold returns the active snooze of source, if any, first adding text to
+// it when it keeps a digest. Callers hold r.mu.
+func (r *Registry) hold(source, text string) (*Snooze, error) {
+	if r.store == nil {
+		return nil, nil
+	}
+	var sn Snooze
+	found, err := r.store.Get(snoozeNamespace, source, &sn)
+	if err != nil {
+		return nil, fmt.Errorf("load snooze: %w", err)
+	}
+	if !found || !sn.Active(r.now()) {
+		return nil, nil
+	}
+	if !sn.Digest {
+		return &sn, nil
+	}
+	if len(sn.Held) < maxHeld {
+		sn.Held = append(sn.Held, text)
+	} else {
+		sn.Dropped++
+	}
+	if err := r.store.Put(snoozeNamespace, source, sn); err != nil {
+		return nil, err
+	}
+	return &sn, nil
+}
+
+const releaseInterval = time.Minute
+
+// Releaser ends snoozes when their time is up and sends their digests.
+type Releaser struct {
+	registry *Registry
+	send     func(context.Context, string) error
+	logger   *slog.Logger
+}
+
+func NewReleaser(registry *Registry, send func(context.Context, string) error, logger *slog.Logger) *Releaser {
+	if logger == nil {
+		logger = slog.Default()
+	}
+	return &Releaser{registry: registry, send: send, logger: logger}
+}
+
+// Run checks for ended snoozes every minute until ctx is cancelled.
+func (r *Releaser) Run(ctx context.Context) {
+	ticker := time.NewTicker(releaseInterval)
+	defer ticker.Stop()
+
+	for {
+		r.runTick(ctx)
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// runTick sends the digest of every ended snooze. A digest that fails to
+// send is logged and lost, like any other failed notification.
+func (r *Releaser) runTick(ctx context.Context) {
+	ended, err := r.registry.Expired()
+	if err != nil {
+		r.logger.Error("sources: release snoozes failed", "error", err)
+	}
+	for _, sn := range ended {
+		r.logger.Info("sources: snooze ended", "source", sn.Source, "held", len(sn.Held)+sn.Dropped)
+		text := sn.DigestText()
+		if text == "" {
+			continue
+		}
+		sendCtx, cancel := context.WithTimeout(ctx, 10*time.Second)
+		err := r.send(sendCtx, text)
+		cancel()
+		if err != nil {
+			r.logger.Error("sources: send digest failed", "source", sn.Source, "error", err)
+		}
+	}
+}
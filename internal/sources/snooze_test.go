@@ -0,0 +1,112 @@
+package sources
+
+import (
+	"context"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/jdelaire/openslack/internal/state"
+)
+
+func TestSnooze(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "state.json")
+	now := time.Date(2026, 3, 2, 3, 0, 0, 0, time.UTC)
+	cfg := &Config{Sources: map[string]Source{"backup": {Name: "Backup", Emoji: "💾", Priority: PriorityNormal}}}
+	r := NewRegistry(cfg, state.NewStore(path))
+	r.now = func() time.Time { return now }
+
+	if err := r.Mute("cron", now.Add(time.Hour), false); err != nil {
+		t.Fatal(err)
+	}
+	if err := r.Mute("backup", now.Add(2*time.Hour), true); err != nil {
+		t.Fatal(err)
+	}
+	if route, _ := r.Route("cron", "tick"); !route.Muted || route.Held {
+		t.Errorf("cron route = %+v, want muted", route)
+	}
+	for _, text := range []string{"one", "two"} {
+		if route, _ := r.Route("backup", text); !route.Muted || !route.Held {
+			t.Errorf("backup route = %+v, want held", route)
+		}
+	}
+
+	// Snoozes survive a restart.
+	r = NewRegistry(cfg, state.NewStore(path))
+	r.now = func() time.Time { return now }
+	entries, _ := r.List()
+	if len(entries) != 1 || !entries[0].Muted || !entries[0].MutedUntil.Equal(now.Add(2*time.Hour)) {
+		t.Errorf("List = %+v", entries)
+	}
+
+	now = now.Add(90 * time.Minute)
+	if route, _ := r.Route("cron", "tick"); route.Muted {
+		t.Errorf("cron route after snooze = %+v", route)
+	}
+	ended, err := r.Expired()
+	if err != nil || len(ended) != 1 || ended[0].Source != "cron" {
+		t.Fatalf("Expired = %+v, %v", ended, err)
+	}
+
+	sn, muted, err := r.Unmute("backup")
+	if err != nil || !muted {
+		t.Fatalf("Unmute = %v, %v", muted, err)
+	}
+	want := "Held while backup was muted (2):\n- 💾 Backup: one\n- 💾 Backup: two"
+	if got := sn.DigestText(); got != want {
+		t.Errorf("digest = %q, want %q", got, want)
+	}
+	if _, muted, _ := r.Unmute("backup"); muted {
+		t.Error("second Unmute reported muted")
+	}
+}
+
+func TestUnmuteLiftsConfigMute(t *testing.T) {
+	cfg := &Config{Sources: map[string]Source{"noisy": {Name: "Noisy", Priority: PriorityNormal, Muted: true}}}
+	r := NewRegistry(cfg, state.NewStore(filepath.Join(t.TempDir(), "state.json")))
+	if _, muted, err := r.Unmute("noisy"); err != nil || !muted {
+		t.Fatalf("Unmute = %v, %v", muted, err)
+	}
+	if route, _ := r.Route("noisy", "x"); route.Muted {
+		t.Errorf("route = %+v, want delivered", route)
+	}
+}
+
+func TestDigestCap(t *testing.T) {
+	r := NewRegistry(nil, state.NewStore(filepath.Join(t.TempDir(), "state.json")))
+	r.Mute("cron", time.Time{}, true)
+	for range maxHeld + 3 {
+		r.Route("cron", "tick")
+	}
+	sn, _, _ := r.Unmute("cron")
+	if digest := sn.DigestText(); !strings.HasPrefix(digest, "Held while cron was muted (53):") || !strings.HasSuffix(digest, "… 3 more") {
+		t.Errorf("digest = %q", digest)
+	}
+}
+
+func TestReleaser(t *testing.T) {
+	now := time.Now()
+	r := NewRegistry(nil, state.NewStore(filepath.Join(t.TempDir(), "state.json")))
+	r.now = func() time.Time { return now }
+	r.Mute("cron", now.Add(time.Minute), true)
+	r.Route("cron", "tick")
+
+	var sent []string
+	rel := NewReleaser(r, func(_ context.Context, text string) error {
+		sent = append(sent, text)
+		return nil
+	}, nil)
+	rel.runTick(context.Background())
+	if len(sent) != 0 {
+		t.Fatalf("sent before snooze ended: %q", sent)
+	}
+	now = now.Add(time.Minute)
+	rel.runTick(context.Background())
+	if len(sent) != 1 || sent[0] != "Held while cron was muted (1):\n- tick" {
+		t.Errorf("sent = %q", sent)
+	}
+	if snoozes, _ := r.Snoozes(); len(snoozes) != 0 {
+		t.Errorf("snoozes after release = %+v", snoozes)
+	}
+}
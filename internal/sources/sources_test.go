@@ -0,0 +1,94 @@
+package sources
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/jdelaire/openslack/internal/state"
+)
+
+func TestRoute(t *testing.T) {
+	cfg := &Config{Sources: map[string]Source{
+		"backup": {Name: "Backup", Emoji: "💾", Priority: PriorityLow},
+		"alarm":  {Name: "Alarm", Emoji: "🚨", Priority: PriorityHigh, ChatID: -42},
+	}}
+	r := NewRegistry(cfg, state.NewStore(filepath.Join(t.TempDir(), "state.json")))
+
+	tests := []struct {
+		source string
+		want   Route
+	}{
+		{"backup", Route{Text: "💾 Backup: done", Silent: true}},
+		{"alarm", Route{Text: "❗🚨 Alarm: done", ChatID: -42}},
+		{"cron", Route{Text: "done"}},
+	}
+	for _, tt := range tests {
+		got, err := r.Route(tt.source, "done")
+		if err != nil || got != tt.want {
+			t.Errorf("Route(%q) = %+v, %v; want %+v", tt.source, got, err, tt.want)
+		}
+	}
+
+	cfg.Require = true
+	r = NewRegistry(cfg, nil)
+	if _, err := r.Route("cron", "done"); err == nil || err.Error() != `unregistered source "cron"` {
+		t.Errorf("unregistered source error = %v", err)
+	}
+}
+
+func TestSetMuted(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "state.json")
+	cfg := &Config{Sources: map[string]Source{
+		"backup": {Name: "Backup", Priority: PriorityNormal},
+		"noisy":  {Name: "Noisy", Priority: PriorityNormal, Muted: true},
+	}}
+	r := NewRegistry(cfg, state.NewStore(path))
+
+	if err := r.SetMuted("backup", true); err != nil {
+		t.Fatal(err)
+	}
+	if err := r.SetMuted("noisy", false); err != nil {
+		t.Fatal(err)
+	}
+	if err := r.SetMuted("nope", true); err == nil {
+		t.Error("muting an unknown source succeeded")
+	}
+
+	// Mute changes survive a restart and override the config.
+	r = NewRegistry(cfg, state.NewStore(path))
+	entries, err := r.List()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(entries) != 2 || entries[0].Key != "backup" || !entries[0].Muted || entries[1].Muted {
+		t.Errorf("List = %+v", entries)
+	}
+	if route, _ := r.Route("backup", "x"); !route.Muted {
+		t.Errorf("backup route = %+v, want muted", route)
+	}
+}
+
+func TestLoadConfig(t *testing.T) {
+	dir := t.TempDir()
+	if cfg, err := LoadConfig(filepath.Join(dir, "missing.json")); cfg != nil || err != nil {
+		t.Errorf("missing file = %v, %v", cfg, err)
+	}
+
+	path := filepath.Join(dir, "sources.json")
+	os.WriteFile(path, []byte(`{"require": true, "sources": {"backup": {"name": "Backup"}}}`), 0600)
+	cfg, err := LoadConfig(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !cfg.Require || cfg.Sources["backup"].Priority != PriorityNormal {
+		t.Errorf("cfg = %+v", cfg)
+	}
+
+	for _, bad := range []string{`{`, `{"sources": {"x": {"priority": "urgent"}}}`, `{"sources": {"": {}}}`} {
+		os.WriteFile(path, []byte(bad), 0600)
+		if _, err := LoadConfig(path); err == nil {
+			t.Errorf("LoadConfig(%s) succeeded", bad)
+		}
+	}
+}
@@ -0,0 +1,221 @@
+// Package state is a small namespaced key/value store shared by daemon
+// modules that need to persist state between restarts (e.g. feed
+// subscriptions and seen items). Everything lives in one JSON file.
+package state
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"sync"
+)
+
+// Store persists values as JSON under (namespace, key). Values are
+// marshaled on Put and unmarshaled on Get, so each module owns its schema.
+type Store struct {
+	path string
+
+	mu   sync.Mutex
+	data map[string]map[string]json.RawMessage // nil until first load
+}
+
+func NewStore(path string) *Store {
+	return &Store{path: path}
+}
+
+func (s *Store) Path() string {
+	return s.path
+}
+
+// Get unmarshals the value at (ns, key) into out. It reports false if the
+// key does not exist.
+func (s *Store) Get(ns, key string, out any) (bool, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if err := s.load(); err != nil {
+		return false, err
+	}
+	raw, ok := s.data[ns][key]
+	if !ok {
+		return false, nil
+	}
+	if err := json.Unmarshal(raw, out); err != nil {
+		return false, fmt.Errorf("decode state %s/%s: %w", ns, key, err)
+	}
+	return true, nil
+}
+
+// Put stores v at (ns, key) and writes the file.
+func (s *Store) Put(ns, key string, v any) error {
+	raw, err := json.Marshal(v)
+	if err != nil {
+		return fmt.Errorf("encode state %s/%s: %w", ns, key, err)
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if err := s.load(); err != nil {
+		return err
+	}
+	if s.data[ns] == nil {
+		s.data[ns] = make(map[string]json.RawMessage)
+	}
+	prev, had := s.data[ns][key]
+	s.data[ns][key] = raw
+	if err := s.save(); err != nil {
+		if had {
+			s.data[ns][key] = prev
+		} else {
+			delete(s.data[ns], key)
+		}
+		return err
+	}
+	return nil
+}
+
+// Delete removes (ns, key). It reports false if the key did not exist.
+func (s *Store) Delete(ns, key string) (bool, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if err := s.load(); err != nil {
+		return false, err
+	}
+	prev, ok := s.data[ns][key]
+	if !ok {
+		return false, nil
+	}
+	delete(s.data[ns], key)
+	if len(s.data[ns]) == 0 {
+		delete(s.data, ns)
+	}
+	if err := s.save(); err != nil {
+		if s.data[ns] == nil {
+			s.data[ns] = make(map[string]json.RawMessage)
+		}
+		s.data[ns][key] = prev
+		return false, err
+	}
+	return true, nil
+}
+
+// Replace sets ns to exactly values, dropping keys not in it, and writes
+// the file once.
+func (s *Store) Replace(ns string, values map[string]any) error {
+	raws := make(map[string]json.RawMessage, len(values))
+	for key, v := range values {
+		raw, err := json.Marshal(v)
+		if err != nil {
+			return fmt.Errorf("encode state %s/%s: %w", ns, key, err)
+		}
+		raws[key] = raw
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if err := s.load(); err != nil {
+		return err
+	}
+	prev, had := s.data[ns]
+	if len(raws) == 0 {
+		delete(s.data, ns)
+	} else {
+		s.data[ns] = raws
+	}
+	if err := s.save(); err != nil {
+		if had {
+			s.data[ns] = prev
+		} else {
+			delete(s.data, ns)
+		}
+		return err
+	}
+	return nil
+}
+
+// Keys returns the keys in ns, sorted.
+func (s *Store) Keys(ns string) ([]string, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if err := s.load(); err != nil {
+		return nil, err
+	}
+	keys := make([]string, 0, len(s.data[ns]))
+	for k := range s.data[ns] {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys, nil
+}
+
+func (s *Store) load() error {
+	if s.data != nil {
+		return nil
+	}
+
+	data, err := os.ReadFile(s.path)
+	if err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			s.data = make(map[string]map[string]json.RawMessage)
+			return nil
+		}
+		return fmt.Errorf("read state file: %w", err)
+	}
+
+	st := make(map[string]map[string]json.RawMessage)
+	if len(bytes.TrimSpace(data)) > 0 {
+		if err := json.Unmarshal(data, &st); err != nil {
+			return fmt.Errorf("parse state file: %w", err)
+		}
+	}
+	s.data = st
+	return nil
+}
+
+func (s *Store) save() (retErr error) {
+	dir := filepath.Dir(s.path)
+	if err := os.MkdirAll(dir, 0o700); err != nil {
+		return fmt.Errorf("create state dir: %w", err)
+	}
+
+	tmp := s.path + ".tmp"
+	defer func() {
+		if retErr != nil {
+			_ = os.Remove(tmp)
+		}
+	}()
+
+	f, err := os.OpenFile(tmp, os.O_CREATE|os.O_TRUNC|os.O_WRONLY, 0o600)
+	if err != nil {
+		return fmt.Errorf("open temp state file: %w", err)
+	}
+
+	enc := json.NewEncoder(f)
+	enc.SetIndent("", "  ")
+	enc.SetEscapeHTML(false)
+	if err := enc.Encode(s.data); err != nil {
+		_ = f.Close()
+		return fmt.Errorf("write temp state file: %w", err)
+	}
+
+	if err := f.Sync(); err != nil {
+		_ = f.Close()
+		return fmt.Errorf("fsync temp state file: %w", err)
+	}
+	if err := f.Close(); err != nil {
+		return fmt.Errorf("close temp state file: %w", err)
+	}
+
+	if err := os.Rename(tmp, s.path); err != nil {
+		return fmt.Errorf("rename temp state file: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,117 @@
+package state
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+type entry struct {
+	URL   string `json:"url"`
+	Count int    `json:"count"`
+}
+
+func TestStoreRoundTrip(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "state.json")
+	s := NewStore(path)
+
+	var got entry
+	ok, err := s.Get("feeds", "hn", &got)
+	if err != nil || ok {
+		t.Fatalf("get missing = %v, %v", ok, err)
+	}
+
+	want := entry{URL: "https://news.ycombinator.com/rss", Count: 3}
+	if err := s.Put("feeds", "hn", want); err != nil {
+		t.Fatalf("put: %v", err)
+	}
+	if err := s.Put("feeds", "go", entry{URL: "https://go.dev/blog/feed.atom"}); err != nil {
+		t.Fatalf("put: %v", err)
+	}
+	if err := s.Put("monitors", "nas", entry{Count: 1}); err != nil {
+		t.Fatalf("put: %v", err)
+	}
+
+	// A fresh store reads what the first one wrote.
+	s2 := NewStore(path)
+	ok, err = s2.Get("feeds", "hn", &got)
+	if err != nil || !ok {
+		t.Fatalf("get = %v, %v", ok, err)
+	}
+	if got != want {
+		t.Errorf("got %+v, want %+v", got, want)
+	}
+
+	keys, err := s2.Keys("feeds")
+	if err != nil {
+		t.Fatalf("keys: %v", err)
+	}
+	if !reflect.DeepEqual(keys, []string{"go", "hn"}) {
+		t.Errorf("keys = %v", keys)
+	}
+
+	info, err := os.Stat(path)
+	if err != nil {
+		t.Fatalf("stat: %v", err)
+	}
+	if info.Mode().Perm() != 0o600 {
+		t.Errorf("mode = %v, want 0600", info.Mode().Perm())
+	}
+}
+
+func TestStoreDelete(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "state.json")
+	s := NewStore(path)
+	s.Put("feeds", "hn", entry{Count: 1})
+
+	ok, err := s.Delete("feeds", "hn")
+	if err != nil || !ok {
+		t.Fatalf("delete = %v, %v", ok, err)
+	}
+	ok, err = s.Delete("feeds", "hn")
+	if err != nil || ok {
+		t.Fatalf("delete again = %v, %v", ok, err)
+	}
+
+	keys, _ := NewStore(path).Keys("feeds")
+	if len(keys) != 0 {
+		t.Errorf("keys after delete = %v", keys)
+	}
+}
+
+func TestStoreReplace(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "state.json")
+	s := NewStore(path)
+	s.Put("presence", "old", entry{Count: 1})
+	s.Put("feeds", "hn", entry{Count: 2})
+
+	if err := s.Replace("presence", map[string]any{"a": entry{Count: 3}, "b": entry{Count: 4}}); err != nil {
+		t.Fatalf("replace: %v", err)
+	}
+	reopened := NewStore(path)
+	if keys, _ := reopened.Keys("presence"); !reflect.DeepEqual(keys, []string{"a", "b"}) {
+		t.Errorf("presence keys = %v", keys)
+	}
+	var got entry
+	if ok, _ := reopened.Get("feeds", "hn", &got); !ok || got.Count != 2 {
+		t.Errorf("other namespace = %+v, %v", got, ok)
+	}
+
+	if err := s.Replace("presence", nil); err != nil {
+		t.Fatalf("replace with nothing: %v", err)
+	}
+	if keys, _ := NewStore(path).Keys("presence"); len(keys) != 0 {
+		t.Errorf("keys after emptying = %v", keys)
+	}
+}
+
+func TestStoreCorruptFile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "state.json")
+	os.WriteFile(path, []byte("{not json"), 0o600)
+
+	var got entry
+	if _, err := NewStore(path).Get("feeds", "hn", &got); err == nil {
+		t.Fatal("expected parse error")
+	}
+}
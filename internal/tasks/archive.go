@@ -0,0 +1,229 @@
+package tasks
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/jdelaire/openslack/internal/cron"
+)
+
+// DefaultRetentionDays is how long done tasks stay in tasks.json before
+// they are archived, unless configured.
+const DefaultRetentionDays = 30
+
+// archiveSpec is when done tasks are archived: daily at 03:30.
+const archiveSpec = "30 3 * * *"
+
+// ArchiveConfig sets how many days done tasks stay in tasks.json before
+// they move to the archive file.
+type ArchiveConfig struct {
+	RetentionDays int `json:"retention_days"`
+}
+
+// LoadArchiveConfig reads a task archive config file.
+// Returns nil, nil if the file does not exist.
+func LoadArchiveConfig(path string) (*ArchiveConfig, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("read archive config: %w", err)
+	}
+
+	var cfg ArchiveConfig
+	if err := json.Unmarshal(data, &cfg); err != nil {
+		return nil, fmt.Errorf("parse archive config: %w", err)
+	}
+	if cfg.RetentionDays < 0 {
+		return nil, fmt.Errorf("archive config: retention_days must not be negative")
+	}
+	return &cfg, nil
+}
+
+// Retention returns how long done tasks are kept before archiving.
+func (c *ArchiveConfig) Retention() time.Duration {
+	days := DefaultRetentionDays
+	if c != nil && c.RetentionDays > 0 {
+		days = c.RetentionDays
+	}
+	return time.Duration(days) * 24 * time.Hour
+}
+
+// ArchivePath returns the archive file, next to the tasks file: one task
+// per line, as JSON.
+func (s *Store) ArchivePath() string {
+	return strings.TrimSuffix(s.path, filepath.Ext(s.path)) + ".archive.jsonl"
+}
+
+// LoadArchive returns the archived tasks. A task archived twice, e.g. after
+// a crash between writing the archive and saving the tasks file, is
+// returned once.
+func (s *Store) LoadArchive() ([]Task, error) {
+	data, err := os.ReadFile(s.ArchivePath())
+	if err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("read task archive: %w", err)
+	}
+
+	byID := make(map[int]Task)
+	sc := bufio.NewScanner(bytes.NewReader(data))
+	sc.Buffer(make([]byte, 64<<10), 1<<20)
+	for line := 1; sc.Scan(); line++ {
+		if len(bytes.TrimSpace(sc.Bytes())) == 0 {
+			continue
+		}
+		var task Task
+		if err := json.Unmarshal(sc.Bytes(), &task); err != nil {
+			return nil, fmt.Errorf("parse task archive line %d: %w", line, err)
+		}
+		byID[task.ID] = task
+	}
+	if err := sc.Err(); err != nil {
+		return nil, fmt.Errorf("read task archive: %w", err)
+	}
+
+	tasks := make([]Task, 0, len(byID))
+	for _, task := range byID {
+		tasks = append(tasks, task)
+	}
+	sort.Slice(tasks, func(i, j int) bool { return tasks[i].ID < tasks[j].ID })
+	return tasks, nil
+}
+
+// appendArchive appends tasks to the archive file and syncs it.
+func (s *Store) appendArchive(tasks []Task) error {
+	var buf bytes.Buffer
+	enc := json.NewEncoder(&buf)
+	enc.SetEscapeHTML(false)
+	for _, task := range tasks {
+		if err := enc.Encode(task); err != nil {
+			return fmt.Errorf("encode archived task: %w", err)
+		}
+	}
+
+	if err := os.MkdirAll(filepath.Dir(s.path), 0o700); err != nil {
+		return fmt.Errorf("create tasks dir: %w", err)
+	}
+	f, err := os.OpenFile(s.ArchivePath(), os.O_CREATE|os.O_APPEND|os.O_WRONLY, 0o600)
+	if err != nil {
+		return fmt.Errorf("open task archive: %w", err)
+	}
+	if _, err := f.Write(buf.Bytes()); err != nil {
+		f.Close()
+		return fmt.Errorf("write task archive: %w", err)
+	}
+	if err := f.Sync(); err != nil {
+		f.Close()
+		return fmt.Errorf("fsync task archive: %w", err)
+	}
+	return f.Close()
+}
+
+// Compact moves tasks done more than retention ago from tasks.json to the
+// archive file, and returns how many moved. Tasks done before completion
+// times were recorded are stamped now, so they move a retention later.
+func (s *TaskService) Compact(retention time.Duration) (int, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	st, err := s.store.Load()
+	if err != nil {
+		return 0, err
+	}
+
+	now := s.now()
+	cutoff := now.Add(-retention)
+	stamped := false
+	var keep, moved []Task
+	for _, task := range st.Tasks {
+		if task.Status != TaskStatusDone {
+			keep = append(keep, task)
+			continue
+		}
+		if task.CompletedAt == "" {
+			task.CompletedAt = now.Format(time.RFC3339)
+			stamped = true
+		}
+		if done, err := time.Parse(time.RFC3339, task.CompletedAt); err == nil && done.Before(cutoff) {
+			moved = append(moved, task)
+		} else {
+			keep = append(keep, task)
+		}
+	}
+	if len(moved) == 0 && !stamped {
+		return 0, nil
+	}
+
+	if len(moved) > 0 {
+		if err := s.store.appendArchive(moved); err != nil {
+			return 0, err
+		}
+	}
+	st.Tasks = keep
+	if err := s.store.Save(st); err != nil {
+		return 0, fmt.Errorf("save compacted tasks: %w", err)
+	}
+	changes := make([]Change, 0, len(moved))
+	for _, task := range moved {
+		changes = append(changes, Change{Revision: st.Revision + 1, Kind: ChangeArchived, Task: task})
+	}
+	s.feed.publish(changes...)
+	return len(moved), nil
+}
+
+// ArchiveJob returns a cron job that compacts tasks daily at 03:30, with
+// cfg's retention.
+func (s *TaskService) ArchiveJob(cfg *ArchiveConfig) cron.Job {
+	return cron.Job{
+		Name:     "tasks-archive",
+		Spec:     archiveSpec,
+		Location: s.zones.Default(),
+		CatchUp:  true,
+		Run: func(context.Context, time.Time) error {
+			_, err := s.Compact(cfg.Retention())
+			return err
+		},
+	}
+}
+
+// Find returns open, done and archived tasks whose text contains query,
+// ignoring case, newest first.
+func (s *TaskService) Find(query string) ([]Task, error) {
+	query = strings.ToLower(strings.TrimSpace(query))
+
+	s.mu.Lock()
+	st, err := s.store.Load()
+	if err != nil {
+		s.mu.Unlock()
+		return nil, err
+	}
+	archived, err := s.store.LoadArchive()
+	s.mu.Unlock()
+	if err != nil {
+		return nil, err
+	}
+
+	seen := make(map[int]bool)
+	var found []Task
+	for _, task := range append(st.Tasks, archived...) {
+		if seen[task.ID] || !strings.Contains(strings.ToLower(task.Text), query) {
+			continue
+		}
+		seen[task.ID] = true
+		found = append(found, task)
+	}
+	sort.Slice(found, func(i, j int) bool { return found[i].ID > found[j].ID })
+	return found, nil
+}
@@ -0,0 +1,168 @@
+package tasks_test
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/jdelaire/openslack/internal/tasks"
+)
+
+func TestCompactArchivesOldDoneTasks(t *testing.T) {
+	dir := t.TempDir()
+	store := tasks.NewStore(filepath.Join(dir, "tasks.json"))
+	now := time.Date(2026, 3, 1, 12, 0, 0, 0, time.UTC)
+	svc := tasks.NewTaskService(store).WithClock(func() time.Time { return now })
+
+	for _, text := range []string{"Old eggs", "Recent milk", "Open bread"} {
+		if _, err := svc.CreateTomorrow(text); err != nil {
+			t.Fatalf("create %q: %v", text, err)
+		}
+	}
+	now = time.Date(2026, 3, 2, 12, 0, 0, 0, time.UTC)
+	if _, err := svc.Complete(1); err != nil {
+		t.Fatalf("complete: %v", err)
+	}
+	now = time.Date(2026, 3, 20, 12, 0, 0, 0, time.UTC)
+	if _, err := svc.Complete(2); err != nil {
+		t.Fatalf("complete: %v", err)
+	}
+
+	now = time.Date(2026, 3, 25, 12, 0, 0, 0, time.UTC)
+	moved, err := svc.Compact(14 * 24 * time.Hour)
+	if err != nil {
+		t.Fatalf("compact: %v", err)
+	}
+	if moved != 1 {
+		t.Fatalf("moved = %d, want 1", moved)
+	}
+
+	st, err := store.Load()
+	if err != nil {
+		t.Fatalf("load: %v", err)
+	}
+	if len(st.Tasks) != 2 || st.Tasks[0].ID != 2 || st.Tasks[1].ID != 3 {
+		t.Fatalf("tasks after compact = %+v", st.Tasks)
+	}
+	archived, err := store.LoadArchive()
+	if err != nil {
+		t.Fatalf("load archive: %v", err)
+	}
+	if len(archived) != 1 || archived[0].ID != 1 || archived[0].CompletedAt != "2026-03-02T12:00:00Z" {
+		t.Fatalf("archive = %+v", archived)
+	}
+
+	// Nothing more is due: no write.
+	rev := st.Revision
+	if moved, err := svc.Compact(14 * 24 * time.Hour); err != nil || moved != 0 {
+		t.Fatalf("second compact = %d, %v", moved, err)
+	}
+	if st, _ := store.Load(); st.Revision != rev {
+		t.Fatalf("revision = %d, want %d", st.Revision, rev)
+	}
+}
+
+func TestCompactStampsLegacyDoneTasks(t *testing.T) {
+	store := tasks.NewStore(filepath.Join(t.TempDir(), "tasks.json"))
+	now := time.Date(2026, 3, 1, 12, 0, 0, 0, time.UTC)
+	svc := tasks.NewTaskService(store).WithClock(func() time.Time { return now })
+
+	st := tasks.State{NextID: 2, Tasks: []tasks.Task{{ID: 1, Text: "legacy", Status: tasks.TaskStatusDone}}}
+	if err := store.Save(st); err != nil {
+		t.Fatalf("save: %v", err)
+	}
+
+	if moved, err := svc.Compact(time.Hour); err != nil || moved != 0 {
+		t.Fatalf("compact = %d, %v; want 0", moved, err)
+	}
+	got, err := store.Load()
+	if err != nil {
+		t.Fatalf("load: %v", err)
+	}
+	if got.Tasks[0].CompletedAt != "2026-03-01T12:00:00Z" {
+		t.Fatalf("completed_at = %q", got.Tasks[0].CompletedAt)
+	}
+
+	now = now.Add(2 * time.Hour)
+	if moved, err := svc.Compact(time.Hour); err != nil || moved != 1 {
+		t.Fatalf("later compact = %d, %v; want 1", moved, err)
+	}
+}
+
+func TestLoadArchiveDedupes(t *testing.T) {
+	dir := t.TempDir()
+	store := tasks.NewStore(filepath.Join(dir, "tasks.json"))
+	if store.ArchivePath() != filepath.Join(dir, "tasks.archive.jsonl") {
+		t.Fatalf("archive path = %q", store.ArchivePath())
+	}
+	line := `{"id":1,"text":"eggs","status":"done"}` + "\n"
+	if err := os.WriteFile(store.ArchivePath(), []byte(line+"\n"+line), 0o600); err != nil {
+		t.Fatalf("write: %v", err)
+	}
+	got, err := store.LoadArchive()
+	if err != nil {
+		t.Fatalf("load archive: %v", err)
+	}
+	if len(got) != 1 || got[0].Text != "eggs" {
+		t.Fatalf("archive = %+v", got)
+	}
+}
+
+func TestFindSearchesArchive(t *testing.T) {
+	store := tasks.NewStore(filepath.Join(t.TempDir(), "tasks.json"))
+	now := time.Date(2026, 3, 1, 12, 0, 0, 0, time.UTC)
+	svc := tasks.NewTaskService(store).WithClock(func() time.Time { return now })
+
+	for _, text := range []string{"Buy eggs", "Call mom", "Boil EGGS"} {
+		if _, err := svc.CreateTomorrow(text); err != nil {
+			t.Fatalf("create: %v", err)
+		}
+	}
+	if _, err := svc.Complete(1); err != nil {
+		t.Fatalf("complete: %v", err)
+	}
+	now = now.Add(48 * time.Hour)
+	if _, err := svc.Compact(24 * time.Hour); err != nil {
+		t.Fatalf("compact: %v", err)
+	}
+
+	found, err := svc.Find(" eggs ")
+	if err != nil {
+		t.Fatalf("find: %v", err)
+	}
+	var ids []string
+	for _, task := range found {
+		ids = append(ids, task.Text)
+	}
+	if got := strings.Join(ids, ","); got != "Boil EGGS,Buy eggs" {
+		t.Fatalf("found = %s", got)
+	}
+}
+
+func TestLoadArchiveConfig(t *testing.T) {
+	dir := t.TempDir()
+	cfg, err := tasks.LoadArchiveConfig(filepath.Join(dir, "missing.json"))
+	if err != nil || cfg != nil {
+		t.Fatalf("missing = %v, %v", cfg, err)
+	}
+	if got := cfg.Retention(); got != tasks.DefaultRetentionDays*24*time.Hour {
+		t.Fatalf("default retention = %v", got)
+	}
+
+	path := filepath.Join(dir, "archive.json")
+	os.WriteFile(path, []byte(`{"retention_days": 7}`), 0o600)
+	cfg, err = tasks.LoadArchiveConfig(path)
+	if err != nil {
+		t.Fatalf("load: %v", err)
+	}
+	if got := cfg.Retention(); got != 7*24*time.Hour {
+		t.Fatalf("retention = %v", got)
+	}
+
+	os.WriteFile(path, []byte(`{"retention_days": -1}`), 0o600)
+	if _, err := tasks.LoadArchiveConfig(path); err == nil {
+		t.Fatal("negative retention accepted")
+	}
+}
@@ -0,0 +1,106 @@
+package tasks
+
+import (
+	"errors"
+	"sync"
+)
+
+// ChangeKind says what happened to a task.
+type ChangeKind string
+
+const (
+	ChangeCreated  ChangeKind = "created"
+	ChangeDone     ChangeKind = "done"
+	ChangeReminded ChangeKind = "reminded"
+	ChangeArchived ChangeKind = "archived"
+)
+
+// Change is one task change, as saved at Revision.
+type Change struct {
+	Revision int64      `json:"revision"`
+	Kind     ChangeKind `json:"kind"`
+	Task     Task       `json:"task"`
+}
+
+// ErrHistoryGone reports a subscription from a revision older than the
+// changes still held. Take a Snapshot and subscribe from its revision.
+var ErrHistoryGone = errors.New("changes since that revision are no longer held")
+
+const (
+	// feedHistory is how many recent changes are kept for late subscribers.
+	feedHistory = 256
+	// feedBuffer is how many changes a subscriber may fall behind by before
+	// its channel is closed.
+	feedBuffer = 64
+)
+
+// feed fans out task changes to subscribers.
+type feed struct {
+	mu     sync.Mutex
+	subs   map[chan Change]struct{}
+	recent []Change
+}
+
+func (f *feed) publish(changes ...Change) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.recent = append(f.recent, changes...)
+	if len(f.recent) > feedHistory {
+		f.recent = append([]Change(nil), f.recent[len(f.recent)-feedHistory:]...)
+	}
+	for ch := range f.subs {
+		for _, c := range changes {
+			select {
+			case ch <- c:
+			default:
+				// Too slow: close it, so the subscriber resyncs rather
+				// than miss changes silently.
+				delete(f.subs, ch)
+				close(ch)
+			}
+			if _, ok := f.subs[ch]; !ok {
+				break
+			}
+		}
+	}
+}
+
+// subscribe feeds changes after since to a new channel. rev is the
+// store's current revision: with no changes held, as after a restart, any
+// since older than it has missed changes that cannot be replayed.
+func (f *feed) subscribe(since, rev int64) (<-chan Change, func(), error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	oldest := rev + 1
+	if len(f.recent) > 0 {
+		oldest = f.recent[0].Revision
+	}
+	if since < oldest-1 {
+		return nil, nil, ErrHistoryGone
+	}
+	var replay []Change
+	for _, c := range f.recent {
+		if c.Revision > since {
+			replay = append(replay, c)
+		}
+	}
+	ch := make(chan Change, feedBuffer+len(replay))
+	for _, c := range replay {
+		ch <- c
+	}
+	if f.subs == nil {
+		f.subs = make(map[chan Change]struct{})
+	}
+	f.subs[ch] = struct{}{}
+
+	cancel := func() {
+		f.mu.Lock()
+		defer f.mu.Unlock()
+		if _, ok := f.subs[ch]; ok {
+			delete(f.subs, ch)
+			close(ch)
+		}
+	}
+	return ch, cancel, nil
+}
@@ -0,0 +1,165 @@
+package tasks_test
+
+import (
+	"errors"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/jdelaire/openslack/internal/tasks"
+)
+
+func TestStoreRejectsStaleSave(t *testing.T) {
+	store := tasks.NewStore(filepath.Join(t.TempDir(), "tasks.json"))
+	st, err := store.Load()
+	if err != nil {
+		t.Fatalf("load: %v", err)
+	}
+	stale := st
+
+	st.Tasks = append(st.Tasks, tasks.Task{ID: 1, Text: "first", Status: tasks.TaskStatusOpen})
+	if err := store.Save(st); err != nil {
+		t.Fatalf("save: %v", err)
+	}
+	stale.Tasks = append(stale.Tasks, tasks.Task{ID: 1, Text: "clobber", Status: tasks.TaskStatusOpen})
+	if err := store.Save(stale); !errors.Is(err, tasks.ErrConflict) {
+		t.Fatalf("stale save: %v, want ErrConflict", err)
+	}
+
+	got, err := store.Load()
+	if err != nil {
+		t.Fatalf("load: %v", err)
+	}
+	if got.Revision != 1 || len(got.Tasks) != 1 || got.Tasks[0].Text != "first" {
+		t.Fatalf("state after stale save = %+v", got)
+	}
+	got.Tasks[0].Text = "second"
+	if err := store.Save(got); err != nil {
+		t.Fatalf("save at current revision: %v", err)
+	}
+}
+
+func receive(t *testing.T, ch <-chan tasks.Change) tasks.Change {
+	t.Helper()
+	select {
+	case c, ok := <-ch:
+		if !ok {
+			t.Fatal("feed closed")
+		}
+		return c
+	case <-time.After(time.Second):
+		t.Fatal("no change received")
+	}
+	return tasks.Change{}
+}
+
+func TestSubscribeFeedsChanges(t *testing.T) {
+	svc := tasks.NewTaskService(tasks.NewStore(filepath.Join(t.TempDir(), "tasks.json"))).
+		WithClock(func() time.Time { return time.Date(2026, 2, 25, 9, 0, 0, 0, time.Local) })
+	snap, err := svc.Snapshot()
+	if err != nil {
+		t.Fatalf("snapshot: %v", err)
+	}
+	changes, cancel, err := svc.Subscribe(snap.Revision)
+	if err != nil {
+		t.Fatalf("subscribe: %v", err)
+	}
+	defer cancel()
+
+	task, err := svc.CreateTomorrow("Buy eggs")
+	if err != nil {
+		t.Fatalf("create: %v", err)
+	}
+	if c := receive(t, changes); c.Kind != tasks.ChangeCreated || c.Task.ID != task.ID || c.Revision != 1 {
+		t.Fatalf("created change = %+v", c)
+	}
+	if _, err := svc.PrepareDailyReminder("2026-02-26"); err != nil {
+		t.Fatalf("remind: %v", err)
+	}
+	c := receive(t, changes)
+	if c.Kind != tasks.ChangeReminded || c.Task.LastRemindedDate == nil || *c.Task.LastRemindedDate != "2026-02-26" {
+		t.Fatalf("reminded change = %+v", c)
+	}
+	if _, err := svc.Complete(task.ID); err != nil {
+		t.Fatalf("complete: %v", err)
+	}
+	if c := receive(t, changes); c.Kind != tasks.ChangeDone || c.Task.Status != tasks.TaskStatusDone || c.Revision != 3 {
+		t.Fatalf("done change = %+v", c)
+	}
+
+	// A late subscriber replays what it missed.
+	late, cancelLate, err := svc.Subscribe(1)
+	if err != nil {
+		t.Fatalf("subscribe late: %v", err)
+	}
+	defer cancelLate()
+	if c := receive(t, late); c.Revision != 2 || c.Kind != tasks.ChangeReminded {
+		t.Fatalf("replayed change = %+v", c)
+	}
+	if c := receive(t, late); c.Revision != 3 {
+		t.Fatalf("replayed change = %+v", c)
+	}
+
+	cancel()
+	if _, ok := <-changes; ok {
+		t.Fatal("feed still open after cancel")
+	}
+	cancel()
+}
+
+func TestSlowSubscriberIsClosed(t *testing.T) {
+	svc := tasks.NewTaskService(tasks.NewStore(filepath.Join(t.TempDir(), "tasks.json")))
+	changes, cancel, err := svc.Subscribe(0)
+	if err != nil {
+		t.Fatalf("subscribe: %v", err)
+	}
+	defer cancel()
+
+	for i := 0; i < 300; i++ {
+		if _, err := svc.CreateTomorrow("task"); err != nil {
+			t.Fatalf("create: %v", err)
+		}
+	}
+	n := 0
+	for range changes {
+		n++
+	}
+	if n == 0 || n >= 300 {
+		t.Fatalf("slow subscriber got %d changes before closing", n)
+	}
+
+	if _, _, err := svc.Subscribe(0); !errors.Is(err, tasks.ErrHistoryGone) {
+		t.Fatalf("subscribe from revision 0 after 300 changes: %v", err)
+	}
+}
+
+func TestSubscribeAfterRestart(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "tasks.json")
+	svc := tasks.NewTaskService(tasks.NewStore(path))
+	for i := 0; i < 2; i++ {
+		if _, err := svc.CreateTomorrow("task"); err != nil {
+			t.Fatalf("create: %v", err)
+		}
+	}
+
+	// A new service holds no changes, so only the current revision can be
+	// resumed from.
+	restarted := tasks.NewTaskService(tasks.NewStore(path))
+	if _, _, err := restarted.Subscribe(1); !errors.Is(err, tasks.ErrHistoryGone) {
+		t.Fatalf("subscribe from revision 1 after restart: %v", err)
+	}
+	changes, cancel, err := restarted.Subscribe(2)
+	if err != nil {
+		t.Fatalf("subscribe from current revision: %v", err)
+	}
+	defer cancel()
+	if _, err := restarted.CreateTomorrow("next"); err != nil {
+		t.Fatalf("create: %v", err)
+	}
+	if c := receive(t, changes); c.Revision != 3 || c.Task.Text != "next" {
+		t.Fatalf("change = %+v", c)
+	}
+	if _, _, err := restarted.Subscribe(1); !errors.Is(err, tasks.ErrHistoryGone) {
+		t.Fatalf("subscribe from revision 1 with changes held: %v", err)
+	}
+}
@@ -2,19 +2,61 @@ package tasks
 
 import (
 	"context"
+	"encoding/json"
 	"fmt"
 	"log/slog"
+	"os"
 	"sort"
 	"strings"
 	"time"
+
+	"github.com/jdelaire/openslack/internal/cron"
 )
 
-// Scheduler runs a local-time 06:00 reminder loop.
+// reminderSpec is the cron schedule of the daily reminder.
+const reminderSpec = "0 6 * * *"
+
+// lateAfter is how long after its time, jitter aside, a reminder is
+// flagged late.
+const lateAfter = time.Minute
+
+// SchedulerConfig tunes the reminder scheduler. JitterSec spreads each
+// reminder over that many seconds after 06:00, so it does not go out at
+// the same instant as other schedules.
+type SchedulerConfig struct {
+	JitterSec int `json:"jitter_sec"`
+}
+
+// LoadSchedulerConfig reads a scheduler config file.
+// Returns nil, nil if the file does not exist.
+func LoadSchedulerConfig(path string) (*SchedulerConfig, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("read scheduler config: %w", err)
+	}
+
+	var cfg SchedulerConfig
+	if err := json.Unmarshal(data, &cfg); err != nil {
+		return nil, fmt.Errorf("parse scheduler config: %w", err)
+	}
+	if cfg.JitterSec < 0 || cfg.JitterSec > 3600 {
+		return nil, fmt.Errorf("scheduler config: jitter_sec must be between 0 and 3600")
+	}
+	return &cfg, nil
+}
+
+// Scheduler sends the daily 06:00 reminder in each zone that tasks are
+// reminded in, as cron jobs. A reminder missed while the daemon was down
+// or asleep is sent late, once.
 type Scheduler struct {
 	service *TaskService
 	send    func(context.Context, string) error
 	logger  *slog.Logger
 	now     func() time.Time
+	jitter  time.Duration
 }
 
 func NewScheduler(service *TaskService, send func(context.Context, string) error, logger *slog.Logger) *Scheduler {
@@ -29,51 +71,129 @@ func NewScheduler(service *TaskService, send func(context.Context, string) error
 	}
 }
 
+// WithConfig applies cfg; a nil cfg keeps the defaults.
+func (s *Scheduler) WithConfig(cfg *SchedulerConfig) *Scheduler {
+	if cfg != nil {
+		s.jitter = time.Duration(cfg.JitterSec) * time.Second
+	}
+	return s
+}
+
+// Jobs returns a reminder job for the default zone, each chat zone, and
+// each zone open tasks are pinned to, for a shared cron engine. A zone a
+// task is pinned to later, by editing tasks.json, gets a job on the next
+// start.
+func (s *Scheduler) Jobs() ([]cron.Job, error) {
+	zones, err := s.service.ReminderZones()
+	if err != nil {
+		return nil, fmt.Errorf("list reminder zones: %w", err)
+	}
+	seen := make(map[string]bool)
+	var jobs []cron.Job
+	for _, loc := range append(s.service.Zones().All(), zones...) {
+		if seen[loc.String()] {
+			continue
+		}
+		seen[loc.String()] = true
+		name := "tasks-reminder"
+		if len(jobs) > 0 {
+			name += ":" + loc.String()
+		}
+		jobs = append(jobs, cron.Job{
+			Name:     name,
+			Spec:     reminderSpec,
+			Location: loc,
+			Jitter:   s.jitter,
+			CatchUp:  true,
+			Run: func(ctx context.Context, at time.Time) error {
+				return s.remind(ctx, loc, at)
+			},
+		})
+	}
+	return jobs, nil
+}
+
+// Run sends any reminder missed since the last one, then runs the
+// reminder jobs on a cron engine of their own until ctx is cancelled.
 func (s *Scheduler) Run(ctx context.Context) {
-	for {
-		wait := durationUntilNextSixAM(s.now())
-		timer := time.NewTimer(wait)
-		select {
-		case <-ctx.Done():
-			timer.Stop()
+	jobs, err := s.Jobs()
+	if err != nil {
+		s.logger.Error("tasks reminder jobs failed", "error", err)
+		return
+	}
+	engine := cron.New(nil, s.logger).WithClock(s.now)
+	for _, j := range jobs {
+		if err := engine.Add(j); err != nil {
+			s.logger.Error("tasks reminder job failed", "job", j.Name, "error", err)
 			return
-		case <-timer.C:
 		}
+	}
+	s.catchUp(ctx, jobs)
+	engine.Run(ctx)
+}
 
-		if err := s.runTick(ctx); err != nil {
-			s.logger.Error("tasks reminder tick failed", "error", err)
+// catchUp sends the latest reminder of each zone that has reminded before
+// but missed it. A zone that has never reminded has nothing to make up, so
+// a fresh install does not send one at once.
+func (s *Scheduler) catchUp(ctx context.Context, jobs []cron.Job) {
+	now := s.now()
+	for _, j := range jobs {
+		at := PreviousReminder(now, j.Location)
+		last, ok, err := s.service.LastReminder(j.Location)
+		if err != nil {
+			s.logger.Error("tasks reminder catch-up failed", "zone", j.Location.String(), "error", err)
+			continue
+		}
+		if !ok || last >= at.Format(dateLayout) {
+			continue
+		}
+		if err := s.remind(ctx, j.Location, at); err != nil {
+			s.logger.Error("tasks reminder catch-up failed", "zone", j.Location.String(), "error", err)
 		}
 	}
 }
 
-func (s *Scheduler) runTick(ctx context.Context) error {
-	today := s.now().In(time.Local).Format(dateLayout)
-	due, err := s.service.PrepareDailyReminder(today)
+// remind sends loc's reminder for the 06:00 at, unless it went out
+// already.
+func (s *Scheduler) remind(ctx context.Context, loc *time.Location, at time.Time) error {
+	today := at.In(loc).Format(dateLayout)
+	if last, ok, err := s.service.LastReminder(loc); err != nil {
+		return fmt.Errorf("read last reminder: %w", err)
+	} else if ok && last >= today {
+		return nil
+	}
+
+	due, err := s.service.PrepareReminder(loc, today)
 	if err != nil {
 		return fmt.Errorf("select due tasks: %w", err)
 	}
 	if len(due) == 0 {
 		return nil
 	}
+	late := s.now().Sub(at) > s.jitter+lateAfter
+	if late {
+		s.logger.Warn("tasks reminder missed, sending late", "zone", loc.String(), "date", today, "tasks", len(due))
+	}
 
+	var notes []string
+	if loc.String() != s.service.Zones().Default().String() {
+		notes = append(notes, loc.String())
+	}
+	if late {
+		notes = append(notes, "late")
+	}
+	title := today
+	if len(notes) > 0 {
+		title += " (" + strings.Join(notes, ", ") + ")"
+	}
 	sendCtx, cancel := context.WithTimeout(ctx, 10*time.Second)
 	defer cancel()
-	if err := s.send(sendCtx, FormatReminderMessage(today, due)); err != nil {
+	if err := s.send(sendCtx, FormatReminderMessage(title, due)); err != nil {
 		return fmt.Errorf("send reminder: %w", err)
 	}
-
 	return nil
 }
 
-func durationUntilNextSixAM(now time.Time) time.Duration {
-	localNow := now.In(time.Local)
-	next := time.Date(localNow.Year(), localNow.Month(), localNow.Day(), 6, 0, 0, 0, localNow.Location())
-	if !localNow.Before(next) {
-		next = next.AddDate(0, 0, 1)
-	}
-	return next.Sub(localNow)
-}
-
 func FormatReminderMessage(today string, due []Task) string {
 	tasks := make([]Task, len(due))
 	copy(tasks, due)
@@ -0,0 +1,157 @@
+package tasks
+
+import (
+	"context"
+	"io"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+func newTestScheduler(t *testing.T, zones *Zones, now *time.Time) (*Scheduler, *TaskService, *[]string) {
+	t.Helper()
+	svc := NewTaskService(NewStore(filepath.Join(t.TempDir(), "tasks.json"))).
+		WithZones(zones).
+		WithClock(func() time.Time { return *now })
+	var sent []string
+	s := NewScheduler(svc, func(_ context.Context, text string) error {
+		sent = append(sent, text)
+		return nil
+	}, slog.New(slog.NewJSONHandler(io.Discard, nil)))
+	s.now = func() time.Time { return *now }
+	return s, svc, &sent
+}
+
+func remind(t *testing.T, s *Scheduler, loc *time.Location, at time.Time) {
+	t.Helper()
+	if err := s.remind(context.Background(), loc, at); err != nil {
+		t.Fatalf("remind: %v", err)
+	}
+}
+
+func TestSchedulerJobsPerZone(t *testing.T) {
+	zones, err := NewZones(&ZoneConfig{Default: "Europe/Paris", Chats: map[string]string{"2": "Asia/Tokyo", "3": "Europe/Paris"}})
+	if err != nil {
+		t.Skipf("zones unavailable: %v", err)
+	}
+	now := time.Date(2026, 3, 2, 12, 0, 0, 0, time.UTC)
+	s, svc, _ := newTestScheduler(t, zones, &now)
+	svc.store.Save(State{Tasks: []Task{{ID: 1, Text: "pinned", StartDate: "2026-03-01", Status: TaskStatusOpen, Timezone: "UTC"}}})
+
+	jobs, err := s.WithConfig(&SchedulerConfig{JitterSec: 30}).Jobs()
+	if err != nil {
+		t.Fatalf("jobs: %v", err)
+	}
+	var names []string
+	for _, j := range jobs {
+		names = append(names, j.Name)
+		if j.Spec != "0 6 * * *" || !j.CatchUp || j.Jitter != 30*time.Second {
+			t.Fatalf("job %s = %+v", j.Name, j)
+		}
+	}
+	want := []string{"tasks-reminder", "tasks-reminder:Asia/Tokyo", "tasks-reminder:UTC"}
+	if !reflect.DeepEqual(names, want) {
+		t.Fatalf("jobs = %v, want %v", names, want)
+	}
+}
+
+func TestSchedulerRemindsPerZone(t *testing.T) {
+	zones, err := NewZones(&ZoneConfig{Default: "Europe/Paris", Chats: map[string]string{"2": "Asia/Tokyo"}})
+	if err != nil {
+		t.Skipf("zones unavailable: %v", err)
+	}
+	paris, tokyo := zones.Default(), zones.chats[2]
+	now := time.Date(2026, 3, 2, 12, 0, 0, 0, time.UTC)
+	s, svc, sent := newTestScheduler(t, zones, &now)
+	if _, err := svc.CreateTomorrowFor(1, "water plants"); err != nil {
+		t.Fatalf("create: %v", err)
+	}
+	if _, err := svc.CreateTomorrowFor(2, "call office"); err != nil {
+		t.Fatalf("create: %v", err)
+	}
+
+	// 06:00 in Tokyo on Mar 3 is 21:00 UTC on Mar 2; in Paris, 05:00 UTC.
+	now = time.Date(2026, 3, 2, 21, 0, 0, 0, time.UTC)
+	remind(t, s, tokyo, time.Date(2026, 3, 3, 6, 0, 0, 0, tokyo))
+	if len(*sent) != 1 || (*sent)[0] != "Tasks for 2026-03-03 (Asia/Tokyo)\n2: call office\nReply /done <id> when finished" {
+		t.Fatalf("tokyo reminder sent %q", *sent)
+	}
+	now = time.Date(2026, 3, 3, 5, 0, 0, 0, time.UTC)
+	remind(t, s, paris, time.Date(2026, 3, 3, 6, 0, 0, 0, paris))
+	remind(t, s, paris, time.Date(2026, 3, 3, 6, 0, 0, 0, paris))
+	if len(*sent) != 2 || (*sent)[1] != "Tasks for 2026-03-03\n1: water plants\nReply /done <id> when finished" {
+		t.Fatalf("paris reminder sent %q", *sent)
+	}
+}
+
+func TestSchedulerCatchesUpMissedReminder(t *testing.T) {
+	zones, _ := NewZones(&ZoneConfig{Default: "UTC"})
+	now := time.Date(2026, 3, 1, 12, 0, 0, 0, time.UTC)
+	s, svc, sent := newTestScheduler(t, zones, &now)
+	if _, err := svc.CreateTomorrow("renew passport"); err != nil {
+		t.Fatalf("create: %v", err)
+	}
+	jobs, err := s.Jobs()
+	if err != nil {
+		t.Fatalf("jobs: %v", err)
+	}
+
+	// First start after the task was created: nothing has reminded yet,
+	// so there is nothing to make up.
+	now = time.Date(2026, 3, 2, 9, 0, 0, 0, time.UTC)
+	s.catchUp(context.Background(), jobs)
+	if len(*sent) != 0 {
+		t.Fatalf("first start sent %q", *sent)
+	}
+
+	now = time.Date(2026, 3, 3, 6, 0, 0, 0, time.UTC)
+	remind(t, s, time.UTC, now)
+	if len(*sent) != 1 || strings.Contains((*sent)[0], "late") {
+		t.Fatalf("on-time reminder = %q", *sent)
+	}
+
+	// Down across Mar 4's and Mar 5's reminders: only the latest is sent.
+	now = time.Date(2026, 3, 5, 9, 12, 0, 0, time.UTC)
+	s.catchUp(context.Background(), jobs)
+	s.catchUp(context.Background(), jobs)
+	if len(*sent) != 2 || !strings.HasPrefix((*sent)[1], "Tasks for 2026-03-05 (late)\n") {
+		t.Fatalf("missed reminder = %q", *sent)
+	}
+}
+
+func TestSchedulerJitterIsNotLate(t *testing.T) {
+	zones, _ := NewZones(&ZoneConfig{Default: "UTC"})
+	now := time.Date(2026, 3, 1, 12, 0, 0, 0, time.UTC)
+	s, svc, sent := newTestScheduler(t, zones, &now)
+	s.WithConfig(&SchedulerConfig{JitterSec: 600})
+	if _, err := svc.CreateTomorrow("stretch"); err != nil {
+		t.Fatalf("create: %v", err)
+	}
+	six := time.Date(2026, 3, 2, 6, 0, 0, 0, time.UTC)
+	now = six.Add(9 * time.Minute)
+	remind(t, s, time.UTC, six)
+	if len(*sent) != 1 || strings.Contains((*sent)[0], "late") {
+		t.Fatalf("jittered reminder = %q", *sent)
+	}
+}
+
+func TestLoadSchedulerConfig(t *testing.T) {
+	dir := t.TempDir()
+	if cfg, err := LoadSchedulerConfig(filepath.Join(dir, "missing.json")); cfg != nil || err != nil {
+		t.Fatalf("missing file: got %v, %v", cfg, err)
+	}
+	path := filepath.Join(dir, "scheduler.json")
+	os.WriteFile(path, []byte(`{"jitter_sec": -1}`), 0o600)
+	if _, err := LoadSchedulerConfig(path); err == nil {
+		t.Fatal("expected an error for negative jitter")
+	}
+	os.WriteFile(path, []byte(`{"jitter_sec": 120}`), 0o600)
+	cfg, err := LoadSchedulerConfig(path)
+	if err != nil || cfg.JitterSec != 120 {
+		t.Fatalf("got %+v, %v", cfg, err)
+	}
+}
@@ -4,9 +4,12 @@ import (
 	"errors"
 	"fmt"
 	"sort"
+	"strconv"
 	"strings"
 	"sync"
 	"time"
+
+	"github.com/jdelaire/openslack/internal/events"
 )
 
 var ErrEmptyTaskText = errors.New("task text is empty")
@@ -22,9 +25,12 @@ const (
 
 // TaskService provides task CRUD and reminder selection logic.
 type TaskService struct {
-	store *Store
-	now   func() time.Time
-	mu    sync.Mutex
+	store  *Store
+	zones  *Zones
+	now    func() time.Time
+	mu     sync.Mutex
+	feed   feed
+	events *events.Bus
 }
 
 func NewTaskService(store *Store) *TaskService {
@@ -34,6 +40,13 @@ func NewTaskService(store *Store) *TaskService {
 	}
 }
 
+// WithEvents publishes an events.TaskCompleted event to bus whenever a
+// task is marked done.
+func (s *TaskService) WithEvents(bus *events.Bus) *TaskService {
+	s.events = bus
+	return s
+}
+
 func (s *TaskService) WithClock(now func() time.Time) *TaskService {
 	if now != nil {
 		s.now = now
@@ -41,7 +54,58 @@ func (s *TaskService) WithClock(now func() time.Time) *TaskService {
 	return s
 }
 
+// Snapshot returns the saved state, with its revision to subscribe from.
+func (s *TaskService) Snapshot() (State, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.store.Load()
+}
+
+// Subscribe returns the task changes this service saves after revision
+// since, starting with any it still holds, and a func to stop. The channel
+// is closed if the subscriber falls too far behind; take a Snapshot and
+// subscribe again. It returns ErrHistoryGone if changes after since are
+// no longer held, including all changes saved before this service
+// started. Changes made by other processes or by editing the file are
+// not fed.
+func (s *TaskService) Subscribe(since int64) (<-chan Change, func(), error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	st, err := s.store.Load()
+	if err != nil {
+		return nil, nil, err
+	}
+	return s.feed.subscribe(since, st.Revision)
+}
+
+// WithZones sets the time zones tasks are dated and reminded in. Without
+// it, everything follows the server's local zone.
+func (s *TaskService) WithZones(z *Zones) *TaskService {
+	s.zones = z
+	return s
+}
+
+// Zones returns the service's time zones.
+func (s *TaskService) Zones() *Zones {
+	return s.zones
+}
+
+// CreateTomorrow creates a task starting tomorrow in the default zone.
 func (s *TaskService) CreateTomorrow(text string) (Task, error) {
+	return s.create(text, "", s.zones.Default())
+}
+
+// CreateTomorrowFor creates a task for chatID starting tomorrow in the
+// chat's zone. A chat with its own zone pins the task to it.
+func (s *TaskService) CreateTomorrowFor(chatID int64, text string) (Task, error) {
+	loc, own := s.zones.ForChat(chatID)
+	if !own {
+		return s.create(text, "", loc)
+	}
+	return s.create(text, loc.String(), loc)
+}
+
+func (s *TaskService) create(text, zone string, loc *time.Location) (Task, error) {
 	text = strings.TrimSpace(text)
 	if text == "" {
 		return Task{}, ErrEmptyTaskText
@@ -55,7 +119,7 @@ func (s *TaskService) CreateTomorrow(text string) (Task, error) {
 		return Task{}, err
 	}
 
-	now := s.now().In(time.Local)
+	now := s.now().In(loc)
 	tomorrowDate := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, now.Location()).AddDate(0, 0, 1)
 
 	id := st.NextID
@@ -71,6 +135,7 @@ func (s *TaskService) CreateTomorrow(text string) (Task, error) {
 		Status:           TaskStatusOpen,
 		Schedule:         scheduleDaily6AM,
 		LastRemindedDate: nil,
+		Timezone:         zone,
 	}
 
 	st.NextID = id + 1
@@ -78,6 +143,7 @@ func (s *TaskService) CreateTomorrow(text string) (Task, error) {
 	if err := s.store.Save(st); err != nil {
 		return Task{}, err
 	}
+	s.feed.publish(Change{Revision: st.Revision + 1, Kind: ChangeCreated, Task: task})
 
 	return task, nil
 }
@@ -128,16 +194,70 @@ func (s *TaskService) Complete(id int) (CompleteStatus, error) {
 	}
 
 	st.Tasks[idx].Status = TaskStatusDone
+	st.Tasks[idx].CompletedAt = s.now().Format(time.RFC3339)
 	if err := s.store.Save(st); err != nil {
 		return CompleteUnknown, err
 	}
+	s.feed.publish(Change{Revision: st.Revision + 1, Kind: ChangeDone, Task: st.Tasks[idx]})
+	s.events.Publish(events.TaskCompleted, map[string]string{
+		"id":   strconv.Itoa(id),
+		"text": st.Tasks[idx].Text,
+	})
 
 	return CompleteUpdated, nil
 }
 
-// PrepareDailyReminder returns tasks that should be reminded today.
-// It sets and persists last_reminded_date before returning the tasks.
+// PrepareDailyReminder returns tasks in the default zone that should be
+// reminded today. It sets and persists last_reminded_date before returning
+// the tasks.
 func (s *TaskService) PrepareDailyReminder(today string) ([]Task, error) {
+	return s.PrepareReminder(s.zones.Default(), today)
+}
+
+// ReminderZones returns the zones open tasks are reminded in, the default
+// zone first.
+func (s *TaskService) ReminderZones() ([]*time.Location, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	st, err := s.store.Load()
+	if err != nil {
+		return nil, err
+	}
+	def := s.zones.Default()
+	zones := []*time.Location{def}
+	seen := map[string]bool{def.String(): true}
+	for _, task := range st.Tasks {
+		if task.Status != TaskStatusOpen {
+			continue
+		}
+		loc := s.zones.forTask(task)
+		if !seen[loc.String()] {
+			seen[loc.String()] = true
+			zones = append(zones, loc)
+		}
+	}
+	return zones, nil
+}
+
+// LastReminder returns the date of loc's last reminder run, and false if
+// it has never run.
+func (s *TaskService) LastReminder(loc *time.Location) (string, bool, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	st, err := s.store.Load()
+	if err != nil {
+		return "", false, err
+	}
+	date, ok := st.LastReminders[loc.String()]
+	return date, ok, nil
+}
+
+// PrepareReminder returns tasks reminded in loc that should be reminded on
+// today, a date in loc. It sets and persists last_reminded_date before
+// returning the tasks, and records today as loc's last reminder run.
+func (s *TaskService) PrepareReminder(loc *time.Location, today string) ([]Task, error) {
 	s.mu.Lock()
 	defer s.mu.Unlock()
 
@@ -153,6 +273,9 @@ func (s *TaskService) PrepareDailyReminder(today string) ([]Task, error) {
 		if task.Status != TaskStatusOpen {
 			continue
 		}
+		if s.zones.forTask(task).String() != loc.String() {
+			continue
+		}
 		if task.StartDate > today {
 			continue
 		}
@@ -164,10 +287,17 @@ func (s *TaskService) PrepareDailyReminder(today string) ([]Task, error) {
 		selectedIdx = append(selectedIdx, i)
 	}
 
-	if len(selected) == 0 {
+	ran := st.LastReminders[loc.String()] >= today
+	if len(selected) == 0 && (ran || len(st.Tasks) == 0) {
 		return nil, nil
 	}
 
+	if !ran {
+		if st.LastReminders == nil {
+			st.LastReminders = make(map[string]string)
+		}
+		st.LastReminders[loc.String()] = today
+	}
 	for _, idx := range selectedIdx {
 		mark := today
 		st.Tasks[idx].LastRemindedDate = &mark
@@ -176,6 +306,14 @@ func (s *TaskService) PrepareDailyReminder(today string) ([]Task, error) {
 	if err := s.store.Save(st); err != nil {
 		return nil, fmt.Errorf("persist reminder marks: %w", err)
 	}
+	changes := make([]Change, 0, len(selectedIdx))
+	for _, idx := range selectedIdx {
+		changes = append(changes, Change{Revision: st.Revision + 1, Kind: ChangeReminded, Task: st.Tasks[idx]})
+	}
+	s.feed.publish(changes...)
+	if len(selected) == 0 {
+		return nil, nil
+	}
 
 	sort.Slice(selected, func(i, j int) bool {
 		return selected[i].ID < selected[j].ID
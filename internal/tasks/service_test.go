@@ -183,6 +183,7 @@ func TestStoreLoadSaveRoundTrip(t *testing.T) {
 		t.Fatalf("load: %v", err)
 	}
 
+	want.Revision = 1
 	if !reflect.DeepEqual(got, want) {
 		t.Fatalf("roundtrip mismatch\n got: %#v\nwant: %#v", got, want)
 	}
@@ -197,3 +198,19 @@ func TestStoreLoadSaveRoundTrip(t *testing.T) {
 		}
 	}
 }
+
+func TestStoreCheckWritable(t *testing.T) {
+	dir := t.TempDir()
+	store := tasks.NewStore(filepath.Join(dir, "tasks.json"))
+	if err := store.CheckWritable(); err != nil {
+		t.Fatalf("check writable: %v", err)
+	}
+	if entries, _ := os.ReadDir(dir); len(entries) != 0 {
+		t.Errorf("check left %d files behind", len(entries))
+	}
+
+	os.WriteFile(store.Path(), []byte("{"), 0o600)
+	if err := store.CheckWritable(); err == nil {
+		t.Error("corrupt tasks file passed")
+	}
+}
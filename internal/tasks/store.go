@@ -7,6 +7,7 @@ import (
 	"fmt"
 	"os"
 	"path/filepath"
+	"sync"
 )
 
 const (
@@ -31,17 +32,32 @@ type Task struct {
 	Status           TaskStatus `json:"status"`
 	Schedule         string     `json:"schedule"`
 	LastRemindedDate *string    `json:"last_reminded_date"`
+	// Timezone is the IANA zone the task is reminded in; empty follows the
+	// default zone.
+	Timezone string `json:"timezone,omitempty"`
+	// CompletedAt is when the task was marked done (RFC3339).
+	CompletedAt string `json:"completed_at,omitempty"`
 }
 
+// ErrConflict reports a save based on a state that has since been
+// replaced, e.g. by another process. Load again and reapply the change.
+var ErrConflict = errors.New("tasks file changed since it was loaded")
+
 // State is the top-level tasks.json structure.
 type State struct {
-	NextID int    `json:"next_id"`
-	Tasks  []Task `json:"tasks"`
+	// Revision counts saves. Save only accepts a state loaded at the
+	// file's current revision.
+	Revision int64  `json:"revision"`
+	NextID   int    `json:"next_id"`
+	Tasks    []Task `json:"tasks"`
+	// LastReminders maps each zone to the date of its last reminder run.
+	LastReminders map[string]string `json:"last_reminders,omitempty"`
 }
 
 // Store persists tasks in a single JSON file.
 type Store struct {
 	path string
+	mu   sync.Mutex // serializes Save's revision check and write
 }
 
 func NewStore(path string) *Store {
@@ -74,8 +90,21 @@ func (s *Store) Load() (State, error) {
 	return st, nil
 }
 
+// Save writes st as the next revision. It returns ErrConflict, writing
+// nothing, if st was not loaded at the file's current revision.
 func (s *Store) Save(st State) (retErr error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	cur, err := s.Load()
+	if err != nil {
+		return err
+	}
+	if st.Revision != cur.Revision {
+		return fmt.Errorf("save tasks at revision %d, file is at %d: %w", st.Revision, cur.Revision, ErrConflict)
+	}
 	st = normalizeState(st)
+	st.Revision++
 
 	dir := filepath.Dir(s.path)
 	if err := os.MkdirAll(dir, 0o700); err != nil {
@@ -140,3 +169,21 @@ func nextIDFromTasks(tasks []Task) int {
 	}
 	return next
 }
+
+// CheckWritable reports whether the tasks file can be read and its
+// directory written, without touching the file itself.
+func (s *Store) CheckWritable() error {
+	if _, err := s.Load(); err != nil {
+		return err
+	}
+	dir := filepath.Dir(s.path)
+	if err := os.MkdirAll(dir, 0o700); err != nil {
+		return fmt.Errorf("create tasks dir: %w", err)
+	}
+	f, err := os.CreateTemp(dir, ".writable-*")
+	if err != nil {
+		return fmt.Errorf("tasks dir not writable: %w", err)
+	}
+	f.Close()
+	return os.Remove(f.Name())
+}
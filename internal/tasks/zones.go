@@ -0,0 +1,144 @@
+package tasks
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"sort"
+	"strconv"
+	"time"
+)
+
+// ZoneConfig sets the time zones task reminders follow. Default applies to
+// every chat without its own entry in Chats, keyed by chat ID. Zones are
+// IANA names such as "Europe/Paris"; an empty Default means the server's
+// local zone.
+type ZoneConfig struct {
+	Default string            `json:"default"`
+	Chats   map[string]string `json:"chats"`
+}
+
+// LoadZoneConfig reads a time zone config file.
+// Returns nil, nil if the file does not exist.
+func LoadZoneConfig(path string) (*ZoneConfig, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("read timezone config: %w", err)
+	}
+
+	var cfg ZoneConfig
+	if err := json.Unmarshal(data, &cfg); err != nil {
+		return nil, fmt.Errorf("parse timezone config: %w", err)
+	}
+	if _, err := NewZones(&cfg); err != nil {
+		return nil, err
+	}
+	return &cfg, nil
+}
+
+// Zones resolves the time zone of a chat or task. A nil *Zones uses the
+// server's local zone for everything.
+type Zones struct {
+	def   *time.Location
+	chats map[int64]*time.Location
+}
+
+// NewZones resolves cfg's zone names; a nil cfg uses the local zone.
+func NewZones(cfg *ZoneConfig) (*Zones, error) {
+	z := &Zones{def: time.Local, chats: make(map[int64]*time.Location)}
+	if cfg == nil {
+		return z, nil
+	}
+	if cfg.Default != "" {
+		loc, err := time.LoadLocation(cfg.Default)
+		if err != nil {
+			return nil, fmt.Errorf("timezone config: default: %w", err)
+		}
+		z.def = loc
+	}
+	for key, name := range cfg.Chats {
+		chatID, err := strconv.ParseInt(key, 10, 64)
+		if err != nil {
+			return nil, fmt.Errorf("timezone config: chat %q is not a chat ID", key)
+		}
+		loc, err := time.LoadLocation(name)
+		if err != nil {
+			return nil, fmt.Errorf("timezone config: chat %s: %w", key, err)
+		}
+		z.chats[chatID] = loc
+	}
+	return z, nil
+}
+
+// Default returns the zone for chats and tasks without their own.
+func (z *Zones) Default() *time.Location {
+	if z == nil {
+		return time.Local
+	}
+	return z.def
+}
+
+// ForChat returns chatID's zone and whether it has its own.
+func (z *Zones) ForChat(chatID int64) (*time.Location, bool) {
+	if z != nil {
+		if loc, ok := z.chats[chatID]; ok {
+			return loc, true
+		}
+	}
+	return z.Default(), false
+}
+
+// All returns the default zone, then each chat's zone, without repeats.
+func (z *Zones) All() []*time.Location {
+	zones := []*time.Location{z.Default()}
+	if z == nil {
+		return zones
+	}
+	seen := map[string]bool{z.def.String(): true}
+	chats := make([]int64, 0, len(z.chats))
+	for chatID := range z.chats {
+		chats = append(chats, chatID)
+	}
+	sort.Slice(chats, func(i, j int) bool { return chats[i] < chats[j] })
+	for _, chatID := range chats {
+		if loc := z.chats[chatID]; !seen[loc.String()] {
+			seen[loc.String()] = true
+			zones = append(zones, loc)
+		}
+	}
+	return zones
+}
+
+// forTask returns the zone a task is reminded in: its own if it has a
+// valid one, else the default.
+func (z *Zones) forTask(task Task) *time.Location {
+	if task.Timezone != "" {
+		if loc, err := time.LoadLocation(task.Timezone); err == nil {
+			return loc
+		}
+	}
+	return z.Default()
+}
+
+// NextReminder returns the first daily reminder time, 06:00 in loc, after
+// now. It steps by calendar day rather than 24 hours, so it stays at 06:00
+// across DST changes; on a day whose 06:00 falls in a DST gap, it is the
+// first instant after the gap.
+func NextReminder(now time.Time, loc *time.Location) time.Time {
+	local := now.In(loc)
+	next := time.Date(local.Year(), local.Month(), local.Day(), 6, 0, 0, 0, loc)
+	if !next.After(now) {
+		next = time.Date(local.Year(), local.Month(), local.Day()+1, 6, 0, 0, 0, loc)
+	}
+	return next
+}
+
+// PreviousReminder returns the most recent daily reminder time, 06:00 in
+// loc, at or before now.
+func PreviousReminder(now time.Time, loc *time.Location) time.Time {
+	next := NextReminder(now, loc)
+	return time.Date(next.Year(), next.Month(), next.Day()-1, 6, 0, 0, 0, loc)
+}
@@ -0,0 +1,166 @@
+package tasks_test
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/jdelaire/openslack/internal/tasks"
+)
+
+func mustLoad(t *testing.T, name string) *time.Location {
+	t.Helper()
+	loc, err := time.LoadLocation(name)
+	if err != nil {
+		t.Skipf("zone %s unavailable: %v", name, err)
+	}
+	return loc
+}
+
+func TestNextReminderAcrossDST(t *testing.T) {
+	ny := mustLoad(t, "America/New_York")
+	paris := mustLoad(t, "Europe/Paris")
+
+	tests := []struct {
+		name string
+		now  time.Time
+		want time.Time
+	}{
+		// Spring forward: the night is an hour short, 06:00 is still 06:00.
+		{"ny spring forward", time.Date(2026, 3, 7, 22, 0, 0, 0, ny), time.Date(2026, 3, 8, 6, 0, 0, 0, ny)},
+		// Fall back: the night is an hour long.
+		{"ny fall back", time.Date(2026, 10, 31, 22, 0, 0, 0, ny), time.Date(2026, 11, 1, 6, 0, 0, 0, ny)},
+		{"paris spring forward", time.Date(2026, 3, 28, 6, 0, 0, 0, paris), time.Date(2026, 3, 29, 6, 0, 0, 0, paris)},
+		{"paris fall back", time.Date(2026, 10, 25, 1, 0, 0, 0, paris), time.Date(2026, 10, 25, 6, 0, 0, 0, paris)},
+		{"just before", time.Date(2026, 3, 29, 5, 59, 59, 0, paris), time.Date(2026, 3, 29, 6, 0, 0, 0, paris)},
+		{"exactly at", time.Date(2026, 3, 29, 6, 0, 0, 0, paris), time.Date(2026, 3, 30, 6, 0, 0, 0, paris)},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := tasks.NextReminder(tt.now, tt.now.Location())
+			if !got.Equal(tt.want) {
+				t.Fatalf("NextReminder(%v) = %v, want %v", tt.now, got, tt.want)
+			}
+			if h, m, _ := got.Clock(); h != 6 || m != 0 {
+				t.Fatalf("NextReminder(%v) = %v, not 06:00 local", tt.now, got)
+			}
+		})
+	}
+
+	// The spring-forward night skips 02:00-03:00, so midnight to 06:00 is 5h.
+	now := time.Date(2026, 3, 8, 0, 0, 0, 0, ny)
+	if got := tasks.NextReminder(now, ny).Sub(now); got != 5*time.Hour {
+		t.Fatalf("wait from midnight on spring-forward day = %v, want 5h", got)
+	}
+}
+
+func TestNextReminderInOtherZone(t *testing.T) {
+	tokyo := mustLoad(t, "Asia/Tokyo")
+	// 22:00 UTC is 07:00 the next day in Tokyo, past its 06:00.
+	now := time.Date(2026, 3, 1, 22, 0, 0, 0, time.UTC)
+	want := time.Date(2026, 3, 3, 6, 0, 0, 0, tokyo)
+	if got := tasks.NextReminder(now, tokyo); !got.Equal(want) {
+		t.Fatalf("NextReminder = %v, want %v", got, want)
+	}
+	prev := time.Date(2026, 3, 2, 6, 0, 0, 0, tokyo)
+	if got := tasks.PreviousReminder(now, tokyo); !got.Equal(prev) {
+		t.Fatalf("PreviousReminder = %v, want %v", got, prev)
+	}
+}
+
+func TestLoadZoneConfig(t *testing.T) {
+	dir := t.TempDir()
+	if cfg, err := tasks.LoadZoneConfig(filepath.Join(dir, "missing.json")); cfg != nil || err != nil {
+		t.Fatalf("missing file: got %v, %v", cfg, err)
+	}
+
+	for name, body := range map[string]string{
+		"bad zone": `{"default": "Mars/Olympus"}`,
+		"bad chat": `{"chats": {"ops": "Europe/Paris"}}`,
+	} {
+		path := filepath.Join(dir, "tz.json")
+		os.WriteFile(path, []byte(body), 0o600)
+		if _, err := tasks.LoadZoneConfig(path); err == nil {
+			t.Fatalf("%s: expected an error", name)
+		}
+	}
+
+	path := filepath.Join(dir, "tz.json")
+	os.WriteFile(path, []byte(`{"default": "Europe/Paris", "chats": {"-100": "Asia/Tokyo"}}`), 0o600)
+	cfg, err := tasks.LoadZoneConfig(path)
+	if err != nil {
+		t.Fatalf("load: %v", err)
+	}
+	zones, err := tasks.NewZones(cfg)
+	if err != nil {
+		t.Fatalf("zones: %v", err)
+	}
+	if got := zones.Default().String(); got != "Europe/Paris" {
+		t.Fatalf("default = %s", got)
+	}
+	if loc, own := zones.ForChat(-100); !own || loc.String() != "Asia/Tokyo" {
+		t.Fatalf("chat -100 = %s, %v", loc, own)
+	}
+	if loc, own := zones.ForChat(7); own || loc.String() != "Europe/Paris" {
+		t.Fatalf("chat 7 = %s, %v", loc, own)
+	}
+
+	var none *tasks.Zones
+	if none.Default() != time.Local {
+		t.Fatal("nil zones should use the local zone")
+	}
+}
+
+func TestRemindersPerZone(t *testing.T) {
+	paris := mustLoad(t, "Europe/Paris")
+	tokyo := mustLoad(t, "Asia/Tokyo")
+	zones, err := tasks.NewZones(&tasks.ZoneConfig{Default: "Europe/Paris", Chats: map[string]string{"2": "Asia/Tokyo"}})
+	if err != nil {
+		t.Fatalf("zones: %v", err)
+	}
+
+	// 23:30 UTC on Mar 1 is 00:30 Mar 2 in Paris and 08:30 Mar 2 in Tokyo,
+	// so "tomorrow" is Mar 3 in both.
+	now := time.Date(2026, 3, 1, 23, 30, 0, 0, time.UTC)
+	svc := tasks.NewTaskService(tasks.NewStore(filepath.Join(t.TempDir(), "tasks.json"))).
+		WithZones(zones).
+		WithClock(func() time.Time { return now })
+
+	home, err := svc.CreateTomorrowFor(1, "water plants")
+	if err != nil {
+		t.Fatalf("create: %v", err)
+	}
+	away, err := svc.CreateTomorrowFor(2, "call office")
+	if err != nil {
+		t.Fatalf("create: %v", err)
+	}
+	if home.Timezone != "" || home.StartDate != "2026-03-03" {
+		t.Fatalf("home task = %+v", home)
+	}
+	if away.Timezone != "Asia/Tokyo" || away.StartDate != "2026-03-03" {
+		t.Fatalf("away task = %+v", away)
+	}
+
+	got, err := svc.ReminderZones()
+	if err != nil {
+		t.Fatalf("zones: %v", err)
+	}
+	var names []string
+	for _, loc := range got {
+		names = append(names, loc.String())
+	}
+	if !reflect.DeepEqual(names, []string{"Europe/Paris", "Asia/Tokyo"}) {
+		t.Fatalf("reminder zones = %v", names)
+	}
+
+	due, err := svc.PrepareReminder(tokyo, "2026-03-03")
+	if err != nil || len(due) != 1 || due[0].ID != away.ID {
+		t.Fatalf("tokyo reminder = %v, %v", due, err)
+	}
+	due, err = svc.PrepareReminder(paris, "2026-03-03")
+	if err != nil || len(due) != 1 || due[0].ID != home.ID {
+		t.Fatalf("paris reminder = %v, %v", due, err)
+	}
+}
@@ -0,0 +1,61 @@
+// Package templates holds named notification templates, so scripts can
+// send a consistent message by name with a few variables instead of
+// formatting their own text.
+package templates
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+
+	"github.com/jdelaire/openslack/internal/chatvars"
+)
+
+// Set is a collection of templates keyed by name. Templates reference
+// variables as {name}, like commands reference chat variables.
+type Set struct {
+	templates map[string]string
+}
+
+// Load reads a templates file mapping names to template text, e.g.
+// {"backup_done": "Backup of {host} finished ({size})"}. Returns nil, nil
+// if the file does not exist.
+func Load(path string) (*Set, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("read templates config: %w", err)
+	}
+
+	var raw map[string]string
+	if err := json.Unmarshal(data, &raw); err != nil {
+		return nil, fmt.Errorf("parse templates config: %w", err)
+	}
+	for name, text := range raw {
+		if name == "" {
+			return nil, fmt.Errorf("template name cannot be empty")
+		}
+		if text == "" {
+			return nil, fmt.Errorf("template %q is empty", name)
+		}
+	}
+	return &Set{templates: raw}, nil
+}
+
+// Render fills in the template called name. Every variable the template
+// references must be in vars; unused vars are ignored.
+func (s *Set) Render(name string, vars map[string]string) (string, error) {
+	text, ok := s.templates[name]
+	if !ok {
+		return "", fmt.Errorf("unknown template %q", name)
+	}
+	out, err := chatvars.Expand(text, vars)
+	var unset *chatvars.UnsetError
+	if errors.As(err, &unset) {
+		return "", fmt.Errorf("template %q needs variable %q", name, unset.Name)
+	}
+	return out, err
+}
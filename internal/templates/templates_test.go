@@ -0,0 +1,41 @@
+package templates
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestRender(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "templates.json")
+	os.WriteFile(path, []byte(`{"backup_done": "Backup of {host} finished ({size})"}`), 0600)
+	set, err := Load(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	got, err := set.Render("backup_done", map[string]string{"host": "nas", "size": "1.2G", "extra": "x"})
+	if err != nil || got != "Backup of nas finished (1.2G)" {
+		t.Errorf("Render = %q, %v", got, err)
+	}
+	if _, err := set.Render("backup_done", map[string]string{"host": "nas"}); err == nil || err.Error() != `template "backup_done" needs variable "size"` {
+		t.Errorf("missing variable error = %v", err)
+	}
+	if _, err := set.Render("nope", nil); err == nil || err.Error() != `unknown template "nope"` {
+		t.Errorf("unknown template error = %v", err)
+	}
+}
+
+func TestLoad(t *testing.T) {
+	dir := t.TempDir()
+	if set, err := Load(filepath.Join(dir, "missing.json")); set != nil || err != nil {
+		t.Errorf("missing file = %v, %v", set, err)
+	}
+	path := filepath.Join(dir, "templates.json")
+	for _, bad := range []string{`{`, `{"empty": ""}`, `{"": "x"}`} {
+		os.WriteFile(path, []byte(bad), 0600)
+		if _, err := Load(path); err == nil {
+			t.Errorf("Load(%s) succeeded", bad)
+		}
+	}
+}
@@ -0,0 +1,157 @@
+// Package textdiff renders line diffs of command output and page text for
+// chat messages.
+package textdiff
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// Lines renders the lines removed from old (-) and added in new (+), using
+// a longest-common-subsequence table. Output beyond maxLines changed lines
+// is summarized in a final line; maxLines <= 0 means no limit.
+func Lines(old, new string, maxLines int) string {
+	var out []string
+	for _, e := range edits(strings.Split(old, "\n"), strings.Split(new, "\n")) {
+		switch e.op {
+		case '+':
+			out = append(out, "+ "+e.text)
+		case '-':
+			out = append(out, "- "+e.text)
+		}
+	}
+	return limit(out, maxLines, "changed lines")
+}
+
+// Unified renders a unified diff of old and new with context unchanged
+// lines around each change, grouped into "@@ -a,b +c,d @@" hunks. Output
+// beyond maxLines lines is summarized; maxLines <= 0 means no limit.
+func Unified(old, new string, context, maxLines int) string {
+	es := removalsFirst(edits(strings.Split(old, "\n"), strings.Split(new, "\n")))
+
+	var out []string
+	for start := 0; start < len(es); {
+		// Find the next change and extend the hunk while changes are
+		// within 2*context lines of each other.
+		first := start
+		for first < len(es) && es[first].op == ' ' {
+			first++
+		}
+		if first == len(es) {
+			break
+		}
+		last := first
+		for i := first + 1; i < len(es) && i <= last+2*context+1; i++ {
+			if es[i].op != ' ' {
+				last = i
+			}
+		}
+		from := max(first-context, start)
+		to := min(last+context+1, len(es))
+
+		oldStart, newStart := 1, 1
+		for _, e := range es[:from] {
+			if e.op != '+' {
+				oldStart++
+			}
+			if e.op != '-' {
+				newStart++
+			}
+		}
+		var oldCount, newCount int
+		var lines []string
+		for _, e := range es[from:to] {
+			if e.op != '+' {
+				oldCount++
+			}
+			if e.op != '-' {
+				newCount++
+			}
+			lines = append(lines, string(e.op)+e.text)
+		}
+		out = append(out, fmt.Sprintf("@@ -%s +%s @@", hunkRange(oldStart, oldCount), hunkRange(newStart, newCount)))
+		out = append(out, lines...)
+		start = to
+	}
+	return limit(out, maxLines, "lines")
+}
+
+// removalsFirst reorders each run of changes so removed lines come before
+// added ones, as in diff -u.
+func removalsFirst(es []edit) []edit {
+	for i := 0; i < len(es); {
+		if es[i].op == ' ' {
+			i++
+			continue
+		}
+		j := i
+		for j < len(es) && es[j].op != ' ' {
+			j++
+		}
+		run := es[i:j]
+		sort.SliceStable(run, func(a, b int) bool { return run[a].op == '-' && run[b].op == '+' })
+		i = j
+	}
+	return es
+}
+
+// edit is one line of an edit script: ' ' kept, '-' removed, '+' added.
+type edit struct {
+	op   byte
+	text string
+}
+
+// edits computes an edit script using a longest-common-subsequence table.
+func edits(a, b []string) []edit {
+	lcs := make([][]int, len(a)+1)
+	for i := range lcs {
+		lcs[i] = make([]int, len(b)+1)
+	}
+	for i := len(a) - 1; i >= 0; i-- {
+		for j := len(b) - 1; j >= 0; j-- {
+			if a[i] == b[j] {
+				lcs[i][j] = lcs[i+1][j+1] + 1
+			} else {
+				lcs[i][j] = max(lcs[i+1][j], lcs[i][j+1])
+			}
+		}
+	}
+
+	var out []edit
+	i, j := 0, 0
+	for i < len(a) || j < len(b) {
+		switch {
+		case i < len(a) && j < len(b) && a[i] == b[j]:
+			out = append(out, edit{' ', a[i]})
+			i++
+			j++
+		case j < len(b) && (i == len(a) || lcs[i][j+1] >= lcs[i+1][j]):
+			out = append(out, edit{'+', b[j]})
+			j++
+		default:
+			out = append(out, edit{'-', a[i]})
+			i++
+		}
+	}
+	return out
+}
+
+// hunkRange formats a unified diff range. An empty range names the line
+// before it.
+func hunkRange(start, count int) string {
+	if count == 0 {
+		start--
+	}
+	if count == 1 {
+		return fmt.Sprint(start)
+	}
+	return fmt.Sprintf("%d,%d", start, count)
+}
+
+func limit(out []string, maxLines int, noun string) string {
+	if maxLines > 0 && len(out) > maxLines {
+		out = append(out[:maxLines], fmt.Sprintf("… %d more %s", len(out)-maxLines, noun))
+	}
+	return strings.Join(out, "\n")
+}
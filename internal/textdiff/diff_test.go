@@ -0,0 +1,61 @@
+package textdiff
+
+import "testing"
+
+func TestLines(t *testing.T) {
+	tests := []struct {
+		name     string
+		old, new string
+		max      int
+		want     string
+	}{
+		{"identical", "a\nb", "a\nb", 0, ""},
+		{"changed line", "a\nb\nc", "a\nB\nc", 0, "+ B\n- b"},
+		{"added and removed", "a\nb", "b\nc", 0, "- a\n+ c"},
+		{"truncated", "x", "1\n2\n3", 2, "+ 1\n+ 2\n… 2 more changed lines"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := Lines(tt.old, tt.new, tt.max); got != tt.want {
+				t.Errorf("Lines = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestUnified(t *testing.T) {
+	tests := []struct {
+		name     string
+		old, new string
+		context  int
+		max      int
+		want     string
+	}{
+		{"identical", "a\nb", "a\nb", 3, 0, ""},
+		{
+			"changed line with context",
+			"a\nb\nc\nd\ne", "a\nb\nC\nd\ne", 1, 0,
+			"@@ -2,3 +2,3 @@\n b\n-c\n+C\n d",
+		},
+		{
+			"separate hunks",
+			"1\n2\n3\n4\n5\n6\n7\n8", "x\n2\n3\n4\n5\n6\n7\ny", 1, 0,
+			"@@ -1,2 +1,2 @@\n-1\n+x\n 2\n@@ -7,2 +7,2 @@\n 7\n-8\n+y",
+		},
+		{
+			"nearby changes merge",
+			"1\n2\n3\n4", "x\n2\n3\ny", 1, 0,
+			"@@ -1,4 +1,4 @@\n-1\n+x\n 2\n 3\n-4\n+y",
+		},
+		{"appended", "a", "a\nb", 0, 0, "@@ -1,0 +2 @@\n+b"},
+		{"removed", "a\nb", "a", 0, 0, "@@ -2 +1,0 @@\n-b"},
+		{"truncated", "a", "b", 0, 2, "@@ -1 +1 @@\n-a\n… 1 more lines"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := Unified(tt.old, tt.new, tt.context, tt.max); got != tt.want {
+				t.Errorf("Unified =\n%s\nwant\n%s", got, tt.want)
+			}
+		})
+	}
+}
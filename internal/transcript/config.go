@@ -0,0 +1,54 @@
+package transcript
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+
+	"github.com/jdelaire/openslack/internal/homedir"
+)
+
+// Defaults.
+const (
+	DefaultRetainDays = 7
+	maxRetainDays     = 365
+)
+
+// Config controls the transcript. Dir defaults to ~/.openslack/transcripts.
+type Config struct {
+	Dir        string `json:"dir"`
+	RetainDays int    `json:"retain_days"`
+}
+
+// LoadConfig reads and validates a transcript config file.
+// Returns nil, nil if the file does not exist.
+func LoadConfig(path string) (*Config, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("read transcript config: %w", err)
+	}
+
+	var cfg Config
+	if err := json.Unmarshal(data, &cfg); err != nil {
+		return nil, fmt.Errorf("parse transcript config: %w", err)
+	}
+	if cfg.RetainDays < 0 || cfg.RetainDays > maxRetainDays {
+		return nil, fmt.Errorf("transcript retain_days must be between 1 and %d", maxRetainDays)
+	}
+
+	applyDefaults(&cfg)
+	return &cfg, nil
+}
+
+func applyDefaults(cfg *Config) {
+	if cfg.Dir == "" {
+		cfg.Dir = "~/.openslack/transcripts"
+	}
+	cfg.Dir = homedir.Expand(cfg.Dir)
+	if cfg.RetainDays == 0 {
+		cfg.RetainDays = DefaultRetainDays
+	}
+}
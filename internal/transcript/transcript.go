@@ -0,0 +1,158 @@
+// Package transcript keeps a rolling record of what each chat sent the
+// bot and what the bot replied, so /transcript can export it. Unlike the
+// audit log, which records that ops ran, it keeps the words.
+package transcript
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Who an entry is from.
+const (
+	FromChat = "chat"
+	FromBot  = "bot"
+)
+
+const (
+	dayLayout = "2006-01-02"
+	// maxText caps the text kept for one entry.
+	maxText = 16 << 10
+)
+
+// Entry is one message in a transcript.
+type Entry struct {
+	At   time.Time `json:"at"`
+	From string    `json:"from"`
+	Text string    `json:"text"`
+}
+
+// Log stores each chat's entries as JSON lines in <dir>/<chat id>/<day>.jsonl,
+// one file per local day, and deletes days past the retention.
+type Log struct {
+	dir        string
+	retainDays int
+	now        func() time.Time
+
+	mu     sync.Mutex
+	pruned string // the day old files were last pruned
+}
+
+// New opens the transcript in cfg.Dir, creating it with 0700 permissions.
+func New(cfg *Config) (*Log, error) {
+	if err := os.MkdirAll(cfg.Dir, 0700); err != nil {
+		return nil, fmt.Errorf("create transcript directory: %w", err)
+	}
+	return &Log{dir: cfg.Dir, retainDays: cfg.RetainDays, now: time.Now}, nil
+}
+
+// Record appends a message from from, FromChat or FromBot, to the chat's
+// transcript.
+func (l *Log) Record(chatID int64, from, text string, at time.Time) error {
+	if len(text) > maxText {
+		text = strings.ToValidUTF8(text[:maxText], "")
+	}
+	at = at.Local()
+	line, err := json.Marshal(Entry{At: at, From: from, Text: text})
+	if err != nil {
+		return err
+	}
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.prune()
+
+	dir := filepath.Join(l.dir, strconv.FormatInt(chatID, 10))
+	if err := os.MkdirAll(dir, 0700); err != nil {
+		return fmt.Errorf("record transcript: %w", err)
+	}
+	f, err := os.OpenFile(filepath.Join(dir, at.Format(dayLayout)+".jsonl"), os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0600)
+	if err != nil {
+		return fmt.Errorf("record transcript: %w", err)
+	}
+	if _, err := f.Write(append(line, '\n')); err != nil {
+		f.Close()
+		return fmt.Errorf("record transcript: %w", err)
+	}
+	return f.Close()
+}
+
+// Between returns the chat's entries from from up to, but not including,
+// to, oldest first.
+func (l *Log) Between(chatID int64, from, to time.Time) ([]Entry, error) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	from, to = from.Local(), to.Local()
+	var entries []Entry
+	for day := startOfDay(from); day.Before(to); day = day.AddDate(0, 0, 1) {
+		dayEntries, err := l.readDay(chatID, day)
+		if err != nil {
+			return nil, err
+		}
+		for _, e := range dayEntries {
+			if !e.At.Before(from) && e.At.Before(to) {
+				entries = append(entries, e)
+			}
+		}
+	}
+	return entries, nil
+}
+
+// readDay reads one day's file, skipping lines a crash left unfinished.
+func (l *Log) readDay(chatID int64, day time.Time) ([]Entry, error) {
+	f, err := os.Open(filepath.Join(l.dir, strconv.FormatInt(chatID, 10), day.Format(dayLayout)+".jsonl"))
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("read transcript: %w", err)
+	}
+	defer f.Close()
+
+	var entries []Entry
+	sc := bufio.NewScanner(f)
+	sc.Buffer(nil, 8*maxText)
+	for sc.Scan() {
+		var e Entry
+		if json.Unmarshal(sc.Bytes(), &e) == nil {
+			entries = append(entries, e)
+		}
+	}
+	if err := sc.Err(); err != nil {
+		return nil, fmt.Errorf("read transcript: %w", err)
+	}
+	return entries, nil
+}
+
+// prune deletes day files older than the retention, once a day.
+func (l *Log) prune() {
+	now := l.now().Local()
+	today := now.Format(dayLayout)
+	if l.pruned == today {
+		return
+	}
+	l.pruned = today
+	cutoff := now.AddDate(0, 0, -l.retainDays).Format(dayLayout)
+	paths, err := filepath.Glob(filepath.Join(l.dir, "*", "*.jsonl"))
+	if err != nil {
+		return
+	}
+	for _, p := range paths {
+		if strings.TrimSuffix(filepath.Base(p), ".jsonl") <= cutoff {
+			os.Remove(p)
+		}
+	}
+}
+
+func startOfDay(t time.Time) time.Time {
+	y, m, d := t.Date()
+	return time.Date(y, m, d, 0, 0, 0, 0, t.Location())
+}
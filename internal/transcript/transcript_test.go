@@ -0,0 +1,147 @@
+package transcript
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func newTestLog(t *testing.T, now time.Time) *Log {
+	t.Helper()
+	l, err := New(&Config{Dir: t.TempDir(), RetainDays: 7})
+	if err != nil {
+		t.Fatal(err)
+	}
+	l.now = func() time.Time { return now }
+	return l
+}
+
+func TestRecordAndBetween(t *testing.T) {
+	day := time.Date(2026, 10, 18, 0, 0, 0, 0, time.Local)
+	l := newTestLog(t, day.Add(12*time.Hour))
+
+	for _, e := range []Entry{
+		{day.Add(-time.Hour), FromChat, "/status"},
+		{day.Add(9 * time.Hour), FromChat, "/disk ••••••"},
+		{day.Add(9*time.Hour + time.Second), FromBot, "/ 91% used"},
+		{day.Add(10 * time.Hour), FromChat, "/uptime"},
+	} {
+		if err := l.Record(100, e.From, e.Text, e.At); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := l.Record(200, FromChat, "other chat", day.Add(9*time.Hour)); err != nil {
+		t.Fatal(err)
+	}
+
+	got, err := l.Between(100, day, day.AddDate(0, 0, 1))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(got) != 3 || got[0].Text != "/disk ••••••" || got[1].From != FromBot || !got[1].At.Equal(day.Add(9*time.Hour+time.Second)) {
+		t.Fatalf("today = %+v", got)
+	}
+
+	got, err = l.Between(100, day.Add(-2*time.Hour), day.Add(10*time.Hour))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(got) != 3 || got[0].Text != "/status" || got[2].Text != "/ 91% used" {
+		t.Errorf("across midnight = %+v", got)
+	}
+
+	if got, err := l.Between(300, day, day.AddDate(0, 0, 1)); err != nil || len(got) != 0 {
+		t.Errorf("unknown chat = %v, %v", got, err)
+	}
+}
+
+func TestRecordSkipsBrokenLines(t *testing.T) {
+	day := time.Date(2026, 10, 18, 9, 0, 0, 0, time.Local)
+	l := newTestLog(t, day)
+	if err := l.Record(100, FromChat, "/status", day); err != nil {
+		t.Fatal(err)
+	}
+	path := filepath.Join(l.dir, "100", "2026-10-18.jsonl")
+	f, err := os.OpenFile(path, os.O_APPEND|os.O_WRONLY, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	f.WriteString(`{"at":"2026-10-18T09:00:01`)
+	f.Close()
+	// The next record starts on the broken line, so both are lost, but
+	// the ones after are read.
+	l.Record(100, FromBot, "lost", day.Add(time.Second))
+	l.Record(100, FromBot, "kept", day.Add(2*time.Second))
+
+	got, err := l.Between(100, day, day.Add(time.Hour))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(got) != 2 || got[0].Text != "/status" || got[1].Text != "kept" {
+		t.Errorf("entries = %+v", got)
+	}
+}
+
+func TestRecordCapsText(t *testing.T) {
+	at := time.Date(2026, 10, 18, 9, 0, 0, 0, time.Local)
+	l := newTestLog(t, at)
+	if err := l.Record(100, FromBot, strings.Repeat("é", maxText), at); err != nil {
+		t.Fatal(err)
+	}
+	got, err := l.Between(100, at, at.Add(time.Second))
+	if err != nil || len(got) != 1 {
+		t.Fatalf("entries = %v, %v", got, err)
+	}
+	if len(got[0].Text) != maxText || !strings.HasSuffix(got[0].Text, "é") {
+		t.Errorf("kept %d bytes", len(got[0].Text))
+	}
+}
+
+func TestPrune(t *testing.T) {
+	now := time.Date(2026, 10, 18, 9, 0, 0, 0, time.Local)
+	l := newTestLog(t, now)
+	for _, days := range []int{0, 6, 7, 30} {
+		dir := filepath.Join(l.dir, "100")
+		os.MkdirAll(dir, 0700)
+		os.WriteFile(filepath.Join(dir, now.AddDate(0, 0, -days).Format(dayLayout)+".jsonl"), nil, 0600)
+	}
+	if err := l.Record(100, FromChat, "/status", now); err != nil {
+		t.Fatal(err)
+	}
+	paths, _ := filepath.Glob(filepath.Join(l.dir, "100", "*.jsonl"))
+	var kept []string
+	for _, p := range paths {
+		kept = append(kept, filepath.Base(p))
+	}
+	if strings.Join(kept, " ") != "2026-10-12.jsonl 2026-10-18.jsonl" {
+		t.Errorf("kept %v", kept)
+	}
+}
+
+func TestLoadConfig(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "transcript.json")
+
+	cfg, err := LoadConfig(path)
+	if cfg != nil || err != nil {
+		t.Fatalf("missing file = %v, %v; want nil, nil", cfg, err)
+	}
+
+	os.WriteFile(path, []byte(`{}`), 0600)
+	cfg, err = LoadConfig(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if cfg.RetainDays != DefaultRetainDays || !strings.HasSuffix(cfg.Dir, filepath.Join(".openslack", "transcripts")) {
+		t.Errorf("defaults = %+v", cfg)
+	}
+
+	for _, bad := range []string{`{"retain_days": -1}`, `{"retain_days": 400}`, `{"dir": 1}`} {
+		os.WriteFile(path, []byte(bad), 0600)
+		if _, err := LoadConfig(path); err == nil {
+			t.Errorf("LoadConfig(%s) succeeded", bad)
+		}
+	}
+}
@@ -0,0 +1,240 @@
+package watch
+
+import (
+	"fmt"
+	"io"
+	"strings"
+
+	"golang.org/x/net/html"
+)
+
+// A small CSS selector engine covering what page watching needs: type,
+// universal, #id, .class, [attr], [attr=value], descendant and child
+// combinators, and comma-separated groups. Pseudo-classes are rejected.
+
+type attrSel struct {
+	name, value string
+	hasValue    bool
+}
+
+type compound struct {
+	tag     string // "" or "*" matches any element
+	id      string
+	classes []string
+	attrs   []attrSel
+}
+
+type step struct {
+	sel   compound
+	child bool // combinator to the previous step is ">" rather than descendant
+}
+
+// complexSel is a chain of compounds, leftmost first.
+type complexSel []step
+
+// parseCSS parses a selector group.
+func parseCSS(s string) ([]complexSel, error) {
+	var groups []complexSel
+	for _, part := range strings.Split(s, ",") {
+		cs, err := parseComplex(strings.TrimSpace(part))
+		if err != nil {
+			return nil, err
+		}
+		groups = append(groups, cs)
+	}
+	return groups, nil
+}
+
+func parseComplex(s string) (complexSel, error) {
+	if s == "" {
+		return nil, fmt.Errorf("empty selector")
+	}
+	s = strings.ReplaceAll(s, ">", " > ")
+	var cs complexSel
+	child := false
+	for _, tok := range strings.Fields(s) {
+		if tok == ">" {
+			if len(cs) == 0 || child {
+				return nil, fmt.Errorf("misplaced > in selector")
+			}
+			child = true
+			continue
+		}
+		c, err := parseCompound(tok)
+		if err != nil {
+			return nil, err
+		}
+		cs = append(cs, step{sel: c, child: child})
+		child = false
+	}
+	if child {
+		return nil, fmt.Errorf("selector ends with >")
+	}
+	return cs, nil
+}
+
+func parseCompound(s string) (compound, error) {
+	var c compound
+	i := 0
+	for i < len(s) && s[i] != '#' && s[i] != '.' && s[i] != '[' {
+		i++
+	}
+	c.tag = strings.ToLower(s[:i])
+	if strings.ContainsAny(c.tag, ":()") {
+		return c, fmt.Errorf("unsupported selector %q", s)
+	}
+
+	for i < len(s) {
+		switch s[i] {
+		case '#', '.':
+			j := i + 1
+			for j < len(s) && s[j] != '#' && s[j] != '.' && s[j] != '[' {
+				j++
+			}
+			name := s[i+1 : j]
+			if name == "" || strings.ContainsAny(name, ":()") {
+				return c, fmt.Errorf("unsupported selector %q", s)
+			}
+			if s[i] == '#' {
+				c.id = name
+			} else {
+				c.classes = append(c.classes, name)
+			}
+			i = j
+		case '[':
+			j := strings.IndexByte(s[i:], ']')
+			if j < 0 {
+				return c, fmt.Errorf("unterminated [ in selector %q", s)
+			}
+			body := s[i+1 : i+j]
+			var a attrSel
+			if name, value, ok := strings.Cut(body, "="); ok {
+				a = attrSel{name: strings.ToLower(name), value: strings.Trim(value, `"'`), hasValue: true}
+			} else {
+				a = attrSel{name: strings.ToLower(body)}
+			}
+			if a.name == "" {
+				return c, fmt.Errorf("empty attribute in selector %q", s)
+			}
+			c.attrs = append(c.attrs, a)
+			i += j + 1
+		default:
+			return c, fmt.Errorf("unsupported selector %q", s)
+		}
+	}
+	return c, nil
+}
+
+func (c compound) matches(n *html.Node) bool {
+	if n.Type != html.ElementNode {
+		return false
+	}
+	if c.tag != "" && c.tag != "*" && n.Data != c.tag {
+		return false
+	}
+	if c.id != "" && attr(n, "id") != c.id {
+		return false
+	}
+	for _, cls := range c.classes {
+		if !hasClass(n, cls) {
+			return false
+		}
+	}
+	for _, a := range c.attrs {
+		v, ok := lookupAttr(n, a.name)
+		if !ok || (a.hasValue && v != a.value) {
+			return false
+		}
+	}
+	return true
+}
+
+// matches checks n against the chain right to left.
+func (cs complexSel) matches(n *html.Node) bool {
+	return matchFrom(cs, len(cs)-1, n)
+}
+
+func matchFrom(cs complexSel, i int, n *html.Node) bool {
+	if !cs[i].sel.matches(n) {
+		return false
+	}
+	if i == 0 {
+		return true
+	}
+	if cs[i].child {
+		return n.Parent != nil && matchFrom(cs, i-1, n.Parent)
+	}
+	for p := n.Parent; p != nil; p = p.Parent {
+		if matchFrom(cs, i-1, p) {
+			return true
+		}
+	}
+	return false
+}
+
+// selectCSS parses an HTML document and returns the normalized text of
+// every element matching selector, in document order.
+func selectCSS(r io.Reader, selector string) ([]string, error) {
+	groups, err := parseCSS(selector)
+	if err != nil {
+		return nil, err
+	}
+	doc, err := html.Parse(r)
+	if err != nil {
+		return nil, fmt.Errorf("parse html: %w", err)
+	}
+
+	var out []string
+	var walk func(*html.Node)
+	walk = func(n *html.Node) {
+		for _, g := range groups {
+			if g.matches(n) {
+				out = append(out, strings.Join(strings.Fields(textContent(n)), " "))
+				break
+			}
+		}
+		for c := n.FirstChild; c != nil; c = c.NextSibling {
+			walk(c)
+		}
+	}
+	walk(doc)
+	return out, nil
+}
+
+func textContent(n *html.Node) string {
+	if n.Type == html.TextNode {
+		return n.Data
+	}
+	if n.Type == html.ElementNode && (n.Data == "script" || n.Data == "style") {
+		return ""
+	}
+	var b strings.Builder
+	for c := n.FirstChild; c != nil; c = c.NextSibling {
+		b.WriteString(textContent(c))
+		b.WriteByte(' ')
+	}
+	return b.String()
+}
+
+func lookupAttr(n *html.Node, name string) (string, bool) {
+	for _, a := range n.Attr {
+		if a.Key == name {
+			return a.Val, true
+		}
+	}
+	return "", false
+}
+
+func attr(n *html.Node, name string) string {
+	v, _ := lookupAttr(n, name)
+	return v
+}
+
+func hasClass(n *html.Node, cls string) bool {
+	for _, c := range strings.Fields(attr(n, "class")) {
+		if c == cls {
+			return true
+		}
+	}
+	return false
+}
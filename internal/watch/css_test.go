@@ -0,0 +1,52 @@
+package watch
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+const testPage = `<html><head><style>.price{color:red}</style></head><body>
+<div id="product" class="card featured">
+  <h1>Widget</h1>
+  <span class="price" data-currency="USD">$1,299.00</span>
+  <ul><li>small</li><li class="sold-out">medium</li></ul>
+</div>
+<div class="card"><span class="price">$5</span></div>
+<script>var price = 1;</script>
+</body></html>`
+
+func TestSelectCSS(t *testing.T) {
+	tests := []struct {
+		selector string
+		want     []string
+	}{
+		{"h1", []string{"Widget"}},
+		{"#product .price", []string{"$1,299.00"}},
+		{".card.featured > span", []string{"$1,299.00"}},
+		{"div > li", nil},
+		{"ul li", []string{"small", "medium"}},
+		{"span[data-currency=USD]", []string{"$1,299.00"}},
+		{"span[data-currency]", []string{"$1,299.00"}},
+		{".price", []string{"$1,299.00", "$5"}},
+		{"h1, li.sold-out", []string{"Widget", "medium"}},
+		{"body", []string{"Widget $1,299.00 small medium $5"}},
+	}
+	for _, tt := range tests {
+		got, err := selectCSS(strings.NewReader(testPage), tt.selector)
+		if err != nil {
+			t.Fatalf("select %q: %v", tt.selector, err)
+		}
+		if !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("select %q = %q, want %q", tt.selector, got, tt.want)
+		}
+	}
+}
+
+func TestParseCSSErrors(t *testing.T) {
+	for _, sel := range []string{"", "a:hover", "li:nth-child(2)", "> a", "a >", "a[", "a,", "[=x]"} {
+		if _, err := parseCSS(sel); err == nil {
+			t.Errorf("parseCSS(%q) succeeded, want error", sel)
+		}
+	}
+}
@@ -0,0 +1,40 @@
+package watch
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/jdelaire/openslack/internal/textdiff"
+)
+
+const maxDiffLines = 20
+
+var numberRe = regexp.MustCompile(`-?\d[\d,]*(?:\.\d+)?`)
+
+// parseNumber extracts the first number in s, ignoring currency symbols
+// and thousands separators ("$1,299.00" -> 1299).
+func parseNumber(s string) (float64, bool) {
+	m := numberRe.FindString(s)
+	if m == "" {
+		return 0, false
+	}
+	n, err := strconv.ParseFloat(strings.ReplaceAll(m, ",", ""), 64)
+	return n, err == nil
+}
+
+// renderDiff describes a change from old to new. Short numeric values
+// render as "old → new (+x%)"; single lines as "old → new"; multi-line
+// text as a line diff of removed (-) and added (+) lines.
+func renderDiff(old, new string) string {
+	if !strings.Contains(old, "\n") && !strings.Contains(new, "\n") {
+		o, okOld := parseNumber(old)
+		n, okNew := parseNumber(new)
+		if okOld && okNew && len(old) <= 32 && len(new) <= 32 && o != 0 {
+			return fmt.Sprintf("%s → %s (%+.1f%%)", old, new, (n-o)/o*100)
+		}
+		return fmt.Sprintf("%s → %s", truncate(old, 200), truncate(new, 200))
+	}
+	return textdiff.Lines(old, new, maxDiffLines)
+}
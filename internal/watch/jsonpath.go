@@ -0,0 +1,113 @@
+package watch
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+)
+
+// A JSONPath subset: $ followed by .key, ['key'] / ["key"], and [index]
+// steps. Wildcards and filters are not supported.
+
+type pathStep struct {
+	key   string
+	index int
+	isIdx bool
+}
+
+func parseJSONPath(p string) ([]pathStep, error) {
+	if !strings.HasPrefix(p, "$") {
+		return nil, fmt.Errorf("json path must start with $")
+	}
+	rest := p[1:]
+	var steps []pathStep
+	for rest != "" {
+		switch rest[0] {
+		case '.':
+			j := 1
+			for j < len(rest) && rest[j] != '.' && rest[j] != '[' {
+				j++
+			}
+			key := rest[1:j]
+			if key == "" || key == "*" {
+				return nil, fmt.Errorf("unsupported json path %q", p)
+			}
+			steps = append(steps, pathStep{key: key})
+			rest = rest[j:]
+		case '[':
+			j := strings.IndexByte(rest, ']')
+			if j < 0 {
+				return nil, fmt.Errorf("unterminated [ in json path %q", p)
+			}
+			body := rest[1:j]
+			if len(body) >= 2 && (body[0] == '\'' || body[0] == '"') && body[len(body)-1] == body[0] {
+				steps = append(steps, pathStep{key: body[1 : len(body)-1]})
+			} else {
+				idx, err := strconv.Atoi(body)
+				if err != nil || idx < 0 {
+					return nil, fmt.Errorf("unsupported json path %q", p)
+				}
+				steps = append(steps, pathStep{index: idx, isIdx: true})
+			}
+			rest = rest[j+1:]
+		default:
+			return nil, fmt.Errorf("unsupported json path %q", p)
+		}
+	}
+	return steps, nil
+}
+
+// selectJSON decodes a JSON document and returns the value at path.
+// Strings are returned as-is; other values as compact JSON.
+func selectJSON(r io.Reader, path string) (string, error) {
+	steps, err := parseJSONPath(path)
+	if err != nil {
+		return "", err
+	}
+
+	dec := json.NewDecoder(r)
+	dec.UseNumber()
+	var v any
+	if err := dec.Decode(&v); err != nil {
+		return "", fmt.Errorf("parse json: %w", err)
+	}
+
+	for _, s := range steps {
+		switch cur := v.(type) {
+		case map[string]any:
+			if s.isIdx {
+				return "", fmt.Errorf("%s: index into object", path)
+			}
+			next, ok := cur[s.key]
+			if !ok {
+				return "", fmt.Errorf("%s: key %q not found", path, s.key)
+			}
+			v = next
+		case []any:
+			if !s.isIdx {
+				return "", fmt.Errorf("%s: key %q on array", path, s.key)
+			}
+			if s.index >= len(cur) {
+				return "", fmt.Errorf("%s: index %d out of range", path, s.index)
+			}
+			v = cur[s.index]
+		default:
+			return "", fmt.Errorf("%s: cannot descend into %T", path, cur)
+		}
+	}
+
+	switch val := v.(type) {
+	case string:
+		return val, nil
+	case json.Number:
+		return val.String(), nil
+	default:
+		out, err := json.Marshal(val)
+		if err != nil {
+			return "", err
+		}
+		return string(out), nil
+	}
+}
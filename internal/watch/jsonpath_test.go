@@ -0,0 +1,46 @@
+package watch
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestSelectJSON(t *testing.T) {
+	doc := `{"data":{"price":129.99,"name":"Widget","tags":["a","b"],"stock":{"n":3}},"items":[{"id":1},{"id":2}],"odd.key":true}`
+	tests := []struct {
+		path string
+		want string
+	}{
+		{"$.data.price", "129.99"},
+		{"$.data.name", "Widget"},
+		{"$.data.tags", `["a","b"]`},
+		{"$.data.tags[1]", "b"},
+		{"$.data.stock", `{"n":3}`},
+		{"$.items[1].id", "2"},
+		{"$['odd.key']", "true"},
+		{`$["data"]["name"]`, "Widget"},
+	}
+	for _, tt := range tests {
+		got, err := selectJSON(strings.NewReader(doc), tt.path)
+		if err != nil {
+			t.Fatalf("select %q: %v", tt.path, err)
+		}
+		if got != tt.want {
+			t.Errorf("select %q = %q, want %q", tt.path, got, tt.want)
+		}
+	}
+
+	for _, path := range []string{"$.missing", "$.items[5]", "$.items.id", "$.data[0]", "$.data.name.x"} {
+		if _, err := selectJSON(strings.NewReader(doc), path); err == nil {
+			t.Errorf("select %q succeeded, want error", path)
+		}
+	}
+}
+
+func TestParseJSONPathErrors(t *testing.T) {
+	for _, p := range []string{"data", "$.", "$.*", "$[*]", "$[-1]", "$[0", "$x"} {
+		if _, err := parseJSONPath(p); err == nil {
+			t.Errorf("parseJSONPath(%q) succeeded, want error", p)
+		}
+	}
+}
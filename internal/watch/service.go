@@ -0,0 +1,257 @@
+// Package watch tracks a value extracted from a web page or JSON endpoint
+// and notifies when it changes or crosses a threshold.
+package watch
+
+import (
+	"errors"
+	"fmt"
+	"net/url"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/jdelaire/openslack/internal/state"
+)
+
+const (
+	watchNamespace = "watch"
+	metaNamespace  = "watch.meta"
+
+	DefaultInterval = time.Hour
+	MinInterval     = 5 * time.Minute
+)
+
+var (
+	ErrInvalidURL      = errors.New("watch url must be http or https")
+	ErrBothBounds      = errors.New("use either below or above, not both")
+	ErrInvalidSelector = errors.New("invalid selector")
+)
+
+// Watch is a persisted page watch. Selectors starting with "$" are JSON
+// paths; anything else is a CSS selector. With Below or Above set, only
+// threshold crossings are reported; otherwise every change is.
+type Watch struct {
+	ID          int       `json:"id"`
+	URL         string    `json:"url"`
+	Selector    string    `json:"selector"`
+	IntervalSec int       `json:"interval_sec"`
+	Below       *float64  `json:"below,omitempty"`
+	Above       *float64  `json:"above,omitempty"`
+	Value       string    `json:"value"`
+	HasValue    bool      `json:"has_value"`
+	LastChecked time.Time `json:"last_checked,omitempty"`
+	LastError   string    `json:"last_error,omitempty"`
+}
+
+// Interval returns the poll interval.
+func (w Watch) Interval() time.Duration {
+	return time.Duration(w.IntervalSec) * time.Second
+}
+
+// IsJSON reports whether the selector is a JSON path.
+func (w Watch) IsJSON() bool {
+	return strings.HasPrefix(w.Selector, "$")
+}
+
+// inRange reports whether value satisfies the threshold. Values without a
+// number never do.
+func (w Watch) inRange(value string) bool {
+	n, ok := parseNumber(value)
+	if !ok {
+		return false
+	}
+	if w.Below != nil {
+		return n < *w.Below
+	}
+	return n > *w.Above
+}
+
+func (w Watch) hasThreshold() bool {
+	return w.Below != nil || w.Above != nil
+}
+
+// String formats a watch for /watch list.
+func (w Watch) String() string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "#%d %s %s (every %s)", w.ID, w.URL, w.Selector, formatInterval(w.Interval()))
+	if w.Below != nil {
+		fmt.Fprintf(&b, " below %g", *w.Below)
+	}
+	if w.Above != nil {
+		fmt.Fprintf(&b, " above %g", *w.Above)
+	}
+	if w.HasValue {
+		fmt.Fprintf(&b, "\n  value: %s", truncate(w.Value, 100))
+	}
+	if w.LastError != "" {
+		fmt.Fprintf(&b, "\n  last error: %s", w.LastError)
+	}
+	return b.String()
+}
+
+// Service manages watches in the shared state store.
+type Service struct {
+	store *state.Store
+	now   func() time.Time
+	mu    sync.Mutex
+}
+
+func NewService(store *state.Store) *Service {
+	return &Service{
+		store: store,
+		now:   time.Now,
+	}
+}
+
+func (s *Service) WithClock(now func() time.Time) *Service {
+	if now != nil {
+		s.now = now
+	}
+	return s
+}
+
+// Add validates w, assigns it an ID, and stores it.
+func (s *Service) Add(w Watch) (Watch, error) {
+	u, err := url.Parse(w.URL)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		return Watch{}, ErrInvalidURL
+	}
+	if w.Below != nil && w.Above != nil {
+		return Watch{}, ErrBothBounds
+	}
+	var selErr error
+	if w.IsJSON() {
+		_, selErr = parseJSONPath(w.Selector)
+	} else {
+		_, selErr = parseCSS(w.Selector)
+	}
+	if selErr != nil {
+		return Watch{}, fmt.Errorf("%w: %v", ErrInvalidSelector, selErr)
+	}
+	if w.IntervalSec <= 0 {
+		w.IntervalSec = int(DefaultInterval / time.Second)
+	}
+	if w.Interval() < MinInterval {
+		w.IntervalSec = int(MinInterval / time.Second)
+	}
+	w.Value, w.HasValue, w.LastChecked, w.LastError = "", false, time.Time{}, ""
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	next := 1
+	if _, err := s.store.Get(metaNamespace, "next_id", &next); err != nil {
+		return Watch{}, err
+	}
+	w.ID = next
+	if err := s.store.Put(watchNamespace, strconv.Itoa(w.ID), w); err != nil {
+		return Watch{}, err
+	}
+	if err := s.store.Put(metaNamespace, "next_id", next+1); err != nil {
+		return Watch{}, err
+	}
+	return w, nil
+}
+
+// Remove deletes a watch. It reports false if no such watch exists.
+func (s *Service) Remove(id int) (bool, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.store.Delete(watchNamespace, strconv.Itoa(id))
+}
+
+// List returns all watches sorted by ID.
+func (s *Service) List() ([]Watch, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	keys, err := s.store.Keys(watchNamespace)
+	if err != nil {
+		return nil, err
+	}
+	watches := make([]Watch, 0, len(keys))
+	for _, k := range keys {
+		var w Watch
+		if _, err := s.store.Get(watchNamespace, k, &w); err != nil {
+			return nil, err
+		}
+		watches = append(watches, w)
+	}
+	sort.Slice(watches, func(i, j int) bool { return watches[i].ID < watches[j].ID })
+	return watches, nil
+}
+
+// Due returns watches whose interval has elapsed since the last check.
+func (s *Service) Due() ([]Watch, error) {
+	watches, err := s.List()
+	if err != nil {
+		return nil, err
+	}
+	now := s.now()
+	var due []Watch
+	for _, w := range watches {
+		if w.LastChecked.IsZero() || !now.Before(w.LastChecked.Add(w.Interval())) {
+			due = append(due, w)
+		}
+	}
+	return due, nil
+}
+
+// record stores a check result and returns the notification to send, or
+// "". The first successful check only records the value.
+func (s *Service) record(id int, value string, checkErr error) (string, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	key := strconv.Itoa(id)
+	var w Watch
+	ok, err := s.store.Get(watchNamespace, key, &w)
+	if err != nil || !ok {
+		return "", err
+	}
+	w.LastChecked = s.now()
+	if checkErr != nil {
+		w.LastError = checkErr.Error()
+		return "", s.store.Put(watchNamespace, key, w)
+	}
+	w.LastError = ""
+
+	var msg string
+	if w.HasValue && value != w.Value {
+		switch {
+		case !w.hasThreshold():
+			msg = fmt.Sprintf("#%d %s changed:\n%s\n%s", w.ID, host(w.URL), renderDiff(w.Value, value), w.URL)
+		case w.inRange(value) && !w.inRange(w.Value):
+			bound, limit := "below", w.Below
+			if w.Above != nil {
+				bound, limit = "above", w.Above
+			}
+			msg = fmt.Sprintf("#%d %s is now %s %g: %s\n%s", w.ID, host(w.URL), bound, *limit, renderDiff(w.Value, value), w.URL)
+		}
+	}
+	w.Value, w.HasValue = value, true
+	return msg, s.store.Put(watchNamespace, key, w)
+}
+
+func host(raw string) string {
+	if u, err := url.Parse(raw); err == nil && u.Host != "" {
+		return u.Host
+	}
+	return raw
+}
+
+func formatInterval(d time.Duration) string {
+	if d%time.Hour == 0 {
+		return fmt.Sprintf("%dh", d/time.Hour)
+	}
+	return fmt.Sprintf("%dm", d/time.Minute)
+}
+
+func truncate(s string, n int) string {
+	if len(s) <= n {
+		return s
+	}
+	return s[:n] + "…"
+}
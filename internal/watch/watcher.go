@@ -0,0 +1,127 @@
+package watch
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"strings"
+	"time"
+)
+
+const (
+	tickInterval = time.Minute
+	fetchTimeout = 15 * time.Second
+	maxPageBytes = 2 << 20
+)
+
+// Watcher polls due watches and sends change notifications.
+type Watcher struct {
+	service *Service
+	send    func(context.Context, string) error
+	client  *http.Client
+	admit   func() bool
+	logger  *slog.Logger
+}
+
+func NewWatcher(service *Service, send func(context.Context, string) error, logger *slog.Logger) *Watcher {
+	if logger == nil {
+		logger = slog.Default()
+	}
+	return &Watcher{
+		service: service,
+		send:    send,
+		client:  &http.Client{Timeout: fetchTimeout},
+		logger:  logger,
+	}
+}
+
+// WithGate defers checks while admit reports false, e.g. while the daemon
+// is under load. Deferred watches stay due and are checked on a later tick.
+func (w *Watcher) WithGate(admit func() bool) *Watcher {
+	w.admit = admit
+	return w
+}
+
+// Run checks due watches every minute until ctx is cancelled.
+func (w *Watcher) Run(ctx context.Context) {
+	ticker := time.NewTicker(tickInterval)
+	defer ticker.Stop()
+
+	for {
+		w.runTick(ctx)
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+func (w *Watcher) runTick(ctx context.Context) {
+	due, err := w.service.Due()
+	if err != nil {
+		w.logger.Error("watch: list due failed", "error", err)
+		return
+	}
+	for _, wt := range due {
+		if w.admit != nil && !w.admit() {
+			w.logger.Debug("watch: deferring due checks under load", "watches", len(due))
+			return
+		}
+		value, err := w.Extract(ctx, wt)
+		if err != nil {
+			w.logger.Warn("watch: check failed", "id", wt.ID, "error", err)
+		}
+		msg, recErr := w.service.record(wt.ID, value, err)
+		if recErr != nil {
+			w.logger.Error("watch: record check failed", "id", wt.ID, "error", recErr)
+		}
+		if msg == "" {
+			continue
+		}
+		sendCtx, cancel := context.WithTimeout(ctx, 10*time.Second)
+		if err := w.send(sendCtx, msg); err != nil {
+			w.logger.Error("watch: send notification failed", "id", wt.ID, "error", err)
+		}
+		cancel()
+	}
+}
+
+// Extract fetches the watch's URL and returns the selected value. Multiple
+// CSS matches are joined one per line.
+func (w *Watcher) Extract(ctx context.Context, wt Watch) (string, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, wt.URL, nil)
+	if err != nil {
+		return "", fmt.Errorf("create request: %w", err)
+	}
+	req.Header.Set("User-Agent", "openslack-watch")
+
+	resp, err := w.client.Do(req)
+	if err != nil {
+		return "", fmt.Errorf("fetch: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return "", fmt.Errorf("fetch: HTTP %d", resp.StatusCode)
+	}
+	data, err := io.ReadAll(io.LimitReader(resp.Body, maxPageBytes))
+	if err != nil {
+		return "", fmt.Errorf("read: %w", err)
+	}
+
+	if wt.IsJSON() {
+		return selectJSON(bytes.NewReader(data), wt.Selector)
+	}
+	matches, err := selectCSS(bytes.NewReader(data), wt.Selector)
+	if err != nil {
+		return "", err
+	}
+	if len(matches) == 0 {
+		return "", fmt.Errorf("selector %q matched nothing", wt.Selector)
+	}
+	return strings.Join(matches, "\n"), nil
+}
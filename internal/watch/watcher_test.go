@@ -0,0 +1,176 @@
+package watch
+
+import (
+	"context"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/jdelaire/openslack/internal/state"
+)
+
+type watchTest struct {
+	svc  *Service
+	w    *Watcher
+	url  string
+	body *string
+	sent *[]string
+	now  *time.Time
+}
+
+func newWatchTest(t *testing.T) watchTest {
+	t.Helper()
+	body := ""
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if body == "" {
+			w.WriteHeader(http.StatusBadGateway)
+			return
+		}
+		w.Write([]byte(body))
+	}))
+	t.Cleanup(srv.Close)
+
+	now := time.Date(2026, 3, 1, 10, 0, 0, 0, time.UTC)
+	svc := NewService(state.NewStore(filepath.Join(t.TempDir(), "state.json"))).WithClock(func() time.Time { return now })
+	var sent []string
+	w := NewWatcher(svc, func(_ context.Context, text string) error {
+		sent = append(sent, text)
+		return nil
+	}, slog.New(slog.NewJSONHandler(io.Discard, nil)))
+	return watchTest{svc: svc, w: w, url: srv.URL, body: &body, sent: &sent, now: &now}
+}
+
+// tick advances past the interval and runs one check.
+func (wt watchTest) tick(t *testing.T, body string) {
+	t.Helper()
+	*wt.body = body
+	*wt.now = wt.now.Add(DefaultInterval)
+	wt.w.runTick(context.Background())
+}
+
+func page(price string) string {
+	return `<html><body><span class="price">` + price + `</span></body></html>`
+}
+
+func TestWatcherNotifiesOnChange(t *testing.T) {
+	wt := newWatchTest(t)
+	if _, err := wt.svc.Add(Watch{URL: wt.url, Selector: ".price"}); err != nil {
+		t.Fatalf("add: %v", err)
+	}
+
+	wt.tick(t, page("$129.99"))
+	if len(*wt.sent) != 0 {
+		t.Fatalf("first check sent %q", *wt.sent)
+	}
+
+	wt.tick(t, page("$129.99"))
+	if len(*wt.sent) != 0 {
+		t.Fatalf("unchanged value sent %q", *wt.sent)
+	}
+
+	wt.tick(t, page("$119.99"))
+	if len(*wt.sent) != 1 {
+		t.Fatalf("sent %d messages, want 1", len(*wt.sent))
+	}
+	if !strings.Contains((*wt.sent)[0], "$129.99 → $119.99 (-7.7%)") {
+		t.Errorf("message = %q", (*wt.sent)[0])
+	}
+}
+
+func TestWatcherThreshold(t *testing.T) {
+	wt := newWatchTest(t)
+	below := 100.0
+	if _, err := wt.svc.Add(Watch{URL: wt.url, Selector: "$.price", Below: &below}); err != nil {
+		t.Fatalf("add: %v", err)
+	}
+
+	for _, body := range []string{`{"price":120}`, `{"price":110}`, `{"price":95}`, `{"price":90}`, `{"price":105}`, `{"price":99}`} {
+		wt.tick(t, body)
+	}
+	// Crossings only: 110 -> 95 and 105 -> 99.
+	if len(*wt.sent) != 2 {
+		t.Fatalf("sent %q, want 2 messages", *wt.sent)
+	}
+	if !strings.Contains((*wt.sent)[0], "is now below 100: 110 → 95") {
+		t.Errorf("message = %q", (*wt.sent)[0])
+	}
+}
+
+func TestWatcherRecordsErrors(t *testing.T) {
+	wt := newWatchTest(t)
+	if _, err := wt.svc.Add(Watch{URL: wt.url, Selector: ".missing"}); err != nil {
+		t.Fatalf("add: %v", err)
+	}
+
+	wt.tick(t, page("$1"))
+	watches, err := wt.svc.List()
+	if err != nil {
+		t.Fatalf("list: %v", err)
+	}
+	if !strings.Contains(watches[0].LastError, "matched nothing") {
+		t.Errorf("last error = %q", watches[0].LastError)
+	}
+
+	wt.tick(t, "")
+	watches, _ = wt.svc.List()
+	if !strings.Contains(watches[0].LastError, "HTTP 502") {
+		t.Errorf("last error = %q", watches[0].LastError)
+	}
+	if len(*wt.sent) != 0 {
+		t.Errorf("errors sent %q", *wt.sent)
+	}
+}
+
+func TestServiceAdd(t *testing.T) {
+	svc := NewService(state.NewStore(filepath.Join(t.TempDir(), "state.json")))
+	one := 1.0
+	tests := []struct {
+		w    Watch
+		want error
+	}{
+		{Watch{URL: "ftp://example.com", Selector: "h1"}, ErrInvalidURL},
+		{Watch{URL: "https://example.com", Selector: "a:hover"}, ErrInvalidSelector},
+		{Watch{URL: "https://example.com", Selector: "$.a[*]"}, ErrInvalidSelector},
+		{Watch{URL: "https://example.com", Selector: "$.a", Below: &one, Above: &one}, ErrBothBounds},
+	}
+	for _, tt := range tests {
+		if _, err := svc.Add(tt.w); err == nil || !strings.Contains(err.Error(), tt.want.Error()) {
+			t.Errorf("add %+v: err = %v, want %v", tt.w, err, tt.want)
+		}
+	}
+
+	a, err := svc.Add(Watch{URL: "https://example.com", Selector: "h1", IntervalSec: 60})
+	if err != nil {
+		t.Fatalf("add: %v", err)
+	}
+	b, err := svc.Add(Watch{URL: "https://example.com", Selector: "h2"})
+	if err != nil {
+		t.Fatalf("add: %v", err)
+	}
+	if a.ID != 1 || b.ID != 2 {
+		t.Errorf("ids = %d, %d, want 1, 2", a.ID, b.ID)
+	}
+	if a.Interval() != MinInterval || b.Interval() != DefaultInterval {
+		t.Errorf("intervals = %s, %s", a.Interval(), b.Interval())
+	}
+}
+
+func TestRenderDiff(t *testing.T) {
+	tests := []struct {
+		old, new, want string
+	}{
+		{"$1,000", "$1,250", "$1,000 → $1,250 (+25.0%)"},
+		{"In stock", "Sold out", "In stock → Sold out"},
+		{"a\nb\nc", "a\nc\nd", "- b\n+ d"},
+	}
+	for _, tt := range tests {
+		if got := renderDiff(tt.old, tt.new); got != tt.want {
+			t.Errorf("renderDiff(%q, %q) = %q, want %q", tt.old, tt.new, got, tt.want)
+		}
+	}
+}
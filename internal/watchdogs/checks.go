@@ -0,0 +1,99 @@
+package watchdogs
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/pem"
+	"fmt"
+	"net"
+	"os"
+	"time"
+)
+
+const dialTimeout = 10 * time.Second
+
+// checkDisk returns a problem description if free space is below the
+// threshold, or "" if the disk is fine.
+func checkDisk(dc DiskConfig) (string, error) {
+	free, err := freePercent(dc.Path)
+	if err != nil {
+		return "", err
+	}
+	if free < dc.MinFreePercent {
+		return fmt.Sprintf("%.1f%% free on %s (threshold %g%%)", free, dc.Path, dc.MinFreePercent), nil
+	}
+	return "", nil
+}
+
+// checkCert returns a problem description if the certificate expires
+// within WarnDays, or "" if it is fine.
+func checkCert(ctx context.Context, cc CertConfig, now time.Time) (string, error) {
+	var (
+		cert *x509.Certificate
+		err  error
+		what string
+	)
+	if cc.Endpoint != "" {
+		cert, err = endpointCert(ctx, cc.Endpoint)
+		what = cc.Endpoint
+	} else {
+		cert, err = fileCert(cc.File)
+		what = cc.File
+	}
+	if err != nil {
+		return "", err
+	}
+
+	left := cert.NotAfter.Sub(now)
+	if left <= 0 {
+		return fmt.Sprintf("certificate for %s expired on %s", what, cert.NotAfter.Format("2006-01-02")), nil
+	}
+	if left < time.Duration(cc.WarnDays)*24*time.Hour {
+		return fmt.Sprintf("certificate for %s expires in %d days (%s)", what, int(left.Hours()/24), cert.NotAfter.Format("2006-01-02")), nil
+	}
+	return "", nil
+}
+
+// endpointCert fetches the leaf certificate. Verification is skipped on
+// purpose: the point is to read the expiry, including of a cert that has
+// already lapsed or is self-signed.
+func endpointCert(ctx context.Context, endpoint string) (*x509.Certificate, error) {
+	host, _, _ := net.SplitHostPort(endpoint)
+	d := tls.Dialer{
+		NetDialer: &net.Dialer{Timeout: dialTimeout},
+		Config:    &tls.Config{ServerName: host, InsecureSkipVerify: true},
+	}
+	conn, err := d.DialContext(ctx, "tcp", endpoint)
+	if err != nil {
+		return nil, fmt.Errorf("tls dial %s: %w", endpoint, err)
+	}
+	defer conn.Close()
+
+	certs := conn.(*tls.Conn).ConnectionState().PeerCertificates
+	if len(certs) == 0 {
+		return nil, fmt.Errorf("tls dial %s: no certificate presented", endpoint)
+	}
+	return certs[0], nil
+}
+
+func fileCert(path string) (*x509.Certificate, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("read cert: %w", err)
+	}
+	for {
+		var block *pem.Block
+		block, data = pem.Decode(data)
+		if block == nil {
+			return nil, fmt.Errorf("no certificate found in %s", path)
+		}
+		if block.Type == "CERTIFICATE" {
+			cert, err := x509.ParseCertificate(block.Bytes)
+			if err != nil {
+				return nil, fmt.Errorf("parse cert %s: %w", path, err)
+			}
+			return cert, nil
+		}
+	}
+}
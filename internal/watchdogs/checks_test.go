@@ -0,0 +1,110 @@
+package watchdogs
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func writeCert(t *testing.T, notAfter time.Time) string {
+	t.Helper()
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatalf("generate key: %v", err)
+	}
+	tmpl := &x509.Certificate{
+		SerialNumber: big.NewInt(1),
+		Subject:      pkix.Name{CommonName: "test"},
+		NotBefore:    notAfter.Add(-365 * 24 * time.Hour),
+		NotAfter:     notAfter,
+	}
+	der, err := x509.CreateCertificate(rand.Reader, tmpl, tmpl, &key.PublicKey, key)
+	if err != nil {
+		t.Fatalf("create cert: %v", err)
+	}
+	path := filepath.Join(t.TempDir(), "cert.pem")
+	keyDER, _ := x509.MarshalECPrivateKey(key)
+	data := pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: keyDER})
+	data = append(data, pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der})...)
+	os.WriteFile(path, data, 0644)
+	return path
+}
+
+func TestCheckCertFile(t *testing.T) {
+	now := time.Date(2026, 3, 1, 0, 0, 0, 0, time.UTC)
+
+	tests := []struct {
+		name     string
+		notAfter time.Time
+		want     string
+	}{
+		{"healthy", now.Add(60 * 24 * time.Hour), ""},
+		{"expiring", now.Add(9*24*time.Hour + time.Hour), "expires in 9 days (2026-03-10)"},
+		{"expired", now.Add(-24 * time.Hour), "expired on 2026-02-28"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cc := CertConfig{File: writeCert(t, tt.notAfter), WarnDays: 14}
+			got, err := checkCert(context.Background(), cc, now)
+			if err != nil {
+				t.Fatalf("checkCert: %v", err)
+			}
+			if tt.want == "" && got != "" || !strings.Contains(got, tt.want) {
+				t.Errorf("problem = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestCheckCertEndpoint(t *testing.T) {
+	srv := httptest.NewTLSServer(http.HandlerFunc(func(http.ResponseWriter, *http.Request) {}))
+	defer srv.Close()
+
+	endpoint := strings.TrimPrefix(srv.URL, "https://")
+	leaf := srv.Certificate()
+
+	// Self-signed test cert: verification is skipped, expiry is still read.
+	got, err := checkCert(context.Background(), CertConfig{Endpoint: endpoint, WarnDays: 14}, leaf.NotAfter.Add(-7*24*time.Hour))
+	if err != nil {
+		t.Fatalf("checkCert: %v", err)
+	}
+	if !strings.Contains(got, "expires in 7 days") {
+		t.Errorf("problem = %q", got)
+	}
+}
+
+func TestCheckDisk(t *testing.T) {
+	dir := t.TempDir()
+
+	got, err := checkDisk(DiskConfig{Path: dir, MinFreePercent: 0.0001})
+	if err != nil {
+		t.Fatalf("checkDisk: %v", err)
+	}
+	if got != "" {
+		t.Errorf("problem at tiny threshold = %q", got)
+	}
+
+	got, err = checkDisk(DiskConfig{Path: dir, MinFreePercent: 99.9999})
+	if err != nil {
+		t.Fatalf("checkDisk: %v", err)
+	}
+	if !strings.Contains(got, "free on "+dir) {
+		t.Errorf("problem at huge threshold = %q", got)
+	}
+
+	if _, err := checkDisk(DiskConfig{Path: filepath.Join(dir, "missing"), MinFreePercent: 10}); err == nil {
+		t.Error("expected error for missing path")
+	}
+}
@@ -0,0 +1,132 @@
+package watchdogs
+
+import (
+	"encoding/json"
+	"fmt"
+	"net"
+	"os"
+	"strings"
+)
+
+// Defaults.
+const (
+	DefaultDiskIntervalSec = 300
+	DefaultMinFreePercent  = 10
+	DefaultCertIntervalSec = 6 * 3600
+	DefaultWarnDays        = 14
+	DefaultRepeatHours     = 24
+)
+
+// Config is the top-level watchdogs configuration. Names must be unique
+// across disks and certs since /ack addresses them by name.
+type Config struct {
+	Disks       map[string]DiskConfig `json:"disks"`
+	Certs       map[string]CertConfig `json:"certs"`
+	RepeatHours int                   `json:"repeat_hours"`
+}
+
+// DiskConfig alerts when free space on the filesystem holding Path drops
+// below MinFreePercent.
+type DiskConfig struct {
+	Path           string  `json:"path"`
+	MinFreePercent float64 `json:"min_free_percent"`
+	IntervalSec    int     `json:"interval_sec"`
+}
+
+// CertConfig alerts WarnDays before a certificate expires. Exactly one of
+// Endpoint (host:port, fetched over TLS) or File (PEM) must be set.
+type CertConfig struct {
+	Endpoint    string `json:"endpoint"`
+	File        string `json:"file"`
+	WarnDays    int    `json:"warn_days"`
+	IntervalSec int    `json:"interval_sec"`
+}
+
+// LoadConfig reads and validates a watchdogs config file.
+// Returns nil, nil if the file does not exist.
+func LoadConfig(path string) (*Config, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("read watchdogs config: %w", err)
+	}
+
+	var cfg Config
+	if err := json.Unmarshal(data, &cfg); err != nil {
+		return nil, fmt.Errorf("parse watchdogs config: %w", err)
+	}
+
+	if err := validateConfig(&cfg); err != nil {
+		return nil, err
+	}
+
+	applyDefaults(&cfg)
+	return &cfg, nil
+}
+
+func validateConfig(cfg *Config) error {
+	for name, dc := range cfg.Disks {
+		if err := validName(name); err != nil {
+			return err
+		}
+		if dc.Path == "" {
+			return fmt.Errorf("disk watchdog %q missing path", name)
+		}
+		if dc.MinFreePercent < 0 || dc.MinFreePercent >= 100 {
+			return fmt.Errorf("disk watchdog %q: min_free_percent must be between 0 and 100", name)
+		}
+	}
+	for name, cc := range cfg.Certs {
+		if err := validName(name); err != nil {
+			return err
+		}
+		if _, dup := cfg.Disks[name]; dup {
+			return fmt.Errorf("watchdog name %q used for both a disk and a cert", name)
+		}
+		if (cc.Endpoint == "") == (cc.File == "") {
+			return fmt.Errorf("cert watchdog %q needs exactly one of endpoint or file", name)
+		}
+		if cc.Endpoint != "" {
+			if _, _, err := net.SplitHostPort(cc.Endpoint); err != nil {
+				return fmt.Errorf("cert watchdog %q: endpoint must be host:port", name)
+			}
+		}
+		if cc.WarnDays < 0 {
+			return fmt.Errorf("cert watchdog %q: warn_days must not be negative", name)
+		}
+	}
+	return nil
+}
+
+func validName(name string) error {
+	if name == "" || strings.ContainsAny(name, " \t\n") {
+		return fmt.Errorf("invalid watchdog name %q", name)
+	}
+	return nil
+}
+
+func applyDefaults(cfg *Config) {
+	for name, dc := range cfg.Disks {
+		if dc.IntervalSec <= 0 {
+			dc.IntervalSec = DefaultDiskIntervalSec
+		}
+		if dc.MinFreePercent == 0 {
+			dc.MinFreePercent = DefaultMinFreePercent
+		}
+		cfg.Disks[name] = dc
+	}
+	for name, cc := range cfg.Certs {
+		if cc.IntervalSec <= 0 {
+			cc.IntervalSec = DefaultCertIntervalSec
+		}
+		if cc.WarnDays == 0 {
+			cc.WarnDays = DefaultWarnDays
+		}
+		cfg.Certs[name] = cc
+	}
+	if cfg.RepeatHours <= 0 {
+		cfg.RepeatHours = DefaultRepeatHours
+	}
+}
@@ -0,0 +1,63 @@
+package watchdogs
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestLoadConfigDefaults(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "watchdogs.json")
+	os.WriteFile(path, []byte(`{
+		"disks": {"root": {"path": "/"}},
+		"certs": {"site": {"endpoint": "example.com:443", "warn_days": 30}}
+	}`), 0644)
+
+	cfg, err := LoadConfig(path)
+	if err != nil {
+		t.Fatalf("LoadConfig: %v", err)
+	}
+	if d := cfg.Disks["root"]; d.MinFreePercent != DefaultMinFreePercent || d.IntervalSec != DefaultDiskIntervalSec {
+		t.Errorf("disk = %+v", d)
+	}
+	if c := cfg.Certs["site"]; c.WarnDays != 30 || c.IntervalSec != DefaultCertIntervalSec {
+		t.Errorf("cert = %+v", c)
+	}
+	if cfg.RepeatHours != DefaultRepeatHours {
+		t.Errorf("repeat_hours = %d", cfg.RepeatHours)
+	}
+}
+
+func TestLoadConfigMissingFile(t *testing.T) {
+	cfg, err := LoadConfig(filepath.Join(t.TempDir(), "missing.json"))
+	if err != nil || cfg != nil {
+		t.Fatalf("LoadConfig = %v, %v; want nil, nil", cfg, err)
+	}
+}
+
+func TestLoadConfigInvalid(t *testing.T) {
+	tests := []struct {
+		name    string
+		data    string
+		wantErr string
+	}{
+		{"disk without path", `{"disks":{"root":{}}}`, "missing path"},
+		{"bad percent", `{"disks":{"root":{"path":"/","min_free_percent":100}}}`, "between 0 and 100"},
+		{"cert without source", `{"certs":{"site":{}}}`, "exactly one of endpoint or file"},
+		{"cert with both", `{"certs":{"site":{"endpoint":"a:443","file":"/x.pem"}}}`, "exactly one of endpoint or file"},
+		{"endpoint without port", `{"certs":{"site":{"endpoint":"example.com"}}}`, "host:port"},
+		{"duplicate name", `{"disks":{"x":{"path":"/"}},"certs":{"x":{"file":"/x.pem"}}}`, "both a disk and a cert"},
+	}
+	dir := t.TempDir()
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			path := filepath.Join(dir, "watchdogs.json")
+			os.WriteFile(path, []byte(tt.data), 0644)
+			_, err := LoadConfig(path)
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("error = %v, want %q", err, tt.wantErr)
+			}
+		})
+	}
+}
@@ -0,0 +1,9 @@
+//go:build !darwin && !linux
+
+package watchdogs
+
+import "fmt"
+
+func freePercent(path string) (float64, error) {
+	return 0, fmt.Errorf("disk watchdog not supported on this platform")
+}
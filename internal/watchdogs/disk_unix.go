@@ -0,0 +1,21 @@
+//go:build darwin || linux
+
+package watchdogs
+
+import (
+	"fmt"
+	"syscall"
+)
+
+// freePercent returns the percentage of space available to unprivileged
+// users on the filesystem holding path.
+func freePercent(path string) (float64, error) {
+	var st syscall.Statfs_t
+	if err := syscall.Statfs(path, &st); err != nil {
+		return 0, fmt.Errorf("statfs %s: %w", path, err)
+	}
+	if st.Blocks == 0 {
+		return 0, fmt.Errorf("statfs %s: filesystem reports no blocks", path)
+	}
+	return float64(st.Bavail) / float64(st.Blocks) * 100, nil
+}
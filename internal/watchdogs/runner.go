@@ -0,0 +1,217 @@
+package watchdogs
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/jdelaire/openslack/internal/state"
+)
+
+const stateNamespace = "watchdogs"
+
+var ErrUnknownWatchdog = errors.New("unknown watchdog")
+
+// State is the persisted state of one watchdog.
+type State struct {
+	Alerting  bool      `json:"alerting"`
+	Acked     bool      `json:"acked"`
+	Problem   string    `json:"problem,omitempty"`
+	LastAlert time.Time `json:"last_alert,omitempty"`
+	LastCheck time.Time `json:"last_check,omitempty"`
+}
+
+// probe returns a problem description, or "" if all is well.
+type probe func(ctx context.Context, now time.Time) (string, error)
+
+type watchdog struct {
+	name     string
+	interval time.Duration
+	probe    probe
+}
+
+// Runner schedules watchdog checks. An alert is sent when a check starts
+// failing and repeated every RepeatHours until it clears or is acked.
+type Runner struct {
+	dogs   []watchdog
+	repeat time.Duration
+	store  *state.Store
+	send   func(context.Context, string) error
+	logger *slog.Logger
+	now    func() time.Time
+
+	mu sync.Mutex
+}
+
+func NewRunner(cfg *Config, store *state.Store, send func(context.Context, string) error, logger *slog.Logger) *Runner {
+	var dogs []watchdog
+	for name, dc := range cfg.Disks {
+		dogs = append(dogs, watchdog{
+			name:     name,
+			interval: time.Duration(dc.IntervalSec) * time.Second,
+			probe: func(context.Context, time.Time) (string, error) {
+				return checkDisk(dc)
+			},
+		})
+	}
+	for name, cc := range cfg.Certs {
+		dogs = append(dogs, watchdog{
+			name:     name,
+			interval: time.Duration(cc.IntervalSec) * time.Second,
+			probe: func(ctx context.Context, now time.Time) (string, error) {
+				return checkCert(ctx, cc, now)
+			},
+		})
+	}
+	sort.Slice(dogs, func(i, j int) bool { return dogs[i].name < dogs[j].name })
+	return newRunner(dogs, time.Duration(cfg.RepeatHours)*time.Hour, store, send, logger)
+}
+
+func newRunner(dogs []watchdog, repeat time.Duration, store *state.Store, send func(context.Context, string) error, logger *slog.Logger) *Runner {
+	if logger == nil {
+		logger = slog.Default()
+	}
+	return &Runner{
+		dogs:   dogs,
+		repeat: repeat,
+		store:  store,
+		send:   send,
+		logger: logger,
+		now:    time.Now,
+	}
+}
+
+// Run checks every watchdog on its own interval until ctx is cancelled.
+func (r *Runner) Run(ctx context.Context) {
+	var wg sync.WaitGroup
+	for _, d := range r.dogs {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			ticker := time.NewTicker(d.interval)
+			defer ticker.Stop()
+			for {
+				r.runCheck(ctx, d)
+				select {
+				case <-ctx.Done():
+					return
+				case <-ticker.C:
+				}
+			}
+		}()
+	}
+	wg.Wait()
+}
+
+func (r *Runner) runCheck(ctx context.Context, d watchdog) {
+	problem, err := d.probe(ctx, r.now())
+	if ctx.Err() != nil {
+		return
+	}
+	if err != nil {
+		problem = "check failed: " + err.Error()
+	}
+
+	msg := r.record(d.name, problem)
+	if msg == "" {
+		return
+	}
+	sendCtx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	if err := r.send(sendCtx, msg); err != nil {
+		r.logger.Error("watchdogs: send notification failed", "watchdog", d.name, "error", err)
+	}
+}
+
+// record applies a check result and returns the notification to send, or "".
+func (r *Runner) record(name, problem string) string {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	now := r.now()
+	st := r.load(name)
+	st.LastCheck = now
+
+	var msg string
+	switch {
+	case problem == "":
+		if st.Alerting {
+			msg = fmt.Sprintf("%s: resolved", name)
+		}
+		st = State{LastCheck: now}
+	case !st.Alerting, !st.Acked && now.Sub(st.LastAlert) >= r.repeat:
+		st.Alerting, st.LastAlert = true, now
+		msg = fmt.Sprintf("%s: %s\nReply /ack %s to silence", name, problem, name)
+	}
+	if problem != "" {
+		st.Problem = problem
+	}
+
+	r.save(name, st)
+	return msg
+}
+
+// Ack silences an alerting watchdog until its check passes again. It
+// reports false if the watchdog is not alerting.
+func (r *Runner) Ack(name string) (bool, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if !r.has(name) {
+		return false, ErrUnknownWatchdog
+	}
+	st := r.load(name)
+	if !st.Alerting {
+		return false, nil
+	}
+	st.Acked = true
+	r.save(name, st)
+	return true, nil
+}
+
+// Alerting lists alerting watchdogs as "name: problem", marking acked ones.
+func (r *Runner) Alerting() []string {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	var lines []string
+	for _, d := range r.dogs {
+		st := r.load(d.name)
+		if !st.Alerting {
+			continue
+		}
+		line := fmt.Sprintf("%s: %s", d.name, st.Problem)
+		if st.Acked {
+			line += " (acked)"
+		}
+		lines = append(lines, line)
+	}
+	return lines
+}
+
+func (r *Runner) has(name string) bool {
+	for _, d := range r.dogs {
+		if d.name == name {
+			return true
+		}
+	}
+	return false
+}
+
+func (r *Runner) load(name string) State {
+	var st State
+	if _, err := r.store.Get(stateNamespace, name, &st); err != nil {
+		r.logger.Error("watchdogs: load state failed", "watchdog", name, "error", err)
+	}
+	return st
+}
+
+func (r *Runner) save(name string, st State) {
+	if err := r.store.Put(stateNamespace, name, st); err != nil {
+		r.logger.Error("watchdogs: save state failed", "watchdog", name, "error", err)
+	}
+}
@@ -0,0 +1,98 @@
+package watchdogs
+
+import (
+	"context"
+	"io"
+	"log/slog"
+	"path/filepath"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/jdelaire/openslack/internal/state"
+)
+
+type runnerTest struct {
+	r       *Runner
+	dog     watchdog
+	problem *string
+	sent    *[]string
+	now     *time.Time
+}
+
+func newRunnerTest(t *testing.T) runnerTest {
+	t.Helper()
+	var problem string
+	dog := watchdog{name: "root", interval: time.Minute, probe: func(context.Context, time.Time) (string, error) {
+		return problem, nil
+	}}
+	var sent []string
+	now := time.Date(2026, 3, 1, 10, 0, 0, 0, time.UTC)
+	r := newRunner([]watchdog{dog}, 24*time.Hour, state.NewStore(filepath.Join(t.TempDir(), "state.json")),
+		func(_ context.Context, text string) error {
+			sent = append(sent, text)
+			return nil
+		}, slog.New(slog.NewJSONHandler(io.Discard, nil)))
+	r.now = func() time.Time { return now }
+	return runnerTest{r: r, dog: dog, problem: &problem, sent: &sent, now: &now}
+}
+
+func (rt runnerTest) tickAfter(d time.Duration) {
+	*rt.now = rt.now.Add(d)
+	rt.r.runCheck(context.Background(), rt.dog)
+}
+
+func TestRunnerAlertRepeatAndResolve(t *testing.T) {
+	rt := newRunnerTest(t)
+
+	rt.tickAfter(0)
+	*rt.problem = "4.0% free on / (threshold 10%)"
+	rt.tickAfter(time.Minute)
+	rt.tickAfter(time.Hour)
+	rt.tickAfter(24 * time.Hour)
+	*rt.problem = ""
+	rt.tickAfter(time.Minute)
+
+	want := []string{
+		"root: 4.0% free on / (threshold 10%)\nReply /ack root to silence",
+		"root: 4.0% free on / (threshold 10%)\nReply /ack root to silence",
+		"root: resolved",
+	}
+	if !reflect.DeepEqual(*rt.sent, want) {
+		t.Errorf("sent = %q, want %q", *rt.sent, want)
+	}
+}
+
+func TestRunnerAckSuppressesUntilRecovery(t *testing.T) {
+	rt := newRunnerTest(t)
+
+	if ok, err := rt.r.Ack("root"); ok || err != nil {
+		t.Fatalf("ack while healthy = %v, %v", ok, err)
+	}
+	if _, err := rt.r.Ack("nope"); err != ErrUnknownWatchdog {
+		t.Fatalf("ack unknown = %v", err)
+	}
+
+	*rt.problem = "expires in 9 days"
+	rt.tickAfter(0)
+	if ok, err := rt.r.Ack("root"); !ok || err != nil {
+		t.Fatalf("ack = %v, %v", ok, err)
+	}
+	if got := rt.r.Alerting(); !reflect.DeepEqual(got, []string{"root: expires in 9 days (acked)"}) {
+		t.Errorf("alerting = %q", got)
+	}
+
+	rt.tickAfter(48 * time.Hour)
+	if len(*rt.sent) != 1 {
+		t.Fatalf("acked alert repeated: %q", *rt.sent)
+	}
+
+	// Recovery clears the ack, so a new problem alerts again.
+	*rt.problem = ""
+	rt.tickAfter(time.Minute)
+	*rt.problem = "expires in 3 days"
+	rt.tickAfter(time.Minute)
+	if len(*rt.sent) != 3 || (*rt.sent)[2] != "root: expires in 3 days\nReply /ack root to silence" {
+		t.Errorf("sent = %q", *rt.sent)
+	}
+}